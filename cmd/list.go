@@ -26,13 +26,13 @@ import (
 	"github.com/pingcap/tiup/pkg/tui"
 	"github.com/pingcap/tiup/pkg/version"
 	"github.com/spf13/cobra"
-	"golang.org/x/mod/semver"
 )
 
 type listOptions struct {
 	installedOnly bool
 	verbose       bool
 	showAll       bool
+	showNotes     string // the version to show the release notes of
 }
 
 func newListCmd() *cobra.Command {
@@ -49,7 +49,10 @@ components or versions which have not been installed.
   tiup list --installed
 
   # List all installed versions of TiDB
-  tiup list tidb --installed`,
+  tiup list tidb --installed
+
+  # Show the release notes of TiDB v4.0.0
+  tiup list tidb --show-notes v4.0.0`,
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -71,7 +74,8 @@ components or versions which have not been installed.
 
 	cmd.Flags().BoolVar(&opt.installedOnly, "installed", false, "List installed components only.")
 	cmd.Flags().BoolVar(&opt.verbose, "verbose", false, "Show detailed component information.")
-	cmd.Flags().BoolVar(&opt.showAll, "all", false, "Show all components include hidden ones.")
+	cmd.Flags().BoolVar(&opt.showAll, "all", false, "Show all components include hidden ones, and the yanked versions of a component.")
+	cmd.Flags().StringVar(&opt.showNotes, "show-notes", "", "Show the release date and notes of the `version` of the component.")
 
 	return cmd
 }
@@ -79,6 +83,7 @@ components or versions which have not been installed.
 type listResult struct {
 	header   string
 	cmpTable [][]string
+	footer   string
 }
 
 func (lr *listResult) print() {
@@ -87,6 +92,9 @@ func (lr *listResult) print() {
 	}
 	fmt.Printf(lr.header)
 	tui.PrintTable(lr.cmpTable, true)
+	if lr.footer != "" {
+		fmt.Printf("\n%s", lr.footer)
+	}
 }
 
 func showComponentList(env *environment.Environment, opt listOptions) (*listResult, error) {
@@ -178,11 +186,15 @@ func showComponentList(env *environment.Environment, opt listOptions) (*listResu
 func showComponentVersions(env *environment.Environment, component string, opt listOptions) (*listResult, error) {
 	var comp *v1manifest.Component
 	var err error
-	comp, err = env.V1Repository().FetchComponentManifest(component, false)
+	comp, err = env.V1Repository().FetchComponentManifest(component, opt.showAll)
 	if err != nil {
 		return nil, errors.Annotate(err, "failed to fetch component")
 	}
+	return componentVersions(env, component, comp, opt)
+}
 
+// componentVersions lists the versions in the manifest of the component
+func componentVersions(env *environment.Environment, component string, comp *v1manifest.Component, opt listOptions) (*listResult, error) {
 	versions, err := env.Profile().InstalledVersions(component)
 	if err != nil {
 		return nil, err
@@ -190,20 +202,37 @@ func showComponentVersions(env *environment.Environment, component string, opt l
 	installed := set.NewStringSet(versions...)
 
 	var cmpTable [][]string
-	cmpTable = append(cmpTable, []string{"Version", "Installed", "Release", "Platforms"})
+	if opt.verbose {
+		cmpTable = append(cmpTable, []string{"Version", "Installed", "Installed At", "Release", "Platforms", "Entry Points", "Release Notes"})
+	} else {
+		cmpTable = append(cmpTable, []string{"Version", "Installed", "Release", "Platforms"})
+	}
 
 	platforms := make(map[string][]string)
 	released := make(map[string]string)
+	notes := make(map[string]string)
+	entries := make(map[string]string)
+	yanked := set.NewStringSet()
 
 	for plat := range comp.Platforms {
 		versions := comp.VersionList(plat)
+		if opt.showAll {
+			versions = comp.VersionListWithYanked(plat)
+		}
 		for ver, verinfo := range versions {
 			if v0manifest.Version(ver).IsNightly() && ver == comp.Nightly {
-				platforms[version.NightlyVersion] = append(platforms[version.NightlyVersion], plat)
-				released[version.NightlyVersion] = verinfo.Released
-			} else {
-				platforms[ver] = append(platforms[ver], plat)
-				released[ver] = verinfo.Released
+				ver = version.NightlyVersion
+			}
+			if verinfo.Yanked {
+				yanked.Insert(ver)
+			}
+			platforms[ver] = append(platforms[ver], plat)
+			released[ver] = verinfo.Released
+			if verinfo.ReleaseNotes != "" {
+				notes[ver] = verinfo.ReleaseNotes
+			}
+			if entries[ver] == "" || plat == env.V1Repository().PlatformString() {
+				entries[ver] = strings.Join(verinfo.EntryPoints(), ",")
 			}
 		}
 	}
@@ -218,7 +247,7 @@ func showComponentVersions(env *environment.Environment, component string, opt l
 		verList = append(verList, version.NightlyVersion)
 	}
 	sort.Slice(verList, func(p, q int) bool {
-		return semver.Compare(verList[p], verList[q]) < 0
+		return version.Compare(verList[p], verList[q]) < 0
 	})
 
 	for _, v := range verList {
@@ -230,11 +259,44 @@ func showComponentVersions(env *environment.Environment, component string, opt l
 				continue
 			}
 		}
-		cmpTable = append(cmpTable, []string{v, installStatus, released[v], strings.Join(platforms[v], ",")})
+		name := v
+		if yanked.Exist(v) {
+			name += " (yanked)"
+		}
+		row := []string{name, installStatus}
+		if opt.verbose {
+			row = append(row, installTime(env, component, v))
+		}
+		row = append(row, released[v], strings.Join(platforms[v], ","))
+		if opt.verbose {
+			row = append(row, entries[v], notes[v])
+		}
+		cmpTable = append(cmpTable, row)
 	}
 
-	return &listResult{
+	result := &listResult{
 		header:   fmt.Sprintf("Available versions for %s:\n", component),
 		cmpTable: cmpTable,
-	}, nil
+	}
+	if v := opt.showNotes; v != "" {
+		if _, found := platforms[v]; !found {
+			return result, errors.Errorf("version %s of %s is not found", v, component)
+		}
+		note := notes[v]
+		if note == "" {
+			note = "(not available)"
+		}
+		result.footer = fmt.Sprintf("Release notes of %s %s:\n  Released: %s\n  Notes:    %s\n", component, v, released[v], note)
+	}
+	return result, nil
+}
+
+// installTime returns the time the version of component is installed, it's
+// empty if the version is not installed or is installed without a receipt
+func installTime(env *environment.Environment, component, version string) string {
+	receipt, err := env.Profile().InstallReceipt(component, version)
+	if err != nil || receipt == nil {
+		return ""
+	}
+	return receipt.InstalledAt.Local().Format("2006-01-02 15:04:05")
 }
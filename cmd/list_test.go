@@ -0,0 +1,69 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/environment"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/repository"
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+)
+
+func (s *testCmdSuite) TestListReleaseNotes(c *C) {
+	dir, err := ioutil.TempDir("", "tiup-list-test")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	env := environment.NewV0(localdata.NewProfile(dir, &localdata.TiUPConfig{}),
+		&repository.Repository{Options: repository.Options{GOOS: "linux", GOARCH: "amd64"}})
+
+	comp := &v1manifest.Component{Platforms: map[string]map[string]v1manifest.VersionItem{
+		"linux/amd64": {
+			"v4.0.0": {Entry: "tidb-server", Released: "2020-05-28T16:23:25+08:00", ReleaseNotes: "https://docs.pingcap.com/tidb/v4.0/release-4.0-ga"},
+			"v4.0.1": {Entry: "tidb-server", Released: "2020-06-12T21:21:12+08:00"},
+		},
+	}}
+
+	// the notes are only listed verbosely
+	result, err := componentVersions(env, "tidb", comp, listOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(result.cmpTable, DeepEquals, [][]string{
+		{"Version", "Installed", "Release", "Platforms"},
+		{"v4.0.0", "", "2020-05-28T16:23:25+08:00", "linux/amd64"},
+		{"v4.0.1", "", "2020-06-12T21:21:12+08:00", "linux/amd64"},
+	})
+	c.Assert(result.footer, Equals, "")
+	result, err = componentVersions(env, "tidb", comp, listOptions{verbose: true})
+	c.Assert(err, IsNil)
+	c.Assert(result.cmpTable[0][6], Equals, "Release Notes")
+	c.Assert(result.cmpTable[1][6], Equals, "https://docs.pingcap.com/tidb/v4.0/release-4.0-ga")
+	c.Assert(result.cmpTable[2][6], Equals, "")
+
+	// the notes of a version are shown under the list
+	result, err = componentVersions(env, "tidb", comp, listOptions{showNotes: "v4.0.0"})
+	c.Assert(err, IsNil)
+	c.Assert(result.footer, Equals, "Release notes of tidb v4.0.0:\n"+
+		"  Released: 2020-05-28T16:23:25+08:00\n"+
+		"  Notes:    https://docs.pingcap.com/tidb/v4.0/release-4.0-ga\n")
+	result, err = componentVersions(env, "tidb", comp, listOptions{showNotes: "v4.0.1"})
+	c.Assert(err, IsNil)
+	c.Assert(result.footer, Equals, "Release notes of tidb v4.0.1:\n"+
+		"  Released: 2020-06-12T21:21:12+08:00\n"+
+		"  Notes:    (not available)\n")
+	_, err = componentVersions(env, "tidb", comp, listOptions{showNotes: "v4.0.2"})
+	c.Assert(err, ErrorMatches, "version v4.0.2 of tidb is not found")
+}
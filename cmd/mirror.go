@@ -16,6 +16,7 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -74,12 +75,14 @@ of components or the repository itself.`,
 		newMirrorCompCmd(),
 		newMirrorAddCompCmd(),
 		newMirrorYankCompCmd(),
+		newMirrorRotateCmd(),
 		newMirrorDelCompCmd(),
 		newMirrorGenkeyCmd(),
 		newMirrorCloneCmd(),
 		newMirrorPublishCmd(),
 		newMirrorSetCmd(),
 		newMirrorModifyCmd(),
+		newMirrorShowCmd(),
 	)
 
 	return cmd
@@ -290,6 +293,87 @@ func newMirrorModifyCmd() *cobra.Command {
 	return cmd
 }
 
+// the `mirror show` sub command
+func newMirrorShowCmd() *cobra.Command {
+	jsonOutput := false
+	cmd := &cobra.Command{
+		Use:   "show [component]",
+		Short: "Show the manifest of a component or the index of the mirror",
+		Long: `Show the manifest of a component, or the index manifest of the mirror if no
+component is specified. The local manifests are refreshed from the mirror if they
+are stale, and verified before shown. With --json, the signed manifest is printed
+as is, so tools could inspect the entry points or files of the components.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return cmd.Help()
+			}
+			env := environment.GlobalEnv()
+
+			var raw []byte
+			var err error
+			if len(args) == 0 {
+				raw, err = env.GetIndexRaw()
+			} else {
+				raw, err = env.GetComponentManifestRaw(args[0])
+			}
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				fmt.Println(string(raw))
+				return nil
+			}
+			return showManifest(raw, len(args) == 0)
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the signed manifest in JSON")
+	return cmd
+}
+
+// showManifest prints the summary of the raw manifest, which is the index if
+// isIndex is true, or the one of a component otherwise
+func showManifest(raw []byte, isIndex bool) error {
+	var m v1manifest.RawManifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return errors.AddStack(err)
+	}
+
+	if isIndex {
+		var index v1manifest.Index
+		if err := json.Unmarshal(m.Signed, &index); err != nil {
+			return errors.AddStack(err)
+		}
+		fmt.Printf("Index version: %d, expires: %s\n", index.Version, index.Expires)
+		var ids []string
+		for id := range index.ComponentList() {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			fmt.Printf("  %s (owner: %s)\n", id, index.Components[id].Owner)
+		}
+		return nil
+	}
+
+	var comp v1manifest.Component
+	if err := json.Unmarshal(m.Signed, &comp); err != nil {
+		return errors.AddStack(err)
+	}
+	fmt.Printf("Component: %s\n", comp.ID)
+	fmt.Printf("Description: %s\n", comp.Description)
+	fmt.Printf("Manifest version: %d, expires: %s\n", comp.Version, comp.Expires)
+	var platforms []string
+	for p := range comp.Platforms {
+		platforms = append(platforms, p)
+	}
+	sort.Strings(platforms)
+	for _, p := range platforms {
+		fmt.Printf("  %s: %d version(s)\n", p, len(comp.Platforms[p]))
+	}
+	return nil
+}
+
 // the `mirror publish` sub command
 func newMirrorPublishCmd() *cobra.Command {
 	var privPath string
@@ -299,11 +383,15 @@ func newMirrorPublishCmd() *cobra.Command {
 	desc := ""
 	standalone := false
 	hidden := false
+	overwriteYanked := false
 
 	cmd := &cobra.Command{
 		Use:   "publish <comp-name> <version> <tarball> <entry>",
 		Short: "Publish a component",
-		Long:  "Publish a component to the repository",
+		Long: `Publish a component to the repository. If the endpoint is the directory
+of a local mirror, e.g. one created by 'tiup mirror clone', the component is
+published to it directly, with the index, snapshot and timestamp manifests
+signed by the keys in the keys directory of the mirror.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 4 {
 				return cmd.Help()
@@ -330,6 +418,24 @@ func newMirrorPublishCmd() *cobra.Command {
 				return err
 			}
 
+			if utils.IsExist(endpoint) {
+				info := repository.PublishInfo{
+					ID:          args[0],
+					Version:     args[1],
+					Platform:    repository.PlatformString(goos, goarch),
+					Entry:       args[3],
+					Description: desc,
+					Standalone:  standalone,
+					Hidden:      hidden,
+				}
+				opt := repository.PublishOptions{OverwriteYanked: overwriteYanked}
+				if err := repository.Publish(endpoint, args[2], info, &ki, opt); err != nil {
+					return err
+				}
+				fmt.Printf("Publish %s(%s) for platform %s/%s to %s success\n", args[0], args[1], goos, goarch, endpoint)
+				return nil
+			}
+
 			flagSet := set.NewStringSet()
 			cmd.Flags().Visit(func(f *pflag.Flag) {
 				flagSet.Insert(f.Name)
@@ -374,9 +480,10 @@ func newMirrorPublishCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&goos, "os", "", goos, "the target operation system")
 	cmd.Flags().StringVarP(&goarch, "arch", "", goarch, "the target system architecture")
 	cmd.Flags().StringVarP(&desc, "desc", "", desc, "description of the component")
-	cmd.Flags().StringVarP(&endpoint, "endpoint", "", endpoint, "endpoint of the server")
+	cmd.Flags().StringVarP(&endpoint, "endpoint", "", endpoint, "endpoint of the server, or the directory of a local mirror")
 	cmd.Flags().BoolVarP(&standalone, "standalone", "", standalone, "can this component run directly")
 	cmd.Flags().BoolVarP(&hidden, "hide", "", hidden, "is this component invisible on listing")
+	cmd.Flags().BoolVarP(&overwriteYanked, "overwrite-yanked", "", overwriteYanked, "overwrite the version if it's yanked, only for local mirrors")
 	return cmd
 }
 
@@ -574,36 +681,124 @@ func createOwner(repo, id, name string) error {
 
 // the `mirror yank` sub command
 func newMirrorYankCompCmd() *cobra.Command {
+	var (
+		privPath string
+		unyank   bool
+		dryRun   bool
+	)
 	cmd := &cobra.Command{
-		Use:   "yank <component> [version]",
-		Short: "Yank a component in the repository",
-		Long: `Yank a component in the repository. If version is not specified, all versions
-of the given component will be yanked.
-A yanked component is still in the repository, but not visible to client, and is
-no longer considered stable to use. A yanked component is expected to be removed
-from the repository in the future.`,
-		Hidden: true, // WIP, remove when it becomes working and stable
+		Use:   "yank <component> <version>",
+		Short: "Yank a version of a component in the repository",
+		Long: `Yank a version of a component in the local repository specified by --repo,
+on all the platforms it's published for.
+A yanked version is still in the repository, but not visible to client, and is
+no longer considered stable to use. A yanked version is expected to be removed
+from the repository in the future, or overwritten by publishing it again with
+the --overwrite-yanked flag.
+
+  # Show what would be changed by yanking v1.0.0 of a component
+  tiup mirror yank --repo /path/to/mirror <component> v1.0.0 --dry-run
+
+  # Make v1.0.0 of a component visible again
+  tiup mirror yank --repo /path/to/mirror <component> v1.0.0 --unyank`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			compVer := ""
-			switch len(args) {
-			case 2:
-				compVer = args[1]
-			default:
+			if len(args) != 2 {
 				return cmd.Help()
 			}
 
-			return yankComp(repoPath, args[0], compVer)
+			return yankComp(repoPath, args[0], args[1], privPath, !unyank, dryRun)
 		},
 	}
 
+	cmd.Flags().StringVarP(&privPath, "key", "k", "", "private key path of the owner of the component")
+	cmd.Flags().BoolVar(&unyank, "unyank", false, "Reverse yanking the version")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the diff of the component manifest without modifying the repository")
+
 	return cmd
 }
 
-func yankComp(repo, id, version string) error {
-	// TODO
+func yankComp(repo, id, version, privPath string, yanked, dryRun bool) error {
+	if privPath == "" {
+		privPath = environment.GlobalEnv().Profile().Path(localdata.KeyInfoParentDir, "private.json")
+	}
+	data, err := ioutil.ReadFile(privPath)
+	if err != nil {
+		return err
+	}
+	ki := v1manifest.KeyInfo{}
+	if err := json.Unmarshal(data, &ki); err != nil {
+		return err
+	}
+
+	diff, err := repository.Yank(repo, id, version, yanked, &ki, repository.YankOptions{DryRun: dryRun})
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		state := "yanked"
+		if !yanked {
+			state = "not yanked"
+		}
+		fmt.Printf("Nothing to do, %s:%s is already %s\n", id, version, state)
+		return nil
+	}
+	fmt.Println(diff)
+	if !dryRun {
+		fmt.Printf("Update %s:%s in %s success\n", id, version, repo)
+	}
 	return nil
 }
 
+// the `mirror rotate` sub command
+func newMirrorRotateCmd() *cobra.Command {
+	var (
+		keyDir string
+		roles  []string
+		owners []string
+	)
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Rotate the signing keys of the repository",
+		Long: `Rotate the keys of the roles and owners of the local repository specified by --repo.
+A new root manifest is signed by both the old and the new root keys, so clients
+trusting the old root can move to the new one on the next update. The private
+keys of the old root must be in the key directory, the new private keys are
+saved to it, and the replaced manifests are backed up in the repository.
+
+  # Rotate the keys of the snapshot and timestamp roles
+  tiup mirror rotate --repo /path/to/mirror --role snapshot --role timestamp
+
+  # Rotate the keys of an owner
+  tiup mirror rotate --repo /path/to/mirror --owner pingcap`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(roles) == 0 && len(owners) == 0 {
+				return cmd.Help()
+			}
+
+			result, err := repository.RotateKeys(repoPath, repository.RotateOptions{
+				KeyDir: keyDir,
+				Roles:  roles,
+				Owners: owners,
+			})
+			if err != nil {
+				return err
+			}
+			for _, f := range result.KeyFiles {
+				fmt.Printf("New private key saved to %s\n", f)
+			}
+			fmt.Printf("Replaced manifests are backed up to %s\n", result.BackupDir)
+			fmt.Printf("Rotate keys of %s success\n", repoPath)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyDir, "key-dir", "k", "", "Path to the private keys of the repository (default to the keys directory of the repository)")
+	cmd.Flags().StringSliceVar(&roles, "role", nil, "The roles to rotate the keys of (root, index, snapshot, timestamp)")
+	cmd.Flags().StringSliceVar(&owners, "owner", nil, "The owners to rotate the keys of")
+
+	return cmd
+}
+
 // the `mirror clone` sub command
 func newMirrorCloneCmd() *cobra.Command {
 	var (
@@ -640,7 +835,9 @@ func newMirrorCloneCmd() *cobra.Command {
 		Example: `  tiup mirror clone /path/to/local --arch amd64,arm --os linux,darwin    # Specify the architectures and OSs
   tiup mirror clone /path/to/local --full                                # Build a full local mirror
   tiup mirror clone /path/to/local --tikv v4  --prefix                   # Specify the version via prefix
-  tiup mirror clone /path/to/local --tidb all --pd all                   # Download all version for specific component`,
+  tiup mirror clone /path/to/local --tidb all --pd all                   # Download all version for specific component
+  tiup mirror clone /path/to/local --os linux --arch amd64 --constraint v4 --constraint v5 --skip-nightly --dry-run
+                                                                         # Show the size of two major releases for linux/amd64`,
 		Short:              "Clone a local mirror from remote mirror and download all selected components",
 		SilenceUsage:       true,
 		DisableFlagParsing: true,
@@ -678,6 +875,11 @@ func newMirrorCloneCmd() *cobra.Command {
 	cmd.Flags().StringSliceVarP(&options.Archs, "arch", "a", []string{"amd64", "arm64"}, "Specify the downloading architecture")
 	cmd.Flags().StringSliceVarP(&options.OSs, "os", "o", []string{"linux", "darwin"}, "Specify the downloading os")
 	cmd.Flags().BoolVarP(&options.Prefix, "prefix", "", false, "Download the version with matching prefix")
+	cmd.Flags().StringSliceVar(&options.Allowlist, "components", nil, "Clone the specified components only")
+	cmd.Flags().StringArrayVar(&options.Constraints, "constraint", nil, "Download the versions satisfying the constraint, e.g. \">=v4.0.0, <v5.0.0\" or \"v4.0\", can be specified multiple times")
+	cmd.Flags().BoolVar(&options.SkipNightly, "skip-nightly", false, "Skip the nightly versions")
+	cmd.Flags().IntVar(&options.Concurrency, "concurrency", 4, "The number of files downloaded in parallel")
+	cmd.Flags().BoolVar(&options.DryRun, "dry-run", false, "Print the number and size of the files to download without downloading them")
 
 	originHelpFunc := cmd.HelpFunc()
 	cmd.SetHelpFunc(func(command *cobra.Command, args []string) {
@@ -72,12 +72,13 @@ the latest stable version will be downloaded from the repository.`,
 			}
 			env := environment.GlobalEnv()
 			if binary != "" {
-				component, ver := environment.ParseCompVersion(binary)
+				spec, entry := environment.ParseCompBinary(binary)
+				component, ver := environment.ParseCompVersion(spec)
 				selectedVer, err := env.SelectInstalledVersion(component, ver)
 				if err != nil {
 					return err
 				}
-				binaryPath, err := env.BinaryPath(component, selectedVer)
+				binaryPath, err := env.ComponentBinaryPath(component, selectedVer, entry)
 				if err != nil {
 					return err
 				}
@@ -114,7 +115,7 @@ the latest stable version will be downloaded from the repository.`,
 
 	rootCmd.PersistentFlags().BoolVarP(&repoOpts.SkipVersionCheck, "skip-version-check", "", false, "Skip the strict version check, by default a version must be a valid SemVer string")
 	rootCmd.Flags().BoolVarP(&printVersion, "version", "v", false, "Print the version of tiup")
-	rootCmd.Flags().StringVarP(&binary, "binary", "B", "", "Print binary path of a specific version of a component `<component>[:version]`\n"+
+	rootCmd.Flags().StringVarP(&binary, "binary", "B", "", "Print binary path of a specific version of a component `<component>[:version][#binary]`\n"+
 		"and the latest version installed will be selected if no version specified")
 	rootCmd.Flags().StringVarP(&tag, "tag", "T", "", "Specify a tag for component instance")
 	rootCmd.Flags().StringVar(&binPath, "binpath", "", "Specify the binary path of component instance")
@@ -126,6 +127,7 @@ the latest stable version will be downloaded from the repository.`,
 	rootCmd.AddCommand(
 		newInstallCmd(),
 		newListCmd(),
+		newRunCmd(),
 		newUninstallCmd(),
 		newUpdateCmd(),
 		newStatusCmd(),
@@ -0,0 +1,54 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/pingcap/tiup/pkg/environment"
+	"github.com/pingcap/tiup/pkg/exec"
+	"github.com/spf13/cobra"
+)
+
+func newRunCmd() *cobra.Command {
+	var tag string
+	cmd := &cobra.Command{
+		Use:   "run <component>[:version][#binary] [-- args...]",
+		Short: "Run a component, or one of its binaries",
+		Long: `Run a component like "tiup <component>[:version]", a binary other than the
+entry point of the component can be run by its name after "#". The binaries
+of the installed versions are listed by "tiup list <component> --verbose".
+The arguments after "--" are passed to the component.
+
+  # Run the entry point of the latest installed tidb
+  tiup run tidb -- -V
+
+  # Run tidb-lightning shipped with tidb v4.0.0
+  tiup run tidb:v4.0.0#tidb-lightning -- --config tidb-lightning.toml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return cmd.Help()
+			}
+			var params []string
+			if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+				params = args[dash:]
+				args = args[:dash]
+			}
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			return exec.RunComponent(environment.GlobalEnv(), tag, args[0], "", params)
+		},
+	}
+	cmd.Flags().StringVarP(&tag, "tag", "T", "", "Specify a tag for component instance")
+	return cmd
+}
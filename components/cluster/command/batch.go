@@ -0,0 +1,145 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/spf13/cobra"
+)
+
+func newBatchCmd() *cobra.Command {
+	opt := cluster.BatchOptions{}
+	var tags []string
+	cmd := &cobra.Command{
+		Use:   "batch <operation> [cluster-name...]",
+		Short: "Run an operation on multiple clusters",
+		Long: `Run an operation on multiple TiDB clusters, some clusters are operated at the
+same time. The supported operations are: start, stop, restart, reload, enable,
+disable and redeploy-agents. The failure on a cluster doesn't abort the others
+unless --fail-fast is set.
+
+The clusters may be selected by --tag instead of names, if both are specified,
+only the named clusters with the tags are operated.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 && (len(args) < 1 || len(tags) == 0) {
+				return cmd.Help()
+			}
+
+			opType, err := cluster.ParseOperationType(args[0])
+			if err != nil {
+				return err
+			}
+			if err := validRoles(gOpt.Roles); err != nil {
+				return err
+			}
+			if err := parseSelector(); err != nil {
+				return err
+			}
+
+			names := args[1:]
+			if len(tags) > 0 {
+				filters, err := cluster.ParseTagFilters(tags)
+				if err != nil {
+					return err
+				}
+				if names, err = filterClustersByTags(names, filters); err != nil {
+					return err
+				}
+				if len(names) == 0 {
+					return perrs.Errorf("No cluster matches the tags %s", strings.Join(tags, ","))
+				}
+			}
+			for _, name := range names {
+				teleCommand = append(teleCommand, scrubClusterName(name))
+			}
+
+			results, err := manager.BatchOperate(names, opType, gOpt, opt)
+			if err != nil {
+				return err
+			}
+			return printBatchResults(results)
+		},
+	}
+
+	cmd.Flags().IntVar(&opt.Concurrency, "concurrency", cluster.DefaultBatchConcurrency, "The number of clusters operated at the same time")
+	cmd.Flags().StringSliceVar(&tags, "tag", nil, "Operate the clusters with the tags, in the form of key=value or key to match any value")
+	cmd.Flags().BoolVar(&opt.FailFast, "fail-fast", false, "Don't operate the remaining clusters once the operation fails on a cluster")
+	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only operate specified roles")
+	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only operate specified nodes")
+	addSelectorFlags(cmd)
+
+	return cmd
+}
+
+// filterClustersByTags returns the clusters matching the tag filters, only the
+// ones in names are considered if it's not empty
+func filterClustersByTags(names []string, filters []cluster.TagFilter) ([]string, error) {
+	matched, err := manager.ClustersByTags(filters)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return matched, nil
+	}
+	tagged := set.NewStringSet(matched...)
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		if tagged.Exist(name) {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+// printBatchResults prints the result of each cluster, an error is returned
+// if the operation failed on any cluster
+func printBatchResults(results map[string]*cluster.BatchResult) error {
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	failed := 0
+	table := [][]string{{"Cluster", "Status", "Tasks", "Elapsed", "Error"}}
+	for _, name := range names {
+		r := results[name]
+		status := "ok"
+		if r.Err != nil {
+			status = "failed"
+			failed++
+		}
+		table = append(table, []string{
+			name,
+			status,
+			fmt.Sprintf("%d/%d", r.Progress.TasksFinished-r.Progress.TasksFailed, r.Progress.TasksBegun),
+			fmt.Sprintf("%.1fs", r.Progress.ElapsedSecs),
+			r.Error,
+		})
+	}
+	fmt.Println()
+	cliutil.PrintTable(table, true)
+
+	if failed > 0 {
+		return cluster.ErrBatchFailed.New("The operation failed on %d of %d clusters", failed, len(names))
+	}
+	return nil
+}
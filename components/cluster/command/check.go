@@ -19,28 +19,37 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/joomcode/errorx"
 	perrs "github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster"
 	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/cluster/task"
 	"github.com/pingcap/tiup/pkg/logger/log"
 	"github.com/pingcap/tiup/pkg/meta"
+	"github.com/pingcap/tiup/pkg/set"
 	tiuputils "github.com/pingcap/tiup/pkg/utils"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 )
 
 type checkOptions struct {
-	user         string // username to login to the SSH server
-	identityFile string // path to the private key file
-	usePassword  bool   // use password instead of identity file for ssh connection
-	opr          *operator.CheckOptions
-	applyFix     bool // try to apply fixes of failed checks
-	existCluster bool // check an exist cluster
+	user               string // username to login to the SSH server
+	identityFile       string // path to the private key file
+	usePassword        bool   // use password instead of identity file for ssh connection
+	opr                *operator.CheckOptions
+	applyFix           bool   // try to apply fixes of failed checks
+	existCluster       bool   // check an exist cluster
+	ignoreErrors       bool   // skip unreachable hosts
+	outputDir          string // the dir large outputs of hosts are saved to
+	allowUnknownFields bool   // ignore the unknown fields of the topology file
+	self               bool   // check the control machine instead of the deploy servers
+	strict             bool   // fail if the control machine fails the checks
 }
 
 func newCheckCmd() *cobra.Command {
@@ -49,14 +58,20 @@ func newCheckCmd() *cobra.Command {
 		identityFile: path.Join(tiuputils.UserHome(), ".ssh", "id_rsa"),
 	}
 	cmd := &cobra.Command{
-		Use:   "check <topology.yml | cluster-name>",
+		Use:   "check <topology.yml | cluster-name> | --self",
 		Short: "Perform preflight checks for the cluster.",
 		Long: `Perform preflight checks for the cluster. By default, it checks deploy servers
 before a cluster is deployed, the input is the topology.yaml for the cluster.
 If '--cluster' is set, it will perform checks for an existing cluster, the input
 is the cluster name. Some checks are ignore in this mode, such as port and dir
-conflict checks with other clusters`,
+conflict checks with other clusters.
+If '--self' is set, it checks the control machine instead, e.g., the umask, the
+locale, the free space and the clock, which are also checked before deploying and
+upgrading clusters.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if opt.self {
+				return cluster.CheckControlMachine(opt.strict)
+			}
 			if len(args) != 1 {
 				return cmd.Help()
 			}
@@ -80,8 +95,9 @@ conflict checks with other clusters`,
 				}
 
 				topo = *metadata.Topology
+				opt.outputDir = tidbSpec.Path(clusterName, "logs")
 			} else { // check before cluster is deployed
-				if err := clusterutil.ParseTopologyYaml(args[0], &topo); err != nil {
+				if err := clusterutil.ParseTopologyYaml(args[0], &topo, clusterutil.AllowUnknownFields(opt.allowUnknownFields)); err != nil {
 					return err
 				}
 
@@ -96,6 +112,7 @@ conflict checks with other clusters`,
 				if err := spec.CheckClusterDirConflict(clusterList, "nonexist-dummy-tidb-cluster", &topo); err != nil {
 					return err
 				}
+				opt.outputDir = spec.ProfilePath("logs")
 			}
 
 			sshConnProps, err := cliutil.ReadIdentityFileOrPassword(opt.identityFile, opt.usePassword)
@@ -103,37 +120,65 @@ conflict checks with other clusters`,
 				return err
 			}
 
-			return checkSystemInfo(sshConnProps, &topo, &opt)
+			excluded, err := cluster.PreflightSSHAuth(cluster.SSHHosts(&topo), opt.user, sshConnProps, gOpt.SSHTimeout, gOpt.NativeSSH, opt.ignoreErrors)
+			if err != nil {
+				return err
+			}
+
+			return checkSystemInfo(sshConnProps, &topo, &opt, excluded)
 		},
 	}
 
 	cmd.Flags().StringVarP(&opt.user, "user", "u", tiuputils.CurrentUser(), "The user name to login via SSH. The user must has root (or sudo) privilege.")
 	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", opt.identityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
 	cmd.Flags().BoolVarP(&opt.usePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
+	cmd.Flags().BoolVar(&opt.allowUnknownFields, "allow-unknown-fields", false, "Accept fields in the topology file that are unknown to this version, they are ignored")
 
 	cmd.Flags().BoolVar(&opt.opr.EnableCPU, "enable-cpu", false, "Enable CPU thread count check")
 	cmd.Flags().BoolVar(&opt.opr.EnableMem, "enable-mem", false, "Enable memory size check")
 	cmd.Flags().BoolVar(&opt.opr.EnableDisk, "enable-disk", false, "Enable disk IO (fio) check")
+	cmd.Flags().BoolVar(&opt.opr.EnableDiskBench, "enable-disk-bench", false, "Enable a quick benchmark (dd) of empty data directories of TiKV and PD")
+	cmd.Flags().Float64Var(&opt.opr.DiskBenchMinSeqWrite, "disk-bench-min-seq-write", 100, "Minimal sequential write throughput (MB/s) of data directories in disk benchmark")
+	cmd.Flags().DurationVar(&opt.opr.DiskBenchMaxFsyncLat, "disk-bench-max-fsync-latency", 10*time.Millisecond, "Maximal average fsync latency of data directories in disk benchmark")
+	cmd.Flags().BoolVar(&opt.opr.EnableConnectivity, "enable-connectivity", false, "Enable TCP connectivity check between hosts, listeners are started on the ports if the cluster is not deployed yet")
+	cmd.Flags().IntVar(&opt.opr.ConnectivityConcurrency, "connectivity-concurrency", 8, "Maximal number of connectivity probes running at the same time on each host")
+	cmd.Flags().DurationVar(&opt.opr.ConnectivityTimeout, "connectivity-timeout", 3*time.Second, "Timeout of each connectivity probe")
 	cmd.Flags().BoolVar(&opt.applyFix, "apply", false, "Try to fix failed checks")
 	cmd.Flags().BoolVar(&opt.existCluster, "cluster", false, "Check existing cluster, the input is a cluster name.")
+	cmd.Flags().BoolVar(&opt.self, "self", false, "Check the control machine (umask, locale, free space and clock) instead of the deploy servers")
+	cmd.Flags().BoolVar(&opt.strict, "strict", false, "Fail if the control machine fails the checks of --self, they are only warned otherwise")
+	cmd.Flags().BoolVar(&opt.ignoreErrors, "ignore-errors", false, "Skip the hosts which are unreachable via SSH and check the others")
 
 	return cmd
 }
 
-// checkSystemInfo performs series of checks and tests of the deploy server
-func checkSystemInfo(s *cliutil.SSHConnectionProps, topo *spec.Specification, opt *checkOptions) error {
+// connListenDuration is the maximal time listeners of connectivity check
+// keep running, they are killed when cleaning up the check files
+const connListenDuration = 10 * time.Minute
+
+// checkSystemInfo performs series of checks and tests of the deploy server,
+// the excluded hosts are skipped
+func checkSystemInfo(s *cliutil.SSHConnectionProps, topo *spec.Specification, opt *checkOptions, excluded set.StringSet) error {
 	var (
 		collectTasks  []*task.StepDisplay
 		checkSysTasks []*task.StepDisplay
+		benchTasks    []*task.StepDisplay
+		listenTasks   []*task.StepDisplay
+		connTasks     []*task.StepDisplay
 		cleanTasks    []*task.StepDisplay
 		applyFixTasks []*task.StepDisplay
 		downloadTasks []*task.StepDisplay
 	)
 	insightVer := spec.TiDBComponentVersion(spec.ComponentCheckCollector, "")
+	// start listeners for connectivity check if the services are not running yet
+	listenerMode := opt.opr.EnableConnectivity && !opt.existCluster
 
 	uniqueHosts := map[string]int{}             // host -> ssh-port
 	uniqueArchList := make(map[string]struct{}) // map["os-arch"]{}
 	topo.IterInstance(func(inst spec.Instance) {
+		if excluded.Exist(inst.GetHost()) {
+			return
+		}
 		archKey := fmt.Sprintf("%s-%s", inst.OS(), inst.Arch())
 		if _, found := uniqueArchList[archKey]; !found {
 			uniqueArchList[archKey] = struct{}{}
@@ -149,6 +194,7 @@ func checkSystemInfo(s *cliutil.SSHConnectionProps, topo *spec.Specification, op
 		}
 		if _, found := uniqueHosts[inst.GetHost()]; !found {
 			uniqueHosts[inst.GetHost()] = inst.GetSSHPort()
+			toolsDir := task.CheckToolsDir(topo.GlobalOptions.RemoteTmpDirOf(inst.GetHost()))
 
 			// build system info collecting tasks
 			t1 := task.NewBuilder().
@@ -162,7 +208,7 @@ func checkSystemInfo(s *cliutil.SSHConnectionProps, topo *spec.Specification, op
 					gOpt.SSHTimeout,
 					gOpt.NativeSSH,
 				).
-				Mkdir(opt.user, inst.GetHost(), filepath.Join(task.CheckToolsPathDir, "bin")).
+				Mkdir(opt.user, inst.GetHost(), filepath.Join(toolsDir, "bin")).
 				CopyComponent(
 					spec.ComponentCheckCollector,
 					inst.OS(),
@@ -170,11 +216,11 @@ func checkSystemInfo(s *cliutil.SSHConnectionProps, topo *spec.Specification, op
 					insightVer,
 					"", // use default srcPath
 					inst.GetHost(),
-					task.CheckToolsPathDir,
+					toolsDir,
 				).
 				Shell(
 					inst.GetHost(),
-					filepath.Join(task.CheckToolsPathDir, "bin", "insight"),
+					filepath.Join(toolsDir, "bin", "insight"),
 					false,
 				).
 				BuildAsStep(fmt.Sprintf("  - Getting system info of %s:%d", inst.GetHost(), inst.GetSSHPort()))
@@ -236,6 +282,13 @@ func checkSystemInfo(s *cliutil.SSHConnectionProps, topo *spec.Specification, op
 						topo,
 						opt.opr,
 					).
+					CheckSys(
+						inst.GetHost(),
+						dataDir,
+						task.CheckTypeOSSettings,
+						topo,
+						opt.opr,
+					).
 					CheckSys(
 						inst.GetHost(),
 						dataDir,
@@ -271,22 +324,98 @@ func checkSystemInfo(s *cliutil.SSHConnectionProps, topo *spec.Specification, op
 					s.IdentityFilePassphrase,
 					gOpt.SSHTimeout,
 					gOpt.NativeSSH,
-				).
-				Rmdir(inst.GetHost(), task.CheckToolsPathDir).
-				BuildAsStep(fmt.Sprintf("  - Cleanup check files on %s:%d", inst.GetHost(), inst.GetSSHPort()))
-			cleanTasks = append(cleanTasks, t3)
+				)
+			if listenerMode {
+				t3 = t3.Shell(inst.GetHost(), fmt.Sprintf("pkill -f %s || true", task.PortListenerScript), false)
+			}
+			t3 = t3.Rmdir(inst.GetHost(), task.CheckToolsDir(topo.GlobalOptions.RemoteTmpDirOf(inst.GetHost())))
+			cleanTasks = append(cleanTasks, t3.
+				BuildAsStep(fmt.Sprintf("  - Cleanup check files on %s:%d", inst.GetHost(), inst.GetSSHPort())))
 		}
 	})
 
-	t := task.NewBuilder().
+	// the disk benchmark only makes sense for the data directories of storage components
+	if opt.opr.EnableDiskBench {
+		benchDirs := make(map[string][]string) // host -> data dirs
+		topo.IterInstance(func(inst spec.Instance) {
+			if excluded.Exist(inst.GetHost()) {
+				return
+			}
+			switch inst.ComponentName() {
+			case spec.ComponentTiKV, spec.ComponentPD:
+				benchDirs[inst.GetHost()] = append(benchDirs[inst.GetHost()],
+					clusterutil.MultiDirAbs(opt.user, inst.DataDir())...)
+			}
+		})
+		for host, dirs := range benchDirs {
+			tb := task.NewBuilder()
+			for _, dataDir := range dirs {
+				tb.CheckSys(host, dataDir, task.CheckTypeDiskBench, topo, opt.opr)
+			}
+			benchTasks = append(benchTasks, tb.BuildAsStep(fmt.Sprintf("  - Benchmarking data directories on %s", host)))
+		}
+	}
+
+	// probe the connections between hosts implied by the topology
+	connMatrix := operator.NewConnectivityMatrix()
+	if opt.opr.EnableConnectivity {
+		clientPairs := make(map[string][]operator.ConnectivityPair) // client host -> pairs
+		serverPorts := make(map[string][]int)                       // server host -> ports
+		seenPorts := make(map[string]bool)
+		for _, p := range operator.ConnectivityPairs(topo) {
+			if excluded.Exist(p.ClientHost) || excluded.Exist(p.ServerHost) {
+				continue
+			}
+			clientPairs[p.ClientHost] = append(clientPairs[p.ClientHost], p)
+			if key := fmt.Sprintf("%s:%d", p.ServerHost, p.Port); !seenPorts[key] {
+				seenPorts[key] = true
+				serverPorts[p.ServerHost] = append(serverPorts[p.ServerHost], p.Port)
+			}
+		}
+		if listenerMode {
+			for host, ports := range serverPorts {
+				listenTasks = append(listenTasks, task.NewBuilder().
+					PortListener(host, ports, connListenDuration).
+					BuildAsStep(fmt.Sprintf("  - Starting listeners on %s", host)))
+			}
+		}
+		for host, pairs := range clientPairs {
+			connTasks = append(connTasks, task.NewBuilder().
+				CheckConnectivity(host, pairs, opt.opr, connMatrix).
+				BuildAsStep(fmt.Sprintf("  - Probing connections from %s", host)))
+		}
+	}
+
+	tb := task.NewBuilder().
 		ParallelStep("+ Download necessary tools", downloadTasks...).
 		ParallelStep("+ Collect basic system information", collectTasks...).
-		ParallelStep("+ Check system requirements", checkSysTasks...).
-		ParallelStep("+ Cleanup check files", cleanTasks...).
+		ParallelStep("+ Check system requirements", checkSysTasks...)
+	if len(listenTasks) > 0 {
+		tb.ParallelStep("+ Start port listeners", listenTasks...)
+	}
+	if len(connTasks) > 0 {
+		tb.ParallelStep("+ Check connectivity between hosts", connTasks...)
+	}
+	if len(benchTasks) > 0 {
+		tb.ParallelStep("+ Benchmark data directories", benchTasks...)
+	}
+	t := tb.ParallelStep("+ Cleanup check files", cleanTasks...).
 		Build()
 
 	ctx := task.NewContext()
-	if err := t.Execute(ctx); err != nil {
+	ctx.SetRemoteTmpDirs(&topo.GlobalOptions)
+	// the outputs of all hosts are kept until the results are handled, limit the
+	// memory used by them, as it could be huge when checking a lot of hosts
+	ctx.SetOutputLimits(task.DefaultOutputLimit, task.DefaultOutputSpillThreshold, opt.outputDir)
+	err := t.Execute(ctx)
+	usage := ctx.OutputUsage()
+	zap.L().Debug("Memory used by outputs of hosts",
+		zap.Int64("current", usage.Current), zap.Int64("peak", usage.Peak),
+		zap.Int("evicted", usage.Evicted), zap.Int("spilled", usage.Spilled))
+	if usage.Spilled > 0 {
+		log.Infof("Large outputs of %d command(s) are saved in %s", usage.Spilled, opt.outputDir)
+	}
+	if err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -323,6 +452,10 @@ func checkSystemInfo(s *cliutil.SSHConnectionProps, topo *spec.Specification, op
 	// print check results *before* trying to applying checks
 	// FIXME: add fix result to output, and display the table after fixing
 	cliutil.PrintTable(checkResultTable, true)
+	if len(connTasks) > 0 {
+		fmt.Println("\nConnectivity between hosts:")
+		cliutil.PrintTable(connMatrix.Table(), true)
+	}
 
 	if opt.applyFix {
 		tc := task.NewBuilder().
@@ -14,6 +14,7 @@
 package command
 
 import (
+	"github.com/pingcap/tiup/pkg/cluster"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/spf13/cobra"
 )
@@ -58,6 +59,7 @@ You can retain some nodes and roles data when cleanup the cluster, eg:
 	cmd.Flags().BoolVar(&cleanOpt.CleanupData, "data", false, "Cleanup data")
 	cmd.Flags().BoolVar(&cleanOpt.CleanupLog, "log", false, "Cleanup log")
 	cmd.Flags().BoolVar(&cleanALl, "all", false, "Cleanup both log and data")
+	cmd.Flags().BoolVar(&gOpt.OverrideProtection, cluster.OverrideProtectionFlag, false, "Confirm the operation on a protected cluster")
 
 	return cmd
 }
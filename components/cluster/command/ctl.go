@@ -0,0 +1,43 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newCtlCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ctl <cluster-name> <component> [args...]",
+		Short: "Run the ctl of a component against the cluster",
+		Long: `Run the ctl of a component against the cluster. The ctl matching the version
+of the cluster is used, and the endpoints and TLS material of the cluster are
+added to the arguments unless they are specified explicitly. Supported components
+are: pd, tikv, tidb, etcd, binlog and cdc.`,
+		// all the arguments after the component are passed to the ctl
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 || args[0] == "-h" || args[0] == "--help" {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName), args[1])
+
+			return manager.Ctl(clusterName, args[1], args[2:])
+		},
+	}
+
+	return cmd
+}
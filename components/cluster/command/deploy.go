@@ -47,6 +47,8 @@ func newDeploy() *cobra.Command {
 	opt := cluster.DeployOptions{
 		IdentityFile: path.Join(tiuputils.UserHome(), ".ssh", "id_rsa"),
 	}
+	dryRun := false
+	planFormat := task.PlanFormatText
 	cmd := &cobra.Command{
 		Use:          "deploy <cluster-name> <version> <topology.yaml>",
 		Short:        "Deploy a cluster for production",
@@ -70,7 +72,12 @@ func newDeploy() *cobra.Command {
 			if data, err := ioutil.ReadFile(topoFile); err == nil {
 				teleTopology = string(data)
 			}
+			if dryRun {
+				opt.PlanFormat = planFormat
+			}
 
+			opt.Transfer = task.NewTransferOptions(gOpt)
+			opt.IgnoreVersionCheck = gOpt.IgnoreVersionCheck
 			return manager.Deploy(
 				clusterName,
 				version,
@@ -89,7 +96,15 @@ func newDeploy() *cobra.Command {
 	cmd.Flags().BoolVarP(&opt.SkipCreateUser, "skip-create-user", "", false, "Skip creating the user specified in topology.")
 	cmd.Flags().StringVarP(&opt.IdentityFile, "identity_file", "i", opt.IdentityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
 	cmd.Flags().BoolVarP(&opt.UsePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
+	cmd.Flags().BoolVar(&opt.AllowUnknownFields, "allow-unknown-fields", false, "Accept fields in the topology file that are unknown to this version, they are ignored")
+	cmd.Flags().BoolVar(&opt.StrictSelfCheck, "strict-self-check", false, "Fail if the control machine fails the self checks (umask, locale, free space and clock), they are only warned otherwise")
+	cmd.Flags().BoolVar(&opt.Force, "force", false, "Remove the units left on the hosts which point to other deploy directories, e.g., by destroyed clusters, instead of failing")
+	cmd.Flags().BoolVar(&opt.AllowColocation, "allow-colocation", false, "Allow instances on hosts used by other clusters, as long as the ports and directories are distinct")
 	cmd.Flags().BoolVarP(&opt.IgnoreConfigCheck, "ignore-config-check", "", opt.IgnoreConfigCheck, "Ignore the config check result")
+	cmd.Flags().BoolVarP(&opt.BootstrapUser, "bootstrap-user", "", false, "Create the deploy user with sudo privileges limited to systemctl on the cluster services, requires SSH login as root. The files are deployed as root, and the operations changing them afterwards, e.g., upgrade and scale-out, are refused for the cluster.")
+	cmd.Flags().StringVar((*string)(&opt.ErrorScope), "error-scope", "", "How far a failure in preparing the hosts reaches: 'operation' stops at it, 'host' and 'step' keep preparing the other hosts to report all the failures (default \"operation\")")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the plan of the tasks to deploy the cluster, the hosts are not connected to")
+	cmd.Flags().StringVar(&planFormat, "plan-format", planFormat, "The format of the plan printed in dry-run mode, 'text' or 'dot' (Graphviz)")
 
 	return cmd
 }
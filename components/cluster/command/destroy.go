@@ -15,6 +15,7 @@ package command
 
 import (
 	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/set"
@@ -55,6 +56,8 @@ You can retain some nodes and roles data when destroy cluster, eg:
 
 	cmd.Flags().StringArrayVar(&destoyOpt.RetainDataNodes, "retain-node-data", nil, "Specify the nodes or hosts whose data will be retained")
 	cmd.Flags().StringArrayVar(&destoyOpt.RetainDataRoles, "retain-role-data", nil, "Specify the roles whose data will be retained")
+	cmd.Flags().BoolVar(&destoyOpt.Force, "force", false, "Destroy the cluster even if some hosts can't be cleaned up, they are recorded and checked by the next deploy to them")
+	cmd.Flags().BoolVar(&gOpt.OverrideProtection, cluster.OverrideProtectionFlag, false, "Confirm the operation on a protected cluster")
 
 	return cmd
 }
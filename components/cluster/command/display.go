@@ -75,6 +75,8 @@ func newDisplayCmd() *cobra.Command {
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only display specified roles")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only display specified nodes")
 	cmd.Flags().BoolVar(&showDashboardOnly, "dashboard", false, "Only display TiDB Dashboard information")
+	cmd.Flags().BoolVar(&gOpt.Resources, "resources", false, "Display the CPU, memory, open files and data size of the instances")
+	cmd.Flags().Int64Var(&gOpt.ResourcesTimeout, "resources-timeout", 10, "Timeout in seconds to gather the resource usage of the instances on a host")
 
 	return cmd
 }
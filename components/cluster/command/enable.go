@@ -0,0 +1,98 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/spf13/cobra"
+)
+
+func newEnableCmd() *cobra.Command {
+	opt := cluster.EnableOptions{}
+	var class string
+	wait := true
+	cmd := &cobra.Command{
+		Use:   "enable <cluster-name>",
+		Short: "Enable a TiDB cluster automatically at boot",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			if err := validRoles(gOpt.Roles); err != nil {
+				return err
+			}
+			componentClass, err := operator.ParseComponentClass(class)
+			if err != nil {
+				return err
+			}
+			gOpt.ComponentClass = componentClass
+			if cmd.Flags().Changed("wait") {
+				if cmd.Flags().Changed("no-wait") {
+					return perrs.Errorf("--wait and --no-wait can't be specified at the same time")
+				}
+				opt.NoWait = !wait
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return manager.EnableCluster(clusterName, true, opt, gOpt)
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only enable specified roles")
+	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only enable specified nodes")
+	cmd.Flags().StringVar(&class, "class", "", "Only enable the components of the class: core, monitoring or all, the monitoring agents are only included by monitoring and all")
+	cmd.Flags().BoolVar(&wait, "wait", wait, "Verify the services start on boot after enabling them, it's the default")
+	cmd.Flags().BoolVar(&opt.NoWait, "no-wait", false, "Don't verify the services start on boot after enabling them")
+	cmd.Flags().BoolVar(&opt.VerifyStart, "verify-start", false, "Restart an instance of each component to verify it starts")
+
+	return cmd
+}
+
+func newDisableCmd() *cobra.Command {
+	var class string
+	cmd := &cobra.Command{
+		Use:   "disable <cluster-name>",
+		Short: "Disable starting a TiDB cluster automatically at boot",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			if err := validRoles(gOpt.Roles); err != nil {
+				return err
+			}
+			componentClass, err := operator.ParseComponentClass(class)
+			if err != nil {
+				return err
+			}
+			gOpt.ComponentClass = componentClass
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return manager.EnableCluster(clusterName, false, cluster.EnableOptions{}, gOpt)
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only disable specified roles")
+	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only disable specified nodes")
+	cmd.Flags().StringVar(&class, "class", "", "Only disable the components of the class: core, monitoring or all, the monitoring agents are only included by monitoring and all")
+
+	return cmd
+}
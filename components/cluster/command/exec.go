@@ -28,6 +28,10 @@ func newExecCmd() *cobra.Command {
 				return cmd.Help()
 			}
 
+			if err := parseSelector(); err != nil {
+				return err
+			}
+
 			clusterName := args[0]
 			teleCommand = append(teleCommand, scrubClusterName(clusterName))
 
@@ -39,6 +43,7 @@ func newExecCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&opt.Sudo, "sudo", false, "use root permissions (default false)")
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only exec on host with specified roles")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only exec on host with specified nodes")
+	addSelectorFlags(cmd)
 
 	return cmd
 }
@@ -0,0 +1,87 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/pingcap/tiup/pkg/cluster"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/spf13/cobra"
+)
+
+func newExportTargetsCmd() *cobra.Command {
+	var (
+		format string
+		output string
+	)
+	cmd := &cobra.Command{
+		Use:   "export-targets <cluster-name>",
+		Short: "Export the Prometheus scrape targets of a TiDB cluster",
+		Long: `Export the metrics endpoints of all the instances of a TiDB cluster and the
+monitoring agents on its hosts, for scraping by a Prometheus not deployed by
+tiup. The targets are labeled with cluster, component and instance.
+
+The format is either file_sd, the JSON file referred by file_sd_configs, or
+scrape_configs, a YAML fragment to put under scrape_configs of prometheus.yml.
+If the format is not specified, it's file_sd for an output file ending with
+.json and scrape_configs otherwise.
+
+  # Write the targets to a file watched by Prometheus
+  tiup cluster export-targets test-cluster --output /etc/prometheus/tidb/test-cluster.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			if output != "" {
+				if err := manager.WriteScrapeTargets(clusterName, format, output); err != nil {
+					return err
+				}
+				log.Infof("Exported the scrape targets of cluster `%s` to %s", clusterName, output)
+				return nil
+			}
+			if format == "" {
+				format = cluster.ScrapeTargetsStatic
+			}
+			data, err := manager.ExportScrapeTargets(clusterName, format)
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(data))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "", "The format of the targets, file_sd or scrape_configs")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the targets to the file instead of printing them")
+
+	return cmd
+}
+
+// exportTargetsAfterScale re-exports the scrape targets of the cluster to the
+// file after the topology is changed by scaling, see --export-targets
+func exportTargetsAfterScale(clusterName, path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := manager.WriteScrapeTargets(clusterName, "", path); err != nil {
+		return err
+	}
+	log.Infof("Exported the scrape targets of cluster `%s` to %s", clusterName, path)
+	return nil
+}
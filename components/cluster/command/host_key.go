@@ -0,0 +1,41 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newAcceptHostKeyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "accept-host-key <cluster-name> <host>",
+		Short: "Accept the new SSH host key of a host rebuilt intentionally",
+		Long: `Accept the new SSH host key of a host rebuilt intentionally.
+The SSH host keys of the hosts are recorded in the metadata when they are
+connected the first time, and the operations on a host presenting another
+key fail, as the host may be replaced or the connection may be intercepted.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return manager.AcceptNewHostKey(clusterName, args[1])
+		},
+	}
+
+	return cmd
+}
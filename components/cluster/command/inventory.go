@@ -0,0 +1,65 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pingcap/tiup/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+func newInventoryCmd() *cobra.Command {
+	var (
+		opt        cluster.InventoryOptions
+		jsonOutput bool
+	)
+	cmd := &cobra.Command{
+		Use:   "inventory <cluster-name>",
+		Short: "Show the hardware and OS inventory of the hosts of a TiDB cluster",
+		Long: `Show the hardware and OS inventory of the hosts of a TiDB cluster, including
+CPU, memory, the disks of data directories, NICs, OS release, kernel and the
+deployed components. The inventory is cached once gathered, use --refresh to
+gather it from the hosts again.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			inv, err := manager.Inventory(clusterName, opt, gOpt)
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				data, err := json.MarshalIndent(inv, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+			cluster.PrintInventory(inv)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&opt.Refresh, "refresh", false, "Gather the inventory from the hosts instead of showing the cached one")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the inventory in JSON")
+
+	return cmd
+}
@@ -14,16 +14,24 @@
 package command
 
 import (
+	"github.com/pingcap/tiup/pkg/cluster"
 	"github.com/spf13/cobra"
 )
 
 func newListCmd() *cobra.Command {
+	var tags []string
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all clusters",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return manager.ListCluster()
+			filters, err := cluster.ParseTagFilters(tags)
+			if err != nil {
+				return err
+			}
+			return manager.ListCluster(filters...)
 		},
 	}
+
+	cmd.Flags().StringSliceVar(&tags, "tag", nil, "Only list the clusters with the tags, in the form of key=value or key to match any value")
 	return cmd
 }
@@ -0,0 +1,50 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/pingcap/tiup/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateDataDirCmd() *cobra.Command {
+	opt := cluster.MigrateDataDirOptions{}
+	cmd := &cobra.Command{
+		Use:   "migrate-data-dir <cluster-name> <instance-id> <new-data-dir>",
+		Short: "Move the data directory of an instance to another directory on its host",
+		Long: `Move the data directory of an instance to another directory on its host.
+The instance is stopped during the migration. The data is renamed if both
+directories are on the same filesystem, or copied and spot-checked otherwise,
+and the old directory is kept after copying. If the instance fails to start
+with the new data directory, the migration is rolled back.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 3 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			opt.SkipConfirm = skipConfirm
+			return manager.MigrateDataDir(clusterName, args[1], args[2], opt, gOpt)
+		},
+	}
+
+	cmd.Flags().BoolVar(&gOpt.Force, "force", false, "Migrate without transferring PD and TiKV store leaders")
+	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
+	cmd.Flags().BoolVarP(&gOpt.IgnoreConfigCheck, "ignore-config-check", "", false, "Ignore the config check result")
+	cmd.Flags().IntVar(&opt.VerifySamples, "verify-samples", cluster.DefaultMigrateVerifySamples, "The number of files whose checksums are compared after copying, 0 disables the verification")
+
+	return cmd
+}
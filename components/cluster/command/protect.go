@@ -0,0 +1,59 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newProtectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "protect <cluster-name>",
+		Short: "Protect a TiDB cluster from being destroyed, cleaned or scaled in by mistake",
+		Long: `Protect a TiDB cluster from being destroyed, cleaned or scaled in by mistake.
+The destroy, clean and scale-in commands on a protected cluster require the
+extra flag --i-know-this-is-protected.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return manager.SetProtection(clusterName, true)
+		},
+	}
+
+	return cmd
+}
+
+func newUnprotectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unprotect <cluster-name>",
+		Short: "Remove the protection of a TiDB cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return manager.SetProtection(clusterName, false)
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/pingcap/tiup/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+func newRecoverCmd() *cobra.Command {
+	opt := cluster.RecoverOptions{
+		TmpFileAge: cluster.DefaultTmpFileAge,
+	}
+	cmd := &cobra.Command{
+		Use:   "recover <cluster-name>",
+		Short: "Clean up the stale lock and temp files left by a crashed operation",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			opt.SkipConfirm = skipConfirm
+			return manager.Recover(clusterName, opt)
+		},
+	}
+
+	cmd.Flags().DurationVar(&opt.TmpFileAge, "tmp-age", opt.TmpFileAge, "Only remove the temp files not modified within this duration")
+
+	return cmd
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/pingcap/tiup/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+func newRedeployAgentsCmd() *cobra.Command {
+	opt := cluster.RedeployAgentsOptions{}
+	cmd := &cobra.Command{
+		Use:   "redeploy-agents <cluster-name>",
+		Short: "Redeploy the monitoring agents of a TiDB cluster",
+		Long: `Redeploy the monitoring agents (node_exporter and blackbox_exporter) of a TiDB
+cluster. Only the configs and binaries of the agents are pushed, and only the agents
+and Prometheus are restarted.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return manager.RedeployMonitoringAgents(clusterName, opt, gOpt)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opt.BootstrapMissing, "bootstrap-missing", false, "Deploy the agents to hosts where they were never deployed instead of skipping them")
+
+	return cmd
+}
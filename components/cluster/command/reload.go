@@ -15,6 +15,7 @@ package command
 
 import (
 	perrs "github.com/pingcap/errors"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/spf13/cobra"
 )
@@ -46,10 +47,23 @@ func newReloadCmd() *cobra.Command {
 	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
 	cmd.Flags().BoolVarP(&gOpt.IgnoreConfigCheck, "ignore-config-check", "", false, "Ignore the config check result")
 	cmd.Flags().BoolVar(&skipRestart, "skip-restart", false, "Only refresh configuration to remote and do not restart services")
+	cmd.Flags().BoolVar(&gOpt.OverwriteDashboards, "overwrite-dashboards", false, "Overwrite the dashboards modified in Grafana when they are provisioned via the API")
 
 	return cmd
 }
 
+// validRolesOrMeta is validRoles accepting the meta-roles like "@monitoring",
+// which are expanded and checked by the manager
+func validRolesOrMeta(roles []string) error {
+	var plain []string
+	for _, r := range roles {
+		if !operator.IsMetaRole(r) {
+			plain = append(plain, r)
+		}
+	}
+	return validRoles(plain)
+}
+
 func validRoles(roles []string) error {
 	for _, r := range roles {
 		match := false
@@ -14,10 +14,13 @@
 package command
 
 import (
+	"github.com/pingcap/tiup/pkg/cluster/task"
 	"github.com/spf13/cobra"
 )
 
 func newRestartCmd() *cobra.Command {
+	dryRun := false
+	planFormat := task.PlanFormatText
 	cmd := &cobra.Command{
 		Use:   "restart <cluster-name>",
 		Short: "Restart a TiDB cluster",
@@ -26,19 +29,34 @@ func newRestartCmd() *cobra.Command {
 				return cmd.Help()
 			}
 
-			if err := validRoles(gOpt.Roles); err != nil {
+			if err := validRolesOrMeta(gOpt.Roles); err != nil {
+				return err
+			}
+
+			if err := parseSelector(); err != nil {
 				return err
 			}
 
 			clusterName := args[0]
 			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			if dryRun {
+				gOpt.PlanFormat = planFormat
+			}
 
 			return manager.RestartCluster(clusterName, gOpt)
 		},
 	}
 
-	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only restart specified roles")
+	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only restart specified roles, @core and @monitoring stand for the database and monitoring components")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only restart specified nodes")
+	cmd.Flags().DurationVar(&gOpt.StartStagger, "start-stagger", 0, "Delay the k-th instance of each component by k times this duration when starting them, e.g. 5s, for the hosts sharing the same storage")
+	cmd.Flags().DurationVar(&gOpt.StartStaggerJitter, "start-stagger-jitter", 0, "Delay each instance by a random duration up to this one in addition to --start-stagger")
+	cmd.Flags().BoolVar(&gOpt.Serial, "serial", false, "Restart the instances one at a time in the component order, for environments that can't afford many of them restarting at once")
+	cmd.Flags().StringVar((*string)(&gOpt.ErrorScope), "error-scope", "", "How far a failure reaches: 'operation' stops at it, 'host' skips the rest steps on its host, 'step' only fails its step (default \"operation\")")
+	addSelectorFlags(cmd)
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the plan of the tasks to restart the cluster, with the units and commands of the instances, the hosts are not connected to")
+	cmd.Flags().StringVar(&planFormat, "plan-format", planFormat, "The format of the plan printed in dry-run mode, 'text' or 'dot' (Graphviz)")
+	addSilenceFlags(cmd)
 
 	return cmd
 }
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -37,7 +38,9 @@ import (
 	"github.com/pingcap/tiup/pkg/logger"
 	"github.com/pingcap/tiup/pkg/logger/log"
 	"github.com/pingcap/tiup/pkg/repository"
+	"github.com/pingcap/tiup/pkg/server"
 	"github.com/pingcap/tiup/pkg/telemetry"
+	"github.com/pingcap/tiup/pkg/tui"
 	"github.com/pingcap/tiup/pkg/version"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
@@ -48,10 +51,14 @@ var (
 	rootCmd     *cobra.Command
 	gOpt        operator.Options
 	skipConfirm bool
+	colorMode   string
+	statusAddr  string
+	statusToken string
 )
 
 var tidbSpec *spec.SpecManager
 var manager *cluster.Manager
+var statusServer *server.Server
 
 func scrubClusterName(n string) string {
 	return "cluster_" + telemetry.HashReport(n)
@@ -84,6 +91,9 @@ func init() {
 	if nativeEnvVar == "true" || nativeEnvVar == "1" || nativeEnvVar == "enable" {
 		gOpt.NativeSSH = true
 	}
+	if skipVersionCheck, _ := strconv.ParseBool(os.Getenv(localdata.EnvNameSkipVersionCheck)); skipVersionCheck {
+		gOpt.IgnoreVersionCheck = true
+	}
 
 	rootCmd = &cobra.Command{
 		Use:           cliutil.OsArgs0(),
@@ -92,8 +102,12 @@ func init() {
 		SilenceErrors: true,
 		Version:       version.NewTiUPVersion().String(),
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			var err error
 			var env *tiupmeta.Environment
+			mode, err := tui.ParseColorMode(colorMode)
+			if err != nil {
+				return err
+			}
+			tui.SetColorMode(mode)
 			if err = spec.Initialize("cluster"); err != nil {
 				return err
 			}
@@ -101,11 +115,39 @@ func init() {
 			tidbSpec = spec.GetSpecManager()
 			manager = cluster.NewManager("tidb", tidbSpec, spec.TiDBComponentVersion)
 			logger.EnableAuditLog(spec.AuditDir())
+			logger.EnableLogBudget(spec.ProfileDir())
+
+			if statusAddr != "" && statusServer == nil {
+				if statusToken == "" {
+					statusToken = os.Getenv(server.EnvNameStatusToken)
+				}
+				statusServer = server.New(manager, statusAddr, statusToken)
+				if err = statusServer.Start(); err != nil {
+					return err
+				}
+			}
+
+			// seed the options with the standing defaults, unless they are set by flags
+			optDefaults, err := manager.LoadOptionDefaults()
+			if err != nil {
+				return err
+			}
+			if err = optDefaults.Apply(&gOpt, func(key string) bool {
+				flag := cmd.Flags().Lookup(key)
+				return flag != nil && flag.Changed
+			}); err != nil {
+				return err
+			}
+
+			if len(gOpt.Breakpoints) > 0 || len(gOpt.BreakOnErrors) > 0 {
+				go cluster.ResumeOnEnter(os.Stdin, nil)
+			}
 
 			// Running in other OS/ARCH Should be fine we only download manifest file.
 			env, err = tiupmeta.InitEnv(repository.Options{
-				GOOS:   "linux",
-				GOARCH: "amd64",
+				GOOS:             "linux",
+				GOARCH:           "amd64",
+				SkipVersionCheck: gOpt.IgnoreVersionCheck,
 			})
 			if err != nil {
 				return err
@@ -135,8 +177,25 @@ func init() {
 	// the value of wait-timeout is also used for `systemctl` commands, as the default timeout of systemd for
 	// start/stop operations is 90s, the default value of this argument is better be longer than that
 	rootCmd.PersistentFlags().Int64Var(&gOpt.OptTimeout, "wait-timeout", 120, "Timeout in seconds to wait for an operation to complete, ignored for operations that don't fit.")
+	rootCmd.PersistentFlags().Int64Var(&gOpt.FirstStartTimeout, "first-start-timeout", 0, "Timeout in seconds to wait for the instances deployed with empty data directories to start the first time, 0 uses --wait-timeout.")
+	rootCmd.PersistentFlags().Int64Var(&gOpt.RestartTimeout, "restart-timeout", 0, "Timeout in seconds to wait for the instances started before to start or restart, 0 uses --wait-timeout.")
+	rootCmd.PersistentFlags().Int64Var(&gOpt.OperationTimeout, "operation-timeout", 0, "Abort the operation if it's not finished in the seconds, and report where the time went, 0 means unlimited.")
+	rootCmd.PersistentFlags().BoolVar(&gOpt.OverwriteConfig, "overwrite-config", false, "Overwrite the configs on the hosts even if they are pushed by another operation after the configs are rendered.")
 	rootCmd.PersistentFlags().BoolVarP(&skipConfirm, "yes", "y", false, "Skip all confirmations and assumes 'yes'")
 	rootCmd.PersistentFlags().BoolVar(&gOpt.NativeSSH, "native-ssh", gOpt.NativeSSH, "Use the native SSH client installed on local system instead of the build-in one.")
+	rootCmd.PersistentFlags().BoolVar(&gOpt.IgnoreVersionCheck, "ignore-version-check", gOpt.IgnoreVersionCheck, fmt.Sprintf("Accept the versions not valid SemVer strings as they are, e.g., of custom builds, set by %s too.", localdata.EnvNameSkipVersionCheck))
+	rootCmd.PersistentFlags().IntVar(&gOpt.Concurrency, "concurrency", 0, "The max number of tasks of a parallel step executing at the same time, e.g., the instances configured at once, 0 means unlimited.")
+	rootCmd.PersistentFlags().Int64Var(&gOpt.TransferChunkSize, "transfer-chunk-size", 0, "Split the files larger than the size in MiB into chunks transferred in parallel, 0 disables chunking.")
+	rootCmd.PersistentFlags().IntVar(&gOpt.TransferParallel, "transfer-parallel", 4, "The max number of SSH sessions transferring the chunks of a file at the same time.")
+	rootCmd.PersistentFlags().StringSliceVar(&gOpt.Breakpoints, "break-before", nil, "Pause before the steps with the names, e.g., 'UpgradeInstance tikv 10.0.0.7:20160', until Enter is pressed.")
+	rootCmd.PersistentFlags().StringSliceVar(&gOpt.BreakOnErrors, "break-on-error", nil, "Pause at the first failure of the error types, e.g., 'executor.ssh_execute_failed', until Enter is pressed.")
+	rootCmd.PersistentFlags().StringSliceVar(&gOpt.FollowHosts, "follow-host", nil, "Print the commands executed on the hosts to stderr as they begin, and their outputs once they finish (not while the commands run).")
+	rootCmd.PersistentFlags().StringVar(&gOpt.PprofAddr, "pprof-addr", "", fmt.Sprintf("Serve pprof of tiup itself on the localhost address, e.g., 127.0.0.1:6060, during the operation, read from %s if not set.", localdata.EnvNamePprofAddr))
+	rootCmd.PersistentFlags().Int64Var(&gOpt.ProfileAfter, "profile-after", 0, "Capture a CPU profile and a heap snapshot of tiup into the log directory of the cluster if the operation runs longer than the seconds, 0 disables it.")
+	rootCmd.PersistentFlags().Int64Var(&gOpt.ProfileAboveMemory, "profile-above-memory", 0, "Capture a CPU profile and a heap snapshot of tiup into the log directory of the cluster if its heap grows above the MiB, 0 disables it.")
+	rootCmd.PersistentFlags().StringVar(&statusAddr, "status-addr", "", "Serve the status of operations over HTTP on the address, e.g., 127.0.0.1:9180, while the command runs. A token is required unless it's a loopback address.")
+	rootCmd.PersistentFlags().StringVar(&statusToken, "status-token", "", fmt.Sprintf("The token required by the status server, read from %s if not set.", server.EnvNameStatusToken))
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", tui.ColorAuto.String(), "When to use colors in the output: auto, always or never, NO_COLOR and FORCE_COLOR are honored in auto mode.")
 
 	rootCmd.AddCommand(
 		newCheckCmd(),
@@ -144,20 +203,34 @@ func init() {
 		newStartCmd(),
 		newStopCmd(),
 		newRestartCmd(),
+		newEnableCmd(),
+		newDisableCmd(),
 		newScaleInCmd(),
 		newScaleOutCmd(),
 		newDestroyCmd(),
 		newCleanCmd(),
 		newUpgradeCmd(),
 		newExecCmd(),
+		newCtlCmd(),
 		newDisplayCmd(),
 		newListCmd(),
 		newAuditCmd(),
 		newImportCmd(),
 		newEditConfigCmd(),
 		newReloadCmd(),
+		newRedeployAgentsCmd(),
+		newMigrateDataDirCmd(),
+		newBatchCmd(),
 		newPatchCmd(),
 		newRenameCmd(),
+		newRecoverCmd(),
+		newProtectCmd(),
+		newUnprotectCmd(),
+		newTagCmd(),
+		newAcceptHostKeyCmd(),
+		newInventoryCmd(),
+		newExportTargetsCmd(),
+		newVerifyVersionsCmd(),
 		newTestCmd(), // hidden command for test internally
 		newTelemetryCmd(),
 	)
@@ -245,6 +318,11 @@ func Execute() {
 	if err != nil {
 		code = 1
 	}
+	if statusServer != nil {
+		if err := statusServer.Stop(time.Second * 5); err != nil {
+			zap.L().Warn("Failed to stop the status server", zap.Error(err))
+		}
+	}
 
 	zap.L().Info("Execute command finished", zap.Int("code", code), zap.Error(err))
 
@@ -286,6 +364,12 @@ func Execute() {
 	}
 
 	if err != nil {
+		// the cluster not existing and the corrupt metadata are reported
+		// by their own messages and suggestions, even if they are wrapped
+		if errx := errutil.Cast(err); errx != nil &&
+			(errx.IsOfType(spec.ErrClusterNotExist) || errx.IsOfType(spec.ErrMetaCorrupt)) {
+			err = errx
+		}
 		if errx := errorx.Cast(err); errx != nil {
 			printErrorMessageForErrorX(errx)
 		} else {
@@ -303,7 +387,7 @@ func Execute() {
 		}
 	}
 
-	err = logger.OutputAuditLogIfEnabled()
+	_, err = logger.OutputAuditLogIfEnabled()
 	if err != nil {
 		zap.L().Warn("Write audit log file failed", zap.Error(err))
 		code = 1
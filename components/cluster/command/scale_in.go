@@ -14,6 +14,7 @@
 package command
 
 import (
+	"github.com/pingcap/tiup/pkg/cluster"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/cluster/task"
@@ -21,6 +22,7 @@ import (
 )
 
 func newScaleInCmd() *cobra.Command {
+	var exportTargets string
 	cmd := &cobra.Command{
 		Use:   "scale-in <cluster-name>",
 		Short: "Scale in a TiDB cluster",
@@ -45,21 +47,29 @@ func newScaleInCmd() *cobra.Command {
 				}
 			}
 
-			return manager.ScaleIn(
+			if err := manager.ScaleIn(
 				clusterName,
 				skipConfirm,
 				gOpt.SSHTimeout,
 				gOpt.NativeSSH,
 				gOpt.Force,
+				gOpt.OverrideProtection,
 				gOpt.Nodes,
+				gOpt.ErrorScope,
 				scale,
-			)
+			); err != nil {
+				return err
+			}
+			return exportTargetsAfterScale(clusterName, exportTargets)
 		},
 	}
 
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Specify the nodes")
 	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
 	cmd.Flags().BoolVar(&gOpt.Force, "force", false, "Force just try stop and destroy instance before removing the instance from topo")
+	cmd.Flags().BoolVar(&gOpt.OverrideProtection, cluster.OverrideProtectionFlag, false, "Confirm the operation on a protected cluster")
+	cmd.Flags().StringVar((*string)(&gOpt.ErrorScope), "error-scope", "", "How far a failure in regenerating the configs reaches: 'operation' stops at it, 'host' and 'step' keep regenerating the configs on the other hosts (default \"operation\")")
+	cmd.Flags().StringVar(&exportTargets, "export-targets", "", "Re-export the Prometheus scrape targets to the file after scaling in, see the export-targets command")
 
 	_ = cmd.MarkFlagRequired("node")
 
@@ -31,6 +31,7 @@ func newScaleOutCmd() *cobra.Command {
 	opt := cluster.ScaleOutOptions{
 		IdentityFile: filepath.Join(tiuputils.UserHome(), ".ssh", "id_rsa"),
 	}
+	var exportTargets string
 	cmd := &cobra.Command{
 		Use:          "scale-out <cluster-name> <topology.yaml>",
 		Short:        "Scale out a TiDB cluster",
@@ -48,7 +49,9 @@ func newScaleOutCmd() *cobra.Command {
 				teleTopology = string(data)
 			}
 
-			return manager.ScaleOut(
+			opt.Transfer = task.NewTransferOptions(gOpt)
+			opt.FirstStartTimeout, opt.RestartTimeout = gOpt.FirstStartTimeout, gOpt.RestartTimeout
+			if err := manager.ScaleOut(
 				clusterName,
 				topoFile,
 				postScaleOutHook,
@@ -58,7 +61,10 @@ func newScaleOutCmd() *cobra.Command {
 				gOpt.OptTimeout,
 				gOpt.SSHTimeout,
 				gOpt.NativeSSH,
-			)
+			); err != nil {
+				return err
+			}
+			return exportTargetsAfterScale(clusterName, exportTargets)
 		},
 	}
 
@@ -66,6 +72,13 @@ func newScaleOutCmd() *cobra.Command {
 	cmd.Flags().BoolVarP(&opt.SkipCreateUser, "skip-create-user", "", false, "Skip creating the user specified in topology.")
 	cmd.Flags().StringVarP(&opt.IdentityFile, "identity_file", "i", opt.IdentityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
 	cmd.Flags().BoolVarP(&opt.UsePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
+	cmd.Flags().BoolVar(&opt.AllowUnknownFields, "allow-unknown-fields", false, "Accept fields in the topology file that are unknown to this version, they are ignored")
+	cmd.Flags().BoolVar(&opt.ForceRegenerate, "force-regenerate", false, "Regenerate the Prometheus configs from scratch, the scrape jobs and rule files added outside the blocks managed by tiup are dropped")
+	cmd.Flags().BoolVar(&opt.Force, "force", false, "Remove the units left on the hosts which point to other deploy directories, e.g., by destroyed clusters, instead of failing")
+	cmd.Flags().BoolVar(&opt.AllowColocation, "allow-colocation", false, "Allow instances on hosts used by other clusters, as long as the ports and directories are distinct")
+	cmd.Flags().StringVar(&exportTargets, "export-targets", "", "Re-export the Prometheus scrape targets to the file after scaling out, see the export-targets command")
+	cmd.Flags().BoolVar(&opt.NoResume, "no-resume", false, "Start over instead of skipping the phases finished on the hosts by the last failed scale-out with the same topology")
+	cmd.Flags().StringVar((*string)(&opt.ErrorScope), "error-scope", "", "How far a failure in preparing the new hosts reaches: 'operation' stops at it, 'host' and 'step' keep preparing the other hosts and fail before any instance is started, the hosts prepared are skipped by the retry (default \"operation\")")
 
 	return cmd
 }
@@ -0,0 +1,40 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/spf13/cobra"
+)
+
+// selectorExpr is the selector expression set by the --selector flag
+var selectorExpr string
+
+// addSelectorFlags adds the flags to select the instances to operate by a
+// selector expression, see spec.ParseSelector for the syntax
+func addSelectorFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&selectorExpr, "selector", "S", "",
+		"Only operate the instances matched by the selector, e.g., 'role=tikv AND zone=us-west-1a AND NOT host=10.1.2.3'")
+	cmd.Flags().BoolVar(&gOpt.ExplainSelector, "explain-selector", false, "Print the instances matched before running")
+}
+
+// parseSelector parses the selector expression into the global options
+func parseSelector() error {
+	selector, err := spec.ParseSelector(selectorExpr)
+	if err != nil {
+		return err
+	}
+	gOpt.Selector = selector
+	return nil
+}
@@ -0,0 +1,25 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// addSilenceFlags adds the flags to silence the alerts of the affected
+// instances in Alertmanager during the operation
+func addSilenceFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&gOpt.SilenceAlerts, "silence-alerts", false, "Silence the alerts of the affected instances in Alertmanager during the operation")
+	cmd.Flags().BoolVar(&gOpt.RequireSilence, "require-silence", false, "Abort if the alerts can't be silenced, it's only warned by default")
+}
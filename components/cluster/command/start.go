@@ -20,6 +20,8 @@ import (
 )
 
 func newStartCmd() *cobra.Command {
+	dryRun := false
+	planFormat := task.PlanFormatText
 	cmd := &cobra.Command{
 		Use:   "start <cluster-name>",
 		Short: "Start a TiDB cluster",
@@ -28,12 +30,19 @@ func newStartCmd() *cobra.Command {
 				return cmd.Help()
 			}
 
-			if err := validRoles(gOpt.Roles); err != nil {
+			if err := validRolesOrMeta(gOpt.Roles); err != nil {
 				return nil
 			}
 
+			if err := parseSelector(); err != nil {
+				return err
+			}
+
 			clusterName := args[0]
 			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			if dryRun {
+				gOpt.PlanFormat = planFormat
+			}
 
 			return manager.StartCluster(clusterName, gOpt, func(b *task.Builder, metadata spec.Metadata) {
 				tidbMeta := metadata.(*spec.ClusterMeta)
@@ -42,8 +51,20 @@ func newStartCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only start specified roles")
+	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only start specified roles, @core and @monitoring stand for the database and monitoring components")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only start specified nodes")
+	cmd.Flags().DurationVar(&gOpt.StartStagger, "start-stagger", 0, "Delay the k-th instance of each component by k times this duration when starting them, e.g. 5s, for the hosts sharing the same storage")
+	cmd.Flags().DurationVar(&gOpt.StartStaggerJitter, "start-stagger-jitter", 0, "Delay each instance by a random duration up to this one in addition to --start-stagger")
+	cmd.Flags().BoolVar(&gOpt.Serial, "serial", false, "Start the instances one at a time in the component order, for environments that can't afford many of them starting at once")
+	addSelectorFlags(cmd)
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the plan of the tasks to start the cluster, with the units and commands of the instances, the hosts are not connected to")
+	cmd.Flags().StringVar(&planFormat, "plan-format", planFormat, "The format of the plan printed in dry-run mode, 'text' or 'dot' (Graphviz)")
+	cmd.Flags().BoolVar(&gOpt.IgnoreErrors, "ignore-errors", false, "Keep starting the other instances when some of them fail, the failures are reported at the end, same as --error-scope=step")
+	cmd.Flags().StringVar((*string)(&gOpt.ErrorScope), "error-scope", "", "How far a failure reaches: 'operation' stops at it, 'host' skips the rest steps on its host, 'step' only fails its step (default \"operation\")")
+	cmd.Flags().BoolVar(&gOpt.Force, "force", false, "Start the instances even if their data directories are nearly full")
+	cmd.Flags().Int64Var(&gOpt.DiskUsageWarn, "disk-usage-warn", 85, "Warn when the filesystem of a data directory is used above this percentage, 0 to disable")
+	cmd.Flags().Int64Var(&gOpt.DiskUsageFail, "disk-usage-fail", 95, "Refuse to start when the filesystem of a data directory is used above this percentage, 0 to disable")
+	cmd.Flags().BoolVar(&gOpt.OverwriteDashboards, "overwrite-dashboards", false, "Overwrite the dashboards modified in Grafana when they are provisioned via the API")
 
 	return cmd
 }
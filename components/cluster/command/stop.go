@@ -14,10 +14,13 @@
 package command
 
 import (
+	"github.com/pingcap/tiup/pkg/cluster/task"
 	"github.com/spf13/cobra"
 )
 
 func newStopCmd() *cobra.Command {
+	dryRun := false
+	planFormat := task.PlanFormatText
 	cmd := &cobra.Command{
 		Use:   "stop <cluster-name>",
 		Short: "Stop a TiDB cluster",
@@ -26,19 +29,34 @@ func newStopCmd() *cobra.Command {
 				return cmd.Help()
 			}
 
-			if err := validRoles(gOpt.Roles); err != nil {
+			if err := validRolesOrMeta(gOpt.Roles); err != nil {
+				return err
+			}
+
+			if err := parseSelector(); err != nil {
 				return err
 			}
 
 			clusterName := args[0]
 			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+			if dryRun {
+				gOpt.PlanFormat = planFormat
+			}
 
 			return manager.StopCluster(clusterName, gOpt)
 		},
 	}
 
-	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only stop specified roles")
+	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only stop specified roles, @core and @monitoring stand for the database and monitoring components")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only stop specified nodes")
+	cmd.Flags().BoolVar(&gOpt.Serial, "serial", false, "Stop the instances one at a time in the component order, for environments that can't afford many of them stopping at once")
+	cmd.Flags().StringVar((*string)(&gOpt.ErrorScope), "error-scope", "", "How far a failure reaches: 'operation' stops at it, 'host' skips the rest steps on its host, 'step' only fails its step (default \"operation\")")
+	cmd.Flags().BoolVar(&gOpt.KillOrphans, "kill-orphans", false, "Kill the processes left running under the deploy and data directories of the stopped instances")
+	cmd.Flags().Int64Var(&gOpt.OrphanGracePeriod, "orphan-grace-period", 10, "Seconds to wait for the orphaned processes to exit after SIGTERM before sending SIGKILL")
+	addSelectorFlags(cmd)
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the plan of the tasks to stop the cluster, with the units and commands of the instances, the hosts are not connected to")
+	cmd.Flags().StringVar(&planFormat, "plan-format", planFormat, "The format of the plan printed in dry-run mode, 'text' or 'dot' (Graphviz)")
+	addSilenceFlags(cmd)
 
 	return cmd
 }
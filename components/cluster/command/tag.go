@@ -0,0 +1,131 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+func newTagCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tag",
+		Short: "Manage the tags of TiDB clusters",
+		Long: `Manage the tags of TiDB clusters, the tags are key/values like team=payments
+or env=staging, clusters can be filtered by them in the list and batch commands.`,
+	}
+
+	cmd.AddCommand(
+		newTagSetCmd(),
+		newTagDeleteCmd(),
+		newTagListCmd(),
+	)
+	return cmd
+}
+
+func newTagSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set <cluster-name> <key>=<value>...",
+		Short: "Set tags of a TiDB cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			for _, tag := range args[1:] {
+				idx := strings.Index(tag, "=")
+				if idx < 0 {
+					return fmt.Errorf("tag `%s` should be in the form of <key>=<value>", tag)
+				}
+				if err := cluster.ValidateTag(tag[:idx], tag[idx+1:]); err != nil {
+					return err
+				}
+			}
+			for _, tag := range args[1:] {
+				idx := strings.Index(tag, "=")
+				if err := manager.SetTag(clusterName, tag[:idx], tag[idx+1:]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newTagDeleteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete <cluster-name> <key>...",
+		Short: "Delete tags of a TiDB cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			for _, key := range args[1:] {
+				if err := manager.DeleteTag(clusterName, key); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newTagListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list <cluster-name>",
+		Short: "List the tags of a TiDB cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			tags, err := manager.GetTags(clusterName)
+			if err != nil {
+				return err
+			}
+			keys := make([]string, 0, len(tags))
+			for k := range tags {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			table := [][]string{{"Key", "Value"}}
+			for _, k := range keys {
+				table = append(table, []string{k, tags[k]})
+			}
+			cliutil.PrintTable(table, true)
+			return nil
+		},
+	}
+
+	return cmd
+}
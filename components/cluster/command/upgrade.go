@@ -14,10 +14,13 @@
 package command
 
 import (
+	"github.com/pingcap/tiup/pkg/cluster/task"
 	"github.com/spf13/cobra"
 )
 
 func newUpgradeCmd() *cobra.Command {
+	dryRun := false
+	planFormat := task.PlanFormatText
 	cmd := &cobra.Command{
 		Use:   "upgrade <cluster-name> <version>",
 		Short: "Upgrade a specified TiDB cluster",
@@ -30,13 +33,30 @@ func newUpgradeCmd() *cobra.Command {
 			version := args[1]
 			teleCommand = append(teleCommand, scrubClusterName(clusterName))
 			teleCommand = append(teleCommand, version)
+			if dryRun {
+				gOpt.PlanFormat = planFormat
+			}
+			gOpt.SkipConfirm = skipConfirm
 
 			return manager.Upgrade(clusterName, version, gOpt)
 		},
 	}
 	cmd.Flags().BoolVar(&gOpt.Force, "force", false, "Force upgrade without transferring PD leader")
+	cmd.Flags().StringSliceVar(&gOpt.Components, "component", nil, "Only upgrade the components, e.g. tidb, the others keep their versions")
 	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
 	cmd.Flags().BoolVarP(&gOpt.IgnoreConfigCheck, "ignore-config-check", "", false, "Ignore the config check result")
+	cmd.Flags().BoolVar(&gOpt.CachePackages, "cache-packages", false, "Keep the component packages on hosts and skip pushing them if checksums match")
+	cmd.Flags().StringSliceVar(&gOpt.SeedHosts, "seed-hosts", nil, "Push the component packages to these hosts first, other hosts fetch them from the seed hosts (implies --cache-packages)")
+	cmd.Flags().StringVar((*string)(&gOpt.ErrorScope), "error-scope", "", "How far a failure in copying the packages reaches: 'operation' stops at it, 'host' and 'step' keep copying to the other hosts and fail the upgrade before any instance is upgraded (default \"operation\")")
+	cmd.Flags().BoolVar(&gOpt.StrictSelfCheck, "strict-self-check", false, "Fail if the control machine fails the self checks (umask, locale, free space and clock), they are only warned otherwise")
+	cmd.Flags().StringVar(&gOpt.PackageDir, "package-dir", "", "Use the component packages (<component>-<version>-<os>-<arch>.tar.gz) in the directory instead of downloading them, checksums are read from sha256sum.txt in it or the local manifests")
+	cmd.Flags().BoolVar(&gOpt.ReadinessGate, "readiness-gate", false, "Wait after upgrading each TiKV instance until PD reports the regions are healthy again")
+	cmd.Flags().IntVar(&gOpt.ReadinessMaxPendingPeers, "readiness-max-pending-peers", 0, "The max number of regions with pending peers to pass the readiness gate")
+	cmd.Flags().Float64Var(&gOpt.ReadinessMaxScoreSpread, "readiness-max-score-spread", 0, "The max spread of the region scores of the stores in percent to pass the readiness gate, 0 doesn't check the balance")
+	cmd.Flags().Int64Var(&gOpt.ReadinessTimeout, "readiness-timeout", 600, "Timeout in seconds waiting for the readiness gate, the upgrade fails with the last readings after it")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the plan of the tasks to upgrade the cluster")
+	cmd.Flags().StringVar(&planFormat, "plan-format", planFormat, "The format of the plan printed in dry-run mode, 'text' or 'dot' (Graphviz)")
+	addSilenceFlags(cmd)
 
 	return cmd
 }
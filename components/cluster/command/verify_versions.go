@@ -0,0 +1,67 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pingcap/tiup/pkg/cluster"
+	"github.com/spf13/cobra"
+)
+
+func newVerifyVersionsCmd() *cobra.Command {
+	var (
+		opt        cluster.VerifyVersionsOptions
+		jsonOutput bool
+	)
+	cmd := &cobra.Command{
+		Use:   "verify-versions <cluster-name>",
+		Short: "Verify the versions of the deployed binaries against the metadata",
+		Long: `Verify the versions of the deployed binaries against the metadata of a TiDB
+cluster, the binaries replaced on the hosts by hand are reported with both the
+versions. Use --adopt to update the version in the metadata to the one of the
+binaries, which requires all the binaries to be of the same version.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			report, err := manager.VerifyVersions(clusterName, opt, gOpt)
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+			cluster.PrintVersionReport(report)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only verify specified roles")
+	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only verify specified nodes")
+	cmd.Flags().BoolVar(&opt.Adopt, "adopt", false, "Update the version in the metadata to the one of the binaries")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the result in JSON")
+
+	return cmd
+}
@@ -14,6 +14,7 @@
 package command
 
 import (
+	"github.com/pingcap/tiup/pkg/cluster"
 	"github.com/pingcap/tiup/pkg/cluster/audit"
 	cspec "github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/spf13/cobra"
@@ -34,5 +35,30 @@ func newAuditCmd() *cobra.Command {
 			}
 		},
 	}
+	cmd.AddCommand(newAuditScaleOutPostMortemCmd())
+	return cmd
+}
+
+func newAuditScaleOutPostMortemCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scale-out-post-mortem <cluster-name | checkpoint-file>",
+		Short: "Analyze the checkpoint of the last failed scale-out",
+		Long: `Analyze the checkpoint of the last failed scale-out of a DM cluster, or a
+checkpoint file copied from another control machine. The failed step, its
+error and host, the steps completed before it and how long each phase took
+are shown. Only the scale-out records its steps in the checkpoint.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			pm, err := manager.ScaleOutPostMortem(args[0])
+			if err != nil {
+				return err
+			}
+			cluster.PrintPostMortem(pm)
+			return nil
+		},
+	}
 	return cmd
 }
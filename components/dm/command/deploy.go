@@ -19,9 +19,10 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cliutil"
 	"github.com/pingcap/tiup/pkg/cluster"
+	"github.com/pingcap/tiup/pkg/cluster/task"
 	tiuputils "github.com/pingcap/tiup/pkg/utils"
+	"github.com/pingcap/tiup/pkg/version"
 	"github.com/spf13/cobra"
-	"golang.org/x/mod/semver"
 )
 
 func newDeploy() *cobra.Command {
@@ -50,6 +51,8 @@ func newDeploy() *cobra.Command {
 				return err
 			}
 
+			opt.Transfer = task.NewTransferOptions(gOpt)
+			opt.IgnoreVersionCheck = gOpt.IgnoreVersionCheck
 			return manager.Deploy(
 				clusterName,
 				version,
@@ -67,18 +70,21 @@ func newDeploy() *cobra.Command {
 	cmd.Flags().StringVarP(&opt.User, "user", "u", tiuputils.CurrentUser(), "The user name to login via SSH. The user must has root (or sudo) privilege.")
 	cmd.Flags().StringVarP(&opt.IdentityFile, "identity_file", "i", opt.IdentityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
 	cmd.Flags().BoolVarP(&opt.UsePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
+	cmd.Flags().BoolVar(&opt.AllowUnknownFields, "allow-unknown-fields", false, "Accept fields in the topology file that are unknown to this version, they are ignored")
+	cmd.Flags().BoolVar(&opt.StrictSelfCheck, "strict-self-check", false, "Fail if the control machine fails the self checks (umask, locale, free space and clock), they are only warned otherwise")
+	cmd.Flags().BoolVar(&opt.AllowColocation, "allow-colocation", false, "Allow instances on hosts used by other clusters, as long as the ports and directories are distinct")
 	cmd.Flags().BoolVarP(&opt.IgnoreConfigCheck, "ignore-config-check", "", opt.IgnoreConfigCheck, "Ignore the config check result")
 
 	return cmd
 }
 
 func supportVersion(vs string) error {
-	if !semver.IsValid(vs) {
+	v, err := version.Parse(vs)
+	if err != nil || v.IsNightly() {
 		return nil
 	}
 
-	majorMinor := semver.MajorMinor(vs)
-	if semver.Compare(majorMinor, "v2.0") < 0 {
+	if version.Compare(v.MajorMinor(), "v2.0") < 0 {
 		return errors.Errorf("Only support version not less than v2.0")
 	}
 
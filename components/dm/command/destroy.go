@@ -14,6 +14,7 @@
 package command
 
 import (
+	"github.com/pingcap/tiup/pkg/cluster"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/spf13/cobra"
 )
@@ -34,5 +35,7 @@ func newDestroyCmd() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().BoolVar(&gOpt.OverrideProtection, cluster.OverrideProtectionFlag, false, "Confirm the operation on a protected cluster")
+
 	return cmd
 }
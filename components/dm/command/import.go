@@ -23,6 +23,7 @@ import (
 	"github.com/pingcap/tiup/pkg/cliutil"
 	"github.com/pingcap/tiup/pkg/cluster"
 	cansible "github.com/pingcap/tiup/pkg/cluster/ansible"
+	"github.com/pingcap/tiup/pkg/cluster/task"
 	tiuputils "github.com/pingcap/tiup/pkg/utils"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
@@ -97,6 +98,7 @@ func newImportCmd() *cobra.Command {
 				cluster.DeployOptions{
 					IdentityFile: cansible.SSHKeyPath(),
 					User:         tiuputils.CurrentUser(),
+					Transfer:     task.NewTransferOptions(gOpt),
 				},
 				nil,
 				skipConfirm,
@@ -34,6 +34,7 @@ func newRestartCmd() *cobra.Command {
 
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only restart specified roles")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only restart specified nodes")
+	cmd.Flags().BoolVar(&gOpt.Serial, "serial", false, "Restart the instances one at a time in the component order, for environments that can't afford many of them restarting at once")
 
 	return cmd
 }
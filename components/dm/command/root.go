@@ -16,6 +16,7 @@ package command
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/fatih/color"
@@ -32,6 +33,7 @@ import (
 	"github.com/pingcap/tiup/pkg/localdata"
 	"github.com/pingcap/tiup/pkg/logger"
 	"github.com/pingcap/tiup/pkg/repository"
+	"github.com/pingcap/tiup/pkg/tui"
 	"github.com/pingcap/tiup/pkg/version"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
@@ -43,6 +45,7 @@ var (
 	rootCmd     *cobra.Command
 	gOpt        operator.Options
 	skipConfirm bool
+	colorMode   string
 )
 
 var dmspec *cspec.SpecManager
@@ -61,6 +64,9 @@ func init() {
 	if nativeEnvVar == "true" || nativeEnvVar == "1" || nativeEnvVar == "enable" {
 		gOpt.NativeSSH = true
 	}
+	if skipVersionCheck, _ := strconv.ParseBool(os.Getenv(localdata.EnvNameSkipVersionCheck)); skipVersionCheck {
+		gOpt.IgnoreVersionCheck = true
+	}
 
 	rootCmd = &cobra.Command{
 		Use:           cliutil.OsArgs0(),
@@ -69,26 +75,36 @@ func init() {
 		SilenceErrors: true,
 		Version:       version.NewTiUPVersion().String(),
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			var err error
 			var env *tiupmeta.Environment
+			mode, err := tui.ParseColorMode(colorMode)
+			if err != nil {
+				return err
+			}
+			tui.SetColorMode(mode)
 			if err = cspec.Initialize("dm"); err != nil {
 				return err
 			}
 
 			dmspec = spec.GetSpecManager()
 			logger.EnableAuditLog(cspec.AuditDir())
+			logger.EnableLogBudget(cspec.ProfileDir())
 			manager = cluster.NewManager("dm", dmspec, spec.DMComponentVersion)
 
 			// Running in other OS/ARCH Should be fine we only download manifest file.
 			env, err = tiupmeta.InitEnv(repository.Options{
-				GOOS:   "linux",
-				GOARCH: "amd64",
+				GOOS:             "linux",
+				GOARCH:           "amd64",
+				SkipVersionCheck: gOpt.IgnoreVersionCheck,
 			})
 			if err != nil {
 				return err
 			}
 			tiupmeta.SetGlobalEnv(env)
 
+			if len(gOpt.Breakpoints) > 0 || len(gOpt.BreakOnErrors) > 0 {
+				go cluster.ResumeOnEnter(os.Stdin, nil)
+			}
+
 			if gOpt.NativeSSH {
 				zap.L().Info("Native ssh client will be used",
 					zap.String(localdata.EnvNameNativeSSHClient, os.Getenv(localdata.EnvNameNativeSSHClient)))
@@ -108,8 +124,18 @@ func init() {
 
 	rootCmd.PersistentFlags().Int64Var(&gOpt.SSHTimeout, "ssh-timeout", 5, "Timeout in seconds to connect host via SSH, ignored for operations that don't need an SSH connection.")
 	rootCmd.PersistentFlags().Int64Var(&gOpt.OptTimeout, "wait-timeout", 60, "Timeout in seconds to wait for an operation to complete, ignored for operations that don't fit.")
+	rootCmd.PersistentFlags().Int64Var(&gOpt.FirstStartTimeout, "first-start-timeout", 0, "Timeout in seconds to wait for the instances deployed with empty data directories to start the first time, 0 uses --wait-timeout.")
+	rootCmd.PersistentFlags().Int64Var(&gOpt.RestartTimeout, "restart-timeout", 0, "Timeout in seconds to wait for the instances started before to start or restart, 0 uses --wait-timeout.")
+	rootCmd.PersistentFlags().Int64Var(&gOpt.OperationTimeout, "operation-timeout", 0, "Abort the operation if it's not finished in the seconds, and report where the time went, 0 means unlimited.")
+	rootCmd.PersistentFlags().BoolVar(&gOpt.OverwriteConfig, "overwrite-config", false, "Overwrite the configs on the hosts even if they are pushed by another operation after the configs are rendered.")
 	rootCmd.PersistentFlags().BoolVarP(&skipConfirm, "yes", "y", false, "Skip all confirmations and assumes 'yes'")
 	rootCmd.PersistentFlags().BoolVar(&gOpt.NativeSSH, "native-ssh", gOpt.NativeSSH, "Use the native SSH client installed on local system instead of the build-in one.")
+	rootCmd.PersistentFlags().BoolVar(&gOpt.IgnoreVersionCheck, "ignore-version-check", gOpt.IgnoreVersionCheck, fmt.Sprintf("Accept the versions not valid SemVer strings as they are, e.g., of custom builds, set by %s too.", localdata.EnvNameSkipVersionCheck))
+	rootCmd.PersistentFlags().Int64Var(&gOpt.TransferChunkSize, "transfer-chunk-size", 0, "Split the files larger than the size in MiB into chunks transferred in parallel, 0 disables chunking.")
+	rootCmd.PersistentFlags().IntVar(&gOpt.TransferParallel, "transfer-parallel", 4, "The max number of SSH sessions transferring the chunks of a file at the same time.")
+	rootCmd.PersistentFlags().StringSliceVar(&gOpt.Breakpoints, "break-before", nil, "Pause before the steps with the names, e.g., 'UpgradeInstance dm-worker 10.0.0.7:8262', until Enter is pressed.")
+	rootCmd.PersistentFlags().StringSliceVar(&gOpt.BreakOnErrors, "break-on-error", nil, "Pause at the first failure of the error types, e.g., 'executor.ssh_execute_failed', until Enter is pressed.")
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", tui.ColorAuto.String(), "When to use colors in the output: auto, always or never, NO_COLOR and FORCE_COLOR are honored in auto mode.")
 
 	rootCmd.AddCommand(
 		newDeploy(),
@@ -207,6 +233,12 @@ func Execute() {
 	zap.L().Info("Execute command finished", zap.Int("code", code), zap.Error(err))
 
 	if err != nil {
+		// the cluster not existing and the corrupt metadata are reported
+		// by their own messages and suggestions, even if they are wrapped
+		if errx := errutil.Cast(err); errx != nil &&
+			(errx.IsOfType(cspec.ErrClusterNotExist) || errx.IsOfType(cspec.ErrMetaCorrupt)) {
+			err = errx
+		}
 		if errx := errorx.Cast(err); errx != nil {
 			printErrorMessageForErrorX(errx)
 		} else {
@@ -224,7 +256,7 @@ func Execute() {
 		}
 	}
 
-	err = logger.OutputAuditLogIfEnabled()
+	_, err = logger.OutputAuditLogIfEnabled()
 	if err != nil {
 		zap.L().Warn("Write audit log file failed", zap.Error(err))
 		code = 1
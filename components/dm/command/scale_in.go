@@ -20,6 +20,7 @@ import (
 	"github.com/pingcap/errors"
 	dm "github.com/pingcap/tiup/components/dm/spec"
 	dmtask "github.com/pingcap/tiup/components/dm/task"
+	"github.com/pingcap/tiup/pkg/cluster"
 	"github.com/pingcap/tiup/pkg/cluster/api"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
@@ -57,7 +58,9 @@ func newScaleInCmd() *cobra.Command {
 				gOpt.SSHTimeout,
 				gOpt.NativeSSH,
 				gOpt.Force,
+				gOpt.OverrideProtection,
 				gOpt.Nodes,
+				gOpt.ErrorScope,
 				scale,
 			)
 		},
@@ -66,6 +69,7 @@ func newScaleInCmd() *cobra.Command {
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Specify the nodes")
 	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring dm-master leaders")
 	cmd.Flags().BoolVar(&gOpt.Force, "force", false, "Force just try stop and destroy instance before removing the instance from topo")
+	cmd.Flags().BoolVar(&gOpt.OverrideProtection, cluster.OverrideProtectionFlag, false, "Confirm the operation on a protected cluster")
 
 	_ = cmd.MarkFlagRequired("node")
 
@@ -17,6 +17,7 @@ import (
 	"path/filepath"
 
 	"github.com/pingcap/tiup/pkg/cluster"
+	"github.com/pingcap/tiup/pkg/cluster/task"
 	tiuputils "github.com/pingcap/tiup/pkg/utils"
 	"github.com/spf13/cobra"
 )
@@ -37,6 +38,8 @@ func newScaleOutCmd() *cobra.Command {
 			clusterName := args[0]
 			topoFile := args[1]
 
+			opt.Transfer = task.NewTransferOptions(gOpt)
+			opt.FirstStartTimeout, opt.RestartTimeout = gOpt.FirstStartTimeout, gOpt.RestartTimeout
 			return manager.ScaleOut(
 				clusterName,
 				topoFile,
@@ -54,6 +57,9 @@ func newScaleOutCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&opt.User, "user", "u", tiuputils.CurrentUser(), "The user name to login via SSH. The user must has root (or sudo) privilege.")
 	cmd.Flags().StringVarP(&opt.IdentityFile, "identity_file", "i", opt.IdentityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
 	cmd.Flags().BoolVarP(&opt.UsePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
+	cmd.Flags().BoolVar(&opt.AllowUnknownFields, "allow-unknown-fields", false, "Accept fields in the topology file that are unknown to this version, they are ignored")
+	cmd.Flags().BoolVar(&opt.AllowColocation, "allow-colocation", false, "Allow instances on hosts used by other clusters, as long as the ports and directories are distinct")
+	cmd.Flags().BoolVar(&opt.NoResume, "no-resume", false, "Start over instead of skipping the phases finished on the hosts by the last failed scale-out with the same topology")
 
 	return cmd
 }
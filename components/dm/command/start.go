@@ -34,6 +34,7 @@ func newStartCmd() *cobra.Command {
 
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only start specified roles")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only start specified nodes")
+	cmd.Flags().BoolVar(&gOpt.Serial, "serial", false, "Start the instances one at a time in the component order, for environments that can't afford many of them starting at once")
 
 	return cmd
 }
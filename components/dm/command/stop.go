@@ -34,6 +34,9 @@ func newStopCmd() *cobra.Command {
 
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only stop specified roles")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only stop specified nodes")
+	cmd.Flags().BoolVar(&gOpt.Serial, "serial", false, "Stop the instances one at a time in the component order, for environments that can't afford many of them stopping at once")
+	cmd.Flags().BoolVar(&gOpt.KillOrphans, "kill-orphans", false, "Kill the processes left running under the deploy and data directories of the stopped instances")
+	cmd.Flags().Int64Var(&gOpt.OrphanGracePeriod, "orphan-grace-period", 10, "Seconds to wait for the orphaned processes to exit after SIGTERM before sending SIGKILL")
 
 	return cmd
 }
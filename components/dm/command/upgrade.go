@@ -26,12 +26,16 @@ func newUpgradeCmd() *cobra.Command {
 				return cmd.Help()
 			}
 
+			gOpt.SkipConfirm = skipConfirm
 			return manager.Upgrade(args[0], args[1], gOpt)
 		},
 	}
 	cmd.Flags().BoolVar(&gOpt.Force, "force", false, "Force upgrade won't transfer leader")
+	cmd.Flags().StringSliceVar(&gOpt.Components, "component", nil, "Only upgrade the components, e.g. tidb, the others keep their versions")
 	cmd.Flags().Int64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring dm-master leaders")
 	cmd.Flags().BoolVarP(&gOpt.IgnoreConfigCheck, "ignore-config-check", "", false, "Ignore the config check result")
+	cmd.Flags().BoolVar(&gOpt.StrictSelfCheck, "strict-self-check", false, "Fail if the control machine fails the self checks (umask, locale, free space and clock), they are only warned otherwise")
+	cmd.Flags().StringVar(&gOpt.PackageDir, "package-dir", "", "Use the component packages (<component>-<version>-<os>-<arch>.tar.gz) in the directory instead of downloading them, checksums are read from sha256sum.txt in it or the local manifests")
 
 	return cmd
 }
@@ -71,18 +71,18 @@ type AlertManagerInstance struct {
 
 // InitConfig implement Instance interface
 func (i *AlertManagerInstance) InitConfig(e executor.Executor, clusterName, clusterVersion, deployUser string, paths meta.DirPaths) error {
-	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, deployUser, paths); err != nil {
+	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, clusterName, clusterVersion, deployUser, paths); err != nil {
 		return err
 	}
 
 	// Transfer start script
-	spec := i.InstanceSpec.(AlertManagerSpec)
-	cfg := scripts.NewAlertManagerScript(spec.Host, paths.Deploy, paths.Data[0], paths.Log).
-		WithWebPort(spec.WebPort).WithClusterPort(spec.ClusterPort).WithNumaNode(spec.NumaNode).
+	amSpec := i.InstanceSpec.(AlertManagerSpec)
+	cfg := scripts.NewAlertManagerScript(amSpec.Host, paths.Deploy, paths.Data[0], paths.Log).
+		WithWebPort(amSpec.WebPort).WithClusterPort(amSpec.ClusterPort).WithNumaNode(amSpec.NumaNode).
 		AppendEndpoints(cspec.AlertManagerEndpoints(i.topo.Alertmanager, deployUser))
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_alertmanager_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := cspec.RenderTemplate(cfg, clusterName, clusterVersion, ComponentAlertManager, "run_alertmanager.sh.tpl", fp); err != nil {
 		return err
 	}
 
@@ -96,7 +96,7 @@ func (i *AlertManagerInstance) InitConfig(e executor.Executor, clusterName, clus
 
 	// transfer config
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("alertmanager_%s.yml", i.GetHost()))
-	if err := config.NewAlertManagerConfig().ConfigToFile(fp); err != nil {
+	if err := cspec.RenderTemplate(config.NewAlertManagerConfig(), clusterName, clusterVersion, ComponentAlertManager, "alertmanager.yml", fp); err != nil {
 		return err
 	}
 	dst = filepath.Join(paths.Deploy, "conf", "alertmanager.yml")
@@ -29,11 +29,31 @@ type Metadata struct {
 	Version string `yaml:"dm_version"` // the version of TiDB cluster
 	//EnableTLS      bool   `yaml:"enable_tls"`
 	//EnableFirewall bool   `yaml:"firewall"`
+	// Destructive operations on the cluster require an extra flag to confirm
+	Protected bool `yaml:"protected,omitempty"`
+	// User defined key/values of the cluster, e.g., team=payments
+	Tags map[string]string `yaml:"tags,omitempty"`
+	// The generation of the configs pushed to the hosts, increased by each
+	// succeeded operation pushing configs
+	ConfigGeneration uint64 `yaml:"config_generation,omitempty"`
+	// The fingerprints of the SSH host keys of the hosts, recorded when the
+	// hosts are connected the first time
+	HostKeys map[string]string `yaml:"host_keys,omitempty"`
+	// The versions of the components upgraded apart from the others, the
+	// components not in it are of the version of the cluster
+	ComponentVersions map[string]string `yaml:"component_versions,omitempty"`
 
 	Topology *Topology `yaml:"topology"`
 }
 
-var _ cspec.UpgradableMetadata = &Metadata{}
+var (
+	_ cspec.UpgradableMetadata         = &Metadata{}
+	_ cspec.ProtectableMetadata        = &Metadata{}
+	_ cspec.TaggableMetadata           = &Metadata{}
+	_ cspec.GenerationalMetadata       = &Metadata{}
+	_ cspec.HostKeyedMetadata          = &Metadata{}
+	_ cspec.ComponentVersionedMetadata = &Metadata{}
+)
 
 // SetVersion implement UpgradableMetadata interface.
 func (m *Metadata) SetVersion(s string) {
@@ -45,6 +65,31 @@ func (m *Metadata) SetUser(s string) {
 	m.User = s
 }
 
+// SetProtected implement ProtectableMetadata interface.
+func (m *Metadata) SetProtected(protected bool) {
+	m.Protected = protected
+}
+
+// SetConfigGeneration implement GenerationalMetadata interface.
+func (m *Metadata) SetConfigGeneration(generation uint64) {
+	m.ConfigGeneration = generation
+}
+
+// SetHostKeys implement HostKeyedMetadata interface.
+func (m *Metadata) SetHostKeys(keys map[string]string) {
+	m.HostKeys = keys
+}
+
+// SetComponentVersions implement ComponentVersionedMetadata interface.
+func (m *Metadata) SetComponentVersions(versions map[string]string) {
+	m.ComponentVersions = versions
+}
+
+// SetTags implement TaggableMetadata interface.
+func (m *Metadata) SetTags(tags map[string]string) {
+	m.Tags = tags
+}
+
 // GetTopology implements Metadata interface.
 func (m *Metadata) GetTopology() cspec.Topology {
 	return m.Topology
@@ -63,8 +108,15 @@ func (m *Metadata) SetTopology(topo cspec.Topology) {
 // GetBaseMeta implements Metadata interface.
 func (m *Metadata) GetBaseMeta() *cspec.BaseMeta {
 	return &cspec.BaseMeta{
-		Version: m.Version,
-		User:    m.User,
+		Version:   m.Version,
+		User:      m.User,
+		Protected: m.Protected,
+		Tags:      m.Tags,
+
+		ConfigGeneration: m.ConfigGeneration,
+		HostKeys:         m.HostKeys,
+
+		ComponentVersions: m.ComponentVersions,
 	}
 }
 
@@ -76,7 +76,7 @@ func (i *GrafanaInstance) InitConfig(e executor.Executor, clusterName, clusterVe
 		return errors.New("no prometheus found in topology")
 	}
 
-	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, deployUser, paths); err != nil {
+	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, clusterName, clusterVersion, deployUser, paths); err != nil {
 		return err
 	}
 
@@ -84,7 +84,7 @@ func (i *GrafanaInstance) InitConfig(e executor.Executor, clusterName, clusterVe
 	tpl := filepath.Join("/templates", "scripts", "dm", "run_grafana.sh.tpl")
 	cfg := scripts.NewGrafanaScript(clusterName, paths.Deploy).WithTPLFile(tpl)
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_grafana_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := spec.RenderTemplate(cfg, clusterName, clusterVersion, ComponentGrafana, "run_grafana.sh.tpl", fp); err != nil {
 		return err
 	}
 
@@ -99,7 +99,8 @@ func (i *GrafanaInstance) InitConfig(e executor.Executor, clusterName, clusterVe
 
 	// transfer config
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("grafana_%s.ini", i.GetHost()))
-	if err := config.NewGrafanaConfig(i.GetHost(), paths.Deploy).WithPort(uint64(i.GetPort())).ConfigToFile(fp); err != nil {
+	grafanaCfg := config.NewGrafanaConfig(i.GetHost(), paths.Deploy).WithPort(uint64(i.GetPort()))
+	if err := spec.RenderTemplate(grafanaCfg, clusterName, clusterVersion, ComponentGrafana, "grafana.ini.tpl", fp); err != nil {
 		return err
 	}
 	dst = filepath.Join(paths.Deploy, "conf", "grafana.ini")
@@ -126,7 +127,8 @@ func (i *GrafanaInstance) InitConfig(e executor.Executor, clusterName, clusterVe
 
 	// transfer dashboard.yml
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("dashboard_%s.yml", i.GetHost()))
-	if err := config.NewDashboardConfig(clusterName, paths.Deploy).ConfigToFile(fp); err != nil {
+	dashboardCfg := config.NewDashboardConfig(clusterName, paths.Deploy)
+	if err := spec.RenderTemplate(dashboardCfg, clusterName, clusterVersion, ComponentGrafana, "dashboard.yml.tpl", fp); err != nil {
 		return err
 	}
 	dst = filepath.Join(dashboardDir, "dashboard.yml")
@@ -136,9 +138,9 @@ func (i *GrafanaInstance) InitConfig(e executor.Executor, clusterName, clusterVe
 
 	// transfer datasource.yml
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("datasource_%s.yml", i.GetHost()))
-	if err := config.NewDatasourceConfig(clusterName, i.topo.Monitors[0].Host).
-		WithPort(uint64(i.topo.Monitors[0].Port)).
-		ConfigToFile(fp); err != nil {
+	datasourceCfg := config.NewDatasourceConfig(clusterName, i.topo.Monitors[0].Host).
+		WithPort(uint64(i.topo.Monitors[0].Port))
+	if err := spec.RenderTemplate(datasourceCfg, clusterName, clusterVersion, ComponentGrafana, "datasource.yml.tpl", fp); err != nil {
 		return err
 	}
 	dst = filepath.Join(datasourceDir, "datasource.yml")
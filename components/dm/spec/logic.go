@@ -99,21 +99,21 @@ type MasterInstance struct {
 
 // InitConfig implement Instance interface
 func (i *MasterInstance) InitConfig(e executor.Executor, clusterName, clusterVersion, deployUser string, paths meta.DirPaths) error {
-	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, deployUser, paths); err != nil {
+	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, clusterName, clusterVersion, deployUser, paths); err != nil {
 		return err
 	}
 
-	spec := i.InstanceSpec.(MasterSpec)
+	masterSpec := i.InstanceSpec.(MasterSpec)
 	cfg := scripts.NewDMMasterScript(
-		spec.Name,
+		masterSpec.Name,
 		i.GetHost(),
 		paths.Deploy,
 		paths.Data[0],
 		paths.Log,
-	).WithPort(spec.Port).WithNumaNode(spec.NumaNode).WithPeerPort(spec.PeerPort).AppendEndpoints(i.topo.Endpoints(deployUser)...).WithV1SourcePath(spec.V1SourcePath)
+	).WithPort(masterSpec.Port).WithNumaNode(masterSpec.NumaNode).WithPeerPort(masterSpec.PeerPort).AppendEndpoints(i.topo.Endpoints(deployUser)...).WithV1SourcePath(masterSpec.V1SourcePath)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_dm-master_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := spec.RenderTemplate(cfg, clusterName, clusterVersion, ComponentDMMaster, "run_dm-master.sh.tpl", fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "scripts", "run_dm-master.sh")
@@ -124,7 +124,7 @@ func (i *MasterInstance) InitConfig(e executor.Executor, clusterName, clusterVer
 		return err
 	}
 
-	specConfig := spec.Config
+	specConfig := masterSpec.Config
 	return i.MergeServerConfig(e, i.topo.ServerConfigs.Master, specConfig, paths)
 }
 
@@ -135,18 +135,18 @@ func (i *MasterInstance) ScaleConfig(e executor.Executor, topo spec.Topology, cl
 	}
 
 	c := topo.(*Topology)
-	spec := i.InstanceSpec.(MasterSpec)
+	masterSpec := i.InstanceSpec.(MasterSpec)
 	cfg := scripts.NewDMMasterScaleScript(
-		spec.Name,
+		masterSpec.Name,
 		i.GetHost(),
 		paths.Deploy,
 		paths.Data[0],
 		paths.Log,
-	).WithPort(spec.Port).WithNumaNode(spec.NumaNode).WithPeerPort(spec.PeerPort).AppendEndpoints(c.Endpoints(deployUser)...)
+	).WithPort(masterSpec.Port).WithNumaNode(masterSpec.NumaNode).WithPeerPort(masterSpec.PeerPort).AppendEndpoints(c.Endpoints(deployUser)...)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_dm-master_%s_%d.sh", i.GetHost(), i.GetPort()))
 	log.Infof("script path: %s", fp)
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := spec.RenderTemplate(cfg, clusterName, clusterVersion, ComponentDMMaster, "run_dm-master_scale.sh.tpl", fp); err != nil {
 		return err
 	}
 
@@ -210,19 +210,19 @@ type WorkerInstance struct {
 
 // InitConfig implement Instance interface
 func (i *WorkerInstance) InitConfig(e executor.Executor, clusterName, clusterVersion, deployUser string, paths meta.DirPaths) error {
-	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, deployUser, paths); err != nil {
+	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, clusterName, clusterVersion, deployUser, paths); err != nil {
 		return err
 	}
 
-	spec := i.InstanceSpec.(WorkerSpec)
+	workerSpec := i.InstanceSpec.(WorkerSpec)
 	cfg := scripts.NewDMWorkerScript(
 		i.Name,
 		i.GetHost(),
 		paths.Deploy,
 		paths.Log,
-	).WithPort(spec.Port).WithNumaNode(spec.NumaNode).AppendEndpoints(i.topo.Endpoints(deployUser)...)
+	).WithPort(workerSpec.Port).WithNumaNode(workerSpec.NumaNode).AppendEndpoints(i.topo.Endpoints(deployUser)...)
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_dm-worker_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := spec.RenderTemplate(cfg, clusterName, clusterVersion, ComponentDMWorker, "run_dm-worker.sh.tpl", fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "scripts", "run_dm-worker.sh")
@@ -235,7 +235,7 @@ func (i *WorkerInstance) InitConfig(e executor.Executor, clusterName, clusterVer
 		return err
 	}
 
-	specConfig := spec.Config
+	specConfig := workerSpec.Config
 	return i.MergeServerConfig(e, i.topo.ServerConfigs.Worker, specConfig, paths)
 }
 
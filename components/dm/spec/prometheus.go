@@ -69,23 +69,23 @@ type MonitorInstance struct {
 
 // InitConfig implement Instance interface
 func (i *MonitorInstance) InitConfig(e executor.Executor, clusterName, clusterVersion, deployUser string, paths meta.DirPaths) error {
-	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, deployUser, paths); err != nil {
+	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, clusterName, clusterVersion, deployUser, paths); err != nil {
 		return err
 	}
 
 	// transfer run script
-	spec := i.InstanceSpec.(PrometheusSpec)
+	promSpec := i.InstanceSpec.(PrometheusSpec)
 	cfg := scripts.NewPrometheusScript(
 		i.GetHost(),
 		paths.Deploy,
 		paths.Data[0],
 		paths.Log,
-	).WithPort(spec.Port).
-		WithNumaNode(spec.NumaNode).
+	).WithPort(promSpec.Port).
+		WithNumaNode(promSpec.NumaNode).
 		WithTPLFile(filepath.Join("/templates", "scripts", "dm", "run_prometheus.sh.tpl"))
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_prometheus_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := spec.RenderTemplate(cfg, clusterName, clusterVersion, ComponentPrometheus, "run_prometheus.sh.tpl", fp); err != nil {
 		return err
 	}
 
@@ -116,7 +116,7 @@ func (i *MonitorInstance) InitConfig(e executor.Executor, clusterName, clusterVe
 		cfig.AddAlertmanager(alertmanager.Host, uint64(alertmanager.WebPort))
 	}
 
-	if err := cfig.ConfigToFile(fp); err != nil {
+	if err := spec.RenderTemplate(cfig, clusterName, clusterVersion, ComponentPrometheus, "prometheus.yml.tpl", fp); err != nil {
 		return err
 	}
 	dst = filepath.Join(paths.Deploy, "conf", "prometheus.yml")
@@ -224,9 +224,16 @@ func (s WorkerSpec) IsImported() bool {
 	return s.Imported
 }
 
+// topology is decoded by UnmarshalYAML without calling it recursively
+type topology Topology
+
+// YAMLAliases implements the clusterutil.YAMLAliased interface
+func (topo *Topology) YAMLAliases() []interface{} {
+	return []interface{}{(*topology)(nil)}
+}
+
 // UnmarshalYAML sets default values when unmarshaling the topology file
 func (topo *Topology) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	type topology Topology
 	if err := unmarshal((*topology)(topo)); err != nil {
 		return err
 	}
@@ -542,6 +549,19 @@ func (topo *Topology) Validate() error {
 		return err
 	}
 
+	hosts := make(map[string]struct{})
+	topo.IterInstance(func(inst Instance) {
+		hosts[inst.GetHost()] = struct{}{}
+	})
+	if topo.GlobalOptions.Proxy != nil {
+		if err := topo.GlobalOptions.Proxy.Validate(hosts); err != nil {
+			return err
+		}
+	}
+	if err := topo.GlobalOptions.ValidateRemoteTmpDirs(hosts); err != nil {
+		return err
+	}
+
 	return topo.dirConflictsDetect()
 }
 
@@ -18,7 +18,7 @@ import (
 	"io"
 
 	"github.com/pingcap/tiup/pkg/repository/v0manifest"
-	"golang.org/x/mod/semver"
+	tiupver "github.com/pingcap/tiup/pkg/version"
 )
 
 const tiflashProxyConfig = `
@@ -51,7 +51,7 @@ func writeTiFlashProxyConfig(w io.Writer, version v0manifest.Version, ip, deploy
 	dataDir := fmt.Sprintf("%s/flash", deployDir)
 	logDir := fmt.Sprintf("%s/log", deployDir)
 	var statusAddr string
-	if semver.Compare(version.String(), "v4.0.5") >= 0 || version.String() == "nightly" {
+	if tiupver.Compare(version.String(), "v4.0.5") >= 0 {
 		statusAddr = fmt.Sprintf(`status-addr = "0.0.0.0:%[2]d"
 advertise-status-addr = "%[1]s:%[2]d"`, ip, proxyStatusPort)
 	} else {
@@ -42,7 +42,7 @@ import (
 	"github.com/pingcap/tiup/pkg/localdata"
 	"github.com/pingcap/tiup/pkg/repository/v0manifest"
 	"github.com/pingcap/tiup/pkg/utils"
-	"golang.org/x/mod/semver"
+	tiupver "github.com/pingcap/tiup/pkg/version"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -655,11 +655,11 @@ func (p *Playground) bootCluster(env *environment.Environment, options *bootOpti
 `, options.version))
 	}
 
-	if options.version != "nightly" {
-		if semver.Compare(options.version, "v3.1.0") < 0 && options.tiflash.Num != 0 {
+	if !tiupver.IsNightly(options.version) {
+		if tiupver.Compare(options.version, "v3.1.0") < 0 && options.tiflash.Num != 0 {
 			fmt.Println(color.YellowString("Warning: current version %s doesn't support TiFlash", options.version))
 			options.tiflash.Num = 0
-		} else if runtime.GOOS == "darwin" && semver.Compare(options.version, "v4.0.0") < 0 {
+		} else if runtime.GOOS == "darwin" && tiupver.Compare(options.version, "v4.0.0") < 0 {
 			// only runs tiflash on version later than v4.0.0 when executing on darwin
 			fmt.Println(color.YellowString("Warning: current version %s doesn't support TiFlash on darwin", options.version))
 			options.tiflash.Num = 0
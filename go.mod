@@ -49,6 +49,7 @@ require (
 	github.com/pingcap/kvproto v0.0.0-20200518112156-d4aeb467de29
 	github.com/pingcap/pd/v4 v4.0.0
 	github.com/pingcap/tidb-insight v0.3.1
+	github.com/prometheus/client_golang v1.2.1
 	github.com/r3labs/diff v0.0.0-20200627101315-aecd9dd05dd2
 	github.com/relex/aini v1.1.3
 	github.com/sergi/go-diff v1.0.1-0.20180205163309-da645544ed44
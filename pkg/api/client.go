@@ -0,0 +1,139 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil/progress"
+	"github.com/pingcap/tiup/pkg/cluster"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/environment"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/repository"
+)
+
+// the defaults of the timeouts, the same as the ones of the cluster command
+const (
+	defaultSSHTimeout = 5
+	defaultOptTimeout = 120
+	defaultAPITimeout = 300
+)
+
+// Config is the configuration of a Client
+type Config struct {
+	// SSHTimeout is the timeout in seconds to connect the hosts via SSH, 5 if it's 0
+	SSHTimeout int64
+	// WaitTimeout is the timeout in seconds to wait for an operation on an
+	// instance to complete, e.g., starting it, 120 if it's 0
+	WaitTimeout int64
+	// NativeSSH uses the SSH client installed on the system instead of the builtin one
+	NativeSSH bool
+	// Env is the environment of TiUP the components are listed from, it's
+	// initialized from the data directories of the tiup command if it's nil.
+	// It's not closed by the Client if it's given.
+	Env *environment.Environment
+	// Platform is the platform the packages are fetched for by the environment
+	// initialized by the Client, in the form of os/arch, e.g., linux/arm64.
+	// It's linux/amd64 if it's empty, the same as the cluster command.
+	Platform string
+}
+
+// Client manages the TiDB clusters and the components of TiUP on this host,
+// it's safe for concurrent use
+type Client struct {
+	cfg     Config
+	manager *cluster.Manager
+	env     *environment.Environment
+	ownEnv  bool // the env is initialized by the Client
+}
+
+// initProfile initializes the profile of the cluster command once for all
+// the Clients of the process
+var initProfile struct {
+	sync.Once
+	err error
+}
+
+// New returns a Client, the data directories are the same as the ones of
+// the tiup command, so the clusters and components are shared with it. The
+// profile of the cluster command, which is global to the process, is
+// initialized by the first Client created unless the process has done it,
+// e.g., by running the cluster command, and it's shared by the Clients after
+// that. The output of the operations is global as well, see SetOutput.
+func New(cfg Config) (*Client, error) {
+	if cfg.SSHTimeout == 0 {
+		cfg.SSHTimeout = defaultSSHTimeout
+	}
+	if cfg.WaitTimeout == 0 {
+		cfg.WaitTimeout = defaultOptTimeout
+	}
+
+	initProfile.Do(func() {
+		if !spec.Initialized() {
+			initProfile.err = spec.Initialize("cluster")
+		}
+	})
+	if initProfile.err != nil {
+		return nil, errors.Annotate(initProfile.err, "failed to initialize the cluster profile")
+	}
+	c := &Client{
+		cfg:     cfg,
+		manager: cluster.NewManager("tidb", spec.GetSpecManager(), spec.TiDBComponentVersion),
+		env:     cfg.Env,
+	}
+	if c.env != nil {
+		return c, nil
+	}
+
+	platform := cfg.Platform
+	if platform == "" {
+		platform = "linux/amd64"
+	}
+	parts := strings.Split(platform, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, errors.Errorf("invalid platform '%s', it should be in the form of os/arch", platform)
+	}
+	env, err := environment.InitEnv(repository.Options{
+		GOOS:   parts[0],
+		GOARCH: parts[1],
+	})
+	if err != nil {
+		return nil, errors.Annotate(err, "failed to initialize the environment")
+	}
+	c.env = env
+	// the environment of the tiup command is reused if it's initialized
+	c.ownEnv = env != environment.GlobalEnv()
+	return c, nil
+}
+
+// Close releases the resources held by the client
+func (c *Client) Close() error {
+	if !c.ownEnv {
+		return nil
+	}
+	return c.env.Close()
+}
+
+// SetOutput sets where the messages and the progress bars of the operations
+// are printed, they're printed to stdout by default. Use ioutil.Discard to
+// drop them and track the operations by their handles instead. The output is
+// shared by all the Clients of the process.
+func SetOutput(w io.Writer) {
+	log.SetStdout(w)
+	progress.SetOutput(w)
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/environment"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiup-api-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	os.Setenv(localdata.EnvNameHome, filepath.Join(dir, "home"))
+	os.Setenv(localdata.EnvNameComponentDataDir, filepath.Join(dir, "data"))
+	defer os.Unsetenv(localdata.EnvNameHome)
+	defer os.Unsetenv(localdata.EnvNameComponentDataDir)
+	// the initial root manifest of the mirror is required to initialize the environment
+	root, err := ioutil.ReadFile("../../tests/tiup/bin/root.json")
+	require.Nil(t, err)
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "home", "bin"), 0755))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "home", "bin", "root.json"), root, 0644))
+
+	_, err = New(Config{Platform: "linux"})
+	assert.NotNil(t, err)
+	c, err := New(Config{Platform: "linux/arm64"})
+	require.Nil(t, err)
+	defer c.Close()
+	assert.Equal(t, int64(defaultSSHTimeout), c.cfg.SSHTimeout)
+	assert.Equal(t, int64(defaultOptTimeout), c.cfg.WaitTimeout)
+	assert.True(t, c.ownEnv)
+	// the global environment is left as it is
+	assert.Nil(t, environment.GlobalEnv())
+
+	// the environment given is used and not closed by the client, the profile
+	// is initialized once and shared by the clients
+	sm := spec.GetSpecManager()
+	other, err := New(Config{Env: c.env})
+	require.Nil(t, err)
+	assert.True(t, sm == spec.GetSpecManager())
+	assert.Equal(t, c.env, other.env)
+	assert.False(t, other.ownEnv)
+	assert.Nil(t, other.Close())
+
+	for name, meta := range map[string]string{
+		"staging": "user: tidb\ntidb_version: v4.0.0\ntags:\n  env: staging\n",
+		"prod":    "user: tidb\ntidb_version: v4.0.0\nprotected: true\n",
+	} {
+		path := spec.GetSpecManager().Path(name, "meta.yaml")
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.Nil(t, ioutil.WriteFile(path, []byte(meta), 0644))
+	}
+
+	clusters, err := c.ListClusters()
+	require.Nil(t, err)
+	require.Len(t, clusters, 2)
+	assert.Equal(t, "prod", clusters[0].Name)
+	assert.True(t, clusters[0].Protected)
+	assert.Equal(t, "staging", clusters[1].Name)
+	assert.Equal(t, "v4.0.0", clusters[1].Version)
+
+	clusters, err = c.ListClusters("env=staging")
+	require.Nil(t, err)
+	require.Len(t, clusters, 1)
+	assert.Equal(t, map[string]string{"env": "staging"}, clusters[0].Tags)
+	_, err = c.ListClusters("env=a b")
+	assert.NotNil(t, err)
+
+	_, err = c.Status("missing")
+	assert.NotNil(t, err)
+	_, ok := c.Operation("staging")
+	assert.False(t, ok)
+
+	// the operation fails as the cluster doesn't exist, the messages are
+	// printed to the output set
+	var output bytes.Buffer
+	SetOutput(&output)
+	defer SetOutput(os.Stdout)
+	op := c.Start("missing", OperationOptions{})
+	assert.Equal(t, OperationStart, op.Type)
+	assert.NotNil(t, op.Wait())
+	assert.Equal(t, "Starting cluster missing...\n", output.String())
+	assert.NotNil(t, op.Err())
+	_, ok = op.Progress()
+	assert.False(t, ok)
+}
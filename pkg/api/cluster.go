@@ -0,0 +1,190 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"sort"
+
+	"github.com/pingcap/tiup/pkg/cluster"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+)
+
+// ClusterSummary is the summary of a cluster
+type ClusterSummary struct {
+	Name      string            `json:"name"`
+	User      string            `json:"user,omitempty"`
+	Version   string            `json:"version,omitempty"`
+	Protected bool              `json:"protected,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	// Error is why the metadata of the cluster can't be loaded, the other
+	// fields except the name are empty if it's set
+	Error string `json:"error,omitempty"`
+}
+
+// ListClusters returns the summaries of the clusters sorted by names, only
+// the clusters having all the tags are returned if tags are given. The tags
+// are in the form of key=value, or key to match any value.
+func (c *Client) ListClusters(tags ...string) ([]ClusterSummary, error) {
+	filters, err := cluster.ParseTagFilters(tags)
+	if err != nil {
+		return nil, err
+	}
+	summaries, err := c.manager.ClusterSummaries(filters...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ClusterSummary, 0, len(summaries))
+	for _, s := range summaries {
+		result = append(result, ClusterSummary{
+			Name:      s.Name,
+			User:      s.User,
+			Version:   s.Version,
+			Protected: s.Protected,
+			Tags:      s.Tags,
+			Error:     s.Error,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// InstanceStatus is the status of an instance of a cluster
+type InstanceStatus struct {
+	ID    string `json:"id"`
+	Role  string `json:"role"`
+	Host  string `json:"host"`
+	Ports []int  `json:"ports"`
+	OS    string `json:"os"`
+	Arch  string `json:"arch"`
+	// Status is what `tiup cluster display` shows, e.g., Up, Down or Healthy|L
+	Status string `json:"status"`
+	// Reason is why the instance is unhealthy, empty if it's not known
+	Reason    string `json:"reason,omitempty"`
+	DataDir   string `json:"data_dir,omitempty"`
+	DeployDir string `json:"deploy_dir"`
+	// LastError is the failure of the last operation on an unhealthy instance
+	LastError *cluster.InstanceError `json:"last_error,omitempty"`
+	// Resources is the resource usage of the instance, only set if it's
+	// requested by StatusOptions, the values not collected are null
+	Resources *cluster.InstanceResources `json:"resources,omitempty"`
+}
+
+// ClusterStatus is the status of a cluster and its instances
+type ClusterStatus struct {
+	ClusterSummary
+	Instances []InstanceStatus `json:"instances"`
+}
+
+// StatusOptions are the options of probing the status of a cluster
+type StatusOptions struct {
+	// Resources gathers the resource usage of the instances
+	Resources bool
+	// ResourcesTimeout is the timeout in seconds to gather the resource usage
+	// of the instances on a host, 10 if it's 0
+	ResourcesTimeout int64
+}
+
+// Status probes the instances of the cluster and returns their status
+func (c *Client) Status(clusterName string) (*ClusterStatus, error) {
+	return c.StatusWithOptions(clusterName, StatusOptions{})
+}
+
+// StatusWithOptions probes the instances of the cluster and returns their
+// status, with the details requested by opt
+func (c *Client) StatusWithOptions(clusterName string, opt StatusOptions) (*ClusterStatus, error) {
+	operatorOpt := c.operatorOptions(OperationOptions{})
+	operatorOpt.Resources = opt.Resources
+	operatorOpt.ResourcesTimeout = opt.ResourcesTimeout
+	// an error is returned here if the cluster doesn't exist
+	statuses, err := c.manager.InstanceStatuses(clusterName, operatorOpt)
+	if err != nil {
+		return nil, err
+	}
+	summaries, err := c.ListClusters()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ClusterStatus{}
+	for _, s := range summaries {
+		if s.Name == clusterName {
+			status.ClusterSummary = s
+			break
+		}
+	}
+	for _, s := range statuses {
+		dataDir := s.DataDir
+		if dataDir == "-" {
+			dataDir = ""
+		}
+		status.Instances = append(status.Instances, InstanceStatus{
+			ID:        s.ID,
+			Role:      s.Role,
+			Host:      s.Host,
+			Ports:     s.Ports,
+			OS:        s.OS,
+			Arch:      s.Arch,
+			Status:    s.Status,
+			Reason:    s.Reason,
+			DataDir:   dataDir,
+			DeployDir: s.DeployDir,
+			LastError: s.LastError,
+			Resources: s.Resources,
+		})
+	}
+	return status, nil
+}
+
+// OperationOptions are the options of the operations on a cluster
+type OperationOptions struct {
+	// Roles only operates the instances of the roles, empty means all
+	Roles []string
+	// Nodes only operates the instances of the IDs (host:port), empty means all
+	Nodes []string
+}
+
+func (c *Client) operatorOptions(opt OperationOptions) operator.Options {
+	return operator.Options{
+		Roles:      opt.Roles,
+		Nodes:      opt.Nodes,
+		SSHTimeout: c.cfg.SSHTimeout,
+		OptTimeout: c.cfg.WaitTimeout,
+		APITimeout: defaultAPITimeout,
+		NativeSSH:  c.cfg.NativeSSH,
+	}
+}
+
+// Start starts the cluster in background, the returned handle tracks the operation
+func (c *Client) Start(clusterName string, opt OperationOptions) *Operation {
+	return c.run(clusterName, OperationStart, func() error {
+		return c.manager.StartCluster(clusterName, c.operatorOptions(opt))
+	})
+}
+
+// Stop stops the cluster in background, the returned handle tracks the operation
+func (c *Client) Stop(clusterName string, opt OperationOptions) *Operation {
+	return c.run(clusterName, OperationStop, func() error {
+		return c.manager.StopCluster(clusterName, c.operatorOptions(opt))
+	})
+}
+
+// Restart restarts the cluster in background, the returned handle tracks the operation
+func (c *Client) Restart(clusterName string, opt OperationOptions) *Operation {
+	return c.run(clusterName, OperationRestart, func() error {
+		return c.manager.RestartCluster(clusterName, c.operatorOptions(opt))
+	})
+}
@@ -0,0 +1,172 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"sort"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/repository/v0manifest"
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/version"
+)
+
+// Component is a component in the mirror
+type Component struct {
+	Name        string `json:"name"`
+	Owner       string `json:"owner"`
+	Description string `json:"description"`
+	Hidden      bool   `json:"hidden,omitempty"`
+	// Installed are the versions installed on this host
+	Installed []string `json:"installed,omitempty"`
+	// Platforms are the platforms the component is available on, e.g., linux/amd64
+	Platforms []string `json:"platforms"`
+}
+
+// ListComponentsOptions are the options of listing the components
+type ListComponentsOptions struct {
+	// InstalledOnly only lists the components installed on this host
+	InstalledOnly bool
+	// ShowHidden also lists the hidden components, the installed components
+	// are always listed
+	ShowHidden bool
+}
+
+// ListComponents fetches the latest manifests from the mirror and returns
+// the components sorted by names
+func (c *Client) ListComponents(opt ListComponentsOptions) ([]Component, error) {
+	repo := c.env.V1Repository()
+	if repo == nil {
+		return nil, errors.New("listing components requires the v1 repository")
+	}
+	if err := repo.UpdateComponentManifests(); err != nil {
+		return nil, err
+	}
+
+	installed, err := c.env.Profile().InstalledComponents()
+	if err != nil {
+		return nil, err
+	}
+	localComponents := set.NewStringSet(installed...)
+
+	index := v1manifest.Index{}
+	_, exists, err := repo.Local().LoadManifest(&index)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.Errorf("unreachable: index.json not found in manifests directory")
+	}
+
+	result := []Component{}
+	for id, item := range index.ComponentList() {
+		if opt.InstalledOnly && !localComponents.Exist(id) {
+			continue
+		}
+		if item.Hidden && !opt.ShowHidden && !localComponents.Exist(id) {
+			continue
+		}
+
+		item := item
+		manifest, err := repo.Local().LoadComponentManifest(&item, v1manifest.ComponentManifestFilename(id))
+		if err != nil {
+			return nil, err
+		}
+
+		comp := Component{
+			Name:        id,
+			Owner:       item.Owner,
+			Description: manifest.Description,
+			Hidden:      item.Hidden,
+			Platforms:   []string{},
+		}
+		if localComponents.Exist(id) {
+			if comp.Installed, err = c.env.Profile().InstalledVersions(id); err != nil {
+				return nil, err
+			}
+		}
+		for p := range manifest.Platforms {
+			comp.Platforms = append(comp.Platforms, p)
+		}
+		sort.Strings(comp.Platforms)
+		result = append(result, comp)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// ComponentVersion is a version of a component in the mirror
+type ComponentVersion struct {
+	Version   string   `json:"version"`
+	Installed bool     `json:"installed,omitempty"`
+	Released  string   `json:"released"`
+	Platforms []string `json:"platforms"`
+	// ReleaseNotes is the URL or summary of the release notes, it's optional
+	ReleaseNotes string `json:"release_notes,omitempty"`
+}
+
+// ComponentVersions fetches the latest manifest of the component from the
+// mirror and returns its versions sorted by semantic versioning, the nightly
+// version is the last one if there is any
+func (c *Client) ComponentVersions(component string) ([]ComponentVersion, error) {
+	repo := c.env.V1Repository()
+	if repo == nil {
+		return nil, errors.New("listing versions requires the v1 repository")
+	}
+	manifest, err := repo.FetchComponentManifest(component, false)
+	if err != nil {
+		return nil, errors.Annotate(err, "failed to fetch component")
+	}
+	installed, err := c.env.Profile().InstalledVersions(component)
+	if err != nil {
+		return nil, err
+	}
+	installedSet := set.NewStringSet(installed...)
+
+	versions := make(map[string]*ComponentVersion)
+	for plat := range manifest.Platforms {
+		for ver, item := range manifest.VersionList(plat) {
+			if v0manifest.Version(ver).IsNightly() {
+				if ver != manifest.Nightly {
+					continue
+				}
+				ver = version.NightlyVersion
+			}
+			v, ok := versions[ver]
+			if !ok {
+				v = &ComponentVersion{
+					Version:      ver,
+					Installed:    installedSet.Exist(ver),
+					Released:     item.Released,
+					ReleaseNotes: item.ReleaseNotes,
+				}
+				versions[ver] = v
+			}
+			v.Platforms = append(v.Platforms, plat)
+		}
+	}
+
+	result := make([]ComponentVersion, 0, len(versions))
+	for _, v := range versions {
+		sort.Strings(v.Platforms)
+		result = append(result, *v)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return version.Compare(result[i].Version, result[j].Version) < 0
+	})
+	return result, nil
+}
@@ -0,0 +1,23 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package api is the stable Go API for programs embedding TiUP, e.g., UIs
+// managing clusters. It's a small facade over the cluster manager and the
+// component repository, all the types exposed are plain data structs defined
+// here, so the internal packages may change freely without breaking the
+// programs compiled against this package.
+//
+// The API follows semantic versioning: within a major version, exported
+// identifiers are never removed or changed incompatibly, new fields and
+// methods may be added. Fields of the structs should be set by names.
+package api
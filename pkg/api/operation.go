@@ -0,0 +1,156 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster"
+)
+
+// OperationType is the type of an operation on a cluster, the operations not
+// started by the Client have the types like "deploy" and "scale-out"
+type OperationType string
+
+// The types of the operations started by the Client
+const (
+	OperationStart   OperationType = "start"
+	OperationStop    OperationType = "stop"
+	OperationRestart OperationType = "restart"
+)
+
+// Progress is the progress of an operation on a cluster
+type Progress struct {
+	Cluster       string        `json:"cluster"`
+	Operation     OperationType `json:"operation"`
+	Finished      bool          `json:"finished"`
+	Error         string        `json:"error,omitempty"`
+	StartTime     time.Time     `json:"start_time"`
+	Elapsed       time.Duration `json:"elapsed"`
+	TasksBegun    int           `json:"tasks_begun"`
+	TasksFinished int           `json:"tasks_finished"`
+	TasksFailed   int           `json:"tasks_failed"`
+	Percent       int           `json:"percent"`
+	// CurrentTask is the description of the task running now
+	CurrentTask string `json:"current_task,omitempty"`
+	// Estimated is the estimated duration of the operation, and Remaining is
+	// the time left updated by the progress, they're 0 if there is no
+	// history of the operation to estimate from
+	Estimated time.Duration `json:"estimated,omitempty"`
+	Remaining time.Duration `json:"remaining,omitempty"`
+	// FailedSteps are the steps failed so far, with all the errors of each
+	FailedSteps []StepResult `json:"failed_steps,omitempty"`
+}
+
+// StepResult is a step failed in an operation
+type StepResult struct {
+	Step string `json:"step"`
+	// Status is "Error", or "Skipped" if the step is skipped on a degraded host
+	Status string   `json:"status"`
+	Errors []string `json:"errors"`
+}
+
+// Operation is the handle of an operation running in background
+type Operation struct {
+	Cluster string
+	Type    OperationType
+
+	startTime time.Time
+	done      chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+func (c *Client) run(clusterName string, typ OperationType, fn func() error) *Operation {
+	op := &Operation{
+		Cluster:   clusterName,
+		Type:      typ,
+		startTime: time.Now(),
+		done:      make(chan struct{}),
+	}
+	go func() {
+		err := fn()
+		op.mu.Lock()
+		op.err = err
+		op.mu.Unlock()
+		close(op.done)
+	}()
+	return op
+}
+
+// Done returns a channel closed when the operation is finished
+func (op *Operation) Done() <-chan struct{} {
+	return op.done
+}
+
+// Wait waits for the operation to finish and returns its error
+func (op *Operation) Wait() error {
+	<-op.done
+	return op.Err()
+}
+
+// Err returns the error of the operation, nil if it succeeded or is running
+func (op *Operation) Err() error {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	return op.err
+}
+
+// Progress returns the progress of the operation, false is returned if
+// the operation hasn't begun, e.g., it's waiting for another operation on
+// the cluster or it failed in the pre-checks
+func (op *Operation) Progress() (Progress, bool) {
+	p, ok := currentProgress(op.Cluster)
+	if !ok || p.Operation != op.Type || p.StartTime.Before(op.startTime) {
+		return Progress{}, false
+	}
+	return p, true
+}
+
+// Operation returns the progress of the latest operation on the cluster run
+// by this process, including the ones not started by the Client, false is
+// returned if there is none
+func (c *Client) Operation(clusterName string) (Progress, bool) {
+	return currentProgress(clusterName)
+}
+
+func currentProgress(clusterName string) (Progress, bool) {
+	info := cluster.GetCurrentOperation(clusterName)
+	if info == nil {
+		return Progress{}, false
+	}
+	p := info.ComputeProgress()
+	var steps []StepResult
+	for _, r := range p.FailedSteps {
+		steps = append(steps, StepResult{Step: r.Step, Status: r.Status, Errors: r.Errors})
+	}
+	return Progress{
+		Cluster:       p.Cluster,
+		Operation:     OperationType(p.Operation.String()),
+		Finished:      p.Finished,
+		Error:         p.Error,
+		StartTime:     p.StartTime,
+		Elapsed:       time.Duration(p.ElapsedSecs * float64(time.Second)),
+		TasksBegun:    p.TasksBegun,
+		TasksFinished: p.TasksFinished,
+		TasksFailed:   p.TasksFailed,
+		Percent:       p.Percent,
+		CurrentTask:   p.CurTask.Task,
+		Estimated:     time.Duration(p.EstimatedSecs * float64(time.Second)),
+		Remaining:     time.Duration(p.RemainingSecs * float64(time.Second)),
+		FailedSteps:   steps,
+	}, true
+}
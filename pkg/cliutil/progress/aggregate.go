@@ -0,0 +1,159 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	// a running item is slow if it takes longer than slowFactor times the
+	// median duration of the finished items of its group
+	slowFactor = 2
+	// the items finishing in it are never slow
+	slowMinDuration = 10 * time.Second
+	// the slowness is only judged after so many items of the group finished
+	slowMinSamples = 3
+	// the label of the items without a group
+	defaultGroup = "Steps"
+)
+
+// groupCounter counts the items of a group
+type groupCounter struct {
+	name      string
+	total     int
+	done      int
+	failed    int
+	durations []time.Duration // of the finished items
+}
+
+func (c *groupCounter) String() string {
+	s := fmt.Sprintf("  - %s: %d/%d done", c.name, c.done, c.total)
+	if c.failed > 0 {
+		s += fmt.Sprintf(", %s", colorError.Sprintf("%d failed", c.failed))
+	}
+	return s
+}
+
+// slowAfter returns the duration a running item of the group is slow after,
+// 0 if it's unknown yet
+func (c *groupCounter) slowAfter() time.Duration {
+	if len(c.durations) < slowMinSamples {
+		return 0
+	}
+	ds := append([]time.Duration{}, c.durations...)
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+	after := ds[len(ds)/2] * slowFactor
+	if after < slowMinDuration {
+		after = slowMinDuration
+	}
+	return after
+}
+
+// aggregatedLines returns the lines rendered in the aggregated mode, they are
+// the prefix, the counters of the groups in the order they are added, then
+// the failed and slow items
+func (b *MultiBar) aggregatedLines(now time.Time) []string {
+	var groups []*groupCounter
+	byName := make(map[string]*groupCounter)
+	for _, bar := range b.bars {
+		name := bar.group
+		if name == "" {
+			name = defaultGroup
+		}
+		g, ok := byName[name]
+		if !ok {
+			g = &groupCounter{name: name}
+			byName[name] = g
+			groups = append(groups, g)
+		}
+		g.total++
+		dp := bar.core.displayProps.Load().(*DisplayProps)
+		switch dp.Mode {
+		case ModeDone:
+			g.done++
+		case ModeError:
+			g.failed++
+		}
+		if started, finished := bar.started.Load(), bar.finished.Load(); started > 0 && finished > 0 {
+			g.durations = append(g.durations, time.Duration(finished-started))
+		}
+	}
+
+	lines := []string{b.prefix}
+	for _, g := range groups {
+		lines = append(lines, g.String())
+	}
+
+	// expand the failed and slow items
+	for _, bar := range b.bars {
+		dp := bar.core.displayProps.Load().(*DisplayProps)
+		expand := dp.Mode == ModeError
+		if !expand && bar.finished.Load() == 0 && bar.started.Load() > 0 {
+			name := bar.group
+			if name == "" {
+				name = defaultGroup
+			}
+			after := byName[name].slowAfter()
+			expand = after > 0 && now.Sub(time.Unix(0, bar.started.Load())) > after
+		}
+		if !expand {
+			continue
+		}
+		var buf bytes.Buffer
+		bar.core.renderTo(&buf)
+		lines = append(lines, buf.String())
+	}
+	return lines
+}
+
+// renderAggregated renders the aggregated lines, the space reserved grows
+// with the lines expanded
+func (b *MultiBar) renderAggregated() {
+	lines := b.aggregatedLines(time.Now())
+	if height := int(termSizeHeight.Load()) - 1; height > 0 && len(lines) > height {
+		lines = lines[:height]
+	}
+
+	f := bufio.NewWriter(Output())
+	if n := len(lines) - b.reserved; n > 0 {
+		for i := 0; i < n; i++ {
+			_, _ = fmt.Fprintln(f)
+		}
+		b.reserved = len(lines)
+	}
+
+	width := int(termSizeWidth.Load())
+	moveCursorUp(f, b.reserved)
+	for i := 0; i < b.reserved; i++ {
+		moveCursorToLineStart(f)
+		clearLine(f)
+		if i < len(lines) {
+			line := lines[i]
+			if width > 0 {
+				line = runewidth.Truncate(line, width, "...")
+			}
+			_, _ = fmt.Fprint(f, line)
+		}
+		moveCursorDown(f, 1)
+	}
+	moveCursorToLineStart(f)
+	_ = f.Flush()
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregatedLines(t *testing.T) {
+	b := NewMultiBar("+ Deploy")
+	b.SetAggregateAbove(4)
+	var tikv []*MultiBarItem
+	for _, p := range []string{"a", "b", "c", "d", "e"} {
+		tikv = append(tikv, b.AddGroupedBar("  - Copy tikv -> "+p, "tikv"))
+	}
+	pd := b.AddGroupedBar("  - Copy pd -> a", "pd")
+	other := b.AddBar("  - Other")
+	assert.True(t, b.Aggregated())
+
+	now := time.Now()
+	assert.Equal(t, []string{
+		"+ Deploy",
+		"  - tikv: 0/5 done",
+		"  - pd: 0/1 done",
+		"  - Steps: 0/1 done",
+	}, b.aggregatedLines(now))
+
+	// 3 tikv steps finished in 1 second, the 4th fails and the 5th is slow
+	start := now.Add(-time.Minute).UnixNano()
+	for _, item := range tikv {
+		dp := *item.core.displayProps.Load().(*DisplayProps)
+		dp.Mode = ModeSpinner
+		item.UpdateDisplay(&dp)
+		item.started.Store(start)
+	}
+	for _, item := range tikv[:3] {
+		item.UpdateDisplay(&DisplayProps{Mode: ModeDone})
+		item.finished.Store(start + int64(time.Second))
+	}
+	tikv[3].UpdateDisplay(&DisplayProps{Prefix: "  - Copy tikv -> d", Mode: ModeError})
+	pd.UpdateDisplay(&DisplayProps{Prefix: "  - Copy pd -> a", Mode: ModeSpinner})
+	other.UpdateDisplay(&DisplayProps{Mode: ModeDone})
+
+	lines := b.aggregatedLines(now)
+	assert.Len(t, lines, 6)
+	assert.Equal(t, "  - tikv: 3/5 done, ", lines[1][:len("  - tikv: 3/5 done, ")])
+	assert.Contains(t, lines[1], "1 failed")
+	assert.Equal(t, "  - pd: 0/1 done", lines[2])
+	assert.Equal(t, "  - Steps: 1/1 done", lines[3])
+	assert.Contains(t, lines[4], "Copy tikv -> d")
+	assert.Contains(t, lines[5], "Copy tikv -> e")
+
+	// not aggregated below the threshold
+	b.SetAggregateAbove(0)
+	assert.False(t, b.Aggregated())
+}
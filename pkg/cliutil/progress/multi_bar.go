@@ -16,21 +16,44 @@ package progress
 import (
 	"bufio"
 	"fmt"
-	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/mattn/go-runewidth"
+	"go.uber.org/atomic"
+)
+
+// SortOrder is the order in which the bar items of a MultiBar are rendered
+type SortOrder int
+
+// Orders of rendering bar items
+const (
+	// SortByAdded renders bar items in the order they are added
+	SortByAdded SortOrder = iota
+	// SortByActivity renders the most recently updated bar items first
+	SortByActivity
 )
 
 // MultiBarItem controls a bar item inside MultiBar.
 type MultiBarItem struct {
-	core singleBarCore
+	core       singleBarCore
+	group      string       // the items are counted by groups in the aggregated mode
+	lastActive atomic.Int64 // unix nano of the last update
+	started    atomic.Int64 // unix nano of the first update
+	finished   atomic.Int64 // unix nano of being done or failed
 }
 
 // UpdateDisplay updates the display property of this bar item.
 // This function is thread safe.
 func (i *MultiBarItem) UpdateDisplay(newDisplay *DisplayProps) {
 	i.core.displayProps.Store(newDisplay)
+	now := time.Now().UnixNano()
+	i.lastActive.Store(now)
+	i.started.CAS(0, now)
+	if newDisplay.Mode == ModeDone || newDisplay.Mode == ModeError {
+		i.finished.CAS(0, now)
+	}
 }
 
 // MultiBar renders multiple progress bars.
@@ -38,7 +61,13 @@ type MultiBar struct {
 	prefix string
 
 	bars     []*MultiBarItem
+	order    SortOrder
 	renderer *renderer
+
+	// the items are aggregated by groups if there are more than it, 0 never
+	aggregateAbove int
+	// the lines reserved for rendering in the aggregated mode
+	reserved int
 }
 
 // NewMultiBar creates a new MultiBar.
@@ -55,13 +84,54 @@ func NewMultiBar(prefix string) *MultiBar {
 // AddBar adds a new bar item.
 // This function is not thread safe. Must be called before render loop is started.
 func (b *MultiBar) AddBar(prefix string) *MultiBarItem {
+	return b.AddGroupedBar(prefix, "")
+}
+
+// AddGroupedBar adds a new bar item counted in the group in the aggregated
+// mode, see SetAggregateAbove.
+// This function is not thread safe. Must be called before render loop is started.
+func (b *MultiBar) AddGroupedBar(prefix, group string) *MultiBarItem {
 	i := &MultiBarItem{
-		core: newSingleBarCore(prefix),
+		core:  newSingleBarCore(prefix),
+		group: group,
 	}
 	b.bars = append(b.bars, i)
 	return i
 }
 
+// SetAggregateAbove sets the number of bar items above which they are
+// aggregated by groups, 0 never aggregates them. In the aggregated mode the
+// bar items are rendered as the counters of the groups, only the failed and
+// slow ones are rendered on their own.
+// This function is not thread safe. Must be called before render loop is started.
+func (b *MultiBar) SetAggregateAbove(n int) {
+	b.aggregateAbove = n
+}
+
+// Aggregated is true if the bar items are aggregated by groups
+func (b *MultiBar) Aggregated() bool {
+	return b.aggregateAbove > 0 && len(b.bars) > b.aggregateAbove
+}
+
+// SetSortOrder sets the order in which the bar items are rendered.
+// This function is not thread safe. Must be called before render loop is started.
+func (b *MultiBar) SetSortOrder(order SortOrder) {
+	b.order = order
+}
+
+// sortedBars returns the bar items in the order to be rendered
+func (b *MultiBar) sortedBars() []*MultiBarItem {
+	if b.order != SortByActivity {
+		return b.bars
+	}
+	bars := make([]*MultiBarItem, len(b.bars))
+	copy(bars, b.bars)
+	sort.SliceStable(bars, func(i, j int) bool {
+		return bars[i].lastActive.Load() > bars[j].lastActive.Load()
+	})
+	return bars
+}
+
 // StartRenderLoop starts the render loop.
 // This function is thread safe.
 func (b *MultiBar) StartRenderLoop() {
@@ -76,21 +146,32 @@ func (b *MultiBar) StopRenderLoop() {
 }
 
 func (b *MultiBar) preRender() {
+	if b.Aggregated() {
+		b.reserved = len(b.aggregatedLines(time.Now()))
+		fmt.Fprint(Output(), strings.Repeat("\n", b.reserved))
+		return
+	}
 	// Preserve space for the bar
-	fmt.Print(strings.Repeat("\n", len(b.bars)+1))
+	fmt.Fprint(Output(), strings.Repeat("\n", len(b.bars)+1))
 }
 
 func (b *MultiBar) render() {
-	f := bufio.NewWriter(os.Stdout)
+	if b.Aggregated() {
+		b.renderAggregated()
+		return
+	}
+
+	f := bufio.NewWriter(Output())
 
 	y := int(termSizeHeight.Load()) - 1
 	movedY := 0
-	for i := len(b.bars) - 1; i >= 0; i-- {
+	bars := b.sortedBars()
+	for i := len(bars) - 1; i >= 0; i-- {
 		moveCursorUp(f, 1)
 		y--
 		movedY++
 
-		bar := b.bars[i]
+		bar := bars[i]
 		moveCursorToLineStart(f)
 		clearLine(f)
 		bar.core.renderTo(f)
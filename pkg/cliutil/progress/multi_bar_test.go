@@ -0,0 +1,40 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortByActivity(t *testing.T) {
+	b := NewMultiBar("+ Deploy")
+	a1 := b.AddBar("  - a")
+	a2 := b.AddBar("  - b")
+	a3 := b.AddBar("  - c")
+	// the bars are rendered in the order they are added by default
+	assert.Equal(t, []*MultiBarItem{a1, a2, a3}, b.sortedBars())
+	a3.lastActive.Store(100)
+	a1.lastActive.Store(200)
+	assert.Equal(t, []*MultiBarItem{a1, a2, a3}, b.sortedBars())
+
+	// the most recently updated ones go first, the ones never updated are
+	// kept in the order they are added
+	b.SetSortOrder(SortByActivity)
+	assert.Equal(t, []*MultiBarItem{a1, a3, a2}, b.sortedBars())
+	a2.UpdateDisplay(&DisplayProps{Prefix: "  - b", Mode: ModeProgress})
+	assert.Equal(t, []*MultiBarItem{a2, a1, a3}, b.sortedBars())
+	assert.Equal(t, []*MultiBarItem{a1, a2, a3}, b.bars)
+}
@@ -15,7 +15,9 @@ package progress
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/fatih/color"
@@ -33,6 +35,28 @@ var (
 
 var refreshRate = time.Millisecond * 50
 
+// output is the writer the bars are rendered to, stdout by default
+var output atomic.Value
+
+func init() {
+	output.Store(writer{os.Stdout})
+}
+
+// writer wraps the writers of different types to be stored in output
+type writer struct {
+	io.Writer
+}
+
+// SetOutput sets the writer the bars are rendered to
+func SetOutput(w io.Writer) {
+	output.Store(writer{w})
+}
+
+// Output returns the writer the bars are rendered to
+func Output() io.Writer {
+	return output.Load().(writer).Writer
+}
+
 const (
 	doneTail  = "Done"
 	errorTail = "Error"
@@ -30,17 +30,23 @@ var (
 	systemdUnitPath = "/etc/systemd/system"
 )
 
-// parseDirs sets values of directories of component
-func parseDirs(user string, ins spec.InstanceSpec, sshTimeout int64, nativeClient bool) (spec.InstanceSpec, error) {
-	hostName, sshPort := ins.SSH()
-
-	e := executor.NewSSHExecutor(executor.SSHConfig{
-		Host:    hostName,
-		Port:    sshPort,
+// newDirsExecutor returns the executor to read the start scripts of the
+// instances on the host
+var newDirsExecutor = func(user, host string, port int, sshTimeout int64, nativeClient bool) executor.Executor {
+	return executor.NewSSHExecutor(executor.SSHConfig{
+		Host:    host,
+		Port:    port,
 		User:    user,
 		KeyFile: SSHKeyPath(), // ansible generated keyfile
 		Timeout: time.Second * time.Duration(sshTimeout),
 	}, false /* not using global sudo */, nativeClient)
+}
+
+// parseDirs sets values of directories of component
+func parseDirs(user string, ins spec.InstanceSpec, sshTimeout int64, nativeClient bool) (spec.InstanceSpec, error) {
+	hostName, sshPort := ins.SSH()
+
+	e := newDirsExecutor(user, hostName, sshPort, sshTimeout, nativeClient)
 	log.Debugf("Detecting deploy paths on %s...", hostName)
 
 	stdout, err := readStartScript(e, ins.Role(), hostName, ins.GetMainPort())
@@ -19,10 +19,12 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/creasty/defaults"
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"gopkg.in/yaml.v2"
 )
@@ -132,3 +134,90 @@ func sortClusterMeta(clsMeta *spec.ClusterMeta) {
 		return clsMeta.Topology.Alertmanager[i].Host < clsMeta.Topology.Alertmanager[j].Host
 	})
 }
+
+func (s *ansSuite) TestUnrecognizedVars(c *C) {
+	dir := "test-data"
+	invData, err := os.Open(filepath.Join(dir, "inventory.ini"))
+	c.Assert(err, IsNil)
+	_, _, inv, err := parseInventoryFile(invData)
+	c.Assert(err, IsNil)
+
+	vars, err := unrecognizedVars(dir, inv)
+	c.Assert(err, IsNil)
+	for _, v := range vars {
+		c.Assert(knownVars.Exist(v.Name), IsFalse)
+		c.Assert(v.Value, Not(Matches), ".*\\{\\{.*")
+	}
+}
+
+func (s *ansSuite) TestPreview(c *C) {
+	// the start script of tikv on 172.16.1.219, the ones of the others are
+	// not found
+	hosts := make(map[string]*executor.Fake)
+	defer func(fn func(string, string, int, int64, bool) executor.Executor) { newDirsExecutor = fn }(newDirsExecutor)
+	newDirsExecutor = func(user, host string, port int, sshTimeout int64, nativeClient bool) executor.Executor {
+		if _, ok := hosts[host]; !ok {
+			hosts[host] = executor.NewFake(host)
+		}
+		return hosts[host]
+	}
+	hosts["172.16.1.219"] = executor.NewFake("172.16.1.219")
+	hosts["172.16.1.219"].Respond("cat `grep 'ExecStart' /etc/systemd/system/tikv-20160.service", strings.Join([]string{
+		`#!/bin/bash`,
+		`cd "/home/tiops/deploy" || exit 1`,
+		`exec bin/tikv-server \`,
+		`    --addr "0.0.0.0:20160" \`,
+		`    --data-dir "/data/tikv" \`,
+		`    --log-file "/home/tiops/deploy/log/tikv.log" 2>> "/home/tiops/deploy/log/tikv_stderr.log"`,
+	}, "\n"), "")
+
+	dir := "test-data"
+	_, clsMeta, inv, err := ReadInventory(dir, "")
+	c.Assert(err, IsNil)
+	report, err := Preview(dir, filepath.Join(dir, "ansible.cfg"), "", clsMeta, inv, 5, false)
+	c.Assert(err, IsNil)
+
+	// the dirs detected are reported with the defaults of the rest
+	var tikv *ImportedInstance
+	for i, inst := range report.Instances {
+		if inst.Role == spec.ComponentTiKV && inst.Host == "172.16.1.219" {
+			tikv = &report.Instances[i]
+		}
+	}
+	c.Assert(tikv, NotNil)
+	c.Assert(*tikv, DeepEquals, ImportedInstance{
+		Role:      spec.ComponentTiKV,
+		Host:      "172.16.1.219",
+		Ports:     []int{20160, 20180},
+		DeployDir: "/home/tiops/deploy",
+		DataDir:   "/data/tikv",
+		LogDir:    "/home/tiops/deploy/log",
+	})
+	c.Assert(report.Instances, HasLen, 16)
+
+	// the required settings not found are blocking instead of being defaulted
+	blocking := strings.Join(report.Blocking, "\n")
+	c.Assert(report.Blocking[0], Equals, "cluster_name is not set in the inventory")
+	c.Assert(strings.Contains(blocking, "the data dir of tikv on 172.16.1.220 is not detected"), IsTrue)
+	c.Assert(strings.Contains(blocking, "the deploy dir of tidb on 172.16.1.218 is not detected"), IsTrue)
+	c.Assert(strings.Contains(blocking, "tikv on 172.16.1.219"), IsFalse)
+	c.Assert(strings.Contains(blocking, "the data dir of tidb"), IsFalse)
+
+	// the variables not mapped are reported with where they are set
+	c.Assert(report.Unrecognized, Not(HasLen), 0)
+	var timezone *UnrecognizedVar
+	for i, v := range report.Unrecognized {
+		if v.Name == "timezone" {
+			timezone = &report.Unrecognized[i]
+		}
+	}
+	c.Assert(timezone, NotNil)
+	c.Assert(*timezone, DeepEquals, UnrecognizedVar{Source: "inventory", Name: "timezone", Value: "Asia/Shanghai"})
+
+	// nothing but the start scripts is read on the hosts
+	for host, e := range hosts {
+		for _, cmd := range e.Commands() {
+			c.Assert(strings.HasPrefix(cmd, "cat `grep 'ExecStart' "), IsTrue, Commentf("%s: %s", host, cmd))
+		}
+	}
+}
@@ -49,6 +49,17 @@ var (
 
 // ParseAndImportInventory builds a basic ClusterMeta from the main Ansible inventory
 func ParseAndImportInventory(dir, ansCfgFile string, clsMeta *spec.ClusterMeta, inv *aini.InventoryData, sshTimeout int64, nativeClient bool) error {
+	if err := parseInventory(dir, ansCfgFile, clsMeta, inv, sshTimeout, nativeClient); err != nil {
+		return err
+	}
+
+	// TODO: get values from templates of roles to overwrite defaults
+	return defaults.Set(clsMeta)
+}
+
+// parseInventory sets the instances of the inventory with their ports and
+// dirs to clsMeta, the values not found are left empty
+func parseInventory(dir, ansCfgFile string, clsMeta *spec.ClusterMeta, inv *aini.InventoryData, sshTimeout int64, nativeClient bool) error {
 	if err := parseGroupVars(dir, ansCfgFile, clsMeta, inv); err != nil {
 		return err
 	}
@@ -126,8 +137,7 @@ func ParseAndImportInventory(dir, ansCfgFile string, clsMeta *spec.ClusterMeta,
 		clsMeta.Topology.Grafana[i] = ins.(spec.GrafanaSpec)
 	}
 
-	// TODO: get values from templates of roles to overwrite defaults
-	return defaults.Set(clsMeta)
+	return nil
 }
 
 func parseGroupVars(dir, ansCfgFile string, clsMeta *spec.ClusterMeta, inv *aini.InventoryData) error {
@@ -0,0 +1,201 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ansible
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/creasty/defaults"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/relex/aini"
+)
+
+// knownVars are the variables of TiDB-Ansible which are mapped to the
+// topology, or not needed to be mapped
+var knownVars = set.NewStringSet(
+	// inventory
+	"ansible_host", "ansible_port", "ansible_user", "cluster_name", "tidb_version",
+	"process_supervision", "enable_binlog", "deploy_dir",
+	// monitoring agents
+	"blackbox_exporter_port", "node_exporter_port",
+	// tidb
+	"tidb_port", "tidb_status_port", "tidb_log_dir",
+	// tikv
+	"tikv_port", "tikv_status_port", "tikv_data_dir", "tikv_log_dir",
+	// pd
+	"pd_client_port", "pd_peer_port", "pd_data_dir", "pd_log_dir",
+	// tiflash
+	"tcp_port", "http_port", "flash_service_port", "flash_proxy_port",
+	"flash_proxy_status_port", "metrics_port", "data_dir", "tiflash_log_dir", "tmp_path",
+	// monitoring
+	"prometheus_port", "prometheus_storage_retention",
+	"alertmanager_port", "alertmanager_cluster_port", "grafana_port",
+	// binlog
+	"pump_port", "pump_data_dir", "pump_log_dir", "drainer_port",
+)
+
+// componentsRequireDataDir are the components whose data dir can't be
+// defaulted, as their data would be lost if the dir is guessed wrong
+var componentsRequireDataDir = set.NewStringSet(
+	spec.ComponentPD,
+	spec.ComponentTiKV,
+	spec.ComponentTiFlash,
+	spec.ComponentPump,
+)
+
+// ImportedInstance is an instance detected from the inventory
+type ImportedInstance struct {
+	Role      string
+	Host      string
+	Ports     []int
+	DeployDir string
+	DataDir   string
+	LogDir    string
+}
+
+// UnrecognizedVar is a variable of TiDB-Ansible which is not mapped to the topology
+type UnrecognizedVar struct {
+	Source string // the inventory or file of group_vars the variable is set in
+	Name   string
+	Value  string
+}
+
+// ImportReport is the report of how a cluster deployed by TiDB-Ansible is
+// mapped to the topology of TiUP
+type ImportReport struct {
+	ClusterName  string
+	Version      string
+	User         string
+	Instances    []ImportedInstance
+	Unrecognized []UnrecognizedVar
+	// the required settings can't be found in the inventory, the import must
+	// not be applied unless they are fixed
+	Blocking []string
+}
+
+// Preview parses the inventory and group_vars like ParseAndImportInventory
+// does, and reports how the cluster is mapped. The directories are detected
+// by reading the start scripts on the hosts, nothing is changed on them.
+// Required settings which can't be found are reported as blocking errors
+// instead of being defaulted, clsMeta is set with defaults otherwise.
+func Preview(dir, ansCfgFile, clsName string, clsMeta *spec.ClusterMeta, inv *aini.InventoryData, sshTimeout int64, nativeClient bool) (*ImportReport, error) {
+	report := &ImportReport{
+		ClusterName: clsName,
+		Version:     clsMeta.Version,
+		User:        clsMeta.User,
+	}
+	if clsName == "" {
+		report.Blocking = append(report.Blocking, "cluster_name is not set in the inventory")
+	}
+	if clsMeta.Version == "" {
+		report.Blocking = append(report.Blocking, "tidb_version is not set in the inventory")
+	}
+	if clsMeta.User == "" {
+		report.Blocking = append(report.Blocking, "ansible_user is not set in the inventory")
+	}
+
+	if err := parseInventory(dir, ansCfgFile, clsMeta, inv, sshTimeout, nativeClient); err != nil {
+		return nil, err
+	}
+
+	topo := clsMeta.Topology
+	if len(topo.PDServers) == 0 {
+		report.Blocking = append(report.Blocking, "no PD server is found in the inventory")
+	}
+	topo.IterInstance(func(inst spec.Instance) {
+		if inst.DeployDir() == "" {
+			report.Blocking = append(report.Blocking,
+				fmt.Sprintf("the deploy dir of %s on %s is not detected", inst.ComponentName(), inst.GetHost()))
+		}
+		if componentsRequireDataDir.Exist(inst.ComponentName()) && inst.DataDir() == "" {
+			report.Blocking = append(report.Blocking,
+				fmt.Sprintf("the data dir of %s on %s is not detected", inst.ComponentName(), inst.GetHost()))
+		}
+	})
+
+	unrecognized, err := unrecognizedVars(dir, inv)
+	if err != nil {
+		return nil, err
+	}
+	report.Unrecognized = unrecognized
+
+	if err := defaults.Set(clsMeta); err != nil {
+		return nil, err
+	}
+	topo.IterInstance(func(inst spec.Instance) {
+		report.Instances = append(report.Instances, ImportedInstance{
+			Role:      inst.Role(),
+			Host:      inst.GetHost(),
+			Ports:     inst.UsedPorts(),
+			DeployDir: inst.DeployDir(),
+			DataDir:   inst.DataDir(),
+			LogDir:    inst.LogDir(),
+		})
+	})
+
+	return report, nil
+}
+
+// unrecognizedVars returns the variables set in the inventory and group_vars
+// which are not mapped. Variables referencing other variables are derived
+// values of TiDB-Ansible and are not reported.
+func unrecognizedVars(dir string, inv *aini.InventoryData) ([]UnrecognizedVar, error) {
+	var result []UnrecognizedVar
+	seen := set.NewStringSet()
+	add := func(source string, vars map[string]string) {
+		for name, value := range vars {
+			if knownVars.Exist(name) || seen.Exist(name) || strings.Contains(value, "{{") {
+				continue
+			}
+			seen.Insert(name)
+			result = append(result, UnrecognizedVar{Source: source, Name: name, Value: value})
+		}
+	}
+
+	for _, host := range inv.Hosts {
+		add("inventory", host.Vars)
+	}
+	for _, file := range []string{
+		groupVarsGlobal,
+		groupVarsTiDB,
+		groupVarsTiKV,
+		groupVarsPD,
+		groupVarsTiFlash,
+		groupVarsAlertManager,
+		groupVarsGrafana,
+		groupVarsPrometheus,
+	} {
+		if _, err := os.Stat(filepath.Join(dir, file)); os.IsNotExist(err) {
+			continue
+		}
+		vars, err := readGroupVars(dir, file)
+		if err != nil {
+			return nil, err
+		}
+		add(file, vars)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Source != result[j].Source {
+			return result[i].Source < result[j].Source
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
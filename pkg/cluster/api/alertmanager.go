@@ -0,0 +1,110 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pingcap/errors"
+)
+
+// AlertmanagerClient is an HTTP client of the v2 API of Alertmanager
+type AlertmanagerClient struct {
+	addr       string
+	httpClient *http.Client
+}
+
+// NewAlertmanagerClient returns a new AlertmanagerClient
+func NewAlertmanagerClient(addr string, timeout time.Duration) *AlertmanagerClient {
+	return &AlertmanagerClient{
+		addr:       addr,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// SilenceMatcher matches the alerts with the label
+type SilenceMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+}
+
+// Silence is a silence of Alertmanager
+type Silence struct {
+	Matchers  []SilenceMatcher `json:"matchers"`
+	StartsAt  time.Time        `json:"startsAt"`
+	EndsAt    time.Time        `json:"endsAt"`
+	CreatedBy string           `json:"createdBy"`
+	Comment   string           `json:"comment"`
+}
+
+var (
+	alertmanagerSilencesURI = "api/v2/silences"
+	alertmanagerSilenceURI  = "api/v2/silence"
+)
+
+func (ac *AlertmanagerClient) do(method, uri string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return errors.AddStack(err)
+		}
+		body = data
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("http://%s/%s", ac.addr, uri), bytes.NewReader(body))
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := ac.httpClient.Do(req)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("alertmanager %s responded %d: %s", ac.addr, resp.StatusCode, bytes.TrimSpace(data))
+	}
+	if out == nil {
+		return nil
+	}
+	return errors.AddStack(json.Unmarshal(data, out))
+}
+
+// CreateSilence creates the silence, the ID of the silence is returned
+func (ac *AlertmanagerClient) CreateSilence(s Silence) (string, error) {
+	resp := struct {
+		SilenceID string `json:"silenceID"`
+	}{}
+	if err := ac.do(http.MethodPost, alertmanagerSilencesURI, s, &resp); err != nil {
+		return "", err
+	}
+	return resp.SilenceID, nil
+}
+
+// ExpireSilence expires the silence of the ID
+func (ac *AlertmanagerClient) ExpireSilence(id string) error {
+	return ac.do(http.MethodDelete, alertmanagerSilenceURI+"/"+url.PathEscape(id), nil, nil)
+}
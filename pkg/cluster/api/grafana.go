@@ -0,0 +1,192 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pingcap/errors"
+)
+
+// GrafanaClient is an HTTP client of the Grafana server
+type GrafanaClient struct {
+	addr       string
+	user       string
+	password   string
+	httpClient *http.Client
+}
+
+// NewGrafanaClient returns a new GrafanaClient authenticated with basic auth
+func NewGrafanaClient(addr, user, password string, timeout time.Duration) *GrafanaClient {
+	return &GrafanaClient{
+		addr:       addr,
+		user:       user,
+		password:   password,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// GrafanaError is the error response of the Grafana server
+type GrafanaError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements the error interface
+func (e *GrafanaError) Error() string {
+	return fmt.Sprintf("grafana responded %d: %s", e.StatusCode, e.Message)
+}
+
+// IsGrafanaStatus checks if err is a response of the Grafana server with the status code
+func IsGrafanaStatus(err error, code int) bool {
+	e, ok := errors.Cause(err).(*GrafanaError)
+	return ok && e.StatusCode == code
+}
+
+// GrafanaDatasource is a datasource of Grafana
+type GrafanaDatasource struct {
+	ID        int64  `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	URL       string `json:"url"`
+	Access    string `json:"access"`
+	IsDefault bool   `json:"isDefault"`
+}
+
+// GrafanaDashboardMeta is the metadata of a dashboard saved in Grafana
+type GrafanaDashboardMeta struct {
+	ID      int64  `json:"id"`
+	UID     string `json:"uid"`
+	Version int    `json:"version"`
+}
+
+var (
+	grafanaHealthURI     = "api/health"
+	grafanaFoldersURI    = "api/folders"
+	grafanaDatasourceURI = "api/datasources"
+	grafanaDashboardURI  = "api/dashboards"
+)
+
+func (gc *GrafanaClient) do(method, uri string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return errors.AddStack(err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("http://%s/%s", gc.addr, uri), body)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	req.SetBasicAuth(gc.user, gc.password)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := gc.httpClient.Do(req)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := struct {
+			Message string `json:"message"`
+		}{}
+		if json.Unmarshal(data, &msg) != nil || msg.Message == "" {
+			msg.Message = string(data)
+		}
+		return &GrafanaError{StatusCode: resp.StatusCode, Message: msg.Message}
+	}
+	if out == nil {
+		return nil
+	}
+	return errors.AddStack(json.Unmarshal(data, out))
+}
+
+// CheckHealth checks if the Grafana server is ready to serve the API
+func (gc *GrafanaClient) CheckHealth() error {
+	return gc.do(http.MethodGet, grafanaHealthURI, nil, nil)
+}
+
+// EnsureFolder creates the folder if it doesn't exist, the ID of the folder is returned
+func (gc *GrafanaClient) EnsureFolder(uid, title string) (int64, error) {
+	folder := struct {
+		ID int64 `json:"id"`
+	}{}
+	err := gc.do(http.MethodGet, grafanaFoldersURI+"/"+url.PathEscape(uid), nil, &folder)
+	if IsGrafanaStatus(err, http.StatusNotFound) {
+		err = gc.do(http.MethodPost, grafanaFoldersURI, map[string]string{"uid": uid, "title": title}, &folder)
+	}
+	return folder.ID, err
+}
+
+// UpsertDatasource creates the datasource, or updates the one of the same
+// name, whether it's created is returned
+func (gc *GrafanaClient) UpsertDatasource(ds GrafanaDatasource) (bool, error) {
+	var existing GrafanaDatasource
+	err := gc.do(http.MethodGet, grafanaDatasourceURI+"/name/"+url.PathEscape(ds.Name), nil, &existing)
+	switch {
+	case IsGrafanaStatus(err, http.StatusNotFound):
+		ds.ID = 0
+		return true, gc.do(http.MethodPost, grafanaDatasourceURI, ds, nil)
+	case err != nil:
+		return false, err
+	}
+	ds.ID = existing.ID
+	return false, gc.do(http.MethodPut, fmt.Sprintf("%s/%d", grafanaDatasourceURI, existing.ID), ds, nil)
+}
+
+// GetDashboard returns the metadata of the dashboard, nil if it doesn't exist
+func (gc *GrafanaClient) GetDashboard(uid string) (*GrafanaDashboardMeta, error) {
+	resp := struct {
+		Dashboard GrafanaDashboardMeta `json:"dashboard"`
+	}{}
+	err := gc.do(http.MethodGet, grafanaDashboardURI+"/uid/"+url.PathEscape(uid), nil, &resp)
+	if IsGrafanaStatus(err, http.StatusNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Dashboard, nil
+}
+
+// SaveDashboard saves the dashboard model in the folder. Unless overwrite is
+// set, Grafana rejects the dashboard with 412 if the version in the model
+// doesn't match the saved one. The metadata of the saved dashboard is returned.
+func (gc *GrafanaClient) SaveDashboard(dashboard map[string]interface{}, folderID int64, overwrite bool) (*GrafanaDashboardMeta, error) {
+	req := map[string]interface{}{
+		"dashboard": dashboard,
+		"folderId":  folderID,
+		"overwrite": overwrite,
+		"message":   "Provisioned by TiUP",
+	}
+	var saved GrafanaDashboardMeta
+	if err := gc.do(http.MethodPost, grafanaDashboardURI+"/db", req, &saved); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
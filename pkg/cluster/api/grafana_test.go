@@ -0,0 +1,139 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// grafanaRequest is a request received by the test server
+type grafanaRequest struct {
+	method string
+	path   string
+	body   map[string]interface{}
+}
+
+// newGrafanaServer starts a server answering the requests by handle, which
+// are recorded after being authenticated
+func newGrafanaServer(t *testing.T, handle func(r *grafanaRequest) (int, interface{})) (*httptest.Server, *[]grafanaRequest) {
+	var requests []grafanaRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message":"invalid username or password"}`))
+			return
+		}
+		req := grafanaRequest{method: r.Method, path: r.URL.EscapedPath()}
+		if r.ContentLength > 0 {
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&req.body))
+		}
+		requests = append(requests, req)
+		code, resp := handle(&req)
+		w.WriteHeader(code)
+		if resp != nil {
+			assert.Nil(t, json.NewEncoder(w).Encode(resp))
+		}
+	}))
+	return srv, &requests
+}
+
+func newTestGrafanaClient(srv *httptest.Server, password string) *GrafanaClient {
+	return NewGrafanaClient(strings.TrimPrefix(srv.URL, "http://"), "admin", password, 5*time.Second)
+}
+
+func TestGrafanaClient(t *testing.T) {
+	notFound := map[string]string{"message": "not found"}
+	srv, requests := newGrafanaServer(t, func(r *grafanaRequest) (int, interface{}) {
+		switch r.method + " " + r.path {
+		case "GET /api/health":
+			return http.StatusOK, map[string]string{"database": "ok"}
+		case "GET /api/folders/new-folder", "GET /api/datasources/name/new", "GET /api/dashboards/uid/missing":
+			return http.StatusNotFound, notFound
+		case "GET /api/folders/my-cluster":
+			return http.StatusOK, map[string]interface{}{"id": 3, "uid": "my-cluster"}
+		case "POST /api/folders":
+			return http.StatusOK, map[string]interface{}{"id": 4, "uid": r.body["uid"]}
+		case "GET /api/datasources/name/my%20cluster":
+			return http.StatusOK, map[string]interface{}{"id": 7, "name": "my cluster"}
+		case "POST /api/datasources", "PUT /api/datasources/7":
+			return http.StatusOK, map[string]string{"message": "Datasource saved"}
+		case "GET /api/dashboards/uid/overview":
+			return http.StatusOK, map[string]interface{}{"dashboard": map[string]interface{}{"id": 9, "uid": "overview", "version": 2}}
+		case "POST /api/dashboards/db":
+			if r.body["overwrite"] != true {
+				return http.StatusPreconditionFailed, map[string]string{"message": "The dashboard has been changed by someone else"}
+			}
+			return http.StatusOK, map[string]interface{}{"id": 9, "uid": "overview", "version": 3}
+		}
+		return http.StatusInternalServerError, nil
+	})
+	defer srv.Close()
+	client := newTestGrafanaClient(srv, "secret")
+
+	assert.Nil(t, client.CheckHealth())
+
+	// the existing folder is reused, the missing one is created
+	id, err := client.EnsureFolder("my-cluster", "my-cluster")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), id)
+	id, err = client.EnsureFolder("new-folder", "new folder")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), id)
+	last := (*requests)[len(*requests)-1]
+	assert.Equal(t, map[string]interface{}{"uid": "new-folder", "title": "new folder"}, last.body)
+
+	// the datasource of the same name is updated in place
+	created, err := client.UpsertDatasource(GrafanaDatasource{Name: "my cluster", Type: "prometheus", URL: "http://10.0.1.1:9090"})
+	assert.Nil(t, err)
+	assert.False(t, created)
+	last = (*requests)[len(*requests)-1]
+	assert.Equal(t, "PUT", last.method)
+	assert.Equal(t, float64(7), last.body["id"])
+	created, err = client.UpsertDatasource(GrafanaDatasource{ID: 7, Name: "new", Type: "prometheus"})
+	assert.Nil(t, err)
+	assert.True(t, created)
+	last = (*requests)[len(*requests)-1]
+	assert.Equal(t, "POST", last.method)
+	_, hasID := last.body["id"]
+	assert.False(t, hasID)
+
+	meta, err := client.GetDashboard("missing")
+	assert.Nil(t, err)
+	assert.Nil(t, meta)
+	meta, err = client.GetDashboard("overview")
+	assert.Nil(t, err)
+	assert.Equal(t, &GrafanaDashboardMeta{ID: 9, UID: "overview", Version: 2}, meta)
+
+	// the message of the error responses is kept
+	_, err = client.SaveDashboard(map[string]interface{}{"uid": "overview"}, 3, false)
+	assert.True(t, IsGrafanaStatus(err, http.StatusPreconditionFailed), "%v", err)
+	assert.Contains(t, err.Error(), "changed by someone else")
+	meta, err = client.SaveDashboard(map[string]interface{}{"uid": "overview"}, 3, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, meta.Version)
+	last = (*requests)[len(*requests)-1]
+	assert.Equal(t, float64(3), last.body["folderId"])
+	assert.Equal(t, map[string]interface{}{"uid": "overview"}, last.body["dashboard"])
+
+	err = newTestGrafanaClient(srv, "wrong").CheckHealth()
+	assert.True(t, IsGrafanaStatus(err, http.StatusUnauthorized), "%v", err)
+	assert.Contains(t, err.Error(), "invalid username or password")
+}
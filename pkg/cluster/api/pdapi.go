@@ -66,6 +66,7 @@ func (pc *PDClient) GetURL(addr string) string {
 // nolint (some is unused now)
 var (
 	pdPingURI           = "pd/ping"
+	pdHealthURI         = "pd/api/v1/health"
 	pdMembersURI        = "pd/api/v1/members"
 	pdStoresURI         = "pd/api/v1/stores"
 	pdStoreURI          = "pd/api/v1/store"
@@ -76,6 +77,7 @@ var (
 	pdLeaderTransferURI = "pd/api/v1/leader/transfer"
 	pdConfigReplicate   = "pd/api/v1/config/replicate"
 	pdConfigSchedule    = "pd/api/v1/config/schedule"
+	pdRegionsCheckURI   = "pd/api/v1/regions/check"
 )
 
 func tryURLs(endpoints []string, f func(endpoint string) ([]byte, error)) ([]byte, error) {
@@ -132,6 +134,27 @@ func (pc *PDClient) CheckHealth() error {
 	return nil
 }
 
+// GetHealth queries the health of all PD members from PD server
+func (pc *PDClient) GetHealth() ([]pdserverapi.Health, error) {
+	endpoints := pc.getEndpoints(pdHealthURI)
+
+	var healths []pdserverapi.Health
+
+	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
+		body, err := pc.httpClient.Get(endpoint)
+		if err != nil {
+			return body, err
+		}
+
+		return body, json.Unmarshal(body, &healths)
+	})
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	return healths, nil
+}
+
 // GetStores queries the stores info from PD server
 func (pc *PDClient) GetStores() (*pdserverapi.StoresInfo, error) {
 	// Return all stores
@@ -160,6 +183,30 @@ func (pc *PDClient) GetStores() (*pdserverapi.StoresInfo, error) {
 	return &storesInfo, nil
 }
 
+// GetPendingPeerRegionCount returns the number of regions with pending peers,
+// e.g., the peers on a restarted store catching up the logs
+func (pc *PDClient) GetPendingPeerRegionCount() (int, error) {
+	endpoints := pc.getEndpoints(pdRegionsCheckURI + "/pending-peer")
+
+	regions := struct {
+		Count int `json:"count"`
+	}{}
+
+	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
+		body, err := pc.httpClient.Get(endpoint)
+		if err != nil {
+			return body, err
+		}
+
+		return body, json.Unmarshal(body, &regions)
+	})
+	if err != nil {
+		return 0, errors.AddStack(err)
+	}
+
+	return regions.Count, nil
+}
+
 // WaitLeader wait until there's a leader or timeout.
 func (pc *PDClient) WaitLeader(retryOpt *utils.RetryOption) error {
 	if retryOpt == nil {
@@ -20,6 +20,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +28,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/pingcap/tiup/pkg/base52"
 	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/localdata"
 	tiuputils "github.com/pingcap/tiup/pkg/utils"
 )
 
@@ -56,7 +58,7 @@ func ShowAuditList(dir string) error {
 		if fi.IsDir() {
 			continue
 		}
-		ts, err := base52.Decode(fi.Name())
+		ts, _, err := parseAuditID(fi.Name())
 		if err != nil {
 			continue
 		}
@@ -72,18 +74,106 @@ func ShowAuditList(dir string) error {
 		})
 	}
 
+	// the logs of the same second are ordered by their sequence numbers
 	sort.Slice(clusterTable[1:], func(i, j int) bool {
-		return clusterTable[i+1][1] > clusterTable[j+1][1]
+		if clusterTable[i+1][1] != clusterTable[j+1][1] {
+			return clusterTable[i+1][1] > clusterTable[j+1][1]
+		}
+		_, si, _ := parseAuditID(clusterTable[i+1][0])
+		_, sj, _ := parseAuditID(clusterTable[j+1][0])
+		return si > sj
 	})
 
 	cliutil.PrintTable(clusterTable, true)
 	return nil
 }
 
-// OutputAuditLog outputs audit log.
-func OutputAuditLog(dir string, data []byte) error {
-	fname := filepath.Join(dir, base52.Encode(time.Now().Unix()))
-	return ioutil.WriteFile(fname, data, 0644)
+// auditIDSeparator separates the timestamp and the sequence number of the
+// audit logs written in the same second, it's not in the space of base52
+const auditIDSeparator = "-"
+
+// maxAuditLogsPerSecond limits the retries of OutputAuditLog for a free name
+const maxAuditLogsPerSecond = 10000
+
+// parseAuditID returns the timestamp and the sequence number in the second of
+// an audit ID, the first log of a second has no sequence number, which is 0
+func parseAuditID(id string) (ts int64, seq int, err error) {
+	parts := strings.SplitN(id, auditIDSeparator, 2)
+	if parts[0] == "" {
+		return 0, 0, errors.Errorf("invalid audit id '%s'", id)
+	}
+	if ts, err = base52.Decode(parts[0]); err != nil {
+		return 0, 0, errors.Trace(err)
+	}
+	if len(parts) == 2 {
+		if seq, err = strconv.Atoi(parts[1]); err != nil || seq <= 0 {
+			return 0, 0, errors.Errorf("invalid audit id '%s'", id)
+		}
+	}
+	return ts, seq, nil
+}
+
+// OutputAuditLog outputs audit log and returns the path of it. The log is named
+// by the current time, and the ones written in the same second are suffixed by
+// their sequence numbers, the file is created exclusively so that concurrent
+// writers never overwrite each other.
+func OutputAuditLog(dir string, data []byte) (string, error) {
+	prefix := base52.Encode(time.Now().Unix())
+	for seq := 0; seq < maxAuditLogsPerSecond; seq++ {
+		fname := filepath.Join(dir, prefix)
+		if seq > 0 {
+			fname += auditIDSeparator + strconv.Itoa(seq)
+		}
+		file, err := os.OpenFile(fname, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		_, err = file.Write(data)
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		return fname, RemoveExpired(dir)
+	}
+	return "", errors.Errorf("too many audit logs in %s written in the same second", dir)
+}
+
+// RetainDays returns how many days audit logs and other logs sharing the same
+// retention policy are kept, 0 means they are kept forever
+func RetainDays() int {
+	days, err := strconv.Atoi(os.Getenv(localdata.EnvNameAuditRetainDays))
+	if err != nil || days < 0 {
+		return 0
+	}
+	return days
+}
+
+// RemoveExpired removes files in dir which are older than the retention
+func RemoveExpired(dir string) error {
+	days := RetainDays()
+	if days == 0 {
+		return nil
+	}
+
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	deadline := time.Now().AddDate(0, 0, -days)
+	for _, fi := range fileInfos {
+		if fi.IsDir() || fi.ModTime().After(deadline) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, fi.Name())); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
 }
 
 // ShowAuditLog show the audit with the specified auditID
@@ -93,7 +183,7 @@ func ShowAuditLog(dir string, auditID string) error {
 		return errors.Errorf("cannot find the audit log '%s'", auditID)
 	}
 
-	ts, err := base52.Decode(auditID)
+	ts, _, err := parseAuditID(auditID)
 	if err != nil {
 		return errors.Annotatef(err, "unrecognized audit id '%s'", auditID)
 	}
@@ -0,0 +1,121 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputAuditLogNoCollision(t *testing.T) {
+	assert := require.New(t)
+	dir, err := ioutil.TempDir("", "tiup-audit-test")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	paths := make(map[string]struct{})
+	for i := 0; i < 100; i++ {
+		path, err := OutputAuditLog(dir, []byte("audit "+strconv.Itoa(i)+"\n"))
+		assert.Nil(err)
+		paths[path] = struct{}{}
+
+		content, err := ioutil.ReadFile(path)
+		assert.Nil(err)
+		assert.Equal("audit "+strconv.Itoa(i)+"\n", string(content))
+	}
+	assert.Len(paths, 100)
+
+	fileInfos, err := ioutil.ReadDir(dir)
+	assert.Nil(err)
+	assert.Len(fileInfos, 100)
+	for _, fi := range fileInfos {
+		_, _, err := parseAuditID(fi.Name())
+		assert.Nil(err, fi.Name())
+	}
+}
+
+func TestParseAuditID(t *testing.T) {
+	assert := require.New(t)
+	ts, seq, err := parseAuditID("fDKZ3Q-12")
+	assert.Nil(err)
+	assert.Equal(12, seq)
+	ts0, seq, err := parseAuditID("fDKZ3Q")
+	assert.Nil(err)
+	assert.Equal(0, seq)
+	assert.Equal(ts0, ts)
+
+	for _, id := range []string{"", "-1", "fDKZ3Q-0", "fDKZ3Q-x", "audit.log"} {
+		_, _, err := parseAuditID(id)
+		assert.NotNil(err, id)
+	}
+}
+
+func TestLogBudget(t *testing.T) {
+	assert := require.New(t)
+	root, err := ioutil.TempDir("", "tiup-log-budget-test")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+	defer os.Unsetenv(localdata.EnvNameLogBudget)
+
+	assert.Equal(DefaultLogBudget, LogBudget())
+	os.Setenv(localdata.EnvNameLogBudget, "1")
+	assert.Equal(int64(1<<20), LogBudget())
+
+	now := time.Now()
+	write := func(path string, size int, age time.Duration) {
+		assert.Nil(os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(ioutil.WriteFile(path, make([]byte, size), 0644))
+		assert.Nil(os.Chtimes(path, now.Add(-age), now.Add(-age)))
+	}
+	oldest := filepath.Join(root, "audit", "oldest")
+	older := filepath.Join(root, "clusters", "prod", "logs", "reload.log")
+	recent := filepath.Join(root, "clusters", "test", "logs", "deploy.log")
+	write(oldest, 512<<10, 72*time.Hour)
+	write(older, 512<<10, 48*time.Hour)
+	write(recent, 512<<10, time.Hour)
+	write(filepath.Join(root, "clusters", "prod", "meta.yaml"), 1<<20, 96*time.Hour)
+
+	usage, err := Usage(root)
+	assert.Nil(err)
+	assert.Equal(3, usage.Files)
+	assert.Equal(int64(3*512<<10), usage.Bytes)
+	assert.True(usage.OverBudget())
+
+	// the oldest logs are removed until they are within the budget
+	removed, err := EnforceLogBudget(root)
+	assert.Nil(err)
+	assert.Equal([]string{oldest}, removed)
+	usage, err = Usage(root)
+	assert.Nil(err)
+	assert.Equal(int64(1<<20), usage.Bytes)
+	assert.False(usage.OverBudget())
+
+	// the logs younger than the min retention are kept
+	write(filepath.Join(root, "audit", "new"), 1<<20, time.Minute)
+	delete(logUsages, root)
+	removed, err = EnforceLogBudget(root)
+	assert.Nil(err)
+	assert.Equal([]string{older}, removed)
+	usage, err = Usage(root)
+	assert.Nil(err)
+	assert.Equal(2, usage.Files)
+	assert.True(usage.OverBudget())
+}
@@ -0,0 +1,191 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultLogBudget is the default bytes the logs under a profile take at most
+	DefaultLogBudget int64 = 2 << 30
+	// MinLogRetention is how long the logs are kept at least, even if they
+	// are over the budget
+	MinLogRetention = 24 * time.Hour
+	// the usage is computed again if it's older than this
+	logUsageTTL = time.Minute
+)
+
+// LogUsage is the disk usage of the logs under a profile, which are the
+// audit logs and the operation logs of the clusters
+type LogUsage struct {
+	Root       string    `json:"root"`
+	Files      int       `json:"files"`
+	Bytes      int64     `json:"bytes"`
+	Budget     int64     `json:"budget"` // 0 means unlimited
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// OverBudget checks if the logs take more than the budget
+func (u LogUsage) OverBudget() bool {
+	return u.Budget > 0 && u.Bytes > u.Budget
+}
+
+var (
+	logUsageMu sync.Mutex
+	logUsages  = make(map[string]LogUsage) // keyed by the root of profiles
+)
+
+// LogBudget returns how many bytes the logs under a profile take at most,
+// 0 means unlimited
+func LogBudget() int64 {
+	env, ok := os.LookupEnv(localdata.EnvNameLogBudget)
+	if !ok {
+		return DefaultLogBudget
+	}
+	mb, err := strconv.ParseInt(env, 10, 64)
+	if err != nil || mb < 0 {
+		return DefaultLogBudget
+	}
+	return mb << 20
+}
+
+// logFile is a log file counted in the budget
+type logFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// logDirs returns the dirs of the logs under the profile, which are the audit
+// dir and the logs dirs of the clusters
+func logDirs(root string) ([]string, error) {
+	dirs, err := filepath.Glob(filepath.Join(root, "clusters", "*", "logs"))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return append([]string{filepath.Join(root, "audit")}, dirs...), nil
+}
+
+// scanLogs returns the log files under the profile, the oldest first
+func scanLogs(root string) ([]logFile, error) {
+	dirs, err := logDirs(root)
+	if err != nil {
+		return nil, err
+	}
+	var files []logFile
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if info.Mode().IsRegular() {
+				files = append(files, logFile{path: path, size: info.Size(), modTime: info.ModTime()})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	return files, nil
+}
+
+// computeLogUsage sums up the sizes of the files
+func computeLogUsage(root string, files []logFile) LogUsage {
+	usage := LogUsage{Root: root, Files: len(files), Budget: LogBudget(), ComputedAt: time.Now()}
+	for _, f := range files {
+		usage.Bytes += f.size
+	}
+	return usage
+}
+
+// Usage returns the disk usage of the logs under the profile root, it's
+// computed lazily, the one computed in the last minute is reused
+func Usage(root string) (LogUsage, error) {
+	logUsageMu.Lock()
+	defer logUsageMu.Unlock()
+	return usageLocked(root)
+}
+
+func usageLocked(root string) (LogUsage, error) {
+	if usage, ok := logUsages[root]; ok && time.Since(usage.ComputedAt) < logUsageTTL {
+		usage.Budget = LogBudget()
+		return usage, nil
+	}
+	files, err := scanLogs(root)
+	if err != nil {
+		return LogUsage{}, err
+	}
+	usage := computeLogUsage(root, files)
+	logUsages[root] = usage
+	return usage, nil
+}
+
+// EnforceLogBudget removes the oldest logs under the profile root until they
+// take no more than the budget, the logs younger than MinLogRetention are
+// never removed. The paths of the removed files are returned.
+func EnforceLogBudget(root string) ([]string, error) {
+	logUsageMu.Lock()
+	defer logUsageMu.Unlock()
+
+	usage, err := usageLocked(root)
+	if err != nil || !usage.OverBudget() {
+		return nil, err
+	}
+	// the cached usage may be stale
+	files, err := scanLogs(root)
+	if err != nil {
+		return nil, err
+	}
+	usage = computeLogUsage(root, files)
+
+	var removed []string
+	deadline := time.Now().Add(-MinLogRetention)
+	for _, f := range files {
+		if !usage.OverBudget() || f.modTime.After(deadline) {
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			logUsages[root] = usage
+			return removed, errors.Trace(err)
+		}
+		zap.L().Info("Removed log over the disk budget",
+			zap.String("file", f.path), zap.Int64("size", f.size), zap.Time("modified", f.modTime))
+		removed = append(removed, f.path)
+		usage.Files--
+		usage.Bytes -= f.size
+	}
+	if usage.OverBudget() {
+		zap.L().Warn("Logs are still over the disk budget, the rest are younger than the min retention",
+			zap.String("root", root), zap.Int64("bytes", usage.Bytes), zap.Int64("budget", usage.Budget))
+	}
+	logUsages[root] = usage
+	return removed, nil
+}
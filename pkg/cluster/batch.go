@@ -0,0 +1,304 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/joomcode/errorx"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/logger"
+	"github.com/pingcap/tiup/pkg/set"
+	"go.uber.org/zap"
+)
+
+var (
+	errNSBatch = errorx.NewNamespace("batch")
+	// ErrBatchUnsupported is returned when the operation can't run on multiple clusters
+	ErrBatchUnsupported = errNSBatch.NewType("unsupported", errutil.ErrTraitPreCheck)
+	// ErrBatchClusterBusy is the result of a cluster being operated by another process
+	ErrBatchClusterBusy = errNSBatch.NewType("cluster_busy", errutil.ErrTraitPreCheck)
+	// ErrBatchSkipped is the result of a cluster skipped for a failure in fail-fast mode
+	ErrBatchSkipped = errNSBatch.NewType("skipped")
+	// ErrBatchFailed is returned when the operation fails on some clusters of a batch
+	ErrBatchFailed = errNSBatch.NewType("failed")
+)
+
+// DefaultBatchConcurrency is the default number of clusters operated at the same time
+const DefaultBatchConcurrency = 4
+
+// BatchOptions contains the options for operating multiple clusters
+type BatchOptions struct {
+	Concurrency int  // the number of clusters operated at the same time, DefaultBatchConcurrency if not positive
+	FailFast    bool // don't operate the clusters not begun yet once an operation fails
+	// called with the rolled up progress when an operation on a cluster
+	// begins, finishes or one of its tasks makes progress
+	OnProgress func(BatchProgress)
+}
+
+// BatchResult is the result of the operation on a cluster of a batch
+type BatchResult struct {
+	Err      error             `json:"-"`
+	Error    string            `json:"error,omitempty"`
+	Progress OperationProgress `json:"progress"`
+}
+
+// BatchProgress is the progress of an operation on multiple clusters,
+// rolled up from the progress of the operation on each cluster
+type BatchProgress struct {
+	Operation     OperationType       `json:"operation"`
+	Pending       int                 `json:"pending"` // the clusters not begun yet
+	Skipped       int                 `json:"skipped"` // the clusters failed before their operations begin, e.g. busy ones
+	Running       int                 `json:"running"`
+	Succeeded     int                 `json:"succeeded"`
+	Failed        int                 `json:"failed"`
+	TasksBegun    int                 `json:"tasks_begun"`
+	TasksFinished int                 `json:"tasks_finished"`
+	TasksFailed   int                 `json:"tasks_failed"`
+	Clusters      []OperationProgress `json:"clusters"` // the clusters begun, sorted by names
+}
+
+// batchOperations are the operations which can run on multiple clusters
+var batchOperations = map[OperationType]bool{
+	OperationStart:          true,
+	OperationStop:           true,
+	OperationRestart:        true,
+	OperationReload:         true,
+	OperationEnable:         true,
+	OperationDisable:        true,
+	OperationRedeployAgents: true,
+}
+
+// batch tracks the operations on the clusters of a batch
+type batch struct {
+	mu            sync.Mutex
+	operationType OperationType
+	names         []string
+	startTime     time.Time
+	ops           map[string]*OperationInfo // the operations begun
+	notOperated   set.StringSet             // the clusters failed before their operations begin
+	failed        bool
+}
+
+// begun tracks the operation on the cluster if it's begun by the batch
+func (b *batch) begun(name string) {
+	info := GetCurrentOperation(name)
+	if info == nil || info.operationType != b.operationType || info.startTime.Before(b.startTime) {
+		return
+	}
+	b.mu.Lock()
+	b.ops[name] = info
+	b.mu.Unlock()
+}
+
+// progress rolls up the progress of the operations begun
+func (b *batch) progress() BatchProgress {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p := BatchProgress{Operation: b.operationType}
+	for _, name := range b.names {
+		info, ok := b.ops[name]
+		if !ok {
+			if b.notOperated.Exist(name) {
+				p.Skipped++
+			} else {
+				p.Pending++
+			}
+			continue
+		}
+		cp := info.ComputeProgress()
+		switch {
+		case !cp.Finished:
+			p.Running++
+		case cp.Error != "":
+			p.Failed++
+		default:
+			p.Succeeded++
+		}
+		p.TasksBegun += cp.TasksBegun
+		p.TasksFinished += cp.TasksFinished
+		p.TasksFailed += cp.TasksFailed
+		p.Clusters = append(p.Clusters, cp)
+	}
+	sort.Slice(p.Clusters, func(i, j int) bool {
+		return p.Clusters[i].Cluster < p.Clusters[j].Cluster
+	})
+	return p
+}
+
+// operate runs the operation on a single cluster of the batch
+func (m *Manager) operate(name string, operationType OperationType, options operator.Options) error {
+	switch operationType {
+	case OperationStart:
+		return m.StartCluster(name, options)
+	case OperationStop:
+		return m.StopCluster(name, options)
+	case OperationRestart:
+		return m.RestartCluster(name, options)
+	case OperationReload:
+		return m.Reload(name, options, false)
+	case OperationEnable, OperationDisable:
+		return m.EnableCluster(name, operationType == OperationEnable, EnableOptions{}, options)
+	case OperationRedeployAgents:
+		return m.RedeployMonitoringAgents(name, RedeployAgentsOptions{}, options)
+	}
+	return ErrBatchUnsupported.New("Operation %s is not supported on multiple clusters", operationType)
+}
+
+// operateLocked runs the operation on a single cluster of the batch holding
+// its operation lock, which is taken before the cluster is touched, and the
+// operation is audited on its own
+func (m *Manager) operateLocked(name string, operationType OperationType, options operator.Options) error {
+	pid, err := m.tryOperationLock(name)
+	if err != nil {
+		return err
+	}
+	if pid != 0 {
+		return ErrBatchClusterBusy.New("Cluster %s is being operated by process %d", name, pid)
+	}
+	// released by the operation when it ends, or here if it fails to begin
+	defer m.releaseOperationLock(name)
+
+	logger.StartScopedAuditLog(name)
+	err = m.operate(name, operationType, options)
+	if _, aerr := logger.OutputScopedAuditLog(name); aerr != nil {
+		zap.L().Warn("Failed to output the audit log", zap.String("cluster", name), zap.Error(aerr))
+	}
+	return err
+}
+
+// BatchOperate runs the operation on the clusters, opt.Concurrency clusters
+// at the same time. The operation on each cluster takes the lock of the
+// cluster and is recorded in the audit log and history of the cluster as if
+// it runs alone. A cluster being operated by another process is not operated.
+// The failure on a cluster doesn't abort the others unless opt.FailFast is
+// set, and the result of each cluster is returned keyed by the names.
+func (m *Manager) BatchOperate(
+	names []string,
+	operationType OperationType,
+	options operator.Options,
+	opt BatchOptions,
+) (map[string]*BatchResult, error) {
+	if !batchOperations[operationType] {
+		return nil, ErrBatchUnsupported.New("Operation %s is not supported on multiple clusters", operationType)
+	}
+	if len(set.NewStringSet(names...)) != len(names) {
+		return nil, ErrBatchUnsupported.New("The clusters to operate are duplicated: %v", names)
+	}
+	for _, name := range names {
+		if err := m.checkExist(name); err != nil {
+			return nil, err
+		}
+	}
+
+	concurrency := opt.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	b := &batch{
+		operationType: operationType,
+		names:         names,
+		startTime:     time.Now(),
+		ops:           make(map[string]*OperationInfo),
+		notOperated:   set.NewStringSet(),
+	}
+	var reportMu sync.Mutex
+	report := func() {
+		if opt.OnProgress == nil {
+			return
+		}
+		reportMu.Lock()
+		defer reportMu.Unlock()
+		opt.OnProgress(b.progress())
+	}
+
+	// track the operations and roll up the progress on their events
+	if opt.OnProgress != nil {
+		inBatch := set.NewStringSet(names...)
+		events, unsubscribe := SubscribeOperationEvents(256)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for e := range events {
+				if !inBatch.Exist(e.Cluster) || e.Operation != operationType {
+					continue
+				}
+				if e.Kind == EventOperationBegin {
+					b.begun(e.Cluster)
+				}
+				report()
+			}
+		}()
+		defer func() {
+			unsubscribe()
+			<-done
+		}()
+	}
+
+	var (
+		wg       sync.WaitGroup
+		resultMu sync.Mutex
+		results  = make(map[string]*BatchResult, len(names))
+		sem      = make(chan struct{}, concurrency)
+	)
+	for _, name := range names {
+		name := name
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			b.mu.Lock()
+			failed := b.failed
+			b.mu.Unlock()
+
+			var err error
+			if opt.FailFast && failed {
+				err = ErrBatchSkipped.New("Cluster %s is skipped as the operation on another cluster failed", name)
+			} else {
+				err = m.operateLocked(name, operationType, options)
+				b.begun(name)
+			}
+
+			result := &BatchResult{Err: err}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			b.mu.Lock()
+			b.failed = b.failed || err != nil
+			if info, ok := b.ops[name]; ok {
+				result.Progress = info.ComputeProgress()
+			} else {
+				// failed before the operation begins, e.g. the cluster is busy
+				b.notOperated.Insert(name)
+				result.Progress = OperationProgress{Operation: operationType, Cluster: name, Finished: true, Error: result.Error}
+			}
+			b.mu.Unlock()
+
+			resultMu.Lock()
+			results[name] = result
+			resultMu.Unlock()
+			report()
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
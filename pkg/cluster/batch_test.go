@@ -0,0 +1,65 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchOperate(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+	for _, name := range []string{"staging-1", "staging-2"} {
+		require.Nil(t, os.MkdirAll(m.specManager.Path(name), 0755))
+		require.Nil(t, ioutil.WriteFile(m.specManager.Path(name, "meta.yaml"), []byte("user: tidb\n"), 0644))
+		// held by a process that is always alive
+		require.Nil(t, ioutil.WriteFile(m.specManager.Path(name, operationLockFile), []byte("1"), 0644))
+	}
+
+	_, err := m.BatchOperate([]string{"staging-1"}, OperationDeploy, operator.Options{}, BatchOptions{})
+	require.NotNil(t, err)
+	assert.True(t, errutil.Cast(err).IsOfType(ErrBatchUnsupported))
+	_, err = m.BatchOperate([]string{"staging-1", "staging-1"}, OperationStop, operator.Options{}, BatchOptions{})
+	require.NotNil(t, err)
+	assert.True(t, errutil.Cast(err).IsOfType(ErrBatchUnsupported))
+	_, err = m.BatchOperate([]string{"staging-1", "staging-3"}, OperationStop, operator.Options{}, BatchOptions{})
+	require.NotNil(t, err)
+
+	var last BatchProgress
+	results, err := m.BatchOperate([]string{"staging-1", "staging-2"}, OperationStop, operator.Options{}, BatchOptions{
+		OnProgress: func(p BatchProgress) { last = p },
+	})
+	require.Nil(t, err)
+	require.Len(t, results, 2)
+	for _, r := range results {
+		assert.True(t, errutil.Cast(r.Err).IsOfType(ErrBatchClusterBusy))
+	}
+	assert.Equal(t, 2, last.Skipped)
+	assert.Equal(t, 0, last.Pending)
+
+	results, err = m.BatchOperate([]string{"staging-1", "staging-2"}, OperationStop, operator.Options{}, BatchOptions{
+		Concurrency: 1,
+		FailFast:    true,
+	})
+	require.Nil(t, err)
+	assert.True(t, errutil.Cast(results["staging-1"].Err).IsOfType(ErrBatchClusterBusy))
+	assert.True(t, errutil.Cast(results["staging-2"].Err).IsOfType(ErrBatchSkipped))
+}
@@ -0,0 +1,121 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"os"
+
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"golang.org/x/crypto/ssh/terminal"
+	"golang.org/x/sys/unix"
+)
+
+// Paused returns where the operation is paused at a breakpoint, false if
+// it's not paused, see operator.Options.Breakpoints
+func (info *OperationInfo) Paused() (string, bool) {
+	info.mu.RLock()
+	ctx := info.ctx
+	info.mu.RUnlock()
+	if ctx == nil {
+		return "", false
+	}
+	return ctx.Paused()
+}
+
+// Resume resumes the operation paused at a breakpoint, false is returned if
+// it's not paused
+func (info *OperationInfo) Resume() bool {
+	info.mu.RLock()
+	ctx := info.ctx
+	info.mu.RUnlock()
+	if ctx == nil {
+		return false
+	}
+	where, _ := ctx.Paused()
+	if !ctx.Resume() {
+		return false
+	}
+	log.Infof("Resumed the operation on cluster `%s` paused %s", info.clusterName, where)
+	return true
+}
+
+// ResumeOnEnter resumes the operations paused at breakpoints once the Enter
+// key is pressed in the terminal in while they are paused. Nothing is read
+// if in isn't a terminal, or no operation is paused, so the lines typed for
+// the confirmations prompted by the operations are never consumed. It
+// returns when stop is closed or in is closed.
+func ResumeOnEnter(in *os.File, stop <-chan struct{}) {
+	if !terminal.IsTerminal(int(in.Fd())) {
+		return
+	}
+	events, unsubscribe := SubscribeOperationEvents(16)
+	defer unsubscribe()
+	for {
+		select {
+		case <-stop:
+			return
+		case e := <-events:
+			if e.Kind != string(task.EventTaskPhase) || e.Phase != task.PhasePaused {
+				continue
+			}
+			info := GetCurrentOperation(e.Cluster)
+			if info == nil {
+				continue
+			}
+			paused := func() bool {
+				_, paused := info.Paused()
+				return paused
+			}
+			read, err := readLineWhile(in, stop, paused)
+			if err != nil {
+				return
+			}
+			if read {
+				info.Resume()
+			}
+		}
+	}
+}
+
+// readLineWhile reads a line from in once it's typed, as long as cond holds
+// and stop isn't closed. Nothing is read if cond fails first, e.g., the
+// operation is resumed in other ways, so the line is left for other readers.
+func readLineWhile(in *os.File, stop <-chan struct{}, cond func() bool) (bool, error) {
+	fds := []unix.PollFd{{Fd: int32(in.Fd()), Events: unix.POLLIN}}
+	for cond() {
+		select {
+		case <-stop:
+			return false, nil
+		default:
+		}
+		n, err := unix.Poll(fds, 100)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if n == 0 {
+			continue
+		}
+		// the terminal in canonical mode returns a line at most each read
+		buf := make([]byte, 4096)
+		if _, err := in.Read(buf); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakBeforeUpgradeInstance(t *testing.T) {
+	m, _, _, cleanup := newTestMockCluster(t, 2)
+	defer cleanup()
+
+	// the steps of the operator functions are breakpoints as well
+	opt := operator.Options{
+		SSHTimeout: 5, OptTimeout: 10, APITimeout: 10, IgnoreConfigCheck: true, Force: true,
+		Breakpoints: []string{"UpgradeInstance tikv mock-2:20160"},
+	}
+	errC := make(chan error, 1)
+	go func() { errC <- m.Reload("mock", opt, false) }()
+
+	var where string
+	for deadline := time.Now().Add(10 * time.Second); where == "" && time.Now().Before(deadline); {
+		select {
+		case err := <-errC:
+			require.FailNow(t, "the operation isn't paused", "%v", err)
+		case <-time.After(10 * time.Millisecond):
+		}
+		if info := GetCurrentOperation("mock"); info != nil {
+			where, _ = info.Paused()
+		}
+	}
+	assert.Equal(t, "before step `UpgradeInstance tikv mock-2:20160`", where)
+	require.True(t, GetCurrentOperation("mock").Resume())
+	select {
+	case err := <-errC:
+		assert.Nil(t, err)
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "the operation isn't resumed")
+	}
+}
+
+func TestReadLineWhilePaused(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.Nil(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	// nothing is read once the operation isn't paused, the line is left
+	// for the confirmations prompted later
+	_, err = w.Write([]byte("y\n"))
+	require.Nil(t, err)
+	read, err := readLineWhile(r, nil, func() bool { return false })
+	require.Nil(t, err)
+	assert.False(t, read)
+
+	read, err = readLineWhile(r, nil, func() bool { return true })
+	require.Nil(t, err)
+	assert.True(t, read)
+
+	// it gives up waiting for a line once the operation is resumed in other ways
+	deadline := time.Now().Add(300 * time.Millisecond)
+	read, err = readLineWhile(r, nil, func() bool { return time.Now().Before(deadline) })
+	require.Nil(t, err)
+	assert.False(t, read)
+}
@@ -29,6 +29,9 @@ type Repository interface {
 	DownloadComponent(comp, version, target string) error
 	VerifyComponent(comp, version, target string) error
 	ComponentBinEntry(comp, version string) (string, error)
+	ComponentAvailable(comp, version string) bool
+	ComponentReleaseInfo(comp, version string) (released, notes string, err error)
+	ComponentSize(comp, version string) (int64, error)
 }
 
 type repositoryT struct {
@@ -37,10 +40,22 @@ type repositoryT struct {
 
 // NewRepository returns repository
 func NewRepository(os, arch string) (Repository, error) {
-	profile := localdata.InitProfile()
-	mirror := repository.NewMirror(environment.Mirror(), repository.MirrorOptions{
-		Progress: repository.DisableProgress{},
+	return NewRepositoryWithProgress(os, arch, repository.DisableProgress{})
+}
+
+// NewRepositoryWithProgress returns repository notifying the progress of
+// downloading packages to progress
+func NewRepositoryWithProgress(os, arch string, progress repository.DownloadProgress) (Repository, error) {
+	return NewRepositoryWithOptions(os, arch, repository.MirrorOptions{
+		Progress: progress,
 	})
+}
+
+// NewRepositoryWithOptions returns repository downloading from the mirror
+// with options
+func NewRepositoryWithOptions(os, arch string, options repository.MirrorOptions) (Repository, error) {
+	profile := localdata.InitProfile()
+	mirror := repository.NewMirror(environment.Mirror(), options)
 	local, err := v1manifest.NewManifests(profile)
 	if err != nil {
 		return nil, err
@@ -53,12 +68,46 @@ func NewRepository(os, arch string) (Repository, error) {
 	return &repositoryT{repo}, nil
 }
 
+// ComponentAvailable returns whether the version of comp is published for the
+// platform of the repository
+func (r *repositoryT) ComponentAvailable(comp, version string) bool {
+	_, err := r.repo.ComponentVersion(comp, version, false)
+	return err == nil
+}
+
+// ComponentReleaseInfo returns the release date and release notes of the
+// version of comp, the notes are empty if the manifest doesn't have them
+func (r *repositoryT) ComponentReleaseInfo(comp, version string) (string, string, error) {
+	versionItem, err := r.repo.ComponentVersion(comp, version, false)
+	if err != nil {
+		return "", "", err
+	}
+	return versionItem.Released, versionItem.ReleaseNotes, nil
+}
+
+// ComponentSize returns the size in bytes of the package of the version of
+// comp declared in the manifest
+func (r *repositoryT) ComponentSize(comp, version string) (int64, error) {
+	versionItem, err := r.repo.ComponentVersion(comp, version, false)
+	if err != nil {
+		return 0, err
+	}
+	return int64(versionItem.Length), nil
+}
+
 func (r *repositoryT) DownloadComponent(comp, version, target string) error {
 	versionItem, err := r.repo.ComponentVersion(comp, version, false)
 	if err != nil {
 		return err
 	}
 
+	// link the package from shared cache instead of copying it to memory
+	if cache := repository.ConfiguredSharedCache(); cache != nil {
+		if ok, err := cache.Link(versionItem, target); err != nil || ok {
+			return err
+		}
+	}
+
 	reader, err := r.repo.FetchComponent(versionItem)
 	if err != nil {
 		return err
@@ -89,6 +138,51 @@ func (r *repositoryT) VerifyComponent(comp, version, target string) error {
 	return utils.CheckSHA256(file, versionItem.Hashes["sha256"])
 }
 
+// LocalComponentHash returns the sha256 hash of the package of the version of
+// comp for the platform from the manifests in the local profile, nothing is
+// fetched from the mirror. It's empty if the local manifests don't have it.
+func LocalComponentHash(comp, version, os, arch string) (string, error) {
+	versionItem, err := localVersionItem(comp, version, os, arch)
+	if err != nil || versionItem == nil {
+		return "", err
+	}
+	return versionItem.Hashes["sha256"], nil
+}
+
+// LocalComponentBinEntry returns the binary entry of the version of comp for
+// the platform from the manifests in the local profile, nothing is fetched
+// from the mirror. It's empty if the local manifests don't have it.
+func LocalComponentBinEntry(comp, version, os, arch string) (string, error) {
+	versionItem, err := localVersionItem(comp, version, os, arch)
+	if err != nil || versionItem == nil {
+		return "", err
+	}
+	return versionItem.Entry, nil
+}
+
+// localVersionItem returns the version item of comp from the manifests in the
+// local profile, nil if the local manifests don't have it
+func localVersionItem(comp, version, os, arch string) (*v1manifest.VersionItem, error) {
+	local, err := v1manifest.NewManifests(localdata.InitProfile())
+	if err != nil {
+		return nil, err
+	}
+	var index v1manifest.Index
+	_, exists, err := local.LoadManifest(&index)
+	if err != nil || !exists {
+		return nil, err
+	}
+	item, found := index.ComponentListWithYanked()[comp]
+	if !found {
+		return nil, nil
+	}
+	manifest, err := local.LoadComponentManifest(&item, v1manifest.ComponentManifestFilename(comp))
+	if err != nil || manifest == nil {
+		return nil, err
+	}
+	return manifest.VersionItem(repository.PlatformString(os, arch), version, true), nil
+}
+
 func (r *repositoryT) ComponentBinEntry(comp, version string) (string, error) {
 	versionItem, err := r.repo.ComponentVersion(comp, version, true)
 	if err != nil {
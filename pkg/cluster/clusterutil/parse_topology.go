@@ -31,8 +31,29 @@ var (
 	ErrTopologyParseFailed = errNSTopolohy.NewType("parse_failed", errutil.ErrTraitPreCheck)
 )
 
-// ParseTopologyYaml read yaml content from `file` and unmarshal it to `out`
-func ParseTopologyYaml(file string, out interface{}) error {
+// ParseOption is an option of ParseTopologyYaml
+type ParseOption func(opt *parseOptions)
+
+type parseOptions struct {
+	allowUnknownFields bool
+}
+
+// AllowUnknownFields makes ParseTopologyYaml ignore the unknown fields
+// instead of failing, e.g., the ones of newer versions
+func AllowUnknownFields(allow bool) ParseOption {
+	return func(opt *parseOptions) {
+		opt.allowUnknownFields = allow
+	}
+}
+
+// ParseTopologyYaml read yaml content from `file` and unmarshal it to `out`,
+// the unknown fields are rejected unless AllowUnknownFields is set
+func ParseTopologyYaml(file string, out interface{}, opts ...ParseOption) error {
+	var opt parseOptions
+	for _, o := range opts {
+		o(&opt)
+	}
+
 	suggestionProps := map[string]string{
 		"File": file,
 	}
@@ -51,11 +72,24 @@ To generate a sample topology file:
 `, suggestionProps))
 	}
 
-	if err = yaml.UnmarshalStrict(yamlFile, out); err != nil {
+	unmarshal := yaml.UnmarshalStrict
+	if opt.allowUnknownFields {
+		unmarshal = yaml.Unmarshal
+	}
+	if err = unmarshal(yamlFile, out); err != nil {
+		if te, ok := err.(*yaml.TypeError); ok {
+			schema := GenerateSchema(out)
+			for i, msg := range te.Errors {
+				te.Errors[i] = schema.explainUnknownField(msg)
+			}
+		}
 		return ErrTopologyParseFailed.
 			Wrap(err, "Failed to parse topology file %s", file).
 			WithProperty(cliutil.SuggestionFromTemplate(`
 Please check the syntax of your topology file {{ColorKeyword}}{{.File}}{{ColorReset}} and try again.
+
+To ignore the fields unknown to this version:
+  {{ColorCommand}}--allow-unknown-fields{{ColorReset}}
 `, suggestionProps))
 	}
 
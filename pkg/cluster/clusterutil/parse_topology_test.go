@@ -1,6 +1,8 @@
 package clusterutil
 
 import (
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -22,3 +24,80 @@ func (s *topoSuite) TestParseTopologyYaml(c *check.C) {
 	err := ParseTopologyYaml(file, &mp)
 	c.Assert(err, check.IsNil)
 }
+
+type schemaGlobal struct {
+	User string `yaml:"user" default:"tidb"`
+	Arch string `yaml:"arch,omitempty" enum:"amd64,arm64"`
+}
+
+type schemaServer struct {
+	Host      string `yaml:"host"`
+	DeployDir string `yaml:"deploy_dir,omitempty"`
+}
+
+type schemaTopo struct {
+	Global  schemaGlobal    `yaml:"global"`
+	Servers []*schemaServer `yaml:"servers"`
+}
+
+// schemaAliasedTopo is decoded through an alias type like the topologies
+type schemaAliasedTopo schemaTopo
+
+type schemaTopoAlias schemaAliasedTopo
+
+func (t *schemaAliasedTopo) YAMLAliases() []interface{} {
+	return []interface{}{(*schemaTopoAlias)(nil)}
+}
+
+func (t *schemaAliasedTopo) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return unmarshal((*schemaTopoAlias)(t))
+}
+
+func (s *topoSuite) TestParseUnknownFields(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-topo-*")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "topo.yaml")
+	err = ioutil.WriteFile(file, []byte(`
+servers:
+  - host: 172.16.5.1
+    deploy_dirr: /data
+`), 0644)
+	c.Assert(err, check.IsNil)
+
+	var topo schemaTopo
+	err = ParseTopologyYaml(file, &topo)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Matches, "(?s).*line 4: unknown field `deploy_dirr`, did you mean `deploy_dir`\\?.*")
+
+	err = ParseTopologyYaml(file, &topo, AllowUnknownFields(true))
+	c.Assert(err, check.IsNil)
+	c.Assert(topo.Servers[0].Host, check.Equals, "172.16.5.1")
+
+	// the unknown top level fields are named after the alias type
+	err = ioutil.WriteFile(file, []byte(`
+globall:
+  user: tidb
+`), 0644)
+	c.Assert(err, check.IsNil)
+	err = ParseTopologyYaml(file, new(schemaAliasedTopo))
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Matches, "(?s).*line 2: unknown field `globall`, did you mean `global`\\?.*")
+}
+
+func (s *topoSuite) TestGenerateSchema(c *check.C) {
+	schema := GenerateSchema(&schemaTopo{})
+	c.Assert(schema.Type, check.Equals, "object")
+	c.Assert(schema.Fields, check.HasLen, 2)
+
+	global := schema.Fields[0]
+	c.Assert(global.Name, check.Equals, "global")
+	c.Assert(global.Fields[0].Default, check.Equals, "tidb")
+	c.Assert(global.Fields[1].Enum, check.DeepEquals, []string{"amd64", "arm64"})
+
+	servers := schema.Fields[1]
+	c.Assert(servers.Type, check.Equals, "list")
+	c.Assert(servers.Items.Type, check.Equals, "object")
+	c.Assert(servers.Items.Fields[1].Name, check.Equals, "deploy_dir")
+}
@@ -0,0 +1,180 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterutil
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// SchemaField describes a field of a topology, or the topology itself, so
+// editors and UIs can offer completion. The field names are the YAML keys,
+// the default values come from the `default` tags and the enum values come
+// from the `enum` tags of the fields.
+type SchemaField struct {
+	Name    string         `json:"name,omitempty"`
+	Type    string         `json:"type"` // string, int, uint, float, bool, list, map, object or any
+	Default string         `json:"default,omitempty"`
+	Enum    []string       `json:"enum,omitempty"`
+	Items   *SchemaField   `json:"items,omitempty"`  // the elements of a list or the values of a map
+	Fields  []*SchemaField `json:"fields,omitempty"` // the fields of an object
+
+	goType  string   // the Go type of an object, e.g., spec.TiDBSpec
+	aliases []string // the Go types the object is decoded through, see YAMLAliased
+}
+
+// YAMLAliased is implemented by the types decoded through alias types by
+// their UnmarshalYAML, e.g., to set the default values after decoding. The
+// errors of unknown fields name the alias types instead, so the aliases are
+// recorded in the schema to explain them.
+type YAMLAliased interface {
+	YAMLAliases() []interface{}
+}
+
+// GenerateSchema generates the schema of the YAML representation of v
+func GenerateSchema(v interface{}) *SchemaField {
+	return schemaOf(reflect.TypeOf(v), make(map[reflect.Type]bool))
+}
+
+func schemaOf(t reflect.Type, visiting map[reflect.Type]bool) *SchemaField {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &SchemaField{Type: "string"}
+	case reflect.Bool:
+		return &SchemaField{Type: "bool"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &SchemaField{Type: "int"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &SchemaField{Type: "uint"}
+	case reflect.Float32, reflect.Float64:
+		return &SchemaField{Type: "float"}
+	case reflect.Slice, reflect.Array:
+		return &SchemaField{Type: "list", Items: schemaOf(t.Elem(), visiting)}
+	case reflect.Map:
+		return &SchemaField{Type: "map", Items: schemaOf(t.Elem(), visiting)}
+	case reflect.Struct:
+		s := &SchemaField{Type: "object", goType: t.String()}
+		if a, ok := reflect.New(t).Interface().(YAMLAliased); ok {
+			for _, alias := range a.YAMLAliases() {
+				at := reflect.TypeOf(alias)
+				for at.Kind() == reflect.Ptr {
+					at = at.Elem()
+				}
+				s.aliases = append(s.aliases, at.String())
+			}
+		}
+		// the fields of recursive types are described only once
+		if visiting[t] {
+			return s
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		s.Fields = structFields(t, visiting)
+		return s
+	}
+	return &SchemaField{Type: "any"}
+}
+
+// structFields returns the fields of the struct type t decoded by YAML
+func structFields(t reflect.Type, visiting map[reflect.Type]bool) []*SchemaField {
+	var fields []*SchemaField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("yaml"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		if len(tag) > 1 && tag[1] == "inline" {
+			fields = append(fields, schemaOf(f.Type, visiting).Fields...)
+			continue
+		}
+		field := schemaOf(f.Type, visiting)
+		field.Name = tag[0]
+		if field.Name == "" {
+			field.Name = strings.ToLower(f.Name)
+		}
+		field.Default = f.Tag.Get("default")
+		if enum := f.Tag.Get("enum"); enum != "" {
+			field.Enum = strings.Split(enum, ",")
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// object returns the schema of the object of the Go type in s
+func (s *SchemaField) object(goType string) *SchemaField {
+	if s == nil {
+		return nil
+	}
+	if s.goType == goType {
+		return s
+	}
+	for _, alias := range s.aliases {
+		if alias == goType {
+			return s
+		}
+	}
+	if found := s.Items.object(goType); found != nil {
+		return found
+	}
+	for _, f := range s.Fields {
+		if found := f.object(goType); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// NearestField returns the name of the field of the object of the Go type
+// most similar to name, empty if there isn't a similar one
+func (s *SchemaField) NearestField(goType, name string) string {
+	obj := s.object(goType)
+	if obj == nil {
+		return ""
+	}
+	nearest, minDist := "", len(name)/3+2
+	for _, f := range obj.Fields {
+		if d := utils.EditDistance(name, f.Name); d < minDist {
+			nearest, minDist = f.Name, d
+		}
+	}
+	return nearest
+}
+
+// the error of yaml.UnmarshalStrict for an unknown field
+var unknownFieldRegexp = regexp.MustCompile(`^line (\d+): field (\S+) not found in type (\S+)$`)
+
+// explainUnknownField rewrites the error message of an unknown field with
+// the nearest valid field name, other messages are returned as is
+func (s *SchemaField) explainUnknownField(msg string) string {
+	m := unknownFieldRegexp.FindStringSubmatch(strings.TrimSpace(msg))
+	if m == nil {
+		return msg
+	}
+	if nearest := s.NearestField(m[3], m[2]); nearest != "" {
+		return fmt.Sprintf("line %s: unknown field `%s`, did you mean `%s`?", m[1], m[2], nearest)
+	}
+	return fmt.Sprintf("line %s: unknown field `%s`", m[1], m[2])
+}
@@ -0,0 +1,227 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/version"
+)
+
+var (
+	errNSVersion = errorx.NewNamespace("version")
+	// ErrInvalidVersion is returned when the version to deploy or upgrade to
+	// is not a valid SemVer string
+	ErrInvalidVersion = errNSVersion.NewType("invalid", errutil.ErrTraitPreCheck)
+)
+
+// normalizeClusterVersion returns the version to deploy or upgrade to in the
+// form of the repository and the metadata, e.g., v6.5.0 for 6.5.0, see
+// version.Parse. The versions not valid are refused unless ignoreCheck is
+// set, they are used as they are then, e.g., for custom builds.
+func normalizeClusterVersion(ver string, ignoreCheck bool) (string, error) {
+	v, err := version.Parse(ver)
+	if err == nil {
+		return v.String(), nil
+	}
+	if ignoreCheck {
+		log.Warnf("Version %s is not a valid SemVer string, it's used as it is", ver)
+		return ver, nil
+	}
+	return "", ErrInvalidVersion.Wrap(err, "Invalid version %s", ver).
+		WithProperty(cliutil.SuggestionFromString(
+			"Please specify a version like v4.0.0, or nightly for the latest build.\n" +
+				"Use `--ignore-version-check` to use it as it is, e.g., for a custom build."))
+}
+
+// upgradeScope is the components of the cluster an upgrade replaces the
+// binaries of, the others keep their versions
+type upgradeScope struct {
+	topo  spec.Topology
+	comps set.StringSet // nil means all the components
+}
+
+// newUpgradeScope checks the components are in the topology, empty means
+// upgrading all of them
+func newUpgradeScope(topo spec.Topology, comps []string) (*upgradeScope, error) {
+	if len(comps) == 0 {
+		return &upgradeScope{topo: topo}, nil
+	}
+	deployed := set.NewStringSet()
+	for _, comp := range topo.ComponentsByUpdateOrder() {
+		if len(comp.Instances()) > 0 {
+			deployed.Insert(comp.Name())
+		}
+	}
+	for _, comp := range comps {
+		if !deployed.Exist(comp) {
+			return nil, perrs.Errorf("component %s is not deployed in the cluster", comp)
+		}
+	}
+	return &upgradeScope{topo: topo, comps: set.NewStringSet(comps...)}, nil
+}
+
+// partial is true if only some components are upgraded
+func (s *upgradeScope) partial() bool {
+	return s.comps != nil
+}
+
+// includes is true if the component is upgraded
+func (s *upgradeScope) includes(comp string) bool {
+	return s.comps == nil || s.comps.Exist(comp)
+}
+
+// IterInstance implements InstanceIter, only the upgraded instances are iterated
+func (s *upgradeScope) IterInstance(fn func(inst spec.Instance)) {
+	s.topo.IterInstance(func(inst spec.Instance) {
+		if s.includes(inst.ComponentName()) {
+			fn(inst)
+		}
+	})
+}
+
+// checkVersions refuses the upgrade if any upgraded component is not older
+// than the target version
+func (s *upgradeScope) checkVersions(base *spec.BaseMeta, target string) error {
+	if !s.partial() {
+		if err := versionCompare(base.Version, target); err != nil {
+			return err
+		}
+		// the components upgraded apart may be newer than the cluster
+		for comp, v := range base.ComponentVersions {
+			if version.Compare(v, target) > 0 {
+				return perrs.Errorf("component %s is of %s, please specify a version not lower than it", comp, v)
+			}
+		}
+		return nil
+	}
+	for _, comp := range s.comps.Slice() {
+		if err := versionCompare(base.ComponentVersion(comp), target); err != nil {
+			return perrs.Annotatef(err, "component %s", comp)
+		}
+	}
+	return nil
+}
+
+// versions returns the versions of the components after upgrading, see
+// deployedVersions
+func (s *upgradeScope) versions(base *spec.BaseMeta, target string) map[string]string {
+	versions := deployedVersions(s.topo, base)
+	for comp := range versions {
+		if s.includes(comp) {
+			versions[comp] = target
+		}
+	}
+	return versions
+}
+
+// deployedVersions returns the versions of the deployed components, only the
+// components whose versions follow the version of the cluster are included
+func deployedVersions(topo spec.Topology, base *spec.BaseMeta) map[string]string {
+	versions := make(map[string]string)
+	for _, comp := range topo.ComponentsByUpdateOrder() {
+		if _, ok := versionCommands[comp.Name()]; ok && len(comp.Instances()) > 0 {
+			versions[comp.Name()] = base.ComponentVersion(comp.Name())
+		}
+	}
+	return versions
+}
+
+// componentVersions returns the version of the cluster and the versions of
+// the components apart from it after upgrading
+func (s *upgradeScope) componentVersions(base *spec.BaseMeta, target string) (string, map[string]string) {
+	if !s.partial() {
+		return target, nil
+	}
+	versions := s.versions(base, target)
+	clusterVersion := base.Version
+	if allOf(versions, target) {
+		clusterVersion = target
+	}
+	apart := make(map[string]string)
+	for comp, v := range versions {
+		if v != clusterVersion {
+			apart[comp] = v
+		}
+	}
+	if len(apart) == 0 {
+		return clusterVersion, nil
+	}
+	return clusterVersion, apart
+}
+
+func allOf(versions map[string]string, v string) bool {
+	for _, cv := range versions {
+		if cv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// mixedVersionWarnings returns the pairs of components whose versions are of
+// different minor versions, the components are only tested together within
+// a minor version
+func mixedVersionWarnings(versions map[string]string) []string {
+	comps := make([]string, 0, len(versions))
+	for comp := range versions {
+		comps = append(comps, comp)
+	}
+	sort.Strings(comps)
+
+	var warnings []string
+	for i, a := range comps {
+		for _, b := range comps[i+1:] {
+			va, vb := versions[a], versions[b]
+			pa, erra := version.Parse(va)
+			pb, errb := version.Parse(vb)
+			if erra != nil || errb != nil || pa.IsNightly() || pb.IsNightly() {
+				continue
+			}
+			if pa.MajorMinor() != pb.MajorMinor() {
+				warnings = append(warnings, fmt.Sprintf("%s %s and %s %s are of different minor versions", a, va, b, vb))
+			}
+		}
+	}
+	return warnings
+}
+
+// formatComponentVersions formats the versions as "comp:version" sorted by
+// the components
+func formatComponentVersions(versions map[string]string) string {
+	items := make([]string, 0, len(versions))
+	for comp, v := range versions {
+		items = append(items, comp+":"+v)
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+// componentBindVersion binds the versions of the components to the ones in
+// the metadata, the version passed in is ignored, as the components may be
+// upgraded apart from each other
+func (m *Manager) componentBindVersion(base *spec.BaseMeta) spec.BindVersion {
+	return func(comp, _ string) string {
+		return m.bindVersion(comp, base.ComponentVersion(comp))
+	}
+}
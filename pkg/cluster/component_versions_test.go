@@ -0,0 +1,82 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUpgradeScope(t *testing.T) {
+	topo := new(spec.Specification)
+	require.Nil(t, yaml.UnmarshalStrict([]byte(`
+pd_servers:
+  - host: 172.16.5.1
+tikv_servers:
+  - host: 172.16.5.1
+tidb_servers:
+  - host: 172.16.5.2
+`), topo))
+	base := &spec.BaseMeta{Version: "v4.0.8"}
+
+	_, err := newUpgradeScope(topo, []string{"tiflash"})
+	assert.NotNil(t, err)
+
+	// only tidb is upgraded, the storage is left as is
+	scope, err := newUpgradeScope(topo, []string{"tidb"})
+	require.Nil(t, err)
+	assert.Nil(t, scope.checkVersions(base, "v4.0.9"))
+	assert.NotNil(t, scope.checkVersions(base, "v4.0.8"))
+	var upgraded []string
+	scope.IterInstance(func(inst spec.Instance) {
+		upgraded = append(upgraded, inst.ID())
+	})
+	assert.Equal(t, []string{"172.16.5.2:4000"}, upgraded)
+	assert.Empty(t, mixedVersionWarnings(scope.versions(base, "v4.0.9")))
+	assert.Equal(t, []string{
+		"pd v4.0.8 and tidb v5.0.0 are of different minor versions",
+		"tidb v5.0.0 and tikv v4.0.8 are of different minor versions",
+	}, mixedVersionWarnings(scope.versions(base, "v5.0.0")))
+
+	version, versions := scope.componentVersions(base, "v4.0.9")
+	assert.Equal(t, "v4.0.8", version)
+	assert.Equal(t, map[string]string{"tidb": "v4.0.9"}, versions)
+	base.ComponentVersions = versions
+	assert.Equal(t, "v4.0.9", base.ComponentVersion("tidb"))
+	assert.Equal(t, "v4.0.8", base.ComponentVersion("tikv"))
+	assert.Equal(t, "pd:v4.0.8,tidb:v4.0.9,tikv:v4.0.8", formatComponentVersions(deployedVersions(topo, base)))
+
+	// tidb is already of the version
+	assert.NotNil(t, scope.checkVersions(base, "v4.0.9"))
+
+	// the storage catches up, the cluster is of a single version again
+	scope, err = newUpgradeScope(topo, []string{"pd", "tikv"})
+	require.Nil(t, err)
+	version, versions = scope.componentVersions(base, "v4.0.9")
+	assert.Equal(t, "v4.0.9", version)
+	assert.Nil(t, versions)
+
+	// upgrading all the components can't downgrade the ones upgraded apart
+	scope, err = newUpgradeScope(topo, nil)
+	require.Nil(t, err)
+	assert.NotNil(t, scope.checkVersions(base, "v4.0.8"))
+	assert.Nil(t, scope.checkVersions(base, "v4.0.10"))
+	version, versions = scope.componentVersions(base, "v4.0.10")
+	assert.Equal(t, "v4.0.10", version)
+	assert.Nil(t, versions)
+}
@@ -0,0 +1,196 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+)
+
+// ConfigAction is what an instance needs for a config change to take effect
+type ConfigAction string
+
+// the actions of instances for config changes
+const (
+	// ConfigActionNone means the config of the instance isn't changed
+	ConfigActionNone ConfigAction = "none"
+	// ConfigActionReload means all the changed keys can be changed online by
+	// the config API of the component, e.g. pd-ctl, instead of restarting.
+	// The reload command still restarts the instance unless --skip-restart.
+	ConfigActionReload ConfigAction = "reload"
+	// ConfigActionRestart means the instance must be restarted
+	ConfigActionRestart ConfigAction = "restart"
+)
+
+// ConfigKeyChange is a changed config key of an instance, the key of a field
+// of the instance spec other than its config is prefixed by "spec."
+type ConfigKeyChange struct {
+	Key       string      `json:"key"`
+	From      interface{} `json:"from,omitempty"`
+	To        interface{} `json:"to,omitempty"`
+	HotReload bool        `json:"hot_reload"`
+}
+
+// InstanceImpact is the impact of a config change on an instance
+type InstanceImpact struct {
+	ID      string            `json:"id"`
+	Role    string            `json:"role"`
+	Host    string            `json:"host"`
+	Changes []ConfigKeyChange `json:"changes,omitempty"`
+	Action  ConfigAction      `json:"action"`
+}
+
+// ImpactReport is the impact of a config change on the instances of a
+// cluster, with the numbers of instances by the actions they need
+type ImpactReport struct {
+	Instances []InstanceImpact `json:"instances"`
+	Restart   int              `json:"restart"`
+	Reload    int              `json:"reload"`
+	None      int              `json:"none"`
+}
+
+// PlanConfigChange reports which instances of the cluster have their config
+// changed by the new topology, and whether they need to be restarted
+func (m *Manager) PlanConfigChange(name string, newTopo spec.Topology) (*ImpactReport, error) {
+	metadata, err := m.meta(name)
+	if err != nil {
+		return nil, err
+	}
+	return configImpact(metadata.GetTopology(), newTopo)
+}
+
+// configImpact compares the effective config of each instance in both
+// topologies, the instances not in both are ignored
+func configImpact(origTopo, newTopo spec.Topology) (*ImpactReport, error) {
+	type configs struct {
+		config, fields map[string]interface{}
+	}
+	orig := make(map[string]configs)
+	var firstErr error
+	origTopo.IterInstance(func(inst spec.Instance) {
+		config, fields, err := spec.InstanceConfigs(origTopo, inst)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		orig[inst.ID()] = configs{config, fields}
+	})
+
+	report := &ImpactReport{}
+	newTopo.IterInstance(func(inst spec.Instance) {
+		o, ok := orig[inst.ID()]
+		if !ok {
+			return
+		}
+		config, fields, err := spec.InstanceConfigs(newTopo, inst)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+
+		impact := InstanceImpact{
+			ID:     inst.ID(),
+			Role:   inst.Role(),
+			Host:   inst.GetHost(),
+			Action: ConfigActionNone,
+		}
+		for _, c := range diffConfigs(o.config, config) {
+			c.HotReload = spec.IsHotReloadable(inst.ComponentName(), c.Key)
+			impact.Changes = append(impact.Changes, c)
+		}
+		for _, c := range diffConfigs(o.fields, fields) {
+			c.Key = "spec." + c.Key
+			impact.Changes = append(impact.Changes, c)
+		}
+		for _, c := range impact.Changes {
+			if !c.HotReload {
+				impact.Action = ConfigActionRestart
+				break
+			}
+			impact.Action = ConfigActionReload
+		}
+
+		switch impact.Action {
+		case ConfigActionRestart:
+			report.Restart++
+		case ConfigActionReload:
+			report.Reload++
+		default:
+			report.None++
+		}
+		report.Instances = append(report.Instances, impact)
+	})
+	if firstErr != nil {
+		if errorx.Cast(firstErr) != nil {
+			return nil, firstErr
+		}
+		return nil, perrs.Trace(firstErr)
+	}
+	return report, nil
+}
+
+// diffConfigs returns the keys added, removed or changed, sorted by keys
+func diffConfigs(orig, changed map[string]interface{}) []ConfigKeyChange {
+	var changes []ConfigKeyChange
+	for k, v := range changed {
+		if ov, ok := orig[k]; !ok || !reflect.DeepEqual(ov, v) {
+			changes = append(changes, ConfigKeyChange{Key: k, From: orig[k], To: v})
+		}
+	}
+	for k, v := range orig {
+		if _, ok := changed[k]; !ok {
+			changes = append(changes, ConfigKeyChange{Key: k, From: v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// printImpactReport prints the instances affected by a config change
+func printImpactReport(report *ImpactReport) {
+	rows := [][]string{{"Instance", "Role", "Action", "Changed Keys"}}
+	for _, impact := range report.Instances {
+		if impact.Action == ConfigActionNone {
+			continue
+		}
+		var keys []string
+		for _, c := range impact.Changes {
+			key := c.Key
+			if c.HotReload {
+				key += " (online-capable)"
+			}
+			keys = append(keys, key)
+		}
+		action := color.RedString(string(ConfigActionRestart))
+		if impact.Action == ConfigActionReload {
+			action = color.YellowString("restart, or change online")
+		}
+		rows = append(rows, []string{impact.ID, impact.Role, action, strings.Join(keys, ", ")})
+	}
+	if len(rows) > 1 {
+		cliutil.PrintTable(rows, true)
+	}
+	fmt.Printf("%d instance(s) to be restarted by reload, %d of them can change the keys online instead via\n"+
+		"the config API after `reload --skip-restart`, %d not affected\n",
+		report.Restart+report.Reload, report.Reload, report.None)
+}
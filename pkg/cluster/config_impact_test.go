@@ -0,0 +1,113 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestPlanConfigChange(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+	parse := func(data string) *spec.Specification {
+		topo := new(spec.Specification)
+		require.Nil(t, yaml.UnmarshalStrict([]byte(data), topo))
+		return topo
+	}
+	orig := parse(`
+server_configs:
+  tikv:
+    raftstore.sync-log: true
+pd_servers:
+  - host: 172.16.5.1
+tikv_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.2
+    config:
+      storage.block-cache.capacity: 4GB
+  - host: 172.16.5.3
+    config:
+      raftstore.sync-log: false
+`)
+	require.Nil(t, m.specManager.SaveMeta("test", &spec.ClusterMeta{User: "tidb", Version: "v4.0.0", Topology: orig}))
+
+	// hot-reloadable globally, but overridden by the third instance
+	report, err := m.PlanConfigChange("test", parse(`
+server_configs:
+  tikv:
+    raftstore.sync-log: false
+pd_servers:
+  - host: 172.16.5.1
+tikv_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.2
+    config:
+      storage.block-cache.capacity: 4GB
+      server.grpc-concurrency: 8
+  - host: 172.16.5.3
+    config:
+      raftstore.sync-log: false
+`))
+	require.Nil(t, err)
+	actions := make(map[string]InstanceImpact)
+	for _, impact := range report.Instances {
+		actions[impact.ID] = impact
+	}
+	assert.Equal(t, ConfigActionNone, actions["172.16.5.1:2379"].Action)
+	assert.Equal(t, ConfigActionReload, actions["172.16.5.1:20160"].Action)
+	assert.Equal(t, []ConfigKeyChange{{Key: "raftstore.sync-log", From: true, To: false, HotReload: true}},
+		actions["172.16.5.1:20160"].Changes)
+	assert.Equal(t, ConfigActionRestart, actions["172.16.5.2:20160"].Action)
+	require.Len(t, actions["172.16.5.2:20160"].Changes, 2)
+	assert.Equal(t, "server.grpc-concurrency", actions["172.16.5.2:20160"].Changes[1].Key)
+	assert.False(t, actions["172.16.5.2:20160"].Changes[1].HotReload)
+	assert.Equal(t, ConfigActionNone, actions["172.16.5.3:20160"].Action)
+	assert.Equal(t, 1, report.Restart)
+	assert.Equal(t, 1, report.Reload)
+	assert.Equal(t, 2, report.None)
+
+	// the fields of instances other than config need restarting
+	report, err = configImpact(orig, parse(`
+server_configs:
+  tikv:
+    raftstore.sync-log: true
+pd_servers:
+  - host: 172.16.5.1
+    numa_node: "0"
+tikv_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.2
+    config:
+      storage.block-cache.capacity: 4GB
+  - host: 172.16.5.3
+    config:
+      raftstore.sync-log: false
+`))
+	require.Nil(t, err)
+	assert.Equal(t, "spec.numa_node", report.Instances[0].Changes[0].Key)
+	assert.Equal(t, ConfigActionRestart, report.Instances[0].Action)
+	assert.Equal(t, 1, report.Restart)
+
+	// only the keys known to be changeable online are hot-reloadable
+	assert.True(t, spec.IsHotReloadable(spec.ComponentTiKV, "rocksdb.writecf.block-cache-size"))
+	assert.True(t, spec.IsHotReloadable(spec.ComponentPD, "schedule.leader-schedule-limit"))
+	assert.False(t, spec.IsHotReloadable(spec.ComponentTiKV, "raftstore.store-pool-size"))
+	assert.False(t, spec.IsHotReloadable(spec.ComponentTiKV, "server.grpc-memory-pool-quota"))
+	assert.False(t, spec.IsHotReloadable(spec.ComponentPD, "pd-server.use-region-storage"))
+}
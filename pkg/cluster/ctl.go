@@ -0,0 +1,206 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/environment"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/meta"
+	"github.com/pingcap/tiup/pkg/repository/v0manifest"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+var (
+	errNSCtl = errorx.NewNamespace("ctl")
+	// ErrCtlNotSupported is returned when the ctl of a component is not supported
+	ErrCtlNotSupported = errNSCtl.NewType("not_supported", errutil.ErrTraitPreCheck)
+)
+
+// the ctl component containing the controllers of all components
+const ctlComponent = "ctl"
+
+// the files of TLS material of a cluster, under the tls directory of its metadata
+const (
+	ctlTLSCACert     = "ca.crt"
+	ctlTLSClientCert = "client.crt"
+	ctlTLSClientKey  = "client.pem"
+)
+
+// ctlSpec describes how to run the ctl of a component
+type ctlSpec struct {
+	binary []string // the binary and its sub command in the ctl component
+	// the flag to specify endpoints, and how to build the arguments specifying
+	// endpoints from the topology
+	endpointFlag string
+	endpoint     func(topo *spec.Specification) []string
+	// the flags to specify CA certificate, client certificate and client key
+	tlsFlags [3]string
+}
+
+// pdEndpoint returns a function building the arguments of flag specifying
+// the PD endpoints, with the URL scheme if withScheme is set
+func pdEndpoint(flag string, withScheme bool) func(topo *spec.Specification) []string {
+	return func(topo *spec.Specification) []string {
+		addrs := topo.GetPDList()
+		if len(addrs) == 0 {
+			return nil
+		}
+		if withScheme {
+			for i := range addrs {
+				addrs[i] = "http://" + addrs[i]
+			}
+		}
+		return []string{flag, strings.Join(addrs, ",")}
+	}
+}
+
+var ctlSpecs = map[string]ctlSpec{
+	spec.ComponentPD: {
+		binary:       []string{"pd-ctl"},
+		endpointFlag: "-u",
+		endpoint:     pdEndpoint("-u", true),
+		tlsFlags:     [3]string{"--cacert", "--cert", "--key"},
+	},
+	spec.ComponentTiKV: {
+		binary:       []string{"tikv-ctl"},
+		endpointFlag: "--pd",
+		endpoint:     pdEndpoint("--pd", false),
+		tlsFlags:     [3]string{"--ca-path", "--cert-path", "--key-path"},
+	},
+	spec.ComponentTiDB: {
+		binary:       []string{"tidb-ctl"},
+		endpointFlag: "--host",
+		endpoint: func(topo *spec.Specification) []string {
+			if len(topo.TiDBServers) == 0 {
+				return nil
+			}
+			return []string{"--host", topo.TiDBServers[0].Host, "--port", fmt.Sprintf("%d", topo.TiDBServers[0].StatusPort)}
+		},
+		tlsFlags: [3]string{"--ca", "--ssl-cert", "--ssl-key"},
+	},
+	"etcd": {
+		binary:       []string{"etcdctl"},
+		endpointFlag: "--endpoints",
+		endpoint:     pdEndpoint("--endpoints", true),
+		tlsFlags:     [3]string{"--cacert", "--cert", "--key"},
+	},
+	"binlog": {
+		binary:       []string{"binlogctl"},
+		endpointFlag: "-pd-urls",
+		endpoint:     pdEndpoint("-pd-urls", true),
+		tlsFlags:     [3]string{"-ssl-ca", "-ssl-cert", "-ssl-key"},
+	},
+	spec.ComponentCDC: {
+		binary:       []string{"cdc", "cli"},
+		endpointFlag: "--pd",
+		endpoint:     pdEndpoint("--pd", true),
+		tlsFlags:     [3]string{"--ca", "--cert", "--key"},
+	},
+}
+
+// hasFlag returns whether flag is specified in args
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag || strings.HasPrefix(arg, flag+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// Ctl runs the ctl of component against the cluster, the ctl matching the
+// version of the cluster is used, the endpoints and TLS material of the
+// cluster are injected unless they are specified in args. The stdio of the
+// ctl is streamed to the current process.
+func (m *Manager) Ctl(clusterName, component string, args []string) error {
+	cs, ok := ctlSpecs[component]
+	if !ok {
+		supported := make([]string, 0, len(ctlSpecs))
+		for name := range ctlSpecs {
+			supported = append(supported, name)
+		}
+		sort.Strings(supported)
+		return ErrCtlNotSupported.
+			New("The ctl of '%s' is not supported", component).
+			WithProperty(cliutil.SuggestionFromFormat("Supported components are: %s", strings.Join(supported, ", ")))
+	}
+
+	metadata, err := m.meta(clusterName)
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+		return err
+	}
+	topo, ok := metadata.GetTopology().(*spec.Specification)
+	if !ok {
+		return ErrCtlNotSupported.New("The ctl is only supported for TiDB clusters")
+	}
+
+	// the ctl of the cluster version must be installed
+	env := environment.GlobalEnv()
+	version := v0manifest.Version(metadata.GetBaseMeta().Version)
+	installed, err := env.Profile().VersionIsInstalled(ctlComponent, version.String())
+	if err != nil {
+		return err
+	}
+	if !installed {
+		if err := cliutil.PromptForConfirmOrAbortError(
+			"The component `%s:%s` required by the cluster is not installed.\nDo you want to install it? [y/N]:",
+			ctlComponent, version); err != nil {
+			return err
+		}
+		if _, err := env.DownloadComponentIfMissing(ctlComponent, version); err != nil {
+			return err
+		}
+	}
+	installPath, err := env.Profile().ComponentInstalledPath(ctlComponent, version)
+	if err != nil {
+		return err
+	}
+
+	tlsDir := m.specManager.Path(clusterName, "tls")
+	tlsEnabled := utils.IsExist(filepath.Join(tlsDir, ctlTLSCACert))
+
+	ctlArgs := append([]string{}, cs.binary[1:]...)
+	if !hasFlag(args, cs.endpointFlag) {
+		for _, arg := range cs.endpoint(topo) {
+			if tlsEnabled {
+				arg = strings.Replace(arg, "http://", "https://", -1)
+			}
+			ctlArgs = append(ctlArgs, arg)
+		}
+	}
+	if tlsEnabled && !hasFlag(args, cs.tlsFlags[0]) {
+		for i, file := range []string{ctlTLSCACert, ctlTLSClientCert, ctlTLSClientKey} {
+			ctlArgs = append(ctlArgs, cs.tlsFlags[i], filepath.Join(tlsDir, file))
+		}
+	}
+	ctlArgs = append(ctlArgs, args...)
+
+	cmd := exec.Command(filepath.Join(installPath, cs.binary[0]), ctlArgs...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
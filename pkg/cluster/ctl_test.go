@@ -0,0 +1,87 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/environment"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCtl(t *testing.T) {
+	m, _, _, cleanup := newTestMockCluster(t, 2)
+	defer cleanup()
+	dir, err := ioutil.TempDir("", "tiup-ctl-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer environment.SetGlobalEnv(environment.GlobalEnv())
+	environment.SetGlobalEnv(environment.NewV0(localdata.NewProfile(dir, &localdata.TiUPConfig{}),
+		&repository.Repository{Options: repository.Options{GOOS: "linux", GOARCH: "amd64"}}))
+
+	err = m.Ctl("mock", "tiflash", nil)
+	assert.True(t, errorx.IsOfType(err, ErrCtlNotSupported))
+
+	// the ctl of the cluster version isn't installed, and the user refuses
+	// to install it
+	stdin, err := ioutil.TempFile(dir, "stdin")
+	require.Nil(t, err)
+	_, err = stdin.WriteString("n\n")
+	require.Nil(t, err)
+	_, err = stdin.Seek(0, 0)
+	require.Nil(t, err)
+	defer func(f *os.File) { os.Stdin = f }(os.Stdin)
+	os.Stdin = stdin
+	err = m.Ctl("mock", "pd", []string{"store"})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Operation aborted by user")
+
+	// the ctls installed record the arguments they're run with
+	argsFile := filepath.Join(dir, "args")
+	ctlDir := filepath.Join(dir, localdata.ComponentParentDir, ctlComponent, "v4.0.0")
+	require.Nil(t, os.MkdirAll(ctlDir, 0755))
+	for _, bin := range []string{"pd-ctl", "tikv-ctl"} {
+		require.Nil(t, ioutil.WriteFile(filepath.Join(ctlDir, bin), []byte("#!/bin/sh\necho \"$@\" > "+argsFile+"\n"), 0755))
+	}
+	ctl := func(component string, args ...string) string {
+		require.Nil(t, m.Ctl("mock", component, args))
+		data, err := ioutil.ReadFile(argsFile)
+		require.Nil(t, err)
+		return strings.TrimSpace(string(data))
+	}
+	assert.Equal(t, "-u http://mock-1:2379,http://mock-2:2379 store", ctl("pd", "store"))
+	assert.Equal(t, "--pd mock-1:2379,mock-2:2379 raft region", ctl("tikv", "raft", "region"))
+	// the endpoints specified are not overridden
+	assert.Equal(t, "-u=http://mock-2:2379 store", ctl("pd", "-u=http://mock-2:2379", "store"))
+
+	// the TLS material of the cluster is injected if it's enabled
+	tlsDir := m.specManager.Path("mock", "tls")
+	require.Nil(t, os.MkdirAll(tlsDir, 0755))
+	for _, file := range []string{ctlTLSCACert, ctlTLSClientCert, ctlTLSClientKey} {
+		require.Nil(t, ioutil.WriteFile(filepath.Join(tlsDir, file), nil, 0644))
+	}
+	assert.Equal(t, "-u https://mock-1:2379,https://mock-2:2379"+
+		" --cacert "+filepath.Join(tlsDir, ctlTLSCACert)+
+		" --cert "+filepath.Join(tlsDir, ctlTLSClientCert)+
+		" --key "+filepath.Join(tlsDir, ctlTLSClientKey)+" store", ctl("pd", "store"))
+	assert.Equal(t, "--pd mock-1:2379,mock-2:2379 --ca-path /ca.crt raft region", ctl("tikv", "--ca-path", "/ca.crt", "raft", "region"))
+}
@@ -0,0 +1,226 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/logger/log"
+)
+
+var (
+	errNSDiskUsage = errorx.NewNamespace("disk_usage")
+	// ErrDataDirFull is returned when the filesystem of a data directory is
+	// used above the hard threshold on starting the instance
+	ErrDataDirFull = errNSDiskUsage.NewType("data_dir_full", errutil.ErrTraitPreCheck)
+)
+
+// the levels of the usage of data directories
+const (
+	DiskUsageOK   = "ok"
+	DiskUsageWarn = "warn"
+	DiskUsageFail = "fail"
+)
+
+// DataDirUsage is the usage of the filesystem of a data directory of an instance
+type DataDirUsage struct {
+	Instance    string  `json:"instance"`
+	Dir         string  `json:"dir"`
+	MountPoint  string  `json:"mount_point"`
+	SizeBytes   uint64  `json:"size_bytes"`
+	AvailBytes  uint64  `json:"avail_bytes"`
+	UsedPercent float64 `json:"used_percent"`
+	Level       string  `json:"level"`
+}
+
+// StartResult is the structured result of starting a cluster
+type StartResult struct {
+	DataDirUsage []DataDirUsage                     `json:"data_dir_usage,omitempty"`
+	Grafana      []*operator.GrafanaProvisionReport `json:"grafana,omitempty"`
+	// the total time the instances waited for their turns to start, see
+	// operator.Options.StartStagger
+	StaggerDelay time.Duration `json:"stagger_delay,omitempty"`
+}
+
+// checkDataDirUsage appends the steps measuring the usage of the filesystems
+// of the data directories of the instances to start, the instances above the
+// thresholds of options are warned, or refused to start unless forced. The
+// measurements are appended to usages. It returns false if there is nothing
+// to check.
+func checkDataDirUsage(b *task.Builder, deployUser string, topo spec.Topology, options operator.Options, usages *[]DataDirUsage) bool {
+	if options.DiskUsageWarn <= 0 && options.DiskUsageFail <= 0 {
+		return false
+	}
+
+	dirs := make(map[string]map[string][]string) // host -> instance -> data dirs
+	for _, inst := range selectInstances(topo, options) {
+		if inst.DataDir() == "" {
+			continue
+		}
+		if dirs[inst.GetHost()] == nil {
+			dirs[inst.GetHost()] = make(map[string][]string)
+		}
+		dirs[inst.GetHost()][inst.ID()] = clusterutil.MultiDirAbs(deployUser, inst.DataDir())
+	}
+	if len(dirs) == 0 {
+		return false
+	}
+
+	var mu sync.Mutex
+	var steps []*task.StepDisplay
+	for host, instDirs := range dirs {
+		host, instDirs := host, instDirs
+		steps = append(steps, task.NewBuilder().
+			Func(fmt.Sprintf("CheckDataDirUsage %s", host), func(ctx *task.Context) error {
+				e, ok := ctx.GetExecutor(host)
+				if !ok {
+					return task.ErrNoExecutor
+				}
+				for id, dirs := range instDirs {
+					for _, dir := range dirs {
+						// measure the exact path, the data directory may be a mount point
+						stdout, stderr, err := e.Execute(fmt.Sprintf("df -Pk '%s'", dir), false)
+						if err != nil {
+							log.Warnf("Failed to measure the usage of %s on %s: %s", dir, host, strings.TrimSpace(string(stderr)))
+							continue
+						}
+						usage, err := parseDataDirUsage(stdout)
+						if err != nil {
+							return perrs.Annotatef(err, "failed to measure the usage of %s on %s", dir, host)
+						}
+						usage.Instance, usage.Dir = id, dir
+						usage.Level = diskUsageLevel(usage.UsedPercent, options)
+						mu.Lock()
+						*usages = append(*usages, usage)
+						mu.Unlock()
+					}
+				}
+				return nil
+			}).
+			BuildAsStep(fmt.Sprintf("  - Checking the usage of data directories on %s", host)))
+	}
+
+	b.ParallelStep("+ Check the usage of data directories", steps...).
+		Func("CheckDataDirUsageThreshold", func(ctx *task.Context) error {
+			sort.Slice(*usages, func(i, j int) bool {
+				if (*usages)[i].Instance != (*usages)[j].Instance {
+					return (*usages)[i].Instance < (*usages)[j].Instance
+				}
+				return (*usages)[i].Dir < (*usages)[j].Dir
+			})
+			return evaluateDataDirUsage(*usages, options)
+		})
+	return true
+}
+
+// parseDataDirUsage parses the output of `df -Pk`, the used percentage is
+// computed as df does, the space reserved for root isn't taken as available
+func parseDataDirUsage(output []byte) (DataDirUsage, error) {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) < 2 {
+		return DataDirUsage{}, perrs.Errorf("unexpected output of df: %s", output)
+	}
+	// Filesystem 1024-blocks Used Available Capacity Mounted-on
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 6 {
+		return DataDirUsage{}, perrs.Errorf("unexpected output of df: %s", output)
+	}
+	size, err1 := strconv.ParseUint(fields[1], 10, 64)
+	used, err2 := strconv.ParseUint(fields[2], 10, 64)
+	avail, err3 := strconv.ParseUint(fields[3], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return DataDirUsage{}, perrs.Errorf("unexpected output of df: %s", output)
+	}
+
+	usage := DataDirUsage{
+		MountPoint: strings.Join(fields[5:], " "),
+		SizeBytes:  size * 1024,
+		AvailBytes: avail * 1024,
+	}
+	if used+avail > 0 {
+		usage.UsedPercent = float64(used) * 100 / float64(used+avail)
+	}
+	return usage, nil
+}
+
+// diskUsageLevel returns the level of a used percentage against the thresholds
+func diskUsageLevel(percent float64, options operator.Options) string {
+	switch {
+	case options.DiskUsageFail > 0 && percent >= float64(options.DiskUsageFail):
+		return DiskUsageFail
+	case options.DiskUsageWarn > 0 && percent >= float64(options.DiskUsageWarn):
+		return DiskUsageWarn
+	default:
+		return DiskUsageOK
+	}
+}
+
+// evaluateDataDirUsage prints the data directories above the thresholds, an
+// error is returned if any is above the hard one and options.Force isn't set
+func evaluateDataDirUsage(usages []DataDirUsage, options operator.Options) error {
+	rows := [][]string{{"Instance", "Data Dir", "Mount Point", "Used", "Available", "Level"}}
+	var failed []string
+	for _, u := range usages {
+		if u.Level == DiskUsageOK {
+			continue
+		}
+		level := color.YellowString(u.Level)
+		if u.Level == DiskUsageFail {
+			level = color.RedString(u.Level)
+			// the usages are sorted by instances
+			if len(failed) == 0 || failed[len(failed)-1] != u.Instance {
+				failed = append(failed, u.Instance)
+			}
+		}
+		rows = append(rows, []string{
+			u.Instance,
+			u.Dir,
+			u.MountPoint,
+			fmt.Sprintf("%.1f%%", u.UsedPercent),
+			fmt.Sprintf("%.1f GiB", float64(u.AvailBytes)/1024/1024/1024),
+			level,
+		})
+	}
+	if len(rows) == 1 {
+		return nil
+	}
+
+	log.Warnf("The filesystems of some data directories are nearly full:")
+	cliutil.PrintTable(rows, true)
+	if len(failed) == 0 {
+		return nil
+	}
+	if options.Force {
+		log.Warnf("Starting %d instance(s) with data directories used above %d%% as forced", len(failed), options.DiskUsageFail)
+		return nil
+	}
+	return ErrDataDirFull.New("The data directories of %s are used above %d%%, the instances may fail to start",
+		strings.Join(failed, ", "), options.DiskUsageFail).
+		WithProperty(cliutil.SuggestionFromString(
+			"Free up space on the disks, or start the instances anyway by adding --force,\nor raise the threshold by --disk-usage-fail."))
+}
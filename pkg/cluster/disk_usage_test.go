@@ -0,0 +1,59 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/joomcode/errorx"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataDirUsage(t *testing.T) {
+	usage, err := parseDataDirUsage([]byte(`Filesystem     1024-blocks     Used Available Capacity Mounted on
+/dev/nvme0n1     103081248 92773120  10308128      91% /data 1
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "/data 1", usage.MountPoint)
+	assert.Equal(t, uint64(103081248*1024), usage.SizeBytes)
+	assert.Equal(t, uint64(10308128*1024), usage.AvailBytes)
+	assert.InDelta(t, 90.0, usage.UsedPercent, 0.01)
+
+	_, err = parseDataDirUsage([]byte("df: /data: No such file or directory\n"))
+	assert.Error(t, err)
+
+	opt := operator.Options{DiskUsageWarn: 85, DiskUsageFail: 95}
+	assert.Equal(t, DiskUsageOK, diskUsageLevel(84.9, opt))
+	assert.Equal(t, DiskUsageWarn, diskUsageLevel(85, opt))
+	assert.Equal(t, DiskUsageFail, diskUsageLevel(97, opt))
+	assert.Equal(t, DiskUsageOK, diskUsageLevel(97, operator.Options{}))
+
+	usages := []DataDirUsage{
+		{Instance: "tikv-1", Dir: "/data1", UsedPercent: 96, Level: DiskUsageFail},
+		{Instance: "tikv-1", Dir: "/data2", UsedPercent: 97, Level: DiskUsageFail},
+		{Instance: "tikv-2", Dir: "/data1", UsedPercent: 88, Level: DiskUsageWarn},
+	}
+	assert.NoError(t, evaluateDataDirUsage(usages[2:], opt))
+	err = evaluateDataDirUsage(usages, opt)
+	require.Error(t, err)
+	assert.True(t, errorx.IsOfType(err, ErrDataDirFull))
+	assert.Contains(t, err.Error(), "tikv-1 are")
+	opt.Force = true
+	assert.NoError(t, evaluateDataDirUsage(usages, opt))
+
+	fail := int64(101)
+	assert.Error(t, (&OptionDefaults{DiskUsageFail: &fail}).Validate())
+}
@@ -22,7 +22,7 @@ func init() {
 	autogenFiles["/templates/config/datasource.yml.tpl"] = "YXBpVmVyc2lvbjogMQpkZWxldGVEYXRhc291cmNlczoKICAtIG5hbWU6IHt7LkNsdXN0ZXJOYW1lfX0KZGF0YXNvdXJjZXM6CiAgLSBuYW1lOiB7ey5DbHVzdGVyTmFtZX19CiAgICB0eXBlOiBwcm9tZXRoZXVzCiAgICBhY2Nlc3M6IHByb3h5CiAgICB1cmw6IGh0dHA6Ly97ey5JUH19Ont7LlBvcnR9fQogICAgd2l0aENyZWRlbnRpYWxzOiBmYWxzZQogICAgaXNEZWZhdWx0OiBmYWxzZQogICAgdGxzQXV0aDogZmFsc2UKICAgIHRsc0F1dGhXaXRoQ0FDZXJ0OiBmYWxzZQogICAgdmVyc2lvbjogMQogICAgZWRpdGFibGU6IHRydWU="
 	autogenFiles["/templates/config/dm/prometheus.yml.tpl"] = "LS0tCmdsb2JhbDoKICBzY3JhcGVfaW50ZXJ2YWw6ICAgICAxNXMgIyBCeSBkZWZhdWx0LCBzY3JhcGUgdGFyZ2V0cyBldmVyeSAxNSBzZWNvbmRzLgogIGV2YWx1YXRpb25faW50ZXJ2YWw6IDE1cyAjIEJ5IGRlZmF1bHQsIHNjcmFwZSB0YXJnZXRzIGV2ZXJ5IDE1IHNlY29uZHMuCiAgIyBzY3JhcGVfdGltZW91dCBpcyBzZXQgdG8gdGhlIGdsb2JhbCBkZWZhdWx0ICgxMHMpLgogIGV4dGVybmFsX2xhYmVsczoKICAgIGNsdXN0ZXI6ICd7ey5DbHVzdGVyTmFtZX19JwogICAgbW9uaXRvcjogInByb21ldGhldXMiCgojIExvYWQgYW5kIGV2YWx1YXRlIHJ1bGVzIGluIHRoaXMgZmlsZSBldmVyeSAnZXZhbHVhdGlvbl9pbnRlcnZhbCcgc2Vjb25kcy4KcnVsZV9maWxlczoKICAtICdkbV93b3JrZXIucnVsZXMueW1sJwogIC0gJ2RtX21hc3Rlci5ydWxlcy55bWwnCgp7ey0gaWYgLkFsZXJ0bWFuYWdlckFkZHJzfX0KYWxlcnRpbmc6CiBhbGVydG1hbmFnZXJzOgogLSBzdGF0aWNfY29uZmlnczoKICAgLSB0YXJnZXRzOgp7ey0gcmFuZ2UgLkFsZXJ0bWFuYWdlckFkZHJzfX0KICAgICAtICd7ey59fScKe3stIGVuZH19Cnt7LSBlbmR9fQoKc2NyYXBlX2NvbmZpZ3M6Cnt7LSBpZiAuTWFzdGVyQWRkcnN9fQogIC0gam9iX25hbWU6ICJkbV9tYXN0ZXIiCiAgICBob25vcl9sYWJlbHM6IHRydWUgIyBkb24ndCBvdmVyd3JpdGUgam9iICYgaW5zdGFuY2UgbGFiZWxzCiAgICBzdGF0aWNfY29uZmlnczoKICAgIC0gdGFyZ2V0czoKICAgIHt7LSByYW5nZSAuTWFzdGVyQWRkcnN9fQogICAgICAgLSAne3sufX0nCiAgICB7ey0gZW5kfX0Ke3stIGVuZH19Cgp7ey0gaWYgLldvcmtlckFkZHJzfX0KICAtIGpvYl9uYW1lOiAiZG1fd29ya2VyIgogICAgaG9ub3JfbGFiZWxzOiB0cnVlICMgZG9uJ3Qgb3ZlcndyaXRlIGpvYiAmIGluc3RhbmNlIGxhYmVscwogICAgc3RhdGljX2NvbmZpZ3M6CiAgICAtIHRhcmdldHM6CiAgICB7ey0gcmFuZ2UgLldvcmtlckFkZHJzfX0KICAgICAgIC0gJ3t7Ln19JwogICAge3stIGVuZH19Cnt7LSBlbmR9fQo="
 	autogenFiles["/templates/config/grafana.ini.tpl"] = "IyMjIyMjIyMjIyMjIyMjIyMjIyMjIEdyYWZhbmEgQ29uZmlndXJhdGlvbiBFeGFtcGxlICMjIyMjIyMjIyMjIyMjIyMjIyMjIwojCiMgRXZlcnl0aGluZyBoYXMgZGVmYXVsdHMgc28geW91IG9ubHkgbmVlZCB0byB1bmNvbW1lbnQgdGhpbmdzIHlvdSB3YW50IHRvCiMgY2hhbmdlCgojIHBvc3NpYmxlIHZhbHVlcyA6IHByb2R1Y3Rpb24sIGRldmVsb3BtZW50CjsgYXBwX21vZGUgPSBwcm9kdWN0aW9uCgojIGluc3RhbmNlIG5hbWUsIGRlZmF1bHRzIHRvIEhPU1ROQU1FIGVudmlyb25tZW50IHZhcmlhYmxlIHZhbHVlIG9yIGhvc3RuYW1lIGlmIEhPU1ROQU1FIHZhciBpcyBlbXB0eQo7IGluc3RhbmNlX25hbWUgPSAke0hPU1ROQU1FfQoKIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIFBhdGhzICMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIwpbcGF0aHNdCiMgUGF0aCB0byB3aGVyZSBncmFmYW5hIGNhbiBzdG9yZSB0ZW1wIGZpbGVzLCBzZXNzaW9ucywgYW5kIHRoZSBzcWxpdGUzIGRiIChpZiB0aGF0IGlzIHVzZWQpCiMKZGF0YSA9IHt7LkRlcGxveURpcn19L2RhdGEKIwojIERpcmVjdG9yeSB3aGVyZSBncmFmYW5hIGNhbiBzdG9yZSBsb2dzCiMKbG9ncyA9IHt7LkRlcGxveURpcn19L2xvZ3MKIwojIERpcmVjdG9yeSB3aGVyZSBncmFmYW5hIHdpbGwgYXV0b21hdGljYWxseSBzY2FuIGFuZCBsb29rIGZvciBwbHVnaW5zCiMKcGx1Z2lucyA9IHt7LkRlcGxveURpcn19L3BsdWdpbnMKIwojIGZvbGRlciB0aGF0IGNvbnRhaW5zIHByb3Zpc2lvbmluZyBjb25maWcgZmlsZXMgdGhhdCBncmFmYW5hIHdpbGwgYXBwbHkgb24gc3RhcnR1cCBhbmQgd2hpbGUgcnVubmluZy4KcHJvdmlzaW9uaW5nID0ge3suRGVwbG95RGlyfX0vcHJvdmlzaW9uaW5nCgojCiMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyBTZXJ2ZXIgIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjCltzZXJ2ZXJdCiMgUHJvdG9jb2wgKGh0dHAgb3IgaHR0cHMpCjtwcm90b2NvbCA9IGh0dHAKCiMgVGhlIGlwIGFkZHJlc3MgdG8gYmluZCB0bywgZW1wdHkgd2lsbCBiaW5kIHRvIGFsbCBpbnRlcmZhY2VzCjtodHRwX2FkZHIgPQoKIyBUaGUgaHR0cCBwb3J0ICB0byB1c2UKaHR0cF9wb3J0ID0ge3suUG9ydH19CgojIFRoZSBwdWJsaWMgZmFjaW5nIGRvbWFpbiBuYW1lIHVzZWQgdG8gYWNjZXNzIGdyYWZhbmEgZnJvbSBhIGJyb3dzZXIKZG9tYWluID0ge3suSVB9fQoKIyBSZWRpcmVjdCB0byBjb3JyZWN0IGRvbWFpbiBpZiBob3N0IGhlYWRlciBkb2VzIG5vdCBtYXRjaCBkb21haW4KIyBQcmV2ZW50cyBETlMgcmViaW5kaW5nIGF0dGFja3MKO2VuZm9yY2VfZG9tYWluID0gZmFsc2UKCiMgVGhlIGZ1bGwgcHVibGljIGZhY2luZyB1cmwKO3Jvb3RfdXJsID0gJShwcm90b2NvbClzOi8vJShkb21haW4pczolKGh0dHBfcG9ydClzLwoKIyBMb2cgd2ViIHJlcXVlc3RzCjtyb3V0ZXJfbG9nZ2luZyA9IGZhbHNlCgojIHRoZSBwYXRoIHJlbGF0aXZlIHdvcmtpbmcgcGF0aAo7c3RhdGljX3Jvb3RfcGF0aCA9IHB1YmxpYwoKIyBlbmFibGUgZ3ppcAo7ZW5hYmxlX2d6aXAgPSBmYWxzZQoKIyBodHRwcyBjZXJ0cyAmIGtleSBmaWxlCjtjZXJ0X2ZpbGUgPQo7Y2VydF9rZXkgPQoKIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIERhdGFiYXNlICMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIwpbZGF0YWJhc2VdCiMgRWl0aGVyICJteXNxbCIsICJwb3N0Z3JlcyIgb3IgInNxbGl0ZTMiLCBpdCdzIHlvdXIgY2hvaWNlCjt0eXBlID0gc3FsaXRlMwo7aG9zdCA9IDEyNy4wLjAuMTozMzA2CjtuYW1lID0gZ3JhZmFuYQo7dXNlciA9IHJvb3QKO3Bhc3N3b3JkID0KCiMgRm9yICJwb3N0Z3JlcyIgb25seSwgZWl0aGVyICJkaXNhYmxlIiwgInJlcXVpcmUiIG9yICJ2ZXJpZnktZnVsbCIKO3NzbF9tb2RlID0gZGlzYWJsZQoKIyBGb3IgInNxbGl0ZTMiIG9ubHksIHBhdGggcmVsYXRpdmUgdG8gZGF0YV9wYXRoIHNldHRpbmcKO3BhdGggPSBncmFmYW5hLmRiCgojIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMgU2Vzc2lvbiAjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMKW3Nlc3Npb25dCiMgRWl0aGVyICJtZW1vcnkiLCAiZmlsZSIsICJyZWRpcyIsICJteXNxbCIsICJwb3N0Z3JlcyIsIGRlZmF1bHQgaXMgImZpbGUiCjtwcm92aWRlciA9IGZpbGUKCiMgUHJvdmlkZXIgY29uZmlnIG9wdGlvbnMKIyBtZW1vcnk6IG5vdCBoYXZlIGFueSBjb25maWcgeWV0CiMgZmlsZTogc2Vzc2lvbiBkaXIgcGF0aCwgaXMgcmVsYXRpdmUgdG8gZ3JhZmFuYSBkYXRhX3BhdGgKIyByZWRpczogY29uZmlnIGxpa2UgcmVkaXMgc2VydmVyIGUuZy4gYGFkZHI9MTI3LjAuMC4xOjYzNzkscG9vbF9zaXplPTEwMCxkYj1ncmFmYW5hYAojIG15c3FsOiBnby1zcWwtZHJpdmVyL215c3FsIGRzbiBjb25maWcgc3RyaW5nLCBlLmcuIGB1c2VyOnBhc3N3b3JkQHRjcCgxMjcuMC4wLjE6MzMwNikvZGF0YWJhc2VfbmFtZWAKIyBwb3N0Z3JlczogdXNlcj1hIHBhc3N3b3JkPWIgaG9zdD1sb2NhbGhvc3QgcG9ydD01NDMyIGRibmFtZT1jIHNzbG1vZGU9ZGlzYWJsZQo7cHJvdmlkZXJfY29uZmlnID0gc2Vzc2lvbnMKCiMgU2Vzc2lvbiBjb29raWUgbmFtZQo7Y29va2llX25hbWUgPSBncmFmYW5hX3Nlc3MKCiMgSWYgeW91IHVzZSBzZXNzaW9uIGluIGh0dHBzIG9ubHksIGRlZmF1bHQgaXMgZmFsc2UKO2Nvb2tpZV9zZWN1cmUgPSBmYWxzZQoKIyBTZXNzaW9uIGxpZmUgdGltZSwgZGVmYXVsdCBpcyA4NjQwMAo7c2Vzc2lvbl9saWZlX3RpbWUgPSA4NjQwMAoKIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIEFuYWx5dGljcyAjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMKW2FuYWx5dGljc10KIyBTZXJ2ZXIgcmVwb3J0aW5nLCBzZW5kcyB1c2FnZSBjb3VudGVycyB0byBzdGF0cy5ncmFmYW5hLm9yZyBldmVyeSAyNCBob3Vycy4KIyBObyBpcCBhZGRyZXNzZXMgYXJlIGJlaW5nIHRyYWNrZWQsIG9ubHkgc2ltcGxlIGNvdW50ZXJzIHRvIHRyYWNrCiMgcnVubmluZyBpbnN0YW5jZXMsIGRhc2hib2FyZCBhbmQgZXJyb3IgY291bnRzLiBJdCBpcyB2ZXJ5IGhlbHBmdWwgdG8gdXMuCiMgQ2hhbmdlIHRoaXMgb3B0aW9uIHRvIGZhbHNlIHRvIGRpc2FibGUgcmVwb3J0aW5nLgo7cmVwb3J0aW5nX2VuYWJsZWQgPSB0cnVlCgojIFNldCB0byBmYWxzZSB0byBkaXNhYmxlIGFsbCBjaGVja3MgdG8gaHR0cHM6Ly9ncmFmYW5hLm5ldAojIGZvciBuZXcgdmVzaW9ucyAoZ3JhZmFuYSBpdHNlbGYgYW5kIHBsdWdpbnMpLCBjaGVjayBpcyB1c2VkCiMgaW4gc29tZSBVSSB2aWV3cyB0byBub3RpZnkgdGhhdCBncmFmYW5hIG9yIHBsdWdpbiB1cGRhdGUgZXhpc3RzCiMgVGhpcyBvcHRpb24gZG9lcyBub3QgY2F1c2UgYW55IGF1dG8gdXBkYXRlcywgbm9yIHNlbmQgYW55IGluZm9ybWF0aW9uCiMgb25seSBhIEdFVCByZXF1ZXN0IHRvIGh0dHA6Ly9ncmFmYW5hLm5ldCB0byBnZXQgbGF0ZXN0IHZlcnNpb25zCmNoZWNrX2Zvcl91cGRhdGVzID0gdHJ1ZQoKIyBHb29nbGUgQW5hbHl0aWNzIHVuaXZlcnNhbCB0cmFja2luZyBjb2RlLCBvbmx5IGVuYWJsZWQgaWYgeW91IHNwZWNpZnkgYW4gaWQgaGVyZQo7Z29vZ2xlX2FuYWx5dGljc191YV9pZCA9CgojIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMgU2VjdXJpdHkgIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjCltzZWN1cml0eV0KIyBkZWZhdWx0IGFkbWluIHVzZXIsIGNyZWF0ZWQgb24gc3RhcnR1cAo7YWRtaW5fdXNlciA9IGFkbWluCgojIGRlZmF1bHQgYWRtaW4gcGFzc3dvcmQsIGNhbiBiZSBjaGFuZ2VkIGJlZm9yZSBmaXJzdCBzdGFydCBvZiBncmFmYW5hLCAgb3IgaW4gcHJvZmlsZSBzZXR0aW5ncwo7YWRtaW5fcGFzc3dvcmQgPSBhZG1pbgoKIyB1c2VkIGZvciBzaWduaW5nCjtzZWNyZXRfa2V5ID0gU1cyWWN3VEliOXpwT09ob1BzTW0KCiMgQXV0by1sb2dpbiByZW1lbWJlciBkYXlzCjtsb2dpbl9yZW1lbWJlcl9kYXlzID0gNwo7Y29va2llX3VzZXJuYW1lID0gZ3JhZmFuYV91c2VyCjtjb29raWVfcmVtZW1iZXJfbmFtZSA9IGdyYWZhbmFfcmVtZW1iZXIKCiMgZGlzYWJsZSBncmF2YXRhciBwcm9maWxlIGltYWdlcwo7ZGlzYWJsZV9ncmF2YXRhciA9IGZhbHNlCgojIGRhdGEgc291cmNlIHByb3h5IHdoaXRlbGlzdCAoaXBfb3JfZG9tYWluOnBvcnQgc2VwYXJhdGVkIGJ5IHNwYWNlcykKO2RhdGFfc291cmNlX3Byb3h5X3doaXRlbGlzdCA9Cgpbc25hcHNob3RzXQojIHNuYXBzaG90IHNoYXJpbmcgb3B0aW9ucwo7ZXh0ZXJuYWxfZW5hYmxlZCA9IHRydWUKO2V4dGVybmFsX3NuYXBzaG90X3VybCA9IGh0dHBzOi8vc25hcHNob3RzLW9yaWdpbi5yYWludGFuay5pbwo7ZXh0ZXJuYWxfc25hcHNob3RfbmFtZSA9IFB1Ymxpc2ggdG8gc25hcHNob3QucmFpbnRhbmsuaW8KCiMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyBVc2VycyAjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMKW3VzZXJzXQojIGRpc2FibGUgdXNlciBzaWdudXAgLyByZWdpc3RyYXRpb24KO2FsbG93X3NpZ25fdXAgPSB0cnVlCgojIEFsbG93IG5vbiBhZG1pbiB1c2VycyB0byBjcmVhdGUgb3JnYW5pemF0aW9ucwo7YWxsb3dfb3JnX2NyZWF0ZSA9IHRydWUKCiMgU2V0IHRvIHRydWUgdG8gYXV0b21hdGljYWxseSBhc3NpZ24gbmV3IHVzZXJzIHRvIHRoZSBkZWZhdWx0IG9yZ2FuaXphdGlvbiAoaWQgMSkKO2F1dG9fYXNzaWduX29yZyA9IHRydWUKCiMgRGVmYXVsdCByb2xlIG5ldyB1c2VycyB3aWxsIGJlIGF1dG9tYXRpY2FsbHkgYXNzaWduZWQgKGlmIGRpc2FibGVkIGFib3ZlIGlzIHNldCB0byB0cnVlKQo7YXV0b19hc3NpZ25fb3JnX3JvbGUgPSBWaWV3ZXIKCiMgQmFja2dyb3VuZCB0ZXh0IGZvciB0aGUgdXNlciBmaWVsZCBvbiB0aGUgbG9naW4gcGFnZQo7bG9naW5faGludCA9IGVtYWlsIG9yIHVzZXJuYW1lCgojIERlZmF1bHQgVUkgdGhlbWUgKCJkYXJrIiBvciAibGlnaHQiKQo7ZGVmYXVsdF90aGVtZSA9IGRhcmsKCiMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyBBbm9ueW1vdXMgQXV0aCAjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIwpbYXV0aC5hbm9ueW1vdXNdCiMgZW5hYmxlIGFub255bW91cyBhY2Nlc3MKO2VuYWJsZWQgPSBmYWxzZQoKIyBzcGVjaWZ5IG9yZ2FuaXphdGlvbiBuYW1lIHRoYXQgc2hvdWxkIGJlIHVzZWQgZm9yIHVuYXV0aGVudGljYXRlZCB1c2Vycwo7b3JnX25hbWUgPSBNYWluIE9yZy4KCiMgc3BlY2lmeSByb2xlIGZvciB1bmF1dGhlbnRpY2F0ZWQgdXNlcnMKO29yZ19yb2xlID0gVmlld2VyCgojIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMgQmFzaWMgQXV0aCAjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIwpbYXV0aC5iYXNpY10KO2VuYWJsZWQgPSB0cnVlCgojIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMgQXV0aCBMREFQICMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjClthdXRoLmxkYXBdCjtlbmFibGVkID0gZmFsc2UKO2NvbmZpZ19maWxlID0gL2V0Yy9ncmFmYW5hL2xkYXAudG9tbAoKIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIFNNVFAgLyBFbWFpbGluZyAjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIwpbc210cF0KO2VuYWJsZWQgPSBmYWxzZQo7aG9zdCA9IGxvY2FsaG9zdDoyNQo7dXNlciA9CjtwYXNzd29yZCA9CjtjZXJ0X2ZpbGUgPQo7a2V5X2ZpbGUgPQo7c2tpcF92ZXJpZnkgPSBmYWxzZQo7ZnJvbV9hZGRyZXNzID0gYWRtaW5AZ3JhZmFuYS5sb2NhbGhvc3QKCltlbWFpbHNdCjt3ZWxjb21lX2VtYWlsX29uX3NpZ25fdXAgPSBmYWxzZQoKIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIExvZ2dpbmcgIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMKW2xvZ10KIyBFaXRoZXIgImNvbnNvbGUiLCAiZmlsZSIsICJzeXNsb2ciLiBEZWZhdWx0IGlzIGNvbnNvbGUgYW5kICBmaWxlCiMgVXNlIHNwYWNlIHRvIHNlcGFyYXRlIG11bHRpcGxlIG1vZGVzLCBlLmcuICJjb25zb2xlIGZpbGUiCm1vZGUgPSBmaWxlCgojIEVpdGhlciAidHJhY2UiLCAiZGVidWciLCAiaW5mbyIsICJ3YXJuIiwgImVycm9yIiwgImNyaXRpY2FsIiwgZGVmYXVsdCBpcyAiaW5mbyIKO2xldmVsID0gaW5mbwoKIyBGb3IgImNvbnNvbGUiIG1vZGUgb25seQpbbG9nLmNvbnNvbGVdCjtsZXZlbCA9CgojIGxvZyBsaW5lIGZvcm1hdCwgdmFsaWQgb3B0aW9ucyBhcmUgdGV4dCwgY29uc29sZSBhbmQganNvbgo7Zm9ybWF0ID0gY29uc29sZQoKIyBGb3IgImZpbGUiIG1vZGUgb25seQpbbG9nLmZpbGVdCmxldmVsID0gaW5mbwoKIyBsb2cgbGluZSBmb3JtYXQsIHZhbGlkIG9wdGlvbnMgYXJlIHRleHQsIGNvbnNvbGUgYW5kIGpzb24KZm9ybWF0ID0gdGV4dAoKIyBUaGlzIGVuYWJsZXMgYXV0b21hdGVkIGxvZyByb3RhdGUoc3dpdGNoIG9mIGZvbGxvd2luZyBvcHRpb25zKSwgZGVmYXVsdCBpcyB0cnVlCjtsb2dfcm90YXRlID0gdHJ1ZQoKIyBNYXggbGluZSBudW1iZXIgb2Ygc2luZ2xlIGZpbGUsIGRlZmF1bHQgaXMgMTAwMDAwMAo7bWF4X2xpbmVzID0gMTAwMDAwMAoKIyBNYXggc2l6ZSBzaGlmdCBvZiBzaW5nbGUgZmlsZSwgZGVmYXVsdCBpcyAyOCBtZWFucyAxIDw8IDI4LCAyNTZNQgo7bWF4X3NpemVfc2hpZnQgPSAyOAoKIyBTZWdtZW50IGxvZyBkYWlseSwgZGVmYXVsdCBpcyB0cnVlCjtkYWlseV9yb3RhdGUgPSB0cnVlCgojIEV4cGlyZWQgZGF5cyBvZiBsb2cgZmlsZShkZWxldGUgYWZ0ZXIgbWF4IGRheXMpLCBkZWZhdWx0IGlzIDcKO21heF9kYXlzID0gNwoKW2xvZy5zeXNsb2ddCjtsZXZlbCA9CgojIGxvZyBsaW5lIGZvcm1hdCwgdmFsaWQgb3B0aW9ucyBhcmUgdGV4dCwgY29uc29sZSBhbmQganNvbgo7Zm9ybWF0ID0gdGV4dAoKIyBTeXNsb2cgbmV0d29yayB0eXBlIGFuZCBhZGRyZXNzLiBUaGlzIGNhbiBiZSB1ZHAsIHRjcCwgb3IgdW5peC4gSWYgbGVmdCBibGFuaywgdGhlIGRlZmF1bHQgdW5peCBlbmRwb2ludHMgd2lsbCBiZSB1c2VkLgo7bmV0d29yayA9CjthZGRyZXNzID0KCiMgU3lzbG9nIGZhY2lsaXR5LiB1c2VyLCBkYWVtb24gYW5kIGxvY2FsMCB0aHJvdWdoIGxvY2FsNyBhcmUgdmFsaWQuCjtmYWNpbGl0eSA9CgojIFN5c2xvZyB0YWcuIEJ5IGRlZmF1bHQsIHRoZSBwcm9jZXNzJyBhcmd2WzBdIGlzIHVzZWQuCjt0YWcgPQoKCiMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyBBTVFQIEV2ZW50IFB1Ymxpc2hlciAjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIwpbZXZlbnRfcHVibGlzaGVyXQo7ZW5hYmxlZCA9IGZhbHNlCjtyYWJiaXRtcV91cmwgPSBhbXFwOi8vbG9jYWxob3N0Lwo7ZXhjaGFuZ2UgPSBncmFmYW5hX2V2ZW50cwoKOyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyBEYXNoYm9hcmQgSlNPTiBmaWxlcyAjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIwpbZGFzaGJvYXJkcy5qc29uXQplbmFibGVkID0gZmFsc2UKcGF0aCA9IHt7LkRlcGxveURpcn19L2Rhc2hib2FyZHMKCiMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyBJbnRlcm5hbCBHcmFmYW5hIE1ldHJpY3MgIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMKIyBNZXRyaWNzIGF2YWlsYWJsZSBhdCBIVFRQIEFQSSBVcmwgL2FwaS9tZXRyaWNzClttZXRyaWNzXQojIERpc2FibGUgLyBFbmFibGUgaW50ZXJuYWwgbWV0cmljcwo7ZW5hYmxlZCAgICAgICAgICAgPSB0cnVlCgojIFB1Ymxpc2ggaW50ZXJ2YWwKO2ludGVydmFsX3NlY29uZHMgID0gMTAKCiMgU2VuZCBpbnRlcm5hbCBtZXRyaWNzIHRvIEdyYXBoaXRlCjsgW21ldHJpY3MuZ3JhcGhpdGVdCjsgYWRkcmVzcyA9IGxvY2FsaG9zdDoyMDAzCjsgcHJlZml4ID0gcHJvZC5ncmFmYW5hLiUoaW5zdGFuY2VfbmFtZSlzLgoKIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIEludGVybmFsIEdyYWZhbmEgTWV0cmljcyAjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIyMjIwojIFVybCB1c2VkIHRvIHRvIGltcG9ydCBkYXNoYm9hcmRzIGRpcmVjdGx5IGZyb20gR3JhZmFuYS5uZXQKW2dyYWZhbmFfbmV0XQp1cmwgPSBodHRwczovL2dyYWZhbmEubmV0"
-	autogenFiles["/templates/config/prometheus.yml.tpl"] = "LS0tCmdsb2JhbDoKICBzY3JhcGVfaW50ZXJ2YWw6ICAgICAxNXMgIyBCeSBkZWZhdWx0LCBzY3JhcGUgdGFyZ2V0cyBldmVyeSAxNSBzZWNvbmRzLgogIGV2YWx1YXRpb25faW50ZXJ2YWw6IDE1cyAjIEJ5IGRlZmF1bHQsIHNjcmFwZSB0YXJnZXRzIGV2ZXJ5IDE1IHNlY29uZHMuCiAgIyBzY3JhcGVfdGltZW91dCBpcyBzZXQgdG8gdGhlIGdsb2JhbCBkZWZhdWx0ICgxMHMpLgogIGV4dGVybmFsX2xhYmVsczoKICAgIGNsdXN0ZXI6ICd7ey5DbHVzdGVyTmFtZX19JwogICAgbW9uaXRvcjogInByb21ldGhldXMiCgojIExvYWQgYW5kIGV2YWx1YXRlIHJ1bGVzIGluIHRoaXMgZmlsZSBldmVyeSAnZXZhbHVhdGlvbl9pbnRlcnZhbCcgc2Vjb25kcy4KcnVsZV9maWxlczoKICAtICdub2RlLnJ1bGVzLnltbCcKICAtICdibGFja2VyLnJ1bGVzLnltbCcKICAtICdieXBhc3MucnVsZXMueW1sJwogIC0gJ3BkLnJ1bGVzLnltbCcKICAtICd0aWRiLnJ1bGVzLnltbCcKICAtICd0aWt2LnJ1bGVzLnltbCcKICAtICd0aWt2LmFjY2VsZXJhdGUucnVsZXMueW1sJwp7ey0gaWYgLlRpRmxhc2hTdGF0dXNBZGRyc319CiAgLSAndGlmbGFzaC5ydWxlcy55bWwnCnt7LSBlbmR9fQp7ey0gaWYgLlB1bXBBZGRyc319CiAgLSAnYmlubG9nLnJ1bGVzLnltbCcKe3stIGVuZH19Cnt7LSBpZiAuQ0RDQWRkcnN9fQogIC0gJ3RpY2RjLnJ1bGVzLnltbCcKe3stIGVuZH19Cnt7LSBpZiAuS2Fma2FBZGRyc319CiAgLSAna2Fma2EucnVsZXMueW1sJwp7ey0gZW5kfX0Ke3stIGlmIC5MaWdodG5pbmdBZGRyc319CiAgLSAnbGlnaHRuaW5nLnJ1bGVzLnltbCcKe3stIGVuZH19Cgp7ey0gaWYgLkFsZXJ0bWFuYWdlckFkZHJzfX0KYWxlcnRpbmc6CiBhbGVydG1hbmFnZXJzOgogLSBzdGF0aWNfY29uZmlnczoKICAgLSB0YXJnZXRzOgp7ey0gcmFuZ2UgLkFsZXJ0bWFuYWdlckFkZHJzfX0KICAgICAtICd7ey59fScKe3stIGVuZH19Cnt7LSBlbmR9fQoKc2NyYXBlX2NvbmZpZ3M6Cnt7LSBpZiAuUHVzaGdhdGV3YXlBZGRyfX0KICAtIGpvYl9uYW1lOiAnb3ZlcndyaXR0ZW4tY2x1c3RlcicKICAgIHNjcmFwZV9pbnRlcnZhbDogMTVzCiAgICBob25vcl9sYWJlbHM6IHRydWUgIyBkb24ndCBvdmVyd3JpdGUgam9iICYgaW5zdGFuY2UgbGFiZWxzCiAgICBzdGF0aWNfY29uZmlnczoKICAgICAgLSB0YXJnZXRzOiBbJ3t7LlB1c2hnYXRld2F5QWRkcn19J10KCiAgLSBqb2JfbmFtZTogImJsYWNrYm94X2V4cG9ydGVyX2h0dHAiCiAgICBzY3JhcGVfaW50ZXJ2YWw6IDMwcwogICAgbWV0cmljc19wYXRoOiAvcHJvYmUKICAgIHBhcmFtczoKICAgICAgbW9kdWxlOiBbaHR0cF8yeHhdCiAgICBzdGF0aWNfY29uZmlnczoKICAgIC0gdGFyZ2V0czoKICAgICAgLSAnaHR0cDovL3t7LlB1c2hnYXRld2F5QWRkcn19L21ldHJpY3MnCiAgICByZWxhYmVsX2NvbmZpZ3M6CiAgICAgIC0gc291cmNlX2xhYmVsczogW19fYWRkcmVzc19fXQogICAgICAgIHRhcmdldF9sYWJlbDogX19wYXJhbV90YXJnZXQKICAgICAgLSBzb3VyY2VfbGFiZWxzOiBbX19wYXJhbV90YXJnZXRdCiAgICAgICAgdGFyZ2V0X2xhYmVsOiBpbnN0YW5jZQogICAgICAtIHRhcmdldF9sYWJlbDogX19hZGRyZXNzX18KICAgICAgICByZXBsYWNlbWVudDoge3suQmxhY2tib3hBZGRyfX0Ke3stIGVuZH19Cnt7LSBpZiAuTGlnaHRuaW5nQWRkcnN9fQogIC0gam9iX25hbWU6ICJsaWdodG5pbmciCiAgICBzdGF0aWNfY29uZmlnczoKICAgICAgLSB0YXJnZXRzOiBbJ3t7aW5kZXggLkxpZ2h0bmluZ0FkZHJzIDB9fSddCnt7LSBlbmR9fQogIC0gam9iX25hbWU6ICJvdmVyd3JpdHRlbi1ub2RlcyIKICAgIGhvbm9yX2xhYmVsczogdHJ1ZSAjIGRvbid0IG92ZXJ3cml0ZSBqb2IgJiBpbnN0YW5jZSBsYWJlbHMKICAgIHN0YXRpY19jb25maWdzOgogICAgLSB0YXJnZXRzOgp7ey0gcmFuZ2UgLk5vZGVFeHBvcnRlckFkZHJzfX0KICAgICAgLSAne3sufX0nCnt7LSBlbmR9fQogIC0gam9iX25hbWU6ICJ0aWRiIgogICAgaG9ub3JfbGFiZWxzOiB0cnVlICMgZG9uJ3Qgb3ZlcndyaXRlIGpvYiAmIGluc3RhbmNlIGxhYmVscwogICAgc3RhdGljX2NvbmZpZ3M6CiAgICAtIHRhcmdldHM6Cnt7LSByYW5nZSAuVGlEQlN0YXR1c0FkZHJzfX0KICAgICAgLSAne3sufX0nCnt7LSBlbmR9fQogIC0gam9iX25hbWU6ICJ0aWt2IgogICAgaG9ub3JfbGFiZWxzOiB0cnVlICMgZG9uJ3Qgb3ZlcndyaXRlIGpvYiAmIGluc3RhbmNlIGxhYmVscwogICAgc3RhdGljX2NvbmZpZ3M6CiAgICAtIHRhcmdldHM6Cnt7LSByYW5nZSAuVGlLVlN0YXR1c0FkZHJzfX0KICAgICAgLSAne3sufX0nCnt7LSBlbmR9fQogIC0gam9iX25hbWU6ICJwZCIKICAgIGhvbm9yX2xhYmVsczogdHJ1ZSAjIGRvbid0IG92ZXJ3cml0ZSBqb2IgJiBpbnN0YW5jZSBsYWJlbHMKICAgIHN0YXRpY19jb25maWdzOgogICAgLSB0YXJnZXRzOgp7ey0gcmFuZ2UgLlBEQWRkcnN9fQogICAgICAtICd7ey59fScKe3stIGVuZH19Cnt7LSBpZiAuVGlGbGFzaFN0YXR1c0FkZHJzfX0KICAtIGpvYl9uYW1lOiAidGlmbGFzaCIKICAgIGhvbm9yX2xhYmVsczogdHJ1ZSAjIGRvbid0IG92ZXJ3cml0ZSBqb2IgJiBpbnN0YW5jZSBsYWJlbHMKICAgIHN0YXRpY19jb25maWdzOgogICAgLSB0YXJnZXRzOgogICAge3stIHJhbmdlIC5UaUZsYXNoU3RhdHVzQWRkcnN9fQogICAgICAgLSAne3sufX0nCiAgICB7ey0gZW5kfX0KICAgIHt7LSByYW5nZSAuVGlGbGFzaExlYXJuZXJTdGF0dXNBZGRyc319CiAgICAgICAtICd7ey59fScKICAgIHt7LSBlbmR9fQp7ey0gZW5kfX0Ke3stIGlmIC5QdW1wQWRkcnN9fQp7ey0gaWYgLkthZmthRXhwb3J0ZXJBZGRyfX0KICAtIGpvYl9uYW1lOiAna2Fma2FfZXhwb3J0ZXInCiAgICBob25vcl9sYWJlbHM6IHRydWUgIyBkb24ndCBvdmVyd3JpdGUgam9iICYgaW5zdGFuY2UgbGFiZWxzCiAgICBzdGF0aWNfY29uZmlnczoKICAgIC0gdGFyZ2V0czoKICAgICAgLSAne3suS2Fma2FFeHBvcnRlckFkZHJ9fScKe3stIGVuZH19CiAgLSBqb2JfbmFtZTogJ3B1bXAnCiAgICBob25vcl9sYWJlbHM6IHRydWUgIyBkb24ndCBvdmVyd3JpdGUgam9iICYgaW5zdGFuY2UgbGFiZWxzCiAgICBzdGF0aWNfY29uZmlnczoKICAgIC0gdGFyZ2V0czoKICAgIHt7LSByYW5nZSAuUHVtcEFkZHJzfX0KICAgICAgLSAne3sufX0nCiAgICB7ey0gZW5kfX0KICAtIGpvYl9uYW1lOiAnZHJhaW5lcicKICAgIGhvbm9yX2xhYmVsczogdHJ1ZSAjIGRvbid0IG92ZXJ3cml0ZSBqb2IgJiBpbnN0YW5jZSBsYWJlbHMKICAgIHN0YXRpY19jb25maWdzOgogICAgLSB0YXJnZXRzOgogICAge3stIHJhbmdlIC5EcmFpbmVyQWRkcnN9fQogICAgICAtICd7ey59fScKICAgIHt7LSBlbmR9fQogIC0gam9iX25hbWU6ICJwb3J0X3Byb2JlIgogICAgc2NyYXBlX2ludGVydmFsOiAzMHMKICAgIG1ldHJpY3NfcGF0aDogL3Byb2JlCiAgICBwYXJhbXM6CiAgICAgIG1vZHVsZTogW3RjcF9jb25uZWN0XQogICAgc3RhdGljX2NvbmZpZ3M6Cnt7LSBpZiAuS2Fma2FBZGRyc319CiAgICAtIHRhcmdldHM6CiAgICB7ey0gcmFuZ2UgLkthZmthQWRkcnN9fQogICAgICAgIC0gJ3t7Ln19JwogICAge3stIGVuZH19CiAgICAgIGxhYmVsczoKICAgICAgICBncm91cDogJ2thZmthJwp7ey0gZW5kfX0Ke3stIGlmIC5ab29rZWVwZXJBZGRyc319CiAgICAtIHRhcmdldHM6CiAgICB7ey0gcmFuZ2UgLlpvb2tlZXBlckFkZHJzfX0KICAgICAgLSAne3sufX0nCiAgICB7ey0gZW5kfX0KICAgICAgbGFiZWxzOgogICAgICAgIGdyb3VwOiAnem9va2VlcGVyJwp7ey0gZW5kfX0KICAgIC0gdGFyZ2V0czoKe3stIHJhbmdlIC5QdW1wQWRkcnN9fQogICAgICAtICd7ey59fScKe3stIGVuZH19CiAgICAgIGxhYmVsczoKICAgICAgICBncm91cDogJ3B1bXAnCiAgICAtIHRhcmdldHM6CiAgICB7ey0gcmFuZ2UgLkRyYWluZXJBZGRyc319CiAgICAgIC0gJ3t7Ln19JwogICAge3stIGVuZH19CiAgICAgIGxhYmVsczoKICAgICAgICBncm91cDogJ2RyYWluZXInCnt7LSBpZiAuS2Fma2FFeHBvcnRlckFkZHJ9fQogICAgLSB0YXJnZXRzOgogICAgICAtICd7ey5LYWZrYUV4cG9ydGVyQWRkcn19JwogICAgICBsYWJlbHM6CiAgICAgICAgZ3JvdXA6ICdrYWZrYV9leHBvcnRlcicKe3stIGVuZH19CiAgICByZWxhYmVsX2NvbmZpZ3M6CiAgICAgIC0gc291cmNlX2xhYmVsczogW19fYWRkcmVzc19fXQogICAgICAgIHRhcmdldF9sYWJlbDogX19wYXJhbV90YXJnZXQKICAgICAgLSBzb3VyY2VfbGFiZWxzOiBbX19wYXJhbV90YXJnZXRdCiAgICAgICAgdGFyZ2V0X2xhYmVsOiBpbnN0YW5jZQogICAgICAtIHRhcmdldF9sYWJlbDogX19hZGRyZXNzX18KICAgICAgICByZXBsYWNlbWVudDoge3suQmxhY2tib3hBZGRyfX0Ke3stIGVuZH19Cnt7LSBpZiAuQ0RDQWRkcnN9fQogIC0gam9iX25hbWU6ICJ0aWNkYyIKICAgIGhvbm9yX2xhYmVsczogdHJ1ZSAjIGRvbid0IG92ZXJ3cml0ZSBqb2IgJiBpbnN0YW5jZSBsYWJlbHMKICAgIHN0YXRpY19jb25maWdzOgogICAgLSB0YXJnZXRzOgp7ey0gcmFuZ2UgLkNEQ0FkZHJzfX0KICAgICAgLSAne3sufX0nCnt7LSBlbmR9fQp7ey0gZW5kfX0KICAtIGpvYl9uYW1lOiAidGlkYl9wb3J0X3Byb2JlIgogICAgc2NyYXBlX2ludGVydmFsOiAzMHMKICAgIG1ldHJpY3NfcGF0aDogL3Byb2JlCiAgICBwYXJhbXM6CiAgICAgIG1vZHVsZTogW3RjcF9jb25uZWN0XQogICAgc3RhdGljX2NvbmZpZ3M6CiAgICAtIHRhcmdldHM6CiAgICB7ey0gcmFuZ2UgLlRpREJTdGF0dXNBZGRyc319CiAgICAgIC0gJ3t7Ln19JyAKICAgIHt7LSBlbmR9fQogICAgICBsYWJlbHM6CiAgICAgICAgZ3JvdXA6ICd0aWRiJwogICAgLSB0YXJnZXRzOgogICAge3stIHJhbmdlIC5UaUtWU3RhdHVzQWRkcnN9fQogICAgICAtICd7ey59fScKICAgIHt7LSBlbmR9fQogICAgICBsYWJlbHM6CiAgICAgICAgZ3JvdXA6ICd0aWt2JwogICAgLSB0YXJnZXRzOgogICAge3stIHJhbmdlIC5QREFkZHJzfX0KICAgICAgLSAne3sufX0nCiAgICB7ey0gZW5kfX0KICAgICAgbGFiZWxzOgogICAgICAgIGdyb3VwOiAncGQnCnt7LSBpZiAuVGlGbGFzaFN0YXR1c0FkZHJzfX0KICAgIC0gdGFyZ2V0czoKICAgIHt7LSByYW5nZSAuVGlGbGFzaFN0YXR1c0FkZHJzfX0KICAgICAgIC0gJ3t7Ln19JwogICAge3stIGVuZH19CiAgICAgIGxhYmVsczoKICAgICAgICBncm91cDogJ3RpZmxhc2gnCnt7LSBlbmR9fQp7ey0gaWYgLlB1c2hnYXRld2F5QWRkcn19CiAgICAtIHRhcmdldHM6CiAgICAgIC0gJ3t7LlB1c2hnYXRld2F5QWRkcn19JwogICAgICBsYWJlbHM6CiAgICAgICAgZ3JvdXA6ICdwdXNoZ2F0ZXdheScKe3stIGVuZH19Cnt7LSBpZiAuR3JhZmFuYUFkZHJ9fQogICAgLSB0YXJnZXRzOgogICAgICAtICd7ey5HcmFmYW5hQWRkcn19JwogICAgICBsYWJlbHM6CiAgICAgICAgZ3JvdXA6ICdncmFmYW5hJwp7ey0gZW5kfX0KICAgIC0gdGFyZ2V0czoKICAgIHt7LSByYW5nZSAuTm9kZUV4cG9ydGVyQWRkcnN9fQogICAgICAtICd7ey59fScKICAgIHt7LSBlbmR9fQogICAgICBsYWJlbHM6CiAgICAgICAgZ3JvdXA6ICdub2RlX2V4cG9ydGVyJwogICAgLSB0YXJnZXRzOgogICAge3stIHJhbmdlIC5CbGFja2JveEV4cG9ydGVyQWRkcnN9fQogICAgICAtICd7ey59fScKICAgIHt7LSBlbmR9fQogICAgICBsYWJlbHM6CiAgICAgICAgZ3JvdXA6ICdibGFja2JveF9leHBvcnRlcicKICAgIHJlbGFiZWxfY29uZmlnczoKICAgICAgLSBzb3VyY2VfbGFiZWxzOiBbX19hZGRyZXNzX19dCiAgICAgICAgdGFyZ2V0X2xhYmVsOiBfX3BhcmFtX3RhcmdldAogICAgICAtIHNvdXJjZV9sYWJlbHM6IFtfX3BhcmFtX3RhcmdldF0KICAgICAgICB0YXJnZXRfbGFiZWw6IGluc3RhbmNlCiAgICAgIC0gdGFyZ2V0X2xhYmVsOiBfX2FkZHJlc3NfXwogICAgICAgIHJlcGxhY2VtZW50OiB7ey5CbGFja2JveEFkZHJ9fQp7ey0gcmFuZ2UgJGFkZHIgOj0gLkJsYWNrYm94RXhwb3J0ZXJBZGRyc319CiAgLSBqb2JfbmFtZTogImJsYWNrYm94X2V4cG9ydGVyX3t7JGFkZHJ9fV9pY21wIgogICAgc2NyYXBlX2ludGVydmFsOiA2cwogICAgbWV0cmljc19wYXRoOiAvcHJvYmUKICAgIHBhcmFtczoKICAgICAgbW9kdWxlOiBbaWNtcF0KICAgIHN0YXRpY19jb25maWdzOgogICAgLSB0YXJnZXRzOgogICAge3stIHJhbmdlICQuTW9uaXRvcmVkU2VydmVyc319CiAgICAgIC0gJ3t7Ln19JwogICAge3stIGVuZH19CiAgICByZWxhYmVsX2NvbmZpZ3M6CiAgICAgIC0gc291cmNlX2xhYmVsczogW19fYWRkcmVzc19fXQogICAgICAgIHJlZ2V4OiAoLiopKDo4MCk/CiAgICAgICAgdGFyZ2V0X2xhYmVsOiBfX3BhcmFtX3RhcmdldAogICAgICAgIHJlcGxhY2VtZW50OiAkezF9CiAgICAgIC0gc291cmNlX2xhYmVsczogW19fcGFyYW1fdGFyZ2V0XQogICAgICAgIHJlZ2V4OiAoLiopCiAgICAgICAgdGFyZ2V0X2xhYmVsOiBwaW5nCiAgICAgICAgcmVwbGFjZW1lbnQ6ICR7MX0KICAgICAgLSBzb3VyY2VfbGFiZWxzOiBbXQogICAgICAgIHJlZ2V4OiAuKgogICAgICAgIHRhcmdldF9sYWJlbDogX19hZGRyZXNzX18KICAgICAgICByZXBsYWNlbWVudDoge3skYWRkcn19Cnt7LSBlbmR9fQ=="
+	autogenFiles["/templates/config/prometheus.yml.tpl"] = "LS0tCmdsb2JhbDoKICBzY3JhcGVfaW50ZXJ2YWw6ICAgICAxNXMgIyBCeSBkZWZhdWx0LCBzY3JhcGUgdGFyZ2V0cyBldmVyeSAxNSBzZWNvbmRzLgogIGV2YWx1YXRpb25faW50ZXJ2YWw6IDE1cyAjIEJ5IGRlZmF1bHQsIHNjcmFwZSB0YXJnZXRzIGV2ZXJ5IDE1IHNlY29uZHMuCiAgIyBzY3JhcGVfdGltZW91dCBpcyBzZXQgdG8gdGhlIGdsb2JhbCBkZWZhdWx0ICgxMHMpLgogIGV4dGVybmFsX2xhYmVsczoKICAgIGNsdXN0ZXI6ICd7ey5DbHVzdGVyTmFtZX19JwogICAgbW9uaXRvcjogInByb21ldGhldXMiCgojIExvYWQgYW5kIGV2YWx1YXRlIHJ1bGVzIGluIHRoaXMgZmlsZSBldmVyeSAnZXZhbHVhdGlvbl9pbnRlcnZhbCcgc2Vjb25kcy4KcnVsZV9maWxlczoKICAjIEJFR0lOIG1hbmFnZWQgYnkgdGl1cCwgcmVnZW5lcmF0ZWQgb24gY2hhbmdlcyBvZiB0aGUgY2x1c3RlciwgYWRkIHJ1bGUgZmlsZXMgYWZ0ZXIgdGhlIEVORCBsaW5lCiAgLSAnbm9kZS5ydWxlcy55bWwnCiAgLSAnYmxhY2tlci5ydWxlcy55bWwnCiAgLSAnYnlwYXNzLnJ1bGVzLnltbCcKICAtICdwZC5ydWxlcy55bWwnCiAgLSAndGlkYi5ydWxlcy55bWwnCiAgLSAndGlrdi5ydWxlcy55bWwnCiAgLSAndGlrdi5hY2NlbGVyYXRlLnJ1bGVzLnltbCcKe3stIGlmIC5UaUZsYXNoU3RhdHVzQWRkcnN9fQogIC0gJ3RpZmxhc2gucnVsZXMueW1sJwp7ey0gZW5kfX0Ke3stIGlmIC5QdW1wQWRkcnN9fQogIC0gJ2JpbmxvZy5ydWxlcy55bWwnCnt7LSBlbmR9fQp7ey0gaWYgLkNEQ0FkZHJzfX0KICAtICd0aWNkYy5ydWxlcy55bWwnCnt7LSBlbmR9fQp7ey0gaWYgLkthZmthQWRkcnN9fQogIC0gJ2thZmthLnJ1bGVzLnltbCcKe3stIGVuZH19Cnt7LSBpZiAuTGlnaHRuaW5nQWRkcnN9fQogIC0gJ2xpZ2h0bmluZy5ydWxlcy55bWwnCnt7LSBlbmR9fQogICMgRU5EIG1hbmFnZWQgYnkgdGl1cAoKe3stIGlmIC5BbGVydG1hbmFnZXJBZGRyc319CmFsZXJ0aW5nOgogYWxlcnRtYW5hZ2VyczoKIC0gc3RhdGljX2NvbmZpZ3M6CiAgIC0gdGFyZ2V0czoKe3stIHJhbmdlIC5BbGVydG1hbmFnZXJBZGRyc319CiAgICAgLSAne3sufX0nCnt7LSBlbmR9fQp7ey0gZW5kfX0KCnNjcmFwZV9jb25maWdzOgogICMgQkVHSU4gbWFuYWdlZCBieSB0aXVwLCByZWdlbmVyYXRlZCBvbiBjaGFuZ2VzIG9mIHRoZSBjbHVzdGVyLCBhZGQgc2NyYXBlIGpvYnMgYWZ0ZXIgdGhlIEVORCBsaW5lCnt7LSBpZiAuUHVzaGdhdGV3YXlBZGRyfX0KICAtIGpvYl9uYW1lOiAnb3ZlcndyaXR0ZW4tY2x1c3RlcicKICAgIHNjcmFwZV9pbnRlcnZhbDogMTVzCiAgICBob25vcl9sYWJlbHM6IHRydWUgIyBkb24ndCBvdmVyd3JpdGUgam9iICYgaW5zdGFuY2UgbGFiZWxzCiAgICBzdGF0aWNfY29uZmlnczoKICAgICAgLSB0YXJnZXRzOiBbJ3t7LlB1c2hnYXRld2F5QWRkcn19J10KCiAgLSBqb2JfbmFtZTogImJsYWNrYm94X2V4cG9ydGVyX2h0dHAiCiAgICBzY3JhcGVfaW50ZXJ2YWw6IDMwcwogICAgbWV0cmljc19wYXRoOiAvcHJvYmUKICAgIHBhcmFtczoKICAgICAgbW9kdWxlOiBbaHR0cF8yeHhdCiAgICBzdGF0aWNfY29uZmlnczoKICAgIC0gdGFyZ2V0czoKICAgICAgLSAnaHR0cDovL3t7LlB1c2hnYXRld2F5QWRkcn19L21ldHJpY3MnCiAgICByZWxhYmVsX2NvbmZpZ3M6CiAgICAgIC0gc291cmNlX2xhYmVsczogW19fYWRkcmVzc19fXQogICAgICAgIHRhcmdldF9sYWJlbDogX19wYXJhbV90YXJnZXQKICAgICAgLSBzb3VyY2VfbGFiZWxzOiBbX19wYXJhbV90YXJnZXRdCiAgICAgICAgdGFyZ2V0X2xhYmVsOiBpbnN0YW5jZQogICAgICAtIHRhcmdldF9sYWJlbDogX19hZGRyZXNzX18KICAgICAgICByZXBsYWNlbWVudDoge3suQmxhY2tib3hBZGRyfX0Ke3stIGVuZH19Cnt7LSBpZiAuTGlnaHRuaW5nQWRkcnN9fQogIC0gam9iX25hbWU6ICJsaWdodG5pbmciCiAgICBzdGF0aWNfY29uZmlnczoKICAgICAgLSB0YXJnZXRzOiBbJ3t7aW5kZXggLkxpZ2h0bmluZ0FkZHJzIDB9fSddCnt7LSBlbmR9fQogIC0gam9iX25hbWU6ICJvdmVyd3JpdHRlbi1ub2RlcyIKICAgIGhvbm9yX2xhYmVsczogdHJ1ZSAjIGRvbid0IG92ZXJ3cml0ZSBqb2IgJiBpbnN0YW5jZSBsYWJlbHMKICAgIHN0YXRpY19jb25maWdzOgogICAgLSB0YXJnZXRzOgp7ey0gcmFuZ2UgLk5vZGVFeHBvcnRlckFkZHJzfX0KICAgICAgLSAne3sufX0nCnt7LSBlbmR9fQogIC0gam9iX25hbWU6ICJ0aWRiIgogICAgaG9ub3JfbGFiZWxzOiB0cnVlICMgZG9uJ3Qgb3ZlcndyaXRlIGpvYiAmIGluc3RhbmNlIGxhYmVscwogICAgc3RhdGljX2NvbmZpZ3M6CiAgICAtIHRhcmdldHM6Cnt7LSByYW5nZSAuVGlEQlN0YXR1c0FkZHJzfX0KICAgICAgLSAne3sufX0nCnt7LSBlbmR9fQogIC0gam9iX25hbWU6ICJ0aWt2IgogICAgaG9ub3JfbGFiZWxzOiB0cnVlICMgZG9uJ3Qgb3ZlcndyaXRlIGpvYiAmIGluc3RhbmNlIGxhYmVscwogICAgc3RhdGljX2NvbmZpZ3M6CiAgICAtIHRhcmdldHM6Cnt7LSByYW5nZSAuVGlLVlN0YXR1c0FkZHJzfX0KICAgICAgLSAne3sufX0nCnt7LSBlbmR9fQogIC0gam9iX25hbWU6ICJwZCIKICAgIGhvbm9yX2xhYmVsczogdHJ1ZSAjIGRvbid0IG92ZXJ3cml0ZSBqb2IgJiBpbnN0YW5jZSBsYWJlbHMKICAgIHN0YXRpY19jb25maWdzOgogICAgLSB0YXJnZXRzOgp7ey0gcmFuZ2UgLlBEQWRkcnN9fQogICAgICAtICd7ey59fScKe3stIGVuZH19Cnt7LSBpZiAuVGlGbGFzaFN0YXR1c0FkZHJzfX0KICAtIGpvYl9uYW1lOiAidGlmbGFzaCIKICAgIGhvbm9yX2xhYmVsczogdHJ1ZSAjIGRvbid0IG92ZXJ3cml0ZSBqb2IgJiBpbnN0YW5jZSBsYWJlbHMKICAgIHN0YXRpY19jb25maWdzOgogICAgLSB0YXJnZXRzOgogICAge3stIHJhbmdlIC5UaUZsYXNoU3RhdHVzQWRkcnN9fQogICAgICAgLSAne3sufX0nCiAgICB7ey0gZW5kfX0KICAgIHt7LSByYW5nZSAuVGlGbGFzaExlYXJuZXJTdGF0dXNBZGRyc319CiAgICAgICAtICd7ey59fScKICAgIHt7LSBlbmR9fQp7ey0gZW5kfX0Ke3stIGlmIC5QdW1wQWRkcnN9fQp7ey0gaWYgLkthZmthRXhwb3J0ZXJBZGRyfX0KICAtIGpvYl9uYW1lOiAna2Fma2FfZXhwb3J0ZXInCiAgICBob25vcl9sYWJlbHM6IHRydWUgIyBkb24ndCBvdmVyd3JpdGUgam9iICYgaW5zdGFuY2UgbGFiZWxzCiAgICBzdGF0aWNfY29uZmlnczoKICAgIC0gdGFyZ2V0czoKICAgICAgLSAne3suS2Fma2FFeHBvcnRlckFkZHJ9fScKe3stIGVuZH19CiAgLSBqb2JfbmFtZTogJ3B1bXAnCiAgICBob25vcl9sYWJlbHM6IHRydWUgIyBkb24ndCBvdmVyd3JpdGUgam9iICYgaW5zdGFuY2UgbGFiZWxzCiAgICBzdGF0aWNfY29uZmlnczoKICAgIC0gdGFyZ2V0czoKICAgIHt7LSByYW5nZSAuUHVtcEFkZHJzfX0KICAgICAgLSAne3sufX0nCiAgICB7ey0gZW5kfX0KICAtIGpvYl9uYW1lOiAnZHJhaW5lcicKICAgIGhvbm9yX2xhYmVsczogdHJ1ZSAjIGRvbid0IG92ZXJ3cml0ZSBqb2IgJiBpbnN0YW5jZSBsYWJlbHMKICAgIHN0YXRpY19jb25maWdzOgogICAgLSB0YXJnZXRzOgogICAge3stIHJhbmdlIC5EcmFpbmVyQWRkcnN9fQogICAgICAtICd7ey59fScKICAgIHt7LSBlbmR9fQogIC0gam9iX25hbWU6ICJwb3J0X3Byb2JlIgogICAgc2NyYXBlX2ludGVydmFsOiAzMHMKICAgIG1ldHJpY3NfcGF0aDogL3Byb2JlCiAgICBwYXJhbXM6CiAgICAgIG1vZHVsZTogW3RjcF9jb25uZWN0XQogICAgc3RhdGljX2NvbmZpZ3M6Cnt7LSBpZiAuS2Fma2FBZGRyc319CiAgICAtIHRhcmdldHM6CiAgICB7ey0gcmFuZ2UgLkthZmthQWRkcnN9fQogICAgICAgIC0gJ3t7Ln19JwogICAge3stIGVuZH19CiAgICAgIGxhYmVsczoKICAgICAgICBncm91cDogJ2thZmthJwp7ey0gZW5kfX0Ke3stIGlmIC5ab29rZWVwZXJBZGRyc319CiAgICAtIHRhcmdldHM6CiAgICB7ey0gcmFuZ2UgLlpvb2tlZXBlckFkZHJzfX0KICAgICAgLSAne3sufX0nCiAgICB7ey0gZW5kfX0KICAgICAgbGFiZWxzOgogICAgICAgIGdyb3VwOiAnem9va2VlcGVyJwp7ey0gZW5kfX0KICAgIC0gdGFyZ2V0czoKe3stIHJhbmdlIC5QdW1wQWRkcnN9fQogICAgICAtICd7ey59fScKe3stIGVuZH19CiAgICAgIGxhYmVsczoKICAgICAgICBncm91cDogJ3B1bXAnCiAgICAtIHRhcmdldHM6CiAgICB7ey0gcmFuZ2UgLkRyYWluZXJBZGRyc319CiAgICAgIC0gJ3t7Ln19JwogICAge3stIGVuZH19CiAgICAgIGxhYmVsczoKICAgICAgICBncm91cDogJ2RyYWluZXInCnt7LSBpZiAuS2Fma2FFeHBvcnRlckFkZHJ9fQogICAgLSB0YXJnZXRzOgogICAgICAtICd7ey5LYWZrYUV4cG9ydGVyQWRkcn19JwogICAgICBsYWJlbHM6CiAgICAgICAgZ3JvdXA6ICdrYWZrYV9leHBvcnRlcicKe3stIGVuZH19CiAgICByZWxhYmVsX2NvbmZpZ3M6CiAgICAgIC0gc291cmNlX2xhYmVsczogW19fYWRkcmVzc19fXQogICAgICAgIHRhcmdldF9sYWJlbDogX19wYXJhbV90YXJnZXQKICAgICAgLSBzb3VyY2VfbGFiZWxzOiBbX19wYXJhbV90YXJnZXRdCiAgICAgICAgdGFyZ2V0X2xhYmVsOiBpbnN0YW5jZQogICAgICAtIHRhcmdldF9sYWJlbDogX19hZGRyZXNzX18KICAgICAgICByZXBsYWNlbWVudDoge3suQmxhY2tib3hBZGRyfX0Ke3stIGVuZH19Cnt7LSBpZiAuQ0RDQWRkcnN9fQogIC0gam9iX25hbWU6ICJ0aWNkYyIKICAgIGhvbm9yX2xhYmVsczogdHJ1ZSAjIGRvbid0IG92ZXJ3cml0ZSBqb2IgJiBpbnN0YW5jZSBsYWJlbHMKICAgIHN0YXRpY19jb25maWdzOgogICAgLSB0YXJnZXRzOgp7ey0gcmFuZ2UgLkNEQ0FkZHJzfX0KICAgICAgLSAne3sufX0nCnt7LSBlbmR9fQp7ey0gZW5kfX0KICAtIGpvYl9uYW1lOiAidGlkYl9wb3J0X3Byb2JlIgogICAgc2NyYXBlX2ludGVydmFsOiAzMHMKICAgIG1ldHJpY3NfcGF0aDogL3Byb2JlCiAgICBwYXJhbXM6CiAgICAgIG1vZHVsZTogW3RjcF9jb25uZWN0XQogICAgc3RhdGljX2NvbmZpZ3M6CiAgICAtIHRhcmdldHM6CiAgICB7ey0gcmFuZ2UgLlRpREJTdGF0dXNBZGRyc319CiAgICAgIC0gJ3t7Ln19JyAKICAgIHt7LSBlbmR9fQogICAgICBsYWJlbHM6CiAgICAgICAgZ3JvdXA6ICd0aWRiJwogICAgLSB0YXJnZXRzOgogICAge3stIHJhbmdlIC5UaUtWU3RhdHVzQWRkcnN9fQogICAgICAtICd7ey59fScKICAgIHt7LSBlbmR9fQogICAgICBsYWJlbHM6CiAgICAgICAgZ3JvdXA6ICd0aWt2JwogICAgLSB0YXJnZXRzOgogICAge3stIHJhbmdlIC5QREFkZHJzfX0KICAgICAgLSAne3sufX0nCiAgICB7ey0gZW5kfX0KICAgICAgbGFiZWxzOgogICAgICAgIGdyb3VwOiAncGQnCnt7LSBpZiAuVGlGbGFzaFN0YXR1c0FkZHJzfX0KICAgIC0gdGFyZ2V0czoKICAgIHt7LSByYW5nZSAuVGlGbGFzaFN0YXR1c0FkZHJzfX0KICAgICAgIC0gJ3t7Ln19JwogICAge3stIGVuZH19CiAgICAgIGxhYmVsczoKICAgICAgICBncm91cDogJ3RpZmxhc2gnCnt7LSBlbmR9fQp7ey0gaWYgLlB1c2hnYXRld2F5QWRkcn19CiAgICAtIHRhcmdldHM6CiAgICAgIC0gJ3t7LlB1c2hnYXRld2F5QWRkcn19JwogICAgICBsYWJlbHM6CiAgICAgICAgZ3JvdXA6ICdwdXNoZ2F0ZXdheScKe3stIGVuZH19Cnt7LSBpZiAuR3JhZmFuYUFkZHJ9fQogICAgLSB0YXJnZXRzOgogICAgICAtICd7ey5HcmFmYW5hQWRkcn19JwogICAgICBsYWJlbHM6CiAgICAgICAgZ3JvdXA6ICdncmFmYW5hJwp7ey0gZW5kfX0KICAgIC0gdGFyZ2V0czoKICAgIHt7LSByYW5nZSAuTm9kZUV4cG9ydGVyQWRkcnN9fQogICAgICAtICd7ey59fScKICAgIHt7LSBlbmR9fQogICAgICBsYWJlbHM6CiAgICAgICAgZ3JvdXA6ICdub2RlX2V4cG9ydGVyJwogICAgLSB0YXJnZXRzOgogICAge3stIHJhbmdlIC5CbGFja2JveEV4cG9ydGVyQWRkcnN9fQogICAgICAtICd7ey59fScKICAgIHt7LSBlbmR9fQogICAgICBsYWJlbHM6CiAgICAgICAgZ3JvdXA6ICdibGFja2JveF9leHBvcnRlcicKICAgIHJlbGFiZWxfY29uZmlnczoKICAgICAgLSBzb3VyY2VfbGFiZWxzOiBbX19hZGRyZXNzX19dCiAgICAgICAgdGFyZ2V0X2xhYmVsOiBfX3BhcmFtX3RhcmdldAogICAgICAtIHNvdXJjZV9sYWJlbHM6IFtfX3BhcmFtX3RhcmdldF0KICAgICAgICB0YXJnZXRfbGFiZWw6IGluc3RhbmNlCiAgICAgIC0gdGFyZ2V0X2xhYmVsOiBfX2FkZHJlc3NfXwogICAgICAgIHJlcGxhY2VtZW50OiB7ey5CbGFja2JveEFkZHJ9fQp7ey0gcmFuZ2UgJGFkZHIgOj0gLkJsYWNrYm94RXhwb3J0ZXJBZGRyc319CiAgLSBqb2JfbmFtZTogImJsYWNrYm94X2V4cG9ydGVyX3t7JGFkZHJ9fV9pY21wIgogICAgc2NyYXBlX2ludGVydmFsOiA2cwogICAgbWV0cmljc19wYXRoOiAvcHJvYmUKICAgIHBhcmFtczoKICAgICAgbW9kdWxlOiBbaWNtcF0KICAgIHN0YXRpY19jb25maWdzOgogICAgLSB0YXJnZXRzOgogICAge3stIHJhbmdlICQuTW9uaXRvcmVkU2VydmVyc319CiAgICAgIC0gJ3t7Ln19JwogICAge3stIGVuZH19CiAgICByZWxhYmVsX2NvbmZpZ3M6CiAgICAgIC0gc291cmNlX2xhYmVsczogW19fYWRkcmVzc19fXQogICAgICAgIHJlZ2V4OiAoLiopKDo4MCk/CiAgICAgICAgdGFyZ2V0X2xhYmVsOiBfX3BhcmFtX3RhcmdldAogICAgICAgIHJlcGxhY2VtZW50OiAkezF9CiAgICAgIC0gc291cmNlX2xhYmVsczogW19fcGFyYW1fdGFyZ2V0XQogICAgICAgIHJlZ2V4OiAoLiopCiAgICAgICAgdGFyZ2V0X2xhYmVsOiBwaW5nCiAgICAgICAgcmVwbGFjZW1lbnQ6ICR7MX0KICAgICAgLSBzb3VyY2VfbGFiZWxzOiBbXQogICAgICAgIHJlZ2V4OiAuKgogICAgICAgIHRhcmdldF9sYWJlbDogX19hZGRyZXNzX18KICAgICAgICByZXBsYWNlbWVudDoge3skYWRkcn19Cnt7LSBlbmR9fQogICMgRU5EIG1hbmFnZWQgYnkgdGl1cAo="
 	autogenFiles["/templates/config/spark-defaults.conf.tpl"] = "IwojIExpY2Vuc2VkIHRvIHRoZSBBcGFjaGUgU29mdHdhcmUgRm91bmRhdGlvbiAoQVNGKSB1bmRlciBvbmUgb3IgbW9yZQojIGNvbnRyaWJ1dG9yIGxpY2Vuc2UgYWdyZWVtZW50cy4gIFNlZSB0aGUgTk9USUNFIGZpbGUgZGlzdHJpYnV0ZWQgd2l0aAojIHRoaXMgd29yayBmb3IgYWRkaXRpb25hbCBpbmZvcm1hdGlvbiByZWdhcmRpbmcgY29weXJpZ2h0IG93bmVyc2hpcC4KIyBUaGUgQVNGIGxpY2Vuc2VzIHRoaXMgZmlsZSB0byBZb3UgdW5kZXIgdGhlIEFwYWNoZSBMaWNlbnNlLCBWZXJzaW9uIDIuMAojICh0aGUgIkxpY2Vuc2UiKTsgeW91IG1heSBub3QgdXNlIHRoaXMgZmlsZSBleGNlcHQgaW4gY29tcGxpYW5jZSB3aXRoCiMgdGhlIExpY2Vuc2UuICBZb3UgbWF5IG9idGFpbiBhIGNvcHkgb2YgdGhlIExpY2Vuc2UgYXQKIwojICAgIGh0dHA6Ly93d3cuYXBhY2hlLm9yZy9saWNlbnNlcy9MSUNFTlNFLTIuMAojCiMgVW5sZXNzIHJlcXVpcmVkIGJ5IGFwcGxpY2FibGUgbGF3IG9yIGFncmVlZCB0byBpbiB3cml0aW5nLCBzb2Z0d2FyZQojIGRpc3RyaWJ1dGVkIHVuZGVyIHRoZSBMaWNlbnNlIGlzIGRpc3RyaWJ1dGVkIG9uIGFuICJBUyBJUyIgQkFTSVMsCiMgV0lUSE9VVCBXQVJSQU5USUVTIE9SIENPTkRJVElPTlMgT0YgQU5ZIEtJTkQsIGVpdGhlciBleHByZXNzIG9yIGltcGxpZWQuCiMgU2VlIHRoZSBMaWNlbnNlIGZvciB0aGUgc3BlY2lmaWMgbGFuZ3VhZ2UgZ292ZXJuaW5nIHBlcm1pc3Npb25zIGFuZAojIGxpbWl0YXRpb25zIHVuZGVyIHRoZSBMaWNlbnNlLgojCgojIERlZmF1bHQgc3lzdGVtIHByb3BlcnRpZXMgaW5jbHVkZWQgd2hlbiBydW5uaW5nIHNwYXJrLXN1Ym1pdC4KIyBUaGlzIGlzIHVzZWZ1bCBmb3Igc2V0dGluZyBkZWZhdWx0IGVudmlyb25tZW50YWwgc2V0dGluZ3MuCgojIEV4YW1wbGU6CiNzcGFyay5ldmVudExvZy5kaXI6ICJoZGZzOi8vbmFtZW5vZGU6ODAyMS9kaXJlY3RvcnkiCiMgc3BhcmsuZXhlY3V0b3IuZXh0cmFKYXZhT3B0aW9ucyAgLVhYOitQcmludEdDRGV0YWlscyAtRGtleT12YWx1ZSAtRG51bWJlcnM9Im9uZSB0d28gdGhyZWUiCgp7ey0gZGVmaW5lICJQRExpc3QifX0KICB7ey0gcmFuZ2UgJGlkeCwgJHBkIDo9IC59fQogICAge3stIGlmIGVxICRpZHggMH19CiAgICAgIHt7LSAkcGR9fQogICAge3stIGVsc2UgLX19CiAgICAgICx7eyRwZH19CiAgICB7ey0gZW5kfX0KICB7ey0gZW5kfX0Ke3stIGVuZH19Cgp7eyByYW5nZSAkaywgJHYgOj0gLkN1c3RvbUZpZWxkc319Cnt7ICRrIH19ICAge3sgJHYgfX0Ke3stIGVuZCB9fQpzcGFyay5zcWwuZXh0ZW5zaW9ucyAgIG9yZy5hcGFjaGUuc3Bhcmsuc3FsLlRpRXh0ZW5zaW9ucwoKe3stIGlmIC5UaVNwYXJrTWFzdGVyc319CnNwYXJrLm1hc3RlciAgIHNwYXJrOi8ve3suVGlTcGFya01hc3RlcnN9fQp7ey0gZW5kfX0KCnNwYXJrLnRpc3BhcmsucGQuYWRkcmVzc2VzIHt7dGVtcGxhdGUgIlBETGlzdCIgLkVuZHBvaW50c319Cg=="
 	autogenFiles["/templates/config/spark-log4j.properties.tpl"] = "IwojIExpY2Vuc2VkIHRvIHRoZSBBcGFjaGUgU29mdHdhcmUgRm91bmRhdGlvbiAoQVNGKSB1bmRlciBvbmUgb3IgbW9yZQojIGNvbnRyaWJ1dG9yIGxpY2Vuc2UgYWdyZWVtZW50cy4gIFNlZSB0aGUgTk9USUNFIGZpbGUgZGlzdHJpYnV0ZWQgd2l0aAojIHRoaXMgd29yayBmb3IgYWRkaXRpb25hbCBpbmZvcm1hdGlvbiByZWdhcmRpbmcgY29weXJpZ2h0IG93bmVyc2hpcC4KIyBUaGUgQVNGIGxpY2Vuc2VzIHRoaXMgZmlsZSB0byBZb3UgdW5kZXIgdGhlIEFwYWNoZSBMaWNlbnNlLCBWZXJzaW9uIDIuMAojICh0aGUgIkxpY2Vuc2UiKTsgeW91IG1heSBub3QgdXNlIHRoaXMgZmlsZSBleGNlcHQgaW4gY29tcGxpYW5jZSB3aXRoCiMgdGhlIExpY2Vuc2UuICBZb3UgbWF5IG9idGFpbiBhIGNvcHkgb2YgdGhlIExpY2Vuc2UgYXQKIwojICAgIGh0dHA6Ly93d3cuYXBhY2hlLm9yZy9saWNlbnNlcy9MSUNFTlNFLTIuMAojCiMgVW5sZXNzIHJlcXVpcmVkIGJ5IGFwcGxpY2FibGUgbGF3IG9yIGFncmVlZCB0byBpbiB3cml0aW5nLCBzb2Z0d2FyZQojIGRpc3RyaWJ1dGVkIHVuZGVyIHRoZSBMaWNlbnNlIGlzIGRpc3RyaWJ1dGVkIG9uIGFuICJBUyBJUyIgQkFTSVMsCiMgV0lUSE9VVCBXQVJSQU5USUVTIE9SIENPTkRJVElPTlMgT0YgQU5ZIEtJTkQsIGVpdGhlciBleHByZXNzIG9yIGltcGxpZWQuCiMgU2VlIHRoZSBMaWNlbnNlIGZvciB0aGUgc3BlY2lmaWMgbGFuZ3VhZ2UgZ292ZXJuaW5nIHBlcm1pc3Npb25zIGFuZAojIGxpbWl0YXRpb25zIHVuZGVyIHRoZSBMaWNlbnNlLgojCgojIFNldCBldmVyeXRoaW5nIHRvIGJlIGxvZ2dlZCB0byB0aGUgY29uc29sZQpsb2c0ai5yb290Q2F0ZWdvcnk9SU5GTywgY29uc29sZQpsb2c0ai5hcHBlbmRlci5jb25zb2xlPW9yZy5hcGFjaGUubG9nNGouQ29uc29sZUFwcGVuZGVyCmxvZzRqLmFwcGVuZGVyLmNvbnNvbGUudGFyZ2V0PVN5c3RlbS5lcnIKbG9nNGouYXBwZW5kZXIuY29uc29sZS5sYXlvdXQ9b3JnLmFwYWNoZS5sb2c0ai5QYXR0ZXJuTGF5b3V0CmxvZzRqLmFwcGVuZGVyLmNvbnNvbGUubGF5b3V0LkNvbnZlcnNpb25QYXR0ZXJuPSVke3l5L01NL2RkIEhIOm1tOnNzfSAlcCAlY3sxfTogJW0lbgoKIyBTZXQgdGhlIGRlZmF1bHQgc3Bhcmstc2hlbGwgbG9nIGxldmVsIHRvIFdBUk4uIFdoZW4gcnVubmluZyB0aGUgc3Bhcmstc2hlbGwsIHRoZQojIGxvZyBsZXZlbCBmb3IgdGhpcyBjbGFzcyBpcyB1c2VkIHRvIG92ZXJ3cml0ZSB0aGUgcm9vdCBsb2dnZXIncyBsb2cgbGV2ZWwsIHNvIHRoYXQKIyB0aGUgdXNlciBjYW4gaGF2ZSBkaWZmZXJlbnQgZGVmYXVsdHMgZm9yIHRoZSBzaGVsbCBhbmQgcmVndWxhciBTcGFyayBhcHBzLgpsb2c0ai5sb2dnZXIub3JnLmFwYWNoZS5zcGFyay5yZXBsLk1haW49V0FSTgoKIyBTZXR0aW5ncyB0byBxdWlldCB0aGlyZCBwYXJ0eSBsb2dzIHRoYXQgYXJlIHRvbyB2ZXJib3NlCmxvZzRqLmxvZ2dlci5vcmcuc3BhcmtfcHJvamVjdC5qZXR0eT1XQVJOCmxvZzRqLmxvZ2dlci5vcmcuc3BhcmtfcHJvamVjdC5qZXR0eS51dGlsLmNvbXBvbmVudC5BYnN0cmFjdExpZmVDeWNsZT1FUlJPUgpsb2c0ai5sb2dnZXIub3JnLmFwYWNoZS5zcGFyay5yZXBsLlNwYXJrSU1haW4kZXhwclR5cGVyPUlORk8KbG9nNGoubG9nZ2VyLm9yZy5hcGFjaGUuc3BhcmsucmVwbC5TcGFya0lMb29wJFNwYXJrSUxvb3BJbnRlcnByZXRlcj1JTkZPCmxvZzRqLmxvZ2dlci5vcmcuYXBhY2hlLnBhcnF1ZXQ9RVJST1IKbG9nNGoubG9nZ2VyLnBhcnF1ZXQ9RVJST1IKCiMgU1BBUkstOTE4MzogU2V0dGluZ3MgdG8gYXZvaWQgYW5ub3lpbmcgbWVzc2FnZXMgd2hlbiBsb29raW5nIHVwIG5vbmV4aXN0ZW50IFVERnMgaW4gU3BhcmtTUUwgd2l0aCBIaXZlIHN1cHBvcnQKbG9nNGoubG9nZ2VyLm9yZy5hcGFjaGUuaGFkb29wLmhpdmUubWV0YXN0b3JlLlJldHJ5aW5nSE1TSGFuZGxlcj1GQVRBTApsb2c0ai5sb2dnZXIub3JnLmFwYWNoZS5oYWRvb3AuaGl2ZS5xbC5leGVjLkZ1bmN0aW9uUmVnaXN0cnk9RVJST1IKCiMgdGlzcGFyayBkaXNhYmxlICJXQVJOIE9iamVjdFN0b3JlOjU2OCAtIEZhaWxlZCB0byBnZXQgZGF0YWJhc2UiCmxvZzRqLmxvZ2dlci5vcmcuYXBhY2hlLmhhZG9vcC5oaXZlLm1ldGFzdG9yZS5PYmplY3RTdG9yZT1FUlJPUgo="
 	autogenFiles["/templates/scripts/dm/run_grafana.sh.tpl"] = "IyEvYmluL2Jhc2gKc2V0IC1lCgojIFdBUk5JTkc6IFRoaXMgZmlsZSB3YXMgYXV0by1nZW5lcmF0ZWQuIERvIG5vdCBlZGl0IQojICAgICAgICAgIEFsbCB5b3VyIGVkaXQgbWlnaHQgYmUgb3ZlcndyaXR0ZW4hCkRFUExPWV9ESVI9e3suRGVwbG95RGlyfX0KY2QgIiR7REVQTE9ZX0RJUn0iIHx8IGV4aXQgMQoKTEFORz1lbl9VUy5VVEYtOCBcCnt7LSBpZiAuTnVtYU5vZGV9fQpleGVjIG51bWFjdGwgLS1jcHVub2RlYmluZD17ey5OdW1hTm9kZX19IC0tbWVtYmluZD17ey5OdW1hTm9kZX19IGJpbi9iaW4vZ3JhZmFuYS1zZXJ2ZXIgXAp7ey0gZWxzZX19CmV4ZWMgYmluL2Jpbi9ncmFmYW5hLXNlcnZlciBcCnt7LSBlbmR9fQogICAgLS1ob21lcGF0aD0ie3suRGVwbG95RGlyfX0vYmluIiBcCiAgICAtLWNvbmZpZz0ie3suRGVwbG95RGlyfX0vY29uZi9ncmFmYW5hLmluaSIK"
@@ -34,7 +34,7 @@ func init() {
 	autogenFiles["/templates/scripts/run_dm-master_scale.sh.tpl"] = "IyEvYmluL2Jhc2gKc2V0IC1lCgojIFdBUk5JTkc6IFRoaXMgZmlsZSB3YXMgYXV0by1nZW5lcmF0ZWQuIERvIG5vdCBlZGl0IQojICAgICAgICAgIEFsbCB5b3VyIGVkaXQgbWlnaHQgYmUgb3ZlcndyaXR0ZW4hCkRFUExPWV9ESVI9e3suRGVwbG95RGlyfX0KY2QgIiR7REVQTE9ZX0RJUn0iIHx8IGV4aXQgMQoKe3stIGRlZmluZSAiTWFzdGVyTGlzdCJ9fQogIHt7LSByYW5nZSAkaWR4LCAkbWFzdGVyIDo9IC59fQogICAge3stIGlmIGVxICRpZHggMH19CiAgICAgIHt7LSAkbWFzdGVyLklQfX06e3skbWFzdGVyLlBvcnR9fQogICAge3stIGVsc2UgLX19CiAgICAgICx7ey0gJG1hc3Rlci5JUH19Ont7JG1hc3Rlci5Qb3J0fX0KICAgIHt7LSBlbmR9fQogIHt7LSBlbmR9fQp7ey0gZW5kfX0KCnt7LSBpZiAuTnVtYU5vZGV9fQpleGVjIG51bWFjdGwgLS1jcHVub2RlYmluZD17ey5OdW1hTm9kZX19IC0tbWVtYmluZD17ey5OdW1hTm9kZX19IGJpbi9kbS1tYXN0ZXIvZG0tbWFzdGVyIFwKe3stIGVsc2V9fQpleGVjIGJpbi9kbS1tYXN0ZXIvZG0tbWFzdGVyIFwKe3stIGVuZH19CiAgICAtLW5hbWU9Int7Lk5hbWV9fSIgXAogICAgLS1tYXN0ZXItYWRkcj0iMC4wLjAuMDp7ey5Qb3J0fX0iIFwKICAgIC0tYWR2ZXJ0aXNlLWFkZHI9Int7LklQfX06e3suUG9ydH19IiBcCiAgICAtLXBlZXItdXJscz0ie3suU2NoZW1lfX06Ly97ey5JUH19Ont7LlBlZXJQb3J0fX0iIFwKICAgIC0tYWR2ZXJ0aXNlLXBlZXItdXJscz0ie3suU2NoZW1lfX06Ly97ey5JUH19Ont7LlBlZXJQb3J0fX0iIFwKICAgIC0tbG9nLWZpbGU9Int7LkxvZ0Rpcn19L2RtLW1hc3Rlci5sb2ciIFwKICAgIC0tZGF0YS1kaXI9Int7LkRhdGFEaXJ9fSIgXAogICAgLS1qb2luPSJ7e3RlbXBsYXRlICJNYXN0ZXJMaXN0IiAuRW5kcG9pbnRzfX0iIFwKICAgIC0tY29uZmlnPWNvbmYvZG0tbWFzdGVyLnRvbWwgMj4+ICJ7ey5Mb2dEaXJ9fS9kbS1tYXN0ZXJfc3RkZXJyLmxvZyIK"
 	autogenFiles["/templates/scripts/run_dm-worker.sh.tpl"] = "IyEvYmluL2Jhc2gKc2V0IC1lCgojIFdBUk5JTkc6IFRoaXMgZmlsZSB3YXMgYXV0by1nZW5lcmF0ZWQuIERvIG5vdCBlZGl0IQojICAgICAgICAgIEFsbCB5b3VyIGVkaXQgbWlnaHQgYmUgb3ZlcndyaXR0ZW4hCkRFUExPWV9ESVI9e3suRGVwbG95RGlyfX0KCmNkICIke0RFUExPWV9ESVJ9IiB8fCBleGl0IDEKCnt7LSBkZWZpbmUgIk1hc3Rlckxpc3QifX0KICB7ey0gcmFuZ2UgJGlkeCwgJG1hc3RlciA6PSAufX0KICAgIHt7LSBpZiBlcSAkaWR4IDB9fQogICAgICB7ey0gJG1hc3Rlci5JUH19Ont7JG1hc3Rlci5Qb3J0fX0KICAgIHt7LSBlbHNlIC19fQogICAgICAse3skbWFzdGVyLklQfX06e3skbWFzdGVyLlBvcnR9fQogICAge3stIGVuZH19CiAge3stIGVuZH19Cnt7LSBlbmR9fQoKe3stIGlmIC5OdW1hTm9kZX19CmV4ZWMgbnVtYWN0bCAtLWNwdW5vZGViaW5kPXt7Lk51bWFOb2RlfX0gLS1tZW1iaW5kPXt7Lk51bWFOb2RlfX0gYmluL2RtLXdvcmtlci9kbS13b3JrZXIgXAp7ey0gZWxzZX19CmV4ZWMgYmluL2RtLXdvcmtlci9kbS13b3JrZXIgXAp7ey0gZW5kfX0KICAgIC0tbmFtZT0ie3suTmFtZX19IiBcCiAgICAtLXdvcmtlci1hZGRyPSIwLjAuMC4wOnt7LlBvcnR9fSIgXAogICAgLS1hZHZlcnRpc2UtYWRkcj0ie3suSVB9fTp7ey5Qb3J0fX0iIFwKICAgIC0tbG9nLWZpbGU9Int7LkxvZ0Rpcn19L2RtLXdvcmtlci5sb2ciIFwKICAgIC0tam9pbj0ie3t0ZW1wbGF0ZSAiTWFzdGVyTGlzdCIgLkVuZHBvaW50c319IgogICAgLS1jb25maWc9Y29uZi9kbS13b3JrZXIudG9tbCAyPj4gInt7LkxvZ0Rpcn19L2RtLXdvcmtlcl9zdGRlcnIubG9nIgo="
 	autogenFiles["/templates/scripts/run_drainer.sh.tpl"] = "IyEvYmluL2Jhc2gKc2V0IC1lCgojIFdBUk5JTkc6IFRoaXMgZmlsZSB3YXMgYXV0by1nZW5lcmF0ZWQuIERvIG5vdCBlZGl0IQojICAgICAgICAgIEFsbCB5b3VyIGVkaXQgbWlnaHQgYmUgb3ZlcndyaXR0ZW4hCkRFUExPWV9ESVI9e3suRGVwbG95RGlyfX0KCmNkICIke0RFUExPWV9ESVJ9IiB8fCBleGl0IDEKCnt7LSBkZWZpbmUgIlBETGlzdCJ9fQogIHt7LSByYW5nZSAkaWR4LCAkcGQgOj0gLn19CiAgICB7ey0gaWYgZXEgJGlkeCAwfX0KICAgICAge3stICRwZC5TY2hlbWV9fTovL3t7JHBkLklQfX06e3skcGQuQ2xpZW50UG9ydH19CiAgICB7ey0gZWxzZSAtfX0KICAgICAgLHt7LSAkcGQuU2NoZW1lfX06Ly97eyRwZC5JUH19Ont7JHBkLkNsaWVudFBvcnR9fQogICAge3stIGVuZH19CiAge3stIGVuZH19Cnt7LSBlbmR9fQoKe3stIGlmIC5OdW1hTm9kZX19CmV4ZWMgbnVtYWN0bCAtLWNwdW5vZGViaW5kPXt7Lk51bWFOb2RlfX0gLS1tZW1iaW5kPXt7Lk51bWFOb2RlfX0gYmluL2RyYWluZXIgXAp7ey0gZWxzZX19CmV4ZWMgYmluL2RyYWluZXIgXAp7ey0gZW5kfX0KICAgIC0tbm9kZS1pZD0ie3suTm9kZUlEfX0iIFwKICAgIC0tYWRkcj0ie3suSVB9fTp7ey5Qb3J0fX0iIFwKICAgIC0tcGQtdXJscz0ie3t0ZW1wbGF0ZSAiUERMaXN0IiAuRW5kcG9pbnRzfX0iIFwKICAgIC0tZGF0YS1kaXI9Int7LkRhdGFEaXJ9fSIgXAogICAgLS1sb2ctZmlsZT0ie3suTG9nRGlyfX0vZHJhaW5lci5sb2ciIFwKICAgIC0tY29uZmlnPWNvbmYvZHJhaW5lci50b21sIFwKICAgIC0taW5pdGlhbC1jb21taXQtdHM9Int7LkNvbW1pdFRzfX0iIDI+PiAie3suTG9nRGlyfX0vZHJhaW5lcl9zdGRlcnIubG9nIgo="
-	autogenFiles["/templates/scripts/run_grafana.sh.tpl"] = "IyEvYmluL2Jhc2gKc2V0IC1lCgojIFdBUk5JTkc6IFRoaXMgZmlsZSB3YXMgYXV0by1nZW5lcmF0ZWQuIERvIG5vdCBlZGl0IQojICAgICAgICAgIEFsbCB5b3VyIGVkaXQgbWlnaHQgYmUgb3ZlcndyaXR0ZW4hCkRFUExPWV9ESVI9e3suRGVwbG95RGlyfX0KY2QgIiR7REVQTE9ZX0RJUn0iIHx8IGV4aXQgMQoKbWtkaXIgLXAge3suRGVwbG95RGlyfX0vcGx1Z2lucwpta2RpciAtcCB7ey5EZXBsb3lEaXJ9fS9kYXNoYm9hcmRzCm1rZGlyIC1wIHt7LkRlcGxveURpcn19L3Byb3Zpc2lvbmluZy9kYXNoYm9hcmRzCm1rZGlyIC1wIHt7LkRlcGxveURpcn19L3Byb3Zpc2lvbmluZy9kYXRhc291cmNlcwoKY3Age3suRGVwbG95RGlyfX0vYmluLyouanNvbiB7ey5EZXBsb3lEaXJ9fS9kYXNoYm9hcmRzLwpjcCB7ey5EZXBsb3lEaXJ9fS9jb25mL2RhdGFzb3VyY2UueW1sIHt7LkRlcGxveURpcn19L3Byb3Zpc2lvbmluZy9kYXRhc291cmNlcwpjcCB7ey5EZXBsb3lEaXJ9fS9jb25mL2Rhc2hib2FyZC55bWwge3suRGVwbG95RGlyfX0vcHJvdmlzaW9uaW5nL2Rhc2hib2FyZHMKCmZpbmQge3suRGVwbG95RGlyfX0vZGFzaGJvYXJkcy8gLXR5cGUgZiAtZXhlYyBzZWQgLWkgInMvXCR7RFNfLiotQ0xVU1RFUn0ve3suQ2x1c3Rlck5hbWV9fS9nIiB7fSBcOwpmaW5kIHt7LkRlcGxveURpcn19L2Rhc2hib2FyZHMvIC10eXBlIGYgLWV4ZWMgc2VkIC1pICJzL1wke0RTX0xJR0hUTklOR30ve3suQ2x1c3Rlck5hbWV9fS9nIiB7fSBcOwpmaW5kIHt7LkRlcGxveURpcn19L2Rhc2hib2FyZHMvIC10eXBlIGYgLWV4ZWMgc2VkIC1pICJzL3Rlc3QtY2x1c3Rlci97ey5DbHVzdGVyTmFtZX19L2ciIHt9IFw7CmZpbmQge3suRGVwbG95RGlyfX0vZGFzaGJvYXJkcy8gLXR5cGUgZiAtZXhlYyBzZWQgLWkgInMvVGVzdC1DbHVzdGVyL3t7LkNsdXN0ZXJOYW1lfX0vZyIge30gXDsKCkxBTkc9ZW5fVVMuVVRGLTggXAp7ey0gaWYgLk51bWFOb2RlfX0KZXhlYyBudW1hY3RsIC0tY3B1bm9kZWJpbmQ9e3suTnVtYU5vZGV9fSAtLW1lbWJpbmQ9e3suTnVtYU5vZGV9fSBiaW4vYmluL2dyYWZhbmEtc2VydmVyIFwKe3stIGVsc2V9fQpleGVjIGJpbi9iaW4vZ3JhZmFuYS1zZXJ2ZXIgXAp7ey0gZW5kfX0KICAgIC0taG9tZXBhdGg9Int7LkRlcGxveURpcn19L2JpbiIgXAogICAgLS1jb25maWc9Int7LkRlcGxveURpcn19L2NvbmYvZ3JhZmFuYS5pbmkiCg=="
+	autogenFiles["/templates/scripts/run_grafana.sh.tpl"] = "IyEvYmluL2Jhc2gKc2V0IC1lCgojIFdBUk5JTkc6IFRoaXMgZmlsZSB3YXMgYXV0by1nZW5lcmF0ZWQuIERvIG5vdCBlZGl0IQojICAgICAgICAgIEFsbCB5b3VyIGVkaXQgbWlnaHQgYmUgb3ZlcndyaXR0ZW4hCkRFUExPWV9ESVI9e3suRGVwbG95RGlyfX0KY2QgIiR7REVQTE9ZX0RJUn0iIHx8IGV4aXQgMQoKbWtkaXIgLXAge3suRGVwbG95RGlyfX0vcGx1Z2lucwpta2RpciAtcCB7ey5EZXBsb3lEaXJ9fS9kYXNoYm9hcmRzCm1rZGlyIC1wIHt7LkRlcGxveURpcn19L3Byb3Zpc2lvbmluZy9kYXNoYm9hcmRzCm1rZGlyIC1wIHt7LkRlcGxveURpcn19L3Byb3Zpc2lvbmluZy9kYXRhc291cmNlcwoKY3Age3suRGVwbG95RGlyfX0vYmluLyouanNvbiB7ey5EZXBsb3lEaXJ9fS9kYXNoYm9hcmRzLwp7ey0gaWYgLlByb3Zpc2lvbkZpbGVzfX0KY3Age3suRGVwbG95RGlyfX0vY29uZi9kYXRhc291cmNlLnltbCB7ey5EZXBsb3lEaXJ9fS9wcm92aXNpb25pbmcvZGF0YXNvdXJjZXMKY3Age3suRGVwbG95RGlyfX0vY29uZi9kYXNoYm9hcmQueW1sIHt7LkRlcGxveURpcn19L3Byb3Zpc2lvbmluZy9kYXNoYm9hcmRzCnt7LSBlbHNlfX0KIyB0aGUgZGFzaGJvYXJkcyBhbmQgdGhlIGRhdGFzb3VyY2UgYXJlIHByb3Zpc2lvbmVkIHZpYSB0aGUgSFRUUCBBUEkKcm0gLWYge3suRGVwbG95RGlyfX0vcHJvdmlzaW9uaW5nL2RhdGFzb3VyY2VzL2RhdGFzb3VyY2UueW1sCnJtIC1mIHt7LkRlcGxveURpcn19L3Byb3Zpc2lvbmluZy9kYXNoYm9hcmRzL2Rhc2hib2FyZC55bWwKe3stIGVuZH19CgpmaW5kIHt7LkRlcGxveURpcn19L2Rhc2hib2FyZHMvIC10eXBlIGYgLWV4ZWMgc2VkIC1pICJzL1wke0RTXy4qLUNMVVNURVJ9L3t7LkNsdXN0ZXJOYW1lfX0vZyIge30gXDsKZmluZCB7ey5EZXBsb3lEaXJ9fS9kYXNoYm9hcmRzLyAtdHlwZSBmIC1leGVjIHNlZCAtaSAicy9cJHtEU19MSUdIVE5JTkd9L3t7LkNsdXN0ZXJOYW1lfX0vZyIge30gXDsKZmluZCB7ey5EZXBsb3lEaXJ9fS9kYXNoYm9hcmRzLyAtdHlwZSBmIC1leGVjIHNlZCAtaSAicy90ZXN0LWNsdXN0ZXIve3suQ2x1c3Rlck5hbWV9fS9nIiB7fSBcOwpmaW5kIHt7LkRlcGxveURpcn19L2Rhc2hib2FyZHMvIC10eXBlIGYgLWV4ZWMgc2VkIC1pICJzL1Rlc3QtQ2x1c3Rlci97ey5DbHVzdGVyTmFtZX19L2ciIHt9IFw7CgpMQU5HPWVuX1VTLlVURi04IFwKe3stIGlmIC5OdW1hTm9kZX19CmV4ZWMgbnVtYWN0bCAtLWNwdW5vZGViaW5kPXt7Lk51bWFOb2RlfX0gLS1tZW1iaW5kPXt7Lk51bWFOb2RlfX0gYmluL2Jpbi9ncmFmYW5hLXNlcnZlciBcCnt7LSBlbHNlfX0KZXhlYyBiaW4vYmluL2dyYWZhbmEtc2VydmVyIFwKe3stIGVuZH19CiAgICAtLWhvbWVwYXRoPSJ7ey5EZXBsb3lEaXJ9fS9iaW4iIFwKICAgIC0tY29uZmlnPSJ7ey5EZXBsb3lEaXJ9fS9jb25mL2dyYWZhbmEuaW5pIgo="
 	autogenFiles["/templates/scripts/run_node_exporter.sh.tpl"] = "IyEvYmluL2Jhc2gKc2V0IC1lCgojIFdBUk5JTkc6IFRoaXMgZmlsZSB3YXMgYXV0by1nZW5lcmF0ZWQuIERvIG5vdCBlZGl0IQojICAgICAgICAgIEFsbCB5b3VyIGVkaXQgbWlnaHQgYmUgb3ZlcndyaXR0ZW4hCkRFUExPWV9ESVI9e3suRGVwbG95RGlyfX0KY2QgIiR7REVQTE9ZX0RJUn0iIHx8IGV4aXQgMQoKZXhlYyA+ID4odGVlIC1pIC1hICJ7ey5Mb2dEaXJ9fS9ub2RlX2V4cG9ydGVyLmxvZyIpCmV4ZWMgMj4mMQoKe3stIGlmIC5OdW1hTm9kZX19CmV4ZWMgbnVtYWN0bCAtLWNwdW5vZGViaW5kPXt7Lk51bWFOb2RlfX0gLS1tZW1iaW5kPXt7Lk51bWFOb2RlfX0gYmluL25vZGVfZXhwb3J0ZXIvbm9kZV9leHBvcnRlciBcCnt7LSBlbHNlfX0KZXhlYyBiaW4vbm9kZV9leHBvcnRlci9ub2RlX2V4cG9ydGVyIFwKe3stIGVuZH19CiAgICAtLXdlYi5saXN0ZW4tYWRkcmVzcz0iOnt7LlBvcnR9fSIgXAogICAgLS1jb2xsZWN0b3IudGNwc3RhdCBcCiAgICAtLWNvbGxlY3Rvci5zeXN0ZW1kIFwKICAgIC0tY29sbGVjdG9yLm1vdW50c3RhdHMgXAogICAgLS1jb2xsZWN0b3IubWVtaW5mb19udW1hIFwKICAgIC0tY29sbGVjdG9yLmludGVycnVwdHMgXAogICAgLS1jb2xsZWN0b3Iudm1zdGF0LmZpZWxkcz0iXi4qIiBcCiAgICAtLWxvZy5sZXZlbD0iaW5mbyIK"
 	autogenFiles["/templates/scripts/run_pd.sh.tpl"] = "IyEvYmluL2Jhc2gKc2V0IC1lCgojIFdBUk5JTkc6IFRoaXMgZmlsZSB3YXMgYXV0by1nZW5lcmF0ZWQuIERvIG5vdCBlZGl0IQojICAgICAgICAgIEFsbCB5b3VyIGVkaXQgbWlnaHQgYmUgb3ZlcndyaXR0ZW4hCkRFUExPWV9ESVI9e3suRGVwbG95RGlyfX0KCmNkICIke0RFUExPWV9ESVJ9IiB8fCBleGl0IDEKCnt7LSBkZWZpbmUgIlBETGlzdCJ9fQogIHt7LSByYW5nZSAkaWR4LCAkcGQgOj0gLn19CiAgICB7ey0gaWYgZXEgJGlkeCAwfX0KICAgICAge3stICRwZC5OYW1lfX09e3skcGQuU2NoZW1lfX06Ly97eyRwZC5JUH19Ont7JHBkLlBlZXJQb3J0fX0KICAgIHt7LSBlbHNlIC19fQogICAgICAse3stICRwZC5OYW1lfX09e3skcGQuU2NoZW1lfX06Ly97eyRwZC5JUH19Ont7JHBkLlBlZXJQb3J0fX0KICAgIHt7LSBlbmR9fQogIHt7LSBlbmR9fQp7ey0gZW5kfX0KCnt7LSBpZiAuTnVtYU5vZGV9fQpleGVjIG51bWFjdGwgLS1jcHVub2RlYmluZD17ey5OdW1hTm9kZX19IC0tbWVtYmluZD17ey5OdW1hTm9kZX19IGJpbi9wZC1zZXJ2ZXIgXAp7ey0gZWxzZX19CmV4ZWMgYmluL3BkLXNlcnZlciBcCnt7LSBlbmR9fQogICAgLS1uYW1lPSJ7ey5OYW1lfX0iIFwKICAgIC0tY2xpZW50LXVybHM9Int7LlNjaGVtZX19Oi8ve3suTGlzdGVuSG9zdH19Ont7LkNsaWVudFBvcnR9fSIgXAogICAgLS1hZHZlcnRpc2UtY2xpZW50LXVybHM9Int7LlNjaGVtZX19Oi8ve3suSVB9fTp7ey5DbGllbnRQb3J0fX0iIFwKICAgIC0tcGVlci11cmxzPSJ7ey5TY2hlbWV9fTovL3t7LklQfX06e3suUGVlclBvcnR9fSIgXAogICAgLS1hZHZlcnRpc2UtcGVlci11cmxzPSJ7ey5TY2hlbWV9fTovL3t7LklQfX06e3suUGVlclBvcnR9fSIgXAogICAgLS1kYXRhLWRpcj0ie3suRGF0YURpcn19IiBcCiAgICAtLWluaXRpYWwtY2x1c3Rlcj0ie3t0ZW1wbGF0ZSAiUERMaXN0IiAuRW5kcG9pbnRzfX0iIFwKICAgIC0tY29uZmlnPWNvbmYvcGQudG9tbCBcCiAgICAtLWxvZy1maWxlPSJ7ey5Mb2dEaXJ9fS9wZC5sb2ciIDI+PiAie3suTG9nRGlyfX0vcGRfc3RkZXJyLmxvZyIKICAK"
 	autogenFiles["/templates/scripts/run_pd_scale.sh.tpl"] = "IyEvYmluL2Jhc2gKc2V0IC1lCgojIFdBUk5JTkc6IFRoaXMgZmlsZSB3YXMgYXV0by1nZW5lcmF0ZWQuIERvIG5vdCBlZGl0IQojICAgICAgICAgIEFsbCB5b3VyIGVkaXQgbWlnaHQgYmUgb3ZlcndyaXR0ZW4hCkRFUExPWV9ESVI9e3suRGVwbG95RGlyfX0KCmNkICIke0RFUExPWV9ESVJ9IiB8fCBleGl0IDEKCnt7LSBkZWZpbmUgIlBETGlzdCJ9fQogIHt7LSByYW5nZSAkaWR4LCAkcGQgOj0gLn19CiAgICB7ey0gaWYgZXEgJGlkeCAwfX0KICAgICAge3stICRwZC5TY2hlbWV9fTovL3t7JHBkLklQfX06e3skcGQuQ2xpZW50UG9ydH19CiAgICB7ey0gZWxzZSAtfX0KICAgICAgLHt7LSAkcGQuU2NoZW1lfX06Ly97eyRwZC5JUH19Ont7JHBkLkNsaWVudFBvcnR9fQogICAge3stIGVuZH19CiAge3stIGVuZH19Cnt7LSBlbmR9fQoKe3stIGlmIC5OdW1hTm9kZX19CmV4ZWMgbnVtYWN0bCAtLWNwdW5vZGViaW5kPXt7Lk51bWFOb2RlfX0gLS1tZW1iaW5kPXt7Lk51bWFOb2RlfX0gYmluL3BkLXNlcnZlciBcCnt7LSBlbHNlfX0KZXhlYyBiaW4vcGQtc2VydmVyIFwKe3stIGVuZH19CiAgICAtLW5hbWU9Int7Lk5hbWV9fSIgXAogICAgLS1jbGllbnQtdXJscz0ie3suU2NoZW1lfX06Ly97ey5MaXN0ZW5Ib3N0fX06e3suQ2xpZW50UG9ydH19IiBcCiAgICAtLWFkdmVydGlzZS1jbGllbnQtdXJscz0ie3suU2NoZW1lfX06Ly97ey5JUH19Ont7LkNsaWVudFBvcnR9fSIgXAogICAgLS1wZWVyLXVybHM9Int7LlNjaGVtZX19Oi8ve3suSVB9fTp7ey5QZWVyUG9ydH19IiBcCiAgICAtLWFkdmVydGlzZS1wZWVyLXVybHM9Int7LlNjaGVtZX19Oi8ve3suSVB9fTp7ey5QZWVyUG9ydH19IiBcCiAgICAtLWRhdGEtZGlyPSJ7ey5EYXRhRGlyfX0iIFwKICAgIC0tam9pbj0ie3t0ZW1wbGF0ZSAiUERMaXN0IiAuRW5kcG9pbnRzfX0iIFwKICAgIC0tY29uZmlnPWNvbmYvcGQudG9tbCBcCiAgICAtLWxvZy1maWxlPSJ7ey5Mb2dEaXJ9fS9wZC5sb2ciIDI+PiAie3suTG9nRGlyfX0vcGRfc3RkZXJyLmxvZyIKICAK"
@@ -45,6 +45,7 @@ func init() {
 	autogenFiles["/templates/scripts/run_tikv.sh.tpl"] = "IyEvYmluL2Jhc2gKc2V0IC1lCgojIFdBUk5JTkc6IFRoaXMgZmlsZSB3YXMgYXV0by1nZW5lcmF0ZWQuIERvIG5vdCBlZGl0IQojICAgICAgICAgIEFsbCB5b3VyIGVkaXQgbWlnaHQgYmUgb3ZlcndyaXR0ZW4hCmNkICJ7ey5EZXBsb3lEaXJ9fSIgfHwgZXhpdCAxCgplY2hvIC1uICdzeW5jIC4uLiAnCnN0YXQ9JCh0aW1lIHN5bmMgfHwgc3luYykKZWNobyBvawplY2hvICRzdGF0Cgp7ey0gZGVmaW5lICJQRExpc3QifX0KICB7ey0gcmFuZ2UgJGlkeCwgJHBkIDo9IC59fQogICAge3stIGlmIGVxICRpZHggMH19CiAgICAgIHt7LSAkcGQuSVB9fTp7eyRwZC5DbGllbnRQb3J0fX0KICAgIHt7LSBlbHNlIC19fQogICAgICAse3skcGQuSVB9fTp7eyRwZC5DbGllbnRQb3J0fX0KICAgIHt7LSBlbmR9fQogIHt7LSBlbmR9fQp7ey0gZW5kfX0KCnt7LSBpZiAuTnVtYU5vZGV9fQpleGVjIG51bWFjdGwgLS1jcHVub2RlYmluZD17ey5OdW1hTm9kZX19IC0tbWVtYmluZD17ey5OdW1hTm9kZX19IGJpbi90aWt2LXNlcnZlciBcCnt7LSBlbHNlfX0KZXhlYyBiaW4vdGlrdi1zZXJ2ZXIgXAp7ey0gZW5kfX0KICAgIC0tYWRkciAie3suTGlzdGVuSG9zdH19Ont7LlBvcnR9fSIgXAogICAgLS1hZHZlcnRpc2UtYWRkciAie3suSVB9fTp7ey5Qb3J0fX0iIFwKICAgIC0tc3RhdHVzLWFkZHIgInt7LklQfX06e3suU3RhdHVzUG9ydH19IiBcCiAgICAtLXBkICJ7e3RlbXBsYXRlICJQRExpc3QiIC5FbmRwb2ludHN9fSIgXAogICAgLS1kYXRhLWRpciAie3suRGF0YURpcn19IiBcCiAgICAtLWNvbmZpZyBjb25mL3Rpa3YudG9tbCBcCiAgICAtLWxvZy1maWxlICJ7ey5Mb2dEaXJ9fS90aWt2LmxvZyIgMj4+ICJ7ey5Mb2dEaXJ9fS90aWt2X3N0ZGVyci5sb2ciCg=="
 	autogenFiles["/templates/scripts/spark-env.sh.tpl"] = "IyEvdXNyL2Jpbi9lbnYgYmFzaAoKIwojIExpY2Vuc2VkIHRvIHRoZSBBcGFjaGUgU29mdHdhcmUgRm91bmRhdGlvbiAoQVNGKSB1bmRlciBvbmUgb3IgbW9yZQojIGNvbnRyaWJ1dG9yIGxpY2Vuc2UgYWdyZWVtZW50cy4gIFNlZSB0aGUgTk9USUNFIGZpbGUgZGlzdHJpYnV0ZWQgd2l0aAojIHRoaXMgd29yayBmb3IgYWRkaXRpb25hbCBpbmZvcm1hdGlvbiByZWdhcmRpbmcgY29weXJpZ2h0IG93bmVyc2hpcC4KIyBUaGUgQVNGIGxpY2Vuc2VzIHRoaXMgZmlsZSB0byBZb3UgdW5kZXIgdGhlIEFwYWNoZSBMaWNlbnNlLCBWZXJzaW9uIDIuMAojICh0aGUgIkxpY2Vuc2UiKTsgeW91IG1heSBub3QgdXNlIHRoaXMgZmlsZSBleGNlcHQgaW4gY29tcGxpYW5jZSB3aXRoCiMgdGhlIExpY2Vuc2UuICBZb3UgbWF5IG9idGFpbiBhIGNvcHkgb2YgdGhlIExpY2Vuc2UgYXQKIwojICAgIGh0dHA6Ly93d3cuYXBhY2hlLm9yZy9saWNlbnNlcy9MSUNFTlNFLTIuMAojCiMgVW5sZXNzIHJlcXVpcmVkIGJ5IGFwcGxpY2FibGUgbGF3IG9yIGFncmVlZCB0byBpbiB3cml0aW5nLCBzb2Z0d2FyZQojIGRpc3RyaWJ1dGVkIHVuZGVyIHRoZSBMaWNlbnNlIGlzIGRpc3RyaWJ1dGVkIG9uIGFuICJBUyBJUyIgQkFTSVMsCiMgV0lUSE9VVCBXQVJSQU5USUVTIE9SIENPTkRJVElPTlMgT0YgQU5ZIEtJTkQsIGVpdGhlciBleHByZXNzIG9yIGltcGxpZWQuCiMgU2VlIHRoZSBMaWNlbnNlIGZvciB0aGUgc3BlY2lmaWMgbGFuZ3VhZ2UgZ292ZXJuaW5nIHBlcm1pc3Npb25zIGFuZAojIGxpbWl0YXRpb25zIHVuZGVyIHRoZSBMaWNlbnNlLgojCgojIFRoaXMgZmlsZSBpcyBzb3VyY2VkIHdoZW4gcnVubmluZyB2YXJpb3VzIFNwYXJrIHByb2dyYW1zLgojIENvcHkgaXQgYXMgc3BhcmstZW52LnNoIGFuZCBlZGl0IHRoYXQgdG8gY29uZmlndXJlIFNwYXJrIGZvciB5b3VyIHNpdGUuCgojIE9wdGlvbnMgcmVhZCB3aGVuIGxhdW5jaGluZyBwcm9ncmFtcyBsb2NhbGx5IHdpdGgKIyAuL2Jpbi9ydW4tZXhhbXBsZSBvciAuL2Jpbi9zcGFyay1zdWJtaXQKIyAtIEhBRE9PUF9DT05GX0RJUiwgdG8gcG9pbnQgU3BhcmsgdG93YXJkcyBIYWRvb3AgY29uZmlndXJhdGlvbiBmaWxlcwojIC0gU1BBUktfTE9DQUxfSVAsIHRvIHNldCB0aGUgSVAgYWRkcmVzcyBTcGFyayBiaW5kcyB0byBvbiB0aGlzIG5vZGUKIyAtIFNQQVJLX1BVQkxJQ19ETlMsIHRvIHNldCB0aGUgcHVibGljIGRucyBuYW1lIG9mIHRoZSBkcml2ZXIgcHJvZ3JhbQojIC0gU1BBUktfQ0xBU1NQQVRILCBkZWZhdWx0IGNsYXNzcGF0aCBlbnRyaWVzIHRvIGFwcGVuZAoKIyBPcHRpb25zIHJlYWQgYnkgZXhlY3V0b3JzIGFuZCBkcml2ZXJzIHJ1bm5pbmcgaW5zaWRlIHRoZSBjbHVzdGVyCiMgLSBTUEFSS19MT0NBTF9JUCwgdG8gc2V0IHRoZSBJUCBhZGRyZXNzIFNwYXJrIGJpbmRzIHRvIG9uIHRoaXMgbm9kZQojIC0gU1BBUktfUFVCTElDX0ROUywgdG8gc2V0IHRoZSBwdWJsaWMgRE5TIG5hbWUgb2YgdGhlIGRyaXZlciBwcm9ncmFtCiMgLSBTUEFSS19DTEFTU1BBVEgsIGRlZmF1bHQgY2xhc3NwYXRoIGVudHJpZXMgdG8gYXBwZW5kCiMgLSBTUEFSS19MT0NBTF9ESVJTLCBzdG9yYWdlIGRpcmVjdG9yaWVzIHRvIHVzZSBvbiB0aGlzIG5vZGUgZm9yIHNodWZmbGUgYW5kIFJERCBkYXRhCiMgLSBNRVNPU19OQVRJVkVfSkFWQV9MSUJSQVJZLCB0byBwb2ludCB0byB5b3VyIGxpYm1lc29zLnNvIGlmIHlvdSB1c2UgTWVzb3MKCiMgT3B0aW9ucyByZWFkIGluIFlBUk4gY2xpZW50IG1vZGUKIyAtIEhBRE9PUF9DT05GX0RJUiwgdG8gcG9pbnQgU3BhcmsgdG93YXJkcyBIYWRvb3AgY29uZmlndXJhdGlvbiBmaWxlcwojIC0gU1BBUktfRVhFQ1VUT1JfSU5TVEFOQ0VTLCBOdW1iZXIgb2YgZXhlY3V0b3JzIHRvIHN0YXJ0IChEZWZhdWx0OiAyKQojIC0gU1BBUktfRVhFQ1VUT1JfQ09SRVMsIE51bWJlciBvZiBjb3JlcyBmb3IgdGhlIGV4ZWN1dG9ycyAoRGVmYXVsdDogMSkuCiMgLSBTUEFSS19FWEVDVVRPUl9NRU1PUlksIE1lbW9yeSBwZXIgRXhlY3V0b3IgKGUuZy4gMTAwME0sIDJHKSAoRGVmYXVsdDogMUcpCiMgLSBTUEFSS19EUklWRVJfTUVNT1JZLCBNZW1vcnkgZm9yIERyaXZlciAoZS5nLiAxMDAwTSwgMkcpIChEZWZhdWx0OiAxRykKCiMgT3B0aW9ucyBmb3IgdGhlIGRhZW1vbnMgdXNlZCBpbiB0aGUgc3RhbmRhbG9uZSBkZXBsb3kgbW9kZQojIC0gU1BBUktfTUFTVEVSX0hPU1QsIHRvIGJpbmQgdGhlIG1hc3RlciB0byBhIGRpZmZlcmVudCBJUCBhZGRyZXNzIG9yIGhvc3RuYW1lCiMgLSBTUEFSS19NQVNURVJfUE9SVCAvIFNQQVJLX01BU1RFUl9XRUJVSV9QT1JULCB0byB1c2Ugbm9uLWRlZmF1bHQgcG9ydHMgZm9yIHRoZSBtYXN0ZXIKIyAtIFNQQVJLX01BU1RFUl9PUFRTLCB0byBzZXQgY29uZmlnIHByb3BlcnRpZXMgb25seSBmb3IgdGhlIG1hc3RlciAoZS5nLiAiLUR4PXkiKQojIC0gU1BBUktfV09SS0VSX0NPUkVTLCB0byBzZXQgdGhlIG51bWJlciBvZiBjb3JlcyB0byB1c2Ugb24gdGhpcyBtYWNoaW5lCiMgLSBTUEFSS19XT1JLRVJfTUVNT1JZLCB0byBzZXQgaG93IG11Y2ggdG90YWwgbWVtb3J5IHdvcmtlcnMgaGF2ZSB0byBnaXZlIGV4ZWN1dG9ycyAoZS5nLiAxMDAwbSwgMmcpCiMgLSBTUEFSS19XT1JLRVJfUE9SVCAvIFNQQVJLX1dPUktFUl9XRUJVSV9QT1JULCB0byB1c2Ugbm9uLWRlZmF1bHQgcG9ydHMgZm9yIHRoZSB3b3JrZXIKIyAtIFNQQVJLX1dPUktFUl9JTlNUQU5DRVMsIHRvIHNldCB0aGUgbnVtYmVyIG9mIHdvcmtlciBwcm9jZXNzZXMgcGVyIG5vZGUKIyAtIFNQQVJLX1dPUktFUl9ESVIsIHRvIHNldCB0aGUgd29ya2luZyBkaXJlY3Rvcnkgb2Ygd29ya2VyIHByb2Nlc3NlcwojIC0gU1BBUktfV09SS0VSX09QVFMsIHRvIHNldCBjb25maWcgcHJvcGVydGllcyBvbmx5IGZvciB0aGUgd29ya2VyIChlLmcuICItRHg9eSIpCiMgLSBTUEFSS19EQUVNT05fTUVNT1JZLCB0byBhbGxvY2F0ZSB0byB0aGUgbWFzdGVyLCB3b3JrZXIgYW5kIGhpc3Rvcnkgc2VydmVyIHRoZW1zZWx2ZXMgKGRlZmF1bHQ6IDFnKS4KIyAtIFNQQVJLX0hJU1RPUllfT1BUUywgdG8gc2V0IGNvbmZpZyBwcm9wZXJ0aWVzIG9ubHkgZm9yIHRoZSBoaXN0b3J5IHNlcnZlciAoZS5nLiAiLUR4PXkiKQojIC0gU1BBUktfU0hVRkZMRV9PUFRTLCB0byBzZXQgY29uZmlnIHByb3BlcnRpZXMgb25seSBmb3IgdGhlIGV4dGVybmFsIHNodWZmbGUgc2VydmljZSAoZS5nLiAiLUR4PXkiKQojIC0gU1BBUktfREFFTU9OX0pBVkFfT1BUUywgdG8gc2V0IGNvbmZpZyBwcm9wZXJ0aWVzIGZvciBhbGwgZGFlbW9ucyAoZS5nLiAiLUR4PXkiKQojIC0gU1BBUktfUFVCTElDX0ROUywgdG8gc2V0IHRoZSBwdWJsaWMgZG5zIG5hbWUgb2YgdGhlIG1hc3RlciBvciB3b3JrZXJzCgojIEdlbmVyaWMgb3B0aW9ucyBmb3IgdGhlIGRhZW1vbnMgdXNlZCBpbiB0aGUgc3RhbmRhbG9uZSBkZXBsb3kgbW9kZQojIC0gU1BBUktfQ09ORl9ESVIgICAgICBBbHRlcm5hdGUgY29uZiBkaXIuIChEZWZhdWx0OiAke1NQQVJLX0hPTUV9L2NvbmYpCiMgLSBTUEFSS19MT0dfRElSICAgICAgIFdoZXJlIGxvZyBmaWxlcyBhcmUgc3RvcmVkLiAgKERlZmF1bHQ6ICR7U1BBUktfSE9NRX0vbG9ncykKIyAtIFNQQVJLX1BJRF9ESVIgICAgICAgV2hlcmUgdGhlIHBpZCBmaWxlIGlzIHN0b3JlZC4gKERlZmF1bHQ6IC90bXApCiMgLSBTUEFSS19JREVOVF9TVFJJTkcgIEEgc3RyaW5nIHJlcHJlc2VudGluZyB0aGlzIGluc3RhbmNlIG9mIHNwYXJrLiAoRGVmYXVsdDogJFVTRVIpCiMgLSBTUEFSS19OSUNFTkVTUyAgICAgIFRoZSBzY2hlZHVsaW5nIHByaW9yaXR5IGZvciBkYWVtb25zLiAoRGVmYXVsdDogMCkKIyAtIFNQQVJLX05PX0RBRU1PTklaRSAgUnVuIHRoZSBwcm9wb3NlZCBjb21tYW5kIGluIHRoZSBmb3JlZ3JvdW5kLiBJdCB3aWxsIG5vdCBvdXRwdXQgYSBQSUQgZmlsZS4KCiNleHBvcnQgSkFWQV9IT01FLCB0byBzZXQgamRrIGhvbWUKCnt7IHJhbmdlICRrLCAkdiA6PSAuQ3VzdG9tRW52c319Cnt7ICRrIH19PXt7ICR2IH19Cnt7LSBlbmQgfX0KCnt7LSBpZiAuVGlTcGFya01hc3Rlcn19ClNQQVJLX01BU1RFUl9IT1NUPXt7LlRpU3BhcmtNYXN0ZXJ9fQp7ey0gZW5kfX0Ke3stIGlmIG5lIC5NYXN0ZXJQb3J0IDB9fQpTUEFSS19NQVNURVJfUE9SVD17ey5NYXN0ZXJQb3J0fX0Ke3stIGVuZH19Cnt7LSBpZiBuZSAuTWFzdGVyVUlQb3J0IDB9fQpTUEFSS19NQVNURVJfV0VCVUlfUE9SVD17ey5NYXN0ZXJVSVBvcnR9fQp7ey0gZW5kfX0Ke3stIGlmIG5lIC5Xb3JrZXJQb3J0IDB9fQpTUEFSS19XT1JLRVJfUE9SVD17ey5Xb3JrZXJQb3J0fX0Ke3stIGVuZH19Cnt7LSBpZiBuZSAuV29ya2VyVUlQb3J0IDB9fQpTUEFSS19XT1JLRVJfV0VCVUlfUE9SVD17ey5Xb3JrZXJVSVBvcnR9fQp7ey0gZW5kfX0Ke3stIGlmIG5lIC5UaVNwYXJrTG9jYWxJUCAiIn19ClNQQVJLX0xPQ0FMX0lQPXt7LlRpU3BhcmtMb2NhbElQfX0Ke3stIGVuZH19Cg=="
 	autogenFiles["/templates/scripts/start_tispark_slave.sh.tpl"] = "IyEvdXNyL2Jpbi9lbnYgYmFzaAoKIwojIExpY2Vuc2VkIHRvIHRoZSBBcGFjaGUgU29mdHdhcmUgRm91bmRhdGlvbiAoQVNGKSB1bmRlciBvbmUgb3IgbW9yZQojIGNvbnRyaWJ1dG9yIGxpY2Vuc2UgYWdyZWVtZW50cy4gIFNlZSB0aGUgTk9USUNFIGZpbGUgZGlzdHJpYnV0ZWQgd2l0aAojIHRoaXMgd29yayBmb3IgYWRkaXRpb25hbCBpbmZvcm1hdGlvbiByZWdhcmRpbmcgY29weXJpZ2h0IG93bmVyc2hpcC4KIyBUaGUgQVNGIGxpY2Vuc2VzIHRoaXMgZmlsZSB0byBZb3UgdW5kZXIgdGhlIEFwYWNoZSBMaWNlbnNlLCBWZXJzaW9uIDIuMAojICh0aGUgIkxpY2Vuc2UiKTsgeW91IG1heSBub3QgdXNlIHRoaXMgZmlsZSBleGNlcHQgaW4gY29tcGxpYW5jZSB3aXRoCiMgdGhlIExpY2Vuc2UuICBZb3UgbWF5IG9idGFpbiBhIGNvcHkgb2YgdGhlIExpY2Vuc2UgYXQKIwojICAgIGh0dHA6Ly93d3cuYXBhY2hlLm9yZy9saWNlbnNlcy9MSUNFTlNFLTIuMAojCiMgVW5sZXNzIHJlcXVpcmVkIGJ5IGFwcGxpY2FibGUgbGF3IG9yIGFncmVlZCB0byBpbiB3cml0aW5nLCBzb2Z0d2FyZQojIGRpc3RyaWJ1dGVkIHVuZGVyIHRoZSBMaWNlbnNlIGlzIGRpc3RyaWJ1dGVkIG9uIGFuICJBUyBJUyIgQkFTSVMsCiMgV0lUSE9VVCBXQVJSQU5USUVTIE9SIENPTkRJVElPTlMgT0YgQU5ZIEtJTkQsIGVpdGhlciBleHByZXNzIG9yIGltcGxpZWQuCiMgU2VlIHRoZSBMaWNlbnNlIGZvciB0aGUgc3BlY2lmaWMgbGFuZ3VhZ2UgZ292ZXJuaW5nIHBlcm1pc3Npb25zIGFuZAojIGxpbWl0YXRpb25zIHVuZGVyIHRoZSBMaWNlbnNlLgojCgojIFN0YXJ0cyBhIHNsYXZlIG9uIHRoZSBtYWNoaW5lIHRoaXMgc2NyaXB0IGlzIGV4ZWN1dGVkIG9uLgojCiMgRW52aXJvbm1lbnQgVmFyaWFibGVzCiMKIyAgIFNQQVJLX1dPUktFUl9JTlNUQU5DRVMgIFRoZSBudW1iZXIgb2Ygd29ya2VyIGluc3RhbmNlcyB0byBydW4gb24gdGhpcwojICAgICAgICAgICAgICAgICAgICAgICAgICAgc2xhdmUuICBEZWZhdWx0IGlzIDEuCiMgICBTUEFSS19XT1JLRVJfUE9SVCAgICAgICBUaGUgYmFzZSBwb3J0IG51bWJlciBmb3IgdGhlIGZpcnN0IHdvcmtlci4gSWYgc2V0LAojICAgICAgICAgICAgICAgICAgICAgICAgICAgc3Vic2VxdWVudCB3b3JrZXJzIHdpbGwgaW5jcmVtZW50IHRoaXMgbnVtYmVyLiAgSWYKIyAgICAgICAgICAgICAgICAgICAgICAgICAgIHVuc2V0LCBTcGFyayB3aWxsIGZpbmQgYSB2YWxpZCBwb3J0IG51bWJlciwgYnV0CiMgICAgICAgICAgICAgICAgICAgICAgICAgICB3aXRoIG5vIGd1YXJhbnRlZSBvZiBhIHByZWRpY3RhYmxlIHBhdHRlcm4uCiMgICBTUEFSS19XT1JLRVJfV0VCVUlfUE9SVCBUaGUgYmFzZSBwb3J0IGZvciB0aGUgd2ViIGludGVyZmFjZSBvZiB0aGUgZmlyc3QKIyAgICAgICAgICAgICAgICAgICAgICAgICAgIHdvcmtlci4gIFN1YnNlcXVlbnQgd29ya2VycyB3aWxsIGluY3JlbWVudCB0aGlzCiMgICAgICAgICAgICAgICAgICAgICAgICAgICBudW1iZXIuICBEZWZhdWx0IGlzIDgwODEuCgppZiBbIC16ICIke1NQQVJLX0hPTUV9IiBdOyB0aGVuCiAgZXhwb3J0IFNQQVJLX0hPTUU9IiQoY2QgImBkaXJuYW1lICIkMCJgIi8uLjsgcHdkKSIKZmkKCiMgTk9URTogVGhpcyBleGFjdCBjbGFzcyBuYW1lIGlzIG1hdGNoZWQgZG93bnN0cmVhbSBieSBTcGFya1N1Ym1pdC4KIyBBbnkgY2hhbmdlcyBuZWVkIHRvIGJlIHJlZmxlY3RlZCB0aGVyZS4KQ0xBU1M9Im9yZy5hcGFjaGUuc3BhcmsuZGVwbG95Lndvcmtlci5Xb3JrZXIiCgppZiBbWyAiJEAiID0gKi0taGVscCBdXSB8fCBbWyAiJEAiID0gKi1oIF1dOyB0aGVuCiAgZWNobyAiVXNhZ2U6IC4vc2Jpbi9zdGFydC1zbGF2ZS5zaCBbb3B0aW9uc10gPG1hc3Rlcj4iCiAgcGF0dGVybj0iVXNhZ2U6IgogIHBhdHRlcm4rPSJcfFVzaW5nIFNwYXJrJ3MgZGVmYXVsdCBsb2c0aiBwcm9maWxlOiIKICBwYXR0ZXJuKz0iXHxSZWdpc3RlcmVkIHNpZ25hbCBoYW5kbGVycyBmb3IiCgogICIke1NQQVJLX0hPTUV9Ii9iaW4vc3BhcmstY2xhc3MgJENMQVNTIC0taGVscCAyPiYxIHwgZ3JlcCAtdiAiJHBhdHRlcm4iIDE+JjIKICBleGl0IDEKZmkKCi4gIiR7U1BBUktfSE9NRX0vc2Jpbi9zcGFyay1jb25maWcuc2giCgouICIke1NQQVJLX0hPTUV9L2Jpbi9sb2FkLXNwYXJrLWVudi5zaCIKCiMgRmlyc3QgYXJndW1lbnQgc2hvdWxkIGJlIHRoZSBtYXN0ZXI7IHdlIG5lZWQgdG8gc3RvcmUgaXQgYXNpZGUgYmVjYXVzZSB3ZSBtYXkKIyBuZWVkIHRvIGluc2VydCBhcmd1bWVudHMgYmV0d2VlbiBpdCBhbmQgdGhlIG90aGVyIGFyZ3VtZW50cwoKe3stIGlmIC5UaVNwYXJrTWFzdGVyfX0KTUFTVEVSPXNwYXJrOi8ve3suVGlTcGFya01hc3Rlcn19Ont7Lk1hc3RlclBvcnR9fQpzaGlmdAp7ey0gZW5kfX0KCiMgRGV0ZXJtaW5lIGRlc2lyZWQgd29ya2VyIHBvcnQKaWYgWyAiJFNQQVJLX1dPUktFUl9XRUJVSV9QT1JUIiA9ICIiIF07IHRoZW4KICBTUEFSS19XT1JLRVJfV0VCVUlfUE9SVD04MDgxCmZpCgojIFN0YXJ0IHVwIHRoZSBhcHByb3ByaWF0ZSBudW1iZXIgb2Ygd29ya2VycyBvbiB0aGlzIG1hY2hpbmUuCiMgcXVpY2sgbG9jYWwgZnVuY3Rpb24gdG8gc3RhcnQgYSB3b3JrZXIKZnVuY3Rpb24gc3RhcnRfaW5zdGFuY2UgewogIFdPUktFUl9OVU09JDEKICBzaGlmdAoKICBpZiBbICIkU1BBUktfV09SS0VSX1BPUlQiID0gIiIgXTsgdGhlbgogICAgUE9SVF9GTEFHPQogICAgUE9SVF9OVU09CiAgZWxzZQogICAgUE9SVF9GTEFHPSItLXBvcnQiCiAgICBQT1JUX05VTT0kKCggJFNQQVJLX1dPUktFUl9QT1JUICsgJFdPUktFUl9OVU0gLSAxICkpCiAgZmkKICBXRUJVSV9QT1JUPSQoKCAkU1BBUktfV09SS0VSX1dFQlVJX1BPUlQgKyAkV09SS0VSX05VTSAtIDEgKSkKCiAgIiR7U1BBUktfSE9NRX0vc2JpbiIvc3BhcmstZGFlbW9uLnNoIHN0YXJ0ICRDTEFTUyAkV09SS0VSX05VTSBcCiAgICAgLS13ZWJ1aS1wb3J0ICIkV0VCVUlfUE9SVCIgJFBPUlRfRkxBRyAkUE9SVF9OVU0gJE1BU1RFUiAiJEAiCn0KCmlmIFsgIiRTUEFSS19XT1JLRVJfSU5TVEFOQ0VTIiA9ICIiIF07IHRoZW4KICBzdGFydF9pbnN0YW5jZSAxICIkQCIKZWxzZQogIGZvciAoKGk9MDsgaTwkU1BBUktfV09SS0VSX0lOU1RBTkNFUzsgaSsrKSk7IGRvCiAgICBzdGFydF9pbnN0YW5jZSAkKCggMSArICRpICkpICIkQCIKICBkb25lCmZpCg=="
-	autogenFiles["/templates/systemd/system.service.tpl"] = "W1VuaXRdCkRlc2NyaXB0aW9uPXt7LlNlcnZpY2VOYW1lfX0gc2VydmljZQpBZnRlcj1zeXNsb2cudGFyZ2V0IG5ldHdvcmsudGFyZ2V0IHJlbW90ZS1mcy50YXJnZXQgbnNzLWxvb2t1cC50YXJnZXQKCltTZXJ2aWNlXQp7ey0gaWYgLk1lbW9yeUxpbWl0fX0KTWVtb3J5TGltaXQ9e3suTWVtb3J5TGltaXR9fQp7ey0gZW5kfX0Ke3stIGlmIC5DUFVRdW90YX19CkNQVVF1b3RhPXt7LkNQVVF1b3RhfX0Ke3stIGVuZH19Cnt7LSBpZiAuSU9SZWFkQmFuZHdpZHRoTWF4fX0KSU9SZWFkQmFuZHdpZHRoTWF4PXt7LklPUmVhZEJhbmR3aWR0aE1heH19Cnt7LSBlbmR9fQp7ey0gaWYgLklPV3JpdGVCYW5kd2lkdGhNYXh9fQpJT1dyaXRlQmFuZHdpZHRoTWF4PXt7LklPV3JpdGVCYW5kd2lkdGhNYXh9fQp7ey0gZW5kfX0KTGltaXROT0ZJTEU9MTAwMDAwMAojTGltaXRDT1JFPWluZmluaXR5CkxpbWl0U1RBQ0s9MTA0ODU3NjAKClVzZXI9e3suVXNlcn19CkV4ZWNTdGFydD17ey5EZXBsb3lEaXJ9fS9zY3JpcHRzL3J1bl97ey5TZXJ2aWNlTmFtZX19LnNoCgp7ey0gaWYgLlJlc3RhcnR9fQpSZXN0YXJ0PXt7LlJlc3RhcnR9fQp7e2Vsc2V9fQpSZXN0YXJ0PWFsd2F5cwp7e2VuZH19ClJlc3RhcnRTZWM9MTVzCnt7LSBpZiAuRGlzYWJsZVNlbmRTaWdraWxsfX0KU2VuZFNJR0tJTEw9bm8Ke3stIGVuZH19CgpbSW5zdGFsbF0KV2FudGVkQnk9bXVsdGktdXNlci50YXJnZXQK"
-	autogenFiles["/templates/systemd/tispark.service.tpl"] = "W1VuaXRdCkRlc2NyaXB0aW9uPXt7LlNlcnZpY2VOYW1lfX0gc2VydmljZQpBZnRlcj1zeXNsb2cudGFyZ2V0IG5ldHdvcmsudGFyZ2V0IHJlbW90ZS1mcy50YXJnZXQgbnNzLWxvb2t1cC50YXJnZXQKCltTZXJ2aWNlXQpVc2VyPXt7LlVzZXJ9fQp7ey0gaWYgbmUgLkphdmFIb21lICIifX0KRW52aXJvbm1lbnQ9IkpBVkFfSE9NRT17ey5KYXZhSG9tZX19Igp7ey0gZW5kfX0KRXhlY1N0YXJ0PXt7LkRlcGxveURpcn19L3NiaW4vc3RhcnQte3suU2VydmljZU5hbWV9fS5zaApFeGVjU3RvcD17ey5EZXBsb3lEaXJ9fS9zYmluL3N0b3Ate3suU2VydmljZU5hbWV9fS5zaApUeXBlPWZvcmtpbmcKe3stIGlmIC5SZXN0YXJ0fX0KUmVzdGFydD17ey5SZXN0YXJ0fX0Ke3tlbHNlfX0KUmVzdGFydD1hbHdheXMKe3stIGVuZH19ClJlc3RhcnRTZWM9MTVzClNlbmRTSUdLSUxMPW5vCgpbSW5zdGFsbF0KV2FudGVkQnk9bXVsdGktdXNlci50YXJnZXQK"
+	autogenFiles["/templates/support/platforms.json"] = "ewogICJydWxlcyI6IFsKICAgIHsKICAgICAgIm9zIjogImNlbnRvcyIsCiAgICAgICJtYXhfdmVyc2lvbiI6ICI2IiwKICAgICAgInN0YXR1cyI6ICJicm9rZW4iLAogICAgICAibm90ZSI6ICJnbGliYyAyLjEyIG9mIENlbnRPUyA2IGlzIG9sZGVyIHRoYW4gdGhlIDIuMTcgdGhlIGJpbmFyaWVzIHJlcXVpcmUsIHRoZXkgZmFpbCB0byBzdGFydCIKICAgIH0sCiAgICB7CiAgICAgICJvcyI6ICJyaGVsIiwKICAgICAgIm1heF92ZXJzaW9uIjogIjYiLAogICAgICAic3RhdHVzIjogImJyb2tlbiIsCiAgICAgICJub3RlIjogImdsaWJjIDIuMTIgb2YgUkhFTCA2IGlzIG9sZGVyIHRoYW4gdGhlIDIuMTcgdGhlIGJpbmFyaWVzIHJlcXVpcmUsIHRoZXkgZmFpbCB0byBzdGFydCIKICAgIH0sCiAgICB7CiAgICAgICJvcyI6ICJ1YnVudHUiLAogICAgICAibWF4X3ZlcnNpb24iOiAiMTIuMDQiLAogICAgICAic3RhdHVzIjogImJyb2tlbiIsCiAgICAgICJub3RlIjogImdsaWJjIDIuMTUgb2YgVWJ1bnR1IDEyLjA0IGlzIG9sZGVyIHRoYW4gdGhlIDIuMTcgdGhlIGJpbmFyaWVzIHJlcXVpcmUsIHRoZXkgZmFpbCB0byBzdGFydCIKICAgIH0sCiAgICB7CiAgICAgICJjb21wb25lbnQiOiAidGlmbGFzaCIsCiAgICAgICJtYWpvciI6ICJ2NCIsCiAgICAgICJvcyI6ICJjZW50b3MiLAogICAgICAibWluX3ZlcnNpb24iOiAiNy4zIiwKICAgICAgImFyY2giOiBbImFtZDY0Il0sCiAgICAgICJzdGF0dXMiOiAic3VwcG9ydGVkIgogICAgfSwKICAgIHsKICAgICAgImNvbXBvbmVudCI6ICJ0aWZsYXNoIiwKICAgICAgIm1ham9yIjogInY0IiwKICAgICAgInN0YXR1cyI6ICJ1bnRlc3RlZCIsCiAgICAgICJub3RlIjogIlRpRmxhc2ggdjQgaXMgb25seSB0ZXN0ZWQgb24gQ2VudE9TIDcuMyBvciBsYXRlciBvbiBhbWQ2NCIKICAgIH0sCiAgICB7CiAgICAgICJvcyI6ICJjZW50b3MiLAogICAgICAibWluX3ZlcnNpb24iOiAiNy4zIiwKICAgICAgImFyY2giOiBbImFtZDY0IiwgImFybTY0Il0sCiAgICAgICJzdGF0dXMiOiAic3VwcG9ydGVkIgogICAgfSwKICAgIHsKICAgICAgIm9zIjogInJoZWwiLAogICAgICAibWluX3ZlcnNpb24iOiAiNy4zIiwKICAgICAgImFyY2giOiBbImFtZDY0IiwgImFybTY0Il0sCiAgICAgICJzdGF0dXMiOiAic3VwcG9ydGVkIgogICAgfSwKICAgIHsKICAgICAgIm9zIjogIm9sIiwKICAgICAgIm1pbl92ZXJzaW9uIjogIjcuMyIsCiAgICAgICJhcmNoIjogWyJhbWQ2NCJdLAogICAgICAic3RhdHVzIjogInN1cHBvcnRlZCIKICAgIH0sCiAgICB7CiAgICAgICJvcyI6ICJ1YnVudHUiLAogICAgICAibWluX3ZlcnNpb24iOiAiMTYuMDQiLAogICAgICAiYXJjaCI6IFsiYW1kNjQiXSwKICAgICAgInN0YXR1cyI6ICJzdXBwb3J0ZWQiCiAgICB9LAogICAgewogICAgICAib3MiOiAiZGViaWFuIiwKICAgICAgIm1pbl92ZXJzaW9uIjogIjkiLAogICAgICAiYXJjaCI6IFsiYW1kNjQiXSwKICAgICAgInN0YXR1cyI6ICJzdXBwb3J0ZWQiCiAgICB9LAogICAgewogICAgICAib3MiOiAia3lsaW4iLAogICAgICAibWluX3ZlcnNpb24iOiAiMTAiLAogICAgICAiYXJjaCI6IFsiYXJtNjQiXSwKICAgICAgInN0YXR1cyI6ICJzdXBwb3J0ZWQiCiAgICB9LAogICAgewogICAgICAib3MiOiAiZGFyd2luIiwKICAgICAgInN0YXR1cyI6ICJ1bnRlc3RlZCIsCiAgICAgICJub3RlIjogIm1hY09TIGlzIG9ubHkgc3VpdGFibGUgZm9yIGRldmVsb3BtZW50LCBub3QgZm9yIHByb2R1Y3Rpb24iCiAgICB9CiAgXQp9Cg=="
+	autogenFiles["/templates/systemd/system.service.tpl"] = "W1VuaXRdCkRlc2NyaXB0aW9uPXt7LlNlcnZpY2VOYW1lfX0gc2VydmljZQpBZnRlcj1zeXNsb2cudGFyZ2V0IG5ldHdvcmsudGFyZ2V0IHJlbW90ZS1mcy50YXJnZXQgbnNzLWxvb2t1cC50YXJnZXQKCltTZXJ2aWNlXQp7ey0gaWYgLk1lbW9yeUxpbWl0fX0KTWVtb3J5TGltaXQ9e3suTWVtb3J5TGltaXR9fQp7ey0gZW5kfX0Ke3stIGlmIC5DUFVRdW90YX19CkNQVVF1b3RhPXt7LkNQVVF1b3RhfX0Ke3stIGVuZH19Cnt7LSBpZiAuSU9SZWFkQmFuZHdpZHRoTWF4fX0KSU9SZWFkQmFuZHdpZHRoTWF4PXt7LklPUmVhZEJhbmR3aWR0aE1heH19Cnt7LSBlbmR9fQp7ey0gaWYgLklPV3JpdGVCYW5kd2lkdGhNYXh9fQpJT1dyaXRlQmFuZHdpZHRoTWF4PXt7LklPV3JpdGVCYW5kd2lkdGhNYXh9fQp7ey0gZW5kfX0KTGltaXROT0ZJTEU9MTAwMDAwMAojTGltaXRDT1JFPWluZmluaXR5CkxpbWl0U1RBQ0s9MTA0ODU3NjAKe3stIGlmIC5FbnZpcm9ubWVudEZpbGV9fQpFbnZpcm9ubWVudEZpbGU9e3suRW52aXJvbm1lbnRGaWxlfX0Ke3stIGVuZH19CgpVc2VyPXt7LlVzZXJ9fQpFeGVjU3RhcnQ9e3suRGVwbG95RGlyfX0vc2NyaXB0cy9ydW5fe3suU2VydmljZU5hbWV9fS5zaAoKe3stIGlmIC5SZXN0YXJ0fX0KUmVzdGFydD17ey5SZXN0YXJ0fX0Ke3tlbHNlfX0KUmVzdGFydD1hbHdheXMKe3tlbmR9fQpSZXN0YXJ0U2VjPTE1cwp7ey0gaWYgLkRpc2FibGVTZW5kU2lna2lsbH19ClNlbmRTSUdLSUxMPW5vCnt7LSBlbmR9fQoKW0luc3RhbGxdCldhbnRlZEJ5PW11bHRpLXVzZXIudGFyZ2V0Cg=="
+	autogenFiles["/templates/systemd/tispark.service.tpl"] = "W1VuaXRdCkRlc2NyaXB0aW9uPXt7LlNlcnZpY2VOYW1lfX0gc2VydmljZQpBZnRlcj1zeXNsb2cudGFyZ2V0IG5ldHdvcmsudGFyZ2V0IHJlbW90ZS1mcy50YXJnZXQgbnNzLWxvb2t1cC50YXJnZXQKCltTZXJ2aWNlXQpVc2VyPXt7LlVzZXJ9fQp7ey0gaWYgbmUgLkphdmFIb21lICIifX0KRW52aXJvbm1lbnQ9IkpBVkFfSE9NRT17ey5KYXZhSG9tZX19Igp7ey0gZW5kfX0Ke3stIGlmIC5FbnZpcm9ubWVudEZpbGV9fQpFbnZpcm9ubWVudEZpbGU9e3suRW52aXJvbm1lbnRGaWxlfX0Ke3stIGVuZH19CkV4ZWNTdGFydD17ey5EZXBsb3lEaXJ9fS9zYmluL3N0YXJ0LXt7LlNlcnZpY2VOYW1lfX0uc2gKRXhlY1N0b3A9e3suRGVwbG95RGlyfX0vc2Jpbi9zdG9wLXt7LlNlcnZpY2VOYW1lfX0uc2gKVHlwZT1mb3JraW5nCnt7LSBpZiAuUmVzdGFydH19ClJlc3RhcnQ9e3suUmVzdGFydH19Cnt7ZWxzZX19ClJlc3RhcnQ9YWx3YXlzCnt7LSBlbmR9fQpSZXN0YXJ0U2VjPTE1cwpTZW5kU0lHS0lMTD1ubwoKW0luc3RhbGxdCldhbnRlZEJ5PW11bHRpLXVzZXIudGFyZ2V0Cg=="
 }
@@ -0,0 +1,163 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/meta"
+)
+
+var (
+	errNSEnable = errorx.NewNamespace("enable")
+	// ErrEnableVerifyFailed is returned when some services are not verified to start on boot
+	ErrEnableVerifyFailed = errNSEnable.NewType("verify_failed", errutil.ErrTraitPreCheck)
+	// ErrEnableFailed is returned when some units failed to be enabled or disabled
+	ErrEnableFailed = errNSEnable.NewType("failed")
+)
+
+// EnableOptions contains the options for enabling the services of a cluster
+type EnableOptions struct {
+	NoWait      bool // return right after enabling, without verifying the services
+	VerifyStart bool // restart a canary instance of each component to verify it starts
+}
+
+// EnableCluster enables or disables the services of the components in
+// gOpt.ComponentClass of the cluster to start on boot. When enabling, the
+// services are verified unless opt.NoWait is set, the results of
+// verification are printed and recorded as the result of the operation. If
+// some units fail, the results of each host are printed and recorded instead.
+func (m *Manager) EnableCluster(clusterName string, isEnable bool, opt EnableOptions, gOpt operator.Options) (err error) {
+	metadata, err := m.meta(clusterName)
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+		return perrs.AddStack(err)
+	}
+
+	opType := OperationDisable
+	if isEnable {
+		opType = OperationEnable
+	}
+	op, err := m.beginOperation(clusterName, opType, opt, gOpt)
+	if err != nil {
+		return err
+	}
+	defer func() { m.endOperation(op, err) }()
+
+	topo := metadata.GetTopology()
+	base := metadata.GetBaseMeta()
+
+	class := gOpt.ComponentClass
+	if class == "" {
+		class = operator.ComponentClassAll
+	}
+	action := "disable"
+	if isEnable {
+		action = "enable"
+	}
+	log.Infof("%sing cluster `%s`, component class: %s", strings.Title(action[:len(action)-1]), clusterName, class)
+	if gOpt.ComponentClass == "" && len(gOpt.Roles) == 0 && topo.GetMonitoredOptions() != nil {
+		log.Infof("The monitoring agents are not %sd, specify the class monitoring or all to include them", action)
+	}
+
+	var report *operator.EnableReport
+	var results []*operator.VerifyResult
+	b := task.NewBuilder().
+		SSHKeySet(
+			m.specManager.Path(clusterName, "ssh", "id_rsa"),
+			m.specManager.Path(clusterName, "ssh", "id_rsa.pub")).
+		ClusterSSH(topo, base.User, gOpt.SSHTimeout, gOpt.NativeSSH).
+		Func("EnableCluster", func(ctx *task.Context) error {
+			report = operator.Enable(ctx, topo, gOpt, isEnable)
+			if failed := report.Failed(); failed > 0 {
+				return ErrEnableFailed.New("Failed to %s %d unit(s) of cluster `%s`", action, failed, clusterName).
+					WithProperty(cliutil.SuggestionFromString("The units marked as missing don't exist on the hosts, they could be re-rendered by `reload`."))
+			}
+			return nil
+		})
+	if isEnable && !opt.NoWait {
+		b.Func("VerifyEnabled", func(ctx *task.Context) error {
+			results = operator.VerifyEnabled(ctx, topo, gOpt, opt.VerifyStart)
+			return nil
+		})
+	}
+
+	if err := b.Build().Execute(op.newTaskContext()); err != nil {
+		if report != nil && report.Failed() > 0 {
+			op.setResult(report)
+			printEnableReport(report)
+		}
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return perrs.Trace(err)
+	}
+
+	if results != nil {
+		op.setResult(results)
+
+		failed := 0
+		rows := [][]string{{"Instance", "Unit", "State", "Restarted", "Result", "Message"}}
+		for _, r := range results {
+			result := color.GreenString("Pass")
+			if !r.Passed {
+				failed++
+				result = color.RedString("Fail")
+			}
+			restarted := ""
+			if r.Restarted {
+				restarted = "yes"
+			}
+			rows = append(rows, []string{r.Instance, r.Unit, r.State, restarted, result, r.Message})
+		}
+		cliutil.PrintTable(rows, true)
+
+		if failed > 0 {
+			return ErrEnableVerifyFailed.
+				New("%d of %d instance(s) of cluster `%s` may not start on boot", failed, len(results), clusterName).
+				WithProperty(cliutil.SuggestionFromString(
+					"Please check the unit files of the instances above, they could be re-rendered by `reload`."))
+		}
+	}
+
+	log.Infof("%sd cluster `%s` successfully, component class: %s", strings.Title(action), clusterName, class)
+	return nil
+}
+
+// printEnableReport prints the results of the units on each host
+func printEnableReport(report *operator.EnableReport) {
+	rows := [][]string{{"Host", "Unit", "Instance", "Result", "Message"}}
+	for _, h := range report.Hosts {
+		for _, u := range h.Units {
+			result := color.GreenString("OK")
+			switch {
+			case u.Missing:
+				result = color.RedString("Missing")
+			case u.Error != "":
+				result = color.RedString("Fail")
+			}
+			rows = append(rows, []string{h.Host, u.Unit, u.Instance, result, u.Error})
+		}
+	}
+	cliutil.PrintTable(rows, true)
+}
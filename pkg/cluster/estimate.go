@@ -0,0 +1,217 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"go.uber.org/zap"
+)
+
+// the number of the latest succeeded operations an estimate is based on
+const estimateWindow = 10
+
+// StepEstimate is the estimated duration of a phase of an operation, e.g.,
+// "+ Start tikv"
+type StepEstimate struct {
+	Step     string        `json:"step"`
+	Estimate time.Duration `json:"estimate"`
+}
+
+// DurationEstimate is the estimated duration of an operation on a cluster,
+// based on the time spent on the phases of the previous operations
+type DurationEstimate struct {
+	Operation OperationType  `json:"operation"`
+	Samples   int            `json:"samples"` // the number of previous operations it's based on
+	Total     time.Duration  `json:"total"`
+	Steps     []StepEstimate `json:"steps"`
+}
+
+// EstimateDuration estimates the duration of the operation of the type on a
+// cluster of so many instances from the history of operations on it. Each
+// phase of the latest succeeded operation of the type is estimated by the
+// median of the time spent on it by the latest ones, scaled by the number
+// of instances then, and the total is the sum of them. It's nil if there is
+// no history of the type to estimate from.
+func EstimateDuration(history []OperationRecord, op OperationType, instances int) *DurationEstimate {
+	var samples []OperationRecord
+	for i := len(history) - 1; i >= 0 && len(samples) < estimateWindow; i-- {
+		r := history[i]
+		if r.Operation == op && r.Error == "" && len(r.Phases) > 0 {
+			samples = append(samples, r)
+		}
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+
+	elapsed := make(map[string][]time.Duration)
+	for _, r := range samples {
+		for _, p := range r.Phases {
+			d := p.Elapsed
+			if instances > 0 && r.Instances > 0 {
+				d = d * time.Duration(instances) / time.Duration(r.Instances)
+			}
+			elapsed[p.Phase] = append(elapsed[p.Phase], d)
+		}
+	}
+
+	e := &DurationEstimate{Operation: op, Samples: len(samples)}
+	seen := make(map[string]bool)
+	// the phases of the latest operation are the ones most likely to run
+	for _, p := range samples[0].Phases {
+		if seen[p.Phase] {
+			continue
+		}
+		seen[p.Phase] = true
+		step := StepEstimate{Step: p.Phase, Estimate: median(elapsed[p.Phase])}
+		e.Steps = append(e.Steps, step)
+		e.Total += step.Estimate
+	}
+	return e
+}
+
+// median returns the median of the durations, which are sorted in place
+func median(ds []time.Duration) time.Duration {
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+	n := len(ds)
+	if n%2 == 1 {
+		return ds[n/2]
+	}
+	return (ds[n/2-1] + ds[n/2]) / 2
+}
+
+// Remaining estimates the time left of the operation after the phases done,
+// the estimates of the phases left are scaled by how much faster or slower
+// the phases done ran than estimated
+func (e *DurationEstimate) Remaining(done []task.PhaseTiming) time.Duration {
+	finished := make(map[string]time.Duration)
+	for _, p := range done {
+		if !p.InFlight {
+			finished[p.Phase] += p.Elapsed
+		}
+	}
+
+	var actual, estimated, left time.Duration
+	for _, s := range e.Steps {
+		if d, ok := finished[s.Step]; ok {
+			actual += d
+			estimated += s.Estimate
+			continue
+		}
+		left += s.Estimate
+	}
+	if actual > 0 && estimated > 0 {
+		left = time.Duration(float64(left) * float64(actual) / float64(estimated))
+	}
+	return left
+}
+
+// String implements the fmt.Stringer interface
+func (e *DurationEstimate) String() string {
+	return fmt.Sprintf("about %s, based on %d previous %s operation(s)",
+		e.Total.Round(time.Second), e.Samples, e.Operation)
+}
+
+// readHistory returns the history of the operations on the cluster, the
+// records failed to parse are skipped
+func (m *Manager) readHistory(clusterName string) ([]OperationRecord, error) {
+	f, err := os.Open(m.specManager.Path(clusterName, operationHistoryFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, perrs.AddStack(err)
+	}
+	defer f.Close()
+
+	var history []OperationRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var record OperationRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			zap.L().Debug("Failed to parse the history of operations", zap.String("line", line), zap.Error(err))
+			continue
+		}
+		history = append(history, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	return history, nil
+}
+
+// countInstances returns the number of instances of the topology
+func countInstances(topo spec.Topology) int {
+	n := 0
+	if topo != nil {
+		topo.IterInstance(func(spec.Instance) { n++ })
+	}
+	return n
+}
+
+// EstimateDuration estimates the duration of the operation of the type on
+// the cluster from its history, see EstimateDuration. It's nil if there is
+// no history to estimate from.
+func (m *Manager) EstimateDuration(clusterName string, op OperationType) (*DurationEstimate, error) {
+	history, err := m.readHistory(clusterName)
+	if err != nil || len(history) == 0 {
+		return nil, err
+	}
+	instances := 0
+	if metadata, _ := m.metaFresh(clusterName); metadata != nil {
+		instances = countInstances(metadata.GetTopology())
+	}
+	return EstimateDuration(history, op, instances), nil
+}
+
+// writePlan prints the plan of the operation in the format, followed by the
+// estimated duration if it's the text format
+func (m *Manager) writePlan(clusterName string, op OperationType, t task.Task, format string) error {
+	if err := task.WritePlan(os.Stdout, t, format); err != nil {
+		return err
+	}
+	if format == task.PlanFormatText {
+		fmt.Println(m.estimateHint(clusterName, op))
+	}
+	return nil
+}
+
+// estimateHint returns the line telling the estimated duration of the
+// operation for confirmations and plans, the operations not asking for a
+// confirmation, e.g., start, print it before executing
+func (m *Manager) estimateHint(clusterName string, op OperationType) string {
+	e, err := m.EstimateDuration(clusterName, op)
+	if err != nil {
+		zap.L().Debug("Failed to estimate the duration", zap.String("cluster", clusterName), zap.Error(err))
+	}
+	if e == nil {
+		return fmt.Sprintf("Estimated duration: unknown, no previous %s operation on the cluster is recorded", op)
+	}
+	return fmt.Sprintf("Estimated duration: %s", e)
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateDuration(t *testing.T) {
+	record := func(op OperationType, instances int, failed bool, phases ...task.PhaseTiming) OperationRecord {
+		r := OperationRecord{Operation: op, Instances: instances, Phases: phases}
+		if failed {
+			r.Error = "failed"
+		}
+		return r
+	}
+	phase := func(name string, secs int) task.PhaseTiming {
+		return task.PhaseTiming{Phase: name, Elapsed: time.Duration(secs) * time.Second}
+	}
+
+	assert.Nil(t, EstimateDuration(nil, OperationStart, 3))
+	history := []OperationRecord{
+		record(OperationStart, 3, false, phase("+ Start pd", 10), phase("+ Start tikv", 30)),
+		record(OperationStart, 3, false, phase("+ Start pd", 20), phase("+ Start tikv", 60)),
+		// failed ones and other types are not counted
+		record(OperationStart, 3, true, phase("+ Start pd", 1000)),
+		record(OperationStop, 3, false, phase("+ Stop pd", 1000)),
+		// the cluster was scaled from 3 instances to 6 since, so is the time
+		record(OperationStart, 6, false, phase("+ Start pd", 30), phase("+ Start tikv", 60), phase("+ Start tidb", 12)),
+	}
+	assert.Nil(t, EstimateDuration(history, OperationUpgrade, 6))
+
+	e := EstimateDuration(history, OperationStart, 6)
+	require.NotNil(t, e)
+	assert.Equal(t, 3, e.Samples)
+	assert.Equal(t, []StepEstimate{
+		{Step: "+ Start pd", Estimate: 30 * time.Second},   // median of 20, 40, 30
+		{Step: "+ Start tikv", Estimate: 60 * time.Second}, // median of 60, 120, 60
+		{Step: "+ Start tidb", Estimate: 12 * time.Second}, // the only one
+	}, e.Steps)
+	assert.Equal(t, 102*time.Second, e.Total)
+
+	// the time left is scaled by the actual time of the phases done
+	assert.Equal(t, 102*time.Second, e.Remaining(nil))
+	assert.Equal(t, 72*time.Second, e.Remaining([]task.PhaseTiming{phase("+ Start pd", 30)}))
+	assert.Equal(t, 144*time.Second, e.Remaining([]task.PhaseTiming{phase("+ Start pd", 60)}))
+	inFlight := phase("+ Start tikv", 10)
+	inFlight.InFlight = true
+	assert.Equal(t, 72*time.Second, e.Remaining([]task.PhaseTiming{phase("+ Start pd", 30), inFlight}))
+
+	// the phases of the operations are recorded in the history
+	m, _, _, cleanup := newTestMockCluster(t, 2)
+	defer cleanup()
+
+	e, err := m.EstimateDuration("mock", OperationStart)
+	require.Nil(t, err)
+	assert.Nil(t, e)
+	assert.Contains(t, m.estimateHint("mock", OperationStart), "unknown")
+
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10}
+	require.Nil(t, m.StartCluster("mock", opt))
+	require.Nil(t, m.StartCluster("mock", opt))
+	history, err = m.readHistory("mock")
+	require.Nil(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, 6, history[1].Instances)
+	assert.NotEmpty(t, history[1].Phases)
+
+	e, err = m.EstimateDuration("mock", OperationStart)
+	require.Nil(t, err)
+	require.NotNil(t, e)
+	assert.Equal(t, 2, e.Samples)
+	assert.Len(t, e.Steps, len(history[1].Phases))
+	assert.Contains(t, m.estimateHint("mock", OperationStart), "based on 2 previous start operation(s)")
+
+	// so are the ones of stop and restart
+	require.Nil(t, m.StopCluster("mock", opt))
+	require.Nil(t, m.RestartCluster("mock", opt))
+	assert.Contains(t, m.estimateHint("mock", OperationStop), "based on 1 previous stop operation(s)")
+	assert.Contains(t, m.estimateHint("mock", OperationRestart), "based on 1 previous restart operation(s)")
+
+	// the operation running is estimated by the previous ones
+	info, err := m.beginOperation("mock", OperationStart, opt)
+	require.Nil(t, err)
+	p := info.ComputeProgress()
+	assert.Equal(t, e.Total.Seconds(), p.EstimatedSecs)
+	m.endOperation(info, nil)
+}
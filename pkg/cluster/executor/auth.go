@@ -0,0 +1,196 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ScaleFT/sshkeys"
+	"github.com/pingcap/errors"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// AuthStatus is the outcome of authenticating to a SSH server
+type AuthStatus string
+
+// outcomes of authenticating to a SSH server
+const (
+	AuthKeyOK            AuthStatus = "key ok"
+	AuthPasswordOK       AuthStatus = "password ok"
+	AuthKeyRejected      AuthStatus = "key rejected"
+	AuthPasswordNeeded   AuthStatus = "password needed"
+	AuthPasswordRejected AuthStatus = "password rejected"
+	AuthUnreachable      AuthStatus = "unreachable"
+)
+
+// OK returns whether the authentication succeeded
+func (s AuthStatus) OK() bool {
+	return s == AuthKeyOK || s == AuthPasswordOK
+}
+
+// errNoPassword is returned when the server asks for a password but none is provided
+var errNoPassword = errors.New("no password provided")
+
+// CheckAuth tries to authenticate to the SSH server with the key or password
+// in c, and classifies the outcome without running any command. The keys of
+// the ssh-agent are also tried like the builtin executor does, and with native
+// set, the authentication is done by the SSH client installed on the system,
+// so its config (e.g., ~/.ssh/config) is honored like the native executor.
+// The detail of the failure is returned as an error if the authentication does
+// not succeed.
+func CheckAuth(c SSHConfig, native bool) (AuthStatus, error) {
+	if c.Port <= 0 {
+		c.Port = 22
+	}
+	if c.Timeout == 0 {
+		c.Timeout = time.Second * 5
+	}
+	if native {
+		return checkAuthNative(c)
+	}
+
+	var methods []ssh.AuthMethod
+	if c.KeyFile != "" {
+		buf, err := ioutil.ReadFile(c.KeyFile)
+		if err != nil {
+			return AuthKeyRejected, errors.Trace(err)
+		}
+		var signer ssh.Signer
+		if c.Passphrase != "" {
+			signer, err = sshkeys.ParseEncryptedPrivateKey(buf, []byte(c.Passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(buf)
+		}
+		if err != nil {
+			return AuthKeyRejected, errors.Trace(err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+	if sock, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK")); err == nil {
+		defer sock.Close()
+		methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(sock).Signers))
+	}
+
+	// the callbacks are only called when the server accepts password authentication
+	passwordAsked := false
+	password := func() (string, error) {
+		passwordAsked = true
+		if c.Password == "" {
+			return "", errNoPassword
+		}
+		return c.Password, nil
+	}
+	methods = append(methods,
+		ssh.PasswordCallback(password),
+		ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+			for i := range questions {
+				p, err := password()
+				if err != nil {
+					return nil, err
+				}
+				answers[i] = p
+			}
+			return answers, nil
+		}),
+	)
+
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	conn, err := net.DialTimeout("tcp", addr, c.Timeout)
+	if err != nil {
+		return AuthUnreachable, errors.Trace(err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(c.Timeout)); err != nil {
+		return AuthUnreachable, errors.Trace(err)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, &ssh.ClientConfig{
+		User:            c.User,
+		Auth:            methods,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // nolint:gosec
+		Timeout:         c.Timeout,
+	})
+	if err != nil {
+		if !strings.Contains(err.Error(), "unable to authenticate") {
+			return AuthUnreachable, errors.Trace(err)
+		}
+		switch {
+		case c.Password != "":
+			return AuthPasswordRejected, errors.Trace(err)
+		case passwordAsked:
+			return AuthPasswordNeeded, errors.Trace(err)
+		default:
+			return AuthKeyRejected, errors.Trace(err)
+		}
+	}
+	ssh.NewClient(sshConn, chans, reqs).Close()
+
+	if c.Password != "" && passwordAsked {
+		return AuthPasswordOK, nil
+	}
+	return AuthKeyOK, nil
+}
+
+// checkAuthNative authenticates to the SSH server with the SSH client installed
+// on the system, and classifies the outcome by the messages of it
+func checkAuthNative(c SSHConfig) (AuthStatus, error) {
+	e := &NativeSSHExecutor{Config: &c}
+	args := []string{"ssh", "-o", "StrictHostKeyChecking=no", "-o", "NumberOfPasswordPrompts=1", "-p", strconv.Itoa(c.Port)}
+	if c.Password == "" && c.Passphrase == "" {
+		// never prompt for the password or passphrase missing
+		args = append(args, "-o", "BatchMode=yes")
+	}
+	args = e.configArgs(args)
+	args = append(args, c.User+"@"+c.Host, "true")
+
+	ctx, cancel := context.WithTimeout(context.Background(), executeDefaultTimeout)
+	defer cancel()
+	command := exec.CommandContext(ctx, args[0], args[1:]...)
+	stderr := new(bytes.Buffer)
+	command.Stderr = stderr
+	err := command.Run()
+	if err == nil {
+		if c.Password != "" {
+			return AuthPasswordOK, nil
+		}
+		return AuthKeyOK, nil
+	}
+
+	// e.g., Permission denied (publickey,password).
+	msg := strings.TrimSpace(stderr.String())
+	err = errors.Annotate(err, msg)
+	i := strings.Index(msg, "Permission denied")
+	if i < 0 {
+		return AuthUnreachable, err
+	}
+	methods := msg[i:]
+	switch {
+	case c.Password != "":
+		return AuthPasswordRejected, err
+	case strings.Contains(methods, "password") || strings.Contains(methods, "keyboard-interactive"):
+		return AuthPasswordNeeded, err
+	default:
+		return AuthKeyRejected, err
+	}
+}
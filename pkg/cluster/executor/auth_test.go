@@ -0,0 +1,96 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckAuthUnreachable(t *testing.T) {
+	assert := require.New(t)
+
+	// take a free port and close it, so nothing is listening on it
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(err)
+	port := l.Addr().(*net.TCPAddr).Port
+	assert.Nil(l.Close())
+
+	status, err := CheckAuth(SSHConfig{
+		Host:    "127.0.0.1",
+		Port:    port,
+		User:    "root",
+		Timeout: time.Second,
+	}, false)
+	assert.NotNil(err)
+	assert.Equal(AuthUnreachable, status)
+	assert.False(status.OK())
+}
+
+func TestCheckAuthNative(t *testing.T) {
+	assert := require.New(t)
+
+	// the ssh shim records its arguments, and fails like the SSH client does
+	// for the hosts
+	dir, err := ioutil.TempDir("", "tiup-auth-test")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	argsLog := filepath.Join(dir, "args.log")
+	shim := `#!/bin/sh
+echo "$@" > ` + argsLog + `
+case "$*" in
+*key-rejected*) echo "root@key-rejected: Permission denied (publickey)." >&2; exit 255;;
+*password-needed*) echo "root@password-needed: Permission denied (publickey,password)." >&2; exit 255;;
+*unreachable*) echo "ssh: connect to host unreachable port 22: Connection refused" >&2; exit 255;;
+esac
+`
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, "ssh"), []byte(shim), 0755))
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, "sshpass"), []byte("#!/bin/sh\nshift 4\nexec \"$@\"\n"), 0755))
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir+":"+os.Getenv("PATH"))
+
+	for host, expected := range map[string]AuthStatus{
+		"key-ok":          AuthKeyOK,
+		"key-rejected":    AuthKeyRejected,
+		"password-needed": AuthPasswordNeeded,
+		"unreachable":     AuthUnreachable,
+	} {
+		status, err := CheckAuth(SSHConfig{Host: host, Port: 2222, User: "root", KeyFile: "/root/.ssh/id_rsa"}, true)
+		assert.Equal(expected, status, host)
+		assert.Equal(expected == AuthKeyOK, err == nil, host)
+		args, err := ioutil.ReadFile(argsLog)
+		assert.Nil(err)
+		// the config and the agent of the system client are honored, it never
+		// prompts for the password missing
+		assert.Contains(string(args), "-p 2222 -o BatchMode=yes")
+		assert.Contains(string(args), "-i /root/.ssh/id_rsa root@"+host+" true")
+	}
+
+	status, err := CheckAuth(SSHConfig{Host: "key-ok", User: "root", Password: "secret"}, true)
+	assert.Nil(err)
+	assert.Equal(AuthPasswordOK, status)
+	status, err = CheckAuth(SSHConfig{Host: "password-needed", User: "root", Password: "wrong"}, true)
+	assert.NotNil(err)
+	assert.Equal(AuthPasswordRejected, status)
+	args, err := ioutil.ReadFile(argsLog)
+	assert.Nil(err)
+	assert.False(strings.Contains(string(args), "BatchMode"))
+}
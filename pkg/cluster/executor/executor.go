@@ -36,3 +36,12 @@ type Executor interface {
 	// Transfer copies files from or to a target
 	Transfer(src string, dst string, download bool) error
 }
+
+// AgentForwarder is implemented by the executors able to forward an SSH agent
+// to the commands executed, e.g., for the host to log in to another host
+// without the private key placed on it. The agent only holds the key of
+// keyFile, and lives as long as the command. The command is never run by sudo
+// as the agent isn't reachable by other users.
+type AgentForwarder interface {
+	ExecuteWithAgent(cmd, keyFile string, timeout time.Duration) (stdout []byte, stderr []byte, err error)
+}
@@ -0,0 +1,200 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrFakeExecuteFailed is returned by Fake when a command fails, e.g.,
+	// querying the status of an inactive service
+	ErrFakeExecuteFailed = errNS.NewType("fake_execute_failed")
+)
+
+// Fake is an in-process executor simulating a host whose services are
+// managed by systemd, for developing and testing the operations without
+// real hosts. The services are started and stopped by the systemctl
+// commands, and the ports of the active services, parsed from the names of
+// their units, e.g., tikv-20160.service, are listed by `ss -ltn`. The other
+// commands succeed with no output unless scripted by Respond.
+type Fake struct {
+	Host string
+
+	mu        sync.Mutex
+	active    map[string]bool // the units loaded and whether they are active
+	responses []fakeResponse
+	commands  []string
+}
+
+type fakeResponse struct {
+	prefix string
+	stdout []byte
+	err    error
+}
+
+var _ Executor = &Fake{}
+
+// NewFake returns a Fake executor of the host without any service
+func NewFake(host string) *Fake {
+	return &Fake{
+		Host:   host,
+		active: make(map[string]bool),
+	}
+}
+
+// Respond scripts the output of the commands with the prefix, the commands
+// are failed with ErrFakeExecuteFailed if err isn't empty. The scripts set
+// later take precedence.
+func (f *Fake) Respond(prefix, stdout, err string) {
+	r := fakeResponse{prefix: prefix, stdout: []byte(stdout)}
+	if err != "" {
+		r.err = ErrFakeExecuteFailed.New("%s", err)
+	}
+	f.mu.Lock()
+	f.responses = append([]fakeResponse{r}, f.responses...)
+	f.mu.Unlock()
+}
+
+// Commands returns the commands executed in order
+func (f *Fake) Commands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.commands...)
+}
+
+// Active returns whether the service of the unit is active
+func (f *Fake) Active(unit string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.active[unit]
+}
+
+// Execute implements Executor interface.
+func (f *Fake) Execute(cmd string, sudo bool, timeout ...time.Duration) (stdout []byte, stderr []byte, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, cmd)
+
+	for _, r := range f.responses {
+		if strings.HasPrefix(cmd, r.prefix) {
+			if r.err != nil {
+				return r.stdout, []byte(r.err.Error()), r.err
+			}
+			return r.stdout, nil, nil
+		}
+	}
+
+	var out []string
+	for _, c := range strings.Split(cmd, "&&") {
+		fields := strings.Fields(c)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "systemctl":
+			o, err := f.systemctl(fields[1:])
+			out = append(out, o...)
+			if err != nil {
+				stdout = []byte(strings.Join(out, "\n"))
+				return stdout, []byte(err.Error()), err
+			}
+		case "ss":
+			out = append(out, f.listening()...)
+		}
+	}
+	if len(out) > 0 {
+		stdout = []byte(strings.Join(out, "\n") + "\n")
+	}
+	return stdout, nil, nil
+}
+
+// systemctl simulates a systemctl command, the flags are ignored
+func (f *Fake) systemctl(args []string) ([]string, error) {
+	var action, unit string
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "-"):
+		case action == "":
+			action = arg
+		case unit == "":
+			unit = arg
+		}
+	}
+
+	switch action {
+	case "start", "restart":
+		f.active[unit] = true
+	case "stop":
+		if _, ok := f.active[unit]; ok {
+			f.active[unit] = false
+		}
+	case "enable", "disable", "daemon-reload":
+		if _, ok := f.active[unit]; !ok && unit != "" {
+			f.active[unit] = false
+		}
+	case "is-active":
+		if f.active[unit] {
+			return []string{"active"}, nil
+		}
+		return []string{"inactive"}, ErrFakeExecuteFailed.New("unit %s is inactive", unit)
+	case "status":
+		active, loaded := f.active[unit]
+		if !loaded {
+			return nil, ErrFakeExecuteFailed.New("Unit %s could not be found.", unit)
+		}
+		lines := []string{
+			fmt.Sprintf("● %s - fake service on %s", unit, f.Host),
+			fmt.Sprintf("   Loaded: loaded (/etc/systemd/system/%s; enabled; vendor preset: disabled)", unit),
+		}
+		if active {
+			return append(lines, "   Active: active (running)"), nil
+		}
+		return append(lines, "   Active: inactive (dead)"), ErrFakeExecuteFailed.New("unit %s is inactive", unit)
+	}
+	return nil, nil
+}
+
+// listening lists the ports of the active services in the format of `ss -ltn`
+func (f *Fake) listening() []string {
+	lines := []string{"State  Recv-Q Send-Q Local Address:Port  Peer Address:Port"}
+	var ports []int
+	for unit, active := range f.active {
+		if !active {
+			continue
+		}
+		name := strings.TrimSuffix(unit, ".service")
+		if port, err := strconv.Atoi(name[strings.LastIndex(name, "-")+1:]); err == nil {
+			ports = append(ports, port)
+		}
+	}
+	sort.Ints(ports)
+	for _, port := range ports {
+		lines = append(lines, fmt.Sprintf("LISTEN 0      128    0.0.0.0:%d       0.0.0.0:*", port))
+	}
+	return lines
+}
+
+// Transfer implements Executer interface, the files are not copied
+func (f *Fake) Transfer(src string, dst string, download bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, fmt.Sprintf("transfer %s %s", src, dst))
+	return nil
+}
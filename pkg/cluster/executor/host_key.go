@@ -0,0 +1,121 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pingcap/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// errHostKeyCaught aborts the handshake once the host key is presented
+var errHostKeyCaught = errors.New("host key caught")
+
+// FetchHostKey returns the host key the SSH server presents, the handshake
+// is aborted before authenticating so no credential is needed.
+func FetchHostKey(c SSHConfig) (ssh.PublicKey, error) {
+	if c.Port <= 0 {
+		c.Port = 22
+	}
+	if c.Timeout == 0 {
+		c.Timeout = time.Second * 5
+	}
+
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	conn, err := net.DialTimeout("tcp", addr, c.Timeout)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(c.Timeout)); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var hostKey ssh.PublicKey
+	_, _, _, err = ssh.NewClientConn(conn, addr, &ssh.ClientConfig{
+		User: c.User,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return errHostKeyCaught
+		},
+		Timeout: c.Timeout,
+	})
+	if hostKey != nil {
+		return hostKey, nil
+	}
+	if err == nil {
+		err = errors.Errorf("no host key presented by %s", addr)
+	}
+	return nil, errors.Trace(err)
+}
+
+// hostKeyCallback returns the callback accepting only the host key of c, or
+// any key if it's not set
+func hostKeyCallback(c SSHConfig) ssh.HostKeyCallback {
+	if c.HostKey == nil {
+		return ssh.InsecureIgnoreHostKey()
+	}
+	return ssh.FixedHostKey(c.HostKey)
+}
+
+// hostKeyAlias is the name the host key of c is recorded by in the
+// known_hosts file for the native ssh client
+const hostKeyAlias = "tiup-pinned-host"
+
+// knownHostsFile writes the host key of c to a known_hosts file for the
+// native ssh client, the file is named by the key so it's shared by the
+// executors of the host
+func knownHostsFile(c SSHConfig) (string, error) {
+	line := fmt.Sprintf("%s %s", hostKeyAlias, ssh.MarshalAuthorizedKey(c.HostKey))
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("tiup-known-hosts-%x", sha256.Sum256([]byte(line))))
+	if data, err := ioutil.ReadFile(path); err == nil && string(data) == line {
+		return path, nil
+	}
+
+	f, err := ioutil.TempFile(os.TempDir(), "tiup-known-hosts-")
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(line); err != nil {
+		f.Close()
+		return "", errors.Trace(err)
+	}
+	if err := f.Close(); err != nil {
+		return "", errors.Trace(err)
+	}
+	return path, errors.Trace(os.Rename(f.Name(), path))
+}
+
+// hostKeyArgs returns the options of the native ssh client to check the host
+// key of the host against the one pinned, or not to check it if none is
+func (e *NativeSSHExecutor) hostKeyArgs() []string {
+	if e.knownHosts == "" {
+		return []string{"-o", "StrictHostKeyChecking=no"}
+	}
+	return []string{
+		"-o", "StrictHostKeyChecking=yes",
+		"-o", "UserKnownHostsFile=" + e.knownHosts,
+		"-o", "GlobalKnownHostsFile=/dev/null",
+		"-o", "HostKeyAlias=" + hostKeyAlias,
+	}
+}
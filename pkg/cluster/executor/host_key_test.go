@@ -0,0 +1,154 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// startSSHServer starts a server presenting the key of signer, the password
+// "secret" is accepted and each command run echoes itself
+func startSSHServer(t *testing.T, signer ssh.Signer) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(_ ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) != "secret" {
+				return nil, errors.New("denied")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for nc := range chans {
+					ch, reqs, err := nc.Accept()
+					if err != nil {
+						return
+					}
+					for req := range reqs {
+						if req.Type != "exec" {
+							_ = req.Reply(false, nil)
+							continue
+						}
+						_ = req.Reply(true, nil)
+						cmd := string(req.Payload[4:])
+						_, _ = ch.Write([]byte(cmd + "\n"))
+						_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+						ch.Close()
+					}
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+func newTestSigner(t *testing.T) ssh.Signer {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.Nil(t, err)
+	return signer
+}
+
+func TestFetchHostKey(t *testing.T) {
+	assert := require.New(t)
+
+	signer := newTestSigner(t)
+	l := startSSHServer(t, signer)
+	defer l.Close()
+
+	key, err := FetchHostKey(SSHConfig{
+		Host:    "127.0.0.1",
+		Port:    l.Addr().(*net.TCPAddr).Port,
+		User:    "tidb",
+		Timeout: time.Second * 5,
+	})
+	assert.Nil(err)
+	assert.Equal(ssh.FingerprintSHA256(signer.PublicKey()), ssh.FingerprintSHA256(key))
+
+	// nothing is listening on the port
+	port := l.Addr().(*net.TCPAddr).Port
+	assert.Nil(l.Close())
+	_, err = FetchHostKey(SSHConfig{Host: "127.0.0.1", Port: port, Timeout: time.Second})
+	assert.NotNil(err)
+}
+
+func TestPinnedHostKey(t *testing.T) {
+	assert := require.New(t)
+
+	signer := newTestSigner(t)
+	l := startSSHServer(t, signer)
+	defer l.Close()
+	cfg := SSHConfig{
+		Host:     "127.0.0.1",
+		Port:     l.Addr().(*net.TCPAddr).Port,
+		User:     "tidb",
+		Password: "secret",
+	}
+
+	// the commands are run on the host presenting the key pinned
+	cfg.HostKey = signer.PublicKey()
+	stdout, _, err := NewSSHExecutor(cfg, false, false).Execute("ls", false)
+	assert.Nil(err)
+	assert.Equal("export LANG=C; PATH=$PATH:/usr/bin:/usr/sbin ls\n", string(stdout))
+
+	// and refused on the one presenting another key, e.g., intercepted
+	cfg.HostKey = newTestSigner(t).PublicKey()
+	e := NewSSHExecutor(cfg, false, false)
+	_, _, err = e.Execute("ls", false)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "host key mismatch")
+	f, err := ioutil.TempFile("", "tiup-pinned-host-key-test")
+	assert.Nil(err)
+	defer os.Remove(f.Name())
+	f.Close()
+	assert.NotNil(e.Transfer(f.Name(), "/tmp/x", false))
+
+	// the native ssh client checks the key by the known_hosts file
+	native := NewSSHExecutor(cfg, false, true).(*NativeSSHExecutor)
+	args := strings.Join(native.hostKeyArgs(), " ")
+	assert.Contains(args, "StrictHostKeyChecking=yes")
+	data, err := ioutil.ReadFile(native.knownHosts)
+	assert.Nil(err)
+	assert.Equal(hostKeyAlias+" "+string(ssh.MarshalAuthorizedKey(cfg.HostKey)), string(data))
+	cfg.HostKey = nil
+	native = NewSSHExecutor(cfg, false, true).(*NativeSSHExecutor)
+	assert.Equal([]string{"-o", "StrictHostKeyChecking=no"}, native.hostKeyArgs())
+}
@@ -20,6 +20,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -27,11 +28,13 @@ import (
 	"github.com/appleboy/easyssh-proxy"
 	"github.com/fatih/color"
 	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/tiup/pkg/cliutil"
 	"github.com/pingcap/tiup/pkg/localdata"
 	"github.com/pingcap/tiup/pkg/utils"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
 )
 
 var (
@@ -52,6 +55,12 @@ var (
 
 var executeDefaultTimeout = time.Second * 60
 
+// DefaultExecuteTimeout returns the timeout of the commands executed without
+// one specified, it's overridden by TIUP_CLUSTER_EXECUTE_DEFAULT_TIMEOUT
+func DefaultExecuteTimeout() time.Duration {
+	return executeDefaultTimeout
+}
+
 // This command will be execute once the NativeSSHExecutor is created.
 // It's used to predict if the connection can establish success in the future.
 // Its main purpose is to avoid sshpass hang when user speficied a wrong prompt.
@@ -76,6 +85,9 @@ type (
 		Config *easyssh.MakeConfig
 		Locale string // the locale used when executing the command
 		Sudo   bool   // all commands run with this executor will be using sudo
+
+		hostKey ssh.PublicKey // the host key the host must present, any if nil
+		logger  *zap.Logger   // where the commands executed are logged
 	}
 
 	// NativeSSHExecutor implements Excutor with native SSH transportation layer.
@@ -84,6 +96,8 @@ type (
 		Locale               string // the locale used when executing the command
 		Sudo                 bool   // all commands run with this executor will be using sudo
 		ConnectionTestResult error  // test if the connection can be established in initialization phase
+
+		knownHosts string // the known_hosts file of the host key pinned
 	}
 
 	// SSHConfig is the configuration needed to establish SSH connection.
@@ -96,6 +110,12 @@ type (
 		Passphrase string // passphrase of the private key file
 		// Timeout is the maximum amount of time for the TCP connection to establish.
 		Timeout time.Duration
+		// HostKey is the host key the SSH server must present, the connection
+		// is refused if it presents another one, any key is accepted if nil
+		HostKey ssh.PublicKey
+		// Logger is where the commands executed are logged, the global logger
+		// if nil
+		Logger *zap.Logger
 	}
 )
 
@@ -119,6 +139,11 @@ func NewSSHExecutor(c SSHConfig, sudo bool, native bool) Executor {
 			Locale: "C",
 			Sudo:   sudo,
 		}
+		if c.HostKey != nil {
+			if e.knownHosts, e.ConnectionTestResult = knownHostsFile(c); e.ConnectionTestResult != nil {
+				return e
+			}
+		}
 		if c.Password != "" || (c.KeyFile != "" && c.Passphrase != "") {
 			_, _, e.ConnectionTestResult = e.Execute(connectionTestCommand, false, executeDefaultTimeout)
 		}
@@ -141,6 +166,56 @@ func NewSSHExecutor(c SSHConfig, sudo bool, native bool) Executor {
 	return e
 }
 
+// sudoEscaper escapes the characters special in a double-quoted string, so
+// the command wrapped by sudo is run by root as it is, rather than expanded
+// by the shell of the user logged in
+var sudoEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+
+// systemctlRegexp matches the systemctl commands with plain arguments only
+var systemctlRegexp = regexp.MustCompile(`^systemctl( [A-Za-z0-9@._:=-]+)+$`)
+
+// sudoCommand wraps cmd to be run by root. The chains of plain systemctl
+// commands are run by sudo directly rather than by a shell, so they are
+// allowed by the sudoers rules scoped to systemctl, e.g., the ones written
+// when bootstrapping the deploy user.
+func sudoCommand(cmd string) string {
+	cmds := strings.Split(cmd, " && ")
+	for _, c := range cmds {
+		if !systemctlRegexp.MatchString(c) {
+			return fmt.Sprintf("sudo -H -u root bash -c \"%s\"", sudoEscaper.Replace(cmd))
+		}
+	}
+	for i, c := range cmds {
+		cmds[i] = "sudo -H -u root " + c
+	}
+	return strings.Join(cmds, " && ")
+}
+
+// RemoteCommand returns the command line sent to the remote host to run cmd
+// with the locale, as root if sudo is set
+func RemoteCommand(cmd string, sudo bool, locale string) string {
+	// try to acquire root permission
+	if sudo {
+		cmd = sudoCommand(cmd)
+	}
+
+	// set a basic PATH in case it's empty on login
+	cmd = fmt.Sprintf("PATH=$PATH:/usr/bin:/usr/sbin %s", cmd)
+
+	if locale != "" {
+		cmd = fmt.Sprintf("export LANG=%s; %s", locale, cmd)
+	}
+	return cmd
+}
+
+// loggerOf returns where the commands executed with the config are logged
+func loggerOf(c SSHConfig) *zap.Logger {
+	if c.Logger == nil {
+		return zap.L()
+	}
+	return c.Logger
+}
+
 // Initialize builds and initializes a EasySSHExecutor
 func (e *EasySSHExecutor) initialize(config SSHConfig) {
 	// build easyssh config
@@ -150,6 +225,8 @@ func (e *EasySSHExecutor) initialize(config SSHConfig) {
 		User:    config.User,
 		Timeout: config.Timeout, // timeout when connecting to remote
 	}
+	e.hostKey = config.HostKey
+	e.logger = loggerOf(config)
 
 	// prefer private key authentication
 	if len(config.KeyFile) > 0 {
@@ -162,17 +239,7 @@ func (e *EasySSHExecutor) initialize(config SSHConfig) {
 
 // Execute run the command via SSH, it's not invoking any specific shell by default.
 func (e *EasySSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
-	// try to acquire root permission
-	if e.Sudo || sudo {
-		cmd = fmt.Sprintf("sudo -H -u root bash -c \"%s\"", cmd)
-	}
-
-	// set a basic PATH in case it's empty on login
-	cmd = fmt.Sprintf("PATH=$PATH:/usr/bin:/usr/sbin %s", cmd)
-
-	if e.Locale != "" {
-		cmd = fmt.Sprintf("export LANG=%s; %s", e.Locale, cmd)
-	}
+	cmd = RemoteCommand(cmd, e.Sudo || sudo, e.Locale)
 
 	// run command on remote host
 	// default timeout is 60s in easyssh-proxy
@@ -180,9 +247,24 @@ func (e *EasySSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Duratio
 		timeout = append(timeout, executeDefaultTimeout)
 	}
 
-	stdout, stderr, done, err := e.Config.Run(cmd, timeout...)
+	return e.execute(cmd, timeout[0], nil)
+}
 
-	zap.L().Info("SSHCommand",
+// ExecuteWithAgent implements the AgentForwarder interface
+func (e *EasySSHExecutor) ExecuteWithAgent(cmd, keyFile string, timeout time.Duration) ([]byte, []byte, error) {
+	prepare, err := forwardAgent(keyFile)
+	if err != nil {
+		return nil, nil, errors.Annotatef(err, "failed to load the key %s to forward", keyFile)
+	}
+	return e.execute(RemoteCommand(cmd, false, e.Locale), timeout, prepare)
+}
+
+// execute runs the remote command cmd, the session is prepared by prepare
+// if it's not nil, see runWith
+func (e *EasySSHExecutor) execute(cmd string, timeout time.Duration, prepare func(*ssh.Client, *ssh.Session) error) ([]byte, []byte, error) {
+	stdout, stderr, done, err := e.runWith(cmd, timeout, prepare)
+
+	e.logger.Info("SSHCommand",
 		zap.String("host", e.Config.Server),
 		zap.String("port", e.Config.Port),
 		zap.String("cmd", cmd),
@@ -223,11 +305,11 @@ func (e *EasySSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Duratio
 // file from remote to local.
 func (e *EasySSHExecutor) Transfer(src string, dst string, download bool) error {
 	if !download {
-		return e.Config.Scp(src, dst)
+		return e.scp(src, dst)
 	}
 
 	// download file from remote
-	session, client, err := e.Config.Connect()
+	session, client, err := e.connect()
 	if err != nil {
 		return err
 	}
@@ -276,17 +358,7 @@ func (e *NativeSSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Durat
 		return nil, nil, e.ConnectionTestResult
 	}
 
-	// try to acquire root permission
-	if e.Sudo || sudo {
-		cmd = fmt.Sprintf("sudo -H -u root bash -c \"%s\"", cmd)
-	}
-
-	// set a basic PATH in case it's empty on login
-	cmd = fmt.Sprintf("PATH=$PATH:/usr/bin:/usr/sbin %s", cmd)
-
-	if e.Locale != "" {
-		cmd = fmt.Sprintf("export LANG=%s; %s", e.Locale, cmd)
-	}
+	cmd = RemoteCommand(cmd, e.Sudo || sudo, e.Locale)
 
 	// run command on remote host
 	// default timeout is 60s in easyssh-proxy
@@ -301,7 +373,7 @@ func (e *NativeSSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Durat
 		defer cancel()
 	}
 
-	args := []string{"ssh", "-o", "StrictHostKeyChecking=no"}
+	args := append([]string{"ssh"}, e.hostKeyArgs()...)
 	args = e.configArgs(args) // prefix and postfix args
 	args = append(args, fmt.Sprintf("%s@%s", e.Config.User, e.Config.Host), cmd)
 
@@ -314,7 +386,7 @@ func (e *NativeSSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Durat
 
 	err := command.Run()
 
-	zap.L().Info("SSHCommand",
+	loggerOf(*e.Config).Info("SSHCommand",
 		zap.String("host", e.Config.Host),
 		zap.Int("port", e.Config.Port),
 		zap.String("cmd", cmd),
@@ -348,7 +420,7 @@ func (e *NativeSSHExecutor) Transfer(src string, dst string, download bool) erro
 		return e.ConnectionTestResult
 	}
 
-	args := []string{"scp", "-r", "-o", "StrictHostKeyChecking=no"}
+	args := append([]string{"scp", "-r"}, e.hostKeyArgs()...)
 	args = e.configArgs(args) // prefix and postfix args
 
 	if download {
@@ -369,7 +441,7 @@ func (e *NativeSSHExecutor) Transfer(src string, dst string, download bool) erro
 
 	err := command.Run()
 
-	zap.L().Info("SSPCommand",
+	loggerOf(*e.Config).Info("SSPCommand",
 		zap.String("host", e.Config.Host),
 		zap.Int("port", e.Config.Port),
 		zap.String("cmd", strings.Join(args, " ")),
@@ -0,0 +1,224 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ScaleFT/sshkeys"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// The sessions of EasySSHExecutor are established the same way as
+// easyssh.MakeConfig does, except that the host key is verified against the
+// one pinned, which easyssh always ignores.
+
+// clientConfig returns the config to connect the host, the closer returned
+// should be closed once connected if it's not nil
+func (e *EasySSHExecutor) clientConfig() (*ssh.ClientConfig, io.Closer, error) {
+	var auths []ssh.AuthMethod
+	if e.Config.Password != "" {
+		auths = append(auths, ssh.Password(e.Config.Password))
+	}
+	if e.Config.KeyPath != "" {
+		buf, err := ioutil.ReadFile(e.Config.KeyPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		var signer ssh.Signer
+		if e.Config.Passphrase != "" {
+			signer, err = sshkeys.ParseEncryptedPrivateKey(buf, []byte(e.Config.Passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(buf)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+
+	var closer io.Closer
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			auths = append(auths, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+			closer = conn
+		}
+	}
+
+	callback := ssh.InsecureIgnoreHostKey()
+	if e.hostKey != nil {
+		callback = ssh.FixedHostKey(e.hostKey)
+	}
+	return &ssh.ClientConfig{
+		Timeout:         e.Config.Timeout,
+		User:            e.Config.User,
+		Auth:            auths,
+		HostKeyCallback: callback,
+	}, closer, nil
+}
+
+// connect opens a session on the host
+func (e *EasySSHExecutor) connect() (*ssh.Session, *ssh.Client, error) {
+	config, closer, err := e.clientConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+
+	client, err := ssh.Dial("tcp", net.JoinHostPort(e.Config.Server, e.Config.Port), config)
+	if err != nil {
+		return nil, nil, err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+	return session, client, nil
+}
+
+// run runs cmd on the host like easyssh.MakeConfig.Run, the output is
+// returned line by line without the empty ones, and done is false if cmd
+// doesn't finish in time
+func (e *EasySSHExecutor) run(cmd string, timeout time.Duration) (stdout, stderr string, done bool, err error) {
+	return e.runWith(cmd, timeout, nil)
+}
+
+// runWith is like run, the session is prepared by prepare before cmd is
+// started if it's not nil
+func (e *EasySSHExecutor) runWith(cmd string, timeout time.Duration, prepare func(*ssh.Client, *ssh.Session) error) (stdout, stderr string, done bool, err error) {
+	session, client, err := e.connect()
+	if err != nil {
+		return "", "", false, err
+	}
+	defer client.Close()
+	defer session.Close()
+	if prepare != nil {
+		if err := prepare(client, session); err != nil {
+			return "", "", false, err
+		}
+	}
+
+	outBuf, errBuf := new(bytes.Buffer), new(bytes.Buffer)
+	session.Stdout, session.Stderr = outBuf, errBuf
+	if err := session.Start(cmd); err != nil {
+		return "", "", false, err
+	}
+
+	waitC := make(chan error, 1)
+	go func() { waitC <- session.Wait() }()
+	select {
+	case err = <-waitC:
+		done = true
+	case <-time.After(timeout):
+		// the output is copied until the session is closed
+		client.Close()
+		<-waitC
+		errBuf.WriteString("Run Command Timeout!\n")
+	}
+	return nonEmptyLines(outBuf), nonEmptyLines(errBuf), done, err
+}
+
+// nonEmptyLines returns the lines of r not empty, each ends with a newline
+func nonEmptyLines(r io.Reader) string {
+	var b strings.Builder
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// scp copies the local file src to dst on the host like
+// easyssh.MakeConfig.Scp, by the sink mode of the remote scp
+func (e *EasySSHExecutor) scp(src, dst string) error {
+	session, client, err := e.connect()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	defer session.Close()
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	w, err := session.StdinPipe()
+	if err != nil {
+		return err
+	}
+	copyErrC := make(chan error, 1)
+	go func() {
+		defer w.Close()
+		if _, err := fmt.Fprintln(w, "C0644", st.Size(), filepath.Base(dst)); err != nil {
+			copyErrC <- err
+			return
+		}
+		if _, err := io.Copy(w, f); err != nil {
+			copyErrC <- err
+			return
+		}
+		_, err := fmt.Fprint(w, "\x00")
+		copyErrC <- err
+	}()
+
+	if err := session.Run(fmt.Sprintf("scp -tr %s", dst)); err != nil {
+		return err
+	}
+	return <-copyErrC
+}
+
+// forwardAgent returns the function preparing a session with an agent
+// forwarded, which only holds the private key of keyFile
+func forwardAgent(keyFile string) (func(*ssh.Client, *ssh.Session) error, error) {
+	buf, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ssh.ParseRawPrivateKey(buf)
+	if err != nil {
+		return nil, err
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: key}); err != nil {
+		return nil, err
+	}
+	return func(client *ssh.Client, session *ssh.Session) error {
+		if err := agent.ForwardToAgent(client, keyring); err != nil {
+			return err
+		}
+		return agent.RequestAgentForwarding(session)
+	}, nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runRemoteCommand runs the command line sent to the host for cmd in a local
+// shell, where sudo is simulated by running the command as the current user
+func runRemoteCommand(t *testing.T, cmd string, sudo bool) string {
+	dir, err := ioutil.TempDir("", "tiup-remote-command-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	// sudo -H -u root bash -c ...
+	shim := "#!/bin/sh\nshift 3\nexec \"$@\"\n"
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "sudo"), []byte(shim), 0755))
+
+	c := exec.Command("sh", "-c", RemoteCommand(cmd, sudo, "C"))
+	c.Env = append(os.Environ(), "PATH="+dir+":"+os.Getenv("PATH"), "NAME=user")
+	out, err := c.CombinedOutput()
+	require.Nil(t, err, string(out))
+	return string(out)
+}
+
+func TestRemoteCommand(t *testing.T) {
+	assert.Equal(t, "export LANG=C; PATH=$PATH:/usr/bin:/usr/sbin ls", RemoteCommand("ls", false, "C"))
+	assert.Equal(t, `PATH=$PATH:/usr/bin:/usr/sbin sudo -H -u root bash -c "echo \"\$NAME\""`,
+		RemoteCommand(`echo "$NAME"`, true, ""))
+
+	// the commands wrapped by sudo are run as they are, rather than being
+	// expanded by the shell of the user logged in
+	script := `NAME=root; for u in a b; do f=/tmp/$u; [ -n "$f" ] && echo "$u $(echo "$NAME" | tr a-z A-Z) \$ ` + "`echo x`" + `"; done`
+	expected := "a ROOT $ x\nb ROOT $ x\n"
+	assert.Equal(t, expected, runRemoteCommand(t, script, false))
+	assert.Equal(t, expected, runRemoteCommand(t, script, true))
+	assert.Equal(t, "a\tb\n", runRemoteCommand(t, `printf 'a\tb\n'`, true))
+
+	// the plain systemctl commands are run by sudo directly
+	assert.Equal(t, "sudo -H -u root systemctl daemon-reload && sudo -H -u root systemctl start tikv-20160.service",
+		sudoCommand("systemctl daemon-reload && systemctl start tikv-20160.service"))
+	assert.Equal(t, `sudo -H -u root bash -c "systemctl start tikv-20160.service; rm -f /etc/a"`,
+		sudoCommand("systemctl start tikv-20160.service; rm -f /etc/a"))
+	assert.Equal(t, `sudo -H -u root bash -c "systemctl show -p MainPID \$(cat a)"`,
+		sudoCommand("systemctl show -p MainPID $(cat a)"))
+}
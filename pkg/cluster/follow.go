@@ -0,0 +1,48 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"io"
+	"os"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/errutil"
+)
+
+var (
+	errNSFollow = errorx.NewNamespace("follow")
+	// ErrFollowFinished is returned when following a host of a finished operation
+	ErrFollowFinished = errNSFollow.NewType("finished", errutil.ErrTraitPreCheck)
+)
+
+// followOutput is where the hosts in operator.Options.FollowHosts are streamed to
+var followOutput io.Writer = os.Stderr
+
+// FollowHost streams the commands executed on the host by the operation and
+// their outputs to w, until stop is called or the operation finishes, done
+// is closed then. The outputs of the other hosts are not affected. It could
+// be called any time the operation is running, see task.HostFollowers.
+func (info *OperationInfo) FollowHost(host string, w io.Writer) (done <-chan struct{}, stop func(), err error) {
+	if info.Finished() || info.followers == nil {
+		return nil, nil, ErrFollowFinished.New("The operation on cluster `%s` is finished", info.clusterName)
+	}
+	done, stop = info.followers.Follow(host, w)
+	return done, stop, nil
+}
+
+// FollowedHosts returns the hosts being followed
+func (info *OperationInfo) FollowedHosts() []string {
+	return info.followers.Following()
+}
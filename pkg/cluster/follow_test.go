@@ -0,0 +1,46 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/joomcode/errorx"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollowHost(t *testing.T) {
+	m, _, _, cleanup := newTestMockCluster(t, 2)
+	defer cleanup()
+
+	var out strings.Builder
+	followOutput = &out
+	defer func() { followOutput = os.Stderr }()
+
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10, FollowHosts: []string{"mock-2"}}
+	require.Nil(t, m.StartCluster("mock", opt))
+	assert.Contains(t, out.String(), "[mock-2] $ systemctl daemon-reload && systemctl start tikv-20160.service")
+	assert.NotContains(t, out.String(), "mock-1")
+
+	// the hosts can't be followed once the operation finishes
+	info := GetCurrentOperation("mock")
+	require.NotNil(t, info)
+	assert.Empty(t, info.FollowedHosts())
+	_, _, err := info.FollowHost("mock-1", &out)
+	assert.True(t, errorx.IsOfType(err, ErrFollowFinished))
+}
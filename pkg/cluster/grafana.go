@@ -0,0 +1,135 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/fatih/color"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// the file the versions of the dashboards provisioned via the API of Grafana
+// are saved to, under the cluster dir
+const grafanaProvisionStateFile = "grafana_dashboards.json"
+
+// hasAPIProvisionedGrafana checks if any Grafana instance of the topology
+// provisions the dashboards via the API
+func hasAPIProvisionedGrafana(topo spec.Topology) bool {
+	cluster, ok := topo.(*spec.Specification)
+	if !ok {
+		return false
+	}
+	for _, g := range cluster.Grafana {
+		if g.APIProvisioning() {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) loadGrafanaProvisionState(clusterName string) (operator.GrafanaProvisionState, error) {
+	state := make(operator.GrafanaProvisionState)
+	data, err := ioutil.ReadFile(m.specManager.Path(clusterName, grafanaProvisionStateFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, perrs.AddStack(err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, perrs.Annotatef(err, "corrupted %s of cluster %s", grafanaProvisionStateFile, clusterName)
+	}
+	return state, nil
+}
+
+func (m *Manager) saveGrafanaProvisionState(clusterName string, state operator.GrafanaProvisionState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	return perrs.AddStack(ioutil.WriteFile(m.specManager.Path(clusterName, grafanaProvisionStateFile), data, 0644))
+}
+
+// provisionGrafana appends a step provisioning the dashboards and the
+// datasource of the Grafana instances with the API provisioning, the reports
+// are saved to reports. Nothing is appended if there is no such instance or
+// Grafana is excluded by the roles of options.
+func (m *Manager) provisionGrafana(
+	b *task.Builder,
+	clusterName string,
+	topo spec.Topology,
+	options operator.Options,
+	reports *[]*operator.GrafanaProvisionReport,
+) {
+	if !hasAPIProvisionedGrafana(topo) {
+		return
+	}
+	if len(options.Roles) > 0 && !set.NewStringSet(options.Roles...).Exist(spec.ComponentGrafana) {
+		return
+	}
+	var provision *task.Func
+	provision = task.NewFunc("ProvisionGrafana", func(ctx *task.Context) error {
+		state, err := m.loadGrafanaProvisionState(clusterName)
+		if err != nil {
+			return err
+		}
+		*reports = operator.ProvisionGrafana(ctx.PhaseGetter(provision), clusterName,
+			topo.(*spec.Specification), state, options.OverwriteDashboards, options)
+		return m.saveGrafanaProvisionState(clusterName, state)
+	})
+	b.Serial(provision)
+}
+
+// printGrafanaReports prints the results of provisioning the dashboards, the
+// number of the dashboards failed to be provisioned is returned
+func printGrafanaReports(reports []*operator.GrafanaProvisionReport) int {
+	if len(reports) == 0 {
+		return 0
+	}
+	failed, skipped := 0, 0
+	rows := [][]string{{"Instance", "Dashboard", "UID", "Result", "Reason"}}
+	for _, r := range reports {
+		if r.Error != "" {
+			failed++
+			rows = append(rows, []string{r.Instance, "-", "-", color.RedString("Failed"), r.Error})
+		}
+		for _, d := range r.Dashboards {
+			result := color.GreenString("Updated")
+			switch d.Status {
+			case operator.DashboardSkipped:
+				skipped++
+				result = color.YellowString("Skipped")
+			case operator.DashboardFailed:
+				failed++
+				result = color.RedString("Failed")
+			}
+			rows = append(rows, []string{r.Instance, d.Title, d.UID, result, d.Reason})
+		}
+	}
+	log.Infof("Provisioned the dashboards of grafana:")
+	cliutil.PrintTable(rows, true)
+	if skipped > 0 {
+		log.Warnf("%d dashboard(s) modified in grafana are skipped, use --overwrite-dashboards to overwrite them", skipped)
+	}
+	return failed
+}
@@ -0,0 +1,131 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"errors"
+	"os"
+	"reflect"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/meta"
+	"golang.org/x/crypto/ssh"
+)
+
+// hostKeyFetcher returns the fetcher of the host keys of the cluster, the
+// hosts of mock clusters present the keys scripted
+func (m *Manager) hostKeyFetcher(clusterName string) spec.HostKeyFetcher {
+	if mc := m.mockCluster(clusterName); mc != nil {
+		return mc.hostKey
+	}
+	return executor.FetchHostKey
+}
+
+// AcceptNewHostKey records the SSH host key the host presents now in the
+// metadata of the cluster, for the host rebuilt or rotating its keys
+// intentionally, the operations on it fail with ErrHostKeyMismatch otherwise.
+func (m *Manager) AcceptNewHostKey(clusterName, host string) error {
+	metadata, err := m.metaFresh(clusterName)
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+		return perrs.AddStack(err)
+	}
+	if err := m.specManager.CheckWritable(); err != nil {
+		return err
+	}
+
+	hm, ok := metadata.(spec.HostKeyedMetadata)
+	if !ok {
+		return perrs.Errorf("cluster `%s` doesn't record host keys", clusterName)
+	}
+	port, ok := SSHHosts(metadata.GetTopology())[host]
+	if !ok {
+		return perrs.Errorf("host %s is not in cluster `%s`", host, clusterName)
+	}
+
+	base := metadata.GetBaseMeta()
+	key, err := m.hostKeyFetcher(clusterName)(executor.SSHConfig{
+		Host: host,
+		Port: port,
+		User: base.User,
+	})
+	if err != nil {
+		return perrs.Annotatef(err, "failed to fetch the SSH host key of %s", host)
+	}
+	fingerprint := ssh.FingerprintSHA256(key)
+	known := base.HostKeys[host]
+	if known == fingerprint {
+		log.Infof("The SSH host key %s of %s is already accepted", fingerprint, host)
+		return nil
+	}
+
+	keys := make(map[string]string, len(base.HostKeys)+1)
+	for h, k := range base.HostKeys {
+		keys[h] = k
+	}
+	keys[host] = fingerprint
+	hm.SetHostKeys(keys)
+	if err := m.specManager.SaveMeta(clusterName, metadata); err != nil {
+		return perrs.Annotate(err, "failed to save meta")
+	}
+	if known == "" {
+		log.Infof("Accepted the SSH host key %s of %s", fingerprint, host)
+	} else {
+		log.Infof("Accepted the SSH host key %s of %s, replacing %s", fingerprint, host, known)
+	}
+	return nil
+}
+
+// saveHostKeys records the host keys trusted on first use by the operation
+// to the metadata, the ones of the hosts not in the cluster any longer are
+// dropped
+func (m *Manager) saveHostKeys(info *OperationInfo) error {
+	if info.hostKeys == nil {
+		return nil
+	}
+	metadata, err := m.metaFresh(info.clusterName)
+	if err != nil && os.IsNotExist(perrs.Cause(err)) {
+		// e.g., failed to deploy, or destroyed
+		return nil
+	}
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) &&
+		!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
+		return err
+	}
+	hm, ok := metadata.(spec.HostKeyedMetadata)
+	if !ok || metadata.GetTopology() == nil {
+		return nil
+	}
+
+	known := metadata.GetBaseMeta().HostKeys
+	recorded := info.hostKeys.Recorded()
+	keys := make(map[string]string)
+	for host := range SSHHosts(metadata.GetTopology()) {
+		if fingerprint, ok := known[host]; ok {
+			keys[host] = fingerprint
+		} else if fingerprint, ok := recorded[host]; ok {
+			keys[host] = fingerprint
+		}
+	}
+	if len(keys) == 0 && len(known) == 0 || reflect.DeepEqual(keys, known) {
+		return nil
+	}
+	if err := m.specManager.CheckWritable(); err != nil {
+		return err
+	}
+	hm.SetHostKeys(keys)
+	return m.specManager.SaveMeta(info.clusterName, metadata)
+}
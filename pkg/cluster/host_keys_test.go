@@ -0,0 +1,59 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/joomcode/errorx"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestHostKeys(t *testing.T) {
+	m, mc, _, cleanup := newTestMockCluster(t, 3)
+	defer cleanup()
+
+	// the keys are recorded when the hosts are connected the first time
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10}
+	require.Nil(t, m.StartCluster("mock", opt))
+	saved, err := m.meta("mock")
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"mock-1": ssh.FingerprintSHA256(mockHostKey("mock-1")),
+		"mock-2": ssh.FingerprintSHA256(mockHostKey("mock-2")),
+		"mock-3": ssh.FingerprintSHA256(mockHostKey("mock-3")),
+	}, saved.GetBaseMeta().HostKeys)
+
+	// the host rebuilt is refused until its new key is accepted
+	mc.SetHostKey("mock-2", "rebuilt")
+	rebuilt := ssh.FingerprintSHA256(mockHostKey("rebuilt"))
+	err = m.StopCluster("mock", opt)
+	require.NotNil(t, err)
+	assert.True(t, errorx.IsOfType(errorx.Cast(err), spec.ErrHostKeyMismatch), err.Error())
+	assert.Contains(t, err.Error(), rebuilt)
+	assert.True(t, mc.Host("mock-2").Active("tikv-20160.service"))
+
+	assert.NotNil(t, m.AcceptNewHostKey("mock", "mock-9"))
+	require.Nil(t, m.AcceptNewHostKey("mock", "mock-2"))
+	saved, err = m.meta("mock")
+	require.Nil(t, err)
+	assert.Equal(t, rebuilt, saved.GetBaseMeta().HostKeys["mock-2"])
+	assert.Equal(t, ssh.FingerprintSHA256(mockHostKey("mock-1")), saved.GetBaseMeta().HostKeys["mock-1"])
+	require.Nil(t, m.StopCluster("mock", opt))
+	assert.False(t, mc.Host("mock-2").Active("tikv-20160.service"))
+}
@@ -0,0 +1,213 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/ansible"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+var (
+	errNSImport = errorx.NewNamespace("import")
+	// ErrImportBlocked is returned when required settings can't be found in the inventory
+	ErrImportBlocked = errNSImport.NewType("blocked", errutil.ErrTraitPreCheck)
+	// ErrImportReloadFailed is returned when the configs of the imported cluster can't be regenerated
+	ErrImportReloadFailed = errNSImport.NewType("reload_failed")
+)
+
+// ImportOptions contains the options for importing a cluster from TiDB-Ansible
+type ImportOptions struct {
+	InventoryFileName string
+	AnsibleConfigFile string
+	Rename            string // rename the imported cluster
+	NoBackup          bool   // keep the ansible directory at its current location
+	DryRun            bool   // only report how the cluster is mapped, nothing is written
+	SkipConfirm       bool
+	SSHTimeout        int64
+	NativeSSH         bool
+}
+
+// printImportReport prints the mapping report of importing a cluster
+func printImportReport(report *ansible.ImportReport) {
+	fmt.Printf("Cluster name:    %s\n", color.HiYellowString(report.ClusterName))
+	fmt.Printf("Cluster version: %s\n", color.HiYellowString(report.Version))
+	fmt.Printf("Deploy user:     %s\n", color.HiYellowString(report.User))
+
+	rows := [][]string{{"Role", "Host", "Ports", "Deploy Dir", "Data Dir", "Log Dir"}}
+	for _, inst := range report.Instances {
+		ports := make([]string, 0, len(inst.Ports))
+		for _, port := range inst.Ports {
+			ports = append(ports, fmt.Sprintf("%d", port))
+		}
+		rows = append(rows, []string{
+			inst.Role,
+			inst.Host,
+			strings.Join(ports, "/"),
+			inst.DeployDir,
+			inst.DataDir,
+			inst.LogDir,
+		})
+	}
+	fmt.Println("Detected instances:")
+	cliutil.PrintTable(rows, true)
+
+	if len(report.Unrecognized) > 0 {
+		rows = [][]string{{"Source", "Variable", "Value"}}
+		for _, v := range report.Unrecognized {
+			rows = append(rows, []string{v.Source, v.Name, v.Value})
+		}
+		fmt.Println("\nUnrecognized variables, they are not imported:")
+		cliutil.PrintTable(rows, true)
+	}
+
+	if len(report.Blocking) > 0 {
+		fmt.Println(color.RedString("\nBlocking errors:"))
+		for _, msg := range report.Blocking {
+			fmt.Printf("  - %s\n", msg)
+		}
+	}
+}
+
+// ImportAnsible imports a cluster deployed by TiDB-Ansible from the ansible
+// directory dir. The mapping report of the cluster is printed and returned,
+// and nothing is written if opt.DryRun is set. Otherwise the metadata of the
+// cluster is written, the configs are fetched from the hosts and then
+// regenerated by TiUP, without restarting the instances.
+func (m *Manager) ImportAnsible(dir string, opt ImportOptions) (*ansible.ImportReport, error) {
+	if opt.InventoryFileName == "" {
+		opt.InventoryFileName = ansible.AnsibleInventoryFile
+	}
+	if err := m.specManager.CheckWritable(); err != nil {
+		return nil, err
+	}
+
+	// migrate cluster metadata from Ansible inventory
+	clsName, clsMeta, inv, err := ansible.ReadInventory(dir, opt.InventoryFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rename the imported cluster
+	if opt.Rename != "" {
+		clsName = opt.Rename
+	}
+
+	report, err := ansible.Preview(dir, opt.AnsibleConfigFile, clsName, clsMeta, inv, opt.SSHTimeout, opt.NativeSSH)
+	if err != nil {
+		return nil, err
+	}
+
+	if clsName != "" {
+		exist, err := m.specManager.Exist(clsName)
+		if err != nil {
+			return nil, perrs.AddStack(err)
+		}
+		if exist {
+			report.Blocking = append(report.Blocking, fmt.Sprintf(
+				"cluster name '%s' is duplicated, please use --rename `NAME` to specify another name", clsName))
+		}
+	}
+
+	printImportReport(report)
+	if opt.DryRun {
+		return report, nil
+	}
+
+	if len(report.Blocking) > 0 {
+		return report, ErrImportBlocked.
+			New("Failed to import cluster `%s` with %d blocking error(s)", clsName, len(report.Blocking)).
+			WithProperty(cliutil.SuggestionFromString(
+				"Please fix the blocking errors above in the inventory and try again."))
+	}
+
+	// prompt for backups
+	backupDir := m.specManager.Path(clsName, "ansible-backup")
+	backupFile := filepath.Join(dir, fmt.Sprintf("tiup-%s.bak", opt.InventoryFileName))
+	prompt := fmt.Sprintf("The ansible directory will be moved to %s after import.", backupDir)
+	if opt.NoBackup {
+		log.Infof("The '--no-backup' flag is set, the ansible directory will be kept at its current location.")
+		prompt = fmt.Sprintf("The inventory file will be renamed to %s after import.", backupFile)
+	}
+	log.Warnf("TiDB-Ansible and TiUP Cluster can NOT be used together, please DO NOT try to use ansible to manage the imported cluster anymore to avoid metadata conflict.")
+	log.Infof(prompt)
+	if !opt.SkipConfirm {
+		if err := cliutil.PromptForConfirmOrAbortError(
+			"Prepared to import TiDB %s cluster %s.\nDo you want to continue? [y/N]:",
+			clsMeta.Version,
+			clsName); err != nil {
+			return report, err
+		}
+	}
+
+	// copy SSH key to TiOps profile directory
+	if err := utils.CreateDir(m.specManager.Path(clsName, "ssh")); err != nil {
+		return report, err
+	}
+	srcKeyPathPriv := ansible.SSHKeyPath()
+	dstKeyPathPriv := m.specManager.Path(clsName, "ssh", "id_rsa")
+	if err := utils.CopyFile(srcKeyPathPriv, dstKeyPathPriv); err != nil {
+		return report, err
+	}
+	if err := utils.CopyFile(srcKeyPathPriv+".pub", dstKeyPathPriv+".pub"); err != nil {
+		return report, err
+	}
+
+	// copy config files form deployment servers
+	if err := ansible.ImportConfig(clsName, clsMeta, opt.SSHTimeout, opt.NativeSSH); err != nil {
+		return report, err
+	}
+
+	if err := m.specManager.SaveMeta(clsName, clsMeta); err != nil {
+		return report, err
+	}
+
+	// backup ansible files
+	if opt.NoBackup {
+		// rename original TiDB-Ansible inventory file
+		if err := utils.Move(filepath.Join(dir, opt.InventoryFileName), backupFile); err != nil {
+			return report, err
+		}
+		log.Infof("Ansible inventory renamed to %s.", color.HiCyanString(backupFile))
+	} else {
+		// move original TiDB-Ansible directory to a staged location
+		if err := utils.Move(dir, backupDir); err != nil {
+			return report, err
+		}
+		log.Infof("Ansible inventory saved in %s.", color.HiCyanString(backupDir))
+	}
+
+	// regenerate the configs, the instances are not restarted so they keep
+	// running with the imported ones until the next restart
+	reloadOpt := operator.Options{SSHTimeout: opt.SSHTimeout, NativeSSH: opt.NativeSSH}
+	if err := m.Reload(clsName, reloadOpt, true); err != nil {
+		return report, ErrImportReloadFailed.Wrap(err, "Cluster %s imported, but failed to regenerate its configs", clsName).
+			WithProperty(cliutil.SuggestionFromFormat(
+				"Please fix the error and run `%s reload %s --skip-restart` to regenerate the configs.",
+				cliutil.OsArgs0(), clsName))
+	}
+
+	log.Infof("Cluster %s imported.", clsName)
+	return report, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	perrs "github.com/pingcap/errors"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/tui"
+	"go.uber.org/zap"
+)
+
+// the file the failures of the last operations on the instances are recorded
+// in, under the cluster dir, so they are shown after the error scrolled away
+const instanceErrorsFile = "instance_errors.json"
+
+// the max length of the errors shown in the table of display
+const maxDisplayErrorLen = 60
+
+// InstanceError is the failure of the last operation on an instance
+type InstanceError struct {
+	Operation OperationType `json:"operation"`
+	Step      string        `json:"step"`
+	Error     string        `json:"error"`
+	Time      time.Time     `json:"time"`
+}
+
+// instanceErrorsMu serializes the updates of the files by the operations and
+// the displays in this process
+var instanceErrorsMu sync.Mutex
+
+// loadInstanceErrors returns the recorded failures by the IDs of instances
+func (m *Manager) loadInstanceErrors(clusterName string) (map[string]InstanceError, error) {
+	data, err := ioutil.ReadFile(m.specManager.Path(clusterName, instanceErrorsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, perrs.AddStack(err)
+	}
+	records := make(map[string]InstanceError)
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, perrs.Annotatef(err, "corrupted %s of cluster %s", instanceErrorsFile, clusterName)
+	}
+	return records, nil
+}
+
+// updateInstanceErrors applies f to the recorded failures of the cluster, the
+// file is only written if f returns true
+func (m *Manager) updateInstanceErrors(clusterName string, f func(map[string]InstanceError) bool) error {
+	instanceErrorsMu.Lock()
+	defer instanceErrorsMu.Unlock()
+	records, err := m.loadInstanceErrors(clusterName)
+	if err != nil {
+		return err
+	}
+	if records == nil {
+		records = make(map[string]InstanceError)
+	}
+	if !f(records) {
+		return nil
+	}
+	fname := m.specManager.Path(clusterName, instanceErrorsFile)
+	if len(records) == 0 {
+		if err := os.Remove(fname); err != nil && !os.IsNotExist(err) {
+			return perrs.AddStack(err)
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	return perrs.AddStack(ioutil.WriteFile(fname, data, 0644))
+}
+
+// recordInstanceErrors records the failures of the operation on the
+// instances, an instance is told by the InstanceFailure of operator the error
+// wraps. The failures of the steps collecting errors are recorded one by one,
+// see operator.Options.IgnoreErrors, otherwise the step executing when the
+// operation failed is recorded.
+func (m *Manager) recordInstanceErrors(info *OperationInfo, err error) {
+	now := time.Now()
+	failures := make(map[string]InstanceError)
+	record := func(step string, err error) {
+		if id, ok := operator.FailedInstance(err); ok {
+			failures[id] = InstanceError{
+				Operation: info.operationType,
+				Step:      tui.StripColor(step),
+				Error:     tui.StripColor(err.Error()),
+				Time:      now,
+			}
+		}
+	}
+	var degraded *task.DegradedError
+	if errors.As(err, &degraded) {
+		for _, f := range degraded.Failures {
+			record(f.Step, f.Err)
+		}
+	} else {
+		record(info.CurTask().Task, err)
+	}
+	if len(failures) == 0 {
+		return
+	}
+
+	if uerr := m.updateInstanceErrors(info.clusterName, func(records map[string]InstanceError) bool {
+		for id, f := range failures {
+			records[id] = f
+		}
+		return true
+	}); uerr != nil {
+		zap.L().Warn("Failed to record the failures of instances", zap.Error(uerr))
+	}
+}
+
+// clearInstanceErrors forgets the failures of the healthy instances
+func (m *Manager) clearInstanceErrors(clusterName string, healthy []string) {
+	if len(healthy) == 0 || m.specManager.CheckWritable() != nil {
+		return
+	}
+	if err := m.updateInstanceErrors(clusterName, func(records map[string]InstanceError) bool {
+		cleared := false
+		for _, id := range healthy {
+			if _, ok := records[id]; ok {
+				delete(records, id)
+				cleared = true
+			}
+		}
+		return cleared
+	}); err != nil {
+		zap.L().Warn("Failed to clear the failures of instances", zap.Error(err))
+	}
+}
+
+// isHealthyStatus returns whether the instance of the status is healthy
+func isHealthyStatus(status string) bool {
+	s := strings.ToLower(status)
+	return strings.HasPrefix(s, "up") || strings.HasPrefix(s, "healthy")
+}
+
+// formatInstanceError formats the failure in a cell of the table of display
+func formatInstanceError(e *InstanceError) string {
+	if e == nil {
+		return "-"
+	}
+	text := strings.Split(strings.TrimSpace(e.Error), "\n")[0]
+	if len(text) > maxDisplayErrorLen {
+		text = text[:maxDisplayErrorLen-3] + "..."
+	}
+	return e.Operation.String() + ": " + text
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"os"
+	"testing"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceErrors(t *testing.T) {
+	m, mc, _, cleanup := newTestMockCluster(t, 3)
+	defer cleanup()
+
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10}
+	lastErrors := func() map[string]*InstanceError {
+		insts, err := m.InstanceStatuses("mock", opt)
+		require.Nil(t, err)
+		errs := make(map[string]*InstanceError)
+		for _, inst := range insts {
+			if inst.LastError != nil {
+				errs[inst.ID] = inst.LastError
+			}
+		}
+		return errs
+	}
+
+	mc.Host("mock-2").Respond("systemctl daemon-reload && systemctl start tikv-20160", "", "address already in use")
+	require.NotNil(t, m.StartCluster("mock", opt))
+	errs := lastErrors()
+	require.Len(t, errs, 1)
+	require.NotNil(t, errs["mock-2:20160"])
+	assert.Equal(t, OperationStart, errs["mock-2:20160"].Operation)
+	assert.Contains(t, errs["mock-2:20160"].Error, "address already in use")
+	assert.Contains(t, formatInstanceError(errs["mock-2:20160"]), "start: ")
+	assert.Equal(t, "-", formatInstanceError(nil))
+
+	// the failure survives the operations on the other instances
+	require.Nil(t, m.RestartCluster("mock", operator.Options{
+		SSHTimeout: 5, OptTimeout: 10, APITimeout: 10, Roles: []string{spec.ComponentTiDB},
+	}))
+	assert.NotNil(t, lastErrors()["mock-2:20160"])
+
+	// and is forgotten once the instance is healthy
+	mc.SetStatus("mock-2:20160", "Up")
+	assert.Empty(t, lastErrors())
+	_, err := os.Stat(m.specManager.Path("mock", instanceErrorsFile))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,207 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/logger/log"
+)
+
+// defaultResourcesTimeout is the time to gather the resource usage of the
+// instances on a host if the options don't set it
+const defaultResourcesTimeout = 10 * time.Second
+
+// InstanceResources is the resource usage of an instance, the values that
+// couldn't be collected, e.g., the instance isn't running or its host timed
+// out, are nil rather than zero
+type InstanceResources struct {
+	CPUPercent   *float64 `json:"cpu_percent"`
+	RSSBytes     *uint64  `json:"rss_bytes"`
+	OpenFDs      *uint64  `json:"open_fds"`
+	DataDirBytes *uint64  `json:"data_dir_bytes"`
+	// Error is why the usage couldn't be gathered from the host
+	Error string `json:"error,omitempty"`
+}
+
+// the interval the CPU time of the instances is sampled over, so the CPU usage
+// is the current one rather than the average over the lifetime of the process
+const cpuSampleSeconds = 1
+
+// resourcesScript returns the command printing the resource usage of the
+// instances on a host, in the form of key=value lines, each instance starts
+// with an instance=<id> line. A key is printed with an empty value or not at
+// all if the value can't be read. The CPU usage is the CPU time of the
+// process in /proc/<pid>/stat sampled twice in cpuSampleSeconds, the
+// instances on the host are sampled at once. The size of data directories is
+// measured by du without descending into the subdirectories in the output.
+func resourcesScript(insts []spec.Instance, deployUser string) string {
+	var b strings.Builder
+	// the utime and stime fields, counted after the command name which may
+	// contain spaces
+	b.WriteString(`hz=$(getconf CLK_TCK 2>/dev/null || echo 100); `)
+	b.WriteString(`ticks() { sed 's/.*) //' /proc/$1/stat 2>/dev/null | awk '{print $12+$13}'; }; `)
+	for i, ins := range insts {
+		fmt.Fprintf(&b, "pid%d=$(systemctl show -p MainPID %s 2>/dev/null | cut -d= -f2); ", i, ins.ServiceName())
+		fmt.Fprintf(&b, `if [ -n "$pid%[1]d" ] && [ "$pid%[1]d" != 0 ]; then ticks%[1]d=$(ticks $pid%[1]d); fi; `, i)
+	}
+	fmt.Fprintf(&b, "up0=$(cut -d' ' -f1 /proc/uptime); sleep %d; up1=$(cut -d' ' -f1 /proc/uptime); ", cpuSampleSeconds)
+	for i, ins := range insts {
+		fmt.Fprintf(&b, "echo 'instance=%s'; pid=$pid%d; ", ins.ID(), i)
+		b.WriteString(`if [ -n "$pid" ] && [ "$pid" != 0 ] && [ -d /proc/$pid ]; then `)
+		fmt.Fprintf(&b, `if [ -n "$ticks%d" ]; then echo "cpu=$(awk -v t0="$ticks%[1]d" -v t1="$(ticks $pid)" -v u0="$up0" -v u1="$up1" -v hz="$hz" 'BEGIN{if (t1 != "" && u1 > u0) printf "%%.1f", (t1-t0)*100/hz/(u1-u0)}')"; fi; `, i)
+		b.WriteString(`echo "rss_kb=$(awk '/^VmRSS:/{print $2}' /proc/$pid/status 2>/dev/null)"; `)
+		b.WriteString(`if [ -r /proc/$pid/fd ]; then echo "fds=$(ls /proc/$pid/fd | wc -l)"; fi; `)
+		b.WriteString("fi; ")
+		if ins.DataDir() == "" {
+			continue
+		}
+		for _, dir := range clusterutil.MultiDirAbs(deployUser, ins.DataDir()) {
+			fmt.Fprintf(&b, `echo "data_kb=$(du -k --max-depth=0 '%s' 2>/dev/null | cut -f1)"; `, dir)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// parseResources parses the output of resourcesScript to the usage of the
+// instances by IDs
+func parseResources(output []byte) map[string]*InstanceResources {
+	result := make(map[string]*InstanceResources)
+	var cur *InstanceResources
+	dataDirOK := make(map[*InstanceResources]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		kv := strings.SplitN(strings.TrimSpace(scanner.Text()), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key, value := kv[0], strings.TrimSpace(kv[1])
+		if key == "instance" {
+			cur = &InstanceResources{}
+			result[value] = cur
+			dataDirOK[cur] = true
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		switch key {
+		case "cpu":
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				cur.CPUPercent = &v
+			}
+		case "rss_kb":
+			if v, err := strconv.ParseUint(value, 10, 64); err == nil {
+				v *= 1024
+				cur.RSSBytes = &v
+			}
+		case "fds":
+			if v, err := strconv.ParseUint(value, 10, 64); err == nil {
+				cur.OpenFDs = &v
+			}
+		case "data_kb":
+			// the size is only known if all the data dirs are measured
+			v, err := strconv.ParseUint(value, 10, 64)
+			if err != nil || !dataDirOK[cur] {
+				dataDirOK[cur] = false
+				cur.DataDirBytes = nil
+				continue
+			}
+			if cur.DataDirBytes == nil {
+				cur.DataDirBytes = new(uint64)
+			}
+			*cur.DataDirBytes += v * 1024
+		}
+	}
+	return result
+}
+
+// gatherResources gathers the resource usage of the instances with a single
+// command per host, the hosts are queried in parallel and each of them is
+// given the timeout, so a slow host only leaves its own instances unknown
+func gatherResources(ctx *task.Context, insts []spec.Instance, deployUser string, timeout time.Duration) map[string]*InstanceResources {
+	if timeout <= 0 {
+		timeout = defaultResourcesTimeout
+	}
+	hosts := make(map[string][]spec.Instance)
+	for _, ins := range insts {
+		hosts[ins.GetHost()] = append(hosts[ins.GetHost()], ins)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	result := make(map[string]*InstanceResources, len(insts))
+	for host, hostInsts := range hosts {
+		wg.Add(1)
+		go func(host string, hostInsts []spec.Instance) {
+			defer wg.Done()
+			var usage map[string]*InstanceResources
+			var reason string
+			if e, ok := ctx.GetExecutor(host); !ok {
+				reason = "no executor for the host"
+			} else {
+				stdout, stderr, err := e.Execute(resourcesScript(hostInsts, deployUser), false, timeout)
+				if err != nil {
+					log.Debugf("Failed to gather the resource usage on %s: %s, %s", host, err, strings.TrimSpace(string(stderr)))
+					reason = fmt.Sprintf("failed to gather the resource usage on %s in %s", host, timeout)
+				} else {
+					usage = parseResources(stdout)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, ins := range hostInsts {
+				r, ok := usage[ins.ID()]
+				if !ok {
+					r = &InstanceResources{Error: reason}
+				}
+				result[ins.ID()] = r
+			}
+		}(host, hostInsts)
+	}
+	wg.Wait()
+	return result
+}
+
+// formatResources returns the columns of the resource usage in the display
+func formatResources(r *InstanceResources) []string {
+	cols := []string{"-", "-", "-", "-"}
+	if r == nil {
+		return cols
+	}
+	if r.CPUPercent != nil {
+		cols[0] = strconv.FormatFloat(*r.CPUPercent, 'f', 1, 64)
+	}
+	if r.RSSBytes != nil {
+		cols[1] = humanBytes(*r.RSSBytes)
+	}
+	if r.OpenFDs != nil {
+		cols[2] = strconv.FormatUint(*r.OpenFDs, 10)
+	}
+	if r.DataDirBytes != nil {
+		cols[3] = humanBytes(*r.DataDirBytes)
+	}
+	return cols
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceResources(t *testing.T) {
+	m, mc, _, cleanup := newTestMockCluster(t, 2)
+	defer cleanup()
+
+	mc.Host("mock-1").Respond("hz=", strings.Join([]string{
+		"instance=mock-1:2379",
+		"cpu=12.5",
+		"rss_kb=2048",
+		"fds=42",
+		"data_kb=100",
+		"instance=mock-1:20160",
+		"cpu=",
+		"data_kb=",
+	}, "\n"), "")
+	mc.Host("mock-2").Respond("hz=", "", "timed out")
+
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10, Resources: true}
+	insts, err := m.InstanceStatuses("mock", opt)
+	require.Nil(t, err)
+	require.Len(t, insts, 6)
+	usage := make(map[string]*InstanceResources)
+	for _, s := range insts {
+		require.NotNil(t, s.Resources, s.ID)
+		usage[s.ID] = s.Resources
+	}
+
+	pd := usage["mock-1:2379"]
+	require.NotNil(t, pd.CPUPercent)
+	assert.Equal(t, 12.5, *pd.CPUPercent)
+	require.NotNil(t, pd.RSSBytes)
+	assert.Equal(t, uint64(2048*1024), *pd.RSSBytes)
+	require.NotNil(t, pd.OpenFDs)
+	assert.Equal(t, uint64(42), *pd.OpenFDs)
+	require.NotNil(t, pd.DataDirBytes)
+	assert.Equal(t, uint64(100*1024), *pd.DataDirBytes)
+
+	// the values not collected are null rather than zero
+	data, err := json.Marshal(usage["mock-1:20160"])
+	require.Nil(t, err)
+	assert.Equal(t, `{"cpu_percent":null,"rss_bytes":null,"open_fds":null,"data_dir_bytes":null}`, string(data))
+	for _, id := range []string{"mock-2:2379", "mock-2:20160", "mock-2:4000"} {
+		assert.Nil(t, usage[id].CPUPercent)
+		assert.NotEmpty(t, usage[id].Error)
+	}
+
+	// a single command per host
+	var gathered []string
+	for _, cmd := range mc.Host("mock-1").Commands() {
+		if strings.HasPrefix(cmd, "hz=") {
+			gathered = append(gathered, cmd)
+		}
+	}
+	require.Len(t, gathered, 1)
+	assert.Contains(t, gathered[0], "du -k --max-depth=0")
+	// the CPU time is sampled twice rather than averaged over the lifetime
+	assert.Contains(t, gathered[0], "/proc/$1/stat")
+	assert.Equal(t, 1, strings.Count(gathered[0], "sleep "))
+	assert.Nil(t, m.Display("mock", opt))
+}
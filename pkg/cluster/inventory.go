@@ -0,0 +1,293 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/meta"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// inventoryFile is the cached inventory in the directory of the cluster
+const inventoryFile = "inventory.json"
+
+// Inventory is the hardware and OS inventory of the hosts of a cluster
+type Inventory struct {
+	Cluster    string                    `json:"cluster"`
+	GatheredAt time.Time                 `json:"gathered_at"`
+	Hosts      []*operator.HostInventory `json:"hosts"`
+}
+
+// InventoryOptions are the options of gathering the inventory
+type InventoryOptions struct {
+	// Regather the inventory from the hosts instead of serving the cached one
+	Refresh bool
+}
+
+// Inventory returns the inventory of the hosts of the cluster. The inventory
+// is cached in the directory of the cluster, it's only gathered from the
+// hosts if there is no cache or opt.Refresh is set.
+func (m *Manager) Inventory(clusterName string, opt InventoryOptions, gOpt operator.Options) (*Inventory, error) {
+	metadata, err := m.meta(clusterName)
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+		return nil, perrs.AddStack(err)
+	}
+
+	if !opt.Refresh {
+		inv, err := m.loadInventory(clusterName)
+		if err != nil {
+			return nil, err
+		}
+		if inv != nil {
+			// the components may be changed since the inventory is cached
+			if metadata != nil {
+				inv.setComponents(hostComponents(metadata))
+			}
+			return inv, nil
+		}
+	}
+
+	inv, err := m.gatherInventory(clusterName, metadata, gOpt)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	if err := ioutil.WriteFile(m.specManager.Path(clusterName, inventoryFile), data, 0644); err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	return inv, nil
+}
+
+// loadInventory loads the cached inventory, nil is returned if there is none
+func (m *Manager) loadInventory(clusterName string) (*Inventory, error) {
+	data, err := ioutil.ReadFile(m.specManager.Path(clusterName, inventoryFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, perrs.AddStack(err)
+	}
+	inv := &Inventory{}
+	if err := json.Unmarshal(data, inv); err != nil {
+		return nil, perrs.Annotatef(err, "corrupted %s of cluster %s", inventoryFile, clusterName)
+	}
+	return inv, nil
+}
+
+func (m *Manager) gatherInventory(clusterName string, metadata spec.Metadata, gOpt operator.Options) (*Inventory, error) {
+	topo := metadata.GetTopology()
+	base := metadata.GetBaseMeta()
+	insightVer := spec.TiDBComponentVersion(spec.ComponentCheckCollector, "")
+
+	type hostInfo struct {
+		os, arch string
+		dirs     set.StringSet
+	}
+	hosts := make(map[string]*hostInfo)
+	topo.IterInstance(func(inst spec.Instance) {
+		h, ok := hosts[inst.GetHost()]
+		if !ok {
+			h = &hostInfo{
+				os:   inst.OS(),
+				arch: inst.Arch(),
+				dirs: set.NewStringSet(),
+			}
+			hosts[inst.GetHost()] = h
+		}
+		if inst.DataDir() != "" {
+			for _, dir := range clusterutil.MultiDirAbs(base.User, inst.DataDir()) {
+				h.dirs.Insert(dir)
+			}
+		}
+	})
+
+	inv := &Inventory{
+		Cluster:    clusterName,
+		GatheredAt: time.Now(),
+	}
+	comps := hostComponents(metadata)
+	var downloadTasks, collectTasks []*task.StepDisplay
+	downloaded := set.NewStringSet()
+	for host, h := range hosts {
+		if platform := h.os + "/" + h.arch; !downloaded.Exist(platform) {
+			downloaded.Insert(platform)
+			downloadTasks = append(downloadTasks, task.NewBuilder().
+				Download(spec.ComponentCheckCollector, h.os, h.arch, insightVer).
+				BuildAsStep(fmt.Sprintf("  - Downloading check tools for %s", platform)))
+		}
+
+		hostInv := &operator.HostInventory{Host: host, Components: comps[host]}
+		inv.Hosts = append(inv.Hosts, hostInv)
+
+		dirs := h.dirs.Slice()
+		sort.Strings(dirs)
+		toolsDir := task.CheckToolsDir(topo.BaseTopo().GlobalOptions.RemoteTmpDirOf(host))
+		collectTasks = append(collectTasks, task.NewBuilder().
+			Mkdir(base.User, host, filepath.Join(toolsDir, "bin")).
+			CopyComponent(
+				spec.ComponentCheckCollector,
+				h.os,
+				h.arch,
+				insightVer,
+				"", // use default srcPath
+				host,
+				toolsDir,
+			).
+			CollectInventory(host, dirs, hostInv).
+			Rmdir(host, toolsDir).
+			BuildAsStep(fmt.Sprintf("  - Gathering the inventory of %s", host)))
+	}
+	sort.Slice(inv.Hosts, func(i, j int) bool {
+		return inv.Hosts[i].Host < inv.Hosts[j].Host
+	})
+
+	t := task.NewBuilder().
+		SSHKeySet(
+			m.specManager.Path(clusterName, "ssh", "id_rsa"),
+			m.specManager.Path(clusterName, "ssh", "id_rsa.pub")).
+		ClusterSSH(topo, base.User, gOpt.SSHTimeout, gOpt.NativeSSH).
+		ParallelStep("+ Download check tools", downloadTasks...).
+		ParallelStep("+ Gather the inventory of hosts", collectTasks...).
+		Build()
+
+	ctx := task.NewContext()
+	ctx.SetRemoteTmpDirs(topo.BaseTopo().GlobalOptions)
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return nil, err
+		}
+		return nil, perrs.Trace(err)
+	}
+	for _, h := range inv.Hosts {
+		if h.Error != "" {
+			log.Warnf("The inventory of %s is incomplete: %s", h.Host, h.Error)
+		}
+	}
+	return inv, nil
+}
+
+// hostComponents returns the components deployed on each host, the versions
+// are the ones recorded in the metadata
+func hostComponents(metadata spec.Metadata) map[string][]operator.ComponentInventory {
+	base := metadata.GetBaseMeta()
+	comps := make(map[string]map[string]*operator.ComponentInventory)
+	metadata.GetTopology().IterInstance(func(inst spec.Instance) {
+		if comps[inst.GetHost()] == nil {
+			comps[inst.GetHost()] = make(map[string]*operator.ComponentInventory)
+		}
+		comp, ok := comps[inst.GetHost()][inst.ComponentName()]
+		if !ok {
+			comp = &operator.ComponentInventory{
+				Component: inst.ComponentName(),
+				Version:   base.ComponentVersion(inst.ComponentName()),
+			}
+			comps[inst.GetHost()][inst.ComponentName()] = comp
+		}
+		comp.Instances = append(comp.Instances, inst.ID())
+	})
+
+	hosts := make(map[string][]operator.ComponentInventory)
+	for host, hostComps := range comps {
+		for _, comp := range hostComps {
+			sort.Strings(comp.Instances)
+			hosts[host] = append(hosts[host], *comp)
+		}
+		sort.Slice(hosts[host], func(i, j int) bool {
+			return hosts[host][i].Component < hosts[host][j].Component
+		})
+	}
+	return hosts
+}
+
+// setComponents sets the components of the hosts in the inventory
+func (inv *Inventory) setComponents(comps map[string][]operator.ComponentInventory) {
+	for _, h := range inv.Hosts {
+		h.Components = comps[h.Host]
+	}
+}
+
+// PrintInventory prints the inventory as tables of hosts and disks
+func PrintInventory(inv *Inventory) {
+	fmt.Printf("Inventory of cluster %s, gathered at %s\n\n", color.CyanString(inv.Cluster),
+		inv.GatheredAt.Local().Format("2006-01-02 15:04:05"))
+
+	hostTable := [][]string{{"Host", "CPU", "Cores", "Memory", "OS", "Kernel", "NICs", "Components"}}
+	diskTable := [][]string{{"Host", "Dir", "Device", "Filesystem", "Mount Point", "Mount Options", "Size", "Available"}}
+	for _, h := range inv.Hosts {
+		var nics, comps []string
+		for _, nic := range h.NICs {
+			speed := "unknown"
+			if nic.SpeedMbps > 0 {
+				speed = fmt.Sprintf("%dMb/s", nic.SpeedMbps)
+			}
+			nics = append(nics, fmt.Sprintf("%s(%s)", nic.Name, speed))
+		}
+		for _, c := range h.Components {
+			comps = append(comps, fmt.Sprintf("%s:%s", c.Component, c.Version))
+		}
+		host := h.Host
+		if h.Error != "" {
+			host += color.YellowString(" (incomplete)")
+		}
+		hostTable = append(hostTable, []string{
+			host,
+			h.CPUModel,
+			fmt.Sprintf("%d/%d", h.CPUCores, h.CPUThreads),
+			fmt.Sprintf("%.1f GiB", float64(h.MemoryMB)/1024),
+			h.OSRelease,
+			h.Kernel,
+			strings.Join(nics, ","),
+			strings.Join(comps, ","),
+		})
+		for _, d := range h.Disks {
+			diskTable = append(diskTable, []string{
+				h.Host,
+				d.Dir,
+				d.Device,
+				d.FSType,
+				d.MountPoint,
+				d.MountOptions,
+				fmt.Sprintf("%.1f GiB", float64(d.SizeBytes)/1024/1024/1024),
+				fmt.Sprintf("%.1f GiB", float64(d.AvailBytes)/1024/1024/1024),
+			})
+		}
+	}
+	cliutil.PrintTable(hostTable, true)
+	if len(diskTable) > 1 {
+		fmt.Println()
+		cliutil.PrintTable(diskTable, true)
+	}
+}
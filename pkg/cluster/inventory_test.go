@@ -0,0 +1,65 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInventoryCache(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+	require.Nil(t, os.MkdirAll(m.specManager.Path("test"), 0755))
+	require.Nil(t, ioutil.WriteFile(m.specManager.Path("test", "meta.yaml"), []byte(`
+user: tidb
+tidb_version: v4.0.8
+component_versions:
+  tikv: v4.0.9
+topology:
+  tikv_servers:
+    - host: 172.16.5.1
+    - host: 172.16.5.1
+      port: 20161
+      status_port: 20181
+  alertmanager_servers:
+    - host: 172.16.5.1
+`), 0644))
+
+	cached := `{"cluster": "test", "gathered_at": "2020-07-01T00:00:00Z", "hosts": [
+		{"host": "172.16.5.1", "cpu_cores": 16, "components": [{"component": "tikv", "version": "v4.0.0"}], "disks": [{"dir": "/data", "device": "/dev/sdb", "fs_type": "ext4",
+		 "mount_point": "/data", "size_bytes": 1024, "avail_bytes": 512}]}]}`
+	require.Nil(t, ioutil.WriteFile(m.specManager.Path("test", inventoryFile), []byte(cached), 0644))
+
+	inv, err := m.Inventory("test", InventoryOptions{}, operator.Options{})
+	require.Nil(t, err)
+	assert.Equal(t, "test", inv.Cluster)
+	require.Len(t, inv.Hosts, 1)
+	assert.Equal(t, uint(16), inv.Hosts[0].CPUCores)
+	assert.Equal(t, "ext4", inv.Hosts[0].Disks[0].FSType)
+	// the versions of the components are the deployed ones in the metadata
+	assert.Equal(t, []operator.ComponentInventory{
+		{Component: "alertmanager", Version: "v4.0.8", Instances: []string{"172.16.5.1:9093"}},
+		{Component: "tikv", Version: "v4.0.9", Instances: []string{"172.16.5.1:20160", "172.16.5.1:20161"}},
+	}, inv.Hosts[0].Components)
+
+	require.Nil(t, ioutil.WriteFile(m.specManager.Path("test", inventoryFile), []byte("{"), 0644))
+	_, err = m.Inventory("test", InventoryOptions{}, operator.Options{})
+	assert.NotNil(t, err)
+}
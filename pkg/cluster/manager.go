@@ -24,7 +24,10 @@ import (
 	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/joomcode/errorx"
@@ -40,17 +43,25 @@ import (
 	"github.com/pingcap/tiup/pkg/set"
 	"github.com/pingcap/tiup/pkg/utils"
 	"github.com/pingcap/tiup/pkg/version"
-	"golang.org/x/mod/semver"
 	"gopkg.in/yaml.v2"
 )
 
 var (
 	errNSDeploy            = errorx.NewNamespace("deploy")
 	errDeployNameDuplicate = errNSDeploy.NewType("name_dup", errutil.ErrTraitPreCheck)
+	errDeployBootstrapUser = errNSDeploy.NewType("bootstrap_user", errutil.ErrTraitPreCheck)
 
 	errNSRename              = errorx.NewNamespace("rename")
 	errorRenameNameNotExist  = errNSRename.NewType("name_not_exist", errutil.ErrTraitPreCheck)
 	errorRenameNameDuplicate = errNSRename.NewType("name_dup", errutil.ErrTraitPreCheck)
+
+	errNSStop = errorx.NewNamespace("stop")
+	// ErrStopOrphansLeft is returned when orphaned processes survive being killed after stopping
+	ErrStopOrphansLeft = errNSStop.NewType("orphans_left")
+
+	errNSStart = errorx.NewNamespace("start")
+	// ErrStartErrorScope is returned when the scope of failures is unknown
+	ErrStartErrorScope = errNSStart.NewType("error_scope", errutil.ErrTraitPreCheck)
 )
 
 // Manager to deploy a cluster.
@@ -58,6 +69,12 @@ type Manager struct {
 	sysName     string
 	specManager *spec.SpecManager
 	bindVersion spec.BindVersion
+
+	// the clusters whose hosts are simulated, see NewMockCluster
+	mocks struct {
+		sync.Mutex
+		clusters map[string]*MockCluster
+	}
 }
 
 // NewManager create a Manager.
@@ -70,33 +87,105 @@ func NewManager(sysName string, specManager *spec.SpecManager, bindVersion spec.
 }
 
 // StartCluster start the cluster with specified name.
-func (m *Manager) StartCluster(name string, options operator.Options, fn ...func(b *task.Builder, metadata spec.Metadata)) error {
+//
+// The lifecycle operations (StartCluster, StopCluster, RestartCluster and
+// Upgrade) accept optional hooks fn, which are called in order after all the
+// core steps of the operation have been appended to the builder and before it
+// is built, so the steps they append run after the core steps, in the order
+// the hooks are given. The hooks receive the same metadata object the
+// operation works on, so the injected steps can read the topology.
+func (m *Manager) StartCluster(name string, options operator.Options, fn ...func(b *task.Builder, metadata spec.Metadata)) (err error) {
 	log.Infof("Starting cluster %s...", name)
 
+	if err := validateErrorScope(options.ErrorScope); err != nil {
+		return err
+	}
+
 	metadata, err := m.meta(name)
 	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
 		return perrs.AddStack(err)
 	}
 
+	// only the plan is printed in dry-run mode, the hosts are not connected to
+	dryRun := options.PlanFormat != ""
+	var op *OperationInfo
+	if !dryRun {
+		if op, err = m.beginOperation(name, OperationStart, options); err != nil {
+			return err
+		}
+		defer func() { m.endOperation(op, err) }()
+	}
+
 	topo := metadata.GetTopology()
 	base := metadata.GetBaseMeta()
+	if err := expandRoles(topo, &options); err != nil {
+		return err
+	}
+	if options.ExplainSelector {
+		explainInstances(selectInstances(topo, options), options)
+	}
 
-	b := task.NewBuilder().
-		SSHKeySet(
-			m.specManager.Path(name, "ssh", "id_rsa"),
-			m.specManager.Path(name, "ssh", "id_rsa.pub")).
-		ClusterSSH(topo, base.User, options.SSHTimeout, options.NativeSSH).
-		Func("StartCluster", func(ctx *task.Context) error {
-			return operator.Start(ctx, topo, options)
-		})
+	newBuilder := func() *task.Builder {
+		return task.NewBuilder().
+			SSHKeySet(
+				m.specManager.Path(name, "ssh", "id_rsa"),
+				m.specManager.Path(name, "ssh", "id_rsa.pub")).
+			ClusterSSH(topo, base.User, options.SSHTimeout, options.NativeSSH)
+	}
+	var ctx *task.Context
+	if !dryRun {
+		ctx = op.newTaskContext()
+	}
+
+	// the usages are checked by a separate task ahead of starting, so that a
+	// refused start is not swallowed by the steps collecting errors
+	var usages []DataDirUsage
+	check := newBuilder()
+	if !dryRun && checkDataDirUsage(check, base.User, topo, options, &usages) {
+		if err = check.Build().Execute(ctx); err != nil {
+			if len(usages) > 0 {
+				op.setResult(&StartResult{DataDirUsage: usages})
+			}
+			if errorx.Cast(err) != nil {
+				return err
+			}
+			return perrs.Trace(err)
+		}
+	}
+
+	b := newBuilder()
+	if scope := options.EffectiveErrorScope(); scope != operator.ErrorScopeOperation {
+		b.Mode(task.ErrorModeOf(scope))
+		buildStartInstanceSteps(b, topo, options)
+	} else {
+		var start *task.Func
+		start = task.NewFunc("StartCluster", func(ctx *task.Context) error {
+			return operator.Start(ctx.PhaseGetter(start), topo, options)
+		}, task.WithPlan(operator.ExplainStart(topo, options)))
+		b.Serial(start)
+	}
+
+	var grafanaReports []*operator.GrafanaProvisionReport
+	m.provisionGrafana(b, name, topo, options, &grafanaReports)
 
 	for _, f := range fn {
 		f(b, metadata)
 	}
 
 	t := b.Build()
+	if dryRun {
+		return m.writePlan(name, OperationStart, t, options.PlanFormat)
+	}
+	fmt.Println(m.estimateHint(name, OperationStart))
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	err = t.Execute(ctx)
+	if len(usages) > 0 || len(grafanaReports) > 0 || ctx.AddedDelay() > 0 {
+		op.setResult(&StartResult{DataDirUsage: usages, Grafana: grafanaReports, StaggerDelay: ctx.AddedDelay()})
+	}
+	if err != nil {
+		if warnDegraded(err, "Started", name) {
+			return err
+		}
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -104,31 +193,286 @@ func (m *Manager) StartCluster(name string, options operator.Options, fn ...func
 		return perrs.Trace(err)
 	}
 
+	if len(grafanaReports) > 0 {
+		if failed := printGrafanaReports(grafanaReports); failed > 0 {
+			log.Warnf("Failed to provision %d dashboard(s) of grafana, retry by `reload`", failed)
+		}
+	}
+
+	if d := ctx.AddedDelay(); d > 0 {
+		log.Infof("The starts of instances were staggered by %s in total", d.Round(time.Second))
+	}
 	log.Infof("Started cluster `%s` successfully", name)
 	return nil
 }
 
-// StopCluster stop the cluster.
-func (m *Manager) StopCluster(clusterName string, options operator.Options) error {
+// buildStartInstanceSteps appends a step for each instance to start, and a step
+// for the monitoring agents of each host, so the failures are confined to the
+// error scope of the mode of b
+func buildStartInstanceSteps(b *task.Builder, topo spec.Topology, options operator.Options) {
+	if options.Serial {
+		b.Serialize()
+	}
+
+	roleFilter := set.NewStringSet(options.Roles...)
+	nodeFilter := set.NewStringSet(options.Nodes...)
+	components := operator.FilterComponent(topo.ComponentsByStartOrder(), roleFilter)
+
+	// the instances wait for their turns in the steps, instead of in StartComponent
+	startOpts := options
+	startOpts.StartStagger, startOpts.StartStaggerJitter = 0, 0
+
+	uniqueHosts := set.NewStringSet()
+	var monitoredSteps []*task.StepDisplay
+	for _, com := range components {
+		var steps []*task.StepDisplay
+		insts := operator.SelectInstance(operator.FilterInstance(com.Instances(), nodeFilter), options.Selector)
+		schedule := operator.NewStaggerSchedule(insts, options.StartStagger, options.StartStaggerJitter)
+		for _, inst := range insts {
+			inst := inst
+			var start *task.Func
+			start = task.NewFunc(fmt.Sprintf("Start %s", inst.ID()), func(ctx *task.Context) error {
+				getter := ctx.PhaseGetter(start)
+				schedule.Wait(getter, inst)
+				return operator.StartComponent(getter, []spec.Instance{inst}, topo.BaseTopo().GlobalOptions.User, startOpts)
+			}, task.OnHosts(inst.GetHost()))
+			steps = append(steps, task.NewBuilder().
+				Serial(start).
+				BuildAsStepMessage(task.MsgStartInstance, task.MessageParams{"component": com.Name(), "instance": inst.ID()}))
+
+			monitoredOptions := topo.GetMonitoredOptions()
+			if monitoredOptions == nil || uniqueHosts.Exist(inst.GetHost()) {
+				continue
+			}
+			uniqueHosts.Insert(inst.GetHost())
+			var startMonitored *task.Func
+			startMonitored = task.NewFunc(fmt.Sprintf("StartMonitored %s", inst.GetHost()), func(ctx *task.Context) error {
+				return operator.StartMonitored(ctx.PhaseGetter(startMonitored), inst, monitoredOptions, options.OptTimeout)
+			}, task.OnHosts(inst.GetHost()))
+			monitoredSteps = append(monitoredSteps, task.NewBuilder().
+				Serial(startMonitored).
+				BuildAsStepMessage(task.MsgStartMonitorAgents, task.MessageParams{"host": inst.GetHost()}))
+		}
+		if len(steps) > 0 {
+			b.ParallelStep(fmt.Sprintf("+ Start %s", com.Name()), steps...)
+		}
+	}
+	// the agents selected explicitly are started on all the hosts
+	if monitoredOptions := topo.GetMonitoredOptions(); monitoredOptions != nil &&
+		(roleFilter.Exist(spec.ComponentNodeExporter) || roleFilter.Exist(spec.ComponentBlackboxExporter)) {
+		for _, inst := range selectInstances(topo, operator.Options{Nodes: options.Nodes, Selector: options.Selector}) {
+			inst := inst
+			if uniqueHosts.Exist(inst.GetHost()) {
+				continue
+			}
+			uniqueHosts.Insert(inst.GetHost())
+			var startMonitored *task.Func
+			startMonitored = task.NewFunc(fmt.Sprintf("StartMonitored %s", inst.GetHost()), func(ctx *task.Context) error {
+				return operator.StartMonitored(ctx.PhaseGetter(startMonitored), inst, monitoredOptions, options.OptTimeout)
+			}, task.OnHosts(inst.GetHost()))
+			monitoredSteps = append(monitoredSteps, task.NewBuilder().
+				Serial(startMonitored).
+				BuildAsStepMessage(task.MsgStartMonitorAgents, task.MessageParams{"host": inst.GetHost()}))
+		}
+	}
+	if len(monitoredSteps) > 0 {
+		b.ParallelStep("+ Start monitoring agents", monitoredSteps...)
+	}
+}
+
+// buildStopInstanceSteps appends a step for each instance to stop, and a step
+// for the monitoring agents of each host, in the order of operator.Stop, so
+// the failures are confined to the error scope of the mode of b
+func buildStopInstanceSteps(b *task.Builder, topo spec.Topology, options operator.Options) {
+	if options.Serial {
+		b.Serialize()
+	}
+
+	for _, g := range operator.StopGroups(topo, options) {
+		var steps []*task.StepDisplay
+		for _, inst := range g.Instances() {
+			inst := inst
+			var stop *task.Func
+			if g.Agents {
+				stop = task.NewFunc(fmt.Sprintf("StopMonitored %s", inst.GetHost()), func(ctx *task.Context) error {
+					return operator.StopMonitored(ctx.PhaseGetter(stop), inst, topo.GetMonitoredOptions(), options.OptTimeout)
+				}, task.OnHosts(inst.GetHost()))
+				steps = append(steps, task.NewBuilder().
+					Serial(stop).
+					BuildAsStepMessage(task.MsgStopMonitorAgents, task.MessageParams{"host": inst.GetHost()}))
+				continue
+			}
+			stop = task.NewFunc(fmt.Sprintf("Stop %s", inst.ID()), func(ctx *task.Context) error {
+				return operator.StopComponent(ctx.PhaseGetter(stop), []spec.Instance{inst}, options.OptTimeout)
+			}, task.OnHosts(inst.GetHost()))
+			steps = append(steps, task.NewBuilder().
+				Serial(stop).
+				BuildAsStepMessage(task.MsgStopInstance, task.MessageParams{"component": g.Component, "instance": inst.ID()}))
+		}
+		if len(steps) == 0 {
+			continue
+		}
+		if g.Agents {
+			b.ParallelStep("+ Stop monitoring agents", steps...)
+		} else {
+			b.ParallelStep(fmt.Sprintf("+ Stop %s", g.Component), steps...)
+		}
+	}
+}
+
+// validateErrorScope checks if the scope of failures is known
+func validateErrorScope(scope operator.ErrorScope) error {
+	if !scope.Valid() {
+		return ErrStartErrorScope.New("Unknown error scope '%s', it's one of %v", scope, operator.ErrorScopes)
+	}
+	return nil
+}
+
+// hostPhaseMode returns the mode of the phases preparing the hosts in the
+// error scope, e.g., copying the packages. The steps on a host depend on the
+// ones before them, so a failure skips the rest steps on its host in both the
+// host and the step scopes. The phases are built with the mode, so a failure
+// in them stops the operation after they are done on the other hosts.
+func hostPhaseMode(scope operator.ErrorScope) task.ErrorMode {
+	if scope == "" || scope == operator.ErrorScopeOperation {
+		return task.StopOnError
+	}
+	return task.SkipFailedHosts
+}
+
+// warnDegraded prints the failures confined to the error scope if err is a
+// task.DegradedError, and reports whether it is
+func warnDegraded(err error, done, name string) bool {
+	var degraded *task.DegradedError
+	if !errors.As(err, &degraded) {
+		return false
+	}
+	log.Warnf("%s cluster `%s` with %d failed step(s)", done, name, len(degraded.Failures))
+	for _, h := range degraded.Hosts {
+		log.Warnf("Host %s is degraded, retry it from step: %s", h.Host, h.FirstFailedStep)
+	}
+	return true
+}
+
+// expandRoles expands the meta-roles in the roles of the options, the roles
+// they stand for are printed
+func expandRoles(topo spec.Topology, options *operator.Options) error {
+	roles, err := operator.ExpandRoles(topo, options.Roles)
+	if err != nil {
+		return err
+	}
+	if strings.Join(roles, ",") != strings.Join(options.Roles, ",") {
+		log.Infof("Roles %s are expanded to: %s", strings.Join(options.Roles, ","), strings.Join(roles, ","))
+	}
+	options.Roles = roles
+	return nil
+}
+
+// selectInstances returns the instances to operate, filtered by the roles,
+// nodes and selector of the options
+func selectInstances(topo spec.Topology, options operator.Options) []spec.Instance {
+	roleFilter := set.NewStringSet(options.Roles...)
+	nodeFilter := set.NewStringSet(options.Nodes...)
+	var insts []spec.Instance
+	for _, com := range operator.FilterComponent(topo.ComponentsByStartOrder(), roleFilter) {
+		insts = append(insts, operator.SelectInstance(operator.FilterInstance(com.Instances(), nodeFilter), options.Selector)...)
+	}
+	return insts
+}
+
+// explainInstances prints the instances matched by the filters of the options
+func explainInstances(insts []spec.Instance, options operator.Options) {
+	if options.Selector != nil {
+		log.Infof("Instances matched by selector `%s`:", options.Selector)
+	} else {
+		log.Infof("Instances matched:")
+	}
+	rows := [][]string{{"ID", "Role", "Host", "Port", "Labels"}}
+	for _, inst := range insts {
+		labels := "-"
+		if l, ok := inst.(interface{ Labels() map[string]string }); ok && len(l.Labels()) > 0 {
+			var kvs []string
+			for k, v := range l.Labels() {
+				kvs = append(kvs, k+"="+v)
+			}
+			sort.Strings(kvs)
+			labels = strings.Join(kvs, ",")
+		}
+		rows = append(rows, []string{inst.ID(), inst.Role(), inst.GetHost(), strconv.Itoa(inst.GetPort()), labels})
+	}
+	cliutil.PrintTable(rows, true)
+}
+
+// StopCluster stop the cluster, see StartCluster for the usage of fn.
+func (m *Manager) StopCluster(clusterName string, options operator.Options, fn ...func(b *task.Builder, metadata spec.Metadata)) (err error) {
+	if err := validateErrorScope(options.ErrorScope); err != nil {
+		return err
+	}
+
 	metadata, err := m.meta(clusterName)
 	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
 		return perrs.AddStack(err)
 	}
 
+	// only the plan is printed in dry-run mode, the hosts are not connected to
+	dryRun := options.PlanFormat != ""
+	var op *OperationInfo
+	if !dryRun {
+		if op, err = m.beginOperation(clusterName, OperationStop, options); err != nil {
+			return err
+		}
+		defer func() { m.endOperation(op, err) }()
+	}
+
 	topo := metadata.GetTopology()
 	base := metadata.GetBaseMeta()
+	if err := expandRoles(topo, &options); err != nil {
+		return err
+	}
+	if options.ExplainSelector {
+		explainInstances(selectInstances(topo, options), options)
+	}
 
-	t := task.NewBuilder().
+	b := task.NewBuilder().
 		SSHKeySet(
 			m.specManager.Path(clusterName, "ssh", "id_rsa"),
 			m.specManager.Path(clusterName, "ssh", "id_rsa.pub")).
-		ClusterSSH(metadata.GetTopology(), base.User, options.SSHTimeout, options.NativeSSH).
-		Func("StopCluster", func(ctx *task.Context) error {
+		ClusterSSH(metadata.GetTopology(), base.User, options.SSHTimeout, options.NativeSSH)
+	if scope := options.EffectiveErrorScope(); scope != operator.ErrorScopeOperation {
+		b.Mode(task.ErrorModeOf(scope))
+		buildStopInstanceSteps(b, topo, options)
+	} else {
+		b.Func("StopCluster", func(ctx *task.Context) error {
 			return operator.Stop(ctx, topo, options)
-		}).
-		Build()
+		}, task.WithPlan(operator.ExplainStop(topo, options)))
+	}
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	for _, f := range fn {
+		f(b, metadata)
+	}
+
+	var report *operator.StopReport
+	b.Func("VerifyStopped", func(ctx *task.Context) error {
+		report = operator.VerifyStopped(ctx, topo, options)
+		return nil
+	})
+
+	t := b.Build()
+	if dryRun {
+		return m.writePlan(clusterName, OperationStop, t, options.PlanFormat)
+	}
+	fmt.Println(m.estimateHint(clusterName, OperationStop))
+
+	unsilence, err := m.silenceAlerts(op, topo, options)
+	if err != nil {
+		return err
+	}
+	defer unsilence()
+
+	if err := t.Execute(op.newTaskContext()); err != nil {
+		if warnDegraded(err, "Stopped", clusterName) {
+			return err
+		}
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -136,31 +480,112 @@ func (m *Manager) StopCluster(clusterName string, options operator.Options) erro
 		return perrs.Trace(err)
 	}
 
+	op.setResult(report)
+	if !report.Safe {
+		printStopReport(report)
+		if options.KillOrphans {
+			return ErrStopOrphansLeft.
+				New("Some processes of cluster `%s` are still running after being killed", clusterName).
+				WithProperty(cliutil.SuggestionFromString(
+					"Please check the processes above on the hosts, they may be stuck in uninterruptible sleep."))
+		}
+		log.Warnf("Stopped cluster `%s`, but %d process(es) are left running under the directories of instances", clusterName, len(report.Orphans()))
+		log.Warnf("Use `--kill-orphans` to kill them before patching or rebooting the hosts")
+		return nil
+	}
+
 	log.Infof("Stopped cluster `%s` successfully", clusterName)
 	return nil
 }
 
-// RestartCluster restart the cluster.
-func (m *Manager) RestartCluster(clusterName string, options operator.Options) error {
+// printStopReport prints the processes left running on each host after stopping
+func printStopReport(report *operator.StopReport) {
+	rows := [][]string{{"Host", "PID", "Instance", "Path", "Result"}}
+	for _, h := range report.Hosts {
+		if h.Error != "" {
+			rows = append(rows, []string{h.Host, "", "", "", color.RedString(h.Error)})
+		}
+		for _, p := range h.Orphans {
+			result := color.YellowString("Running")
+			if p.Killed {
+				result = color.GreenString("Killed")
+			}
+			rows = append(rows, []string{h.Host, strconv.Itoa(p.PID), p.Instance, p.Path, result})
+		}
+	}
+	cliutil.PrintTable(rows, true)
+}
+
+// RestartCluster restart the cluster, see StartCluster for the usage of fn.
+func (m *Manager) RestartCluster(clusterName string, options operator.Options, fn ...func(b *task.Builder, metadata spec.Metadata)) (err error) {
+	if err := validateErrorScope(options.ErrorScope); err != nil {
+		return err
+	}
+
 	metadata, err := m.meta(clusterName)
 	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
 		return perrs.AddStack(err)
 	}
 
+	// only the plan is printed in dry-run mode, the hosts are not connected to
+	dryRun := options.PlanFormat != ""
+	var op *OperationInfo
+	if !dryRun {
+		if op, err = m.beginOperation(clusterName, OperationRestart, options); err != nil {
+			return err
+		}
+		defer func() { m.endOperation(op, err) }()
+	}
+
 	topo := metadata.GetTopology()
 	base := metadata.GetBaseMeta()
+	if err := expandRoles(topo, &options); err != nil {
+		return err
+	}
+	if options.ExplainSelector {
+		explainInstances(selectInstances(topo, options), options)
+	}
 
-	t := task.NewBuilder().
+	b := task.NewBuilder().
 		SSHKeySet(
 			m.specManager.Path(clusterName, "ssh", "id_rsa"),
 			m.specManager.Path(clusterName, "ssh", "id_rsa.pub")).
-		ClusterSSH(topo, base.User, options.SSHTimeout, options.NativeSSH).
-		Func("RestartCluster", func(ctx *task.Context) error {
+		ClusterSSH(topo, base.User, options.SSHTimeout, options.NativeSSH)
+	if scope := options.EffectiveErrorScope(); scope != operator.ErrorScopeOperation {
+		b.Mode(task.ErrorModeOf(scope))
+		buildStopInstanceSteps(b, topo, options)
+		buildStartInstanceSteps(b, topo, options)
+	} else {
+		b.Func("RestartCluster", func(ctx *task.Context) error {
 			return operator.Restart(ctx, topo, options)
-		}).
-		Build()
+		}, task.WithPlan(operator.ExplainRestart(topo, options)))
+	}
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	for _, f := range fn {
+		f(b, metadata)
+	}
+
+	t := b.Build()
+	if dryRun {
+		return m.writePlan(clusterName, OperationRestart, t, options.PlanFormat)
+	}
+	fmt.Println(m.estimateHint(clusterName, OperationRestart))
+
+	unsilence, err := m.silenceAlerts(op, topo, options)
+	if err != nil {
+		return err
+	}
+	defer unsilence()
+
+	ctx := op.newTaskContext()
+	err = t.Execute(ctx)
+	if ctx.AddedDelay() > 0 {
+		op.setResult(&StartResult{StaggerDelay: ctx.AddedDelay()})
+	}
+	if err != nil {
+		if warnDegraded(err, "Restarted", clusterName) {
+			return err
+		}
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -168,12 +593,16 @@ func (m *Manager) RestartCluster(clusterName string, options operator.Options) e
 		return perrs.Trace(err)
 	}
 
+	if d := ctx.AddedDelay(); d > 0 {
+		log.Infof("The starts of instances were staggered by %s in total", d.Round(time.Second))
+	}
 	log.Infof("Restarted cluster `%s` successfully", clusterName)
 	return nil
 }
 
-// ListCluster list the clusters.
-func (m *Manager) ListCluster() error {
+// ListCluster list the clusters, only the ones matching all the tag filters
+// are listed if there is any.
+func (m *Manager) ListCluster(filters ...TagFilter) error {
 	names, err := m.specManager.List()
 	if err != nil {
 		return perrs.AddStack(err)
@@ -181,21 +610,31 @@ func (m *Manager) ListCluster() error {
 
 	clusterTable := [][]string{
 		// Header
-		{"Name", "User", "Version", "Path", "PrivateKey"},
+		{"Name", "User", "Version", "Protected", "Tags", "Path", "PrivateKey"},
 	}
 
 	for _, name := range names {
-		metadata, err := m.meta(name)
-		if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+		// the summaries are read so the topologies are not parsed
+		summary, err := m.specManager.Summary(name)
+		if err != nil {
 			return perrs.Trace(err)
 		}
 
-		base := metadata.GetBaseMeta()
+		if !MatchTags(summary.Tags, filters) {
+			continue
+		}
+
+		protected := ""
+		if summary.Protected {
+			protected = "yes"
+		}
 
 		clusterTable = append(clusterTable, []string{
 			name,
-			base.User,
-			base.Version,
+			summary.User,
+			summary.Version,
+			protected,
+			FormatTags(summary.Tags),
 			m.specManager.Path(name),
 			m.specManager.Path(name, "ssh", "id_rsa"),
 		})
@@ -205,6 +644,75 @@ func (m *Manager) ListCluster() error {
 	return nil
 }
 
+// ClusterSummary is the summary of a cluster with its last operation
+type ClusterSummary struct {
+	Name          string             `json:"name"`
+	User          string             `json:"user,omitempty"`
+	Version       string             `json:"version,omitempty"`
+	Protected     bool               `json:"protected,omitempty"`
+	Tags          map[string]string  `json:"tags,omitempty"`
+	Instances     map[string]int     `json:"instances,omitempty"` // the count of instances by component
+	Error         string             `json:"error,omitempty"`     // the error loading the metadata
+	LastOperation *OperationProgress `json:"last_operation,omitempty"`
+}
+
+// ClusterSummaries returns the summaries of all clusters, the last operations
+// are the ones run by this process. If there are tag filters, only the
+// clusters matching all of them are returned.
+func (m *Manager) ClusterSummaries(filters ...TagFilter) ([]ClusterSummary, error) {
+	names, err := m.specManager.List()
+	if err != nil {
+		return nil, perrs.AddStack(err)
+	}
+
+	summaries := make([]ClusterSummary, 0, len(names))
+	for _, name := range names {
+		summary, err := m.clusterSummary(name)
+		if err != nil {
+			summary.Error = err.Error()
+		}
+		if !MatchTags(summary.Tags, filters) {
+			continue
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// ClusterSummary returns the summary of the cluster. ErrClusterNotExist is
+// returned if the cluster doesn't exist, and ErrMetaCorrupt if the meta file
+// can't be parsed.
+func (m *Manager) ClusterSummary(name string) (*ClusterSummary, error) {
+	if err := m.checkExist(name); err != nil {
+		return nil, err
+	}
+	summary, err := m.clusterSummary(name)
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// clusterSummary returns the summary of the cluster, only the name and the
+// last operation are set if its metadata can't be loaded
+func (m *Manager) clusterSummary(name string) (ClusterSummary, error) {
+	summary := ClusterSummary{Name: name}
+	if info := GetCurrentOperation(name); info != nil {
+		progress := info.ComputeProgress()
+		summary.LastOperation = &progress
+	}
+	cached, err := m.specManager.Summary(name)
+	if err != nil {
+		return summary, err
+	}
+	summary.User = cached.User
+	summary.Version = cached.Version
+	summary.Protected = cached.Protected
+	summary.Tags = cached.Tags
+	summary.Instances = cached.Instances
+	return summary, nil
+}
+
 // CleanCluster clean the cluster without destroying it
 func (m *Manager) CleanCluster(clusterName string, gOpt operator.Options, cleanOpt operator.Options, skipConfirm bool) error {
 	metadata, err := m.meta(clusterName)
@@ -215,6 +723,13 @@ func (m *Manager) CleanCluster(clusterName string, gOpt operator.Options, cleanO
 	topo := metadata.GetTopology()
 	base := metadata.GetBaseMeta()
 
+	if err := checkProtection(clusterName, base, gOpt.OverrideProtection, "clean"); err != nil {
+		return err
+	}
+	if err := checkScopedSudo(clusterName, base, "clean"); err != nil {
+		return err
+	}
+
 	if !skipConfirm {
 		target := ""
 		if cleanOpt.CleanupData && cleanOpt.CleanupLog {
@@ -269,10 +784,20 @@ func (m *Manager) DestroyCluster(clusterName string, gOpt operator.Options, dest
 		!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
 		return perrs.AddStack(err)
 	}
+	if err := m.specManager.CheckWritable(); err != nil {
+		return err
+	}
 
 	topo := metadata.GetTopology()
 	base := metadata.GetBaseMeta()
 
+	if err := checkProtection(clusterName, base, gOpt.OverrideProtection, "destroy"); err != nil {
+		return err
+	}
+	if err := checkScopedSudo(clusterName, base, "destroy"); err != nil {
+		return err
+	}
+
 	if !skipConfirm {
 		if err := cliutil.PromptForConfirmOrAbortError(
 			"This operation will destroy %s %s cluster %s and its data.\nDo you want to continue? [y/N]:",
@@ -284,20 +809,58 @@ func (m *Manager) DestroyCluster(clusterName string, gOpt operator.Options, dest
 		log.Infof("Destroying cluster...")
 	}
 
-	t := task.NewBuilder().
+	// remove the drop-in files of OS settings of the cluster
+	var removeOSSettingsTasks []task.Task
+	if spec.GetOSSettings(topo) != nil {
+		cmd := fmt.Sprintf("rm -f %s && sysctl --system >/dev/null",
+			strings.Join(spec.OSSettingsDropInPaths(clusterName), " "))
+		hosts := set.NewStringSet()
+		topo.IterInstance(func(inst spec.Instance) {
+			if !hosts.Exist(inst.GetHost()) {
+				hosts.Insert(inst.GetHost())
+				removeOSSettingsTasks = append(removeOSSettingsTasks,
+					task.NewBuilder().Shell(inst.GetHost(), cmd, true).Build())
+			}
+		})
+	}
+
+	var notCleaned *operator.HostsNotCleanedError
+	tb := task.NewBuilder().
 		SSHKeySet(
 			m.specManager.Path(clusterName, "ssh", "id_rsa"),
 			m.specManager.Path(clusterName, "ssh", "id_rsa.pub")).
 		ClusterSSH(topo, base.User, gOpt.SSHTimeout, gOpt.NativeSSH).
 		Func("StopCluster", func(ctx *task.Context) error {
-			return operator.Stop(ctx, topo, operator.Options{})
+			err := operator.Stop(ctx, topo, operator.Options{})
+			if err != nil && destroyOpt.Force {
+				log.Warnf("Failed to stop cluster `%s`, destroying it anyway: %s", clusterName, err)
+				return nil
+			}
+			return err
 		}).
 		Func("DestroyCluster", func(ctx *task.Context) error {
-			return operator.Destroy(ctx, topo, destroyOpt)
-		}).
-		Build()
+			err := operator.Destroy(ctx, topo, destroyOpt)
+			if e, ok := err.(*operator.HostsNotCleanedError); ok {
+				notCleaned = e
+				return nil
+			}
+			return err
+		})
+	if destroyOpt.Force {
+		removeOSSettings := task.NewBuilder().Parallel(removeOSSettingsTasks...).Build()
+		tb.Func("RemoveOSSettings", func(ctx *task.Context) error {
+			if err := removeOSSettings.Execute(ctx); err != nil {
+				log.Warnf("Failed to remove the OS settings of cluster `%s`: %s", clusterName, err)
+			}
+			return nil
+		})
+	} else {
+		tb.Parallel(removeOSSettingsTasks...)
+	}
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	ctx := task.NewContext()
+	m.applyMock(clusterName, ctx)
+	if err := tb.Build().Execute(ctx); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -305,6 +868,15 @@ func (m *Manager) DestroyCluster(clusterName string, gOpt operator.Options, dest
 		return perrs.Trace(err)
 	}
 
+	if notCleaned != nil {
+		// the next deploy on the hosts warns about the units left
+		if err := m.recordUncleanHosts(clusterName, topo, notCleaned); err != nil {
+			log.Warnf("Failed to record the hosts not cleaned up: %s", err)
+		}
+		log.Warnf("Cluster `%s` is destroyed, but %s\nThe units left on the hosts are checked when deploying to them again.",
+			clusterName, notCleaned)
+	}
+
 	if err := m.specManager.Remove(clusterName); err != nil {
 		return perrs.Trace(err)
 	}
@@ -334,20 +906,29 @@ func (m *Manager) Exec(clusterName string, opt ExecOptions, gOpt operator.Option
 	filterNodes := set.NewStringSet(gOpt.Nodes...)
 
 	var shellTasks []task.Task
+	var matched []spec.Instance
 	uniqueHosts := map[string]int{} // host -> ssh-port
 	topo.IterInstance(func(inst spec.Instance) {
-		if _, found := uniqueHosts[inst.GetHost()]; !found {
-			if len(gOpt.Roles) > 0 && !filterRoles.Exist(inst.Role()) {
-				return
-			}
+		if len(gOpt.Roles) > 0 && !filterRoles.Exist(inst.Role()) {
+			return
+		}
 
-			if len(gOpt.Nodes) > 0 && !filterNodes.Exist(inst.GetHost()) {
-				return
-			}
+		if len(gOpt.Nodes) > 0 && !filterNodes.Exist(inst.GetHost()) {
+			return
+		}
+
+		if !gOpt.Selector.Match(inst) {
+			return
+		}
 
+		matched = append(matched, inst)
+		if _, found := uniqueHosts[inst.GetHost()]; !found {
 			uniqueHosts[inst.GetHost()] = inst.GetSSHPort()
 		}
 	})
+	if gOpt.ExplainSelector {
+		explainInstances(matched, gOpt)
+	}
 
 	for host := range uniqueHosts {
 		shellTasks = append(shellTasks,
@@ -407,29 +988,119 @@ func (m *Manager) Display(clusterName string, opt operator.Options) error {
 	// display cluster meta
 	cyan := color.New(color.FgCyan, color.Bold)
 	fmt.Printf("%s Cluster: %s\n", m.sysName, cyan.Sprint(clusterName))
-	fmt.Printf("%s Version: %s\n", m.sysName, cyan.Sprint(base.Version))
+	if len(base.ComponentVersions) > 0 {
+		// the components are upgraded apart from each other
+		fmt.Printf("%s Version: %s\n", m.sysName, cyan.Sprint(formatComponentVersions(deployedVersions(topo, base))))
+	} else {
+		fmt.Printf("%s Version: %s\n", m.sysName, cyan.Sprint(base.Version))
+	}
+	if base.Protected {
+		fmt.Printf("%s Protected: %s\n", m.sysName, color.HiRedString("yes"))
+	}
+	if len(base.Tags) > 0 {
+		fmt.Printf("%s Tags: %s\n", m.sysName, cyan.Sprint(FormatTags(base.Tags)))
+	}
+
+	statuses, err := m.instanceStatuses(clusterName, topo, base, opt)
+	if err != nil {
+		return err
+	}
 
 	// display topology
-	clusterTable := [][]string{
-		// Header
-		{"ID", "Role", "Host", "Ports", "OS/Arch", "Status", "Data Dir", "Deploy Dir"},
+	header := []string{"ID", "Role", "Host", "Ports", "OS/Arch", "Status"}
+	if opt.Resources {
+		header = append(header, "CPU%", "Memory", "FDs", "Data Size")
+	}
+	header = append(header, "Data Dir", "Deploy Dir", "Last Error")
+	clusterTable := [][]string{header}
+	for _, s := range statuses {
+		status := formatInstanceStatus(s.Status)
+		if s.Reason != "" {
+			status += " (" + s.Reason + ")"
+		}
+		row := []string{
+			color.CyanString(s.ID),
+			s.Role,
+			s.Host,
+			utils.JoinInt(s.Ports, "/"),
+			cliutil.OsArch(s.OS, s.Arch),
+			status,
+		}
+		if opt.Resources {
+			row = append(row, formatResources(s.Resources)...)
+		}
+		clusterTable = append(clusterTable, append(row,
+			s.DataDir,
+			s.DeployDir,
+			formatInstanceError(s.LastError),
+		))
 	}
 
+	// Sort by role,host,ports
+	sort.Slice(clusterTable[1:], func(i, j int) bool {
+		lhs, rhs := clusterTable[i+1], clusterTable[j+1]
+		// column: 1 => role, 2 => host, 3 => ports
+		for _, col := range []int{1, 2} {
+			if lhs[col] != rhs[col] {
+				return lhs[col] < rhs[col]
+			}
+		}
+		return lhs[3] < rhs[3]
+	})
+
+	cliutil.PrintTable(clusterTable, true)
+
+	return nil
+}
+
+// InstanceStatus is the status of an instance of the cluster
+type InstanceStatus struct {
+	ID        string
+	Role      string
+	Host      string
+	Ports     []int
+	OS        string
+	Arch      string
+	Status    string // e.g., Up, Down, Healthy|L
+	Reason    string // why the instance is unhealthy, empty if it's not known
+	DataDir   string // "-" if the instance has no data dir
+	DeployDir string
+	// LastError is the failure of the last operation on the instance, it's
+	// only set if the instance isn't healthy
+	LastError *InstanceError
+	// Resources is the resource usage of the instance, it's only gathered
+	// if the Resources of the options is set
+	Resources *InstanceResources
+}
+
+// InstanceStatuses returns the status of the instances of the cluster, filtered
+// by the roles and nodes of opt
+func (m *Manager) InstanceStatuses(clusterName string, opt operator.Options) ([]InstanceStatus, error) {
+	metadata, err := m.meta(clusterName)
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) &&
+		!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
+		return nil, perrs.AddStack(err)
+	}
+	return m.instanceStatuses(clusterName, metadata.GetTopology(), metadata.GetBaseMeta(), opt)
+}
+
+func (m *Manager) instanceStatuses(clusterName string, topo spec.Topology, base *spec.BaseMeta, opt operator.Options) ([]InstanceStatus, error) {
 	ctx := task.NewContext()
-	err = ctx.SetSSHKeySet(m.specManager.Path(clusterName, "ssh", "id_rsa"),
+	m.applyMock(clusterName, ctx)
+	err := ctx.SetSSHKeySet(m.specManager.Path(clusterName, "ssh", "id_rsa"),
 		m.specManager.Path(clusterName, "ssh", "id_rsa.pub"))
 	if err != nil {
-		return perrs.AddStack(err)
+		return nil, perrs.AddStack(err)
 	}
 
 	err = ctx.SetClusterSSH(topo, base.User, opt.SSHTimeout, opt.NativeSSH)
 	if err != nil {
-		return perrs.AddStack(err)
+		return nil, perrs.AddStack(err)
 	}
 
 	filterRoles := set.NewStringSet(opt.Roles...)
 	filterNodes := set.NewStringSet(opt.Nodes...)
-	pdList := topo.BaseTopo().MasterList
+	var insts []spec.Instance
 	for _, comp := range topo.ComponentsByStartOrder() {
 		for _, ins := range comp.Instances() {
 			// apply role filter
@@ -440,66 +1111,90 @@ func (m *Manager) Display(clusterName string, opt operator.Options) error {
 			if len(filterNodes) > 0 && !filterNodes.Exist(ins.ID()) {
 				continue
 			}
+			insts = append(insts, ins)
+		}
+	}
 
-			dataDir := "-"
-			insDirs := ins.UsedDirs()
-			deployDir := insDirs[0]
-			if len(insDirs) > 1 {
-				dataDir = insDirs[1]
-			}
+	// probe the health of instances in parallel
+	pdList := topo.BaseTopo().MasterList
+	var results []spec.ProbeResult
+	if mc := m.mockCluster(clusterName); mc != nil {
+		results = mc.probe(insts)
+	} else {
+		results = spec.ProbeInstances(insts, nil, spec.DefaultProbeConcurrency, pdList...)
+	}
+	lastErrors, err := m.loadInstanceErrors(clusterName)
+	if err != nil {
+		// the failures are only hints
+		log.Debugf("Failed to load the failures of instances: %s", err)
+	}
+	var resources map[string]*InstanceResources
+	if opt.Resources {
+		resources = gatherResources(ctx, insts, base.User, time.Duration(opt.ResourcesTimeout)*time.Second)
+	}
+	var healthy []string
+	statuses := make([]InstanceStatus, 0, len(insts))
+	for i, ins := range insts {
+		dataDir := "-"
+		insDirs := ins.UsedDirs()
+		deployDir := insDirs[0]
+		if len(insDirs) > 1 {
+			dataDir = insDirs[1]
+		}
 
-			status := ins.Status(pdList...)
-			// Query the service status
-			if status == "-" {
-				e, found := ctx.GetExecutor(ins.GetHost())
-				if found {
-					active, _ := operator.GetServiceStatus(e, ins.ServiceName())
-					if parts := strings.Split(strings.TrimSpace(active), " "); len(parts) > 2 {
-						if parts[1] == "active" {
-							status = "Up"
-						} else {
-							status = parts[1]
-						}
+		status := results[i].Status
+		// Query the service status
+		if status == "-" {
+			e, found := ctx.GetExecutor(ins.GetHost())
+			if found {
+				active, _ := operator.GetServiceStatus(e, ins.ServiceName())
+				if parts := strings.Split(strings.TrimSpace(active), " "); len(parts) > 2 {
+					if parts[1] == "active" {
+						status = "Up"
+					} else {
+						status = parts[1]
 					}
 				}
 			}
-			clusterTable = append(clusterTable, []string{
-				color.CyanString(ins.ID()),
-				ins.Role(),
-				ins.GetHost(),
-				utils.JoinInt(ins.UsedPorts(), "/"),
-				cliutil.OsArch(ins.OS(), ins.Arch()),
-				formatInstanceStatus(status),
-				dataDir,
-				deployDir,
-			})
-
 		}
-	}
-
-	// Sort by role,host,ports
-	sort.Slice(clusterTable[1:], func(i, j int) bool {
-		lhs, rhs := clusterTable[i+1], clusterTable[j+1]
-		// column: 1 => role, 2 => host, 3 => ports
-		for _, col := range []int{1, 2} {
-			if lhs[col] != rhs[col] {
-				return lhs[col] < rhs[col]
+		var lastError *InstanceError
+		if e, ok := lastErrors[ins.ID()]; ok {
+			if isHealthyStatus(status) {
+				healthy = append(healthy, ins.ID())
+			} else {
+				lastError = &e
 			}
 		}
-		return lhs[3] < rhs[3]
-	})
-
-	cliutil.PrintTable(clusterTable, true)
-
-	return nil
+		statuses = append(statuses, InstanceStatus{
+			ID:        ins.ID(),
+			Role:      ins.Role(),
+			Host:      ins.GetHost(),
+			Ports:     ins.UsedPorts(),
+			OS:        ins.OS(),
+			Arch:      ins.Arch(),
+			Status:    status,
+			Reason:    results[i].Reason,
+			DataDir:   dataDir,
+			DeployDir: deployDir,
+			LastError: lastError,
+			Resources: resources[ins.ID()],
+		})
+	}
+	m.clearInstanceErrors(clusterName, healthy)
+	return statuses, nil
 }
 
 // EditConfig let the user edit the config.
 func (m *Manager) EditConfig(clusterName string, skipConfirm bool) error {
-	metadata, err := m.meta(clusterName)
+	// the edited topology replaces the saved one, so it must be based on the
+	// freshest data
+	metadata, err := m.metaFresh(clusterName)
 	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
 		return perrs.AddStack(err)
 	}
+	if err := m.specManager.CheckWritable(); err != nil {
+		return err
+	}
 
 	topo := metadata.GetTopology()
 
@@ -540,10 +1235,15 @@ func (m *Manager) Rename(clusterName string, opt operator.Options, newName strin
 			New("Cluster name '%s' is duplicated", newName).
 			WithProperty(cliutil.SuggestionFromFormat("Please specify another cluster name"))
 	}
+	if err := m.specManager.CheckWritable(); err != nil {
+		return err
+	}
 
 	if err := os.Rename(m.specManager.Path(clusterName), m.specManager.Path(newName)); err != nil {
 		return perrs.AddStack(err)
 	}
+	m.specManager.InvalidateMetadata(clusterName)
+	m.specManager.InvalidateMetadata(newName)
 
 	log.Infof("Rename cluster `%s` -> `%s` successfully", clusterName, newName)
 
@@ -552,7 +1252,7 @@ func (m *Manager) Rename(clusterName string, opt operator.Options, newName strin
 }
 
 // Reload the cluster.
-func (m *Manager) Reload(clusterName string, opt operator.Options, skipRestart bool) error {
+func (m *Manager) Reload(clusterName string, opt operator.Options, skipRestart bool) (err error) {
 	sshTimeout := opt.SSHTimeout
 	nativeSSH := opt.NativeSSH
 
@@ -561,10 +1261,24 @@ func (m *Manager) Reload(clusterName string, opt operator.Options, skipRestart b
 		return perrs.AddStack(err)
 	}
 
+	op, err := m.beginOperation(clusterName, OperationReload, opt, map[string]interface{}{"SkipRestart": skipRestart})
+	if err != nil {
+		return err
+	}
+	defer func() { m.endOperation(op, err) }()
+
 	topo := metadata.GetTopology()
 	base := metadata.GetBaseMeta()
+	if err := checkScopedSudo(clusterName, base, "reload"); err != nil {
+		return err
+	}
 
 	var refreshConfigTasks []*task.StepDisplay
+	// the changes are recorded through pointers to the elements, so the slice
+	// must not grow after the tasks are built
+	instCount := 0
+	topo.IterInstance(func(spec.Instance) { instCount++ })
+	changes := make([]spec.ServiceFilesChange, 0, instCount)
 
 	hasImported := false
 	uniqueHosts := make(map[string]hostInfo) // host -> ssh-port, os, arch
@@ -589,16 +1303,18 @@ func (m *Manager) Reload(clusterName string, opt operator.Options, skipRestart b
 		if inst.IsImported() {
 			switch compName := inst.ComponentName(); compName {
 			case spec.ComponentGrafana, spec.ComponentPrometheus, spec.ComponentAlertManager:
-				version := m.bindVersion(compName, base.Version)
+				version := m.bindVersion(compName, base.ComponentVersion(compName))
 				tb.Download(compName, inst.OS(), inst.Arch(), version).
 					CopyComponent(compName, inst.OS(), inst.Arch(), version, "", inst.GetHost(), deployDir)
 			}
 			hasImported = true
 		}
 
-		// Refresh all configuration
-		t := tb.InitConfig(clusterName,
-			base.Version,
+		// Refresh all configuration, the systemd unit and the run script are
+		// only pushed if they are changed
+		changes = append(changes, spec.ServiceFilesChange{Instance: inst.ID()})
+		t := tb.RefreshConfig(clusterName,
+			base.ComponentVersion(inst.ComponentName()),
 			m.specManager,
 			inst, base.User,
 			opt.IgnoreConfigCheck,
@@ -607,14 +1323,16 @@ func (m *Manager) Reload(clusterName string, opt operator.Options, skipRestart b
 				Data:   dataDirs,
 				Log:    logDir,
 				Cache:  m.specManager.Path(clusterName, spec.TempConfigPath),
-			}).
-			BuildAsStep(fmt.Sprintf("  - Refresh config %s -> %s", inst.ComponentName(), inst.ID()))
+			},
+			&changes[len(changes)-1]).
+			BuildAsStepMessage(task.MsgRefreshConfig, task.MessageParams{"component": inst.ComponentName(), "instance": inst.ID()})
 		refreshConfigTasks = append(refreshConfigTasks, t)
 	})
 
 	monitorConfigTasks := refreshMonitoredConfigTask(
 		m.specManager,
 		clusterName,
+		base.Version,
 		uniqueHosts,
 		*topo.BaseTopo().GlobalOptions,
 		topo.GetMonitoredOptions(),
@@ -640,14 +1358,19 @@ func (m *Manager) Reload(clusterName string, opt operator.Options, skipRestart b
 	}
 
 	if !skipRestart {
-		tb = tb.Func("UpgradeCluster", func(ctx *task.Context) error {
-			return operator.Upgrade(ctx, topo, opt)
+		var upgrade *task.Func
+		upgrade = task.NewFunc("UpgradeCluster", func(ctx *task.Context) error {
+			return operator.Upgrade(ctx.PhaseGetter(upgrade), topo, opt)
 		})
+		tb = tb.Serial(upgrade)
 	}
 
+	var grafanaReports []*operator.GrafanaProvisionReport
+	m.provisionGrafana(tb, clusterName, topo, opt, &grafanaReports)
+
 	t := tb.Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	if err := t.Execute(op.newTaskContext()); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -655,34 +1378,119 @@ func (m *Manager) Reload(clusterName string, opt operator.Options, skipRestart b
 		return perrs.Trace(err)
 	}
 
+	printTemplateOverrides(clusterName)
+	printServiceFilesChanges(changes)
+	op.setResult(&ReloadResult{ServiceFiles: changes, Grafana: grafanaReports})
+	if len(grafanaReports) > 0 {
+		if failed := printGrafanaReports(grafanaReports); failed > 0 {
+			log.Warnf("Failed to provision %d dashboard(s) of grafana", failed)
+		}
+	}
 	log.Infof("Reloaded cluster `%s` successfully", clusterName)
 
 	return nil
 }
 
-// Upgrade the cluster.
-func (m *Manager) Upgrade(clusterName string, clusterVersion string, opt operator.Options) error {
+// Upgrade the cluster, see StartCluster for the usage of fn.
+func (m *Manager) Upgrade(clusterName string, clusterVersion string, opt operator.Options, fn ...func(b *task.Builder, metadata spec.Metadata)) (err error) {
+	if err := validateErrorScope(opt.ErrorScope); err != nil {
+		return err
+	}
+
 	metadata, err := m.meta(clusterName)
 	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
 		return perrs.AddStack(err)
 	}
+	clusterVersion, err = normalizeClusterVersion(clusterVersion, opt.IgnoreVersionCheck)
+	if err != nil {
+		return err
+	}
+
+	// only the plan is printed in dry-run mode, nothing is changed
+	dryRun := opt.PlanFormat != ""
+	if !dryRun {
+		if err := m.specManager.CheckWritable(); err != nil {
+			return err
+		}
+		if err := CheckControlMachine(opt.StrictSelfCheck); err != nil {
+			return err
+		}
+	}
+	var op *OperationInfo
+	if !dryRun {
+		if op, err = m.beginOperation(clusterName, OperationUpgrade, opt, map[string]interface{}{"Version": clusterVersion}); err != nil {
+			return err
+		}
+		defer func() { m.endOperation(op, err) }()
+	}
 
 	topo := metadata.GetTopology()
 	base := metadata.GetBaseMeta()
+	if err := checkScopedSudo(clusterName, base, "upgrade"); err != nil {
+		return err
+	}
 
 	var (
 		downloadCompTasks []task.Task // tasks which are used to download components
+		seedCompTasks     []task.Task // tasks which are used to push components to seed hosts
 		copyCompTasks     []task.Task // tasks which are used to copy components to remote host
 
 		uniqueComps = map[string]struct{}{}
+		seededComps = map[string]struct{}{}
+
+		// component:version -> release info of the target version
+		releaseRows = map[string][]string{}
 	)
 
-	if err := versionCompare(base.Version, clusterVersion); err != nil {
+	scope, err := newUpgradeScope(topo, opt.Components)
+	if err != nil {
+		return err
+	}
+	if err := scope.checkVersions(base, clusterVersion); err != nil {
 		return err
 	}
 
+	// the components left behind may not work with the upgraded ones
+	mixedWarnings := mixedVersionWarnings(scope.versions(base, clusterVersion))
+
+	// all the packages must be staged in the package directory, so that the
+	// upgrade doesn't stop halfway for a missing one
+	if opt.PackageDir != "" {
+		if err := CheckPackageDir(opt.PackageDir, clusterVersion, scope, m.bindVersion); err != nil {
+			return err
+		}
+	}
+
+	// the packages are pushed through the host-level cache, the seed hosts
+	// get them first and other hosts fetch them from the seed hosts
+	var cache *task.PackageCache
+	if opt.CachePackages || len(opt.SeedHosts) > 0 {
+		hosts := SSHHosts(topo)
+		seeds := make(map[string]int)
+		for _, host := range opt.SeedHosts {
+			port, found := hosts[host]
+			if !found {
+				return perrs.Errorf("seed host %s is not a host of the cluster", host)
+			}
+			seeds[host] = port
+		}
+		cache = task.NewPackageCache(clusterutil.Abs(base.User, task.PackageCacheDir), base.User,
+			m.specManager.Path(clusterName, "ssh", "id_rsa"), seeds)
+	}
+
+	// the release info is the same on all the platforms, the repository is
+	// likely unreachable with a package directory
+	var releaseRepo clusterutil.Repository
+	if opt.PackageDir == "" {
+		globalOptions := topo.BaseTopo().GlobalOptions
+		releaseRepo, _ = clusterutil.NewRepository(globalOptions.OS, globalOptions.Arch)
+	}
+
 	hasImported := false
 	for _, comp := range topo.ComponentsByUpdateOrder() {
+		if !scope.includes(comp.Name()) {
+			continue
+		}
 		for _, inst := range comp.Instances() {
 			version := m.bindVersion(inst.ComponentName(), clusterVersion)
 			if version == "" {
@@ -698,10 +1506,13 @@ func (m *Manager) Upgrade(clusterName string, clusterVersion string, opt operato
 			if _, found := uniqueComps[key]; !found {
 				uniqueComps[key] = struct{}{}
 				t := task.NewBuilder().
-					Download(inst.ComponentName(), inst.OS(), inst.Arch(), version).
+					DownloadFrom(opt.PackageDir, inst.ComponentName(), inst.OS(), inst.Arch(), version).
 					Build()
 				downloadCompTasks = append(downloadCompTasks, t)
 			}
+			if _, found := releaseRows[compInfo.component+":"+version]; !found {
+				releaseRows[compInfo.component+":"+version] = releaseInfoRow(releaseRepo, compInfo)
+			}
 
 			deployDir := clusterutil.Abs(base.User, inst.DeployDir())
 			// data dir would be empty for components which don't need it
@@ -710,7 +1521,7 @@ func (m *Manager) Upgrade(clusterName string, clusterVersion string, opt operato
 			logDir := clusterutil.Abs(base.User, inst.LogDir())
 
 			// Deploy component
-			tb := task.NewBuilder()
+			tb := task.NewBuilder().Transfer(task.NewTransferOptions(opt))
 			if inst.IsImported() {
 				switch inst.ComponentName() {
 				case spec.ComponentPrometheus, spec.ComponentGrafana, spec.ComponentAlertManager:
@@ -728,12 +1539,32 @@ func (m *Manager) Upgrade(clusterName string, clusterVersion string, opt operato
 			}
 
 			// backup files of the old version
-			tb = tb.BackupComponent(inst.ComponentName(), base.Version, inst.GetHost(), deployDir)
+			tb = tb.BackupComponent(inst.ComponentName(), base.ComponentVersion(inst.ComponentName()), inst.GetHost(), deployDir)
 
 			// copy dependency component if needed
-			switch inst.ComponentName() {
-			case spec.ComponentTiSpark:
+			switch {
+			case inst.ComponentName() == spec.ComponentTiSpark:
 				tb = tb.DeploySpark(inst, version, "" /* default srcPath */, deployDir, m.bindVersion)
+			case cache != nil:
+				if seed, _ := cache.SeedOf(inst.GetHost()); seed != "" {
+					if _, found := seededComps[seed+"/"+key]; !found {
+						seededComps[seed+"/"+key] = struct{}{}
+						srcPath := task.PackagePath(inst.ComponentName(), version, inst.OS(), inst.Arch())
+						seedCompTasks = append(seedCompTasks, task.NewBuilder().
+							CachePackage(srcPath, seed, cache).
+							Build())
+					}
+				}
+				tb = tb.CopyComponentCached(
+					inst.ComponentName(),
+					inst.OS(),
+					inst.Arch(),
+					version,
+					"", // use default srcPath
+					inst.GetHost(),
+					deployDir,
+					cache,
+				)
 			default:
 				tb = tb.CopyComponent(
 					inst.ComponentName(),
@@ -764,26 +1595,97 @@ func (m *Manager) Upgrade(clusterName string, clusterVersion string, opt operato
 		}
 	}
 
+	// show the release info of the target versions before upgrading
+	rows := [][]string{{"Component", "Version", "Released", "Release Notes"}}
+	for _, row := range releaseRows {
+		rows = append(rows, row)
+	}
+	sort.Slice(rows[1:], func(i, j int) bool {
+		return rows[i+1][0] < rows[j+1][0]
+	})
+	if !dryRun {
+		fmt.Printf("Target versions of cluster `%s`:\n", clusterName)
+		cliutil.PrintTable(rows, true)
+		fmt.Println(m.estimateHint(clusterName, OperationUpgrade))
+	}
+	for _, w := range mixedWarnings {
+		log.Warnf("After upgrading, %s, they may be incompatible", w)
+	}
+	if !opt.SkipConfirm && !dryRun && scope.partial() {
+		comps := scope.comps.Slice()
+		sort.Strings(comps)
+		if err := cliutil.PromptForConfirmOrAbortError(
+			"This operation will upgrade %s of %s cluster %s to %s, other components are left as is.\nDo you want to continue? [y/N]:",
+			color.HiYellowString(strings.Join(comps, ",")),
+			m.sysName,
+			color.HiYellowString(clusterName),
+			color.HiYellowString(clusterVersion)); err != nil {
+			return err
+		}
+	} else if !opt.SkipConfirm && !dryRun {
+		if err := cliutil.PromptForConfirmOrAbortError(
+			"This operation will upgrade %s %s cluster %s to %s.\nDo you want to continue? [y/N]:",
+			m.sysName,
+			color.HiYellowString(base.Version),
+			color.HiYellowString(clusterName),
+			color.HiYellowString(clusterVersion)); err != nil {
+			return err
+		}
+	}
+
 	// handle dir scheme changes
-	if hasImported {
+	if hasImported && !dryRun {
 		if err := spec.HandleImportPathMigration(clusterName); err != nil {
 			return err
 		}
 	}
 
-	t := task.NewBuilder().
+	var upgrade *task.Func
+	upgrade = task.NewFunc("UpgradeCluster", func(ctx *task.Context) error {
+		return operator.Upgrade(ctx.PhaseGetter(upgrade), topo, opt)
+	})
+
+	// the instances are only upgraded after the packages are copied to all
+	// the hosts, the failures of copying are confined to the error scope
+	copyPackages := task.NewBuilder().
+		Mode(hostPhaseMode(opt.EffectiveErrorScope())).
+		Parallel(downloadCompTasks...).
+		Parallel(seedCompTasks...).
+		Parallel(copyCompTasks...).
+		Build()
+
+	b := task.NewBuilder().
 		SSHKeySet(
 			m.specManager.Path(clusterName, "ssh", "id_rsa"),
 			m.specManager.Path(clusterName, "ssh", "id_rsa.pub")).
 		ClusterSSH(topo, base.User, opt.SSHTimeout, opt.NativeSSH).
-		Parallel(downloadCompTasks...).
-		Parallel(copyCompTasks...).
-		Func("UpgradeCluster", func(ctx *task.Context) error {
-			return operator.Upgrade(ctx, topo, opt)
-		}).
-		Build()
+		Serial(copyPackages).
+		Serial(upgrade)
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	for _, f := range fn {
+		f(b, metadata)
+	}
+
+	t := b.Build()
+
+	if dryRun {
+		return m.writePlan(clusterName, OperationUpgrade, t, opt.PlanFormat)
+	}
+
+	// the sizes of packages are looked up in the repository
+	if opt.PackageDir == "" {
+		if err := CheckDownloadSpace(clusterVersion, scope, m.bindVersion); err != nil {
+			return err
+		}
+	}
+
+	unsilence, err := m.silenceAlerts(op, topo, opt)
+	if err != nil {
+		return err
+	}
+	defer unsilence()
+
+	if err := t.Execute(op.newTaskContext()); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -791,7 +1693,11 @@ func (m *Manager) Upgrade(clusterName string, clusterVersion string, opt operato
 		return perrs.Trace(err)
 	}
 
-	metadata.SetVersion(clusterVersion)
+	newVersion, compVersions := scope.componentVersions(base, clusterVersion)
+	metadata.SetVersion(newVersion)
+	if vm, ok := metadata.(spec.ComponentVersionedMetadata); ok {
+		vm.SetComponentVersions(compVersions)
+	}
 
 	if err := m.specManager.SaveMeta(clusterName, metadata); err != nil {
 		return perrs.Trace(err)
@@ -801,20 +1707,41 @@ func (m *Manager) Upgrade(clusterName string, clusterVersion string, opt operato
 		return perrs.Trace(err)
 	}
 
+	printTemplateOverrides(clusterName)
 	log.Infof("Upgraded cluster `%s` successfully", clusterName)
 
 	return nil
 }
 
+// printTemplateOverrides notes the override templates of the cluster used to
+// render the configs, as they may differ from the embedded ones shipped
+func printTemplateOverrides(clusterName string) {
+	for _, o := range spec.UsedTemplateOverrides(clusterName) {
+		log.Infof("Rendered with the override template %s", o)
+	}
+}
+
 // Patch the cluster.
-func (m *Manager) Patch(clusterName string, packagePath string, opt operator.Options, overwrite bool) error {
+func (m *Manager) Patch(clusterName string, packagePath string, opt operator.Options, overwrite bool) (err error) {
 	metadata, err := m.meta(clusterName)
 	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
 		return perrs.AddStack(err)
 	}
+	if err := m.specManager.CheckWritable(); err != nil {
+		return err
+	}
+
+	op, err := m.beginOperation(clusterName, OperationPatch, opt, map[string]interface{}{"Package": packagePath, "Overwrite": overwrite})
+	if err != nil {
+		return err
+	}
+	defer func() { m.endOperation(op, err) }()
 
 	topo := metadata.GetTopology()
 	base := metadata.GetBaseMeta()
+	if err := checkScopedSudo(clusterName, base, "patch"); err != nil {
+		return err
+	}
 
 	if exist := utils.IsExist(packagePath); !exist {
 		return perrs.New("specified package not exists")
@@ -831,24 +1758,27 @@ func (m *Manager) Patch(clusterName string, packagePath string, opt operator.Opt
 	var replacePackageTasks []task.Task
 	for _, inst := range insts {
 		deployDir := clusterutil.Abs(base.User, inst.DeployDir())
-		tb := task.NewBuilder()
-		tb.BackupComponent(inst.ComponentName(), base.Version, inst.GetHost(), deployDir).
+		tb := task.NewBuilder().Transfer(task.NewTransferOptions(opt))
+		tb.BackupComponent(inst.ComponentName(), base.ComponentVersion(inst.ComponentName()), inst.GetHost(), deployDir).
 			InstallPackage(packagePath, inst.GetHost(), deployDir)
 		replacePackageTasks = append(replacePackageTasks, tb.Build())
 	}
 
+	var upgrade *task.Func
+	upgrade = task.NewFunc("UpgradeCluster", func(ctx *task.Context) error {
+		return operator.Upgrade(ctx.PhaseGetter(upgrade), topo, opt)
+	})
+
 	t := task.NewBuilder().
 		SSHKeySet(
 			m.specManager.Path(clusterName, "ssh", "id_rsa"),
 			m.specManager.Path(clusterName, "ssh", "id_rsa.pub")).
 		ClusterSSH(topo, base.User, opt.SSHTimeout, opt.NativeSSH).
 		Parallel(replacePackageTasks...).
-		Func("UpgradeCluster", func(ctx *task.Context) error {
-			return operator.Upgrade(ctx, topo, opt)
-		}).
+		Serial(upgrade).
 		Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	if err := t.Execute(op.newTaskContext()); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -871,16 +1801,53 @@ type ScaleOutOptions struct {
 	SkipCreateUser bool   // don't create user
 	IdentityFile   string // path to the private key file
 	UsePassword    bool   // use password instead of identity file for ssh connection
+	// ignore the unknown fields of the topology file instead of failing
+	AllowUnknownFields bool
+	// allow the new instances on hosts of other clusters, ports and dirs must still differ
+	AllowColocation bool
+	// regenerate the Prometheus configs from scratch, dropping the scrape jobs
+	// and rule files added by the users
+	ForceRegenerate bool
+	// start over instead of skipping the phases finished on the hosts by the
+	// last failed scale-out with the same topology
+	NoResume bool
+	// remove the units left on the hosts pointing to other deploy directories
+	// instead of failing, see checkStaleUnits
+	Force bool
+	// the timeouts in seconds to start the new instances and to restart the
+	// existing ones, 0 uses the wait timeout, see operator.Options
+	FirstStartTimeout int64
+	RestartTimeout    int64
+	// how far a failure reaches in preparing the new hosts, see hostPhaseMode
+	ErrorScope operator.ErrorScope
+
+	Transfer task.TransferOptions // how the component packages are pushed to hosts
 }
 
 // DeployOptions contains the options for scale out.
 // TODO: merge ScaleOutOptions, should check config too when scale out.
 type DeployOptions struct {
-	User              string // username to login to the SSH server
-	SkipCreateUser    bool   // don't create the user
-	IdentityFile      string // path to the private key file
-	UsePassword       bool   // use password instead of identity file for ssh connection
-	IgnoreConfigCheck bool   // ignore config check result
+	User               string // username to login to the SSH server
+	SkipCreateUser     bool   // don't create the user
+	IdentityFile       string // path to the private key file
+	UsePassword        bool   // use password instead of identity file for ssh connection
+	IgnoreConfigCheck  bool   // ignore config check result
+	IgnoreVersionCheck bool   // accept the version as it is if it's not a valid SemVer string
+	BootstrapUser      bool   // create the deploy user with sudo privileges scoped to the cluster units, see checkScopedSudo
+	PlanFormat         string // only print the task plan in the format, nothing is executed
+	// ignore the unknown fields of the topology file instead of failing
+	AllowUnknownFields bool
+	// allow the instances on hosts of other clusters, ports and dirs must still differ
+	AllowColocation bool
+	// fail if the control machine fails the self checks instead of warning
+	StrictSelfCheck bool
+	// remove the units left on the hosts pointing to other deploy directories
+	// instead of failing, see checkStaleUnits
+	Force bool
+	// how far a failure reaches in preparing the hosts, see hostPhaseMode
+	ErrorScope operator.ErrorScope
+
+	Transfer task.TransferOptions // how the component packages are pushed to hosts
 }
 
 // DeployerInstance is a instance can deploy to a target deploy directory.
@@ -899,10 +1866,17 @@ func (m *Manager) Deploy(
 	optTimeout int64,
 	sshTimeout int64,
 	nativeSSH bool,
-) error {
+) (err error) {
 	if err := clusterutil.ValidateClusterNameOrError(clusterName); err != nil {
 		return err
 	}
+	if err := validateErrorScope(opt.ErrorScope); err != nil {
+		return err
+	}
+	clusterVersion, err = normalizeClusterVersion(clusterVersion, opt.IgnoreVersionCheck)
+	if err != nil {
+		return err
+	}
 
 	exist, err := m.specManager.Exist(clusterName)
 	if err != nil {
@@ -922,7 +1896,7 @@ func (m *Manager) Deploy(
 	// The no tispark master error is ignored, as if the tispark master is removed from the topology
 	// file for some reason (manual edit, for example), it is still possible to scale-out it to make
 	// the whole topology back to normal state.
-	if err := clusterutil.ParseTopologyYaml(topoFile, topo); err != nil &&
+	if err := clusterutil.ParseTopologyYaml(topoFile, topo, clusterutil.AllowUnknownFields(opt.AllowUnknownFields)); err != nil &&
 		!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
 		return err
 	}
@@ -939,22 +1913,80 @@ func (m *Manager) Deploy(
 	if err := spec.CheckClusterDirConflict(clusterList, clusterName, topo); err != nil {
 		return err
 	}
+	if !opt.AllowColocation {
+		if err := spec.CheckClusterHostConflict(clusterList, clusterName, topo); err != nil {
+			return err
+		}
+	}
+	warnUnmonitoredHosts(topo.GetMonitoredOptions(), topo)
 
-	if !skipConfirm {
-		if err := m.confirmTopology(clusterName, clusterVersion, topo, set.NewStringSet()); err != nil {
+	// only the plan is printed in dry-run mode, nothing is changed on the hosts
+	// or the local machine, so the hosts are not connected to either
+	dryRun := opt.PlanFormat != ""
+	if !dryRun {
+		if err := m.specManager.CheckWritable(); err != nil {
+			return err
+		}
+		if err := CheckControlMachine(opt.StrictSelfCheck); err != nil {
+			return err
+		}
+	}
+
+	if !skipConfirm && !dryRun {
+		if err := m.confirmTopology(clusterName, OperationDeploy, clusterVersion, topo, set.NewStringSet()); err != nil {
 			return err
 		}
 	}
 
-	sshConnProps, err := cliutil.ReadIdentityFileOrPassword(opt.IdentityFile, opt.UsePassword)
-	if err != nil {
-		return err
+	if opt.BootstrapUser {
+		if opt.User != "root" {
+			return errDeployBootstrapUser.
+				New("Bootstrapping the deploy user requires SSH login as root, but '%s' is specified", opt.User).
+				WithProperty(cliutil.SuggestionFromString("Please specify '--user root' and try again."))
+		}
+		if opt.SkipCreateUser || base.GlobalOptions.User == opt.User {
+			return errDeployBootstrapUser.
+				New("Bootstrapping the deploy user conflicts with skipping user creation or deploying as '%s'", opt.User)
+		}
 	}
 
-	if err := os.MkdirAll(m.specManager.Path(clusterName), 0755); err != nil {
-		return errorx.InitializationFailed.
-			Wrap(err, "Failed to create cluster metadata directory '%s'", m.specManager.Path(clusterName)).
-			WithProperty(cliutil.SuggestionFromString("Please check file system permissions and try again."))
+	sshConnProps := &cliutil.SSHConnectionProps{}
+	var op *OperationInfo
+	if !dryRun {
+		sshConnProps, err = cliutil.ReadIdentityFileOrPassword(opt.IdentityFile, opt.UsePassword)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(m.specManager.Path(clusterName), 0755); err != nil {
+			return errorx.InitializationFailed.
+				Wrap(err, "Failed to create cluster metadata directory '%s'", m.specManager.Path(clusterName)).
+				WithProperty(cliutil.SuggestionFromString("Please check file system permissions and try again."))
+		}
+
+		if op, err = m.beginOperation(clusterName, OperationDeploy, opt, map[string]interface{}{
+			"Version":    clusterVersion,
+			"Topology":   topoFile,
+			"OptTimeout": optTimeout,
+			"SSHTimeout": sshTimeout,
+			"NativeSSH":  nativeSSH,
+		}); err != nil {
+			return err
+		}
+		defer func() { m.endOperation(op, err) }()
+		// there is no metadata yet to take the remote tmp dirs from
+		op.globalOpts = topo.BaseTopo().GlobalOptions
+
+		if _, err := PreflightSSHAuth(SSHHosts(topo), opt.User, sshConnProps, sshTimeout, nativeSSH, false); err != nil {
+			return err
+		}
+		if err := m.checkHostPlatforms(topo, clusterVersion, opt.User, sshConnProps, sshTimeout, nativeSSH); err != nil {
+			return err
+		}
+		if err := m.checkStaleUnits(clusterName, topo, topo.GetMonitoredOptions(), nil, base.GlobalOptions.User,
+			opt.User, sshConnProps, sshTimeout, nativeSSH, opt.Force); err != nil {
+			return err
+		}
 	}
 
 	var (
@@ -966,6 +1998,52 @@ func (m *Manager) Deploy(
 	// Initialize environment
 	uniqueHosts := make(map[string]hostInfo) // host -> ssh-port, os, arch
 	globalOptions := base.GlobalOptions
+
+	// the units and directories of each host, only used when bootstrapping the deploy user
+	hostUnits := make(map[string][]string)
+	hostDirs := make(map[string][]string)
+	bootstrapReports := make([]*task.BootstrapReport, 0)
+	if opt.BootstrapUser {
+		monitoredOptions := topo.GetMonitoredOptions()
+		topo.IterInstance(func(inst spec.Instance) {
+			host := inst.GetHost()
+			if _, found := hostUnits[host]; !found && monitoredOptions != nil && monitoredOptions.DeployAgents(host) {
+				hostUnits[host] = append(hostUnits[host],
+					fmt.Sprintf("%s-%d.service", spec.ComponentNodeExporter, monitoredOptions.NodeExporterPort),
+					fmt.Sprintf("%s-%d.service", spec.ComponentBlackboxExporter, monitoredOptions.BlackboxExporterPort),
+				)
+				deployDir := clusterutil.Abs(globalOptions.User, monitoredOptions.DeployDir)
+				hostDirs[host] = append(hostDirs[host], deployDir, clusterutil.Abs(globalOptions.User, monitoredOptions.LogDir))
+				if dataDir := monitoredOptions.DataDir; dataDir != "" {
+					if !strings.HasPrefix(dataDir, "/") {
+						dataDir = filepath.Join(deployDir, dataDir)
+					}
+					hostDirs[host] = append(hostDirs[host], dataDir)
+				}
+			}
+			hostUnits[host] = append(hostUnits[host], inst.ServiceName())
+		})
+	}
+
+	// connect opens the session used to copy files to the host, the bootstrapped
+	// deploy user can not use sudo for anything but systemctl, so the files are
+	// copied as root and their owner is fixed after that
+	connect := func(b *task.Builder, host string, port int) *task.Builder {
+		if opt.BootstrapUser {
+			return b.RootSSH(
+				host,
+				port,
+				opt.User,
+				sshConnProps.Password,
+				sshConnProps.IdentityFile,
+				sshConnProps.IdentityFilePassphrase,
+				sshTimeout,
+				nativeSSH,
+			)
+		}
+		return b.UserSSH(host, port, globalOptions.User, sshTimeout, nativeSSH)
+	}
+
 	var iterErr error // error when itering over instances
 	iterErr = nil
 	topo.IterInstance(func(inst spec.Instance) {
@@ -1005,11 +2083,18 @@ func (m *Manager) Deploy(
 					sshConnProps.IdentityFilePassphrase,
 					sshTimeout,
 					nativeSSH,
-				).
-				EnvInit(inst.GetHost(), globalOptions.User, globalOptions.Group, opt.SkipCreateUser || globalOptions.User == opt.User).
+				)
+			if opt.BootstrapUser {
+				report := &task.BootstrapReport{}
+				bootstrapReports = append(bootstrapReports, report)
+				t = t.BootstrapUser(inst.GetHost(), clusterName, globalOptions.User, globalOptions.Group, hostUnits[inst.GetHost()], report)
+			} else {
+				t = t.EnvInit(inst.GetHost(), globalOptions.User, globalOptions.Group, opt.SkipCreateUser || globalOptions.User == opt.User)
+			}
+			envInitTasks = append(envInitTasks, t.
+				ApplyOSSettings(inst.GetHost(), clusterName, spec.GetOSSettings(topo)).
 				Mkdir(globalOptions.User, inst.GetHost(), dirs...).
-				BuildAsStep(fmt.Sprintf("  - Prepare %s:%d", inst.GetHost(), inst.GetSSHPort()))
-			envInitTasks = append(envInitTasks, t)
+				BuildAsStepMessage(task.MsgPrepareHost, task.MessageParams{"host": inst.GetHost(), "port": strconv.Itoa(inst.GetSSHPort())}))
 		}
 	})
 
@@ -1030,14 +2115,18 @@ func (m *Manager) Deploy(
 		logDir := clusterutil.Abs(globalOptions.User, inst.LogDir())
 		// Deploy component
 		// prepare deployment server
-		t := task.NewBuilder().
-			UserSSH(inst.GetHost(), inst.GetSSHPort(), globalOptions.User, sshTimeout, nativeSSH).
+		if opt.BootstrapUser {
+			hostDirs[inst.GetHost()] = append(hostDirs[inst.GetHost()], deployDir, logDir)
+			hostDirs[inst.GetHost()] = append(hostDirs[inst.GetHost()], dataDirs...)
+		}
+		t := connect(task.NewBuilder().Transfer(opt.Transfer), inst.GetHost(), inst.GetSSHPort()).
 			Mkdir(globalOptions.User, inst.GetHost(),
 				deployDir, logDir,
 				filepath.Join(deployDir, "bin"),
 				filepath.Join(deployDir, "conf"),
 				filepath.Join(deployDir, "scripts")).
-			Mkdir(globalOptions.User, inst.GetHost(), dataDirs...)
+			Mkdir(globalOptions.User, inst.GetHost(), dataDirs...).
+			Shell(inst.GetHost(), operator.FirstStartMarkCommand(deployDir, dataDirs), false)
 
 		if deployerInstance, ok := inst.(DeployerInstance); ok {
 			deployerInstance.Deploy(t, deployDir, version, clusterName, clusterVersion)
@@ -1076,7 +2165,7 @@ func (m *Manager) Deploy(
 		)
 
 		deployCompTasks = append(deployCompTasks,
-			t.BuildAsStep(fmt.Sprintf("  - Copy %s -> %s", inst.ComponentName(), inst.GetHost())),
+			t.BuildAsStepMessage(task.MsgCopy, task.MessageParams{"component": inst.ComponentName(), "host": inst.GetHost()}),
 		)
 	})
 
@@ -1089,26 +2178,50 @@ func (m *Manager) Deploy(
 		globalOptions,
 		topo.GetMonitoredOptions(),
 		clusterVersion,
-		sshTimeout,
-		nativeSSH,
+		connect,
 	)
 	downloadCompTasks = append(downloadCompTasks, dlTasks...)
 	deployCompTasks = append(deployCompTasks, dpTasks...)
 
+	// the tasks of each host depend on each other, e.g., files are copied to
+	// the dirs created, so they are rolled back in the reverse order
 	builder := task.NewBuilder().
+		Mode(hostPhaseMode(opt.ErrorScope)).
+		OrderedRollback().
 		Step("+ Generate SSH keys",
 			task.NewBuilder().SSHKeyGen(m.specManager.Path(clusterName, "ssh", "id_rsa")).Build()).
 		ParallelStep("+ Download TiDB components", downloadCompTasks...).
 		ParallelStep("+ Initialize target host environments", envInitTasks...).
 		ParallelStep("+ Copy files", deployCompTasks...)
 
+	if opt.BootstrapUser {
+		var chownTasks []*task.StepDisplay
+		for host, info := range uniqueHosts {
+			dirs := set.NewStringSet(hostDirs[host]...).Slice()
+			sort.Strings(dirs)
+			cmd := fmt.Sprintf("chown -R %[1]s:$(id -g -n %[1]s) %[2]s", globalOptions.User, strings.Join(dirs, " "))
+			chownTasks = append(chownTasks, connect(task.NewBuilder(), host, info.ssh).
+				Shell(host, cmd, true).
+				BuildAsStepMessage(task.MsgChown, task.MessageParams{"host": host}))
+		}
+		builder = builder.ParallelStep("+ Set owner of deployed files", chownTasks...)
+	}
+
 	if afterDeploy != nil {
 		afterDeploy(builder, topo)
 	}
 
 	t := builder.Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	if dryRun {
+		return m.writePlan(clusterName, OperationDeploy, t, opt.PlanFormat)
+	}
+
+	if err := CheckDownloadSpace(clusterVersion, topo, m.bindVersion); err != nil {
+		return err
+	}
+
+	if err := t.Execute(op.newTaskContext()); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -1116,8 +2229,15 @@ func (m *Manager) Deploy(
 		return perrs.AddStack(err)
 	}
 
+	if opt.BootstrapUser {
+		printBootstrapReports(bootstrapReports)
+	}
+
 	metadata.SetUser(globalOptions.User)
 	metadata.SetVersion(clusterVersion)
+	if sm, ok := metadata.(spec.ScopedSudoMetadata); ok && opt.BootstrapUser {
+		sm.SetScopedSudo(true)
+	}
 	err = m.specManager.SaveMeta(clusterName, metadata)
 
 	if err != nil {
@@ -1136,10 +2256,34 @@ func (m *Manager) ScaleIn(
 	sshTimeout int64,
 	nativeSSH bool,
 	force bool,
+	overrideProtection bool,
 	nodes []string,
+	errorScope operator.ErrorScope,
 	scale func(builer *task.Builder, metadata spec.Metadata),
-) error {
+) (err error) {
+	if err := validateErrorScope(errorScope); err != nil {
+		return err
+	}
+
+	metadata, err := m.meta(clusterName)
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+		// ignore conflict check error, node may be deployed by former version
+		// that lack of some certain conflict checks
+		return perrs.AddStack(err)
+	}
+	if err := m.specManager.CheckWritable(); err != nil {
+		return err
+	}
+
+	if err := checkProtection(clusterName, metadata.GetBaseMeta(), overrideProtection, "scale in"); err != nil {
+		return err
+	}
+	if err := checkScopedSudo(clusterName, metadata.GetBaseMeta(), "scale in"); err != nil {
+		return err
+	}
+
 	if !skipConfirm {
+		fmt.Println(m.estimateHint(clusterName, OperationScaleIn))
 		if err := cliutil.PromptForConfirmOrAbortError(
 			"This operation will delete the %s nodes in `%s` and all their data.\nDo you want to continue? [y/N]:",
 			strings.Join(nodes, ","),
@@ -1158,12 +2302,17 @@ func (m *Manager) ScaleIn(
 		log.Infof("Scale-in nodes...")
 	}
 
-	metadata, err := m.meta(clusterName)
-	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
-		// ignore conflict check error, node may be deployed by former version
-		// that lack of some certain conflict checks
-		return perrs.AddStack(err)
+	op, err := m.beginOperation(clusterName, OperationScaleIn, map[string]interface{}{
+		"Nodes":              nodes,
+		"Force":              force,
+		"OverrideProtection": overrideProtection,
+		"SSHTimeout":         sshTimeout,
+		"NativeSSH":          nativeSSH,
+	})
+	if err != nil {
+		return err
 	}
+	defer func() { m.endOperation(op, err) }()
 
 	topo := metadata.GetTopology()
 	base := metadata.GetBaseMeta()
@@ -1188,7 +2337,7 @@ func (m *Manager) ScaleIn(
 			if instance.IsImported() {
 				switch compName := instance.ComponentName(); compName {
 				case spec.ComponentGrafana, spec.ComponentPrometheus, spec.ComponentAlertManager:
-					version := m.bindVersion(compName, base.Version)
+					version := m.bindVersion(compName, base.ComponentVersion(compName))
 					tb.Download(compName, instance.OS(), instance.Arch(), version).
 						CopyComponent(
 							compName,
@@ -1204,7 +2353,7 @@ func (m *Manager) ScaleIn(
 			}
 
 			t := tb.InitConfig(clusterName,
-				base.Version,
+				base.ComponentVersion(instance.ComponentName()),
 				m.specManager,
 				instance,
 				base.User,
@@ -1236,9 +2385,15 @@ func (m *Manager) ScaleIn(
 	// TODO: support command scale in operation.
 	scale(b, metadata)
 
-	t := b.Parallel(regenConfigTasks...).Build()
+	// the configs are regenerated after the nodes are removed, the failures
+	// of regenerating are confined to the error scope
+	regenConfigs := task.NewBuilder().
+		Mode(hostPhaseMode(errorScope)).
+		Parallel(regenConfigTasks...).
+		Build()
+	t := b.Serial(regenConfigs).Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	if err := t.Execute(op.newTaskContext()); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -1262,14 +2417,35 @@ func (m *Manager) ScaleOut(
 	optTimeout int64,
 	sshTimeout int64,
 	nativeSSH bool,
-) error {
-	metadata, err := m.meta(clusterName)
+) (err error) {
+	if err := validateErrorScope(opt.ErrorScope); err != nil {
+		return err
+	}
+
+	metadata, err := m.metaFresh(clusterName)
 	if err != nil { // not allowing validation errors
 		return perrs.AddStack(err)
 	}
+	if err := m.specManager.CheckWritable(); err != nil {
+		return err
+	}
+
+	op, err := m.beginOperation(clusterName, OperationScaleOut, opt, map[string]interface{}{
+		"Topology":   topoFile,
+		"OptTimeout": optTimeout,
+		"SSHTimeout": sshTimeout,
+		"NativeSSH":  nativeSSH,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { m.endOperation(op, err) }()
 
 	topo := metadata.GetTopology()
 	base := metadata.GetBaseMeta()
+	if err := checkScopedSudo(clusterName, base, "scale out"); err != nil {
+		return err
+	}
 
 	// not allowing validation errors
 	if err := topo.Validate(); err != nil {
@@ -1283,7 +2459,7 @@ func (m *Manager) ScaleOut(
 	// The no tispark master error is ignored, as if the tispark master is removed from the topology
 	// file for some reason (manual edit, for example), it is still possible to scale-out it to make
 	// the whole topology back to normal state.
-	if err := clusterutil.ParseTopologyYaml(topoFile, newPart); err != nil &&
+	if err := clusterutil.ParseTopologyYaml(topoFile, newPart, clusterutil.AllowUnknownFields(opt.AllowUnknownFields)); err != nil &&
 		!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
 		return err
 	}
@@ -1308,6 +2484,13 @@ func (m *Manager) ScaleOut(
 	if err := spec.CheckClusterDirConflict(clusterList, clusterName, mergedTopo); err != nil {
 		return err
 	}
+	// only the new part is checked, the existing instances may be co-located on purpose
+	if !opt.AllowColocation {
+		if err := spec.CheckClusterHostConflict(clusterList, clusterName, newPart); err != nil {
+			return err
+		}
+	}
+	warnUnmonitoredHosts(mergedTopo.GetMonitoredOptions(), newPart)
 
 	patchedComponents := set.NewStringSet()
 	newPart.IterInstance(func(instance spec.Instance) {
@@ -1318,7 +2501,7 @@ func (m *Manager) ScaleOut(
 
 	if !skipConfirm {
 		// patchedComponents are components that have been patched and overwrited
-		if err := m.confirmTopology(clusterName, base.Version, newPart, patchedComponents); err != nil {
+		if err := m.confirmTopology(clusterName, OperationScaleOut, base.Version, newPart, patchedComponents); err != nil {
 			return err
 		}
 	}
@@ -1328,13 +2511,50 @@ func (m *Manager) ScaleOut(
 		return err
 	}
 
+	if _, err := PreflightSSHAuth(SSHHosts(newPart), opt.User, sshConnProps, sshTimeout, nativeSSH, false); err != nil {
+		return err
+	}
+	if err := m.checkHostPlatforms(newPart, base.Version, opt.User, sshConnProps, sshTimeout, nativeSSH); err != nil {
+		return err
+	}
+	if err := m.checkStaleUnits(clusterName, newPart, mergedTopo.GetMonitoredOptions(), topo, base.User,
+		opt.User, sshConnProps, sshTimeout, nativeSSH, opt.Force); err != nil {
+		return err
+	}
+
+	if err := CheckDownloadSpace(base.Version, newPart, m.componentBindVersion(base)); err != nil {
+		return err
+	}
+
+	hash, err := scaleOutHash(topo, newPart, base.Version)
+	if err != nil {
+		return err
+	}
+	cp, err := m.loadScaleOutCheckpoint(clusterName, hash)
+	if err != nil {
+		return err
+	}
+	if opt.NoResume {
+		if err := cp.remove(); err != nil {
+			return err
+		}
+		cp.Hosts = make(map[string][]string)
+	}
+	cp.printSkipped()
+
 	// Build the scale out tasks
-	t, err := buildScaleOutTask(m, clusterName, metadata, mergedTopo, opt, sshConnProps, newPart, patchedComponents, optTimeout, sshTimeout, nativeSSH, afterDeploy, final)
+	t, err := buildScaleOutTask(m, clusterName, metadata, mergedTopo, opt, sshConnProps, newPart, patchedComponents, optTimeout, sshTimeout, nativeSSH, cp, afterDeploy, final)
 	if err != nil {
 		return err
 	}
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	ctx := op.newTaskContext()
+	cp.trackTasks(ctx, t)
+	err = t.Execute(ctx)
+	if cerr := cp.closeTasks(err); cerr != nil {
+		log.Warnf("Failed to save the scale-out checkpoint of cluster `%s`: %s", clusterName, cerr)
+	}
+	if err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -1347,25 +2567,67 @@ func (m *Manager) ScaleOut(
 	return nil
 }
 
+// meta returns the metadata of the cluster, it's read from the in-process
+// cache unless the meta file is changed since cached, see metaFresh to bypass
+// the cache. ErrClusterNotExist is returned if the cluster doesn't exist, and
+// ErrMetaCorrupt if the meta file can't be parsed.
 func (m *Manager) meta(name string) (metadata spec.Metadata, err error) {
-	exist, err := m.specManager.Exist(name)
+	if err := m.checkExist(name); err != nil {
+		return nil, err
+	}
+
+	metadata, err = m.specManager.CachedMetadata(name)
 	if err != nil {
-		return nil, perrs.AddStack(err)
+		if errorx.Cast(err) != nil {
+			return metadata, err
+		}
+		return metadata, perrs.AddStack(err)
 	}
 
-	if !exist {
-		return nil, perrs.Errorf("%s cluster `%s` not exists", m.sysName, name)
+	return metadata, nil
+}
+
+// metaFresh reads the metadata of the cluster from the meta file bypassing
+// the cache, for operations that must see the freshest data
+func (m *Manager) metaFresh(name string) (metadata spec.Metadata, err error) {
+	if err := m.checkExist(name); err != nil {
+		return nil, err
 	}
 
 	metadata = m.specManager.NewMetadata()
 	err = m.specManager.Metadata(name, metadata)
 	if err != nil {
+		if errorx.Cast(err) != nil {
+			return metadata, err
+		}
 		return metadata, perrs.AddStack(err)
 	}
 
 	return metadata, nil
 }
 
+// checkExist returns ErrClusterNotExist carrying the names of the similar
+// existing clusters if the cluster doesn't exist
+func (m *Manager) checkExist(name string) error {
+	exist, err := m.specManager.Exist(name)
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	if exist {
+		return nil
+	}
+
+	similar := m.specManager.SimilarNames(name)
+	suggestion := fmt.Sprintf("Please check the cluster name, use `%s list` to see all clusters.", cliutil.OsArgs0())
+	if len(similar) > 0 {
+		suggestion = fmt.Sprintf("Did you mean: %s?", strings.Join(similar, ", "))
+	}
+	return spec.ErrClusterNotExist.
+		New("%s cluster `%s` not exists", m.sysName, name).
+		WithProperty(spec.ErrPropSimilarClusters, similar).
+		WithProperty(cliutil.SuggestionFromString(suggestion))
+}
+
 // 1. Write Topology to a temporary file.
 // 2. Open file in editor.
 // 3. Check and update Topology.
@@ -1435,6 +2697,13 @@ func (m *Manager) editTopo(origTopo spec.Topology, data []byte, skipConfirm bool
 
 	utils.ShowDiff(string(origData), string(newData), os.Stdout)
 
+	// the impact is informative only, the change is applied anyway if confirmed
+	if report, err := configImpact(origTopo, newTopo); err != nil {
+		log.Warnf("Failed to analyze the impact of the change: %s", err)
+	} else {
+		printImpactReport(report)
+	}
+
 	if !skipConfirm {
 		if err := cliutil.PromptForConfirmOrAbortError(
 			color.HiYellowString("Please check change highlight above, do you want to apply the change? [y/N]:"),
@@ -1478,7 +2747,7 @@ func versionCompare(curVersion, newVersion string) error {
 		return nil
 	}
 
-	switch semver.Compare(curVersion, newVersion) {
+	switch version.Compare(curVersion, newVersion) {
 	case -1:
 		return nil
 	case 0, 1:
@@ -1493,6 +2762,22 @@ type componentInfo struct {
 	version   string
 }
 
+// releaseInfoRow returns the release date and notes of the component to show
+// in a table, the columns are empty if they are not available in repo, or
+// there is no repo
+func releaseInfoRow(repo clusterutil.Repository, comp componentInfo) []string {
+	row := []string{comp.component, comp.version, "", ""}
+	if repo == nil {
+		return row
+	}
+	released, notes, err := repo.ComponentReleaseInfo(comp.component, comp.version)
+	if err != nil {
+		return row
+	}
+	row[2], row[3] = released, notes
+	return row
+}
+
 func instancesToPatch(topo spec.Topology, options operator.Options) ([]spec.Instance, error) {
 	roleFilter := set.NewStringSet(options.Roles...)
 	nodeFilter := set.NewStringSet(options.Nodes...)
@@ -1593,7 +2878,7 @@ func validateNewTopo(topo spec.Topology) (err error) {
 	return err
 }
 
-func (m *Manager) confirmTopology(clusterName, version string, topo spec.Topology, patchedRoles set.StringSet) error {
+func (m *Manager) confirmTopology(clusterName string, op OperationType, version string, topo spec.Topology, patchedRoles set.StringSet) error {
 	log.Infof("Please confirm your topology:")
 
 	cyan := color.New(color.FgCyan, color.Bold)
@@ -1635,6 +2920,7 @@ func (m *Manager) confirmTopology(clusterName, version string, topo spec.Topolog
 		}
 	}
 
+	fmt.Println(m.estimateHint(clusterName, op))
 	return cliutil.PromptForConfirmOrAbortError("Do you want to continue? [y/N]: ")
 }
 
@@ -1650,11 +2936,11 @@ func buildScaleOutTask(
 	optTimeout int64,
 	sshTimeout int64,
 	nativeSSH bool,
+	cp *scaleOutCheckpoint,
 	afterDeploy func(b *task.Builder, newPart spec.Topology),
 	final func(b *task.Builder, name string, meta spec.Metadata),
 ) (task.Task, error) {
 	var (
-		envInitTasks       []task.Task // tasks which are used to initialize environment
 		downloadCompTasks  []task.Task // tasks which are used to download components
 		deployCompTasks    []task.Task // tasks which are used to copy components to remote host
 		refreshConfigTasks []task.Task // tasks which are used to refresh configuration
@@ -1664,14 +2950,29 @@ func buildScaleOutTask(
 	base := metadata.GetBaseMeta()
 	specManager := m.specManager
 
-	// Initialize the environments
+	// the phases finished on the hosts by the last try are skipped, the hosts
+	// are copied to before the configs are generated
+	copyHosts := make(map[string]struct{})
+	configHosts := make(map[string]struct{})
+	newPart.IterInstance(func(inst spec.Instance) {
+		if !cp.finished(inst.GetHost(), scaleOutPhaseCopy) {
+			copyHosts[inst.GetHost()] = struct{}{}
+		}
+		if !cp.finished(inst.GetHost(), scaleOutPhaseConfig) {
+			configHosts[inst.GetHost()] = struct{}{}
+		}
+	})
+
+	// Initialize the environments, the hosts initialized by the last try are
+	// still deployed the monitored components to
+	envInitTasks := make(map[string]task.Task) // host -> task
 	initializedHosts := set.NewStringSet()
 	metadata.GetTopology().IterInstance(func(instance spec.Instance) {
 		initializedHosts.Insert(instance.GetHost())
 	})
 	// uninitializedHosts are hosts which haven't been initialized yet
 	uninitializedHosts := make(map[string]hostInfo) // host -> ssh-port, os, arch
-	newPart.IterInstance(func(instance spec.Instance) {
+	(&hostsIter{topo: newPart, hosts: copyHosts}).IterInstance(func(instance spec.Instance) {
 		if host := instance.GetHost(); !initializedHosts.Exist(host) {
 			if _, found := uninitializedHosts[host]; found {
 				return
@@ -1682,6 +2983,9 @@ func buildScaleOutTask(
 				os:   instance.OS(),
 				arch: instance.Arch(),
 			}
+			if cp.finished(host, scaleOutPhaseEnvInit) {
+				return
+			}
 
 			var dirs []string
 			globalOptions := metadata.GetTopology().BaseTopo().GlobalOptions
@@ -1705,68 +3009,92 @@ func buildScaleOutTask(
 					nativeSSH,
 				).
 				EnvInit(instance.GetHost(), base.User, base.Group, opt.SkipCreateUser || globalOptions.User == opt.User).
+				ApplyOSSettings(instance.GetHost(), clusterName, spec.GetOSSettings(mergedTopo)).
 				Mkdir(globalOptions.User, instance.GetHost(), dirs...).
 				Build()
-			envInitTasks = append(envInitTasks, t)
+			envInitTasks[host] = t
 		}
 	})
 
-	// Download missing component
-	downloadCompTasks = convertStepDisplaysToTasks(BuildDownloadCompTasks(base.Version, newPart, m.bindVersion))
+	// Download missing component, only for the hosts to copy to
+	downloadHosts := make([]string, 0, len(copyHosts))
+	for host := range copyHosts {
+		if !cp.finished(host, scaleOutPhaseDownload) {
+			downloadHosts = append(downloadHosts, host)
+		}
+	}
+	if len(downloadHosts) > 0 {
+		downloadCompTasks = convertStepDisplaysToTasks(BuildDownloadCompTasks(base.Version, &hostsIter{topo: newPart, hosts: copyHosts}, m.componentBindVersion(base)))
+	}
 
-	// Deploy the new topology and refresh the configuration
+	// Deploy the new topology and refresh the configuration, the tasks of a
+	// host, from initializing its environment, are executed in serial and the
+	// phases are recorded once finished
+	copyTasks := make(map[string][]task.Task)   // host -> tasks
+	configTasks := make(map[string][]task.Task) // host -> tasks
 	newPart.IterInstance(func(inst spec.Instance) {
-		version := m.bindVersion(inst.ComponentName(), base.Version)
+		host := inst.GetHost()
+		if _, ok := configHosts[host]; !ok {
+			return
+		}
+		version := m.bindVersion(inst.ComponentName(), base.ComponentVersion(inst.ComponentName()))
 		deployDir := clusterutil.Abs(base.User, inst.DeployDir())
 		// data dir would be empty for components which don't need it
 		dataDirs := clusterutil.MultiDirAbs(base.User, inst.DataDir())
 		// log dir will always be with values, but might not used by the component
 		logDir := clusterutil.Abs(base.User, inst.LogDir())
 
-		// Deploy component
-		tb := task.NewBuilder().
-			UserSSH(inst.GetHost(), inst.GetSSHPort(), base.User, sshTimeout, nativeSSH).
-			Mkdir(base.User, inst.GetHost(),
-				deployDir, logDir,
-				filepath.Join(deployDir, "bin"),
-				filepath.Join(deployDir, "conf"),
-				filepath.Join(deployDir, "scripts")).
-			Mkdir(base.User, inst.GetHost(), dataDirs...)
-
-		srcPath := ""
-		if patchedComponents.Exist(inst.ComponentName()) {
-			srcPath = specManager.Path(clusterName, spec.PatchDirName, inst.ComponentName()+".tar.gz")
-		}
-
-		// copy dependency component if needed
-		switch inst.ComponentName() {
-		case spec.ComponentTiSpark:
-			tb = tb.DeploySpark(inst, version, srcPath, deployDir, m.bindVersion)
-		default:
-			tb.CopyComponent(
-				inst.ComponentName(),
-				inst.OS(),
-				inst.Arch(),
-				version,
-				srcPath,
-				inst.GetHost(),
-				deployDir,
-			)
+		if _, ok := copyHosts[host]; ok {
+			// Deploy component
+			tb := task.NewBuilder().
+				Transfer(opt.Transfer).
+				UserSSH(host, inst.GetSSHPort(), base.User, sshTimeout, nativeSSH).
+				Mkdir(base.User, host,
+					deployDir, logDir,
+					filepath.Join(deployDir, "bin"),
+					filepath.Join(deployDir, "conf"),
+					filepath.Join(deployDir, "scripts")).
+				Mkdir(base.User, host, dataDirs...).
+				Shell(host, operator.FirstStartMarkCommand(deployDir, dataDirs), false)
+
+			srcPath := ""
+			if patchedComponents.Exist(inst.ComponentName()) {
+				srcPath = specManager.Path(clusterName, spec.PatchDirName, inst.ComponentName()+".tar.gz")
+			}
+
+			// copy dependency component if needed
+			switch inst.ComponentName() {
+			case spec.ComponentTiSpark:
+				tb = tb.DeploySpark(inst, version, srcPath, deployDir, m.bindVersion)
+			default:
+				tb.CopyComponent(
+					inst.ComponentName(),
+					inst.OS(),
+					inst.Arch(),
+					version,
+					srcPath,
+					host,
+					deployDir,
+				)
+			}
+			copyTasks[host] = append(copyTasks[host], tb.Build())
 		}
 
-		t := tb.ScaleConfig(clusterName,
-			base.Version,
-			m.specManager,
-			topo,
-			inst,
-			base.User,
-			meta.DirPaths{
-				Deploy: deployDir,
-				Data:   dataDirs,
-				Log:    logDir,
-			},
-		).Build()
-		deployCompTasks = append(deployCompTasks, t)
+		t := task.NewBuilder().
+			UserSSH(host, inst.GetSSHPort(), base.User, sshTimeout, nativeSSH).
+			ScaleConfig(clusterName,
+				base.ComponentVersion(inst.ComponentName()),
+				m.specManager,
+				topo,
+				inst,
+				base.User,
+				meta.DirPaths{
+					Deploy: deployDir,
+					Data:   dataDirs,
+					Log:    logDir,
+				},
+			).Build()
+		configTasks[host] = append(configTasks[host], t)
 	})
 
 	hasImported := false
@@ -1783,16 +3111,21 @@ func buildScaleOutTask(
 		if inst.IsImported() {
 			switch compName := inst.ComponentName(); compName {
 			case spec.ComponentGrafana, spec.ComponentPrometheus, spec.ComponentAlertManager:
-				version := m.bindVersion(compName, base.Version)
+				version := m.bindVersion(compName, base.ComponentVersion(compName))
 				tb.Download(compName, inst.OS(), inst.Arch(), version).
 					CopyComponent(compName, inst.OS(), inst.Arch(), version, "", inst.GetHost(), deployDir)
 			}
 			hasImported = true
 		}
 
+		// the existing config is merged into the regenerated one unless it's removed
+		if opt.ForceRegenerate && inst.ComponentName() == spec.ComponentPrometheus {
+			tb.Shell(inst.GetHost(), fmt.Sprintf("rm -f %s", filepath.Join(deployDir, "conf", "prometheus.yml")), false)
+		}
+
 		// Refresh all configuration
 		t := tb.InitConfig(clusterName,
-			base.Version,
+			base.ComponentVersion(inst.ComponentName()),
 			m.specManager,
 			inst,
 			base.User,
@@ -1814,29 +3147,69 @@ func buildScaleOutTask(
 		}
 	}
 
-	// Deploy monitor relevant components to remote
-	dlTasks, dpTasks := buildMonitoredDeployTask(
-		m.bindVersion,
-		specManager,
-		clusterName,
-		uninitializedHosts,
-		topo.BaseTopo().GlobalOptions,
-		topo.BaseTopo().MonitoredOptions,
-		base.Version,
-		sshTimeout,
-		nativeSSH,
-	)
-	downloadCompTasks = append(downloadCompTasks, convertStepDisplaysToTasks(dlTasks)...)
-	deployCompTasks = append(deployCompTasks, convertStepDisplaysToTasks(dpTasks)...)
+	// Deploy monitor relevant components to remote, the tasks of each host are
+	// built separately to be recorded with the host
+	buildMonitored := func(hosts map[string]hostInfo) ([]*task.StepDisplay, []*task.StepDisplay) {
+		return buildMonitoredDeployTask(
+			m.bindVersion,
+			specManager,
+			clusterName,
+			hosts,
+			topo.BaseTopo().GlobalOptions,
+			topo.BaseTopo().MonitoredOptions,
+			base.Version,
+			func(b *task.Builder, host string, port int) *task.Builder {
+				return b.UserSSH(host, port, topo.BaseTopo().GlobalOptions.User, sshTimeout, nativeSSH)
+			},
+		)
+	}
+	if len(downloadHosts) > 0 {
+		dlTasks, _ := buildMonitored(uninitializedHosts)
+		downloadCompTasks = append(downloadCompTasks, convertStepDisplaysToTasks(dlTasks)...)
+	}
+	for host, info := range uninitializedHosts {
+		_, dpTasks := buildMonitored(map[string]hostInfo{host: info})
+		copyTasks[host] = append(copyTasks[host], convertStepDisplaysToTasks(dpTasks)...)
+	}
+
+	hosts := make([]string, 0, len(configHosts))
+	for host := range configHosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		var tasks []task.Task
+		if t, ok := envInitTasks[host]; ok {
+			tasks = append(tasks, t, cp.finishTask(scaleOutPhaseEnvInit, host))
+		}
+		if _, ok := copyHosts[host]; ok {
+			tasks = append(tasks, copyTasks[host]...)
+			tasks = append(tasks, cp.finishTask(scaleOutPhaseCopy, host))
+		}
+		tasks = append(tasks, configTasks[host]...)
+		tasks = append(tasks, cp.finishTask(scaleOutPhaseConfig, host))
+		deployCompTasks = append(deployCompTasks, task.NewBuilder().Serial(tasks...).Build())
+	}
+
+	// the existing instances are only touched after all the new hosts are
+	// prepared, the failures of preparing are confined to the error scope.
+	// The tasks of each host are rolled back in the reverse order as deploy.
+	prepare := task.NewBuilder().
+		Mode(hostPhaseMode(opt.ErrorScope)).
+		OrderedRollback().
+		Parallel(downloadCompTasks...)
+	if len(downloadHosts) > 0 {
+		prepare.Serial(cp.finishTask(scaleOutPhaseDownload, downloadHosts...))
+	}
+	prepare.
+		ClusterSSH(topo, base.User, sshTimeout, nativeSSH).
+		Parallel(deployCompTasks...)
 
 	builder := task.NewBuilder().
 		SSHKeySet(
 			specManager.Path(clusterName, "ssh", "id_rsa"),
 			specManager.Path(clusterName, "ssh", "id_rsa.pub")).
-		Parallel(downloadCompTasks...).
-		Parallel(envInitTasks...).
-		ClusterSSH(topo, base.User, sshTimeout, nativeSSH).
-		Parallel(deployCompTasks...)
+		Serial(prepare.Build())
 
 	if afterDeploy != nil {
 		afterDeploy(builder, newPart)
@@ -1845,21 +3218,35 @@ func buildScaleOutTask(
 	// TODO: find another way to make sure current cluster started
 	builder.
 		Func("StartCluster", func(ctx *task.Context) error {
-			return operator.Start(ctx, metadata.GetTopology(), operator.Options{OptTimeout: optTimeout})
+			return operator.Start(ctx, metadata.GetTopology(), operator.Options{
+				OptTimeout:        optTimeout,
+				FirstStartTimeout: opt.FirstStartTimeout,
+				RestartTimeout:    opt.RestartTimeout,
+			})
 		}).
 		ClusterSSH(newPart, base.User, sshTimeout, nativeSSH).
 		Func("Save meta", func(_ *task.Context) error {
 			metadata.SetTopology(mergedTopo)
-			return m.specManager.SaveMeta(clusterName, metadata)
+			if err := m.specManager.SaveMeta(clusterName, metadata); err != nil {
+				return err
+			}
+			// the new instances are a part of the cluster now
+			return cp.remove()
 		}).
 		Func("StartCluster", func(ctx *task.Context) error {
-			return operator.Start(ctx, newPart, operator.Options{OptTimeout: optTimeout})
+			return operator.Start(ctx, newPart, operator.Options{
+				OptTimeout:        optTimeout,
+				FirstStartTimeout: opt.FirstStartTimeout,
+				RestartTimeout:    opt.RestartTimeout,
+			})
 		}).
 		Parallel(refreshConfigTasks...).
 		Func("RestartCluster", func(ctx *task.Context) error {
 			return operator.Restart(ctx, metadata.GetTopology(), operator.Options{
-				Roles:      []string{spec.ComponentPrometheus},
-				OptTimeout: optTimeout,
+				Roles:             []string{spec.ComponentPrometheus},
+				OptTimeout:        optTimeout,
+				FirstStartTimeout: opt.FirstStartTimeout,
+				RestartTimeout:    opt.RestartTimeout,
 			})
 		})
 
@@ -1893,9 +3280,8 @@ func buildMonitoredDeployTask(
 	uniqueHosts map[string]hostInfo, // host -> ssh-port, os, arch
 	globalOptions *spec.GlobalOptions,
 	monitoredOptions *spec.MonitoredOptions,
-	version string,
-	sshTimeout int64,
-	nativeSSH bool,
+	clusterVersion string,
+	connect func(b *task.Builder, host string, port int) *task.Builder,
 ) (downloadCompTasks []*task.StepDisplay, deployCompTasks []*task.StepDisplay) {
 	if monitoredOptions == nil {
 		return
@@ -1904,16 +3290,19 @@ func buildMonitoredDeployTask(
 	uniqueCompOSArch := make(map[string]struct{}) // comp-os-arch -> {}
 	// monitoring agents
 	for _, comp := range []string{spec.ComponentNodeExporter, spec.ComponentBlackboxExporter} {
-		version := bindVersion(comp, version)
+		version := bindVersion(comp, clusterVersion)
 
 		for host, info := range uniqueHosts {
+			if !monitoredOptions.DeployAgents(host) {
+				continue
+			}
 			// populate unique os/arch set
 			key := fmt.Sprintf("%s-%s-%s", comp, info.os, info.arch)
 			if _, found := uniqueCompOSArch[key]; !found {
 				uniqueCompOSArch[key] = struct{}{}
 				downloadCompTasks = append(downloadCompTasks, task.NewBuilder().
 					Download(comp, info.os, info.arch, version).
-					BuildAsStep(fmt.Sprintf("  - Download %s:%s (%s/%s)", comp, version, info.os, info.arch)))
+					BuildAsStepMessage(task.MsgDownload, task.MessageParams{"component": comp, "version": version, "os": info.os, "arch": info.arch}))
 			}
 
 			deployDir := clusterutil.Abs(globalOptions.User, monitoredOptions.DeployDir)
@@ -1926,8 +3315,7 @@ func buildMonitoredDeployTask(
 			// log dir will always be with values, but might not used by the component
 			logDir := clusterutil.Abs(globalOptions.User, monitoredOptions.LogDir)
 			// Deploy component
-			t := task.NewBuilder().
-				UserSSH(host, info.ssh, globalOptions.User, sshTimeout, nativeSSH).
+			t := connect(task.NewBuilder(), host, info.ssh).
 				Mkdir(globalOptions.User, host,
 					deployDir, dataDir, logDir,
 					filepath.Join(deployDir, "bin"),
@@ -1944,9 +3332,10 @@ func buildMonitoredDeployTask(
 				).
 				MonitoredConfig(
 					clusterName,
+					clusterVersion,
 					comp,
 					host,
-					globalOptions.ResourceControl,
+					*globalOptions,
 					monitoredOptions,
 					globalOptions.User,
 					meta.DirPaths{
@@ -1956,16 +3345,42 @@ func buildMonitoredDeployTask(
 						Cache:  specManager.Path(clusterName, spec.TempConfigPath),
 					},
 				).
-				BuildAsStep(fmt.Sprintf("  - Copy %s -> %s", comp, host))
+				BuildAsStepMessage(task.MsgCopy, task.MessageParams{"component": comp, "host": host})
 			deployCompTasks = append(deployCompTasks, t)
 		}
 	}
 	return
 }
 
+// warnUnmonitoredHosts warns the hosts of the topology neither running the
+// monitoring agents nor an external node_exporter
+func warnUnmonitoredHosts(monitoredOptions *spec.MonitoredOptions, topo spec.Topology) {
+	if monitoredOptions == nil {
+		return
+	}
+	if hosts := monitoredOptions.UnmonitoredHosts(topo); len(hosts) > 0 {
+		log.Warnf("The monitoring agents are not deployed on %s and no external_exporter_port is set, the metrics of the hosts are not collected",
+			strings.Join(hosts, ", "))
+	}
+}
+
+// printBootstrapReports prints what has been done when bootstrapping the deploy user
+func printBootstrapReports(reports []*task.BootstrapReport) {
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Host < reports[j].Host
+	})
+	rows := [][]string{{"Host", "User", "Authorized Key", "Sudoers"}}
+	for _, r := range reports {
+		rows = append(rows, []string{r.Host, r.User, r.AuthorizedKey, r.Sudoers})
+	}
+	fmt.Println("Bootstrap of the deploy user:")
+	cliutil.PrintTable(rows, true)
+}
+
 func refreshMonitoredConfigTask(
 	specManager *spec.SpecManager,
 	clusterName string,
+	clusterVersion string,
 	uniqueHosts map[string]hostInfo, // host -> ssh-port, os, arch
 	globalOptions spec.GlobalOptions,
 	monitoredOptions *spec.MonitoredOptions,
@@ -1977,9 +3392,16 @@ func refreshMonitoredConfigTask(
 	}
 
 	tasks := []*task.StepDisplay{}
+	ports := map[string]int{
+		spec.ComponentNodeExporter:     monitoredOptions.NodeExporterPort,
+		spec.ComponentBlackboxExporter: monitoredOptions.BlackboxExporterPort,
+	}
 	// monitoring agents
 	for _, comp := range []string{spec.ComponentNodeExporter, spec.ComponentBlackboxExporter} {
 		for host, info := range uniqueHosts {
+			if !monitoredOptions.DeployAgents(host) {
+				continue
+			}
 			deployDir := clusterutil.Abs(globalOptions.User, monitoredOptions.DeployDir)
 			// data dir would be empty for components which don't need it
 			dataDir := monitoredOptions.DataDir
@@ -1994,9 +3416,10 @@ func refreshMonitoredConfigTask(
 				UserSSH(host, info.ssh, globalOptions.User, sshTimeout, nativeSSH).
 				MonitoredConfig(
 					clusterName,
+					clusterVersion,
 					comp,
 					host,
-					globalOptions.ResourceControl,
+					globalOptions,
 					monitoredOptions,
 					globalOptions.User,
 					meta.DirPaths{
@@ -2006,7 +3429,7 @@ func refreshMonitoredConfigTask(
 						Cache:  specManager.Path(clusterName, spec.TempConfigPath),
 					},
 				).
-				BuildAsStep(fmt.Sprintf("  - Refresh config %s -> %s", comp, host))
+				BuildAsStepMessage(task.MsgRefreshConfig, task.MessageParams{"component": comp, "instance": fmt.Sprintf("%s:%d", host, ports[comp])})
 			tasks = append(tasks, t)
 		}
 	}
@@ -14,9 +14,20 @@
 package cluster
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/errutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v2"
@@ -36,6 +47,25 @@ func TestVersionCompare(t *testing.T) {
 
 	err = versionCompare("nightly", "nightly")
 	assert.Nil(t, err)
+
+	// the versions with and without the v prefix are the same
+	err = versionCompare("v6.1.0", "6.5.0")
+	assert.Nil(t, err)
+	err = versionCompare("6.5.0", "v6.5.0")
+	assert.NotNil(t, err)
+	assert.Empty(t, mixedVersionWarnings(map[string]string{"tidb": "6.5.0", "tikv": "v6.5.1", "pd": "nightly"}))
+
+	ver, err := normalizeClusterVersion("6.5.0", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "v6.5.0", ver)
+	ver, err = normalizeClusterVersion("Nightly", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "nightly", ver)
+	_, err = normalizeClusterVersion("master-custom", false)
+	assert.True(t, errorx.IsOfType(err, ErrInvalidVersion))
+	ver, err = normalizeClusterVersion("master-custom", true)
+	assert.Nil(t, err)
+	assert.Equal(t, "master-custom", ver)
 }
 
 func TestValidateNewTopo(t *testing.T) {
@@ -86,3 +116,349 @@ pd_servers:
 	err = validateNewTopo(&topo)
 	assert.NotNil(err)
 }
+
+func TestMetaErrors(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+	for _, name := range []string{"prod-cluster", "prod-cluster-2", "test"} {
+		require.Nil(t, os.MkdirAll(m.specManager.Path(name), 0755))
+		require.Nil(t, ioutil.WriteFile(m.specManager.Path(name, "meta.yaml"), []byte("user: tidb\n"), 0644))
+	}
+
+	_, err := m.meta("prod-clustr")
+	require.NotNil(t, err)
+	errx := errutil.Cast(err)
+	require.NotNil(t, errx)
+	assert.True(t, errx.IsOfType(spec.ErrClusterNotExist))
+	similar, ok := errx.Property(spec.ErrPropSimilarClusters)
+	require.True(t, ok)
+	assert.Equal(t, []string{"prod-cluster", "prod-cluster-2"}, similar)
+
+	require.Nil(t, ioutil.WriteFile(m.specManager.Path("test", "meta.yaml"),
+		[]byte("user: tidb\ntidb_version: v4.0.0\ntopology:\n  pd_servers: [\n"), 0644))
+	for _, load := range []func(string) (spec.Metadata, error){m.meta, m.metaFresh} {
+		_, err = load("test")
+		require.NotNil(t, err)
+		errx = errutil.Cast(err)
+		require.NotNil(t, errx)
+		assert.True(t, errx.IsOfType(spec.ErrMetaCorrupt))
+		line, ok := errx.Property(spec.ErrPropMetaLine)
+		require.True(t, ok)
+		assert.Equal(t, 4, line)
+	}
+}
+
+func TestMetaRoles(t *testing.T) {
+	m, mc, metadata, cleanup := newTestMockCluster(t, 2)
+	defer cleanup()
+
+	roles, err := operator.ExpandRoles(metadata.Topology, []string{"tikv", "@core"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"tikv", "pd", "tidb"}, roles)
+	roles, err = operator.ExpandRoles(metadata.Topology, []string{"@monitoring"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"node_exporter", "blackbox_exporter"}, roles)
+
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10}
+	require.Nil(t, m.StartCluster("mock", opt))
+
+	// the agents are stopped on all the hosts while the database keeps running
+	opt.Roles = []string{"@monitoring"}
+	require.Nil(t, m.StopCluster("mock", opt))
+	for _, host := range []string{"mock-1", "mock-2"} {
+		assert.False(t, mc.Host(host).Active("node_exporter-9100.service"), host)
+		assert.False(t, mc.Host(host).Active("blackbox_exporter-9115.service"), host)
+		assert.True(t, mc.Host(host).Active("tikv-20160.service"), host)
+	}
+	require.Nil(t, m.StartCluster("mock", opt))
+	assert.True(t, mc.Host("mock-2").Active("node_exporter-9100.service"))
+
+	opt.Roles = []string{"@storage"}
+	err = m.StopCluster("mock", opt)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "@core, @monitoring")
+}
+
+func TestReadOnlyProfile(t *testing.T) {
+	m, _, _, cleanup := newTestMockCluster(t, 2)
+	defer cleanup()
+	require.Nil(t, m.specManager.CheckWritable())
+
+	chmod := func(dirMode, fileMode os.FileMode) {
+		require.Nil(t, filepath.Walk(filepath.Dir(m.specManager.Path("mock")), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return os.Chmod(path, dirMode)
+			}
+			return os.Chmod(path, fileMode)
+		}))
+	}
+	// snapshot returns the files of the profile with their sizes and
+	// modification times
+	snapshot := func() map[string]string {
+		files := make(map[string]string)
+		require.Nil(t, filepath.Walk(filepath.Dir(m.specManager.Path("mock")), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			files[path] = fmt.Sprintf("%d %s", info.Size(), info.ModTime())
+			return nil
+		}))
+		return files
+	}
+	chmod(0555, 0444)
+	defer chmod(0755, 0644)
+
+	// the read paths don't write anything, which is checked by the files
+	// rather than by the permissions, as they are not enforced for root
+	files := snapshot()
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10}
+	assert.Nil(t, m.ListCluster())
+	assert.Nil(t, m.Display("mock", opt))
+	insts, err := m.InstanceStatuses("mock", opt)
+	assert.Nil(t, err)
+	assert.Len(t, insts, 6)
+	_, err = m.GetTags("mock")
+	assert.Nil(t, err)
+	assert.Equal(t, files, snapshot())
+
+	if os.Geteuid() == 0 {
+		t.Skip("the permissions of the read-only profile are not enforced for root")
+	}
+	// the operations not changing the metadata work
+	assert.Nil(t, m.StartCluster("mock", opt))
+	err = m.SetProtection("mock", true)
+	assert.True(t, errorx.IsOfType(err, spec.ErrReadOnlyProfile), "%v", err)
+	err = m.SetTag("mock", "env", "prod")
+	assert.True(t, errorx.IsOfType(err, spec.ErrReadOnlyProfile), "%v", err)
+	err = m.EditConfig("mock", true)
+	assert.True(t, errorx.IsOfType(err, spec.ErrReadOnlyProfile), "%v", err)
+}
+
+func TestSerialStartStop(t *testing.T) {
+	m, mc, _, cleanup := newTestMockCluster(t, 3)
+	defer cleanup()
+
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10, Serial: true}
+	require.Nil(t, m.StartCluster("mock", opt))
+	for _, host := range []string{"mock-1", "mock-2", "mock-3"} {
+		assert.True(t, mc.Host(host).Active("tikv-20160.service"), host)
+	}
+	require.Nil(t, m.RestartCluster("mock", opt))
+	require.Nil(t, m.StopCluster("mock", opt))
+	for _, host := range []string{"mock-1", "mock-2", "mock-3"} {
+		assert.False(t, mc.Host(host).Active("tikv-20160.service"), host)
+	}
+
+	// the steps collecting errors are serialized as well
+	opt.IgnoreErrors = true
+	require.Nil(t, m.StartCluster("mock", opt))
+	assert.True(t, mc.Host("mock-3").Active("tidb-4000.service"))
+
+	// the option is recorded for replaying the operation
+	last, err := m.LastOptions("mock")
+	require.Nil(t, err)
+	require.NotNil(t, last)
+	assert.Equal(t, true, last.Resolved["Serial"])
+}
+
+func TestStartErrorScope(t *testing.T) {
+	m, mc, _, cleanup := newTestMockCluster(t, 2)
+	defer cleanup()
+
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10, ErrorScope: "cluster"}
+	err := m.StartCluster("mock", opt)
+	assert.True(t, errorx.IsOfType(err, ErrStartErrorScope), "%v", err)
+
+	// the rest instances on the host are skipped, the others are started
+	mc.Host("mock-2").Respond("systemctl daemon-reload && systemctl start tikv-20160", "", "address already in use")
+	opt.ErrorScope = operator.ErrorScopeHost
+	err = m.StartCluster("mock", opt)
+	var degraded *task.DegradedError
+	require.True(t, errors.As(err, &degraded), "%v", err)
+	assert.True(t, mc.Host("mock-1").Active("tidb-4000.service"))
+	assert.True(t, mc.Host("mock-2").Active("pd-2379.service"))
+	assert.False(t, mc.Host("mock-2").Active("tidb-4000.service"))
+	require.Len(t, degraded.Hosts, 1)
+	assert.Equal(t, "mock-2", degraded.Hosts[0].Host)
+
+	// the failures are recorded in the history, including the skipped steps
+	history, err := m.readHistory("mock")
+	require.Nil(t, err)
+	last := history[len(history)-1]
+	var failed, skipped int
+	for _, f := range last.Failures {
+		assert.Contains(t, f.Step, "mock-2")
+		if f.Skipped {
+			skipped++
+		} else {
+			failed++
+		}
+	}
+	// starting tidb and the monitoring agents on mock-2
+	assert.Equal(t, 1, failed)
+	assert.Equal(t, 2, skipped)
+}
+
+func TestStartIgnoreErrors(t *testing.T) {
+	m, mc, _, cleanup := newTestMockCluster(t, 2)
+	defer cleanup()
+	mc.Host("mock-2").Respond("systemctl daemon-reload && systemctl start tikv-20160", "", "address already in use")
+
+	// the start stops at the failure by default
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10}
+	err := m.StartCluster("mock", opt)
+	require.NotNil(t, err)
+	var degraded *task.DegradedError
+	assert.False(t, errors.As(err, &degraded), "%v", err)
+	assert.False(t, mc.Host("mock-1").Active("tidb-4000.service"))
+
+	// all the other instances are started, including the ones on the same
+	// host, and the cluster is reported as degraded
+	opt.IgnoreErrors = true
+	err = m.StartCluster("mock", opt)
+	require.True(t, errors.As(err, &degraded), "%v", err)
+	require.Len(t, degraded.Failures, 1)
+	assert.Contains(t, degraded.Failures[0].Step, "mock-2:20160")
+	assert.Contains(t, degraded.Failures[0].Err.Error(), "address already in use")
+	assert.False(t, degraded.Failures[0].Skipped)
+	assert.Empty(t, degraded.Hosts)
+	for _, h := range []string{"mock-1", "mock-2"} {
+		assert.True(t, mc.Host(h).Active("pd-2379.service"), h)
+		assert.True(t, mc.Host(h).Active("tidb-4000.service"), h)
+		assert.True(t, mc.Host(h).Active("node_exporter-9100.service"), h)
+	}
+	assert.True(t, mc.Host("mock-1").Active("tikv-20160.service"))
+	assert.False(t, mc.Host("mock-2").Active("tikv-20160.service"))
+	assert.Equal(t, err, GetCurrentOperation("mock").Error())
+
+	// the failed steps are kept in the progress with their errors
+	steps := GetCurrentOperation("mock").ComputeProgress().FailedSteps
+	require.Len(t, steps, 1)
+	assert.Contains(t, steps[0].Step, "mock-2:20160")
+	assert.Equal(t, "Error", steps[0].Status)
+	require.Len(t, steps[0].Errors, 1)
+	assert.Contains(t, steps[0].Errors[0], "address already in use")
+}
+
+func TestStopErrorScope(t *testing.T) {
+	m, mc, _, cleanup := newTestMockCluster(t, 2)
+	defer cleanup()
+
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10, ErrorScope: "cluster"}
+	err := m.StopCluster("mock", opt)
+	assert.True(t, errorx.IsOfType(err, ErrStartErrorScope), "%v", err)
+	err = m.RestartCluster("mock", opt)
+	assert.True(t, errorx.IsOfType(err, ErrStartErrorScope), "%v", err)
+
+	// the instances after tikv on the host failing to stop it are skipped,
+	// the other hosts are stopped
+	opt.ErrorScope = ""
+	require.Nil(t, m.StartCluster("mock", opt))
+	mc.Host("mock-2").Respond("systemctl daemon-reload && systemctl stop tikv-20160", "", "timed out")
+	opt.ErrorScope = operator.ErrorScopeHost
+	err = m.StopCluster("mock", opt)
+	var degraded *task.DegradedError
+	require.True(t, errors.As(err, &degraded), "%v", err)
+	require.Len(t, degraded.Hosts, 1)
+	assert.Equal(t, "mock-2", degraded.Hosts[0].Host)
+	assert.False(t, mc.Host("mock-1").Active("pd-2379.service"))
+	assert.False(t, mc.Host("mock-2").Active("tidb-4000.service"))
+	assert.True(t, mc.Host("mock-2").Active("pd-2379.service"))
+
+	// restarting in the scope stops and starts the instances of the other
+	// hosts, even if the host fails to stop
+	err = m.RestartCluster("mock", opt)
+	require.True(t, errors.As(err, &degraded), "%v", err)
+	assert.True(t, mc.Host("mock-1").Active("tikv-20160.service"))
+	assert.True(t, mc.Host("mock-1").Active("tidb-4000.service"))
+}
+
+func TestEnableClusterFailed(t *testing.T) {
+	m, mc, _, cleanup := newTestMockCluster(t, 2)
+	defer cleanup()
+
+	// the other units are disabled even if one of them fails, which is an
+	// error of the operation rather than of its pre-checks
+	mc.Host("mock-1").Respond("systemctl daemon-reload && systemctl disable tikv-20160.service", "", "timed out")
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10, ComponentClass: operator.ComponentClassCore}
+	err := m.EnableCluster("mock", false, EnableOptions{}, opt)
+	require.True(t, errorx.IsOfType(err, ErrEnableFailed), "%v", err)
+	assert.False(t, errorx.HasTrait(err, errutil.ErrTraitPreCheck))
+	assert.Contains(t, err.Error(), "Failed to disable 1 unit(s)")
+	var disabled int
+	for _, h := range []string{"mock-1", "mock-2"} {
+		for _, cmd := range mc.Host(h).Commands() {
+			if strings.HasPrefix(cmd, "systemctl daemon-reload && systemctl disable ") {
+				disabled++
+			}
+		}
+	}
+	assert.Equal(t, 6, disabled)
+}
+
+func TestStartDryRun(t *testing.T) {
+	m, mc, _, cleanup := newTestMockCluster(t, 2)
+	defer cleanup()
+
+	// nothing is executed on the hosts or recorded in the history
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10, PlanFormat: task.PlanFormatText}
+	require.Nil(t, m.StartCluster("mock", opt))
+	require.Nil(t, m.StopCluster("mock", opt))
+	require.Nil(t, m.RestartCluster("mock", opt))
+	for _, h := range []string{"mock-1", "mock-2"} {
+		assert.Empty(t, mc.Host(h).Commands())
+	}
+	history, err := m.readHistory("mock")
+	require.Nil(t, err)
+	assert.Empty(t, history)
+}
+
+// releaseRepository is a repository knowing the releases of the components
+type releaseRepository struct {
+	clusterutil.Repository
+	notes map[string][2]string // component:version -> release date and notes
+}
+
+func (r *releaseRepository) ComponentReleaseInfo(comp, version string) (string, string, error) {
+	info, ok := r.notes[comp+":"+version]
+	if !ok {
+		return "", "", fmt.Errorf("%s %s not found", comp, version)
+	}
+	return info[0], info[1], nil
+}
+
+func TestReleaseInfoRow(t *testing.T) {
+	repo := &releaseRepository{notes: map[string][2]string{
+		"tidb:v4.0.1": {"2020-06-12T21:21:12+08:00", "https://docs.pingcap.com/tidb/v4.0/release-4.0.1"},
+		"tikv:v4.0.1": {"2020-06-12T21:21:12+08:00", ""},
+	}}
+	assert.Equal(t,
+		[]string{"tidb", "v4.0.1", "2020-06-12T21:21:12+08:00", "https://docs.pingcap.com/tidb/v4.0/release-4.0.1"},
+		releaseInfoRow(repo, componentInfo{component: "tidb", version: "v4.0.1"}))
+	assert.Equal(t,
+		[]string{"tikv", "v4.0.1", "2020-06-12T21:21:12+08:00", ""},
+		releaseInfoRow(repo, componentInfo{component: "tikv", version: "v4.0.1"}))
+
+	// the columns are left empty if the release is unknown
+	assert.Equal(t, []string{"pd", "v4.0.1", "", ""}, releaseInfoRow(repo, componentInfo{component: "pd", version: "v4.0.1"}))
+	assert.Equal(t, []string{"tidb", "v4.0.1", "", ""}, releaseInfoRow(nil, componentInfo{component: "tidb", version: "v4.0.1"}))
+}
+
+func TestRefreshMonitoredConfigMessages(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+
+	monitored := &spec.MonitoredOptions{NodeExporterPort: 9100, BlackboxExporterPort: 9115}
+	tasks := refreshMonitoredConfigTask(m.specManager, "test", "v4.0.0",
+		map[string]hostInfo{"10.0.0.1": {ssh: 22}}, spec.GlobalOptions{User: "tidb"}, monitored, 5, false)
+	require.Len(t, tasks, 2)
+	// the steps carry the IDs of the agents, not only the hosts
+	var instances []string
+	for _, step := range tasks {
+		instances = append(instances, step.Message().Params["instance"])
+	}
+	assert.Equal(t, []string{"10.0.0.1:9100", "10.0.0.1:9115"}, instances)
+}
@@ -0,0 +1,315 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/meta"
+)
+
+var (
+	errNSMigrate = errorx.NewNamespace("migrate_data_dir")
+	// ErrMigrateInvalidInstance is returned when the data directory of the instance can't be migrated
+	ErrMigrateInvalidInstance = errNSMigrate.NewType("invalid_instance", errutil.ErrTraitPreCheck)
+	// ErrMigrateTargetNotEmpty is returned when the new data directory contains files
+	ErrMigrateTargetNotEmpty = errNSMigrate.NewType("target_not_empty", errutil.ErrTraitPreCheck)
+	// ErrMigrateNoSpace is returned when the data doesn't fit in the filesystem of the new data directory
+	ErrMigrateNoSpace = errNSMigrate.NewType("no_space", errutil.ErrTraitPreCheck)
+	// ErrMigrateFailed is returned when the migration failed and is rolled back
+	ErrMigrateFailed = errNSMigrate.NewType("failed", errutil.ErrTraitPreCheck)
+)
+
+const (
+	// DefaultMigrateVerifySamples is the default number of files whose checksums are spot-checked
+	DefaultMigrateVerifySamples = 16
+	// migrateProgressInterval is how often the progress of copying data is reported
+	migrateProgressInterval = 5 * time.Second
+)
+
+// MigrateDataDirOptions contains the options for migrating the data directory of an instance
+type MigrateDataDirOptions struct {
+	VerifySamples int // the files spot-checked after copying, 0 disables the verification
+	SkipConfirm   bool
+}
+
+// dataDirSetter is implemented by the topologies whose data directories can be changed
+type dataDirSetter interface {
+	SetDataDir(inst spec.Instance, dir string) error
+}
+
+// MigrateDataDir moves the data directory of the instance to newDir on its
+// host. The instance is stopped, with its leaders evicted unless gOpt.Force is
+// set, then the data is renamed if both directories are on the same
+// filesystem, or copied and spot-checked otherwise. The config and the unit
+// of the instance are regenerated for the new directory before it's started
+// and its health is verified. If any of these fails, the data, the spec and
+// the config are rolled back and the instance is started in place. The old
+// directory is kept after copying, and it's up to the user to remove it.
+func (m *Manager) MigrateDataDir(clusterName, instanceID, newDir string, opt MigrateDataDirOptions, gOpt operator.Options) (err error) {
+	metadata, err := m.meta(clusterName)
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+		return perrs.AddStack(err)
+	}
+
+	topo := metadata.GetTopology()
+	base := metadata.GetBaseMeta()
+	if err := checkScopedSudo(clusterName, base, "migrate the data dir of"); err != nil {
+		return err
+	}
+
+	setter, ok := topo.(dataDirSetter)
+	if !ok {
+		return ErrMigrateInvalidInstance.New("The data directories of cluster `%s` can't be migrated", clusterName)
+	}
+	inst := findInstance(topo, instanceID)
+	if inst == nil {
+		return ErrMigrateInvalidInstance.New("Instance %s is not found in cluster `%s`", instanceID, clusterName).
+			WithProperty(cliutil.SuggestionFromFormat("Please check the instances by `%s display %s`.", cliutil.OsArgs0(), clusterName))
+	}
+	dataDirs := clusterutil.MultiDirAbs(base.User, inst.DataDir())
+	if len(dataDirs) != 1 {
+		return ErrMigrateInvalidInstance.New("Instance %s has %d data directories, only the one having exactly one could be migrated",
+			instanceID, len(dataDirs))
+	}
+	from := filepath.Clean(dataDirs[0])
+	to := filepath.Clean(clusterutil.Abs(base.User, newDir))
+	if to == from || strings.HasPrefix(to, from+"/") || strings.HasPrefix(from, to+"/") {
+		return ErrMigrateInvalidInstance.New("The new data directory %s of instance %s overlaps the current one %s", to, instanceID, from)
+	}
+
+	op, err := m.beginOperation(clusterName, OperationMigrateDataDir, opt, gOpt)
+	if err != nil {
+		return err
+	}
+	defer func() { m.endOperation(op, err) }()
+
+	var migration *operator.DataDirMigration
+	ctx := op.newTaskContext()
+	t := task.NewBuilder().
+		SSHKeySet(
+			m.specManager.Path(clusterName, "ssh", "id_rsa"),
+			m.specManager.Path(clusterName, "ssh", "id_rsa.pub")).
+		ClusterSSH(topo, base.User, gOpt.SSHTimeout, gOpt.NativeSSH).
+		Func("ProbeDataDir", func(ctx *task.Context) error {
+			e, _ := ctx.GetExecutor(inst.GetHost())
+			var err error
+			migration, err = operator.ProbeDataDirMigration(e, inst, from, to)
+			return err
+		}).
+		Build()
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			return err
+		}
+		return perrs.Trace(err)
+	}
+
+	if !migration.EmptyTarget {
+		return ErrMigrateTargetNotEmpty.New("The new data directory %s of instance %s is not empty", to, instanceID).
+			WithProperty(cliutil.SuggestionFromString("Please specify a directory which doesn't exist or is empty."))
+	}
+	if !migration.Rename && migration.Avail < migration.Size {
+		return ErrMigrateNoSpace.New("%.1f MiB of data doesn't fit in %s, only %.1f MiB available",
+			float64(migration.Size)/(1<<20), to, float64(migration.Avail)/(1<<20))
+	}
+
+	how := "copied"
+	if migration.Rename {
+		how = "renamed, as both are on the same filesystem"
+	}
+	log.Infof("The data of %s (%.1f MiB) will be %s from %s to %s", instanceID, float64(migration.Size)/(1<<20), how, from, to)
+	if !opt.SkipConfirm {
+		if err := cliutil.PromptForConfirmOrAbortError(
+			"Instance %s will be stopped during the migration.\nDo you want to continue? [y/N]:", instanceID); err != nil {
+			return err
+		}
+	}
+
+	t = task.NewBuilder().
+		Func("MigrateDataDir", func(ctx *task.Context) error {
+			return m.migrateDataDir(ctx, clusterName, topo, base, setter, inst, migration, opt, gOpt)
+		}).
+		Build()
+	if err := t.Execute(ctx); err != nil {
+		op.setResult(migration)
+		if errorx.Cast(err) != nil {
+			return err
+		}
+		return perrs.Trace(err)
+	}
+	op.setResult(migration)
+
+	if err := m.specManager.SaveMeta(clusterName, metadata); err != nil {
+		return ErrMigrateFailed.Wrap(err, "Failed to save the new data directory of %s", instanceID).WithProperty(cliutil.SuggestionFromFormat(
+			"The data has been migrated, please set the data_dir of %s to %s by `%s edit-config %s`.",
+			instanceID, to, cliutil.OsArgs0(), clusterName))
+	}
+
+	if !migration.Rename {
+		log.Infof("The data in %s is kept, it could be removed once the instance is verified", from)
+	}
+	log.Infof("Migrated the data directory of %s to %s successfully", instanceID, to)
+	return nil
+}
+
+// migrateDataDir stops the instance, migrates its data and starts it with the
+// new data directory, everything is rolled back on failure
+func (m *Manager) migrateDataDir(ctx *task.Context, clusterName string, topo spec.Topology, base *spec.BaseMeta,
+	setter dataDirSetter, inst spec.Instance, migration *operator.DataDirMigration, opt MigrateDataDirOptions, gOpt operator.Options) error {
+	e, _ := ctx.GetExecutor(inst.GetHost())
+	insts := []spec.Instance{inst}
+
+	var rollingInstance spec.RollingUpdateInstance
+	var isRollingInstance bool
+	if !gOpt.Force {
+		rollingInstance, isRollingInstance = inst.(spec.RollingUpdateInstance)
+	}
+	postRestart := func() {
+		if !isRollingInstance {
+			return
+		}
+		if err := rollingInstance.PostRestart(topo); err != nil {
+			log.Warnf("Failed to clean up after restarting %s: %s", inst.ID(), err)
+		}
+	}
+
+	if isRollingInstance {
+		if err := rollingInstance.PreRestart(topo, int(gOpt.APITimeout)); err != nil {
+			return perrs.AddStack(err)
+		}
+	}
+	if err := operator.StopComponent(ctx, insts, gOpt.OptTimeout); err != nil {
+		postRestart()
+		return perrs.Annotatef(err, "failed to stop %s", inst.ID())
+	}
+
+	oldDir := inst.DataDir()
+	copied := false
+	// rollback restores the data, the spec and the config of the instance,
+	// and starts it in place. running is the instance started with the new
+	// data directory, which is stopped first.
+	rollback := func(cause error, running spec.Instance) error {
+		log.Warnf("Rolling back the migration of %s: %s", inst.ID(), cause)
+		var failures []string
+		if running != nil {
+			if err := operator.StopComponent(ctx, []spec.Instance{running}, gOpt.OptTimeout); err != nil {
+				failures = append(failures, err.Error())
+			}
+		}
+		if copied {
+			if err := operator.RevertDataDir(e, migration); err != nil {
+				failures = append(failures, err.Error())
+			}
+		}
+		if err := setter.SetDataDir(inst, oldDir); err != nil {
+			failures = append(failures, err.Error())
+		}
+		if err := m.refreshInstanceConfig(ctx, clusterName, base, inst, gOpt); err != nil {
+			failures = append(failures, err.Error())
+		}
+		if err := operator.StartComponent(ctx, insts, base.User, gOpt); err != nil {
+			failures = append(failures, err.Error())
+		}
+		postRestart()
+
+		if len(failures) > 0 {
+			return ErrMigrateFailed.Wrap(cause, "Failed to migrate the data directory of %s, and failed to roll back: %s",
+				inst.ID(), strings.Join(failures, "; ")).
+				WithProperty(cliutil.SuggestionFromFormat("The data of %s is in %s, please check the instance before retrying.",
+					inst.ID(), migration.From))
+		}
+		return ErrMigrateFailed.Wrap(cause, "Failed to migrate the data directory of %s, it's rolled back", inst.ID())
+	}
+
+	log.Infof("Migrating the data of %s to %s", inst.ID(), migration.To)
+	copied = true
+	if err := operator.CopyDataDir(ctx, migration, migrateProgressInterval); err != nil {
+		return rollback(err, nil)
+	}
+	if err := operator.VerifyDataDirCopy(e, migration, opt.VerifySamples); err != nil {
+		return rollback(err, nil)
+	}
+
+	if err := setter.SetDataDir(inst, migration.To); err != nil {
+		return rollback(err, nil)
+	}
+	migrated := findInstance(topo, inst.ID())
+	if migrated == nil {
+		return rollback(fmt.Errorf("instance %s is not found after changing its data directory", inst.ID()), nil)
+	}
+	if err := m.refreshInstanceConfig(ctx, clusterName, base, migrated, gOpt); err != nil {
+		return rollback(err, nil)
+	}
+	if err := operator.StartComponent(ctx, []spec.Instance{migrated}, base.User, gOpt); err != nil {
+		return rollback(err, migrated)
+	}
+
+	// the port being open doesn't mean the instance works with the data
+	var results []spec.ProbeResult
+	if mc := m.mockCluster(clusterName); mc != nil {
+		results = mc.probe([]spec.Instance{migrated})
+	} else {
+		results = spec.ProbeInstances([]spec.Instance{migrated}, nil, 1, topo.BaseTopo().MasterList...)
+	}
+	if status := results[0].Status; status != "-" && !isHealthyStatus(status) {
+		return rollback(fmt.Errorf("instance %s is %s after migration: %s", inst.ID(), status, results[0].Reason), migrated)
+	}
+	postRestart()
+	return nil
+}
+
+// refreshInstanceConfig regenerates the config, the systemd unit and the run
+// script of the instance
+func (m *Manager) refreshInstanceConfig(ctx *task.Context, clusterName string, base *spec.BaseMeta, inst spec.Instance, gOpt operator.Options) error {
+	return task.NewBuilder().
+		RefreshConfig(clusterName,
+			base.ComponentVersion(inst.ComponentName()),
+			m.specManager,
+			inst, base.User,
+			gOpt.IgnoreConfigCheck,
+			meta.DirPaths{
+				Deploy: clusterutil.Abs(base.User, inst.DeployDir()),
+				Data:   clusterutil.MultiDirAbs(base.User, inst.DataDir()),
+				Log:    clusterutil.Abs(base.User, inst.LogDir()),
+				Cache:  m.specManager.Path(clusterName, spec.TempConfigPath),
+			},
+			nil).
+		Build().
+		Execute(ctx)
+}
+
+// findInstance returns the instance of the id in the topology, nil if it's not found
+func findInstance(topo spec.Topology, id string) spec.Instance {
+	var found spec.Instance
+	topo.IterInstance(func(inst spec.Instance) {
+		if inst.ID() == id {
+			found = inst
+		}
+	})
+	return found
+}
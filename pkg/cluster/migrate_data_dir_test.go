@@ -0,0 +1,90 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateDataDir(t *testing.T) {
+	m, mc, _, cleanup := newTestMockCluster(t, 2)
+	defer cleanup()
+	dataDir := func(id string) string {
+		metadata, err := m.metaFresh("mock")
+		require.Nil(t, err)
+		inst := findInstance(metadata.GetTopology(), id)
+		require.NotNil(t, inst)
+		return clusterutil.Abs("tidb", inst.DataDir())
+	}
+	hasCommand := func(host, prefix string) bool {
+		for _, cmd := range mc.Host(host).Commands() {
+			if strings.HasPrefix(cmd, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	opt := MigrateDataDirOptions{VerifySamples: DefaultMigrateVerifySamples, SkipConfirm: true}
+	gOpt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10, IgnoreConfigCheck: true, Force: true}
+	require.Nil(t, m.StartCluster("mock", gOpt))
+	old := dataDir("mock-1:20160")
+
+	// the data is copied to another filesystem and spot-checked
+	mc.Host("mock-1").Respond("from=", "size=1024\navail=4096\nsame_fs=0\nempty=1\nrsync=1\n", "")
+	mc.Host("mock-1").Respond("cd "+old, "checked=./a.sst\nchecked=./b.sst\n", "")
+	require.Nil(t, m.MigrateDataDir("mock", "mock-1:20160", "/data2/tikv", opt, gOpt))
+	assert.Equal(t, "/data2/tikv", dataDir("mock-1:20160"))
+	assert.True(t, hasCommand("mock-1", "mkdir -p /data2/tikv && rsync -a "+old+"/ /data2/tikv/"))
+	assert.True(t, mc.Host("mock-1").Active("tikv-20160.service"))
+
+	// the mismatched copy is removed, and the instance is started in place
+	mc.Host("mock-1").Respond("from=", "size=1024\navail=4096\nsame_fs=0\nempty=1\nrsync=0\n", "")
+	mc.Host("mock-1").Respond("cd /data2/tikv", "checked=./a.sst\nmismatched=./a.sst\n", "")
+	err := m.MigrateDataDir("mock", "mock-1:20160", "/data3/tikv", opt, gOpt)
+	require.NotNil(t, err)
+	assert.True(t, errorx.IsOfType(err, ErrMigrateFailed))
+	assert.True(t, hasCommand("mock-1", "rm -rf /data3/tikv"))
+	assert.Equal(t, "/data2/tikv", dataDir("mock-1:20160"))
+	assert.True(t, mc.Host("mock-1").Active("tikv-20160.service"))
+
+	// the renamed data is moved back if the instance fails to start
+	old = dataDir("mock-2:20160")
+	mc.Host("mock-2").Respond("from=", "size=1024\navail=0\nsame_fs=1\nempty=1\nrsync=1\n", "")
+	mc.SetStatus("mock-2:20160", "Down")
+	err = m.MigrateDataDir("mock", "mock-2:20160", "/home/tidb/data2/tikv", opt, gOpt)
+	require.NotNil(t, err)
+	assert.True(t, errorx.IsOfType(err, ErrMigrateFailed))
+	assert.True(t, hasCommand("mock-2", "[ -e "+old+" ] || mv /home/tidb/data2/tikv "+old))
+	assert.Equal(t, old, dataDir("mock-2:20160"))
+
+	// the pre-checks
+	mc.Host("mock-2").Respond("from=", "size=4096\navail=1024\nsame_fs=0\nempty=1\nrsync=1\n", "")
+	err = m.MigrateDataDir("mock", "mock-2:20160", "/data2/tikv", opt, gOpt)
+	assert.True(t, errorx.IsOfType(err, ErrMigrateNoSpace))
+	mc.Host("mock-2").Respond("from=", "size=1024\navail=4096\nsame_fs=0\nempty=0\nrsync=1\n", "")
+	err = m.MigrateDataDir("mock", "mock-2:20160", "/data2/tikv", opt, gOpt)
+	assert.True(t, errorx.IsOfType(err, ErrMigrateTargetNotEmpty))
+	err = m.MigrateDataDir("mock", "mock-2:4000", "/data2/tidb", opt, gOpt)
+	assert.True(t, errorx.IsOfType(err, ErrMigrateInvalidInstance))
+	err = m.MigrateDataDir("mock", "mock-2:20160", old+"/sub", opt, gOpt)
+	assert.True(t, errorx.IsOfType(err, ErrMigrateInvalidInstance))
+}
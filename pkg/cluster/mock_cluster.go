@@ -0,0 +1,187 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/yaml.v2"
+)
+
+// MockCluster is a cluster whose hosts are simulated in process by the fake
+// executors, the operations on it run through the whole stack of manager,
+// tasks and operators without real hosts, for developing and testing. The
+// statuses of instances are the ones of their services on the fake hosts,
+// unless they are scripted by SetStatus.
+type MockCluster struct {
+	Name string
+
+	mu       sync.Mutex
+	hosts    map[string]*executor.Fake
+	statuses map[string]string // the scripted statuses by the IDs of instances
+	hostKeys map[string]string // the seeds of the scripted host keys by hosts
+}
+
+// MockClusterMeta returns the metadata of a TiDB cluster of n mock hosts,
+// named mock-1 to mock-n, each of them runs a PD, a TiKV and a TiDB
+func MockClusterMeta(n int) (*spec.ClusterMeta, error) {
+	if n < 1 {
+		return nil, perrs.Errorf("a mock cluster needs at least one host, got %d", n)
+	}
+	var pd, tikv, tidb []string
+	for i := 1; i <= n; i++ {
+		host := fmt.Sprintf("  - host: mock-%d", i)
+		pd, tikv, tidb = append(pd, host), append(tikv, host), append(tidb, host)
+	}
+	data := fmt.Sprintf("pd_servers:\n%s\ntikv_servers:\n%s\ntidb_servers:\n%s\n",
+		strings.Join(pd, "\n"), strings.Join(tikv, "\n"), strings.Join(tidb, "\n"))
+
+	topo := new(spec.Specification)
+	if err := yaml.UnmarshalStrict([]byte(data), topo); err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	return &spec.ClusterMeta{
+		User:     "tidb",
+		Version:  "v4.0.0",
+		Topology: topo,
+	}, nil
+}
+
+// NewMockCluster saves the cluster of the metadata, and simulates its hosts
+// with the fake executors in process, see MockClusterMeta for a topology.
+// The cluster is mocked only by this manager, for other processes it's just
+// a cluster with the hosts unreachable.
+func (m *Manager) NewMockCluster(name string, metadata spec.Metadata) (*MockCluster, error) {
+	if err := m.specManager.SaveMeta(name, metadata); err != nil {
+		return nil, err
+	}
+	mc := &MockCluster{
+		Name:     name,
+		hosts:    make(map[string]*executor.Fake),
+		statuses: make(map[string]string),
+		hostKeys: make(map[string]string),
+	}
+	metadata.GetTopology().IterInstance(func(inst spec.Instance) {
+		mc.hosts[inst.GetHost()] = executor.NewFake(inst.GetHost())
+	})
+
+	m.mocks.Lock()
+	if m.mocks.clusters == nil {
+		m.mocks.clusters = make(map[string]*MockCluster)
+	}
+	m.mocks.clusters[name] = mc
+	m.mocks.Unlock()
+	return mc, nil
+}
+
+// mockCluster returns the mock cluster of the name, nil if it's not mocked
+func (m *Manager) mockCluster(name string) *MockCluster {
+	m.mocks.Lock()
+	defer m.mocks.Unlock()
+	return m.mocks.clusters[name]
+}
+
+// Host returns the fake executor simulating the host, nil if the host isn't
+// in the cluster
+func (mc *MockCluster) Host(host string) *executor.Fake {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.hosts[host]
+}
+
+// SetStatus scripts the status of the instance, e.g., "Down" or "Up|L", an
+// empty status makes it the one of the service again
+func (mc *MockCluster) SetStatus(id, status string) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if status == "" {
+		delete(mc.statuses, id)
+		return
+	}
+	mc.statuses[id] = status
+}
+
+// SetHostKey scripts the SSH host key the host presents by the seed it's
+// generated from, e.g., to simulate the host being rebuilt, the key is
+// generated from the host name by default
+func (mc *MockCluster) SetHostKey(host, seed string) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.hostKeys[host] = seed
+}
+
+// mockHostKey returns the SSH host key generated from the seed, see SetHostKey
+func mockHostKey(seed string) ssh.PublicKey {
+	s := sha256.Sum256([]byte(seed))
+	key, err := ssh.NewPublicKey(ed25519.NewKeyFromSeed(s[:]).Public())
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// hostKey returns the host key the host presents
+func (mc *MockCluster) hostKey(cfg executor.SSHConfig) (ssh.PublicKey, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if seed, ok := mc.hostKeys[cfg.Host]; ok {
+		return mockHostKey(seed), nil
+	}
+	return mockHostKey(cfg.Host), nil
+}
+
+// executorFactory connects the hosts of the cluster with the fake executors
+func (mc *MockCluster) executorFactory(cfg executor.SSHConfig, sudo, native bool) executor.Executor {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	e, ok := mc.hosts[cfg.Host]
+	if !ok {
+		// e.g., the hosts scaled out
+		e = executor.NewFake(cfg.Host)
+		mc.hosts[cfg.Host] = e
+	}
+	return e
+}
+
+// probe returns the scripted statuses of the instances, the unknown ones
+// "-" are queried from the services
+func (mc *MockCluster) probe(insts []spec.Instance) []spec.ProbeResult {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	results := make([]spec.ProbeResult, len(insts))
+	for i, inst := range insts {
+		results[i].Status = "-"
+		if status, ok := mc.statuses[inst.ID()]; ok {
+			results[i].Status = status
+		}
+	}
+	return results
+}
+
+// applyMock makes the context connect the hosts of the cluster with the fake
+// executors if the cluster is mocked
+func (m *Manager) applyMock(clusterName string, ctx *task.Context) {
+	if mc := m.mockCluster(clusterName); mc != nil {
+		ctx.SetExecutorFactory(mc.executorFactory)
+	}
+}
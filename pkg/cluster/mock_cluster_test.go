@@ -0,0 +1,97 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestManager returns a manager of the clusters under a temporary
+// directory, which is removed by the cleanup function
+func newTestManager(t *testing.T) (*Manager, string, func()) {
+	dir, err := ioutil.TempDir("", "tiup-cluster-test")
+	require.Nil(t, err)
+	specManager := spec.NewSpec(dir, func() spec.Metadata {
+		return &spec.ClusterMeta{Topology: new(spec.Specification)}
+	})
+	m := NewManager("tidb", specManager, spec.TiDBComponentVersion)
+	return m, dir, func() { os.RemoveAll(dir) }
+}
+
+// newTestMockCluster returns a manager of a temporary directory, and the
+// mock cluster of n hosts named "mock" with its metadata
+func newTestMockCluster(t *testing.T, n int) (*Manager, *MockCluster, *spec.ClusterMeta, func()) {
+	m, _, cleanup := newTestManager(t)
+	metadata, err := MockClusterMeta(n)
+	require.Nil(t, err)
+	mc, err := m.NewMockCluster("mock", metadata)
+	require.Nil(t, err)
+	return m, mc, metadata, cleanup
+}
+
+func TestMockCluster(t *testing.T) {
+	m, mc, _, cleanup := newTestMockCluster(t, 3)
+	defer cleanup()
+
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10}
+	statuses := func() map[string]string {
+		insts, err := m.InstanceStatuses("mock", opt)
+		require.Nil(t, err)
+		require.Len(t, insts, 9)
+		m := make(map[string]string)
+		for _, inst := range insts {
+			m[inst.ID] = inst.Status
+		}
+		return m
+	}
+
+	require.Nil(t, m.StartCluster("mock", opt))
+	assert.True(t, mc.Host("mock-2").Active("tikv-20160.service"))
+	assert.True(t, mc.Host("mock-3").Active("node_exporter-9100.service"))
+	for id, status := range statuses() {
+		assert.Equal(t, "Up", status, id)
+	}
+
+	mc.SetStatus("mock-1:2379", "Up|L")
+	mc.SetStatus("mock-3:20160", "Down")
+	st := statuses()
+	assert.Equal(t, "Up|L", st["mock-1:2379"])
+	assert.Equal(t, "Down", st["mock-3:20160"])
+	assert.Equal(t, "Up", st["mock-2:2379"])
+	mc.SetStatus("mock-3:20160", "")
+
+	require.Nil(t, m.RestartCluster("mock", opt))
+	commands := strings.Join(mc.Host("mock-1").Commands(), "\n")
+	assert.Contains(t, commands, "systemctl stop tidb-4000.service")
+	assert.Contains(t, commands, "systemctl start tidb-4000.service")
+	assert.Equal(t, "Up", statuses()["mock-3:20160"])
+
+	require.Nil(t, m.StopCluster("mock", opt))
+	assert.False(t, mc.Host("mock-1").Active("pd-2379.service"))
+	for id, status := range statuses() {
+		if id != "mock-1:2379" {
+			assert.Equal(t, "inactive", status, id)
+		}
+	}
+	assert.Equal(t, OperationStop, GetCurrentOperation("mock").Type())
+	assert.Nil(t, GetCurrentOperation("mock").Error())
+}
@@ -92,6 +92,11 @@ func NewSystemdModule(config SystemdModuleConfig) *SystemdModule {
 	return mod
 }
 
+// Command returns the command executed by the module
+func (mod *SystemdModule) Command() string {
+	return mod.cmd
+}
+
 // Execute passes the command to executor and returns its results, the executor
 // should be already initialized.
 func (mod *SystemdModule) Execute(exec executor.Executor) ([]byte, []byte, error) {
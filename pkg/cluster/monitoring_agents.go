@@ -0,0 +1,196 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/meta"
+)
+
+// RedeployAgentsOptions contains the options for redeploying monitoring agents
+type RedeployAgentsOptions struct {
+	// deploy the agents to the hosts where they were never deployed, e.g. hosts
+	// of clusters imported from TiDB-Ansible, such hosts are skipped otherwise
+	BootstrapMissing bool
+}
+
+// agentUnits returns the systemd units of the monitoring agents
+func agentUnits(monitoredOptions *spec.MonitoredOptions) []string {
+	return []string{
+		fmt.Sprintf("%s-%d.service", spec.ComponentNodeExporter, monitoredOptions.NodeExporterPort),
+		fmt.Sprintf("%s-%d.service", spec.ComponentBlackboxExporter, monitoredOptions.BlackboxExporterPort),
+	}
+}
+
+// RedeployMonitoringAgents re-renders and pushes the configs and binaries of
+// the monitoring agents (node_exporter and blackbox_exporter) to all hosts of
+// the cluster, restarts only the agents, and updates the targets of Prometheus.
+func (m *Manager) RedeployMonitoringAgents(clusterName string, opt RedeployAgentsOptions, gOpt operator.Options) (err error) {
+	metadata, err := m.meta(clusterName)
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+		return perrs.AddStack(err)
+	}
+
+	op, err := m.beginOperation(clusterName, OperationRedeployAgents, opt, gOpt)
+	if err != nil {
+		return err
+	}
+	defer func() { m.endOperation(op, err) }()
+
+	topo := metadata.GetTopology()
+	base := metadata.GetBaseMeta()
+	if err := checkScopedSudo(clusterName, base, "redeploy the monitoring agents of"); err != nil {
+		return err
+	}
+	monitoredOptions := topo.GetMonitoredOptions()
+	if monitoredOptions == nil {
+		log.Infof("No monitoring agents in cluster `%s`", clusterName)
+		return nil
+	}
+	units := agentUnits(monitoredOptions)
+
+	uniqueHosts := make(map[string]hostInfo) // host -> ssh-port, os, arch
+	topo.IterInstance(func(inst spec.Instance) {
+		if !monitoredOptions.DeployAgents(inst.GetHost()) {
+			return
+		}
+		if _, found := uniqueHosts[inst.GetHost()]; !found {
+			uniqueHosts[inst.GetHost()] = hostInfo{
+				ssh:  inst.GetSSHPort(),
+				os:   inst.OS(),
+				arch: inst.Arch(),
+			}
+		}
+	})
+
+	// detect the hosts where the agents were never deployed by their units
+	var detectTasks []*task.StepDisplay
+	for host, info := range uniqueHosts {
+		detectTasks = append(detectTasks, task.NewBuilder().
+			UserSSH(host, info.ssh, base.User, gOpt.SSHTimeout, gOpt.NativeSSH).
+			Shell(host, fmt.Sprintf("test -e /etc/systemd/system/%s && echo deployed || echo missing", units[0]), false).
+			BuildAsStepMessage(task.MsgDetectMonitorAgents, task.MessageParams{"host": host}))
+	}
+	ctx := op.newTaskContext()
+	if err := task.NewBuilder().
+		SSHKeySet(
+			m.specManager.Path(clusterName, "ssh", "id_rsa"),
+			m.specManager.Path(clusterName, "ssh", "id_rsa.pub")).
+		ParallelStep("+ Detect monitoring agents", detectTasks...).
+		Build().
+		Execute(ctx); err != nil {
+		return err
+	}
+
+	targetHosts := make(map[string]hostInfo)
+	var skipped []string
+	for host, info := range uniqueHosts {
+		stdout, _, _ := ctx.GetOutputs(host)
+		if strings.TrimSpace(string(stdout)) != "deployed" && !opt.BootstrapMissing {
+			skipped = append(skipped, host)
+			continue
+		}
+		targetHosts[host] = info
+	}
+	if len(skipped) > 0 {
+		sort.Strings(skipped)
+		log.Warnf("Monitoring agents were never deployed on %s, they are skipped", strings.Join(skipped, ", "))
+	}
+	if len(targetHosts) == 0 {
+		return nil
+	}
+
+	connect := func(b *task.Builder, host string, port int) *task.Builder {
+		return b.UserSSH(host, port, base.User, gOpt.SSHTimeout, gOpt.NativeSSH)
+	}
+	downloadTasks, deployTasks := buildMonitoredDeployTask(
+		m.bindVersion,
+		m.specManager,
+		clusterName,
+		targetHosts,
+		topo.BaseTopo().GlobalOptions,
+		monitoredOptions,
+		base.Version,
+		connect,
+	)
+
+	var restartTasks []*task.StepDisplay
+	for host, info := range targetHosts {
+		cmds := []string{"systemctl daemon-reload"}
+		for _, unit := range units {
+			cmds = append(cmds, "systemctl enable "+unit, "systemctl restart "+unit)
+		}
+		restartTasks = append(restartTasks, connect(task.NewBuilder(), host, info.ssh).
+			Shell(host, strings.Join(cmds, " && "), true).
+			BuildAsStepMessage(task.MsgRestartMonitorAgent, task.MessageParams{"host": host}))
+	}
+
+	// the targets of Prometheus are rendered from the topology
+	var promConfigTasks []*task.StepDisplay
+	topo.IterInstance(func(inst spec.Instance) {
+		if inst.ComponentName() != spec.ComponentPrometheus {
+			return
+		}
+		promConfigTasks = append(promConfigTasks, connect(task.NewBuilder(), inst.GetHost(), inst.GetSSHPort()).
+			InitConfig(clusterName,
+				base.Version,
+				m.specManager,
+				inst,
+				base.User,
+				gOpt.IgnoreConfigCheck,
+				meta.DirPaths{
+					Deploy: clusterutil.Abs(base.User, inst.DeployDir()),
+					Data:   clusterutil.MultiDirAbs(base.User, inst.DataDir()),
+					Log:    clusterutil.Abs(base.User, inst.LogDir()),
+					Cache:  m.specManager.Path(clusterName, spec.TempConfigPath),
+				}).
+			BuildAsStepMessage(task.MsgRefreshConfig, task.MessageParams{"component": inst.ComponentName(), "instance": inst.ID()}))
+	})
+
+	tb := task.NewBuilder().
+		ParallelStep("+ Download monitoring agents", downloadTasks...).
+		ParallelStep("+ Copy monitoring agents", deployTasks...).
+		ParallelStep("+ Restart monitoring agents", restartTasks...)
+	if len(promConfigTasks) > 0 {
+		promOpt := gOpt
+		promOpt.Roles = []string{spec.ComponentPrometheus}
+		promOpt.Nodes = nil
+		tb.ParallelStep("+ Refresh Prometheus configs", promConfigTasks...).
+			Func("RestartPrometheus", func(ctx *task.Context) error {
+				return operator.Restart(ctx, topo, promOpt)
+			})
+	}
+
+	if err := tb.Build().Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return perrs.Trace(err)
+	}
+
+	log.Infof("Redeployed monitoring agents of cluster `%s` successfully", clusterName)
+	return nil
+}
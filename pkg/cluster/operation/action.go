@@ -16,83 +16,62 @@ package operator
 import (
 	"bytes"
 	"context"
+	stderrors "errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
 	"github.com/pingcap/tiup/pkg/cluster/module"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/logger/log"
-	"github.com/pingcap/tiup/pkg/set"
 	"golang.org/x/sync/errgroup"
 )
 
-// Start the cluster.
+// Start the cluster, see ExplainStart for what is done.
 func Start(
 	getter ExecutorGetter,
 	cluster spec.Topology,
 	options Options,
 ) error {
-	uniqueHosts := set.NewStringSet()
-	roleFilter := set.NewStringSet(options.Roles...)
-	nodeFilter := set.NewStringSet(options.Nodes...)
-	components := cluster.ComponentsByStartOrder()
-	components = FilterComponent(components, roleFilter)
-
-	for _, com := range components {
-		insts := FilterInstance(com.Instances(), nodeFilter)
-		err := StartComponent(getter, insts, options)
-		if err != nil {
-			return errors.Annotatef(err, "failed to start %s", com.Name())
-		}
-		for _, inst := range insts {
-			if !uniqueHosts.Exist(inst.GetHost()) {
-				uniqueHosts.Insert(inst.GetHost())
-				if cluster.GetMonitoredOptions() != nil {
-					if err := StartMonitored(getter, inst, cluster.GetMonitoredOptions(), options.OptTimeout); err != nil {
-						return err
-					}
+	groups := startGroups(cluster, options)
+	for i, g := range groups {
+		reportProgress(getter, i, len(groups))
+		if g.Agents {
+			for _, inst := range g.instances {
+				if err := StartMonitored(getter, inst, cluster.GetMonitoredOptions(), options.OptTimeout); err != nil {
+					return err
 				}
 			}
+			continue
+		}
+		if err := StartComponent(getter, g.instances, cluster.BaseTopo().GlobalOptions.User, options); err != nil {
+			return errors.Annotatef(err, "failed to start %s", g.Component)
 		}
 	}
-
 	return nil
 }
 
-// Stop the cluster.
+// Stop the cluster, see ExplainStop for what is done.
 func Stop(
 	getter ExecutorGetter,
 	cluster spec.Topology,
 	options Options,
 ) error {
-	roleFilter := set.NewStringSet(options.Roles...)
-	nodeFilter := set.NewStringSet(options.Nodes...)
-	components := cluster.ComponentsByStopOrder()
-	components = FilterComponent(components, roleFilter)
-
-	instCount := map[string]int{}
-	cluster.IterInstance(func(inst spec.Instance) {
-		instCount[inst.GetHost()] = instCount[inst.GetHost()] + 1
-	})
-
-	for _, com := range components {
-		insts := FilterInstance(com.Instances(), nodeFilter)
-		err := StopComponent(getter, insts, options.OptTimeout)
-		if err != nil {
-			return errors.Annotatef(err, "failed to stop %s", com.Name())
-		}
-		for _, inst := range insts {
-			instCount[inst.GetHost()]--
-			if instCount[inst.GetHost()] == 0 {
-				if cluster.GetMonitoredOptions() != nil {
-					if err := StopMonitored(getter, inst, cluster.GetMonitoredOptions(), options.OptTimeout); err != nil {
-						return err
-					}
+	for _, g := range StopGroups(cluster, options) {
+		if g.Agents {
+			for _, inst := range g.instances {
+				if err := StopMonitored(getter, inst, cluster.GetMonitoredOptions(), options.OptTimeout); err != nil {
+					return err
 				}
 			}
+			continue
+		}
+		if err := stopComponent(getter, g.instances, options.OptTimeout, options.Serial); err != nil {
+			return errors.Annotatef(err, "failed to stop %s", g.Component)
 		}
 	}
 	return nil
@@ -343,20 +322,18 @@ func Restart(
 
 // StartMonitored start BlackboxExporter and NodeExporter
 func StartMonitored(getter ExecutorGetter, instance spec.Instance, options *spec.MonitoredOptions, timeout int64) error {
+	if !options.DeployAgents(instance.GetHost()) {
+		return nil
+	}
 	ports := map[string]int{
 		spec.ComponentNodeExporter:     options.NodeExporterPort,
 		spec.ComponentBlackboxExporter: options.BlackboxExporterPort,
 	}
 	e := getter.Get(instance.GetHost())
-	for _, comp := range []string{spec.ComponentNodeExporter, spec.ComponentBlackboxExporter} {
+	for _, comp := range agentComponents {
 		log.Infof("Starting component %s", comp)
 		log.Infof("\tStarting instance %s", instance.GetHost())
-		c := module.SystemdModuleConfig{
-			Unit:         fmt.Sprintf("%s-%d.service", comp, ports[comp]),
-			ReloadDaemon: true,
-			Action:       "start",
-			Timeout:      time.Second * time.Duration(timeout),
-		}
+		c := systemdConfig(agentUnit(comp, options), "start", false, time.Second*time.Duration(timeout))
 		systemd := module.NewSystemdModule(c)
 		stdout, stderr, err := systemd.Execute(e)
 
@@ -372,6 +349,7 @@ func StartMonitored(getter ExecutorGetter, instance spec.Instance, options *spec
 		}
 
 		// Check ready.
+		reportWaiting(getter, "waiting for port %d", ports[comp])
 		if err := spec.PortStarted(e, ports[comp], timeout); err != nil {
 			str := fmt.Sprintf("\t%s failed to start: %s", instance.GetHost(), err)
 			log.Errorf(str)
@@ -384,16 +362,26 @@ func StartMonitored(getter ExecutorGetter, instance spec.Instance, options *spec
 	return nil
 }
 
-func restartInstance(getter ExecutorGetter, ins spec.Instance, timeout int64) error {
+// needDaemonReload checks if the unit file is changed on the host since
+// systemd loaded it, e.g., pushed by upgrade. Reload has reloaded systemd for
+// the units it changed, so they're restarted without reloading it again. It's
+// reloaded if the state can't be told.
+func needDaemonReload(e executor.Executor, unit string) bool {
+	stdout, _, err := e.Execute(fmt.Sprintf("systemctl show -p NeedDaemonReload %s", unit), true)
+	return err != nil || strings.TrimSpace(string(stdout)) != "NeedDaemonReload=no"
+}
+
+func restartInstance(getter ExecutorGetter, ins spec.Instance, deployUser string, options Options) error {
 	e := getter.Get(ins.GetHost())
 	log.Infof("\tRestarting instance %s", ins.GetHost())
+	timeout := pickStartTimeout(e, ins, deployUser, options)
 
 	// Restart by systemd.
 	c := module.SystemdModuleConfig{
 		Unit:         ins.ServiceName(),
-		ReloadDaemon: true,
+		ReloadDaemon: needDaemonReload(e, ins.ServiceName()),
 		Action:       "restart",
-		Timeout:      time.Second * time.Duration(timeout),
+		Timeout:      time.Second * time.Duration(timeout.seconds),
 	}
 	systemd := module.NewSystemdModule(c)
 	stdout, stderr, err := systemd.Execute(e)
@@ -406,24 +394,29 @@ func restartInstance(getter ExecutorGetter, ins spec.Instance, timeout int64) er
 	}
 
 	if err != nil {
-		return errors.Annotatef(err, "failed to restart: %s", ins.GetHost())
+		return errors.Annotatef(err, "failed to restart: %s (%s)", ins.GetHost(), timeout)
 	}
 
 	// Check ready.
-	err = ins.Ready(e, timeout)
+	reportWaiting(getter, "waiting for port %d", ins.GetPort())
+	err = ins.Ready(e, timeout.seconds)
 	if err != nil {
-		str := fmt.Sprintf("\t%s failed to restart: %s", ins.GetHost(), err)
+		str := fmt.Sprintf("\t%s failed to restart with %s: %s", ins.GetHost(), timeout, err)
 		log.Errorf(str)
 		return errors.Annotatef(err, str)
 	}
+	if err := clearFirstStart(e, ins, deployUser, timeout); err != nil {
+		return errors.Annotatef(err, "failed to clear the first start marker: %s", ins.GetHost())
+	}
 
 	log.Infof("\tRestart %s success", ins.GetHost())
 
 	return nil
 }
 
-// RestartComponent restarts the component.
-func RestartComponent(getter ExecutorGetter, instances []spec.Instance, timeout int64) error {
+// RestartComponent restarts the component, the relative dirs of the instances
+// are resolved under the home of the deploy user.
+func RestartComponent(getter ExecutorGetter, instances []spec.Instance, deployUser string, timeout int64) error {
 	if len(instances) <= 0 {
 		return nil
 	}
@@ -432,30 +425,25 @@ func RestartComponent(getter ExecutorGetter, instances []spec.Instance, timeout
 	log.Infof("Restarting component %s", name)
 
 	for _, ins := range instances {
-		err := restartInstance(getter, ins, timeout)
+		err := restartInstance(getter, ins, deployUser, Options{OptTimeout: timeout})
 		if err != nil {
-			return errors.AddStack(err)
+			return errors.AddStack(instanceFailure(ins, err))
 		}
 	}
 
 	return nil
 }
 
-func startInstance(getter ExecutorGetter, ins spec.Instance, timeout int64) error {
+func startInstance(getter ExecutorGetter, ins spec.Instance, deployUser string, options Options) error {
 	e := getter.Get(ins.GetHost())
 	log.Infof("\tStarting instance %s %s:%d",
 		ins.ComponentName(),
 		ins.GetHost(),
 		ins.GetPort())
+	timeout := pickStartTimeout(e, ins, deployUser, options)
 
 	// Start by systemd.
-	c := module.SystemdModuleConfig{
-		Unit:         ins.ServiceName(),
-		ReloadDaemon: true,
-		Action:       "start",
-		Enabled:      true,
-		Timeout:      time.Second * time.Duration(timeout),
-	}
+	c := systemdConfig(ins.ServiceName(), "start", true, time.Second*time.Duration(timeout.seconds))
 	systemd := module.NewSystemdModule(c)
 	stdout, stderr, err := systemd.Execute(e)
 
@@ -467,22 +455,30 @@ func startInstance(getter ExecutorGetter, ins spec.Instance, timeout int64) erro
 	}
 
 	if err != nil {
-		return errors.Annotatef(err, "failed to start: %s %s:%d",
+		return errors.Annotatef(err, "failed to start: %s %s:%d (%s)",
 			ins.ComponentName(),
 			ins.GetHost(),
-			ins.GetPort())
+			ins.GetPort(),
+			timeout)
 	}
 
 	// Check ready.
-	err = ins.Ready(e, timeout)
+	reportWaiting(getter, "waiting for port %d", ins.GetPort())
+	err = ins.Ready(e, timeout.seconds)
 	if err != nil {
-		str := fmt.Sprintf("\t%s %s:%d failed to start: %s, please check the log of the instance",
+		str := fmt.Sprintf("\t%s %s:%d failed to start with %s: %s, please check the log of the instance",
 			ins.ComponentName(),
 			ins.GetHost(),
-			ins.GetPort(), err)
+			ins.GetPort(), timeout, err)
 		log.Errorf(str)
 		return errors.Annotatef(err, str)
 	}
+	if err := clearFirstStart(e, ins, deployUser, timeout); err != nil {
+		return errors.Annotatef(err, "failed to clear the first start marker: %s %s:%d",
+			ins.ComponentName(),
+			ins.GetHost(),
+			ins.GetPort())
+	}
 
 	log.Infof("\tStart %s %s:%d success",
 		ins.ComponentName(),
@@ -492,8 +488,9 @@ func startInstance(getter ExecutorGetter, ins spec.Instance, timeout int64) erro
 	return nil
 }
 
-// StartComponent start the instances.
-func StartComponent(getter ExecutorGetter, instances []spec.Instance, options Options) error {
+// StartComponent start the instances, the relative dirs of the instances are
+// resolved under the home of the deploy user.
+func StartComponent(getter ExecutorGetter, instances []spec.Instance, deployUser string, options Options) error {
 	if len(instances) <= 0 {
 		return nil
 	}
@@ -501,42 +498,35 @@ func StartComponent(getter ExecutorGetter, instances []spec.Instance, options Op
 	name := instances[0].ComponentName()
 	log.Infof("Starting component %s", name)
 
-	errg, _ := errgroup.WithContext(context.Background())
-
-	for _, ins := range instances {
-		ins := ins
-
-		errg.Go(func() error {
-			if err := ins.PrepareStart(); err != nil {
-				return err
-			}
-			err := startInstance(getter, ins, options.OptTimeout)
-			if err != nil {
-				return errors.AddStack(err)
-			}
-			return nil
-		})
-	}
-
-	return errg.Wait()
+	schedule := NewStaggerSchedule(instances, options.StartStagger, options.StartStaggerJitter)
+	return forEachInstance(instances, options.Serial, func(ins spec.Instance) error {
+		schedule.Wait(getter, ins)
+		if err := ins.PrepareStart(); err != nil {
+			return instanceFailure(ins, err)
+		}
+		err := startInstance(getter, ins, deployUser, options)
+		if err != nil {
+			return errors.AddStack(instanceFailure(ins, err))
+		}
+		return nil
+	})
 }
 
 // StopMonitored stop BlackboxExporter and NodeExporter
 func StopMonitored(getter ExecutorGetter, instance spec.Instance, options *spec.MonitoredOptions, timeout int64) error {
+	// the node_exporter on the host isn't ours
+	if !options.DeployAgents(instance.GetHost()) {
+		return nil
+	}
 	ports := map[string]int{
 		spec.ComponentNodeExporter:     options.NodeExporterPort,
 		spec.ComponentBlackboxExporter: options.BlackboxExporterPort,
 	}
 	e := getter.Get(instance.GetHost())
-	for _, comp := range []string{spec.ComponentNodeExporter, spec.ComponentBlackboxExporter} {
+	for _, comp := range agentComponents {
 		log.Infof("Stopping component %s", comp)
 
-		c := module.SystemdModuleConfig{
-			Unit:         fmt.Sprintf("%s-%d.service", comp, ports[comp]),
-			Action:       "stop",
-			ReloadDaemon: true,
-			Timeout:      time.Second * time.Duration(timeout),
-		}
+		c := systemdConfig(agentUnit(comp, options), "stop", false, time.Second*time.Duration(timeout))
 		systemd := module.NewSystemdModule(c)
 		stdout, stderr, err := systemd.Execute(e)
 
@@ -582,12 +572,7 @@ func stopInstance(getter ExecutorGetter, ins spec.Instance, timeout int64) error
 	log.Infof("\tStopping instance %s", ins.GetHost())
 
 	// Stop by systemd.
-	c := module.SystemdModuleConfig{
-		Unit:         ins.ServiceName(),
-		Action:       "stop",
-		ReloadDaemon: true, // always reload before operate
-		Timeout:      time.Second * time.Duration(timeout),
-	}
+	c := systemdConfig(ins.ServiceName(), "stop", false, time.Second*time.Duration(timeout))
 	systemd := module.NewSystemdModule(c)
 	stdout, stderr, err := systemd.Execute(e)
 
@@ -624,6 +609,11 @@ func stopInstance(getter ExecutorGetter, ins spec.Instance, timeout int64) error
 
 // StopComponent stop the instances.
 func StopComponent(getter ExecutorGetter, instances []spec.Instance, timeout int64) error {
+	return stopComponent(getter, instances, timeout, false)
+}
+
+// stopComponent stops the instances at once, or one by one if serial
+func stopComponent(getter ExecutorGetter, instances []spec.Instance, timeout int64, serial bool) error {
 	if len(instances) <= 0 {
 		return nil
 	}
@@ -631,23 +621,84 @@ func StopComponent(getter ExecutorGetter, instances []spec.Instance, timeout int
 	name := instances[0].ComponentName()
 	log.Infof("Stopping component %s", name)
 
-	errg, _ := errgroup.WithContext(context.Background())
+	return forEachInstance(instances, serial, func(ins spec.Instance) error {
+		err := stopInstance(getter, ins, timeout)
+		if err != nil {
+			return errors.AddStack(instanceFailure(ins, err))
+		}
+		return nil
+	})
+}
+
+// forEachInstance calls fn for the instances concurrently, or one by one in
+// order if serial, the first error is returned
+func forEachInstance(instances []spec.Instance, serial bool, fn func(ins spec.Instance) error) error {
+	if serial {
+		for _, ins := range instances {
+			if err := fn(ins); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 
+	errg, _ := errgroup.WithContext(context.Background())
 	for _, ins := range instances {
 		ins := ins
 		errg.Go(func() error {
-
-			err := stopInstance(getter, ins, timeout)
-			if err != nil {
-				return errors.AddStack(err)
-			}
-			return nil
+			return fn(ins)
 		})
 	}
-
 	return errg.Wait()
 }
 
+// InstanceFailure is the failure of an operation on an instance, which tells
+// the instance failed without parsing the error
+type InstanceFailure struct {
+	ID  string
+	Err error
+}
+
+// instanceFailure returns err of the instance as an InstanceFailure, nil is
+// returned if err is nil
+func instanceFailure(ins spec.Instance, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &InstanceFailure{ID: ins.ID(), Err: err}
+}
+
+func (e *InstanceFailure) Error() string {
+	return e.Err.Error()
+}
+
+// Cause returns the error of the instance, so the errors are told by their
+// causes as they were
+func (e *InstanceFailure) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the error of the instance
+func (e *InstanceFailure) Unwrap() error {
+	return e.Err
+}
+
+// FailedInstance returns the ID of the instance failed with err, false is
+// returned if err isn't, or doesn't wrap, an InstanceFailure
+func FailedInstance(err error) (string, bool) {
+	for err != nil {
+		if f, ok := err.(*InstanceFailure); ok {
+			return f.ID, true
+		}
+		next := errors.Unwrap(err)
+		if next == nil {
+			next = stderrors.Unwrap(err)
+		}
+		err = next
+	}
+	return "", false
+}
+
 // PrintClusterStatus print cluster status into the io.Writer.
 func PrintClusterStatus(getter ExecutorGetter, cluster *spec.Specification) (health bool) {
 	health = true
@@ -0,0 +1,72 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"testing"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestRestartDaemonReload(t *testing.T) {
+	topo := new(spec.Specification)
+	require.Nil(t, yaml.UnmarshalStrict([]byte("tidb_servers:\n  - host: 10.0.0.1\n"), topo))
+	ins := (&spec.TiDBComponent{Specification: topo}).Instances()[0]
+	options := Options{OptTimeout: 1}
+
+	// systemd is only reloaded if the unit file is changed since loaded
+	for state, reload := range map[string]bool{
+		"NeedDaemonReload=no\n":  false,
+		"NeedDaemonReload=yes\n": true,
+		"":                       true,
+	} {
+		hosts := fakeHosts{"10.0.0.1": executor.NewFake("10.0.0.1")}
+		if state != "" {
+			hosts["10.0.0.1"].Respond("systemctl show -p NeedDaemonReload tidb-4000.service", state, "")
+		} else {
+			hosts["10.0.0.1"].Respond("systemctl show -p NeedDaemonReload tidb-4000.service", "", "timed out")
+		}
+		require.Nil(t, restartInstance(hosts, ins, "tidb", options))
+		assert.True(t, hosts["10.0.0.1"].Active("tidb-4000.service"))
+		cmd := "systemctl restart tidb-4000.service"
+		if reload {
+			cmd = "systemctl daemon-reload && " + cmd
+		}
+		assert.Contains(t, hosts["10.0.0.1"].Commands(), cmd, "state: %q", state)
+	}
+}
+
+func TestFailedInstance(t *testing.T) {
+	topo := new(spec.Specification)
+	require.Nil(t, yaml.UnmarshalStrict([]byte("tidb_servers:\n  - host: 10.0.0.1\n"), topo))
+	ins := (&spec.TiDBComponent{Specification: topo}).Instances()[0]
+	hosts := fakeHosts{"10.0.0.1": executor.NewFake("10.0.0.1")}
+	hosts["10.0.0.1"].Respond("systemctl daemon-reload && systemctl start tidb-4000.service", "", "address already in use")
+
+	err := Start(hosts, topo, Options{OptTimeout: 1})
+	require.NotNil(t, err)
+	id, ok := FailedInstance(err)
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.1:4000", id)
+	assert.Nil(t, instanceFailure(ins, nil))
+
+	// the instances mentioned in the error aren't taken as failed
+	_, ok = FailedInstance(errors.Errorf("failed to start %s", ins.ID()))
+	assert.False(t, ok)
+}
@@ -17,9 +17,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/AstroProfundis/sysinfo"
 	"github.com/pingcap/tidb-insight/collector/insight"
@@ -37,28 +39,41 @@ type CheckOptions struct {
 	EnableMem  bool
 	EnableDisk bool
 
+	// the quick disk benchmark of data directories, and its thresholds
+	EnableDiskBench      bool
+	DiskBenchMinSeqWrite float64       // minimal sequential write throughput, in MB/s (10^6 bytes)
+	DiskBenchMaxFsyncLat time.Duration // maximal average latency of a fsync'ed 4k write
+
+	// the TCP connectivity check between hosts
+	EnableConnectivity      bool
+	ConnectivityConcurrency int           // maximal number of probes running at the same time on a host
+	ConnectivityTimeout     time.Duration // timeout of each probe
+
 	// pre-defined goups of checks
 	//GroupMinimal bool // a minimal set of checks
 }
 
 // Names of checks
 var (
-	CheckNameGeneral     = "general" // errors that don't fit any specific check
-	CheckNameNTP         = "ntp"
-	CheckNameOSVer       = "os-version"
-	CheckNameSwap        = "swap"
-	CheckNameSysctl      = "sysctl"
-	CheckNameCPUThreads  = "cpu-cores"
-	CheckNameCPUGovernor = "cpu-governor"
-	CheckNameDisks       = "disk"
-	CheckNamePortListen  = "listening-port"
-	CheckNameEpoll       = "epoll-exclusive"
-	CheckNameMem         = "memory"
-	CheckNameLimits      = "limits"
-	CheckNameSysService  = "service"
-	CheckNameSELinux     = "selinux"
-	CheckNameCommand     = "command"
-	CheckNameFio         = "fio"
+	CheckNameGeneral      = "general" // errors that don't fit any specific check
+	CheckNameNTP          = "ntp"
+	CheckNameOSVer        = "os-version"
+	CheckNameSwap         = "swap"
+	CheckNameSysctl       = "sysctl"
+	CheckNameCPUThreads   = "cpu-cores"
+	CheckNameCPUGovernor  = "cpu-governor"
+	CheckNameDisks        = "disk"
+	CheckNamePortListen   = "listening-port"
+	CheckNameEpoll        = "epoll-exclusive"
+	CheckNameMem          = "memory"
+	CheckNameLimits       = "limits"
+	CheckNameSysService   = "service"
+	CheckNameSELinux      = "selinux"
+	CheckNameCommand      = "command"
+	CheckNameFio          = "fio"
+	CheckNameDiskBench    = "disk-bench"
+	CheckNameConnectivity = "connectivity"
+	CheckNameOSSettings   = "os-settings"
 )
 
 // CheckResult is the result of a check
@@ -649,3 +664,177 @@ func CheckFIOResult(rr, rw, lat []byte) []*CheckResult {
 
 	return results
 }
+
+// the dd summary line, e.g.:
+// 268435456 bytes (268 MB, 256 MiB) copied, 1.22936 s, 218 MB/s
+var ddSummaryRegexp = regexp.MustCompile(`(\d+) bytes .*copied, ([0-9.]+) s`)
+
+// parseDDOutput reads the bytes written and the time used from outputs of dd
+func parseDDOutput(out []byte) (bytes int64, secs float64, err error) {
+	matches := ddSummaryRegexp.FindSubmatch(out)
+	if len(matches) != 3 {
+		return 0, 0, fmt.Errorf("unrecognized output of dd: %s", strings.TrimSpace(string(out)))
+	}
+	if bytes, err = strconv.ParseInt(string(matches[1]), 10, 64); err != nil {
+		return 0, 0, err
+	}
+	if secs, err = strconv.ParseFloat(string(matches[2]), 64); err != nil {
+		return 0, 0, err
+	}
+	return bytes, secs, nil
+}
+
+// CheckDiskBenchResult parses and checks the result of the quick disk benchmark,
+// seqOut is the output of a sequential direct write, and syncOut is the output
+// of syncCount 4k writes with each of them fsync'ed
+func CheckDiskBenchResult(opt *CheckOptions, dataDir string, seqOut, syncOut []byte, syncCount int) []*CheckResult {
+	var results []*CheckResult
+
+	written, secs, err := parseDDOutput(seqOut)
+	if err == nil && secs <= 0 {
+		err = fmt.Errorf("invalid time used %fs", secs)
+	}
+	if err != nil {
+		results = append(results, &CheckResult{
+			Name: CheckNameDiskBench,
+			Err:  fmt.Errorf("error parsing result of sequential write test of %s, %s", dataDir, err),
+		})
+	} else {
+		// in MB/s (10^6 bytes) as dd reports it and the threshold is given
+		throughput := float64(written) / 1000 / 1000 / secs
+		result := &CheckResult{
+			Name: CheckNameDiskBench,
+			Msg:  fmt.Sprintf("sequential write of %s: %.2fMB/s", dataDir, throughput),
+		}
+		if opt.DiskBenchMinSeqWrite > 0 && throughput < opt.DiskBenchMinSeqWrite {
+			result.Err = fmt.Errorf("sequential write of %s is %.2fMB/s, lower than %.2fMB/s",
+				dataDir, throughput, opt.DiskBenchMinSeqWrite)
+		}
+		results = append(results, result)
+	}
+
+	_, secs, err = parseDDOutput(syncOut)
+	if err == nil && syncCount <= 0 {
+		err = fmt.Errorf("invalid write count %d", syncCount)
+	}
+	if err != nil {
+		results = append(results, &CheckResult{
+			Name: CheckNameDiskBench,
+			Err:  fmt.Errorf("error parsing result of fsync latency test of %s, %s", dataDir, err),
+		})
+	} else {
+		latency := time.Duration(secs * float64(time.Second) / float64(syncCount))
+		result := &CheckResult{
+			Name: CheckNameDiskBench,
+			Msg:  fmt.Sprintf("fsync latency of %s: %s", dataDir, latency),
+		}
+		if opt.DiskBenchMaxFsyncLat > 0 && latency > opt.DiskBenchMaxFsyncLat {
+			result.Err = fmt.Errorf("fsync latency of %s is %s, higher than %s",
+				dataDir, latency, opt.DiskBenchMaxFsyncLat)
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// ulimitFlags are the flags of ulimit to show the items of limits.conf
+var ulimitFlags = map[string]string{
+	"core":    "c",
+	"data":    "d",
+	"fsize":   "f",
+	"memlock": "l",
+	"nofile":  "n",
+	"stack":   "s",
+	"cpu":     "t",
+	"nproc":   "u",
+	"as":      "v",
+}
+
+// normalizeLimit converts the unlimited values of limits.conf to the output of ulimit
+func normalizeLimit(v string) string {
+	switch v {
+	case "-1", "infinity", "unlimited":
+		return "unlimited"
+	}
+	return v
+}
+
+// CheckOSSettings checks the live values of the OS settings declared in the
+// topology on the host. Limits are checked by the login sessions of users,
+// the entries of groups or wildcards, and of unknown items are skipped.
+func CheckOSSettings(e executor.Executor, settings *spec.OSSettings) []*CheckResult {
+	var results []*CheckResult
+	if settings == nil {
+		return results
+	}
+
+	keys := make([]string, 0, len(settings.Sysctl))
+	for key := range settings.Sysctl {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		result := &CheckResult{Name: CheckNameOSSettings}
+		want := strings.Join(strings.Fields(settings.Sysctl[key]), " ")
+		stdout, stderr, err := e.Execute(fmt.Sprintf("sysctl -n %s", key), false)
+		got := strings.Join(strings.Fields(string(stdout)), " ")
+		switch {
+		case err != nil:
+			result.Err = fmt.Errorf("failed to read %s: %s", key, strings.TrimSpace(string(stderr)))
+		case got != want:
+			result.Err = fmt.Errorf("%s = %s, should be %s", key, got, want)
+			result.Msg = fmt.Sprintf("%s = %s", key, want)
+		default:
+			result.Msg = fmt.Sprintf("%s = %s", key, got)
+		}
+		results = append(results, result)
+	}
+
+	for _, l := range settings.Limits {
+		flag, ok := ulimitFlags[l.Item]
+		if !ok || strings.ContainsAny(l.Domain[:1], "@*%") {
+			continue
+		}
+		kind := "S"
+		if l.Type == "hard" {
+			kind = "H"
+		}
+		result := &CheckResult{Name: CheckNameOSSettings}
+		stdout, stderr, err := e.Execute(
+			fmt.Sprintf("su - %s -s /bin/sh -c 'ulimit -%s%s'", l.Domain, kind, flag), true)
+		got := strings.TrimSpace(string(stdout))
+		switch {
+		case err != nil:
+			result.Err = fmt.Errorf("failed to read %s limit %s of %s: %s", l.Type, l.Item, l.Domain, strings.TrimSpace(string(stderr)))
+		case got != normalizeLimit(l.Value):
+			result.Err = fmt.Errorf("%s limit %s of %s = %s, should be %s", l.Type, l.Item, l.Domain, got, l.Value)
+			result.Msg = fmt.Sprintf("%s %s %s %s", l.Domain, l.Type, l.Item, l.Value)
+		default:
+			result.Msg = fmt.Sprintf("%s %s %s %s", l.Domain, l.Type, l.Item, got)
+		}
+		results = append(results, result)
+	}
+
+	if settings.TransparentHugepage != "" {
+		result := &CheckResult{Name: CheckNameOSSettings}
+		stdout, stderr, err := e.Execute(fmt.Sprintf("cat %s", spec.THPEnabledPath), false)
+		// the output is like "always madvise [never]"
+		got := ""
+		if m := regexp.MustCompile(`\[(\w+)\]`).FindSubmatch(stdout); m != nil {
+			got = string(m[1])
+		}
+		switch {
+		case err != nil:
+			result.Err = fmt.Errorf("failed to read transparent hugepage setting: %s", strings.TrimSpace(string(stderr)))
+		case got != settings.TransparentHugepage:
+			result.Err = fmt.Errorf("transparent hugepage is %s, should be %s", got, settings.TransparentHugepage)
+			result.Msg = fmt.Sprintf("transparent hugepage = %s", settings.TransparentHugepage)
+		default:
+			result.Msg = fmt.Sprintf("transparent hugepage = %s", got)
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
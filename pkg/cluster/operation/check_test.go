@@ -0,0 +1,70 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDiskBenchResult(t *testing.T) {
+	// 256MiB written in 1.28s, i.e., 209.72MB/s as dd reports it
+	seqOut := []byte("256+0 records in\n256+0 records out\n268435456 bytes (268 MB, 256 MiB) copied, 1.28 s, 210 MB/s\n")
+	// 1000 fsync'ed writes in 2.5s
+	syncOut := []byte("1000+0 records in\n1000+0 records out\n4096000 bytes (4.1 MB, 3.9 MiB) copied, 2.5 s, 1.6 MB/s\n")
+	opt := &CheckOptions{DiskBenchMinSeqWrite: 205, DiskBenchMaxFsyncLat: 5 * time.Millisecond}
+
+	// the throughput is compared in MB/s, it'd fail in MiB/s (200MiB/s)
+	results := CheckDiskBenchResult(opt, "/data/tikv", seqOut, syncOut, 1000)
+	require.Len(t, results, 2)
+	assert.Equal(t, "sequential write of /data/tikv: 209.72MB/s", results[0].Msg)
+	assert.Nil(t, results[0].Err)
+	assert.Equal(t, "fsync latency of /data/tikv: 2.5ms", results[1].Msg)
+	assert.Nil(t, results[1].Err)
+
+	opt = &CheckOptions{DiskBenchMinSeqWrite: 210, DiskBenchMaxFsyncLat: 2 * time.Millisecond}
+	results = CheckDiskBenchResult(opt, "/data/tikv", seqOut, syncOut, 1000)
+	require.Len(t, results, 2)
+	assert.EqualError(t, results[0].Err, "sequential write of /data/tikv is 209.72MB/s, lower than 210.00MB/s")
+	assert.EqualError(t, results[1].Err, "fsync latency of /data/tikv is 2.5ms, higher than 2ms")
+
+	// the thresholds are not checked if unset
+	results = CheckDiskBenchResult(&CheckOptions{}, "/data/tikv", seqOut, syncOut, 1000)
+	require.Len(t, results, 2)
+	assert.Nil(t, results[0].Err)
+	assert.Nil(t, results[1].Err)
+
+	results = CheckDiskBenchResult(opt, "/data/tikv", []byte("dd: failed to open 'seq_write_test': Permission denied"), syncOut, 0)
+	require.Len(t, results, 2)
+	assert.Contains(t, results[0].Err.Error(), "error parsing result of sequential write test of /data/tikv")
+	assert.Contains(t, results[1].Err.Error(), "invalid write count 0")
+}
+
+func TestParseDDOutput(t *testing.T) {
+	// the outputs of dd of different versions
+	for out, expected := range map[string]float64{
+		"268435456 bytes (268 MB, 256 MiB) copied, 1.22936 s, 218 MB/s": 1.22936,
+		"268435456 bytes (268 MB) copied, 0.5 s, 537 MB/s":              0.5,
+	} {
+		written, secs, err := parseDDOutput([]byte(out))
+		require.NoError(t, err, out)
+		assert.Equal(t, int64(268435456), written)
+		assert.Equal(t, expected, secs)
+	}
+	_, _, err := parseDDOutput([]byte("268435456 bytes transferred in 1.2 secs"))
+	assert.Error(t, err)
+}
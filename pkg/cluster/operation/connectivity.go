@@ -0,0 +1,288 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// ConnectivityPair is a port of a server component that a client component
+// needs to connect to
+type ConnectivityPair struct {
+	Client     string // component name of the client
+	ClientHost string
+	Server     string // component name of the server
+	ServerHost string
+	Port       int
+}
+
+// String implements the fmt.Stringer interface
+func (p ConnectivityPair) String() string {
+	return fmt.Sprintf("%s(%s) -> %s(%s:%d)", p.Client, p.ClientHost, p.Server, p.ServerHost, p.Port)
+}
+
+type connEndpoint struct {
+	comp string
+	host string
+	port int
+}
+
+// ConnectivityPairs returns the connections between hosts implied by the
+// topology, connections between components on the same host are ignored
+func ConnectivityPairs(topo *spec.Specification) []ConnectivityPair {
+	var (
+		pdClient, pdPeer, tikv, tikvStatus, tidbStatus       []connEndpoint
+		flashService, flashProxy, flashStatus, pump, drainer []connEndpoint
+		cdc, prometheus, alertmanager, exporters             []connEndpoint
+	)
+	hosts := make(map[string][]string) // component -> hosts
+	addHost := func(comp, host string) {
+		hosts[comp] = append(hosts[comp], host)
+	}
+
+	for _, s := range topo.PDServers {
+		addHost(spec.ComponentPD, s.Host)
+		pdClient = append(pdClient, connEndpoint{spec.ComponentPD, s.Host, s.ClientPort})
+		pdPeer = append(pdPeer, connEndpoint{spec.ComponentPD, s.Host, s.PeerPort})
+	}
+	for _, s := range topo.TiKVServers {
+		addHost(spec.ComponentTiKV, s.Host)
+		tikv = append(tikv, connEndpoint{spec.ComponentTiKV, s.Host, s.Port})
+		tikvStatus = append(tikvStatus, connEndpoint{spec.ComponentTiKV, s.Host, s.StatusPort})
+	}
+	for _, s := range topo.TiDBServers {
+		addHost(spec.ComponentTiDB, s.Host)
+		tidbStatus = append(tidbStatus, connEndpoint{spec.ComponentTiDB, s.Host, s.StatusPort})
+	}
+	for _, s := range topo.TiFlashServers {
+		addHost(spec.ComponentTiFlash, s.Host)
+		flashService = append(flashService, connEndpoint{spec.ComponentTiFlash, s.Host, s.FlashServicePort})
+		flashProxy = append(flashProxy, connEndpoint{spec.ComponentTiFlash, s.Host, s.FlashProxyPort})
+		flashStatus = append(flashStatus,
+			connEndpoint{spec.ComponentTiFlash, s.Host, s.StatusPort},
+			connEndpoint{spec.ComponentTiFlash, s.Host, s.FlashProxyStatusPort})
+	}
+	for _, s := range topo.PumpServers {
+		addHost(spec.ComponentPump, s.Host)
+		pump = append(pump, connEndpoint{spec.ComponentPump, s.Host, s.Port})
+	}
+	for _, s := range topo.Drainers {
+		addHost(spec.ComponentDrainer, s.Host)
+		drainer = append(drainer, connEndpoint{spec.ComponentDrainer, s.Host, s.Port})
+	}
+	for _, s := range topo.CDCServers {
+		addHost(spec.ComponentCDC, s.Host)
+		cdc = append(cdc, connEndpoint{spec.ComponentCDC, s.Host, s.Port})
+	}
+	for _, s := range topo.Monitors {
+		addHost(spec.ComponentPrometheus, s.Host)
+		prometheus = append(prometheus, connEndpoint{spec.ComponentPrometheus, s.Host, s.Port})
+	}
+	for _, s := range topo.Grafana {
+		addHost(spec.ComponentGrafana, s.Host)
+	}
+	for _, s := range topo.Alertmanager {
+		alertmanager = append(alertmanager, connEndpoint{spec.ComponentAlertManager, s.Host, s.WebPort})
+	}
+	seenHost := make(map[string]bool)
+	topo.IterInstance(func(inst spec.Instance) {
+		if seenHost[inst.GetHost()] {
+			return
+		}
+		seenHost[inst.GetHost()] = true
+		if port := topo.MonitoredOptions.ExporterPort(inst.GetHost()); port > 0 {
+			exporters = append(exporters, connEndpoint{spec.ComponentNodeExporter, inst.GetHost(), port})
+		}
+		if topo.MonitoredOptions.DeployAgents(inst.GetHost()) {
+			exporters = append(exporters, connEndpoint{spec.ComponentBlackboxExporter, inst.GetHost(), topo.MonitoredOptions.BlackboxExporterPort})
+		}
+	})
+
+	relations := map[string][][]connEndpoint{
+		spec.ComponentTiDB:       {pdClient, tikv, flashService, pump},
+		spec.ComponentTiKV:       {pdClient, tikv, flashProxy},
+		spec.ComponentPD:         {pdClient, pdPeer},
+		spec.ComponentTiFlash:    {pdClient, tikv, flashProxy},
+		spec.ComponentPump:       {pdClient},
+		spec.ComponentDrainer:    {pdClient, pump},
+		spec.ComponentCDC:        {pdClient, tikv},
+		spec.ComponentPrometheus: {pdClient, tikvStatus, tidbStatus, flashStatus, pump, drainer, cdc, alertmanager, exporters},
+		spec.ComponentGrafana:    {prometheus},
+	}
+
+	var pairs []ConnectivityPair
+	seen := make(map[string]bool)
+	for _, client := range []string{
+		spec.ComponentPD, spec.ComponentTiKV, spec.ComponentTiFlash, spec.ComponentTiDB,
+		spec.ComponentPump, spec.ComponentDrainer, spec.ComponentCDC,
+		spec.ComponentPrometheus, spec.ComponentGrafana,
+	} {
+		for _, clientHost := range hosts[client] {
+			for _, servers := range relations[client] {
+				for _, srv := range servers {
+					if srv.host == clientHost || srv.port == 0 {
+						continue
+					}
+					key := fmt.Sprintf("%s-%s:%d", clientHost, srv.host, srv.port)
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+					pairs = append(pairs, ConnectivityPair{
+						Client:     client,
+						ClientHost: clientHost,
+						Server:     srv.comp,
+						ServerHost: srv.host,
+						Port:       srv.port,
+					})
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// ConnectivityProbeCommand builds the shell command that probes TCP reachability
+// of all pairs from the client host, at most concurrency probes run at the same time.
+// Each line of the output is "<host> <port> ok|blocked"
+func ConnectivityProbeCommand(pairs []ConnectivityPair, concurrency int, timeoutSec int) string {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if timeoutSec < 1 {
+		timeoutSec = 1
+	}
+	// the hosts and ports are passed as separate arguments, the IPv6 hosts
+	// have colons in them
+	targets := make([]string, 0, 2*len(pairs))
+	for _, p := range pairs {
+		targets = append(targets, utils.ShellQuote(p.ServerHost), strconv.Itoa(p.Port))
+	}
+	return fmt.Sprintf(
+		`printf '%%s %%s\n' %s | xargs -P %d -n 2 sh -c 'h=$0; p=$1; `+
+			`if timeout %d bash -c "cat < /dev/null > /dev/tcp/$h/$p" 2>/dev/null; `+
+			`then echo "$h $p ok"; else echo "$h $p blocked"; fi'`,
+		strings.Join(targets, " "), concurrency, timeoutSec)
+}
+
+// ConnectivityMatrix collects results of connectivity probes, it is safe
+// to be used concurrently
+type ConnectivityMatrix struct {
+	sync.Mutex
+	results map[ConnectivityPair]bool // pair -> reachable
+}
+
+// NewConnectivityMatrix creates an empty ConnectivityMatrix
+func NewConnectivityMatrix() *ConnectivityMatrix {
+	return &ConnectivityMatrix{results: make(map[ConnectivityPair]bool)}
+}
+
+// ParseProbeOutput records the output of ConnectivityProbeCommand, and returns
+// the check results of the client host
+func (m *ConnectivityMatrix) ParseProbeOutput(pairs []ConnectivityPair, out []byte) []*CheckResult {
+	status := make(map[string]string) // host:port -> ok/blocked
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		status[fields[0]+":"+fields[1]] = fields[2]
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	var results []*CheckResult
+	reachable := 0
+	for _, p := range pairs {
+		ok := status[p.ServerHost+":"+strconv.Itoa(p.Port)] == "ok"
+		m.results[p] = ok
+		if ok {
+			reachable++
+			continue
+		}
+		results = append(results, &CheckResult{
+			Name: CheckNameConnectivity,
+			Err:  fmt.Errorf("%s is blocked", p),
+		})
+	}
+	if reachable > 0 {
+		results = append(results, &CheckResult{
+			Name: CheckNameConnectivity,
+			Msg:  fmt.Sprintf("%d of %d connections are reachable", reachable, len(pairs)),
+		})
+	}
+	return results
+}
+
+// Table renders the matrix, rows are client hosts and columns are server hosts
+func (m *ConnectivityMatrix) Table() [][]string {
+	m.Lock()
+	defer m.Unlock()
+
+	clients := make(map[string]bool)
+	servers := make(map[string]bool)
+	blocked := make(map[string][]int) // client -> server -> ports
+	probed := make(map[string]bool)
+	for p, ok := range m.results {
+		clients[p.ClientHost] = true
+		servers[p.ServerHost] = true
+		key := p.ClientHost + " " + p.ServerHost
+		probed[key] = true
+		if !ok {
+			blocked[key] = append(blocked[key], p.Port)
+		}
+	}
+
+	sortedKeys := func(s map[string]bool) []string {
+		keys := make([]string, 0, len(s))
+		for k := range s {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+
+	serverHosts := sortedKeys(servers)
+	table := [][]string{append([]string{"From \\ To"}, serverHosts...)}
+	for _, client := range sortedKeys(clients) {
+		row := []string{client}
+		for _, server := range serverHosts {
+			key := client + " " + server
+			switch {
+			case !probed[key]:
+				row = append(row, "-")
+			case len(blocked[key]) == 0:
+				row = append(row, "ok")
+			default:
+				ports := blocked[key]
+				sort.Ints(ports)
+				strs := make([]string, 0, len(ports))
+				for _, port := range ports {
+					strs = append(strs, strconv.Itoa(port))
+				}
+				row = append(row, "blocked: "+strings.Join(strs, ","))
+			}
+		}
+		table = append(table, row)
+	}
+	return table
+}
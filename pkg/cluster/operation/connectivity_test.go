@@ -0,0 +1,136 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConnectivityPairs(t *testing.T) {
+	topo := &spec.Specification{}
+	require.NoError(t, yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 10.0.1.1
+tikv_servers:
+  - host: 10.0.1.2
+tidb_servers:
+  - host: 10.0.1.2
+monitoring_servers:
+  - host: 10.0.1.1
+`), topo))
+
+	// the connections on the same host are ignored, and each port of a
+	// host is only probed once from a host
+	var pairs []string
+	for _, p := range ConnectivityPairs(topo) {
+		pairs = append(pairs, p.String())
+	}
+	assert.Equal(t, []string{
+		"tikv(10.0.1.2) -> pd(10.0.1.1:2379)",
+		"prometheus(10.0.1.1) -> tikv(10.0.1.2:20180)",
+		"prometheus(10.0.1.1) -> tidb(10.0.1.2:10080)",
+		"prometheus(10.0.1.1) -> node_exporter(10.0.1.2:9100)",
+		"prometheus(10.0.1.1) -> blackbox_exporter(10.0.1.2:9115)",
+	}, pairs)
+}
+
+func TestConnectivityProbe(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is required to probe the ports")
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	open := l.Addr().(*net.TCPAddr).Port
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	blocked := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	pairs := []ConnectivityPair{
+		{Client: spec.ComponentTiKV, ClientHost: "10.0.1.2", Server: spec.ComponentPD, ServerHost: "127.0.0.1", Port: open},
+		{Client: spec.ComponentTiKV, ClientHost: "10.0.1.2", Server: spec.ComponentTiKV, ServerHost: "127.0.0.1", Port: blocked},
+	}
+	out, err := exec.Command("sh", "-c", ConnectivityProbeCommand(pairs, 2, 1)).Output()
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	sort.Strings(lines)
+	expected := []string{
+		"127.0.0.1 " + strings.TrimPrefix(l.Addr().String(), "127.0.0.1:") + " ok",
+		"127.0.0.1 " + strings.TrimPrefix(closed.Addr().String(), "127.0.0.1:") + " blocked",
+	}
+	sort.Strings(expected)
+	assert.Equal(t, expected, lines)
+
+	// the blocked connections fail the check of the client host
+	m := NewConnectivityMatrix()
+	results := m.ParseProbeOutput(pairs, out)
+	require.Len(t, results, 2)
+	assert.EqualError(t, results[0].Err, pairs[1].String()+" is blocked")
+	assert.Nil(t, results[1].Err)
+	assert.Equal(t, "1 of 2 connections are reachable", results[1].Msg)
+
+	// the IPv6 hosts are probed as well
+	l6, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 is not available")
+	}
+	defer l6.Close()
+	pairs = []ConnectivityPair{
+		{Client: spec.ComponentTiKV, ClientHost: "10.0.1.2", Server: spec.ComponentPD, ServerHost: "::1", Port: l6.Addr().(*net.TCPAddr).Port},
+	}
+	out, err = exec.Command("sh", "-c", ConnectivityProbeCommand(pairs, 2, 1)).Output()
+	require.NoError(t, err)
+	results = m.ParseProbeOutput(pairs, out)
+	require.Len(t, results, 1)
+	assert.Nil(t, results[0].Err, string(out))
+}
+
+func TestConnectivityMatrix(t *testing.T) {
+	pairs := map[string][]ConnectivityPair{
+		"10.0.1.1": {
+			{Client: spec.ComponentPrometheus, ClientHost: "10.0.1.1", Server: spec.ComponentTiKV, ServerHost: "10.0.1.2", Port: 20180},
+			{Client: spec.ComponentPrometheus, ClientHost: "10.0.1.1", Server: spec.ComponentNodeExporter, ServerHost: "10.0.1.2", Port: 9100},
+			{Client: spec.ComponentPrometheus, ClientHost: "10.0.1.1", Server: spec.ComponentTiDB, ServerHost: "10.0.1.2", Port: 10080},
+		},
+		"10.0.1.2": {
+			{Client: spec.ComponentTiKV, ClientHost: "10.0.1.2", Server: spec.ComponentPD, ServerHost: "10.0.1.1", Port: 2379},
+		},
+	}
+	m := NewConnectivityMatrix()
+	results := m.ParseProbeOutput(pairs["10.0.1.1"], []byte("10.0.1.2 20180 ok\n10.0.1.2 9100 blocked\n"))
+	// the ports missing in the output are blocked
+	require.Len(t, results, 3)
+	assert.NotNil(t, results[0].Err)
+	assert.NotNil(t, results[1].Err)
+	assert.Equal(t, "1 of 3 connections are reachable", results[2].Msg)
+	results = m.ParseProbeOutput(pairs["10.0.1.2"], []byte("10.0.1.1 2379 ok\n"))
+	require.Len(t, results, 1)
+	assert.Nil(t, results[0].Err)
+
+	assert.Equal(t, [][]string{
+		{"From \\ To", "10.0.1.1", "10.0.1.2"},
+		{"10.0.1.1", "-", "blocked: 9100,10080"},
+		{"10.0.1.2", "ok", "-"},
+	}, m.Table())
+}
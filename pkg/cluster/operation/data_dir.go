@@ -0,0 +1,198 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+)
+
+// the time limits of the commands on the data directory, which may be of
+// terabytes, scanning it, e.g., du and sha256sum, and copying it
+const (
+	dataDirScanTimeout = 2 * time.Hour
+	dataDirCopyTimeout = 72 * time.Hour
+)
+
+// DataDirMigration is how the data directory of an instance is moved to
+// another directory on its host
+type DataDirMigration struct {
+	Instance string `json:"instance"`
+	Host     string `json:"host"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Size     int64  `json:"size"`      // the bytes of the data
+	Avail    int64  `json:"available"` // the bytes available in the filesystem of To
+	// moved by renaming the directory, as both are on the same filesystem
+	Rename bool `json:"rename"`
+	// To doesn't exist or is empty, it's required to move the data into it
+	EmptyTarget bool `json:"empty_target"`
+	// rsync is installed on the host, the data is copied by cp otherwise
+	Rsync bool `json:"rsync"`
+	// the files whose checksums are spot-checked after copying
+	Verified int `json:"verified,omitempty"`
+}
+
+// ProbeDataDirMigration gathers the facts of moving the data directory of
+// the instance from one directory to another on its host, nothing is changed
+func ProbeDataDirMigration(e executor.Executor, inst spec.Instance, from, to string) (*DataDirMigration, error) {
+	// the facts of the target are taken from the closest existing directory
+	script := fmt.Sprintf(`from=%s; to=%s; p="$to"; while [ ! -e "$p" ]; do p=$(dirname "$p"); done
+echo "size=$(du -sk "$from" | cut -f1)"
+echo "avail=$(df -Pk "$p" | tail -n 1 | awk '{print $4}')"
+echo "same_fs=$([ "$(stat -c %%d "$from")" = "$(stat -c %%d "$p")" ] && echo 1 || echo 0)"
+echo "empty=$( ([ ! -e "$to" ] || [ -z "$(ls -A "$to")" ]) && echo 1 || echo 0)"
+echo "rsync=$(command -v rsync >/dev/null 2>&1 && echo 1 || echo 0)"`, from, to)
+	stdout, stderr, err := e.Execute(script, true, dataDirScanTimeout)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to probe the data directories of %s: %s", inst.ID(), stderr)
+	}
+
+	m := &DataDirMigration{Instance: inst.ID(), Host: inst.GetHost(), From: from, To: to}
+	scanner := bufio.NewScanner(strings.NewReader(string(stdout)))
+	for scanner.Scan() {
+		kv := strings.SplitN(strings.TrimSpace(scanner.Text()), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "size":
+			kb, _ := strconv.ParseInt(kv[1], 10, 64)
+			m.Size = kb * 1024
+		case "avail":
+			kb, _ := strconv.ParseInt(kv[1], 10, 64)
+			m.Avail = kb * 1024
+		case "same_fs":
+			m.Rename = kv[1] == "1"
+		case "empty":
+			m.EmptyTarget = kv[1] == "1"
+		case "rsync":
+			m.Rsync = kv[1] == "1"
+		}
+	}
+	return m, nil
+}
+
+// CopyDataDir moves the data of the migration to its target, by renaming the
+// directory if possible, or by copying the data, whose progress is reported
+// to the getter every interval
+func CopyDataDir(getter ExecutorGetter, m *DataDirMigration, interval time.Duration) error {
+	e := getter.Get(m.Host)
+	if m.Rename {
+		// the target is empty, it's replaced by the data directory
+		cmd := fmt.Sprintf("mkdir -p %[1]s && rmdir %[2]s 2>/dev/null; mv %[3]s %[2]s", filepath.Dir(m.To), m.To, m.From)
+		if _, stderr, err := e.Execute(cmd, true); err != nil {
+			return errors.Annotatef(err, "failed to move %s to %s: %s", m.From, m.To, stderr)
+		}
+		return nil
+	}
+
+	cmd := fmt.Sprintf("mkdir -p %[2]s && cp -a %[1]s/. %[2]s/", m.From, m.To)
+	if m.Rsync {
+		cmd = fmt.Sprintf("mkdir -p %[2]s && rsync -a %[1]s/ %[2]s/", m.From, m.To)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	if interval > 0 && m.Size > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+				}
+				stdout, _, err := e.Execute(fmt.Sprintf("du -sk %s | cut -f1", m.To), true, dataDirScanTimeout)
+				if err != nil {
+					continue
+				}
+				kb, err := strconv.ParseInt(strings.TrimSpace(string(stdout)), 10, 64)
+				if err != nil {
+					continue
+				}
+				copied := kb * 1024
+				if copied > m.Size {
+					copied = m.Size
+				}
+				reportProgress(getter, int(copied*100/m.Size), 100)
+				reportWaiting(getter, "copied %.1f/%.1f MiB", float64(copied)/(1<<20), float64(m.Size)/(1<<20))
+			}
+		}()
+	}
+	_, stderr, err := e.Execute(cmd, true, dataDirCopyTimeout)
+	close(done)
+	wg.Wait()
+	if err != nil {
+		return errors.Annotatef(err, "failed to copy %s to %s: %s", m.From, m.To, stderr)
+	}
+	return nil
+}
+
+// VerifyDataDirCopy compares the checksums of at most samples files picked
+// randomly from the source and the copy of the migration, the renamed
+// directories are not verified
+func VerifyDataDirCopy(e executor.Executor, m *DataDirMigration, samples int) error {
+	if m.Rename || samples <= 0 {
+		return nil
+	}
+	script := fmt.Sprintf(`cd %[1]s && for f in $(find . -type f | shuf -n %[3]d); do
+echo "checked=$f"
+[ "$(sha256sum < "%[1]s/$f")" = "$(sha256sum < "%[2]s/$f")" ] || echo "mismatched=$f"
+done`, m.From, m.To, samples)
+	stdout, stderr, err := e.Execute(script, true, dataDirScanTimeout)
+	if err != nil {
+		return errors.Annotatef(err, "failed to verify the copy of %s: %s", m.From, stderr)
+	}
+	var mismatched []string
+	m.Verified = 0
+	for _, line := range strings.Split(string(stdout), "\n") {
+		switch {
+		case strings.HasPrefix(line, "checked="):
+			m.Verified++
+		case strings.HasPrefix(line, "mismatched="):
+			mismatched = append(mismatched, strings.TrimPrefix(line, "mismatched="))
+		}
+	}
+	if len(mismatched) > 0 {
+		return errors.Errorf("the checksums of %d of %d files copied from %s to %s mismatch: %s",
+			len(mismatched), m.Verified, m.From, m.To, strings.Join(mismatched, ", "))
+	}
+	return nil
+}
+
+// RevertDataDir undoes CopyDataDir, the renamed directory is moved back, and
+// the copy is removed
+func RevertDataDir(e executor.Executor, m *DataDirMigration) error {
+	cmd := fmt.Sprintf("rm -rf %s", m.To)
+	if m.Rename {
+		cmd = fmt.Sprintf("[ -e %[2]s ] || mv %[1]s %[2]s", m.To, m.From)
+	}
+	if _, stderr, err := e.Execute(cmd, true); err != nil {
+		return errors.Annotatef(err, "failed to revert the data directory %s: %s", m.From, stderr)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// shellExecutor runs the command lines sent to the hosts in a local shell,
+// where sudo is simulated by the shim in bin running the commands as the
+// current user
+type shellExecutor struct {
+	bin string
+}
+
+func (e *shellExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	ctx := context.Background()
+	if len(timeout) > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout[0])
+		defer cancel()
+	}
+	c := exec.CommandContext(ctx, "sh", "-c", executor.RemoteCommand(cmd, sudo, "C"))
+	c.Env = append(os.Environ(), "PATH="+e.bin+":"+os.Getenv("PATH"))
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	c.Stdout, c.Stderr = stdout, stderr
+	err := c.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+func (e *shellExecutor) Transfer(src string, dst string, download bool) error {
+	return nil
+}
+
+func (e *shellExecutor) Get(host string) executor.Executor {
+	return e
+}
+
+func TestDataDirMigration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiup-data-dir-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	bin := filepath.Join(dir, "bin")
+	require.Nil(t, os.MkdirAll(bin, 0755))
+	// sudo -H -u root bash -c ...
+	require.Nil(t, ioutil.WriteFile(filepath.Join(bin, "sudo"), []byte("#!/bin/sh\nshift 3\nexec \"$@\"\n"), 0755))
+	e := &shellExecutor{bin: bin}
+
+	from := filepath.Join(dir, "tikv")
+	require.Nil(t, os.MkdirAll(filepath.Join(from, "db"), 0755))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(from, "db", "1.sst"), bytes.Repeat([]byte("a"), 64<<10), 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(from, "LOCK"), []byte("lock"), 0644))
+	inst := (&spec.TiKVComponent{Specification: &spec.Specification{
+		TiKVServers: []spec.TiKVSpec{{Host: "10.0.0.1", Port: 20160}},
+	}}).Instances()[0]
+
+	// the facts are gathered on the host rather than expanded before sudo
+	to := filepath.Join(dir, "data2", "tikv")
+	m, err := ProbeDataDirMigration(e, inst, from, to)
+	require.Nil(t, err)
+	assert.True(t, m.Size >= 64<<10, "%d", m.Size)
+	assert.True(t, m.Avail > 0)
+	assert.True(t, m.Rename)
+	assert.True(t, m.EmptyTarget)
+	_, lookErr := exec.LookPath("rsync")
+	assert.Equal(t, lookErr == nil, m.Rsync)
+
+	require.Nil(t, os.MkdirAll(to, 0755))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(to, "x"), nil, 0644))
+	m, err = ProbeDataDirMigration(e, inst, from, to)
+	require.Nil(t, err)
+	assert.False(t, m.EmptyTarget)
+	require.Nil(t, os.RemoveAll(to))
+
+	// copied as if it's to another filesystem, and spot-checked
+	m.Rename = false
+	require.Nil(t, CopyDataDir(e, m, time.Millisecond))
+	data, err := ioutil.ReadFile(filepath.Join(to, "db", "1.sst"))
+	require.Nil(t, err)
+	assert.Len(t, data, 64<<10)
+	require.Nil(t, VerifyDataDirCopy(e, m, 10))
+	assert.Equal(t, 2, m.Verified)
+
+	require.Nil(t, ioutil.WriteFile(filepath.Join(to, "LOCK"), []byte("corrupted"), 0644))
+	err = VerifyDataDirCopy(e, m, 10)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "./LOCK")
+
+	require.Nil(t, RevertDataDir(e, m))
+	_, err = os.Stat(to)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(from, "LOCK"))
+	assert.Nil(t, err)
+
+	// renamed within the filesystem, and moved back
+	m.Rename = true
+	require.Nil(t, CopyDataDir(e, m, 0))
+	_, err = os.Stat(filepath.Join(to, "db", "1.sst"))
+	assert.Nil(t, err)
+	_, err = os.Stat(from)
+	assert.True(t, os.IsNotExist(err))
+	require.Nil(t, RevertDataDir(e, m))
+	_, err = os.Stat(filepath.Join(from, "db", "1.sst"))
+	assert.Nil(t, err)
+}
@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/pingcap/errors"
@@ -50,7 +51,52 @@ func Cleanup(
 	return nil
 }
 
-// Destroy the cluster.
+// HostsNotCleanedError is returned by Destroy in force mode if some hosts
+// failed to be cleaned up, the other hosts are cleaned up anyway
+type HostsNotCleanedError struct {
+	Hosts map[string]error // the first error of each host
+}
+
+// Error implements the error interface
+func (e *HostsNotCleanedError) Error() string {
+	hosts := make([]string, 0, len(e.Hosts))
+	for host := range e.Hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	lines := []string{fmt.Sprintf("failed to clean up %d host(s):", len(hosts))}
+	for _, host := range hosts {
+		lines = append(lines, fmt.Sprintf("  - %s: %s", host, e.Hosts[host]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// add records the failure of the host if it's not failed yet, true is
+// returned if the failure is recorded, the error is returned as it is if the
+// failures are not collected
+func (e *HostsNotCleanedError) add(host string, err error) bool {
+	if e == nil {
+		return false
+	}
+	if _, ok := e.Hosts[host]; !ok {
+		log.Warnf("Failed to clean up %s, the rest of it is skipped: %s", host, err)
+		e.Hosts[host] = err
+	}
+	return true
+}
+
+// failed checks if the host is failed to be cleaned up
+func (e *HostsNotCleanedError) failed(host string) bool {
+	if e == nil {
+		return false
+	}
+	_, ok := e.Hosts[host]
+	return ok
+}
+
+// Destroy the cluster. In force mode, i.e. options.Force is set, the hosts
+// failed to be cleaned up, e.g. unreachable, are skipped instead of aborting,
+// and a HostsNotCleanedError is returned with them at the end.
 func Destroy(
 	getter ExecutorGetter,
 	cluster spec.Topology,
@@ -64,18 +110,25 @@ func Destroy(
 		instCount[inst.GetHost()] = instCount[inst.GetHost()] + 1
 	})
 
+	var notCleaned *HostsNotCleanedError
+	if options.Force {
+		notCleaned = &HostsNotCleanedError{Hosts: make(map[string]error)}
+	}
+
 	for _, com := range coms {
 		insts := com.Instances()
-		err := DestroyComponent(getter, insts, cluster, options)
+		err := destroyComponent(getter, insts, cluster, options, notCleaned)
 		if err != nil {
 			return errors.Annotatef(err, "failed to destroy %s", com.Name())
 		}
 		for _, inst := range insts {
 			instCount[inst.GetHost()]--
-			if instCount[inst.GetHost()] == 0 {
+			if instCount[inst.GetHost()] == 0 && !notCleaned.failed(inst.GetHost()) {
 				if cluster.GetMonitoredOptions() != nil {
 					if err := DestroyMonitored(getter, inst, cluster.GetMonitoredOptions(), options.OptTimeout); err != nil {
-						return err
+						if !notCleaned.add(inst.GetHost(), err) {
+							return err
+						}
 					}
 				}
 			}
@@ -89,6 +142,9 @@ func Destroy(
 		}
 	}
 
+	if notCleaned != nil && len(notCleaned.Hosts) > 0 {
+		return notCleaned
+	}
 	return nil
 }
 
@@ -134,6 +190,10 @@ func DeleteGlobalDirs(getter ExecutorGetter, host string, options *spec.GlobalOp
 
 // DestroyMonitored destroy the monitored service.
 func DestroyMonitored(getter ExecutorGetter, inst spec.Instance, options *spec.MonitoredOptions, timeout int64) error {
+	// the node_exporter on the host isn't ours
+	if !options.DeployAgents(inst.GetHost()) {
+		return nil
+	}
 	e := getter.Get(inst.GetHost())
 	log.Infof("Destroying monitored %s", inst.GetHost())
 
@@ -255,6 +315,12 @@ func CleanupComponent(getter ExecutorGetter, instances []spec.Instance, cls spec
 
 // DestroyComponent destroy the instances.
 func DestroyComponent(getter ExecutorGetter, instances []spec.Instance, cls spec.Topology, options Options) error {
+	return destroyComponent(getter, instances, cls, options, nil)
+}
+
+// destroyComponent destroys the instances, the ones on the hosts failed to be
+// cleaned up are skipped and recorded in notCleaned if it's not nil
+func destroyComponent(getter ExecutorGetter, instances []spec.Instance, cls spec.Topology, options Options, notCleaned *HostsNotCleanedError) error {
 	if len(instances) <= 0 {
 		return nil
 	}
@@ -269,6 +335,9 @@ func DestroyComponent(getter ExecutorGetter, instances []spec.Instance, cls spec
 		// Some data of instances will be retained
 		dataRetained := retainDataRoles.Exist(ins.ComponentName()) ||
 			retainDataNodes.Exist(ins.ID()) || retainDataNodes.Exist(ins.GetHost())
+		if notCleaned.failed(ins.GetHost()) {
+			continue
+		}
 
 		e := getter.Get(ins.GetHost())
 		log.Infof("Destroying instance %s", ins.GetHost())
@@ -357,6 +426,9 @@ func DestroyComponent(getter ExecutorGetter, instances []spec.Instance, cls spec
 		}
 
 		if err != nil {
+			if notCleaned.add(ins.GetHost(), err) {
+				continue
+			}
 			return errors.Annotatef(err, "failed to destroy: %s", ins.GetHost())
 		}
 
@@ -14,20 +14,112 @@
 package operator
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/repository"
 	"github.com/pingcap/tiup/pkg/utils"
 	tiupver "github.com/pingcap/tiup/pkg/version"
 )
 
+// DownloadProgress is notified the progress of DownloadWithProgress
+type DownloadProgress interface {
+	repository.DownloadProgress
+	// Verifying is called before verifying the package already downloaded
+	Verifying()
+}
+
+type noDownloadProgress struct {
+	repository.DisableProgress
+}
+
+func (noDownloadProgress) Verifying() {}
+
+// PackageChecksumFile is the file listing the sha256 checksums of the packages
+// in a package directory, in the format of the output of sha256sum
+const PackageChecksumFile = "sha256sum.txt"
+
+// PackageFileName returns the file name of the package of the version of a
+// component, which is the same in the package cache and package directories
+func PackageFileName(component, nodeOS, arch, version string) string {
+	return fmt.Sprintf("%s-%s-%s-%s.tar.gz", component, version, nodeOS, arch)
+}
+
+// ImportPackage copies the specific version of a component from the package
+// directory dir to the package cache, in place of downloading it from the
+// repository. The package is verified with the checksum file in dir, or with
+// the local manifests if the checksum file doesn't list it.
+func ImportPackage(dir, component, nodeOS, arch, version string) error {
+	fileName := PackageFileName(component, nodeOS, arch, version)
+	checksums, err := readChecksums(filepath.Join(dir, PackageChecksumFile))
+	if err != nil {
+		return err
+	}
+	hash, found := checksums[fileName]
+	if !found {
+		if hash, err = clusterutil.LocalComponentHash(component, version, nodeOS, arch); err != nil {
+			return err
+		}
+	}
+	if hash == "" {
+		return errors.Errorf("no checksum of %s in %s or the local manifests", fileName, PackageChecksumFile)
+	}
+
+	src, err := os.Open(filepath.Join(dir, fileName))
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer src.Close()
+	if err := utils.CheckSHA256(src, hash); err != nil {
+		return errors.Annotatef(err, "failed to verify %s", fileName)
+	}
+
+	if err := os.MkdirAll(spec.ProfilePath(spec.TiOpsPackageCacheDir), 0755); err != nil {
+		return err
+	}
+	return utils.CopyFile(filepath.Join(dir, fileName), spec.ProfilePath(spec.TiOpsPackageCacheDir, fileName))
+}
+
+// readChecksums reads the file name to checksum mapping from the checksum file,
+// it's empty if the file doesn't exist
+func readChecksums(path string) (map[string]string, error) {
+	checksums := make(map[string]string)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return checksums, nil
+	}
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		// sha256sum marks the files read in binary mode with '*'
+		checksums[strings.TrimPrefix(fields[1], "*")] = fields[0]
+	}
+	return checksums, errors.AddStack(scanner.Err())
+}
+
 // Download the specific version of a component from
 // the repository, there is nothing to do if the specified version exists.
 func Download(component, nodeOS, arch string, version string) error {
+	return DownloadWithProgress(component, nodeOS, arch, version, noDownloadProgress{})
+}
+
+// DownloadWithProgress is Download notifying the progress to progress
+func DownloadWithProgress(component, nodeOS, arch string, version string, progress DownloadProgress) error {
 	if component == "" {
 		return errors.New("component name not specified")
 	}
@@ -43,12 +135,13 @@ func Download(component, nodeOS, arch string, version string) error {
 		return err
 	}
 
-	repo, err := clusterutil.NewRepository(nodeOS, arch)
+	repo, err := clusterutil.NewRepositoryWithProgress(nodeOS, arch, progress)
 	if err != nil {
 		return err
 	}
 
 	if utils.IsExist(srcPath) {
+		progress.Verifying()
 		if err := repo.VerifyComponent(component, version, srcPath); err != nil {
 			os.Remove(srcPath)
 		}
@@ -0,0 +1,85 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportPackage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiup-import-package-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	os.Setenv(localdata.EnvNameHome, filepath.Join(dir, "home"))
+	os.Setenv(localdata.EnvNameComponentDataDir, filepath.Join(dir, "data"))
+	defer os.Unsetenv(localdata.EnvNameHome)
+	defer os.Unsetenv(localdata.EnvNameComponentDataDir)
+	require.Nil(t, spec.Initialize("cluster"))
+	// only the root manifest is in the local profile, and nothing is fetched
+	// from the mirror
+	mirror, keys := filepath.Join(dir, "mirror"), filepath.Join(dir, "keys")
+	require.Nil(t, os.MkdirAll(keys, 0755))
+	require.Nil(t, os.MkdirAll(mirror, 0755))
+	require.Nil(t, v1manifest.Init(mirror, keys, time.Now()))
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "home", "bin"), 0755))
+	require.Nil(t, utils.CopyFile(filepath.Join(mirror, "root.json"), filepath.Join(dir, "home", "bin", "root.json")))
+
+	packageDir := filepath.Join(dir, "packages")
+	require.Nil(t, os.MkdirAll(packageDir, 0755))
+	name := PackageFileName("tikv", "linux", "amd64", "v4.0.0")
+	assert.Equal(t, "tikv-v4.0.0-linux-amd64.tar.gz", name)
+	content := []byte("tikv package")
+	require.Nil(t, ioutil.WriteFile(filepath.Join(packageDir, name), content, 0644))
+	cached := spec.ProfilePath(spec.TiOpsPackageCacheDir, name)
+
+	// there is no checksum to verify the package with
+	err = ImportPackage(packageDir, "tikv", "linux", "amd64", "v4.0.0")
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "no checksum of "+name)
+	_, err = os.Stat(cached)
+	assert.True(t, os.IsNotExist(err))
+	entry, err := clusterutil.LocalComponentBinEntry("tikv", "v4.0.0", "linux", "amd64")
+	require.Nil(t, err)
+	assert.Empty(t, entry)
+
+	// the checksum doesn't match
+	checksums := filepath.Join(packageDir, PackageChecksumFile)
+	require.Nil(t, ioutil.WriteFile(checksums, []byte(fmt.Sprintf("%x  %s\n", sha256.Sum256([]byte("other")), name)), 0644))
+	err = ImportPackage(packageDir, "tikv", "linux", "amd64", "v4.0.0")
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "failed to verify "+name)
+	_, err = os.Stat(cached)
+	assert.True(t, os.IsNotExist(err))
+
+	// the checksum matches, the files read in binary mode are marked by '*'
+	require.Nil(t, ioutil.WriteFile(checksums, []byte(fmt.Sprintf("malformed\n%x *%s\n", sha256.Sum256(content), name)), 0644))
+	require.Nil(t, ImportPackage(packageDir, "tikv", "linux", "amd64", "v4.0.0"))
+	data, err := ioutil.ReadFile(cached)
+	require.Nil(t, err)
+	assert.Equal(t, content, data)
+}
@@ -0,0 +1,241 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/module"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// VerifyResult is the result of verifying that an instance starts on boot
+type VerifyResult struct {
+	Instance  string `json:"instance"`
+	Unit      string `json:"unit"`
+	State     string `json:"state"`               // the output of `systemctl is-enabled`
+	Restarted bool   `json:"restarted,omitempty"` // restarted as the canary of its component
+	Passed    bool   `json:"passed"`
+	Message   string `json:"message,omitempty"`
+}
+
+// ComponentClass classifies the components by whether they serve the
+// database or monitor it
+type ComponentClass string
+
+// the classes of components
+const (
+	ComponentClassAll        ComponentClass = "all"
+	ComponentClassCore       ComponentClass = "core"
+	ComponentClassMonitoring ComponentClass = "monitoring"
+)
+
+// ParseComponentClass parses the name of a component class, empty means the
+// instances of all the components, without the monitoring agents
+func ParseComponentClass(name string) (ComponentClass, error) {
+	switch c := ComponentClass(name); c {
+	case "", ComponentClassAll, ComponentClassCore, ComponentClassMonitoring:
+		return c, nil
+	}
+	return "", errors.Errorf("unknown component class %s, it should be one of all, core and monitoring", name)
+}
+
+// ClassOfComponent returns the class of the component
+func ClassOfComponent(name string) ComponentClass {
+	switch name {
+	case spec.ComponentPrometheus,
+		spec.ComponentGrafana,
+		spec.ComponentAlertManager,
+		spec.ComponentPushwaygate,
+		spec.ComponentNodeExporter,
+		spec.ComponentBlackboxExporter:
+		return ComponentClassMonitoring
+	}
+	return ComponentClassCore
+}
+
+// includes checks if the components of class are included in c
+func (c ComponentClass) includes(class ComponentClass) bool {
+	return c == "" || c == ComponentClassAll || c == class
+}
+
+// filterComponentClass returns the components in the class
+func filterComponentClass(comps []spec.Component, class ComponentClass) []spec.Component {
+	var res []spec.Component
+	for _, c := range comps {
+		if class.includes(ClassOfComponent(c.Name())) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// UnitResult is the result of enabling or disabling a unit
+type UnitResult struct {
+	Unit     string `json:"unit"`
+	Instance string `json:"instance,omitempty"` // empty for the monitoring agents
+	Missing  bool   `json:"missing,omitempty"`  // the unit file doesn't exist on the host
+	Error    string `json:"error,omitempty"`
+}
+
+// HostEnableResult is the results of enabling or disabling the units on a host
+type HostEnableResult struct {
+	Host  string        `json:"host"`
+	Units []*UnitResult `json:"units"`
+}
+
+// EnableReport is the structured result of enabling or disabling the
+// services of a cluster, some units may fail while others succeed
+type EnableReport struct {
+	Class ComponentClass      `json:"class"`
+	Hosts []*HostEnableResult `json:"hosts"`
+}
+
+// Failed returns the number of units failed
+func (r *EnableReport) Failed() int {
+	failed := 0
+	for _, h := range r.Hosts {
+		for _, u := range h.Units {
+			if u.Error != "" {
+				failed++
+			}
+		}
+	}
+	return failed
+}
+
+func (r *EnableReport) add(host string, result *UnitResult) {
+	for _, h := range r.Hosts {
+		if h.Host == host {
+			h.Units = append(h.Units, result)
+			return
+		}
+	}
+	r.Hosts = append(r.Hosts, &HostEnableResult{Host: host, Units: []*UnitResult{result}})
+}
+
+// isMissingUnit checks if the unit file doesn't exist on the host, rather
+// than guessing it from the messages of systemctl, which vary by version
+func isMissingUnit(e executor.Executor, unit string) bool {
+	stdout, _, err := e.Execute(fmt.Sprintf("test -e /etc/systemd/system/%s && echo exist || echo missing", unit), false)
+	return err == nil && strings.TrimSpace(string(stdout)) == "missing"
+}
+
+// Enable enables or disables the services of the components in
+// options.ComponentClass to start on boot. The monitoring agents are
+// included if the class is specified as monitoring or all and no role is
+// specified. All the units are tried even if some of them fail, the results
+// are reported per host.
+// See ExplainEnable for what is done.
+func Enable(
+	getter ExecutorGetter,
+	cluster spec.Topology,
+	options Options,
+	isEnable bool,
+) *EnableReport {
+	action := "disable"
+	if isEnable {
+		action = "enable"
+	}
+	class := options.ComponentClass
+	if class == "" {
+		class = ComponentClassAll
+	}
+	report := &EnableReport{Class: class}
+	for _, g := range enableGroups(cluster, options, action) {
+		for _, a := range g.Actions {
+			log.Infof("\t%s %s %s on %s", strings.Title(action), class, a.Unit, a.Host)
+			systemd := module.NewSystemdModule(systemdConfig(a.Unit, action, false, time.Second*time.Duration(options.OptTimeout)))
+			result := &UnitResult{Unit: a.Unit, Instance: a.Instance}
+			e := getter.Get(a.Host)
+			if _, stderr, err := systemd.Execute(e); err != nil {
+				result.Missing = isMissingUnit(e, a.Unit)
+				result.Error = fmt.Sprintf("%s: %s", err, strings.TrimSpace(string(stderr)))
+				log.Warnf("\tFailed to %s %s on %s: %s", action, a.Unit, a.Host, result.Error)
+			}
+			report.add(a.Host, result)
+		}
+	}
+	return report
+}
+
+// verifyInstance checks the unit file of the instance by `systemd-analyze verify`
+// and confirms the unit is enabled after reloading systemd
+func verifyInstance(getter ExecutorGetter, ins spec.Instance) *VerifyResult {
+	e := getter.Get(ins.GetHost())
+	unit := ins.ServiceName()
+	result := &VerifyResult{
+		Instance: ins.ID(),
+		Unit:     unit,
+	}
+
+	var messages []string
+	// syntax errors of unit files are only reported on daemon-reload, the
+	// command is skipped if systemd-analyze is not available
+	cmd := fmt.Sprintf("command -v systemd-analyze >/dev/null || exit 0; systemd-analyze verify /etc/systemd/system/%s", unit)
+	if _, stderr, err := e.Execute(cmd, true); err != nil {
+		messages = append(messages, fmt.Sprintf("verify failed: %s", strings.TrimSpace(string(stderr))))
+	}
+
+	stdout, stderr, err := e.Execute(fmt.Sprintf("systemctl daemon-reload && systemctl is-enabled %s", unit), true)
+	result.State = strings.TrimSpace(string(stdout))
+	if err != nil && result.State == "" {
+		result.State = "unknown"
+		messages = append(messages, strings.TrimSpace(string(stderr)))
+	}
+	if result.State != "enabled" {
+		messages = append(messages, "the unit is not enabled")
+	}
+
+	result.Passed = len(messages) == 0
+	result.Message = strings.Join(messages, "; ")
+	return result
+}
+
+// VerifyEnabled verifies the services of the cluster start on boot, the first
+// instance of each component is restarted as a canary if verifyStart is set
+func VerifyEnabled(
+	getter ExecutorGetter,
+	cluster spec.Topology,
+	options Options,
+	verifyStart bool,
+) []*VerifyResult {
+	var results []*VerifyResult
+
+	roleFilter := set.NewStringSet(options.Roles...)
+	nodeFilter := set.NewStringSet(options.Nodes...)
+	components := filterComponentClass(FilterComponent(cluster.ComponentsByStartOrder(), roleFilter), options.ComponentClass)
+	for _, com := range components {
+		canary := verifyStart
+		for _, ins := range FilterInstance(com.Instances(), nodeFilter) {
+			result := verifyInstance(getter, ins)
+			if canary && result.Passed {
+				canary = false
+				result.Restarted = true
+				if err := restartInstance(getter, ins, cluster.BaseTopo().GlobalOptions.User, options); err != nil {
+					result.Passed = false
+					result.Message = fmt.Sprintf("failed to restart: %s", err)
+				}
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
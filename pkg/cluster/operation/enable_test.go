@@ -0,0 +1,108 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// enabledUnits returns the units in the report sorted
+func enabledUnits(report *EnableReport) []string {
+	var units []string
+	for _, h := range report.Hosts {
+		for _, u := range h.Units {
+			units = append(units, u.Unit+"@"+h.Host)
+		}
+	}
+	sort.Strings(units)
+	return units
+}
+
+func TestEnableComponentClass(t *testing.T) {
+	topo := &spec.Specification{}
+	require.NoError(t, yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 10.0.1.1
+tidb_servers:
+  - host: 10.0.1.2
+monitoring_servers:
+  - host: 10.0.1.2
+`), topo))
+	newHosts := func() fakeHosts {
+		return fakeHosts{"10.0.1.1": executor.NewFake("10.0.1.1"), "10.0.1.2": executor.NewFake("10.0.1.2")}
+	}
+
+	for _, name := range []string{"", "all", "core", "monitoring"} {
+		_, err := ParseComponentClass(name)
+		assert.Nil(t, err, name)
+	}
+	_, err := ParseComponentClass("agents")
+	assert.NotNil(t, err)
+
+	// the monitoring agents are only included if the class is specified
+	for class, expected := range map[ComponentClass][]string{
+		"": {
+			"pd-2379.service@10.0.1.1",
+			"prometheus-9090.service@10.0.1.2",
+			"tidb-4000.service@10.0.1.2",
+		},
+		ComponentClassCore: {
+			"pd-2379.service@10.0.1.1",
+			"tidb-4000.service@10.0.1.2",
+		},
+		ComponentClassMonitoring: {
+			"blackbox_exporter-9115.service@10.0.1.1",
+			"blackbox_exporter-9115.service@10.0.1.2",
+			"node_exporter-9100.service@10.0.1.1",
+			"node_exporter-9100.service@10.0.1.2",
+			"prometheus-9090.service@10.0.1.2",
+		},
+		ComponentClassAll: {
+			"blackbox_exporter-9115.service@10.0.1.1",
+			"blackbox_exporter-9115.service@10.0.1.2",
+			"node_exporter-9100.service@10.0.1.1",
+			"node_exporter-9100.service@10.0.1.2",
+			"pd-2379.service@10.0.1.1",
+			"prometheus-9090.service@10.0.1.2",
+			"tidb-4000.service@10.0.1.2",
+		},
+	} {
+		report := Enable(newHosts(), topo, Options{ComponentClass: class}, false)
+		assert.Equal(t, expected, enabledUnits(report), "class: %s", class)
+		assert.Equal(t, 0, report.Failed())
+	}
+
+	// the failures are reported per host, the units are only marked as
+	// missing if their files don't exist
+	hosts := newHosts()
+	hosts["10.0.1.1"].Respond("systemctl daemon-reload && systemctl disable pd-2379.service", "", "Unit pd-2379.service not found")
+	hosts["10.0.1.1"].Respond("test -e /etc/systemd/system/pd-2379.service", "missing\n", "")
+	hosts["10.0.1.2"].Respond("systemctl daemon-reload && systemctl disable tidb-4000.service", "", "Connection not found")
+	hosts["10.0.1.2"].Respond("test -e /etc/systemd/system/tidb-4000.service", "exist\n", "")
+	report := Enable(hosts, topo, Options{ComponentClass: ComponentClassCore}, false)
+	assert.Equal(t, 2, report.Failed())
+	require.Len(t, report.Hosts, 2)
+	for _, h := range report.Hosts {
+		require.Len(t, h.Units, 1)
+		assert.NotEmpty(t, h.Units[0].Error)
+		assert.Equal(t, h.Host == "10.0.1.1", h.Units[0].Missing, h.Host)
+	}
+}
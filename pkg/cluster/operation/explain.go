@@ -0,0 +1,260 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/module"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// PlanAction is an action an operation performs on a systemd unit
+type PlanAction struct {
+	Instance string `json:"instance,omitempty"` // empty for the monitoring agents
+	Host     string `json:"host"`
+	Action   string `json:"action"`
+	Unit     string `json:"unit"`
+	Command  string `json:"command"` // the systemctl command executed on the host
+}
+
+// PlanGroup is the actions on the instances of a component, or on the
+// monitoring agents of some hosts. The actions in a group are performed
+// concurrently unless Serial, the groups are performed in order.
+type PlanGroup struct {
+	Component string        `json:"component,omitempty"` // empty for the monitoring agents
+	Agents    bool          `json:"agents,omitempty"`
+	Serial    bool          `json:"serial"`
+	Actions   []*PlanAction `json:"actions"`
+
+	// the instances acted on, one on each host for the monitoring agents
+	instances []spec.Instance
+}
+
+// Instances returns the instances the group acts on
+func (g *PlanGroup) Instances() []spec.Instance {
+	return g.instances
+}
+
+// Plan is what an operation does to a cluster, explained without connecting
+// to any host
+type Plan struct {
+	Operation string       `json:"operation"`
+	Groups    []*PlanGroup `json:"groups"`
+}
+
+// systemdConfig returns the config of the systemctl command performing the
+// action on the unit, the operations and their plans share it so the plans
+// tell the commands executed exactly
+func systemdConfig(unit, action string, enable bool, timeout time.Duration) module.SystemdModuleConfig {
+	return module.SystemdModuleConfig{
+		Unit:         unit,
+		ReloadDaemon: true, // always reload before operate
+		Action:       action,
+		Enabled:      enable,
+		Timeout:      timeout,
+	}
+}
+
+// agentUnit returns the systemd unit of the monitoring agent comp
+func agentUnit(comp string, options *spec.MonitoredOptions) string {
+	port := options.NodeExporterPort
+	if comp == spec.ComponentBlackboxExporter {
+		port = options.BlackboxExporterPort
+	}
+	return fmt.Sprintf("%s-%d.service", comp, port)
+}
+
+// agentComponents are the monitoring agents in the order they're operated
+var agentComponents = []string{spec.ComponentNodeExporter, spec.ComponentBlackboxExporter}
+
+func newAction(host, instance, unit, action string, enable bool) *PlanAction {
+	return &PlanAction{
+		Instance: instance,
+		Host:     host,
+		Action:   action,
+		Unit:     unit,
+		Command:  module.NewSystemdModule(systemdConfig(unit, action, enable, 0)).Command(),
+	}
+}
+
+// componentGroup returns the group performing the action on the instances
+func componentGroup(name string, insts []spec.Instance, action string, serial bool) *PlanGroup {
+	g := &PlanGroup{Component: name, Serial: serial, instances: insts}
+	for _, ins := range insts {
+		// the services are enabled when they're started
+		g.Actions = append(g.Actions, newAction(ins.GetHost(), ins.ID(), ins.ServiceName(), action, action == "start"))
+	}
+	return g
+}
+
+// agentsGroup returns the group performing the action on the monitoring
+// agents of the hosts of insts, the hosts without our agents are left out.
+// The hosts are operated one by one.
+func agentsGroup(insts []spec.Instance, options *spec.MonitoredOptions, action string) *PlanGroup {
+	g := &PlanGroup{Agents: true, Serial: true}
+	for _, ins := range insts {
+		if !options.DeployAgents(ins.GetHost()) {
+			continue
+		}
+		g.instances = append(g.instances, ins)
+		for _, comp := range agentComponents {
+			g.Actions = append(g.Actions, newAction(ins.GetHost(), "", agentUnit(comp, options), action, false))
+		}
+	}
+	return g
+}
+
+// appendGroup appends g to the groups if it has any action
+func appendGroup(groups []*PlanGroup, g *PlanGroup) []*PlanGroup {
+	if len(g.Actions) == 0 {
+		return groups
+	}
+	return append(groups, g)
+}
+
+// ExplainStart returns the plan of Start, nothing is executed
+func ExplainStart(cluster spec.Topology, options Options) *Plan {
+	return &Plan{Operation: "start", Groups: startGroups(cluster, options)}
+}
+
+// ExplainStop returns the plan of Stop, nothing is executed
+func ExplainStop(cluster spec.Topology, options Options) *Plan {
+	return &Plan{Operation: "stop", Groups: StopGroups(cluster, options)}
+}
+
+// ExplainRestart returns the plan of Restart, nothing is executed
+func ExplainRestart(cluster spec.Topology, options Options) *Plan {
+	return &Plan{
+		Operation: "restart",
+		Groups:    append(StopGroups(cluster, options), startGroups(cluster, options)...),
+	}
+}
+
+// ExplainEnable returns the plan of Enable, nothing is executed
+func ExplainEnable(cluster spec.Topology, options Options, isEnable bool) *Plan {
+	action := "disable"
+	if isEnable {
+		action = "enable"
+	}
+	return &Plan{Operation: action, Groups: enableGroups(cluster, options, action)}
+}
+
+func startGroups(cluster spec.Topology, options Options) []*PlanGroup {
+	var groups []*PlanGroup
+	uniqueHosts := set.NewStringSet()
+	roleFilter := set.NewStringSet(options.Roles...)
+	nodeFilter := set.NewStringSet(options.Nodes...)
+	monitored := cluster.GetMonitoredOptions()
+
+	// the agents of a host are started after the first component on it
+	newHosts := func(insts []spec.Instance) []spec.Instance {
+		var res []spec.Instance
+		for _, inst := range insts {
+			if !uniqueHosts.Exist(inst.GetHost()) {
+				uniqueHosts.Insert(inst.GetHost())
+				res = append(res, inst)
+			}
+		}
+		return res
+	}
+
+	for _, com := range FilterComponent(cluster.ComponentsByStartOrder(), roleFilter) {
+		insts := SelectInstance(FilterInstance(com.Instances(), nodeFilter), options.Selector)
+		groups = appendGroup(groups, componentGroup(com.Name(), insts, "start", options.Serial))
+		if hosts := newHosts(insts); monitored != nil {
+			groups = appendGroup(groups, agentsGroup(hosts, monitored, "start"))
+		}
+	}
+
+	// the agents selected explicitly are started on all the hosts, not only
+	// the ones of the components started above
+	if monitorAgentsSelected(roleFilter) && monitored != nil {
+		groups = appendGroup(groups, agentsGroup(newHosts(agentHosts(cluster, nodeFilter, options.Selector)), monitored, "start"))
+	}
+	return groups
+}
+
+// StopGroups returns the groups of instances Stop stops in order, the
+// monitoring agents of a host are stopped after the last instance on it
+func StopGroups(cluster spec.Topology, options Options) []*PlanGroup {
+	var groups []*PlanGroup
+	roleFilter := set.NewStringSet(options.Roles...)
+	nodeFilter := set.NewStringSet(options.Nodes...)
+	monitored := cluster.GetMonitoredOptions()
+
+	instCount := map[string]int{}
+	cluster.IterInstance(func(inst spec.Instance) {
+		instCount[inst.GetHost()]++
+	})
+
+	// the agents selected explicitly are stopped on all the hosts, even if
+	// other instances on the hosts are kept running
+	agentsSelected := monitorAgentsSelected(roleFilter)
+
+	for _, com := range FilterComponent(cluster.ComponentsByStopOrder(), roleFilter) {
+		insts := SelectInstance(FilterInstance(com.Instances(), nodeFilter), options.Selector)
+		groups = appendGroup(groups, componentGroup(com.Name(), insts, "stop", options.Serial))
+
+		// the agents of a host are stopped after the last instance on it
+		var idle []spec.Instance
+		for _, inst := range insts {
+			instCount[inst.GetHost()]--
+			if instCount[inst.GetHost()] == 0 {
+				idle = append(idle, inst)
+			}
+		}
+		if monitored != nil && !agentsSelected {
+			groups = appendGroup(groups, agentsGroup(idle, monitored, "stop"))
+		}
+	}
+
+	if agentsSelected && monitored != nil {
+		groups = appendGroup(groups, agentsGroup(agentHosts(cluster, nodeFilter, options.Selector), monitored, "stop"))
+	}
+	return groups
+}
+
+func enableGroups(cluster spec.Topology, options Options, action string) []*PlanGroup {
+	var groups []*PlanGroup
+	class := options.ComponentClass
+	if class == "" {
+		class = ComponentClassAll
+	}
+	roleFilter := set.NewStringSet(options.Roles...)
+	nodeFilter := set.NewStringSet(options.Nodes...)
+	for _, com := range filterComponentClass(FilterComponent(cluster.ComponentsByStartOrder(), roleFilter), class) {
+		groups = appendGroup(groups, componentGroup(com.Name(), FilterInstance(com.Instances(), nodeFilter), action, true))
+	}
+
+	// the agents are included only if the class is specified and no role is
+	monitored := cluster.GetMonitoredOptions()
+	if monitored == nil || len(options.Roles) > 0 || options.ComponentClass == "" ||
+		!class.includes(ComponentClassMonitoring) {
+		return groups
+	}
+	var insts []spec.Instance
+	hosts := set.NewStringSet()
+	cluster.IterInstance(func(ins spec.Instance) {
+		host := ins.GetHost()
+		if hosts.Exist(host) || (len(nodeFilter) > 0 && !nodeFilter.Exist(host)) {
+			return
+		}
+		hosts.Insert(host)
+		insts = append(insts, ins)
+	})
+	return appendGroup(groups, agentsGroup(insts, monitored, action))
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// fakeHosts gets the Fake executors of the hosts
+type fakeHosts map[string]*executor.Fake
+
+func (f fakeHosts) Get(host string) executor.Executor {
+	return f[host]
+}
+
+// systemdCommands returns the systemctl commands performing the actions
+// executed on each host in order
+func (f fakeHosts) systemdCommands() map[string][]string {
+	res := make(map[string][]string)
+	for host, e := range f {
+		for _, cmd := range e.Commands() {
+			if strings.HasPrefix(cmd, "systemctl daemon-reload && ") {
+				res[host] = append(res[host], cmd)
+			}
+		}
+	}
+	return res
+}
+
+// planCommands returns the commands of the plan on each host in order
+func planCommands(plan *Plan) map[string][]string {
+	res := make(map[string][]string)
+	for _, g := range plan.Groups {
+		for _, a := range g.Actions {
+			res[a.Host] = append(res[a.Host], a.Command)
+		}
+	}
+	return res
+}
+
+func TestExplain(t *testing.T) {
+	topo := &spec.Specification{}
+	require.NoError(t, yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 10.0.1.1
+tikv_servers:
+  - host: 10.0.1.1
+  - host: 10.0.1.2
+tidb_servers:
+  - host: 10.0.1.3
+`), topo))
+
+	plan := ExplainStart(topo, Options{})
+	assert.Equal(t, "start", plan.Operation)
+	var groups []string
+	for _, g := range plan.Groups {
+		if g.Agents {
+			groups = append(groups, "agents@"+g.Actions[0].Host)
+			continue
+		}
+		groups = append(groups, g.Component)
+	}
+	assert.Equal(t, []string{"pd", "agents@10.0.1.1", "tikv", "agents@10.0.1.2", "tidb", "agents@10.0.1.3"}, groups)
+	assert.Equal(t, &PlanAction{
+		Instance: "10.0.1.1:2379",
+		Host:     "10.0.1.1",
+		Action:   "start",
+		Unit:     "pd-2379.service",
+		Command:  "systemctl daemon-reload && systemctl start pd-2379.service && systemctl enable pd-2379.service",
+	}, plan.Groups[0].Actions[0])
+	assert.Equal(t, "node_exporter-9100.service", plan.Groups[1].Actions[0].Unit)
+	assert.Equal(t, "blackbox_exporter-9115.service", plan.Groups[1].Actions[1].Unit)
+
+	// the agents of a host are stopped with its last instance
+	plan = ExplainStop(topo, Options{Roles: []string{"tikv"}})
+	require.Len(t, plan.Groups, 2)
+	assert.Equal(t, "tikv", plan.Groups[0].Component)
+	assert.True(t, plan.Groups[1].Agents)
+	assert.Equal(t, "10.0.1.2", plan.Groups[1].Actions[0].Host)
+
+	plan = ExplainEnable(topo, Options{ComponentClass: ComponentClassMonitoring}, false)
+	require.Len(t, plan.Groups, 1)
+	assert.Len(t, plan.Groups[0].Actions, 6)
+	assert.Equal(t, "systemctl daemon-reload && systemctl disable node_exporter-9100.service", plan.Groups[0].Actions[0].Command)
+
+	// the plans match what the operations execute
+	for _, options := range []Options{{}, {Serial: true}, {Roles: []string{"tikv"}}, {Nodes: []string{"10.0.1.1:20160"}}} {
+		hosts := fakeHosts{}
+		for _, h := range []string{"10.0.1.1", "10.0.1.2", "10.0.1.3"} {
+			hosts[h] = executor.NewFake(h)
+		}
+		require.NoError(t, Restart(hosts, topo, options))
+		Enable(hosts, topo, options, true)
+
+		expected := planCommands(ExplainRestart(topo, options))
+		for host, cmds := range planCommands(ExplainEnable(topo, options, true)) {
+			expected[host] = append(expected[host], cmds...)
+		}
+		assert.Equal(t, expected, hosts.systemdCommands(), "options: %+v", options)
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// the results of provisioning a dashboard
+const (
+	DashboardUpdated = "updated"
+	DashboardSkipped = "skipped"
+	DashboardFailed  = "failed"
+)
+
+// DashboardResult is the result of provisioning a dashboard
+type DashboardResult struct {
+	Title  string `json:"title"`
+	UID    string `json:"uid"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// GrafanaProvisionReport is the result of provisioning the dashboards and the
+// datasource of a Grafana instance via its HTTP API
+type GrafanaProvisionReport struct {
+	Instance   string            `json:"instance"`
+	Folder     string            `json:"folder"`
+	Datasource string            `json:"datasource"` // created, updated or failed
+	Error      string            `json:"error,omitempty"`
+	Dashboards []DashboardResult `json:"dashboards"`
+}
+
+// GrafanaProvisionState is the versions of the dashboards saved by the last
+// provisioning, keyed by the instances and then the UIDs of the dashboards.
+// A dashboard with a different version is modified in Grafana since then.
+type GrafanaProvisionState map[string]map[string]int
+
+// invalid characters in the UIDs of Grafana, which can be 40 characters at most
+var grafanaUIDRegexp = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+func grafanaUID(s string) string {
+	uid := grafanaUIDRegexp.ReplaceAllString(s, "-")
+	if len(uid) > 40 {
+		uid = uid[:40]
+	}
+	return uid
+}
+
+// ProvisionGrafana provisions the dashboards and the Prometheus datasource of
+// the Grafana instances with the API provisioning, the dashboards are saved
+// into a folder named after the cluster. A dashboard modified in Grafana since
+// it was provisioned is skipped unless overwrite is set, the same for the
+// dashboards not provisioned via the API before. state is updated with the
+// saved versions.
+func ProvisionGrafana(
+	getter ExecutorGetter,
+	clusterName string,
+	topo *spec.Specification,
+	state GrafanaProvisionState,
+	overwrite bool,
+	options Options,
+) []*GrafanaProvisionReport {
+	var reports []*GrafanaProvisionReport
+	for _, inst := range (&spec.GrafanaComponent{Specification: topo}).Instances() {
+		s := inst.(*spec.GrafanaInstance).InstanceSpec.(spec.GrafanaSpec)
+		if !s.APIProvisioning() {
+			continue
+		}
+		report := &GrafanaProvisionReport{Instance: inst.ID(), Folder: clusterName}
+		reports = append(reports, report)
+		if err := provisionGrafana(getter, clusterName, topo, inst, s, state, overwrite, options, report); err != nil {
+			zap.L().Warn("Failed to provision grafana", zap.String("instance", inst.ID()), zap.Error(err))
+			report.Error = err.Error()
+		}
+	}
+	return reports
+}
+
+func provisionGrafana(
+	getter ExecutorGetter,
+	clusterName string,
+	topo *spec.Specification,
+	inst spec.Instance,
+	s spec.GrafanaSpec,
+	state GrafanaProvisionState,
+	overwrite bool,
+	options Options,
+	report *GrafanaProvisionReport,
+) error {
+	user, password, err := s.Credentials()
+	if err != nil {
+		return err
+	}
+	client := api.NewGrafanaClient(fmt.Sprintf("%s:%d", inst.GetHost(), inst.GetPort()), user, password, 10*time.Second)
+
+	reportWaiting(getter, "grafana %s to serve the API", inst.ID())
+	timeout := time.Duration(options.OptTimeout) * time.Second
+	if err := utils.Retry(client.CheckHealth, utils.RetryOption{Delay: time.Second, Timeout: timeout}); err != nil {
+		return errors.Annotatef(err, "grafana %s is not ready", inst.ID())
+	}
+
+	if len(topo.Monitors) > 0 {
+		created, err := client.UpsertDatasource(api.GrafanaDatasource{
+			Name:   clusterName,
+			Type:   "prometheus",
+			URL:    fmt.Sprintf("http://%s:%d", topo.Monitors[0].Host, topo.Monitors[0].Port),
+			Access: "proxy",
+		})
+		switch {
+		case err != nil:
+			report.Datasource = DashboardFailed
+			return errors.Annotate(err, "failed to provision the datasource")
+		case created:
+			report.Datasource = "created"
+		default:
+			report.Datasource = DashboardUpdated
+		}
+	}
+
+	folderID, err := client.EnsureFolder(grafanaUID(clusterName), clusterName)
+	if err != nil {
+		return errors.Annotate(err, "failed to create the folder")
+	}
+
+	// the dashboards with the cluster name filled in by the run script
+	e := getter.Get(inst.GetHost())
+	dir := filepath.Join(inst.DeployDir(), "dashboards")
+	stdout, _, err := e.Execute(fmt.Sprintf("ls %s/*.json", dir), false)
+	if err != nil {
+		return errors.Annotatef(err, "failed to list the dashboards in %s", dir)
+	}
+	files := strings.Fields(string(stdout))
+	sort.Strings(files)
+
+	versions := state[inst.ID()]
+	if versions == nil {
+		versions = make(map[string]int)
+		state[inst.ID()] = versions
+	}
+	for _, file := range files {
+		result := provisionDashboard(e, client, file, folderID, versions, overwrite)
+		report.Dashboards = append(report.Dashboards, result)
+	}
+	return nil
+}
+
+// provisionDashboard saves the dashboard in file to Grafana
+func provisionDashboard(
+	e executor.Executor,
+	client *api.GrafanaClient,
+	file string,
+	folderID int64,
+	versions map[string]int,
+	overwrite bool,
+) DashboardResult {
+	result := DashboardResult{Title: filepath.Base(file), Status: DashboardFailed}
+	data, _, err := e.Execute("cat "+file, false)
+	if err != nil {
+		result.Reason = err.Error()
+		return result
+	}
+	var dashboard map[string]interface{}
+	if err := json.Unmarshal(data, &dashboard); err != nil {
+		result.Reason = fmt.Sprintf("invalid dashboard: %s", err)
+		return result
+	}
+	if title, ok := dashboard["title"].(string); ok && title != "" {
+		result.Title = title
+	}
+	uid, _ := dashboard["uid"].(string)
+	if uid == "" {
+		uid = grafanaUID(strings.ToLower(result.Title))
+		dashboard["uid"] = uid
+	}
+	result.UID = uid
+
+	existing, err := client.GetDashboard(uid)
+	if err != nil {
+		result.Reason = err.Error()
+		return result
+	}
+	dashboard["id"] = nil
+	if existing != nil {
+		provisioned, ok := versions[uid]
+		switch {
+		case overwrite:
+		case !ok:
+			result.Status = DashboardSkipped
+			result.Reason = "not provisioned by TiUP"
+			return result
+		case provisioned != existing.Version:
+			result.Status = DashboardSkipped
+			result.Reason = fmt.Sprintf("modified in grafana (version %d, provisioned %d)", existing.Version, provisioned)
+			return result
+		}
+		dashboard["id"] = existing.ID
+		dashboard["version"] = existing.Version
+	}
+
+	saved, err := client.SaveDashboard(dashboard, folderID, overwrite)
+	if err != nil {
+		if api.IsGrafanaStatus(err, http.StatusPreconditionFailed) {
+			// modified between the check and the save
+			result.Status = DashboardSkipped
+			result.Reason = "modified in grafana"
+			return result
+		}
+		result.Reason = err.Error()
+		return result
+	}
+	versions[uid] = saved.Version
+	result.Status = DashboardUpdated
+	return result
+}
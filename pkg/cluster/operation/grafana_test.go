@@ -0,0 +1,141 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGrafana keeps the versions of the dashboards saved, the saves with
+// stale versions are rejected like Grafana does
+type fakeGrafana struct {
+	mu       sync.Mutex
+	versions map[string]int
+	saves    int
+	conflict bool // modified by someone else right before the next save
+}
+
+func (g *fakeGrafana) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	reply := func(code int, resp interface{}) {
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/dashboards/uid/"):
+		uid := strings.TrimPrefix(r.URL.Path, "/api/dashboards/uid/")
+		version, ok := g.versions[uid]
+		if !ok {
+			reply(http.StatusNotFound, map[string]string{"message": "Dashboard not found"})
+			return
+		}
+		reply(http.StatusOK, map[string]interface{}{"dashboard": map[string]interface{}{"id": 1, "uid": uid, "version": version}})
+	case r.Method == http.MethodPost && r.URL.Path == "/api/dashboards/db":
+		var req struct {
+			Dashboard map[string]interface{} `json:"dashboard"`
+			Overwrite bool                   `json:"overwrite"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		uid := req.Dashboard["uid"].(string)
+		version, _ := req.Dashboard["version"].(float64)
+		if g.conflict {
+			g.conflict = false
+			g.versions[uid]++
+		}
+		if existing, ok := g.versions[uid]; ok && !req.Overwrite && int(version) != existing {
+			reply(http.StatusPreconditionFailed, map[string]string{"message": "The dashboard has been changed by someone else"})
+			return
+		}
+		g.saves++
+		g.versions[uid]++
+		reply(http.StatusOK, map[string]interface{}{"id": 1, "uid": uid, "version": g.versions[uid]})
+	default:
+		reply(http.StatusNotFound, map[string]string{"message": "Not found"})
+	}
+}
+
+// modify simulates modifying the dashboard in Grafana
+func (g *fakeGrafana) modify(uid string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.versions[uid]++
+}
+
+func TestProvisionDashboard(t *testing.T) {
+	grafana := &fakeGrafana{versions: make(map[string]int)}
+	srv := httptest.NewServer(grafana)
+	defer srv.Close()
+	client := api.NewGrafanaClient(strings.TrimPrefix(srv.URL, "http://"), "admin", "admin", 5*time.Second)
+
+	e := executor.NewFake("10.0.1.1")
+	e.Respond("cat /dashboards/overview.json", `{"title": "Cluster Overview", "uid": "overview", "id": 12}`, "")
+	e.Respond("cat /dashboards/tikv.json", `{"title": "TiKV Details"}`, "")
+	e.Respond("cat /dashboards/broken.json", `{"title": `, "")
+	e.Respond("cat /dashboards/lost.json", "", "No such file or directory")
+	versions := make(map[string]int)
+
+	// the new dashboards are saved, the UIDs are derived from the titles if
+	// they're missing
+	result := provisionDashboard(e, client, "/dashboards/overview.json", 3, versions, false)
+	assert.Equal(t, DashboardResult{Title: "Cluster Overview", UID: "overview", Status: DashboardUpdated}, result)
+	result = provisionDashboard(e, client, "/dashboards/tikv.json", 3, versions, false)
+	assert.Equal(t, DashboardResult{Title: "TiKV Details", UID: "tikv-details", Status: DashboardUpdated}, result)
+	assert.Equal(t, map[string]int{"overview": 1, "tikv-details": 1}, versions)
+
+	// the dashboards unchanged since provisioned are updated
+	result = provisionDashboard(e, client, "/dashboards/overview.json", 3, versions, false)
+	assert.Equal(t, DashboardUpdated, result.Status)
+	assert.Equal(t, 2, versions["overview"])
+
+	// the dashboards modified in Grafana are kept unless overwritten
+	grafana.modify("overview")
+	result = provisionDashboard(e, client, "/dashboards/overview.json", 3, versions, false)
+	assert.Equal(t, DashboardSkipped, result.Status)
+	assert.Equal(t, "modified in grafana (version 3, provisioned 2)", result.Reason)
+	result = provisionDashboard(e, client, "/dashboards/overview.json", 3, versions, true)
+	assert.Equal(t, DashboardUpdated, result.Status)
+	assert.Equal(t, 4, versions["overview"])
+
+	// the dashboards not provisioned by TiUP are kept unless overwritten
+	result = provisionDashboard(e, client, "/dashboards/tikv.json", 3, map[string]int{}, false)
+	assert.Equal(t, DashboardSkipped, result.Status)
+	assert.Equal(t, "not provisioned by TiUP", result.Reason)
+
+	// the dashboards modified between the check and the save are kept
+	grafana.conflict = true
+	result = provisionDashboard(e, client, "/dashboards/overview.json", 3, versions, false)
+	assert.Equal(t, DashboardSkipped, result.Status)
+	assert.Equal(t, "modified in grafana", result.Reason)
+	assert.Equal(t, 4, versions["overview"])
+
+	saves := grafana.saves
+	result = provisionDashboard(e, client, "/dashboards/broken.json", 3, versions, false)
+	assert.Equal(t, DashboardFailed, result.Status)
+	assert.Contains(t, result.Reason, "invalid dashboard")
+	result = provisionDashboard(e, client, "/dashboards/lost.json", 3, versions, false)
+	assert.Equal(t, DashboardFailed, result.Status)
+	assert.Equal(t, "lost.json", result.Title)
+	assert.Equal(t, saves, grafana.saves)
+}
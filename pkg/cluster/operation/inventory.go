@@ -0,0 +1,166 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb-insight/collector/insight"
+)
+
+// InventoryScript prints the disks of the directories in the arguments, the
+// mount options of the filesystems and the physical NICs of the host, as
+// tab separated lines led by the type of the line
+const InventoryScript = `#!/bin/bash
+for dir in "$@"; do
+    # the directory may not be created yet, use its nearest existing parent
+    d="$dir"
+    while [ ! -e "$d" ] && [ "$d" != "/" ]; do d=$(dirname "$d"); done
+    df -PTk "$d" 2>/dev/null | awk -v dir="$dir" 'NR==2 {printf "disk\t%s\t%s\t%s\t%s\t%s\t%s\n", dir, $1, $2, $3, $5, $7}'
+done
+while read -r dev mnt fs opts rest; do
+    printf "mount\t%s\t%s\n" "$mnt" "$opts"
+done < /proc/mounts
+for nic in /sys/class/net/*; do
+    [ -e "$nic/device" ] || continue
+    printf "nic\t%s\t%s\t%s\t%s\n" "$(basename "$nic")" "$(cat "$nic/speed" 2>/dev/null || echo -1)" \
+        "$(cat "$nic/mtu" 2>/dev/null)" "$(cat "$nic/operstate" 2>/dev/null)"
+done
+`
+
+// HostInventory is the hardware and OS inventory of a host
+type HostInventory struct {
+	Host       string               `json:"host"`
+	CPUModel   string               `json:"cpu_model,omitempty"`
+	CPUCores   uint                 `json:"cpu_cores,omitempty"`   // physical cores
+	CPUThreads uint                 `json:"cpu_threads,omitempty"` // logical cores
+	MemoryMB   uint                 `json:"memory_mb,omitempty"`
+	SwapMB     uint                 `json:"swap_mb,omitempty"`
+	Kernel     string               `json:"kernel,omitempty"`
+	OSRelease  string               `json:"os_release,omitempty"`
+	Arch       string               `json:"arch,omitempty"`
+	Disks      []DiskInventory      `json:"disks,omitempty"`
+	NICs       []NICInventory       `json:"nics,omitempty"`
+	Components []ComponentInventory `json:"components,omitempty"`
+	// Error is why the inventory of the host is incomplete
+	Error string `json:"error,omitempty"`
+}
+
+// DiskInventory is the disk a data directory is on
+type DiskInventory struct {
+	Dir          string `json:"dir"`
+	Device       string `json:"device"`
+	FSType       string `json:"fs_type"`
+	MountPoint   string `json:"mount_point"`
+	MountOptions string `json:"mount_options,omitempty"`
+	SizeBytes    uint64 `json:"size_bytes"`
+	AvailBytes   uint64 `json:"avail_bytes"`
+}
+
+// NICInventory is a physical network interface
+type NICInventory struct {
+	Name      string   `json:"name"`
+	SpeedMbps int      `json:"speed_mbps"` // -1 if the link is down or the speed is unknown
+	MTU       int      `json:"mtu,omitempty"`
+	State     string   `json:"state,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// ComponentInventory is a component deployed on a host
+type ComponentInventory struct {
+	Component string   `json:"component"`
+	Version   string   `json:"version"`
+	Instances []string `json:"instances"`
+}
+
+// ParseInsight fills the inventory with the output of the insight collector,
+// the addresses of NICs are only filled if ParseInventoryScript is called first
+func (inv *HostInventory) ParseInsight(data []byte) error {
+	var info insight.InsightInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return errors.Annotate(err, "failed to parse the system info")
+	}
+	sys := info.SysInfo
+	inv.CPUModel = sys.CPU.Model
+	inv.CPUCores = sys.CPU.Cores
+	inv.CPUThreads = sys.CPU.Threads
+	inv.MemoryMB = sys.Memory.Size
+	inv.SwapMB = sys.Memory.Swap
+	inv.Kernel = sys.Kernel.Release
+	inv.OSRelease = strings.TrimSpace(sys.OS.Name + " " + sys.OS.Version)
+	inv.Arch = sys.Kernel.Architecture
+
+	addrs := make(map[string][]string)
+	for _, dev := range sys.Network {
+		addrs[dev.Name] = dev.IPAddress
+	}
+	for i := range inv.NICs {
+		inv.NICs[i].Addresses = addrs[inv.NICs[i].Name]
+	}
+	return nil
+}
+
+// ParseInventoryScript fills the inventory with the output of InventoryScript
+func (inv *HostInventory) ParseInventoryScript(data []byte) error {
+	mountOptions := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		switch {
+		case fields[0] == "disk" && len(fields) == 7:
+			size, err := strconv.ParseUint(fields[4], 10, 64)
+			if err != nil {
+				return errors.Annotatef(err, "invalid size of disk %s", fields[2])
+			}
+			avail, err := strconv.ParseUint(fields[5], 10, 64)
+			if err != nil {
+				return errors.Annotatef(err, "invalid available size of disk %s", fields[2])
+			}
+			inv.Disks = append(inv.Disks, DiskInventory{
+				Dir:        fields[1],
+				Device:     fields[2],
+				FSType:     fields[3],
+				SizeBytes:  size * 1024,
+				AvailBytes: avail * 1024,
+				MountPoint: fields[6],
+			})
+		case fields[0] == "mount" && len(fields) == 3:
+			mountOptions[fields[1]] = fields[2]
+		case fields[0] == "nic" && len(fields) == 5:
+			speed, err := strconv.Atoi(fields[2])
+			if err != nil {
+				speed = -1
+			}
+			mtu, _ := strconv.Atoi(fields[3])
+			inv.NICs = append(inv.NICs, NICInventory{
+				Name:      fields[1],
+				SpeedMbps: speed,
+				MTU:       mtu,
+				State:     fields[4],
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Trace(err)
+	}
+	for i := range inv.Disks {
+		inv.Disks[i].MountOptions = mountOptions[inv.Disks[i].MountPoint]
+	}
+	return nil
+}
@@ -15,6 +15,7 @@ package operator
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/pingcap/tiup/pkg/cluster/executor"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
@@ -23,14 +24,124 @@ import (
 
 // Options represents the operation options
 type Options struct {
-	Roles             []string
-	Nodes             []string
-	Force             bool  // Option for upgrade subcommand
-	SSHTimeout        int64 // timeout in seconds when connecting an SSH server
-	OptTimeout        int64 // timeout in seconds for operations that support it, not to confuse with SSH timeout
-	APITimeout        int64 // timeout in seconds for API operations that support it, like transfering store leader
-	IgnoreConfigCheck bool  // should we ignore the config check result after init config
-	NativeSSH         bool  // should use native ssh client or builtin easy ssh
+	Roles              []string
+	Nodes              []string
+	Force              bool  // Option for upgrade subcommand, and starting with nearly full data directories
+	SSHTimeout         int64 // timeout in seconds when connecting an SSH server
+	OptTimeout         int64 // timeout in seconds for operations that support it, not to confuse with SSH timeout
+	FirstStartTimeout  int64 // timeout in seconds to start the instances deployed with empty data dirs, 0 uses OptTimeout
+	RestartTimeout     int64 // timeout in seconds to start or restart the instances started before, 0 uses OptTimeout
+	APITimeout         int64 // timeout in seconds for API operations that support it, like transfering store leader
+	OperationTimeout   int64 // the whole operation is aborted if it's not finished in so many seconds, 0 means unlimited
+	IgnoreConfigCheck  bool  // should we ignore the config check result after init config
+	IgnoreVersionCheck bool  // accept the versions not valid SemVer strings as they are, e.g., of custom builds
+	NativeSSH          bool  // should use native ssh client or builtin easy ssh
+	IgnoreErrors       bool  // continue when some instances fail, the failures are reported at the end, same as ErrorScopeStep
+	OverwriteConfig    bool  // overwrite the configs on the hosts pushed by concurrent operations
+	SkipConfirm        bool  // skip the confirmations and assume 'yes'
+
+	// How far a failure reaches in the operation, see ErrorScope and
+	// EffectiveErrorScope for the default
+	ErrorScope ErrorScope
+
+	// Allow destroying, cleaning or scaling in a protected cluster
+	OverrideProtection bool
+
+	// Only enable or disable the components of the class, empty means the
+	// instances of all the components, without the monitoring agents
+	ComponentClass ComponentClass
+
+	// Only upgrade the components, the others keep their versions, empty means all
+	Components []string
+
+	// Only operate the instances matched by the selector, nil matches all
+	Selector *spec.Selector
+	// Print the instances matched by the roles, nodes and selector before operating
+	ExplainSelector bool
+
+	// Start and stop the instances one at a time in the component order, instead
+	// of all the instances of a component at once, for the environments that
+	// fall over when many instances start simultaneously
+	Serial bool
+	// The max number of tasks of a parallel step executing at the same time,
+	// e.g., the instances configured or the hosts checked, 0 means unlimited
+	Concurrency int
+
+	// Delay the k-th instance of a component started at once by k*StartStagger
+	// plus a random jitter up to StartStaggerJitter, so that they don't saturate
+	// the storage shared by them, see StaggerSchedule. Stopping isn't delayed.
+	StartStagger       time.Duration
+	StartStaggerJitter time.Duration
+
+	// Wait between upgrading the TiKV instances until PD reports the regions
+	// are healthy again, see ReadinessGate
+	ReadinessGate bool
+	// The max number of regions with pending peers to pass the gate
+	ReadinessMaxPendingPeers int
+	// The max spread of the region scores of the up stores to pass the gate,
+	// in percent of the highest one, 0 doesn't check the balance
+	ReadinessMaxScoreSpread float64
+	// Seconds to wait for the regions to recover before failing the operation
+	ReadinessTimeout int64
+
+	// Kill the processes left under the directories of instances after stopping them
+	KillOrphans bool
+	// Seconds to wait for the orphaned processes to exit after SIGTERM before SIGKILL
+	OrphanGracePeriod int64
+
+	// Split the files larger than it (in MiB) into chunks transferred in parallel, 0 disables chunking
+	TransferChunkSize int64
+	// The max number of SSH sessions transferring the chunks of a file at the same time
+	TransferParallel int
+
+	// Keep the pushed component packages on hosts and reuse them if checksums match
+	CachePackages bool
+	// Hosts to push component packages to first, other hosts fetch the packages from them
+	SeedHosts []string
+	// Take the component packages from the directory instead of the repository, for
+	// air-gapped sites, see operator.ImportPackage for the layout
+	PackageDir string
+	// Fail the operation if the control machine fails the self checks, they are
+	// only warned otherwise, see cluster.SelfCheck
+	StrictSelfCheck bool
+
+	// Silence the alerts of the affected instances in Alertmanager during the operation
+	SilenceAlerts bool
+	// Fail the operation if the alerts can't be silenced, it's only warned otherwise
+	RequireSilence bool
+
+	// Overwrite the dashboards modified in Grafana when provisioning them via the API
+	OverwriteDashboards bool
+
+	// The usage percentages of the filesystems of data directories to warn and
+	// to refuse starting the instances above, 0 disables the threshold
+	DiskUsageWarn int64
+	DiskUsageFail int64
+
+	// Pause before the steps with the names or identities, e.g.
+	// "UpgradeInstance tikv 10.0.0.7:20160", until the operation is resumed
+	Breakpoints []string
+	// Pause at the first failure of the errorx types, e.g. "executor.ssh_execute_failed"
+	BreakOnErrors []string
+	// Print the commands executed on the hosts to stderr, the outputs are
+	// printed once the commands finish, see task.HostFollowers
+	FollowHosts []string
+
+	// Serve pprof of tiup itself on the localhost address during the operation,
+	// and capture a CPU profile and a heap snapshot into the log directory of
+	// the cluster once the operation runs longer than ProfileAfter seconds, or
+	// the heap grows above ProfileAboveMemory MiB, 0 disables the threshold
+	PprofAddr          string
+	ProfileAfter       int64
+	ProfileAboveMemory int64
+
+	// Gather the CPU, memory, open files and data size of the instances in the
+	// status, each host is given ResourcesTimeout seconds, 0 uses a default
+	Resources        bool
+	ResourcesTimeout int64
+
+	// Only print the task plan in the format instead of executing it
+	PlanFormat string
 
 	// What type of things should we cleanup in clean command
 	CleanupData bool // should we cleanup data
@@ -41,6 +152,56 @@ type Options struct {
 	RetainDataNodes []string
 }
 
+// ErrorScope is how far a failure reaches in an operation, the steps are
+// the tasks displayed as a line of progress, or the tasks not grouped in
+// such a line. Whatever the scope is, the failures are all recorded in the
+// result of the operation, including the ones not failing it.
+type ErrorScope string
+
+// the scopes of failures
+const (
+	// ErrorScopeOperation fails the operation at the first failure, the
+	// steps not started yet are not executed
+	ErrorScopeOperation ErrorScope = "operation"
+	// ErrorScopeHost skips the rest steps on the hosts of a failed step, the
+	// steps on other hosts keep executing, and the failures are reported at
+	// the end. A failure not attributed to any host fails the operation.
+	ErrorScopeHost ErrorScope = "host"
+	// ErrorScopeStep only fails the failed step, the rest steps keep
+	// executing even on the same host, and the failures are reported at the end
+	ErrorScopeStep ErrorScope = "step"
+)
+
+// ErrorScopes are the valid scopes of failures
+var ErrorScopes = []ErrorScope{ErrorScopeOperation, ErrorScopeHost, ErrorScopeStep}
+
+// Valid checks if the scope is one of ErrorScopes, empty is valid as it's
+// the default
+func (s ErrorScope) Valid() bool {
+	if s == "" {
+		return true
+	}
+	for _, scope := range ErrorScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveErrorScope returns the scope of failures of the options, it's
+// ErrorScopeStep if it's not set but IgnoreErrors is, or ErrorScopeOperation
+func (opt Options) EffectiveErrorScope() ErrorScope {
+	switch {
+	case opt.ErrorScope != "":
+		return opt.ErrorScope
+	case opt.IgnoreErrors:
+		return ErrorScopeStep
+	default:
+		return ErrorScopeOperation
+	}
+}
+
 // Operation represents the type of cluster operation
 type Operation byte
 
@@ -109,7 +270,92 @@ func FilterInstance(instances []spec.Instance, nodes set.StringSet) (res []spec.
 	return
 }
 
+// SelectInstance filters instances by the selector, all of them are returned
+// if the selector is nil
+func SelectInstance(instances []spec.Instance, selector *spec.Selector) (res []spec.Instance) {
+	if selector == nil {
+		return instances
+	}
+
+	for _, inst := range instances {
+		if selector.Match(inst) {
+			res = append(res, inst)
+		}
+	}
+
+	return
+}
+
 // ExecutorGetter get the executor by host.
 type ExecutorGetter interface {
 	Get(host string) (e executor.Executor)
 }
+
+// PhaseReporter is implemented by the ExecutorGetter reporting what the
+// operation is doing to the step using it, e.g., waiting for a port
+type PhaseReporter interface {
+	ReportWaiting(detail string)
+}
+
+// ProgressReporter is implemented by the ExecutorGetter tracking how much
+// of the operation using it is done, e.g., the instances restarted
+type ProgressReporter interface {
+	ReportProgress(percent int)
+}
+
+// Breaker is implemented by the ExecutorGetter pausing the operation before
+// the steps which are breakpoints
+type Breaker interface {
+	Break(step string)
+}
+
+// DeadlineChecker is implemented by the ExecutorGetter aborting the
+// operation once its deadline is exceeded, the operator functions check it
+// at the points safe to stop, e.g., between restarting two instances, and
+// bound their waits by Deadline
+type DeadlineChecker interface {
+	CheckDeadline(step string) error
+	// Deadline returns the deadline of the operation, false if there is none
+	Deadline() (time.Time, bool)
+}
+
+// checkDeadline returns the error of the getter if it's a DeadlineChecker and
+// the deadline of the operation is exceeded before the step
+func checkDeadline(getter ExecutorGetter, format string, args ...interface{}) error {
+	if c, ok := getter.(DeadlineChecker); ok {
+		return c.CheckDeadline(fmt.Sprintf(format, args...))
+	}
+	return nil
+}
+
+// operationDeadline returns the deadline of the operation if the getter is a
+// DeadlineChecker, false is returned if there is none
+func operationDeadline(getter ExecutorGetter) (time.Time, bool) {
+	if c, ok := getter.(DeadlineChecker); ok {
+		return c.Deadline()
+	}
+	return time.Time{}, false
+}
+
+// breakBefore pauses before the step if the getter is a Breaker and the step
+// is a breakpoint
+func breakBefore(getter ExecutorGetter, format string, args ...interface{}) {
+	if b, ok := getter.(Breaker); ok {
+		b.Break(fmt.Sprintf(format, args...))
+	}
+}
+
+// reportProgress reports done of total parts of the operation are finished to
+// the getter if it's a ProgressReporter
+func reportProgress(getter ExecutorGetter, done, total int) {
+	if r, ok := getter.(ProgressReporter); ok && total > 0 {
+		r.ReportProgress(done * 100 / total)
+	}
+}
+
+// reportWaiting reports the operation is waiting to the getter if it's a PhaseReporter
+func reportWaiting(getter ExecutorGetter, format string, args ...interface{}) {
+	if r, ok := getter.(PhaseReporter); ok {
+		r.ReportWaiting(fmt.Sprintf(format, args...))
+	}
+}
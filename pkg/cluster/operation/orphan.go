@@ -0,0 +1,210 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// the default seconds to wait for orphaned processes to exit after SIGTERM
+const defaultOrphanGracePeriod = 10
+
+// listProcLinksCmd lists the cwd and binary path of all processes in lines of
+// `/proc/<pid>\t<cwd|exe>\t<path>`, processes may exit while being listed so
+// the errors are ignored
+const listProcLinksCmd = `find /proc -mindepth 2 -maxdepth 2 \( -name cwd -o -name exe \) -printf '%h\t%f\t%l\n' 2>/dev/null; true`
+
+// OrphanProcess is a process left running under the directories of an
+// instance after the instance is stopped
+type OrphanProcess struct {
+	PID      int    `json:"pid"`
+	Instance string `json:"instance"`
+	Path     string `json:"path"` // the cwd or binary path under the directories of the instance
+	Killed   bool   `json:"killed"`
+}
+
+// HostStopReport is the result of verifying that no process is left running
+// on a host after stopping the instances on it
+type HostStopReport struct {
+	Host    string           `json:"host"`
+	Orphans []*OrphanProcess `json:"orphans,omitempty"`
+	Safe    bool             `json:"safe"`            // no process is left running under the directories of instances
+	Error   string           `json:"error,omitempty"` // the error listing or killing processes
+}
+
+// StopReport is the structured result of stopping a cluster
+type StopReport struct {
+	Hosts []*HostStopReport `json:"hosts"`
+	Safe  bool              `json:"safe"` // all the hosts are safe to patch or reboot
+}
+
+// Orphans returns the orphaned processes not killed on all hosts
+func (r *StopReport) Orphans() []*OrphanProcess {
+	var orphans []*OrphanProcess
+	for _, h := range r.Hosts {
+		for _, p := range h.Orphans {
+			if !p.Killed {
+				orphans = append(orphans, p)
+			}
+		}
+	}
+	return orphans
+}
+
+// VerifyStopped finds the processes whose cwd or binary path falls under the
+// deploy or data directories of the stopped instances, they are killed by
+// SIGTERM and then SIGKILL after the grace period if options.KillOrphans is set
+func VerifyStopped(
+	getter ExecutorGetter,
+	cluster spec.Topology,
+	options Options,
+) *StopReport {
+	// the directories of instances on each host
+	hostDirs := make(map[string]map[string]string)
+	var hosts []string
+	roleFilter := set.NewStringSet(options.Roles...)
+	nodeFilter := set.NewStringSet(options.Nodes...)
+	for _, com := range FilterComponent(cluster.ComponentsByStopOrder(), roleFilter) {
+		for _, ins := range SelectInstance(FilterInstance(com.Instances(), nodeFilter), options.Selector) {
+			dirs, ok := hostDirs[ins.GetHost()]
+			if !ok {
+				dirs = make(map[string]string)
+				hostDirs[ins.GetHost()] = dirs
+				hosts = append(hosts, ins.GetHost())
+			}
+			for _, dir := range instanceDirs(cluster.BaseTopo().GlobalOptions.User, ins) {
+				dirs[dir] = ins.ID()
+			}
+		}
+	}
+
+	report := &StopReport{Safe: true}
+	for _, host := range hosts {
+		h := verifyHostStopped(getter, host, hostDirs[host], options)
+		report.Hosts = append(report.Hosts, h)
+		report.Safe = report.Safe && h.Safe
+	}
+	return report
+}
+
+// instanceDirs returns the absolute deploy and data directories of the
+// instance, the relative ones are under the home of the deploy user
+func instanceDirs(user string, ins spec.Instance) []string {
+	var dirs []string
+	for _, dir := range append([]string{ins.DeployDir()}, strings.Split(ins.DataDir(), ",")...) {
+		dir = strings.TrimSpace(dir)
+		if dir == "" {
+			continue
+		}
+		if dir = filepath.Clean(clusterutil.Abs(user, dir)); dir != "/" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+func verifyHostStopped(getter ExecutorGetter, host string, dirs map[string]string, options Options) *HostStopReport {
+	report := &HostStopReport{Host: host}
+	e := getter.Get(host)
+
+	stdout, stderr, err := e.Execute(listProcLinksCmd, true)
+	if err != nil {
+		report.Error = fmt.Sprintf("failed to list processes: %s", strings.TrimSpace(string(stderr)))
+		return report
+	}
+	report.Orphans = findOrphans(string(stdout), dirs)
+	if len(report.Orphans) == 0 || !options.KillOrphans {
+		report.Safe = len(report.Orphans) == 0
+		return report
+	}
+
+	pids := make([]string, 0, len(report.Orphans))
+	for _, p := range report.Orphans {
+		pids = append(pids, strconv.Itoa(p.PID))
+	}
+	log.Infof("\tKilling orphaned processes %s on %s", strings.Join(pids, ","), host)
+	if _, stderr, err := e.Execute(killCmd(pids, options.OrphanGracePeriod), true); err != nil {
+		report.Error = fmt.Sprintf("failed to kill processes: %s", strings.TrimSpace(string(stderr)))
+	}
+
+	// check again, the processes still listed survived SIGKILL
+	stdout, stderr, err = e.Execute(listProcLinksCmd, true)
+	if err != nil {
+		report.Error = fmt.Sprintf("failed to list processes: %s", strings.TrimSpace(string(stderr)))
+		return report
+	}
+	survivors := make(map[int]bool)
+	for _, p := range findOrphans(string(stdout), dirs) {
+		survivors[p.PID] = true
+	}
+	for _, p := range report.Orphans {
+		p.Killed = !survivors[p.PID]
+	}
+	report.Safe = len(survivors) == 0 && report.Error == ""
+	return report
+}
+
+// killCmd sends SIGTERM to the processes, and SIGKILL to the ones not exited
+// after the grace period
+func killCmd(pids []string, gracePeriod int64) string {
+	if gracePeriod <= 0 {
+		gracePeriod = defaultOrphanGracePeriod
+	}
+	seconds := make([]string, gracePeriod)
+	for i := range seconds {
+		seconds[i] = strconv.Itoa(i + 1)
+	}
+	return fmt.Sprintf("kill -TERM %[1]s 2>/dev/null; for i in %[2]s; do ps -p %[3]s >/dev/null || break; sleep 1; done; kill -KILL %[1]s 2>/dev/null; true",
+		strings.Join(pids, " "), strings.Join(seconds, " "), strings.Join(pids, ","))
+}
+
+// findOrphans parses the output of listProcLinksCmd, and returns the
+// processes whose cwd or binary path falls under one of the directories
+func findOrphans(output string, dirs map[string]string) []*OrphanProcess {
+	found := make(map[int]*OrphanProcess)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.TrimPrefix(fields[0], "/proc/"))
+		if err != nil || found[pid] != nil {
+			continue
+		}
+		// the binary path of a process is suffixed if the file is replaced
+		path := strings.TrimSuffix(fields[2], " (deleted)")
+		for dir, ins := range dirs {
+			if path == dir || strings.HasPrefix(path, dir+"/") {
+				found[pid] = &OrphanProcess{PID: pid, Instance: ins, Path: path}
+				break
+			}
+		}
+	}
+
+	orphans := make([]*OrphanProcess, 0, len(found))
+	for _, p := range found {
+		orphans = append(orphans, p)
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].PID < orphans[j].PID })
+	return orphans
+}
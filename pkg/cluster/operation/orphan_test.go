@@ -0,0 +1,123 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// procExecutor lists the processes in turn from listings, the last one is
+// kept once the others are consumed
+type procExecutor struct {
+	*executor.Fake
+	listings []string
+}
+
+func (e *procExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	if cmd != listProcLinksCmd {
+		return e.Fake.Execute(cmd, sudo, timeout...)
+	}
+	out := e.listings[0]
+	if len(e.listings) > 1 {
+		e.listings = e.listings[1:]
+	}
+	return []byte(out), nil, nil
+}
+
+type procHosts map[string]*procExecutor
+
+func (h procHosts) Get(host string) executor.Executor {
+	return h[host]
+}
+
+func TestVerifyStopped(t *testing.T) {
+	// the relative dirs are under the home of the deploy user
+	topo := &spec.Specification{}
+	require.NoError(t, yaml.Unmarshal([]byte(`
+tikv_servers:
+  - host: 10.0.1.1
+    deploy_dir: deploy/tikv-20160
+    data_dir: /data/tikv-20160
+pd_servers:
+  - host: 10.0.1.2
+    deploy_dir: /home/tidb/deploy/pd-2379
+    data_dir: /data/pd-2379
+`), topo))
+	// the processes under the directories of tikv, and an unrelated one
+	listing := strings.Join([]string{
+		"/proc/1\tcwd\t/",
+		"/proc/1\texe\t/usr/lib/systemd/systemd",
+		"/proc/300\tcwd\t/home/tidb/deploy/tikv-20160",
+		"/proc/300\texe\t/home/tidb/deploy/tikv-20160/bin/tikv-server (deleted)",
+		"/proc/200\tcwd\t/tmp",
+		"/proc/200\texe\t/data/tikv-20160/helper",
+		"/proc/400\tcwd\t/data/tikv-20160-backup",
+		"/proc/400\texe\t/usr/bin/rsync",
+	}, "\n")
+	newHosts := func(tikvListings ...string) procHosts {
+		return procHosts{
+			"10.0.1.1": &procExecutor{Fake: executor.NewFake("10.0.1.1"), listings: tikvListings},
+			"10.0.1.2": &procExecutor{Fake: executor.NewFake("10.0.1.2"), listings: []string{"/proc/1\tcwd\t/"}},
+		}
+	}
+
+	// the orphans are only reported without KillOrphans
+	hosts := newHosts(listing)
+	report := VerifyStopped(hosts, topo, Options{})
+	assert.False(t, report.Safe)
+	require.Len(t, report.Hosts, 2)
+	for _, h := range report.Hosts {
+		if h.Host == "10.0.1.2" {
+			assert.True(t, h.Safe)
+			assert.Empty(t, h.Orphans)
+			continue
+		}
+		assert.False(t, h.Safe)
+		assert.Equal(t, []*OrphanProcess{
+			{PID: 200, Instance: "10.0.1.1:20160", Path: "/data/tikv-20160/helper"},
+			{PID: 300, Instance: "10.0.1.1:20160", Path: "/home/tidb/deploy/tikv-20160"},
+		}, h.Orphans)
+	}
+	assert.Len(t, report.Orphans(), 2)
+	assert.Empty(t, hosts["10.0.1.1"].Commands())
+
+	// the orphans are killed, the ones surviving SIGKILL are reported
+	hosts = newHosts(listing, "/proc/300\tcwd\t/home/tidb/deploy/tikv-20160")
+	report = VerifyStopped(hosts, topo, Options{KillOrphans: true, OrphanGracePeriod: 2})
+	assert.False(t, report.Safe)
+	assert.Equal(t, []*OrphanProcess{{PID: 300, Instance: "10.0.1.1:20160", Path: "/home/tidb/deploy/tikv-20160"}}, report.Orphans())
+	assert.Equal(t, []string{
+		"kill -TERM 200 300 2>/dev/null; for i in 1 2; do ps -p 200,300 >/dev/null || break; sleep 1; done; kill -KILL 200 300 2>/dev/null; true",
+	}, hosts["10.0.1.1"].Commands())
+
+	hosts = newHosts(listing, "")
+	report = VerifyStopped(hosts, topo, Options{KillOrphans: true})
+	assert.True(t, report.Safe)
+	assert.Empty(t, report.Orphans())
+
+	// only the hosts of the instances stopped are checked
+	hosts = newHosts(listing)
+	report = VerifyStopped(hosts, topo, Options{Roles: []string{spec.ComponentPD}})
+	assert.True(t, report.Safe)
+	require.Len(t, report.Hosts, 1)
+	assert.Equal(t, "10.0.1.2", report.Hosts[0].Host)
+}
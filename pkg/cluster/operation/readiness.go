@@ -0,0 +1,182 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+)
+
+// the interval between two polls of the region health
+const readinessPollInterval = 5 * time.Second
+
+// ReadinessGate is the region health PD must report before the rolling
+// upgrade moves on to the next TiKV instance, instead of a fixed sleep
+type ReadinessGate struct {
+	// The max number of regions with pending peers
+	MaxPendingPeers int
+	// The max spread of the region scores of the up stores, in percent of
+	// the highest one, 0 doesn't check the balance
+	MaxScoreSpread float64
+	// How long to wait for the regions to recover before failing
+	Timeout time.Duration
+	// The interval between two polls
+	Interval time.Duration
+}
+
+// readinessGate returns the gate configured by the options, nil if disabled
+func (opt *Options) readinessGate() *ReadinessGate {
+	if !opt.ReadinessGate {
+		return nil
+	}
+	return &ReadinessGate{
+		MaxPendingPeers: opt.ReadinessMaxPendingPeers,
+		MaxScoreSpread:  opt.ReadinessMaxScoreSpread,
+		Timeout:         time.Duration(opt.ReadinessTimeout) * time.Second,
+		Interval:        readinessPollInterval,
+	}
+}
+
+// RegionHealth is the region health reported by PD
+type RegionHealth struct {
+	PendingPeers int
+	// the spread of the region scores of the up stores, in percent of the
+	// highest one
+	ScoreSpread float64
+}
+
+func (h *RegionHealth) String() string {
+	return fmt.Sprintf("%d region(s) with pending peers, region score spread %.1f%%", h.PendingPeers, h.ScoreSpread)
+}
+
+// RegionHealthSource reports the region health of a cluster
+type RegionHealthSource interface {
+	RegionHealth() (*RegionHealth, error)
+}
+
+// pdRegionHealth reads the region health from the APIs of PD
+type pdRegionHealth struct {
+	client *api.PDClient
+}
+
+// RegionHealth implements RegionHealthSource
+func (p *pdRegionHealth) RegionHealth() (*RegionHealth, error) {
+	pending, err := p.client.GetPendingPeerRegionCount()
+	if err != nil {
+		return nil, err
+	}
+	stores, err := p.client.GetStores()
+	if err != nil {
+		return nil, err
+	}
+	var scores []float64
+	for _, s := range stores.Stores {
+		if s.Store == nil || s.Store.Store == nil || s.Status == nil ||
+			s.Store.State != metapb.StoreState_Up {
+			continue
+		}
+		scores = append(scores, s.Status.RegionScore)
+	}
+	return &RegionHealth{PendingPeers: pending, ScoreSpread: scoreSpread(scores)}, nil
+}
+
+// scoreSpread returns the difference between the highest and lowest scores in
+// percent of the highest one
+func scoreSpread(scores []float64) float64 {
+	if len(scores) < 2 {
+		return 0
+	}
+	min, max := scores[0], scores[0]
+	for _, s := range scores[1:] {
+		if s < min {
+			min = s
+		}
+		if s > max {
+			max = s
+		}
+	}
+	if max <= 0 {
+		return 0
+	}
+	return (max - min) * 100 / max
+}
+
+// passes is true if the region health is within the thresholds
+func (g *ReadinessGate) passes(h *RegionHealth) bool {
+	if h.PendingPeers > g.MaxPendingPeers {
+		return false
+	}
+	return g.MaxScoreSpread <= 0 || h.ScoreSpread <= g.MaxScoreSpread
+}
+
+// Wait polls the region health until it passes the gate, the live values are
+// reported to the getter if it's a PhaseReporter. It fails with the last
+// readings if the regions don't recover in time. The wait is bounded by the
+// deadline of the operation if the getter is a DeadlineChecker, the
+// ErrDeadlineExceeded of it is returned if the deadline is exceeded.
+func (g *ReadinessGate) Wait(getter ExecutorGetter, source RegionHealthSource, after string) error {
+	start := time.Now()
+	deadline := start.Add(g.Timeout)
+	if d, ok := operationDeadline(getter); ok && d.Before(deadline) {
+		deadline = d
+	}
+	var last *RegionHealth
+	var lastErr error
+	for {
+		h, err := source.RegionHealth()
+		if err == nil {
+			if g.passes(h) {
+				return nil
+			}
+			last = h
+			reportWaiting(getter, "regions to recover after %s, %s", after, h)
+		} else {
+			lastErr = err
+			reportWaiting(getter, "regions to recover after %s, %s", after, err)
+		}
+		left := time.Until(deadline)
+		if left <= 0 {
+			break
+		}
+		// polls the last time at the deadline
+		if left > g.Interval {
+			left = g.Interval
+		}
+		time.Sleep(left)
+	}
+
+	if err := checkDeadline(getter, "regions to recover after %s", after); err != nil {
+		return err
+	}
+	waited := deadline.Sub(start)
+	if last == nil {
+		return errors.Annotatef(lastErr, "failed to read the region health after %s in %s", after, waited)
+	}
+	expected := fmt.Sprintf("at most %d region(s) with pending peers", g.MaxPendingPeers)
+	if g.MaxScoreSpread > 0 {
+		expected += fmt.Sprintf(", region score spread at most %.1f%%", g.MaxScoreSpread)
+	}
+	return errors.Errorf("regions are not recovered after %s in %s, last reading: %s, expected %s",
+		after, waited, last, expected)
+}
+
+// newRegionHealthSource returns the source of the region health of the cluster
+func newRegionHealthSource(topo spec.Topology) RegionHealthSource {
+	return &pdRegionHealth{client: api.NewPDClient(topo.BaseTopo().MasterList, 10*time.Second, nil)}
+}
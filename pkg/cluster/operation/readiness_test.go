@@ -0,0 +1,124 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRegionHealth returns the readings in order, the last one is repeated
+type fakeRegionHealth struct {
+	readings []*RegionHealth
+	err      error
+	polls    int
+}
+
+func (f *fakeRegionHealth) RegionHealth() (*RegionHealth, error) {
+	f.polls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	h := f.readings[0]
+	if len(f.readings) > 1 {
+		f.readings = f.readings[1:]
+	}
+	return h, nil
+}
+
+type waitingRecorder struct {
+	details []string
+}
+
+func (r *waitingRecorder) Get(host string) executor.Executor {
+	return nil
+}
+
+func (r *waitingRecorder) ReportWaiting(detail string) {
+	r.details = append(r.details, detail)
+}
+
+// deadlineRecorder is a waitingRecorder with the deadline of the operation
+type deadlineRecorder struct {
+	waitingRecorder
+	deadline time.Time
+}
+
+func (r *deadlineRecorder) CheckDeadline(step string) error {
+	if time.Now().Before(r.deadline) {
+		return nil
+	}
+	return errors.New("deadline exceeded before " + step)
+}
+
+func (r *deadlineRecorder) Deadline() (time.Time, bool) {
+	return r.deadline, true
+}
+
+func TestReadinessGate(t *testing.T) {
+	gate := &ReadinessGate{
+		MaxPendingPeers: 2,
+		MaxScoreSpread:  10,
+		Timeout:         time.Second,
+		Interval:        time.Millisecond,
+	}
+
+	// recovered after the pending peers catch up and the scores are balanced
+	source := &fakeRegionHealth{readings: []*RegionHealth{
+		{PendingPeers: 30, ScoreSpread: 40},
+		{PendingPeers: 1, ScoreSpread: 25.5},
+		{PendingPeers: 1, ScoreSpread: 5},
+	}}
+	recorder := &waitingRecorder{}
+	require.Nil(t, gate.Wait(recorder, source, "172.16.5.1:20160"))
+	assert.Equal(t, 3, source.polls)
+	assert.Equal(t, []string{
+		"regions to recover after 172.16.5.1:20160, 30 region(s) with pending peers, region score spread 40.0%",
+		"regions to recover after 172.16.5.1:20160, 1 region(s) with pending peers, region score spread 25.5%",
+	}, recorder.details)
+
+	// fails with the last readings
+	gate.Timeout = 20 * time.Millisecond
+	source = &fakeRegionHealth{readings: []*RegionHealth{{PendingPeers: 7, ScoreSpread: 3}}}
+	err := gate.Wait(recorder, source, "172.16.5.2:20160")
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "last reading: 7 region(s) with pending peers, region score spread 3.0%")
+	assert.Contains(t, err.Error(), "expected at most 2 region(s) with pending peers, region score spread at most 10.0%")
+
+	// the wait is bounded by the deadline of the operation
+	gate.Timeout, gate.Interval = time.Minute, time.Minute
+	start := time.Now()
+	err = gate.Wait(&deadlineRecorder{deadline: start.Add(20 * time.Millisecond)}, source, "172.16.5.2:20160")
+	require.NotNil(t, err)
+	assert.Equal(t, "deadline exceeded before regions to recover after 172.16.5.2:20160", err.Error())
+	assert.True(t, time.Since(start) < 10*time.Second)
+	gate.Timeout, gate.Interval = 20*time.Millisecond, time.Millisecond
+
+	// PD is unreachable
+	source = &fakeRegionHealth{err: errors.New("connection refused")}
+	err = gate.Wait(recorder, source, "172.16.5.2:20160")
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "connection refused")
+}
+
+func TestScoreSpread(t *testing.T) {
+	assert.Equal(t, float64(0), scoreSpread(nil))
+	assert.Equal(t, float64(0), scoreSpread([]float64{80}))
+	assert.Equal(t, float64(25), scoreSpread([]float64{80, 100, 75}))
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// metaRoles are the shortcuts accepted in Options.Roles for all the
+// components of a class, e.g. "@monitoring"
+var metaRoles = map[string]ComponentClass{
+	"@" + string(ComponentClassCore):       ComponentClassCore,
+	"@" + string(ComponentClassMonitoring): ComponentClassMonitoring,
+}
+
+// IsMetaRole checks if the role is a shortcut for a group of roles
+func IsMetaRole(role string) bool {
+	return strings.HasPrefix(role, "@")
+}
+
+// MetaRoles returns the names of the meta-roles
+func MetaRoles() []string {
+	names := make([]string, 0, len(metaRoles))
+	for name := range metaRoles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExpandRoles replaces the meta-roles in roles with the roles of the components
+// of topo in their classes, in the start order of the components, the ones
+// without instances are left out. The
+// monitoring agents are included in "@monitoring" as node_exporter and
+// blackbox_exporter, which make Start and Stop operate the agents on all the
+// hosts regardless of the instances on them.
+func ExpandRoles(topo spec.Topology, roles []string) ([]string, error) {
+	classes := []ComponentClass{}
+	expanded := []string{}
+	seen := set.NewStringSet()
+	add := func(role string) {
+		if !seen.Exist(role) {
+			seen.Insert(role)
+			expanded = append(expanded, role)
+		}
+	}
+	for _, role := range roles {
+		if !IsMetaRole(role) {
+			add(role)
+			continue
+		}
+		class, found := metaRoles[role]
+		if !found {
+			return nil, errors.Errorf("unknown meta-role %s, it should be one of %s", role, strings.Join(MetaRoles(), ", "))
+		}
+		classes = append(classes, class)
+	}
+	if len(classes) == 0 {
+		return roles, nil
+	}
+
+	for _, class := range classes {
+		for _, comp := range topo.ComponentsByStartOrder() {
+			if ClassOfComponent(comp.Name()) == class && len(comp.Instances()) > 0 {
+				add(comp.Name())
+			}
+		}
+		if class == ComponentClassMonitoring && topo.GetMonitoredOptions() != nil {
+			add(spec.ComponentNodeExporter)
+			add(spec.ComponentBlackboxExporter)
+		}
+	}
+	return expanded, nil
+}
+
+// monitorAgentsSelected checks if the monitoring agents are selected by the
+// roles explicitly, which is only possible by expanding "@monitoring"
+func monitorAgentsSelected(roles set.StringSet) bool {
+	return roles.Exist(spec.ComponentNodeExporter) || roles.Exist(spec.ComponentBlackboxExporter)
+}
+
+// agentHosts returns an instance on each host of the instances matched by the
+// nodes and selector, which the monitoring agents of the host are operated by
+func agentHosts(cluster spec.Topology, nodes set.StringSet, selector *spec.Selector) []spec.Instance {
+	var insts []spec.Instance
+	hosts := set.NewStringSet()
+	for _, com := range cluster.ComponentsByStartOrder() {
+		for _, inst := range SelectInstance(FilterInstance(com.Instances(), nodes), selector) {
+			if !hosts.Exist(inst.GetHost()) {
+				hosts.Insert(inst.GetHost())
+				insts = append(insts, inst)
+			}
+		}
+	}
+	return insts
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+)
+
+// DelayReporter is implemented by the ExecutorGetter accounting the delays
+// added to the operation on purpose, e.g., the staggered starts
+type DelayReporter interface {
+	ReportDelay(d time.Duration)
+}
+
+// StaggerSchedule spreads the starts of the instances started at once, so
+// that they don't saturate the storage shared by them. The schedule begins
+// when the first instance waits for its turn, and an instance isn't delayed
+// if its turn has passed when it's going to start, e.g., the instances are
+// started one by one, so the schedule composes with the limit of the
+// instances started at the same time.
+type StaggerSchedule struct {
+	once   sync.Once
+	begin  time.Time
+	delays map[string]time.Duration // by the IDs of instances
+}
+
+// NewStaggerSchedule schedules the k-th instance of insts to start after
+// k*stagger plus a random jitter up to jitter, it's nil if there is no delay.
+func NewStaggerSchedule(insts []spec.Instance, stagger, jitter time.Duration) *StaggerSchedule {
+	if stagger <= 0 && jitter <= 0 {
+		return nil
+	}
+	s := &StaggerSchedule{delays: make(map[string]time.Duration)}
+	for k, ins := range insts {
+		delay := time.Duration(k) * stagger
+		if jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(jitter)))
+		}
+		s.delays[ins.ID()] = delay
+	}
+	return s
+}
+
+// Delay returns the delay scheduled for the instance since the schedule begins
+func (s *StaggerSchedule) Delay(ins spec.Instance) time.Duration {
+	if s == nil {
+		return 0
+	}
+	return s.delays[ins.ID()]
+}
+
+// Wait waits until the turn of the instance to start, the wait is reported to
+// the getter. It returns the time waited.
+func (s *StaggerSchedule) Wait(getter ExecutorGetter, ins spec.Instance) time.Duration {
+	if s == nil {
+		return 0
+	}
+	s.once.Do(func() {
+		s.begin = time.Now()
+	})
+	wait := time.Until(s.begin.Add(s.delays[ins.ID()]))
+	if wait <= 0 {
+		return 0
+	}
+	reportWaiting(getter, "scheduled in %s", wait.Round(time.Second))
+	time.Sleep(wait)
+	if r, ok := getter.(DelayReporter); ok {
+		r.ReportDelay(wait)
+	}
+	return wait
+}
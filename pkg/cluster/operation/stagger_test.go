@@ -0,0 +1,98 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// delayRecorder records the waits and delays reported by the operation
+type delayRecorder struct {
+	fakeHosts
+
+	mu      sync.Mutex
+	waiting []string
+	delay   time.Duration
+}
+
+func (r *delayRecorder) ReportWaiting(detail string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.waiting = append(r.waiting, detail)
+}
+
+func (r *delayRecorder) ReportDelay(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.delay += d
+}
+
+func TestStaggerSchedule(t *testing.T) {
+	topo := &spec.Specification{}
+	require.NoError(t, yaml.Unmarshal([]byte(`
+tikv_servers:
+  - host: 10.0.1.1
+  - host: 10.0.1.2
+  - host: 10.0.1.3
+`), topo))
+	insts := topo.ComponentsByStartOrder()[1].Instances()
+	require.Len(t, insts, 3)
+
+	assert.Nil(t, NewStaggerSchedule(insts, 0, 0))
+	s := NewStaggerSchedule(insts, time.Second, 0)
+	for k, ins := range insts {
+		assert.Equal(t, time.Duration(k)*time.Second, s.Delay(ins))
+	}
+	s = NewStaggerSchedule(insts, time.Second, 500*time.Millisecond)
+	for k, ins := range insts {
+		assert.True(t, s.Delay(ins) >= time.Duration(k)*time.Second)
+		assert.True(t, s.Delay(ins) < time.Duration(k)*time.Second+500*time.Millisecond)
+	}
+
+	// the instances wait for their turns whether they're started at once or
+	// one by one, the waits are not added up when started one by one
+	for _, serial := range []bool{false, true} {
+		r := &delayRecorder{fakeHosts: fakeHosts{}}
+		for _, h := range []string{"10.0.1.1", "10.0.1.2", "10.0.1.3"} {
+			r.fakeHosts[h] = executor.NewFake(h)
+		}
+		begin := time.Now()
+		options := Options{OptTimeout: 10, StartStagger: 100 * time.Millisecond, Serial: serial}
+		require.NoError(t, StartComponent(r, insts, "tidb", options))
+		elapsed := time.Since(begin)
+		assert.True(t, elapsed >= 200*time.Millisecond, "elapsed: %s", elapsed)
+		assert.True(t, elapsed < 600*time.Millisecond, "elapsed: %s", elapsed)
+		expected := 300 * time.Millisecond
+		if serial {
+			expected = 200 * time.Millisecond
+		}
+		assert.InDelta(t, float64(expected), float64(r.delay), float64(50*time.Millisecond), "delay: %s", r.delay)
+		scheduled := 0
+		for _, w := range r.waiting {
+			if strings.HasPrefix(w, "scheduled in ") {
+				scheduled++
+			}
+		}
+		assert.Equal(t, 2, scheduled)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+)
+
+// FirstStartMarker is the file left in the deploy dir of an instance whose
+// data dirs are empty when it's deployed, the first start of it is given
+// Options.FirstStartTimeout and the file is removed once it's started
+const FirstStartMarker = ".tiup_first_start"
+
+// FirstStartMarkCommand returns the command leaving FirstStartMarker in the
+// deploy dir if the data dirs are empty, e.g., not reused from an older
+// deployment
+func FirstStartMarkCommand(deployDir string, dataDirs []string) string {
+	marker := filepath.Join(deployDir, FirstStartMarker)
+	if len(dataDirs) == 0 {
+		return fmt.Sprintf("touch %s", marker)
+	}
+	return fmt.Sprintf(`if [ -z "$(find %s -mindepth 1 -print -quit 2>/dev/null)" ]; then touch %s; fi`,
+		strings.Join(dataDirs, " "), marker)
+}
+
+// startTimeout is the timeout of starting an instance
+type startTimeout struct {
+	seconds int64
+	first   bool // the instance is started the first time
+}
+
+// String implements the fmt.Stringer interface, it's included in the errors
+// of starting the instance
+func (t startTimeout) String() string {
+	if t.first {
+		return fmt.Sprintf("first start timeout %ds", t.seconds)
+	}
+	return fmt.Sprintf("restart timeout %ds", t.seconds)
+}
+
+// firstStartMarker returns the path of FirstStartMarker of the instance, the
+// relative deploy dir is resolved under the home of the deploy user
+func firstStartMarker(deployUser string, ins spec.Instance) string {
+	return filepath.Join(clusterutil.Abs(deployUser, ins.DeployDir()), FirstStartMarker)
+}
+
+// pickStartTimeout returns the timeout of starting or restarting the
+// instance, it's FirstStartTimeout if the instance isn't started since
+// deployed with empty data dirs, and RestartTimeout otherwise, the ones not
+// set fall back to OptTimeout
+func pickStartTimeout(e executor.Executor, ins spec.Instance, deployUser string, options Options) startTimeout {
+	marker := firstStartMarker(deployUser, ins)
+	stdout, _, err := e.Execute(fmt.Sprintf("test -f %s && echo first || true", marker), false)
+	if err == nil && strings.TrimSpace(string(stdout)) == "first" {
+		t := startTimeout{seconds: options.FirstStartTimeout, first: true}
+		if t.seconds <= 0 {
+			t.seconds = options.OptTimeout
+		}
+		return t
+	}
+	t := startTimeout{seconds: options.RestartTimeout}
+	if t.seconds <= 0 {
+		t.seconds = options.OptTimeout
+	}
+	return t
+}
+
+// clearFirstStart removes FirstStartMarker of the instance once it's started
+func clearFirstStart(e executor.Executor, ins spec.Instance, deployUser string, timeout startTimeout) error {
+	if !timeout.first {
+		return nil
+	}
+	_, _, err := e.Execute(fmt.Sprintf("rm -f %s", firstStartMarker(deployUser, ins)), false)
+	return err
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestStartTimeout(t *testing.T) {
+	assert.Equal(t, "touch /data/tikv/.tiup_first_start", FirstStartMarkCommand("/data/tikv", nil))
+	assert.Equal(t,
+		`if [ -z "$(find /data/d1 /data/d2 -mindepth 1 -print -quit 2>/dev/null)" ]; then touch /data/tikv/.tiup_first_start; fi`,
+		FirstStartMarkCommand("/data/tikv", []string{"/data/d1", "/data/d2"}))
+
+	topo := new(spec.Specification)
+	require.Nil(t, yaml.UnmarshalStrict([]byte("tikv_servers:\n  - host: 10.0.0.1\n    deploy_dir: /data/tikv\n"), topo))
+	ins := (&spec.TiKVComponent{Specification: topo}).Instances()
+	require.Len(t, ins, 1)
+
+	options := Options{OptTimeout: 120, FirstStartTimeout: 600, RestartTimeout: 60}
+
+	// started before
+	e := executor.NewFake("10.0.0.1")
+	timeout := pickStartTimeout(e, ins[0], "tidb", options)
+	assert.Equal(t, startTimeout{seconds: 60}, timeout)
+	assert.Equal(t, "restart timeout 60s", timeout.String())
+	require.Nil(t, clearFirstStart(e, ins[0], "tidb", timeout))
+	for _, cmd := range e.Commands() {
+		assert.False(t, strings.HasPrefix(cmd, "rm "), cmd)
+	}
+
+	// the first start, the marker is removed once started
+	e = executor.NewFake("10.0.0.1")
+	e.Respond("test -f /data/tikv/.tiup_first_start", "first\n", "")
+	timeout = pickStartTimeout(e, ins[0], "tidb", options)
+	assert.Equal(t, startTimeout{seconds: 600, first: true}, timeout)
+	assert.Equal(t, "first start timeout 600s", timeout.String())
+	require.Nil(t, clearFirstStart(e, ins[0], "tidb", timeout))
+	assert.Contains(t, e.Commands(), "rm -f /data/tikv/.tiup_first_start")
+
+	// the timeouts not set fall back to the wait timeout
+	assert.Equal(t, startTimeout{seconds: 120, first: true}, pickStartTimeout(e, ins[0], "tidb", Options{OptTimeout: 120}))
+	e = executor.NewFake("10.0.0.1")
+	assert.Equal(t, startTimeout{seconds: 120}, pickStartTimeout(e, ins[0], "tidb", Options{OptTimeout: 120}))
+
+	// the relative deploy dir is resolved under the home of the deploy user
+	require.Nil(t, yaml.UnmarshalStrict([]byte("tikv_servers:\n  - host: 10.0.0.1\n    deploy_dir: tikv\n"), topo))
+	rel := (&spec.TiKVComponent{Specification: topo}).Instances()[0]
+	e = executor.NewFake("10.0.0.1")
+	e.Respond("test -f /home/tidb/tikv/.tiup_first_start", "first\n", "")
+	timeout = pickStartTimeout(e, rel, "tidb", options)
+	assert.True(t, timeout.first)
+	require.Nil(t, clearFirstStart(e, rel, "tidb", timeout))
+	assert.Contains(t, e.Commands(), "rm -f /home/tidb/tikv/.tiup_first_start")
+}
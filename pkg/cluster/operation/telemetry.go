@@ -35,7 +35,6 @@ func GetNodeInfo(
 	topo spec.Topology,
 ) (nodes []*telemetry.NodeInfo, err error) {
 	ver := version.NewTiUPVersion().String()
-	dir := "/tmp/_cluster"
 
 	// Download cluster binary
 	errg, _ := errgroup.WithContext(ctx)
@@ -68,6 +67,8 @@ func GetNodeInfo(
 		}
 		foundHosts[host] = struct{}{}
 
+		// the binary is staged in the remote tmp dir of the host
+		dir := filepath.Join(topo.BaseTopo().GlobalOptions.RemoteTmpDirOf(host), "_cluster")
 		errg.Go(func() error {
 			exec := getter.Get(host)
 
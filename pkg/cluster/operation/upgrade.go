@@ -32,6 +32,22 @@ func Upgrade(
 	nodeFilter := set.NewStringSet(options.Nodes...)
 	components := topo.ComponentsByUpdateOrder()
 	components = FilterComponent(components, roleFilter)
+	if len(options.Components) > 0 {
+		components = FilterComponent(components, set.NewStringSet(options.Components...))
+	}
+
+	// the progress is counted by the instances restarted, as evicting the
+	// leaders of an instance could take minutes
+	total, restarted := 0, 0
+	for _, component := range components {
+		total += len(FilterInstance(component.Instances(), nodeFilter))
+	}
+
+	var regions RegionHealthSource
+	gate := options.readinessGate()
+	if gate != nil {
+		regions = newRegionHealthSource(topo)
+	}
 
 	for _, component := range components {
 		instances := FilterInstance(component.Instances(), nodeFilter)
@@ -44,6 +60,13 @@ func Upgrade(
 		log.Infof("Restarting component %s", component.Name())
 
 		for _, instance := range instances {
+			reportProgress(getter, restarted, total)
+			restarted++
+			breakBefore(getter, "UpgradeInstance %s %s", instance.ComponentName(), instance.ID())
+			if err := checkDeadline(getter, "UpgradeInstance %s %s", instance.ComponentName(), instance.ID()); err != nil {
+				return err
+			}
+
 			var rollingInstance spec.RollingUpdateInstance
 			var isRollingInstance bool
 
@@ -58,8 +81,14 @@ func Upgrade(
 				}
 			}
 
-			if err := restartInstance(getter, instance, options.OptTimeout); err != nil {
-				return errors.AddStack(err)
+			if err := restartInstance(getter, instance, topo.BaseTopo().GlobalOptions.User, options); err != nil {
+				// don't leave the leaders evicted from the instance
+				if isRollingInstance {
+					if perr := rollingInstance.PostRestart(topo); perr != nil {
+						log.Warnf("Failed to clean up after restarting %s: %s", instance.ID(), perr)
+					}
+				}
+				return errors.AddStack(instanceFailure(instance, err))
 			}
 
 			if isRollingInstance {
@@ -68,6 +97,13 @@ func Upgrade(
 					return errors.AddStack(err)
 				}
 			}
+
+			// the regions catch up on the restarted store before the next one
+			if gate != nil && instance.ComponentName() == spec.ComponentTiKV {
+				if err := gate.Wait(getter, regions, instance.ID()); err != nil {
+					return err
+				}
+			}
 		}
 	}
 
@@ -0,0 +1,109 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/tui"
+	"go.uber.org/zap"
+)
+
+// the kinds of operation events besides the task events of task.EventKind
+const (
+	EventOperationBegin  = "operation_begin"
+	EventOperationFinish = "operation_finish"
+)
+
+// OperationEvent is an event of an operation, e.g., the operation begins or
+// a task of it finishes
+type OperationEvent struct {
+	Kind      string        `json:"kind"`
+	Operation OperationType `json:"operation"`
+	Cluster   string        `json:"cluster"`
+	Task      string        `json:"task,omitempty"`
+	Message   *task.Message `json:"message,omitempty"` // the identified message of the task for localization
+	ID        string        `json:"id,omitempty"`
+	Progress  string        `json:"progress,omitempty"`
+	Phase     task.Phase    `json:"phase,omitempty"`
+	Detail    string        `json:"detail,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Time      time.Time     `json:"time"`
+}
+
+// the subscribers of the events of all operations of the process
+var (
+	eventSubscribersMu sync.RWMutex
+	eventSubscribers   = make(map[chan OperationEvent]struct{})
+)
+
+// SubscribeOperationEvents returns a channel receiving the events of all
+// operations of the process, with a buffer of size. The events are dropped
+// if the buffer is full, so slow subscribers never block the operations.
+// Call the returned function to unsubscribe, the channel is closed then.
+func SubscribeOperationEvents(size int) (<-chan OperationEvent, func()) {
+	ch := make(chan OperationEvent, size)
+	eventSubscribersMu.Lock()
+	eventSubscribers[ch] = struct{}{}
+	eventSubscribersMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			eventSubscribersMu.Lock()
+			delete(eventSubscribers, ch)
+			eventSubscribersMu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func publishOperationEvent(e OperationEvent) {
+	e.Time = time.Now()
+	eventSubscribersMu.RLock()
+	defer eventSubscribersMu.RUnlock()
+	for ch := range eventSubscribers {
+		select {
+		case ch <- e:
+		default:
+			zap.L().Debug("Operation event dropped", zap.String("kind", e.Kind), zap.String("cluster", e.Cluster))
+		}
+	}
+}
+
+// publishTaskEvent publishes a task event of the operation, the task of p
+// is ignored as it's t
+func (info *OperationInfo) publishTaskEvent(kind task.EventKind, t task.Task, p TaskProgress, err error) {
+	e := OperationEvent{
+		Kind:      string(kind),
+		Operation: info.operationType,
+		Cluster:   info.clusterName,
+		Task:      tui.StripColor(t.String()),
+		ID:        p.ID,
+		Progress:  tui.StripColor(p.Progress),
+		Phase:     p.Phase,
+		Detail:    p.Detail,
+	}
+	if m, ok := t.(task.Messager); ok {
+		if msg := m.Message(); msg.ID != "" {
+			e.Message = &msg
+		}
+	}
+	if err != nil {
+		e.Error = tui.StripColor(err.Error())
+	}
+	publishOperationEvent(e)
+}
@@ -0,0 +1,635 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	perrs "github.com/pingcap/errors"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/logger"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/meta"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// OperationType is the type of an operation on a cluster
+type OperationType int
+
+// the types of operations on a cluster
+const (
+	OperationDeploy OperationType = iota
+	OperationStart
+	OperationStop
+	OperationRestart
+	OperationUpgrade
+	OperationScaleIn
+	OperationScaleOut
+	OperationReload
+	OperationPatch
+	OperationRedeployAgents
+	OperationEnable
+	OperationDisable
+	OperationMigrateDataDir
+)
+
+var operationTypeNames = [...]string{
+	"deploy",
+	"start",
+	"stop",
+	"restart",
+	"upgrade",
+	"scale-in",
+	"scale-out",
+	"reload",
+	"patch",
+	"redeploy-agents",
+	"enable",
+	"disable",
+	"migrate-data-dir",
+}
+
+// String implements the fmt.Stringer interface
+func (t OperationType) String() string {
+	if t < 0 || int(t) >= len(operationTypeNames) {
+		return fmt.Sprintf("unknown-operation(%d)", int(t))
+	}
+	return operationTypeNames[t]
+}
+
+// ParseOperationType parses the name of an operation type, e.g. "scale-out"
+func ParseOperationType(name string) (OperationType, error) {
+	for i, n := range operationTypeNames {
+		if n == name {
+			return OperationType(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown operation type: %s", name)
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (t OperationType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (t *OperationType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	parsed, err := ParseOperationType(name)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// TaskProgress is a snapshot of the task being executed by an operation
+type TaskProgress struct {
+	Task     string     `json:"task"`
+	ID       string     `json:"id"` // the identity of the task, see task.Context.TaskID
+	Progress string     `json:"progress,omitempty"`
+	Phase    task.Phase `json:"phase,omitempty"`  // e.g. downloading, waiting, see task.Context.SetPhase
+	Detail   string     `json:"detail,omitempty"` // the detail of the phase, e.g. "43MB/512MB"
+}
+
+// OperationInfo is the information of an operation on a cluster,
+// it's safe to be accessed concurrently
+type OperationInfo struct {
+	mu            sync.RWMutex
+	operationType OperationType
+	clusterName   string
+	err           error
+	finished      bool
+	curTask       TaskProgress
+	result        interface{} // the structured result of the operation
+	logFile       string      // the full log of the operation
+	logDir        string      // the log dir of the cluster, large outputs of hosts are spilled under it, see spillDir
+	options       *OperationOptions
+	silences      []SilenceRecord // the silences of alerts created for the operation
+	breakpoints   []string        // the steps to pause before, see task.Context.SetBreakpoints
+	breakOnErrors []string        // the errorx types to pause at the first failure of
+	deadline      time.Time       // the operation is aborted after it, zero if unlimited
+	concurrency   int             // the max number of parallel tasks executing at once, see task.Context.SetConcurrency
+	mock          *MockCluster    // the cluster is mocked, see Manager.NewMockCluster
+	stamper       *spec.ConfigStamper
+	hostKeys      *spec.HostKeyVerifier
+	globalOpts    *spec.GlobalOptions // where the files are staged on the hosts, nil if not deployed
+	followers     *task.HostFollowers // the followers of the commands on the hosts, see FollowHost
+	profiler      *operationProfiler  // nil if the operation isn't profiled
+	instances     int                 // the number of instances when the operation begins
+	estimate      *DurationEstimate   // nil if there is no history to estimate from
+	ctx           *task.Context
+	startTime     time.Time
+	endTime       time.Time
+	tasksBegun    int
+	tasksFinished int
+	tasksFailed   int
+}
+
+// OperationProgress is the progress of an operation computed from the
+// events of its tasks
+type OperationProgress struct {
+	Operation     OperationType `json:"operation"`
+	Cluster       string        `json:"cluster"`
+	Finished      bool          `json:"finished"`
+	Error         string        `json:"error,omitempty"`
+	StartTime     time.Time     `json:"start_time"`
+	ElapsedSecs   float64       `json:"elapsed_secs"`
+	TasksBegun    int           `json:"tasks_begun"`
+	TasksFinished int           `json:"tasks_finished"`
+	TasksFailed   int           `json:"tasks_failed"`
+	Percent       int           `json:"percent"` // counting in the partial progress of the task executing
+	CurTask       TaskProgress  `json:"current_task"`
+	// the estimated duration of the whole operation and the time left, see
+	// DurationEstimate, they're 0 if there is no history to estimate from
+	EstimatedSecs float64 `json:"estimated_secs,omitempty"`
+	RemainingSecs float64 `json:"remaining_secs,omitempty"`
+	// the steps failed so far, they're kept after the operation moves on to
+	// the next steps, e.g., with the failures ignored
+	FailedSteps []StepResult `json:"failed_steps,omitempty"`
+}
+
+// StepResult is the result of a step failed in an operation, with all the
+// errors of it in the order they happened, e.g., a step may fail on a host
+// again after being retried
+type StepResult struct {
+	Step   string   `json:"step"`
+	Status string   `json:"status"` // "Error", or "Skipped" if it's skipped on a degraded host
+	Errors []string `json:"errors"`
+}
+
+// stepResults groups the failures by the steps in the order they failed
+func stepResults(failures []task.StepFailure) []StepResult {
+	var results []StepResult
+	index := make(map[string]int)
+	for _, f := range failures {
+		status := "Error"
+		if f.Skipped {
+			status = "Skipped"
+		}
+		key := status + "\x00" + f.Step
+		i, ok := index[key]
+		if !ok {
+			i = len(results)
+			index[key] = i
+			results = append(results, StepResult{Step: tui.StripColor(f.Step), Status: status})
+		}
+		if f.Err != nil {
+			results[i].Errors = append(results[i].Errors, tui.StripColor(f.Err.Error()))
+		}
+	}
+	return results
+}
+
+// Type returns the type of the operation
+func (info *OperationInfo) Type() OperationType {
+	return info.operationType
+}
+
+// ClusterName returns the name of the cluster operated on
+func (info *OperationInfo) ClusterName() string {
+	return info.clusterName
+}
+
+// Error returns the error of the operation, nil if it succeeded or is running
+func (info *OperationInfo) Error() error {
+	info.mu.RLock()
+	defer info.mu.RUnlock()
+	return info.err
+}
+
+// Finished returns whether the operation is finished
+func (info *OperationInfo) Finished() bool {
+	info.mu.RLock()
+	defer info.mu.RUnlock()
+	return info.finished
+}
+
+// CurTask returns a snapshot of the task being executed by the operation
+func (info *OperationInfo) CurTask() TaskProgress {
+	info.mu.RLock()
+	defer info.mu.RUnlock()
+	return info.curTask
+}
+
+// Result returns the structured result of the operation, nil if the
+// operation doesn't have one
+func (info *OperationInfo) Result() interface{} {
+	info.mu.RLock()
+	defer info.mu.RUnlock()
+	return info.result
+}
+
+// LogFile returns the path of the full log of the operation, empty if it's
+// not saved
+func (info *OperationInfo) LogFile() string {
+	info.mu.RLock()
+	defer info.mu.RUnlock()
+	return info.logFile
+}
+
+// OutputUsage returns the usage of memory by the outputs of hosts collected
+// by the operation
+func (info *OperationInfo) OutputUsage() task.OutputUsage {
+	info.mu.RLock()
+	ctx := info.ctx
+	info.mu.RUnlock()
+	if ctx == nil {
+		return task.OutputUsage{}
+	}
+	return ctx.OutputUsage()
+}
+
+// ComputeProgress returns a snapshot of the progress of the operation
+func (info *OperationInfo) ComputeProgress() OperationProgress {
+	info.mu.RLock()
+	defer info.mu.RUnlock()
+	end := info.endTime
+	if !info.finished {
+		end = time.Now()
+	}
+	p := OperationProgress{
+		Operation:     info.operationType,
+		Cluster:       info.clusterName,
+		Finished:      info.finished,
+		StartTime:     info.startTime,
+		ElapsedSecs:   end.Sub(info.startTime).Seconds(),
+		TasksBegun:    info.tasksBegun,
+		TasksFinished: info.tasksFinished,
+		TasksFailed:   info.tasksFailed,
+		CurTask: TaskProgress{
+			Task:     tui.StripColor(info.curTask.Task),
+			ID:       info.curTask.ID,
+			Progress: tui.StripColor(info.curTask.Progress),
+			Phase:    info.curTask.Phase,
+			Detail:   info.curTask.Detail,
+		},
+	}
+	if info.err != nil {
+		p.Error = tui.StripColor(info.err.Error())
+	}
+	switch {
+	case info.finished && info.err == nil:
+		p.Percent = 100
+	case info.ctx != nil:
+		p.Percent, _ = info.ctx.Progress()
+	}
+	if info.ctx != nil {
+		p.FailedSteps = stepResults(info.ctx.Failures())
+	}
+	if info.estimate != nil {
+		p.EstimatedSecs = info.estimate.Total.Seconds()
+		if !info.finished && info.ctx != nil {
+			p.RemainingSecs = info.estimate.Remaining(info.ctx.PhaseTimings()).Seconds()
+		}
+	}
+	return p
+}
+
+// Options returns the command line and the resolved options the operation
+// runs with
+func (info *OperationInfo) Options() *OperationOptions {
+	return info.options
+}
+
+// Failures returns the failures of all the steps of the operation, including
+// the ones suppressed by the error scope of the options, see operator.ErrorScope
+func (info *OperationInfo) Failures() []task.StepFailure {
+	info.mu.RLock()
+	ctx := info.ctx
+	info.mu.RUnlock()
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Failures()
+}
+
+// setResult records the structured result of the operation
+func (info *OperationInfo) setResult(result interface{}) {
+	info.mu.Lock()
+	info.result = result
+	info.mu.Unlock()
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (info *OperationInfo) MarshalJSON() ([]byte, error) {
+	usage := info.OutputUsage()
+	info.mu.RLock()
+	// the JSON output is meant to be parsed, so it never contains color
+	// escapes whatever the color mode is
+	defer info.mu.RUnlock()
+	v := struct {
+		Type      OperationType      `json:"type"`
+		Cluster   string             `json:"cluster"`
+		Finished  bool               `json:"finished"`
+		Error     string             `json:"error,omitempty"`
+		ErrorType string             `json:"error_type,omitempty"` // the type of errorx errors, e.g., spec.cluster_not_exist
+		CurTask   TaskProgress       `json:"current_task"`
+		Result    interface{}        `json:"result,omitempty"`
+		LogFile   string             `json:"log_file,omitempty"`
+		Outputs   task.OutputUsage   `json:"output_usage"`
+		Options   *OperationOptions  `json:"options,omitempty"`
+		Silences  []SilenceRecord    `json:"silences,omitempty"`
+		Budget    *task.BudgetReport `json:"budget,omitempty"` // where the time went if the deadline is exceeded
+		// the failures of all the steps, including the ones not failing the operation
+		Failures []task.StepFailure `json:"failures,omitempty"`
+	}{
+		Type:     info.operationType,
+		Cluster:  info.clusterName,
+		Finished: info.finished,
+		CurTask: TaskProgress{
+			Task:     tui.StripColor(info.curTask.Task),
+			ID:       info.curTask.ID,
+			Progress: tui.StripColor(info.curTask.Progress),
+			Phase:    info.curTask.Phase,
+			Detail:   info.curTask.Detail,
+		},
+		Result:   info.result,
+		LogFile:  info.logFile,
+		Outputs:  usage,
+		Options:  info.options,
+		Silences: info.silences,
+	}
+	if info.ctx != nil {
+		v.Budget = info.ctx.BudgetReport()
+		v.Failures = info.ctx.Failures()
+	}
+	if info.err != nil {
+		v.Error = tui.StripColor(info.err.Error())
+		if errx := errutil.Cast(info.err); errx != nil {
+			v.ErrorType = errx.Type().FullName()
+		}
+	}
+	return json.Marshal(v)
+}
+
+// newTaskContext creates a context for the tasks of the operation, the task
+// being executed and the outputs collected are tracked by the operation
+func (info *OperationInfo) newTaskContext() *task.Context {
+	ctx := task.NewContext()
+	ctx.SetOutputLimits(task.DefaultOutputLimit, task.DefaultOutputSpillThreshold, info.spillDir())
+	info.mu.Lock()
+	info.ctx = ctx
+	info.mu.Unlock()
+	ctx.SetNamespace(info.operationType.String() + "/" + info.clusterName)
+	ctx.SetLogger(zap.L().With(logger.OperationScope(info.clusterName)))
+	ctx.SetBreakpoints(info.breakpoints, info.breakOnErrors)
+	ctx.SetConcurrency(info.concurrency)
+	if !info.deadline.IsZero() {
+		ctx.SetDeadline(info.deadline)
+	}
+	if info.mock != nil {
+		ctx.SetExecutorFactory(info.mock.executorFactory)
+	}
+	ctx.SetConfigStamper(info.stamper)
+	ctx.SetHostKeyVerifier(info.hostKeys)
+	if info.globalOpts != nil {
+		ctx.SetRemoteTmpDirs(info.globalOpts)
+	}
+	ctx.SetHostFollowers(info.followers)
+	ctx.Subscribe(task.EventTaskBegin, func(t task.Task, id string) {
+		info.mu.Lock()
+		info.curTask = TaskProgress{Task: t.String(), ID: id}
+		info.tasksBegun++
+		info.mu.Unlock()
+		info.publishTaskEvent(task.EventTaskBegin, t, TaskProgress{ID: id}, nil)
+	})
+	ctx.Subscribe(task.EventTaskProgress, func(t task.Task, progress string) {
+		id := ctx.TaskID(t)
+		info.mu.Lock()
+		info.curTask = TaskProgress{Task: t.String(), ID: id, Progress: progress}
+		info.mu.Unlock()
+		info.publishTaskEvent(task.EventTaskProgress, t, TaskProgress{ID: id, Progress: progress}, nil)
+	})
+	ctx.Subscribe(task.EventTaskPhase, func(t task.Task, phase task.Phase, detail string) {
+		id := ctx.TaskID(t)
+		info.mu.Lock()
+		info.curTask = TaskProgress{Task: t.String(), ID: id, Phase: phase, Detail: detail}
+		info.mu.Unlock()
+		info.publishTaskEvent(task.EventTaskPhase, t, TaskProgress{ID: id, Phase: phase, Detail: detail}, nil)
+		if phase == task.PhasePaused {
+			log.Warnf("Paused %s, inspect the hosts and resume by pressing Enter, or by POST /operations/%s/resume to the status server",
+				detail, info.clusterName)
+		}
+	})
+	ctx.Subscribe(task.EventTaskFinish, func(t task.Task, err error) {
+		info.mu.Lock()
+		info.tasksFinished++
+		if err != nil {
+			info.tasksFailed++
+		}
+		info.mu.Unlock()
+		info.publishTaskEvent(task.EventTaskFinish, t, TaskProgress{ID: ctx.TaskID(t)}, err)
+	})
+	return ctx
+}
+
+// the operations running or last run on each cluster
+var (
+	operationInfoMu sync.RWMutex
+	operationInfos  = make(map[string]*OperationInfo)
+)
+
+// GetCurrentOperation returns the operation running or last run on the
+// cluster by this process, nil if there is no such operation
+func GetCurrentOperation(clusterName string) *OperationInfo {
+	operationInfoMu.RLock()
+	defer operationInfoMu.RUnlock()
+	return operationInfos[clusterName]
+}
+
+// ListOperations returns the operations running or last run on each cluster
+// by this process, sorted by the names of clusters
+func ListOperations() []*OperationInfo {
+	operationInfoMu.RLock()
+	defer operationInfoMu.RUnlock()
+	infos := make([]*OperationInfo, 0, len(operationInfos))
+	for _, info := range operationInfos {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].clusterName < infos[j].clusterName
+	})
+	return infos
+}
+
+// spillDir returns the dir large outputs of hosts are spilled to. It's removed
+// once the operation succeeds, and kept after a failure for the reports to
+// reference the full outputs, until the next operation of the cluster begins.
+func (info *OperationInfo) spillDir() string {
+	return filepath.Join(info.logDir, "outputs")
+}
+
+// removeSpilledOutputs removes the outputs of hosts spilled to files
+func (info *OperationInfo) removeSpilledOutputs() {
+	if err := os.RemoveAll(info.spillDir()); err != nil {
+		zap.L().Warn("Failed to remove the spilled outputs of hosts", logger.OperationScope(info.clusterName), zap.Error(err))
+	}
+}
+
+// beginOperation takes the operation lock of the cluster, records the
+// operation and the options it runs with, and starts saving its full log
+// under the log directory of the cluster. See newOperationOptions for the
+// options. ErrClusterBusy is returned if the cluster is being operated by
+// another process, see acquireOperationLock.
+func (m *Manager) beginOperation(clusterName string, operationType OperationType, options ...interface{}) (*OperationInfo, error) {
+	if err := m.acquireOperationLock(clusterName); err != nil {
+		return nil, err
+	}
+	info := &OperationInfo{
+		operationType: operationType,
+		clusterName:   clusterName,
+		logDir:        m.specManager.Path(clusterName, "logs"),
+		options:       newOperationOptions(options...),
+		startTime:     time.Now(),
+		mock:          m.mockCluster(clusterName),
+		followers:     task.NewHostFollowers(),
+	}
+	// the outputs kept after the last operation failed are no longer needed
+	info.removeSpilledOutputs()
+	// the configs are rendered from the metadata of the current generation,
+	// it's 0 for the clusters not deployed yet
+	var generation uint64
+	var hostKeys map[string]string
+	if metadata, err := m.metaFresh(clusterName); metadata != nil && (err == nil || errors.Is(perrs.Cause(err), meta.ErrValidate)) {
+		generation = metadata.GetBaseMeta().ConfigGeneration
+		hostKeys = metadata.GetBaseMeta().HostKeys
+		info.instances = countInstances(metadata.GetTopology())
+		info.globalOpts = metadata.GetTopology().BaseTopo().GlobalOptions
+	}
+	if history, err := m.readHistory(clusterName); err == nil {
+		info.estimate = EstimateDuration(history, operationType, info.instances)
+	}
+	overwriteConfig := false
+	for _, o := range options {
+		if opt, ok := o.(operator.Options); ok {
+			if info.profiler == nil {
+				info.profiler = startOperationProfiler(info.logDir, operationType, info.startTime, opt)
+			}
+			overwriteConfig = opt.OverwriteConfig
+			info.breakpoints, info.breakOnErrors = opt.Breakpoints, opt.BreakOnErrors
+			info.concurrency = opt.Concurrency
+			if opt.OperationTimeout > 0 {
+				info.deadline = info.startTime.Add(time.Duration(opt.OperationTimeout) * time.Second)
+			}
+			for _, host := range opt.FollowHosts {
+				info.followers.Follow(host, followOutput)
+			}
+		}
+	}
+	info.stamper = spec.NewConfigStamper(clusterName, generation, overwriteConfig)
+	info.hostKeys = spec.NewHostKeyVerifier(clusterName, hostKeys, m.hostKeyFetcher(clusterName))
+	operationInfoMu.Lock()
+	operationInfos[clusterName] = info
+	operationInfoMu.Unlock()
+	publishOperationEvent(OperationEvent{Kind: EventOperationBegin, Operation: operationType, Cluster: clusterName})
+
+	logFile, err := logger.StartOperationLog(m.specManager.Path(clusterName, "logs"), operationType.String(), clusterName)
+	if err != nil {
+		zap.L().Warn("Failed to create operation log file", logger.OperationScope(clusterName), zap.Error(err))
+	}
+	info.mu.Lock()
+	info.logFile = logFile
+	info.mu.Unlock()
+	// goes to the audit log, and the operation log if it's created
+	if data, err := json.Marshal(info.options); err == nil {
+		zap.L().Info("Operation options", logger.OperationScope(clusterName), zap.String("operation", operationType.String()), zap.ByteString("options", data))
+	}
+	return info, nil
+}
+
+// saveConfigGeneration saves the generation the configs pushed by the
+// operation are stamped with to the metadata, whether the operation succeeds
+// or not, otherwise the configs pushed before it fails are taken as the ones
+// of a concurrent operation by the next operation
+func (m *Manager) saveConfigGeneration(info *OperationInfo) error {
+	if info.stamper == nil || !info.stamper.Pushed() {
+		return nil
+	}
+	if err := m.specManager.CheckWritable(); err != nil {
+		return err
+	}
+	metadata, err := m.metaFresh(info.clusterName)
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) &&
+		!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
+		return err
+	}
+	gm, ok := metadata.(spec.GenerationalMetadata)
+	if !ok || metadata.GetBaseMeta().ConfigGeneration >= info.stamper.Generation() {
+		return nil
+	}
+	gm.SetConfigGeneration(info.stamper.Generation())
+	return m.specManager.SaveMeta(info.clusterName, metadata)
+}
+
+// endOperation records the result of the operation, releases the operation
+// lock, and prints the path of its full log
+func (m *Manager) endOperation(info *OperationInfo, err error) {
+	info.mu.Lock()
+	info.err = err
+	info.finished = true
+	info.endTime = time.Now()
+	ctx := info.ctx
+	info.mu.Unlock()
+	if ctx != nil {
+		ctx.StopDeadline()
+	}
+	info.followers.Close()
+	info.profiler.stop()
+	if err != nil {
+		m.recordInstanceErrors(info, err)
+	}
+	if serr := m.saveConfigGeneration(info); serr != nil {
+		zap.L().Warn("Failed to save the generation of configs", logger.OperationScope(info.clusterName), zap.Error(serr))
+	}
+	if serr := m.saveHostKeys(info); serr != nil {
+		zap.L().Warn("Failed to save the host keys", logger.OperationScope(info.clusterName), zap.Error(serr))
+	}
+	m.releaseOperationLock(info.clusterName)
+	e := OperationEvent{Kind: EventOperationFinish, Operation: info.operationType, Cluster: info.clusterName}
+	if err != nil {
+		e.Error = tui.StripColor(err.Error())
+	}
+	publishOperationEvent(e)
+	if err := m.appendHistory(info); err != nil {
+		zap.L().Warn("Failed to record the operation in history", logger.OperationScope(info.clusterName), zap.Error(err))
+	}
+	if err == nil {
+		info.removeSpilledOutputs()
+	} else if utils.IsExist(info.spillDir()) {
+		log.Infof("full outputs of hosts: %s", info.spillDir())
+	}
+	if info.logFile == "" {
+		return
+	}
+	log.Infof("full log: %s", info.logFile)
+	if err := logger.StopOperationLog(info.logFile); err != nil {
+		zap.L().Warn("Failed to close operation log file", logger.OperationScope(info.clusterName), zap.Error(err))
+	}
+}
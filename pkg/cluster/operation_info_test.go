@@ -0,0 +1,156 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joomcode/errorx"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/logger"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationType(t *testing.T) {
+	for tp := OperationDeploy; tp <= OperationMigrateDataDir; tp++ {
+		parsed, err := ParseOperationType(tp.String())
+		assert.Nil(t, err)
+		assert.Equal(t, tp, parsed)
+	}
+	_, err := ParseOperationType("unknown")
+	assert.NotNil(t, err)
+
+	info := &OperationInfo{operationType: OperationScaleOut, clusterName: "test"}
+	data, err := json.Marshal(info)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"type":"scale-out"`)
+}
+
+func TestConfigGeneration(t *testing.T) {
+	m, mc, _, cleanup := newTestMockCluster(t, 1)
+	defer cleanup()
+	generation := func() uint64 {
+		metadata, err := m.metaFresh("mock")
+		require.Nil(t, err)
+		return metadata.GetBaseMeta().ConfigGeneration
+	}
+
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10, IgnoreConfigCheck: true}
+	require.Nil(t, m.Reload("mock", opt, true))
+	assert.Equal(t, uint64(1), generation())
+
+	// a concurrent operation pushed the config after it's rendered
+	conf := "/home/tidb/deploy/tidb-4000/conf/tidb.toml"
+	mc.Host("mock-1").Respond("head -n 2 "+conf, "# tiup-config: cluster=mock generation=2 hash=other\n", "")
+	err := m.Reload("mock", opt, true)
+	require.NotNil(t, err)
+	assert.True(t, errorx.IsOfType(err, spec.ErrConfigConflict))
+	// the configs pushed before the conflict are of the generation
+	assert.Equal(t, uint64(2), generation())
+
+	opt.OverwriteConfig = true
+	require.Nil(t, m.Reload("mock", opt, true))
+	assert.Equal(t, uint64(3), generation())
+
+	// the configs are pushed by the operation failing afterwards, and are not
+	// taken as the ones of a concurrent operation by the next one
+	mc.Host("mock-1").Respond("head -n 2 "+conf, "", "")
+	mc.Host("mock-1").Respond("systemctl daemon-reload", "", "failed to reload")
+	opt.OverwriteConfig = false
+	require.NotNil(t, m.Reload("mock", opt, false))
+	assert.Equal(t, uint64(4), generation())
+	mc.Host("mock-1").Respond("head -n 2 "+conf, "# tiup-config: cluster=mock generation=4 hash=pushed\n", "")
+	mc.Host("mock-1").Respond("systemctl daemon-reload", "", "")
+	require.Nil(t, m.Reload("mock", opt, true))
+	assert.Equal(t, uint64(5), generation())
+}
+
+func TestOperationLogFile(t *testing.T) {
+	m, mc, _, cleanup := newTestMockCluster(t, 1)
+	defer cleanup()
+	logger.InitGlobalLogger()
+	var out bytes.Buffer
+	log.SetStdout(&out)
+	defer log.SetStdout(os.Stdout)
+
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10}
+	mc.Host("mock-1").Respond("systemctl daemon-reload && systemctl start tikv-20160", "", "address already in use")
+	require.NotNil(t, m.StartCluster("mock", opt))
+	info := GetCurrentOperation("mock")
+	logFile := info.LogFile()
+	require.NotEmpty(t, logFile)
+	assert.Equal(t, m.specManager.Path("mock", "logs"), filepath.Dir(logFile))
+	assert.Contains(t, out.String(), "full log: "+logFile)
+	data, err := json.Marshal(info)
+	require.Nil(t, err)
+	assert.Contains(t, string(data), `"log_file":`)
+
+	// the log of the operation has the details of the failure besides the
+	// messages printed, and ends with the path of itself
+	content, err := ioutil.ReadFile(logFile)
+	require.Nil(t, err)
+	assert.Contains(t, string(content), "Operation options")
+	assert.Contains(t, string(content), "address already in use")
+	assert.Contains(t, string(content), "full log: "+logFile)
+}
+
+func TestSpilledOutputs(t *testing.T) {
+	m, _, _, cleanup := newTestMockCluster(t, 1)
+	defer cleanup()
+
+	spill := func() (*OperationInfo, string) {
+		info, err := m.beginOperation("mock", OperationStart)
+		require.Nil(t, err)
+		ctx := info.newTaskContext()
+		ctx.SetOutputLimits(0, 1, info.spillDir())
+		ctx.SetOutputs("mock-1", []byte("large stdout"), []byte("large stderr"))
+		stdoutFile, _ := ctx.GetOutputFiles("mock-1")
+		require.FileExists(t, stdoutFile)
+		return info, stdoutFile
+	}
+
+	// the outputs are kept after a failure until the next operation begins
+	info, file := spill()
+	m.endOperation(info, errors.New("failed"))
+	assert.FileExists(t, file)
+	info, next := spill()
+	assert.NoFileExists(t, file)
+	m.endOperation(info, nil)
+	assert.NoFileExists(t, next)
+}
+
+func TestStepResults(t *testing.T) {
+	assert.Empty(t, stepResults(nil))
+	results := stepResults([]task.StepFailure{
+		{Step: "Start tikv 10.0.1.1:20160", Err: errors.New("address already in use")},
+		{Step: "Start tidb 10.0.1.2:4000", Err: errors.New("timed out")},
+		{Step: "Start tikv 10.0.1.1:20160", Err: errors.New("no space left")},
+		{Step: "Start pd 10.0.1.2:2379", Err: errors.New("host 10.0.1.2 is degraded"), Skipped: true},
+	})
+	assert.Equal(t, []StepResult{
+		{Step: "Start tikv 10.0.1.1:20160", Status: "Error", Errors: []string{"address already in use", "no space left"}},
+		{Step: "Start tidb 10.0.1.2:4000", Status: "Error", Errors: []string{"timed out"}},
+		{Step: "Start pd 10.0.1.2:2379", Status: "Skipped", Errors: []string{"host 10.0.1.2 is degraded"}},
+	}, results)
+}
@@ -0,0 +1,248 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/pingcap/tiup/pkg/version"
+	"go.uber.org/zap"
+)
+
+// the file the operations on a cluster are appended to, one JSON object per line
+const operationHistoryFile = "history.jsonl"
+
+// the value secrets are replaced with
+const maskedSecret = "******"
+
+// the names of the options and flags holding secrets
+var secretNameRegexp = regexp.MustCompile(`(?i)password|passwd|passphrase|secret|token`)
+
+// the flags taking a secret as their value, which may be passed as the next
+// argument, the others (e.g., the boolean --password) are only masked in the
+// "--flag=value" form
+var secretValueFlags = map[string]bool{
+	"--status-token": true,
+}
+
+// OperationOptions is the effective command line and the fully resolved
+// options an operation runs with, i.e. after the defaults are applied.
+// The secrets are masked.
+type OperationOptions struct {
+	Command           []string               `json:"command"`
+	TiUPVersion       string                 `json:"tiup_version"`
+	MetaSchemaVersion int                    `json:"meta_schema_version"`
+	Resolved          map[string]interface{} `json:"resolved"`
+}
+
+// OperationRecord is an entry of the history of the operations on a cluster
+type OperationRecord struct {
+	Operation OperationType     `json:"operation"`
+	Cluster   string            `json:"cluster"`
+	StartTime time.Time         `json:"start_time"`
+	EndTime   time.Time         `json:"end_time"`
+	Error     string            `json:"error,omitempty"`
+	Options   *OperationOptions `json:"options"`
+	// the number of instances of the cluster and the time spent on each
+	// phase, for estimating the duration of later operations
+	Instances int                `json:"instances,omitempty"`
+	Phases    []task.PhaseTiming `json:"phases,omitempty"`
+	// the failures of all the steps, including the ones not failing the operation
+	Failures []task.StepFailure `json:"failures,omitempty"`
+}
+
+// newOperationOptions records the command line and the options, each of
+// options is a struct, e.g. operator.Options, or a map[string]interface{},
+// and their fields are merged by names
+func newOperationOptions(options ...interface{}) *OperationOptions {
+	resolved := make(map[string]interface{})
+	for _, o := range options {
+		if m, ok := o.(map[string]interface{}); ok {
+			for k, v := range m {
+				resolved[k] = maskOption(k, v)
+			}
+			continue
+		}
+		if m, ok := optionValue(reflect.ValueOf(o)).(map[string]interface{}); ok {
+			for k, v := range m {
+				resolved[k] = v
+			}
+		}
+	}
+	return &OperationOptions{
+		Command:           maskCommand(os.Args),
+		TiUPVersion:       version.NewTiUPVersion().String(),
+		MetaSchemaVersion: spec.MetaSchemaVersion,
+		Resolved:          resolved,
+	}
+}
+
+// optionValue converts v to a value which is serialized as is, structs are
+// converted to maps of their exported fields with the secrets masked
+func optionValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		// e.g. the selector of instances, which only makes sense as text
+		if s, ok := v.Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return v.Interface()
+	}
+	m := make(map[string]interface{})
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		m[f.Name] = maskOption(f.Name, optionValue(v.Field(i)))
+	}
+	return m
+}
+
+// maskOption masks the value of the option if it's a secret
+func maskOption(name string, value interface{}) interface{} {
+	if s, ok := value.(string); ok && s != "" && secretNameRegexp.MatchString(name) {
+		return maskedSecret
+	}
+	return value
+}
+
+// maskCommand returns a copy of args with the values of the flags holding
+// secrets masked, the "--flag value" form is only supported for the flags
+// known to take a value
+func maskCommand(args []string) []string {
+	masked := make([]string, len(args))
+	copy(masked, args)
+	for i := 1; i < len(masked); i++ {
+		arg := masked[i]
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if idx := strings.Index(arg, "="); idx >= 0 {
+			if secretNameRegexp.MatchString(arg[:idx]) {
+				masked[i] = arg[:idx+1] + maskedSecret
+			}
+		} else if secretValueFlags[arg] && i+1 < len(masked) {
+			masked[i+1] = maskedSecret
+			i++
+		}
+	}
+	return masked
+}
+
+// appendHistory appends the record of the operation to the history of the cluster
+func (m *Manager) appendHistory(info *OperationInfo) error {
+	info.mu.RLock()
+	record := OperationRecord{
+		Operation: info.operationType,
+		Cluster:   info.clusterName,
+		StartTime: info.startTime,
+		EndTime:   info.endTime,
+		Options:   info.options,
+		Instances: info.instances,
+	}
+	if info.err != nil {
+		record.Error = info.err.Error()
+	}
+	ctx := info.ctx
+	info.mu.RUnlock()
+	if ctx != nil {
+		record.Phases = ctx.PhaseTimings()
+		record.Failures = ctx.Failures()
+	}
+	if record.Instances == 0 {
+		// e.g., deployed by the operation
+		if metadata, _ := m.metaFresh(info.clusterName); metadata != nil {
+			record.Instances = countInstances(metadata.GetTopology())
+		}
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	if err := utils.CreateDir(m.specManager.Path(info.clusterName)); err != nil {
+		return perrs.AddStack(err)
+	}
+	f, err := os.OpenFile(m.specManager.Path(info.clusterName, operationHistoryFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	defer f.Close()
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		return perrs.AddStack(err)
+	}
+
+	// the summary is only a cache for listing, and the cluster may be gone
+	_ = m.specManager.UpdateSummaryOperation(info.clusterName, spec.SummaryOperation{
+		Operation: record.Operation.String(),
+		EndTime:   record.EndTime,
+		Error:     record.Error,
+	})
+	return nil
+}
+
+// LastOptions returns the command line and the options the last operation on
+// the cluster ran with, nil if no operation is recorded
+func (m *Manager) LastOptions(clusterName string) (*OperationOptions, error) {
+	f, err := os.Open(m.specManager.Path(clusterName, operationHistoryFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, perrs.AddStack(err)
+	}
+	defer f.Close()
+
+	var last string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			last = line
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	if last == "" {
+		return nil, nil
+	}
+
+	var record OperationRecord
+	if err := json.Unmarshal([]byte(last), &record); err != nil {
+		zap.L().Debug("Failed to parse the history of operations", zap.String("line", last), zap.Error(err))
+		return nil, perrs.Annotatef(err, "corrupted history of operations of cluster %s", clusterName)
+	}
+	return record.Options, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"testing"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationHistory(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+
+	last, err := m.LastOptions("prod-eu")
+	require.Nil(t, err)
+	assert.Nil(t, last)
+
+	selector, err := spec.ParseSelector("role=tikv")
+	require.Nil(t, err)
+	secrets := struct {
+		User     string
+		Password string
+	}{"tidb", "hunter2"}
+	info, err := m.beginOperation("prod-eu", OperationStart, operator.Options{SSHTimeout: 7, Selector: selector}, secrets,
+		map[string]interface{}{"Version": "v4.0.0"})
+	require.Nil(t, err)
+	m.endOperation(info, nil)
+
+	last, err = m.LastOptions("prod-eu")
+	require.Nil(t, err)
+	require.NotNil(t, last)
+	assert.Equal(t, spec.MetaSchemaVersion, last.MetaSchemaVersion)
+	assert.NotEmpty(t, last.TiUPVersion)
+	assert.Equal(t, float64(7), last.Resolved["SSHTimeout"])
+	assert.Equal(t, "role=tikv", last.Resolved["Selector"])
+	assert.Equal(t, "tidb", last.Resolved["User"])
+	assert.Equal(t, maskedSecret, last.Resolved["Password"])
+	assert.Equal(t, "v4.0.0", last.Resolved["Version"])
+
+	data, err := json.Marshal(info)
+	require.Nil(t, err)
+	assert.Contains(t, string(data), `"meta_schema_version":1`)
+	assert.NotContains(t, string(data), "hunter2")
+
+	assert.Equal(t,
+		[]string{"tiup", "--db-password=" + maskedSecret, "--status-token", maskedSecret, "-y"},
+		maskCommand([]string{"tiup", "--db-password=hunter2", "--status-token", "abc", "-y"}))
+	// the boolean flags don't take the next argument as their value
+	assert.Equal(t,
+		[]string{"tiup-cluster", "deploy", "--password", "prod-eu", "v4.0.0", "topology.yaml"},
+		maskCommand([]string{"tiup-cluster", "deploy", "--password", "prod-eu", "v4.0.0", "topology.yaml"}))
+	assert.Equal(t,
+		[]string{"tiup-cluster", "scale-out", "-p", "prod-eu", "scale.yaml"},
+		maskCommand([]string{"tiup-cluster", "scale-out", "-p", "prod-eu", "scale.yaml"}))
+}
@@ -0,0 +1,210 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/utils"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	errNSOptionDefaults = errorx.NewNamespace("option_defaults")
+	// ErrOptionDefaultsInvalid is returned when the default options are invalid
+	ErrOptionDefaultsInvalid = errNSOptionDefaults.NewType("invalid", errutil.ErrTraitPreCheck)
+)
+
+// the file of default options, under the profile dir
+const optionDefaultsFile = "defaults.yaml"
+
+// OptionDefaults are the standing default values of operator.Options. The keys
+// are named after the flags setting the options, and the fields are named
+// after the fields of operator.Options they set. Nil fields are not set.
+type OptionDefaults struct {
+	SSHTimeout        *int64   `yaml:"ssh-timeout,omitempty"`
+	OptTimeout        *int64   `yaml:"wait-timeout,omitempty"`
+	FirstStartTimeout *int64   `yaml:"first-start-timeout,omitempty"`
+	RestartTimeout    *int64   `yaml:"restart-timeout,omitempty"`
+	APITimeout        *int64   `yaml:"transfer-timeout,omitempty"`
+	IgnoreConfigCheck *bool    `yaml:"ignore-config-check,omitempty"`
+	NativeSSH         *bool    `yaml:"native-ssh,omitempty"`
+	Concurrency       *int     `yaml:"concurrency,omitempty"`
+	IgnoreErrors      *bool    `yaml:"ignore-errors,omitempty"`
+	CachePackages     *bool    `yaml:"cache-packages,omitempty"`
+	SeedHosts         []string `yaml:"seed-hosts,omitempty"`
+	SilenceAlerts     *bool    `yaml:"silence-alerts,omitempty"`
+	DiskUsageWarn     *int64   `yaml:"disk-usage-warn,omitempty"`
+	DiskUsageFail     *int64   `yaml:"disk-usage-fail,omitempty"`
+}
+
+// optionDefaultKeys returns the keys of the default options
+func optionDefaultKeys() []string {
+	var keys []string
+	t := reflect.TypeOf(OptionDefaults{})
+	for i := 0; i < t.NumField(); i++ {
+		keys = append(keys, strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0])
+	}
+	return keys
+}
+
+// OptionDefaultEnv returns the environment variable overriding the default
+// option of key, e.g. TIUP_CLUSTER_SSH_TIMEOUT for ssh-timeout
+func OptionDefaultEnv(key string) string {
+	if key == "native-ssh" {
+		return localdata.EnvNameNativeSSHClient
+	}
+	return "TIUP_CLUSTER_" + strings.ToUpper(strings.Replace(key, "-", "_", -1))
+}
+
+// Validate checks the values of the default options
+func (d *OptionDefaults) Validate() error {
+	for key, v := range map[string]*int64{
+		"ssh-timeout":         d.SSHTimeout,
+		"wait-timeout":        d.OptTimeout,
+		"first-start-timeout": d.FirstStartTimeout,
+		"restart-timeout":     d.RestartTimeout,
+		"transfer-timeout":    d.APITimeout,
+	} {
+		if v != nil && *v <= 0 {
+			return ErrOptionDefaultsInvalid.New("The default value of '%s' must be positive, got %d", key, *v)
+		}
+	}
+	if d.Concurrency != nil && *d.Concurrency <= 0 {
+		return ErrOptionDefaultsInvalid.New("The default value of 'concurrency' must be positive, got %d", *d.Concurrency)
+	}
+	for key, v := range map[string]*int64{
+		"disk-usage-warn": d.DiskUsageWarn,
+		"disk-usage-fail": d.DiskUsageFail,
+	} {
+		if v != nil && *v > 100 {
+			return ErrOptionDefaultsInvalid.New("The default value of '%s' is a percentage, got %d", key, *v)
+		}
+	}
+	return nil
+}
+
+// parseOptionEnv parses the value of an environment variable as the kind of the field
+func parseOptionEnv(key, value string, kind reflect.Kind) (reflect.Value, error) {
+	switch kind {
+	case reflect.Int, reflect.Int64:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || v <= 0 {
+			return reflect.Value{}, ErrOptionDefaultsInvalid.
+				New("The value of %s must be a positive integer, got '%s'", OptionDefaultEnv(key), value)
+		}
+		if kind == reflect.Int {
+			return reflect.ValueOf(int(v)), nil
+		}
+		return reflect.ValueOf(v), nil
+	case reflect.Bool:
+		switch strings.ToLower(value) {
+		case "true", "1", "yes", "enable":
+			return reflect.ValueOf(true), nil
+		case "false", "0", "no", "disable":
+			return reflect.ValueOf(false), nil
+		}
+		return reflect.Value{}, ErrOptionDefaultsInvalid.
+			New("The value of %s must be a boolean, got '%s'", OptionDefaultEnv(key), value)
+	default:
+		return reflect.ValueOf(strings.Split(value, ",")), nil
+	}
+}
+
+// Apply seeds opt with the default options, the precedence is: the defaults
+// file < environment variables < explicit options. explicit reports whether
+// the option of key is set explicitly, e.g. by a flag of the invocation, and
+// such options are kept untouched.
+func (d *OptionDefaults) Apply(opt *operator.Options, explicit func(key string) bool) error {
+	dv := reflect.ValueOf(d).Elem()
+	ov := reflect.ValueOf(opt).Elem()
+	for i := 0; i < dv.NumField(); i++ {
+		field := dv.Type().Field(i)
+		key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if explicit != nil && explicit(key) {
+			continue
+		}
+
+		target := ov.FieldByName(field.Name)
+		if value, ok := os.LookupEnv(OptionDefaultEnv(key)); ok && value != "" {
+			v, err := parseOptionEnv(key, value, target.Kind())
+			if err != nil {
+				return err
+			}
+			target.Set(v)
+			continue
+		}
+
+		fv := dv.Field(i)
+		switch {
+		case fv.Kind() == reflect.Ptr && !fv.IsNil():
+			target.Set(fv.Elem())
+		case fv.Kind() == reflect.Slice && fv.Len() > 0:
+			target.Set(fv)
+		}
+	}
+	return nil
+}
+
+// LoadOptionDefaults loads the default options from the defaults file under
+// the profile dir, empty defaults are returned if the file doesn't exist.
+// Unknown keys are rejected, so typos don't silently take no effect.
+func (m *Manager) LoadOptionDefaults() (*OptionDefaults, error) {
+	d := &OptionDefaults{}
+	fname := spec.ProfilePath(optionDefaultsFile)
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return d, nil
+		}
+		return nil, perrs.AddStack(err)
+	}
+
+	if err := yaml.UnmarshalStrict(data, d); err != nil {
+		return nil, ErrOptionDefaultsInvalid.
+			Wrap(err, "Failed to parse the default options in %s", fname).
+			WithProperty(cliutil.SuggestionFromFormat(
+				"The supported keys are: %s", strings.Join(optionDefaultKeys(), ", ")))
+	}
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// SaveOptionDefaults saves the default options to the defaults file under the profile dir
+func (m *Manager) SaveOptionDefaults(d *OptionDefaults) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(d)
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	if err := utils.CreateDir(spec.ProfileDir()); err != nil {
+		return perrs.AddStack(err)
+	}
+	return perrs.AddStack(ioutil.WriteFile(spec.ProfilePath(optionDefaultsFile), data, 0644))
+}
@@ -0,0 +1,46 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"os"
+	"testing"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestOptionDefaults(t *testing.T) {
+	d := &OptionDefaults{}
+	err := yaml.UnmarshalStrict([]byte("ssh-timeout: 30\nnative-ssh: true\n"), d)
+	require.Nil(t, err)
+	err = yaml.UnmarshalStrict([]byte("ssh-timout: 30\n"), &OptionDefaults{})
+	assert.NotNil(t, err)
+
+	os.Setenv(OptionDefaultEnv("wait-timeout"), "200")
+	defer os.Unsetenv(OptionDefaultEnv("wait-timeout"))
+
+	// the defaults file < environment variables < explicit options
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 120, NativeSSH: false}
+	err = d.Apply(&opt, func(key string) bool { return key == "native-ssh" })
+	require.Nil(t, err)
+	assert.Equal(t, int64(30), opt.SSHTimeout)
+	assert.Equal(t, int64(200), opt.OptTimeout)
+	assert.False(t, opt.NativeSSH)
+
+	timeout := int64(0)
+	assert.NotNil(t, (&OptionDefaults{SSHTimeout: &timeout}).Validate())
+}
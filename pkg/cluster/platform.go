@@ -0,0 +1,239 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/set"
+	"golang.org/x/mod/semver"
+)
+
+// the command to gather the OS and architecture of a host, followed by the
+// distribution and its version if it's linux, see parseHostFacts
+const hostPlatformCommand = "uname -s -m; cat /etc/os-release 2>/dev/null || true"
+
+var (
+	errDeployPlatformMismatch    = errNSDeploy.NewType("platform_mismatch", errutil.ErrTraitPreCheck)
+	errDeployPlatformUnsupported = errNSDeploy.NewType("platform_unsupported", errutil.ErrTraitPreCheck)
+)
+
+// parseHostPlatform converts the output of `uname -s -m` to the OS and
+// architecture names used by the repository
+func parseHostPlatform(output string) (string, string) {
+	fields := strings.Fields(output)
+	if len(fields) < 2 {
+		return "", ""
+	}
+	hostOS := strings.ToLower(fields[0])
+	hostArch := strings.ToLower(fields[1])
+	switch hostArch {
+	case "x86_64":
+		hostArch = "amd64"
+	case "aarch64":
+		hostArch = "arm64"
+	}
+	return hostOS, hostArch
+}
+
+// checkHostPlatforms gathers the platforms of the hosts of topo, and verifies
+// the platform of the component selected for each instance matches the one of
+// its host, see checkPlatformFacts
+func (m *Manager) checkHostPlatforms(
+	topo spec.Topology,
+	clusterVersion string,
+	user string,
+	sshConnProps *cliutil.SSHConnectionProps,
+	sshTimeout int64,
+	nativeSSH bool,
+) error {
+	var detectTasks []*task.StepDisplay
+	uniqueHosts := set.NewStringSet()
+	topo.IterInstance(func(inst spec.Instance) {
+		host := inst.GetHost()
+		if uniqueHosts.Exist(host) {
+			return
+		}
+		uniqueHosts.Insert(host)
+		t := task.NewBuilder().
+			RootSSH(
+				host,
+				inst.GetSSHPort(),
+				user,
+				sshConnProps.Password,
+				sshConnProps.IdentityFile,
+				sshConnProps.IdentityFilePassphrase,
+				sshTimeout,
+				nativeSSH,
+			).
+			Shell(host, hostPlatformCommand, false).
+			BuildAsStepMessage(task.MsgDetectPlatform, task.MessageParams{"host": host})
+		detectTasks = append(detectTasks, t)
+	})
+
+	ctx := task.NewContext()
+	t := task.NewBuilder().
+		ParallelStep("+ Detect platforms of target hosts", detectTasks...).
+		Build()
+	if err := t.Execute(ctx); err != nil {
+		return err
+	}
+
+	facts := make(map[string]HostFacts)
+	for host := range uniqueHosts {
+		stdout, _, _ := ctx.GetOutputs(host)
+		facts[host] = parseHostFacts(string(stdout))
+	}
+
+	// whether a component is published for a platform, keyed by component and platform
+	available := make(map[string]bool)
+	componentAvailable := func(comp, version, nodeOS, arch string) bool {
+		key := fmt.Sprintf("%s:%s-%s", comp, nodeOS, arch)
+		if ok, found := available[key]; found {
+			return ok
+		}
+		repo, err := clusterutil.NewRepository(nodeOS, arch)
+		available[key] = err == nil && repo.ComponentAvailable(comp, version)
+		return available[key]
+	}
+	return m.checkPlatformFacts(topo, clusterVersion, facts, componentAvailable)
+}
+
+// checkPlatformFacts compares the platform of each instance in topo with the
+// facts of its host, the mismatches are printed as a table and an error is
+// returned if there is any, componentAvailable tells whether a version of a
+// component is published for a platform to suggest the right one. The
+// platforms are then checked against the support matrix, see checkSupportMatrix.
+func (m *Manager) checkPlatformFacts(
+	topo spec.Topology,
+	clusterVersion string,
+	facts map[string]HostFacts,
+	componentAvailable func(comp, version, nodeOS, arch string) bool,
+) error {
+	rows := [][]string{{"Host", "Instance", "Topology Platform", "Host Platform", "Hint"}}
+	suggestions := set.NewStringSet()
+	topo.IterInstance(func(inst spec.Instance) {
+		hostOS, hostArch := facts[inst.GetHost()].OS, facts[inst.GetHost()].Arch
+		if hostOS == "" || (hostOS == inst.OS() && hostArch == inst.Arch()) {
+			return
+		}
+
+		comp := inst.ComponentName()
+		version := m.bindVersion(comp, clusterVersion)
+		hint := fmt.Sprintf("%s:%s is not published for %s/%s", comp, version, hostOS, hostArch)
+		if componentAvailable(comp, version, hostOS, hostArch) {
+			hint = fmt.Sprintf("set `os: %s` and `arch: %s` for this instance", hostOS, hostArch)
+			suggestions.Insert(fmt.Sprintf("%s/%s", hostOS, hostArch))
+		}
+		rows = append(rows, []string{
+			inst.GetHost(),
+			inst.ID(),
+			fmt.Sprintf("%s/%s", inst.OS(), inst.Arch()),
+			fmt.Sprintf("%s/%s", hostOS, hostArch),
+			hint,
+		})
+	})
+
+	if len(rows) == 1 {
+		return m.checkSupportMatrix(topo, clusterVersion, facts)
+	}
+
+	sort.Slice(rows[1:], func(i, j int) bool {
+		return rows[i+1][0] < rows[j+1][0] || (rows[i+1][0] == rows[j+1][0] && rows[i+1][1] < rows[j+1][1])
+	})
+	fmt.Println(color.RedString("The platform of the following instances does not match their hosts:"))
+	cliutil.PrintTable(rows, true)
+
+	err := errDeployPlatformMismatch.New("Platform of %d instance(s) does not match the target host", len(rows)-1)
+	if len(suggestions) > 0 {
+		platforms := suggestions.Slice()
+		sort.Strings(platforms)
+		return err.WithProperty(cliutil.SuggestionFromFormat(
+			"Builds for %s exist in the repository, please set the `os` and `arch` fields of the\n"+
+				"mismatched instances, or the `global` section of the topology file, and try again.",
+			strings.Join(platforms, ", ")))
+	}
+	return err.WithProperty(cliutil.SuggestionFromString(
+		"Please deploy to hosts of a platform the components are published for."))
+}
+
+// checkSupportMatrix evaluates the platforms of the hosts of the instances
+// against the support matrix, the untested ones are warned and an error is
+// returned for the ones known to be broken
+func (m *Manager) checkSupportMatrix(topo spec.Topology, clusterVersion string, facts map[string]HostFacts) error {
+	matrix, err := LoadSupportMatrix()
+	if err != nil {
+		return err
+	}
+
+	// the instances of a host with the same result are reported in a row
+	type result struct {
+		host   string
+		status SupportStatus
+		note   string
+	}
+	instances := make(map[result][]string)
+	topo.IterInstance(func(inst spec.Instance) {
+		hf, ok := facts[inst.GetHost()]
+		if !ok || hf.OS == "" {
+			return
+		}
+		comp := inst.ComponentName()
+		status, note := matrix.Evaluate(comp, semver.Major(m.bindVersion(comp, clusterVersion)), hf)
+		if status == PlatformSupported {
+			return
+		}
+		r := result{inst.GetHost(), status, note}
+		instances[r] = append(instances[r], inst.ID())
+	})
+	if len(instances) == 0 {
+		return nil
+	}
+
+	rows := [][]string{{"Host", "Platform", "Instances", "Status", "Note"}}
+	broken := 0
+	for r, ids := range instances {
+		status := color.YellowString(string(r.status))
+		if r.status == PlatformBroken {
+			status = color.RedString(string(r.status))
+			broken += len(ids)
+		}
+		sort.Strings(ids)
+		rows = append(rows, []string{r.host, facts[r.host].String(), strings.Join(ids, ","), status, r.note})
+	}
+	sort.Slice(rows[1:], func(i, j int) bool {
+		return rows[i+1][0] < rows[j+1][0] || (rows[i+1][0] == rows[j+1][0] && rows[i+1][2] < rows[j+1][2])
+	})
+	fmt.Println(color.YellowString("The following hosts are not in the supported platforms:"))
+	cliutil.PrintTable(rows, true)
+
+	if broken == 0 {
+		log.Warnf("The components are not tested on the platforms above, they may not work as expected")
+		return nil
+	}
+	return errDeployPlatformUnsupported.New("%d instance(s) are on the platforms known to be broken", broken).
+		WithProperty(cliutil.SuggestionFromFormat(
+			"Please deploy to hosts of the supported platforms. If the platform is known to work,\n"+
+				"add a rule for it to %s, the rules in it take precedence over the built in ones.",
+			spec.ProfilePath(supportMatrixFile)))
+}
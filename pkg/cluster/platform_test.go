@@ -0,0 +1,95 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseHostPlatform(t *testing.T) {
+	for output, expected := range map[string][2]string{
+		"Linux x86_64\n":  {"linux", "amd64"},
+		"Linux aarch64\n": {"linux", "arm64"},
+		"Darwin arm64\n":  {"darwin", "arm64"},
+		"":                {"", ""},
+	} {
+		hostOS, hostArch := parseHostPlatform(output)
+		assert.Equal(t, expected, [2]string{hostOS, hostArch}, output)
+	}
+}
+
+func TestCheckPlatformFacts(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+
+	topo := new(spec.Specification)
+	require.Nil(t, yaml.UnmarshalStrict([]byte(`
+pd_servers:
+  - host: 172.16.5.1
+tikv_servers:
+  - host: 172.16.5.2
+tidb_servers:
+  - host: 172.16.5.3
+`), topo))
+	centos7 := HostFacts{OS: "linux", Arch: "amd64", Distro: "centos", Version: "7.6.1810"}
+	arm64 := HostFacts{OS: "linux", Arch: "arm64", Distro: "centos", Version: "7.6.1810"}
+	// only tikv is published for linux/arm64
+	var queried []string
+	available := func(comp, version, nodeOS, arch string) bool {
+		queried = append(queried, comp+":"+version+"-"+nodeOS+"/"+arch)
+		return comp == spec.ComponentTiKV
+	}
+
+	facts := map[string]HostFacts{"172.16.5.1": centos7, "172.16.5.2": centos7, "172.16.5.3": centos7}
+	assert.Nil(t, m.checkPlatformFacts(topo, "v4.0.0", facts, available))
+	assert.Empty(t, queried)
+
+	// the platform of the hosts not detected isn't checked
+	assert.Nil(t, m.checkPlatformFacts(topo, "v4.0.0", map[string]HostFacts{"172.16.5.1": centos7}, available))
+
+	// the platform of the build is suggested if it's published
+	facts["172.16.5.2"] = arm64
+	err := m.checkPlatformFacts(topo, "v4.0.0", facts, available)
+	require.True(t, errorx.IsOfType(err, errDeployPlatformMismatch))
+	assert.Contains(t, err.Error(), "Platform of 1 instance(s) does not match the target host")
+	suggestion, _ := errorx.ExtractProperty(err, errutil.ErrPropSuggestion)
+	assert.Contains(t, suggestion, "Builds for linux/arm64 exist in the repository")
+	assert.Equal(t, []string{"tikv:v4.0.0-linux/arm64"}, queried)
+
+	facts["172.16.5.3"] = arm64
+	err = m.checkPlatformFacts(topo, "v4.0.0", facts, available)
+	require.True(t, errorx.IsOfType(err, errDeployPlatformMismatch))
+	assert.Contains(t, err.Error(), "Platform of 2 instance(s) does not match the target host")
+
+	topo.TiKVServers[0].Arch = "arm64"
+	err = m.checkPlatformFacts(topo, "v4.0.0", facts, available)
+	require.True(t, errorx.IsOfType(err, errDeployPlatformMismatch))
+	suggestion, _ = errorx.ExtractProperty(err, errutil.ErrPropSuggestion)
+	assert.Contains(t, suggestion, "Please deploy to hosts of a platform the components are published for")
+
+	// the matched platforms are then checked against the support matrix
+	facts["172.16.5.3"] = HostFacts{OS: "linux", Arch: "amd64", Distro: "centos", Version: "6.10"}
+	err = m.checkPlatformFacts(topo, "v4.0.0", facts, available)
+	require.True(t, errorx.IsOfType(err, errDeployPlatformUnsupported))
+	assert.Contains(t, err.Error(), "1 instance(s) are on the platforms known to be broken")
+	facts["172.16.5.3"] = HostFacts{OS: "linux", Arch: "amd64", Distro: "ubuntu", Version: "20.04"}
+	assert.Nil(t, m.checkPlatformFacts(topo, "v4.0.0", facts, available))
+}
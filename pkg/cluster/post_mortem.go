@@ -0,0 +1,178 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/errutil"
+)
+
+var (
+	errNSPostMortem = errorx.NewNamespace("post_mortem")
+	// ErrNoCheckpoint is returned when there is no checkpoint to analyze
+	ErrNoCheckpoint = errNSPostMortem.NewType("no_checkpoint", errutil.ErrTraitPreCheck)
+)
+
+// the number of the completed steps printed by PrintPostMortem
+const postMortemCompletedSteps = 10
+
+// PostMortem is the analysis of the tasks recorded in the checkpoint of a
+// failed scale-out, only the scale-out records its tasks
+type PostMortem struct {
+	Cluster string `json:"cluster"`
+	// the step failed first, nil if no step failed
+	Failed *task.TaskNode `json:"failed,omitempty"`
+	// the steps finished before the failed one, in the order they finished
+	Completed []*task.TaskNode `json:"completed"`
+	// the top level steps of the operation
+	Phases []*task.TaskNode `json:"phases"`
+}
+
+// ScaleOutPostMortem analyzes the checkpoint of the last scale-out, which is
+// only kept if it failed. The target is the name of a cluster, or the path of
+// a checkpoint file, e.g., the one copied from another control machine.
+func (m *Manager) ScaleOutPostMortem(target string) (*PostMortem, error) {
+	if fi, err := os.Stat(target); err == nil && !fi.IsDir() {
+		return AnalyzeScaleOutCheckpoint(target)
+	}
+	if err := m.checkExist(target); err != nil {
+		return nil, err
+	}
+	path := m.specManager.Path(target, scaleOutCheckpointFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, ErrNoCheckpoint.New("There is no checkpoint of cluster `%s`, the last scale-out succeeded or is not tried", target)
+	}
+	return AnalyzeScaleOutCheckpoint(path)
+}
+
+// AnalyzeScaleOutCheckpoint loads the checkpoint file of a scale-out and
+// analyzes the tasks in it
+func AnalyzeScaleOutCheckpoint(path string) (*PostMortem, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	cp := scaleOutCheckpoint{}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, perrs.Annotatef(err, "corrupted checkpoint %s", path)
+	}
+	if cp.Tasks == nil {
+		return nil, ErrNoCheckpoint.New("There are no tasks recorded in the checkpoint %s", path)
+	}
+	return analyzeTasks(cp.Cluster, cp.Tasks), nil
+}
+
+// analyzeTasks returns the post-mortem of the task tree
+func analyzeTasks(clusterName string, root *task.TaskNode) *PostMortem {
+	pm := &PostMortem{Cluster: clusterName, Phases: root.Children}
+	if len(pm.Phases) == 0 {
+		pm.Phases = []*task.TaskNode{root}
+	}
+
+	var completed []*task.TaskNode
+	root.Walk(func(node *task.TaskNode, _ int) {
+		switch node.Status {
+		case task.TaskStatusSucceeded:
+			if len(node.Children) == 0 && node.End != nil {
+				completed = append(completed, node)
+			}
+		case task.TaskStatusFailed:
+			// the groups fail with the steps in them
+			for _, child := range node.Children {
+				if child.Status == task.TaskStatusFailed {
+					return
+				}
+			}
+			if pm.Failed == nil || endsBefore(node, pm.Failed) {
+				pm.Failed = node
+			}
+		}
+	})
+	for _, node := range completed {
+		if pm.Failed == nil || pm.Failed.Begin == nil || !node.End.After(*pm.Failed.Begin) {
+			pm.Completed = append(pm.Completed, node)
+		}
+	}
+	sort.SliceStable(pm.Completed, func(i, j int) bool {
+		return pm.Completed[i].End.Before(*pm.Completed[j].End)
+	})
+	return pm
+}
+
+// endsBefore is true if the node a ends before b, the nodes not ended are
+// the last
+func endsBefore(a, b *task.TaskNode) bool {
+	if a.End == nil || b.End == nil {
+		return a.End != nil
+	}
+	return a.End.Before(*b.End)
+}
+
+// PrintPostMortem prints the post-mortem of a failed scale-out
+func PrintPostMortem(pm *PostMortem) {
+	fmt.Printf("Post-mortem of the last scale-out of cluster %s\n", color.CyanString(pm.Cluster))
+	if pm.Failed == nil {
+		fmt.Println("No step failed")
+	} else {
+		name := pm.Failed.Name
+		if pm.Failed.ID != "" && pm.Failed.ID != name {
+			name = fmt.Sprintf("%s (%s)", name, pm.Failed.ID)
+		}
+		fmt.Printf("Failed step: %s\n", color.RedString(name))
+		if len(pm.Failed.Hosts) > 0 {
+			fmt.Printf("Host:        %s\n", strings.Join(pm.Failed.Hosts, ", "))
+		}
+		fmt.Printf("Error:       %s\n", pm.Failed.Error)
+	}
+
+	fmt.Printf("\nCompleted before it: %d step(s)\n", len(pm.Completed))
+	completed := pm.Completed
+	if len(completed) > postMortemCompletedSteps {
+		fmt.Printf("  ... %d more\n", len(completed)-postMortemCompletedSteps)
+		completed = completed[len(completed)-postMortemCompletedSteps:]
+	}
+	for _, node := range completed {
+		fmt.Printf("  - %s (%s)\n", node.Name, node.Duration.Round(time.Millisecond))
+	}
+
+	fmt.Println()
+	rows := [][]string{{"Phase", "Status", "Duration"}}
+	for _, phase := range pm.Phases {
+		status := phase.Status
+		switch status {
+		case task.TaskStatusSucceeded:
+			status = color.GreenString(status)
+		case task.TaskStatusFailed:
+			status = color.RedString(status)
+		}
+		duration := "-"
+		if phase.End != nil {
+			duration = phase.Duration.Round(time.Millisecond).String()
+		}
+		rows = append(rows, []string{phase.Name, status, duration})
+	}
+	cliutil.PrintTable(rows, true)
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScaleOutPostMortem(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+	metadata, err := MockClusterMeta(3)
+	require.Nil(t, err)
+	require.Nil(t, m.specManager.SaveMeta("test", metadata))
+
+	cp, err := m.loadScaleOutCheckpoint("test", "hash")
+	require.Nil(t, err)
+	noop := func(ctx *task.Context) error { return nil }
+	st := task.NewBuilder().
+		Step("+ Download components", task.NewBuilder().Func("Download", noop).Build()).
+		Step("+ Copy files", task.NewBuilder().
+			Func("CopyPD", noop).
+			Func("CopyTiKV", func(ctx *task.Context) error { return errors.New("no space left on device") }).
+			Build()).
+		Func("StartCluster", noop).
+		Build()
+
+	ctx := task.NewContext()
+	cp.trackTasks(ctx, st)
+	execErr := st.Execute(ctx)
+	require.NotNil(t, execErr)
+	require.Nil(t, cp.closeTasks(execErr))
+
+	pm, err := m.ScaleOutPostMortem("test")
+	require.Nil(t, err)
+	assert.Equal(t, "test", pm.Cluster)
+	require.NotNil(t, pm.Failed)
+	assert.Equal(t, "CopyTiKV", pm.Failed.ID)
+	assert.Equal(t, "no space left on device", pm.Failed.Error)
+	require.Len(t, pm.Completed, 2)
+	assert.Equal(t, "Download", pm.Completed[0].ID)
+	assert.Equal(t, "CopyPD", pm.Completed[1].ID)
+	require.Len(t, pm.Phases, 3)
+	assert.Equal(t, task.TaskStatusSucceeded, pm.Phases[0].Status)
+	assert.Equal(t, task.TaskStatusFailed, pm.Phases[1].Status)
+	assert.Equal(t, task.TaskStatusPending, pm.Phases[2].Status)
+
+	// the checkpoint kept for the post-mortem after the new instances are
+	// saved doesn't affect the next scale-out
+	require.Nil(t, cp.remove())
+	require.Nil(t, cp.closeTasks(execErr))
+	// the checkpoint file is analyzed wherever it is
+	pm, err = m.ScaleOutPostMortem(m.specManager.Path("test", scaleOutCheckpointFile))
+	require.Nil(t, err)
+	assert.Equal(t, "CopyTiKV", pm.Failed.ID)
+	cp, err = m.loadScaleOutCheckpoint("test", "another")
+	require.Nil(t, err)
+	assert.Empty(t, cp.Hosts)
+
+	// the checkpoint is removed once the scale-out succeeds
+	require.Nil(t, cp.closeTasks(nil))
+	_, err = m.ScaleOutPostMortem("test")
+	assert.True(t, errorx.IsOfType(err, ErrNoCheckpoint))
+}
@@ -15,9 +15,35 @@ package cluster
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/utils"
+	tiupver "github.com/pingcap/tiup/pkg/version"
+)
+
+// extractionRatio is the estimated ratio of the space needed to extract a
+// package to the size of the package
+const extractionRatio = 3
+
+var (
+	errNSDownload = errorx.NewNamespace("download")
+	// ErrDownloadNoSpace is returned when there isn't enough space on the
+	// control machine for the packages to download
+	ErrDownloadNoSpace = errNSDownload.NewType("no_space", errutil.ErrTraitPreCheck)
+	// ErrPackageMissing is returned when some packages to use are missing in
+	// the package directory
+	ErrPackageMissing = errNSDownload.NewType("package_missing", errutil.ErrTraitPreCheck)
 )
 
 // InstanceIter to iterate instance.
@@ -42,8 +68,9 @@ func BuildDownloadCompTasks(version string, instanceIter InstanceIter, bindVersi
 			version := bindVersion(inst.ComponentName(), version)
 			t := task.NewBuilder().
 				Download(inst.ComponentName(), inst.OS(), inst.Arch(), version).
-				BuildAsStep(fmt.Sprintf("  - Download %s:%s (%s/%s)",
-					inst.ComponentName(), version, inst.OS(), inst.Arch()))
+				BuildAsStepMessage(task.MsgDownload, task.MessageParams{
+					"component": inst.ComponentName(), "version": version, "os": inst.OS(), "arch": inst.Arch(),
+				})
 			tasks = append(tasks, t)
 		}
 	})
@@ -56,6 +83,117 @@ func buildDownloadSparkTask(version string, inst spec.Instance, bindVersion spec
 	ver := bindVersion(spec.ComponentSpark, version)
 	return task.NewBuilder().
 		Download(spec.ComponentSpark, inst.OS(), inst.Arch(), ver).
-		BuildAsStep(fmt.Sprintf("  - Download %s:%s (%s/%s)",
-			spec.ComponentSpark, version, inst.OS(), inst.Arch()))
+		BuildAsStepMessage(task.MsgDownload, task.MessageParams{
+			"component": spec.ComponentSpark, "version": version, "os": inst.OS(), "arch": inst.Arch(),
+		})
+}
+
+// downloadItem is a package to download to the control machine
+type downloadItem struct {
+	component, os, arch, version string
+}
+
+func (d downloadItem) fileName() string {
+	return fmt.Sprintf("%s-%s-%s-%s.tar.gz", d.component, d.version, d.os, d.arch)
+}
+
+// downloadItems returns the packages downloaded by BuildDownloadCompTasks
+func downloadItems(version string, instanceIter InstanceIter, bindVersion spec.BindVersion) []downloadItem {
+	var items []downloadItem
+	unique := make(map[downloadItem]struct{})
+	add := func(item downloadItem) {
+		if _, found := unique[item]; !found {
+			unique[item] = struct{}{}
+			items = append(items, item)
+		}
+	}
+	instanceIter.IterInstance(func(inst spec.Instance) {
+		if inst.ComponentName() == spec.ComponentTiSpark {
+			add(downloadItem{spec.ComponentSpark, inst.OS(), inst.Arch(), bindVersion(spec.ComponentSpark, version)})
+		}
+		add(downloadItem{inst.ComponentName(), inst.OS(), inst.Arch(), bindVersion(inst.ComponentName(), version)})
+	})
+	return items
+}
+
+// CheckDownloadSpace checks if there is enough space on the control machine
+// for the packages to download, before anything is downloaded. The packages
+// are saved to the package cache in the profile directory, and extracted in
+// the temporary directory, which are checked separately unless they are on
+// the same file system. Packages already in the cache are not counted.
+func CheckDownloadSpace(version string, instanceIter InstanceIter, bindVersion spec.BindVersion) error {
+	cacheDir := spec.ProfilePath(spec.TiOpsPackageCacheDir)
+	var cacheNeed, tempNeed int64
+	for _, item := range downloadItems(version, instanceIter, bindVersion) {
+		if item.version != tiupver.NightlyVersion && utils.IsExist(spec.ProfilePath(spec.TiOpsPackageCacheDir, item.fileName())) {
+			continue
+		}
+		repo, err := clusterutil.NewRepository(item.os, item.arch)
+		if err != nil {
+			return err
+		}
+		size, err := repo.ComponentSize(item.component, item.version)
+		if err != nil {
+			return errors.Annotatef(err, "failed to get the size of %s:%s (%s/%s)", item.component, item.version, item.os, item.arch)
+		}
+		cacheNeed += size
+		// the packages are extracted one by one
+		if size*extractionRatio > tempNeed {
+			tempNeed = size * extractionRatio
+		}
+	}
+	if cacheNeed == 0 {
+		return nil
+	}
+
+	tempDir := os.TempDir()
+	same, err := utils.SameFileSystem(cacheDir, tempDir)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	if same {
+		return checkFreeSpace(cacheDir, cacheNeed+tempNeed, "downloading and extracting packages", localdata.EnvNameHome)
+	}
+	if err := checkFreeSpace(cacheDir, cacheNeed, "downloading packages", localdata.EnvNameHome); err != nil {
+		return err
+	}
+	return checkFreeSpace(tempDir, tempNeed, "extracting packages", "TMPDIR")
+}
+
+// CheckPackageDir checks if all the packages needed are in the package
+// directory, before any host is touched
+func CheckPackageDir(dir, version string, instanceIter InstanceIter, bindVersion spec.BindVersion) error {
+	missing := []string{}
+	for _, item := range downloadItems(version, instanceIter, bindVersion) {
+		if utils.IsNotExist(filepath.Join(dir, item.fileName())) {
+			missing = append(missing, item.fileName())
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return ErrPackageMissing.New("%d packages are missing in %s", len(missing), dir).
+		WithProperty(cliutil.SuggestionFromFormat("Put the following packages and their checksums in %s into the directory:\n  %s",
+			operator.PackageChecksumFile, strings.Join(missing, "\n  ")))
+}
+
+// checkFreeSpace checks if there are need bytes available in dir, which can
+// be moved by the environment variable env
+func checkFreeSpace(dir string, need int64, purpose, env string) error {
+	free, err := utils.FreeSpace(dir)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	if uint64(need) <= free {
+		return nil
+	}
+	shortfall := uint64(need) - free
+	return ErrDownloadNoSpace.New("Not enough space in %s for %s: %s needed, %s available, %s short",
+		dir, purpose, humanBytes(uint64(need)), humanBytes(free), humanBytes(shortfall)).
+		WithProperty(cliutil.SuggestionFromFormat("Free up at least %d bytes in %s, or set %s to a directory with more space",
+			shortfall, dir, env))
+}
+
+func humanBytes(n uint64) string {
+	return fmt.Sprintf("%.1f MiB", float64(n)/1024/1024)
 }
@@ -0,0 +1,54 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestCheckPackageDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiup-package-dir-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	topo := new(spec.Specification)
+	require.Nil(t, yaml.UnmarshalStrict([]byte(`
+pd_servers:
+  - host: 172.16.5.1
+tikv_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.2
+`), topo))
+
+	err = CheckPackageDir(dir, "v4.0.0", topo, spec.TiDBComponentVersion)
+	require.True(t, errorx.IsOfType(err, ErrPackageMissing))
+	suggestion, _ := errorx.ExtractProperty(err, errutil.ErrPropSuggestion)
+	assert.Contains(t, suggestion, "pd-v4.0.0-linux-amd64.tar.gz")
+	assert.Contains(t, suggestion, "tikv-v4.0.0-linux-amd64.tar.gz")
+
+	for _, name := range []string{"pd-v4.0.0-linux-amd64.tar.gz", "tikv-v4.0.0-linux-amd64.tar.gz"} {
+		require.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	assert.Nil(t, CheckPackageDir(dir, "v4.0.0", topo, spec.TiDBComponentVersion))
+}
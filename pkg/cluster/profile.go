@@ -0,0 +1,238 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	rpprof "runtime/pprof"
+	"strconv"
+	"time"
+
+	perrs "github.com/pingcap/errors"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/logger/log"
+)
+
+var (
+	// profileCheckInterval is how often the thresholds of capturing the
+	// profiles of an operation are checked
+	profileCheckInterval = time.Second
+	// profileCPUDuration is how long the CPU profile is captured for at most,
+	// it's stopped earlier if the operation finishes
+	profileCPUDuration = 30 * time.Second
+)
+
+// operationProfiler serves pprof of this process during an operation, and
+// captures a CPU profile and a heap snapshot once the operation exceeds the
+// duration or memory threshold. Everything it does is best-effort, failures
+// are only warned and never fail the operation.
+type operationProfiler struct {
+	addr   string // the address pprof is served on, empty if not served
+	server *http.Server
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+// pprofListenAddr returns the address to serve pprof on, a bare port is
+// served on 127.0.0.1, and only the loopback addresses are allowed as the
+// profiles expose the internals of the process
+func pprofListenAddr(addr string) (string, error) {
+	if _, err := strconv.Atoi(addr); err == nil {
+		return net.JoinHostPort("127.0.0.1", addr), nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", perrs.Annotatef(err, "invalid pprof address %s", addr)
+	}
+	switch host {
+	case "":
+		host = "127.0.0.1"
+	case "localhost":
+	default:
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return "", perrs.Errorf("pprof can only be served on localhost, not %s", host)
+		}
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// startOperationProfiler starts profiling the operation as configured by
+// opt, the pprof address is read from the environment if it's not set. It
+// returns nil if there is nothing to do.
+func startOperationProfiler(logDir string, operationType OperationType, startTime time.Time, opt operator.Options) *operationProfiler {
+	addr := opt.PprofAddr
+	if addr == "" {
+		addr = os.Getenv(localdata.EnvNamePprofAddr)
+	}
+	if addr == "" && opt.ProfileAfter <= 0 && opt.ProfileAboveMemory <= 0 {
+		return nil
+	}
+
+	p := &operationProfiler{
+		stopCh: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if addr != "" {
+		p.serve(addr)
+	}
+	if opt.ProfileAfter <= 0 && opt.ProfileAboveMemory <= 0 {
+		close(p.done)
+		return p
+	}
+	prefix := filepath.Join(logDir, fmt.Sprintf("%s-%s", operationType, startTime.Format("2006-01-02T15-04-05")))
+	go p.watch(prefix, startTime,
+		time.Duration(opt.ProfileAfter)*time.Second,
+		uint64(opt.ProfileAboveMemory)*1024*1024)
+	return p
+}
+
+// serve serves pprof on the address until the profiler is stopped
+func (p *operationProfiler) serve(addr string) {
+	addr, err := pprofListenAddr(addr)
+	if err != nil {
+		log.Warnf("Not serving pprof: %s", err)
+		return
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Warnf("Not serving pprof, failed to listen on %s: %s", addr, err)
+		return
+	}
+
+	// not the default mux, the status server may be serving it
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	p.server = &http.Server{Handler: mux}
+	p.addr = l.Addr().String()
+	go func() {
+		if err := p.server.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.Warnf("Stopped serving pprof on %s: %s", p.addr, err)
+		}
+	}()
+	log.Infof("Serving pprof on http://%s/debug/pprof/ during the operation", p.addr)
+}
+
+// watch captures the profiles once the operation runs longer than after,
+// or the heap grows above aboveBytes, 0 disables the threshold
+func (p *operationProfiler) watch(prefix string, startTime time.Time, after time.Duration, aboveBytes uint64) {
+	defer close(p.done)
+	defer func() {
+		if r := recover(); r != nil {
+			log.Warnf("Failed to capture the profiles: %v", r)
+		}
+	}()
+
+	ticker := time.NewTicker(profileCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		var reason string
+		if elapsed := time.Since(startTime); after > 0 && elapsed >= after {
+			reason = fmt.Sprintf("it has run for %s", elapsed.Round(time.Second))
+		} else if aboveBytes > 0 {
+			var ms runtime.MemStats
+			runtime.ReadMemStats(&ms)
+			if ms.HeapAlloc >= aboveBytes {
+				reason = fmt.Sprintf("its heap grows to %.1f MiB", float64(ms.HeapAlloc)/1024/1024)
+			}
+		}
+		if reason != "" {
+			p.capture(prefix, reason)
+			return
+		}
+	}
+}
+
+// capture writes a heap snapshot, and a CPU profile until the profiler is
+// stopped or profileCPUDuration passes, to the files of the prefix
+func (p *operationProfiler) capture(prefix, reason string) {
+	if err := os.MkdirAll(filepath.Dir(prefix), 0755); err != nil {
+		log.Warnf("Failed to capture the profiles: %s", err)
+		return
+	}
+	log.Infof("Capturing the profiles of the operation as %s", reason)
+
+	heapFile := prefix + ".heap.pprof"
+	if err := writeProfileFile(heapFile, func(f *os.File) error {
+		return rpprof.Lookup("heap").WriteTo(f, 0)
+	}); err != nil {
+		log.Warnf("Failed to capture the heap snapshot: %s", err)
+	} else {
+		log.Infof("heap snapshot: %s", heapFile)
+	}
+
+	cpuFile := prefix + ".cpu.pprof"
+	if err := writeProfileFile(cpuFile, func(f *os.File) error {
+		// fails if the CPU is being profiled, e.g., via pprof
+		if err := rpprof.StartCPUProfile(f); err != nil {
+			return err
+		}
+		select {
+		case <-p.stopCh:
+		case <-time.After(profileCPUDuration):
+		}
+		rpprof.StopCPUProfile()
+		return nil
+	}); err != nil {
+		log.Warnf("Failed to capture the CPU profile: %s", err)
+	} else {
+		log.Infof("CPU profile: %s", cpuFile)
+	}
+}
+
+// writeProfileFile creates the file and writes the profile to it by write,
+// the file is removed if it fails
+func writeProfileFile(fname string, write func(f *os.File) error) error {
+	f, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(fname)
+	}
+	return err
+}
+
+// stop stops serving pprof, and waits for the profiles being captured to
+// be written
+func (p *operationProfiler) stop() {
+	if p == nil {
+		return
+	}
+	close(p.stopCh)
+	<-p.done
+	if p.server != nil {
+		p.server.Close()
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationProfiler(t *testing.T) {
+	m, _, _, cleanup := newTestMockCluster(t, 1)
+	defer cleanup()
+	dir := m.specManager.Path("mock")
+
+	for addr, expected := range map[string]string{
+		"6060":           "127.0.0.1:6060",
+		":6060":          "127.0.0.1:6060",
+		"localhost:6060": "localhost:6060",
+		"[::1]:6060":     "[::1]:6060",
+		"0.0.0.0:6060":   "",
+		"10.0.0.1:6060":  "",
+	} {
+		listenAddr, err := pprofListenAddr(addr)
+		if expected == "" {
+			assert.NotNil(t, err, addr)
+		} else {
+			assert.Nil(t, err, addr)
+			assert.Equal(t, expected, listenAddr)
+		}
+	}
+	assert.Nil(t, startOperationProfiler(dir, OperationStart, time.Now(), operator.Options{}))
+
+	// pprof is served during the operation
+	p := startOperationProfiler(dir, OperationStart, time.Now(), operator.Options{PprofAddr: "127.0.0.1:0"})
+	require.NotNil(t, p)
+	require.NotEmpty(t, p.addr)
+	resp, err := http.Get("http://" + p.addr + "/debug/pprof/")
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	p.stop()
+	_, err = http.Get("http://" + p.addr + "/debug/pprof/")
+	assert.NotNil(t, err)
+
+	// the profiles are captured into the log dir once the operation exceeds
+	// the threshold, and the CPU profile is stopped with the operation
+	defer func(interval, duration time.Duration) {
+		profileCheckInterval, profileCPUDuration = interval, duration
+	}(profileCheckInterval, profileCPUDuration)
+	profileCheckInterval, profileCPUDuration = 10*time.Millisecond, time.Minute
+
+	start := time.Now()
+	info, err := m.beginOperation("mock", OperationStart, operator.Options{ProfileAboveMemory: 1})
+	require.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+	m.endOperation(info, nil)
+	assert.True(t, time.Since(start) < time.Minute)
+	for _, suffix := range []string{".heap.pprof", ".cpu.pprof"} {
+		files, err := filepath.Glob(filepath.Join(m.specManager.Path("mock", "logs"), "start-*"+suffix))
+		require.Nil(t, err)
+		require.Len(t, files, 1, suffix)
+		st, err := os.Stat(files[0])
+		require.Nil(t, err)
+		assert.True(t, st.Size() > 0, suffix)
+	}
+}
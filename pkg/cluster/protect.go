@@ -0,0 +1,93 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"errors"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/meta"
+)
+
+// OverrideProtectionFlag is the flag to confirm destructive operations on
+// protected clusters
+const OverrideProtectionFlag = "i-know-this-is-protected"
+
+var (
+	errNSProtect = errorx.NewNamespace("protect")
+	// ErrClusterProtected is returned when a destructive operation is run on
+	// a protected cluster without the override flag
+	ErrClusterProtected = errNSProtect.NewType("protected", errutil.ErrTraitPreCheck)
+)
+
+// SetProtection marks or unmarks the cluster as protected. Destroying,
+// cleaning or scaling in a protected cluster requires the extra flag
+// --i-know-this-is-protected, so that a cluster isn't destroyed by retyping
+// a similar name by mistake.
+func (m *Manager) SetProtection(clusterName string, protected bool) error {
+	metadata, err := m.metaFresh(clusterName)
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+		return perrs.AddStack(err)
+	}
+	if err := m.specManager.CheckWritable(); err != nil {
+		return err
+	}
+
+	pm, ok := metadata.(spec.ProtectableMetadata)
+	if !ok {
+		return perrs.Errorf("cluster `%s` doesn't support protection", clusterName)
+	}
+	if metadata.GetBaseMeta().Protected == protected {
+		log.Infof("Cluster `%s` is already %s", clusterName, protectionState(protected))
+		return nil
+	}
+
+	pm.SetProtected(protected)
+	if err := m.specManager.SaveMeta(clusterName, metadata); err != nil {
+		return perrs.Annotate(err, "failed to save meta")
+	}
+	log.Infof("Cluster `%s` is %s", clusterName, protectionState(protected))
+	return nil
+}
+
+func protectionState(protected bool) string {
+	if protected {
+		return "protected"
+	}
+	return "unprotected"
+}
+
+// checkProtection refuses the destructive action on the protected cluster
+// unless override is set, the override is logged prominently in the audit log
+func checkProtection(clusterName string, base *spec.BaseMeta, override bool, action string) error {
+	if !base.Protected {
+		return nil
+	}
+	if !override {
+		return ErrClusterProtected.New("Cluster `%s` is protected, refusing to %s it", clusterName, action).
+			WithProperty(cliutil.SuggestionFromFormat(
+				"Please make sure `%s` is the cluster you mean, and add --%s to %s it anyway,\nor unprotect it first by `%s unprotect %s`.",
+				clusterName, OverrideProtectionFlag, action, cliutil.OsArgs0(), clusterName))
+	}
+	// log.Warnf also writes to the audit log
+	log.Warnf("%s", color.HiRedString("!!! OVERRIDING PROTECTION: %s protected cluster `%s` with --%s !!!",
+		action, clusterName, OverrideProtectionFlag))
+	return nil
+}
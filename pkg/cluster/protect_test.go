@@ -0,0 +1,49 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtection(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+	require.Nil(t, os.MkdirAll(m.specManager.Path("prod-eu"), 0755))
+	require.Nil(t, ioutil.WriteFile(m.specManager.Path("prod-eu", "meta.yaml"), []byte("user: tidb\n"), 0644))
+
+	require.Nil(t, m.SetProtection("prod-eu", true))
+	metadata, err := m.metaFresh("prod-eu")
+	require.Nil(t, err)
+	assert.True(t, metadata.GetBaseMeta().Protected)
+
+	err = m.DestroyCluster("prod-eu", operator.Options{}, operator.Options{}, true)
+	require.NotNil(t, err)
+	assert.True(t, errutil.Cast(err).IsOfType(ErrClusterProtected))
+	err = m.ScaleIn("prod-eu", true, 5, false, false, false, []string{"127.0.0.1:4000"}, "", nil)
+	require.NotNil(t, err)
+	assert.True(t, errutil.Cast(err).IsOfType(ErrClusterProtected))
+
+	require.Nil(t, m.SetProtection("prod-eu", false))
+	metadata, err = m.metaFresh("prod-eu")
+	require.Nil(t, err)
+	assert.False(t, metadata.GetBaseMeta().Protected)
+}
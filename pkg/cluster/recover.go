@@ -0,0 +1,343 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/file"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	gops "github.com/shirou/gopsutil/process"
+	"go.uber.org/zap"
+)
+
+var (
+	errNSRecover = errorx.NewNamespace("recover")
+	// ErrRecoverLockInUse is returned when recovering a cluster which is being operated
+	ErrRecoverLockInUse = errNSRecover.NewType("lock_in_use", errutil.ErrTraitPreCheck)
+	// ErrClusterBusy is returned when operating a cluster which is being
+	// operated by another process
+	ErrClusterBusy = errNSRecover.NewType("cluster_busy", errutil.ErrTraitPreCheck)
+)
+
+// operationLockFile is the lock file of a running operation under the
+// cluster dir, it contains the PID of the owning process
+const operationLockFile = "tiup-operation.lck"
+
+// DefaultTmpFileAge is the default age of orphaned temp files to be removed
+const DefaultTmpFileAge = time.Hour
+
+// RecoverOptions contains the options for recovering a cluster
+type RecoverOptions struct {
+	TmpFileAge  time.Duration // only temp files older than this are removed
+	SkipConfirm bool
+}
+
+// staleFile is a file, or another resource, left behind by a crashed process
+type staleFile struct {
+	Kind   string
+	Path   string
+	Reason string
+	remove func() error // how it's removed, the file of Path is removed if nil
+}
+
+// lockOwner returns the PID of the process owning the operation lock of the
+// cluster, 0 is returned if the lock doesn't exist
+func (m *Manager) lockOwner(clusterName string) (int, error) {
+	data, err := ioutil.ReadFile(m.specManager.Path(clusterName, operationLockFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, perrs.AddStack(err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		// a lock without a valid PID is half-written, treat it as stale
+		return -1, nil
+	}
+	return pid, nil
+}
+
+// pidAlive checks if the process of pid exists
+func pidAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	exist, err := gops.PidExists(int32(pid))
+	return err != nil || exist
+}
+
+// tryOperationLock takes the operation lock of the cluster unless it's owned
+// by another process alive, whose PID is returned then. The owner is checked
+// and the lock is written holding the flock of the cluster dir, so only one
+// of the processes taking it at the same time succeeds. A lock left by a
+// process no longer existing is taken over with a warning.
+func (m *Manager) tryOperationLock(clusterName string) (int, error) {
+	if err := os.MkdirAll(m.specManager.Path(clusterName), 0755); err != nil {
+		return 0, perrs.AddStack(err)
+	}
+	unlock, err := m.lockClusterDir(clusterName)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	pid, err := m.lockOwner(clusterName)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case pid == os.Getpid():
+		return 0, nil
+	case pid != 0 && pidAlive(pid):
+		return pid, nil
+	case pid != 0:
+		log.Warnf("The operation lock of cluster `%s` left by a crashed process is taken over, "+
+			"use `recover` to clean up other files it left behind", clusterName)
+	}
+
+	// written to a temp file and renamed, so the lock read is never half-written
+	lockPath := m.specManager.Path(clusterName, operationLockFile)
+	tmp := lockPath + file.TmpFileSuffix
+	if err := ioutil.WriteFile(tmp, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return 0, perrs.AddStack(err)
+	}
+	return 0, perrs.AddStack(os.Rename(tmp, lockPath))
+}
+
+// lockClusterDir takes the flock of the cluster dir, the operation lock is
+// only read and written holding it
+func (m *Manager) lockClusterDir(clusterName string) (func(), error) {
+	dir := m.specManager.Path(clusterName)
+	d, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(d.Fd()), syscall.LOCK_EX); err != nil {
+		d.Close()
+		return nil, perrs.Annotatef(err, "failed to lock %s", dir)
+	}
+	return func() {
+		_ = syscall.Flock(int(d.Fd()), syscall.LOCK_UN)
+		d.Close()
+	}, nil
+}
+
+// acquireOperationLock takes the operation lock of the cluster, see
+// tryOperationLock. ErrClusterBusy is returned if another process alive owns
+// it, so the operations on a cluster are serialized. The operation goes on
+// with a warning if the lock can't be written, e.g., on a read-only profile.
+func (m *Manager) acquireOperationLock(clusterName string) error {
+	pid, err := m.tryOperationLock(clusterName)
+	if err != nil {
+		zap.L().Warn("Failed to take operation lock", zap.String("cluster", clusterName), zap.Error(err))
+		return nil
+	}
+	if pid != 0 {
+		return ErrClusterBusy.New("Cluster `%s` is being operated by process %d", clusterName, pid).
+			WithProperty(cliutil.SuggestionFromFormat(
+				"Please wait for the operation to finish and try again, or run `%s recover %s` if process %d is not operating it.",
+				cliutil.OsArgs0(), clusterName, pid))
+	}
+	return nil
+}
+
+// releaseOperationLock removes the operation lock of the cluster if it's
+// owned by the current process
+func (m *Manager) releaseOperationLock(clusterName string) {
+	unlock, err := m.lockClusterDir(clusterName)
+	if err != nil {
+		// the cluster dir is removed with the lock, e.g., by destroy
+		if !os.IsNotExist(err) {
+			zap.L().Warn("Failed to release operation lock", zap.String("cluster", clusterName), zap.Error(err))
+		}
+		return
+	}
+	defer unlock()
+	if pid, _ := m.lockOwner(clusterName); pid != os.Getpid() {
+		return
+	}
+	if err := os.Remove(m.specManager.Path(clusterName, operationLockFile)); err != nil && !os.IsNotExist(err) {
+		zap.L().Warn("Failed to remove operation lock", zap.String("cluster", clusterName), zap.Error(err))
+	}
+}
+
+// removeStaleLock removes the operation lock left by the process of pid
+// holding the flock of the cluster dir, it fails if the lock is taken by
+// another process since it was found stale, e.g., while confirming
+func (m *Manager) removeStaleLock(clusterName, lockPath string, pid int) error {
+	unlock, err := m.lockClusterDir(clusterName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	owner, err := m.lockOwner(clusterName)
+	if err != nil {
+		return err
+	}
+	if owner != pid && owner != 0 {
+		return ErrRecoverLockInUse.New("Cluster `%s` is being operated by process %d", clusterName, owner).
+			WithProperty(cliutil.SuggestionFromString("Please wait for the operation to finish and try again."))
+	}
+	if err := os.Remove(lockPath); err != nil && !os.IsNotExist(err) {
+		return perrs.AddStack(err)
+	}
+	return nil
+}
+
+// staleTmpFiles returns the temp files under dir modified before the age
+func staleTmpFiles(dir string, age time.Duration) ([]staleFile, error) {
+	var result []staleFile
+	deadline := time.Now().Add(-age)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), file.TmpFileSuffix) {
+			return nil
+		}
+		if info.ModTime().Before(deadline) {
+			result = append(result, staleFile{
+				Kind:   "temp file",
+				Path:   path,
+				Reason: fmt.Sprintf("not modified since %s", info.ModTime().Format(time.RFC3339)),
+			})
+		}
+		return nil
+	})
+	return result, perrs.AddStack(err)
+}
+
+// Recover cleans up the files left behind by crashed processes operating the
+// cluster: the operation lock whose owning process no longer exists, the
+// half-written temp files of metadata older than opt.TmpFileAge, and the
+// silences of alerts not expired. The files are listed and removed after
+// confirmation, every removed file is recorded in the audit log.
+func (m *Manager) Recover(clusterName string, opt RecoverOptions) error {
+	if err := m.checkExist(clusterName); err != nil {
+		return err
+	}
+	if opt.TmpFileAge <= 0 {
+		opt.TmpFileAge = DefaultTmpFileAge
+	}
+
+	// the owner is read holding the flock of the cluster dir, as the operations
+	// taking the lock do, see tryOperationLock
+	var stale []staleFile
+	unlock, err := m.lockClusterDir(clusterName)
+	if err != nil {
+		return err
+	}
+	pid, err := m.lockOwner(clusterName)
+	unlock()
+	if err != nil {
+		return err
+	}
+	switch {
+	case pid != 0 && pidAlive(pid):
+		return ErrRecoverLockInUse.
+			New("Cluster `%s` is being operated by process %d", clusterName, pid).
+			WithProperty(cliutil.SuggestionFromString("Please wait for the operation to finish and try again."))
+	case pid != 0:
+		reason := fmt.Sprintf("process %d no longer exists", pid)
+		if pid < 0 {
+			reason = "no valid PID in the lock"
+		}
+		lockPath := m.specManager.Path(clusterName, operationLockFile)
+		stale = append(stale, staleFile{
+			Kind:   "operation lock",
+			Path:   lockPath,
+			Reason: reason,
+			remove: func() error {
+				return m.removeStaleLock(clusterName, lockPath, pid)
+			},
+		})
+	}
+
+	tmpFiles, err := staleTmpFiles(m.specManager.Path(clusterName), opt.TmpFileAge)
+	if err != nil {
+		return err
+	}
+	stale = append(stale, tmpFiles...)
+
+	silences, err := m.loadSilences(clusterName)
+	if err != nil {
+		return err
+	}
+	for _, s := range silences {
+		s := s
+		stale = append(stale, staleFile{
+			Kind:   "alert silence",
+			Path:   fmt.Sprintf("%s/%s", s.Alertmanager, s.ID),
+			Reason: fmt.Sprintf("created for %s at %s", s.Operation, s.CreatedAt.Format(time.RFC3339)),
+			remove: func() error {
+				err := m.expireSilence(clusterName, s)
+				if err != nil && time.Now().After(s.EndsAt) {
+					// it has ended anyway, only the record is left
+					zap.L().Debug("Failed to expire ended silence", zap.String("id", s.ID), zap.Error(err))
+					return m.forgetSilence(clusterName, s)
+				}
+				return err
+			},
+		})
+	}
+
+	if len(stale) == 0 {
+		log.Infof("Nothing to recover for cluster `%s`", clusterName)
+		return nil
+	}
+
+	rows := [][]string{{"Type", "Path", "Reason"}}
+	for _, f := range stale {
+		rows = append(rows, []string{f.Kind, f.Path, f.Reason})
+	}
+	cliutil.PrintTable(rows, true)
+
+	if !opt.SkipConfirm {
+		if err := cliutil.PromptForConfirmOrAbortError(
+			"The files above of cluster %s will be removed.\nDo you want to continue? [y/N]:",
+			clusterName); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range stale {
+		if f.remove != nil {
+			if err := f.remove(); err != nil {
+				return perrs.Annotatef(err, "failed to remove %s %s", f.Kind, f.Path)
+			}
+		} else if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+			return perrs.AddStack(err)
+		}
+		// log.Infof also writes to the audit log
+		log.Infof("Removed %s %s: %s", f.Kind, f.Path, f.Reason)
+	}
+	log.Infof("Recovered cluster `%s` successfully", clusterName)
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaleTmpFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiup-recover-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	old := filepath.Join(dir, "meta.yaml.tmp")
+	require.Nil(t, ioutil.WriteFile(old, nil, 0644))
+	past := time.Now().Add(-2 * time.Hour)
+	require.Nil(t, os.Chtimes(old, past, past))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "meta.yaml"), nil, 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "topology.yaml.tmp"), nil, 0644))
+
+	stale, err := staleTmpFiles(dir, time.Hour)
+	require.Nil(t, err)
+	require.Len(t, stale, 1)
+	assert.Equal(t, old, stale[0].Path)
+
+	stale, err = staleTmpFiles(filepath.Join(dir, "not-exist"), time.Hour)
+	assert.Nil(t, err)
+	assert.Empty(t, stale)
+}
+
+func TestTryOperationLock(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+	lockPath := m.specManager.Path("prod", operationLockFile)
+
+	pid, err := m.tryOperationLock("prod")
+	require.Nil(t, err)
+	assert.Equal(t, 0, pid)
+	owner, err := m.lockOwner("prod")
+	require.Nil(t, err)
+	assert.Equal(t, os.Getpid(), owner)
+	// taken again by the owner
+	pid, err = m.tryOperationLock("prod")
+	require.Nil(t, err)
+	assert.Equal(t, 0, pid)
+	m.releaseOperationLock("prod")
+
+	// held by a process that is always alive
+	require.Nil(t, ioutil.WriteFile(lockPath, []byte("1"), 0644))
+	pid, err = m.tryOperationLock("prod")
+	require.Nil(t, err)
+	assert.Equal(t, 1, pid)
+	// the operations are refused and don't take it over
+	err = m.acquireOperationLock("prod")
+	require.NotNil(t, err)
+	assert.True(t, errutil.Cast(err).IsOfType(ErrClusterBusy))
+	_, err = m.beginOperation("prod", OperationStart)
+	require.NotNil(t, err)
+	assert.True(t, errutil.Cast(err).IsOfType(ErrClusterBusy))
+	m.releaseOperationLock("prod")
+	owner, err = m.lockOwner("prod")
+	require.Nil(t, err)
+	assert.Equal(t, 1, owner)
+	// neither is it removed by recover
+	require.Nil(t, ioutil.WriteFile(m.specManager.Path("prod", "meta.yaml"), []byte("user: tidb\n"), 0644))
+	err = m.Recover("prod", RecoverOptions{SkipConfirm: true})
+	require.NotNil(t, err)
+	assert.True(t, errutil.Cast(err).IsOfType(ErrRecoverLockInUse))
+	_, err = os.Stat(lockPath)
+	assert.Nil(t, err)
+
+	// the stale lock taken by another process since it was found is kept
+	err = m.removeStaleLock("prod", lockPath, -1)
+	require.NotNil(t, err)
+	assert.True(t, errutil.Cast(err).IsOfType(ErrRecoverLockInUse))
+	_, err = os.Stat(lockPath)
+	assert.Nil(t, err)
+	require.Nil(t, ioutil.WriteFile(lockPath, []byte("-1"), 0644))
+	require.Nil(t, m.removeStaleLock("prod", lockPath, -1))
+	_, err = os.Stat(lockPath)
+	assert.True(t, os.IsNotExist(err))
+
+	// the lock left by a crashed process is taken over
+	require.Nil(t, ioutil.WriteFile(lockPath, []byte("-1"), 0644))
+	pid, err = m.tryOperationLock("prod")
+	require.Nil(t, err)
+	assert.Equal(t, 0, pid)
+	owner, err = m.lockOwner("prod")
+	require.Nil(t, err)
+	assert.Equal(t, os.Getpid(), owner)
+
+	// the cluster dir removed with the lock is not created again
+	require.Nil(t, os.RemoveAll(m.specManager.Path("prod")))
+	m.releaseOperationLock("prod")
+	_, err = os.Stat(m.specManager.Path("prod"))
+	assert.True(t, os.IsNotExist(err))
+}
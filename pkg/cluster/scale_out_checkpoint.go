@@ -0,0 +1,249 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"gopkg.in/yaml.v2"
+)
+
+// the file the phases of a scale-out finished on the new hosts are recorded
+// in, under the cluster dir, so a retried scale-out skips them
+const scaleOutCheckpointFile = "scale_out_checkpoint.json"
+
+// the phases of scaling out a host, in the order they are executed. The new
+// instances are saved to the meta once all the hosts finish the config phase,
+// then they are a part of the cluster and the checkpoint is removed, so the
+// later phases are not recorded.
+const (
+	scaleOutPhaseDownload = "download" // the packages are downloaded to the control machine
+	scaleOutPhaseEnvInit  = "env-init" // the environment of the host is initialized
+	scaleOutPhaseCopy     = "copy"     // the packages are copied
+	scaleOutPhaseConfig   = "config"   // the configs of the new instances are generated
+)
+
+// the tasks of a scale-out are saved to the checkpoint at most once in the
+// interval as they finish, see trackTasks
+var scaleOutCheckpointInterval = time.Second
+
+// scaleOutCheckpoint is the phases of a scale-out finished on the new hosts,
+// and the tasks of the last try of it for the post-mortem, see
+// AnalyzeCheckpoint
+type scaleOutCheckpoint struct {
+	mu sync.Mutex
+	// the name of the cluster scaled out
+	Cluster string `json:"cluster,omitempty"`
+	// the hash of the topology of the cluster, the new part and the version
+	// scaled out to, the checkpoint only applies to the same scale-out
+	TopologyHash string `json:"topology_hash"`
+	// host -> the phases finished
+	Hosts map[string][]string `json:"hosts"`
+	// the tasks of the scale-out, as they are executed
+	Tasks *task.TaskNode `json:"tasks,omitempty"`
+
+	path  string
+	tree  *task.TaskTree
+	saved time.Time
+	// the tasks of the last try resumed, the orders the parallel groups
+	// finished in are restored from it to roll them back in order
+	resumed *task.TaskNode
+}
+
+// scaleOutHash returns the hash identifying a scale-out of the topology by
+// the new part
+func scaleOutHash(topo, newPart spec.Topology, version string) (string, error) {
+	h := sha256.New()
+	for _, t := range []spec.Topology{topo, newPart} {
+		data, err := yaml.Marshal(t)
+		if err != nil {
+			return "", perrs.AddStack(err)
+		}
+		_, _ = h.Write(data)
+	}
+	_, _ = h.Write([]byte(version))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// loadScaleOutCheckpoint returns the checkpoint of the scale-out with the
+// hash, an empty one is returned if there is none or it's of another
+// scale-out, e.g. the topology file is changed since the last try
+func (m *Manager) loadScaleOutCheckpoint(clusterName, hash string) (*scaleOutCheckpoint, error) {
+	cp := &scaleOutCheckpoint{
+		Cluster:      clusterName,
+		TopologyHash: hash,
+		Hosts:        make(map[string][]string),
+		path:         m.specManager.Path(clusterName, scaleOutCheckpointFile),
+	}
+	data, err := ioutil.ReadFile(cp.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cp, nil
+		}
+		return nil, perrs.AddStack(err)
+	}
+	last := scaleOutCheckpoint{}
+	if err := json.Unmarshal(data, &last); err != nil {
+		return nil, perrs.Annotatef(err, "corrupted %s of cluster %s", scaleOutCheckpointFile, clusterName)
+	}
+	// the checkpoint is only kept for the post-mortem once the new
+	// instances are saved to the meta
+	if len(last.Hosts) == 0 {
+		return cp, nil
+	}
+	if last.TopologyHash != hash {
+		log.Warnf("The checkpoint of the last scale-out of cluster `%s` is ignored as the topology is changed", clusterName)
+		return cp, nil
+	}
+	for host, phases := range last.Hosts {
+		cp.Hosts[host] = phases
+	}
+	cp.resumed = last.Tasks
+	return cp, nil
+}
+
+// finished is true if the phase is finished on the host
+func (cp *scaleOutCheckpoint) finished(host, phase string) bool {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	for _, p := range cp.Hosts[host] {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// finish records the phase finished on the hosts and saves the checkpoint
+func (cp *scaleOutCheckpoint) finish(phase string, hosts ...string) error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	for _, host := range hosts {
+		found := false
+		for _, p := range cp.Hosts[host] {
+			found = found || p == phase
+		}
+		if !found {
+			cp.Hosts[host] = append(cp.Hosts[host], phase)
+		}
+	}
+	return cp.save()
+}
+
+// save writes the checkpoint with the current tasks, the lock must be held
+func (cp *scaleOutCheckpoint) save() error {
+	if cp.tree != nil {
+		cp.Tasks = cp.tree.Snapshot()
+	}
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	cp.saved = time.Now()
+	return perrs.AddStack(ioutil.WriteFile(cp.path, data, 0644))
+}
+
+// trackTasks records the tasks of the scale-out executed with the context
+// in the checkpoint, call closeTasks after the execution
+func (cp *scaleOutCheckpoint) trackTasks(ctx *task.Context, t task.Task) {
+	cp.mu.Lock()
+	cp.tree = task.NewTaskTree(t)
+	if cp.resumed != nil {
+		cp.tree.Restore(cp.resumed)
+	}
+	cp.mu.Unlock()
+	cp.tree.Track(ctx, func() {
+		cp.mu.Lock()
+		defer cp.mu.Unlock()
+		if time.Since(cp.saved) < scaleOutCheckpointInterval {
+			return
+		}
+		if err := cp.save(); err != nil {
+			log.Debugf("Failed to save the scale-out checkpoint: %s", err)
+		}
+	})
+}
+
+// closeTasks removes the checkpoint if the scale-out succeeded, otherwise the
+// final status of the tasks is saved for the post-mortem
+func (cp *scaleOutCheckpoint) closeTasks(execErr error) error {
+	if execErr == nil {
+		return cp.remove()
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.save()
+}
+
+// finishTask returns a task recording the phase finished on the hosts, it's
+// skipped if any of the hosts is degraded in the error scope
+func (cp *scaleOutCheckpoint) finishTask(phase string, hosts ...string) task.Task {
+	return task.NewFunc("ScaleOutCheckpoint", func(_ *task.Context) error {
+		return cp.finish(phase, hosts...)
+	}, task.OnHosts(hosts...))
+}
+
+// remove removes the checkpoint once the scale-out doesn't need to be
+// retried, the tasks saved later are only for the post-mortem
+func (cp *scaleOutCheckpoint) remove() error {
+	cp.mu.Lock()
+	cp.Hosts = make(map[string][]string)
+	cp.resumed = nil
+	cp.mu.Unlock()
+	if err := os.Remove(cp.path); err != nil && !os.IsNotExist(err) {
+		return perrs.AddStack(err)
+	}
+	return nil
+}
+
+// printSkipped prints the phases skipped on the hosts
+func (cp *scaleOutCheckpoint) printSkipped() {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	hosts := make([]string, 0, len(cp.Hosts))
+	for host := range cp.Hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		log.Infof("Skip the phases finished by the last scale-out on host %s: %s", host, strings.Join(cp.Hosts[host], ", "))
+	}
+}
+
+// hostsIter iterates the instances of a topology on the hosts
+type hostsIter struct {
+	topo  spec.Topology
+	hosts map[string]struct{}
+}
+
+// IterInstance implements InstanceIter
+func (it *hostsIter) IterInstance(fn func(inst spec.Instance)) {
+	it.topo.IterInstance(func(inst spec.Instance) {
+		if _, ok := it.hosts[inst.GetHost()]; ok {
+			fn(inst)
+		}
+	})
+}
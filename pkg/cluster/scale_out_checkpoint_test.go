@@ -0,0 +1,136 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScaleOutCheckpoint(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+	metadata, err := MockClusterMeta(3)
+	require.Nil(t, err)
+	require.Nil(t, m.specManager.SaveMeta("test", metadata))
+
+	topo := metadata.GetTopology()
+	newPart := &spec.Specification{
+		TiKVServers: []spec.TiKVSpec{{Host: "new-1", Port: 20160}, {Host: "new-2", Port: 20160}},
+	}
+	hash, err := scaleOutHash(topo, newPart, "v4.0.0")
+	require.Nil(t, err)
+
+	cp, err := m.loadScaleOutCheckpoint("test", hash)
+	require.Nil(t, err)
+	assert.Empty(t, cp.Hosts)
+	require.Nil(t, cp.finish(scaleOutPhaseDownload, "new-1", "new-2"))
+	require.Nil(t, cp.finish(scaleOutPhaseCopy, "new-1"))
+	require.Nil(t, cp.finish(scaleOutPhaseCopy, "new-1"))
+
+	// the retry with the same topology resumes
+	cp, err = m.loadScaleOutCheckpoint("test", hash)
+	require.Nil(t, err)
+	assert.True(t, cp.finished("new-1", scaleOutPhaseCopy))
+	assert.False(t, cp.finished("new-1", scaleOutPhaseConfig))
+	assert.False(t, cp.finished("new-2", scaleOutPhaseCopy))
+	assert.Equal(t, []string{scaleOutPhaseDownload, scaleOutPhaseCopy}, cp.Hosts["new-1"])
+
+	var hosts []string
+	(&hostsIter{topo: newPart, hosts: map[string]struct{}{"new-2": {}}}).IterInstance(func(inst spec.Instance) {
+		hosts = append(hosts, inst.GetHost())
+	})
+	assert.Equal(t, []string{"new-2"}, hosts)
+
+	// the order the parallel tasks finished in is saved for rolling them
+	// back after resuming
+	noop := func(_ *task.Context) error { return nil }
+	build := func() task.Task {
+		return task.NewBuilder().OrderedRollback().Parallel(
+			task.NewFunc("CopyNew1", noop, task.OnHosts("new-1")),
+			task.NewFunc("CopyNew2", noop, task.OnHosts("new-2")),
+		).Build()
+	}
+	ctx := task.NewContext()
+	tasks := build()
+	cp.trackTasks(ctx, tasks)
+	require.Nil(t, tasks.Execute(ctx))
+	require.Nil(t, cp.closeTasks(errors.New("failed to start")))
+	cp, err = m.loadScaleOutCheckpoint("test", hash)
+	require.Nil(t, err)
+	require.NotNil(t, cp.resumed)
+	assert.Len(t, cp.resumed.Children[0].FinishOrder, 2)
+
+	// the checkpoint of another scale-out is ignored
+	newPart.TiKVServers[1].Port = 20161
+	other, err := scaleOutHash(topo, newPart, "v4.0.0")
+	require.Nil(t, err)
+	assert.NotEqual(t, hash, other)
+	cp, err = m.loadScaleOutCheckpoint("test", other)
+	require.Nil(t, err)
+	assert.Empty(t, cp.Hosts)
+
+	require.Nil(t, cp.remove())
+	require.Nil(t, cp.remove())
+	_, err = os.Stat(m.specManager.Path("test", scaleOutCheckpointFile))
+	assert.True(t, os.IsNotExist(err))
+	_, err = m.ScaleOutPostMortem("test")
+	assert.True(t, errorx.IsOfType(err, ErrNoCheckpoint))
+}
+
+func TestScaleOutHostChains(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+	metadata, err := MockClusterMeta(3)
+	require.Nil(t, err)
+	require.Nil(t, m.specManager.SaveMeta("test", metadata))
+
+	topo := metadata.GetTopology()
+	newPart := &spec.Specification{
+		TiKVServers: []spec.TiKVSpec{{Host: "new-1", Port: 20160}, {Host: "new-2", Port: 20160}},
+	}
+	cp, err := m.loadScaleOutCheckpoint("test", "hash")
+	require.Nil(t, err)
+	st, err := buildScaleOutTask(m, "test", metadata, topo.MergeTopo(newPart), ScaleOutOptions{User: "root"},
+		&cliutil.SSHConnectionProps{}, newPart, set.NewStringSet(), 60, 5, false, cp, nil, nil)
+	require.Nil(t, err)
+	// the environment of each host is initialized in the chain of the host,
+	// and the phase is recorded before the packages are copied
+	for _, host := range []string{"new-1", "new-2"} {
+		assert.Contains(t, st.String(), "EnvInit: user=tidb, host="+host+"\n"+
+			"ApplyOSSettings: host="+host+", cluster=test\n"+
+			"Mkdir: host="+host+", directories='/home/tidb/deploy','/home/tidb/data'\n"+
+			"ScaleOutCheckpoint\n"+
+			"UserSSH: user=tidb, host="+host+"\n")
+	}
+
+	// the hosts initialized by the last try are not initialized again, but
+	// still deployed the monitored components to
+	require.Nil(t, cp.finish(scaleOutPhaseEnvInit, "new-2"))
+	st, err = buildScaleOutTask(m, "test", metadata, topo.MergeTopo(newPart), ScaleOutOptions{User: "root"},
+		&cliutil.SSHConnectionProps{}, newPart, set.NewStringSet(), 60, 5, false, cp, nil, nil)
+	require.Nil(t, err)
+	assert.Contains(t, st.String(), "EnvInit: user=tidb, host=new-1")
+	assert.NotContains(t, st.String(), "EnvInit: user=tidb, host=new-2")
+	assert.Contains(t, st.String(), "CopyComponent: component=node_exporter, version=v0.17.0, remote=new-2:")
+}
@@ -0,0 +1,38 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/errutil"
+)
+
+// ErrScopedSudo is returned when an operation changing the files on the
+// hosts is run on a cluster whose deploy user can only operate the units
+var ErrScopedSudo = errNSDeploy.NewType("scoped_sudo", errutil.ErrTraitPreCheck)
+
+// checkScopedSudo refuses the action changing the files on the hosts if the
+// deploy user is bootstrapped with the sudo privileges scoped to systemctl,
+// the action runs its commands by sudo as the deploy user, which the sudoers
+// rules don't allow. Starting, stopping and restarting are not refused.
+func checkScopedSudo(clusterName string, base *spec.BaseMeta, action string) error {
+	if !base.ScopedSudo {
+		return nil
+	}
+	return ErrScopedSudo.New("The deploy user `%s` of cluster `%s` can only operate the services by sudo, refusing to %s it", base.User, clusterName, action).
+		WithProperty(cliutil.SuggestionFromFormat(
+			"Please grant `%s` full sudo privileges on the hosts in place of /etc/sudoers.d/tiup-%s,\nand remove `scoped_sudo: true` from the meta.yaml of the cluster, then try again.",
+			base.User, clusterName))
+}
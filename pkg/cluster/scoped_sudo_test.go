@@ -0,0 +1,49 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopedSudo(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+	require.Nil(t, os.MkdirAll(m.specManager.Path("prod-eu"), 0755))
+	require.Nil(t, ioutil.WriteFile(m.specManager.Path("prod-eu", "meta.yaml"), []byte("user: tidb\nscoped_sudo: true\n"), 0644))
+
+	// the deploy user bootstrapped can't change the files on the hosts
+	for action, err := range map[string]error{
+		"reload":   m.Reload("prod-eu", operator.Options{}, false),
+		"upgrade":  m.Upgrade("prod-eu", "v4.0.0", operator.Options{}),
+		"destroy":  m.DestroyCluster("prod-eu", operator.Options{}, operator.Options{}, true),
+		"scale in": m.ScaleIn("prod-eu", true, 5, false, false, false, []string{"127.0.0.1:4000"}, "", nil),
+	} {
+		require.NotNil(t, err, action)
+		assert.True(t, errutil.Cast(err).IsOfType(ErrScopedSudo), "%s: %v", action, err)
+		assert.Contains(t, err.Error(), "refusing to "+action+" it")
+	}
+
+	// the clusters with full sudo are not refused by the check
+	require.Nil(t, ioutil.WriteFile(m.specManager.Path("prod-eu", "meta.yaml"), []byte("user: tidb\n"), 0644))
+	err := m.DestroyCluster("prod-eu", operator.Options{}, operator.Options{}, true)
+	assert.False(t, errutil.Cast(err) != nil && errutil.Cast(err).IsOfType(ErrScopedSudo))
+}
@@ -0,0 +1,257 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/meta"
+	"github.com/pingcap/tiup/pkg/set"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	errNSScrapeTargets = errorx.NewNamespace("scrape_targets")
+	// ErrScrapeTargetsFormat is returned when the format of the scrape targets is unknown
+	ErrScrapeTargetsFormat = errNSScrapeTargets.NewType("unknown_format", errutil.ErrTraitPreCheck)
+	// ErrScrapeTargetsUnsupported is returned when the topology has no scrape targets, e.g. DM
+	ErrScrapeTargetsUnsupported = errNSScrapeTargets.NewType("unsupported", errutil.ErrTraitPreCheck)
+)
+
+// the formats of the exported scrape targets
+const (
+	// ScrapeTargetsFileSD is the JSON file of the file based service discovery
+	// of Prometheus, referred by file_sd_configs
+	ScrapeTargetsFileSD = "file_sd"
+	// ScrapeTargetsStatic is a YAML fragment of static scrape_configs, to be
+	// put under scrape_configs of prometheus.yml
+	ScrapeTargetsStatic = "scrape_configs"
+)
+
+// the instances are served without TLS by the clusters deployed by tiup
+const scrapeScheme = "http"
+
+// ScrapeTarget is a metrics endpoint of an instance
+type ScrapeTarget struct {
+	Component string
+	// the address scraped, host:port, it's also the instance label as in the
+	// Prometheus deployed by tiup, so the dashboards work unmodified
+	Address string
+}
+
+// fileSDGroup is a target group of the file based service discovery
+type fileSDGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// staticScrapeConfig is a job of scrape_configs
+type staticScrapeConfig struct {
+	JobName       string         `yaml:"job_name"`
+	Scheme        string         `yaml:"scheme"`
+	HonorLabels   bool           `yaml:"honor_labels"`
+	StaticConfigs []staticConfig `yaml:"static_configs"`
+}
+
+type staticConfig struct {
+	Targets []string          `yaml:"targets"`
+	Labels  map[string]string `yaml:"labels"`
+}
+
+// advertisedAddr returns the address advertised by the key of the instance
+// config, e.g., for the instances behind NAT, or the one of its host
+func advertisedAddr(config map[string]interface{}, key, host string, port int) string {
+	if addr, ok := spec.ConfigValue(config, key); ok {
+		if s, ok := addr.(string); ok && s != "" {
+			return s
+		}
+	}
+	return fmt.Sprintf("%s:%d", host, port)
+}
+
+// scrapeTargets returns the metrics endpoints of the instances of the
+// topology and the monitoring agents on the hosts, sorted by the components
+// and addresses. The status addresses advertised by the instance configs are
+// honored, the other components are started with the advertised addresses of
+// their hosts on the command lines, which take precedence over the configs.
+func scrapeTargets(topo *spec.Specification) []ScrapeTarget {
+	var targets []ScrapeTarget
+	addAddr := func(comp, addr string) {
+		targets = append(targets, ScrapeTarget{Component: comp, Address: addr})
+	}
+	add := func(comp, host string, port int) {
+		addAddr(comp, fmt.Sprintf("%s:%d", host, port))
+	}
+	hosts := set.NewStringSet()
+	for _, s := range topo.PDServers {
+		hosts.Insert(s.Host)
+		add(spec.ComponentPD, s.Host, s.ClientPort)
+	}
+	for _, s := range topo.TiKVServers {
+		hosts.Insert(s.Host)
+		addAddr(spec.ComponentTiKV, advertisedAddr(s.Config, "server.advertise-status-addr", s.Host, s.StatusPort))
+	}
+	for _, s := range topo.TiDBServers {
+		hosts.Insert(s.Host)
+		add(spec.ComponentTiDB, s.Host, s.StatusPort)
+	}
+	for _, s := range topo.TiFlashServers {
+		hosts.Insert(s.Host)
+		add(spec.ComponentTiFlash, s.Host, s.StatusPort)
+		addAddr(spec.ComponentTiFlash, advertisedAddr(s.LearnerConfig, "server.advertise-status-addr", s.Host, s.FlashProxyStatusPort))
+	}
+	for _, s := range topo.PumpServers {
+		hosts.Insert(s.Host)
+		add(spec.ComponentPump, s.Host, s.Port)
+	}
+	for _, s := range topo.Drainers {
+		hosts.Insert(s.Host)
+		add(spec.ComponentDrainer, s.Host, s.Port)
+	}
+	for _, s := range topo.CDCServers {
+		hosts.Insert(s.Host)
+		add(spec.ComponentCDC, s.Host, s.Port)
+	}
+	for _, s := range topo.Monitors {
+		hosts.Insert(s.Host)
+		add(spec.ComponentPrometheus, s.Host, s.Port)
+	}
+	for _, s := range topo.Grafana {
+		hosts.Insert(s.Host)
+		add(spec.ComponentGrafana, s.Host, s.Port)
+	}
+	for _, s := range topo.Alertmanager {
+		hosts.Insert(s.Host)
+		add(spec.ComponentAlertManager, s.Host, s.WebPort)
+	}
+	for host := range hosts {
+		// the hosts running the node_exporter of others are scraped on its port
+		if port := topo.MonitoredOptions.ExporterPort(host); port > 0 {
+			add(spec.ComponentNodeExporter, host, port)
+		}
+		if topo.MonitoredOptions.DeployAgents(host) {
+			add(spec.ComponentBlackboxExporter, host, topo.MonitoredOptions.BlackboxExporterPort)
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Component != targets[j].Component {
+			return targets[i].Component < targets[j].Component
+		}
+		return targets[i].Address < targets[j].Address
+	})
+	return targets
+}
+
+// formatScrapeTargets renders the targets in the format, every target is
+// labeled with the cluster, the component and the instance
+func formatScrapeTargets(clusterName string, targets []ScrapeTarget, format string) ([]byte, error) {
+	labels := func(t ScrapeTarget) map[string]string {
+		return map[string]string{
+			"cluster":   clusterName,
+			"component": t.Component,
+			"instance":  t.Address,
+		}
+	}
+	switch format {
+	case ScrapeTargetsFileSD:
+		groups := make([]fileSDGroup, 0, len(targets))
+		for _, t := range targets {
+			l := labels(t)
+			l["__scheme__"] = scrapeScheme
+			groups = append(groups, fileSDGroup{Targets: []string{t.Address}, Labels: l})
+		}
+		data, err := json.MarshalIndent(groups, "", "  ")
+		if err != nil {
+			return nil, perrs.AddStack(err)
+		}
+		return append(data, '\n'), nil
+	case ScrapeTargetsStatic:
+		var jobs []staticScrapeConfig
+		for _, t := range targets {
+			// one job for each component, the targets are sorted by components
+			if len(jobs) == 0 || jobs[len(jobs)-1].JobName != clusterName+"-"+t.Component {
+				jobs = append(jobs, staticScrapeConfig{
+					JobName:     clusterName + "-" + t.Component,
+					Scheme:      scrapeScheme,
+					HonorLabels: true,
+				})
+			}
+			job := &jobs[len(jobs)-1]
+			job.StaticConfigs = append(job.StaticConfigs, staticConfig{Targets: []string{t.Address}, Labels: labels(t)})
+		}
+		data, err := yaml.Marshal(jobs)
+		if err != nil {
+			return nil, perrs.AddStack(err)
+		}
+		return data, nil
+	}
+	return nil, ErrScrapeTargetsFormat.New("Unknown format '%s' of the scrape targets, it's either %s or %s",
+		format, ScrapeTargetsFileSD, ScrapeTargetsStatic)
+}
+
+// ScrapeTargetsFormatOf returns the format of the scrape targets written to
+// the file by its extension, file_sd for .json and scrape_configs otherwise
+func ScrapeTargetsFormatOf(path string) string {
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		return ScrapeTargetsFileSD
+	}
+	return ScrapeTargetsStatic
+}
+
+// ExportScrapeTargets returns the metrics endpoints of all the instances of
+// the cluster and the monitoring agents on its hosts, for a Prometheus not
+// deployed by tiup, in the format of ScrapeTargetsFileSD or
+// ScrapeTargetsStatic. The targets are labeled with cluster, component and
+// instance.
+func (m *Manager) ExportScrapeTargets(name string, format string) ([]byte, error) {
+	metadata, err := m.meta(name)
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) &&
+		!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
+		return nil, err
+	}
+	topo, ok := metadata.GetTopology().(*spec.Specification)
+	if !ok {
+		return nil, ErrScrapeTargetsUnsupported.New("Exporting the scrape targets of cluster `%s` is not supported", name)
+	}
+	return formatScrapeTargets(name, scrapeTargets(topo), format)
+}
+
+// WriteScrapeTargets exports the scrape targets of the cluster to the file,
+// in the format by its extension if format is empty, see ExportScrapeTargets
+func (m *Manager) WriteScrapeTargets(name, format, path string) error {
+	if format == "" {
+		format = ScrapeTargetsFormatOf(path)
+	}
+	data, err := m.ExportScrapeTargets(name, format)
+	if err != nil {
+		return err
+	}
+	// the file is replaced at once, as Prometheus reloads it on changes
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return perrs.AddStack(err)
+	}
+	return perrs.AddStack(os.Rename(tmp, path))
+}
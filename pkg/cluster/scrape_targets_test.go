@@ -0,0 +1,87 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestExportScrapeTargets(t *testing.T) {
+	m, dir, cleanup := newTestManager(t)
+	defer cleanup()
+	metadata, err := MockClusterMeta(2)
+	require.Nil(t, err)
+	metadata.Topology.MonitoredOptions.NodeExporterPort = 9100
+	metadata.Topology.MonitoredOptions.BlackboxExporterPort = 9115
+	// the status address advertised by the config is scraped
+	metadata.Topology.TiKVServers[0].Config = map[string]interface{}{
+		"server": map[string]interface{}{"advertise-status-addr": "172.16.0.1:30180"},
+	}
+	require.Nil(t, m.specManager.SaveMeta("test", metadata))
+
+	data, err := m.ExportScrapeTargets("test", ScrapeTargetsFileSD)
+	require.Nil(t, err)
+	var groups []fileSDGroup
+	require.Nil(t, json.Unmarshal(data, &groups))
+	// pd, tikv, tidb and the two agents on each host
+	require.Len(t, groups, 10)
+	found, advertised := false, false
+	for _, g := range groups {
+		require.Len(t, g.Targets, 1)
+		assert.Equal(t, "test", g.Labels["cluster"])
+		assert.Equal(t, g.Targets[0], g.Labels["instance"])
+		assert.Equal(t, "http", g.Labels["__scheme__"])
+		if g.Labels["component"] == spec.ComponentTiKV && g.Targets[0] == "mock-2:20180" {
+			found = true
+		}
+		if g.Targets[0] == "172.16.0.1:30180" {
+			advertised = true
+			assert.Equal(t, spec.ComponentTiKV, g.Labels["component"])
+		}
+	}
+	assert.True(t, found)
+	assert.True(t, advertised)
+
+	data, err = m.ExportScrapeTargets("test", ScrapeTargetsStatic)
+	require.Nil(t, err)
+	var jobs []staticScrapeConfig
+	require.Nil(t, yaml.UnmarshalStrict(data, &jobs))
+	require.Len(t, jobs, 5)
+	for _, job := range jobs {
+		assert.True(t, strings.HasPrefix(job.JobName, "test-"))
+		require.Len(t, job.StaticConfigs, 2)
+		assert.Equal(t, job.JobName, "test-"+job.StaticConfigs[0].Labels["component"])
+	}
+
+	_, err = m.ExportScrapeTargets("test", "prometheus")
+	assert.True(t, errorx.IsOfType(err, ErrScrapeTargetsFormat))
+
+	// the format is told by the extension of the file
+	fname := filepath.Join(dir, "targets.json")
+	require.Nil(t, m.WriteScrapeTargets("test", "", fname))
+	data, err = ioutil.ReadFile(fname)
+	require.Nil(t, err)
+	require.Nil(t, json.Unmarshal(data, &groups))
+	assert.Equal(t, ScrapeTargetsStatic, ScrapeTargetsFormatOf("targets.yml"))
+}
@@ -0,0 +1,247 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/flags"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/environment"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// the names of the checks of the control machine
+const (
+	selfCheckUmask  = "umask"
+	selfCheckLocale = "locale"
+	selfCheckSpace  = "free-space"
+	selfCheckClock  = "clock"
+)
+
+// the thresholds of the checks of the control machine
+const (
+	selfCheckMinFreeSpace = 1 << 30 // 1GiB
+	selfCheckMaxClockSkew = time.Minute
+)
+
+var (
+	errNSSelfCheck = errorx.NewNamespace("self_check")
+	// ErrSelfCheckFailed is returned when the control machine fails the checks in strict mode
+	ErrSelfCheckFailed = errNSSelfCheck.NewType("failed", errutil.ErrTraitPreCheck)
+)
+
+// SelfCheck checks the control machine for the issues known to break the
+// operations, e.g., a umask making the pushed files unreadable, a non-UTF-8
+// locale, the lack of space in the profile and temp dirs, or a clock far off
+// the one of the mirror. The results which couldn't be checked are warnings.
+// The clock is not checked if mirror is empty.
+func SelfCheck(mirror string) []*operator.CheckResult {
+	return []*operator.CheckResult{
+		checkProcessUmask(),
+		checkLocale(os.Getenv("LC_ALL"), os.Getenv("LC_CTYPE"), os.Getenv("LANG")),
+		checkDirSpace(spec.ProfileDir()),
+		checkDirSpace(os.TempDir()),
+		checkClock(mirror, time.Now),
+	}
+}
+
+// CheckControlMachine runs SelfCheck against the current mirror and prints
+// the results if any of the checks doesn't pass, or all of them in debug
+// mode. The failed checks are errors if strict is set, or warnings.
+func CheckControlMachine(strict bool) error {
+	return checkControlMachine(environment.Mirror(), strict)
+}
+
+func checkControlMachine(mirror string, strict bool) error {
+	results := SelfCheck(mirror)
+	lines := [][]string{{"Check", "Result", "Message"}}
+	var failed []string
+	show := flags.DebugMode
+	for _, r := range results {
+		switch {
+		case r.Passed():
+			lines = append(lines, []string{r.Name, color.GreenString("Pass"), r.Msg})
+		case r.IsWarning():
+			lines = append(lines, []string{r.Name, color.YellowString("Warn"), r.Error()})
+			show = true
+		default:
+			lines = append(lines, []string{r.Name, color.HiRedString("Fail"), r.Error()})
+			failed = append(failed, fmt.Sprintf("%s: %s", r.Name, r.Error()))
+			show = true
+		}
+	}
+	if show {
+		log.Infof("Checks of the control machine:")
+		cliutil.PrintTable(lines, true)
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+	if !strict {
+		log.Warnf("The control machine failed %d check(s), the operation may fail", len(failed))
+		return nil
+	}
+	return ErrSelfCheckFailed.New("The control machine failed %d check(s)", len(failed)).
+		WithProperty(cliutil.SuggestionFromFormat(
+			"Please fix the following issues of the control machine, or run without --strict-self-check to ignore them:\n  %s",
+			strings.Join(failed, "\n  ")))
+}
+
+// checkProcessUmask checks the umask of the process, which is read from
+// /proc rather than set and restored by umask(2), as that changes the umask of
+// the files created by other goroutines meanwhile
+func checkProcessUmask() *operator.CheckResult {
+	status, err := ioutil.ReadFile("/proc/self/status")
+	if os.IsNotExist(err) {
+		// not on Linux
+		return &operator.CheckResult{Name: selfCheckUmask, Msg: "skipped"}
+	}
+	if err != nil {
+		return &operator.CheckResult{Name: selfCheckUmask, Err: fmt.Errorf("unable to read the umask: %s", err), Warn: true}
+	}
+	mask, err := parseUmask(string(status))
+	if err != nil {
+		return &operator.CheckResult{Name: selfCheckUmask, Err: err, Warn: true}
+	}
+	return checkUmask(mask)
+}
+
+// parseUmask returns the umask in the status of a process in /proc, it's
+// there since Linux 4.7
+func parseUmask(status string) (int, error) {
+	for _, line := range strings.Split(status, "\n") {
+		if !strings.HasPrefix(line, "Umask:") {
+			continue
+		}
+		mask, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(line, "Umask:")), 8, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid umask in the status of the process: %s", line)
+		}
+		return int(mask), nil
+	}
+	return 0, fmt.Errorf("no umask in the status of the process")
+}
+
+// checkUmask checks the files created are readable by the others, as they
+// are pushed to the hosts and read by the deploy user there
+func checkUmask(mask int) *operator.CheckResult {
+	r := &operator.CheckResult{Name: selfCheckUmask, Msg: fmt.Sprintf("%04o", mask)}
+	if mask&0044 != 0 {
+		r.Err = fmt.Errorf("umask %04o makes the files pushed to the hosts unreadable by the deploy user, run `umask 0022` first", mask)
+	}
+	return r
+}
+
+// checkLocale checks the charset of the locale is UTF-8, the variables are
+// in the order of precedence
+func checkLocale(vars ...string) *operator.CheckResult {
+	r := &operator.CheckResult{Name: selfCheckLocale}
+	locale := ""
+	for _, v := range vars {
+		if v != "" {
+			locale = v
+			break
+		}
+	}
+	r.Msg = locale
+	switch locale {
+	case "", "C", "POSIX", "C.UTF-8", "C.utf8":
+		if locale == "" {
+			r.Msg = "POSIX"
+		}
+		return r
+	}
+	charset := ""
+	if i := strings.Index(locale, "."); i >= 0 {
+		charset = strings.ToLower(locale[i+1:])
+		if j := strings.Index(charset, "@"); j >= 0 {
+			charset = charset[:j]
+		}
+	}
+	if charset != "utf-8" && charset != "utf8" {
+		r.Err = fmt.Errorf("locale %s is not UTF-8, the rendered configs may be broken, export LANG=en_US.UTF-8 first", locale)
+	}
+	return r
+}
+
+// checkDirSpace checks there is enough space in the dir for the packages
+// and the configs generated
+func checkDirSpace(dir string) *operator.CheckResult {
+	r := &operator.CheckResult{Name: selfCheckSpace}
+	free, err := utils.FreeSpace(dir)
+	if err != nil {
+		r.Err = fmt.Errorf("unable to get the free space of %s: %s", dir, err)
+		r.Warn = true
+		return r
+	}
+	r.Msg = fmt.Sprintf("%s: %dMB", dir, free>>20)
+	if free < selfCheckMinFreeSpace {
+		r.Err = fmt.Errorf("only %dMB is free in %s, at least %dMB is needed", free>>20, dir, selfCheckMinFreeSpace>>20)
+	}
+	return r
+}
+
+// checkClock checks the clock against the Date header of the responses of
+// the mirror, as a clock far off breaks the verification of certificates
+// and manifests. It's skipped if the mirror is a local directory.
+func checkClock(mirror string, now func() time.Time) *operator.CheckResult {
+	r := &operator.CheckResult{Name: selfCheckClock}
+	if mirror == "" {
+		r.Msg = "skipped"
+		return r
+	}
+	if !strings.HasPrefix(mirror, "http://") && !strings.HasPrefix(mirror, "https://") {
+		r.Msg = "skipped for local mirror"
+		return r
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	before := now()
+	resp, err := client.Head(mirror)
+	if err != nil {
+		r.Err = fmt.Errorf("unable to reach the mirror %s: %s", mirror, err)
+		r.Warn = true
+		return r
+	}
+	resp.Body.Close()
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		r.Err = fmt.Errorf("no valid Date header from the mirror %s", mirror)
+		r.Warn = true
+		return r
+	}
+
+	// compare to the middle of the request, the Date header is in seconds
+	local := before.Add(now().Sub(before) / 2)
+	skew := local.Sub(date).Round(time.Second)
+	r.Msg = fmt.Sprintf("%s off the mirror", skew)
+	if skew > selfCheckMaxClockSkew+time.Second || skew < -selfCheckMaxClockSkew-time.Second {
+		r.Err = fmt.Errorf("the clock is %s off the mirror, TLS and manifest verification may fail, sync the clock with NTP first", skew)
+	}
+	return r
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfCheck(t *testing.T) {
+	assert.Nil(t, checkUmask(0022).Err)
+	assert.NotNil(t, checkUmask(0077).Err)
+	mask, err := parseUmask("Name:\ttiup-cluster\nUmask:\t0027\nState:\tR (running)\n")
+	require.Nil(t, err)
+	assert.Equal(t, 0027, mask)
+	_, err = parseUmask("Name:\ttiup-cluster\n")
+	assert.NotNil(t, err)
+	_, err = parseUmask("Umask:\t0089\n")
+	assert.NotNil(t, err)
+	// the umask of the process is read from /proc on Linux
+	r := checkProcessUmask()
+	if r.Msg != "skipped" {
+		assert.False(t, r.Warn, "%v", r.Err)
+	}
+
+	assert.Nil(t, checkLocale("", "", "").Err)
+	assert.Nil(t, checkLocale("", "", "en_US.UTF-8").Err)
+	assert.Nil(t, checkLocale("C", "", "zh_CN.GBK").Err)
+	assert.NotNil(t, checkLocale("", "", "zh_CN.GBK").Err)
+	assert.NotNil(t, checkLocale("", "en_US", "en_US.utf8").Err)
+
+	var skew time.Duration
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-skew).UTC().Format(http.TimeFormat))
+	}))
+	defer srv.Close()
+
+	r = checkClock(srv.URL, time.Now)
+	assert.Nil(t, r.Err)
+	skew = 10 * time.Minute
+	r = checkClock(srv.URL, time.Now)
+	assert.NotNil(t, r.Err)
+	assert.False(t, r.IsWarning())
+
+	// the clock fails the operation in strict mode, whether in debug mode or not
+	err = checkControlMachine(srv.URL, true)
+	require.True(t, errorx.IsOfType(err, ErrSelfCheckFailed))
+	suggestion, _ := errorx.ExtractProperty(err, errutil.ErrPropSuggestion)
+	// the Date header is in seconds, the skew may be rounded up by a second
+	assert.Contains(t, suggestion, "clock: the clock is 10m")
+	assert.Contains(t, suggestion, "run without --strict-self-check to ignore them")
+	assert.Nil(t, checkControlMachine(srv.URL, false))
+
+	// the clock can't be checked with local or unreachable mirrors, and it's
+	// not checked without a mirror
+	assert.Nil(t, checkClock("/data/mirror", time.Now).Err)
+	assert.Equal(t, "skipped", checkClock("", time.Now).Msg)
+	srv.Close()
+	r = checkClock(srv.URL, time.Now)
+	assert.NotNil(t, r.Err)
+	assert.True(t, r.IsWarning())
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"github.com/fatih/color"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/logger/log"
+)
+
+// ReloadResult is the structured result of reloading a cluster
+type ReloadResult struct {
+	ServiceFiles []spec.ServiceFilesChange          `json:"service_files"`
+	Grafana      []*operator.GrafanaProvisionReport `json:"grafana,omitempty"`
+}
+
+// printServiceFilesChanges prints the instances whose systemd unit or run
+// script were updated on the host, it returns the number of such instances
+func printServiceFilesChanges(changes []spec.ServiceFilesChange) int {
+	mark := func(changed bool) string {
+		if changed {
+			return color.GreenString("Updated")
+		}
+		return "-"
+	}
+
+	rows := [][]string{{"Instance", "Systemd Unit", "Run Script"}}
+	for _, c := range changes {
+		if c.Updated() {
+			rows = append(rows, []string{c.Instance, mark(c.UnitChanged), mark(c.ScriptChanged)})
+		}
+	}
+	if len(rows) == 1 {
+		log.Infof("No systemd unit or run script is changed")
+		return 0
+	}
+	log.Infof("Updated the service files of %d instance(s):", len(rows)-1)
+	cliutil.PrintTable(rows, true)
+	return len(rows) - 1
+}
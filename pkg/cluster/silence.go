@@ -0,0 +1,230 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"go.uber.org/zap"
+)
+
+var (
+	errNSSilence = errorx.NewNamespace("silence")
+	// ErrSilenceFailed is returned when the alerts can't be silenced and the silence is required
+	ErrSilenceFailed = errNSSilence.NewType("failed", errutil.ErrTraitPreCheck)
+)
+
+// the file the silences not expired yet are recorded in, under the cluster
+// dir, so the ones left by crashed operations can be expired by recover
+const silencesFile = "silences.json"
+
+// the minimal duration of silences, in case the operation timeout is small
+const minSilenceDuration = 10 * time.Minute
+
+// SilenceRecord is a silence of Alertmanager created for an operation
+type SilenceRecord struct {
+	ID           string        `json:"id"`
+	Alertmanager string        `json:"alertmanager"` // the address of the Alertmanager
+	Operation    OperationType `json:"operation"`
+	CreatedAt    time.Time     `json:"created_at"`
+	EndsAt       time.Time     `json:"ends_at"`
+}
+
+func (m *Manager) loadSilences(clusterName string) ([]SilenceRecord, error) {
+	data, err := ioutil.ReadFile(m.specManager.Path(clusterName, silencesFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, perrs.AddStack(err)
+	}
+	var records []SilenceRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, perrs.Annotatef(err, "corrupted %s of cluster %s", silencesFile, clusterName)
+	}
+	return records, nil
+}
+
+// updateSilences applies f to the recorded silences of the cluster
+func (m *Manager) updateSilences(clusterName string, f func([]SilenceRecord) []SilenceRecord) error {
+	records, err := m.loadSilences(clusterName)
+	if err != nil {
+		return err
+	}
+	records = f(records)
+	fname := m.specManager.Path(clusterName, silencesFile)
+	if len(records) == 0 {
+		if err := os.Remove(fname); err != nil && !os.IsNotExist(err) {
+			return perrs.AddStack(err)
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	return perrs.AddStack(ioutil.WriteFile(fname, data, 0644))
+}
+
+// silenceDuration returns how long the alerts are silenced for an operation
+// on n instances, each of which may take the operation timeout
+func silenceDuration(options operator.Options, n int) time.Duration {
+	d := time.Duration(options.OptTimeout) * time.Second * time.Duration(n+1)
+	if d < minSilenceDuration {
+		d = minSilenceDuration
+	}
+	return d
+}
+
+// silenceMatchers returns the matchers of the alerts of the instances of the
+// cluster, the instance label is not matched if all instances are affected
+func silenceMatchers(clusterName string, insts []spec.Instance, all bool) []api.SilenceMatcher {
+	matchers := []api.SilenceMatcher{{Name: "cluster", Value: clusterName}}
+	if all {
+		return matchers
+	}
+	// the instance label is the address scraped, which is one of the ports
+	var addrs []string
+	for _, inst := range insts {
+		var ports []string
+		for _, p := range inst.UsedPorts() {
+			ports = append(ports, strconv.Itoa(p))
+		}
+		addrs = append(addrs, fmt.Sprintf("%s:(%s)", regexp.QuoteMeta(inst.GetHost()), strings.Join(ports, "|")))
+	}
+	sort.Strings(addrs)
+	return append(matchers, api.SilenceMatcher{Name: "instance", Value: strings.Join(addrs, "|"), IsRegex: true})
+}
+
+// alertmanagerAddrs returns the addresses of the Alertmanagers of the topology
+func alertmanagerAddrs(topo spec.Topology) []string {
+	var addrs []string
+	for _, com := range topo.ComponentsByStartOrder() {
+		if com.Name() != spec.ComponentAlertManager {
+			continue
+		}
+		for _, inst := range com.Instances() {
+			addrs = append(addrs, fmt.Sprintf("%s:%d", inst.GetHost(), inst.GetPort()))
+		}
+	}
+	return addrs
+}
+
+// silenceAlerts silences the alerts of the instances affected by the operation
+// if options.SilenceAlerts is set, the returned function expires the silence
+// and must always be called. The silence is recorded in the result of the
+// operation and the silences file of the cluster until it's expired. Failing
+// to create the silence is only warned unless options.RequireSilence is set.
+func (m *Manager) silenceAlerts(op *OperationInfo, topo spec.Topology, options operator.Options) (func(), error) {
+	noop := func() {}
+	if !options.SilenceAlerts {
+		return noop, nil
+	}
+	fail := func(format string, args ...interface{}) (func(), error) {
+		if options.RequireSilence {
+			return noop, ErrSilenceFailed.New(format, args...).
+				WithProperty(cliutil.SuggestionFromString("Please check the Alertmanagers, or run without `--require-silence`."))
+		}
+		log.Warnf(format+", the alerts are not silenced", args...)
+		return noop, nil
+	}
+
+	addrs := alertmanagerAddrs(topo)
+	if len(addrs) == 0 {
+		return fail("No Alertmanager is deployed in cluster `%s`", op.clusterName)
+	}
+
+	insts := selectInstances(topo, options)
+	all := len(options.Roles) == 0 && len(options.Nodes) == 0 && options.Selector == nil
+	now := time.Now()
+	silence := api.Silence{
+		Matchers:  silenceMatchers(op.clusterName, insts, all),
+		StartsAt:  now,
+		EndsAt:    now.Add(silenceDuration(options, len(insts))),
+		CreatedBy: "tiup",
+		Comment:   fmt.Sprintf("%s of cluster %s", op.operationType, op.clusterName),
+	}
+
+	var record *SilenceRecord
+	var lastErr error
+	for _, addr := range addrs {
+		id, err := api.NewAlertmanagerClient(addr, 10*time.Second).CreateSilence(silence)
+		if err != nil {
+			zap.L().Debug("Failed to create silence", zap.String("alertmanager", addr), zap.Error(err))
+			lastErr = err
+			continue
+		}
+		record = &SilenceRecord{ID: id, Alertmanager: addr, Operation: op.operationType, CreatedAt: now, EndsAt: silence.EndsAt}
+		break
+	}
+	if record == nil {
+		return fail("Failed to silence the alerts of cluster `%s`: %s", op.clusterName, lastErr)
+	}
+
+	op.mu.Lock()
+	op.silences = append(op.silences, *record)
+	op.mu.Unlock()
+	if err := m.updateSilences(op.clusterName, func(rs []SilenceRecord) []SilenceRecord {
+		return append(rs, *record)
+	}); err != nil {
+		zap.L().Warn("Failed to record silence", zap.String("id", record.ID), zap.Error(err))
+	}
+	log.Infof("Silenced the alerts of cluster `%s` until %s, silence %s on %s",
+		op.clusterName, record.EndsAt.Format(time.RFC3339), record.ID, record.Alertmanager)
+
+	return func() {
+		if err := m.expireSilence(op.clusterName, *record); err != nil {
+			log.Warnf("Failed to expire silence %s on %s: %s, use `recover` to expire it later", record.ID, record.Alertmanager, err)
+			return
+		}
+		log.Infof("Expired silence %s on %s", record.ID, record.Alertmanager)
+	}, nil
+}
+
+// expireSilence expires the silence and removes its record
+func (m *Manager) expireSilence(clusterName string, record SilenceRecord) error {
+	if err := api.NewAlertmanagerClient(record.Alertmanager, 10*time.Second).ExpireSilence(record.ID); err != nil {
+		return err
+	}
+	return m.forgetSilence(clusterName, record)
+}
+
+// forgetSilence removes the record of the silence
+func (m *Manager) forgetSilence(clusterName string, record SilenceRecord) error {
+	return m.updateSilences(clusterName, func(rs []SilenceRecord) []SilenceRecord {
+		kept := rs[:0]
+		for _, r := range rs {
+			if r.ID != record.ID || r.Alertmanager != record.Alertmanager {
+				kept = append(kept, r)
+			}
+		}
+		return kept
+	})
+}
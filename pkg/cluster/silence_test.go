@@ -0,0 +1,126 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSilenceAlerts(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+
+	var (
+		mu       sync.Mutex
+		created  []api.Silence
+		expired  []string
+		silences int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v2/silences":
+			var s api.Silence
+			require.Nil(t, json.NewDecoder(r.Body).Decode(&s))
+			created = append(created, s)
+			silences++
+			_, _ = w.Write([]byte(`{"silenceID":"s` + strconv.Itoa(silences) + `"}`))
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/v2/silence/"):
+			expired = append(expired, strings.TrimPrefix(r.URL.Path, "/api/v2/silence/"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.Nil(t, err)
+	webPort, err := strconv.Atoi(port)
+	require.Nil(t, err)
+
+	require.Nil(t, os.MkdirAll(m.specManager.Path("prod-eu"), 0755))
+	require.Nil(t, ioutil.WriteFile(m.specManager.Path("prod-eu", "meta.yaml"), []byte("user: tidb\n"), 0644))
+	topo := &spec.Specification{
+		TiDBServers:  []spec.TiDBSpec{{Host: "10.0.1.1", Port: 4000, StatusPort: 10080}},
+		Alertmanager: []spec.AlertManagerSpec{{Host: host, WebPort: webPort, ClusterPort: 9094}},
+	}
+
+	// nothing is silenced unless requested
+	op, err := m.beginOperation("prod-eu", OperationStop)
+	require.Nil(t, err)
+	unsilence, err := m.silenceAlerts(op, topo, operator.Options{})
+	require.Nil(t, err)
+	unsilence()
+	assert.Empty(t, created)
+
+	options := operator.Options{SilenceAlerts: true, OptTimeout: 120, Roles: []string{spec.ComponentTiDB}}
+	unsilence, err = m.silenceAlerts(op, topo, options)
+	require.Nil(t, err)
+	require.Len(t, created, 1)
+	assert.Equal(t, []api.SilenceMatcher{
+		{Name: "cluster", Value: "prod-eu"},
+		{Name: "instance", Value: `10\.0\.1\.1:(4000|10080)`, IsRegex: true},
+	}, created[0].Matchers)
+	assert.True(t, created[0].EndsAt.Sub(created[0].StartsAt) >= minSilenceDuration)
+	records, err := m.loadSilences("prod-eu")
+	require.Nil(t, err)
+	require.Len(t, records, 1)
+	data, err := json.Marshal(op)
+	require.Nil(t, err)
+	assert.Contains(t, string(data), `"id":"s1"`)
+
+	unsilence()
+	assert.Equal(t, []string{"s1"}, expired)
+	records, err = m.loadSilences("prod-eu")
+	require.Nil(t, err)
+	assert.Empty(t, records)
+
+	// the silence of a crashed operation is expired by recover
+	_, err = m.silenceAlerts(op, topo, options)
+	require.Nil(t, err)
+	m.endOperation(op, nil)
+	require.Nil(t, m.Recover("prod-eu", RecoverOptions{SkipConfirm: true}))
+	assert.Equal(t, []string{"s1", "s2"}, expired)
+	records, err = m.loadSilences("prod-eu")
+	require.Nil(t, err)
+	assert.Empty(t, records)
+
+	// failing to silence only blocks the operation if required
+	server.Close()
+	op, err = m.beginOperation("prod-eu", OperationStop)
+	require.Nil(t, err)
+	_, err = m.silenceAlerts(op, topo, options)
+	assert.Nil(t, err)
+	options.RequireSilence = true
+	_, err = m.silenceAlerts(op, topo, options)
+	require.NotNil(t, err)
+	assert.True(t, errutil.Cast(err).IsOfType(ErrSilenceFailed))
+	m.endOperation(op, nil)
+}
@@ -112,7 +112,7 @@ func (i *CDCInstance) ScaleConfig(e executor.Executor, topo Topology, clusterNam
 
 // InitConfig implements Instance interface.
 func (i *CDCInstance) InitConfig(e executor.Executor, clusterName, clusterVersion, deployUser string, paths meta.DirPaths) error {
-	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, deployUser, paths); err != nil {
+	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, clusterName, clusterVersion, deployUser, paths); err != nil {
 		return err
 	}
 
@@ -125,7 +125,7 @@ func (i *CDCInstance) InitConfig(e executor.Executor, clusterName, clusterVersio
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_cdc_%s_%d.sh", i.GetHost(), i.GetPort()))
 
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := RenderTemplate(cfg, clusterName, clusterVersion, ComponentCDC, "run_cdc.sh.tpl", fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "scripts", "run_cdc.sh")
@@ -0,0 +1,156 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"path"
+	"reflect"
+
+	perrs "github.com/pingcap/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// hotReloadableConfigs are the config keys each component can change online
+// without restarting, via its config API or pd-ctl, see IsHotReloadable. Only
+// the keys listed by the docs of modifying the configs dynamically of v4.0 are
+// here, the others are treated as needing a restart. A "*" matches any part
+// of a key, including the dots.
+var hotReloadableConfigs = map[string][]string{
+	ComponentTiKV: {
+		"raftstore.sync-log",
+		"raftstore.raft-log-gc-threshold",
+		"raftstore.raft-log-gc-count-limit",
+		"raftstore.raft-log-gc-size-limit",
+		"raftstore.region-split-check-diff",
+		"raftstore.messages-per-tick",
+		"coprocessor.split-region-on-table",
+		"coprocessor.batch-split-limit",
+		"coprocessor.region-max-size",
+		"coprocessor.region-split-size",
+		"coprocessor.region-max-keys",
+		"coprocessor.region-split-keys",
+		"pessimistic-txn.wait-for-lock-timeout",
+		"pessimistic-txn.wake-up-delay-duration",
+		"gc.ratio-threshold",
+		"gc.batch-keys",
+		"gc.max-write-bytes-per-sec",
+		"split.qps-threshold",
+		"storage.block-cache.capacity",
+		"rocksdb.max-background-jobs",
+		"rocksdb.defaultcf.block-cache-size",
+		"rocksdb.writecf.block-cache-size",
+		"rocksdb.lockcf.block-cache-size",
+	},
+	ComponentPD: {
+		"schedule.*",
+		"replication.*",
+		"label-property.*",
+		"log.level",
+	},
+	ComponentTiDB: {
+		"log.level",
+	},
+}
+
+// IsHotReloadable returns whether the config key of the component, e.g.,
+// "raftstore.sync-log" of tikv, can be changed online without restarting.
+// The reload command still restarts the instances unless --skip-restart.
+func IsHotReloadable(comp, key string) bool {
+	for _, pattern := range hotReloadableConfigs[comp] {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// GlobalServerConfig returns the config of the component in server_configs
+func (s *Specification) GlobalServerConfig(comp string) map[string]interface{} {
+	switch comp {
+	case ComponentTiDB:
+		return s.ServerConfigs.TiDB
+	case ComponentTiKV:
+		return s.ServerConfigs.TiKV
+	case ComponentPD:
+		return s.ServerConfigs.PD
+	case ComponentTiFlash:
+		return s.ServerConfigs.TiFlash
+	case ComponentPump:
+		return s.ServerConfigs.Pump
+	case ComponentDrainer:
+		return s.ServerConfigs.Drainer
+	case ComponentCDC:
+		return s.ServerConfigs.CDC
+	}
+	return nil
+}
+
+// InstanceConfigs returns the flattened config of the instance, i.e., the
+// config in server_configs overridden by the one of the instance, and the
+// other flattened fields of the spec of the instance. The keys are joined
+// by dots, e.g., "raftstore.sync-log".
+func InstanceConfigs(topo Topology, inst Instance) (config map[string]interface{}, fields map[string]interface{}, err error) {
+	var global, local map[string]interface{}
+	if t, ok := topo.(interface {
+		GlobalServerConfig(comp string) map[string]interface{}
+	}); ok {
+		global = t.GlobalServerConfig(inst.ComponentName())
+	}
+
+	fields = make(map[string]interface{})
+	if v := reflect.ValueOf(inst); v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct {
+		if is := v.Elem().FieldByName("InstanceSpec"); is.IsValid() && !is.IsNil() {
+			data, err := yaml.Marshal(is.Interface())
+			if err != nil {
+				return nil, nil, perrs.AddStack(err)
+			}
+			var m map[string]interface{}
+			if err := yaml.Unmarshal(data, &m); err != nil {
+				return nil, nil, perrs.AddStack(err)
+			}
+			if c, ok := strKeyMap(m["config"]).(map[string]interface{}); ok {
+				local = c
+			}
+			delete(m, "config")
+			flattenConfig("", strKeyMap(m), fields)
+		}
+	}
+
+	merged, err := merge(global, local)
+	if err != nil {
+		return nil, nil, perrs.AddStack(err)
+	}
+	config = make(map[string]interface{})
+	flattenConfig("", merged, config)
+	return config, fields, nil
+}
+
+// flattenConfig flattens the nested maps of val into out with the keys
+// joined by dots, the other values are leaves
+func flattenConfig(prefix string, val interface{}, out map[string]interface{}) {
+	m, ok := val.(map[string]interface{})
+	if !ok {
+		if prefix != "" {
+			out[prefix] = val
+		}
+		return
+	}
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		flattenConfig(key, v, out)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+var (
+	// ErrConfigConflict is returned when a config on the host was pushed by
+	// another operation after the config pushed was rendered
+	ErrConfigConflict = errNS.NewType("config_conflict")
+)
+
+// the prefix of the header comment stamped on the configs pushed to hosts
+const configHeaderPrefix = "# tiup-config:"
+
+// ConfigHeader is the header comment of a config pushed to a host, it tells
+// which cluster and generation the config is rendered for, and the sha256
+// hash of the config without the header
+type ConfigHeader struct {
+	Cluster    string
+	Generation uint64
+	Hash       string
+}
+
+// String implements the fmt.Stringer interface, it's the header line
+func (h ConfigHeader) String() string {
+	return fmt.Sprintf("%s cluster=%s generation=%d hash=%s", configHeaderPrefix, h.Cluster, h.Generation, h.Hash)
+}
+
+// ParseConfigHeader parses the header of a config, which is the first line or
+// the second one following a shebang, it's false if there is no header
+func ParseConfigHeader(content []byte) (ConfigHeader, bool) {
+	lines := strings.SplitN(string(content), "\n", 3)
+	for i, line := range lines {
+		if i >= 2 {
+			break
+		}
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, configHeaderPrefix) {
+			continue
+		}
+		var h ConfigHeader
+		for _, field := range strings.Fields(strings.TrimPrefix(line, configHeaderPrefix)) {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch kv[0] {
+			case "cluster":
+				h.Cluster = kv[1]
+			case "generation":
+				gen, err := strconv.ParseUint(kv[1], 10, 64)
+				if err != nil {
+					return ConfigHeader{}, false
+				}
+				h.Generation = gen
+			case "hash":
+				h.Hash = kv[1]
+			}
+		}
+		return h, h.Cluster != "" && h.Hash != ""
+	}
+	return ConfigHeader{}, false
+}
+
+// stampConfig adds the header to the config, following the shebang if any
+func stampConfig(content []byte, h ConfigHeader) []byte {
+	header := []byte(h.String() + "\n")
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return append(header, content...)
+	}
+	idx := bytes.IndexByte(content, '\n')
+	if idx < 0 {
+		return append(append(content, '\n'), header...)
+	}
+	stamped := append([]byte{}, content[:idx+1]...)
+	stamped = append(stamped, header...)
+	return append(stamped, content[idx+1:]...)
+}
+
+// stampable checks if the config pushed to the path takes # comments, the
+// other files, e.g., the JSON dashboards, are pushed as they are
+func stampable(path string) bool {
+	switch filepath.Ext(path) {
+	case ".toml", ".yml", ".yaml", ".sh", ".service", ".ini", ".conf", ".properties":
+		return true
+	}
+	return false
+}
+
+// ConfigStamper stamps the configs pushed by an operation with the generation
+// after the one of the cluster metadata the configs are rendered from, and
+// refuses to overwrite the configs pushed by a concurrent operation, which
+// have a newer generation than the one rendered from and a different content.
+type ConfigStamper struct {
+	cluster string
+	base    uint64 // the generation the configs are rendered from
+	force   bool   // overwrite the configs pushed by other operations
+
+	mu     sync.Mutex
+	pushed map[string]bool // the host:path of the configs pushed by the operation
+}
+
+// NewConfigStamper returns a ConfigStamper for an operation on the cluster
+// rendering configs from the metadata of the generation base
+func NewConfigStamper(cluster string, base uint64, force bool) *ConfigStamper {
+	return &ConfigStamper{
+		cluster: cluster,
+		base:    base,
+		force:   force,
+		pushed:  make(map[string]bool),
+	}
+}
+
+// Generation returns the generation the configs are stamped with, which is
+// saved to the metadata if the operation succeeds
+func (s *ConfigStamper) Generation() uint64 {
+	return s.base + 1
+}
+
+// Pushed checks if any config is pushed by the operation
+func (s *ConfigStamper) Pushed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pushed) > 0
+}
+
+// Wrap returns an executor of the host stamping the configs transferred by e
+func (s *ConfigStamper) Wrap(e executor.Executor, host string) executor.Executor {
+	return &stampExecutor{Executor: e, stamper: s, host: host}
+}
+
+// check refuses to overwrite the config dst on the host with content if it's
+// pushed by another operation after the configs are rendered
+func (s *ConfigStamper) check(e executor.Executor, host, dst string, content []byte) error {
+	s.mu.Lock()
+	pushed := s.pushed[host+":"+dst]
+	s.mu.Unlock()
+	if s.force || pushed {
+		return nil
+	}
+
+	remote, err := remoteConfigHeader(e, dst)
+	if err != nil {
+		return err
+	}
+	if remote == nil || remote.Cluster != s.cluster || remote.Generation <= s.base {
+		return nil
+	}
+	hash, err := utils.SHA256(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	if remote.Hash == hash {
+		return nil
+	}
+	return ErrConfigConflict.New("%s on %s was pushed by another operation of generation %d, newer than the generation %d the config is rendered from",
+		dst, host, remote.Generation, s.base).
+		WithProperty(cliutil.SuggestionFromString(
+			"Another operation may be changing the configs of the cluster, wait for it to finish and retry,\nor overwrite the configs by --overwrite-config."))
+}
+
+// remoteConfigHeader returns the header of the config on the host, it's nil
+// if the config doesn't exist or has no header
+func remoteConfigHeader(e executor.Executor, path string) (*ConfigHeader, error) {
+	stdout, _, err := e.Execute(fmt.Sprintf("head -n 2 %s 2>/dev/null || true", path), true)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to read the header of %s", path)
+	}
+	h, ok := ParseConfigHeader(stdout)
+	if !ok {
+		return nil, nil
+	}
+	return &h, nil
+}
+
+// CheckConfigConflict checks if the config src can be pushed to dst by e,
+// for the configs transferred to a temporary path and moved to dst, e.g.,
+// the systemd units. It's always nil if e doesn't stamp the configs.
+func CheckConfigConflict(e executor.Executor, src, dst string) error {
+	if d, ok := e.(*DiffExecutor); ok {
+		e = d.Executor
+	}
+	s, ok := e.(*stampExecutor)
+	if !ok {
+		return nil
+	}
+	content, err := ioutil.ReadFile(src)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	return s.stamper.check(s.Executor, s.host, dst, content)
+}
+
+// stampExecutor stamps the configs transferred to the host with the header
+type stampExecutor struct {
+	executor.Executor
+	stamper *ConfigStamper
+	host    string
+}
+
+// Transfer implements executor.Executor
+func (s *stampExecutor) Transfer(src, dst string, download bool) error {
+	if download || !stampable(dst) {
+		return s.Executor.Transfer(src, dst, download)
+	}
+	st, err := os.Stat(src)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	content, err := ioutil.ReadFile(src)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	if err := s.stamper.check(s.Executor, s.host, dst, content); err != nil {
+		return err
+	}
+	hash, err := utils.SHA256(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile("", "stamped-*"+filepath.Ext(dst))
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer os.Remove(f.Name())
+	h := ConfigHeader{Cluster: s.stamper.cluster, Generation: s.stamper.Generation(), Hash: hash}
+	if _, err := f.Write(stampConfig(content, h)); err != nil {
+		f.Close()
+		return errors.AddStack(err)
+	}
+	if err := f.Close(); err != nil {
+		return errors.AddStack(err)
+	}
+	if err := os.Chmod(f.Name(), st.Mode()); err != nil {
+		return errors.AddStack(err)
+	}
+	if err := s.Executor.Transfer(f.Name(), dst, false); err != nil {
+		return err
+	}
+	s.stamper.mu.Lock()
+	s.stamper.pushed[s.host+":"+dst] = true
+	s.stamper.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+type configStampSuite struct{}
+
+var _ = Suite(&configStampSuite{})
+
+func (s *configStampSuite) TestHeader(c *C) {
+	h := ConfigHeader{Cluster: "prod", Generation: 7, Hash: "abc"}
+	stamped := stampConfig([]byte("[log]\nlevel = \"info\"\n"), h)
+	parsed, ok := ParseConfigHeader(stamped)
+	c.Assert(ok, IsTrue)
+	c.Assert(parsed, DeepEquals, h)
+	c.Assert(string(stamped), Equals, h.String()+"\n[log]\nlevel = \"info\"\n")
+
+	// the header follows the shebang of scripts
+	stamped = stampConfig([]byte("#!/bin/bash\nexec bin/tikv-server\n"), h)
+	c.Assert(string(stamped), Equals, "#!/bin/bash\n"+h.String()+"\nexec bin/tikv-server\n")
+	parsed, ok = ParseConfigHeader(stamped)
+	c.Assert(ok, IsTrue)
+	c.Assert(parsed, DeepEquals, h)
+
+	_, ok = ParseConfigHeader([]byte("#!/bin/bash\nexec bin/tikv-server\n"))
+	c.Assert(ok, IsFalse)
+	_, ok = ParseConfigHeader([]byte("# tiup-config: cluster=prod generation=x hash=abc\n"))
+	c.Assert(ok, IsFalse)
+
+	c.Assert(stampable("/home/tidb/deploy/tikv-20160/conf/tikv.toml"), IsTrue)
+	c.Assert(stampable("/etc/systemd/system/tikv-20160.service"), IsTrue)
+	c.Assert(stampable("/home/tidb/deploy/grafana-3000/dashboards/tidb.json"), IsFalse)
+}
+
+func (s *configStampSuite) TestConflict(c *C) {
+	dir, err := ioutil.TempDir("", "config-stamp")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "tikv.toml")
+	c.Assert(ioutil.WriteFile(src, []byte("[log]\n"), 0644), IsNil)
+	hash, err := utils.SHA256(bytes.NewReader([]byte("[log]\n")))
+	c.Assert(err, IsNil)
+	conf := "/home/tidb/deploy/tikv-20160/conf/tikv.toml"
+	remote := func(fake *executor.Fake, h ConfigHeader) {
+		fake.Respond("head -n 2 "+conf, h.String()+"\n[log]\n", "")
+	}
+
+	// the configs are stamped with the next generation
+	fake := executor.NewFake("172.16.5.1")
+	stamper := NewConfigStamper("prod", 3, false)
+	c.Assert(stamper.Pushed(), IsFalse)
+	c.Assert(stamper.Wrap(fake, "172.16.5.1").Transfer(src, conf, false), IsNil)
+	c.Assert(stamper.Pushed(), IsTrue)
+	c.Assert(stamper.Generation(), Equals, uint64(4))
+
+	// the configs pushed by older operations, other clusters, or with the
+	// same contents are overwritten
+	for _, h := range []ConfigHeader{
+		{Cluster: "prod", Generation: 3, Hash: "other"},
+		{Cluster: "test", Generation: 9, Hash: "other"},
+		{Cluster: "prod", Generation: 9, Hash: hash},
+	} {
+		fake = executor.NewFake("172.16.5.1")
+		remote(fake, h)
+		c.Assert(NewConfigStamper("prod", 3, false).Wrap(fake, "172.16.5.1").Transfer(src, conf, false), IsNil)
+	}
+
+	// the configs pushed by a concurrent operation are not
+	fake = executor.NewFake("172.16.5.1")
+	remote(fake, ConfigHeader{Cluster: "prod", Generation: 4, Hash: "other"})
+	err = NewConfigStamper("prod", 3, false).Wrap(fake, "172.16.5.1").Transfer(src, conf, false)
+	c.Assert(errorx.IsOfType(err, ErrConfigConflict), IsTrue)
+	err = CheckConfigConflict(&DiffExecutor{Executor: NewConfigStamper("prod", 3, false).Wrap(fake, "172.16.5.1")}, src, conf)
+	c.Assert(errorx.IsOfType(err, ErrConfigConflict), IsTrue)
+	c.Assert(CheckConfigConflict(fake, src, conf), IsNil)
+
+	// unless forced, or pushed by the operation itself
+	c.Assert(NewConfigStamper("prod", 3, true).Wrap(fake, "172.16.5.1").Transfer(src, conf, false), IsNil)
+	stamper = NewConfigStamper("prod", 4, false)
+	e := stamper.Wrap(fake, "172.16.5.1")
+	c.Assert(e.Transfer(src, conf, false), IsNil)
+	remote(fake, ConfigHeader{Cluster: "prod", Generation: 5, Hash: "other"})
+	c.Assert(e.Transfer(src, conf, false), IsNil)
+
+	// the stamped files are compared by the hashes in their headers
+	fake = executor.NewFake("172.16.5.1")
+	remote(fake, ConfigHeader{Cluster: "prod", Generation: 2, Hash: hash})
+	same, err := sameContent(fake, src, conf, false)
+	c.Assert(err, IsNil)
+	c.Assert(same, IsTrue)
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// SetDataDir sets the data_dir of the instance in the topology, the instance
+// is matched by its component, host and port
+func (s *Specification) SetDataDir(inst Instance, dir string) error {
+	var is reflect.Value
+	if v := reflect.ValueOf(inst); v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct {
+		is = v.Elem().FieldByName("InstanceSpec")
+	}
+	if !is.IsValid() || is.IsNil() {
+		return errors.Errorf("the spec of instance %s is unknown", inst.ID())
+	}
+	specType := is.Elem().Type()
+
+	topoSpec := reflect.ValueOf(s).Elem()
+	for i := 0; i < topoSpec.NumField(); i++ {
+		compSpecs := topoSpec.Field(i)
+		if isSkipField(compSpecs) || compSpecs.Kind() != reflect.Slice || compSpecs.Type().Elem() != specType {
+			continue
+		}
+		for index := 0; index < compSpecs.Len(); index++ {
+			compSpec := compSpecs.Index(index)
+			if compSpec.FieldByName("Host").String() != inst.GetHost() || !hasPort(compSpec, inst.GetPort()) {
+				continue
+			}
+			dataDir := compSpec.FieldByName("DataDir")
+			if !dataDir.IsValid() || dataDir.Kind() != reflect.String {
+				return errors.Errorf("instance %s has no data_dir", inst.ID())
+			}
+			dataDir.SetString(dir)
+			return nil
+		}
+	}
+	return errors.Errorf("instance %s is not found in the topology", inst.ID())
+}
+
+// hasPort checks if any of the ports of the spec of an instance is port
+func hasPort(compSpec reflect.Value, port int) bool {
+	for i := 0; i < compSpec.NumField(); i++ {
+		field := compSpec.Type().Field(i)
+		if !strings.HasSuffix(field.Name, "Port") || field.Name == "SSHPort" || field.Type.Kind() != reflect.Int {
+			continue
+		}
+		if compSpec.Field(i).Int() == int64(port) {
+			return true
+		}
+	}
+	return false
+}
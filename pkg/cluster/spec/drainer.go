@@ -117,7 +117,7 @@ func (i *DrainerInstance) ScaleConfig(e executor.Executor, topo Topology, cluste
 
 // InitConfig implements Instance interface.
 func (i *DrainerInstance) InitConfig(e executor.Executor, clusterName, clusterVersion, deployUser string, paths meta.DirPaths) error {
-	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, deployUser, paths); err != nil {
+	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, clusterName, clusterVersion, deployUser, paths); err != nil {
 		return err
 	}
 
@@ -134,7 +134,7 @@ func (i *DrainerInstance) InitConfig(e executor.Executor, clusterName, clusterVe
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_drainer_%s_%d.sh", i.GetHost(), i.GetPort()))
 
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := RenderTemplate(cfg, clusterName, clusterVersion, ComponentDrainer, "run_drainer.sh.tpl", fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "scripts", "run_drainer.sh")
@@ -34,6 +34,41 @@ type GrafanaSpec struct {
 	ResourceControl meta.ResourceControl `yaml:"resource_control,omitempty" validate:"resource_control:editable"`
 	Arch            string               `yaml:"arch,omitempty"`
 	OS              string               `yaml:"os,omitempty"`
+	// how the dashboards and the datasource are provisioned, "file" or "api"
+	Provisioning string `yaml:"provisioning,omitempty" default:"file" enum:"file,api"`
+	// the admin credentials for the HTTP API, secret references are supported
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+}
+
+// the ways the dashboards and the datasource of Grafana are provisioned
+const (
+	GrafanaProvisioningFile = "file" // copied to the provisioning dir of Grafana
+	GrafanaProvisioningAPI  = "api"  // upserted via the HTTP API of Grafana
+)
+
+// APIProvisioning returns whether the dashboards and the datasource are
+// provisioned via the HTTP API
+func (s GrafanaSpec) APIProvisioning() bool {
+	return s.Provisioning == GrafanaProvisioningAPI
+}
+
+// Credentials returns the admin credentials for the HTTP API with the secret
+// references resolved, the default ones of Grafana are returned if not set
+func (s GrafanaSpec) Credentials() (string, string, error) {
+	user, password := "admin", "admin"
+	var err error
+	if s.Username != "" {
+		if user, err = resolveSecretString(s.Username); err != nil {
+			return "", "", ErrSecretResolveFailed.Wrap(err, "Failed to resolve the username of grafana %s:%d", s.Host, s.Port)
+		}
+	}
+	if s.Password != "" {
+		if password, err = resolveSecretString(s.Password); err != nil {
+			return "", "", ErrSecretResolveFailed.Wrap(err, "Failed to resolve the password of grafana %s:%d", s.Host, s.Port)
+		}
+	}
+	return user, password, nil
 }
 
 // Role returns the component role of the instance
@@ -100,14 +135,15 @@ type GrafanaInstance struct {
 
 // InitConfig implement Instance interface
 func (i *GrafanaInstance) InitConfig(e executor.Executor, clusterName, clusterVersion, deployUser string, paths meta.DirPaths) error {
-	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, deployUser, paths); err != nil {
+	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, clusterName, clusterVersion, deployUser, paths); err != nil {
 		return err
 	}
 
 	// transfer run script
-	cfg := scripts.NewGrafanaScript(clusterName, paths.Deploy)
+	spec := i.InstanceSpec.(GrafanaSpec)
+	cfg := scripts.NewGrafanaScript(clusterName, paths.Deploy).WithProvisionFiles(!spec.APIProvisioning())
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_grafana_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := RenderTemplate(cfg, clusterName, clusterVersion, ComponentGrafana, "run_grafana.sh.tpl", fp); err != nil {
 		return err
 	}
 
@@ -122,7 +158,8 @@ func (i *GrafanaInstance) InitConfig(e executor.Executor, clusterName, clusterVe
 
 	// transfer config
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("grafana_%s.ini", i.GetHost()))
-	if err := config.NewGrafanaConfig(i.GetHost(), paths.Deploy).WithPort(uint64(i.GetPort())).ConfigToFile(fp); err != nil {
+	grafanaCfg := config.NewGrafanaConfig(i.GetHost(), paths.Deploy).WithPort(uint64(i.GetPort()))
+	if err := RenderTemplate(grafanaCfg, clusterName, clusterVersion, ComponentGrafana, "grafana.ini.tpl", fp); err != nil {
 		return err
 	}
 	dst = filepath.Join(paths.Deploy, "conf", "grafana.ini")
@@ -132,7 +169,8 @@ func (i *GrafanaInstance) InitConfig(e executor.Executor, clusterName, clusterVe
 
 	// transfer dashboard.yml
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("dashboard_%s.yml", i.GetHost()))
-	if err := config.NewDashboardConfig(clusterName, paths.Deploy).ConfigToFile(fp); err != nil {
+	dashboardCfg := config.NewDashboardConfig(clusterName, paths.Deploy)
+	if err := RenderTemplate(dashboardCfg, clusterName, clusterVersion, ComponentGrafana, "dashboard.yml.tpl", fp); err != nil {
 		return err
 	}
 	dst = filepath.Join(paths.Deploy, "conf", "dashboard.yml")
@@ -145,9 +183,9 @@ func (i *GrafanaInstance) InitConfig(e executor.Executor, clusterName, clusterVe
 		return errors.New("no prometheus found in topology")
 	}
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("datasource_%s.yml", i.GetHost()))
-	if err := config.NewDatasourceConfig(clusterName, i.topo.Monitors[0].Host).
-		WithPort(uint64(i.topo.Monitors[0].Port)).
-		ConfigToFile(fp); err != nil {
+	datasourceCfg := config.NewDatasourceConfig(clusterName, i.topo.Monitors[0].Host).
+		WithPort(uint64(i.topo.Monitors[0].Port))
+	if err := RenderTemplate(datasourceCfg, clusterName, clusterVersion, ComponentGrafana, "datasource.yml.tpl", fp); err != nil {
 		return err
 	}
 	dst = filepath.Join(paths.Deploy, "conf", "datasource.yml")
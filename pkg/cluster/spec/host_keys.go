@@ -0,0 +1,136 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	// ErrHostKeyMismatch is returned when a host presents an SSH host key
+	// other than the one recorded in the metadata of the cluster
+	ErrHostKeyMismatch = errNS.NewType("host_key_mismatch")
+	// ErrHostKeyUnavailable is returned when the SSH host key of a host
+	// can't be fetched to be verified
+	ErrHostKeyUnavailable = errNS.NewType("host_key_unavailable")
+	// ErrPropKnownHostKey is the fingerprint recorded of the host
+	ErrPropKnownHostKey = errorx.RegisterPrintableProperty("known_host_key")
+	// ErrPropPresentedHostKey is the fingerprint the host presents
+	ErrPropPresentedHostKey = errorx.RegisterPrintableProperty("presented_host_key")
+)
+
+// HostKeyFetcher returns the SSH host key a host presents, see
+// executor.FetchHostKey
+type HostKeyFetcher func(cfg executor.SSHConfig) (ssh.PublicKey, error)
+
+// hostKeyResult is the result of verifying a host
+type hostKeyResult struct {
+	key ssh.PublicKey
+	err error
+}
+
+// HostKeyVerifier verifies the SSH host keys of the hosts connected by an
+// operation against the fingerprints recorded in the metadata, the ones of
+// the hosts not recorded yet, e.g., deployed or scaled out, are trusted on
+// first use and recorded after the operation. Each host is verified once,
+// and the key verified is pinned for the connections to the host.
+type HostKeyVerifier struct {
+	cluster string
+	known   map[string]string // host -> fingerprint
+	fetch   HostKeyFetcher
+
+	mu       sync.Mutex
+	verified map[string]hostKeyResult
+	recorded map[string]string
+}
+
+// NewHostKeyVerifier creates a verifier of the hosts against the known
+// fingerprints, the keys are fetched by executor.FetchHostKey if fetch is nil
+func NewHostKeyVerifier(cluster string, known map[string]string, fetch HostKeyFetcher) *HostKeyVerifier {
+	if fetch == nil {
+		fetch = executor.FetchHostKey
+	}
+	return &HostKeyVerifier{
+		cluster:  cluster,
+		known:    known,
+		fetch:    fetch,
+		verified: make(map[string]hostKeyResult),
+		recorded: make(map[string]string),
+	}
+}
+
+// Verify checks the host key presented by the host of cfg, and returns it to
+// be pinned for connecting the host, e.g., by executor.SSHConfig.HostKey. An
+// ErrHostKeyMismatch is returned if it's not the one recorded, and an
+// ErrHostKeyUnavailable if it can't be fetched.
+func (v *HostKeyVerifier) Verify(cfg executor.SSHConfig) (ssh.PublicKey, error) {
+	v.mu.Lock()
+	r, ok := v.verified[cfg.Host]
+	v.mu.Unlock()
+	if ok {
+		return r.key, r.err
+	}
+
+	// not locked while fetching, so the hosts are verified concurrently
+	key, err := v.fetch(cfg)
+	if err != nil {
+		// not cached, the host may be reachable on retrying
+		return nil, ErrHostKeyUnavailable.Wrap(err, "Failed to fetch the SSH host key of %s to verify it", cfg.Host)
+	}
+	presented := ssh.FingerprintSHA256(key)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	known, ok := v.known[cfg.Host]
+	switch {
+	case !ok || known == "":
+		v.recorded[cfg.Host] = presented
+	case known != presented:
+		key = nil
+		err = ErrHostKeyMismatch.New("The SSH host key of %s has changed since it was recorded, known %s, presented %s",
+			cfg.Host, known, presented).
+			WithProperty(ErrPropKnownHostKey, known).
+			WithProperty(ErrPropPresentedHostKey, presented).
+			WithProperty(cliutil.SuggestionFromString(fmt.Sprintf(
+				"The host may be replaced, or the connection may be intercepted. If the host is rebuilt intentionally,\naccept its new key by `%s accept-host-key %s %s`.",
+				cliutil.OsArgs0(), v.cluster, cfg.Host)))
+	}
+	v.verified[cfg.Host] = hostKeyResult{key: key, err: err}
+	return key, err
+}
+
+// Verified returns the key of the host verified, nil if the host is not
+// verified yet or its key doesn't match the one recorded
+func (v *HostKeyVerifier) Verified(host string) ssh.PublicKey {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.verified[host].key
+}
+
+// Recorded returns the fingerprints of the hosts trusted on first use
+func (v *HostKeyVerifier) Recorded() map[string]string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	recorded := make(map[string]string, len(v.recorded))
+	for host, fingerprint := range v.recorded {
+		recorded[host] = fingerprint
+	}
+	return recorded
+}
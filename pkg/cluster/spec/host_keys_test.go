@@ -0,0 +1,88 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func newHostKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	key, err := ssh.NewPublicKey(pub)
+	require.Nil(t, err)
+	return key
+}
+
+func TestHostKeyVerifier(t *testing.T) {
+	presented := map[string]ssh.PublicKey{
+		"10.0.1.1": newHostKey(t),
+		"10.0.1.2": newHostKey(t),
+		"10.0.1.3": newHostKey(t),
+	}
+	fetched := 0
+	v := NewHostKeyVerifier("prod", map[string]string{
+		"10.0.1.1": ssh.FingerprintSHA256(presented["10.0.1.1"]),
+		"10.0.1.2": "SHA256:old",
+	}, func(cfg executor.SSHConfig) (ssh.PublicKey, error) {
+		fetched++
+		if key, ok := presented[cfg.Host]; ok {
+			return key, nil
+		}
+		return nil, errors.New("connection refused")
+	})
+
+	// the key verified is returned to be pinned
+	key, err := v.Verify(executor.SSHConfig{Host: "10.0.1.1"})
+	assert.Nil(t, err)
+	assert.Equal(t, presented["10.0.1.1"], key)
+
+	key, err = v.Verify(executor.SSHConfig{Host: "10.0.1.2"})
+	require.NotNil(t, err)
+	assert.Nil(t, key)
+	assert.True(t, errorx.IsOfType(err, ErrHostKeyMismatch))
+	known, _ := errorx.Cast(err).Property(ErrPropKnownHostKey)
+	assert.Equal(t, "SHA256:old", known)
+	got, _ := errorx.Cast(err).Property(ErrPropPresentedHostKey)
+	assert.Equal(t, ssh.FingerprintSHA256(presented["10.0.1.2"]), got)
+
+	// the hosts not recorded are trusted on first use
+	key, err = v.Verify(executor.SSHConfig{Host: "10.0.1.3"})
+	assert.Nil(t, err)
+	assert.Equal(t, presented["10.0.1.3"], key)
+	// the hosts whose keys can't be fetched are refused
+	key, err = v.Verify(executor.SSHConfig{Host: "10.0.1.4"})
+	require.NotNil(t, err)
+	assert.Nil(t, key)
+	assert.True(t, errorx.IsOfType(err, ErrHostKeyUnavailable))
+	assert.Equal(t, map[string]string{"10.0.1.3": ssh.FingerprintSHA256(presented["10.0.1.3"])}, v.Recorded())
+
+	// each host is verified once, the ones failed to be fetched are retried
+	_, err = v.Verify(executor.SSHConfig{Host: "10.0.1.2"})
+	assert.NotNil(t, err)
+	_, err = v.Verify(executor.SSHConfig{Host: "10.0.1.1"})
+	assert.Nil(t, err)
+	_, err = v.Verify(executor.SSHConfig{Host: "10.0.1.4"})
+	assert.NotNil(t, err)
+	assert.Equal(t, 5, fetched)
+}
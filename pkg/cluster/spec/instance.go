@@ -130,18 +130,23 @@ func (i *BaseInstance) Ready(e executor.Executor, timeout int64) error {
 }
 
 // InitConfig init the service configuration.
-func (i *BaseInstance) InitConfig(e executor.Executor, opt GlobalOptions, user string, paths meta.DirPaths) error {
+func (i *BaseInstance) InitConfig(e executor.Executor, opt GlobalOptions, clusterName, clusterVersion, user string, paths meta.DirPaths) error {
 	comp := i.ComponentName()
 	host := i.GetHost()
 	port := i.GetPort()
 	sysCfg := filepath.Join(paths.Cache, fmt.Sprintf("%s-%s-%d.service", comp, host, port))
 
+	envFile, err := SyncProxyEnvFile(e, &opt, host, paths)
+	if err != nil {
+		return err
+	}
 	resource := MergeResourceControl(opt.ResourceControl, i.resourceControl())
 	systemCfg := system.NewConfig(comp, user, paths.Deploy).
 		WithMemoryLimit(resource.MemoryLimit).
 		WithCPUQuota(resource.CPUQuota).
 		WithIOReadBandwidthMax(resource.IOReadBandwidthMax).
-		WithIOWriteBandwidthMax(resource.IOWriteBandwidthMax)
+		WithIOWriteBandwidthMax(resource.IOWriteBandwidthMax).
+		WithEnvironmentFile(envFile)
 
 	// For not auto start if using binlogctl to offline.
 	// bad design
@@ -149,14 +154,21 @@ func (i *BaseInstance) InitConfig(e executor.Executor, opt GlobalOptions, user s
 		systemCfg.Restart = "on-failure"
 	}
 
-	if err := systemCfg.ConfigToFile(sysCfg); err != nil {
+	if err := RenderTemplate(systemCfg, clusterName, clusterVersion, comp, "system.service.tpl", sysCfg); err != nil {
 		return errors.Trace(err)
 	}
-	tgt := filepath.Join("/tmp", comp+"_"+uuid.New().String()+".service")
+	unit := fmt.Sprintf("/etc/systemd/system/%s-%d.service", comp, port)
+	if changed, err := unitChanged(e, sysCfg, unit); err != nil || !changed {
+		return err
+	}
+	if err := CheckConfigConflict(e, sysCfg, unit); err != nil {
+		return err
+	}
+	tgt := filepath.Join(opt.RemoteTmpDirOf(host), comp+"_"+uuid.New().String()+".service")
 	if err := e.Transfer(sysCfg, tgt, false); err != nil {
 		return errors.Annotatef(err, "transfer from %s to %s failed", sysCfg, tgt)
 	}
-	cmd := fmt.Sprintf("mv %s /etc/systemd/system/%s-%d.service", tgt, comp, port)
+	cmd := fmt.Sprintf("mv %s %s", tgt, unit)
 	if _, _, err := e.Execute(cmd, true); err != nil {
 		return errors.Annotatef(err, "execute: %s", cmd)
 	}
@@ -167,7 +179,23 @@ func (i *BaseInstance) InitConfig(e executor.Executor, opt GlobalOptions, user s
 // MergeServerConfig merges the server configuration and overwrite the global configuration
 func (i *BaseInstance) MergeServerConfig(e executor.Executor, globalConf, instanceConf map[string]interface{}, paths meta.DirPaths) error {
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("%s-%s-%d.toml", i.ComponentName(), i.GetHost(), i.GetPort()))
-	conf, err := merge2Toml(i.ComponentName(), globalConf, instanceConf)
+	dst := filepath.Join(paths.Deploy, "conf", fmt.Sprintf("%s.toml", i.ComponentName()))
+	return i.transferServerConfig(e, i.ComponentName(), globalConf, instanceConf, fp, dst)
+}
+
+// mergeTiFlashLearnerServerConfig merges the server configuration and overwrite the global configuration
+func (i *BaseInstance) mergeTiFlashLearnerServerConfig(e executor.Executor, globalConf, instanceConf map[string]interface{}, paths meta.DirPaths) error {
+	fp := filepath.Join(paths.Cache, fmt.Sprintf("%s-learner-%s-%d.toml", i.ComponentName(), i.GetHost(), i.GetPort()))
+	dst := filepath.Join(paths.Deploy, "conf", fmt.Sprintf("%s-learner.toml", i.ComponentName()))
+	return i.transferServerConfig(e, i.ComponentName()+"-learner", globalConf, instanceConf, fp, dst)
+}
+
+// transferServerConfig renders the merged config to the cache file fp and
+// transfers it to dst. The cache file keeps secret references as they are,
+// if there are any, a config with them resolved is rendered to a temporary
+// file which is removed right after the transfer.
+func (i *BaseInstance) transferServerConfig(e executor.Executor, comp string, globalConf, instanceConf map[string]interface{}, fp, dst string) error {
+	conf, err := merge2Toml(comp, globalConf, instanceConf)
 	if err != nil {
 		return err
 	}
@@ -175,25 +203,41 @@ func (i *BaseInstance) MergeServerConfig(e executor.Executor, globalConf, instan
 	if err != nil {
 		return err
 	}
-	dst := filepath.Join(paths.Deploy, "conf", fmt.Sprintf("%s.toml", i.ComponentName()))
-	// transfer config
-	return e.Transfer(fp, dst, false)
-}
 
-// mergeTiFlashLearnerServerConfig merges the server configuration and overwrite the global configuration
-func (i *BaseInstance) mergeTiFlashLearnerServerConfig(e executor.Executor, globalConf, instanceConf map[string]interface{}, paths meta.DirPaths) error {
-	fp := filepath.Join(paths.Cache, fmt.Sprintf("%s-learner-%s-%d.toml", i.ComponentName(), i.GetHost(), i.GetPort()))
-	conf, err := merge2Toml(i.ComponentName()+"-learner", globalConf, instanceConf)
+	wrapError := func(err error) error {
+		return ErrSecretResolveFailed.Wrap(err, "Failed to resolve secrets in config of %s %s", i.ComponentName(), i.ID())
+	}
+	resolvedGlobal, foundGlobal, err := ResolveSecrets(globalConf)
+	if err != nil {
+		return wrapError(err)
+	}
+	resolvedInstance, foundInstance, err := ResolveSecrets(instanceConf)
+	if err != nil {
+		return wrapError(err)
+	}
+	if !foundGlobal && !foundInstance {
+		// transfer config
+		return e.Transfer(fp, dst, false)
+	}
+
+	conf, err = merge2Toml(comp, resolvedGlobal, resolvedInstance)
 	if err != nil {
 		return err
 	}
-	err = ioutil.WriteFile(fp, conf, os.ModePerm)
+	f, err := ioutil.TempFile("", fmt.Sprintf("%s-%s-%d-*.toml", comp, i.GetHost(), i.GetPort()))
 	if err != nil {
 		return err
 	}
-	dst := filepath.Join(paths.Deploy, "conf", fmt.Sprintf("%s-learner.toml", i.ComponentName()))
+	defer os.Remove(f.Name())
+	if _, err := f.Write(conf); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
 	// transfer config
-	return e.Transfer(fp, dst, false)
+	return e.Transfer(f.Name(), dst, false)
 }
 
 // ID returns the identifier of this instance, the ID is constructed by host:port
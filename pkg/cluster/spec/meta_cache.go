@@ -0,0 +1,158 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+)
+
+// metaCacheEntry is the parsed meta file of a cluster, it's never modified
+// after being cached, readers get deep copies of it
+type metaCacheEntry struct {
+	modTime time.Time
+	size    int64
+	meta    Metadata
+	err     error // the error of parsing, e.g., a failed validation
+}
+
+// metaCache caches the parsed meta files of clusters in process, an entry is
+// invalidated when the mtime or size of the meta file changes, or explicitly
+// after the metadata is written
+type metaCache struct {
+	mu      sync.RWMutex
+	entries map[string]*metaCacheEntry
+}
+
+func newMetaCache() *metaCache {
+	return &metaCache{entries: make(map[string]*metaCacheEntry)}
+}
+
+func (c *metaCache) get(name string, info os.FileInfo) (*metaCacheEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[name]
+	if !ok || !entry.modTime.Equal(info.ModTime()) || entry.size != info.Size() {
+		return nil, false
+	}
+	return entry, true
+}
+
+func (c *metaCache) put(name string, entry *metaCacheEntry) {
+	c.mu.Lock()
+	c.entries[name] = entry
+	c.mu.Unlock()
+}
+
+func (c *metaCache) invalidate(name string) {
+	c.mu.Lock()
+	delete(c.entries, name)
+	c.mu.Unlock()
+}
+
+// CachedMetadata returns the metadata of a cluster, the meta file is read and
+// parsed only if it's changed since the last read. The metadata returned is a
+// copy owned by the caller, and the error of parsing it, e.g., a failed
+// validation, is returned along with it just like Metadata does. Use
+// Metadata to bypass the cache.
+func (s *SpecManager) CachedMetadata(clusterName string) (Metadata, error) {
+	// stat before reading, so the entry is never newer than its mtime, a
+	// write between them only causes one more read later
+	info, err := os.Stat(s.Path(clusterName, metaFileName))
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	if entry, ok := s.cache.get(clusterName, info); ok {
+		return copyMetadata(entry.meta), entry.err
+	}
+
+	meta := s.NewMetadata()
+	err = s.Metadata(clusterName, meta)
+	if err != nil && os.IsNotExist(errors.Cause(err)) {
+		return nil, err
+	}
+	s.cache.put(clusterName, &metaCacheEntry{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		meta:    copyMetadata(meta),
+		err:     err,
+	})
+	return meta, err
+}
+
+// InvalidateMetadata drops the cached metadata of a cluster, it's called
+// after the metadata is written or removed
+func (s *SpecManager) InvalidateMetadata(clusterName string) {
+	s.cache.invalidate(clusterName)
+}
+
+// copyMetadata returns a deep copy of the metadata
+func copyMetadata(meta Metadata) Metadata {
+	return deepCopy(reflect.ValueOf(meta)).Interface().(Metadata)
+}
+
+// deepCopy copies the value recursively, the unexported fields of structs
+// are copied shallowly
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Elem().Type())
+		cp.Elem().Set(deepCopy(v.Elem()))
+		return cp
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(deepCopy(v.Elem()))
+		return cp
+	case reflect.Struct:
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if cp.Field(i).CanSet() {
+				cp.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return cp
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return cp
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			cp.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return cp
+	default:
+		return v
+	}
+}
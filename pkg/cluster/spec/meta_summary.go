@@ -0,0 +1,170 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/meta"
+)
+
+// the file the summary of the metadata is cached in, in the cluster dir
+const summaryFileName = "summary.json"
+
+// SummaryOperation is the last operation on a cluster in its summary
+type SummaryOperation struct {
+	Operation string    `json:"operation"`
+	EndTime   time.Time `json:"end_time"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// MetaSummary is the summary of the metadata of a cluster, it's cached in the
+// cluster dir so listing clusters doesn't parse every meta file, which is
+// slow with many clusters on network file systems
+type MetaSummary struct {
+	Name      string            `json:"name"`
+	User      string            `json:"user"`
+	Version   string            `json:"version"`
+	Protected bool              `json:"protected,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	// the count of instances by component
+	Instances     map[string]int    `json:"instances,omitempty"`
+	LastOperation *SummaryOperation `json:"last_operation,omitempty"`
+
+	// the mtime and size of the meta file summarized, the summary is stale
+	// if they are changed, e.g., the meta file is edited by hand
+	MetaModTime time.Time `json:"meta_mod_time"`
+	MetaSize    int64     `json:"meta_size"`
+}
+
+// stale checks if the summary doesn't summarize the meta file of info
+func (s *MetaSummary) stale(info os.FileInfo) bool {
+	return !s.MetaModTime.Equal(info.ModTime()) || s.MetaSize != info.Size()
+}
+
+// Summary returns the summary of the metadata of a cluster, the metadata is
+// parsed only if the summary is missing or stale, and the summary is rebuilt
+// then. The error of validating the metadata doesn't fail the summary.
+func (s *SpecManager) Summary(clusterName string) (*MetaSummary, error) {
+	info, err := os.Stat(s.Path(clusterName, metaFileName))
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	cached := s.readSummary(clusterName)
+	if cached != nil && !cached.stale(info) {
+		return cached, nil
+	}
+
+	metadata, err := s.CachedMetadata(clusterName)
+	if err != nil && !stderrors.Is(errors.Cause(err), meta.ErrValidate) {
+		return nil, err
+	}
+	summary := summarize(clusterName, metadata, info)
+	if cached != nil {
+		summary.LastOperation = cached.LastOperation
+	}
+	// the summary is only a cache, it's rebuilt next time if not saved
+	_ = s.writeSummary(clusterName, summary)
+	return summary, nil
+}
+
+// UpdateSummaryOperation records the last operation on a cluster in its summary
+func (s *SpecManager) UpdateSummaryOperation(clusterName string, op SummaryOperation) error {
+	summary, err := s.Summary(clusterName)
+	if err != nil {
+		return err
+	}
+	summary.LastOperation = &op
+	return s.writeSummary(clusterName, summary)
+}
+
+// saveSummary rebuilds the summary after the metadata is saved
+func (s *SpecManager) saveSummary(clusterName string, metadata Metadata) error {
+	info, err := os.Stat(s.Path(clusterName, metaFileName))
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	summary := summarize(clusterName, metadata, info)
+	if cached := s.readSummary(clusterName); cached != nil {
+		summary.LastOperation = cached.LastOperation
+	}
+	return s.writeSummary(clusterName, summary)
+}
+
+// summarize returns the summary of the metadata read from the meta file of info
+func summarize(clusterName string, metadata Metadata, info os.FileInfo) *MetaSummary {
+	base := metadata.GetBaseMeta()
+	summary := &MetaSummary{
+		Name:        clusterName,
+		User:        base.User,
+		Version:     base.Version,
+		Protected:   base.Protected,
+		Tags:        base.Tags,
+		MetaModTime: info.ModTime(),
+		MetaSize:    info.Size(),
+	}
+	topo := metadata.GetTopology()
+	if topo == nil || reflect.ValueOf(topo).IsNil() {
+		return summary
+	}
+	topo.IterInstance(func(inst Instance) {
+		if summary.Instances == nil {
+			summary.Instances = make(map[string]int)
+		}
+		summary.Instances[inst.ComponentName()]++
+	})
+	return summary
+}
+
+// readSummary reads the summary cached, it's nil if it's missing or broken
+func (s *SpecManager) readSummary(clusterName string) *MetaSummary {
+	data, err := ioutil.ReadFile(s.Path(clusterName, summaryFileName))
+	if err != nil {
+		return nil
+	}
+	var summary MetaSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil
+	}
+	return &summary
+}
+
+// writeSummary writes the summary to a temporary file and renames it, so the
+// summary is never half-written
+func (s *SpecManager) writeSummary(clusterName string, summary *MetaSummary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	fname := s.Path(clusterName, summaryFileName)
+	tmp, err := ioutil.TempFile(s.Path(clusterName), summaryFileName+".")
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	return errors.AddStack(os.Rename(tmp.Name(), fname))
+}
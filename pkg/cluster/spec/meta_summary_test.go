@@ -0,0 +1,157 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func summaryTestMeta(version string, tikvs int) *ClusterMeta {
+	topo := &Specification{}
+	topo.PDServers = append(topo.PDServers, PDSpec{Host: "172.16.5.1", ClientPort: 2379})
+	for i := 0; i < tikvs; i++ {
+		topo.TiKVServers = append(topo.TiKVServers, TiKVSpec{
+			Host: fmt.Sprintf("172.16.5.%d", i+10),
+			Port: 20160,
+		})
+	}
+	return &ClusterMeta{
+		User:     "tidb",
+		Version:  version,
+		Tags:     map[string]string{"team": "payments"},
+		Topology: topo,
+	}
+}
+
+func TestMetaSummary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-*")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	newSpec := func() *SpecManager {
+		return NewSpec(dir, func() Metadata { return new(ClusterMeta) })
+	}
+	spec := newSpec()
+	assert.Nil(t, spec.SaveMeta("c1", summaryTestMeta("v4.0.0", 3)))
+
+	// the summary is written along with the metadata
+	_, err = os.Stat(spec.Path("c1", summaryFileName))
+	assert.Nil(t, err)
+	summary, err := newSpec().Summary("c1")
+	assert.Nil(t, err)
+	assert.Equal(t, "c1", summary.Name)
+	assert.Equal(t, "tidb", summary.User)
+	assert.Equal(t, "v4.0.0", summary.Version)
+	assert.Equal(t, map[string]string{"team": "payments"}, summary.Tags)
+	assert.Equal(t, map[string]int{ComponentPD: 1, ComponentTiKV: 3}, summary.Instances)
+	assert.Nil(t, summary.LastOperation)
+
+	end := time.Now().Round(time.Second)
+	assert.Nil(t, spec.UpdateSummaryOperation("c1", SummaryOperation{
+		Operation: "scale-out",
+		EndTime:   end,
+		Error:     "boom",
+	}))
+	summary, err = newSpec().Summary("c1")
+	assert.Nil(t, err)
+	assert.Equal(t, "scale-out", summary.LastOperation.Operation)
+	assert.True(t, end.Equal(summary.LastOperation.EndTime))
+	assert.Equal(t, "boom", summary.LastOperation.Error)
+
+	// the summary is rebuilt if the meta file is changed behind it, and the
+	// last operation is kept
+	other := newSpec()
+	assert.Nil(t, other.SaveMeta("c2", summaryTestMeta("v4.0.1", 5)))
+	data, err := ioutil.ReadFile(other.Path("c2", metaFileName))
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(spec.Path("c1", metaFileName), data, 0644))
+	future := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(spec.Path("c1", metaFileName), future, future))
+	summary, err = newSpec().Summary("c1")
+	assert.Nil(t, err)
+	assert.Equal(t, "v4.0.1", summary.Version)
+	assert.Equal(t, 5, summary.Instances[ComponentTiKV])
+	assert.Equal(t, "scale-out", summary.LastOperation.Operation)
+
+	// a missing or broken summary is rebuilt
+	assert.Nil(t, os.Remove(spec.Path("c2", summaryFileName)))
+	summary, err = newSpec().Summary("c2")
+	assert.Nil(t, err)
+	assert.Equal(t, "v4.0.1", summary.Version)
+	_, err = os.Stat(spec.Path("c2", summaryFileName))
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(spec.Path("c2", summaryFileName), []byte("{"), 0644))
+	summary, err = newSpec().Summary("c2")
+	assert.Nil(t, err)
+	assert.Equal(t, "v4.0.1", summary.Version)
+
+	_, err = newSpec().Summary("not-exist")
+	assert.NotNil(t, err)
+}
+
+// BenchmarkListClusters compares listing clusters by parsing the metadata
+// with reading the summaries, each iteration uses a new SpecManager like
+// each run of the command does
+func BenchmarkListClusters(b *testing.B) {
+	dir, err := ioutil.TempDir("", "test-*")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	newSpec := func() *SpecManager {
+		return NewSpec(dir, func() Metadata { return new(ClusterMeta) })
+	}
+	spec := newSpec()
+	for i := 0; i < 50; i++ {
+		if err := spec.SaveMeta(fmt.Sprintf("cluster-%d", i), summaryTestMeta("v4.0.0", 30)); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("metadata", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			spec := newSpec()
+			names, err := spec.List()
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, name := range names {
+				if _, err := spec.CachedMetadata(name); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("summary", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			spec := newSpec()
+			names, err := spec.List()
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, name := range names {
+				if _, err := spec.Summary(name); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
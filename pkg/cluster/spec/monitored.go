@@ -0,0 +1,92 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+)
+
+// host returns the customization of the monitoring agents of the host, nil
+// if it's not customized
+func (m *MonitoredOptions) host(host string) *MonitoredHost {
+	for i := range m.Hosts {
+		if m.Hosts[i].Host == host {
+			return &m.Hosts[i]
+		}
+	}
+	return nil
+}
+
+// DeployAgents returns whether the node_exporter and blackbox_exporter are
+// deployed on the host
+func (m *MonitoredOptions) DeployAgents(host string) bool {
+	h := m.host(host)
+	if h == nil {
+		return true
+	}
+	if h.MonitorAgents != nil {
+		return *h.MonitorAgents
+	}
+	return h.ExternalExporterPort == 0
+}
+
+// ExporterPort returns the port of the node_exporter scraped on the host, it's
+// the external one if it's set, and 0 if there is no node_exporter on the host
+func (m *MonitoredOptions) ExporterPort(host string) int {
+	if h := m.host(host); h != nil && h.ExternalExporterPort > 0 {
+		return h.ExternalExporterPort
+	}
+	if m.DeployAgents(host) {
+		return m.NodeExporterPort
+	}
+	return 0
+}
+
+// UnmonitoredHosts returns the hosts of the topology without the agents
+// deployed or an external node_exporter, their metrics are not collected
+func (m *MonitoredOptions) UnmonitoredHosts(topo Topology) []string {
+	var hosts []string
+	seen := make(map[string]struct{})
+	topo.IterInstance(func(inst Instance) {
+		if _, ok := seen[inst.GetHost()]; ok {
+			return
+		}
+		seen[inst.GetHost()] = struct{}{}
+		if m.ExporterPort(inst.GetHost()) == 0 {
+			hosts = append(hosts, inst.GetHost())
+		}
+	})
+	return hosts
+}
+
+// validateHosts checks the customizations of hosts
+func (m *MonitoredOptions) validateHosts() error {
+	seen := make(map[string]struct{})
+	for _, h := range m.Hosts {
+		if h.Host == "" {
+			return fmt.Errorf("`monitored.hosts` contains empty host field")
+		}
+		if _, ok := seen[h.Host]; ok {
+			return fmt.Errorf("monitored host %s is customized more than once", h.Host)
+		}
+		seen[h.Host] = struct{}{}
+		if h.ExternalExporterPort < 0 || h.ExternalExporterPort > 65535 {
+			return fmt.Errorf("invalid external_exporter_port %d of monitored host %s", h.ExternalExporterPort, h.Host)
+		}
+		if h.MonitorAgents != nil && *h.MonitorAgents && h.ExternalExporterPort > 0 {
+			return fmt.Errorf("monitored host %s can't have both monitor_agents and external_exporter_port set, the agents deployed conflict with the external node_exporter", h.Host)
+		}
+	}
+	return nil
+}
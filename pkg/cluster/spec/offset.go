@@ -0,0 +1,190 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/tiup/pkg/meta"
+)
+
+// AutoOffsetStep is the distance between the ports derived for two instances
+// of the same component on the same host
+const AutoOffsetStep = 100
+
+// derivedPort is a port derived by auto offset
+type derivedPort struct {
+	cfg   string // the yaml name of the component section
+	host  string
+	tp    string // the yaml name of the port field
+	port  int
+	index int // the index of the instance on its host
+}
+
+// isAutoOffsetPort returns whether the field is a port which can be derived
+func isAutoOffsetPort(field reflect.StructField) bool {
+	if !strings.HasSuffix(field.Name, "Port") || field.Name == "SSHPort" {
+		return false
+	}
+	_, found := field.Tag.Lookup("default")
+	return found && field.Type.Kind() == reflect.Int
+}
+
+// countInstancesOnHost counts the instances of each component section on each host
+func countInstancesOnHost(s *Specification) map[string]int {
+	counts := make(map[string]int)
+	if s == nil {
+		return counts
+	}
+	topoSpec := reflect.ValueOf(s).Elem()
+	for i := 0; i < topoSpec.NumField(); i++ {
+		if isSkipField(topoSpec.Field(i)) {
+			continue
+		}
+		compSpecs := topoSpec.Field(i)
+		for index := 0; index < compSpecs.Len(); index++ {
+			host := compSpecs.Index(index).FieldByName("Host").String()
+			counts[fmt.Sprintf("%d:%s", i, host)]++
+		}
+	}
+	return counts
+}
+
+// autoOffsetPorts derives the omitted ports of instances which share a host
+// with other instances of the same component. The n-th instance on a host
+// (counted from 0) gets the default ports plus n*AutoOffsetStep, and as the
+// default deploy and data directories are named after the main port, they
+// are derived from the offset ports as well. Instances of the base topology
+// are counted first, so instances added by scale-out don't collide with them.
+// It must be called before the default values are set, as a zero port means
+// it is omitted. It's only enabled by auto_offset_ports of the global options,
+// the ports of the other topologies are never changed.
+func (s *Specification) autoOffsetPorts() ([]derivedPort, error) {
+	var derived []derivedPort
+
+	baseCounts := countInstancesOnHost(s.base)
+	counts := countInstancesOnHost(s)
+
+	topoSpec := reflect.ValueOf(s).Elem()
+	topoType := topoSpec.Type()
+	indexes := make(map[string]int)
+	for i := 0; i < topoSpec.NumField(); i++ {
+		if isSkipField(topoSpec.Field(i)) {
+			continue
+		}
+		cfg := strings.Split(topoType.Field(i).Tag.Get("yaml"), ",")[0]
+		compSpecs := topoSpec.Field(i)
+		for index := 0; index < compSpecs.Len(); index++ {
+			compSpec := compSpecs.Index(index)
+			host := compSpec.FieldByName("Host").String()
+			key := fmt.Sprintf("%d:%s", i, host)
+			if baseCounts[key]+counts[key] < 2 {
+				continue
+			}
+			n := baseCounts[key] + indexes[key]
+			indexes[key]++
+			if n == 0 {
+				continue
+			}
+
+			for j := 0; j < compSpec.NumField(); j++ {
+				field := compSpec.Type().Field(j)
+				if !isAutoOffsetPort(field) || compSpec.Field(j).Int() != 0 {
+					continue
+				}
+				base, err := strconv.Atoi(field.Tag.Get("default"))
+				if err != nil {
+					return nil, err
+				}
+				port := base + n*AutoOffsetStep
+				compSpec.Field(j).SetInt(int64(port))
+				derived = append(derived, derivedPort{
+					cfg:   cfg,
+					host:  host,
+					tp:    strings.Split(field.Tag.Get("yaml"), ",")[0],
+					port:  port,
+					index: n,
+				})
+			}
+		}
+	}
+
+	return derived, nil
+}
+
+// validateDerivedPorts confirms the derived ports don't collide with ports of
+// other instances on the same host, including the ones of the base topology.
+// It must be called after the default values are set.
+func (s *Specification) validateDerivedPorts(derived []derivedPort) error {
+	if len(derived) == 0 {
+		return nil
+	}
+
+	type usedPort struct {
+		host string
+		port int
+	}
+	owners := make(map[usedPort][]string)
+	for _, topo := range []*Specification{s.base, s} {
+		if topo == nil {
+			continue
+		}
+		topoSpec := reflect.ValueOf(topo).Elem()
+		topoType := topoSpec.Type()
+		for i := 0; i < topoSpec.NumField(); i++ {
+			if isSkipField(topoSpec.Field(i)) {
+				continue
+			}
+			cfg := strings.Split(topoType.Field(i).Tag.Get("yaml"), ",")[0]
+			compSpecs := topoSpec.Field(i)
+			for index := 0; index < compSpecs.Len(); index++ {
+				compSpec := compSpecs.Index(index)
+				host := compSpec.FieldByName("Host").String()
+				for j := 0; j < compSpec.NumField(); j++ {
+					field := compSpec.Type().Field(j)
+					if !strings.HasSuffix(field.Name, "Port") || field.Name == "SSHPort" || field.Type.Kind() != reflect.Int {
+						continue
+					}
+					item := usedPort{host: host, port: int(compSpec.Field(j).Int())}
+					tp := strings.Split(field.Tag.Get("yaml"), ",")[0]
+					owners[item] = append(owners[item], fmt.Sprintf("%s:%s.%s", cfg, host, tp))
+				}
+			}
+		}
+	}
+
+	for _, d := range derived {
+		// the derived port itself is one of the owners
+		list := owners[usedPort{host: d.host, port: d.port}]
+		if len(list) < 2 {
+			continue
+		}
+		self := fmt.Sprintf("%s:%s.%s", d.cfg, d.host, d.tp)
+		lhs := list[0]
+		if lhs == self {
+			lhs = list[1]
+		}
+		return &meta.ValidateErr{
+			Type:   meta.TypeConflict,
+			Target: "port",
+			LHS:    lhs,
+			RHS:    fmt.Sprintf("%s (derived by auto offset for instance #%d on the host)", self, d.index),
+			Value:  d.port,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/pingcap/errors"
+)
+
+// the dirs the drop-in files of OS settings are rendered to
+const (
+	SysctlDropInDir   = "/etc/sysctl.d"
+	LimitsDropInDir   = "/etc/security/limits.d"
+	TmpfilesDropInDir = "/etc/tmpfiles.d"
+	// THPEnabledPath is the file controlling transparent hugepages
+	THPEnabledPath = "/sys/kernel/mm/transparent_hugepage/enabled"
+)
+
+var (
+	sysctlKeyRegexp  = regexp.MustCompile(`^[a-zA-Z0-9_.\-/]+$`)
+	limitFieldRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.\-@*%:]+$`)
+	// the values of sysctl may contain spaces, e.g., net.ipv4.ip_local_port_range
+	sysctlValueRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.\-/:, \t]+$`)
+)
+
+type (
+	// OSSettings are the kernel parameters, limits and transparent hugepage
+	// setting managed on all hosts of the cluster
+	OSSettings struct {
+		Sysctl              map[string]string `yaml:"sysctl,omitempty"`
+		Limits              []LimitEntry      `yaml:"limits,omitempty"`
+		TransparentHugepage string            `yaml:"transparent_hugepage,omitempty" enum:"always,madvise,never"`
+	}
+
+	// LimitEntry is an entry of limits.conf
+	LimitEntry struct {
+		Domain string `yaml:"domain"` // user, @group or *
+		Type   string `yaml:"type" enum:"soft,hard,-"`
+		Item   string `yaml:"item"`
+		Value  string `yaml:"value"`
+	}
+)
+
+// Validate checks the OS settings can be rendered safely
+func (s *OSSettings) Validate() error {
+	for key, val := range s.Sysctl {
+		if !sysctlKeyRegexp.MatchString(key) {
+			return errors.Errorf("invalid sysctl key '%s' in os_settings", key)
+		}
+		if !sysctlValueRegexp.MatchString(val) {
+			return errors.Errorf("invalid value '%s' of sysctl key '%s' in os_settings", val, key)
+		}
+	}
+	for _, l := range s.Limits {
+		switch l.Type {
+		case "soft", "hard", "-":
+		default:
+			return errors.Errorf("invalid type '%s' of limit '%s' in os_settings, should be soft, hard or -", l.Type, l.Item)
+		}
+		for _, field := range []string{l.Domain, l.Item, l.Value} {
+			if !limitFieldRegexp.MatchString(field) {
+				return errors.Errorf("invalid limit entry '%s %s %s %s' in os_settings", l.Domain, l.Type, l.Item, l.Value)
+			}
+		}
+	}
+	switch s.TransparentHugepage {
+	case "", "always", "madvise", "never":
+	default:
+		return errors.Errorf("invalid transparent_hugepage '%s' in os_settings, should be always, madvise or never",
+			s.TransparentHugepage)
+	}
+	return nil
+}
+
+// OSSettingsDropIn is a drop-in file of OS settings
+type OSSettingsDropIn struct {
+	Path    string
+	Content []byte
+	Reload  string // the command to apply the file
+}
+
+// dropInName returns the name of the drop-in files of the cluster
+func dropInName(clusterName string) string {
+	return fmt.Sprintf("60-tiup-%s.conf", clusterName)
+}
+
+// OSSettingsDropInPaths returns the paths of all drop-in files of the cluster
+func OSSettingsDropInPaths(clusterName string) []string {
+	name := dropInName(clusterName)
+	return []string{
+		SysctlDropInDir + "/" + name,
+		LimitsDropInDir + "/" + name,
+		TmpfilesDropInDir + "/" + name,
+	}
+}
+
+// DropIns renders the drop-in files of the settings, named after the cluster
+func (s *OSSettings) DropIns(clusterName string) []OSSettingsDropIn {
+	var files []OSSettingsDropIn
+	header := fmt.Sprintf("# managed by TiUP for cluster %s, DO NOT EDIT\n", clusterName)
+	name := dropInName(clusterName)
+
+	if len(s.Sysctl) > 0 {
+		keys := make([]string, 0, len(s.Sysctl))
+		for key := range s.Sysctl {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf := bytes.NewBufferString(header)
+		for _, key := range keys {
+			fmt.Fprintf(buf, "%s = %s\n", key, s.Sysctl[key])
+		}
+		path := SysctlDropInDir + "/" + name
+		files = append(files, OSSettingsDropIn{Path: path, Content: buf.Bytes(), Reload: "sysctl -p " + path})
+	}
+
+	if len(s.Limits) > 0 {
+		buf := bytes.NewBufferString(header)
+		for _, l := range s.Limits {
+			fmt.Fprintf(buf, "%s    %s    %s    %s\n", l.Domain, l.Type, l.Item, l.Value)
+		}
+		// limits are applied by pam on new sessions, nothing to reload
+		files = append(files, OSSettingsDropIn{Path: LimitsDropInDir + "/" + name, Content: buf.Bytes()})
+	}
+
+	if s.TransparentHugepage != "" {
+		// the setting of transparent hugepages is persisted by systemd-tmpfiles
+		path := TmpfilesDropInDir + "/" + name
+		content := fmt.Sprintf("%sw %s - - - - %s\n", header, THPEnabledPath, s.TransparentHugepage)
+		files = append(files, OSSettingsDropIn{
+			Path:    path,
+			Content: []byte(content),
+			Reload:  fmt.Sprintf("echo %s > %s", s.TransparentHugepage, THPEnabledPath),
+		})
+	}
+
+	return files
+}
+
+// GetOSSettings returns the OS settings of the topology, nil if there is none
+func GetOSSettings(topo Topology) *OSSettings {
+	if s, ok := topo.(*Specification); ok {
+		return s.OSSettings
+	}
+	return nil
+}
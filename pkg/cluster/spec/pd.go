@@ -14,6 +14,7 @@
 package spec
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
@@ -27,7 +28,7 @@ import (
 	"github.com/pingcap/tiup/pkg/logger/log"
 	"github.com/pingcap/tiup/pkg/meta"
 	"github.com/pingcap/tiup/pkg/utils"
-	"golang.org/x/mod/semver"
+	"github.com/pingcap/tiup/pkg/version"
 )
 
 // PDSpec represents the PD topology specification in topology.yaml
@@ -52,9 +53,13 @@ type PDSpec struct {
 
 // Status queries current status of the instance
 func (s PDSpec) Status(pdList ...string) string {
+	return s.status(nil, statusQueryTimeout, pdList...)
+}
+
+func (s PDSpec) status(tlsCfg *tls.Config, timeout time.Duration, pdList ...string) string {
 	curAddr := fmt.Sprintf("%s:%d", s.Host, s.ClientPort)
-	curPdAPI := api.NewPDClient([]string{curAddr}, statusQueryTimeout, nil)
-	allPdAPI := api.NewPDClient(pdList, statusQueryTimeout, nil)
+	curPdAPI := api.NewPDClient([]string{curAddr}, timeout, tlsCfg)
+	allPdAPI := api.NewPDClient(pdList, timeout, tlsCfg)
 	suffix := ""
 
 	// find dashboard node
@@ -152,7 +157,7 @@ type PDInstance struct {
 
 // InitConfig implement Instance interface
 func (i *PDInstance) InitConfig(e executor.Executor, clusterName, clusterVersion, deployUser string, paths meta.DirPaths) error {
-	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, deployUser, paths); err != nil {
+	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, clusterName, clusterVersion, deployUser, paths); err != nil {
 		return err
 	}
 
@@ -169,7 +174,7 @@ func (i *PDInstance) InitConfig(e executor.Executor, clusterName, clusterVersion
 		WithListenHost(i.GetListenHost())
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_pd_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := RenderTemplate(cfg, clusterName, clusterVersion, ComponentPD, "run_pd.sh.tpl", fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "scripts", "run_pd.sh")
@@ -181,7 +186,7 @@ func (i *PDInstance) InitConfig(e executor.Executor, clusterName, clusterVersion
 	}
 
 	// Set the PD metrics storage address
-	if semver.Compare(clusterVersion, "v3.1.0") >= 0 && len(i.topo.Monitors) > 0 {
+	if version.Compare(clusterVersion, "v3.1.0") >= 0 && len(i.topo.Monitors) > 0 {
 		if spec.Config == nil {
 			spec.Config = map[string]interface{}{}
 		}
@@ -244,7 +249,7 @@ func (i *PDInstance) ScaleConfig(e executor.Executor, topo Topology, clusterName
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_pd_%s_%d.sh", i.GetHost(), i.GetPort()))
 	log.Infof("script path: %s", fp)
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := RenderTemplate(cfg, clusterName, clusterVersion, ComponentPD, "run_pd_scale.sh.tpl", fp); err != nil {
 		return err
 	}
 
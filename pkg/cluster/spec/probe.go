@@ -0,0 +1,178 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+const (
+	// probeTimeout is the timeout of each request of a health probe
+	probeTimeout = 3 * time.Second
+	// DefaultProbeConcurrency is the default max number of instances probed at the same time
+	DefaultProbeConcurrency = 16
+)
+
+// ProbeResult is the health of an instance reported by its probe
+type ProbeResult struct {
+	Status string `json:"status"`           // e.g. Up, Up|L, Down, Offline
+	Reason string `json:"reason,omitempty"` // why the instance is degraded, empty if it's healthy
+}
+
+// Prober is implemented by the instances having a component specific health
+// probe, which is more accurate than checking if the ports are open
+type Prober interface {
+	// Probe checks the health of the instance, HTTPS is used if tlsCfg is not nil
+	Probe(tlsCfg *tls.Config, pdList ...string) ProbeResult
+}
+
+// ProbeInstances probes the instances with at most concurrency of them at the
+// same time, and returns the results in the order of the instances. The status
+// of the instances not implementing Prober is queried by Instance.Status.
+func ProbeInstances(insts []Instance, tlsCfg *tls.Config, concurrency int, pdList ...string) []ProbeResult {
+	if concurrency <= 0 {
+		concurrency = DefaultProbeConcurrency
+	}
+	results := make([]ProbeResult, len(insts))
+	limit := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, ins := range insts {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(i int, ins Instance) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			if p, ok := ins.(Prober); ok {
+				results[i] = p.Probe(tlsCfg, pdList...)
+			} else {
+				results[i] = ProbeResult{Status: ins.Status(pdList...)}
+			}
+		}(i, ins)
+	}
+	wg.Wait()
+	return results
+}
+
+func probeURL(tlsCfg *tls.Config, host string, port int, path string) string {
+	scheme := "http"
+	if tlsCfg != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s:%d%s", scheme, host, port, path)
+}
+
+// probeHTTP checks if the url responds with a successful status code
+func probeHTTP(tlsCfg *tls.Config, url string) error {
+	_, err := utils.NewHTTPClient(probeTimeout, tlsCfg).Get(url)
+	return err
+}
+
+func downResult(what string, err error) ProbeResult {
+	return ProbeResult{Status: "Down", Reason: fmt.Sprintf("%s: %s", what, err)}
+}
+
+// probeStore checks the state of the store registered in PD with the address
+func probeStore(tlsCfg *tls.Config, storeAddr string, offline bool, pdList ...string) ProbeResult {
+	if len(pdList) < 1 {
+		return ProbeResult{Status: "N/A", Reason: "no PD to query the store state"}
+	}
+	stores, err := api.NewPDClient(pdList, probeTimeout, tlsCfg).GetStores()
+	if err != nil {
+		return ProbeResult{Status: "N/A", Reason: fmt.Sprintf("failed to query the store state: %s", err)}
+	}
+	// the stores are sorted by ID in descending order, the latest one of the
+	// address is the first, the older ones might be legacy ones already offlined
+	for _, store := range stores.Stores {
+		if store.Store.Address != storeAddr {
+			continue
+		}
+		state := store.Store.StateName
+		if state == "Up" {
+			return ProbeResult{Status: state}
+		}
+		if offline && strings.ToLower(state) == "offline" {
+			state = "Pending Offline" // avoid misleading
+		}
+		return ProbeResult{Status: state, Reason: fmt.Sprintf("store state: %s", store.Store.StateName)}
+	}
+	return ProbeResult{Status: "N/A", Reason: "store not found in PD"}
+}
+
+// Probe implements Prober interface
+func (i *PDInstance) Probe(tlsCfg *tls.Config, pdList ...string) ProbeResult {
+	s := i.InstanceSpec.(PDSpec)
+	healths, err := api.NewPDClient([]string{fmt.Sprintf("%s:%d", s.Host, s.ClientPort)}, probeTimeout, tlsCfg).GetHealth()
+	if err != nil {
+		return downResult("health api", err)
+	}
+	for _, h := range healths {
+		if h.Name == s.Name && !h.Health {
+			return ProbeResult{Status: "Down", Reason: "member unhealthy"}
+		}
+	}
+	// the leader and dashboard are marked in the status
+	return ProbeResult{Status: s.status(tlsCfg, probeTimeout, pdList...)}
+}
+
+// Probe implements Prober interface
+func (i *TiDBInstance) Probe(tlsCfg *tls.Config, _ ...string) ProbeResult {
+	s := i.InstanceSpec.(TiDBSpec)
+	if err := probeHTTP(tlsCfg, probeURL(tlsCfg, s.Host, s.StatusPort, "/status")); err != nil {
+		return downResult("status api", err)
+	}
+	return ProbeResult{Status: "Up"}
+}
+
+// Probe implements Prober interface
+func (i *TiKVInstance) Probe(tlsCfg *tls.Config, pdList ...string) ProbeResult {
+	s := i.InstanceSpec.(TiKVSpec)
+	if err := probeHTTP(tlsCfg, probeURL(tlsCfg, s.Host, s.StatusPort, "/metrics")); err != nil {
+		return downResult("status port", err)
+	}
+	return probeStore(tlsCfg, fmt.Sprintf("%s:%d", s.Host, s.Port), s.Offline, pdList...)
+}
+
+// Probe implements Prober interface
+func (i *TiFlashInstance) Probe(tlsCfg *tls.Config, pdList ...string) ProbeResult {
+	s := i.InstanceSpec.(TiFlashSpec)
+	if err := probeHTTP(tlsCfg, probeURL(tlsCfg, s.Host, s.FlashProxyStatusPort, "/metrics")); err != nil {
+		return downResult("proxy status", err)
+	}
+	return probeStore(tlsCfg, fmt.Sprintf("%s:%d", s.Host, s.FlashServicePort), s.Offline, pdList...)
+}
+
+// Probe implements Prober interface
+func (i *MonitorInstance) Probe(tlsCfg *tls.Config, _ ...string) ProbeResult {
+	if err := probeHTTP(tlsCfg, probeURL(tlsCfg, i.GetHost(), i.GetPort(), "/-/healthy")); err != nil {
+		return downResult("health api", err)
+	}
+	return ProbeResult{Status: "Up"}
+}
+
+// Probe implements Prober interface
+func (i *GrafanaInstance) Probe(tlsCfg *tls.Config, _ ...string) ProbeResult {
+	if err := probeHTTP(tlsCfg, probeURL(tlsCfg, i.GetHost(), i.GetPort(), "/api/health")); err != nil {
+		return downResult("health api", err)
+	}
+	return ProbeResult{Status: "Up"}
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	. "github.com/pingcap/check"
+)
+
+type probeSuite struct{}
+
+var _ = Suite(&probeSuite{})
+
+func hostPort(c *C, addr string) (string, int) {
+	host, port, err := net.SplitHostPort(addr)
+	c.Assert(err, IsNil)
+	p, err := strconv.Atoi(port)
+	c.Assert(err, IsNil)
+	return host, p
+}
+
+func (s *probeSuite) TestProbeInstances(c *C) {
+	// serves both the status port of TiKV and the stores api of PD
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status", "/metrics":
+		case "/pd/api/v1/stores":
+			_, _ = w.Write([]byte(`{"count":2,"stores":[
+				{"store":{"id":2,"address":"127.0.0.1:20160","state_name":"Offline"}},
+				{"store":{"id":1,"address":"127.0.0.1:20161","state_name":"Up"}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	host, port := hostPort(c, srv.Listener.Addr().String())
+
+	// a port nothing listens on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	_, closedPort := hostPort(c, l.Addr().String())
+	l.Close()
+
+	topo := &Specification{
+		TiDBServers: []TiDBSpec{
+			{Host: host, StatusPort: port},
+			{Host: host, StatusPort: closedPort},
+		},
+		TiKVServers: []TiKVSpec{
+			{Host: host, Port: 20160, StatusPort: port},
+			{Host: host, Port: 20161, StatusPort: port},
+		},
+	}
+	insts := append((&TiDBComponent{topo}).Instances(), (&TiKVComponent{topo}).Instances()...)
+	results := ProbeInstances(insts, nil, 2, srv.Listener.Addr().String())
+	c.Assert(results, HasLen, 4)
+	c.Assert(results[0], Equals, ProbeResult{Status: "Up"})
+	c.Assert(results[1].Status, Equals, "Down")
+	c.Assert(results[1].Reason, Matches, "status api: .*")
+	c.Assert(results[2], Equals, ProbeResult{Status: "Offline", Reason: "store state: Offline"})
+	c.Assert(results[3], Equals, ProbeResult{Status: "Up"})
+}
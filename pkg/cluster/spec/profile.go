@@ -46,6 +46,11 @@ func getHomeDir() (string, error) {
 
 var initialized = false
 
+// Initialized reports if the profile is initialized by Initialize
+func Initialized() bool {
+	return initialized
+}
+
 // Initialize initializes the global variables of meta package. If the
 // environment variable TIUP_COMPONENT_DATA_DIR is set, it is used as root of
 // the profile directory, otherwise the `$HOME/.tiops` of current user is used.
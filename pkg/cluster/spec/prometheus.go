@@ -15,11 +15,14 @@ package spec
 
 import (
 	"fmt"
+	"io/ioutil"
 	"path/filepath"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/executor"
 	"github.com/pingcap/tiup/pkg/cluster/template/config"
 	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
+	"github.com/pingcap/tiup/pkg/logger/log"
 	"github.com/pingcap/tiup/pkg/meta"
 	"github.com/pingcap/tiup/pkg/set"
 )
@@ -102,7 +105,7 @@ type MonitorInstance struct {
 
 // InitConfig implement Instance interface
 func (i *MonitorInstance) InitConfig(e executor.Executor, clusterName, clusterVersion, deployUser string, paths meta.DirPaths) error {
-	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, deployUser, paths); err != nil {
+	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, clusterName, clusterVersion, deployUser, paths); err != nil {
 		return err
 	}
 
@@ -116,7 +119,7 @@ func (i *MonitorInstance) InitConfig(e executor.Executor, clusterName, clusterVe
 	).WithPort(spec.Port).
 		WithNumaNode(spec.NumaNode)
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_prometheus_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := RenderTemplate(cfg, clusterName, clusterVersion, ComponentPrometheus, "run_prometheus.sh.tpl", fp); err != nil {
 		return err
 	}
 
@@ -174,18 +177,49 @@ func (i *MonitorInstance) InitConfig(e executor.Executor, clusterName, clusterVe
 		cfig.AddAlertmanager(alertmanager.Host, uint64(alertmanager.WebPort))
 	}
 	for host := range uniqueHosts {
-		cfig.AddNodeExpoertor(host, uint64(topo.MonitoredOptions.NodeExporterPort))
-		cfig.AddBlackboxExporter(host, uint64(topo.MonitoredOptions.BlackboxExporterPort))
+		// the hosts running the node_exporter of others are scraped on its port
+		if port := topo.MonitoredOptions.ExporterPort(host); port > 0 {
+			cfig.AddNodeExpoertor(host, uint64(port))
+		}
+		if topo.MonitoredOptions.DeployAgents(host) {
+			cfig.AddBlackboxExporter(host, uint64(topo.MonitoredOptions.BlackboxExporterPort))
+		}
 		cfig.AddMonitoredServer(host)
 	}
 
-	if err := cfig.ConfigToFile(fp); err != nil {
+	dst = filepath.Join(paths.Deploy, "conf", "prometheus.yml")
+	generated, err := GenerateTemplate(cfig, clusterName, clusterVersion, ComponentPrometheus, "prometheus.yml.tpl")
+	if err != nil {
 		return err
 	}
-	dst = filepath.Join(paths.Deploy, "conf", "prometheus.yml")
+	data, err := i.mergeConfig(e, generated, dst)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(fp, data, 0755); err != nil {
+		return errors.AddStack(err)
+	}
 	return e.Transfer(fp, dst, false)
 }
 
+// mergeConfig preserves the scrape jobs and rule files added to the existing
+// config dst on the host by the users in the generated config
+func (i *MonitorInstance) mergeConfig(e executor.Executor, generated []byte, dst string) ([]byte, error) {
+	existing, _, err := e.Execute(fmt.Sprintf("cat %s 2>/dev/null || true", dst), false)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to read %s", dst)
+	}
+	data, merge, err := config.MergePrometheusConfig(generated, existing)
+	if err != nil {
+		return nil, err
+	}
+	if merge.Preserved() {
+		log.Infof("Preserved the scrape jobs %v and the rule files %v added to the config of %s, the rest is regenerated",
+			merge.PreservedJobs, merge.PreservedRuleFiles, i.ID())
+	}
+	return data, nil
+}
+
 // ScaleConfig deploy temporary config on scaling
 func (i *MonitorInstance) ScaleConfig(e executor.Executor, topo Topology,
 	clusterName string, clusterVersion string, deployUser string, paths meta.DirPaths) error {
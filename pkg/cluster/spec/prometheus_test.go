@@ -0,0 +1,125 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/template/config"
+	"github.com/pingcap/tiup/pkg/meta"
+	"gopkg.in/yaml.v2"
+)
+
+type prometheusSuite struct{}
+
+var _ = Suite(&prometheusSuite{})
+
+// memExecutor keeps the files transferred to the host in memory, and serves
+// them to cat
+type memExecutor struct {
+	files map[string][]byte
+}
+
+func (e *memExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	if fields := strings.Fields(cmd); len(fields) > 1 && fields[0] == "cat" {
+		return e.files[fields[1]], nil, nil
+	}
+	return nil, nil, nil
+}
+
+func (e *memExecutor) Transfer(src, dst string, download bool) error {
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	e.files[dst] = data
+	return nil
+}
+
+func (s *prometheusSuite) TestCustomJobsPreserved(c *C) {
+	cache, err := ioutil.TempDir("", "prometheus-config")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(cache)
+
+	topo := &Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.1
+tikv_servers:
+  - host: 172.16.5.2
+tidb_servers:
+  - host: 172.16.5.3
+monitoring_servers:
+  - host: 172.16.5.1
+`), topo), IsNil)
+	inst := (&MonitorComponent{topo}).Instances()[0]
+	paths := meta.DirPaths{Deploy: "/deploy/prometheus-9090", Data: []string{"/data"}, Log: "/log", Cache: cache}
+	dst := "/deploy/prometheus-9090/conf/prometheus.yml"
+	e := &memExecutor{files: make(map[string][]byte)}
+	c.Assert(inst.InitConfig(e, "test", "v4.0.0", "tidb", paths), IsNil)
+
+	// add a rule file and a scrape job after the managed blocks
+	generated := string(e.files[dst])
+	end := "  # END managed by tiup\n"
+	c.Assert(strings.Count(generated, end), Equals, 2)
+	i := strings.Index(generated, end) + len(end)
+	e.files[dst] = []byte(generated[:i] + "  - 'custom.rules.yml'\n" + generated[i:] +
+		"  - job_name: 'custom'\n    static_configs:\n    - targets: ['10.0.0.1:9100']\n")
+
+	parse := func() (rules, jobs []string) {
+		var cfg struct {
+			RuleFiles     []string `yaml:"rule_files"`
+			ScrapeConfigs []struct {
+				JobName string `yaml:"job_name"`
+			} `yaml:"scrape_configs"`
+		}
+		c.Assert(yaml.Unmarshal(e.files[dst], &cfg), IsNil)
+		for _, job := range cfg.ScrapeConfigs {
+			jobs = append(jobs, job.JobName)
+		}
+		return cfg.RuleFiles, jobs
+	}
+
+	// the custom parts survive consecutive scale-outs
+	for _, host := range []string{"172.16.5.4", "172.16.5.5"} {
+		topo.TiKVServers = append(topo.TiKVServers, TiKVSpec{Host: host, Port: 20160, StatusPort: 20180})
+		c.Assert(inst.(*MonitorInstance).ScaleConfig(e, topo, "test", "v4.0.0", "tidb", paths), IsNil)
+		c.Assert(string(e.files[dst]), Matches, "(?s).*'"+host+":20180'.*")
+
+		rules, jobs := parse()
+		c.Assert(rules[len(rules)-1], Equals, "custom.rules.yml")
+		c.Assert(jobs[len(jobs)-1], Equals, "custom")
+		c.Assert(strings.Count(string(e.files[dst]), "custom"), Equals, 2)
+	}
+
+	// the configs generated by older versions have no managed blocks, the
+	// custom parts are told by the names
+	legacy := strings.ReplaceAll(strings.ReplaceAll(string(e.files[dst]), end, ""), "  # BEGIN managed by tiup", "  # ")
+	cfig := config.NewPrometheusConfig("test").AddTiDB("172.16.5.3", 10080)
+	regenerated, err := cfig.Config()
+	c.Assert(err, IsNil)
+	merged, merge, err := config.MergePrometheusConfig(regenerated, []byte(legacy))
+	c.Assert(err, IsNil)
+	c.Assert(merge.Legacy, IsTrue)
+	c.Assert(merge.PreservedJobs, DeepEquals, []string{"custom"})
+	c.Assert(merge.PreservedRuleFiles, DeepEquals, []string{"custom.rules.yml"})
+	e.files[dst] = merged
+	rules, jobs := parse()
+	c.Assert(rules[len(rules)-1], Equals, "custom.rules.yml")
+	c.Assert(jobs[len(jobs)-1], Equals, "custom")
+}
@@ -0,0 +1,173 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/meta"
+)
+
+type (
+	// ProxySettings are the proxies the services on the hosts reach the network
+	// through, the settings of a host in Hosts override the global ones
+	ProxySettings struct {
+		ProxyConfig `yaml:",inline"`
+		Hosts       map[string]ProxyConfig `yaml:"hosts,omitempty"`
+	}
+
+	// ProxyConfig is the proxy configuration of a host
+	ProxyConfig struct {
+		HTTPProxy  string `yaml:"http_proxy,omitempty"`
+		HTTPSProxy string `yaml:"https_proxy,omitempty"`
+		NoProxy    string `yaml:"no_proxy,omitempty"`
+	}
+)
+
+// the characters can't be rendered safely into the environment files
+const proxyUnsafeChars = " \t\r\n\"'\\"
+
+// ProxyEnvFile is the file under the deploy directory the proxy environment
+// variables of the services are loaded from. It's only readable by the deploy
+// user as the proxy URLs may contain credentials.
+const ProxyEnvFile = "conf/proxy.env"
+
+// Validate checks the proxy settings, hosts are all hosts of the topology
+func (s *ProxySettings) Validate(hosts map[string]struct{}) error {
+	if err := s.ProxyConfig.validate("global.proxy"); err != nil {
+		return err
+	}
+	for host, cfg := range s.Hosts {
+		if _, ok := hosts[host]; !ok {
+			return errors.Errorf("host '%s' in global.proxy.hosts is not a host of the topology", host)
+		}
+		if err := cfg.validate("global.proxy.hosts." + host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c ProxyConfig) validate(field string) error {
+	for name, proxy := range map[string]string{"http_proxy": c.HTTPProxy, "https_proxy": c.HTTPSProxy} {
+		if proxy == "" {
+			continue
+		}
+		if strings.ContainsAny(proxy, proxyUnsafeChars) {
+			return errors.Errorf("invalid %s '%s' in %s, it should not contain spaces or quotes", name, proxy, field)
+		}
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return errors.Errorf("invalid %s '%s' in %s: %s", name, proxy, field, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return errors.Errorf("invalid %s '%s' in %s, the scheme should be http, https or socks5", name, proxy, field)
+		}
+		if u.Hostname() == "" {
+			return errors.Errorf("invalid %s '%s' in %s, the host of the proxy is missing", name, proxy, field)
+		}
+	}
+	if strings.ContainsAny(c.NoProxy, proxyUnsafeChars) {
+		return errors.Errorf("invalid no_proxy '%s' in %s, it should be a comma separated list without spaces or quotes", c.NoProxy, field)
+	}
+	return nil
+}
+
+// ForHost returns the proxy configuration of the host
+func (s *ProxySettings) ForHost(host string) ProxyConfig {
+	cfg := s.ProxyConfig
+	h, ok := s.Hosts[host]
+	if !ok {
+		return cfg
+	}
+	if h.HTTPProxy != "" {
+		cfg.HTTPProxy = h.HTTPProxy
+	}
+	if h.HTTPSProxy != "" {
+		cfg.HTTPSProxy = h.HTTPSProxy
+	}
+	if h.NoProxy != "" {
+		cfg.NoProxy = h.NoProxy
+	}
+	return cfg
+}
+
+// Env returns the environment variables of the proxy configuration, both the
+// lower and upper case names are set as programs differ in which they read
+func (c ProxyConfig) Env() []string {
+	var env []string
+	for _, kv := range [][2]string{
+		{"http_proxy", c.HTTPProxy},
+		{"https_proxy", c.HTTPSProxy},
+		{"no_proxy", c.NoProxy},
+	} {
+		if kv[1] == "" {
+			continue
+		}
+		env = append(env, kv[0]+"="+kv[1], strings.ToUpper(kv[0])+"="+kv[1])
+	}
+	return env
+}
+
+// ProxyEnv returns the proxy environment variables of the services on the host
+func (g *GlobalOptions) ProxyEnv(host string) []string {
+	if g == nil || g.Proxy == nil {
+		return nil
+	}
+	return g.Proxy.ForHost(host).Env()
+}
+
+// SyncProxyEnvFile pushes the proxy environment variables of the services on
+// the host to ProxyEnvFile under the deploy directory, and returns the path of
+// it to be set as the EnvironmentFile of the systemd units. The file is removed
+// and the path is empty if there is no proxy for the host.
+func SyncProxyEnvFile(e executor.Executor, opt *GlobalOptions, host string, paths meta.DirPaths) (string, error) {
+	envFile := filepath.Join(paths.Deploy, ProxyEnvFile)
+	env := opt.ProxyEnv(host)
+	if len(env) == 0 {
+		cmd := fmt.Sprintf("rm -f %s", envFile)
+		if _, _, err := e.Execute(cmd, false); err != nil {
+			return "", errors.Annotatef(err, "execute: %s", cmd)
+		}
+		return "", nil
+	}
+
+	id := uuid.New().String()
+	src := filepath.Join(paths.Cache, fmt.Sprintf("proxy-%s-%s.env", host, id))
+	if err := ioutil.WriteFile(src, []byte(strings.Join(env, "\n")+"\n"), 0600); err != nil {
+		return "", errors.AddStack(err)
+	}
+	defer os.Remove(src)
+	tgt := filepath.Join(opt.RemoteTmpDirOf(host), "proxy_"+id+".env")
+	if err := e.Transfer(src, tgt, false); err != nil {
+		return "", errors.Annotatef(err, "transfer from %s to %s failed", src, tgt)
+	}
+	// restrict the mode before moving it in place, so it's never readable by
+	// the other users in the deploy directory
+	cmd := fmt.Sprintf("chmod 600 %s && mv %s %s", tgt, tgt, envFile)
+	if _, _, err := e.Execute(cmd, false); err != nil {
+		return "", errors.Annotatef(err, "execute: %s", cmd)
+	}
+	return envFile, nil
+}
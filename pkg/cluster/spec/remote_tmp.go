@@ -0,0 +1,83 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// DefaultRemoteTmpDir is the directory the files are staged in on the hosts
+// if the topology doesn't set global.remote_tmp_dir
+const DefaultRemoteTmpDir = "/tmp"
+
+// the characters can't be used in the commands staging files safely
+const remoteTmpDirUnsafeChars = " \t\r\n\"'\\$`;&|*?"
+
+// RemoteTmpDirOf returns the directory the files are staged in on the host,
+// the one of the host in RemoteTmpDirs overrides RemoteTmpDir
+func (g *GlobalOptions) RemoteTmpDirOf(host string) string {
+	if g == nil {
+		return DefaultRemoteTmpDir
+	}
+	if dir, ok := g.RemoteTmpDirs[host]; ok && dir != "" {
+		return dir
+	}
+	if g.RemoteTmpDir != "" {
+		return g.RemoteTmpDir
+	}
+	return DefaultRemoteTmpDir
+}
+
+// HasRemoteTmpDir checks if the directory to stage files in on the host is
+// set by the topology, the packages are only staged apart from the deploy
+// directories then
+func (g *GlobalOptions) HasRemoteTmpDir(host string) bool {
+	if g == nil {
+		return false
+	}
+	return g.RemoteTmpDir != "" || g.RemoteTmpDirs[host] != ""
+}
+
+// ValidateRemoteTmpDirs checks the directories to stage files in, hosts are
+// all hosts of the topology
+func (g *GlobalOptions) ValidateRemoteTmpDirs(hosts map[string]struct{}) error {
+	if err := validateRemoteTmpDir(g.RemoteTmpDir, "global.remote_tmp_dir"); err != nil {
+		return err
+	}
+	for host, dir := range g.RemoteTmpDirs {
+		if _, ok := hosts[host]; !ok {
+			return errors.Errorf("host '%s' in global.remote_tmp_dirs is not a host of the topology", host)
+		}
+		if err := validateRemoteTmpDir(dir, "global.remote_tmp_dirs."+host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateRemoteTmpDir(dir, field string) error {
+	if dir == "" {
+		return nil
+	}
+	if !filepath.IsAbs(dir) {
+		return errors.Errorf("invalid %s '%s', it should be an absolute path", field, dir)
+	}
+	if strings.ContainsAny(dir, remoteTmpDirUnsafeChars) {
+		return errors.Errorf("invalid %s '%s', it should not contain spaces, quotes or shell metacharacters", field, dir)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrSecretResolveFailed is ErrSecretResolveFailed
+	ErrSecretResolveFailed = errNS.NewType("secret_resolve_failed")
+
+	// secret references in configs, e.g. ${env:VAR}, ${file:/path} or ${exec:command}
+	secretRefRegexp = regexp.MustCompile(`\$\{(env|file|exec):([^}]+)\}`)
+)
+
+// HasSecretRef checks if a string contains any secret reference
+func HasSecretRef(s string) bool {
+	return secretRefRegexp.MatchString(s)
+}
+
+// resolveSecretRef returns the value a single secret reference points to
+func resolveSecretRef(kind, ref string) (string, error) {
+	switch kind {
+	case "env":
+		val, ok := os.LookupEnv(ref)
+		if !ok {
+			return "", fmt.Errorf("environment variable '%s' is not set", ref)
+		}
+		return val, nil
+	case "file":
+		data, err := ioutil.ReadFile(ref)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case "exec":
+		// the output is the secret, so it must never be logged
+		out, err := exec.Command("sh", "-c", ref).Output()
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(out), "\r\n"), nil
+	}
+	return "", fmt.Errorf("unknown secret reference type '%s'", kind)
+}
+
+// resolveSecretString replaces all secret references in s
+func resolveSecretString(s string) (string, error) {
+	var rerr error
+	resolved := secretRefRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		if rerr != nil {
+			return m
+		}
+		sub := secretRefRegexp.FindStringSubmatch(m)
+		val, err := resolveSecretRef(sub[1], sub[2])
+		if err != nil {
+			rerr = fmt.Errorf("failed to resolve '%s': %s", m, err)
+			return m
+		}
+		return val
+	})
+	return resolved, rerr
+}
+
+// ResolveSecrets returns a copy of the config with all secret references
+// replaced by their values, the original config is never changed so the
+// references are what is saved to the metadata. The second return value
+// reports whether any secret reference was found.
+func ResolveSecrets(conf map[string]interface{}) (map[string]interface{}, bool, error) {
+	if conf == nil {
+		return nil, false, nil
+	}
+	found := false
+	var resolve func(v interface{}) (interface{}, error)
+	resolve = func(v interface{}) (interface{}, error) {
+		switch val := v.(type) {
+		case string:
+			if !HasSecretRef(val) {
+				return val, nil
+			}
+			found = true
+			return resolveSecretString(val)
+		case map[string]interface{}:
+			m := make(map[string]interface{}, len(val))
+			for k, item := range val {
+				r, err := resolve(item)
+				if err != nil {
+					return nil, err
+				}
+				m[k] = r
+			}
+			return m, nil
+		case map[interface{}]interface{}:
+			m := make(map[interface{}]interface{}, len(val))
+			for k, item := range val {
+				r, err := resolve(item)
+				if err != nil {
+					return nil, err
+				}
+				m[k] = r
+			}
+			return m, nil
+		case []interface{}:
+			s := make([]interface{}, 0, len(val))
+			for _, item := range val {
+				r, err := resolve(item)
+				if err != nil {
+					return nil, err
+				}
+				s = append(s, r)
+			}
+			return s, nil
+		}
+		return v, nil
+	}
+
+	resolved, err := resolve(conf)
+	if err != nil {
+		return nil, found, err
+	}
+	return resolved.(map[string]interface{}), found, nil
+}
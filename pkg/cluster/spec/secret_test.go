@@ -0,0 +1,83 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pingcap/check"
+)
+
+type secretSuite struct{}
+
+var _ = check.Suite(&secretSuite{})
+
+func (s *secretSuite) TestResolveSecrets(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-secret-test")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	secretFile := filepath.Join(dir, "secret")
+	c.Assert(ioutil.WriteFile(secretFile, []byte("from-file\n"), 0600), check.IsNil)
+
+	os.Setenv("TIUP_TEST_SECRET", "from-env")
+	defer os.Unsetenv("TIUP_TEST_SECRET")
+
+	conf := map[string]interface{}{
+		"plain":              "value",
+		"syncer.to.password": "${env:TIUP_TEST_SECRET}",
+		"nested": map[interface{}]interface{}{
+			"key": "prefix-${file:" + secretFile + "}",
+			"cmd": "${exec:echo from-exec}",
+		},
+		"port": 4000,
+	}
+	resolved, found, err := ResolveSecrets(conf)
+	c.Assert(err, check.IsNil)
+	c.Assert(found, check.IsTrue)
+	c.Assert(resolved["plain"], check.Equals, "value")
+	c.Assert(resolved["syncer.to.password"], check.Equals, "from-env")
+	c.Assert(resolved["port"], check.Equals, 4000)
+	nested := resolved["nested"].(map[interface{}]interface{})
+	c.Assert(nested["key"], check.Equals, "prefix-from-file")
+	c.Assert(nested["cmd"], check.Equals, "from-exec")
+
+	// the original config keeps the references
+	c.Assert(conf["syncer.to.password"], check.Equals, "${env:TIUP_TEST_SECRET}")
+
+	_, found, err = ResolveSecrets(map[string]interface{}{"plain": "value"})
+	c.Assert(err, check.IsNil)
+	c.Assert(found, check.IsFalse)
+
+	_, _, err = ResolveSecrets(map[string]interface{}{"key": "${env:TIUP_TEST_SECRET_NOT_SET}"})
+	c.Assert(err, check.ErrorMatches, `.*'\$\{env:TIUP_TEST_SECRET_NOT_SET\}'.*`)
+}
+
+func (s *secretSuite) TestGrafanaCredentials(c *check.C) {
+	user, password, err := GrafanaSpec{}.Credentials()
+	c.Assert(err, check.IsNil)
+	c.Assert(user, check.Equals, "admin")
+	c.Assert(password, check.Equals, "admin")
+
+	os.Setenv("TIUP_TEST_GRAFANA_PASSWORD", "from-env")
+	defer os.Unsetenv("TIUP_TEST_GRAFANA_PASSWORD")
+	user, password, err = GrafanaSpec{Username: "ops", Password: "${env:TIUP_TEST_GRAFANA_PASSWORD}"}.Credentials()
+	c.Assert(err, check.IsNil)
+	c.Assert(user, check.Equals, "ops")
+	c.Assert(password, check.Equals, "from-env")
+
+	_, _, err = GrafanaSpec{Password: "${env:TIUP_TEST_GRAFANA_MISSING}"}.Credentials()
+	c.Assert(err, check.NotNil)
+}
@@ -0,0 +1,319 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/errutil"
+)
+
+var (
+	// ErrSelectorSyntax is returned when a selector expression is invalid
+	ErrSelectorSyntax = errNS.NewType("selector_syntax", errutil.ErrTraitPreCheck)
+	// ErrPropSelectorPosition is the position in the expression where the syntax error is found
+	ErrPropSelectorPosition = errorx.RegisterPrintableProperty("selector_position")
+)
+
+const selectorSyntaxHint = `A selector is made of matches joined by AND, OR and NOT, e.g.:
+  role=tikv AND zone=us-west-1a AND NOT host=10.1.2.3
+The supported keys of matches are role, host, port, id, zone and label.<name>,
+the labels are the server.labels of instances. Use != for mismatches, and
+parentheses to group matches.`
+
+// Selector is a predicate on instances parsed from a selector expression
+type Selector struct {
+	expr string
+	root selectorNode
+}
+
+type selectorNode interface {
+	match(inst Instance) bool
+}
+
+type (
+	selectorAnd struct{ lhs, rhs selectorNode }
+	selectorOr  struct{ lhs, rhs selectorNode }
+	selectorNot struct{ node selectorNode }
+	// selectorMatch matches the value of the key of an instance
+	selectorMatch struct {
+		key    string
+		value  string
+		negate bool
+	}
+)
+
+func (n *selectorAnd) match(inst Instance) bool { return n.lhs.match(inst) && n.rhs.match(inst) }
+func (n *selectorOr) match(inst Instance) bool  { return n.lhs.match(inst) || n.rhs.match(inst) }
+func (n *selectorNot) match(inst Instance) bool { return !n.node.match(inst) }
+
+func (n *selectorMatch) match(inst Instance) bool {
+	var matched bool
+	switch {
+	case n.key == "role":
+		matched = inst.Role() == n.value || inst.ComponentName() == n.value
+	case n.key == "host":
+		matched = inst.GetHost() == n.value
+	case n.key == "port":
+		matched = strconv.Itoa(inst.GetPort()) == n.value
+	case n.key == "id":
+		matched = inst.ID() == n.value
+	default:
+		// zone or label.<name>
+		if l, ok := inst.(interface{ Labels() map[string]string }); ok {
+			val, found := l.Labels()[strings.TrimPrefix(n.key, "label.")]
+			matched = found && val == n.value
+		}
+	}
+	return matched != n.negate
+}
+
+// ParseSelector parses a selector expression, a nil selector matching all
+// instances is returned if the expression is empty
+func ParseSelector(expr string) (*Selector, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	p := &selectorParser{expr: expr}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorf(tok.pos, "unexpected '%s'", tok.text)
+	}
+	return &Selector{expr: expr, root: root}, nil
+}
+
+// Match checks if the instance is matched by the selector
+func (s *Selector) Match(inst Instance) bool {
+	if s == nil {
+		return true
+	}
+	return s.root.match(inst)
+}
+
+// String implements the fmt.Stringer interface
+func (s *Selector) String() string {
+	if s == nil {
+		return ""
+	}
+	return s.expr
+}
+
+type selectorTokenKind int
+
+const (
+	tokEOF selectorTokenKind = iota
+	tokWord
+	tokEq
+	tokNe
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type selectorToken struct {
+	kind selectorTokenKind
+	text string
+	pos  int // 1-based position in the expression
+}
+
+type selectorParser struct {
+	expr   string
+	tokens []selectorToken
+	cur    int
+}
+
+func (p *selectorParser) errorf(pos int, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	return ErrSelectorSyntax.New("invalid selector at position %d: %s\n  %s\n  %s^",
+		pos, msg, p.expr, strings.Repeat(" ", pos-1)).
+		WithProperty(ErrPropSelectorPosition, pos).
+		WithProperty(cliutil.SuggestionFromString(selectorSyntaxHint))
+}
+
+// lex splits the expression into tokens, the words may be quoted with
+// single or double quotes to contain spaces and special characters
+func (p *selectorParser) lex() error {
+	s := p.expr
+	for i := 0; i < len(s); {
+		c := s[i]
+		pos := i + 1
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			p.tokens = append(p.tokens, selectorToken{tokLParen, "(", pos})
+			i++
+		case c == ')':
+			p.tokens = append(p.tokens, selectorToken{tokRParen, ")", pos})
+			i++
+		case c == '=':
+			p.tokens = append(p.tokens, selectorToken{tokEq, "=", pos})
+			i++
+		case strings.HasPrefix(s[i:], "!="):
+			p.tokens = append(p.tokens, selectorToken{tokNe, "!=", pos})
+			i += 2
+		case c == '!':
+			p.tokens = append(p.tokens, selectorToken{tokNot, "!", pos})
+			i++
+		case strings.HasPrefix(s[i:], "&&"):
+			p.tokens = append(p.tokens, selectorToken{tokAnd, "&&", pos})
+			i += 2
+		case strings.HasPrefix(s[i:], "||"):
+			p.tokens = append(p.tokens, selectorToken{tokOr, "||", pos})
+			i += 2
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return p.errorf(pos, "unterminated quoted string")
+			}
+			p.tokens = append(p.tokens, selectorToken{tokWord, s[i+1 : i+1+end], pos})
+			i += end + 2
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n()=!&|\"'", rune(s[j])) {
+				j++
+			}
+			if j == i {
+				return p.errorf(pos, "unexpected '%c'", c)
+			}
+			word := s[i:j]
+			kind := tokWord
+			switch strings.ToUpper(word) {
+			case "AND":
+				kind = tokAnd
+			case "OR":
+				kind = tokOr
+			case "NOT":
+				kind = tokNot
+			}
+			p.tokens = append(p.tokens, selectorToken{kind, word, pos})
+			i = j
+		}
+	}
+	p.tokens = append(p.tokens, selectorToken{tokEOF, "end of selector", len(s) + 1})
+	return nil
+}
+
+func (p *selectorParser) peek() selectorToken {
+	return p.tokens[p.cur]
+}
+
+func (p *selectorParser) next() selectorToken {
+	tok := p.tokens[p.cur]
+	if tok.kind != tokEOF {
+		p.cur++
+	}
+	return tok
+}
+
+// parseOr parses: and { OR and }
+func (p *selectorParser) parseOr() (selectorNode, error) {
+	lhs, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		rhs, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &selectorOr{lhs, rhs}
+	}
+	return lhs, nil
+}
+
+// parseAnd parses: unary { AND unary }
+func (p *selectorParser) parseAnd() (selectorNode, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		rhs, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &selectorAnd{lhs, rhs}
+	}
+	return lhs, nil
+}
+
+// parseUnary parses: NOT unary | ( or ) | key = value | key != value
+func (p *selectorParser) parseUnary() (selectorNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNot:
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &selectorNot{node}, nil
+	case tokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if end := p.next(); end.kind != tokRParen {
+			return nil, p.errorf(end.pos, "expect ')' but got '%s'", end.text)
+		}
+		return node, nil
+	case tokWord:
+		return p.parseMatch(tok)
+	default:
+		return nil, p.errorf(tok.pos, "expect a match but got '%s'", tok.text)
+	}
+}
+
+func (p *selectorParser) parseMatch(key selectorToken) (selectorNode, error) {
+	k := strings.ToLower(key.text)
+	switch {
+	case k == "role", k == "host", k == "port", k == "id":
+	case k == "zone":
+		k = "label.zone"
+	case strings.HasPrefix(k, "label.") && len(k) > len("label."):
+		// keep the case of label names
+		k = "label." + key.text[len("label."):]
+	default:
+		return nil, p.errorf(key.pos, "unknown key '%s'", key.text)
+	}
+
+	op := p.next()
+	if op.kind != tokEq && op.kind != tokNe {
+		return nil, p.errorf(op.pos, "expect '=' or '!=' after '%s' but got '%s'", key.text, op.text)
+	}
+	val := p.next()
+	if val.kind != tokWord {
+		return nil, p.errorf(val.pos, "expect a value of '%s' but got '%s'", key.text, val.text)
+	}
+	if k == "port" {
+		if _, err := strconv.Atoi(val.text); err != nil {
+			return nil, p.errorf(val.pos, "invalid port '%s'", val.text)
+		}
+	}
+	return &selectorMatch{key: k, value: val.text, negate: op.kind == tokNe}, nil
+}
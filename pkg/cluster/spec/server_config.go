@@ -108,6 +108,26 @@ func flattenMap(ms map[string]interface{}) (map[string]interface{}, error) {
 	return result, nil
 }
 
+// ConfigValue returns the value of the key, e.g., server.advertise-status-addr,
+// in the config, in which it's either nested or dotted
+func ConfigValue(config map[string]interface{}, key string) (interface{}, bool) {
+	nested, err := flattenMap(config)
+	if err != nil {
+		return nil, false
+	}
+	var val interface{} = nested
+	for _, part := range strings.Split(key, ".") {
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if val, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return val, true
+}
+
 func merge(orig map[string]interface{}, overwrites ...map[string]interface{}) (map[string]interface{}, error) {
 	lhs, err := flattenMap(orig)
 	if err != nil {
@@ -172,20 +192,21 @@ func mergeImported(importConfig []byte, specConfigs ...map[string]interface{}) (
 type BindVersion func(comp string, version string) (bindVersion string)
 
 func checkConfig(e executor.Executor, componentName, clusterVersion, nodeOS, arch, config string, paths meta.DirPaths, bindVersion BindVersion) error {
-	repo, err := clusterutil.NewRepository(nodeOS, arch)
-	if err != nil {
-		return perrs.Annotate(ErrorCheckConfig, err.Error())
-	}
-
 	ver := clusterVersion
 	if bindVersion != nil {
 		ver = bindVersion(componentName, clusterVersion)
 	}
 
-	entry, err := repo.ComponentBinEntry(componentName, ver)
+	// the manifests are in the local profile once the package is downloaded,
+	// the mirror is not contacted as it's unreachable with a package directory
+	entry, err := clusterutil.LocalComponentBinEntry(componentName, ver, nodeOS, arch)
 	if err != nil {
 		return perrs.Annotate(ErrorCheckConfig, err.Error())
 	}
+	if entry == "" {
+		log.Warnf("Skip checking the config of %s %s, which is not in the local manifests", componentName, ver)
+		return nil
+	}
 
 	binPath := path.Join(paths.Deploy, "bin", entry)
 	// Skip old versions
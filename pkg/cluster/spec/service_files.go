@@ -0,0 +1,118 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// ServiceFilesChange tells which of the systemd unit and the run script of an
+// instance were changed on the host when its config was initialized
+type ServiceFilesChange struct {
+	Instance      string `json:"instance"`
+	UnitChanged   bool   `json:"unit_changed"`
+	ScriptChanged bool   `json:"script_changed"`
+}
+
+// Updated checks if any of the files were changed
+func (c ServiceFilesChange) Updated() bool {
+	return c.UnitChanged || c.ScriptChanged
+}
+
+// DiffExecutor pushes the systemd unit and the run script of an instance in
+// InitConfig only if their contents differ from the files on the host, and
+// records which of them were changed. Other files and commands are passed to
+// the wrapped executor as they are.
+type DiffExecutor struct {
+	executor.Executor
+	Change ServiceFilesChange
+}
+
+// NewDiffExecutor wraps e for initializing the config of the instance
+func NewDiffExecutor(e executor.Executor, inst Instance) *DiffExecutor {
+	return &DiffExecutor{Executor: e, Change: ServiceFilesChange{Instance: inst.ID()}}
+}
+
+// Transfer implements executor.Executor, the run script is skipped if it's not changed
+func (d *DiffExecutor) Transfer(src, dst string, download bool) error {
+	if download || !isRunScript(dst) {
+		return d.Executor.Transfer(src, dst, download)
+	}
+	same, err := sameContent(d.Executor, src, dst, false)
+	if err != nil || same {
+		return err
+	}
+	d.Change.ScriptChanged = true
+	return d.Executor.Transfer(src, dst, download)
+}
+
+// unitChanged checks if the unit file is different from the installed unit on
+// the host, and records the change if e is a DiffExecutor. It's always true for
+// other executors, as they don't compare the files.
+func unitChanged(e executor.Executor, src, unit string) (bool, error) {
+	d, ok := e.(*DiffExecutor)
+	if !ok {
+		return true, nil
+	}
+	same, err := sameContent(d.Executor, src, unit, true)
+	if err != nil || same {
+		return false, err
+	}
+	d.Change.UnitChanged = true
+	return true, nil
+}
+
+// isRunScript checks if the path is the run script of an instance, which is
+// scripts/run_<component>.sh under the deploy dir
+func isRunScript(path string) bool {
+	name := filepath.Base(path)
+	return filepath.Base(filepath.Dir(path)) == "scripts" &&
+		strings.HasPrefix(name, "run_") && strings.HasSuffix(name, ".sh")
+}
+
+// sameContent checks if the local file src has the same sha256 hash as the
+// file dst on the host, or as the hash in the header of dst if it's stamped by
+// a ConfigStamper, it's false if dst doesn't exist
+func sameContent(e executor.Executor, src, dst string, sudo bool) (bool, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return false, errors.AddStack(err)
+	}
+	defer f.Close()
+	local, err := utils.SHA256(f)
+	if err != nil {
+		return false, err
+	}
+
+	stdout, _, err := e.Execute(fmt.Sprintf("sha256sum %s 2>/dev/null || true", dst), sudo)
+	if err != nil {
+		return false, errors.Annotatef(err, "failed to get the checksum of %s", dst)
+	}
+	fields := strings.Fields(string(stdout))
+	if len(fields) > 0 && fields[0] == local {
+		return true, nil
+	}
+	h, err := remoteConfigHeader(e, dst)
+	if err != nil {
+		return false, err
+	}
+	return h != nil && h.Hash == local, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+type serviceFilesSuite struct{}
+
+var _ = Suite(&serviceFilesSuite{})
+
+func (s *serviceFilesSuite) TestDiffExecutor(c *C) {
+	dir, err := ioutil.TempDir("", "service-files")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "run_tikv.sh")
+	c.Assert(ioutil.WriteFile(src, []byte("#!/bin/bash\n"), 0644), IsNil)
+	f, err := os.Open(src)
+	c.Assert(err, IsNil)
+	hash, err := utils.SHA256(f)
+	f.Close()
+	c.Assert(err, IsNil)
+
+	transferred := func(fake *executor.Fake) []string {
+		var dsts []string
+		for _, cmd := range fake.Commands() {
+			if strings.HasPrefix(cmd, "transfer ") {
+				dsts = append(dsts, strings.Fields(cmd)[2])
+			}
+		}
+		return dsts
+	}
+
+	script := "/home/tidb/deploy/tikv-20160/scripts/run_tikv.sh"
+	unit := "/etc/systemd/system/tikv-20160.service"
+
+	// the files on the host are the same
+	fake := executor.NewFake("172.16.5.1")
+	fake.Respond("sha256sum "+script, hash+"  "+script+"\n", "")
+	fake.Respond("sha256sum "+unit, hash+"  "+unit+"\n", "")
+	d := &DiffExecutor{Executor: fake}
+	c.Assert(d.Transfer(src, script, false), IsNil)
+	changed, err := unitChanged(d, src, unit)
+	c.Assert(err, IsNil)
+	c.Assert(changed, IsFalse)
+	c.Assert(d.Change.Updated(), IsFalse)
+	c.Assert(transferred(fake), HasLen, 0)
+
+	// other files are always transferred
+	conf := "/home/tidb/deploy/tikv-20160/conf/tikv.toml"
+	c.Assert(d.Transfer(src, conf, false), IsNil)
+	c.Assert(transferred(fake), DeepEquals, []string{conf})
+
+	// the files are missing on the host
+	fake = executor.NewFake("172.16.5.1")
+	d = &DiffExecutor{Executor: fake}
+	c.Assert(d.Transfer(src, script, false), IsNil)
+	changed, err = unitChanged(d, src, unit)
+	c.Assert(err, IsNil)
+	c.Assert(changed, IsTrue)
+	c.Assert(d.Change, DeepEquals, ServiceFilesChange{UnitChanged: true, ScriptChanged: true})
+	c.Assert(transferred(fake), DeepEquals, []string{script})
+
+	// executors other than DiffExecutor always push the unit
+	changed, err = unitChanged(fake, src, unit)
+	c.Assert(err, IsNil)
+	c.Assert(changed, IsTrue)
+}
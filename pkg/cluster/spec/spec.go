@@ -59,8 +59,17 @@ type (
 		DataDir         string               `yaml:"data_dir,omitempty" default:"data"`
 		LogDir          string               `yaml:"log_dir,omitempty"`
 		ResourceControl meta.ResourceControl `yaml:"resource_control,omitempty" validate:"resource_control:editable"`
-		OS              string               `yaml:"os,omitempty" default:"linux"`
-		Arch            string               `yaml:"arch,omitempty" default:"amd64"`
+		OS              string               `yaml:"os,omitempty" default:"linux" enum:"linux"`
+		Arch            string               `yaml:"arch,omitempty" default:"amd64" enum:"amd64,arm64"`
+		Proxy           *ProxySettings       `yaml:"proxy,omitempty"`
+		// The directory the files are staged in on the hosts before being
+		// moved or extracted to their places, /tmp if it's empty, and the
+		// directories of the hosts overriding it
+		RemoteTmpDir  string            `yaml:"remote_tmp_dir,omitempty" validate:"remote_tmp_dir:editable"`
+		RemoteTmpDirs map[string]string `yaml:"remote_tmp_dirs,omitempty" validate:"remote_tmp_dirs:ignore"`
+		// Derive the omitted ports of the instances of a component sharing a
+		// host from the default ones, see AutoOffsetStep
+		AutoOffsetPorts bool `yaml:"auto_offset_ports,omitempty"`
 	}
 
 	// MonitoredOptions represents the monitored node configuration
@@ -72,6 +81,20 @@ type (
 		LogDir               string               `yaml:"log_dir,omitempty"`
 		NumaNode             string               `yaml:"numa_node,omitempty" validate:"numa_node:editable"`
 		ResourceControl      meta.ResourceControl `yaml:"resource_control,omitempty" validate:"resource_control:editable"`
+		// Hosts customize the monitoring agents of the hosts
+		Hosts []MonitoredHost `yaml:"hosts,omitempty" validate:"hosts:editable"`
+	}
+
+	// MonitoredHost customizes the monitoring agents of a host, e.g., for the
+	// hosts running the node_exporter managed by others
+	MonitoredHost struct {
+		Host string `yaml:"host" validate:"host:editable"`
+		// Deploy the node_exporter and blackbox_exporter on the host, the
+		// default is true unless ExternalExporterPort is set
+		MonitorAgents *bool `yaml:"monitor_agents,omitempty" validate:"monitor_agents:editable"`
+		// The port of the node_exporter running on the host not deployed by
+		// us, which is scraped by Prometheus instead
+		ExternalExporterPort int `yaml:"external_exporter_port,omitempty" validate:"external_exporter_port:editable"`
 	}
 
 	// ServerConfigs represents the server runtime configuration
@@ -91,6 +114,7 @@ type (
 		GlobalOptions    GlobalOptions       `yaml:"global,omitempty" validate:"global:editable"`
 		MonitoredOptions MonitoredOptions    `yaml:"monitored,omitempty" validate:"monitored:editable"`
 		ServerConfigs    ServerConfigs       `yaml:"server_configs,omitempty" validate:"server_configs:ignore"`
+		OSSettings       *OSSettings         `yaml:"os_settings,omitempty"`
 		TiDBServers      []TiDBSpec          `yaml:"tidb_servers"`
 		TiKVServers      []TiKVSpec          `yaml:"tikv_servers"`
 		TiFlashServers   []TiFlashSpec       `yaml:"tiflash_servers"`
@@ -103,6 +127,9 @@ type (
 		Monitors         []PrometheusSpec    `yaml:"monitoring_servers"`
 		Grafana          []GrafanaSpec       `yaml:"grafana_servers,omitempty"`
 		Alertmanager     []AlertManagerSpec  `yaml:"alertmanager_servers,omitempty"`
+
+		// the existing topology a scale-out part is added to
+		base *Specification
 	}
 )
 
@@ -138,6 +165,29 @@ type BaseMeta struct {
 	Group   string
 	Version string
 	OpsVer  *string `yaml:"last_ops_ver,omitempty"` // the version of ourself that updated the meta last time
+	// Destructive operations on protected clusters must be confirmed by an extra flag
+	Protected bool
+	// Tags are the user defined key/values of the cluster for filtering
+	Tags map[string]string `yaml:"tags,omitempty"`
+	// ConfigGeneration is increased by the operations pushing configs to the
+	// hosts, the configs are stamped with it, see ConfigStamper
+	ConfigGeneration uint64
+	// HostKeys are the fingerprints of the SSH host keys of the hosts, see
+	// HostKeyVerifier
+	HostKeys map[string]string `yaml:"host_keys,omitempty"`
+	// ComponentVersions are the versions of the components upgraded apart
+	// from the others, the components not in it are of Version
+	ComponentVersions map[string]string `yaml:"component_versions,omitempty"`
+	// ScopedSudo is set if the deploy user can only operate the units by sudo
+	ScopedSudo bool `yaml:"scoped_sudo,omitempty"`
+}
+
+// ComponentVersion returns the version of the component in the cluster
+func (m *BaseMeta) ComponentVersion(comp string) string {
+	if v, ok := m.ComponentVersions[comp]; ok {
+		return v
+	}
+	return m.Version
 }
 
 // Metadata of a cluster.
@@ -164,12 +214,49 @@ type UpgradableMetadata interface {
 	SetUser(u string)
 }
 
+// ProtectableMetadata represents a Metadata which can be protected from
+// destructive operations.
+type ProtectableMetadata interface {
+	SetProtected(protected bool)
+}
+
+// ScopedSudoMetadata represents a Metadata whose deploy user can be limited
+// to operating the units by sudo.
+type ScopedSudoMetadata interface {
+	SetScopedSudo(scoped bool)
+}
+
+// TaggableMetadata represents a Metadata which can be tagged.
+type TaggableMetadata interface {
+	SetTags(tags map[string]string)
+}
+
+// GenerationalMetadata represents a Metadata recording the generation of
+// the configs pushed to the hosts.
+type GenerationalMetadata interface {
+	SetConfigGeneration(generation uint64)
+}
+
+// HostKeyedMetadata represents a Metadata recording the SSH host keys of
+// the hosts.
+type HostKeyedMetadata interface {
+	SetHostKeys(keys map[string]string)
+}
+
+// ComponentVersionedMetadata represents a Metadata recording the versions of
+// the components upgraded apart from the others.
+type ComponentVersionedMetadata interface {
+	SetComponentVersions(versions map[string]string)
+}
+
 // NewPart implements ScaleOutTopology interface.
 func (s *Specification) NewPart() Topology {
 	return &Specification{
 		GlobalOptions:    s.GlobalOptions,
 		MonitoredOptions: s.MonitoredOptions,
 		ServerConfigs:    s.ServerConfigs,
+		OSSettings:       s.OSSettings,
+		base:             s,
 	}
 }
 
@@ -208,13 +295,35 @@ func AllComponentNames() (roles []string) {
 	return
 }
 
+// TopologySchema returns the schema of the topology file, with the names,
+// types, default values and enum values of the fields
+func TopologySchema() *clusterutil.SchemaField {
+	return clusterutil.GenerateSchema(&Specification{})
+}
+
+// topology is decoded by UnmarshalYAML without calling it recursively
+type topology Specification
+
+// YAMLAliases implements the clusterutil.YAMLAliased interface
+func (s *Specification) YAMLAliases() []interface{} {
+	return []interface{}{(*topology)(nil)}
+}
+
 // UnmarshalYAML sets default values when unmarshaling the topology file
 func (s *Specification) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	type topology Specification
 	if err := unmarshal((*topology)(s)); err != nil {
 		return err
 	}
 
+	// derive the omitted ports of co-located instances if it's enabled
+	var derived []derivedPort
+	if s.GlobalOptions.AutoOffsetPorts {
+		var err error
+		if derived, err = s.autoOffsetPorts(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
 	// set default values from tag
 	if err := defaults.Set(s); err != nil {
 		return errors.Trace(err)
@@ -242,6 +351,10 @@ func (s *Specification) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return err
 	}
 
+	if err := s.validateDerivedPorts(derived); err != nil {
+		return err
+	}
+
 	return s.Validate()
 }
 
@@ -278,6 +391,7 @@ func (s *Specification) Merge(that *Specification) *Specification {
 		GlobalOptions:    s.GlobalOptions,
 		MonitoredOptions: s.MonitoredOptions,
 		ServerConfigs:    s.ServerConfigs,
+		OSSettings:       s.OSSettings,
 		TiDBServers:      append(s.TiDBServers, that.TiDBServers...),
 		TiKVServers:      append(s.TiKVServers, that.TiKVServers...),
 		PDServers:        append(s.PDServers, that.PDServers...),
@@ -312,10 +426,14 @@ var (
 	globalOptionTypeName  = reflect.TypeOf(GlobalOptions{}).Name()
 	monitorOptionTypeName = reflect.TypeOf(MonitoredOptions{}).Name()
 	serverConfigsTypeName = reflect.TypeOf(ServerConfigs{}).Name()
+	osSettingsType        = reflect.TypeOf(&OSSettings{})
 )
 
-// Skip global/monitored options
+// Skip global/monitored options, OS settings and unexported fields
 func isSkipField(field reflect.Value) bool {
+	if !field.CanInterface() || field.Type() == osSettingsType {
+		return true
+	}
 	tp := field.Type().Name()
 	return tp == globalOptionTypeName || tp == monitorOptionTypeName || tp == serverConfigsTypeName
 }
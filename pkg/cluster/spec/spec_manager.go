@@ -17,10 +17,15 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/joomcode/errorx"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/errutil"
 	"github.com/pingcap/tiup/pkg/file"
 	"github.com/pingcap/tiup/pkg/utils"
 	"github.com/pingcap/tiup/pkg/version"
@@ -33,6 +38,21 @@ var (
 	ErrCreateDirFailed = errNS.NewType("create_dir_failed")
 	// ErrSaveMetaFailed is ErrSaveMetaFailed
 	ErrSaveMetaFailed = errNS.NewType("save_meta_failed")
+	// ErrClusterNotExist is returned when the meta file of a cluster doesn't exist
+	ErrClusterNotExist = errNS.NewType("cluster_not_exist", errutil.ErrTraitPreCheck)
+	// ErrReadOnlyProfile is returned by the operations changing clusters when
+	// the profile directory is not writable, reading clusters still works
+	ErrReadOnlyProfile = errNS.NewType("read_only_profile", errutil.ErrTraitPreCheck)
+	// ErrMetaCorrupt is returned when the meta file of a cluster is not valid YAML
+	ErrMetaCorrupt = errNS.NewType("meta_corrupt")
+
+	// ErrPropSimilarClusters is the names of the existing clusters similar to
+	// the one not existing, a []string
+	ErrPropSimilarClusters = errorx.RegisterProperty("similar_clusters")
+	// ErrPropMetaLine is the line of the meta file failed to be parsed, 0 if unknown
+	ErrPropMetaLine = errorx.RegisterPrintableProperty("meta_line")
+
+	yamlErrorLineRegexp = regexp.MustCompile(`line (\d+)`)
 )
 
 const (
@@ -50,6 +70,7 @@ const (
 type SpecManager struct {
 	base    string
 	newMeta func() Metadata
+	cache   *metaCache
 }
 
 // NewSpec create a spec instance.
@@ -57,6 +78,7 @@ func NewSpec(base string, newMeta func() Metadata) *SpecManager {
 	return &SpecManager{
 		base:    base,
 		newMeta: newMeta,
+		cache:   newMetaCache(),
 	}
 }
 
@@ -81,6 +103,13 @@ func (s *SpecManager) Path(cluster string, subpath ...string) string {
 	}, subpath...)...)
 }
 
+// BasePath returns the full path to a subpath of the dir of all the
+// clusters, it's for the files not belonging to any cluster, e.g., the ones
+// outliving the clusters destroyed.
+func (s *SpecManager) BasePath(subpath ...string) string {
+	return filepath.Join(append([]string{s.base}, subpath...)...)
+}
+
 // SaveMeta save the meta with specified cluster name.
 func (s *SpecManager) SaveMeta(clusterName string, meta Metadata) error {
 	wrapError := func(err error) *errorx.Error {
@@ -109,10 +138,13 @@ func (s *SpecManager) SaveMeta(clusterName string, meta Metadata) error {
 	}
 
 	err = file.SaveFileWithBackup(metaFile, data, backupDir)
+	s.InvalidateMetadata(clusterName)
 	if err != nil {
 		return wrapError(err)
 	}
 
+	// the summary is only a cache, it's rebuilt when listing if not saved
+	_ = s.saveSummary(clusterName, meta)
 	return nil
 }
 
@@ -127,12 +159,46 @@ func (s *SpecManager) Metadata(clusterName string, meta interface{}) error {
 
 	err = yaml.Unmarshal(yamlFile, meta)
 	if err != nil {
+		if corrupt := metaCorruptError(fname, err); corrupt != nil {
+			return corrupt
+		}
 		return errors.AddStack(err)
 	}
 
 	return nil
 }
 
+// metaCorruptError returns an ErrMetaCorrupt if err is a YAML syntax or type
+// error, errors returned by the metadata itself, e.g., a failed validation,
+// are not treated as corruption and nil is returned
+func metaCorruptError(fname string, err error) error {
+	_, isTypeErr := err.(*yaml.TypeError)
+	if !isTypeErr && !strings.HasPrefix(err.Error(), "yaml: ") {
+		return nil
+	}
+	line := 0
+	if m := yamlErrorLineRegexp.FindStringSubmatch(err.Error()); m != nil {
+		line, _ = strconv.Atoi(m[1])
+	}
+	return ErrMetaCorrupt.Wrap(err, "Metadata file %s is corrupt", fname).
+		WithProperty(ErrPropMetaLine, line).
+		WithProperty(cliutil.SuggestionFromFormat(
+			"Please fix the file manually, or restore it from the backups in %s",
+			filepath.Join(filepath.Dir(fname), BackupDirName)))
+}
+
+// CheckWritable returns ErrReadOnlyProfile if the metadata of clusters can't
+// be saved, it's checked ahead of the operations changing clusters so that
+// they don't fail halfway after the hosts have been changed.
+func (s *SpecManager) CheckWritable() error {
+	if err := utils.Writable(s.base); err != nil {
+		return ErrReadOnlyProfile.Wrap(err, "The profile directory %s is read-only", s.base).
+			WithProperty(cliutil.SuggestionFromString(
+				"Only the commands reading clusters work with a read-only profile, run this command as a user who can write the directory."))
+	}
+	return nil
+}
+
 // Exist check if the cluster exist by checking the meta file.
 func (s *SpecManager) Exist(name string) (exist bool, err error) {
 	fname := s.Path(name, metaFileName)
@@ -150,6 +216,7 @@ func (s *SpecManager) Exist(name string) (exist bool, err error) {
 
 // Remove remove the data with specified cluster name.
 func (s *SpecManager) Remove(name string) error {
+	defer s.InvalidateMetadata(name)
 	return os.RemoveAll(s.Path(name))
 }
 
@@ -173,6 +240,32 @@ func (s *SpecManager) List() (names []string, err error) {
 	return
 }
 
+// SimilarNames returns the names of the existing clusters similar to the name,
+// e.g., with typos, the most similar first. The meta files are not loaded.
+func (s *SpecManager) SimilarNames(name string) []string {
+	names, err := s.List()
+	if err != nil {
+		return nil
+	}
+	maxDist := len(name)/3 + 1
+	dists := make(map[string]int)
+	var similar []string
+	for _, n := range names {
+		d := utils.EditDistance(strings.ToLower(name), strings.ToLower(n))
+		if d <= maxDist || strings.Contains(n, name) || strings.Contains(name, n) {
+			dists[n] = d
+			similar = append(similar, n)
+		}
+	}
+	sort.SliceStable(similar, func(i, j int) bool {
+		if dists[similar[i]] != dists[similar[j]] {
+			return dists[similar[i]] < dists[similar[j]]
+		}
+		return similar[i] < similar[j]
+	})
+	return similar
+}
+
 // GetAllClusters get a metadata list of all clusters deployed by current user
 func (s *SpecManager) GetAllClusters() (map[string]Metadata, error) {
 	clusters := make(map[string]Metadata)
@@ -14,12 +14,16 @@
 package spec
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/pingcap/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -177,3 +181,76 @@ func TestSpec(t *testing.T) {
 	err = spec.Remove("name1")
 	assert.Nil(t, err)
 }
+
+func TestMetadataCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-*")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	spec := NewSpec(dir, func() Metadata {
+		return new(TestMetadata)
+	})
+	save := func(i int) {
+		err := spec.SaveMeta("name", &TestMetadata{
+			BaseMeta: BaseMeta{User: fmt.Sprintf("user-%d", i), Version: fmt.Sprintf("v%d", i)},
+			Topo:     &TestTopology{},
+		})
+		assert.Nil(t, err)
+	}
+	save(0)
+
+	m1, err := spec.CachedMetadata("name")
+	assert.Nil(t, err)
+	assert.Equal(t, "v0", m1.GetBaseMeta().Version)
+	// the metadata returned is a copy, modifying it doesn't affect the cache
+	m1.SetVersion("modified")
+	m2, err := spec.CachedMetadata("name")
+	assert.Nil(t, err)
+	assert.Equal(t, "v0", m2.GetBaseMeta().Version)
+	assert.False(t, m1.(*TestMetadata).Topo == m2.(*TestMetadata).Topo)
+
+	// writes invalidate the cache
+	save(1)
+	m3, err := spec.CachedMetadata("name")
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", m3.GetBaseMeta().Version)
+
+	// readers never observe a partially updated entry
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for r := 0; r < 8; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				m, err := spec.CachedMetadata("name")
+				if !assert.Nil(t, err) {
+					return
+				}
+				base := m.GetBaseMeta()
+				if !assert.Equal(t, "user-"+strings.TrimPrefix(base.Version, "v"), base.User) {
+					return
+				}
+				m.SetUser("modified by reader")
+			}
+		}()
+	}
+	for i := 2; i < 100; i++ {
+		save(i)
+	}
+	close(stop)
+	wg.Wait()
+
+	m4, err := spec.CachedMetadata("name")
+	assert.Nil(t, err)
+	assert.Equal(t, "v99", m4.GetBaseMeta().Version)
+
+	assert.Nil(t, spec.Remove("name"))
+	_, err = spec.CachedMetadata("name")
+	assert.True(t, os.IsNotExist(errors.Cause(err)))
+}
@@ -15,10 +15,19 @@ package spec
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/BurntSushi/toml"
+	"github.com/joomcode/errorx"
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
+	system "github.com/pingcap/tiup/pkg/cluster/template/systemd"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/localdata"
 	"gopkg.in/yaml.v2"
 )
 
@@ -219,6 +228,30 @@ tidb_servers:
 	c.Assert(got, DeepEquals, expected)
 }
 
+func (s *metaSuiteTopo) TestConfigValue(c *C) {
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+tikv_servers:
+  - host: 172.16.5.1
+    config:
+      server.advertise-status-addr: 10.0.0.1:20180
+  - host: 172.16.5.2
+    config:
+      server:
+        advertise-status-addr: 10.0.0.2:20180
+`), &topo)
+	c.Assert(err, IsNil)
+	for i, expected := range []string{"10.0.0.1:20180", "10.0.0.2:20180"} {
+		val, ok := ConfigValue(topo.TiKVServers[i].Config, "server.advertise-status-addr")
+		c.Assert(ok, IsTrue)
+		c.Assert(val, Equals, expected)
+	}
+	_, ok := ConfigValue(topo.TiKVServers[0].Config, "server.advertise-status-addr.port")
+	c.Assert(ok, IsFalse)
+	_, ok = ConfigValue(topo.TiKVServers[0].Config, "server.status-addr")
+	c.Assert(ok, IsFalse)
+}
+
 func (s *metaSuiteTopo) TestGlobalConfigPatch(c *C) {
 	topo := Specification{}
 	err := yaml.Unmarshal([]byte(`
@@ -471,3 +504,370 @@ item7 = 700
 	c.Assert(err, IsNil)
 	c.Assert(string(merge2), DeepEquals, expected)
 }
+
+func (s *metaSuiteTopo) TestAutoOffset(c *C) {
+	// the ports are not derived unless it's enabled
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+tikv_servers:
+  - host: 172.16.5.138
+  - host: 172.16.5.138
+`), &topo)
+	c.Assert(err, ErrorMatches, ".*port conflict.*")
+
+	topo = Specification{}
+	err = yaml.Unmarshal([]byte(`
+global:
+  deploy_dir: "/deploy"
+  auto_offset_ports: true
+tikv_servers:
+  - host: 172.16.5.138
+  - host: 172.16.5.138
+  - host: 172.16.5.138
+    status_port: 30180
+  - host: 172.16.5.139
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.TiKVServers[0].Port, Equals, 20160)
+	c.Assert(topo.TiKVServers[0].StatusPort, Equals, 20180)
+	c.Assert(topo.TiKVServers[1].Port, Equals, 20260)
+	c.Assert(topo.TiKVServers[1].StatusPort, Equals, 20280)
+	c.Assert(topo.TiKVServers[1].DeployDir, Equals, "/deploy/tikv-20260")
+	c.Assert(topo.TiKVServers[2].Port, Equals, 20360)
+	c.Assert(topo.TiKVServers[2].StatusPort, Equals, 30180)
+	c.Assert(topo.TiKVServers[3].Port, Equals, 20160)
+
+	// the derived values are persisted
+	data, err := yaml.Marshal(&topo)
+	c.Assert(err, IsNil)
+	reloaded := Specification{}
+	c.Assert(yaml.Unmarshal(data, &reloaded), IsNil)
+	c.Assert(reloaded.TiKVServers[1].Port, Equals, 20260)
+
+	// instances added by scale-out are counted after the existing ones
+	newPart := topo.NewPart()
+	err = yaml.Unmarshal([]byte(`
+tikv_servers:
+  - host: 172.16.5.139
+`), newPart)
+	c.Assert(err, IsNil)
+	c.Assert(newPart.(*Specification).TiKVServers[0].Port, Equals, 20260)
+
+	// derived values must not collide with explicit ones
+	err = yaml.Unmarshal([]byte(`
+global:
+  auto_offset_ports: true
+tikv_servers:
+  - host: 172.16.5.138
+  - host: 172.16.5.138
+    port: 20280
+`), &Specification{})
+	c.Assert(err, ErrorMatches, ".*derived by auto offset.*")
+}
+
+func (s *metaSuiteTopo) TestOSSettings(c *C) {
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+os_settings:
+  sysctl:
+    vm.swappiness: "0"
+    net.core.somaxconn: "32768"
+  limits:
+    - domain: tidb
+      type: soft
+      item: nofile
+      value: "1000000"
+  transparent_hugepage: never
+tikv_servers:
+  - host: 172.16.5.140
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.Validate(), IsNil)
+
+	files := topo.OSSettings.DropIns("test")
+	c.Assert(files, HasLen, 3)
+	c.Assert(files[0].Path, Equals, "/etc/sysctl.d/60-tiup-test.conf")
+	c.Assert(string(files[0].Content), Equals, "# managed by TiUP for cluster test, DO NOT EDIT\n"+
+		"net.core.somaxconn = 32768\nvm.swappiness = 0\n")
+	c.Assert(string(files[1].Content), Matches, "(?s).*\ntidb    soft    nofile    1000000\n")
+	c.Assert(files[2].Reload, Equals, "echo never > "+THPEnabledPath)
+
+	// the settings are inherited by the scale-out part
+	c.Assert(topo.NewPart().(*Specification).OSSettings, Equals, topo.OSSettings)
+
+	topo.OSSettings.TransparentHugepage = "sometimes"
+	c.Assert(topo.Validate(), NotNil)
+	topo.OSSettings.TransparentHugepage = ""
+	topo.OSSettings.Sysctl["vm.swappiness"] = "0; reboot"
+	c.Assert(topo.Validate(), NotNil)
+}
+
+func (s *metaSuiteTopo) TestSelector(c *C) {
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+server_configs:
+  tikv:
+    server.labels: { zone: us-west-1b }
+tikv_servers:
+  - host: 10.1.2.3
+    config:
+      server.labels: { zone: us-west-1a, host: h1 }
+  - host: 10.1.2.4
+    config:
+      server.labels: { zone: us-west-1a, host: h2 }
+  - host: 10.1.2.5
+pd_servers:
+  - host: 10.1.2.3
+`), &topo)
+	c.Assert(err, IsNil)
+
+	matched := func(expr string) []string {
+		selector, err := ParseSelector(expr)
+		c.Assert(err, IsNil)
+		var ids []string
+		topo.IterInstance(func(inst Instance) {
+			if selector.Match(inst) {
+				ids = append(ids, inst.ID())
+			}
+		})
+		return ids
+	}
+	c.Assert(matched("role=tikv and zone=us-west-1a and not host=10.1.2.3"), DeepEquals, []string{"10.1.2.4:20160"})
+	c.Assert(matched("zone=us-west-1b || port=2379"), DeepEquals, []string{"10.1.2.3:2379", "10.1.2.5:20160"})
+	c.Assert(matched("host=10.1.2.3 AND (role=pd OR label.host='h1')"), DeepEquals, []string{"10.1.2.3:2379", "10.1.2.3:20160"})
+	c.Assert(matched("role!=tikv"), DeepEquals, []string{"10.1.2.3:2379"})
+	c.Assert(matched(""), HasLen, 4)
+
+	for expr, pos := range map[string]int{
+		"role=tikv and":      14,
+		"role=tikv zone=a":   11,
+		"(role=tikv":         11,
+		"rack=r1":            1,
+		"port=abc":           6,
+		"host='10.1.2.3":     6,
+		"role=tikv && & x=y": 14,
+	} {
+		_, err := ParseSelector(expr)
+		c.Assert(err, NotNil, Commentf("selector: %s", expr))
+		c.Assert(errorx.IsOfType(err, ErrSelectorSyntax), IsTrue)
+		p, ok := errorx.ExtractProperty(errorx.Cast(err), ErrPropSelectorPosition)
+		c.Assert(ok, IsTrue)
+		c.Assert(p, Equals, pos, Commentf("selector: %s", expr))
+	}
+}
+
+func (s *metaSuiteTopo) TestTemplateOverride(c *C) {
+	dir := c.MkDir()
+	os.Setenv(localdata.EnvNameComponentDataDir, dir)
+	defer os.Unsetenv(localdata.EnvNameComponentDataDir)
+	c.Assert(Initialize("cluster"), IsNil)
+
+	tplDir := ClusterPath("test", TemplateOverrideDirName)
+	c.Assert(os.MkdirAll(tplDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(tplDir, TemplateManifestName), []byte(`
+templates:
+  - component: tikv
+    template: run_tikv.sh.tpl
+    version: ">= v4.0.0, < v5.0.0"
+    file: run_tikv_v4.sh.tpl
+  - component: tikv
+    template: run_tikv.sh.tpl
+    version: ">= v5.0.0"
+    file: run_tikv_bad.sh.tpl
+  - component: tikv
+    template: system.service.tpl
+    file: tikv.service.tpl
+  - component: tispark-worker
+    template: start_tispark_slave.sh.tpl
+    file: start_tispark_slave_bad.sh.tpl
+`), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(tplDir, "tikv.service.tpl"), []byte("User={{.User}}\nLimitNOFILE=65536\n"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(tplDir, "start_tispark_slave_bad.sh.tpl"), []byte("{{.MasterURL}}\n"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(tplDir, "run_tikv_v4.sh.tpl"), []byte("exec bin/tikv-server --addr {{.IP}}:{{.Port}}\n"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(tplDir, "run_tikv_bad.sh.tpl"), []byte("exec bin/tikv-server --zone {{.Zone}}\n"), 0644), IsNil)
+
+	cfg := scripts.NewTiKVScript("10.0.1.1", "/deploy", "/data", "/log")
+	fp := filepath.Join(dir, "run_tikv.sh")
+
+	// no override for other versions or components
+	o, err := FindTemplateOverride("test", "v3.0.0", ComponentTiKV, "run_tikv.sh.tpl")
+	c.Assert(err, IsNil)
+	c.Assert(o, IsNil)
+	o, err = FindTemplateOverride("test", "v4.0.0", ComponentTiDB, "run_tikv.sh.tpl")
+	c.Assert(err, IsNil)
+	c.Assert(o, IsNil)
+
+	c.Assert(RenderTemplate(cfg, "test", "v4.0.8", ComponentTiKV, "run_tikv.sh.tpl", fp), IsNil)
+	data, err := ioutil.ReadFile(fp)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "exec bin/tikv-server --addr 10.0.1.1:20160\n")
+	c.Assert(UsedTemplateOverrides("test"), DeepEquals, []string{"tikv/run_tikv.sh.tpl -> templates/run_tikv_v4.sh.tpl"})
+	c.Assert(UsedTemplateOverrides("test"), HasLen, 0)
+
+	err = RenderTemplate(cfg, "test", "v5.0.0", ComponentTiKV, "run_tikv.sh.tpl", fp)
+	c.Assert(errorx.IsOfType(err, ErrTemplateOverride), IsTrue)
+	c.Assert(err, ErrorMatches, ".*references the variable .Zone.*")
+
+	// the systemd units and the other configs can be overridden too
+	c.Assert(RenderTemplate(system.NewConfig(ComponentTiKV, "tidb", "/deploy"), "test", "v4.0.8", ComponentTiKV, "system.service.tpl", fp), IsNil)
+	data, err = ioutil.ReadFile(fp)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "User=tidb\nLimitNOFILE=65536\n")
+	content, err := GenerateTemplate(system.NewConfig(ComponentPD, "tidb", "/deploy"), "test", "v4.0.8", ComponentPD, "system.service.tpl")
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Matches, "(?s).*ExecStart=/deploy/scripts/run_pd.sh.*")
+	c.Assert(UsedTemplateOverrides("test"), DeepEquals, []string{"tikv/system.service.tpl -> templates/tikv.service.tpl"})
+
+	// the variables promoted from the embedded structs are listed
+	slave := scripts.NewTiSparkSlaveScript(scripts.NewTiSparkEnv("10.0.1.1"))
+	err = RenderTemplate(slave, "test", "v4.0.8", "tispark-worker", "start_tispark_slave.sh.tpl", fp)
+	c.Assert(errorx.IsOfType(err, ErrTemplateOverride), IsTrue)
+	suggestion, _ := errorx.ExtractProperty(err, errutil.ErrPropSuggestion)
+	c.Assert(suggestion, Matches, "(?s).*\\.TiSparkMaster, .*")
+}
+
+func (s *metaSuiteTopo) TestTopologySchema(c *C) {
+	schema := TopologySchema()
+	var global *clusterutil.SchemaField
+	for _, f := range schema.Fields {
+		if f.Name == "global" {
+			global = f
+		}
+	}
+	c.Assert(global, NotNil)
+
+	var arch *clusterutil.SchemaField
+	for _, f := range global.Fields {
+		if f.Name == "arch" {
+			arch = f
+		}
+	}
+	c.Assert(arch, NotNil)
+	c.Assert(arch.Enum, DeepEquals, []string{"amd64", "arm64"})
+	c.Assert(schema.NearestField("spec.TiDBSpec", "deploy_dirr"), Equals, "deploy_dir")
+
+	// the top level fields are decoded through an alias type
+	c.Assert(schema.NearestField("spec.topology", "globall"), Equals, "global")
+	dir, err := ioutil.TempDir("", "tiup-topology-schema-test")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "topology.yaml")
+	c.Assert(ioutil.WriteFile(file, []byte(`
+globall:
+  user: tidb
+tidb_servers:
+  - host: 172.16.5.1
+`), 0644), IsNil)
+	err = clusterutil.ParseTopologyYaml(file, new(Specification))
+	c.Assert(err, NotNil)
+	c.Assert(err, ErrorMatches, "(?s).*line 2: unknown field `globall`, did you mean `global`\\?.*")
+}
+
+func (s *metaSuiteTopo) TestGrafanaProvisioning(c *C) {
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+grafana_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.2
+    provisioning: api
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.Grafana[0].Provisioning, Equals, GrafanaProvisioningFile)
+	c.Assert(topo.Grafana[0].APIProvisioning(), IsFalse)
+	c.Assert(topo.Grafana[1].APIProvisioning(), IsTrue)
+
+	err = yaml.Unmarshal([]byte(`
+grafana_servers:
+  - host: 172.16.5.1
+    provisioning: http
+`), &topo)
+	c.Assert(err, NotNil)
+}
+
+func (s *metaSuiteTopo) TestProxySettings(c *C) {
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  proxy:
+    http_proxy: http://proxy.example.com:3128
+    https_proxy: http://proxy.example.com:3128
+    no_proxy: localhost,172.16.5.0/24
+    hosts:
+      172.16.5.2:
+        http_proxy: socks5://172.16.5.100:1080
+tidb_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.2
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.GlobalOptions.ProxyEnv("172.16.5.1"), DeepEquals, []string{
+		"http_proxy=http://proxy.example.com:3128",
+		"HTTP_PROXY=http://proxy.example.com:3128",
+		"https_proxy=http://proxy.example.com:3128",
+		"HTTPS_PROXY=http://proxy.example.com:3128",
+		"no_proxy=localhost,172.16.5.0/24",
+		"NO_PROXY=localhost,172.16.5.0/24",
+	})
+	env := topo.GlobalOptions.ProxyEnv("172.16.5.2")
+	c.Assert(env[0], Equals, "http_proxy=socks5://172.16.5.100:1080")
+	c.Assert(env[2], Equals, "https_proxy=http://proxy.example.com:3128")
+
+	topo = Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+tidb_servers:
+  - host: 172.16.5.1
+`), &topo), IsNil)
+	c.Assert(topo.GlobalOptions.ProxyEnv("172.16.5.1"), IsNil)
+
+	for _, proxy := range []string{
+		"    http_proxy: proxy.example.com:3128",
+		"    https_proxy: ftp://proxy.example.com",
+		"    http_proxy: http://",
+		`    http_proxy: "http://proxy.example.com:3128\"\nExecStartPre=/bin/true"`,
+		"    no_proxy: localhost, 127.0.0.1",
+		"    hosts:\n      172.16.5.9:\n        http_proxy: http://proxy.example.com:3128",
+	} {
+		topo = Specification{}
+		err = yaml.Unmarshal([]byte(`
+global:
+  proxy:
+`+proxy+`
+tidb_servers:
+  - host: 172.16.5.1
+`), &topo)
+		c.Assert(err, NotNil, Commentf("proxy: %s", proxy))
+	}
+}
+
+func (s *metaSuiteTopo) TestSetDataDir(c *C) {
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+tikv_servers:
+  - host: 172.16.5.1
+    port: 20160
+    data_dir: /data/tikv-20160
+  - host: 172.16.5.1
+    port: 20161
+    status_port: 20181
+    data_dir: /data/tikv-20161
+tidb_servers:
+  - host: 172.16.5.1
+`), &topo)
+	c.Assert(err, IsNil)
+
+	var tikv, tidb []Instance
+	for _, comp := range topo.ComponentsByStartOrder() {
+		switch comp.Name() {
+		case ComponentTiKV:
+			tikv = comp.Instances()
+		case ComponentTiDB:
+			tidb = comp.Instances()
+		}
+	}
+	c.Assert(topo.SetDataDir(tikv[1], "/data2/tikv-20161"), IsNil)
+	c.Assert(topo.TiKVServers[0].DataDir, Equals, "/data/tikv-20160")
+	c.Assert(topo.TiKVServers[1].DataDir, Equals, "/data2/tikv-20161")
+
+	// the instances without data directories
+	c.Assert(topo.SetDataDir(tidb[0], "/data/tidb"), NotNil)
+}
@@ -0,0 +1,201 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/template"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/version"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// TemplateOverrideDirName is the directory of override templates under the directory of a cluster
+	TemplateOverrideDirName = "templates"
+	// TemplateManifestName is the manifest of override templates in TemplateOverrideDirName
+	TemplateManifestName = "manifest.yaml"
+)
+
+var (
+	// ErrTemplateOverride is returned when an override template is invalid
+	ErrTemplateOverride = errNS.NewType("template_override", errutil.ErrTraitPreCheck)
+)
+
+// TemplateOverride declares a template replacing an embedded template of a
+// component for the cluster versions matched by the constraint, e.g.:
+//
+//	templates:
+//	  - component: tikv
+//	    template: run_tikv.sh.tpl
+//	    version: ">= v4.0.0, < v5.0.0"
+//	    file: run_tikv_v4.sh.tpl
+type TemplateOverride struct {
+	Component string `yaml:"component"`
+	Template  string `yaml:"template"`          // the file name of the embedded template replaced, e.g., system.service.tpl
+	Version   string `yaml:"version,omitempty"` // the constraint of cluster versions, empty matches all
+	File      string `yaml:"file"`              // the path of the template relative to the templates directory
+}
+
+// String implements the fmt.Stringer interface
+func (o *TemplateOverride) String() string {
+	return fmt.Sprintf("%s/%s -> %s", o.Component, o.Template, filepath.Join(TemplateOverrideDirName, o.File))
+}
+
+type templateManifest struct {
+	Templates []*TemplateOverride `yaml:"templates"`
+}
+
+// the override templates used by each cluster in this process
+var (
+	usedTemplateOverridesMu sync.Mutex
+	usedTemplateOverrides   = make(map[string]map[string]struct{})
+)
+
+// FindTemplateOverride returns the override template of the embedded template
+// of the component for the cluster version, nil if there isn't one. The first
+// matched one in the manifest is returned if there are several.
+func FindTemplateOverride(clusterName, clusterVersion, comp, tplName string) (*TemplateOverride, error) {
+	if !initialized {
+		return nil, nil
+	}
+	fp := ClusterPath(clusterName, TemplateOverrideDirName, TemplateManifestName)
+	data, err := ioutil.ReadFile(fp)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ErrTemplateOverride.Wrap(err, "Failed to read the manifest of override templates %s", fp)
+	}
+
+	var manifest templateManifest
+	if err := yaml.UnmarshalStrict(data, &manifest); err != nil {
+		return nil, ErrTemplateOverride.Wrap(err, "Failed to parse the manifest of override templates %s", fp)
+	}
+	for _, o := range manifest.Templates {
+		if o.Component != comp || o.Template != tplName {
+			continue
+		}
+		match, err := version.MatchConstraint(clusterVersion, o.Version)
+		if err != nil {
+			return nil, ErrTemplateOverride.Wrap(err, "Invalid override template %s in %s", o, fp)
+		}
+		if match {
+			return o, nil
+		}
+	}
+	return nil, nil
+}
+
+// UsedTemplateOverrides returns the override templates used by the cluster
+// since the last call, sorted
+func UsedTemplateOverrides(clusterName string) []string {
+	usedTemplateOverridesMu.Lock()
+	defer usedTemplateOverridesMu.Unlock()
+	used := make([]string, 0, len(usedTemplateOverrides[clusterName]))
+	for o := range usedTemplateOverrides[clusterName] {
+		used = append(used, o)
+	}
+	delete(usedTemplateOverrides, clusterName)
+	sort.Strings(used)
+	return used
+}
+
+func recordTemplateOverride(clusterName string, o *TemplateOverride) {
+	usedTemplateOverridesMu.Lock()
+	defer usedTemplateOverridesMu.Unlock()
+	if usedTemplateOverrides[clusterName] == nil {
+		usedTemplateOverrides[clusterName] = make(map[string]struct{})
+	}
+	usedTemplateOverrides[clusterName][o.String()] = struct{}{}
+}
+
+var missingFieldRegexp = regexp.MustCompile(`can't evaluate field (\w+)`)
+
+// RenderTemplate renders the embedded template tplName of the component to
+// fp by gen, the override template of the cluster is rendered instead if
+// there is one matching the cluster version
+func RenderTemplate(gen template.ConfigGenerator, clusterName, clusterVersion, comp, tplName, fp string) error {
+	content, err := GenerateTemplate(gen, clusterName, clusterVersion, comp, tplName)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, content, 0755)
+}
+
+// GenerateTemplate returns the content of the embedded template tplName of the
+// component generated by gen, the override template of the cluster is used
+// instead if there is one matching the cluster version
+func GenerateTemplate(gen template.ConfigGenerator, clusterName, clusterVersion, comp, tplName string) ([]byte, error) {
+	o, err := FindTemplateOverride(clusterName, clusterVersion, comp, tplName)
+	if err != nil {
+		return nil, err
+	}
+	if o == nil {
+		return gen.Config()
+	}
+
+	tpl, err := ioutil.ReadFile(ClusterPath(clusterName, TemplateOverrideDirName, o.File))
+	if err != nil {
+		return nil, ErrTemplateOverride.Wrap(err, "Failed to read the override template %s", o)
+	}
+	content, err := gen.ConfigWithTemplate(string(tpl))
+	if err != nil {
+		if m := missingFieldRegexp.FindStringSubmatch(err.Error()); m != nil {
+			return nil, ErrTemplateOverride.New("The override template %s references the variable .%s, which is not provided for %s", o, m[1], comp).
+				WithProperty(cliutil.SuggestionFromFormat("The variables provided for %s are: %s", comp, strings.Join(templateVariables(gen), ", ")))
+		}
+		return nil, ErrTemplateOverride.Wrap(err, "Failed to render the override template %s", o)
+	}
+	recordTemplateOverride(clusterName, o)
+	return content, nil
+}
+
+// templateVariables returns the names of the variables provided to templates by gen
+func templateVariables(gen template.ConfigGenerator) []string {
+	return structFields(reflect.Indirect(reflect.ValueOf(gen)).Type())
+}
+
+// structFields returns the exported fields of t, including the ones promoted
+// from the embedded structs
+func structFields(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				names = append(names, structFields(ft)...)
+				continue
+			}
+		}
+		if f.PkgPath == "" {
+			names = append(names, "."+f.Name)
+		}
+	}
+	return names
+}
@@ -125,7 +125,7 @@ type TiDBInstance struct {
 
 // InitConfig implement Instance interface
 func (i *TiDBInstance) InitConfig(e executor.Executor, clusterName, clusterVersion, deployUser string, paths meta.DirPaths) error {
-	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, deployUser, paths); err != nil {
+	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, clusterName, clusterVersion, deployUser, paths); err != nil {
 		return err
 	}
 
@@ -139,7 +139,7 @@ func (i *TiDBInstance) InitConfig(e executor.Executor, clusterName, clusterVersi
 		AppendEndpoints(i.topo.Endpoints(deployUser)...).
 		WithListenHost(i.GetListenHost())
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_tidb_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := RenderTemplate(cfg, clusterName, clusterVersion, ComponentTiDB, "run_tidb.sh.tpl", fp); err != nil {
 		return err
 	}
 
@@ -154,9 +154,30 @@ type TiKVInstance struct {
 	topo *Specification
 }
 
+// Labels returns the labels of the instance set by server.labels, the ones
+// set in the config of the instance override the ones in server_configs
+func (i *TiKVInstance) Labels() map[string]string {
+	labels := make(map[string]string)
+	var global map[string]interface{}
+	if i.topo != nil {
+		global = i.topo.ServerConfigs.TiKV
+	}
+	merged, err := merge(global, i.InstanceSpec.(TiKVSpec).Config)
+	if err != nil {
+		return labels
+	}
+	server, _ := merged["server"].(map[string]interface{})
+	if m, ok := server["labels"].(map[string]interface{}); ok {
+		for k, v := range m {
+			labels[k] = fmt.Sprint(v)
+		}
+	}
+	return labels
+}
+
 // InitConfig implement Instance interface
 func (i *TiKVInstance) InitConfig(e executor.Executor, clusterName, clusterVersion, deployUser string, paths meta.DirPaths) error {
-	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, deployUser, paths); err != nil {
+	if err := i.BaseInstance.InitConfig(e, i.topo.GlobalOptions, clusterName, clusterVersion, deployUser, paths); err != nil {
 		return err
 	}
 
@@ -172,7 +193,7 @@ func (i *TiKVInstance) InitConfig(e executor.Executor, clusterName, clusterVersi
 		AppendEndpoints(i.topo.Endpoints(deployUser)...).
 		WithListenHost(i.GetListenHost())
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_tikv_%s_%d.sh", i.GetHost(), i.GetPort()))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := RenderTemplate(cfg, clusterName, clusterVersion, ComponentTiKV, "run_tikv.sh.tpl", fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "scripts", "run_tikv.sh")
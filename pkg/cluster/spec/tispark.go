@@ -183,16 +183,25 @@ func (i *TiSparkMasterInstance) InitConfig(e executor.Executor, clusterName, clu
 	port := i.GetPort()
 	sysCfg := filepath.Join(paths.Cache, fmt.Sprintf("%s-%s-%d.service", comp, host, port))
 
-	systemCfg := system.NewTiSparkConfig(comp, deployUser, paths.Deploy, i.GetJavaHome())
+	envFile, err := SyncProxyEnvFile(e, &i.topo.GlobalOptions, host, paths)
+	if err != nil {
+		return err
+	}
+	systemCfg := system.NewTiSparkConfig(comp, deployUser, paths.Deploy, i.GetJavaHome()).
+		WithEnvironmentFile(envFile)
 
-	if err := systemCfg.ConfigToFile(sysCfg); err != nil {
+	if err := RenderTemplate(systemCfg, clusterName, clusterVersion, comp, "tispark.service.tpl", sysCfg); err != nil {
 		return errors.Trace(err)
 	}
-	tgt := filepath.Join("/tmp", comp+"_"+uuid.New().String()+".service")
+	unit := fmt.Sprintf("/etc/systemd/system/%s-%d.service", comp, port)
+	if err := CheckConfigConflict(e, sysCfg, unit); err != nil {
+		return err
+	}
+	tgt := filepath.Join(i.topo.GlobalOptions.RemoteTmpDirOf(host), comp+"_"+uuid.New().String()+".service")
 	if err := e.Transfer(sysCfg, tgt, false); err != nil {
 		return errors.Annotatef(err, "transfer from %s to %s failed", sysCfg, tgt)
 	}
-	cmd := fmt.Sprintf("mv %s /etc/systemd/system/%s-%d.service", tgt, comp, port)
+	cmd := fmt.Sprintf("mv %s %s", tgt, unit)
 	if _, _, err := e.Execute(cmd, true); err != nil {
 		return errors.Annotatef(err, "execute: %s", cmd)
 	}
@@ -211,7 +220,7 @@ func (i *TiSparkMasterInstance) InitConfig(e executor.Executor, clusterName, clu
 		WithCustomFields(i.GetCustomFields())
 	// transfer spark-defaults.conf
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("spark-defaults-%s-%d.conf", host, port))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := RenderTemplate(cfg, clusterName, clusterVersion, comp, "spark-defaults.conf.tpl", fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "conf", "spark-defaults.conf")
@@ -225,7 +234,7 @@ func (i *TiSparkMasterInstance) InitConfig(e executor.Executor, clusterName, clu
 		WithCustomEnv(i.GetCustomEnvs())
 	// transfer spark-env.sh file
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("spark-env-%s-%d.sh", host, port))
-	if err := env.ScriptToFile(fp); err != nil {
+	if err := RenderTemplate(env, clusterName, clusterVersion, comp, "spark-env.sh.tpl", fp); err != nil {
 		return err
 	}
 	// tispark files are all in a "spark" sub-directory of deploy dir
@@ -311,16 +320,25 @@ func (i *TiSparkWorkerInstance) InitConfig(e executor.Executor, clusterName, clu
 	port := i.GetPort()
 	sysCfg := filepath.Join(paths.Cache, fmt.Sprintf("%s-%s-%d.service", comp, host, port))
 
-	systemCfg := system.NewTiSparkConfig(comp, deployUser, paths.Deploy, i.GetJavaHome())
+	envFile, err := SyncProxyEnvFile(e, &i.topo.GlobalOptions, host, paths)
+	if err != nil {
+		return err
+	}
+	systemCfg := system.NewTiSparkConfig(comp, deployUser, paths.Deploy, i.GetJavaHome()).
+		WithEnvironmentFile(envFile)
 
-	if err := systemCfg.ConfigToFile(sysCfg); err != nil {
+	if err := RenderTemplate(systemCfg, clusterName, clusterVersion, comp, "tispark.service.tpl", sysCfg); err != nil {
 		return errors.Trace(err)
 	}
-	tgt := filepath.Join("/tmp", comp+"_"+uuid.New().String()+".service")
+	unit := fmt.Sprintf("/etc/systemd/system/%s-%d.service", comp, port)
+	if err := CheckConfigConflict(e, sysCfg, unit); err != nil {
+		return err
+	}
+	tgt := filepath.Join(i.topo.GlobalOptions.RemoteTmpDirOf(host), comp+"_"+uuid.New().String()+".service")
 	if err := e.Transfer(sysCfg, tgt, false); err != nil {
 		return errors.Annotatef(err, "transfer from %s to %s failed", sysCfg, tgt)
 	}
-	cmd := fmt.Sprintf("mv %s /etc/systemd/system/%s-%d.service", tgt, comp, port)
+	cmd := fmt.Sprintf("mv %s %s", tgt, unit)
 	if _, _, err := e.Execute(cmd, true); err != nil {
 		return errors.Annotatef(err, "execute: %s", cmd)
 	}
@@ -339,7 +357,7 @@ func (i *TiSparkWorkerInstance) InitConfig(e executor.Executor, clusterName, clu
 		WithCustomFields(i.topo.TiSparkMasters[0].SparkConfigs)
 	// transfer spark-defaults.conf
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("spark-defaults-%s-%d.conf", host, port))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := RenderTemplate(cfg, clusterName, clusterVersion, comp, "spark-defaults.conf.tpl", fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(paths.Deploy, "conf", "spark-defaults.conf")
@@ -354,7 +372,7 @@ func (i *TiSparkWorkerInstance) InitConfig(e executor.Executor, clusterName, clu
 		WithCustomEnv(i.topo.TiSparkMasters[0].SparkEnvs)
 	// transfer spark-env.sh file
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("spark-env-%s-%d.sh", host, port))
-	if err := env.ScriptToFile(fp); err != nil {
+	if err := RenderTemplate(env, clusterName, clusterVersion, comp, "spark-env.sh.tpl", fp); err != nil {
 		return err
 	}
 	// tispark files are all in a "spark" sub-directory of deploy dir
@@ -365,11 +383,7 @@ func (i *TiSparkWorkerInstance) InitConfig(e executor.Executor, clusterName, clu
 
 	// transfer start-slave.sh
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("start-tispark-slave-%s-%d.sh", host, port))
-	slaveSh, err := env.SlaveScriptWithTemplate()
-	if err != nil {
-		return err
-	}
-	if err := ioutil.WriteFile(fp, slaveSh, 0755); err != nil {
+	if err := RenderTemplate(scripts.NewTiSparkSlaveScript(env), clusterName, clusterVersion, comp, "start_tispark_slave.sh.tpl", fp); err != nil {
 		return err
 	}
 	dst = filepath.Join(paths.Deploy, "sbin", "start-slave.sh")
@@ -32,6 +32,10 @@ func GetSpecManager() *SpecManager {
 	return tidbSpec
 }
 
+// MetaSchemaVersion is the version of the layout of the metadata files, it's
+// bumped when the layout changes incompatibly
+const MetaSchemaVersion = 1
+
 // ClusterMeta is the specification of generic cluster metadata
 type ClusterMeta struct {
 	User    string `yaml:"user"`         // the user to run and manage cluster on remote
@@ -39,11 +43,35 @@ type ClusterMeta struct {
 	//EnableTLS      bool   `yaml:"enable_tls"`
 	//EnableFirewall bool   `yaml:"firewall"`
 	OpsVer string `yaml:"last_ops_ver,omitempty"` // the version of ourself that updated the meta last time
+	// Destructive operations on the cluster require an extra flag to confirm
+	Protected bool `yaml:"protected,omitempty"`
+	// User defined key/values of the cluster, e.g., team=payments
+	Tags map[string]string `yaml:"tags,omitempty"`
+	// The generation of the configs pushed to the hosts, increased by each
+	// succeeded operation pushing configs
+	ConfigGeneration uint64 `yaml:"config_generation,omitempty"`
+	// The fingerprints of the SSH host keys of the hosts, recorded when the
+	// hosts are connected the first time
+	HostKeys map[string]string `yaml:"host_keys,omitempty"`
+	// The versions of the components upgraded apart from the others, the
+	// components not in it are of the version of the cluster
+	ComponentVersions map[string]string `yaml:"component_versions,omitempty"`
+	// The deploy user can only operate the units of the cluster by sudo, it's
+	// bootstrapped by deploy with --bootstrap-user
+	ScopedSudo bool `yaml:"scoped_sudo,omitempty"`
 
 	Topology *Specification `yaml:"topology"`
 }
 
-var _ UpgradableMetadata = &ClusterMeta{}
+var (
+	_ UpgradableMetadata         = &ClusterMeta{}
+	_ ProtectableMetadata        = &ClusterMeta{}
+	_ TaggableMetadata           = &ClusterMeta{}
+	_ GenerationalMetadata       = &ClusterMeta{}
+	_ HostKeyedMetadata          = &ClusterMeta{}
+	_ ComponentVersionedMetadata = &ClusterMeta{}
+	_ ScopedSudoMetadata         = &ClusterMeta{}
+)
 
 // SetVersion implement UpgradableMetadata interface.
 func (m *ClusterMeta) SetVersion(s string) {
@@ -55,6 +83,36 @@ func (m *ClusterMeta) SetUser(s string) {
 	m.User = s
 }
 
+// SetProtected implement ProtectableMetadata interface.
+func (m *ClusterMeta) SetProtected(protected bool) {
+	m.Protected = protected
+}
+
+// SetScopedSudo implement ScopedSudoMetadata interface.
+func (m *ClusterMeta) SetScopedSudo(scoped bool) {
+	m.ScopedSudo = scoped
+}
+
+// SetConfigGeneration implement GenerationalMetadata interface.
+func (m *ClusterMeta) SetConfigGeneration(generation uint64) {
+	m.ConfigGeneration = generation
+}
+
+// SetHostKeys implement HostKeyedMetadata interface.
+func (m *ClusterMeta) SetHostKeys(keys map[string]string) {
+	m.HostKeys = keys
+}
+
+// SetComponentVersions implement ComponentVersionedMetadata interface.
+func (m *ClusterMeta) SetComponentVersions(versions map[string]string) {
+	m.ComponentVersions = versions
+}
+
+// SetTags implement TaggableMetadata interface.
+func (m *ClusterMeta) SetTags(tags map[string]string) {
+	m.Tags = tags
+}
+
 // GetTopology implement Metadata interface.
 func (m *ClusterMeta) GetTopology() Topology {
 	return m.Topology
@@ -73,9 +131,17 @@ func (m *ClusterMeta) SetTopology(topo Topology) {
 // GetBaseMeta implements Metadata interface.
 func (m *ClusterMeta) GetBaseMeta() *BaseMeta {
 	return &BaseMeta{
-		Version: m.Version,
-		User:    m.User,
-		OpsVer:  &m.OpsVer,
+		Version:   m.Version,
+		User:      m.User,
+		OpsVer:    &m.OpsVer,
+		Protected: m.Protected,
+		Tags:      m.Tags,
+
+		ConfigGeneration: m.ConfigGeneration,
+		HostKeys:         m.HostKeys,
+
+		ComponentVersions: m.ComponentVersions,
+		ScopedSudo:        m.ScopedSudo,
 	}
 }
 
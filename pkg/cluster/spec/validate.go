@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -34,6 +35,7 @@ var (
 	errNSDeploy              = errNS.NewSubNamespace("deploy")
 	errDeployDirConflict     = errNSDeploy.NewType("dir_conflict", errutil.ErrTraitPreCheck)
 	errDeployPortConflict    = errNSDeploy.NewType("port_conflict", errutil.ErrTraitPreCheck)
+	errDeployHostConflict    = errNSDeploy.NewType("host_conflict", errutil.ErrTraitPreCheck)
 	ErrNoTiSparkMaster       = errors.New("there must be a Spark master node if you want to use the TiSpark component")
 	ErrMultipleTiSparkMaster = errors.New("a TiSpark enabled cluster with more than 1 Spark master node is not supported")
 	ErrMultipleTisparkWorker = errors.New("multiple TiSpark workers on the same host is not supported by Spark")
@@ -227,7 +229,7 @@ func CheckClusterPortConflict(clusterList map[string]Metadata, clusterName strin
 					port:        port,
 				})
 			}
-			if !uniqueHosts.Exist(inst.GetHost()) {
+			if !uniqueHosts.Exist(inst.GetHost()) && mOpt.DeployAgents(inst.GetHost()) {
 				uniqueHosts.Insert(inst.GetHost())
 				existingEntries = append(existingEntries,
 					Entry{
@@ -257,7 +259,7 @@ func CheckClusterPortConflict(clusterList map[string]Metadata, clusterName strin
 		if mOpt == nil {
 			return
 		}
-		if !uniqueHosts.Exist(inst.GetHost()) {
+		if !uniqueHosts.Exist(inst.GetHost()) && mOpt.DeployAgents(inst.GetHost()) {
 			uniqueHosts.Insert(inst.GetHost())
 			currentEntries = append(currentEntries,
 				Entry{
@@ -307,6 +309,52 @@ Please change to use another port or another host.
 	return nil
 }
 
+// CheckClusterHostConflict checks if any instance of the topology is placed on a host
+// that is already used by another cluster, which is usually caused by deploying a copy
+// of an existing topology file under a different cluster name. Co-locating clusters
+// on purpose is still possible with distinct ports and directories, the caller should
+// skip this check then and rely on CheckClusterPortConflict and CheckClusterDirConflict.
+func CheckClusterHostConflict(clusterList map[string]Metadata, clusterName string, topo Topology) error {
+	names := make([]string, 0, len(clusterList))
+	for name := range clusterList {
+		if name != clusterName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// host -> instances of other clusters on it
+	existing := make(map[string][]string)
+	for _, name := range names {
+		name := name
+		clusterList[name].GetTopology().IterInstance(func(inst Instance) {
+			existing[inst.GetHost()] = append(existing[inst.GetHost()],
+				fmt.Sprintf("%s %s %s", name, inst.ComponentName(), inst.ID()))
+		})
+	}
+
+	conflicts := []string{}
+	topo.IterInstance(func(inst Instance) {
+		if others, ok := existing[inst.GetHost()]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("  %s %s conflicts to: %s",
+				inst.ComponentName(), inst.ID(), strings.Join(others, ", ")))
+		}
+	})
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	zap.L().Info("Meet deploy host conflict", zap.Strings("conflicts", conflicts))
+	return errDeployHostConflict.New("Deploy hosts are used by an existing cluster").WithProperty(cliutil.SuggestionFromFormat(`
+The instances you specified in the topology file are placed on hosts of existing clusters
+(cluster, component and instance):
+%s
+
+Please change to use other hosts, or specify --allow-colocation if the clusters are
+meant to share the hosts with distinct ports and directories.
+`, strings.Join(conflicts, "\n")))
+}
+
 // platformConflictsDetect checks for conflicts in topology for different OS / Arch
 // set to the same host / IP
 func (s *Specification) platformConflictsDetect() error {
@@ -452,6 +500,20 @@ func (s *Specification) portConflictsDetect() error {
 	}
 	monitoredOpt := topoSpec.FieldByName(monitorOptionTypeName)
 	for host := range uniqueHosts {
+		if !s.MonitoredOptions.DeployAgents(host) {
+			// the external node_exporter is running on the port
+			item := usedPort{host: host, port: s.MonitoredOptions.ExporterPort(host)}
+			if prev, exist := portStats[item]; exist && item.port > 0 {
+				return &meta.ValidateErr{
+					Type:   meta.TypeConflict,
+					Target: "port",
+					LHS:    fmt.Sprintf("%s:%s.%s", prev.cfg, item.host, prev.tp),
+					RHS:    fmt.Sprintf("monitored.hosts:%s.external_exporter_port", item.host),
+					Value:  item.port,
+				}
+			}
+			continue
+		}
 		cfg := "monitored"
 		for _, portType := range monitoredPortTypes {
 			f := monitoredOpt.FieldByName(portType)
@@ -631,6 +693,18 @@ func (s *Specification) CountDir(targetHost, dirPrefix string) int {
 	return count
 }
 
+func (s *Specification) validateGrafanaSpec() error {
+	for _, g := range s.Grafana {
+		switch g.Provisioning {
+		case "", GrafanaProvisioningFile, GrafanaProvisioningAPI:
+		default:
+			return errors.Errorf("the provisioning of grafana %s:%d must be '%s' or '%s', got '%s'",
+				g.Host, g.Port, GrafanaProvisioningFile, GrafanaProvisioningAPI, g.Provisioning)
+		}
+	}
+	return nil
+}
+
 func (s *Specification) validateTiSparkSpec() error {
 	// There must be a Spark master
 	if len(s.TiSparkMasters) == 0 {
@@ -674,5 +748,32 @@ func (s *Specification) Validate() error {
 		return err
 	}
 
+	if err := s.MonitoredOptions.validateHosts(); err != nil {
+		return err
+	}
+
+	if s.OSSettings != nil {
+		if err := s.OSSettings.Validate(); err != nil {
+			return err
+		}
+	}
+
+	hosts := make(map[string]struct{})
+	s.IterInstance(func(inst Instance) {
+		hosts[inst.GetHost()] = struct{}{}
+	})
+	if s.GlobalOptions.Proxy != nil {
+		if err := s.GlobalOptions.Proxy.Validate(hosts); err != nil {
+			return err
+		}
+	}
+	if err := s.GlobalOptions.ValidateRemoteTmpDirs(hosts); err != nil {
+		return err
+	}
+
+	if err := s.validateGrafanaSpec(); err != nil {
+		return err
+	}
+
 	return s.validateTiSparkSpec()
 }
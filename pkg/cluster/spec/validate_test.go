@@ -429,6 +429,57 @@ It conflicts to a port in the existing cluster:
 Please change to use another port or another host.`)
 }
 
+func (s *metaSuiteTopo) TestCrossClusterHostConflicts(c *C) {
+	topo1 := Specification{}
+	err := yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.138
+tidb_servers:
+  - host: 172.16.5.139
+`), &topo1)
+	c.Assert(err, IsNil)
+
+	topo2 := Specification{}
+	err = yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.140
+`), &topo2)
+	c.Assert(err, IsNil)
+
+	clsList := make(map[string]Metadata)
+	clsList["topo1"] = &ClusterMeta{Topology: &topo1}
+
+	// no host shared
+	err = CheckClusterHostConflict(clsList, "topo", &topo2)
+	c.Assert(err, IsNil)
+
+	// the cluster itself is skipped
+	err = CheckClusterHostConflict(clsList, "topo1", &topo1)
+	c.Assert(err, IsNil)
+
+	// same hosts, even with distinct ports
+	topo3 := Specification{}
+	err = yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.138
+    client_port: 2234
+    peer_port: 2235
+  - host: 172.16.5.140
+`), &topo3)
+	c.Assert(err, IsNil)
+	err = CheckClusterHostConflict(clsList, "topo", &topo3)
+	c.Assert(err, NotNil)
+	c.Assert(errors.Cause(err).Error(), Equals, "spec.deploy.host_conflict: Deploy hosts are used by an existing cluster")
+	suggestion, ok := errorx.ExtractProperty(err, errutil.ErrPropSuggestion)
+	c.Assert(ok, IsTrue)
+	c.Assert(suggestion, Equals, `The instances you specified in the topology file are placed on hosts of existing clusters
+(cluster, component and instance):
+  pd 172.16.5.138:2234 conflicts to: topo1 pd 172.16.5.138:2379
+
+Please change to use other hosts, or specify --allow-colocation if the clusters are
+meant to share the hosts with distinct ports and directories.`)
+}
+
 func (s *metaSuiteTopo) TestCrossClusterDirConflicts(c *C) {
 	topo1 := Specification{}
 	err := yaml.Unmarshal([]byte(`
@@ -544,3 +595,119 @@ It conflicts to a directory in the existing cluster:
 
 Please change to use another directory or another host.`)
 }
+
+func (s *metaSuiteTopo) TestMonitoredHosts(c *C) {
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+monitored:
+  hosts:
+    - host: 172.16.5.2
+      external_exporter_port: 9101
+    - host: 172.16.5.3
+      monitor_agents: false
+tidb_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.2
+  - host: 172.16.5.3
+`), &topo)
+	c.Assert(err, IsNil)
+	m := topo.GetMonitoredOptions()
+	c.Assert(m.DeployAgents("172.16.5.1"), IsTrue)
+	c.Assert(m.ExporterPort("172.16.5.1"), Equals, 9100)
+	c.Assert(m.DeployAgents("172.16.5.2"), IsFalse)
+	c.Assert(m.ExporterPort("172.16.5.2"), Equals, 9101)
+	c.Assert(m.DeployAgents("172.16.5.3"), IsFalse)
+	c.Assert(m.ExporterPort("172.16.5.3"), Equals, 0)
+	c.Assert(m.UnmonitoredHosts(&topo), DeepEquals, []string{"172.16.5.3"})
+
+	// the ports of the agents not deployed are free to use
+	err = yaml.Unmarshal([]byte(`
+monitored:
+  hosts:
+    - host: 172.16.5.1
+      external_exporter_port: 9101
+tidb_servers:
+  - host: 172.16.5.1
+    port: 9100
+    status_port: 9115
+`), &topo)
+	c.Assert(err, IsNil)
+
+	// but not the port of the external node_exporter
+	err = yaml.Unmarshal([]byte(`
+monitored:
+  hosts:
+    - host: 172.16.5.1
+      external_exporter_port: 9101
+tidb_servers:
+  - host: 172.16.5.1
+    status_port: 9101
+`), &topo)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, "port conflict for '9101' between 'tidb_servers:172.16.5.1.status_port' and 'monitored.hosts:172.16.5.1.external_exporter_port'")
+
+	err = yaml.Unmarshal([]byte(`
+monitored:
+  hosts:
+    - host: 172.16.5.1
+      monitor_agents: true
+      external_exporter_port: 9101
+tidb_servers:
+  - host: 172.16.5.1
+`), &topo)
+	c.Assert(err, NotNil)
+}
+
+func (s *metaSuiteTopo) TestRemoteTmpDirs(c *C) {
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  remote_tmp_dir: /data/tmp
+  remote_tmp_dirs:
+    172.16.5.2: /data2/tmp
+tidb_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.2
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.GlobalOptions.RemoteTmpDirOf("172.16.5.1"), Equals, "/data/tmp")
+	c.Assert(topo.GlobalOptions.RemoteTmpDirOf("172.16.5.2"), Equals, "/data2/tmp")
+
+	topo = Specification{}
+	err = yaml.Unmarshal([]byte(`
+tidb_servers:
+  - host: 172.16.5.1
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.GlobalOptions.RemoteTmpDirOf("172.16.5.1"), Equals, DefaultRemoteTmpDir)
+	c.Assert(topo.GlobalOptions.HasRemoteTmpDir("172.16.5.1"), IsFalse)
+
+	topo = Specification{}
+	err = yaml.Unmarshal([]byte(`
+global:
+  remote_tmp_dir: data/tmp
+tidb_servers:
+  - host: 172.16.5.1
+`), &topo)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, "invalid global.remote_tmp_dir 'data/tmp', it should be an absolute path")
+
+	err = yaml.Unmarshal([]byte(`
+global:
+  remote_tmp_dir: "/data/tmp; rm -rf /"
+tidb_servers:
+  - host: 172.16.5.1
+`), &topo)
+	c.Assert(err, NotNil)
+
+	topo = Specification{}
+	err = yaml.Unmarshal([]byte(`
+global:
+  remote_tmp_dirs:
+    172.16.5.9: /data/tmp
+tidb_servers:
+  - host: 172.16.5.1
+`), &topo)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, "host '172.16.5.9' in global.remote_tmp_dirs is not a host of the topology")
+}
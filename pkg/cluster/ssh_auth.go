@@ -0,0 +1,153 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+var (
+	errNSSSHAuth = errorx.NewNamespace("ssh_auth")
+	// ErrSSHAuthFailed is returned when the SSH authentication to some hosts fails
+	ErrSSHAuthFailed = errNSSSHAuth.NewType("failed", errutil.ErrTraitPreCheck)
+)
+
+// sshAuthHint returns how to fix the authentication of the status
+func sshAuthHint(status executor.AuthStatus, user string) string {
+	switch status {
+	case executor.AuthKeyRejected:
+		return fmt.Sprintf("add the public key to authorized_keys of '%s', or use password (-p)", user)
+	case executor.AuthPasswordNeeded:
+		return "only password is accepted, use password (-p)"
+	case executor.AuthPasswordRejected:
+		return fmt.Sprintf("check the password of '%s', or use an identity file (-i)", user)
+	case executor.AuthUnreachable:
+		return "check the network and the ssh_port of the host"
+	}
+	return ""
+}
+
+// SSHHosts returns the hosts in topo with their SSH ports
+func SSHHosts(topo spec.Topology) map[string]int {
+	hosts := make(map[string]int)
+	topo.IterInstance(func(inst spec.Instance) {
+		hosts[inst.GetHost()] = inst.GetSSHPort()
+	})
+	return hosts
+}
+
+// PreflightSSHAuth authenticates to all the hosts with the key or password
+// provided by user before any real task runs, and prints a consolidated report
+// of the hosts failed, so the authentication of them could be fixed at once.
+// The hosts are authenticated to in the same way as the executors of the
+// subsequent phases, i.e., with the SSH client installed on the system if
+// nativeSSH is set. An error is returned if any host fails, except that
+// unreachable hosts are returned to be excluded from the subsequent phases
+// when ignoreErrors is set.
+func PreflightSSHAuth(
+	hosts map[string]int, // host -> ssh-port
+	user string,
+	sshConnProps *cliutil.SSHConnectionProps,
+	sshTimeout int64,
+	nativeSSH bool,
+	ignoreErrors bool,
+) (set.StringSet, error) {
+	reports := make([]*task.SSHAuthReport, 0, len(hosts))
+	var checkTasks []*task.StepDisplay
+	for host, port := range hosts {
+		report := &task.SSHAuthReport{}
+		reports = append(reports, report)
+		checkTasks = append(checkTasks, task.NewBuilder().
+			CheckSSHAuth(
+				host,
+				port,
+				user,
+				sshConnProps.Password,
+				sshConnProps.IdentityFile,
+				sshConnProps.IdentityFilePassphrase,
+				sshTimeout,
+				nativeSSH,
+				report,
+			).
+			BuildAsStepMessage(task.MsgAuthenticate, task.MessageParams{"host": host, "port": strconv.Itoa(port)}))
+	}
+
+	t := task.NewBuilder().
+		ParallelStep("+ Check SSH authentication of target hosts", checkTasks...).
+		Build()
+	if err := t.Execute(task.NewContext()); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Host < reports[j].Host
+	})
+	unreachable := set.NewStringSet()
+	failed := make([]string, 0)
+	rows := [][]string{{"Host", "Port", "User", "Status", "Hint"}}
+	for _, r := range reports {
+		if r.Status.OK() {
+			continue
+		}
+		if r.Status == executor.AuthUnreachable {
+			unreachable.Insert(r.Host)
+		}
+		if r.Status != executor.AuthUnreachable || !ignoreErrors {
+			failed = append(failed, r.Host)
+		}
+		if r.Err != nil {
+			log.Debugf("SSH authentication to %s:%d failed: %s", r.Host, r.Port, r.Err)
+		}
+		rows = append(rows, []string{
+			r.Host,
+			fmt.Sprintf("%d", r.Port),
+			r.User,
+			string(r.Status),
+			sshAuthHint(r.Status, r.User),
+		})
+	}
+
+	if len(rows) == 1 {
+		return unreachable, nil
+	}
+
+	fmt.Println(color.YellowString("SSH authentication failed on the following hosts:"))
+	cliutil.PrintTable(rows, true)
+
+	if len(failed) > 0 {
+		return nil, ErrSSHAuthFailed.
+			New("Failed to authenticate to %d host(s): %s", len(failed), strings.Join(failed, ", ")).
+			WithProperty(cliutil.SuggestionFromString(
+				"Please fix the SSH authentication of the hosts above and try again,\n" +
+					"unreachable hosts could be skipped with --ignore-errors if the command supports it."))
+	}
+
+	excluded := unreachable.Slice()
+	sort.Strings(excluded)
+	log.Warnf("Unreachable hosts are excluded: %s", strings.Join(excluded, ", "))
+	return unreachable, nil
+}
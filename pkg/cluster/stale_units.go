@@ -0,0 +1,333 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+var errDeployStaleUnits = errNSDeploy.NewType("stale_units", errutil.ErrTraitPreCheck)
+
+// systemdUnitDir is where the units of the instances are installed
+const systemdUnitDir = "/etc/systemd/system"
+
+// uncleanHostsFile records the hosts the destroyed clusters failed to clean
+// up, it's under the dir of all the clusters, as it outlives them
+const uncleanHostsFile = "unclean_hosts.json"
+
+// UncleanHost is a host a destroyed cluster failed to clean up, the units of
+// the cluster may be left on it
+type UncleanHost struct {
+	Host      string    `json:"host"`
+	Cluster   string    `json:"cluster"`
+	Units     []string  `json:"units"`
+	Error     string    `json:"error"`
+	Destroyed time.Time `json:"destroyed"`
+}
+
+// StaleUnit is a unit file on a host whose ExecStart isn't in the deploy
+// directory planned for the unit, e.g., left by a destroyed cluster
+type StaleUnit struct {
+	Host      string
+	Unit      string
+	Instance  string
+	ExecStart string
+	DeployDir string // the planned deploy directory
+}
+
+// plannedUnit is a unit to be deployed, with the directory its ExecStart
+// should be in
+type plannedUnit struct {
+	unit      string
+	instance  string
+	deployDir string
+}
+
+// loadUncleanHosts loads the hosts the destroyed clusters failed to clean up
+func (m *Manager) loadUncleanHosts() ([]UncleanHost, error) {
+	data, err := ioutil.ReadFile(m.specManager.BasePath(uncleanHostsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, perrs.AddStack(err)
+	}
+	var hosts []UncleanHost
+	if err := json.Unmarshal(data, &hosts); err != nil {
+		return nil, perrs.Annotatef(err, "failed to parse %s", m.specManager.BasePath(uncleanHostsFile))
+	}
+	return hosts, nil
+}
+
+// saveUncleanHosts saves the hosts the destroyed clusters failed to clean
+// up, the file is removed if there is none
+func (m *Manager) saveUncleanHosts(hosts []UncleanHost) error {
+	fname := m.specManager.BasePath(uncleanHostsFile)
+	if len(hosts) == 0 {
+		if err := os.Remove(fname); err != nil && !os.IsNotExist(err) {
+			return perrs.AddStack(err)
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(hosts, "", "  ")
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	if err := os.MkdirAll(m.specManager.BasePath(), 0755); err != nil {
+		return perrs.AddStack(err)
+	}
+	return perrs.AddStack(ioutil.WriteFile(fname, data, 0644))
+}
+
+// recordUncleanHosts records the hosts the cluster failed to clean up when
+// destroyed, with the units of the cluster on them
+func (m *Manager) recordUncleanHosts(clusterName string, topo spec.Topology, notCleaned *operator.HostsNotCleanedError) error {
+	hosts, err := m.loadUncleanHosts()
+	if err != nil {
+		return err
+	}
+	units := plannedUnits(topo, topo.GetMonitoredOptions(), nil, topo.BaseTopo().GlobalOptions.User)
+	now := time.Now()
+	for host, cause := range notCleaned.Hosts {
+		h := UncleanHost{Host: host, Cluster: clusterName, Error: cause.Error(), Destroyed: now}
+		for _, u := range units[host] {
+			h.Units = append(h.Units, u.unit)
+		}
+		hosts = append(hosts, h)
+	}
+	sort.SliceStable(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+	return m.saveUncleanHosts(hosts)
+}
+
+// plannedUnits returns the units of the instances of topo and the agents of
+// monitored on their hosts by the hosts, the agents on the hosts of existing
+// are skipped, as they are deployed already
+func plannedUnits(topo spec.Topology, monitored *spec.MonitoredOptions, existing spec.Topology, deployUser string) map[string][]plannedUnit {
+	deployed := set.NewStringSet()
+	if existing != nil {
+		existing.IterInstance(func(inst spec.Instance) {
+			deployed.Insert(inst.GetHost())
+		})
+	}
+
+	units := make(map[string][]plannedUnit)
+	topo.IterInstance(func(inst spec.Instance) {
+		host := inst.GetHost()
+		if _, found := units[host]; !found && !deployed.Exist(host) && monitored != nil && monitored.DeployAgents(host) {
+			deployDir := clusterutil.Abs(deployUser, monitored.DeployDir)
+			units[host] = append(units[host],
+				plannedUnit{unit: fmt.Sprintf("%s-%d.service", spec.ComponentNodeExporter, monitored.NodeExporterPort), deployDir: deployDir},
+				plannedUnit{unit: fmt.Sprintf("%s-%d.service", spec.ComponentBlackboxExporter, monitored.BlackboxExporterPort), deployDir: deployDir},
+			)
+		}
+		if svc := inst.ServiceName(); svc != "" {
+			units[host] = append(units[host], plannedUnit{
+				unit:      svc,
+				instance:  inst.ID(),
+				deployDir: clusterutil.Abs(deployUser, inst.DeployDir()),
+			})
+		}
+	})
+	return units
+}
+
+// unitExecStartsCommand prints the path of ExecStart of each of the units
+// existing in dir on the host, a line of the unit and the path each
+func unitExecStartsCommand(dir string, units []plannedUnit) string {
+	names := make([]string, 0, len(units))
+	for _, u := range units {
+		names = append(names, u.unit)
+	}
+	return fmt.Sprintf(`for u in %s; do f=%s/$u; `+
+		`[ -f "$f" ] && echo "$u $(grep -m 1 '^ExecStart=' "$f" | cut -d= -f2- | awk '{print $1}')"; done; true`,
+		strings.Join(names, " "), dir)
+}
+
+// parseStaleUnits returns the units whose ExecStart isn't in the planned
+// deploy directory from the output of unitExecStartsCommand
+func parseStaleUnits(host string, units []plannedUnit, output string) []StaleUnit {
+	execStarts := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			execStarts[fields[0]] = fields[1]
+		}
+	}
+	var stale []StaleUnit
+	for _, u := range units {
+		execStart, ok := execStarts[u.unit]
+		if !ok || strings.HasPrefix(execStart, strings.TrimSuffix(u.deployDir, "/")+"/") {
+			continue
+		}
+		stale = append(stale, StaleUnit{
+			Host:      host,
+			Unit:      u.unit,
+			Instance:  u.instance,
+			ExecStart: execStart,
+			DeployDir: u.deployDir,
+		})
+	}
+	return stale
+}
+
+// checkStaleUnits checks if the units to be deployed for topo, and the agents
+// of monitored, exist on the hosts already and point to other deploy
+// directories, e.g., left by a destroyed cluster failed to clean up the
+// hosts, which would start the wrong binaries. The units are removed if clean
+// is set, an error is returned otherwise. The hosts of existing, which is nil
+// for a new cluster, are known to have the agents deployed. The records of
+// the hosts checked are dropped from the hosts the destroyed clusters failed
+// to clean up.
+func (m *Manager) checkStaleUnits(
+	clusterName string,
+	topo spec.Topology,
+	monitored *spec.MonitoredOptions,
+	existing spec.Topology,
+	deployUser string,
+	user string,
+	sshConnProps *cliutil.SSHConnectionProps,
+	sshTimeout int64,
+	nativeSSH bool,
+	clean bool,
+) error {
+	units := plannedUnits(topo, monitored, existing, deployUser)
+
+	uncleanHosts, err := m.loadUncleanHosts()
+	if err != nil {
+		// the records are only hints
+		log.Warnf("Failed to load the hosts destroyed clusters failed to clean up: %s", err)
+	}
+	for _, h := range uncleanHosts {
+		if _, ok := units[h.Host]; ok {
+			log.Warnf("Host %s was not cleaned up when cluster `%s` was destroyed at %s (%s), its units may be left: %s",
+				h.Host, h.Cluster, h.Destroyed.Format(time.RFC3339), h.Error, strings.Join(h.Units, ", "))
+		}
+	}
+
+	hosts := make([]string, 0, len(units))
+	for host := range units {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	sshPorts := SSHHosts(topo)
+	rootSSH := func(host string) *task.Builder {
+		return task.NewBuilder().
+			RootSSH(
+				host,
+				sshPorts[host],
+				user,
+				sshConnProps.Password,
+				sshConnProps.IdentityFile,
+				sshConnProps.IdentityFilePassphrase,
+				sshTimeout,
+				nativeSSH,
+			)
+	}
+	var checkTasks []*task.StepDisplay
+	for _, host := range hosts {
+		checkTasks = append(checkTasks,
+			rootSSH(host).
+				Shell(host, unitExecStartsCommand(systemdUnitDir, units[host]), true).
+				BuildAsStep(fmt.Sprintf("  - Check existing units on %s", host)))
+	}
+	ctx := task.NewContext()
+	m.applyMock(clusterName, ctx)
+	t := task.NewBuilder().
+		ParallelStep("+ Check the units left by destroyed clusters", checkTasks...).
+		Build()
+	if err := t.Execute(ctx); err != nil {
+		return err
+	}
+
+	var stale []StaleUnit
+	staleHosts := make(map[string][]string)
+	for _, host := range hosts {
+		stdout, _, _ := ctx.GetOutputs(host)
+		for _, s := range parseStaleUnits(host, units[host], string(stdout)) {
+			stale = append(stale, s)
+			staleHosts[host] = append(staleHosts[host], s.Unit)
+		}
+	}
+
+	if len(stale) > 0 && !clean {
+		fmt.Println(color.RedString("The following units exist on the hosts and point to other deploy directories:"))
+		rows := [][]string{{"Host", "Unit", "Instance", "ExecStart", "Planned Deploy Dir"}}
+		for _, s := range stale {
+			rows = append(rows, []string{s.Host, s.Unit, s.Instance, s.ExecStart, s.DeployDir})
+		}
+		cliutil.PrintTable(rows, true)
+		return errDeployStaleUnits.New("%d unit(s) left on the hosts would start binaries of other deploy directories", len(stale)).
+			WithProperty(cliutil.SuggestionFromString(
+				"They may be left by a cluster destroyed when the hosts were unreachable. Please remove them,\n" +
+					"or retry with `--force` to stop and remove them."))
+	}
+
+	if len(stale) > 0 {
+		var cleanTasks []*task.StepDisplay
+		for _, host := range hosts {
+			staleUnits := staleHosts[host]
+			if len(staleUnits) == 0 {
+				continue
+			}
+			var cmds []string
+			for _, unit := range staleUnits {
+				cmds = append(cmds, fmt.Sprintf("systemctl disable --now %[1]s; rm -f %[2]s/%[1]s", unit, systemdUnitDir))
+			}
+			cmds = append(cmds, "systemctl daemon-reload")
+			log.Warnf("Removing the units left on %s: %s", host, strings.Join(staleUnits, ", "))
+			cleanTasks = append(cleanTasks,
+				task.NewBuilder().
+					Shell(host, strings.Join(cmds, "; "), true).
+					BuildAsStep(fmt.Sprintf("  - Remove stale units on %s", host)))
+		}
+		t := task.NewBuilder().
+			ParallelStep("+ Remove the units left by destroyed clusters", cleanTasks...).
+			Build()
+		if err := t.Execute(ctx); err != nil {
+			return err
+		}
+	}
+
+	// the hosts checked are clean now
+	var remained []UncleanHost
+	for _, h := range uncleanHosts {
+		if _, ok := units[h.Host]; !ok {
+			remained = append(remained, h)
+		}
+	}
+	if len(remained) != len(uncleanHosts) {
+		if err := m.saveUncleanHosts(remained); err != nil {
+			log.Warnf("Failed to save the hosts destroyed clusters failed to clean up: %s", err)
+		}
+	}
+	return nil
+}
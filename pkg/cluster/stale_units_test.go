@@ -0,0 +1,123 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaleUnits(t *testing.T) {
+	m, mc, metadata, cleanup := newTestMockCluster(t, 2)
+	defer cleanup()
+	topo := metadata.Topology
+	check := func(clean bool) error {
+		return m.checkStaleUnits("mock", topo, topo.GetMonitoredOptions(), nil, "tidb", "root",
+			&cliutil.SSHConnectionProps{}, 5, false, clean)
+	}
+	hasCommand := func(host, prefix string) bool {
+		for _, cmd := range mc.Host(host).Commands() {
+			if strings.HasPrefix(cmd, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// the hosts a destroyed cluster failed to clean up are recorded
+	err := m.recordUncleanHosts("old", topo, &operator.HostsNotCleanedError{
+		Hosts: map[string]error{"mock-1": errors.New("unreachable")},
+	})
+	require.Nil(t, err)
+	hosts, err := m.loadUncleanHosts()
+	require.Nil(t, err)
+	require.Len(t, hosts, 1)
+	assert.Equal(t, "mock-1", hosts[0].Host)
+	assert.Equal(t, "old", hosts[0].Cluster)
+	assert.Contains(t, hosts[0].Units, "tikv-20160.service")
+
+	// the units of the planned deploy directories are not stale
+	mc.Host("mock-1").Respond("for u in", "tikv-20160.service /home/tidb/deploy/tikv-20160/scripts/run_tikv.sh\n", "")
+	require.Nil(t, check(false))
+
+	mc.Host("mock-1").Respond("for u in", "tikv-20160.service /old/deploy/tikv-20160/scripts/run_tikv.sh\n", "")
+	err = check(false)
+	assert.True(t, errorx.IsOfType(err, errDeployStaleUnits))
+	assert.False(t, hasCommand("mock-1", "systemctl disable --now tikv-20160.service"))
+
+	require.Nil(t, check(true))
+	assert.True(t, hasCommand("mock-1", "systemctl disable --now tikv-20160.service; rm -f /etc/systemd/system/tikv-20160.service"))
+	assert.False(t, hasCommand("mock-2", "systemctl disable"))
+
+	// the records of the hosts checked are dropped
+	hosts, err = m.loadUncleanHosts()
+	require.Nil(t, err)
+	assert.Empty(t, hosts)
+
+	// the cluster is destroyed in force mode even if a host can't be cleaned up
+	mc.Host("mock-2").Respond("rm -rf", "", "permission denied")
+	gOpt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10}
+	err = m.DestroyCluster("mock", gOpt, operator.Options{OptTimeout: 10}, true)
+	require.NotNil(t, err)
+	err = m.DestroyCluster("mock", gOpt, operator.Options{OptTimeout: 10, Force: true}, true)
+	require.Nil(t, err)
+	hosts, err = m.loadUncleanHosts()
+	require.Nil(t, err)
+	require.Len(t, hosts, 1)
+	assert.Equal(t, "mock-2", hosts[0].Host)
+	assert.Equal(t, "mock", hosts[0].Cluster)
+	_, err = m.meta("mock")
+	assert.NotNil(t, err)
+}
+
+func TestUnitExecStartsCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiup-unit-exec-starts-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	unitDir := filepath.Join(dir, "system")
+	require.Nil(t, os.MkdirAll(unitDir, 0755))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(unitDir, "tikv-20160.service"), []byte(`[Service]
+ExecStart=/old/deploy/tikv-20160/scripts/run_tikv.sh --flag
+`), 0644))
+	// sudo -H -u root bash -c ...
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "sudo"), []byte("#!/bin/sh\nshift 3\nexec \"$@\"\n"), 0755))
+
+	units := []plannedUnit{
+		{unit: "tikv-20160.service", instance: "mock-1:20160", deployDir: "/home/tidb/deploy/tikv-20160"},
+		{unit: "pd-2379.service", instance: "mock-1:2379", deployDir: "/home/tidb/deploy/pd-2379"},
+	}
+	// the command line sent to the host, with sudo simulated as the current user
+	c := exec.Command("sh", "-c", executor.RemoteCommand(unitExecStartsCommand(unitDir, units), true, "C"))
+	c.Env = append(os.Environ(), "PATH="+dir+":"+os.Getenv("PATH"))
+	out, err := c.CombinedOutput()
+	require.Nil(t, err, string(out))
+	assert.Equal(t, "tikv-20160.service /old/deploy/tikv-20160/scripts/run_tikv.sh\n", string(out))
+
+	stale := parseStaleUnits("mock-1", units, string(out))
+	require.Len(t, stale, 1)
+	assert.Equal(t, "mock-1:20160", stale[0].Instance)
+	assert.Equal(t, "/old/deploy/tikv-20160/scripts/run_tikv.sh", stale[0].ExecStart)
+}
@@ -0,0 +1,234 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/embed"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+)
+
+const (
+	// the support matrix built in
+	supportMatrixEmbedPath = "/templates/support/platforms.json"
+	// the file under the profile extending the built in support matrix, its
+	// rules are evaluated before the built in ones
+	supportMatrixFile = "support_matrix.json"
+)
+
+// SupportStatus is whether the components are known to work on a platform
+type SupportStatus string
+
+// the support statuses of platforms
+const (
+	PlatformSupported SupportStatus = "supported"
+	PlatformUntested  SupportStatus = "untested"
+	PlatformBroken    SupportStatus = "broken"
+)
+
+// PlatformRule is an entry of the support matrix, the empty fields match any
+// value. The versions of the OS are compared by the dot separated numbers,
+// the max version matches all the versions it's a prefix of, e.g., 6 matches
+// 6.10.
+type PlatformRule struct {
+	Component  string        `json:"component,omitempty"`
+	Major      string        `json:"major,omitempty"` // the major version of the component, e.g., v4
+	OS         string        `json:"os,omitempty"`    // the ID in /etc/os-release, or darwin
+	MinVersion string        `json:"min_version,omitempty"`
+	MaxVersion string        `json:"max_version,omitempty"`
+	Arch       []string      `json:"arch,omitempty"`
+	Status     SupportStatus `json:"status"`
+	// Note is the one-line explanation of the status
+	Note string `json:"note,omitempty"`
+}
+
+// SupportMatrix is the platforms the components are known to work, or
+// known to be broken on, the first rule matched is taken
+type SupportMatrix struct {
+	Rules []PlatformRule `json:"rules"`
+}
+
+// HostFacts is the platform of a host
+type HostFacts struct {
+	OS      string // linux or darwin, the OS name used by the repository
+	Arch    string
+	Distro  string // the ID in /etc/os-release, e.g., centos
+	Version string // the VERSION_ID in /etc/os-release, e.g., 7
+}
+
+// String implements the fmt.Stringer interface
+func (f HostFacts) String() string {
+	if f.Distro == "" {
+		return fmt.Sprintf("%s/%s", f.OS, f.Arch)
+	}
+	return fmt.Sprintf("%s %s/%s", f.Distro, f.Version, f.Arch)
+}
+
+// parseHostFacts parses the output of hostPlatformCommand
+func parseHostFacts(output string) HostFacts {
+	var facts HostFacts
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	if scanner.Scan() {
+		facts.OS, facts.Arch = parseHostPlatform(scanner.Text())
+	}
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.Trim(strings.TrimSpace(kv[1]), `"'`)
+		switch strings.TrimSpace(kv[0]) {
+		case "ID":
+			facts.Distro = strings.ToLower(value)
+		case "VERSION_ID":
+			facts.Version = value
+		}
+	}
+	if facts.OS == "darwin" {
+		facts.Distro = "darwin"
+	}
+	return facts
+}
+
+// LoadSupportMatrix loads the built in support matrix, extended by the file
+// under the profile if it exists
+func LoadSupportMatrix() (*SupportMatrix, error) {
+	return loadSupportMatrix(spec.ProfilePath(supportMatrixFile))
+}
+
+// loadSupportMatrix loads the built in support matrix, the rules in the file
+// fp are evaluated before the built in ones if it exists
+func loadSupportMatrix(fp string) (*SupportMatrix, error) {
+	data, err := embed.ReadFile(supportMatrixEmbedPath)
+	if err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	var builtin SupportMatrix
+	if err := json.Unmarshal(data, &builtin); err != nil {
+		return nil, perrs.Annotate(err, "invalid built in support matrix")
+	}
+
+	data, err = ioutil.ReadFile(fp)
+	if os.IsNotExist(err) {
+		return &builtin, nil
+	}
+	if err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	var custom SupportMatrix
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, perrs.Annotatef(err, "invalid support matrix %s", fp)
+	}
+	return &SupportMatrix{Rules: append(custom.Rules, builtin.Rules...)}, nil
+}
+
+// Evaluate returns the support status of the component of the major version
+// on the host and the explanation, the platforms not in the matrix are
+// untested
+func (m *SupportMatrix) Evaluate(comp, major string, facts HostFacts) (SupportStatus, string) {
+	for _, r := range m.Rules {
+		if r.matches(comp, major, facts) {
+			return r.Status, r.Note
+		}
+	}
+	return PlatformUntested, fmt.Sprintf("%s is not in the support matrix of %s %s, tested: %s",
+		facts, comp, major, strings.Join(m.supported(comp, major), ", "))
+}
+
+// supported returns the platforms the component is supported on
+func (m *SupportMatrix) supported(comp, major string) []string {
+	var platforms []string
+	for _, r := range m.Rules {
+		if r.Status != PlatformSupported || r.OS == "" ||
+			(r.Component != "" && r.Component != comp) || (r.Major != "" && r.Major != major) {
+			continue
+		}
+		p := r.OS
+		if r.MinVersion != "" {
+			p += " >= " + r.MinVersion
+		}
+		if len(r.Arch) > 0 {
+			p += " (" + strings.Join(r.Arch, "/") + ")"
+		}
+		platforms = append(platforms, p)
+	}
+	sort.Strings(platforms)
+	return platforms
+}
+
+func (r *PlatformRule) matches(comp, major string, facts HostFacts) bool {
+	if r.Component != "" && r.Component != comp {
+		return false
+	}
+	if r.Major != "" && r.Major != major {
+		return false
+	}
+	if r.OS != "" && r.OS != facts.Distro {
+		return false
+	}
+	if len(r.Arch) > 0 {
+		found := false
+		for _, arch := range r.Arch {
+			found = found || arch == facts.Arch
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.MinVersion != "" && compareOSVersion(facts.Version, r.MinVersion, false) < 0 {
+		return false
+	}
+	if r.MaxVersion != "" && compareOSVersion(facts.Version, r.MaxVersion, true) > 0 {
+		return false
+	}
+	return true
+}
+
+// compareOSVersion compares the dot separated numbers of the versions, only
+// the numbers present in b are compared if prefix is set
+func compareOSVersion(a, b string, prefix bool) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	n := len(as)
+	if len(bs) > n {
+		n = len(bs)
+	}
+	if prefix {
+		n = len(bs)
+	}
+	for i := 0; i < n; i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupportMatrix(t *testing.T) {
+	facts := parseHostFacts(`Linux x86_64
+NAME="CentOS Linux"
+VERSION="6 (Final)"
+ID="centos"
+VERSION_ID="6.10"
+`)
+	assert.Equal(t, HostFacts{OS: "linux", Arch: "amd64", Distro: "centos", Version: "6.10"}, facts)
+	assert.Equal(t, "centos 6.10/amd64", facts.String())
+
+	dir, err := ioutil.TempDir("", "tiup-support-matrix-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fp := filepath.Join(dir, supportMatrixFile)
+
+	matrix, err := loadSupportMatrix(fp)
+	require.Nil(t, err)
+	status, note := matrix.Evaluate("tikv", "v4", facts)
+	assert.Equal(t, PlatformBroken, status)
+	assert.Contains(t, note, "glibc 2.12")
+
+	centos7 := HostFacts{OS: "linux", Arch: "amd64", Distro: "centos", Version: "7.6.1810"}
+	status, _ = matrix.Evaluate("tikv", "v4", centos7)
+	assert.Equal(t, PlatformSupported, status)
+	status, _ = matrix.Evaluate("tikv", "v4", HostFacts{OS: "linux", Arch: "amd64", Distro: "centos", Version: "7.2"})
+	assert.Equal(t, PlatformUntested, status)
+
+	// the component of a major version has its own rules
+	ubuntu := HostFacts{OS: "linux", Arch: "amd64", Distro: "ubuntu", Version: "18.04"}
+	status, _ = matrix.Evaluate("tidb", "v4", ubuntu)
+	assert.Equal(t, PlatformSupported, status)
+	status, note = matrix.Evaluate("tiflash", "v4", ubuntu)
+	assert.Equal(t, PlatformUntested, status)
+	assert.Contains(t, note, "TiFlash v4")
+
+	// the platforms not in the matrix are untested
+	status, note = matrix.Evaluate("tidb", "v4", HostFacts{OS: "linux", Arch: "amd64", Distro: "arch", Version: ""})
+	assert.Equal(t, PlatformUntested, status)
+	assert.Contains(t, note, "centos >= 7.3 (amd64/arm64)")
+
+	// the rules under the profile take precedence
+	require.Nil(t, ioutil.WriteFile(fp, []byte(`{"rules": [
+		{"os": "centos", "min_version": "6.10", "max_version": "6", "status": "supported"}
+	]}`), 0644))
+	matrix, err = loadSupportMatrix(fp)
+	require.Nil(t, err)
+	status, _ = matrix.Evaluate("tikv", "v4", facts)
+	assert.Equal(t, PlatformSupported, status)
+	status, _ = matrix.Evaluate("tikv", "v4", HostFacts{OS: "linux", Arch: "amd64", Distro: "centos", Version: "6.9"})
+	assert.Equal(t, PlatformBroken, status)
+
+	require.Nil(t, ioutil.WriteFile(fp, []byte(`{"rules": [`), 0644))
+	_, err = loadSupportMatrix(fp)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,205 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/meta"
+)
+
+// the max length of the keys and values of tags
+const maxTagLength = 63
+
+var (
+	errNSTag = errorx.NewNamespace("tag")
+	// ErrInvalidTag is returned when the key or value of a tag is malformed
+	ErrInvalidTag = errNSTag.NewType("invalid", errutil.ErrTraitPreCheck)
+
+	// keys may be prefixed like example.com/team, the values are plain
+	tagKeyRegexp   = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9_.\-/]*[a-zA-Z0-9])?$`)
+	tagValueRegexp = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9_.\-]*[a-zA-Z0-9])?)?$`)
+)
+
+// ValidateTag checks the key and value of a tag, the value may be empty
+func ValidateTag(key, value string) error {
+	if len(key) == 0 || len(key) > maxTagLength || !tagKeyRegexp.MatchString(key) {
+		return ErrInvalidTag.New("Invalid tag key `%s`", key).
+			WithProperty(cliutil.SuggestionFromFormat(
+				"The key of a tag should be 1 to %d characters of letters, digits, '_', '-', '.' or '/',\nand start and end with a letter or digit.", maxTagLength))
+	}
+	if len(value) > maxTagLength || !tagValueRegexp.MatchString(value) {
+		return ErrInvalidTag.New("Invalid value `%s` of tag `%s`", value, key).
+			WithProperty(cliutil.SuggestionFromFormat(
+				"The value of a tag should be at most %d characters of letters, digits, '_', '-' or '.',\nand start and end with a letter or digit.", maxTagLength))
+	}
+	return nil
+}
+
+// GetTags returns the tags of the cluster
+func (m *Manager) GetTags(clusterName string) (map[string]string, error) {
+	metadata, err := m.meta(clusterName)
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+		return nil, perrs.AddStack(err)
+	}
+	tags := make(map[string]string)
+	for k, v := range metadata.GetBaseMeta().Tags {
+		tags[k] = v
+	}
+	return tags, nil
+}
+
+// SetTag sets the tag of the cluster, the previous value is overwritten
+func (m *Manager) SetTag(clusterName, key, value string) error {
+	if err := ValidateTag(key, value); err != nil {
+		return err
+	}
+	return m.updateTags(clusterName, func(tags map[string]string) bool {
+		if old, ok := tags[key]; ok {
+			if old == value {
+				log.Infof("Tag `%s` of cluster `%s` is already `%s`", key, clusterName, value)
+				return false
+			}
+			// log.Infof also writes to the audit log
+			log.Infof("Tag `%s` of cluster `%s` is changed from `%s` to `%s`", key, clusterName, old, value)
+		} else {
+			log.Infof("Tag `%s` of cluster `%s` is set to `%s`", key, clusterName, value)
+		}
+		tags[key] = value
+		return true
+	})
+}
+
+// DeleteTag deletes the tag of the cluster
+func (m *Manager) DeleteTag(clusterName, key string) error {
+	return m.updateTags(clusterName, func(tags map[string]string) bool {
+		old, ok := tags[key]
+		if !ok {
+			log.Infof("Cluster `%s` has no tag `%s`", clusterName, key)
+			return false
+		}
+		log.Infof("Tag `%s` of cluster `%s` is deleted, the value was `%s`", key, clusterName, old)
+		delete(tags, key)
+		return true
+	})
+}
+
+// updateTags saves the tags of the cluster if update changes them
+func (m *Manager) updateTags(clusterName string, update func(tags map[string]string) bool) error {
+	metadata, err := m.metaFresh(clusterName)
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+		return perrs.AddStack(err)
+	}
+	if err := m.specManager.CheckWritable(); err != nil {
+		return err
+	}
+
+	tm, ok := metadata.(spec.TaggableMetadata)
+	if !ok {
+		return perrs.Errorf("cluster `%s` doesn't support tags", clusterName)
+	}
+	tags := make(map[string]string)
+	for k, v := range metadata.GetBaseMeta().Tags {
+		tags[k] = v
+	}
+	if !update(tags) {
+		return nil
+	}
+	if len(tags) == 0 {
+		tags = nil
+	}
+
+	tm.SetTags(tags)
+	if err := m.specManager.SaveMeta(clusterName, metadata); err != nil {
+		return perrs.Annotate(err, "failed to save meta")
+	}
+	return nil
+}
+
+// TagFilter matches the clusters having the tag, with the value if it's set
+type TagFilter struct {
+	Key      string
+	Value    string
+	AnyValue bool
+}
+
+// ParseTagFilters parses the filters in the form of key=value, or key to
+// match any value of the tag
+func ParseTagFilters(filters []string) ([]TagFilter, error) {
+	result := make([]TagFilter, 0, len(filters))
+	for _, f := range filters {
+		var filter TagFilter
+		if idx := strings.Index(f, "="); idx >= 0 {
+			filter.Key, filter.Value = f[:idx], f[idx+1:]
+		} else {
+			filter.Key, filter.AnyValue = f, true
+		}
+		if err := ValidateTag(filter.Key, filter.Value); err != nil {
+			return nil, err
+		}
+		result = append(result, filter)
+	}
+	return result, nil
+}
+
+// MatchTags returns if the tags match all the filters
+func MatchTags(tags map[string]string, filters []TagFilter) bool {
+	for _, f := range filters {
+		v, ok := tags[f.Key]
+		if !ok || (!f.AnyValue && v != f.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatTags formats the tags as key=value pairs sorted by the key
+func FormatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// ClustersByTags returns the names of the clusters matching all the filters,
+// the clusters whose metadata can't be loaded are skipped with a warning
+func (m *Manager) ClustersByTags(filters []TagFilter) ([]string, error) {
+	names, err := m.specManager.List()
+	if err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	matched := make([]string, 0, len(names))
+	for _, name := range names {
+		metadata, err := m.meta(name)
+		if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) {
+			log.Warnf("Skip the cluster %s as its metadata can't be loaded: %s", name, err)
+			continue
+		}
+		if MatchTags(metadata.GetBaseMeta().Tags, filters) {
+			matched = append(matched, name)
+		}
+	}
+	return matched, nil
+}
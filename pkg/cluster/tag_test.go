@@ -0,0 +1,75 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTags(t *testing.T) {
+	m, _, cleanup := newTestManager(t)
+	defer cleanup()
+	for _, name := range []string{"payments", "search"} {
+		require.Nil(t, os.MkdirAll(m.specManager.Path(name), 0755))
+		require.Nil(t, ioutil.WriteFile(m.specManager.Path(name, "meta.yaml"), []byte("user: tidb\n"), 0644))
+	}
+
+	require.Nil(t, m.SetTag("payments", "team", "payments"))
+	require.Nil(t, m.SetTag("payments", "env", "staging"))
+	require.Nil(t, m.SetTag("search", "env", "prod"))
+	tags, err := m.GetTags("payments")
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{"team": "payments", "env": "staging"}, tags)
+	assert.Equal(t, "env=staging,team=payments", FormatTags(tags))
+
+	for _, kv := range [][2]string{{"", "v"}, {"-team", "v"}, {"team", "a b"}, {"team", strings.Repeat("v", 64)}} {
+		err = m.SetTag("payments", kv[0], kv[1])
+		require.NotNil(t, err)
+		assert.True(t, errutil.Cast(err).IsOfType(ErrInvalidTag))
+	}
+
+	filters, err := ParseTagFilters([]string{"env"})
+	require.Nil(t, err)
+	names, err := m.ClustersByTags(filters)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"payments", "search"}, names)
+	// the corrupted metadata doesn't fail the others
+	require.Nil(t, os.MkdirAll(m.specManager.Path("corrupted"), 0755))
+	require.Nil(t, ioutil.WriteFile(m.specManager.Path("corrupted", "meta.yaml"), []byte("user: [tidb\n"), 0644))
+	names, err = m.ClustersByTags(filters)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"payments", "search"}, names)
+	filters, err = ParseTagFilters([]string{"env=staging", "team"})
+	require.Nil(t, err)
+	summaries, err := m.ClusterSummaries(filters...)
+	require.Nil(t, err)
+	require.Len(t, summaries, 1)
+	assert.Equal(t, "payments", summaries[0].Name)
+	assert.Equal(t, "staging", summaries[0].Tags["env"])
+	_, err = ParseTagFilters([]string{"env=a b"})
+	assert.NotNil(t, err)
+
+	require.Nil(t, m.DeleteTag("search", "env"))
+	require.Nil(t, m.DeleteTag("search", "env"))
+	metadata, err := m.metaFresh("search")
+	require.Nil(t, err)
+	assert.Nil(t, metadata.GetBaseMeta().Tags)
+}
@@ -78,6 +78,20 @@ func (c *ClusterOperate) Execute(ctx *Context) error {
 	return nil
 }
 
+// Explain returns the plan of the operation, nil if the operation can't be
+// explained without executing it
+func (c *ClusterOperate) Explain() *operator.Plan {
+	switch c.op {
+	case operator.StartOperation:
+		return operator.ExplainStart(c.spec, c.options)
+	case operator.StopOperation:
+		return operator.ExplainStop(c.spec, c.options)
+	case operator.RestartOperation:
+		return operator.ExplainRestart(c.spec, c.options)
+	}
+	return nil
+}
+
 // Rollback implements the Task interface
 func (c *ClusterOperate) Rollback(ctx *Context) error {
 	return ErrUnsupportedRollback
@@ -69,3 +69,8 @@ func (c *BackupComponent) String() string {
 	return fmt.Sprintf("BackupComponent: component=%s, currentVersion=%s, remote=%s:%s",
 		c.component, c.fromVer, c.host, c.deployDir)
 }
+
+// Hosts implements the HostTask interface
+func (c *BackupComponent) Hosts() []string {
+	return []string{c.host}
+}
@@ -0,0 +1,227 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/module"
+)
+
+// The states of an item reported by BootstrapUser
+const (
+	BootstrapCreated = "created"
+	BootstrapUpdated = "updated"
+	BootstrapExisted = "existed"
+)
+
+var (
+	// ErrBootstrapUserFailed is ErrBootstrapUserFailed
+	ErrBootstrapUserFailed = errNSEnvInit.NewType("bootstrap_user_failed")
+
+	// the systemctl actions the deploy user is allowed to run on cluster units,
+	// the executor runs the plain systemctl commands by sudo directly, so they
+	// are matched by the rules. The property shown is checked by restart
+	// before restarting a unit, see needDaemonReload of the operator.
+	bootstrapSystemctlActions = []string{"start", "stop", "restart", "reload", "status", "enable", "disable", "is-active", "is-enabled", "show -p NeedDaemonReload"}
+	sudoersAliasRegexp        = regexp.MustCompile(`[^A-Z0-9_]`)
+)
+
+// BootstrapReport records what BootstrapUser did on a host
+type BootstrapReport struct {
+	Host          string
+	User          string // state of the deploy user
+	AuthorizedKey string // state of the public key in authorized_keys
+	Sudoers       string // state of the sudoers drop-in
+}
+
+// BootstrapUser prepares the deploy user on a remote host with the root
+// executor: it creates the user if missing, authorizes the public key of
+// the control machine and writes a sudoers drop-in that only allows the
+// user to run systemctl on the units of the cluster. The files are deployed
+// by the root executor as well, the user is only trusted with operating the
+// units afterwards, i.e., start, stop and restart, the operations changing
+// the files on the hosts are refused for the cluster.
+type BootstrapUser struct {
+	host        string
+	clusterName string
+	deployUser  string
+	userGroup   string
+	units       []string
+	report      *BootstrapReport
+}
+
+// Execute implements the Task interface
+func (b *BootstrapUser) Execute(ctx *Context) error {
+	wrapError := func(err error) *errorx.Error {
+		return ErrBootstrapUserFailed.Wrap(err, "Failed to bootstrap user '%s' on remote host '%s'", b.deployUser, b.host)
+	}
+
+	exec, found := ctx.GetExecutor(b.host)
+	if !found {
+		panic(ErrNoExecutor)
+	}
+
+	b.report.Host = b.host
+
+	// user
+	if _, _, err := exec.Execute(fmt.Sprintf("id -u %s > /dev/null 2>&1", b.deployUser), true); err == nil {
+		b.report.User = BootstrapExisted
+	} else {
+		um := module.NewUserModule(module.UserModuleConfig{
+			Action: module.UserActionAdd,
+			Name:   b.deployUser,
+			Group:  b.userGroup,
+		})
+		if _, _, err := um.Execute(exec); err != nil {
+			return wrapError(err)
+		}
+		b.report.User = BootstrapCreated
+	}
+
+	// public key
+	pubKey, err := ioutil.ReadFile(ctx.PublicKeyPath)
+	if err != nil {
+		return wrapError(err)
+	}
+	pk := strings.TrimSpace(string(pubKey))
+	fields := strings.Fields(pk)
+	if len(fields) < 2 {
+		return wrapError(fmt.Errorf("invalid public key file '%s'", ctx.PublicKeyPath))
+	}
+
+	cmd := `su - ` + b.deployUser + ` -c 'test -d ~/.ssh || mkdir -p ~/.ssh && chmod 700 ~/.ssh'`
+	if _, _, err := exec.Execute(cmd, true); err != nil {
+		return wrapError(errEnvInitSubCommandFailed.
+			Wrap(err, "Failed to create '~/.ssh' directory for user '%s'", b.deployUser))
+	}
+
+	sshAuthorizedKeys := findSSHAuthorizedKeysFile(exec)
+	// the base64 body of the key is enough to identify it and has no spaces
+	cmd = fmt.Sprintf(`su - %s -c 'grep -qF %s %s'`, b.deployUser, fields[1], sshAuthorizedKeys)
+	if _, _, err := exec.Execute(cmd, true); err == nil {
+		b.report.AuthorizedKey = BootstrapExisted
+	} else {
+		cmd = fmt.Sprintf(`su - %[1]s -c 'echo %[2]s >> %[3]s && chmod 600 %[3]s'`,
+			b.deployUser, pk, sshAuthorizedKeys)
+		if _, _, err := exec.Execute(cmd, true); err != nil {
+			return wrapError(errEnvInitSubCommandFailed.
+				Wrap(err, "Failed to write public keys to '%s' for user '%s'", sshAuthorizedKeys, b.deployUser))
+		}
+		b.report.AuthorizedKey = BootstrapCreated
+	}
+
+	// sudoers
+	state, err := b.writeSudoers(exec, ctx.RemoteTmpDir(b.host))
+	if err != nil {
+		return wrapError(err)
+	}
+	b.report.Sudoers = state
+
+	return nil
+}
+
+func (b *BootstrapUser) sudoersPath() string {
+	// files with a '.' in their names are ignored by sudo in /etc/sudoers.d
+	return fmt.Sprintf("/etc/sudoers.d/tiup-%s", b.clusterName)
+}
+
+func (b *BootstrapUser) sudoersContent(systemctl string) string {
+	alias := "TIUP_" + sudoersAliasRegexp.ReplaceAllString(strings.ToUpper(b.clusterName), "_") + "_UNITS"
+
+	units := append([]string{}, b.units...)
+	sort.Strings(units)
+
+	cmds := []string{fmt.Sprintf("%s daemon-reload", systemctl)}
+	for _, unit := range units {
+		for _, action := range bootstrapSystemctlActions {
+			cmds = append(cmds, fmt.Sprintf("%s %s %s", systemctl, action, unit))
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Generated by TiUP for cluster %s, do not edit\n", b.clusterName)
+	fmt.Fprintf(&sb, "Cmnd_Alias %s = %s\n", alias, strings.Join(cmds, ", \\\n    "))
+	fmt.Fprintf(&sb, "%s ALL=(root) NOPASSWD: %s\n", b.deployUser, alias)
+	return sb.String()
+}
+
+// writeSudoers writes the drop-in only if its content changed, the file is
+// validated with visudo before it is moved into place
+func (b *BootstrapUser) writeSudoers(exec executor.Executor, tmpDir string) (string, error) {
+	systemctl := "/usr/bin/systemctl"
+	if stdout, _, err := exec.Execute("command -v systemctl", true); err == nil {
+		if p := strings.TrimSpace(string(stdout)); p != "" {
+			systemctl = p
+		}
+	}
+	content := b.sudoersContent(systemctl)
+
+	state := BootstrapCreated
+	if stdout, _, err := exec.Execute(fmt.Sprintf("cat %s", b.sudoersPath()), true); err == nil {
+		if strings.TrimSpace(string(stdout)) == strings.TrimSpace(content) {
+			return BootstrapExisted, nil
+		}
+		state = BootstrapUpdated
+	}
+
+	f, err := ioutil.TempFile("", "tiup-sudoers-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	tgt := filepath.Join(tmpDir, "tiup-sudoers-"+uuid.New().String())
+	if err := exec.Transfer(f.Name(), tgt, false); err != nil {
+		return "", err
+	}
+	cmd := fmt.Sprintf("visudo -cf %[1]s && chown root:root %[1]s && chmod 0440 %[1]s && mv %[1]s %[2]s || (rm -f %[1]s; exit 1)",
+		tgt, b.sudoersPath())
+	if _, stderr, err := exec.Execute(cmd, true); err != nil {
+		return "", errEnvInitSubCommandFailed.
+			Wrap(err, "Failed to write sudoers file '%s': %s", b.sudoersPath(), strings.TrimSpace(string(stderr)))
+	}
+	return state, nil
+}
+
+// Rollback implements the Task interface
+func (b *BootstrapUser) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (b *BootstrapUser) String() string {
+	return fmt.Sprintf("BootstrapUser: user=%s, host=%s", b.deployUser, b.host)
+}
+
+// Hosts implements the HostTask interface
+func (b *BootstrapUser) Hosts() []string {
+	return []string{b.host}
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"strings"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/module"
+)
+
+type bootstrapUserSuite struct{}
+
+var _ = check.Suite(&bootstrapUserSuite{})
+
+func (s *bootstrapUserSuite) TestSudoersContent(c *check.C) {
+	b := &BootstrapUser{
+		clusterName: "prod-eu.1",
+		deployUser:  "tidb",
+		units:       []string{"tikv-20160.service", "pd-2379.service"},
+	}
+	c.Assert(b.sudoersPath(), check.Equals, "/etc/sudoers.d/tiup-prod-eu.1")
+
+	content := b.sudoersContent("/usr/bin/systemctl")
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	c.Assert(lines[0], check.Equals, "# Generated by TiUP for cluster prod-eu.1, do not edit")
+	c.Assert(lines[1], check.Equals, "Cmnd_Alias TIUP_PROD_EU_1_UNITS = /usr/bin/systemctl daemon-reload, \\")
+	c.Assert(lines[2], check.Equals, "    /usr/bin/systemctl start pd-2379.service, \\")
+	c.Assert(lines[len(lines)-1], check.Equals, "tidb ALL=(root) NOPASSWD: TIUP_PROD_EU_1_UNITS")
+	c.Assert(len(lines), check.Equals, 3+2*len(bootstrapSystemctlActions))
+	c.Assert(content, check.Not(check.Matches), "(?s).*(bash|\\*).*")
+
+	// the commands operating the units are sent to sudo as they are allowed
+	allowed := make(map[string]bool)
+	for _, line := range lines[1 : len(lines)-1] {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "Cmnd_Alias TIUP_PROD_EU_1_UNITS = ")
+		allowed[strings.TrimSuffix(line, ", \\")] = true
+	}
+	for _, action := range []string{"start", "stop", "restart"} {
+		cmd := module.NewSystemdModule(module.SystemdModuleConfig{
+			Unit:         "tikv-20160.service",
+			Action:       action,
+			Enabled:      true,
+			ReloadDaemon: true,
+		}).Command()
+		for _, sudo := range strings.Split(executor.RemoteCommand(cmd, true, ""), " && ") {
+			sudo = strings.TrimPrefix(sudo, "PATH=$PATH:/usr/bin:/usr/sbin ")
+			c.Assert(strings.HasPrefix(sudo, "sudo -H -u root systemctl "), check.IsTrue, check.Commentf(sudo))
+			sudo = strings.Replace(sudo, "sudo -H -u root systemctl", "/usr/bin/systemctl", 1)
+			c.Assert(allowed[sudo], check.IsTrue, check.Commentf(sudo))
+		}
+	}
+	// restart checks if systemd is to be reloaded for the unit
+	sudo := strings.TrimPrefix(executor.RemoteCommand("systemctl show -p NeedDaemonReload tikv-20160.service", true, ""), "PATH=$PATH:/usr/bin:/usr/sbin ")
+	sudo = strings.Replace(sudo, "sudo -H -u root systemctl", "/usr/bin/systemctl", 1)
+	c.Assert(allowed[sudo], check.IsTrue, check.Commentf(sudo))
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/tiup/pkg/errutil"
+)
+
+// breakpoints are where the tasks executed with a context pause, for
+// debugging an operation by inspecting the hosts at a step
+type breakpoints struct {
+	sync.Mutex
+	steps    map[string]struct{} // the names or identities of steps to pause before
+	onErrors map[string]struct{} // the errorx types to pause at the first failure of
+	resume   chan struct{}       // closed to resume, nil if not paused
+	pausedAt string
+}
+
+// SetBreakpoints sets the steps to pause before, matched against the
+// identities, names and strings of tasks, e.g. "UpgradeInstance tikv
+// 10.0.0.7:20160", and the full names of the errorx types to pause at the
+// first failure of, e.g. "executor.ssh_execute_failed". The paused tasks
+// report PhasePaused and wait until Resume is called.
+func (ctx *Context) SetBreakpoints(steps, onErrors []string) {
+	ctx.breaks.Lock()
+	defer ctx.breaks.Unlock()
+	ctx.breaks.steps = make(map[string]struct{})
+	for _, s := range steps {
+		ctx.breaks.steps[strings.TrimSpace(s)] = struct{}{}
+	}
+	ctx.breaks.onErrors = make(map[string]struct{})
+	for _, e := range onErrors {
+		ctx.breaks.onErrors[strings.TrimSpace(e)] = struct{}{}
+	}
+}
+
+// Paused returns where the tasks are paused, false if they are not
+func (ctx *Context) Paused() (string, bool) {
+	ctx.breaks.Lock()
+	defer ctx.breaks.Unlock()
+	return ctx.breaks.pausedAt, ctx.breaks.resume != nil
+}
+
+// Resume resumes the paused tasks, false is returned if they are not paused
+func (ctx *Context) Resume() bool {
+	ctx.breaks.Lock()
+	defer ctx.breaks.Unlock()
+	if ctx.breaks.resume == nil {
+		return false
+	}
+	close(ctx.breaks.resume)
+	ctx.breaks.resume = nil
+	ctx.breaks.pausedAt = ""
+	return true
+}
+
+// breakBefore pauses before executing t if any name of it is a breakpoint
+func (ctx *Context) breakBefore(t Task) {
+	ctx.breakAt(t, ctx.TaskID(t), stepName(t), strings.Split(t.String(), "\n")[0])
+}
+
+// breakAt pauses t if any of the step names is a breakpoint, it's also used
+// by the operator functions reporting their steps, see phaseGetter.Break
+func (ctx *Context) breakAt(t Task, names ...string) {
+	ctx.breaks.Lock()
+	if len(ctx.breaks.steps) == 0 {
+		ctx.breaks.Unlock()
+		return
+	}
+	for _, name := range names {
+		if _, ok := ctx.breaks.steps[name]; ok {
+			ctx.breaks.Unlock()
+			ctx.pause(t, fmt.Sprintf("before step `%s`", name))
+			return
+		}
+	}
+	ctx.breaks.Unlock()
+}
+
+// breakOnError pauses after t failed with err if it's the first failure of an
+// errorx type to break on
+func (ctx *Context) breakOnError(t Task, err error) {
+	errx := errutil.Cast(err)
+	if errx == nil {
+		return
+	}
+	typ := errx.Type().FullName()
+	ctx.breaks.Lock()
+	if _, ok := ctx.breaks.onErrors[typ]; !ok {
+		ctx.breaks.Unlock()
+		return
+	}
+	delete(ctx.breaks.onErrors, typ)
+	ctx.breaks.Unlock()
+	ctx.pause(t, fmt.Sprintf("on error %s of step `%s`", typ, stepName(t)))
+}
+
+// pause reports PhasePaused for t and blocks until Resume is called, the
+// tasks pausing at the same time are resumed together
+func (ctx *Context) pause(t Task, where string) {
+	ctx.breaks.Lock()
+	if ctx.breaks.resume == nil {
+		ctx.breaks.resume = make(chan struct{})
+		ctx.breaks.pausedAt = where
+	}
+	resume := ctx.breaks.resume
+	ctx.breaks.Unlock()
+
+	ctx.SetPhase(t, PhasePaused, where)
+	<-resume
+}
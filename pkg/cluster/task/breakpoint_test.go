@@ -0,0 +1,82 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"sync"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/check"
+)
+
+type breakpointSuite struct{}
+
+var _ = check.Suite(&breakpointSuite{})
+
+func (s *breakpointSuite) TestBreakpoints(c *check.C) {
+	errTest := errorx.NewNamespace("test").NewType("failed")
+
+	var mu sync.Mutex
+	var steps []string
+	step := func(name string, err error) *Func {
+		return NewFunc(name, func(ctx *Context) error {
+			mu.Lock()
+			steps = append(steps, name)
+			mu.Unlock()
+			return err
+		})
+	}
+
+	ctx := NewContext()
+	ctx.SetNamespace("upgrade/test")
+	ctx.SetBreakpoints([]string{"Second", "upgrade/test/Fourth"}, []string{"test.failed"})
+	paused := make(chan string, 8)
+	ctx.Subscribe(EventTaskPhase, func(t Task, phase Phase, detail string) {
+		if phase == PhasePaused {
+			paused <- detail
+		}
+	})
+	t := NewBuilder().
+		Serial(step("First", nil), step("Second", nil), step("Third", errTest.New("oops"))).
+		Func("Fourth", func(ctx *Context) error { return nil }).
+		Mode(ContinueCollectingErrors).
+		Build()
+
+	done := make(chan error)
+	go func() { done <- t.Execute(ctx) }()
+
+	// paused by the name, before the step is executed
+	c.Assert(<-paused, check.Equals, "before step `Second`")
+	where, ok := ctx.Paused()
+	c.Assert(ok, check.IsTrue)
+	c.Assert(where, check.Equals, "before step `Second`")
+	mu.Lock()
+	c.Assert(steps, check.DeepEquals, []string{"First"})
+	mu.Unlock()
+	c.Assert(ctx.Resume(), check.IsTrue)
+
+	// paused by the type of the error, only at the first failure
+	c.Assert(<-paused, check.Equals, "on error test.failed of step `Third`")
+	c.Assert(ctx.Resume(), check.IsTrue)
+
+	// paused by the identity
+	c.Assert(<-paused, check.Equals, "before step `upgrade/test/Fourth`")
+	c.Assert(ctx.Resume(), check.IsTrue)
+
+	c.Assert(<-done, check.NotNil)
+	_, ok = ctx.Paused()
+	c.Assert(ok, check.IsFalse)
+	c.Assert(ctx.Resume(), check.IsFalse)
+	c.Assert(paused, check.HasLen, 0)
+}
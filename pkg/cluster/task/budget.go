@@ -0,0 +1,269 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+)
+
+// ErrDeadlineExceeded is returned by the steps started after the deadline of
+// the operation, and the commands on hosts started after it, see SetDeadline
+var ErrDeadlineExceeded = errNS.NewType("deadline_exceeded")
+
+// PhaseTiming is the time spent on a phase of the operation, the phases are
+// the steps of the outermost task, e.g., "+ Upgrade Cluster"
+type PhaseTiming struct {
+	Phase    string        `json:"phase"`
+	Elapsed  time.Duration `json:"elapsed"`
+	InFlight bool          `json:"in_flight,omitempty"`
+}
+
+// BudgetReport is where the time went when the deadline of the operation
+// was exceeded
+type BudgetReport struct {
+	Budget  time.Duration `json:"budget"`
+	Elapsed time.Duration `json:"elapsed"`
+	Phases  []PhaseTiming `json:"phases"`
+	// the steps executing when the deadline was exceeded, with their elapsed time
+	InFlight []PhaseTiming `json:"in_flight"`
+}
+
+// String formats the report as lines of the phases and the steps in flight
+func (r *BudgetReport) String() string {
+	lines := []string{fmt.Sprintf("exceeded the time budget %s of the operation after %s:", r.Budget, r.Elapsed.Round(time.Second))}
+	for _, p := range r.Phases {
+		line := fmt.Sprintf("  - %s: %s", p.Phase, p.Elapsed.Round(time.Millisecond))
+		if p.InFlight {
+			line += " (in flight)"
+		}
+		lines = append(lines, line)
+	}
+	for _, s := range r.InFlight {
+		lines = append(lines, fmt.Sprintf("  in flight: %s, for %s", s.Phase, s.Elapsed.Round(time.Millisecond)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// budget is the deadline of the tasks executed with a context, and the time
+// spent on their steps
+type budget struct {
+	sync.Mutex
+	ctx      context.Context // done when the deadline is exceeded, nil if there is none
+	cancel   context.CancelFunc
+	timer    *time.Timer // takes the report at the deadline
+	start    time.Time
+	deadline time.Time
+	phases   []PhaseTiming
+	begun    map[Task]time.Time // the steps executing, by when they began
+	phaseOf  map[Task]int       // the indexes of the phases executing
+	report   *BudgetReport      // taken when the deadline is exceeded
+}
+
+// SetDeadline makes the tasks executed with the context stop starting new
+// steps after the deadline, the steps started after it fail with
+// ErrDeadlineExceeded, so the operation is aborted with the usual cleanup as
+// any failure. The commands the steps executing run on the hosts time out
+// at the deadline, see boundTimeout, while the transfers of files are not
+// interrupted. The time spent on
+// each phase and the steps in flight at the deadline are reported by
+// BudgetReport. The deadline is stopped by StopDeadline when the operation
+// ends.
+func (ctx *Context) SetDeadline(deadline time.Time) {
+	ctx.StopDeadline()
+	dctx, cancel := context.WithDeadline(context.Background(), deadline)
+	ctx.budget.Lock()
+	defer ctx.budget.Unlock()
+	ctx.budget.ctx = dctx
+	ctx.budget.cancel = cancel
+	ctx.budget.start = time.Now()
+	ctx.budget.deadline = deadline
+	if ctx.budget.begun == nil {
+		ctx.budget.begun = make(map[Task]time.Time)
+		ctx.budget.phaseOf = make(map[Task]int)
+	}
+	ctx.budget.timer = time.AfterFunc(time.Until(deadline), func() {
+		cancel()
+		ctx.budget.exceeded()
+	})
+}
+
+// StopDeadline stops the timer of the deadline and cancels the context of
+// the steps still executing, it's called when the operation ends, so neither
+// the timer nor the context outlives it. The steps executed afterwards are
+// not limited by the deadline.
+func (ctx *Context) StopDeadline() {
+	ctx.budget.Lock()
+	defer ctx.budget.Unlock()
+	if ctx.budget.timer != nil {
+		ctx.budget.timer.Stop()
+		ctx.budget.timer = nil
+	}
+	if ctx.budget.cancel != nil {
+		ctx.budget.cancel()
+		ctx.budget.cancel = nil
+	}
+	ctx.budget.ctx = nil
+}
+
+// BudgetReport returns where the time went when the deadline was exceeded,
+// nil if it's not exceeded
+func (ctx *Context) BudgetReport() *BudgetReport {
+	ctx.budget.Lock()
+	defer ctx.budget.Unlock()
+	return ctx.budget.report
+}
+
+// checkDeadline returns ErrDeadlineExceeded if the deadline is exceeded
+// before executing t
+func (ctx *Context) checkDeadline(t Task) error {
+	return ctx.checkDeadlineAt(stepName(t))
+}
+
+// checkDeadlineAt returns ErrDeadlineExceeded if the deadline is exceeded
+// before the step, it's also used by the operator functions reporting their
+// steps, see phaseGetter.CheckDeadline
+func (ctx *Context) checkDeadlineAt(step string) error {
+	ctx.budget.Lock()
+	dctx := ctx.budget.ctx
+	ctx.budget.Unlock()
+	if dctx == nil || dctx.Err() == nil {
+		return nil
+	}
+	return ErrDeadlineExceeded.New("%s\nstep `%s` is not started", ctx.budget.exceeded(), step)
+}
+
+// deadline returns the deadline of the tasks, false if there is none or it's
+// stopped, see SetDeadline
+func (ctx *Context) deadline() (time.Time, bool) {
+	ctx.budget.Lock()
+	dctx := ctx.budget.ctx
+	ctx.budget.Unlock()
+	if dctx == nil {
+		return time.Time{}, false
+	}
+	return dctx.Deadline()
+}
+
+// boundTimeout bounds the timeout of cmd executed on host by the time left
+// before the deadline, the default timeout of the executor is bounded if none
+// is specified. ErrDeadlineExceeded is returned if the deadline is exceeded
+// already. The timeout is unchanged if there is no deadline.
+func (ctx *Context) boundTimeout(host, cmd string, timeout []time.Duration) ([]time.Duration, error) {
+	ctx.budget.Lock()
+	dctx := ctx.budget.ctx
+	ctx.budget.Unlock()
+	if dctx == nil {
+		return timeout, nil
+	}
+	deadline, _ := dctx.Deadline()
+	left := time.Until(deadline)
+	if dctx.Err() != nil || left <= 0 {
+		return nil, ErrDeadlineExceeded.New("%s\ncommand `%s` on %s is not started", ctx.budget.exceeded(), cmd, host)
+	}
+	t := executor.DefaultExecuteTimeout()
+	if len(timeout) > 0 && timeout[0] > 0 {
+		t = timeout[0]
+	}
+	if t > left {
+		t = left
+	}
+	return []time.Duration{t}, nil
+}
+
+// exceeded takes the report when the deadline is exceeded, by the timer or
+// by the first step noticing it, whichever is earlier
+func (b *budget) exceeded() *BudgetReport {
+	b.Lock()
+	defer b.Unlock()
+	if b.report == nil {
+		b.report = b.snapshot(time.Now())
+	}
+	return b.report
+}
+
+// beginStep records t begins executing, it's a phase if it's a step of the
+// outermost task. The phases are timed even if there is no deadline, see
+// PhaseTimings.
+func (ctx *Context) beginStep(t Task, phase bool) {
+	ctx.budget.Lock()
+	defer ctx.budget.Unlock()
+	if ctx.budget.begun == nil {
+		ctx.budget.begun = make(map[Task]time.Time)
+		ctx.budget.phaseOf = make(map[Task]int)
+	}
+	now := time.Now()
+	ctx.budget.begun[t] = now
+	if phase {
+		ctx.budget.phaseOf[t] = len(ctx.budget.phases)
+		ctx.budget.phases = append(ctx.budget.phases, PhaseTiming{Phase: stepName(t), InFlight: true})
+	}
+}
+
+// finishStep records t finishes executing
+func (ctx *Context) finishStep(t Task) {
+	ctx.budget.Lock()
+	defer ctx.budget.Unlock()
+	if ctx.budget.begun == nil {
+		return
+	}
+	if i, ok := ctx.budget.phaseOf[t]; ok {
+		ctx.budget.phases[i].Elapsed = time.Since(ctx.budget.begun[t])
+		ctx.budget.phases[i].InFlight = false
+		delete(ctx.budget.phaseOf, t)
+	}
+	delete(ctx.budget.begun, t)
+}
+
+// PhaseTimings returns the time spent on the phases executed with the
+// context so far, the ones executing are marked in flight
+func (ctx *Context) PhaseTimings() []PhaseTiming {
+	ctx.budget.Lock()
+	defer ctx.budget.Unlock()
+	phases := append([]PhaseTiming{}, ctx.budget.phases...)
+	now := time.Now()
+	for t, i := range ctx.budget.phaseOf {
+		phases[i].Elapsed = now.Sub(ctx.budget.begun[t])
+	}
+	return phases
+}
+
+// snapshot returns the time spent on the phases, and the innermost steps
+// executing at now, the lock must be held
+func (b *budget) snapshot(now time.Time) *BudgetReport {
+	r := &BudgetReport{
+		Budget:  b.deadline.Sub(b.start).Round(time.Second),
+		Elapsed: now.Sub(b.start),
+		Phases:  append([]PhaseTiming{}, b.phases...),
+	}
+	for t, i := range b.phaseOf {
+		r.Phases[i].Elapsed = now.Sub(b.begun[t])
+	}
+	for t, begun := range b.begun {
+		if isDisplayTask(t) {
+			continue
+		}
+		r.InFlight = append(r.InFlight, PhaseTiming{Phase: stepName(t), Elapsed: now.Sub(begun), InFlight: true})
+	}
+	sort.Slice(r.InFlight, func(i, j int) bool {
+		return r.InFlight[i].Elapsed > r.InFlight[j].Elapsed
+	})
+	return r
+}
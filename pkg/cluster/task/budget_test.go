@@ -0,0 +1,170 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/check"
+)
+
+type budgetSuite struct{}
+
+var _ = check.Suite(&budgetSuite{})
+
+func (s *budgetSuite) TestDeadline(c *check.C) {
+	noop := func(ctx *Context) error { return nil }
+	ctx := NewContext()
+	ctx.SetDeadline(time.Now().Add(100 * time.Millisecond))
+
+	started := false
+	t := NewBuilder().
+		Func("CheckStatus", noop).
+		Step("+ Restart instances", NewBuilder().
+			Func("RestartTiKV", func(ctx *Context) error {
+				time.Sleep(200 * time.Millisecond)
+				return nil
+			}).
+			Func("RestartTiDB", func(ctx *Context) error {
+				started = true
+				return nil
+			}).
+			Build()).
+		Func("CheckStatus", noop).
+		Build()
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrDeadlineExceeded), check.IsTrue)
+	c.Assert(started, check.IsFalse)
+	c.Assert(strings.Contains(err.Error(), "step `RestartTiDB` is not started"), check.IsTrue)
+
+	// the report is taken when the deadline is exceeded, in the middle of
+	// restarting TiKV
+	r := ctx.BudgetReport()
+	c.Assert(r, check.NotNil)
+	c.Assert(r.Phases, check.HasLen, 2)
+	c.Assert(r.Phases[0].Phase, check.Equals, "CheckStatus")
+	c.Assert(r.Phases[0].InFlight, check.IsFalse)
+	c.Assert(r.Phases[1].Phase, check.Equals, "Restart instances")
+	c.Assert(r.Phases[1].InFlight, check.IsTrue)
+	c.Assert(r.InFlight, check.HasLen, 1)
+	c.Assert(r.InFlight[0].Phase, check.Equals, "RestartTiKV")
+
+	// no deadline, no report
+	ctx = NewContext()
+	c.Assert(t.Execute(ctx), check.IsNil)
+	c.Assert(ctx.BudgetReport(), check.IsNil)
+}
+
+func (s *budgetSuite) TestPhaseTimings(c *check.C) {
+	// the phases are timed without a deadline
+	ctx := NewContext()
+	t := NewBuilder().
+		Func("CheckStatus", func(ctx *Context) error { return nil }).
+		Func("RestartTiKV", func(ctx *Context) error {
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		}).
+		Build()
+	c.Assert(t.Execute(ctx), check.IsNil)
+	phases := ctx.PhaseTimings()
+	c.Assert(phases, check.HasLen, 2)
+	c.Assert(phases[0].Phase, check.Equals, "CheckStatus")
+	c.Assert(phases[1].Phase, check.Equals, "RestartTiKV")
+	c.Assert(phases[1].Elapsed >= 50*time.Millisecond, check.IsTrue)
+	c.Assert(phases[1].InFlight, check.IsFalse)
+	c.Assert(ctx.BudgetReport(), check.IsNil)
+}
+
+func (s *budgetSuite) TestStopDeadline(c *check.C) {
+	ctx := NewContext()
+	ctx.SetDeadline(time.Now().Add(50 * time.Millisecond))
+	ctx.budget.Lock()
+	dctx := ctx.budget.ctx
+	ctx.budget.Unlock()
+
+	// the timer is stopped and the context is canceled with the operation,
+	// rather than being left until the deadline
+	ctx.StopDeadline()
+	c.Assert(dctx.Err(), check.Equals, context.Canceled)
+	time.Sleep(100 * time.Millisecond)
+	c.Assert(ctx.BudgetReport(), check.IsNil)
+	c.Assert(ctx.checkDeadlineAt("RestartTiKV"), check.IsNil)
+
+	// the deadline set again replaces the previous one
+	ctx.SetDeadline(time.Now().Add(time.Hour))
+	ctx.budget.Lock()
+	timer := ctx.budget.timer
+	ctx.budget.Unlock()
+	ctx.SetDeadline(time.Now())
+	c.Assert(timer.Stop(), check.IsFalse)
+	time.Sleep(50 * time.Millisecond)
+	c.Assert(ctx.BudgetReport(), check.NotNil)
+	ctx.StopDeadline()
+}
+
+// timeoutRecorder records the timeouts of the commands executed
+type timeoutRecorder struct {
+	timeouts [][]time.Duration
+}
+
+func (e *timeoutRecorder) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	e.timeouts = append(e.timeouts, timeout)
+	return nil, nil, nil
+}
+
+func (e *timeoutRecorder) Transfer(src string, dst string, download bool) error {
+	return nil
+}
+
+func (s *budgetSuite) TestBoundTimeout(c *check.C) {
+	ctx := NewContext()
+	rec := &timeoutRecorder{}
+	ctx.SetExecutor("10.0.0.1", rec)
+	e, _ := ctx.GetExecutor("10.0.0.1")
+
+	// the timeouts are passed as is without a deadline
+	_, _, err := e.Execute("uptime", false)
+	c.Assert(err, check.IsNil)
+	_, _, err = e.Execute("uptime", false, time.Hour)
+	c.Assert(err, check.IsNil)
+	c.Assert(rec.timeouts, check.DeepEquals, [][]time.Duration{nil, {time.Hour}})
+
+	// the commands don't outlive the deadline, the default timeout is bounded
+	// as well
+	rec.timeouts = nil
+	ctx.SetDeadline(time.Now().Add(30 * time.Second))
+	_, _, err = e.Execute("uptime", false, time.Hour)
+	c.Assert(err, check.IsNil)
+	_, _, err = e.Execute("uptime", false)
+	c.Assert(err, check.IsNil)
+	_, _, err = e.Execute("uptime", false, time.Second)
+	c.Assert(err, check.IsNil)
+	c.Assert(rec.timeouts, check.HasLen, 3)
+	c.Assert(rec.timeouts[0][0] <= 30*time.Second, check.IsTrue)
+	c.Assert(rec.timeouts[0][0] > 20*time.Second, check.IsTrue)
+	c.Assert(rec.timeouts[1][0] <= 30*time.Second, check.IsTrue)
+	c.Assert(rec.timeouts[2], check.DeepEquals, []time.Duration{time.Second})
+
+	// no command is started after the deadline
+	ctx.SetDeadline(time.Now())
+	time.Sleep(10 * time.Millisecond)
+	_, _, err = e.Execute("uptime", false)
+	c.Assert(errorx.IsOfType(err, ErrDeadlineExceeded), check.IsTrue)
+	c.Assert(strings.Contains(err.Error(), "command `uptime` on 10.0.0.1 is not started"), check.IsTrue)
+	c.Assert(rec.timeouts, check.HasLen, 3)
+	ctx.StopDeadline()
+}
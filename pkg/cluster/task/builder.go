@@ -16,6 +16,7 @@ package task
 import (
 	"fmt"
 	"path/filepath"
+	"time"
 
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
@@ -24,7 +25,12 @@ import (
 
 // Builder is used to build TiOps task
 type Builder struct {
-	tasks []Task
+	tasks           []Task
+	mode            ErrorMode
+	modeFixed       bool
+	orderedRollback bool
+	serial          bool
+	transfer        TransferOptions
 }
 
 // NewBuilder returns a *Builder instance
@@ -66,11 +72,8 @@ func (b *Builder) UserSSH(host string, port int, deployUser string, sshTimeout i
 }
 
 // Func append a func task.
-func (b *Builder) Func(name string, fn func(ctx *Context) error) *Builder {
-	b.tasks = append(b.tasks, &Func{
-		name: name,
-		fn:   fn,
-	})
+func (b *Builder) Func(name string, fn func(ctx *Context) error, opts ...FuncOption) *Builder {
+	b.tasks = append(b.tasks, NewFunc(name, fn, opts...))
 	return b
 }
 
@@ -117,6 +120,7 @@ func (b *Builder) CopyFile(src, dst, server string, download bool) *Builder {
 		dst:      dst,
 		remote:   server,
 		download: download,
+		transfer: b.transfer,
 	})
 	return b
 }
@@ -127,6 +131,19 @@ func (b *Builder) Download(component, os, arch string, version string) *Builder
 	return b
 }
 
+// DownloadFrom appends a Downloader task taking the package from the package
+// directory to the current task collection, an empty dir means the repository
+func (b *Builder) DownloadFrom(dir, component, os, arch string, version string) *Builder {
+	b.tasks = append(b.tasks, &Downloader{
+		component: component,
+		os:        os,
+		arch:      arch,
+		version:   version,
+		dir:       dir,
+	})
+	return b
+}
+
 // CopyComponent appends a CopyComponent task to the current task collection
 func (b *Builder) CopyComponent(component, os, arch string,
 	version string,
@@ -140,6 +157,39 @@ func (b *Builder) CopyComponent(component, os, arch string,
 		srcPath:   srcPath,
 		host:      dstHost,
 		dstDir:    dstDir,
+		transfer:  b.transfer,
+	})
+	return b
+}
+
+// CopyComponentCached appends a CopyComponent task which pushes the package
+// through the host-level package cache to the current task collection
+func (b *Builder) CopyComponentCached(component, os, arch string,
+	version string,
+	srcPath, dstHost, dstDir string,
+	cache *PackageCache,
+) *Builder {
+	b.tasks = append(b.tasks, &CopyComponent{
+		component: component,
+		os:        os,
+		arch:      arch,
+		version:   version,
+		srcPath:   srcPath,
+		host:      dstHost,
+		dstDir:    dstDir,
+		cache:     cache,
+		transfer:  b.transfer,
+	})
+	return b
+}
+
+// CachePackage appends a CachePackage task to the current task collection
+func (b *Builder) CachePackage(srcPath, dstHost string, cache *PackageCache) *Builder {
+	b.tasks = append(b.tasks, &CachePackage{
+		srcPath:  srcPath,
+		host:     dstHost,
+		cache:    cache,
+		transfer: b.transfer,
 	})
 	return b
 }
@@ -147,9 +197,10 @@ func (b *Builder) CopyComponent(component, os, arch string,
 // InstallPackage appends a InstallPackage task to the current task collection
 func (b *Builder) InstallPackage(srcPath, dstHost, dstDir string) *Builder {
 	b.tasks = append(b.tasks, &InstallPackage{
-		srcPath: srcPath,
-		host:    dstHost,
-		dstDir:  dstDir,
+		srcPath:  srcPath,
+		host:     dstHost,
+		dstDir:   dstDir,
+		transfer: b.transfer,
 	})
 	return b
 }
@@ -179,6 +230,23 @@ func (b *Builder) InitConfig(clusterName, clusterVersion string, specManager *sp
 	return b
 }
 
+// RefreshConfig appends an InitConfig task which pushes the systemd unit and
+// the run script only if they are changed on the host, and reloads systemd
+// if the unit is changed. The changes are recorded to change.
+func (b *Builder) RefreshConfig(clusterName, clusterVersion string, specManager *spec.SpecManager, inst spec.Instance, deployUser string, ignoreCheck bool, paths meta.DirPaths, change *spec.ServiceFilesChange) *Builder {
+	b.tasks = append(b.tasks, &InitConfig{
+		specManager:    specManager,
+		clusterName:    clusterName,
+		clusterVersion: clusterVersion,
+		instance:       inst,
+		deployUser:     deployUser,
+		ignoreCheck:    ignoreCheck,
+		paths:          paths,
+		change:         change,
+	})
+	return b
+}
+
 // ScaleConfig generate temporary config on scaling
 func (b *Builder) ScaleConfig(clusterName, clusterVersion string, specManager *spec.SpecManager, topo spec.Topology, inst spec.Instance, deployUser string, paths meta.DirPaths) *Builder {
 	b.tasks = append(b.tasks, &ScaleConfig{
@@ -194,15 +262,16 @@ func (b *Builder) ScaleConfig(clusterName, clusterVersion string, specManager *s
 }
 
 // MonitoredConfig appends a CopyComponent task to the current task collection
-func (b *Builder) MonitoredConfig(name, comp, host string, globResCtl meta.ResourceControl, options *spec.MonitoredOptions, deployUser string, paths meta.DirPaths) *Builder {
+func (b *Builder) MonitoredConfig(name, clusterVersion, comp, host string, globOpts spec.GlobalOptions, options *spec.MonitoredOptions, deployUser string, paths meta.DirPaths) *Builder {
 	b.tasks = append(b.tasks, &MonitoredConfig{
-		name:       name,
-		component:  comp,
-		host:       host,
-		globResCtl: globResCtl,
-		options:    options,
-		deployUser: deployUser,
-		paths:      paths,
+		name:           name,
+		clusterVersion: clusterVersion,
+		component:      comp,
+		host:           host,
+		globOpts:       globOpts,
+		options:        options,
+		deployUser:     deployUser,
+		paths:          paths,
 	})
 	return b
 }
@@ -235,6 +304,20 @@ func (b *Builder) EnvInit(host, deployUser string, userGroup string, skipCreateU
 	return b
 }
 
+// BootstrapUser appends a BootstrapUser task to the current task collection,
+// what has been done on the host is recorded to report
+func (b *Builder) BootstrapUser(host, clusterName, deployUser, userGroup string, units []string, report *BootstrapReport) *Builder {
+	b.tasks = append(b.tasks, &BootstrapUser{
+		host:        host,
+		clusterName: clusterName,
+		deployUser:  deployUser,
+		userGroup:   userGroup,
+		units:       units,
+		report:      report,
+	})
+	return b
+}
+
 // ClusterOperate appends a cluster operation task.
 // All the UserSSH needed must be init first.
 func (b *Builder) ClusterOperate(
@@ -270,6 +353,16 @@ func (b *Builder) Rmdir(host string, dirs ...string) *Builder {
 	return b
 }
 
+// CollectInventory appends a CollectInventory task to the current task collection
+func (b *Builder) CollectInventory(host string, dirs []string, inv *operator.HostInventory) *Builder {
+	b.tasks = append(b.tasks, &CollectInventory{
+		host: host,
+		dirs: dirs,
+		inv:  inv,
+	})
+	return b
+}
+
 // Shell command on cluster host
 func (b *Builder) Shell(host, command string, sudo bool) *Builder {
 	b.tasks = append(b.tasks, &Shell{
@@ -312,6 +405,18 @@ func (b *Builder) Limit(host, domain, limit, item, value string) *Builder {
 	return b
 }
 
+// ApplyOSSettings applies the OS settings of the cluster on the host, the
+// drop-in files left by the settings dropped are removed, all of them if
+// settings is nil
+func (b *Builder) ApplyOSSettings(host, clusterName string, settings *spec.OSSettings) *Builder {
+	b.tasks = append(b.tasks, &ApplyOSSettings{
+		host:        host,
+		clusterName: clusterName,
+		settings:    settings,
+	})
+	return b
+}
+
 // CheckSys checks system information of deploy server
 func (b *Builder) CheckSys(host, dataDir, checkType string, topo *spec.Specification, opt *operator.CheckOptions) *Builder {
 	b.tasks = append(b.tasks, &CheckSys{
@@ -324,6 +429,43 @@ func (b *Builder) CheckSys(host, dataDir, checkType string, topo *spec.Specifica
 	return b
 }
 
+// PortListener appends a PortListener task to the current task collection
+func (b *Builder) PortListener(host string, ports []int, duration time.Duration) *Builder {
+	b.tasks = append(b.tasks, &PortListener{
+		host:     host,
+		ports:    ports,
+		duration: duration,
+	})
+	return b
+}
+
+// CheckConnectivity appends a CheckConnectivity task to the current task collection
+func (b *Builder) CheckConnectivity(host string, pairs []operator.ConnectivityPair, opt *operator.CheckOptions, matrix *operator.ConnectivityMatrix) *Builder {
+	b.tasks = append(b.tasks, &CheckConnectivity{
+		host:   host,
+		pairs:  pairs,
+		opt:    opt,
+		matrix: matrix,
+	})
+	return b
+}
+
+// CheckSSHAuth appends a CheckSSHAuth task to the current task collection
+func (b *Builder) CheckSSHAuth(host string, port int, user, password, keyFile, passphrase string, sshTimeout int64, nativeSSH bool, report *SSHAuthReport) *Builder {
+	b.tasks = append(b.tasks, &CheckSSHAuth{
+		host:       host,
+		port:       port,
+		user:       user,
+		password:   password,
+		keyFile:    keyFile,
+		passphrase: passphrase,
+		timeout:    sshTimeout,
+		native:     nativeSSH,
+		report:     report,
+	})
+	return b
+}
+
 // DeploySpark deployes spark as dependency of TiSpark
 func (b *Builder) DeploySpark(inst spec.Instance, version, srcPath, deployDir string, bindVersion spec.BindVersion) *Builder {
 	sparkSubPath := spec.ComponentSubDir(spec.ComponentSpark,
@@ -376,13 +518,57 @@ func (b *Builder) Serial(tasks ...Task) *Builder {
 	return b
 }
 
+// Mode sets how the built task handles failures of the tasks appended, it
+// applies to all the Serial and Parallel tasks nested in the built task,
+// except the ones built with their own modes set by Mode, which are kept
+func (b *Builder) Mode(mode ErrorMode) *Builder {
+	b.mode = mode
+	b.modeFixed = true
+	return b
+}
+
+// Transfer sets how the files are pushed to hosts by the copy tasks appended
+// after it, e.g., CopyComponent and InstallPackage
+func (b *Builder) Transfer(opts TransferOptions) *Builder {
+	b.transfer = opts
+	return b
+}
+
+// OrderedRollback makes the tasks appended by Parallel and ParallelStep roll
+// back one by one in the reverse order they finished, instead of concurrently
+func (b *Builder) OrderedRollback() *Builder {
+	b.orderedRollback = true
+	return b
+}
+
+// Serialize makes the tasks appended by Parallel and ParallelStep execute
+// their inner tasks one by one in order, the steps are still displayed
+// together and only the one executing is active
+func (b *Builder) Serialize() *Builder {
+	b.serial = true
+	return b
+}
+
 // Build returns a task that contains all tasks appended by previous operation
 func (b *Builder) Build() Task {
 	// Serial handles event internally. So the following 3 lines are commented out.
 	//if len(b.tasks) == 1 {
 	//	return b.tasks[0]
 	//}
-	return &Serial{inner: b.tasks}
+	for _, t := range b.tasks {
+		switch pt := t.(type) {
+		case *Parallel:
+			pt.orderedRollback = b.orderedRollback
+			pt.serial = b.serial
+		case *ParallelStepDisplay:
+			pt.inner.orderedRollback = b.orderedRollback
+			pt.inner.serial = b.serial
+		}
+	}
+	s := &Serial{inner: b.tasks}
+	applyMode(s, b.mode)
+	s.modeFixed = b.modeFixed
+	return s
 }
 
 // Step appends a new StepDisplay task, which will print single line progress for inner tasks.
@@ -403,3 +589,13 @@ func (b *Builder) BuildAsStep(prefix string) *StepDisplay {
 	inner := b.Build()
 	return newStepDisplay(prefix, inner)
 }
+
+// BuildAsStepMessage is like BuildAsStep, the step displays the text of the
+// message as an item of a list and carries the message for consumers to
+// localize
+func (b *Builder) BuildAsStepMessage(id MessageID, params MessageParams) *StepDisplay {
+	msg := NewMessage(id, params)
+	s := b.BuildAsStep("  - " + msg.Text)
+	s.message = &msg
+	return s
+}
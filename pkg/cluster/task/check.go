@@ -19,9 +19,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/utils"
 )
 
 // the check types
@@ -34,13 +36,22 @@ var (
 	CheckTypePackage      = "package"
 	CheckTypePartitions   = "partitions"
 	CheckTypeFIO          = "fio"
+	CheckTypeDiskBench    = "disk-bench"
+	CheckTypeOSSettings   = "os-settings"
 )
 
-// place the check utilities are stored
+// place the check utilities are stored if the remote tmp dir is not set,
+// see CheckToolsDir
 const (
 	CheckToolsPathDir = "/tmp/tiup"
 )
 
+// CheckToolsDir returns the place the check utilities are stored under the
+// remote tmp dir of a host
+func CheckToolsDir(tmpDir string) string {
+	return filepath.Join(tmpDir, "tiup")
+}
+
 // CheckSys performs checks of system information
 type CheckSys struct {
 	host    string
@@ -54,6 +65,9 @@ type CheckSys struct {
 func (c *CheckSys) Execute(ctx *Context) error {
 	stdout, stderr, _ := ctx.GetOutputs(c.host)
 	if len(stderr) > 0 && len(stdout) == 0 {
+		if _, stderrFile := ctx.GetOutputFiles(c.host); stderrFile != "" {
+			return errors.Annotatef(ErrNoOutput, "the full stderr of %s is saved in %s", c.host, stderrFile)
+		}
 		return ErrNoOutput
 	}
 
@@ -75,6 +89,12 @@ func (c *CheckSys) Execute(ctx *Context) error {
 		ctx.SetCheckResults(c.host, results)
 	case CheckTypePort:
 		ctx.SetCheckResults(c.host, operator.CheckListeningPort(c.opt, c.host, c.topo, stdout))
+	case CheckTypeOSSettings:
+		e, ok := ctx.GetExecutor(c.host)
+		if !ok {
+			return ErrNoExecutor
+		}
+		ctx.SetCheckResults(c.host, operator.CheckOSSettings(e, c.topo.OSSettings))
 	case CheckTypeService:
 		e, ok := ctx.GetExecutor(c.host)
 		if !ok {
@@ -126,6 +146,12 @@ func (c *CheckSys) Execute(ctx *Context) error {
 		}
 
 		ctx.SetCheckResults(c.host, operator.CheckFIOResult(rr, rw, lat))
+	case CheckTypeDiskBench:
+		if !c.opt.EnableDiskBench || c.dataDir == "" {
+			break
+		}
+
+		ctx.SetCheckResults(c.host, c.runDiskBench(ctx))
 	}
 
 	return nil
@@ -141,6 +167,11 @@ func (c *CheckSys) String() string {
 	return fmt.Sprintf("CheckSys: host=%s type=%s", c.host, c.check)
 }
 
+// Hosts implements the HostTask interface
+func (c *CheckSys) Hosts() []string {
+	return []string{c.host}
+}
+
 // runFIO performs FIO checks
 func (c *CheckSys) runFIO(ctx *Context) (outRR []byte, outRW []byte, outLat []byte, err error) {
 	e, ok := ctx.GetExecutor(c.host)
@@ -151,7 +182,7 @@ func (c *CheckSys) runFIO(ctx *Context) (outRR []byte, outRW []byte, outLat []by
 
 	dataDir := clusterutil.Abs(c.topo.GlobalOptions.User, c.dataDir)
 	testWd := filepath.Join(dataDir, "tiup-fio-test")
-	fioBin := filepath.Join(CheckToolsPathDir, "bin", "fio")
+	fioBin := filepath.Join(CheckToolsDir(ctx.RemoteTmpDir(c.host)), "bin", "fio")
 
 	var stderr []byte
 
@@ -161,7 +192,7 @@ func (c *CheckSys) runFIO(ctx *Context) (outRR []byte, outRW []byte, outLat []by
 		resRR  = "fio_randread_result.json"
 	)
 	cmdRR := strings.Join([]string{
-		fmt.Sprintf("mkdir -p %s && cd %s", testWd, testWd),
+		fmt.Sprintf("mkdir -p %[1]s && cd %[1]s", utils.ShellQuote(testWd)),
 		fmt.Sprintf("rm -f %s %s", fileRR, resRR), // cleanup any legancy files
 		strings.Join([]string{
 			fioBin,
@@ -199,7 +230,7 @@ func (c *CheckSys) runFIO(ctx *Context) (outRR []byte, outRW []byte, outLat []by
 		resRW  = "fio_randread_write_test.json"
 	)
 	cmdRW := strings.Join([]string{
-		fmt.Sprintf("mkdir -p %s && cd %s", testWd, testWd),
+		fmt.Sprintf("mkdir -p %[1]s && cd %[1]s", utils.ShellQuote(testWd)),
 		fmt.Sprintf("rm -f %s %s", fileRW, resRW), // cleanup any legancy files
 		strings.Join([]string{
 			fioBin,
@@ -238,7 +269,7 @@ func (c *CheckSys) runFIO(ctx *Context) (outRR []byte, outRW []byte, outLat []by
 		resLat  = "fio_randread_write_latency_test.json"
 	)
 	cmdLat := strings.Join([]string{
-		fmt.Sprintf("mkdir -p %s && cd %s", testWd, testWd),
+		fmt.Sprintf("mkdir -p %[1]s && cd %[1]s", utils.ShellQuote(testWd)),
 		fmt.Sprintf("rm -f %s %s", fileLat, resLat), // cleanup any legancy files
 		strings.Join([]string{
 			fioBin,
@@ -285,3 +316,76 @@ func (c *CheckSys) runFIO(ctx *Context) (outRR []byte, outRW []byte, outLat []by
 
 	return
 }
+
+// the size of data written by the quick disk benchmark, the size of the
+// sequential write is in MiB as the 1M blocks of dd are
+const (
+	diskBenchSeqWriteMiB = 256
+	diskBenchSyncCount   = 1000
+)
+
+// runDiskBench performs a quick benchmark of the data dir with dd, it measures
+// the sequential write throughput and the average latency of fsync'ed writes
+func (c *CheckSys) runDiskBench(ctx *Context) []*operator.CheckResult {
+	e, ok := ctx.GetExecutor(c.host)
+	if !ok {
+		return []*operator.CheckResult{{
+			Name: operator.CheckNameDiskBench,
+			Err:  ErrNoExecutor,
+		}}
+	}
+
+	dataDir := clusterutil.Abs(c.topo.GlobalOptions.User, c.dataDir)
+
+	// never write test files to a directory that already contains data
+	stdout, stderr, err := e.Execute(
+		fmt.Sprintf(`if [ -d %[1]s ] && [ -n "$(ls -A %[1]s)" ]; then echo notempty; fi`, utils.ShellQuote(dataDir)),
+		false,
+	)
+	if err != nil {
+		return []*operator.CheckResult{{
+			Name: operator.CheckNameDiskBench,
+			Err:  fmt.Errorf("failed to inspect %s, %s", dataDir, strings.TrimSpace(string(stderr))),
+		}}
+	}
+	if strings.TrimSpace(string(stdout)) == "notempty" {
+		return []*operator.CheckResult{{
+			Name: operator.CheckNameDiskBench,
+			Err:  fmt.Errorf("data directory %s is not empty, refuse to run disk benchmark in it", dataDir),
+			Warn: true,
+		}}
+	}
+
+	// the trap makes sure the test files are removed even if the benchmark
+	// is interrupted (e.g., the SSH session is closed on timeout), the dir is
+	// in a variable so the trap needs no quoting of its own
+	testWd := filepath.Join(dataDir, "tiup-disk-bench")
+	bench := func(dd string) ([]byte, error) {
+		cmd := strings.Join([]string{
+			fmt.Sprintf(`wd=%s && mkdir -p "$wd" && trap 'rm -rf "$wd"' EXIT INT TERM HUP && cd "$wd"`, utils.ShellQuote(testWd)),
+			dd + " 2>&1",
+		}, " && ")
+		stdout, stderr, err := e.Execute(cmd, false, time.Second*300)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s", err, strings.TrimSpace(string(stderr)))
+		}
+		return stdout, nil
+	}
+
+	seqOut, err := bench(fmt.Sprintf("dd if=/dev/zero of=seq_write_test bs=1M count=%d oflag=direct", diskBenchSeqWriteMiB))
+	if err != nil {
+		return []*operator.CheckResult{{
+			Name: operator.CheckNameDiskBench,
+			Err:  fmt.Errorf("sequential write test of %s failed, %s", dataDir, err),
+		}}
+	}
+	syncOut, err := bench(fmt.Sprintf("dd if=/dev/zero of=fsync_test bs=4k count=%d oflag=dsync", diskBenchSyncCount))
+	if err != nil {
+		return []*operator.CheckResult{{
+			Name: operator.CheckNameDiskBench,
+			Err:  fmt.Errorf("fsync latency test of %s failed, %s", dataDir, err),
+		}}
+	}
+
+	return operator.CheckDiskBenchResult(c.opt, dataDir, seqOut, syncOut, diskBenchSyncCount)
+}
@@ -0,0 +1,171 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+)
+
+// PortListenerScript is the name of the listener script on remote hosts
+const PortListenerScript = "tiup-port-listener.py"
+
+// portListenerScript accepts and closes connections on the ports given in
+// arguments until the deadline, ports already in use are skipped as probes
+// would connect to the process listening on them anyway.
+// It works with both python 2 and 3.
+const portListenerScript = `import select, socket, sys, time
+socks = []
+for p in sys.argv[2:]:
+    s = socket.socket(socket.AF_INET, socket.SOCK_STREAM)
+    s.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+    try:
+        s.bind(("0.0.0.0", int(p)))
+        s.listen(128)
+        socks.append(s)
+    except socket.error:
+        s.close()
+deadline = time.time() + float(sys.argv[1])
+while socks and time.time() < deadline:
+    r, _, _ = select.select(socks, [], [], 1)
+    for s in r:
+        c, _ = s.accept()
+        c.close()
+`
+
+// PortListener starts short-lived TCP listeners on a host, so the connectivity
+// to it could be probed before any service is deployed
+type PortListener struct {
+	host     string
+	ports    []int
+	duration time.Duration
+}
+
+// Execute implements the Task interface
+func (l *PortListener) Execute(ctx *Context) error {
+	e, ok := ctx.GetExecutor(l.host)
+	if !ok {
+		return ErrNoExecutor
+	}
+
+	f, err := ioutil.TempFile("", "tiup-port-listener-")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(portListenerScript); err != nil {
+		f.Close()
+		return errors.Trace(err)
+	}
+	if err := f.Close(); err != nil {
+		return errors.Trace(err)
+	}
+
+	script := filepath.Join(CheckToolsDir(ctx.RemoteTmpDir(l.host)), PortListenerScript)
+	if err := e.Transfer(f.Name(), script, false); err != nil {
+		return errors.Annotatef(err, "failed to transfer port listener to %s", l.host)
+	}
+
+	ports := make([]string, 0, len(l.ports))
+	for _, p := range l.ports {
+		ports = append(ports, strconv.Itoa(p))
+	}
+	cmd := fmt.Sprintf(
+		"PY=$(command -v python3 || command -v python) && nohup $PY %s %d %s < /dev/null > /dev/null 2>&1 &",
+		script, int(math.Ceil(l.duration.Seconds())), strings.Join(ports, " "))
+	if _, stderr, err := e.Execute(cmd, false); err != nil {
+		return errors.Annotatef(err, "failed to start port listener on %s, python is required: %s", l.host, stderr)
+	}
+	// give the listener a moment to bind the ports
+	time.Sleep(time.Second)
+	return nil
+}
+
+// Rollback implements the Task interface
+func (l *PortListener) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (l *PortListener) String() string {
+	return fmt.Sprintf("PortListener: host=%s, ports=%v", l.host, l.ports)
+}
+
+// Hosts implements the HostTask interface
+func (l *PortListener) Hosts() []string {
+	return []string{l.host}
+}
+
+// CheckConnectivity probes the TCP reachability from a host to the ports
+// its components connect to
+type CheckConnectivity struct {
+	host   string
+	pairs  []operator.ConnectivityPair
+	opt    *operator.CheckOptions
+	matrix *operator.ConnectivityMatrix
+}
+
+// Execute implements the Task interface
+func (c *CheckConnectivity) Execute(ctx *Context) error {
+	if len(c.pairs) == 0 {
+		return nil
+	}
+	e, ok := ctx.GetExecutor(c.host)
+	if !ok {
+		return ErrNoExecutor
+	}
+
+	concurrency := c.opt.ConnectivityConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	timeout := int(math.Ceil(c.opt.ConnectivityTimeout.Seconds()))
+	if timeout < 1 {
+		timeout = 1
+	}
+	cmd := operator.ConnectivityProbeCommand(c.pairs, concurrency, timeout)
+	// probes in a batch may all time out, leave enough time for the whole command
+	batches := (len(c.pairs) + concurrency - 1) / concurrency
+	stdout, stderr, err := e.Execute(cmd, false, time.Duration(batches*timeout+10)*time.Second)
+	if err != nil {
+		return errors.Annotatef(err, "failed to probe connectivity from %s: %s", c.host, stderr)
+	}
+
+	ctx.SetCheckResults(c.host, c.matrix.ParseProbeOutput(c.pairs, stdout))
+	return nil
+}
+
+// Rollback implements the Task interface
+func (c *CheckConnectivity) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckConnectivity) String() string {
+	return fmt.Sprintf("CheckConnectivity: host=%s, targets=%d", c.host, len(c.pairs))
+}
+
+// Hosts implements the HostTask interface
+func (c *CheckConnectivity) Hosts() []string {
+	return []string{c.host}
+}
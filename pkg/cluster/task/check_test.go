@@ -0,0 +1,80 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+)
+
+type checkSysSuite struct{}
+
+var _ = check.Suite(&checkSysSuite{})
+
+func (s *checkSysSuite) TestDiskBench(c *check.C) {
+	topo := &spec.Specification{GlobalOptions: spec.GlobalOptions{User: "tidb"}}
+	opt := &operator.CheckOptions{EnableDiskBench: true, DiskBenchMinSeqWrite: 100, DiskBenchMaxFsyncLat: 10 * time.Millisecond}
+	bench := `wd='/data/tikv/tiup-disk-bench' && mkdir -p "$wd" && trap 'rm -rf "$wd"' EXIT INT TERM HUP && cd "$wd" && `
+	run := func(e executor.Executor) []*operator.CheckResult {
+		ctx := NewContext()
+		ctx.SetExecutor("10.0.1.1", e)
+		c.Assert(NewBuilder().CheckSys("10.0.1.1", "/data/tikv", CheckTypeDiskBench, topo, opt).Build().Execute(ctx), check.IsNil)
+		results, _ := ctx.GetCheckResults("10.0.1.1")
+		return results
+	}
+
+	// 256MiB are written sequentially, and 1000 4k blocks are fsync'ed
+	e := executor.NewFake("10.0.1.1")
+	e.Respond(bench+"dd if=/dev/zero of=seq_write_test bs=1M count=256 oflag=direct", "268435456 bytes (268 MB, 256 MiB) copied, 2 s, 134 MB/s\n", "")
+	e.Respond(bench+"dd if=/dev/zero of=fsync_test bs=4k count=1000 oflag=dsync", "4096000 bytes (4.1 MB, 3.9 MiB) copied, 20 s, 205 kB/s\n", "")
+	results := run(e)
+	c.Assert(results, check.HasLen, 2)
+	c.Assert(results[0].Msg, check.Equals, "sequential write of /data/tikv: 134.22MB/s")
+	c.Assert(results[0].Err, check.IsNil)
+	c.Assert(results[1].Err, check.ErrorMatches, "fsync latency of /data/tikv is 20ms, higher than 10ms")
+	cmds := e.Commands()
+	c.Assert(cmds, check.HasLen, 3)
+	c.Assert(strings.HasPrefix(cmds[0], "if [ -d '/data/tikv' ]"), check.IsTrue)
+
+	// the data directories not empty are never written
+	e = executor.NewFake("10.0.1.1")
+	e.Respond("if [ -d '/data/tikv' ]", "notempty\n", "")
+	results = run(e)
+	c.Assert(results, check.HasLen, 1)
+	c.Assert(results[0].Warn, check.IsTrue)
+	c.Assert(results[0].Err, check.ErrorMatches, "data directory /data/tikv is not empty.*")
+	c.Assert(e.Commands(), check.HasLen, 1)
+
+	// the failures of dd are reported
+	e = executor.NewFake("10.0.1.1")
+	e.Respond(bench+"dd", "", "No space left on device")
+	results = run(e)
+	c.Assert(results, check.HasLen, 1)
+	c.Assert(results[0].Err, check.ErrorMatches, "sequential write test of /data/tikv failed.*No space left on device")
+
+	// the data directories are quoted in the commands
+	e = executor.NewFake("10.0.1.1")
+	e.Respond("if [ -d '/data/it'\"'\"'s tikv' ]", "notempty\n", "")
+	ctx := NewContext()
+	ctx.SetExecutor("10.0.1.1", e)
+	c.Assert(NewBuilder().CheckSys("10.0.1.1", "/data/it's tikv", CheckTypeDiskBench, topo, opt).Build().Execute(ctx), check.IsNil)
+	results, _ = ctx.GetCheckResults("10.0.1.1")
+	c.Assert(results, check.HasLen, 1)
+	c.Assert(results[0].Warn, check.IsTrue)
+}
@@ -44,7 +44,7 @@ func (ctx *Context) SetClusterSSH(topo spec.Topology, deployUser string, sshTime
 				Timeout: time.Second * time.Duration(sshTimeout),
 			}
 
-			e := executor.NewSSHExecutor(cf, false /* sudo */, nativeClient)
+			e := ctx.newExecutor(cf, false /* sudo */, nativeClient)
 			ctx.SetExecutor(in.GetHost(), e)
 		}
 	}
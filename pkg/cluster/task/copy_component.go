@@ -29,6 +29,8 @@ type CopyComponent struct {
 	host      string
 	srcPath   string
 	dstDir    string
+	cache     *PackageCache
+	transfer  TransferOptions
 }
 
 // PackagePath return the tar bar path
@@ -46,12 +48,14 @@ func (c *CopyComponent) Execute(ctx *Context) error {
 	}
 
 	install := &InstallPackage{
-		srcPath: srcPath,
-		host:    c.host,
-		dstDir:  c.dstDir,
+		srcPath:  srcPath,
+		host:     c.host,
+		dstDir:   c.dstDir,
+		cache:    c.cache,
+		transfer: c.transfer,
 	}
 
-	return install.Execute(ctx)
+	return install.execute(ctx, c)
 }
 
 // Rollback implements the Task interface
@@ -59,8 +63,18 @@ func (c *CopyComponent) Rollback(ctx *Context) error {
 	return ErrUnsupportedRollback
 }
 
+// Message implements the Messager interface
+func (c *CopyComponent) Message() Message {
+	return NewMessage(MsgCopy, MessageParams{"component": c.component, "host": c.host})
+}
+
 // String implements the fmt.Stringer interface
 func (c *CopyComponent) String() string {
 	return fmt.Sprintf("CopyComponent: component=%s, version=%s, remote=%s:%s os=%s, arch=%s",
 		c.component, c.version, c.host, c.dstDir, c.os, c.arch)
 }
+
+// Hosts implements the HostTask interface
+func (c *CopyComponent) Hosts() []string {
+	return []string{c.host}
+}
@@ -25,6 +25,7 @@ type CopyFile struct {
 	dst      string
 	remote   string
 	download bool
+	transfer TransferOptions // only for uploading
 }
 
 // Execute implements the Task interface
@@ -34,7 +35,14 @@ func (c *CopyFile) Execute(ctx *Context) error {
 		return ErrNoExecutor
 	}
 
-	err := e.Transfer(c.src, c.dst, c.download)
+	var err error
+	if c.download {
+		ctx.SetPhase(c, PhaseCopying, fmt.Sprintf("from %s", c.remote))
+		err = e.Transfer(c.src, c.dst, true)
+	} else {
+		ctx.SetPhase(c, PhaseCopying, fmt.Sprintf("to %s", c.remote))
+		err = transferFile(e, c.remote, c.src, c.dst, c.transfer)
+	}
 	if err != nil {
 		return errors.Annotate(err, "failed to transfer file")
 	}
@@ -47,6 +55,15 @@ func (c *CopyFile) Rollback(ctx *Context) error {
 	return ErrUnsupportedRollback
 }
 
+// Message implements the Messager interface
+func (c *CopyFile) Message() Message {
+	id := MsgCopyFile
+	if c.download {
+		id = MsgFetchFile
+	}
+	return NewMessage(id, MessageParams{"src": c.src, "dst": c.dst, "host": c.remote})
+}
+
 // String implements the fmt.Stringer interface
 func (c *CopyFile) String() string {
 	if c.download {
@@ -54,3 +71,8 @@ func (c *CopyFile) String() string {
 	}
 	return fmt.Sprintf("CopyFile: local=%s, remote=%s:%s", c.src, c.remote, c.dst)
 }
+
+// Hosts implements the HostTask interface
+func (c *CopyFile) Hosts() []string {
+	return []string{c.remote}
+}
@@ -21,11 +21,15 @@ import (
 
 // Downloader is used to download the specific version of a component from
 // the repository, there is nothing to do if the specified version exists.
+// The package is imported from the package directory instead if it's set.
 type Downloader struct {
 	component string
 	os        string
 	arch      string
 	version   string
+	dir       string
+
+	taskProgress // the percentage of the package downloaded
 }
 
 // NewDownloader create a Downloader instance.
@@ -39,8 +43,11 @@ func NewDownloader(component string, os string, arch string, version string) *Do
 }
 
 // Execute implements the Task interface
-func (d *Downloader) Execute(_ *Context) error {
-	return operator.Download(d.component, d.os, d.arch, d.version)
+func (d *Downloader) Execute(ctx *Context) error {
+	if d.dir != "" {
+		return operator.ImportPackage(d.dir, d.component, d.os, d.arch, d.version)
+	}
+	return operator.DownloadWithProgress(d.component, d.os, d.arch, d.version, &downloadProgress{ctx: ctx, t: d})
 }
 
 // Rollback implements the Task interface
@@ -49,8 +56,24 @@ func (d *Downloader) Rollback(ctx *Context) error {
 	return nil
 }
 
+// Message implements the Messager interface
+func (d *Downloader) Message() Message {
+	if d.dir != "" {
+		return NewMessage(MsgImportPackage, MessageParams{
+			"component": d.component, "version": d.version, "os": d.os, "arch": d.arch, "dir": d.dir,
+		})
+	}
+	return NewMessage(MsgDownload, MessageParams{
+		"component": d.component, "version": d.version, "os": d.os, "arch": d.arch,
+	})
+}
+
 // String implements the fmt.Stringer interface
 func (d *Downloader) String() string {
+	if d.dir != "" {
+		return fmt.Sprintf("Import: component=%s, version=%s, os=%s, arch=%s, dir=%s",
+			d.component, d.version, d.os, d.arch, d.dir)
+	}
 	return fmt.Sprintf("Download: component=%s, version=%s, os=%s, arch=%s",
 		d.component, d.version, d.os, d.arch)
 }
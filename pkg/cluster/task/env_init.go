@@ -19,6 +19,7 @@ import (
 	"strings"
 
 	"github.com/joomcode/errorx"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
 	"github.com/pingcap/tiup/pkg/cluster/module"
 )
 
@@ -83,13 +84,28 @@ func (e *EnvInit) execute(ctx *Context) error {
 			Wrap(err, "Failed to create '~/.ssh' directory for user '%s'", e.deployUser))
 	}
 
-	// detect if custom path of authorized keys file is set
-	// NOTE: we do not yet support:
-	//   - custom config for user (~/.ssh/config)
-	//   - sshd started with custom config (other than /etc/ssh/sshd_config)
-	//   - ssh server implementations other than OpenSSH (such as dropbear)
+	sshAuthorizedKeys := findSSHAuthorizedKeysFile(exec)
+
+	pk := strings.TrimSpace(string(pubKey))
+	cmd = fmt.Sprintf(`su - %[1]s -c 'grep $(echo %[2]s) %[3]s || echo %[2]s >> %[3]s && chmod 600 %[3]s'`,
+		e.deployUser, pk, sshAuthorizedKeys)
+	_, _, err = exec.Execute(cmd, true)
+	if err != nil {
+		return wrapError(errEnvInitSubCommandFailed.
+			Wrap(err, "Failed to write public keys to '%s' for user '%s'", sshAuthorizedKeys, e.deployUser))
+	}
+
+	return nil
+}
+
+// findSSHAuthorizedKeysFile detects if custom path of authorized keys file is set
+// NOTE: we do not yet support:
+//   - custom config for user (~/.ssh/config)
+//   - sshd started with custom config (other than /etc/ssh/sshd_config)
+//   - ssh server implementations other than OpenSSH (such as dropbear)
+func findSSHAuthorizedKeysFile(exec executor.Executor) string {
 	sshAuthorizedKeys := defaultSSHAuthorizedKeys
-	cmd = "grep -Ev '^\\s*#|^\\s*$' /etc/ssh/sshd_config"
+	cmd := "grep -Ev '^\\s*#|^\\s*$' /etc/ssh/sshd_config"
 	stdout, _, _ := exec.Execute(cmd, true) // error ignored as we have default value
 	for _, line := range strings.Split(string(stdout), "\n") {
 		if !strings.Contains(line, "AuthorizedKeysFile") {
@@ -104,17 +120,7 @@ func (e *EnvInit) execute(ctx *Context) error {
 	if !strings.HasPrefix(sshAuthorizedKeys, "/") && !strings.HasPrefix(sshAuthorizedKeys, "~") {
 		sshAuthorizedKeys = fmt.Sprintf("~/%s", sshAuthorizedKeys)
 	}
-
-	pk := strings.TrimSpace(string(pubKey))
-	cmd = fmt.Sprintf(`su - %[1]s -c 'grep $(echo %[2]s) %[3]s || echo %[2]s >> %[3]s && chmod 600 %[3]s'`,
-		e.deployUser, pk, sshAuthorizedKeys)
-	_, _, err = exec.Execute(cmd, true)
-	if err != nil {
-		return wrapError(errEnvInitSubCommandFailed.
-			Wrap(err, "Failed to write public keys to '%s' for user '%s'", sshAuthorizedKeys, e.deployUser))
-	}
-
-	return nil
+	return sshAuthorizedKeys
 }
 
 // Rollback implements the Task interface
@@ -126,3 +132,8 @@ func (e *EnvInit) Rollback(ctx *Context) error {
 func (e *EnvInit) String() string {
 	return fmt.Sprintf("EnvInit: user=%s, host=%s", e.deployUser, e.host)
 }
+
+// Hosts implements the HostTask interface
+func (e *EnvInit) Hosts() []string {
+	return []string{e.host}
+}
@@ -0,0 +1,231 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"sort"
+	"sync"
+	"time"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// ErrorModeOf returns the mode handling the failures of tasks in the scope,
+// see operator.ErrorScope
+func ErrorModeOf(scope operator.ErrorScope) ErrorMode {
+	switch scope {
+	case operator.ErrorScopeStep:
+		return ContinueCollectingErrors
+	case operator.ErrorScopeHost:
+		return SkipFailedHosts
+	default:
+		return StopOnError
+	}
+}
+
+// applyMode sets the mode of t and the Serial and Parallel tasks nested in
+// it, so the mode of the outermost task applies to the whole tree, except the
+// subtrees built with their own modes. A StepDisplay is a single step, the
+// tasks inside it always stop at the first failure, which fails the step.
+func applyMode(t Task, mode ErrorMode) {
+	switch tt := t.(type) {
+	case *Serial:
+		if tt.modeFixed {
+			return
+		}
+		tt.mode = mode
+		for _, inner := range tt.inner {
+			applyMode(inner, mode)
+		}
+	case *Parallel:
+		tt.mode = mode
+		for _, inner := range tt.inner {
+			applyMode(inner, mode)
+		}
+	case *ParallelStepDisplay:
+		applyMode(tt.inner, mode)
+	}
+}
+
+// isGroup checks if t groups other steps, rather than being a step itself
+func isGroup(t Task) bool {
+	switch t.(type) {
+	case *Serial, *Parallel, *ParallelStepDisplay:
+		return true
+	}
+	return false
+}
+
+// stepFailureJSON is the JSON form of StepFailure, the error is kept as text
+type stepFailureJSON struct {
+	Step    string `json:"step"`
+	Error   string `json:"error"`
+	Skipped bool   `json:"skipped,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (f StepFailure) MarshalJSON() ([]byte, error) {
+	v := stepFailureJSON{Step: f.Step, Skipped: f.Skipped}
+	if f.Err != nil {
+		v.Error = f.Err.Error()
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (f *StepFailure) UnmarshalJSON(data []byte) error {
+	var v stepFailureJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*f = StepFailure{Step: v.Step, Err: stderrors.New(v.Error), Skipped: v.Skipped}
+	return nil
+}
+
+// stepFailures are the failures of the steps executed with a context
+type stepFailures struct {
+	sync.Mutex
+	list []StepFailure
+}
+
+// Failures returns the failures of all the steps executed with the context in
+// the order they failed, including the ones suppressed by the error mode and
+// the ones not returned as they happened after the first failure
+func (ctx *Context) Failures() []StepFailure {
+	ctx.failures.Lock()
+	defer ctx.failures.Unlock()
+	return append([]StepFailure{}, ctx.failures.list...)
+}
+
+// recordFailure records the failure of t executed, the failures in groups
+// are recorded by the groups with their steps instead
+func (ctx *Context) recordFailure(t Task, err error) {
+	if sd, ok := t.(*StepDisplay); isGroup(t) || (ok && isGroup(sd.inner)) {
+		return
+	}
+	ctx.recordStep(t, err)
+}
+
+// recordStep records the failure of the step t
+func (ctx *Context) recordStep(t Task, err error) {
+	_, skipped := IsHostDegraded(err)
+	ctx.failures.Lock()
+	ctx.failures.list = append(ctx.failures.list, StepFailure{Step: stepName(t), Err: err, Skipped: skipped})
+	ctx.failures.Unlock()
+}
+
+// handleFailure handles the failure of t, an inner task of a Serial or a
+// Parallel in the mode. The failure is recorded in degraded and true is
+// returned if the rest tasks should keep executing.
+func (ctx *Context) handleFailure(mode ErrorMode, t Task, err error, degraded *DegradedError) bool {
+	if _, ok := err.(*DegradedError); ok {
+		// the failures of the steps in the group are already handled
+		degraded.add(t, err)
+		return mode != StopOnError
+	}
+	if isGroup(t) {
+		// the group failed as a whole, e.g., the deadline is exceeded
+		return false
+	}
+
+	switch mode {
+	case ContinueCollectingErrors:
+	case SkipFailedHosts:
+		if _, skipped := IsHostDegraded(err); !skipped {
+			hosts := hostsOf(t)
+			if len(hosts) == 0 {
+				return false
+			}
+			ctx.failHosts(hosts, stepName(t), err)
+		}
+	default:
+		return false
+	}
+	degraded.add(t, err)
+	return true
+}
+
+// skipFailedHost returns ErrHostDegraded if t is a step on a host degraded
+// and the mode is SkipFailedHosts, t is skipped without executing then, and
+// the skip is recorded as a failure
+func (ctx *Context) skipFailedHost(mode ErrorMode, t Task) error {
+	if mode != SkipFailedHosts || isGroup(t) {
+		return nil
+	}
+	for _, host := range hostsOf(t) {
+		if err := ctx.checkHost(host); err != nil {
+			ctx.recordStep(t, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// HostTask is a task working on some hosts, its failure is confined to them
+// in the SkipFailedHosts mode
+type HostTask interface {
+	Hosts() []string
+}
+
+// hostsOf returns the hosts the step t is on, which are the hosts of the
+// tasks inside it if it wraps other tasks
+func hostsOf(t Task) []string {
+	hosts := set.NewStringSet()
+	var collect func(t Task)
+	collect = func(t Task) {
+		switch tt := t.(type) {
+		case HostTask:
+			for _, host := range tt.Hosts() {
+				hosts.Insert(host)
+			}
+		case *StepDisplay:
+			collect(tt.inner)
+		case *ParallelStepDisplay:
+			collect(tt.inner)
+		case *Serial:
+			for _, inner := range tt.inner {
+				collect(inner)
+			}
+		case *Parallel:
+			for _, inner := range tt.inner {
+				collect(inner)
+			}
+		}
+	}
+	collect(t)
+	result := hosts.Slice()
+	sort.Strings(result)
+	return result
+}
+
+// failHosts marks the hosts of the failed step as degraded, the rest steps
+// on them are skipped in the SkipFailedHosts mode
+func (ctx *Context) failHosts(hosts []string, step string, err error) {
+	ctx.hosts.Lock()
+	defer ctx.hosts.Unlock()
+	for _, host := range hosts {
+		if _, ok := ctx.hosts.degraded[host]; ok {
+			continue
+		}
+		log.Warnf("Step `%s` failed on host %s, the rest steps on it are skipped", step, host)
+		ctx.hosts.degraded[host] = &HostDegradation{Host: host, Cause: err.Error(), Since: time.Now()}
+		if ctx.hosts.firstSteps[host] == "" {
+			ctx.hosts.firstSteps[host] = step
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/check"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+)
+
+type errorModeSuite struct{}
+
+var _ = check.Suite(&errorModeSuite{})
+
+func (s *errorModeSuite) TestErrorScopes(c *check.C) {
+	var mu sync.Mutex
+	var executed []string
+	// the step named "A@10.0.0.1" is on the host 10.0.0.1
+	step := func(name string, fail bool) Task {
+		var opts []FuncOption
+		if idx := strings.Index(name, "@"); idx >= 0 {
+			opts = append(opts, OnHosts(name[idx+1:]))
+		}
+		return NewFunc(name, func(ctx *Context) error {
+			mu.Lock()
+			executed = append(executed, name)
+			mu.Unlock()
+			if fail {
+				return errors.New("failed")
+			}
+			return nil
+		}, opts...)
+	}
+
+	cases := []struct {
+		name  string
+		build func(b *Builder) *Builder
+		// the steps executed, failed, and skipped in each scope
+		executed map[operator.ErrorScope][]string
+		failed   map[operator.ErrorScope][]string
+		skipped  map[operator.ErrorScope][]string
+	}{
+		{
+			name: "serial",
+			build: func(b *Builder) *Builder {
+				return b.Serial(step("A@10.0.0.1", true), step("B@10.0.0.1", false), step("C@10.0.0.2", false))
+			},
+			executed: map[operator.ErrorScope][]string{
+				operator.ErrorScopeOperation: {"A@10.0.0.1"},
+				operator.ErrorScopeHost:      {"A@10.0.0.1", "C@10.0.0.2"},
+				operator.ErrorScopeStep:      {"A@10.0.0.1", "B@10.0.0.1", "C@10.0.0.2"},
+			},
+			failed: map[operator.ErrorScope][]string{
+				operator.ErrorScopeOperation: {"A@10.0.0.1"},
+				operator.ErrorScopeHost:      {"A@10.0.0.1"},
+				operator.ErrorScopeStep:      {"A@10.0.0.1"},
+			},
+			skipped: map[operator.ErrorScope][]string{
+				operator.ErrorScopeHost: {"B@10.0.0.1"},
+			},
+		},
+		{
+			name: "sibling parallels",
+			build: func(b *Builder) *Builder {
+				return b.Parallel(step("A@10.0.0.1", true), step("B@10.0.0.2", false)).
+					Parallel(step("C@10.0.0.1", false), step("D@10.0.0.2", false))
+			},
+			executed: map[operator.ErrorScope][]string{
+				operator.ErrorScopeOperation: {"A@10.0.0.1", "B@10.0.0.2"},
+				operator.ErrorScopeHost:      {"A@10.0.0.1", "B@10.0.0.2", "D@10.0.0.2"},
+				operator.ErrorScopeStep:      {"A@10.0.0.1", "B@10.0.0.2", "C@10.0.0.1", "D@10.0.0.2"},
+			},
+			failed: map[operator.ErrorScope][]string{
+				operator.ErrorScopeOperation: {"A@10.0.0.1"},
+				operator.ErrorScopeHost:      {"A@10.0.0.1"},
+				operator.ErrorScopeStep:      {"A@10.0.0.1"},
+			},
+			skipped: map[operator.ErrorScope][]string{
+				operator.ErrorScopeHost: {"C@10.0.0.1"},
+			},
+		},
+		{
+			// the mode of the outermost task applies to the nested ones
+			// built in the default mode
+			name: "nested built tasks",
+			build: func(b *Builder) *Builder {
+				inner := NewBuilder().
+					Parallel(step("A@10.0.0.1", true), step("B@10.0.0.2", false)).
+					Serial(NewBuilder().Serial(step("C@10.0.0.1", false)).BuildAsStep("C")).
+					Build()
+				return b.Serial(inner).ParallelStep("+ D",
+					NewBuilder().Serial(step("D@10.0.0.1", false)).BuildAsStep("  - D@10.0.0.1"),
+					NewBuilder().Serial(step("D@10.0.0.2", false)).BuildAsStep("  - D@10.0.0.2"))
+			},
+			executed: map[operator.ErrorScope][]string{
+				operator.ErrorScopeOperation: {"A@10.0.0.1", "B@10.0.0.2"},
+				operator.ErrorScopeHost:      {"A@10.0.0.1", "B@10.0.0.2", "D@10.0.0.2"},
+				operator.ErrorScopeStep:      {"A@10.0.0.1", "B@10.0.0.2", "C@10.0.0.1", "D@10.0.0.1", "D@10.0.0.2"},
+			},
+			failed: map[operator.ErrorScope][]string{
+				operator.ErrorScopeOperation: {"A@10.0.0.1"},
+				operator.ErrorScopeHost:      {"A@10.0.0.1"},
+				operator.ErrorScopeStep:      {"A@10.0.0.1"},
+			},
+			skipped: map[operator.ErrorScope][]string{
+				operator.ErrorScopeHost: {"C", "D@10.0.0.1"},
+			},
+		},
+		{
+			// a failure not on any host fails the operation in the host scope
+			name: "failure without host",
+			build: func(b *Builder) *Builder {
+				return b.Serial(step("A", true), step("B@10.0.0.2", false))
+			},
+			executed: map[operator.ErrorScope][]string{
+				operator.ErrorScopeOperation: {"A"},
+				operator.ErrorScopeHost:      {"A"},
+				operator.ErrorScopeStep:      {"A", "B@10.0.0.2"},
+			},
+			failed: map[operator.ErrorScope][]string{
+				operator.ErrorScopeOperation: {"A"},
+				operator.ErrorScopeHost:      {"A"},
+				operator.ErrorScopeStep:      {"A"},
+			},
+		},
+		{
+			// the failures of siblings are recorded even if they're not returned
+			name: "parallel failures",
+			build: func(b *Builder) *Builder {
+				return b.Parallel(step("A@10.0.0.1", true), step("B@10.0.0.2", true)).
+					Serial(step("C@10.0.0.2", false))
+			},
+			executed: map[operator.ErrorScope][]string{
+				operator.ErrorScopeOperation: {"A@10.0.0.1", "B@10.0.0.2"},
+				operator.ErrorScopeHost:      {"A@10.0.0.1", "B@10.0.0.2"},
+				operator.ErrorScopeStep:      {"A@10.0.0.1", "B@10.0.0.2", "C@10.0.0.2"},
+			},
+			failed: map[operator.ErrorScope][]string{
+				operator.ErrorScopeOperation: {"A@10.0.0.1", "B@10.0.0.2"},
+				operator.ErrorScopeHost:      {"A@10.0.0.1", "B@10.0.0.2"},
+				operator.ErrorScopeStep:      {"A@10.0.0.1", "B@10.0.0.2"},
+			},
+			skipped: map[operator.ErrorScope][]string{
+				operator.ErrorScopeHost: {"C@10.0.0.2"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		for _, scope := range operator.ErrorScopes {
+			comment := check.Commentf("%s in scope %s", tc.name, scope)
+			executed = nil
+			ctx := NewContext()
+			ctx.SetExecutor("10.0.0.1", &shellExecutor{})
+			ctx.SetExecutor("10.0.0.2", &shellExecutor{})
+			err := tc.build(NewBuilder()).Mode(ErrorModeOf(scope)).Build().Execute(ctx)
+			c.Assert(err, check.NotNil, comment)
+
+			sort.Strings(executed)
+			c.Assert(executed, check.DeepEquals, tc.executed[scope], comment)
+
+			var failed, skipped []string
+			for _, f := range ctx.Failures() {
+				if f.Skipped {
+					skipped = append(skipped, f.Step)
+				} else {
+					failed = append(failed, f.Step)
+				}
+			}
+			sort.Strings(failed)
+			sort.Strings(skipped)
+			c.Assert(failed, check.DeepEquals, tc.failed[scope], comment)
+			c.Assert(skipped, check.DeepEquals, tc.skipped[scope], comment)
+
+			// the operation is degraded rather than failed only if the
+			// failures are confined to the scope
+			degraded, ok := err.(*DegradedError)
+			confined := scope == operator.ErrorScopeStep ||
+				(scope == operator.ErrorScopeHost && tc.name != "failure without host")
+			c.Assert(ok, check.Equals, confined, comment)
+			if ok {
+				c.Assert(degraded.Failures, check.HasLen, len(failed)+len(skipped), comment)
+			}
+		}
+	}
+}
+
+func (s *errorModeSuite) TestFixedMode(c *check.C) {
+	var executed []string
+	step := func(name, host string, fail bool) Task {
+		return NewFunc(name, func(ctx *Context) error {
+			executed = append(executed, name)
+			if fail {
+				return errors.New("failed")
+			}
+			return nil
+		}, OnHosts(host))
+	}
+
+	// the hosts are prepared in the host scope, while the rest of the
+	// operation stops once any host fails
+	prepare := NewBuilder().Mode(SkipFailedHosts).
+		Serial(step("A", "10.0.0.1", true), step("B", "10.0.0.2", false)).
+		Build()
+	err := NewBuilder().Serial(prepare, step("C", "10.0.0.2", false)).Build().Execute(NewContext())
+	c.Assert(executed, check.DeepEquals, []string{"A", "B"})
+	_, ok := err.(*DegradedError)
+	c.Assert(ok, check.IsTrue)
+}
+
+func (s *errorModeSuite) TestHostsOf(c *check.C) {
+	noop := func(ctx *Context) error { return nil }
+
+	// the hosts are told by the tasks rather than their names
+	c.Assert(hostsOf(NewFunc("Start 10.0.0.1:20160", noop)), check.HasLen, 0)
+	c.Assert(hostsOf(NewFunc("Start", noop, OnHosts("10.0.0.1"))), check.DeepEquals, []string{"10.0.0.1"})
+	c.Assert(hostsOf(NewBuilder().Shell("10.0.0.2", "ls", false).Build()), check.DeepEquals, []string{"10.0.0.2"})
+
+	// a step is on the hosts of all the tasks inside it
+	t := NewBuilder().
+		Mkdir("tidb", "10.0.0.2", "/data").
+		Parallel(NewFunc("Start", noop, OnHosts("10.0.0.1")), NewFunc("Check", noop)).
+		BuildAsStep("+ Deploy")
+	c.Assert(hostsOf(t), check.DeepEquals, []string{"10.0.0.1", "10.0.0.2"})
+	c.Assert(hostsOf(NewBuilder().ParallelStep("+ Deploy", t).Build()), check.DeepEquals, []string{"10.0.0.1", "10.0.0.2"})
+}
+
+func (s *errorModeSuite) TestParallelConcurrency(c *check.C) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	var steps []Task
+	for i := 0; i < 6; i++ {
+		steps = append(steps, NewFunc("step", func(ctx *Context) error {
+			mu.Lock()
+			running++
+			if running > peak {
+				peak = running
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		}))
+	}
+
+	ctx := NewContext()
+	ctx.SetConcurrency(2)
+	c.Assert(NewBuilder().Parallel(steps...).Build().Execute(ctx), check.IsNil)
+	c.Assert(peak, check.Equals, 2)
+}
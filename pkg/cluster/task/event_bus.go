@@ -14,6 +14,9 @@
 package task
 
 import (
+	"reflect"
+	"sync"
+
 	ev "github.com/asaskevich/EventBus"
 	"go.uber.org/zap"
 )
@@ -21,6 +24,18 @@ import (
 // EventBus is an event bus for task events.
 type EventBus struct {
 	eventBus ev.Bus
+
+	// the adapters of the handlers of EventTaskFinish, by the handlers
+	finishHandlers *struct {
+		sync.Mutex
+		adapters map[uintptr]interface{}
+	}
+}
+
+// taskFinish carries the error of a finished task, as the underlying bus
+// can't pass nil arguments to the handlers
+type taskFinish struct {
+	err error
 }
 
 // EventKind is the task event kind.
@@ -33,25 +48,32 @@ const (
 	EventTaskFinish EventKind = "task_finish"
 	// EventTaskProgress is emitted when a task has made some progress.
 	EventTaskProgress EventKind = "task_progress"
+	// EventTaskPhase is emitted when a task enters a phase, or the detail of its phase changes.
+	EventTaskPhase EventKind = "task_phase"
 )
 
 // NewEventBus creates a new EventBus.
 func NewEventBus() EventBus {
 	return EventBus{
 		eventBus: ev.New(),
+		finishHandlers: &struct {
+			sync.Mutex
+			adapters map[uintptr]interface{}
+		}{adapters: make(map[uintptr]interface{})},
 	}
 }
 
-// PublishTaskBegin publishes a TaskBegin event. This should be called only by Parallel or Serial.
-func (ev *EventBus) PublishTaskBegin(task Task) {
-	zap.L().Debug("TaskBegin", zap.String("task", task.String()))
-	ev.eventBus.Publish(string(EventTaskBegin), task)
+// PublishTaskBegin publishes a TaskBegin event with the identity of the task.
+// This should be called only by Parallel or Serial.
+func (ev *EventBus) PublishTaskBegin(task Task, id string) {
+	zap.L().Debug("TaskBegin", zap.String("task", task.String()), zap.String("id", id))
+	ev.eventBus.Publish(string(EventTaskBegin), task, id)
 }
 
 // PublishTaskFinish publishes a TaskFinish event. This should be called only by Parallel or Serial.
 func (ev *EventBus) PublishTaskFinish(task Task, err error) {
 	zap.L().Debug("TaskFinish", zap.String("task", task.String()), zap.Error(err))
-	ev.eventBus.Publish(string(EventTaskFinish), task, err)
+	ev.eventBus.Publish(string(EventTaskFinish), task, taskFinish{err: err})
 }
 
 // PublishTaskProgress publishes a TaskProgress event.
@@ -60,8 +82,22 @@ func (ev *EventBus) PublishTaskProgress(task Task, progress string) {
 	ev.eventBus.Publish(string(EventTaskProgress), task, progress)
 }
 
-// Subscribe subscribes events.
+// PublishTaskPhase publishes a TaskPhase event.
+func (ev *EventBus) PublishTaskPhase(task Task, phase Phase, detail string) {
+	zap.L().Debug("TaskPhase", zap.String("task", task.String()), zap.String("phase", string(phase)), zap.String("detail", detail))
+	ev.eventBus.Publish(string(EventTaskPhase), task, phase, detail)
+}
+
+// Subscribe subscribes events, the handlers of EventTaskFinish are
+// func(task Task, err error).
 func (ev *EventBus) Subscribe(eventName EventKind, handler interface{}) {
+	if h, ok := handler.(func(Task, error)); ok && eventName == EventTaskFinish {
+		adapter := func(task Task, f taskFinish) { h(task, f.err) }
+		ev.finishHandlers.Lock()
+		ev.finishHandlers.adapters[reflect.ValueOf(handler).Pointer()] = adapter
+		ev.finishHandlers.Unlock()
+		handler = adapter
+	}
 	err := ev.eventBus.Subscribe(string(eventName), handler)
 	if err != nil {
 		panic(err)
@@ -70,6 +106,15 @@ func (ev *EventBus) Subscribe(eventName EventKind, handler interface{}) {
 
 // Unsubscribe unsubscribes events.
 func (ev *EventBus) Unsubscribe(eventName EventKind, handler interface{}) {
+	if eventName == EventTaskFinish {
+		key := reflect.ValueOf(handler).Pointer()
+		ev.finishHandlers.Lock()
+		if adapter, ok := ev.finishHandlers.adapters[key]; ok {
+			delete(ev.finishHandlers.adapters, key)
+			handler = adapter
+		}
+		ev.finishHandlers.Unlock()
+	}
 	err := ev.eventBus.Unsubscribe(string(eventName), handler)
 	if err != nil {
 		panic(err)
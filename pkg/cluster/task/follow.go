@@ -0,0 +1,209 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// followedSecretRegexps match the secrets in the commands and outputs
+// followed, e.g. "--password=xxx", "token: xxx" and "IDENTIFIED BY 'xxx'",
+// the secrets are the last groups
+var followedSecretRegexps = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)([\w.-]*(?:password|passwd|passphrase|secret|token)[\w.-]*["']?\s*[=:]\s*)("[^"]*"|'[^']*'|[^\s"',;&|]+)`),
+	regexp.MustCompile(`(?i)(identified by\s+)("[^"]*"|'[^']*')`),
+}
+
+// maskSecrets masks the secrets in the text followed, the same way the
+// secrets in the commands recorded in the history are masked
+func maskSecrets(text string) string {
+	for _, re := range followedSecretRegexps {
+		text = re.ReplaceAllString(text, "${1}******")
+	}
+	return text
+}
+
+// HostFollowers streams the commands executed on the followed hosts and
+// their outputs to writers, so a misbehaving host can be tailed without
+// raising the verbosity of the others. A command is written when it begins,
+// and its outputs when it finishes, as the executors don't stream them. The
+// followers are shared by the contexts of an operation, see SetHostFollowers.
+type HostFollowers struct {
+	mu        sync.Mutex
+	followers map[string][]*hostFollower
+	closed    bool
+}
+
+// hostFollower is a writer following a host, it's written by the executor
+// of the host only, so a slow writer doesn't delay the other hosts
+type hostFollower struct {
+	mu      sync.Mutex
+	w       io.Writer
+	stopped bool
+	done    chan struct{}
+}
+
+// NewHostFollowers creates a registry without any followers
+func NewHostFollowers() *HostFollowers {
+	return &HostFollowers{followers: make(map[string][]*hostFollower)}
+}
+
+// Follow streams the commands executed on the host to w until stop is
+// called or the followers are closed, done is closed then. Nothing is
+// written to w after stop returns.
+func (f *HostFollowers) Follow(host string, w io.Writer) (done <-chan struct{}, stop func()) {
+	fl := &hostFollower{w: w, done: make(chan struct{})}
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		close(fl.done)
+		return fl.done, func() {}
+	}
+	f.followers[host] = append(f.followers[host], fl)
+	f.mu.Unlock()
+
+	return fl.done, func() {
+		f.mu.Lock()
+		followers := f.followers[host]
+		for i, other := range followers {
+			if other == fl {
+				f.followers[host] = append(followers[:i:i], followers[i+1:]...)
+				break
+			}
+		}
+		if len(f.followers[host]) == 0 {
+			delete(f.followers, host)
+		}
+		f.mu.Unlock()
+		fl.stop()
+	}
+}
+
+// Following returns the hosts being followed in order
+func (f *HostFollowers) Following() []string {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	hosts := make([]string, 0, len(f.followers))
+	for host := range f.followers {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Close stops all the followers, the hosts can't be followed any more
+func (f *HostFollowers) Close() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	followers := f.followers
+	f.followers = make(map[string][]*hostFollower)
+	f.closed = true
+	f.mu.Unlock()
+	for _, fls := range followers {
+		for _, fl := range fls {
+			fl.stop()
+		}
+	}
+}
+
+func (fl *hostFollower) stop() {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	if !fl.stopped {
+		fl.stopped = true
+		close(fl.done)
+	}
+}
+
+// write writes the text to the followers of the host in a single Write
+func (f *HostFollowers) write(host, text string) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	followers := append([]*hostFollower{}, f.followers[host]...)
+	f.mu.Unlock()
+	for _, fl := range followers {
+		fl.mu.Lock()
+		if !fl.stopped {
+			// the host being followed shouldn't fail for its follower
+			_, _ = io.WriteString(fl.w, text)
+		}
+		fl.mu.Unlock()
+	}
+}
+
+// followed checks if the host has any followers
+func (f *HostFollowers) followed(host string) bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.followers[host]) > 0
+}
+
+// begin writes the command, or the transfer, beginning on the host
+func (f *HostFollowers) begin(host, what string) {
+	if !f.followed(host) {
+		return
+	}
+	f.write(host, fmt.Sprintf("[%s] %s\n", host, maskSecrets(what)))
+}
+
+// finish writes the outputs of the command finished on the host, the lines
+// of stderr are prefixed by "!"
+func (f *HostFollowers) finish(host string, stdout, stderr []byte, err error, elapsed time.Duration) {
+	if !f.followed(host) {
+		return
+	}
+	var b strings.Builder
+	for _, line := range outputLines(stdout) {
+		fmt.Fprintf(&b, "[%s]   %s\n", host, line)
+	}
+	for _, line := range outputLines(stderr) {
+		fmt.Fprintf(&b, "[%s] ! %s\n", host, line)
+	}
+	if err != nil {
+		fmt.Fprintf(&b, "[%s] # failed in %s: %s\n", host, elapsed.Round(time.Millisecond), strings.Split(err.Error(), "\n")[0])
+	} else {
+		fmt.Fprintf(&b, "[%s] # done in %s\n", host, elapsed.Round(time.Millisecond))
+	}
+	f.write(host, maskSecrets(b.String()))
+}
+
+func outputLines(out []byte) []string {
+	text := strings.TrimRight(string(out), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// SetHostFollowers makes the commands executed on the hosts with the context
+// streamed to the followers of the hosts
+func (ctx *Context) SetHostFollowers(f *HostFollowers) {
+	ctx.followers = f
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+)
+
+type followSuite struct{}
+
+var _ = check.Suite(&followSuite{})
+
+func (s *followSuite) TestFollowHost(c *check.C) {
+	ctx := NewContext()
+	followers := NewHostFollowers()
+	ctx.SetHostFollowers(followers)
+	for _, host := range []string{"host-1", "host-2"} {
+		e := executor.NewFake(host)
+		e.Respond("cat", "line 1\nline 2\n", "")
+		e.Respond("false", "", "exit status 1")
+		ctx.SetExecutor(host, e)
+	}
+
+	var out bytes.Buffer
+	done, stop := followers.Follow("host-1", &out)
+	c.Assert(followers.Following(), check.DeepEquals, []string{"host-1"})
+	for _, host := range []string{"host-1", "host-2"} {
+		_, _, _ = ctx.Get(host).Execute("cat /tmp/a", false)
+		_, _, _ = ctx.Get(host).Execute("false", true)
+	}
+	text := out.String()
+	c.Assert(text, check.Not(check.Matches), "(?s).*host-2.*")
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	c.Assert(lines, check.HasLen, 7)
+	c.Assert(lines[0], check.Equals, "[host-1] $ cat /tmp/a")
+	c.Assert(lines[1], check.Equals, "[host-1]   line 1")
+	c.Assert(lines[2], check.Equals, "[host-1]   line 2")
+	c.Assert(lines[3], check.Matches, `\[host-1\] # done in .*`)
+	c.Assert(lines[4], check.Equals, "[host-1] $ false")
+	c.Assert(lines[5], check.Matches, `\[host-1\] ! .*exit status 1`)
+	c.Assert(lines[6], check.Matches, `\[host-1\] # failed in .*: exit status 1`)
+
+	// nothing is written after stopping
+	stop()
+	<-done
+	out.Reset()
+	_, _, _ = ctx.Get("host-1").Execute("cat /tmp/a", false)
+	c.Assert(out.Len(), check.Equals, 0)
+	c.Assert(followers.Following(), check.HasLen, 0)
+
+	// all the followers end when closed
+	done, _ = followers.Follow("host-2", &out)
+	followers.Close()
+	<-done
+	_, _, _ = ctx.Get("host-2").Execute("cat /tmp/a", false)
+	c.Assert(out.Len(), check.Equals, 0)
+	done, _ = followers.Follow("host-2", &out)
+	<-done
+}
+
+func (s *followSuite) TestMaskSecrets(c *check.C) {
+	for text, masked := range map[string]string{
+		"mysql --password=abc -h 10.0.0.1":            "mysql --password=****** -h 10.0.0.1",
+		`export TIDB_PASSWORD="a b"; echo ok`:         "export TIDB_PASSWORD=******; echo ok",
+		"security.token: abc\nlog.level: info":        "security.token: ******\nlog.level: info",
+		`{"secret":"abc","user":"root"}`:              `{"secret":******,"user":"root"}`,
+		"CREATE USER u IDENTIFIED BY 'abc'; FLUSH":    "CREATE USER u IDENTIFIED BY ******; FLUSH",
+		"systemctl restart tikv-20160.service":        "systemctl restart tikv-20160.service",
+		"password is required to login to the server": "password is required to login to the server",
+	} {
+		c.Assert(maskSecrets(text), check.Equals, masked, check.Commentf(text))
+	}
+
+	ctx := NewContext()
+	followers := NewHostFollowers()
+	ctx.SetHostFollowers(followers)
+	e := executor.NewFake("host-1")
+	e.Respond("cat", "password = abc\n", "")
+	ctx.SetExecutor("host-1", e)
+	var out bytes.Buffer
+	_, stop := followers.Follow("host-1", &out)
+	defer stop()
+	_, _, _ = ctx.Get("host-1").Execute("cat /tmp/a --token=abc", false)
+	c.Assert(out.String(), check.Not(check.Matches), "(?s).*abc.*")
+	c.Assert(out.String(), check.Matches, `(?s).*--token=\*\*\*\*\*\*.*password = \*\*\*\*\*\*.*`)
+}
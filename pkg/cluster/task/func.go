@@ -13,18 +13,69 @@
 
 package task
 
+import (
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+)
+
 // Func wrap a closure.
 type Func struct {
-	name string
-	fn   func(ctx *Context) error
+	name  string
+	id    string         // the explicit identity, see WithID
+	plan  *operator.Plan // what the closure does, see WithPlan
+	hosts []string       // the hosts the closure works on, see OnHosts
+	fn    func(ctx *Context) error
+
+	taskProgress // reported by the closure, see Context.SetProgress
+}
+
+// FuncOption is an option of Func tasks
+type FuncOption func(f *Func)
+
+// WithID sets the identity of the Func task explicitly. The identity is used
+// as is, instead of the name namespaced by the context executing the task.
+func WithID(id string) FuncOption {
+	return func(f *Func) {
+		f.id = id
+	}
+}
+
+// WithPlan sets the plan of the operation the closure performs, which is
+// written in the plan of the task tree instead of executing the closure
+func WithPlan(plan *operator.Plan) FuncOption {
+	return func(f *Func) {
+		f.plan = plan
+	}
+}
+
+// OnHosts sets the hosts the closure works on, the failure of the task is
+// confined to them in the SkipFailedHosts mode
+func OnHosts(hosts ...string) FuncOption {
+	return func(f *Func) {
+		f.hosts = hosts
+	}
 }
 
 // NewFunc create a Func task
-func NewFunc(name string, fn func(ctx *Context) error) *Func {
-	return &Func{
+func NewFunc(name string, fn func(ctx *Context) error, opts ...FuncOption) *Func {
+	f := &Func{
 		name: name,
 		fn:   fn,
 	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// identity returns the identity of the task in the namespace
+func (m *Func) identity(namespace string) string {
+	if m.id != "" {
+		return m.id
+	}
+	if namespace == "" {
+		return m.name
+	}
+	return namespace + "/" + m.name
 }
 
 // Execute implements the Task interface
@@ -32,6 +83,11 @@ func (m *Func) Execute(ctx *Context) error {
 	return m.fn(ctx)
 }
 
+// Explain returns the plan set by WithPlan, nil if not set
+func (m *Func) Explain() *operator.Plan {
+	return m.plan
+}
+
 // Rollback implements the Task interface
 func (m *Func) Rollback(_ *Context) error {
 	return ErrUnsupportedRollback
@@ -41,3 +97,8 @@ func (m *Func) Rollback(_ *Context) error {
 func (m *Func) String() string {
 	return m.name
 }
+
+// Hosts implements the HostTask interface
+func (m *Func) Hosts() []string {
+	return m.hosts
+}
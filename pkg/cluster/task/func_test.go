@@ -0,0 +1,47 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/pingcap/check"
+)
+
+type funcSuite struct{}
+
+var _ = check.Suite(&funcSuite{})
+
+func (s *funcSuite) TestFuncIdentity(c *check.C) {
+	noop := func(ctx *Context) error { return nil }
+	execute := func(namespace string) []string {
+		var ids []string
+		ctx := NewContext()
+		ctx.SetNamespace(namespace)
+		ctx.Subscribe(EventTaskBegin, func(t Task, id string) {
+			ids = append(ids, id)
+		})
+		t := NewBuilder().
+			Func("StartCluster", noop).
+			Func("CheckStatus", noop, WithID("check-status")).
+			Build()
+		c.Assert(t.Execute(ctx), check.IsNil)
+		return ids
+	}
+
+	// the same named steps of different operations don't share identities
+	start := execute("start/test")
+	restart := execute("restart/test")
+	c.Assert(start, check.DeepEquals, []string{"start/test/StartCluster", "check-status"})
+	c.Assert(restart, check.DeepEquals, []string{"restart/test/StartCluster", "check-status"})
+	c.Assert(execute(""), check.DeepEquals, []string{"StartCluster", "check-status"})
+}
@@ -0,0 +1,263 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/logger/log"
+)
+
+// DefaultHostFailureThreshold is the number of consecutive failures to reach
+// a host after which the host is marked as degraded
+const DefaultHostFailureThreshold = 3
+
+var (
+	// ErrHostDegraded is returned for the commands on a host marked as
+	// degraded, they fail fast instead of waiting for the network timeouts
+	ErrHostDegraded = errNS.NewType("host_degraded")
+	// errPropDegradedHost is the host of ErrHostDegraded
+	errPropDegradedHost = errorx.RegisterProperty("degraded_host")
+)
+
+// isUnreachable checks if err is caused by failing to reach the host, rather
+// than by the command failing on the host. Only the errors dialing the host
+// and the failures of SSH handshakes are counted, or the exit code 255 of the
+// native ssh client, never the messages, which may include the outputs of
+// the commands on the host.
+func isUnreachable(err error) bool {
+	for err != nil {
+		var netErr net.Error
+		if stderrors.As(err, &netErr) {
+			return true
+		}
+		var exitErr *exec.ExitError
+		if stderrors.As(err, &exitErr) {
+			// ssh exits with 255 if it fails to connect, or with the exit
+			// code of the command otherwise
+			return exitErr.ExitCode() == 255
+		}
+		if strings.HasPrefix(err.Error(), "ssh: handshake failed") {
+			return true
+		}
+		// the errorx and pingcap/errors ones
+		cause, ok := err.(interface{ Cause() error })
+		if !ok || cause.Cause() == err {
+			return false
+		}
+		err = cause.Cause()
+	}
+	return false
+}
+
+// IsHostDegraded checks if err is returned because the host is degraded, the
+// host is returned if so
+func IsHostDegraded(err error) (string, bool) {
+	errx := errorx.Cast(err)
+	if errx == nil || !errx.IsOfType(ErrHostDegraded) {
+		return "", false
+	}
+	host, _ := errx.Property(errPropDegradedHost)
+	h, _ := host.(string)
+	return h, true
+}
+
+// HostDegradation is a host marked as degraded during an operation
+type HostDegradation struct {
+	Host  string    `json:"host"`
+	Cause string    `json:"cause"` // the last error failing to reach the host
+	Since time.Time `json:"since"`
+	// the first step failed on the host, the operation should be retried
+	// from it for the host
+	FirstFailedStep string `json:"first_failed_step,omitempty"`
+}
+
+// hostHealth tracks the consecutive failures to reach each host
+type hostHealth struct {
+	sync.Mutex
+	threshold int
+	failures  map[string]int
+	degraded  map[string]*HostDegradation
+	// the first failed steps of the hosts failed to be reached, the step is
+	// the innermost failed one mentioning the host, or the innermost failed
+	// one if there isn't such a step
+	firstSteps map[string]string
+	fallbacks  map[string]string
+}
+
+func newHostHealth() *hostHealth {
+	return &hostHealth{
+		threshold:  DefaultHostFailureThreshold,
+		failures:   make(map[string]int),
+		degraded:   make(map[string]*HostDegradation),
+		firstSteps: make(map[string]string),
+		fallbacks:  make(map[string]string),
+	}
+}
+
+// SetHostFailureThreshold sets the number of consecutive failures to reach a
+// host after which the host is marked as degraded, 0 disables the marking
+func (ctx *Context) SetHostFailureThreshold(threshold int) {
+	ctx.hosts.Lock()
+	ctx.hosts.threshold = threshold
+	ctx.hosts.Unlock()
+}
+
+// DegradedHosts returns the hosts marked as degraded sorted by host
+func (ctx *Context) DegradedHosts() []HostDegradation {
+	ctx.hosts.Lock()
+	defer ctx.hosts.Unlock()
+	hosts := make([]HostDegradation, 0, len(ctx.hosts.degraded))
+	for _, d := range ctx.hosts.degraded {
+		h := *d
+		h.FirstFailedStep = ctx.hosts.firstSteps[h.Host]
+		if h.FirstFailedStep == "" {
+			h.FirstFailedStep = ctx.hosts.fallbacks[h.Host]
+		}
+		hosts = append(hosts, h)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+	return hosts
+}
+
+// checkHost returns ErrHostDegraded if the host is degraded
+func (ctx *Context) checkHost(host string) error {
+	ctx.hosts.Lock()
+	defer ctx.hosts.Unlock()
+	d, ok := ctx.hosts.degraded[host]
+	if !ok {
+		return nil
+	}
+	return ErrHostDegraded.New("Skipped as host %s is degraded since %s: %s",
+		host, d.Since.Format(time.RFC3339), d.Cause).
+		WithProperty(errPropDegradedHost, host)
+}
+
+// recordHost records the result of a command on the host, the host is marked
+// as degraded if it fails to be reached too many times in a row
+func (ctx *Context) recordHost(host string, err error) {
+	ctx.hosts.Lock()
+	defer ctx.hosts.Unlock()
+	if err == nil || !isUnreachable(err) {
+		ctx.hosts.failures[host] = 0
+		return
+	}
+	ctx.hosts.failures[host]++
+	if _, ok := ctx.hosts.firstSteps[host]; !ok {
+		ctx.hosts.firstSteps[host] = ""
+	}
+	if ctx.hosts.threshold <= 0 || ctx.hosts.failures[host] < ctx.hosts.threshold {
+		return
+	}
+	if _, ok := ctx.hosts.degraded[host]; !ok {
+		log.Warnf("Host %s is marked as degraded after failing to be reached %d times in a row, the rest commands on it are skipped",
+			host, ctx.hosts.failures[host])
+		ctx.hosts.degraded[host] = &HostDegradation{Host: host, Cause: err.Error(), Since: time.Now()}
+	}
+}
+
+// recordStepFailure attributes the failed task to the hosts failed to be
+// reached whose first failed steps are not known yet, the tasks fail from
+// the innermost to the outermost
+func (ctx *Context) recordStepFailure(t Task) {
+	ctx.hosts.Lock()
+	defer ctx.hosts.Unlock()
+	if len(ctx.hosts.firstSteps) == 0 {
+		return
+	}
+	step := stepName(t)
+	for host, first := range ctx.hosts.firstSteps {
+		switch {
+		case first != "":
+		case strings.Contains(step, host) || strings.Contains(t.String(), host):
+			ctx.hosts.firstSteps[host] = step
+		case ctx.hosts.fallbacks[host] == "":
+			ctx.hosts.fallbacks[host] = step
+		}
+	}
+}
+
+// healthTrackingExecutor fails fast on degraded hosts, and records the
+// results of the commands to mark the hosts failing to be reached
+type healthTrackingExecutor struct {
+	executor.Executor
+	host string
+	ctx  *Context
+}
+
+// Execute implements the executor.Executor interface
+func (e *healthTrackingExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	if err := e.ctx.checkHost(e.host); err != nil {
+		return nil, nil, err
+	}
+	timeout, err := e.ctx.boundTimeout(e.host, cmd, timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	e.ctx.followers.begin(e.host, "$ "+cmd)
+	start := time.Now()
+	stdout, stderr, err := e.Executor.Execute(cmd, sudo, timeout...)
+	e.ctx.followers.finish(e.host, stdout, stderr, err, time.Since(start))
+	e.ctx.recordHost(e.host, err)
+	return stdout, stderr, err
+}
+
+// ExecuteWithAgent implements the executor.AgentForwarder interface, an
+// error is returned if the executor wrapped can't forward an agent
+func (e *healthTrackingExecutor) ExecuteWithAgent(cmd, keyFile string, timeout time.Duration) ([]byte, []byte, error) {
+	f, ok := e.Executor.(executor.AgentForwarder)
+	if !ok {
+		return nil, nil, errors.Errorf("the executor of %s can't forward an SSH agent", e.host)
+	}
+	if err := e.ctx.checkHost(e.host); err != nil {
+		return nil, nil, err
+	}
+	bounded, err := e.ctx.boundTimeout(e.host, cmd, []time.Duration{timeout})
+	if err != nil {
+		return nil, nil, err
+	}
+	e.ctx.followers.begin(e.host, "$ "+cmd)
+	start := time.Now()
+	stdout, stderr, err := f.ExecuteWithAgent(cmd, keyFile, bounded[0])
+	e.ctx.followers.finish(e.host, stdout, stderr, err, time.Since(start))
+	e.ctx.recordHost(e.host, err)
+	return stdout, stderr, err
+}
+
+// Transfer implements the executor.Executor interface
+func (e *healthTrackingExecutor) Transfer(src string, dst string, download bool) error {
+	if err := e.ctx.checkHost(e.host); err != nil {
+		return err
+	}
+	action := "upload"
+	if download {
+		action = "download"
+	}
+	e.ctx.followers.begin(e.host, fmt.Sprintf("%s %s %s", action, src, dst))
+	start := time.Now()
+	err := e.Executor.Transfer(src, dst, download)
+	e.ctx.followers.finish(e.host, nil, nil, err, time.Since(start))
+	e.ctx.recordHost(e.host, err)
+	return err
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/check"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+)
+
+// errConnRefused is the error dialing a host refusing the connection
+var errConnRefused = executor.ErrSSHExecuteFailed.Wrap(&net.OpError{
+	Op:  "dial",
+	Net: "tcp",
+	Err: errors.New("connect: connection refused"),
+}, "Failed to execute command over SSH for 'tidb@10.0.0.1:22'")
+
+// unreachableExecutor fails to reach the host for every command
+type unreachableExecutor struct {
+	calls int32
+}
+
+func (e *unreachableExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	atomic.AddInt32(&e.calls, 1)
+	return nil, nil, errConnRefused
+}
+
+func (e *unreachableExecutor) Transfer(src string, dst string, download bool) error {
+	atomic.AddInt32(&e.calls, 1)
+	return errConnRefused
+}
+
+type hostHealthSuite struct{}
+
+var _ = check.Suite(&hostHealthSuite{})
+
+func (s *hostHealthSuite) TestDegradedHost(c *check.C) {
+	ctx := NewContext()
+	ctx.SetHostFailureThreshold(2)
+	down := &unreachableExecutor{}
+	ctx.SetExecutor("10.0.0.1", down)
+	ctx.SetExecutor("10.0.0.2", &shellExecutor{})
+
+	run := func(host string) func(ctx *Context) error {
+		return func(ctx *Context) error {
+			e, _ := ctx.GetExecutor(host)
+			_, _, err := e.Execute("true", false)
+			return err
+		}
+	}
+	b := NewBuilder().Mode(ContinueCollectingErrors)
+	for i := 0; i < 4; i++ {
+		b.Func(fmt.Sprintf("Step%d@10.0.0.1", i), run("10.0.0.1"))
+		b.Func(fmt.Sprintf("Step%d@10.0.0.2", i), run("10.0.0.2"))
+	}
+	err := b.Build().Execute(ctx)
+
+	// the commands after the threshold fail fast without reaching the host
+	c.Assert(down.calls, check.Equals, int32(2))
+	degraded, ok := err.(*DegradedError)
+	c.Assert(ok, check.IsTrue)
+	c.Assert(degraded.Failures, check.HasLen, 4)
+	var skipped int
+	for _, f := range degraded.Failures {
+		c.Assert(strings.HasSuffix(f.Step, "@10.0.0.1"), check.IsTrue)
+		if f.Skipped {
+			host, ok := IsHostDegraded(f.Err)
+			c.Assert(ok, check.IsTrue)
+			c.Assert(host, check.Equals, "10.0.0.1")
+			skipped++
+		}
+	}
+	c.Assert(skipped, check.Equals, 2)
+	c.Assert(degraded.Hosts, check.HasLen, 1)
+	c.Assert(degraded.Hosts[0].Host, check.Equals, "10.0.0.1")
+	c.Assert(degraded.Hosts[0].FirstFailedStep, check.Equals, "Step0@10.0.0.1")
+	c.Assert(strings.Contains(degraded.Error(), "2 step(s) skipped on degraded hosts"), check.IsTrue)
+
+	// the failures of commands on the host don't mark it as degraded
+	ctx = NewContext()
+	ctx.SetHostFailureThreshold(1)
+	ctx.SetExecutor("10.0.0.2", &shellExecutor{noSpace: true})
+	c.Assert(run("10.0.0.2")(ctx), check.NotNil)
+	c.Assert(ctx.DegradedHosts(), check.HasLen, 0)
+}
+
+func (s *hostHealthSuite) TestUnreachable(c *check.C) {
+	exitErr := func(code int) error {
+		err := exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+		c.Assert(err, check.NotNil)
+		return executor.ErrSSHExecuteFailed.Wrap(err, "Failed to execute command over SSH for 'tidb@10.0.0.1:22'").
+			WithProperty(executor.ErrPropSSHStderr, "connection refused")
+	}
+
+	c.Assert(isUnreachable(errConnRefused), check.IsTrue)
+	c.Assert(isUnreachable(perrs.Trace(errConnRefused)), check.IsTrue)
+	c.Assert(isUnreachable(perrs.Trace(errors.New("ssh: handshake failed: EOF"))), check.IsTrue)
+	// the native ssh client fails to connect
+	c.Assert(isUnreachable(exitErr(255)), check.IsTrue)
+
+	// the outputs of the commands failed on the host are not inspected
+	c.Assert(isUnreachable(exitErr(1)), check.IsFalse)
+	c.Assert(isUnreachable(errors.New("curl: (7) Failed to connect to 10.0.0.9: connection refused")), check.IsFalse)
+	c.Assert(isUnreachable(executor.ErrSSHExecuteFailed.New("no route to host, i/o timeout")), check.IsFalse)
+}
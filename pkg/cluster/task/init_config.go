@@ -18,6 +18,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/joomcode/errorx"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/meta"
@@ -32,6 +33,9 @@ type InitConfig struct {
 	deployUser     string
 	ignoreCheck    bool
 	paths          meta.DirPaths
+	// change is set to record which of the systemd unit and the run script
+	// were changed, only the changed files are pushed to the host
+	change *spec.ServiceFilesChange
 }
 
 // Execute implements the Task interface
@@ -45,14 +49,34 @@ func (c *InitConfig) Execute(ctx *Context) error {
 	if err := os.MkdirAll(c.paths.Cache, 0755); err != nil {
 		return errors.Annotatef(err, "create cache directory failed: %s", c.paths.Cache)
 	}
+	if s := ctx.ConfigStamper(); s != nil {
+		exec = s.Wrap(exec, c.instance.GetHost())
+	}
+
+	var diff *spec.DiffExecutor
+	if c.change != nil {
+		diff = spec.NewDiffExecutor(exec, c.instance)
+		exec = diff
+	}
 
 	err := c.instance.InitConfig(exec, c.clusterName, c.clusterVersion, c.deployUser, c.paths)
-	if err != nil {
-		if c.ignoreCheck && errors.Cause(err) == spec.ErrorCheckConfig {
-			return nil
-		}
+	if errorx.IsOfType(err, spec.ErrConfigConflict) {
+		// keep the suggestion of the error
+		return err
+	}
+	if err != nil && !(c.ignoreCheck && errors.Cause(err) == spec.ErrorCheckConfig) {
 		return errors.Annotatef(err, "init config failed: %s:%d", c.instance.GetHost(), c.instance.GetPort())
 	}
+	if diff == nil {
+		return nil
+	}
+
+	*c.change = diff.Change
+	if diff.Change.UnitChanged {
+		if _, stderr, err := diff.Executor.Execute("systemctl daemon-reload", true); err != nil {
+			return errors.Annotatef(err, "failed to reload systemd on %s: %s", c.instance.GetHost(), stderr)
+		}
+	}
 	return nil
 }
 
@@ -61,9 +85,19 @@ func (c *InitConfig) Rollback(ctx *Context) error {
 	return ErrUnsupportedRollback
 }
 
+// Message implements the Messager interface
+func (c *InitConfig) Message() Message {
+	return NewMessage(MsgInitConfig, MessageParams{"component": c.instance.ComponentName(), "instance": c.instance.ID()})
+}
+
 // String implements the fmt.Stringer interface
 func (c *InitConfig) String() string {
 	return fmt.Sprintf("InitConfig: cluster=%s, user=%s, host=%s, path=%s, %s",
 		c.clusterName, c.deployUser, c.instance.GetHost(),
 		filepath.Join(c.specManager.Path(c.clusterName, spec.TempConfigPath, c.instance.ServiceName())), c.paths)
 }
+
+// Hosts implements the HostTask interface
+func (c *InitConfig) Hosts() []string {
+	return []string{c.instance.GetHost()}
+}
@@ -15,6 +15,7 @@ package task
 
 import (
 	"fmt"
+	"os"
 	"path"
 	"path/filepath"
 
@@ -24,13 +25,20 @@ import (
 // InstallPackage is used to copy all files related the specific version a component
 // to the target directory of path
 type InstallPackage struct {
-	srcPath string
-	host    string
-	dstDir  string
+	srcPath  string
+	host     string
+	dstDir   string
+	cache    *PackageCache // the package is pushed to the host-level cache if set
+	transfer TransferOptions
 }
 
 // Execute implements the Task interface
 func (c *InstallPackage) Execute(ctx *Context) error {
+	return c.execute(ctx, c)
+}
+
+// execute installs the package, the phases are reported as ones of t
+func (c *InstallPackage) execute(ctx *Context, t Task) error {
 	// Install package to remote server
 	exec, found := ctx.GetExecutor(c.host)
 	if !found {
@@ -40,12 +48,38 @@ func (c *InstallPackage) Execute(ctx *Context) error {
 	dstDir := filepath.Join(c.dstDir, "bin")
 	dstPath := filepath.Join(dstDir, path.Base(c.srcPath))
 
-	err := exec.Transfer(c.srcPath, dstPath, false)
-	if err != nil {
-		return errors.Annotatef(err, "failed to scp %s to %s:%s", c.srcPath, c.host, dstPath)
+	info, err := os.Stat(c.srcPath)
+	if err == nil {
+		ctx.SetPhase(t, PhaseCopying, fmt.Sprintf("%s to %s", formatSize(info.Size()), c.host))
+	}
+	var cmd string
+	switch {
+	case c.cache != nil:
+		cachePath, err := c.cache.ensure(ctx, exec, c.host, c.srcPath, true, c.transfer)
+		if err != nil {
+			return err
+		}
+		// the cached package is kept for later reuse
+		cmd = fmt.Sprintf(`mkdir -p %s && tar -xzf %s -C %s`, dstDir, cachePath, dstDir)
+	case ctx.hasRemoteTmpDir(c.host) && info != nil:
+		// the package is staged apart from the deploy dir, and extracted from there
+		stageDir, cleanup, err := ctx.stage(exec, c.host, info.Size())
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		stagePath := path.Join(stageDir, path.Base(c.srcPath))
+		if err := transferFile(exec, c.host, c.srcPath, stagePath, c.transfer); err != nil {
+			return errors.Annotatef(err, "failed to scp %s to %s:%s", c.srcPath, c.host, stagePath)
+		}
+		cmd = fmt.Sprintf(`mkdir -p %s && tar -xzf %s -C %s`, dstDir, stagePath, dstDir)
+	default:
+		err := transferFile(exec, c.host, c.srcPath, dstPath, c.transfer)
+		if err != nil {
+			return errors.Annotatef(err, "failed to scp %s to %s:%s", c.srcPath, c.host, dstPath)
+		}
+		cmd = fmt.Sprintf(`tar -xzf %s -C %s && rm %s`, dstPath, dstDir, dstPath)
 	}
-
-	cmd := fmt.Sprintf(`tar -xzf %s -C %s && rm %s`, dstPath, dstDir, dstPath)
 
 	_, stderr, err := exec.Execute(cmd, false)
 	if err != nil {
@@ -63,3 +97,8 @@ func (c *InstallPackage) Rollback(ctx *Context) error {
 func (c *InstallPackage) String() string {
 	return fmt.Sprintf("InstallPackage: srcPath=%s, remote=%s:%s", c.srcPath, c.host, c.dstDir)
 }
+
+// Hosts implements the HostTask interface
+func (c *InstallPackage) Hosts() []string {
+	return []string{c.host}
+}
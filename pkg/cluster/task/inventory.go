@@ -0,0 +1,103 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/errors"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+)
+
+// InventoryScript is the name of the inventory script on remote hosts
+const InventoryScript = "tiup-inventory.sh"
+
+// CollectInventory gathers the hardware and OS inventory of a host, the
+// insight collector must be copied to CheckToolsDir before. The failures
+// of collecting are recorded in the inventory rather than failing the task,
+// so the inventories of other hosts are still gathered.
+type CollectInventory struct {
+	host string
+	dirs []string // the data directories to check the disks of
+	inv  *operator.HostInventory
+}
+
+// Execute implements the Task interface
+func (c *CollectInventory) Execute(ctx *Context) error {
+	e, ok := ctx.GetExecutor(c.host)
+	if !ok {
+		return ErrNoExecutor
+	}
+	c.inv.Host = c.host
+
+	var errs []string
+	f, err := ioutil.TempFile("", "tiup-inventory-")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(operator.InventoryScript); err != nil {
+		f.Close()
+		return errors.Trace(err)
+	}
+	if err := f.Close(); err != nil {
+		return errors.Trace(err)
+	}
+
+	toolsDir := CheckToolsDir(ctx.RemoteTmpDir(c.host))
+	script := filepath.Join(toolsDir, InventoryScript)
+	if err := e.Transfer(f.Name(), script, false); err != nil {
+		errs = append(errs, fmt.Sprintf("failed to transfer the inventory script: %s", err))
+	} else {
+		args := make([]string, 0, len(c.dirs))
+		for _, dir := range c.dirs {
+			args = append(args, fmt.Sprintf("'%s'", dir))
+		}
+		stdout, stderr, err := e.Execute(fmt.Sprintf("bash %s %s", script, strings.Join(args, " ")), false)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("failed to collect the disks and NICs: %s %s", err, stderr))
+		} else if err := c.inv.ParseInventoryScript(stdout); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	stdout, stderr, err := e.Execute(filepath.Join(toolsDir, "bin", "insight"), false)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("failed to collect the system info: %s %s", err, stderr))
+	} else if err := c.inv.ParseInsight(stdout); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	c.inv.Error = strings.Join(errs, "; ")
+	return nil
+}
+
+// Rollback implements the Task interface
+func (c *CollectInventory) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CollectInventory) String() string {
+	return fmt.Sprintf("CollectInventory: host=%s, dirs=%v", c.host, c.dirs)
+}
+
+// Hosts implements the HostTask interface
+func (c *CollectInventory) Hosts() []string {
+	return []string{c.host}
+}
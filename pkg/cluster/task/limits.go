@@ -66,3 +66,8 @@ func (l *Limit) Rollback(ctx *Context) error {
 func (l *Limit) String() string {
 	return fmt.Sprintf("Limit: host=%s %s %s %s %s", l.host, l.domain, l.limit, l.item, l.value)
 }
+
+// Hosts implements the HostTask interface
+func (l *Limit) Hosts() []string {
+	return []string{l.host}
+}
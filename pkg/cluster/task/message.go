@@ -0,0 +1,110 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"sort"
+	"strings"
+)
+
+// MessageID identifies a kind of display string of steps and tasks, consumers
+// like UIs can map the ID and the parameters of a message to localized strings
+type MessageID string
+
+// the identifiers of the messages of common steps and tasks
+const (
+	MsgDownload            MessageID = "download"
+	MsgImportPackage       MessageID = "import_package"
+	MsgCopy                MessageID = "copy"
+	MsgCopyFile            MessageID = "copy_file"
+	MsgFetchFile           MessageID = "fetch_file"
+	MsgInitConfig          MessageID = "init_config"
+	MsgRefreshConfig       MessageID = "refresh_config"
+	MsgStartInstance       MessageID = "start_instance"
+	MsgStartMonitorAgents  MessageID = "start_monitor_agents"
+	MsgStopInstance        MessageID = "stop_instance"
+	MsgStopMonitorAgents   MessageID = "stop_monitor_agents"
+	MsgPrepareHost         MessageID = "prepare_host"
+	MsgChown               MessageID = "chown"
+	MsgAuthenticate        MessageID = "authenticate"
+	MsgDetectPlatform      MessageID = "detect_platform"
+	MsgDetectMonitorAgents MessageID = "detect_monitor_agents"
+	MsgRestartMonitorAgent MessageID = "restart_monitor_agents"
+)
+
+// messageDefs are the English templates of the messages, the parameters are
+// referenced as {name}
+var messageDefs = map[MessageID]string{
+	MsgDownload:            "Download {component}:{version} ({os}/{arch})",
+	MsgImportPackage:       "Import {component}:{version} ({os}/{arch}) from {dir}",
+	MsgCopy:                "Copy {component} -> {host}",
+	MsgCopyFile:            "Copy {src} -> {host}:{dst}",
+	MsgFetchFile:           "Copy {host}:{src} -> {dst}",
+	MsgInitConfig:          "Init config {component} -> {instance}",
+	MsgRefreshConfig:       "Refresh config {component} -> {instance}",
+	MsgStartInstance:       "Start {component} {instance}",
+	MsgStartMonitorAgents:  "Start monitoring agents on {host}",
+	MsgStopInstance:        "Stop {component} {instance}",
+	MsgStopMonitorAgents:   "Stop monitoring agents on {host}",
+	MsgPrepareHost:         "Prepare {host}:{port}",
+	MsgChown:               "Chown files on {host}",
+	MsgAuthenticate:        "Authenticate to {host}:{port}",
+	MsgDetectPlatform:      "Detect platform of {host}",
+	MsgDetectMonitorAgents: "Detect monitoring agents on {host}",
+	MsgRestartMonitorAgent: "Restart monitoring agents on {host}",
+}
+
+// MessageDefinition is the English template of a message
+type MessageDefinition struct {
+	ID       MessageID `json:"id"`
+	Template string    `json:"template"`
+}
+
+// MessageDefinitions returns the English templates of all messages sorted by
+// ID, for consumers to build their translations
+func MessageDefinitions() []MessageDefinition {
+	defs := make([]MessageDefinition, 0, len(messageDefs))
+	for id, tpl := range messageDefs {
+		defs = append(defs, MessageDefinition{ID: id, Template: tpl})
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].ID < defs[j].ID })
+	return defs
+}
+
+// MessageParams are the parameters of a message, e.g. the component and host
+type MessageParams map[string]string
+
+// Message is a display string identified by ID, along with the English text
+// rendered from its template and parameters
+type Message struct {
+	ID     MessageID     `json:"id"`
+	Params MessageParams `json:"params,omitempty"`
+	Text   string        `json:"text"`
+}
+
+// NewMessage renders the message of the ID with the parameters
+func NewMessage(id MessageID, params MessageParams) Message {
+	text := messageDefs[id]
+	for name, value := range params {
+		text = strings.Replace(text, "{"+name+"}", value, -1)
+	}
+	return Message{ID: id, Params: params, Text: text}
+}
+
+// Messager is implemented by the steps and tasks having an identified message
+type Messager interface {
+	// Message returns the message of the step or task, the ID is empty if
+	// the display string isn't identified
+	Message() Message
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/pingcap/check"
+)
+
+type messageSuite struct{}
+
+var _ = check.Suite(&messageSuite{})
+
+func (s *messageSuite) TestMessage(c *check.C) {
+	msg := NewMessage(MsgDownload, MessageParams{"component": "tikv", "version": "v4.0.0", "os": "linux", "arch": "amd64"})
+	c.Assert(msg.Text, check.Equals, "Download tikv:v4.0.0 (linux/amd64)")
+
+	// the rendered prefix of steps is kept
+	step := NewBuilder().BuildAsStepMessage(MsgCopy, MessageParams{"component": "pd", "host": "10.0.1.1"})
+	c.Assert(step.prefix, check.Equals, "  - Copy pd -> 10.0.1.1")
+	c.Assert(step.Message().ID, check.Equals, MsgCopy)
+	c.Assert(step.Message().Params["host"], check.Equals, "10.0.1.1")
+	c.Assert(NewBuilder().BuildAsStep("  - Check something").Message(), check.DeepEquals, Message{Text: "Check something"})
+
+	c.Assert((&Downloader{component: "pd", version: "v4.0.0", os: "linux", arch: "arm64"}).Message().Text,
+		check.Equals, "Download pd:v4.0.0 (linux/arm64)")
+
+	// all the messages have templates
+	defs := MessageDefinitions()
+	c.Assert(len(defs), check.Equals, len(messageDefs))
+	for _, def := range defs {
+		c.Assert(def.Template, check.Not(check.Equals), "")
+	}
+}
@@ -75,3 +75,8 @@ func (m *Mkdir) Rollback(ctx *Context) error {
 func (m *Mkdir) String() string {
 	return fmt.Sprintf("Mkdir: host=%s, directories='%s'", m.host, strings.Join(m.dirs, "','"))
 }
+
+// Hosts implements the HostTask interface
+func (m *Mkdir) Hosts() []string {
+	return []string{m.host}
+}
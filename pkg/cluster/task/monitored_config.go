@@ -31,13 +31,14 @@ import (
 
 // MonitoredConfig is used to generate the monitor node configuration
 type MonitoredConfig struct {
-	name       string
-	component  string
-	host       string
-	globResCtl meta.ResourceControl
-	options    *spec.MonitoredOptions
-	deployUser string
-	paths      meta.DirPaths
+	name           string
+	clusterVersion string
+	component      string
+	host           string
+	globOpts       spec.GlobalOptions
+	options        *spec.MonitoredOptions
+	deployUser     string
+	paths          meta.DirPaths
 }
 
 // Execute implements the Task interface
@@ -51,6 +52,9 @@ func (m *MonitoredConfig) Execute(ctx *Context) error {
 	if !found {
 		return ErrNoExecutor
 	}
+	if s := ctx.ConfigStamper(); s != nil {
+		exec = s.Wrap(exec, m.host)
+	}
 
 	if err := os.MkdirAll(m.paths.Cache, 0755); err != nil {
 		return err
@@ -85,21 +89,30 @@ func (m *MonitoredConfig) Execute(ctx *Context) error {
 func (m *MonitoredConfig) syncMonitoredSystemConfig(exec executor.Executor, comp string, port int) error {
 	sysCfg := filepath.Join(m.paths.Cache, fmt.Sprintf("%s-%s-%d.service", comp, m.host, port))
 
-	resource := spec.MergeResourceControl(m.globResCtl, m.options.ResourceControl)
+	envFile, err := spec.SyncProxyEnvFile(exec, &m.globOpts, m.host, m.paths)
+	if err != nil {
+		return err
+	}
+	resource := spec.MergeResourceControl(m.globOpts.ResourceControl, m.options.ResourceControl)
 	systemCfg := system.NewConfig(comp, m.deployUser, m.paths.Deploy).
 		WithMemoryLimit(resource.MemoryLimit).
 		WithCPUQuota(resource.CPUQuota).
 		WithIOReadBandwidthMax(resource.IOReadBandwidthMax).
-		WithIOWriteBandwidthMax(resource.IOWriteBandwidthMax)
+		WithIOWriteBandwidthMax(resource.IOWriteBandwidthMax).
+		WithEnvironmentFile(envFile)
 
-	if err := systemCfg.ConfigToFile(sysCfg); err != nil {
+	if err := spec.RenderTemplate(systemCfg, m.name, m.clusterVersion, comp, "system.service.tpl", sysCfg); err != nil {
 		return err
 	}
-	tgt := filepath.Join("/tmp", comp+"_"+uuid.New().String()+".service")
+	unit := fmt.Sprintf("/etc/systemd/system/%s-%d.service", comp, port)
+	if err := spec.CheckConfigConflict(exec, sysCfg, unit); err != nil {
+		return err
+	}
+	tgt := filepath.Join(m.globOpts.RemoteTmpDirOf(m.host), comp+"_"+uuid.New().String()+".service")
 	if err := exec.Transfer(sysCfg, tgt, false); err != nil {
 		return err
 	}
-	if outp, errp, err := exec.Execute(fmt.Sprintf("mv %s /etc/systemd/system/%s-%d.service", tgt, comp, port), true); err != nil {
+	if outp, errp, err := exec.Execute(fmt.Sprintf("mv %s %s", tgt, unit), true); err != nil {
 		if len(outp) > 0 {
 			fmt.Println(string(outp))
 		}
@@ -113,7 +126,7 @@ func (m *MonitoredConfig) syncMonitoredSystemConfig(exec executor.Executor, comp
 
 func (m *MonitoredConfig) syncMonitoredScript(exec executor.Executor, comp string, cfg template.ConfigGenerator) error {
 	fp := filepath.Join(m.paths.Cache, fmt.Sprintf("run_%s_%s.sh", comp, m.host))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := spec.RenderTemplate(cfg, m.name, m.clusterVersion, comp, fmt.Sprintf("run_%s.sh.tpl", comp), fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(m.paths.Deploy, "scripts", fmt.Sprintf("run_%s.sh", comp))
@@ -129,7 +142,7 @@ func (m *MonitoredConfig) syncMonitoredScript(exec executor.Executor, comp strin
 
 func (m *MonitoredConfig) syncBlackboxConfig(exec executor.Executor, cfg template.ConfigGenerator) error {
 	fp := filepath.Join(m.paths.Cache, fmt.Sprintf("blackbox_%s.yaml", m.host))
-	if err := cfg.ConfigToFile(fp); err != nil {
+	if err := spec.RenderTemplate(cfg, m.name, m.clusterVersion, spec.ComponentBlackboxExporter, "blackbox.yml", fp); err != nil {
 		return err
 	}
 	dst := filepath.Join(m.paths.Deploy, "conf", "blackbox.yml")
@@ -146,3 +159,8 @@ func (m *MonitoredConfig) String() string {
 	return fmt.Sprintf("MonitoredConfig: cluster=%s, user=%s, node_exporter_port=%d, blackbox_exporter_port=%d, %v",
 		m.name, m.deployUser, m.options.NodeExporterPort, m.options.BlackboxExporterPort, m.paths)
 }
+
+// Hosts implements the HostTask interface
+func (m *MonitoredConfig) Hosts() []string {
+	return []string{m.host}
+}
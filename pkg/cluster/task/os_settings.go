@@ -0,0 +1,145 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/set"
+	"go.uber.org/zap"
+)
+
+// ApplyOSSettings renders the OS settings of the cluster into drop-in files
+// on the host and applies them. Files not changed are skipped by comparing
+// the hashes of the contents, so it's safe to be applied repeatedly. The
+// drop-in files of the cluster no longer rendered, e.g., the settings are
+// dropped from the topology, are removed.
+type ApplyOSSettings struct {
+	host        string
+	clusterName string
+	settings    *spec.OSSettings
+}
+
+// Execute implements the Task interface
+func (a *ApplyOSSettings) Execute(ctx *Context) error {
+	e, ok := ctx.GetExecutor(a.host)
+	if !ok {
+		return ErrNoExecutor
+	}
+
+	var files []spec.OSSettingsDropIn
+	if a.settings != nil {
+		files = a.settings.DropIns(a.clusterName)
+	}
+	for _, file := range files {
+		sum := sha256.Sum256(file.Content)
+		stdout, _, err := e.Execute(fmt.Sprintf("sha256sum %s 2>/dev/null || true", file.Path), true)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if fields := strings.Fields(string(stdout)); len(fields) > 0 && fields[0] == hex.EncodeToString(sum[:]) {
+			zap.L().Debug("OS settings file not changed", zap.String("host", a.host), zap.String("file", file.Path))
+			continue
+		}
+
+		local, err := ioutil.TempFile("", "tiup-os-settings-*")
+		if err != nil {
+			return errors.Trace(err)
+		}
+		_, err = local.Write(file.Content)
+		local.Close()
+		// the drop-in dirs are owned by root, so the file is copied to a
+		// temp path first and moved to the dir with sudo
+		tmp := filepath.Join(ctx.RemoteTmpDir(a.host), fmt.Sprintf("tiup-%s-%s", a.clusterName, filepath.Base(filepath.Dir(file.Path))))
+		if err == nil {
+			err = e.Transfer(local.Name(), tmp, false)
+		}
+		os.Remove(local.Name())
+		if err != nil {
+			return errors.Annotatef(err, "failed to transfer %s", file.Path)
+		}
+		cmd := fmt.Sprintf("mkdir -p %s && mv %s %s && chown root:root %s && chmod 644 %s",
+			filepath.Dir(file.Path), tmp, file.Path, file.Path, file.Path)
+		if file.Reload != "" {
+			cmd += " && " + file.Reload
+		}
+		stdout, stderr, err := e.Execute(cmd, true)
+		ctx.SetOutputs(a.host, stdout, stderr)
+		if err != nil {
+			return errors.Annotatef(err, "failed to apply %s: %s", file.Path, stderr)
+		}
+	}
+	return a.removeStale(ctx, e, files)
+}
+
+// removeStale removes the drop-in files of the cluster not in files, the
+// kernel parameters are reloaded if the sysctl one is removed
+func (a *ApplyOSSettings) removeStale(ctx *Context, e executor.Executor, files []spec.OSSettingsDropIn) error {
+	wanted := set.NewStringSet()
+	for _, file := range files {
+		wanted.Insert(file.Path)
+	}
+	var stale []string
+	for _, path := range spec.OSSettingsDropInPaths(a.clusterName) {
+		if !wanted.Exist(path) {
+			stale = append(stale, path)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	// the paths of the files removed are printed
+	cmd := fmt.Sprintf("for f in %s; do if [ -e $f ]; then rm -f $f && echo $f; fi; done", strings.Join(stale, " "))
+	stdout, stderr, err := e.Execute(cmd, true)
+	if err != nil {
+		return errors.Annotatef(err, "failed to remove the stale OS settings files: %s", stderr)
+	}
+	for _, path := range strings.Fields(string(stdout)) {
+		zap.L().Debug("OS settings file removed", zap.String("host", a.host), zap.String("file", path))
+		if filepath.Dir(path) != spec.SysctlDropInDir {
+			continue
+		}
+		stdout, stderr, err := e.Execute("sysctl --system >/dev/null", true)
+		ctx.SetOutputs(a.host, stdout, stderr)
+		if err != nil {
+			return errors.Annotatef(err, "failed to reload the kernel parameters: %s", stderr)
+		}
+	}
+	return nil
+}
+
+// Rollback implements the Task interface
+func (a *ApplyOSSettings) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (a *ApplyOSSettings) String() string {
+	return fmt.Sprintf("ApplyOSSettings: host=%s, cluster=%s", a.host, a.clusterName)
+}
+
+// Hosts implements the HostTask interface
+func (a *ApplyOSSettings) Hosts() []string {
+	return []string{a.host}
+}
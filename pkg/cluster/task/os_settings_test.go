@@ -0,0 +1,60 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+)
+
+type osSettingsSuite struct{}
+
+var _ = check.Suite(&osSettingsSuite{})
+
+func (s *osSettingsSuite) TestRemoveStaleDropIns(c *check.C) {
+	settings := &spec.OSSettings{Limits: []spec.LimitEntry{{Domain: "tidb", Type: "-", Item: "nofile", Value: "1000000"}}}
+	limits := settings.DropIns("test")[0]
+	sum := sha256.Sum256(limits.Content)
+	paths := spec.OSSettingsDropInPaths("test")
+
+	// the limits file is up to date, the sysctl one is left by the
+	// settings dropped, so it's removed and the kernel parameters reloaded
+	e := executor.NewFake("host")
+	e.Respond("sha256sum "+limits.Path, hex.EncodeToString(sum[:])+"  "+limits.Path+"\n", "")
+	e.Respond("for f in", paths[0]+"\n", "")
+	ctx := NewContext()
+	ctx.SetExecutor("host", e)
+	t := &ApplyOSSettings{host: "host", clusterName: "test", settings: settings}
+	c.Assert(t.Execute(ctx), check.IsNil)
+	cmds := e.Commands()
+	c.Assert(cmds, check.HasLen, 3)
+	c.Assert(cmds[1], check.Equals, "for f in "+paths[0]+" "+paths[2]+
+		"; do if [ -e $f ]; then rm -f $f && echo $f; fi; done")
+	c.Assert(cmds[2], check.Equals, "sysctl --system >/dev/null")
+
+	// all the files are removed without settings, nothing to reload if
+	// none of them exists
+	e = executor.NewFake("host")
+	ctx.SetExecutor("host", e)
+	t = &ApplyOSSettings{host: "host", clusterName: "test"}
+	c.Assert(t.Execute(ctx), check.IsNil)
+	cmds = e.Commands()
+	c.Assert(cmds, check.HasLen, 1)
+	c.Assert(strings.HasPrefix(cmds[0], "for f in "+strings.Join(paths, " ")+";"), check.IsTrue)
+}
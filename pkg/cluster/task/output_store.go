@@ -0,0 +1,191 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"container/list"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// the default limits of the outputs of hosts retained in a context
+const (
+	// DefaultOutputLimit is the max total size of outputs kept in memory
+	DefaultOutputLimit = 64 << 20
+	// DefaultOutputSpillThreshold is the size above which an output is
+	// written to a file instead of being kept in memory
+	DefaultOutputSpillThreshold = 1 << 20
+)
+
+// OutputUsage is the usage of memory by the outputs of hosts retained in a context
+type OutputUsage struct {
+	Current int64 `json:"current"` // bytes of outputs kept in memory
+	Peak    int64 `json:"peak"`    // max bytes of outputs kept in memory ever
+	Evicted int   `json:"evicted"` // outputs moved out of memory to keep under the limit
+	Spilled int   `json:"spilled"` // outputs written to files
+}
+
+// hostOutput is the outputs of the last command executed on a host
+type hostOutput struct {
+	host       string
+	stdout     []byte
+	stderr     []byte
+	stdoutFile string // the file stdout is spilled to, if any
+	stderrFile string
+	elem       *list.Element
+}
+
+func (o *hostOutput) size() int64 {
+	return int64(len(o.stdout) + len(o.stderr))
+}
+
+// outputStore retains the outputs of hosts. The total size of outputs in
+// memory is capped by limit, the least recently used outputs are evicted
+// when it's exceeded. Outputs larger than spillThreshold and the evicted ones
+// are written to temp files under spillDir, so they can still be read, and
+// referenced by their paths. Outputs are dropped when evicted if spillDir is
+// not set. Zero limits mean unlimited. It's not thread-safe.
+type outputStore struct {
+	limit          int64
+	spillThreshold int64
+	spillDir       string
+
+	outputs map[string]*hostOutput
+	lru     *list.List
+	usage   OutputUsage
+}
+
+func newOutputStore() *outputStore {
+	return &outputStore{
+		outputs: make(map[string]*hostOutput),
+		lru:     list.New(),
+	}
+}
+
+// spill writes data to a temp file under the spill dir
+func (s *outputStore) spill(host, stream string, data []byte) (string, error) {
+	if err := os.MkdirAll(s.spillDir, 0755); err != nil {
+		return "", err
+	}
+	name := strings.NewReplacer("/", "_", ":", "_").Replace(host)
+	f, err := ioutil.TempFile(s.spillDir, "output-"+name+"-*."+stream)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// spillOutput moves the outputs in memory to files, they are kept in
+// memory if failed to write the files
+func (s *outputStore) spillOutput(o *hostOutput) bool {
+	if s.spillDir == "" {
+		return false
+	}
+	stdoutFile, err := s.spill(o.host, "stdout", o.stdout)
+	if err == nil {
+		var stderrFile string
+		if stderrFile, err = s.spill(o.host, "stderr", o.stderr); err == nil {
+			s.usage.Current -= o.size()
+			o.stdout, o.stderr = nil, nil
+			o.stdoutFile, o.stderrFile = stdoutFile, stderrFile
+			s.usage.Spilled++
+			return true
+		}
+		_ = os.Remove(stdoutFile)
+	}
+	zap.L().Warn("Failed to spill outputs to file", zap.String("host", o.host), zap.Error(err))
+	return false
+}
+
+// remove drops the outputs, the files spilled to are kept, as they may be
+// referenced by reports, the owner of the spill dir removes them
+func (s *outputStore) remove(o *hostOutput) {
+	s.usage.Current -= o.size()
+	if o.elem != nil {
+		s.lru.Remove(o.elem)
+	}
+	delete(s.outputs, o.host)
+}
+
+// evict moves the least recently used outputs out of memory until the total
+// size is under the limit, the last output set is never evicted
+func (s *outputStore) evict() {
+	for s.limit > 0 && s.usage.Current > s.limit && s.lru.Len() > 1 {
+		o := s.lru.Front().Value.(*hostOutput)
+		s.lru.Remove(o.elem)
+		o.elem = nil
+		s.usage.Evicted++
+		if !s.spillOutput(o) {
+			zap.L().Debug("Outputs evicted", zap.String("host", o.host), zap.Int64("size", o.size()))
+			s.remove(o)
+		}
+	}
+}
+
+func (s *outputStore) set(host string, stdout, stderr []byte) {
+	if old, ok := s.outputs[host]; ok {
+		s.remove(old)
+	}
+
+	o := &hostOutput{host: host, stdout: stdout, stderr: stderr}
+	s.outputs[host] = o
+	s.usage.Current += o.size()
+	if s.spillThreshold <= 0 || o.size() <= s.spillThreshold || !s.spillOutput(o) {
+		o.elem = s.lru.PushBack(o)
+		s.evict()
+	}
+	if s.usage.Current > s.usage.Peak {
+		s.usage.Peak = s.usage.Current
+	}
+}
+
+// files returns the files the outputs of the host are spilled to, empty
+// strings are returned if they are kept in memory
+func (s *outputStore) files(host string) (string, string) {
+	if o, ok := s.outputs[host]; ok {
+		return o.stdoutFile, o.stderrFile
+	}
+	return "", ""
+}
+
+// get returns the outputs of the host, spilled outputs are read from files
+func (s *outputStore) get(host string) ([]byte, []byte, bool) {
+	o, ok := s.outputs[host]
+	if !ok {
+		return nil, nil, false
+	}
+	if o.elem != nil {
+		s.lru.MoveToBack(o.elem)
+		return o.stdout, o.stderr, true
+	}
+
+	stdout, err := ioutil.ReadFile(o.stdoutFile)
+	if err != nil {
+		zap.L().Warn("Failed to read spilled outputs", zap.String("file", o.stdoutFile), zap.Error(err))
+		return nil, nil, false
+	}
+	stderr, err := ioutil.ReadFile(o.stderrFile)
+	if err != nil {
+		zap.L().Warn("Failed to read spilled outputs", zap.String("file", o.stderrFile), zap.Error(err))
+		return nil, nil, false
+	}
+	return stdout, stderr, true
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+
+	"github.com/pingcap/check"
+)
+
+type outputStoreSuite struct{}
+
+var _ = check.Suite(&outputStoreSuite{})
+
+func (s *outputStoreSuite) TestEvict(c *check.C) {
+	ctx := NewContext()
+	ctx.SetOutputLimits(10, 0, "")
+	ctx.SetOutputs("host1", []byte("12345"), nil)
+	ctx.SetOutputs("host2", []byte("12345"), nil)
+	// host1 is used recently, so host2 is evicted
+	_, _, ok := ctx.GetOutputs("host1")
+	c.Assert(ok, check.IsTrue)
+	ctx.SetOutputs("host3", []byte("123"), nil)
+
+	_, _, ok = ctx.GetOutputs("host2")
+	c.Assert(ok, check.IsFalse)
+	stdout, _, ok := ctx.GetOutputs("host1")
+	c.Assert(ok, check.IsTrue)
+	c.Assert(string(stdout), check.Equals, "12345")
+	c.Assert(ctx.OutputUsage(), check.DeepEquals, OutputUsage{Current: 8, Peak: 10, Evicted: 1})
+
+	// the last output is kept even if it exceeds the limit
+	ctx.SetOutputs("host4", bytes.Repeat([]byte("x"), 20), nil)
+	_, _, ok = ctx.GetOutputs("host4")
+	c.Assert(ok, check.IsTrue)
+	c.Assert(ctx.OutputUsage().Current, check.Equals, int64(20))
+}
+
+func (s *outputStoreSuite) TestSpill(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-output-test")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+
+	ctx := NewContext()
+	ctx.SetOutputLimits(10, 8, dir)
+	ctx.SetOutputs("10.0.1.1", []byte("123456789"), []byte("err"))
+	stdoutFile, stderrFile := ctx.GetOutputFiles("10.0.1.1")
+	c.Assert(stdoutFile, check.Not(check.Equals), "")
+	data, err := ioutil.ReadFile(stderrFile)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "err")
+
+	stdout, stderr, ok := ctx.GetOutputs("10.0.1.1")
+	c.Assert(ok, check.IsTrue)
+	c.Assert(string(stdout), check.Equals, "123456789")
+	c.Assert(string(stderr), check.Equals, "err")
+
+	// evicted outputs are spilled too
+	ctx.SetOutputs("10.0.1.2", []byte("123456"), nil)
+	ctx.SetOutputs("10.0.1.3", []byte("123456"), nil)
+	stdoutFile, _ = ctx.GetOutputFiles("10.0.1.2")
+	c.Assert(stdoutFile, check.Not(check.Equals), "")
+	stdout, _, ok = ctx.GetOutputs("10.0.1.2")
+	c.Assert(ok, check.IsTrue)
+	c.Assert(string(stdout), check.Equals, "123456")
+	c.Assert(ctx.OutputUsage(), check.DeepEquals, OutputUsage{Current: 6, Peak: 6, Evicted: 1, Spilled: 2})
+}
@@ -0,0 +1,260 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/utils"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// PackageCacheDir is the default cache directory of packages on hosts, relative
+// to the home directory of the deploy user
+const PackageCacheDir = ".tiup/packages"
+
+// remotePackageCacheDir is the cache directory of packages under the remote
+// tmp dir of the host if the topology sets one
+const remotePackageCacheDir = "tiup-packages"
+
+// PackageCache is the host-level cache of component packages. A package pushed
+// to a host is kept in the cache directory of the host, and reused instead of
+// being pushed again if its checksum matches the local one. If seed hosts are
+// specified, the packages are pushed to the seed hosts first, and other hosts
+// fetch them from a seed host rather than from the control machine. The hosts
+// log in to the seed hosts by the agent forwarded by the control machine, the
+// private key is never placed on them, see fetchFromSeed.
+type PackageCache struct {
+	Dir      string         // the cache directory on hosts, see dirOf
+	User     string         // the user to fetch packages from seed hosts
+	Identity string         // the private key of User to log in to seed hosts
+	Seeds    map[string]int // seed host -> ssh-port
+
+	mu        sync.Mutex
+	checksums map[string]string      // local package -> checksum
+	locks     map[string]*sync.Mutex // host and cache path -> the lock of it
+}
+
+// seedFetchTimeout is the timeout of fetching a package from a seed host
+const seedFetchTimeout = 10 * time.Minute
+
+// NewPackageCache creates a PackageCache
+func NewPackageCache(dir, user, identity string, seeds map[string]int) *PackageCache {
+	return &PackageCache{
+		Dir:       dir,
+		User:      user,
+		Identity:  identity,
+		Seeds:     seeds,
+		checksums: make(map[string]string),
+		locks:     make(map[string]*sync.Mutex),
+	}
+}
+
+// SeedOf returns the seed host which host fetches packages from, chosen by the
+// hash of host. An empty string is returned if there is no seed host or host
+// is a seed host itself.
+func (c *PackageCache) SeedOf(host string) (string, int) {
+	if len(c.Seeds) == 0 {
+		return "", 0
+	}
+	if _, isSeed := c.Seeds[host]; isSeed {
+		return "", 0
+	}
+	seeds := make([]string, 0, len(c.Seeds))
+	for seed := range c.Seeds {
+		seeds = append(seeds, seed)
+	}
+	sort.Strings(seeds)
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(host))
+	seed := seeds[h.Sum32()%uint32(len(seeds))]
+	return seed, c.Seeds[seed]
+}
+
+// dirOf returns the cache directory on the host, it's under the remote tmp
+// dir of the host if the topology sets one, see Context.SetRemoteTmpDirs
+func (c *PackageCache) dirOf(ctx *Context, host string) string {
+	if ctx.hasRemoteTmpDir(host) {
+		return path.Join(ctx.RemoteTmpDir(host), remotePackageCacheDir)
+	}
+	return c.Dir
+}
+
+// checksum returns the checksum of the local package, it's only calculated once
+func (c *PackageCache) checksum(srcPath string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if sum, ok := c.checksums[srcPath]; ok {
+		return sum, nil
+	}
+	sum, err := utils.Checksum(srcPath)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	c.checksums[srcPath] = sum
+	return sum, nil
+}
+
+// lockOf returns the lock of the package in the cache of the host, the
+// instances on a host install the same package at the same time
+func (c *PackageCache) lockOf(host, cachePath string) *sync.Mutex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := host + ":" + cachePath
+	l, ok := c.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		c.locks[key] = l
+	}
+	return l
+}
+
+// cached returns whether the package in the cache of the host matches the checksum
+func (c *PackageCache) cached(exec executor.Executor, cachePath, checksum string) bool {
+	stdout, _, err := exec.Execute(fmt.Sprintf("sha1sum %s 2>/dev/null", cachePath), false)
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(stdout))
+	return len(fields) > 0 && fields[0] == checksum
+}
+
+// ensure makes the package present in the cache of host and returns its path
+// on the host. The package is fetched from the seed host of host if fromSeed
+// is set, and pushed from the control machine if it fails.
+func (c *PackageCache) ensure(ctx *Context, exec executor.Executor, host, srcPath string, fromSeed bool, transfer TransferOptions) (string, error) {
+	checksum, err := c.checksum(srcPath)
+	if err != nil {
+		return "", err
+	}
+
+	dir := c.dirOf(ctx, host)
+	cachePath := path.Join(dir, path.Base(srcPath))
+	// the package, its temporary copy and chunks are only written by one
+	// task at a time, the others wait and reuse it
+	l := c.lockOf(host, cachePath)
+	l.Lock()
+	defer l.Unlock()
+
+	if _, stderr, err := exec.Execute(fmt.Sprintf("mkdir -p %s", dir), false); err != nil {
+		return "", errors.Annotatef(err, "stderr: %s", string(stderr))
+	}
+	if c.cached(exec, cachePath, checksum) {
+		log.Debugf("Reuse the cached package %s on %s", cachePath, host)
+		return cachePath, nil
+	}
+
+	if seed, port := c.SeedOf(host); fromSeed && seed != "" {
+		err := c.fetchFromSeed(ctx, exec, seed, port, path.Base(srcPath), cachePath, checksum)
+		if err == nil {
+			return cachePath, nil
+		}
+		log.Warnf("Failed to fetch %s from seed host %s to %s, push it from the control machine instead: %s",
+			path.Base(srcPath), seed, host, err)
+	}
+
+	if err := transferFile(exec, host, srcPath, cachePath, transfer); err != nil {
+		return "", errors.Annotatef(err, "failed to scp %s to %s:%s", srcPath, host, cachePath)
+	}
+	if !c.cached(exec, cachePath, checksum) {
+		_, _, _ = exec.Execute(fmt.Sprintf("rm -f %s", cachePath), false)
+		return "", errors.Errorf("the checksum of %s:%s doesn't match %s after being pushed", host, cachePath, srcPath)
+	}
+	return cachePath, nil
+}
+
+// fetchFromSeed copies the package from the cache of the seed host to the
+// cache of the host. The host logs in to the seed host by the agent holding
+// the private key forwarded by the control machine, and only trusts the host
+// key of the seed host verified by the control machine. The package is only
+// put in the cache if its checksum matches.
+func (c *PackageCache) fetchFromSeed(ctx *Context, exec executor.Executor, seed string, port int, name, cachePath, checksum string) error {
+	forwarder, ok := exec.(executor.AgentForwarder)
+	if !ok {
+		return errors.New("the executor can't forward an SSH agent")
+	}
+	hostKey := ctx.hostKey(seed)
+	if hostKey == nil {
+		return errors.Errorf("the host key of %s is not verified", seed)
+	}
+
+	knownHosts := cachePath + ".known_hosts"
+	defer func() {
+		_, _, _ = exec.Execute(fmt.Sprintf("rm -f %s %s.tmp", knownHosts, cachePath), false)
+	}()
+	line := knownhosts.Line([]string{knownhosts.Normalize(net.JoinHostPort(seed, strconv.Itoa(port)))}, hostKey)
+	if _, stderr, err := exec.Execute(fmt.Sprintf("echo %s > %s", utils.ShellQuote(line), knownHosts), false); err != nil {
+		return errors.Annotatef(err, "stderr: %s", strings.TrimSpace(string(stderr)))
+	}
+
+	seedPath := path.Join(c.dirOf(ctx, seed), name)
+	cmd := fmt.Sprintf(
+		"scp -q -o BatchMode=yes -o StrictHostKeyChecking=yes -o UserKnownHostsFile=%[1]s -P %[2]d %[3]s@%[4]s:%[5]s %[6]s.tmp",
+		knownHosts, port, c.User, seed, seedPath, cachePath)
+	if _, stderr, err := forwarder.ExecuteWithAgent(cmd, c.Identity, seedFetchTimeout); err != nil {
+		return errors.Annotatef(err, "stderr: %s", strings.TrimSpace(string(stderr)))
+	}
+	if !c.cached(exec, cachePath+".tmp", checksum) {
+		return errors.Errorf("the checksum of %s fetched from %s doesn't match", name, seed)
+	}
+	if _, stderr, err := exec.Execute(fmt.Sprintf("mv %[1]s.tmp %[1]s", cachePath), false); err != nil {
+		return errors.Annotatef(err, "stderr: %s", strings.TrimSpace(string(stderr)))
+	}
+	return nil
+}
+
+// CachePackage is used to push a package to the cache of a host only, it's
+// used to push packages to seed hosts before other hosts fetch them
+type CachePackage struct {
+	srcPath  string
+	host     string
+	cache    *PackageCache
+	transfer TransferOptions
+}
+
+// Execute implements the Task interface
+func (c *CachePackage) Execute(ctx *Context) error {
+	exec, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+	_, err := c.cache.ensure(ctx, exec, c.host, c.srcPath, false, c.transfer)
+	return err
+}
+
+// Rollback implements the Task interface
+func (c *CachePackage) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CachePackage) String() string {
+	return fmt.Sprintf("CachePackage: srcPath=%s, remote=%s:%s", c.srcPath, c.host, c.cache.Dir)
+}
+
+// Hosts implements the HostTask interface
+func (c *CachePackage) Hosts() []string {
+	return []string{c.host}
+}
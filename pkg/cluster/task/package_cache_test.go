@@ -0,0 +1,196 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// corruptingExecutor pushes the files with their contents corrupted
+type corruptingExecutor struct {
+	shellExecutor
+}
+
+func (e *corruptingExecutor) Transfer(src string, dst string, download bool) error {
+	atomic.AddInt32(&e.transfers, 1)
+	return ioutil.WriteFile(dst, []byte("corrupted"), 0644)
+}
+
+// agentExecutor runs the commands with an agent forwarded by the local shell
+type agentExecutor struct {
+	shellExecutor
+	keyFiles []string
+}
+
+func (e *agentExecutor) ExecuteWithAgent(cmd, keyFile string, timeout time.Duration) ([]byte, []byte, error) {
+	e.keyFiles = append(e.keyFiles, keyFile)
+	return e.Execute("SSH_AUTH_SOCK=/tmp/agent.sock "+cmd, false, timeout)
+}
+
+type packageCacheSuite struct{}
+
+var _ = check.Suite(&packageCacheSuite{})
+
+func (s *packageCacheSuite) TestFetchFromSeed(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-package-cache-test")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+
+	// the scp shim copies the files of the seed host under dir/seed, it
+	// requires the agent forwarded, and records the known hosts it's given
+	bin := filepath.Join(dir, "bin")
+	seedRoot := filepath.Join(dir, "seed")
+	knownLog := filepath.Join(dir, "known_hosts.log")
+	shim := `#!/bin/sh
+[ -n "$SSH_AUTH_SOCK" ] || exit 1
+strict=""
+known=""
+while [ $# -gt 2 ]; do
+	case "$1" in
+	-i) exit 1 ;;
+	StrictHostKeyChecking=yes) strict=1 ;;
+	UserKnownHostsFile=*) known="${1#UserKnownHostsFile=}" ;;
+	esac
+	shift
+done
+[ -n "$strict" ] && [ -f "$known" ] || exit 1
+cat "$known" >> ` + knownLog + `
+cp "` + seedRoot + `${1#*:}" "$2"
+`
+	c.Assert(os.MkdirAll(bin, 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(bin, "scp"), []byte(shim), 0755), check.IsNil)
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+
+	identity := filepath.Join(dir, "id_rsa")
+	src := filepath.Join(dir, "tidb-v4.0.0-linux-amd64.tar.gz")
+	c.Assert(ioutil.WriteFile(src, []byte("package"), 0644), check.IsNil)
+	cacheDir := filepath.Join(dir, "home", PackageCacheDir)
+	cachePath := filepath.Join(cacheDir, filepath.Base(src))
+	seedPath := seedRoot + cachePath
+	c.Assert(os.MkdirAll(filepath.Dir(seedPath), 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(seedPath, []byte("package"), 0644), check.IsNil)
+
+	// the host key of the seed host is verified by the control machine
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	c.Assert(err, check.IsNil)
+	hostKey, err := ssh.NewPublicKey(pub)
+	c.Assert(err, check.IsNil)
+	ctx := NewContext()
+	ctx.SetHostKeyVerifier(spec.NewHostKeyVerifier("test", nil, func(cfg executor.SSHConfig) (ssh.PublicKey, error) {
+		return hostKey, nil
+	}))
+	c.Assert(ctx.verifyHostKey(&executor.SSHConfig{Host: "seed", Port: 22}), check.IsNil)
+
+	cache := NewPackageCache(cacheDir, "tidb", identity, map[string]int{"seed": 22})
+	e := &agentExecutor{}
+	path, err := cache.ensure(ctx, e, "host", src, true, TransferOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(path, check.Equals, cachePath)
+	data, err := ioutil.ReadFile(cachePath)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "package")
+	c.Assert(e.keyFiles, check.DeepEquals, []string{identity})
+	known, err := ioutil.ReadFile(knownLog)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(known), check.Equals, knownhosts.Line([]string{"seed"}, hostKey)+"\n")
+	// nothing is pushed, and only the package is left in the cache
+	c.Assert(atomic.LoadInt32(&e.transfers), check.Equals, int32(0))
+	files, err := ioutil.ReadDir(cacheDir)
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.HasLen, 1)
+
+	// the package is pushed from the control machine if the seed host isn't
+	// verified, or the agent can't be forwarded
+	for _, t := range []struct {
+		ctx  *Context
+		exec executor.Executor
+	}{{NewContext(), &agentExecutor{}}, {ctx, &shellExecutor{}}} {
+		c.Assert(os.Remove(cachePath), check.IsNil)
+		_, err = cache.ensure(t.ctx, t.exec, "host", src, true, TransferOptions{})
+		c.Assert(err, check.IsNil)
+		data, err = ioutil.ReadFile(cachePath)
+		c.Assert(err, check.IsNil)
+		c.Assert(string(data), check.Equals, "package")
+	}
+	c.Assert(e.keyFiles, check.HasLen, 1)
+
+	// the package corrupted on the seed host is pushed from the control
+	// machine instead
+	c.Assert(os.Remove(cachePath), check.IsNil)
+	c.Assert(ioutil.WriteFile(seedPath, []byte("corrupted"), 0644), check.IsNil)
+	_, err = cache.ensure(ctx, e, "host", src, true, TransferOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(atomic.LoadInt32(&e.transfers), check.Equals, int32(1))
+	data, err = ioutil.ReadFile(cachePath)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "package")
+	files, err = ioutil.ReadDir(cacheDir)
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.HasLen, 1)
+
+	// the package corrupted in pushing is not left in the cache
+	c.Assert(os.Remove(cachePath), check.IsNil)
+	_, err = cache.ensure(NewContext(), &corruptingExecutor{}, "seed", src, false, TransferOptions{})
+	c.Assert(err, check.NotNil)
+	c.Assert(strings.Contains(err.Error(), "checksum"), check.IsTrue, check.Commentf("%v", err))
+	_, err = os.Stat(cachePath)
+	c.Assert(os.IsNotExist(err), check.IsTrue)
+}
+
+func (s *packageCacheSuite) TestEnsureConcurrently(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-package-cache-test")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "tidb-v4.0.0-linux-amd64.tar.gz")
+	c.Assert(ioutil.WriteFile(src, bytes.Repeat([]byte("package"), 1000), 0644), check.IsNil)
+	cacheDir := filepath.Join(dir, "home", PackageCacheDir)
+	cache := NewPackageCache(cacheDir, "tidb", "", nil)
+
+	// the tasks copying the package to the same host push it once
+	e := &shellExecutor{}
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = cache.ensure(NewContext(), e, "host", src, false, TransferOptions{ChunkSize: 1024, Parallel: 2})
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		c.Assert(err, check.IsNil)
+	}
+	c.Assert(atomic.LoadInt32(&e.transfers), check.Equals, int32(7))
+	files, err := ioutil.ReadDir(cacheDir)
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.HasLen, 1)
+}
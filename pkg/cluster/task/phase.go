@@ -0,0 +1,147 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+)
+
+// Phase is what a step is doing, so it can be rendered appropriately
+type Phase string
+
+// the phases of steps reported by the built-in tasks
+const (
+	PhaseDownloading Phase = "downloading"
+	PhaseCopying     Phase = "copying"
+	PhaseWaiting     Phase = "waiting"
+	PhaseVerifying   Phase = "verifying"
+	// PhasePaused is reported when the task pauses at a breakpoint, see SetBreakpoints
+	PhasePaused Phase = "paused"
+)
+
+// SetPhase reports the phase of the task t, with an optional detail of it,
+// e.g., "43MB/512MB" or "waiting for port 20160"
+func (ctx *Context) SetPhase(t Task, phase Phase, detail string) {
+	ctx.ev.PublishTaskPhase(t, phase, detail)
+}
+
+// ReportDelay implements the operator.DelayReporter interface
+func (ctx *Context) ReportDelay(d time.Duration) {
+	atomic.AddInt64(&ctx.addedDelay, int64(d))
+}
+
+// AddedDelay returns the total time the tasks are delayed on purpose, e.g.,
+// the instances waiting for their turns to start, see operator.StaggerSchedule
+func (ctx *Context) AddedDelay() time.Duration {
+	return time.Duration(atomic.LoadInt64(&ctx.addedDelay))
+}
+
+// PhaseGetter returns an operator.ExecutorGetter of the context, the phases
+// reported by the operator functions using it are reported as ones of t
+func (ctx *Context) PhaseGetter(t Task) operator.ExecutorGetter {
+	return &phaseGetter{Context: ctx, t: t}
+}
+
+type phaseGetter struct {
+	*Context
+	t Task
+}
+
+// ReportWaiting implements the operator.PhaseReporter interface
+func (g *phaseGetter) ReportWaiting(detail string) {
+	g.SetPhase(g.t, PhaseWaiting, detail)
+}
+
+// ReportProgress implements the operator.ProgressReporter interface
+func (g *phaseGetter) ReportProgress(percent int) {
+	g.SetProgress(g.t, percent)
+}
+
+// Break implements the operator.Breaker interface
+func (g *phaseGetter) Break(step string) {
+	g.breakAt(g.t, step)
+}
+
+// CheckDeadline implements the operator.DeadlineChecker interface
+func (g *phaseGetter) CheckDeadline(step string) error {
+	return g.checkDeadlineAt(step)
+}
+
+// Deadline implements the operator.DeadlineChecker interface
+func (g *phaseGetter) Deadline() (time.Time, bool) {
+	return g.deadline()
+}
+
+// the minimal interval between two reports of the download progress
+const downloadReportInterval = 500 * time.Millisecond
+
+// downloadProgress reports the progress of downloading a package as the
+// phases of the task
+type downloadProgress struct {
+	ctx *Context
+	t   Task
+
+	mu       sync.Mutex
+	size     int64
+	reported time.Time
+}
+
+// Start implements the operator.DownloadProgress interface
+func (p *downloadProgress) Start(url string, size int64) {
+	p.mu.Lock()
+	p.size = size
+	p.reported = time.Now()
+	p.mu.Unlock()
+	p.ctx.SetPhase(p.t, PhaseDownloading, fmt.Sprintf("%s/%s", formatSize(0), formatSize(size)))
+}
+
+// SetCurrent implements the operator.DownloadProgress interface
+func (p *downloadProgress) SetCurrent(current int64) {
+	p.mu.Lock()
+	if time.Since(p.reported) < downloadReportInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.reported = time.Now()
+	size := p.size
+	p.mu.Unlock()
+	if size > 0 {
+		p.ctx.SetProgress(p.t, int(current*100/size))
+	}
+	p.ctx.SetPhase(p.t, PhaseDownloading, fmt.Sprintf("%s/%s", formatSize(current), formatSize(size)))
+}
+
+// Finish implements the operator.DownloadProgress interface
+func (p *downloadProgress) Finish() {
+	p.mu.Lock()
+	size := p.size
+	p.mu.Unlock()
+	p.ctx.SetProgress(p.t, 100)
+	p.ctx.SetPhase(p.t, PhaseDownloading, fmt.Sprintf("%s/%s", formatSize(size), formatSize(size)))
+}
+
+// Verifying implements the operator.DownloadProgress interface
+func (p *downloadProgress) Verifying() {
+	p.ctx.SetPhase(p.t, PhaseVerifying, "checksum of the cached package")
+}
+
+// formatSize formats the size in bytes in MB, e.g., "43MB"
+func formatSize(size int64) string {
+	return fmt.Sprintf("%dMB", size>>20)
+}
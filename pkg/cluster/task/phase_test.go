@@ -0,0 +1,58 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/pingcap/check"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+)
+
+type phaseSuite struct{}
+
+var _ = check.Suite(&phaseSuite{})
+
+func (s *phaseSuite) TestStepPhase(c *check.C) {
+	type phaseEvent struct {
+		phase  Phase
+		detail string
+	}
+	var events []phaseEvent
+	ctx := NewContext()
+	ctx.Subscribe(EventTaskPhase, func(t Task, phase Phase, detail string) {
+		events = append(events, phaseEvent{phase, detail})
+	})
+
+	var wait *Func
+	wait = NewFunc("wait", func(ctx *Context) error {
+		// the operator functions report phases by the getter
+		getter := ctx.PhaseGetter(wait)
+		getter.(operator.PhaseReporter).ReportWaiting("waiting for port 20160")
+		return nil
+	})
+	step := NewBuilder().Serial(wait).BuildAsStep("  - Start tikv")
+	c.Assert(step.Execute(ctx), check.IsNil)
+	phase, detail := step.Phase()
+	c.Assert(phase, check.Equals, PhaseWaiting)
+	c.Assert(detail, check.Equals, "waiting for port 20160")
+	c.Assert(events, check.DeepEquals, []phaseEvent{{PhaseWaiting, "waiting for port 20160"}})
+
+	// the download progress is reported in MB
+	p := &downloadProgress{ctx: ctx, t: wait}
+	p.Start("/tikv.tar.gz", 512<<20)
+	p.Finish()
+	c.Assert(events[len(events)-2:], check.DeepEquals, []phaseEvent{
+		{PhaseDownloading, "0MB/512MB"},
+		{PhaseDownloading, "512MB/512MB"},
+	})
+}
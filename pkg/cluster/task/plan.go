@@ -0,0 +1,271 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+)
+
+// the formats the plan of a task tree can be written in
+const (
+	PlanFormatText = "text"
+	PlanFormatDOT  = "dot"
+)
+
+// maxPlanLabelLen is the max length of the label of a task in the plan
+const maxPlanLabelLen = 60
+
+// the fill colors of the nodes of components, assigned in the order the
+// components appear in the plan
+var planColors = []string{
+	"lightblue", "palegreen", "lightsalmon", "khaki", "plum",
+	"lightpink", "lightcyan", "wheat", "thistle", "lightgrey",
+}
+
+// WritePlan writes the plan of the task tree t to w in the format, which is
+// one of PlanFormatText and PlanFormatDOT, nothing is executed.
+func WritePlan(w io.Writer, t Task, format string) error {
+	switch format {
+	case PlanFormatText:
+		var b strings.Builder
+		writePlanText(&b, t, 0)
+		_, err := io.WriteString(w, b.String())
+		return err
+	case PlanFormatDOT:
+		d := &dotWriter{colors: make(map[string]string)}
+		d.b.WriteString("digraph plan {\n")
+		d.b.WriteString("  rankdir=LR;\n")
+		d.b.WriteString("  node [shape=box, style=\"rounded,filled\", fillcolor=white];\n")
+		d.walk(t)
+		d.b.WriteString("}\n")
+		_, err := io.WriteString(w, d.b.String())
+		return err
+	default:
+		return fmt.Errorf("unsupported plan format '%s', the supported formats are: %s, %s",
+			format, PlanFormatText, PlanFormatDOT)
+	}
+}
+
+// planLabel returns the first line of the string of t, truncated
+func planLabel(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "\n"); i >= 0 {
+		s = s[:i]
+	}
+	if len(s) > maxPlanLabelLen {
+		s = s[:maxPlanLabelLen-3] + "..."
+	}
+	return s
+}
+
+// componentOf returns the component the task t or its first child operates on
+func componentOf(t Task) string {
+	switch tt := t.(type) {
+	case *CopyComponent:
+		return tt.component
+	case *BackupComponent:
+		return tt.component
+	case *Downloader:
+		return tt.component
+	case *MonitoredConfig:
+		return tt.component
+	case *InitConfig:
+		return tt.instance.ComponentName()
+	case *ScaleConfig:
+		return tt.instance.ComponentName()
+	case *Serial:
+		for _, inner := range tt.inner {
+			if comp := componentOf(inner); comp != "" {
+				return comp
+			}
+		}
+	case *Parallel:
+		for _, inner := range tt.inner {
+			if comp := componentOf(inner); comp != "" {
+				return comp
+			}
+		}
+	case *StepDisplay:
+		return componentOf(tt.inner)
+	}
+	return ""
+}
+
+func writePlanText(b *strings.Builder, t Task, depth int) {
+	indent := strings.Repeat("  ", depth)
+	switch tt := t.(type) {
+	case *Serial:
+		for _, inner := range tt.inner {
+			writePlanText(b, inner, depth)
+		}
+	case *Parallel:
+		if len(tt.inner) == 0 {
+			return
+		}
+		fmt.Fprintf(b, "%s- (parallel)\n", indent)
+		for _, inner := range tt.inner {
+			writePlanText(b, inner, depth+1)
+		}
+	case *StepDisplay:
+		fmt.Fprintf(b, "%s%s\n", indent, planLabel(tt.prefix))
+		writePlanText(b, tt.inner, depth+1)
+	case *ParallelStepDisplay:
+		fmt.Fprintf(b, "%s%s\n", indent, planLabel(tt.prefix))
+		for _, inner := range tt.inner.inner {
+			writePlanText(b, inner, depth+1)
+		}
+	case explainer:
+		fmt.Fprintf(b, "%s- %s\n", indent, planLabel(t.String()))
+		if plan := tt.Explain(); plan != nil {
+			writeOperatorPlanText(b, plan, depth+1)
+		}
+	default:
+		fmt.Fprintf(b, "%s- %s\n", indent, planLabel(t.String()))
+	}
+}
+
+// explainer is implemented by the tasks performing an operation which can be
+// explained without executing it, e.g., operator.Start
+type explainer interface {
+	Explain() *operator.Plan
+}
+
+// writeOperatorPlanText writes the actions of an operation on the units
+func writeOperatorPlanText(b *strings.Builder, plan *operator.Plan, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, g := range plan.Groups {
+		name := g.Component
+		if g.Agents {
+			name = "monitoring agents"
+		}
+		mode := "parallel"
+		if g.Serial || len(g.Actions) == 1 {
+			mode = "serial"
+		}
+		fmt.Fprintf(b, "%s- %s %s (%s)\n", indent, plan.Operation, name, mode)
+		for _, a := range g.Actions {
+			fmt.Fprintf(b, "%s  - %s %s on %s\n", indent, a.Action, a.Unit, a.Host)
+		}
+	}
+}
+
+// dotWriter renders a task tree as a Graphviz digraph, the steps are
+// rendered as clusters, the steps of hosts in parallel steps and other tasks
+// are rendered as nodes, colored by the components they operate on
+type dotWriter struct {
+	b      strings.Builder
+	nextID int
+	colors map[string]string
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func (d *dotWriter) id(prefix string) string {
+	d.nextID++
+	return fmt.Sprintf("%s%d", prefix, d.nextID)
+}
+
+func (d *dotWriter) node(label, comp string) string {
+	id := d.id("t")
+	attrs := "label=" + dotQuote(label)
+	if comp != "" {
+		color, ok := d.colors[comp]
+		if !ok {
+			color = planColors[len(d.colors)%len(planColors)]
+			d.colors[comp] = color
+		}
+		attrs += ", fillcolor=" + color
+	}
+	fmt.Fprintf(&d.b, "  %s [%s];\n", id, attrs)
+	return id
+}
+
+// connect adds the edges from the exits of a task to the entries of the next
+// one, a junction is added between them if both have many nodes
+func (d *dotWriter) connect(from, to []string) {
+	if len(from) > 1 && len(to) > 1 {
+		junction := d.id("j")
+		fmt.Fprintf(&d.b, "  %s [shape=point];\n", junction)
+		d.connect(from, []string{junction})
+		d.connect([]string{junction}, to)
+		return
+	}
+	for _, f := range from {
+		for _, t := range to {
+			fmt.Fprintf(&d.b, "  %s -> %s;\n", f, t)
+		}
+	}
+}
+
+// cluster renders the tasks in a cluster with the label
+func (d *dotWriter) cluster(label string, tasks []Task, asNodes bool) (entries, exits []string) {
+	fmt.Fprintf(&d.b, "  subgraph %s {\n", d.id("cluster_"))
+	fmt.Fprintf(&d.b, "  label=%s;\n", dotQuote(planLabel(label)))
+	for _, t := range tasks {
+		var en, ex []string
+		if sd, ok := t.(*StepDisplay); ok && asNodes {
+			id := d.node(planLabel(strings.TrimLeft(strings.TrimSpace(sd.prefix), "-+ ")), componentOf(sd))
+			en, ex = []string{id}, []string{id}
+		} else {
+			en, ex = d.walk(t)
+		}
+		entries = append(entries, en...)
+		exits = append(exits, ex...)
+	}
+	d.b.WriteString("  }\n")
+	return entries, exits
+}
+
+// walk renders t and returns the nodes it starts and ends with
+func (d *dotWriter) walk(t Task) (entries, exits []string) {
+	switch tt := t.(type) {
+	case *Serial:
+		for _, inner := range tt.inner {
+			en, ex := d.walk(inner)
+			if len(en) == 0 {
+				continue
+			}
+			if entries == nil {
+				entries = en
+			} else {
+				d.connect(exits, en)
+			}
+			exits = ex
+		}
+		return entries, exits
+	case *Parallel:
+		for _, inner := range tt.inner {
+			en, ex := d.walk(inner)
+			entries = append(entries, en...)
+			exits = append(exits, ex...)
+		}
+		return entries, exits
+	case *StepDisplay:
+		return d.cluster(tt.prefix, []Task{tt.inner}, false)
+	case *ParallelStepDisplay:
+		if len(tt.inner.inner) == 0 {
+			return nil, nil
+		}
+		return d.cluster(tt.prefix, tt.inner.inner, true)
+	default:
+		id := d.node(planLabel(t.String()), componentOf(t))
+		return []string{id}, []string{id}
+	}
+}
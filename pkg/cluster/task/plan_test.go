@@ -0,0 +1,92 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/pingcap/check"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+)
+
+type planSuite struct{}
+
+var _ = check.Suite(&planSuite{})
+
+func (s *planSuite) TestWritePlan(c *check.C) {
+	t := NewBuilder().
+		Step("+ Generate SSH keys", NewBuilder().Func("GenerateKeys", nil).Build()).
+		ParallelStep("+ Copy files",
+			NewBuilder().CopyComponent("tikv", "linux", "amd64", "v4.0.0", "", "10.0.1.2", "/deploy").
+				BuildAsStep("  - Copy tikv -> 10.0.1.2"),
+			NewBuilder().CopyComponent("pd", "linux", "amd64", "v4.0.0", "", "10.0.1.1", "/deploy").
+				BuildAsStep("  - Copy pd -> 10.0.1.1"),
+		).
+		Func("StartCluster", nil).
+		Build()
+
+	var buf bytes.Buffer
+	c.Assert(WritePlan(&buf, t, PlanFormatText), check.IsNil)
+	c.Assert(buf.String(), check.Equals, strings.Join([]string{
+		"+ Generate SSH keys",
+		"  - GenerateKeys",
+		"+ Copy files",
+		"  - Copy pd -> 10.0.1.1",
+		"    - " + planLabel(NewBuilder().CopyComponent("pd", "linux", "amd64", "v4.0.0", "", "10.0.1.1", "/deploy").Build().String()),
+		"  - Copy tikv -> 10.0.1.2",
+		"    - " + planLabel(NewBuilder().CopyComponent("tikv", "linux", "amd64", "v4.0.0", "", "10.0.1.2", "/deploy").Build().String()),
+		"- StartCluster",
+		"",
+	}, "\n"))
+
+	buf.Reset()
+	c.Assert(WritePlan(&buf, t, PlanFormatDOT), check.IsNil)
+	dot := buf.String()
+	c.Assert(strings.HasPrefix(dot, "digraph plan {"), check.IsTrue)
+	c.Assert(strings.Count(dot, "subgraph cluster_"), check.Equals, 2)
+	c.Assert(dot, check.Matches, `(?s).*label="Copy pd -> 10.0.1.1", fillcolor=lightblue.*`)
+	c.Assert(dot, check.Matches, `(?s).*label="Copy tikv -> 10.0.1.2", fillcolor=palegreen.*`)
+	// the hosts are joined to the tasks before and after them directly
+	c.Assert(regexp.MustCompile(`(?m)^  t\d+ -> t\d+;$`).FindAllString(dot, -1), check.HasLen, 4)
+
+	c.Assert(WritePlan(&buf, t, "svg"), check.NotNil)
+	c.Assert(planLabel(strings.Repeat("x", 100)), check.HasLen, maxPlanLabelLen)
+}
+
+func (s *planSuite) TestWriteOperatorPlan(c *check.C) {
+	plan := &operator.Plan{Operation: "stop", Groups: []*operator.PlanGroup{
+		{Component: "tikv", Actions: []*operator.PlanAction{
+			{Host: "10.0.1.1", Action: "stop", Unit: "tikv-20160.service"},
+			{Host: "10.0.1.2", Action: "stop", Unit: "tikv-20160.service"},
+		}},
+		{Agents: true, Serial: true, Actions: []*operator.PlanAction{
+			{Host: "10.0.1.2", Action: "stop", Unit: "node_exporter-9100.service"},
+		}},
+	}}
+	t := NewBuilder().Func("StopCluster", nil, WithPlan(plan)).Build()
+
+	var buf bytes.Buffer
+	c.Assert(WritePlan(&buf, t, PlanFormatText), check.IsNil)
+	c.Assert(buf.String(), check.Equals, strings.Join([]string{
+		"- StopCluster",
+		"  - stop tikv (parallel)",
+		"    - stop tikv-20160.service on 10.0.1.1",
+		"    - stop tikv-20160.service on 10.0.1.2",
+		"  - stop monitoring agents (serial)",
+		"    - stop node_exporter-9100.service on 10.0.1.2",
+		"",
+	}, "\n"))
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"sync/atomic"
+)
+
+// Progresser is implemented by the tasks which know how much of them is done,
+// so the progress of a long step keeps moving while it's executing
+type Progresser interface {
+	// Progress returns the percentage of the task done, in [0, 100]
+	Progress() int
+}
+
+// progressOf returns the percentage of t done, it's 0 for the tasks which
+// don't implement Progresser
+func progressOf(t Task) int {
+	p, ok := t.(Progresser)
+	if !ok {
+		return 0
+	}
+	switch percent := p.Progress(); {
+	case percent < 0:
+		return 0
+	case percent > 100:
+		return 100
+	default:
+		return percent
+	}
+}
+
+// taskProgress is embedded by the tasks whose progress is set by what they
+// execute, see Context.SetProgress
+type taskProgress struct {
+	percent int32
+}
+
+// Progress implements the Progresser interface
+func (p *taskProgress) Progress() int {
+	return int(atomic.LoadInt32(&p.percent))
+}
+
+func (p *taskProgress) setProgress(percent int) {
+	atomic.StoreInt32(&p.percent, int32(percent))
+}
+
+// SetProgress sets the percentage of the task t done, it's ignored by the
+// tasks which don't track their progress
+func (ctx *Context) SetProgress(t Task, percent int) {
+	if s, ok := t.(interface{ setProgress(int) }); ok {
+		s.setProgress(percent)
+	}
+}
+
+// Progress returns the percentage done of the outermost task executing, or
+// last executed, with the context, false if no task is executed yet
+func (ctx *Context) Progress() (int, bool) {
+	ctx.root.Lock()
+	t := ctx.root.task
+	ctx.root.Unlock()
+	if t == nil {
+		return 0, false
+	}
+	return progressOf(t), true
+}
+
+// enterRoot records t as the outermost task executing with the context if
+// there is none, it returns true if t is recorded
+func (ctx *Context) enterRoot(t Task) bool {
+	ctx.root.Lock()
+	defer ctx.root.Unlock()
+	if ctx.root.running {
+		return false
+	}
+	ctx.root.task = t
+	ctx.root.running = true
+	return true
+}
+
+// exitRoot marks the outermost task finished executing
+func (ctx *Context) exitRoot() {
+	ctx.root.Lock()
+	ctx.root.running = false
+	ctx.root.Unlock()
+}
+
+// Progress implements the Progresser interface, the partial progress of the
+// inner task executing is counted in
+func (s *Serial) Progress() int {
+	if len(s.inner) == 0 {
+		return 100
+	}
+	done := int(atomic.LoadInt32(&s.done))
+	if done >= len(s.inner) {
+		return 100
+	}
+	return (done*100 + progressOf(s.inner[done])) / len(s.inner)
+}
+
+// Progress implements the Progresser interface
+func (pt *Parallel) Progress() int {
+	if len(pt.inner) == 0 {
+		return 100
+	}
+	pt.finished.Lock()
+	finished := make(map[int]struct{}, len(pt.finished.order))
+	for _, i := range pt.finished.order {
+		finished[i] = struct{}{}
+	}
+	pt.finished.Unlock()
+
+	total := 0
+	for i, t := range pt.inner {
+		if _, ok := finished[i]; ok {
+			total += 100
+		} else {
+			total += progressOf(t)
+		}
+	}
+	return total / len(pt.inner)
+}
+
+// Progress implements the Progresser interface
+func (s *StepDisplay) Progress() int {
+	return progressOf(s.inner)
+}
+
+// Progress implements the Progresser interface
+func (ps *ParallelStepDisplay) Progress() int {
+	return ps.inner.Progress()
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/pingcap/check"
+)
+
+type progressSuite struct{}
+
+var _ = check.Suite(&progressSuite{})
+
+func (s *progressSuite) TestProgress(c *check.C) {
+	noop := func(ctx *Context) error { return nil }
+	ctx := NewContext()
+	_, ok := ctx.Progress()
+	c.Assert(ok, check.IsFalse)
+
+	var percents []int
+	record := func() {
+		p, ok := ctx.Progress()
+		c.Assert(ok, check.IsTrue)
+		percents = append(percents, p)
+	}
+
+	// the long step reports its progress, while the others don't
+	var long *Func
+	long = NewFunc("WaitRegionBalance", func(ctx *Context) error {
+		for _, p := range []int{0, 40, 80} {
+			ctx.SetProgress(long, p)
+			record()
+		}
+		return nil
+	})
+	other := NewFunc("CheckStatus", func(ctx *Context) error {
+		record()
+		return nil
+	})
+	t := NewBuilder().
+		Func("StartCluster", noop).
+		Step("+ Wait region balance", NewBuilder().Serial(long).Build()).
+		Parallel(NewFunc("A", noop), other).
+		Build()
+	c.Assert(t.Execute(ctx), check.IsNil)
+
+	// 1 of the 3 steps is done before the long step, and 2 of them are done
+	// before the parallel step, which counts in its finished tasks
+	c.Assert(percents[:3], check.DeepEquals, []int{33, 46, 60})
+	c.Assert(percents[3] >= 66 && percents[3] < 100, check.IsTrue)
+	p, _ := ctx.Progress()
+	c.Assert(p, check.Equals, 100)
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/google/uuid"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/logger/log"
+)
+
+var (
+	// ErrRemoteTmpNoSpace is returned when the directory to stage files in on
+	// a host doesn't have enough space for them
+	ErrRemoteTmpNoSpace = errNS.NewType("remote_tmp_no_space")
+)
+
+const (
+	// the prefix of the directories files are staged in, under the remote
+	// tmp dirs of the hosts
+	stagingDirPrefix = "tiup-stage-"
+	// the staging directories older than it are left by the operations
+	// interrupted, they are removed when staging files again
+	staleStagingMinutes = 24 * 60
+)
+
+// SetRemoteTmpDirs sets the directories the files are staged in on the hosts,
+// see spec.GlobalOptions.RemoteTmpDirOf
+func (ctx *Context) SetRemoteTmpDirs(opt *spec.GlobalOptions) {
+	ctx.remoteTmp.Lock()
+	defer ctx.remoteTmp.Unlock()
+	ctx.remoteTmp.opt = opt
+	ctx.remoteTmp.created = make(map[string]bool)
+}
+
+// RemoteTmpDir returns the directory the files are staged in on the host,
+// it's spec.DefaultRemoteTmpDir if the directories are not set
+func (ctx *Context) RemoteTmpDir(host string) string {
+	ctx.remoteTmp.Lock()
+	defer ctx.remoteTmp.Unlock()
+	return ctx.remoteTmp.opt.RemoteTmpDirOf(host)
+}
+
+// hasRemoteTmpDir checks if the directory to stage files in on the host is
+// set explicitly by the topology
+func (ctx *Context) hasRemoteTmpDir(host string) bool {
+	ctx.remoteTmp.Lock()
+	defer ctx.remoteTmp.Unlock()
+	return ctx.remoteTmp.opt.HasRemoteTmpDir(host)
+}
+
+// createRemoteTmpDir creates the directory to stage files in on the host if
+// it's set by the topology and missing, once a context. It's called as soon
+// as the host is connected, so the files can be transferred into it by any
+// user, like /tmp, the directory created is world-writable and sticky.
+func (ctx *Context) createRemoteTmpDir(e executor.Executor, host string) error {
+	ctx.remoteTmp.Lock()
+	defer ctx.remoteTmp.Unlock()
+	if !ctx.remoteTmp.opt.HasRemoteTmpDir(host) || ctx.remoteTmp.created[host] {
+		return nil
+	}
+
+	dir := ctx.remoteTmp.opt.RemoteTmpDirOf(host)
+	cmd := fmt.Sprintf("if [ ! -d %[1]s ]; then mkdir -p %[1]s && chmod 1777 %[1]s; fi", dir)
+	// the parent may only be writable by root
+	if _, _, err := e.Execute(cmd, false); err != nil {
+		if _, stderr, err := e.Execute(cmd, true); err != nil {
+			return errors.Annotatef(err, "failed to create the remote tmp dir %s on %s, stderr: %s", dir, host, stderr)
+		}
+	}
+	ctx.remoteTmp.created[host] = true
+	return nil
+}
+
+// stage creates a directory to stage size bytes of files in on the host,
+// under the remote tmp dir of the host, the staging directories left by the
+// interrupted operations are removed first. The directory must be removed by
+// calling cleanup whether staging succeeds or not.
+func (ctx *Context) stage(e executor.Executor, host string, size int64) (dir string, cleanup func(), err error) {
+	tmpDir := ctx.RemoteTmpDir(host)
+	sweep := fmt.Sprintf("find %s -maxdepth 1 -name '%s*' -mmin +%d -exec rm -rf {} +",
+		tmpDir, stagingDirPrefix, staleStagingMinutes)
+	if _, stderr, err := e.Execute(sweep, false); err != nil {
+		log.Debugf("Failed to remove the stale staging directories in %s on %s: %s", tmpDir, host, stderr)
+	}
+
+	dir = path.Join(tmpDir, stagingDirPrefix+uuid.New().String())
+	cleanup = func() {
+		if _, stderr, err := e.Execute(fmt.Sprintf("rm -rf %s", dir), false); err != nil {
+			log.Warnf("Failed to remove the staging directory %s on %s: %s", dir, host, stderr)
+		}
+	}
+	avail, ok := availableSpace(e, dir)
+	if !ok || avail < size {
+		cleanup()
+		return "", nil, ErrRemoteTmpNoSpace.New("Not enough space in %s on %s to stage %s, %s available",
+			tmpDir, host, formatSize(size), formatSize(avail)).
+			WithProperty(cliutil.SuggestionFromFormat(
+				"Free up space in %s on %s, or set global.remote_tmp_dir or global.remote_tmp_dirs of the topology to a directory with more space",
+				tmpDir, host))
+	}
+	return dir, cleanup, nil
+}
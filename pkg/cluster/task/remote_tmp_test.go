@@ -0,0 +1,122 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/check"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+)
+
+type remoteTmpSuite struct{}
+
+var _ = check.Suite(&remoteTmpSuite{})
+
+func (s *remoteTmpSuite) TestStagePackage(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-remote-tmp-test")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+
+	tmpDir := filepath.Join(dir, "tmp")
+	deployDir := filepath.Join(dir, "deploy")
+	c.Assert(os.MkdirAll(filepath.Join(dir, "pkg"), 0755), check.IsNil)
+	c.Assert(os.MkdirAll(tmpDir, 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "pkg", "tidb-server"), []byte("binary"), 0755), check.IsNil)
+	src := filepath.Join(dir, "tidb-v4.0.0-linux-amd64.tar.gz")
+	c.Assert(exec.Command("tar", "-czf", src, "-C", filepath.Join(dir, "pkg"), "tidb-server").Run(), check.IsNil)
+
+	// the staging directories left by the interrupted operations are removed
+	stale := filepath.Join(tmpDir, stagingDirPrefix+"stale")
+	c.Assert(os.Mkdir(stale, 0755), check.IsNil)
+	old := time.Now().Add(-48 * time.Hour)
+	c.Assert(os.Chtimes(stale, old, old), check.IsNil)
+
+	ctx := NewContext()
+	ctx.SetExecutor("host", &shellExecutor{})
+	ctx.SetRemoteTmpDirs(&spec.GlobalOptions{RemoteTmpDirs: map[string]string{"host": tmpDir}})
+	c.Assert(ctx.RemoteTmpDir("host"), check.Equals, tmpDir)
+	c.Assert(ctx.RemoteTmpDir("other"), check.Equals, spec.DefaultRemoteTmpDir)
+
+	t := &InstallPackage{srcPath: src, host: "host", dstDir: deployDir}
+	c.Assert(t.Execute(ctx), check.IsNil)
+	data, err := ioutil.ReadFile(filepath.Join(deployDir, "bin", "tidb-server"))
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "binary")
+	// the package is not left in the deploy dir
+	_, err = os.Stat(filepath.Join(deployDir, "bin", filepath.Base(src)))
+	c.Assert(os.IsNotExist(err), check.IsTrue)
+	left, err := ioutil.ReadDir(tmpDir)
+	c.Assert(err, check.IsNil)
+	c.Assert(left, check.HasLen, 0)
+
+	// the staging directory is removed if the extraction fails
+	c.Assert(ioutil.WriteFile(src, []byte("not a tarball"), 0644), check.IsNil)
+	c.Assert(t.Execute(ctx), check.NotNil)
+	left, err = ioutil.ReadDir(tmpDir)
+	c.Assert(err, check.IsNil)
+	c.Assert(left, check.HasLen, 0)
+
+	// nothing is transferred if the tmp dir has not enough space
+	e := &shellExecutor{noSpace: true}
+	ctx.SetExecutor("host", e)
+	err = t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrRemoteTmpNoSpace), check.IsTrue)
+	c.Assert(e.transfers, check.Equals, int32(0))
+}
+
+func (s *remoteTmpSuite) TestCreateRemoteTmpDir(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-remote-tmp-test")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+
+	tmpDir := filepath.Join(dir, "data", "tmp")
+	ctx := NewContext()
+	ctx.SetExecutorFactory(func(cfg executor.SSHConfig, sudo, native bool) executor.Executor {
+		return &shellExecutor{}
+	})
+	// the default one is not touched
+	c.Assert((&UserSSH{host: "other"}).Execute(ctx), check.IsNil)
+	ctx.SetRemoteTmpDirs(&spec.GlobalOptions{RemoteTmpDir: tmpDir})
+
+	// created once the host is connected, before anything is transferred
+	c.Assert((&UserSSH{host: "host"}).Execute(ctx), check.IsNil)
+	st, err := os.Stat(tmpDir)
+	c.Assert(err, check.IsNil)
+	c.Assert(st.Mode()&os.ModeSticky != 0, check.IsTrue)
+	c.Assert(st.Mode().Perm(), check.Equals, os.FileMode(0777))
+	c.Assert(os.Remove(tmpDir), check.IsNil)
+	c.Assert((&UserSSH{host: "host"}).Execute(ctx), check.IsNil)
+	_, err = os.Stat(tmpDir)
+	c.Assert(os.IsNotExist(err), check.IsTrue)
+
+	// the packages are cached in it as well
+	c.Assert(os.MkdirAll(filepath.Join(dir, "pkg"), 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "pkg", "tidb-server"), []byte("binary"), 0755), check.IsNil)
+	src := filepath.Join(dir, "tidb-v4.0.0-linux-amd64.tar.gz")
+	c.Assert(exec.Command("tar", "-czf", src, "-C", filepath.Join(dir, "pkg"), "tidb-server").Run(), check.IsNil)
+	cache := NewPackageCache(filepath.Join(dir, "home", PackageCacheDir), "tidb", "", nil)
+	t := &InstallPackage{srcPath: src, host: "host", dstDir: filepath.Join(dir, "deploy"), cache: cache}
+	c.Assert(t.Execute(ctx), check.IsNil)
+	_, err = os.Stat(filepath.Join(tmpDir, remotePackageCacheDir, filepath.Base(src)))
+	c.Assert(err, check.IsNil)
+	_, err = os.Stat(filepath.Join(dir, "home"))
+	c.Assert(os.IsNotExist(err), check.IsTrue)
+}
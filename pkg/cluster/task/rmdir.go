@@ -51,3 +51,8 @@ func (r *Rmdir) Rollback(ctx *Context) error {
 func (r *Rmdir) String() string {
 	return fmt.Sprintf("Rmdir: host=%s, directories='%s'", r.host, strings.Join(r.dirs, "','"))
 }
+
+// Hosts implements the HostTask interface
+func (r *Rmdir) Hosts() []string {
+	return []string{r.host}
+}
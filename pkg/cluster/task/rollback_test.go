@@ -0,0 +1,182 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/check"
+)
+
+// rollbackRecorder is a task taking some time to execute, which records the
+// order it's rolled back in
+type rollbackRecorder struct {
+	name       string
+	host       string
+	delay      time.Duration
+	failOnUndo bool
+	wait       chan struct{} // the rollback waits for it to be closed if it's not nil
+	done       chan struct{} // closed once rolled back if it's not nil
+	mu         *sync.Mutex
+	rolledBack *[]string
+}
+
+func (r *rollbackRecorder) Execute(ctx *Context) error {
+	time.Sleep(r.delay)
+	return nil
+}
+
+func (r *rollbackRecorder) Rollback(ctx *Context) error {
+	if r.wait != nil {
+		select {
+		case <-r.wait:
+		case <-time.After(time.Second):
+			return errors.New(r.name + " is blocked")
+		}
+	}
+	if r.done != nil {
+		defer close(r.done)
+	}
+	r.mu.Lock()
+	*r.rolledBack = append(*r.rolledBack, r.name)
+	r.mu.Unlock()
+	if r.failOnUndo {
+		return errors.New(r.name + " is busy")
+	}
+	return nil
+}
+
+func (r *rollbackRecorder) String() string {
+	return r.name
+}
+
+func (r *rollbackRecorder) Hosts() []string {
+	if r.host == "" {
+		return nil
+	}
+	return []string{r.host}
+}
+
+type rollbackSuite struct{}
+
+var _ = check.Suite(&rollbackSuite{})
+
+func (s *rollbackSuite) TestOrderedRollback(c *check.C) {
+	var mu sync.Mutex
+	var rolledBack []string
+	task := func(name string, delay time.Duration, fail bool) Task {
+		return &rollbackRecorder{name: name, delay: delay, failOnUndo: fail, mu: &mu, rolledBack: &rolledBack}
+	}
+
+	// rolled back in the reverse order they finished
+	t := NewBuilder().OrderedRollback().Parallel(
+		task("A", 60*time.Millisecond, false),
+		task("B", 0, false),
+		task("C", 30*time.Millisecond, false),
+	).Build()
+	c.Assert(t.Execute(NewContext()), check.IsNil)
+	c.Assert(t.Rollback(NewContext()), check.IsNil)
+	c.Assert(rolledBack, check.DeepEquals, []string{"A", "C", "B"})
+
+	// the failures don't stop the rest from being rolled back, they are
+	// returned together
+	rolledBack = nil
+	t = NewBuilder().OrderedRollback().Serialize().Parallel(
+		task("A", 0, false),
+		task("B", 0, true),
+		task("C", 0, true),
+	).Build()
+	c.Assert(t.Execute(NewContext()), check.IsNil)
+	err := t.Rollback(NewContext())
+	c.Assert(rolledBack, check.DeepEquals, []string{"C", "B", "A"})
+	c.Assert(err, check.NotNil)
+	c.Assert(strings.HasPrefix(err.Error(), "2 task(s) failed to roll back"), check.IsTrue, check.Commentf("%v", err))
+	c.Assert(strings.Contains(err.Error(), "C is busy"), check.IsTrue)
+	c.Assert(strings.Contains(err.Error(), "B is busy"), check.IsTrue)
+}
+
+func (s *rollbackSuite) TestRollbackHostsConcurrently(c *check.C) {
+	var mu sync.Mutex
+	var rolledBack []string
+	// B waits for A1, which is rolled back after B if the hosts are rolled
+	// back one by one
+	a1Done := make(chan struct{})
+	tasks := []Task{
+		&rollbackRecorder{name: "A1", host: "10.0.1.1", done: a1Done, mu: &mu, rolledBack: &rolledBack},
+		&rollbackRecorder{name: "B", host: "10.0.1.2", delay: 20 * time.Millisecond, wait: a1Done, mu: &mu, rolledBack: &rolledBack},
+		&rollbackRecorder{name: "A2", host: "10.0.1.1", delay: 40 * time.Millisecond, mu: &mu, rolledBack: &rolledBack},
+	}
+	t := NewBuilder().OrderedRollback().Parallel(tasks...).Build()
+	c.Assert(t.Execute(NewContext()), check.IsNil)
+	c.Assert(t.Rollback(NewContext()), check.IsNil)
+	c.Assert(rolledBack, check.DeepEquals, []string{"A2", "A1", "B"})
+
+	// the tasks sharing a host are in the same group, so are the ones of no
+	// host
+	order := []int{0, 1, 2, 3, 4}
+	groups := rollbackGroups([]Task{
+		&rollbackRecorder{host: "10.0.1.1"},
+		&rollbackRecorder{},
+		NewBuilder().Parallel(&rollbackRecorder{host: "10.0.1.1"}, &rollbackRecorder{host: "10.0.1.2"}).Build(),
+		&rollbackRecorder{host: "10.0.1.2"},
+		&rollbackRecorder{},
+	}, order)
+	c.Assert(groups, check.DeepEquals, [][]int{{4, 1}, {3, 2, 0}})
+}
+
+func (s *rollbackSuite) TestRestoreFinishOrder(c *check.C) {
+	var mu sync.Mutex
+	var rolledBack []string
+	build := func() Task {
+		task := func(name string, delay time.Duration) Task {
+			return &rollbackRecorder{name: name, delay: delay, mu: &mu, rolledBack: &rolledBack}
+		}
+		return NewBuilder().OrderedRollback().Parallel(
+			task("A", 40*time.Millisecond),
+			task("B", 0),
+			task("C", 20*time.Millisecond),
+		).Build()
+	}
+
+	// the order is saved in the snapshot of the tree
+	t := build()
+	tree := NewTaskTree(t)
+	ctx := NewContext()
+	tree.Track(ctx, nil)
+	c.Assert(t.Execute(ctx), check.IsNil)
+	saved := tree.Snapshot()
+	data, err := json.Marshal(saved)
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.Contains(string(data), `"finish_order":[1,2,0]`), check.IsTrue, check.Commentf("%s", data))
+
+	// the task rebuilt by a resumed run is rolled back in the order saved
+	var loaded TaskNode
+	c.Assert(json.Unmarshal(data, &loaded), check.IsNil)
+	t = build()
+	NewTaskTree(t).Restore(&loaded)
+	c.Assert(t.Rollback(NewContext()), check.IsNil)
+	c.Assert(rolledBack, check.DeepEquals, []string{"A", "C", "B"})
+
+	// the orders of the groups of another shape are ignored
+	rolledBack = nil
+	loaded.Children[0].Children = loaded.Children[0].Children[:2]
+	t = build()
+	NewTaskTree(t).Restore(&loaded)
+	c.Assert(t.Rollback(NewContext()), check.IsNil)
+	c.Assert(rolledBack, check.DeepEquals, []string{"C", "B", "A"})
+}
@@ -39,6 +39,9 @@ func (c *ScaleConfig) Execute(ctx *Context) error {
 	if !found {
 		return ErrNoExecutor
 	}
+	if s := ctx.ConfigStamper(); s != nil {
+		exec = s.Wrap(exec, c.instance.GetHost())
+	}
 
 	c.paths.Cache = c.specManager.Path(c.clusterName, spec.TempConfigPath)
 	if err := os.MkdirAll(c.paths.Cache, 0755); err != nil {
@@ -58,3 +61,8 @@ func (c *ScaleConfig) String() string {
 	return fmt.Sprintf("ScaleConfig: cluster=%s, user=%s, host=%s, service=%s, %s",
 		c.clusterName, c.deployUser, c.instance.GetHost(), c.instance.ServiceName(), c.paths)
 }
+
+// Hosts implements the HostTask interface
+func (c *ScaleConfig) Hosts() []string {
+	return []string{c.instance.GetHost()}
+}
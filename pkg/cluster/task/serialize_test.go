@@ -0,0 +1,67 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pingcap/check"
+)
+
+type serializeSuite struct{}
+
+var _ = check.Suite(&serializeSuite{})
+
+func (s *serializeSuite) TestSerialize(c *check.C) {
+	execute := func(serial bool) (order []string, maxRunning int) {
+		var mu sync.Mutex
+		running := 0
+		var steps []*StepDisplay
+		for i := 0; i < 5; i++ {
+			name := fmt.Sprintf("host-%d", i)
+			steps = append(steps, NewBuilder().
+				Func(name, func(ctx *Context) error {
+					mu.Lock()
+					running++
+					if running > maxRunning {
+						maxRunning = running
+					}
+					order = append(order, name)
+					mu.Unlock()
+					time.Sleep(10 * time.Millisecond)
+					mu.Lock()
+					running--
+					mu.Unlock()
+					return nil
+				}).
+				BuildAsStep("  - "+name))
+		}
+		b := NewBuilder()
+		if serial {
+			b.Serialize()
+		}
+		t := b.ParallelStep("+ Start", steps...).Build()
+		c.Assert(t.Execute(NewContext()), check.IsNil)
+		return order, maxRunning
+	}
+
+	order, maxRunning := execute(true)
+	c.Assert(maxRunning, check.Equals, 1)
+	c.Assert(order, check.DeepEquals, []string{"host-0", "host-1", "host-2", "host-3", "host-4"})
+
+	_, maxRunning = execute(false)
+	c.Assert(maxRunning > 1, check.IsTrue)
+}
@@ -54,3 +54,8 @@ func (m *Shell) Rollback(ctx *Context) error {
 func (m *Shell) String() string {
 	return fmt.Sprintf("Shell: host=%s, sudo=%v, command=`%s`", m.host, m.sudo, m.command)
 }
+
+// Hosts implements the HostTask interface
+func (m *Shell) Hosts() []string {
+	return []string{m.host}
+}
@@ -39,7 +39,7 @@ type RootSSH struct {
 
 // Execute implements the Task interface
 func (s *RootSSH) Execute(ctx *Context) error {
-	e := executor.NewSSHExecutor(executor.SSHConfig{
+	cfg := executor.SSHConfig{
 		Host:       s.host,
 		Port:       s.port,
 		User:       s.user,
@@ -47,7 +47,14 @@ func (s *RootSSH) Execute(ctx *Context) error {
 		KeyFile:    s.keyFile,
 		Passphrase: s.passphrase,
 		Timeout:    time.Second * time.Duration(s.timeout),
-	}, s.user != "root", s.native) // using sudo by default if user is not root
+	}
+	if err := ctx.verifyHostKey(&cfg); err != nil {
+		return err
+	}
+	e := ctx.newExecutor(cfg, s.user != "root", s.native) // using sudo by default if user is not root
+	if err := ctx.createRemoteTmpDir(e, s.host); err != nil {
+		return err
+	}
 
 	ctx.SetExecutor(s.host, e)
 	return nil
@@ -69,6 +76,11 @@ func (s RootSSH) String() string {
 	return fmt.Sprintf("RootSSH: user=%s, host=%s, port=%d", s.user, s.host, s.port)
 }
 
+// Hosts implements the HostTask interface
+func (s RootSSH) Hosts() []string {
+	return []string{s.host}
+}
+
 // UserSSH is used to establish a SSH connection to the target host with generated key
 type UserSSH struct {
 	host       string
@@ -80,13 +92,20 @@ type UserSSH struct {
 
 // Execute implements the Task interface
 func (s *UserSSH) Execute(ctx *Context) error {
-	e := executor.NewSSHExecutor(executor.SSHConfig{
+	cfg := executor.SSHConfig{
 		Host:    s.host,
 		Port:    s.port,
 		KeyFile: ctx.PrivateKeyPath,
 		User:    s.deployUser,
 		Timeout: time.Second * time.Duration(s.timeout),
-	}, false /* not using sudo by default */, s.native)
+	}
+	if err := ctx.verifyHostKey(&cfg); err != nil {
+		return err
+	}
+	e := ctx.newExecutor(cfg, false /* not using sudo by default */, s.native)
+	if err := ctx.createRemoteTmpDir(e, s.host); err != nil {
+		return err
+	}
 	ctx.SetExecutor(s.host, e)
 	return nil
 }
@@ -103,3 +122,8 @@ func (s *UserSSH) Rollback(ctx *Context) error {
 func (s UserSSH) String() string {
 	return fmt.Sprintf("UserSSH: user=%s, host=%s", s.deployUser, s.host)
 }
+
+// Hosts implements the HostTask interface
+func (s UserSSH) Hosts() []string {
+	return []string{s.host}
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+)
+
+// SSHAuthReport is the outcome of authenticating to the SSH server of a host
+type SSHAuthReport struct {
+	Host   string
+	Port   int
+	User   string
+	Status executor.AuthStatus
+	Err    error // the detail of the failure
+}
+
+// CheckSSHAuth classifies the outcome of authenticating to a host with the
+// key or password provided by user. It never fails, so the outcomes of all
+// hosts could be collected and reported together.
+type CheckSSHAuth struct {
+	host       string
+	port       int
+	user       string
+	password   string
+	keyFile    string
+	passphrase string
+	timeout    int64 // timeout in seconds when connecting via SSH
+	native     bool  // authenticate with the SSH client installed on the system
+	report     *SSHAuthReport
+}
+
+// Execute implements the Task interface
+func (c *CheckSSHAuth) Execute(ctx *Context) error {
+	status, err := executor.CheckAuth(executor.SSHConfig{
+		Host:       c.host,
+		Port:       c.port,
+		User:       c.user,
+		Password:   c.password,
+		KeyFile:    c.keyFile,
+		Passphrase: c.passphrase,
+		Timeout:    time.Second * time.Duration(c.timeout),
+	}, c.native)
+	*c.report = SSHAuthReport{
+		Host:   c.host,
+		Port:   c.port,
+		User:   c.user,
+		Status: status,
+		Err:    err,
+	}
+	return nil
+}
+
+// Rollback implements the Task interface
+func (c *CheckSSHAuth) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckSSHAuth) String() string {
+	return fmt.Sprintf("CheckSSHAuth: user=%s, host=%s, port=%d", c.user, c.host, c.port)
+}
+
+// Hosts implements the HostTask interface
+func (c *CheckSSHAuth) Hosts() []string {
+	return []string{c.host}
+}
@@ -14,9 +14,14 @@
 package task
 
 import (
+	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/pingcap/tiup/pkg/cliutil/progress"
+	"github.com/pingcap/tiup/pkg/localdata"
 )
 
 // StepDisplay is a task that will display a progress bar for inner task.
@@ -24,8 +29,16 @@ type StepDisplay struct {
 	hidden      bool
 	inner       Task
 	prefix      string
+	message     *Message // the identified message of the prefix, nil if not identified
 	children    map[Task]struct{}
 	progressBar progress.Bar
+
+	// the phase of the step reported by the inner tasks, see Context.SetPhase
+	phase struct {
+		sync.Mutex
+		phase  Phase
+		detail string
+	}
 }
 
 func addChildren(m map[Task]struct{}, task Task) {
@@ -61,14 +74,39 @@ func newStepDisplay(prefix string, inner Task) *StepDisplay {
 	}
 }
 
+// Message implements the Messager interface
+func (s *StepDisplay) Message() Message {
+	if s.message != nil {
+		return *s.message
+	}
+	return Message{Text: strings.TrimLeft(s.prefix, " -+")}
+}
+
+// Phase returns the phase of the step and the detail of it, the phase is
+// empty if the inner tasks don't report one
+func (s *StepDisplay) Phase() (Phase, string) {
+	s.phase.Lock()
+	defer s.phase.Unlock()
+	return s.phase.phase, s.phase.detail
+}
+
 // SetHidden set step hidden or not.
 func (s *StepDisplay) SetHidden(h bool) *StepDisplay {
 	s.hidden = h
 	return s
 }
 
+// group returns the component of the step, the steps are counted by it when
+// they are aggregated
+func (s *StepDisplay) group() string {
+	if s.message == nil {
+		return ""
+	}
+	return s.message.Params["component"]
+}
+
 func (s *StepDisplay) resetAsMultiBarItem(b *progress.MultiBar) {
-	s.progressBar = b.AddBar(s.prefix)
+	s.progressBar = b.AddGroupedBar(s.prefix, s.group())
 }
 
 // Execute implements the Task interface
@@ -82,7 +120,9 @@ func (s *StepDisplay) Execute(ctx *Context) error {
 	}
 	ctx.ev.Subscribe(EventTaskBegin, s.handleTaskBegin)
 	ctx.ev.Subscribe(EventTaskProgress, s.handleTaskProgress)
+	ctx.ev.Subscribe(EventTaskPhase, s.handleTaskPhase)
 	err := s.inner.Execute(ctx)
+	ctx.ev.Unsubscribe(EventTaskPhase, s.handleTaskPhase)
 	ctx.ev.Unsubscribe(EventTaskProgress, s.handleTaskProgress)
 	ctx.ev.Unsubscribe(EventTaskBegin, s.handleTaskBegin)
 	if err != nil {
@@ -112,7 +152,7 @@ func (s *StepDisplay) String() string {
 	return s.inner.String()
 }
 
-func (s *StepDisplay) handleTaskBegin(task Task) {
+func (s *StepDisplay) handleTaskBegin(task Task, _ string) {
 	if _, ok := s.children[task]; !ok {
 		return
 	}
@@ -132,6 +172,23 @@ func (s *StepDisplay) handleTaskProgress(task Task, p string) {
 	})
 }
 
+func (s *StepDisplay) handleTaskPhase(task Task, phase Phase, detail string) {
+	if _, ok := s.children[task]; !ok {
+		return
+	}
+	s.phase.Lock()
+	s.phase.phase, s.phase.detail = phase, detail
+	s.phase.Unlock()
+	suffix := string(phase)
+	if detail != "" {
+		suffix += ": " + detail
+	}
+	s.progressBar.UpdateDisplay(&progress.DisplayProps{
+		Prefix: s.prefix,
+		Suffix: suffix,
+	})
+}
+
 // ParallelStepDisplay is a task that will display multiple progress bars in parallel for inner tasks.
 // Inner tasks will be executed in parallel.
 type ParallelStepDisplay struct {
@@ -140,8 +197,65 @@ type ParallelStepDisplay struct {
 	progressBar *progress.MultiBar
 }
 
+// naturalLess compares two strings with the numbers in them compared by their
+// values, so "host-2:9" goes before "host-10:10" and IPs are ordered numerically
+func naturalLess(a, b string) bool {
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	for len(a) > 0 && len(b) > 0 {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			i, j := 0, 0
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+			na, nb := strings.TrimLeft(a[:i], "0"), strings.TrimLeft(b[:j], "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[i:], b[j:]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// defaultDisplayAggregateAbove is the number of steps displayed in parallel
+// above which they are aggregated by default
+const defaultDisplayAggregateAbove = 100
+
+// displayAggregateAbove returns the number of steps displayed in parallel above
+// which they are aggregated, see localdata.EnvNameDisplayAggregateAbove
+func displayAggregateAbove() int {
+	if n, err := strconv.Atoi(os.Getenv(localdata.EnvNameDisplayAggregateAbove)); err == nil && n >= 0 {
+		return n
+	}
+	return defaultDisplayAggregateAbove
+}
+
+// newParallelStepDisplay creates a ParallelStepDisplay, the steps are sorted by
+// their prefixes, which are like "  - Copy tikv -> 10.0.1.1:20160" by convention,
+// so they are ordered by role, host and port, and the output is deterministic
+// regardless of the order the steps are built in.
 func newParallelStepDisplay(prefix string, sdTasks ...*StepDisplay) *ParallelStepDisplay {
+	sdTasks = append([]*StepDisplay{}, sdTasks...)
+	sort.SliceStable(sdTasks, func(i, j int) bool {
+		return naturalLess(sdTasks[i].prefix, sdTasks[j].prefix)
+	})
+
 	bar := progress.NewMultiBar(prefix)
+	if os.Getenv(localdata.EnvNameDisplayOrder) == "active" {
+		bar.SetSortOrder(progress.SortByActivity)
+	}
+	bar.SetAggregateAbove(displayAggregateAbove())
 	tasks := make([]Task, 0, len(sdTasks))
 	for _, t := range sdTasks {
 		if !t.hidden {
@@ -0,0 +1,69 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/pingcap/check"
+)
+
+type stepSuite struct{}
+
+var _ = check.Suite(&stepSuite{})
+
+func (s *stepSuite) TestNaturalLess(c *check.C) {
+	for _, pair := range [][2]string{
+		{"host-2:9", "host-10:10"},
+		{"10.0.1.9:20160", "10.0.1.10:20160"},
+		{"10.0.1.9:2379", "10.0.1.9:20160"},
+		{"Copy pd -> 10.0.1.9", "Copy tikv -> 10.0.1.1"},
+		{"host-02", "host-3"},
+		{"host", "host-1"},
+	} {
+		c.Assert(naturalLess(pair[0], pair[1]), check.IsTrue, check.Commentf("%s < %s", pair[0], pair[1]))
+		c.Assert(naturalLess(pair[1], pair[0]), check.IsFalse, check.Commentf("%s > %s", pair[1], pair[0]))
+	}
+	c.Assert(naturalLess("host-1", "host-1"), check.IsFalse)
+}
+
+func (s *stepSuite) TestParallelStepOrder(c *check.C) {
+	noop := func(ctx *Context) error { return nil }
+	prefixes := []string{
+		"  - Copy tikv -> 10.0.1.10:20160",
+		"  - Copy tikv -> 10.0.1.9:20161",
+		"  - Copy pd -> 10.0.1.10:2379",
+		"  - Copy tikv -> 10.0.1.9:20160",
+	}
+	// the steps are ordered by role, host and port, whatever order they're
+	// built in
+	expected := []string{
+		"  - Copy pd -> 10.0.1.10:2379",
+		"  - Copy tikv -> 10.0.1.9:20160",
+		"  - Copy tikv -> 10.0.1.9:20161",
+		"  - Copy tikv -> 10.0.1.10:20160",
+	}
+	for _, order := range [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}, {2, 0, 3, 1}} {
+		var steps []*StepDisplay
+		for _, i := range order {
+			steps = append(steps, NewBuilder().Func(prefixes[i], noop).BuildAsStep(prefixes[i]))
+		}
+		ps := newParallelStepDisplay("+ Copy files", steps...)
+		var sorted []string
+		for _, t := range ps.inner.inner {
+			sorted = append(sorted, t.(*StepDisplay).prefix)
+		}
+		c.Assert(sorted, check.DeepEquals, expected)
+		// the steps given are not reordered
+		c.Assert(steps[0].prefix, check.Equals, prefixes[order[0]])
+	}
+}
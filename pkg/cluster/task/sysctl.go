@@ -63,3 +63,8 @@ func (s *Sysctl) Rollback(ctx *Context) error {
 func (s *Sysctl) String() string {
 	return fmt.Sprintf("Sysctl: host=%s %s = %s", s.host, s.key, s.val)
 }
+
+// Hosts implements the HostTask interface
+func (s *Sysctl) Hosts() []string {
+	return []string{s.host}
+}
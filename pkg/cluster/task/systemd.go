@@ -56,3 +56,8 @@ func (c *SystemCtl) Rollback(ctx *Context) error {
 func (c *SystemCtl) String() string {
 	return fmt.Sprintf("SystemCtl: host=%s action=%s %s", c.host, c.action, c.unit)
 }
+
+// Hosts implements the HostTask interface
+func (c *SystemCtl) Hosts() []string {
+	return []string{c.host}
+}
@@ -16,13 +16,19 @@ package task
 import (
 	stderrors "errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/executor"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/logger/log"
 	"github.com/pingcap/tiup/pkg/utils/mock"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
 )
 
 var (
@@ -51,11 +57,54 @@ type (
 		exec struct {
 			sync.RWMutex
 			executors    map[string]executor.Executor
-			stdouts      map[string][]byte
-			stderrs      map[string][]byte
+			outputs      *outputStore
 			checkResults map[string][]*operator.CheckResult
 		}
 
+		// the namespace of the identities of Func tasks, see TaskID
+		namespace string
+
+		// the consecutive failures to reach each host, see SetHostFailureThreshold
+		hosts *hostHealth
+		// the failures of all the steps, see Failures
+		failures stepFailures
+
+		// where the tasks pause, see SetBreakpoints
+		breaks breakpoints
+
+		// the deadline of the tasks and the time spent on them, see SetDeadline
+		budget budget
+		// the nanoseconds the tasks are delayed on purpose, see AddedDelay
+		addedDelay int64
+
+		// creates the executors of hosts instead of SSH, see SetExecutorFactory
+		executorFactory ExecutorFactory
+		// where the commands executed on the hosts are logged, see SetLogger
+		logger *zap.Logger
+
+		// stamps the configs pushed to hosts, see SetConfigStamper
+		configStamper *spec.ConfigStamper
+		// verifies the host keys of the hosts connected, see SetHostKeyVerifier
+		hostKeyVerifier *spec.HostKeyVerifier
+		// where the files are staged on the hosts, see SetRemoteTmpDirs
+		remoteTmp struct {
+			sync.Mutex
+			opt     *spec.GlobalOptions
+			created map[string]bool // the hosts the directories are created on
+		}
+		// the followers of the commands on the hosts, see SetHostFollowers
+		followers *HostFollowers
+		// the max number of inner tasks of a Parallel executing at the same
+		// time, see SetConcurrency
+		concurrency int
+
+		// the outermost task executing with the context, see Progress
+		root struct {
+			sync.Mutex
+			task    Task
+			running bool
+		}
+
 		// The public/private key is used to access remote server via the user `tidb`
 		PrivateKeyPath string
 		PublicKeyPath  string
@@ -64,16 +113,115 @@ type (
 	// Serial will execute a bundle of task in serialized way
 	Serial struct {
 		hideDetailDisplay bool
+		mode              ErrorMode
+		modeFixed         bool // the mode is set by Builder.Mode, see applyMode
 		inner             []Task
+		done              int32 // the number of inner tasks executed, see Progress
 	}
 
 	// Parallel will execute a bundle of task in parallelism way
 	Parallel struct {
 		hideDetailDisplay bool
+		mode              ErrorMode
 		inner             []Task
+
+		// roll back the inner tasks in the reverse order they finished
+		// executing, the ones of the independent hosts concurrently, see
+		// rollbackInOrder
+		orderedRollback bool
+		// execute the inner tasks one by one in order, instead of concurrently
+		serial   bool
+		finished struct {
+			sync.Mutex
+			order []int // indexes of the inner tasks in the order they finished
+		}
 	}
 )
 
+// ErrorMode decides what a Serial or Parallel does when its inner tasks fail,
+// the mode of the outermost task built applies to all the tasks nested in it,
+// see ErrorModeOf for the modes of the scopes of operator.Options
+type ErrorMode int
+
+// modes of handling errors of inner tasks
+const (
+	// StopOnError stops executing at the first failed task and returns its error
+	StopOnError ErrorMode = iota
+	// ContinueCollectingErrors keeps executing the rest tasks when some of them
+	// fail, and returns a DegradedError listing all the failures at the end
+	ContinueCollectingErrors
+	// SkipFailedHosts marks the hosts of a failed task as degraded, so the rest
+	// tasks on them are skipped, the others keep executing, and a DegradedError
+	// is returned at the end. It stops as StopOnError if the failed task isn't
+	// on any host.
+	SkipFailedHosts
+)
+
+// StepFailure is a task failed in ContinueCollectingErrors mode
+type StepFailure struct {
+	Step    string
+	Err     error
+	Skipped bool // the task was skipped as its host is degraded, see ErrHostDegraded
+}
+
+// DegradedError is returned when some tasks failed in ContinueCollectingErrors
+// mode, the operation finished but in a degraded state
+type DegradedError struct {
+	Failures []StepFailure
+	// the hosts marked as degraded, with the steps to retry from for them
+	Hosts []HostDegradation
+}
+
+// Error implements the error interface
+func (e *DegradedError) Error() string {
+	var failed, skipped []string
+	for _, f := range e.Failures {
+		line := fmt.Sprintf("  - %s: %s", f.Step, f.Err)
+		if f.Skipped {
+			skipped = append(skipped, line)
+		} else {
+			failed = append(failed, line)
+		}
+	}
+	lines := []string{fmt.Sprintf("%d step(s) failed:", len(failed))}
+	lines = append(lines, failed...)
+	if len(skipped) > 0 {
+		lines = append(lines, fmt.Sprintf("%d step(s) skipped on degraded hosts:", len(skipped)))
+		lines = append(lines, skipped...)
+	}
+	for _, h := range e.Hosts {
+		lines = append(lines, fmt.Sprintf("host %s is degraded (%s), retry it from step: %s", h.Host, h.Cause, h.FirstFailedStep))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// add records the failure of t, the failures of nested tasks are flattened
+func (e *DegradedError) add(t Task, err error) {
+	if de, ok := err.(*DegradedError); ok {
+		e.Failures = append(e.Failures, de.Failures...)
+		return
+	}
+	_, skipped := IsHostDegraded(err)
+	e.Failures = append(e.Failures, StepFailure{Step: stepName(t), Err: err, Skipped: skipped})
+}
+
+// stepName returns the name of t displayed in reports
+func stepName(t Task) string {
+	if sd, ok := t.(*StepDisplay); ok {
+		return strings.TrimSpace(strings.TrimLeft(sd.prefix, " +-"))
+	}
+	return strings.Split(t.String(), "\n")[0]
+}
+
+// result returns nil if nothing failed
+func (e *DegradedError) result(ctx *Context) error {
+	if len(e.Failures) == 0 {
+		return nil
+	}
+	e.Hosts = ctx.DegradedHosts()
+	return e
+}
+
 // NewContext create a context instance.
 func NewContext() *Context {
 	return &Context{
@@ -81,16 +229,114 @@ func NewContext() *Context {
 		exec: struct {
 			sync.RWMutex
 			executors    map[string]executor.Executor
-			stdouts      map[string][]byte
-			stderrs      map[string][]byte
+			outputs      *outputStore
 			checkResults map[string][]*operator.CheckResult
 		}{
 			executors:    make(map[string]executor.Executor),
-			stdouts:      make(map[string][]byte),
-			stderrs:      make(map[string][]byte),
+			outputs:      newOutputStore(),
 			checkResults: make(map[string][]*operator.CheckResult),
 		},
+		hosts: newHostHealth(),
+	}
+}
+
+// Subscribe subscribes the events of the tasks executed with the context
+func (ctx *Context) Subscribe(eventName EventKind, handler interface{}) {
+	ctx.ev.Subscribe(eventName, handler)
+}
+
+// SetNamespace sets the namespace of the identities of the Func tasks executed
+// with the context, so the tasks of different operations having the same
+// name, e.g., "StartCluster", are told apart
+func (ctx *Context) SetNamespace(namespace string) {
+	ctx.namespace = namespace
+}
+
+// TaskID returns the identity of the task executed with the context. The
+// identity of a Func task is its name prefixed with the namespace of the
+// context, unless it's set explicitly by WithID, and it's the string of the
+// task for others.
+func (ctx *Context) TaskID(t Task) string {
+	if f, ok := t.(*Func); ok {
+		return f.identity(ctx.namespace)
 	}
+	return t.String()
+}
+
+// ExecutorFactory creates the executor of a host from the SSH config of it
+type ExecutorFactory func(cfg executor.SSHConfig, sudo, native bool) executor.Executor
+
+// SetExecutorFactory makes the tasks executed with the context connect hosts
+// with the executors created by f instead of SSH executors, e.g., the fake
+// ones simulating the hosts
+func (ctx *Context) SetExecutorFactory(f ExecutorFactory) {
+	ctx.executorFactory = f
+}
+
+// SetConcurrency limits the inner tasks of each Parallel executed with the
+// context to n executing at the same time, 0 means unlimited. The limit
+// applies to each Parallel separately, so the nested ones don't wait for
+// their parents.
+func (ctx *Context) SetConcurrency(n int) {
+	ctx.concurrency = n
+}
+
+// SetLogger makes the commands executed on the hosts logged by l instead of
+// the global logger, e.g., the one tagging the logs of an operation
+func (ctx *Context) SetLogger(l *zap.Logger) {
+	ctx.logger = l
+}
+
+// SetConfigStamper makes the tasks executed with the context stamp the
+// configs pushed to hosts with the generation of s, and refuse to overwrite
+// the ones pushed by concurrent operations
+func (ctx *Context) SetConfigStamper(s *spec.ConfigStamper) {
+	ctx.configStamper = s
+}
+
+// ConfigStamper returns the stamper of the configs pushed, nil if the
+// configs are pushed as they are
+func (ctx *Context) ConfigStamper() *spec.ConfigStamper {
+	return ctx.configStamper
+}
+
+// SetHostKeyVerifier makes the tasks executed with the context verify the
+// SSH host keys of the hosts before connecting them
+func (ctx *Context) SetHostKeyVerifier(v *spec.HostKeyVerifier) {
+	ctx.hostKeyVerifier = v
+}
+
+// verifyHostKey verifies the host key of the host of cfg if there is a
+// verifier set, and pins the key verified in cfg for connecting the host
+func (ctx *Context) verifyHostKey(cfg *executor.SSHConfig) error {
+	if ctx.hostKeyVerifier == nil {
+		return nil
+	}
+	key, err := ctx.hostKeyVerifier.Verify(*cfg)
+	if err != nil {
+		return err
+	}
+	cfg.HostKey = key
+	return nil
+}
+
+// hostKey returns the host key of the host verified by the context, nil if
+// it's not verified, e.g., there is no verifier set
+func (ctx *Context) hostKey(host string) ssh.PublicKey {
+	if ctx.hostKeyVerifier == nil {
+		return nil
+	}
+	return ctx.hostKeyVerifier.Verified(host)
+}
+
+// newExecutor creates the executor of a host by the factory of the context,
+// it's an SSH executor if the factory isn't set
+func (ctx *Context) newExecutor(cfg executor.SSHConfig, sudo, native bool) executor.Executor {
+	cfg.Logger = ctx.logger
+	if ctx.executorFactory != nil {
+		return ctx.executorFactory(cfg, sudo, native)
+	}
+	return executor.NewSSHExecutor(cfg, sudo, native)
 }
 
 // Get implements operation ExecutorGetter interface.
@@ -118,8 +364,12 @@ func (ctx *Context) GetExecutor(host string) (e executor.Executor, ok bool) {
 	return
 }
 
-// SetExecutor set the executor.
+// SetExecutor set the executor, the commands of it fail fast with
+// ErrHostDegraded once the host is marked as degraded.
 func (ctx *Context) SetExecutor(host string, e executor.Executor) {
+	if _, ok := e.(*healthTrackingExecutor); !ok {
+		e = &healthTrackingExecutor{Executor: e, host: host, ctx: ctx}
+	}
 	ctx.exec.Lock()
 	ctx.exec.executors[host] = e
 	ctx.exec.Unlock()
@@ -127,21 +377,46 @@ func (ctx *Context) SetExecutor(host string, e executor.Executor) {
 
 // GetOutputs get the outputs of a host (if has any)
 func (ctx *Context) GetOutputs(host string) ([]byte, []byte, bool) {
-	ctx.exec.RLock()
-	stdout, ok1 := ctx.exec.stderrs[host]
-	stderr, ok2 := ctx.exec.stdouts[host]
-	ctx.exec.RUnlock()
-	return stdout, stderr, ok1 && ok2
+	// the LRU order is updated, so the write lock is needed
+	ctx.exec.Lock()
+	defer ctx.exec.Unlock()
+	return ctx.exec.outputs.get(host)
 }
 
 // SetOutputs set the outputs of a host
 func (ctx *Context) SetOutputs(host string, stdout []byte, stderr []byte) {
 	ctx.exec.Lock()
-	ctx.exec.stderrs[host] = stdout
-	ctx.exec.stdouts[host] = stderr
+	ctx.exec.outputs.set(host, stdout, stderr)
 	ctx.exec.Unlock()
 }
 
+// SetOutputLimits limits the memory used by the outputs of hosts: the total
+// size of outputs kept in memory is capped by limit, and outputs larger than
+// spillThreshold are written to temp files under spillDir. Zero limits mean
+// unlimited, outputs exceeding the limit are dropped if spillDir is empty.
+func (ctx *Context) SetOutputLimits(limit, spillThreshold int64, spillDir string) {
+	ctx.exec.Lock()
+	ctx.exec.outputs.limit = limit
+	ctx.exec.outputs.spillThreshold = spillThreshold
+	ctx.exec.outputs.spillDir = spillDir
+	ctx.exec.Unlock()
+}
+
+// OutputUsage returns the usage of memory by the outputs of hosts
+func (ctx *Context) OutputUsage() OutputUsage {
+	ctx.exec.RLock()
+	defer ctx.exec.RUnlock()
+	return ctx.exec.outputs.usage
+}
+
+// GetOutputFiles returns the files the outputs of a host are spilled to,
+// empty strings are returned if they are not spilled
+func (ctx *Context) GetOutputFiles(host string) (stdoutFile, stderrFile string) {
+	ctx.exec.RLock()
+	defer ctx.exec.RUnlock()
+	return ctx.exec.outputs.files(host)
+}
+
 // GetCheckResults get the the check result of a host (if has any)
 func (ctx *Context) GetCheckResults(host string) (results []*operator.CheckResult, ok bool) {
 	ctx.exec.RLock()
@@ -179,20 +454,43 @@ func isDisplayTask(t Task) bool {
 
 // Execute implements the Task interface
 func (s *Serial) Execute(ctx *Context) error {
+	root := ctx.enterRoot(s)
+	if root {
+		defer ctx.exitRoot()
+	}
+	atomic.StoreInt32(&s.done, 0)
+	degraded := &DegradedError{}
 	for _, t := range s.inner {
 		if !isDisplayTask(t) {
 			if !s.hideDetailDisplay {
 				log.Infof("+ [ Serial ] - %s", t.String())
 			}
 		}
-		ctx.ev.PublishTaskBegin(t)
-		err := t.Execute(ctx)
-		ctx.ev.PublishTaskFinish(t, err)
-		if err != nil {
+		ctx.breakBefore(t)
+		if err := ctx.checkDeadline(t); err != nil {
 			return err
 		}
+		err := ctx.skipFailedHost(s.mode, t)
+		if err == nil {
+			ctx.beginStep(t, root)
+			ctx.ev.PublishTaskBegin(t, ctx.TaskID(t))
+			err = t.Execute(ctx)
+			ctx.finishStep(t)
+			ctx.ev.PublishTaskFinish(t, err)
+			if err != nil {
+				ctx.recordFailure(t, err)
+			}
+		}
+		atomic.AddInt32(&s.done, 1)
+		if err != nil {
+			ctx.breakOnError(t, err)
+			ctx.recordStepFailure(t)
+			if !ctx.handleFailure(s.mode, t, err, degraded) {
+				return err
+			}
+		}
 	}
-	return nil
+	return degraded.result(ctx)
 }
 
 // Rollback implements the Task interface
@@ -226,36 +524,114 @@ func NewParallel(hideDetailDisplay bool, tasks ...Task) *Parallel {
 
 // Execute implements the Task interface
 func (pt *Parallel) Execute(ctx *Context) error {
+	root := ctx.enterRoot(pt)
+	if root {
+		defer ctx.exitRoot()
+	}
+	if err := ctx.checkDeadline(pt); err != nil {
+		return err
+	}
 	var firstError error
+	degraded := &DegradedError{}
 	var mu sync.Mutex
 	wg := sync.WaitGroup{}
-	for _, t := range pt.inner {
+	pt.finished.Lock()
+	pt.finished.order = nil
+	pt.finished.Unlock()
+	var limit chan struct{}
+	if ctx.concurrency > 0 && !pt.serial {
+		limit = make(chan struct{}, ctx.concurrency)
+	}
+	for i, t := range pt.inner {
 		wg.Add(1)
-		go func(t Task) {
+		run := func(i int, t Task) {
 			defer wg.Done()
+			if limit != nil {
+				defer func() { <-limit }()
+			}
 			if !isDisplayTask(t) {
 				if !pt.hideDetailDisplay {
-					log.Infof("+ [Parallel] - %s", t.String())
+					if pt.serial {
+						log.Infof("+ [ Serial ] - %s", t.String())
+					} else {
+						log.Infof("+ [Parallel] - %s", t.String())
+					}
 				}
 			}
-			ctx.ev.PublishTaskBegin(t)
-			err := t.Execute(ctx)
-			ctx.ev.PublishTaskFinish(t, err)
+			err := ctx.skipFailedHost(pt.mode, t)
+			if err == nil {
+				ctx.beginStep(t, root)
+				ctx.ev.PublishTaskBegin(t, ctx.TaskID(t))
+				err = t.Execute(ctx)
+				ctx.finishStep(t)
+				ctx.ev.PublishTaskFinish(t, err)
+				if err != nil {
+					ctx.recordFailure(t, err)
+				}
+			}
+			pt.finished.Lock()
+			pt.finished.order = append(pt.finished.order, i)
+			pt.finished.Unlock()
 			if err != nil {
+				ctx.recordStepFailure(t)
 				mu.Lock()
-				if firstError == nil {
-					firstError = err
+				// the siblings executing are never interrupted, the failures
+				// keep being collected until all of them finish
+				if !ctx.handleFailure(pt.mode, t, err, degraded) {
+					// prefer a genuine failure to a skip on a degraded host
+					if _, skipped := IsHostDegraded(firstError); firstError == nil || skipped {
+						firstError = err
+					}
 				}
 				mu.Unlock()
 			}
-		}(t)
+		}
+		if pt.serial {
+			run(i, t)
+			continue
+		}
+		if limit != nil {
+			limit <- struct{}{}
+		}
+		go run(i, t)
 	}
 	wg.Wait()
-	return firstError
+	if firstError != nil {
+		return firstError
+	}
+	return degraded.result(ctx)
+}
+
+// finishOrder returns the indexes of the inner tasks in the order they
+// finished executing
+func (pt *Parallel) finishOrder() []int {
+	pt.finished.Lock()
+	defer pt.finished.Unlock()
+	return append([]int{}, pt.finished.order...)
+}
+
+// restoreFinishOrder restores the order the inner tasks finished executing
+// in an earlier run, e.g. saved in a checkpoint, so the tasks are rolled back
+// in order after being resumed. The orders of other tasks are ignored.
+func (pt *Parallel) restoreFinishOrder(order []int) {
+	seen := make(map[int]struct{}, len(order))
+	for _, i := range order {
+		if _, ok := seen[i]; ok || i < 0 || i >= len(pt.inner) {
+			return
+		}
+		seen[i] = struct{}{}
+	}
+	pt.finished.Lock()
+	defer pt.finished.Unlock()
+	pt.finished.order = append([]int{}, order...)
 }
 
 // Rollback implements the Task interface
 func (pt *Parallel) Rollback(ctx *Context) error {
+	if pt.orderedRollback {
+		return pt.rollbackInOrder(ctx)
+	}
+
 	var firstError error
 	var mu sync.Mutex
 	wg := sync.WaitGroup{}
@@ -277,6 +653,97 @@ func (pt *Parallel) Rollback(ctx *Context) error {
 	return firstError
 }
 
+// rollbackInOrder rolls back the inner tasks in the reverse order they
+// finished, as later tasks may depend on what the former ones created. The
+// tasks which never finished are rolled back first, as they started last.
+// The tasks of the hosts independent of each other are rolled back
+// concurrently, see rollbackGroups.
+// A failed rollback doesn't stop the rest, the failures are returned together.
+func (pt *Parallel) rollbackInOrder(ctx *Context) error {
+	order := pt.finishOrder()
+	finished := make(map[int]struct{}, len(order))
+	for _, i := range order {
+		finished[i] = struct{}{}
+	}
+	for i := range pt.inner {
+		if _, ok := finished[i]; !ok {
+			order = append(order, i)
+		}
+	}
+
+	var errs []error
+	var mu sync.Mutex
+	wg := sync.WaitGroup{}
+	for _, group := range rollbackGroups(pt.inner, order) {
+		wg.Add(1)
+		go func(group []int) {
+			defer wg.Done()
+			for _, i := range group {
+				t := pt.inner[i]
+				if err := t.Rollback(ctx); err != nil {
+					mu.Lock()
+					errs = append(errs, errors.Annotatef(err, "failed to roll back %s", t))
+					mu.Unlock()
+				}
+			}
+		}(group)
+	}
+	wg.Wait()
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	sort.Strings(msgs)
+	return errors.Errorf("%d task(s) failed to roll back:\n%s", len(errs), strings.Join(msgs, "\n"))
+}
+
+// rollbackGroups splits the tasks into the groups rolled back concurrently,
+// each of them in the reverse of order. The tasks sharing a host are in the
+// same group, and so are the tasks of no host, as they may depend on any.
+func rollbackGroups(tasks []Task, order []int) [][]int {
+	// the hosts are joined by the tasks sharing them
+	parent := make(map[string]string)
+	var root func(host string) string
+	root = func(host string) string {
+		if p, ok := parent[host]; ok && p != host {
+			parent[host] = root(p)
+			return parent[host]
+		}
+		parent[host] = host
+		return host
+	}
+	hosts := make([][]string, len(tasks))
+	for i, t := range tasks {
+		hosts[i] = hostsOf(t)
+		for _, host := range hosts[i] {
+			parent[root(host)] = root(hosts[i][0])
+		}
+	}
+
+	var groups [][]int
+	index := make(map[string]int)
+	for i := len(order) - 1; i >= 0; i-- {
+		key := ""
+		if h := hosts[order[i]]; len(h) > 0 {
+			key = root(h[0])
+		}
+		g, ok := index[key]
+		if !ok {
+			g = len(groups)
+			index[key] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], order[i])
+	}
+	return groups
+}
+
 // String implements the fmt.Stringer interface
 func (pt *Parallel) String() string {
 	var ss []string
@@ -0,0 +1,171 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/executor"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// TransferOptions controls how files are pushed to hosts. Files larger than
+// ChunkSize are split into chunks of ChunkSize, which are pushed over at most
+// Parallel SSH sessions at the same time, and reassembled on the host. The
+// files are pushed in a single stream if ChunkSize is 0 or Parallel is less
+// than 2.
+type TransferOptions struct {
+	ChunkSize int64 // in bytes
+	Parallel  int
+}
+
+// NewTransferOptions returns the transfer options of the operation options
+func NewTransferOptions(opt operator.Options) TransferOptions {
+	return TransferOptions{
+		ChunkSize: opt.TransferChunkSize * 1024 * 1024,
+		Parallel:  opt.TransferParallel,
+	}
+}
+
+func (o TransferOptions) chunked(size int64) bool {
+	return o.ChunkSize > 0 && o.Parallel > 1 && size > o.ChunkSize
+}
+
+// transferFile pushes the local file src to dst on the host, in chunks if the
+// file is large enough. The single-stream transfer is used instead if there
+// isn't enough space for the chunks next to dst on the host.
+func transferFile(e executor.Executor, host, src, dst string, opts TransferOptions) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	if !opts.chunked(info.Size()) {
+		return e.Transfer(src, dst, false)
+	}
+
+	// the chunks and the reassembled file are both on the host for a while
+	chunkDir := dst + ".chunks"
+	if !hasSpace(e, chunkDir, info.Size()*2) {
+		log.Debugf("Not enough space for the chunks of %s on %s, transfer it in a single stream", path.Base(src), host)
+		_, _, _ = e.Execute(fmt.Sprintf("rm -rf %s", chunkDir), false)
+		return e.Transfer(src, dst, false)
+	}
+	defer func() {
+		_, _, _ = e.Execute(fmt.Sprintf("rm -rf %s", chunkDir), false)
+	}()
+
+	checksum, err := utils.Checksum(src)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+
+	count := int((info.Size() + opts.ChunkSize - 1) / opts.ChunkSize)
+	log.Debugf("Transfer %s to %s in %d chunks", path.Base(src), host, count)
+	errs := make([]error, count)
+	limit := make(chan struct{}, opts.Parallel)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			errs[i] = transferChunk(e, src, chunkPath(chunkDir, i), int64(i)*opts.ChunkSize, opts.ChunkSize)
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return errors.Annotatef(err, "failed to transfer chunk %d of %s", i, path.Base(src))
+		}
+	}
+
+	// the names of chunks are zero-padded, so the glob sorts them in order
+	cmd := fmt.Sprintf("cat %s/chunk-* > %s && sha1sum %s", chunkDir, dst, dst)
+	stdout, stderr, err := e.Execute(cmd, false)
+	if err != nil {
+		return errors.Annotatef(err, "failed to reassemble %s: %s", dst, string(stderr))
+	}
+	if fields := strings.Fields(string(stdout)); len(fields) == 0 || fields[0] != checksum {
+		_, _, _ = e.Execute(fmt.Sprintf("rm -f %s", dst), false)
+		return errors.Errorf("checksum mismatch of %s reassembled on %s", dst, host)
+	}
+	return nil
+}
+
+func chunkPath(chunkDir string, i int) string {
+	return path.Join(chunkDir, fmt.Sprintf("chunk-%06d", i))
+}
+
+// transferChunk pushes the range of src to dst through a local temporary
+// file, as the executors only transfer whole files
+func transferChunk(e executor.Executor, src, dst string, offset, size int64) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer f.Close()
+
+	tmp, err := ioutil.TempFile("", "tiup-chunk-")
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, io.NewSectionReader(f, offset, size))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	return e.Transfer(tmp.Name(), dst, false)
+}
+
+// hasSpace creates dir on the host without sudo and checks if the file
+// system of it has at least size bytes available
+func hasSpace(e executor.Executor, dir string, size int64) bool {
+	avail, ok := availableSpace(e, dir)
+	return ok && avail >= size
+}
+
+// availableSpace creates dir on the host without sudo and returns the bytes
+// available in the file system of it, false if it can't be told
+func availableSpace(e executor.Executor, dir string) (int64, bool) {
+	stdout, _, err := e.Execute(fmt.Sprintf("mkdir -p %s && df -Pk %s | tail -n 1", dir, dir), false)
+	if err != nil {
+		return 0, false
+	}
+	// Filesystem 1024-blocks Used Available Capacity Mounted-on
+	fields := strings.Fields(string(stdout))
+	if len(fields) < 4 {
+		return 0, false
+	}
+	avail, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return avail * 1024, true
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/check"
+)
+
+// shellExecutor runs commands by the local shell and counts the transfers
+type shellExecutor struct {
+	transfers int32
+	noSpace   bool
+}
+
+func (e *shellExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	if e.noSpace {
+		cmd = "false"
+	}
+	var stdout, stderr bytes.Buffer
+	command := exec.Command("bash", "-c", cmd)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	err := command.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+func (e *shellExecutor) Transfer(src string, dst string, download bool) error {
+	atomic.AddInt32(&e.transfers, 1)
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dst, data, 0644)
+}
+
+type transferSuite struct{}
+
+var _ = check.Suite(&transferSuite{})
+
+func (s *transferSuite) TestChunkedTransfer(c *check.C) {
+	dir, err := ioutil.TempDir("", "tiup-transfer-test")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1000)
+	src := filepath.Join(dir, "src.tar.gz")
+	c.Assert(ioutil.WriteFile(src, data, 0644), check.IsNil)
+	opts := TransferOptions{ChunkSize: 4096, Parallel: 3}
+
+	e := &shellExecutor{}
+	dst := filepath.Join(dir, "dst.tar.gz")
+	c.Assert(transferFile(e, "host", src, dst, opts), check.IsNil)
+	got, err := ioutil.ReadFile(dst)
+	c.Assert(err, check.IsNil)
+	c.Assert(bytes.Equal(got, data), check.IsTrue)
+	c.Assert(e.transfers, check.Equals, int32(4))
+	_, err = os.Stat(dst + ".chunks")
+	c.Assert(os.IsNotExist(err), check.IsTrue)
+
+	// small files are transferred in a single stream
+	e = &shellExecutor{}
+	c.Assert(transferFile(e, "host", src, dst, TransferOptions{ChunkSize: 1 << 20, Parallel: 3}), check.IsNil)
+	c.Assert(e.transfers, check.Equals, int32(1))
+
+	// fall back to the single stream if there is no space for the chunks
+	e = &shellExecutor{noSpace: true}
+	c.Assert(os.Remove(dst), check.IsNil)
+	c.Assert(transferFile(e, "host", src, dst, opts), check.IsNil)
+	c.Assert(e.transfers, check.Equals, int32(1))
+	got, err = ioutil.ReadFile(dst)
+	c.Assert(err, check.IsNil)
+	c.Assert(bytes.Equal(got, data), check.IsTrue)
+}
@@ -0,0 +1,239 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// the status of a node of a task tree
+const (
+	TaskStatusPending   = "pending"
+	TaskStatusRunning   = "running"
+	TaskStatusSucceeded = "succeeded"
+	TaskStatusFailed    = "failed"
+)
+
+// TaskNode is the summary of a task and the tasks in it, as they are executed
+type TaskNode struct {
+	// the identity of the task, see Context.TaskID, it's empty for the
+	// groups not executed by Serial or Parallel
+	ID       string        `json:"id,omitempty"`
+	Name     string        `json:"name"`
+	Kind     string        `json:"kind"`
+	Hosts    []string      `json:"hosts,omitempty"`
+	Status   string        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Begin    *time.Time    `json:"begin,omitempty"`
+	End      *time.Time    `json:"end,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Children []*TaskNode   `json:"children,omitempty"`
+	// the indexes of the children in the order they finished, only for the
+	// parallel groups rolled back in order, see Builder.OrderedRollback
+	FinishOrder []int `json:"finish_order,omitempty"`
+
+	// the task reported its begin
+	tracked bool
+	// the parallel group of the node, nil if it's not a group
+	parallel *Parallel
+}
+
+// Walk calls fn on the node and the nodes in it, depth first
+func (n *TaskNode) Walk(fn func(node *TaskNode, depth int)) {
+	n.walk(fn, 0)
+}
+
+func (n *TaskNode) walk(fn func(node *TaskNode, depth int), depth int) {
+	fn(n, depth)
+	for _, child := range n.Children {
+		child.walk(fn, depth+1)
+	}
+}
+
+// TaskTree records the status and durations of the tasks of a built task
+// as they are executed, in the shape of the plan, see WritePlan
+type TaskTree struct {
+	mu    sync.Mutex
+	root  *TaskNode
+	nodes map[Task]*TaskNode
+}
+
+// NewTaskTree returns the tree of the task, all the tasks in it are pending
+func NewTaskTree(t Task) *TaskTree {
+	tree := &TaskTree{nodes: make(map[Task]*TaskNode)}
+	tree.root = tree.build(t)
+	return tree
+}
+
+// taskKey is false if the task can't be a map key, e.g. the task isn't a
+// pointer and has slices in it
+func taskKey(t Task) bool {
+	return t != nil && reflect.TypeOf(t).Comparable()
+}
+
+func (tree *TaskTree) build(t Task) *TaskNode {
+	node := &TaskNode{Name: planLabel(stepName(t)), Status: TaskStatusPending}
+	if taskKey(t) {
+		tree.nodes[t] = node
+	}
+	switch tt := t.(type) {
+	case *Serial:
+		node.Kind = "serial"
+		node.Children = tree.buildAll(tt.inner)
+	case *Parallel:
+		node.Kind = "parallel"
+		node.Children = tree.buildAll(tt.inner)
+		node.parallel = tt
+	case *StepDisplay:
+		node.Kind = "step"
+		// the serial built in a step is a detail of the step
+		if s, ok := tt.inner.(*Serial); ok {
+			node.Children = tree.buildAll(s.inner)
+		} else {
+			node.Children = []*TaskNode{tree.build(tt.inner)}
+		}
+	case *ParallelStepDisplay:
+		node.Kind = "parallel step"
+		node.Name = planLabel(tt.prefix)
+		node.Children = tree.buildAll(tt.inner.inner)
+		node.parallel = tt.inner
+	default:
+		node.Kind = "task"
+	}
+	return node
+}
+
+func (tree *TaskTree) buildAll(tasks []Task) []*TaskNode {
+	nodes := make([]*TaskNode, 0, len(tasks))
+	for _, t := range tasks {
+		nodes = append(nodes, tree.build(t))
+	}
+	return nodes
+}
+
+// Track records the tasks executed with the context in the tree, onFinish is
+// called after a task finishes if it's not nil
+func (tree *TaskTree) Track(ctx *Context, onFinish func()) {
+	ctx.Subscribe(EventTaskBegin, func(t Task, id string) {
+		if !taskKey(t) {
+			return
+		}
+		hosts := hostsOf(t)
+		now := time.Now()
+		tree.mu.Lock()
+		if node, ok := tree.nodes[t]; ok {
+			node.ID = id
+			node.Hosts = hosts
+			node.Status = TaskStatusRunning
+			node.Begin = &now
+			node.tracked = true
+		}
+		tree.mu.Unlock()
+	})
+	ctx.Subscribe(EventTaskFinish, func(t Task, err error) {
+		if !taskKey(t) {
+			return
+		}
+		now := time.Now()
+		tree.mu.Lock()
+		if node, ok := tree.nodes[t]; ok {
+			node.Status = TaskStatusSucceeded
+			if err != nil {
+				node.Status = TaskStatusFailed
+				node.Error = err.Error()
+			}
+			node.End = &now
+			if node.Begin != nil {
+				node.Duration = now.Sub(*node.Begin)
+			}
+		}
+		tree.mu.Unlock()
+		if onFinish != nil {
+			onFinish()
+		}
+	})
+}
+
+// Snapshot returns a copy of the tree, the status and durations of the groups
+// not tracked are derived from the tasks in them
+func (tree *TaskTree) Snapshot() *TaskNode {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	return snapshot(tree.root)
+}
+
+// Restore restores the orders the parallel groups finished in from a snapshot
+// of the tree saved by an earlier run, so the groups finished then are rolled
+// back in order after resuming. Only the groups of the same shape in both
+// trees are restored, as a resumed run may skip some of the tasks.
+func (tree *TaskTree) Restore(saved *TaskNode) {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	restore(tree.root, saved)
+}
+
+func restore(n, saved *TaskNode) {
+	if saved == nil || n.Name != saved.Name || n.Kind != saved.Kind || len(n.Children) != len(saved.Children) {
+		return
+	}
+	if n.parallel != nil && len(saved.FinishOrder) > 0 {
+		n.parallel.restoreFinishOrder(saved.FinishOrder)
+	}
+	for i, child := range n.Children {
+		restore(child, saved.Children[i])
+	}
+}
+
+func snapshot(n *TaskNode) *TaskNode {
+	cp := *n
+	cp.Hosts = append([]string(nil), n.Hosts...)
+	if n.parallel != nil && n.parallel.orderedRollback {
+		cp.FinishOrder = n.parallel.finishOrder()
+	}
+	cp.Children = make([]*TaskNode, 0, len(n.Children))
+	for _, child := range n.Children {
+		cp.Children = append(cp.Children, snapshot(child))
+	}
+	if n.tracked || len(cp.Children) == 0 {
+		return &cp
+	}
+
+	counts := make(map[string]int)
+	for _, child := range cp.Children {
+		counts[child.Status]++
+		if child.Begin != nil && (cp.Begin == nil || child.Begin.Before(*cp.Begin)) {
+			cp.Begin = child.Begin
+		}
+		if child.End != nil && (cp.End == nil || child.End.After(*cp.End)) {
+			cp.End = child.End
+		}
+	}
+	switch {
+	case counts[TaskStatusFailed] > 0:
+		cp.Status = TaskStatusFailed
+	case counts[TaskStatusSucceeded] == len(cp.Children):
+		cp.Status = TaskStatusSucceeded
+	case counts[TaskStatusRunning] > 0 || counts[TaskStatusSucceeded] > 0:
+		cp.Status = TaskStatusRunning
+	}
+	if cp.Status == TaskStatusRunning || cp.Status == TaskStatusPending {
+		cp.End = nil
+	}
+	if cp.Begin != nil && cp.End != nil {
+		cp.Duration = cp.End.Sub(*cp.Begin)
+	}
+	return &cp
+}
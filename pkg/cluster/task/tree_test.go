@@ -0,0 +1,77 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+
+	"github.com/pingcap/check"
+)
+
+type treeSuite struct{}
+
+var _ = check.Suite(&treeSuite{})
+
+func (s *treeSuite) TestTaskTree(c *check.C) {
+	noop := func(ctx *Context) error { return nil }
+	t := NewBuilder().
+		Step("+ Prepare", NewBuilder().Func("GenerateKeys", noop).Func("CopyKeys", noop).Build()).
+		ParallelStep("+ Start",
+			NewBuilder().Func("StartPD", noop).BuildAsStep("  - Start pd"),
+			NewBuilder().Func("StartTiKV", func(ctx *Context) error { return errors.New("timed out") }).BuildAsStep("  - Start tikv"),
+		).
+		Func("CheckStatus", noop).
+		Build()
+
+	tree := NewTaskTree(t)
+	root := tree.Snapshot()
+	c.Assert(root.Status, check.Equals, TaskStatusPending)
+	c.Assert(root.Children, check.HasLen, 3)
+	c.Assert(root.Children[0].Name, check.Equals, "Prepare")
+	c.Assert(root.Children[0].Kind, check.Equals, "step")
+	c.Assert(root.Children[0].Children, check.HasLen, 2)
+	c.Assert(root.Children[1].Kind, check.Equals, "parallel step")
+
+	ctx := NewContext()
+	finished := 0
+	tree.Track(ctx, func() { finished++ })
+	c.Assert(t.Execute(ctx), check.NotNil)
+	c.Assert(finished > 0, check.IsTrue)
+
+	root = tree.Snapshot()
+	c.Assert(root.Status, check.Equals, TaskStatusFailed)
+	prepare := root.Children[0]
+	c.Assert(prepare.Status, check.Equals, TaskStatusSucceeded)
+	c.Assert(prepare.Children[0].ID, check.Equals, "GenerateKeys")
+	c.Assert(prepare.Children[1].Status, check.Equals, TaskStatusSucceeded)
+	c.Assert(prepare.Children[1].End, check.NotNil)
+
+	start := root.Children[1]
+	c.Assert(start.Status, check.Equals, TaskStatusFailed)
+	var failed *TaskNode
+	start.Walk(func(node *TaskNode, depth int) {
+		if node.ID == "StartTiKV" {
+			failed = node
+		}
+	})
+	c.Assert(failed, check.NotNil)
+	c.Assert(failed.Status, check.Equals, TaskStatusFailed)
+	c.Assert(failed.Error, check.Equals, "timed out")
+	c.Assert(root.Children[2].Status, check.Equals, TaskStatusPending)
+	c.Assert(root.Children[2].Begin, check.IsNil)
+
+	// the snapshot doesn't change with the tree
+	prepare.Children[0].Status = TaskStatusFailed
+	c.Assert(tree.Snapshot().Children[0].Children[0].Status, check.Equals, TaskStatusSucceeded)
+}
@@ -0,0 +1,227 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/embed"
+	"gopkg.in/yaml.v2"
+)
+
+// the markers of the blocks regenerated by tiup in the sections of the
+// Prometheus config, the lines outside the blocks are added by the users
+const (
+	prometheusManagedBegin = "# BEGIN managed by tiup"
+	prometheusManagedEnd   = "# END managed by tiup"
+)
+
+// the sections of the Prometheus config which could be added to by the users
+var prometheusMergedSections = []string{"rule_files", "scrape_configs"}
+
+// PrometheusConfigMerge is the result of merging the existing Prometheus
+// config into the one regenerated
+type PrometheusConfigMerge struct {
+	PreservedJobs      []string // the scrape jobs added by the users
+	PreservedRuleFiles []string // the rule files added by the users
+	// the existing config has no managed blocks, e.g., generated by an older
+	// version, so the user added parts are told by the names of them
+	Legacy bool
+}
+
+// Preserved checks if anything added by the users is preserved
+func (m *PrometheusConfigMerge) Preserved() bool {
+	return len(m.PreservedJobs) > 0 || len(m.PreservedRuleFiles) > 0
+}
+
+// MergePrometheusConfig preserves the scrape jobs and rule files added to the
+// existing config outside the blocks managed by tiup, the managed blocks are
+// replaced by the ones of the generated config.
+func MergePrometheusConfig(generated, existing []byte) ([]byte, *PrometheusConfigMerge, error) {
+	result := &PrometheusConfigMerge{}
+	if len(strings.TrimSpace(string(existing))) == 0 {
+		return generated, result, nil
+	}
+
+	var custom map[string][]string
+	if strings.Contains(string(existing), prometheusManagedBegin) {
+		custom = customLines(string(existing))
+	} else {
+		var err error
+		if custom, err = legacyCustomLines(generated, existing); err != nil {
+			return nil, nil, err
+		}
+		result.Legacy = true
+	}
+
+	result.PreservedRuleFiles = itemNames(custom["rule_files"], regexp.MustCompile(`^\s*-\s*['"]?([^'"#]+?)['"]?\s*$`))
+	result.PreservedJobs = itemNames(custom["scrape_configs"], regexp.MustCompile(`^\s*-?\s*job_name:\s*['"]?([^'"#]+?)['"]?\s*$`))
+	if !result.Preserved() {
+		return generated, result, nil
+	}
+
+	var out []string
+	section := ""
+	for _, line := range strings.Split(string(generated), "\n") {
+		if key, ok := topLevelKey(line); ok {
+			section = key
+		}
+		out = append(out, line)
+		if strings.TrimSpace(line) == prometheusManagedEnd {
+			out = append(out, custom[section]...)
+		}
+	}
+	return []byte(strings.Join(out, "\n")), result, nil
+}
+
+// topLevelKey returns the key if the line starts a top level section
+func topLevelKey(line string) (string, bool) {
+	if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' || line[0] == '-' {
+		return "", false
+	}
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return "", false
+	}
+	return line[:i], true
+}
+
+// customLines returns the lines outside the managed blocks of the merged
+// sections, the trailing blank lines are dropped
+func customLines(config string) map[string][]string {
+	custom := make(map[string][]string)
+	section := ""
+	managed := false
+	for _, line := range strings.Split(config, "\n") {
+		if key, ok := topLevelKey(line); ok {
+			section = key
+			managed = false
+			continue
+		}
+		// the begin marker is followed by the hint of it
+		if strings.HasPrefix(strings.TrimSpace(line), prometheusManagedBegin) {
+			managed = true
+			continue
+		}
+		if strings.TrimSpace(line) == prometheusManagedEnd {
+			managed = false
+			continue
+		}
+		if managed || !isMergedSection(section) {
+			continue
+		}
+		custom[section] = append(custom[section], line)
+	}
+	for section, lines := range custom {
+		for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+		custom[section] = lines
+	}
+	return custom
+}
+
+func isMergedSection(section string) bool {
+	for _, s := range prometheusMergedSections {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
+
+// legacyCustomLines returns the scrape jobs and rule files of the existing
+// config which are neither generated nor ones tiup could generate
+func legacyCustomLines(generated, existing []byte) (map[string][]string, error) {
+	var gen, old struct {
+		RuleFiles     []string        `yaml:"rule_files"`
+		ScrapeConfigs []yaml.MapSlice `yaml:"scrape_configs"`
+	}
+	if err := yaml.Unmarshal(generated, &gen); err != nil {
+		return nil, errors.Annotate(err, "parse the generated Prometheus config")
+	}
+	if err := yaml.Unmarshal(existing, &old); err != nil {
+		return nil, errors.Annotate(err, "parse the existing Prometheus config")
+	}
+	tpl, err := embed.ReadFile(path.Join("/templates", "config", "prometheus.yml.tpl"))
+	if err != nil {
+		return nil, err
+	}
+
+	// the names in the template, the template actions match anything
+	action := regexp.MustCompile(`\{\{[^}]*\}\}`)
+	managed := func(re, name string, generated []string) bool {
+		for _, n := range generated {
+			if n == name {
+				return true
+			}
+		}
+		for _, m := range regexp.MustCompile(re).FindAllStringSubmatch(string(tpl), -1) {
+			pattern := "^" + strings.Join(strings.Split(regexp.QuoteMeta(action.ReplaceAllString(m[1], "\x00")), "\x00"), ".*") + "$"
+			// the names made of actions only, e.g., the targets, match anything
+			if pattern != "^.*$" && regexp.MustCompile(pattern).MatchString(name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	custom := make(map[string][]string)
+	for _, f := range old.RuleFiles {
+		if !managed(`-\s*'([^']+\.rules\.yml)'`, f, gen.RuleFiles) {
+			custom["rule_files"] = append(custom["rule_files"], "  - '"+f+"'")
+		}
+	}
+	var genJobs []string
+	for _, job := range gen.ScrapeConfigs {
+		genJobs = append(genJobs, jobName(job))
+	}
+	for _, job := range old.ScrapeConfigs {
+		if managed(`job_name:\s*['"]([^'"]+)['"]`, jobName(job), genJobs) {
+			continue
+		}
+		data, err := yaml.Marshal([]yaml.MapSlice{job})
+		if err != nil {
+			return nil, errors.AddStack(err)
+		}
+		for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+			custom["scrape_configs"] = append(custom["scrape_configs"], "  "+line)
+		}
+	}
+	return custom, nil
+}
+
+func jobName(job yaml.MapSlice) string {
+	for _, item := range job {
+		if item.Key == "job_name" {
+			name, _ := item.Value.(string)
+			return name
+		}
+	}
+	return ""
+}
+
+// itemNames returns the first submatches of the lines matching re
+func itemNames(lines []string, re *regexp.Regexp) []string {
+	var names []string
+	for _, line := range lines {
+		if m := re.FindStringSubmatch(line); m != nil {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
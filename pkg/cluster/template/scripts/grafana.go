@@ -24,20 +24,28 @@ import (
 
 // GrafanaScript represent the data to generate Grafana config
 type GrafanaScript struct {
-	ClusterName string
-	DeployDir   string
-	NumaNode    string
-	tplName     string
+	ClusterName    string
+	DeployDir      string
+	NumaNode       string
+	ProvisionFiles bool // provision the dashboards and the datasource from files
+	tplName        string
 }
 
 // NewGrafanaScript returns a GrafanaScript with given arguments
 func NewGrafanaScript(cluster, deployDir string) *GrafanaScript {
 	return &GrafanaScript{
-		ClusterName: cluster,
-		DeployDir:   deployDir,
+		ClusterName:    cluster,
+		DeployDir:      deployDir,
+		ProvisionFiles: true,
 	}
 }
 
+// WithProvisionFiles set ProvisionFiles field of GrafanaScript
+func (c *GrafanaScript) WithProvisionFiles(provision bool) *GrafanaScript {
+	c.ProvisionFiles = provision
+	return c
+}
+
 // WithNumaNode set NumaNode field of GrafanaScript
 func (c *GrafanaScript) WithNumaNode(numa string) *GrafanaScript {
 	c.NumaNode = numa
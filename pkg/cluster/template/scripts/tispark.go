@@ -94,22 +94,51 @@ func (c *TiSparkEnv) ScriptWithTemplate(tpl string) ([]byte, error) {
 	return content.Bytes(), nil
 }
 
-// SlaveScriptWithTemplate parses the template file
-func (c *TiSparkEnv) SlaveScriptWithTemplate() ([]byte, error) {
+// Config implements the template.ConfigGenerator interface
+func (c *TiSparkEnv) Config() ([]byte, error) {
+	return c.Script()
+}
+
+// ConfigWithTemplate implements the template.ConfigGenerator interface
+func (c *TiSparkEnv) ConfigWithTemplate(tpl string) ([]byte, error) {
+	return c.ScriptWithTemplate(tpl)
+}
+
+// ConfigToFile implements the template.ConfigGenerator interface
+func (c *TiSparkEnv) ConfigToFile(file string) error {
+	return c.ScriptToFile(file)
+}
+
+// TiSparkSlaveScript generates the start-slave.sh of TiSpark workers from
+// the variables of TiSparkEnv
+type TiSparkSlaveScript struct {
+	*TiSparkEnv
+}
+
+// NewTiSparkSlaveScript returns a TiSparkSlaveScript of env
+func NewTiSparkSlaveScript(env *TiSparkEnv) *TiSparkSlaveScript {
+	return &TiSparkSlaveScript{env}
+}
+
+// Config generates the script content
+func (c *TiSparkSlaveScript) Config() ([]byte, error) {
 	tpl, err := GetScript("start_tispark_slave.sh.tpl")
 	if err != nil {
 		return nil, err
 	}
+	return c.ConfigWithTemplate(string(tpl))
+}
 
-	tmpl, err := template.New("TiSpark").Parse(string(tpl))
-	if err != nil {
-		return nil, err
-	}
+// ConfigWithTemplate generates the script content by tpl
+func (c *TiSparkSlaveScript) ConfigWithTemplate(tpl string) ([]byte, error) {
+	return c.ScriptWithTemplate(tpl)
+}
 
-	content := bytes.NewBufferString("")
-	if err := tmpl.Execute(content, c); err != nil {
-		return nil, err
+// ConfigToFile writes the script content to file
+func (c *TiSparkSlaveScript) ConfigToFile(file string) error {
+	script, err := c.Config()
+	if err != nil {
+		return err
 	}
-
-	return content.Bytes(), nil
+	return ioutil.WriteFile(file, script, 0755)
 }
@@ -32,6 +32,7 @@ type Config struct {
 	IOWriteBandwidthMax string
 	DeployDir           string
 	DisableSendSigkill  bool
+	EnvironmentFile     string
 	// Takes one of no, on-success, on-failure, on-abnormal, on-watchdog, on-abort, or always.
 	// The Template set as always if this is not setted.
 	Restart string
@@ -70,6 +71,12 @@ func (c *Config) WithIOWriteBandwidthMax(io string) *Config {
 	return c
 }
 
+// WithEnvironmentFile set the EnvironmentFile field of Config
+func (c *Config) WithEnvironmentFile(file string) *Config {
+	c.EnvironmentFile = file
+	return c
+}
+
 // ConfigToFile write config content to specific path
 func (c *Config) ConfigToFile(file string) error {
 	config, err := c.Config()
@@ -25,10 +25,11 @@ import (
 
 // TiSparkConfig represent the data to generate systemd config
 type TiSparkConfig struct {
-	ServiceName string
-	User        string
-	DeployDir   string
-	JavaHome    string
+	ServiceName     string
+	User            string
+	DeployDir       string
+	JavaHome        string
+	EnvironmentFile string
 	// Takes one of no, on-success, on-failure, on-abnormal, on-watchdog, on-abort, or always.
 	// The Template set as always if this is not setted.
 	Restart string
@@ -49,6 +50,12 @@ func NewTiSparkConfig(service, user, deployDir, javaHome string) *TiSparkConfig
 	}
 }
 
+// WithEnvironmentFile set the EnvironmentFile field of TiSparkConfig
+func (c *TiSparkConfig) WithEnvironmentFile(file string) *TiSparkConfig {
+	c.EnvironmentFile = file
+	return c
+}
+
 // ConfigToFile write config content to specific path
 func (c *TiSparkConfig) ConfigToFile(file string) error {
 	config, err := c.Config()
@@ -0,0 +1,329 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cliutil"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"github.com/pingcap/tiup/pkg/logger/log"
+	"github.com/pingcap/tiup/pkg/meta"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/version"
+)
+
+var (
+	errNSVersionSkew = errorx.NewNamespace("version_skew")
+	// ErrVersionNotAdoptable is returned when the versions of the binaries
+	// can't be adopted as the version of the cluster
+	ErrVersionNotAdoptable = errNSVersionSkew.NewType("not_adoptable", errutil.ErrTraitPreCheck)
+)
+
+// versionCommands are the commands printing the versions of the binaries of
+// the components following the version of the cluster, the binaries are
+// relative to the deploy directories. The versions of other components are
+// bound to the version of tiup and not verified.
+var versionCommands = map[string]string{
+	spec.ComponentTiDB:    "bin/tidb-server -V",
+	spec.ComponentTiKV:    "bin/tikv-server -V",
+	spec.ComponentPD:      "bin/pd-server -V",
+	spec.ComponentPump:    "bin/pump -V",
+	spec.ComponentDrainer: "bin/drainer -V",
+	spec.ComponentCDC:     "bin/cdc version",
+	spec.ComponentTiFlash: "bin/tiflash/tiflash version",
+}
+
+// releaseVersionRegexp matches the version in the outputs of versionCommands,
+// some of the components print it without the leading "v"
+var releaseVersionRegexp = regexp.MustCompile(`(?m)^Release Version:\s*(\S+)`)
+
+// InstanceVersion is the version of the binary of an instance compared with
+// the version of the cluster in the metadata
+type InstanceVersion struct {
+	ID        string `json:"id"`
+	Component string `json:"component"`
+	Host      string `json:"host"`
+	Expected  string `json:"expected"`
+	Actual    string `json:"actual,omitempty"`
+	// Skewed is set if the binary isn't the version in the metadata
+	Skewed bool `json:"skewed"`
+	// Error is why the version of the binary is unknown
+	Error string `json:"error,omitempty"`
+}
+
+// VersionReport is the result of verifying the versions of the binaries of
+// a cluster
+type VersionReport struct {
+	Cluster   string            `json:"cluster"`
+	Version   string            `json:"version"`
+	Instances []InstanceVersion `json:"instances"`
+	// Adopted is the version of the binaries adopted as the version of the
+	// cluster, empty if the metadata isn't updated
+	Adopted string `json:"adopted,omitempty"`
+}
+
+// Skewed returns the instances whose binaries aren't the version in the
+// metadata
+func (r *VersionReport) Skewed() []InstanceVersion {
+	var skewed []InstanceVersion
+	for _, v := range r.Instances {
+		if v.Skewed {
+			skewed = append(skewed, v)
+		}
+	}
+	return skewed
+}
+
+// VerifyVersionsOptions are the options of verifying the versions of binaries
+type VerifyVersionsOptions struct {
+	// Update the version of the cluster in the metadata to the one of the
+	// binaries, it's refused if the binaries don't agree on a version
+	Adopt bool
+}
+
+// VerifyVersions runs the binaries of the instances of the cluster to get
+// their versions and compares them with the version in the metadata, e.g.,
+// for the binaries replaced on the hosts by hand. Only the components whose
+// versions follow the version of the cluster are verified.
+func (m *Manager) VerifyVersions(clusterName string, opt VerifyVersionsOptions, gOpt operator.Options) (*VersionReport, error) {
+	if opt.Adopt && (len(gOpt.Roles) > 0 || len(gOpt.Nodes) > 0) {
+		return nil, ErrVersionNotAdoptable.New("adopting the version requires verifying all the instances, drop the --role and --node filters")
+	}
+
+	var metadata spec.Metadata
+	var err error
+	if opt.Adopt {
+		metadata, err = m.metaFresh(clusterName)
+	} else {
+		metadata, err = m.meta(clusterName)
+	}
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) &&
+		!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
+		return nil, perrs.AddStack(err)
+	}
+	topo := metadata.GetTopology()
+	base := metadata.GetBaseMeta()
+
+	report := &VersionReport{
+		Cluster: clusterName,
+		Version: base.Version,
+	}
+	byHost := make(map[string][]int) // host -> indexes of instances in the report
+	for _, inst := range selectInstances(topo, gOpt) {
+		if _, ok := versionCommands[inst.ComponentName()]; !ok {
+			continue
+		}
+		byHost[inst.GetHost()] = append(byHost[inst.GetHost()], len(report.Instances))
+		report.Instances = append(report.Instances, InstanceVersion{
+			ID:        inst.ID(),
+			Component: inst.ComponentName(),
+			Host:      inst.GetHost(),
+			Expected:  m.bindVersion(inst.ComponentName(), base.ComponentVersion(inst.ComponentName())),
+		})
+	}
+	if len(report.Instances) == 0 {
+		log.Warnf("No instance of the components with versions to verify")
+		return report, nil
+	}
+
+	deployDirs := make(map[string]string)
+	topo.IterInstance(func(inst spec.Instance) {
+		deployDirs[inst.ID()] = clusterutil.Abs(base.User, inst.DeployDir())
+	})
+
+	var steps []*task.StepDisplay
+	for host, idxs := range byHost {
+		host, idxs := host, idxs
+		steps = append(steps, task.NewBuilder().
+			Func(fmt.Sprintf("VerifyVersions %s", host), func(ctx *task.Context) error {
+				e, ok := ctx.GetExecutor(host)
+				if !ok {
+					return task.ErrNoExecutor
+				}
+				// each instance is only written by the step of its host
+				for _, idx := range idxs {
+					v := &report.Instances[idx]
+					cmd := filepath.Join(deployDirs[v.ID], versionCommands[v.Component])
+					stdout, stderr, err := e.Execute(cmd, false)
+					if err != nil {
+						v.Error = strings.TrimSpace(string(stderr))
+						if v.Error == "" {
+							v.Error = err.Error()
+						}
+						continue
+					}
+					actual, err := parseReleaseVersion(stdout)
+					if err != nil {
+						v.Error = err.Error()
+						continue
+					}
+					v.Actual = actual
+					// the nightly builds are versioned by the commits
+					v.Skewed = !version.IsNightly(v.Expected) && version.Compare(v.Actual, v.Expected) != 0
+				}
+				return nil
+			}).
+			BuildAsStep(fmt.Sprintf("  - Verifying the versions of binaries on %s", host)))
+	}
+
+	t := task.NewBuilder().
+		SSHKeySet(
+			m.specManager.Path(clusterName, "ssh", "id_rsa"),
+			m.specManager.Path(clusterName, "ssh", "id_rsa.pub")).
+		ClusterSSH(topo, base.User, gOpt.SSHTimeout, gOpt.NativeSSH).
+		ParallelStep("+ Verify the versions of binaries", steps...).
+		Build()
+
+	ctx := task.NewContext()
+	m.applyMock(clusterName, ctx)
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return nil, err
+		}
+		return nil, perrs.Trace(err)
+	}
+	sort.Slice(report.Instances, func(i, j int) bool {
+		return report.Instances[i].ID < report.Instances[j].ID
+	})
+
+	if !opt.Adopt {
+		return report, nil
+	}
+	adopted, err := adoptableVersion(report)
+	if err != nil {
+		return nil, err
+	}
+	if adopted == base.Version && len(base.ComponentVersions) == 0 {
+		log.Infof("The binaries of cluster `%s` are all %s, the metadata is up to date", clusterName, adopted)
+		return report, nil
+	}
+	if err := m.specManager.CheckWritable(); err != nil {
+		return nil, err
+	}
+	if err := m.adoptVersion(clusterName, base, adopted); err != nil {
+		return nil, err
+	}
+	log.Infof("The version of cluster `%s` is updated from %s to %s", clusterName, report.Version, adopted)
+	report.Adopted = adopted
+	return report, nil
+}
+
+// adoptVersion saves adopted as the version of the cluster holding the flock
+// of the cluster dir, as the operation lock is taken, see tryOperationLock.
+// It's refused if the cluster is being operated, or the versions in the
+// metadata are changed since verified, e.g., by an upgrade finished meanwhile.
+func (m *Manager) adoptVersion(clusterName string, verified *spec.BaseMeta, adopted string) error {
+	unlock, err := m.lockClusterDir(clusterName)
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	defer unlock()
+
+	pid, err := m.lockOwner(clusterName)
+	if err != nil {
+		return err
+	}
+	if pid != 0 && pid != os.Getpid() && pidAlive(pid) {
+		return ErrClusterBusy.New("Cluster `%s` is being operated by process %d", clusterName, pid).
+			WithProperty(cliutil.SuggestionFromString("Please wait for the operation to finish and verify the versions again."))
+	}
+
+	metadata, err := m.metaFresh(clusterName)
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) &&
+		!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
+		return perrs.AddStack(err)
+	}
+	base := metadata.GetBaseMeta()
+	if base.Version != verified.Version || !reflect.DeepEqual(base.ComponentVersions, verified.ComponentVersions) {
+		return ErrVersionNotAdoptable.New("the versions of cluster `%s` in the metadata are changed since verified", clusterName).
+			WithProperty(cliutil.SuggestionFromString("Please verify the versions again."))
+	}
+
+	metadata.SetVersion(adopted)
+	if vm, ok := metadata.(spec.ComponentVersionedMetadata); ok {
+		vm.SetComponentVersions(nil)
+	}
+	if err := m.specManager.SaveMeta(clusterName, metadata); err != nil {
+		return perrs.Annotate(err, "failed to save meta")
+	}
+	return nil
+}
+
+// adoptableVersion returns the version all the verified binaries agree on
+func adoptableVersion(report *VersionReport) (string, error) {
+	versions := set.NewStringSet()
+	for _, v := range report.Instances {
+		if v.Error != "" {
+			return "", ErrVersionNotAdoptable.New("the version of %s is unknown: %s", v.ID, v.Error)
+		}
+		versions.Insert(v.Actual)
+	}
+	if len(versions) != 1 {
+		vs := versions.Slice()
+		sort.Strings(vs)
+		return "", ErrVersionNotAdoptable.
+			New("the binaries of the cluster are of different versions %s", strings.Join(vs, ", ")).
+			WithProperty(cliutil.SuggestionFromString("Upgrade or patch the instances to the same version before adopting it."))
+	}
+	return versions.Slice()[0], nil
+}
+
+// parseReleaseVersion parses the version from the output of versionCommands
+func parseReleaseVersion(output []byte) (string, error) {
+	matches := releaseVersionRegexp.FindSubmatch(output)
+	if matches == nil {
+		return "", perrs.Errorf("no release version in the output: %s", strings.TrimSpace(string(output)))
+	}
+	return version.Normalize(string(matches[1])), nil
+}
+
+// PrintVersionReport prints the versions of the binaries of the instances
+// and the ones in the metadata
+func PrintVersionReport(report *VersionReport) {
+	fmt.Printf("Cluster %s, version %s in metadata\n", color.CyanString(report.Cluster), color.CyanString(report.Version))
+	rows := [][]string{{"ID", "Component", "Host", "Metadata", "Binary", "Status"}}
+	for _, v := range report.Instances {
+		actual, status := v.Actual, color.GreenString("ok")
+		switch {
+		case v.Error != "":
+			actual, status = "-", color.YellowString("unknown: %s", v.Error)
+		case v.Skewed:
+			status = color.RedString("skewed")
+		}
+		rows = append(rows, []string{v.ID, v.Component, v.Host, v.Expected, actual, status})
+	}
+	cliutil.PrintTable(rows, true)
+
+	if skewed := report.Skewed(); len(skewed) > 0 && report.Adopted == "" {
+		fmt.Println()
+		log.Warnf("%d instance(s) are not running the version in the metadata, later operations may make wrong assumptions", len(skewed))
+		log.Warnf("Upgrade or patch them to %s, or adopt the versions of the binaries with --adopt", report.Version)
+	}
+}
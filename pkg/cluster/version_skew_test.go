@@ -0,0 +1,82 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/joomcode/errorx"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyVersions(t *testing.T) {
+	m, mc, _, cleanup := newTestMockCluster(t, 2)
+	defer cleanup()
+	for _, host := range []string{"mock-1", "mock-2"} {
+		mc.Host(host).Respond("/home/tidb/deploy/pd-2379/bin/pd-server -V", "Release Version: v4.0.0\nEdition: Community\n", "")
+		mc.Host(host).Respond("/home/tidb/deploy/tikv-20160/bin/tikv-server -V", "TiKV\nRelease Version:   4.0.0\n", "")
+		mc.Host(host).Respond("/home/tidb/deploy/tidb-4000/bin/tidb-server -V", "Release Version: v4.0.0\n", "")
+	}
+	opt := operator.Options{SSHTimeout: 5, OptTimeout: 10, APITimeout: 10}
+
+	report, err := m.VerifyVersions("mock", VerifyVersionsOptions{}, opt)
+	require.Nil(t, err)
+	require.Len(t, report.Instances, 6)
+	assert.Empty(t, report.Skewed())
+	assert.Equal(t, "v4.0.0", report.Instances[0].Actual)
+
+	// a binary replaced by hand
+	mc.Host("mock-2").Respond("/home/tidb/deploy/tidb-4000/bin/tidb-server -V", "Release Version: v4.0.8\n", "")
+	report, err = m.VerifyVersions("mock", VerifyVersionsOptions{}, opt)
+	require.Nil(t, err)
+	skewed := report.Skewed()
+	require.Len(t, skewed, 1)
+	assert.Equal(t, "mock-2:4000", skewed[0].ID)
+	assert.Equal(t, "v4.0.0", skewed[0].Expected)
+	assert.Equal(t, "v4.0.8", skewed[0].Actual)
+
+	// the binaries of different versions can't be adopted
+	_, err = m.VerifyVersions("mock", VerifyVersionsOptions{Adopt: true}, opt)
+	assert.True(t, errorx.IsOfType(err, ErrVersionNotAdoptable))
+	_, err = m.VerifyVersions("mock", VerifyVersionsOptions{Adopt: true}, operator.Options{Roles: []string{"tidb"}})
+	assert.True(t, errorx.IsOfType(err, ErrVersionNotAdoptable))
+
+	for _, host := range []string{"mock-1", "mock-2"} {
+		mc.Host(host).Respond("/home/tidb/deploy/", "Release Version: v4.0.8\n", "")
+	}
+	// the metadata isn't written while the cluster is being operated
+	lockPath := m.specManager.Path("mock", operationLockFile)
+	require.Nil(t, ioutil.WriteFile(lockPath, []byte("1"), 0644))
+	_, err = m.VerifyVersions("mock", VerifyVersionsOptions{Adopt: true}, opt)
+	assert.True(t, errorx.IsOfType(err, ErrClusterBusy))
+	require.Nil(t, os.Remove(lockPath))
+	// nor if the versions are changed since verified
+	err = m.adoptVersion("mock", &spec.BaseMeta{Version: "v4.0.7"}, "v4.0.8")
+	assert.True(t, errorx.IsOfType(err, ErrVersionNotAdoptable))
+
+	report, err = m.VerifyVersions("mock", VerifyVersionsOptions{Adopt: true}, opt)
+	require.Nil(t, err)
+	assert.Equal(t, "v4.0.8", report.Adopted)
+	saved, err := m.meta("mock")
+	require.Nil(t, err)
+	assert.Equal(t, "v4.0.8", saved.GetBaseMeta().Version)
+	report, err = m.VerifyVersions("mock", VerifyVersionsOptions{}, opt)
+	require.Nil(t, err)
+	assert.Empty(t, report.Skewed())
+}
@@ -36,31 +36,28 @@ var (
 	ColorKeyword = color.New(color.FgHiBlue, color.Bold)
 )
 
+// newColorizeFn returns a function returning the escape sequence of the
+// color, it's evaluated on each call so that the color mode set later, e.g.,
+// by tui.SetColorMode, is followed
 func newColorizeFn(c *color.Color) func() string {
 	const sep = "----"
-	seq := c.Sprint(sep)
-	if len(seq) == len(sep) {
-		return func() string {
+	return func() string {
+		seq := c.Sprint(sep)
+		if len(seq) == len(sep) {
 			return ""
 		}
-	}
-	colorSeq := strings.Split(seq, sep)[0]
-	return func() string {
-		return colorSeq
+		return strings.Split(seq, sep)[0]
 	}
 }
 
 func newColorResetFn() func() string {
 	const sep = "----"
-	seq := color.New(color.FgWhite).Sprint(sep)
-	if len(seq) == len(sep) {
-		return func() string {
+	return func() string {
+		seq := color.New(color.FgWhite).Sprint(sep)
+		if len(seq) == len(sep) {
 			return ""
 		}
-	}
-	colorResetSeq := strings.Split(seq, sep)[1]
-	return func() string {
-		return colorResetSeq
+		return strings.Split(seq, sep)[1]
 	}
 }
 
@@ -19,6 +19,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pingcap/errors"
@@ -27,8 +28,7 @@ import (
 	"github.com/pingcap/tiup/pkg/repository/v0manifest"
 	"github.com/pingcap/tiup/pkg/repository/v1manifest"
 	"github.com/pingcap/tiup/pkg/verbose"
-	"github.com/pingcap/tiup/pkg/version"
-	"golang.org/x/mod/semver"
+	tiupver "github.com/pingcap/tiup/pkg/version"
 )
 
 // EnvNameV0 is the name of the env var used to direct TiUp to use old manifests.
@@ -54,30 +54,57 @@ func Mirror() string {
 
 	m := os.Getenv(repository.EnvMirrors)
 	if m != "" {
+		sel := &repository.MirrorSelection{Mirror: m, Source: "env", Reason: "TIUP_MIRRORS is set"}
 		if cfg.Mirror != m {
 			fmt.Printf(`WARNING: both mirror config(%s) 
 and TIUP_MIRRORS(%s) have been set.
 Setting mirror to TIUP_MIRRORS(%s)
 `, cfg.Mirror, m, m)
 			reset(m)
+			if cfg.Mirror != "" {
+				sel.Rejected = []string{cfg.Mirror}
+				sel.Reason = "TIUP_MIRRORS is set and overrides the mirror config"
+			}
 		}
+		repository.NotifyMirrorSelected(sel)
 		return m
 	} else if cfg.Mirror != "" {
 		os.Setenv(repository.EnvMirrors, cfg.Mirror)
+		repository.NotifyMirrorSelected(&repository.MirrorSelection{
+			Mirror: cfg.Mirror,
+			Source: "config",
+			Reason: "the mirror config is set",
+		})
 		return cfg.Mirror
 	}
 
+	repository.NotifyMirrorSelected(&repository.MirrorSelection{
+		Mirror: repository.DefaultMirror,
+		Source: "default",
+		Reason: "neither TIUP_MIRRORS nor the mirror config is set",
+	})
 	return repository.DefaultMirror
 }
 
 // Environment is the user's fundamental configuration including local and remote parts.
+//
+// An Environment is safe for concurrent use by multiple goroutines, e.g. the
+// same GlobalEnv may list and install components at the same time: the local
+// manifest cache, the key store and the profile directory are protected by
+// their own locks and files are replaced atomically. Values returned by the
+// Environment (manifests, versions, etc.) are owned by the caller and must not
+// be shared across goroutines without synchronization.
 type Environment struct {
+	mu sync.RWMutex
 	// profile represents the TiUP local profile
 	profile *localdata.Profile
 	// repo represents the components repository of TiUP, it can be a
 	// local file system or a HTTP URL
 	repo   *repository.Repository
 	v1Repo *repository.V1Repository
+	// the versions not valid SemVer strings are accepted, it's passed on to
+	// the components run, see localdata.EnvNameSkipVersionCheck
+	skipVersionCheck bool
 }
 
 // InitEnv creates a new Environment object configured using env vars and defaults. Uses the EnvNameV0 env var to
@@ -115,13 +142,13 @@ func InitEnv(options repository.Options) (*Environment, error) {
 
 	verbose.Log("Initialize repository finished in %s", time.Since(initRepo))
 
-	return &Environment{profile, repo, v1repo}, nil
+	return &Environment{profile: profile, repo: repo, v1Repo: v1repo, skipVersionCheck: options.SkipVersionCheck}, nil
 }
 
 // NewV0 creates a new Environment with the provided data. Note that environments created with this function do not
 // support v1 repositories.
 func NewV0(profile *localdata.Profile, repo *repository.Repository) *Environment {
-	return &Environment{profile, repo, nil}
+	return &Environment{profile: profile, repo: repo}
 }
 
 // Repository returns the initialized repository
@@ -134,8 +161,15 @@ func (env *Environment) V1Repository() *repository.V1Repository {
 	return env.v1Repo
 }
 
+// SkipVersionCheck returns true if the strict version check is skipped
+func (env *Environment) SkipVersionCheck() bool {
+	return env.skipVersionCheck
+}
+
 // Profile returns the profile of local data
 func (env *Environment) Profile() *localdata.Profile {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
 	return env.profile
 }
 
@@ -150,12 +184,14 @@ func (env *Environment) Close() error {
 
 // SetProfile exports for test
 func (env *Environment) SetProfile(p *localdata.Profile) {
+	env.mu.Lock()
+	defer env.mu.Unlock()
 	env.profile = p
 }
 
 // LocalPath returns the local path absolute path
 func (env *Environment) LocalPath(path ...string) string {
-	return env.profile.Path(filepath.Join(path...))
+	return env.Profile().Path(filepath.Join(path...))
 }
 
 // UpdateComponents updates or installs all components described by specs.
@@ -182,7 +218,7 @@ func (env *Environment) UpdateComponents(specs []string, nightly, force bool) er
 			continue
 		}
 		if nightly {
-			v = version.NightlyVersion
+			v = tiupver.NightlyVersion
 		}
 		if !manifest.HasComponent(component) {
 			compInfo, found := manifest.FindComponent(component)
@@ -246,7 +282,7 @@ func (env *Environment) downloadComponent(component string, version v0manifest.V
 	if err != nil {
 		return err
 	}
-	err = env.profile.SaveVersions(component, versions)
+	err = env.Profile().SaveVersions(component, versions)
 	if err != nil {
 		return err
 	}
@@ -259,7 +295,7 @@ func (env *Environment) downloadComponent(component string, version v0manifest.V
 	}
 	if !overwrite {
 		// Ignore if installed
-		found, err := env.profile.VersionIsInstalled(component, version.String())
+		found, err := env.Profile().VersionIsInstalled(component, version.String())
 		if err != nil {
 			return err
 		}
@@ -275,12 +311,12 @@ func (env *Environment) downloadComponent(component string, version v0manifest.V
 // SelectInstalledVersion selects the installed versions and the latest release version
 // will be chosen if there is an empty version
 func (env *Environment) SelectInstalledVersion(component string, version v0manifest.Version) (v0manifest.Version, error) {
-	return env.profile.SelectInstalledVersion(component, version)
+	return env.Profile().SelectInstalledVersion(component, version)
 }
 
 // DownloadComponentIfMissing downloads the specific version of a component if it is missing
 func (env *Environment) DownloadComponentIfMissing(component string, version v0manifest.Version) (v0manifest.Version, error) {
-	versions, err := env.profile.InstalledVersions(component)
+	versions, err := env.Profile().InstalledVersions(component)
 	if err != nil {
 		return "", err
 	}
@@ -291,7 +327,7 @@ func (env *Environment) DownloadComponentIfMissing(component string, version v0m
 	// Check whether the specific version exist in local
 	if version.IsEmpty() && len(versions) > 0 {
 		sort.Slice(versions, func(i, j int) bool {
-			return semver.Compare(versions[i], versions[j]) < 0
+			return tiupver.Compare(versions[i], versions[j]) < 0
 		})
 		version = v0manifest.Version(versions[len(versions)-1])
 	}
@@ -329,28 +365,49 @@ func (env *Environment) latestManifest() (*v0manifest.ComponentManifest, error)
 	if err != nil {
 		return nil, err
 	}
-	if err := env.profile.SaveManifest(manifest); err != nil {
+	if err := env.Profile().SaveManifest(manifest); err != nil {
 		return nil, err
 	}
 	return manifest, err
 }
 
+// errNoV1Repository is returned by the methods only supported by v1 repositories
+var errNoV1Repository = errors.New("the v1 repository is not initialized")
+
+// GetComponentManifestRaw returns the verified bytes of the manifest of the
+// component, the local manifests are refreshed if they are stale
+func (env *Environment) GetComponentManifestRaw(id string) ([]byte, error) {
+	if env.v1Repo == nil {
+		return nil, errNoV1Repository
+	}
+	return env.v1Repo.FetchComponentManifestRaw(id)
+}
+
+// GetIndexRaw returns the verified bytes of the index manifest, the local
+// manifests are refreshed if they are stale
+func (env *Environment) GetIndexRaw() ([]byte, error) {
+	if env.v1Repo == nil {
+		return nil, errNoV1Repository
+	}
+	return env.v1Repo.FetchIndexManifestRaw()
+}
+
 // GetComponentInstalledVersion return the installed version of component.
 func (env *Environment) GetComponentInstalledVersion(component string, version v0manifest.Version) (v0manifest.Version, error) {
-	return env.profile.GetComponentInstalledVersion(component, version)
+	return env.Profile().GetComponentInstalledVersion(component, version)
 }
 
 // BinaryPath return the installed binary path.
 func (env *Environment) BinaryPath(component string, version v0manifest.Version) (string, error) {
 	if env.v1Repo != nil {
-		installPath, err := env.profile.ComponentInstalledPath(component, version)
+		installPath, err := env.Profile().ComponentInstalledPath(component, version)
 		if err != nil {
 			return "", err
 		}
 		return env.v1Repo.BinaryPath(installPath, component, string(version))
 	}
 
-	return env.profile.BinaryPathV0(component, version)
+	return env.Profile().BinaryPathV0(component, version)
 }
 
 // ParseCompVersion parses component part from <component>[:version] specification
@@ -362,6 +419,26 @@ func ParseCompVersion(spec string) (string, v0manifest.Version) {
 	return spec, ""
 }
 
+// ParseCompBinary parses the binary part from <component>[:version][#binary]
+// specification, the binary is empty if the entry point of the component is
+// invoked
+func ParseCompBinary(spec string) (string, string) {
+	if idx := strings.LastIndex(spec, "#"); idx >= 0 {
+		return spec[:idx], spec[idx+1:]
+	}
+	return spec, ""
+}
+
+// ComponentBinaryPath returns the path of the binary of the installed
+// component version, it's the entry point of the component if the binary
+// is empty
+func (env *Environment) ComponentBinaryPath(component string, version v0manifest.Version, binary string) (string, error) {
+	if binary == "" {
+		return env.BinaryPath(component, version)
+	}
+	return env.Profile().ComponentBinary(component, version, binary)
+}
+
 // IsSupportedComponent return true if support if platform support the component.
 func (env *Environment) IsSupportedComponent(component string) bool {
 	if env.v1Repo != nil {
@@ -13,14 +13,23 @@
 
 package environment
 
-var _env *Environment
+import "sync"
+
+var (
+	_envMu sync.RWMutex
+	_env   *Environment
+)
 
 // SetGlobalEnv the global env used.
 func SetGlobalEnv(env *Environment) {
+	_envMu.Lock()
+	defer _envMu.Unlock()
 	_env = env
 }
 
-// GlobalEnv Get the global env used.
+// GlobalEnv Get the global env used, it's safe for concurrent use.
 func GlobalEnv() *Environment {
+	_envMu.RLock()
+	defer _envMu.RUnlock()
 	return _env
 }
@@ -13,7 +13,10 @@
 
 package errutil
 
-import "github.com/joomcode/errorx"
+import (
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+)
 
 var (
 	// ErrPropSuggestion is a property of an Error that will be printed as the suggestion.
@@ -22,3 +25,16 @@ var (
 	// ErrTraitPreCheck means that the Error is a pre-check error so that no error logs will be outputted directly.
 	ErrTraitPreCheck = errorx.RegisterTrait("pre_check")
 )
+
+// Cast returns the first errorx error in the chain of causes of err, so the
+// errorx errors wrapped by pingcap/errors, e.g., by AddStack, can be handled
+// by their types, nil is returned if there is none
+func Cast(err error) *errorx.Error {
+	for err != nil {
+		if errx := errorx.Cast(err); errx != nil {
+			return errx
+		}
+		err = errors.Unwrap(err)
+	}
+	return nil
+}
@@ -33,15 +33,29 @@ import (
 	"github.com/pingcap/tiup/pkg/localdata"
 	"github.com/pingcap/tiup/pkg/repository/v0manifest"
 	"github.com/pingcap/tiup/pkg/telemetry"
-	"golang.org/x/mod/semver"
+	"github.com/pingcap/tiup/pkg/utils"
+	tiupver "github.com/pingcap/tiup/pkg/version"
 )
 
 // RunComponent start a component and wait it
 func RunComponent(env *environment.Environment, tag, spec, binPath string, args []string) error {
+	spec, binary := environment.ParseCompBinary(spec)
 	component, version := environment.ParseCompVersion(spec)
 	if !env.IsSupportedComponent(component) {
 		return fmt.Errorf("component `%s` does not support `%s/%s` (see `tiup list`)", component, runtime.GOOS, runtime.GOARCH)
 	}
+	if binary != "" {
+		if binPath != "" {
+			return errors.Errorf("the binary `%s` of %s can't be invoked with --binpath", binary, component)
+		}
+		selectVer, err := env.DownloadComponentIfMissing(component, version)
+		if err != nil {
+			return err
+		}
+		if binPath, err = env.ComponentBinaryPath(component, selectVer, binary); err != nil {
+			return err
+		}
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -130,6 +144,32 @@ func base62Tag() string {
 	return string(b)
 }
 
+// checkComponentUpdate prints the hint of updating the component if there is
+// a newer version. The manifests can't be refreshed for that if the profile is
+// mounted read-only, then the check is skipped with a warning.
+func checkComponentUpdate(env *environment.Environment, component string, selectVer v0manifest.Version) error {
+	if err := utils.Writable(env.Profile().Path(localdata.ManifestParentDir)); err != nil {
+		fmt.Fprintln(os.Stderr, color.YellowString("Skip checking the newer versions of %s as the profile is read-only: %s", component, err))
+		return nil
+	}
+
+	latestV, _, err := env.V1Repository().LatestStableVersion(component, true)
+	if err != nil {
+		return err
+	}
+	if tiupver.Compare(selectVer.String(), latestV.String()) < 0 {
+		fmt.Println(color.YellowString(`Found %[1]s newer version:
+
+    The latest version:         %[2]s
+    Local installed version:    %[3]s
+    Update current component:   tiup update %[1]s
+    Update all components:      tiup update --all
+`,
+			component, latestV.String(), selectVer.String()))
+	}
+	return nil
+}
+
 // PrepareCommand will download necessary component and returns a *exec.Cmd
 func PrepareCommand(
 	ctx context.Context,
@@ -146,24 +186,13 @@ func PrepareCommand(
 	}
 
 	if version.IsEmpty() && len(checkUpdate) > 0 && checkUpdate[0] {
-		latestV, _, err := env.V1Repository().LatestStableVersion(component, true)
-		if err != nil {
+		if err := checkComponentUpdate(env, component, selectVer); err != nil {
 			return nil, err
 		}
-		if semver.Compare(selectVer.String(), latestV.String()) < 0 {
-			fmt.Println(color.YellowString(`Found %[1]s newer version:
-
-    The latest version:         %[2]s
-    Local installed version:    %[3]s
-    Update current component:   tiup update %[1]s
-    Update all components:      tiup update --all
-`,
-				component, latestV.String(), selectVer.String()))
-		}
 	}
 
 	// playground && cluster version must greater than v1.0.0
-	if (component == "playground" || component == "cluster") && semver.Compare(selectVer.String(), "v1.0.0") < 0 {
+	if (component == "playground" || component == "cluster") && tiupver.Compare(selectVer.String(), "v1.0.0") < 0 {
 		return nil, errors.Errorf("incompatible component version, please use `tiup update %s` to upgrade to the latest version", component)
 	}
 
@@ -223,6 +252,9 @@ func PrepareCommand(
 		fmt.Sprintf("%s=%s", localdata.EnvNameTelemetryUUID, teleMeta.UUID),
 		fmt.Sprintf("%s=%s", localdata.EnvTag, tag),
 	}
+	if env.SkipVersionCheck() {
+		envs = append(envs, fmt.Sprintf("%s=%s", localdata.EnvNameSkipVersionCheck, "true"))
+	}
 
 	// init the command
 	c := exec.CommandContext(ctx, binPath, args...)
@@ -10,6 +10,9 @@ import (
 	"github.com/pingcap/errors"
 )
 
+// TmpFileSuffix is the suffix of the temp files written by SaveFileWithBackup
+const TmpFileSuffix = ".tmp"
+
 // SaveFileWithBackup will backup the file before save is.
 // back meta.yaml as meta-2006-01-02T15:04:05Z07:00.yaml
 // backup the files in the same dir of path if backupDir is empty.
@@ -55,10 +58,13 @@ func SaveFileWithBackup(path string, data []byte, backupDir string) error {
 		}
 	}
 
-	err = ioutil.WriteFile(path, data, 0644)
+	// write to a temp file and rename it, so a crash never leaves the file
+	// half-written, the temp file may be left behind in that case though
+	tmpPath := path + TmpFileSuffix
+	err = ioutil.WriteFile(tmpPath, data, 0644)
 	if err != nil {
 		return errors.AddStack(err)
 	}
 
-	return nil
+	return errors.AddStack(os.Rename(tmpPath, path))
 }
@@ -0,0 +1,82 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package localdata
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/repository/v0manifest"
+)
+
+// ComponentBinaries returns the binaries of an installed component version,
+// which are the entry points it can be invoked by. The paths are relative to
+// the directory the version is installed to. The binaries are the ones listed
+// in the manifest of the version, recorded in the install receipt, only the
+// entry is recorded if the manifest doesn't list them, e.g., the mirror is
+// published by an older version. nil is returned if they are unknown, e.g.,
+// the version is installed without a receipt.
+func (p *Profile) ComponentBinaries(component string, version v0manifest.Version) ([]string, error) {
+	installPath, err := p.ComponentInstalledPath(component, version)
+	if err != nil {
+		return nil, err
+	}
+	receipt, err := p.InstallReceipt(component, filepath.Base(installPath))
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	return receipt.Binaries, nil
+}
+
+// ComponentBinary returns the path of the binary of an installed component
+// version, the name is the path of the binary relative to the directory the
+// version is installed to, or its base name if it's unique among the
+// binaries. It's an error if the component doesn't ship the binary.
+func (p *Profile) ComponentBinary(component string, version v0manifest.Version, name string) (string, error) {
+	installPath, err := p.ComponentInstalledPath(component, version)
+	if err != nil {
+		return "", err
+	}
+	binaries, err := p.ComponentBinaries(component, version)
+	if err != nil {
+		return "", err
+	}
+
+	if len(binaries) == 0 {
+		return "", errors.Errorf("the binaries of component %s:%s are unknown, it's installed before they are recorded, "+
+			"uninstall and install it again to record them", component, filepath.Base(installPath))
+	}
+
+	name = filepath.ToSlash(filepath.Clean(name))
+	var matched []string
+	for _, bin := range binaries {
+		if bin == name {
+			return filepath.Join(installPath, bin), nil
+		}
+		if filepath.Base(bin) == name {
+			matched = append(matched, bin)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return "", errors.Errorf("component %s:%s doesn't have binary `%s`, available binaries: %s",
+			component, filepath.Base(installPath), name, strings.Join(binaries, ", "))
+	case 1:
+		return filepath.Join(installPath, matched[0]), nil
+	default:
+		return "", errors.Errorf("binary `%s` of component %s:%s is ambiguous, use one of: %s",
+			name, component, filepath.Base(installPath), strings.Join(matched, ", "))
+	}
+}
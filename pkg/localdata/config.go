@@ -28,11 +28,17 @@ type configBase struct {
 type TiUPConfig struct {
 	configBase
 	Mirror string `toml:"mirror"`
+	// SharedCacheDir is the component cache directory shared with the other
+	// users of the machine, TIUP_SHARED_CACHE_DIR takes precedence over it
+	SharedCacheDir string `toml:"shared_cache_dir,omitempty"`
+	// SharedCacheWrite allows adding the components downloaded to the shared
+	// cache directory, TIUP_SHARED_CACHE_WRITE takes precedence over it
+	SharedCacheWrite bool `toml:"shared_cache_write,omitempty"`
 }
 
 // InitConfig returns a TiUPConfig struct which can flush config back to disk
 func InitConfig(root string) (*TiUPConfig, error) {
-	config := TiUPConfig{configBase{path.Join(root, "tiup.toml")}, ""}
+	config := TiUPConfig{configBase: configBase{path.Join(root, "tiup.toml")}}
 	if utils.IsNotExist(config.file) {
 		return &config, nil
 	}
@@ -70,6 +70,42 @@ const (
 	// EnvNameNativeSSHClient is the variable name by which user can specific use natiive ssh client or not
 	EnvNameNativeSSHClient = "TIUP_NATIVE_SSH"
 
+	// EnvNameSharedCacheDir is the variable name by which user can specify a component
+	// cache directory shared with other users of the same machine, it overrides
+	// shared_cache_dir in tiup.toml
+	EnvNameSharedCacheDir = "TIUP_SHARED_CACHE_DIR"
+
+	// EnvNameSharedCacheWrite is the variable name by which user can allow adding
+	// downloaded components to the shared cache directory, it overrides
+	// shared_cache_write in tiup.toml
+	EnvNameSharedCacheWrite = "TIUP_SHARED_CACHE_WRITE"
+
+	// EnvNameAuditRetainDays is the variable name by which user can specify how many
+	// days audit logs and operation logs are kept, they are kept forever if not set
+	EnvNameAuditRetainDays = "TIUP_AUDIT_RETAIN_DAYS"
+
+	// EnvNameLogBudget is the variable name by which user can specify how many MiB
+	// the audit logs and operation logs take at most, 0 means unlimited
+	EnvNameLogBudget = "TIUP_LOG_BUDGET_MB"
+
+	// EnvNameDisplayOrder is the variable name by which user can specify the order of
+	// the steps displayed in parallel, set it to "active" to show the most recently
+	// active steps first, they are ordered by role, host and port otherwise
+	EnvNameDisplayOrder = "TIUP_DISPLAY_ORDER"
+
+	// EnvNameDisplayAggregateAbove is the variable name by which user can specify the
+	// number of steps displayed in parallel above which they are counted by component
+	// instead of displayed one by one, 0 means never
+	EnvNameDisplayAggregateAbove = "TIUP_DISPLAY_AGGREGATE_ABOVE"
+
+	// EnvNamePprofAddr is the variable name by which user can specify the localhost
+	// address to serve pprof of tiup itself on during the operations, e.g., 6060
+	EnvNamePprofAddr = "TIUP_PPROF_ADDR"
+
+	// EnvNameSkipVersionCheck is the variable name set to true by tiup for the components
+	// it runs with --skip-version-check, the versions not valid SemVer strings are accepted
+	EnvNameSkipVersionCheck = "TIUP_SKIP_VERSION_CHECK"
+
 	// MetaFilename represents the process meta file name
 	MetaFilename = "tiup_process_meta"
 )
@@ -28,17 +28,25 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/repository/v0manifest"
 	"github.com/pingcap/tiup/pkg/utils"
-	"golang.org/x/mod/semver"
+	tiupver "github.com/pingcap/tiup/pkg/version"
 )
 
 // Profile represents the `tiup` profile
+//
+// A Profile may be shared by concurrent goroutines: files are replaced
+// atomically so readers never observe a partially written file, and
+// mutations of the profile directory are serialized.
 type Profile struct {
 	root   string
 	Config *TiUPConfig
+
+	// mu serializes the writes to the profile directory
+	mu sync.Mutex
 }
 
 // NewProfile returns a new profile instance
@@ -121,7 +129,7 @@ func (p *Profile) GetComponentInstalledVersion(component string, version v0manif
 	// Check whether the specific version exist in local
 	if len(versions) > 0 {
 		sort.Slice(versions, func(i, j int) bool {
-			return semver.Compare(versions[i], versions[j]) < 0
+			return tiupver.Compare(versions[i], versions[j]) < 0
 		})
 		version = v0manifest.Version(versions[len(versions)-1])
 	} else {
@@ -142,12 +150,37 @@ func (p *Profile) ComponentInstalledPath(component string, version v0manifest.Ve
 // SaveTo saves file to the profile directory, path is relative to the
 // profile directory of current user
 func (p *Profile) SaveTo(path string, data []byte, perm os.FileMode) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	fullPath := filepath.Join(p.root, path)
 	// create sub directory if needed
 	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
 		return errors.Trace(err)
 	}
-	return ioutil.WriteFile(fullPath, data, perm)
+	return writeFileAtomic(fullPath, data, perm)
+}
+
+// writeFileAtomic writes data to a temp file in the same directory and
+// renames it to fp, so concurrent readers see either the old or the new
+// content but never a partial one.
+func writeFileAtomic(fp string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(fp), filepath.Base(fp)+".")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(os.Rename(tmp.Name(), fp))
 }
 
 // WriteJSON writes struct to a file (in the profile directory) in JSON format
@@ -315,7 +348,7 @@ func (p *Profile) SelectInstalledVersion(component string, version v0manifest.Ve
 
 	if version.IsEmpty() {
 		sort.Slice(installed, func(i, j int) bool {
-			return semver.Compare(installed[i], installed[j]) < 0
+			return tiupver.Compare(installed[i], installed[j]) < 0
 		})
 		version = v0manifest.Version(installed[len(installed)-1])
 	}
@@ -334,6 +367,9 @@ func (p *Profile) SelectInstalledVersion(component string, version v0manifest.Ve
 
 // ResetMirror reset root.json and cleanup manifests directory
 func (p *Profile) ResetMirror(addr, root string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	// Calculating root.json path
 	shaWriter := sha256.New()
 	if _, err := io.Copy(shaWriter, strings.NewReader(addr)); err != nil {
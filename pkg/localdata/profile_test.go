@@ -14,9 +14,11 @@
 package localdata
 
 import (
+	"io/ioutil"
 	"os"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/pingcap/check"
@@ -51,3 +53,69 @@ func (s *profileTestSuite) TestResetMirror(c *check.C) {
 	c.Assert(profile.ResetMirror(root, ""), check.NotNil)
 	c.Assert(profile.ResetMirror(root, path.Join(root, "mock-mirror", "root.json")), check.IsNil)
 }
+
+func (s *profileTestSuite) TestInstallReceipt(c *check.C) {
+	root := path.Join("/tmp", uuid.New().String())
+	c.Assert(os.MkdirAll(path.Join(root, ComponentParentDir, "tidb", "v4.0.0"), 0755), check.IsNil)
+	defer os.RemoveAll(root)
+	profile := NewProfile(root, &TiUPConfig{})
+
+	// the versions installed by old versions of tiup have no receipts
+	receipt, err := profile.InstallReceipt("tidb", "v4.0.0")
+	c.Assert(err, check.IsNil)
+	c.Assert(receipt, check.IsNil)
+
+	saved := &InstallReceipt{
+		Component:       "tidb",
+		Version:         "v4.0.0",
+		InstalledAt:     time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC),
+		Mirror:          "https://tiup-mirrors.pingcap.com",
+		SnapshotVersion: 42,
+		URL:             "/tidb-v4.0.0-linux-amd64.tar.gz",
+		Hashes:          map[string]string{"sha256": "abc"},
+	}
+	c.Assert(profile.SaveInstallReceipt(saved), check.IsNil)
+	receipt, err = profile.InstallReceipt("tidb", "v4.0.0")
+	c.Assert(err, check.IsNil)
+	c.Assert(receipt, check.DeepEquals, saved)
+}
+
+func (s *profileTestSuite) TestComponentBinary(c *check.C) {
+	root := path.Join("/tmp", uuid.New().String())
+	dir := path.Join(root, ComponentParentDir, "tidb", "v4.0.0")
+	c.Assert(os.MkdirAll(path.Join(dir, "bin"), 0755), check.IsNil)
+	defer os.RemoveAll(root)
+	profile := NewProfile(root, &TiUPConfig{})
+	// an executable not listed in the manifest isn't a binary
+	c.Assert(ioutil.WriteFile(path.Join(dir, "bin", "debug.sh"), nil, 0755), check.IsNil)
+
+	// the binaries are unknown without a receipt
+	binaries, err := profile.ComponentBinaries("tidb", "v4.0.0")
+	c.Assert(err, check.IsNil)
+	c.Assert(binaries, check.HasLen, 0)
+	_, err = profile.ComponentBinary("tidb", "v4.0.0", "tidb-server")
+	c.Assert(err, check.ErrorMatches, ".*are unknown.*")
+
+	// the binaries listed in the manifest are recorded in the receipt
+	c.Assert(profile.SaveInstallReceipt(&InstallReceipt{
+		Component: "tidb",
+		Version:   "v4.0.0",
+		Binaries:  []string{"bin/tidb-ctl", "bin/tidb-lightning", "tidb-server", "tools/tidb-lightning"},
+	}), check.IsNil)
+	binaries, err = profile.ComponentBinaries("tidb", "v4.0.0")
+	c.Assert(err, check.IsNil)
+	c.Assert(binaries, check.DeepEquals, []string{"bin/tidb-ctl", "bin/tidb-lightning", "tidb-server", "tools/tidb-lightning"})
+
+	p, err := profile.ComponentBinary("tidb", "v4.0.0", "tidb-ctl")
+	c.Assert(err, check.IsNil)
+	c.Assert(p, check.Equals, path.Join(dir, "bin", "tidb-ctl"))
+	_, err = profile.ComponentBinary("pd", "", "pd-server")
+	c.Assert(err, check.NotNil)
+	p, err = profile.ComponentBinary("tidb", "", "tools/tidb-lightning")
+	c.Assert(err, check.IsNil)
+	c.Assert(p, check.Equals, path.Join(dir, "tools", "tidb-lightning"))
+	_, err = profile.ComponentBinary("tidb", "v4.0.0", "tidb-lightning")
+	c.Assert(err, check.ErrorMatches, ".*ambiguous.*")
+	_, err = profile.ComponentBinary("tidb", "v4.0.0", "debug.sh")
+	c.Assert(err, check.ErrorMatches, ".*doesn't have binary.*")
+}
@@ -0,0 +1,75 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package localdata
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/pingcap/errors"
+)
+
+// InstallReceiptFilename is the name of the install receipt in the directory
+// of an installed component version
+const InstallReceiptFilename = ".tiup-receipt.json"
+
+// InstallReceipt records when and where a component version was installed
+// from, so the installs from a mirror in a specific time window can be audited
+type InstallReceipt struct {
+	Component       string            `json:"component"`
+	Version         string            `json:"version"`
+	InstalledAt     time.Time         `json:"installed_at"`
+	Mirror          string            `json:"mirror"`             // the source of the mirror installed from
+	SnapshotVersion uint              `json:"snapshot_version"`   // the version of the snapshot manifest when installing
+	URL             string            `json:"url"`                // the path of the package in the mirror
+	Hashes          map[string]string `json:"hashes"`             // the hashes of the package
+	Binaries        []string          `json:"binaries,omitempty"` // the binaries of the package listed in its manifest, see ComponentBinaries
+}
+
+func (p *Profile) receiptPath(component, version string) string {
+	return p.Path(ComponentParentDir, component, version, InstallReceiptFilename)
+}
+
+// SaveInstallReceipt writes the receipt of an installed component version,
+// the receipt is written to a temp file first and then renamed, so it's never
+// half-written
+func (p *Profile) SaveInstallReceipt(receipt *InstallReceipt) error {
+	data, err := json.MarshalIndent(receipt, "", "  ")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return writeFileAtomic(p.receiptPath(receipt.Component, receipt.Version), data, 0644)
+}
+
+// InstallReceipt returns the receipt of an installed component version, nil
+// is returned if there isn't one, e.g., it's installed by an old version of
+// tiup which didn't write receipts
+func (p *Profile) InstallReceipt(component, version string) (*InstallReceipt, error) {
+	data, err := ioutil.ReadFile(p.receiptPath(component, version))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var receipt InstallReceipt
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		return nil, errors.Annotatef(err, "invalid install receipt of %s:%s", component, version)
+	}
+	return &receipt, nil
+}
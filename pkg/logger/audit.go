@@ -17,6 +17,7 @@ import (
 	"bytes"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/audit"
@@ -29,6 +30,14 @@ import (
 var auditEnabled atomic.Bool
 var auditBuffer *bytes.Buffer
 var auditDir string
+var logBudgetRoot atomic.String
+
+var (
+	auditMu sync.Mutex
+	// the audit logs of the operations audited on their own, keyed by their
+	// scopes, see StartScopedAuditLog
+	scopedAuditBuffers = make(map[string]*bytes.Buffer)
+)
 
 // EnableAuditLog enables audit log.
 func EnableAuditLog(dir string) {
@@ -36,32 +45,107 @@ func EnableAuditLog(dir string) {
 	auditEnabled.Store(true)
 }
 
+// EnableLogBudget limits the disk usage of the audit logs and the operation
+// logs under the profile root, see audit.EnforceLogBudget
+func EnableLogBudget(root string) {
+	logBudgetRoot.Store(root)
+}
+
+// enforceLogBudget removes the oldest logs over the budget if it's enabled
+func enforceLogBudget() error {
+	root := logBudgetRoot.Load()
+	if root == "" {
+		return nil
+	}
+	_, err := audit.EnforceLogBudget(root)
+	return err
+}
+
 // DisableAuditLog disables audit log.
 func DisableAuditLog() {
 	auditEnabled.Store(false)
 }
 
+// newAuditLogCore returns the core writing the logs to the audit log of the
+// command, except the ones tagged by OperationScope with the scope of an
+// operation audited on its own
 func newAuditLogCore() zapcore.Core {
-	auditBuffer = bytes.NewBufferString(strings.Join(os.Args, " ") + "\n")
-	encoder := zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
-	return zapcore.NewCore(encoder, zapcore.Lock(zapcore.AddSync(auditBuffer)), zapcore.InfoLevel)
+	auditBuffer = bytes.NewBufferString(auditCommand())
+	return newScopedCore(zapcore.InfoLevel, writeAuditLog, func() error { return nil })
+}
+
+// auditCommand is the first line of the audit logs
+func auditCommand() string {
+	return strings.Join(os.Args, " ") + "\n"
+}
+
+func writeAuditLog(scope string, p []byte) error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if buf, ok := scopedAuditBuffers[scope]; ok && scope != "" {
+		_, err := buf.Write(p)
+		return err
+	}
+	_, err := auditBuffer.Write(p)
+	return err
 }
 
-// OutputAuditLogIfEnabled outputs audit log if enabled.
-func OutputAuditLogIfEnabled() error {
+// StartScopedAuditLog starts saving the logs tagged by OperationScope with
+// scope to an audit log of their own, which is output by
+// OutputScopedAuditLog, so that each of the operations run by a command is
+// audited as if it runs alone
+func StartScopedAuditLog(scope string) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	scopedAuditBuffers[scope] = bytes.NewBufferString(auditCommand())
+}
+
+// OutputScopedAuditLog outputs the audit log of the scope started by
+// StartScopedAuditLog if audit log is enabled, and returns the path of it,
+// which is empty if disabled
+func OutputScopedAuditLog(scope string) (string, error) {
+	auditMu.Lock()
+	buf, ok := scopedAuditBuffers[scope]
+	delete(scopedAuditBuffers, scope)
+	auditMu.Unlock()
+	if !ok {
+		return "", nil
+	}
+	return outputAuditLog(buf.Bytes())
+}
+
+// OutputAuditLogIfEnabled outputs audit log if enabled, and returns the path
+// of it, which is empty if disabled.
+func OutputAuditLogIfEnabled() (string, error) {
+	if !auditEnabled.Load() || auditBuffer == nil {
+		return "", nil
+	}
+	auditMu.Lock()
+	data := append([]byte{}, auditBuffer.Bytes()...)
+	auditBuffer.Reset()
+	auditMu.Unlock()
+	return outputAuditLog(data)
+}
+
+// outputAuditLog outputs data as an audit log if audit log is enabled
+func outputAuditLog(data []byte) (string, error) {
 	if !auditEnabled.Load() {
-		return nil
+		return "", nil
+	}
+	// the profile may be mounted read-only for reading clusters only
+	if err := utils2.Writable(auditDir); err != nil {
+		zap.L().Info("Audit log is skipped as the directory is not writable", zap.String("dir", auditDir), zap.Error(err))
+		return "", nil
 	}
 
 	if err := utils2.CreateDir(auditDir); err != nil {
-		return errors.AddStack(err)
+		return "", errors.AddStack(err)
 	}
 
-	err := audit.OutputAuditLog(auditDir, auditBuffer.Bytes())
+	path, err := audit.OutputAuditLog(auditDir, data)
 	if err != nil {
-		return errors.AddStack(err)
+		return "", errors.AddStack(err)
 	}
-	auditBuffer.Reset()
 
-	return nil
+	return path, enforceLogBudget()
 }
@@ -15,11 +15,30 @@ package log
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"sync/atomic"
 
 	"go.uber.org/zap"
 )
 
+// stdout is the writer the messages of Infof are printed to, os.Stdout by default
+var stdout atomic.Value
+
+func init() {
+	stdout.Store(writer{os.Stdout})
+}
+
+// writer wraps the writers of different types to be stored in stdout
+type writer struct {
+	io.Writer
+}
+
+// SetStdout sets the writer the messages of Infof are printed to
+func SetStdout(w io.Writer) {
+	stdout.Store(writer{w})
+}
+
 // Debugf output the debug message to console
 // Deprecated: Use zap.L().Debug() instead
 func Debugf(format string, args ...interface{}) {
@@ -31,7 +50,7 @@ func Debugf(format string, args ...interface{}) {
 // Deprecated: Use zap.L().Info() instead
 func Infof(format string, args ...interface{}) {
 	zap.L().Info(fmt.Sprintf(format, args...))
-	fmt.Printf(format+"\n", args...)
+	_, _ = fmt.Fprintf(stdout.Load().(writer), format+"\n", args...)
 }
 
 // Warnf output the warning message to console
@@ -23,6 +23,7 @@ func InitGlobalLogger() {
 	core := zapcore.NewTee(
 		newAuditLogCore(),
 		newDebugLogCore(),
+		newOperationLogCore(),
 	)
 	logger := zap.New(core)
 	zap.ReplaceGlobals(logger)
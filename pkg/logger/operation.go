@@ -0,0 +1,124 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/audit"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	operationLogEnabled atomic.Bool
+	operationLogMu      sync.Mutex
+	// the log files of the running operations, keyed by their scopes,
+	// operations on different clusters may run at the same time
+	operationLogFiles = make(map[string]*os.File)
+)
+
+// newOperationLogCore returns the core writing the logs tagged by
+// OperationScope to the log file of the operation, and the logs not tagged to
+// the log files of all the running operations, e.g., the ones of the command
+func newOperationLogCore() zapcore.Core {
+	enabler := zap.LevelEnablerFunc(func(zapcore.Level) bool {
+		return operationLogEnabled.Load()
+	})
+	return newScopedCore(enabler, writeOperationLogs, syncOperationLogs)
+}
+
+func writeOperationLogs(scope string, p []byte) error {
+	operationLogMu.Lock()
+	defer operationLogMu.Unlock()
+	for s, f := range operationLogFiles {
+		if scope != "" && s != scope {
+			continue
+		}
+		if _, err := f.Write(p); err != nil {
+			return errors.AddStack(err)
+		}
+	}
+	return nil
+}
+
+func syncOperationLogs() error {
+	operationLogMu.Lock()
+	defer operationLogMu.Unlock()
+	for _, f := range operationLogFiles {
+		if err := f.Sync(); err != nil {
+			return errors.AddStack(err)
+		}
+	}
+	return nil
+}
+
+// StartOperationLog starts saving the logs of the operation on scope to a new
+// file in dir, until StopOperationLog is called with the path of the file,
+// which is returned. Only the logs tagged by OperationScope with the scope and
+// the ones not tagged are saved to the file.
+func StartOperationLog(dir, operation, scope string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", errors.AddStack(err)
+	}
+
+	fname := filepath.Join(dir, fmt.Sprintf("%s-%s.log", operation, time.Now().Format("2006-01-02T15-04-05")))
+	f, err := os.OpenFile(fname, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return "", errors.AddStack(err)
+	}
+
+	operationLogMu.Lock()
+	if old, ok := operationLogFiles[scope]; ok {
+		old.Close()
+	}
+	operationLogFiles[scope] = f
+	operationLogMu.Unlock()
+	operationLogEnabled.Store(true)
+
+	return fname, nil
+}
+
+// StopOperationLog stops saving logs to the file of an operation started by
+// StartOperationLog, log files older than the audit retention are removed, and
+// the oldest logs over the budget if it's enabled
+func StopOperationLog(fname string) error {
+	operationLogMu.Lock()
+	defer operationLogMu.Unlock()
+	var scope string
+	var f *os.File
+	for s, file := range operationLogFiles {
+		if file.Name() == fname {
+			scope, f = s, file
+		}
+	}
+	if f == nil {
+		return nil
+	}
+	delete(operationLogFiles, scope)
+	operationLogEnabled.Store(len(operationLogFiles) > 0)
+	if err := f.Close(); err != nil {
+		return errors.AddStack(err)
+	}
+	if err := audit.RemoveExpired(filepath.Dir(fname)); err != nil {
+		return err
+	}
+	return enforceLogBudget()
+}
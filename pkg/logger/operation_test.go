@@ -0,0 +1,129 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestScopedLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiup-scoped-logs-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	InitGlobalLogger()
+	EnableAuditLog(filepath.Join(dir, "audit"))
+	defer DisableAuditLog()
+
+	// the operations on two clusters run at the same time
+	logA, err := StartOperationLog(filepath.Join(dir, "a"), "stop", "a")
+	require.Nil(t, err)
+	logB, err := StartOperationLog(filepath.Join(dir, "b"), "stop", "b")
+	require.Nil(t, err)
+	StartScopedAuditLog("a")
+
+	zap.L().Info("command line", zap.String("args", "batch stop a b"))
+	zap.L().Info("operating a", OperationScope("a"))
+	zap.L().With(OperationScope("b")).Info("operating b")
+	require.Nil(t, StopOperationLog(logA))
+	require.Nil(t, StopOperationLog(logB))
+	zap.L().Info("stopped a", OperationScope("a"))
+
+	read := func(path string) string {
+		data, err := ioutil.ReadFile(path)
+		require.Nil(t, err)
+		return string(data)
+	}
+	// the logs tagged are only saved to the log of their operation
+	a, b := read(logA), read(logB)
+	assert.Contains(t, a, "command line")
+	assert.Contains(t, a, "operating a")
+	assert.NotContains(t, a, "operating b")
+	assert.Contains(t, b, "command line")
+	assert.Contains(t, b, "operating b")
+	assert.NotContains(t, b, "operating a")
+
+	// the operation on a is audited on its own
+	path, err := OutputScopedAuditLog("a")
+	require.Nil(t, err)
+	require.NotEmpty(t, path)
+	a = read(path)
+	assert.Contains(t, a, "operating a")
+	assert.Contains(t, a, "stopped a")
+	assert.NotContains(t, a, "operating b")
+	assert.NotContains(t, a, "command line")
+
+	path, err = OutputAuditLogIfEnabled()
+	require.Nil(t, err)
+	require.NotEmpty(t, path)
+	audit := read(path)
+	assert.Contains(t, audit, "command line")
+	assert.Contains(t, audit, "operating b")
+	assert.NotContains(t, audit, "operating a")
+}
+
+func TestOperationLogRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiup-operation-log-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv(localdata.EnvNameAuditRetainDays)
+	InitGlobalLogger()
+
+	// the logs of operations run 3 days and 1 day ago
+	old := filepath.Join(dir, "stop-2020-06-01T10-00-00.log")
+	recent := filepath.Join(dir, "start-2020-06-03T10-00-00.log")
+	for path, age := range map[string]time.Duration{old: 72 * time.Hour, recent: 24 * time.Hour} {
+		require.Nil(t, ioutil.WriteFile(path, nil, 0644))
+		mtime := time.Now().Add(-age)
+		require.Nil(t, os.Chtimes(path, mtime, mtime))
+	}
+
+	// the logs are kept forever by default
+	fname, err := StartOperationLog(dir, "restart", "test")
+	require.Nil(t, err)
+	assert.Equal(t, dir, filepath.Dir(fname))
+	assert.Regexp(t, regexp.MustCompile(`^restart-\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}\.log$`), filepath.Base(fname))
+	zap.L().Info("restarting", OperationScope("test"))
+	require.Nil(t, StopOperationLog(fname))
+	// stopped already
+	require.Nil(t, StopOperationLog(fname))
+	for _, path := range []string{old, recent, fname} {
+		_, err := os.Stat(path)
+		assert.Nil(t, err, path)
+	}
+	data, err := ioutil.ReadFile(fname)
+	require.Nil(t, err)
+	assert.Contains(t, string(data), "restarting")
+
+	// the logs older than the audit retention are removed
+	os.Setenv(localdata.EnvNameAuditRetainDays, "2")
+	fname, err = StartOperationLog(dir, "restart", "test")
+	require.Nil(t, err)
+	require.Nil(t, StopOperationLog(fname))
+	_, err = os.Stat(old)
+	assert.True(t, os.IsNotExist(err))
+	for _, path := range []string{recent, fname} {
+		_, err := os.Stat(path)
+		assert.Nil(t, err, path)
+	}
+}
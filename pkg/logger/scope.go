@@ -0,0 +1,92 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logger
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// OperationScopeKey is the key of the field tagging the logs of an operation,
+// which is the cluster operated. The logs tagged are only saved to the
+// operation log and the audit log of the operation.
+const OperationScopeKey = "cluster"
+
+// OperationScope returns the field tagging the logs of the operation on scope
+func OperationScope(scope string) zap.Field {
+	return zap.String(OperationScopeKey, scope)
+}
+
+// scopedCore encodes the logs and writes them with the scope they are tagged
+// with, which is empty if they are not tagged
+type scopedCore struct {
+	zapcore.LevelEnabler
+	enc   zapcore.Encoder
+	scope string // tagged by the fields added by With
+	write func(scope string, p []byte) error
+	sync  func() error
+}
+
+func newScopedCore(enabler zapcore.LevelEnabler, write func(string, []byte) error, sync func() error) zapcore.Core {
+	return &scopedCore{
+		LevelEnabler: enabler,
+		enc:          zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()),
+		write:        write,
+		sync:         sync,
+	}
+}
+
+// With implements the zapcore.Core interface
+func (c *scopedCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	clone.scope = scopeOf(c.scope, fields)
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	return &clone
+}
+
+// Check implements the zapcore.Core interface
+func (c *scopedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements the zapcore.Core interface
+func (c *scopedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	return c.write(scopeOf(c.scope, fields), buf.Bytes())
+}
+
+// Sync implements the zapcore.Core interface
+func (c *scopedCore) Sync() error {
+	return c.sync()
+}
+
+// scopeOf returns the scope the fields tag the logs with, scope if none
+func scopeOf(scope string, fields []zapcore.Field) string {
+	for _, f := range fields {
+		if f.Key == OperationScopeKey && f.Type == zapcore.StringType {
+			scope = f.String
+		}
+	}
+	return scope
+}
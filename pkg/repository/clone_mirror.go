@@ -14,11 +14,13 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pingcap/tiup/pkg/cluster/template/install"
@@ -30,9 +32,12 @@ import (
 	"github.com/pingcap/tiup/pkg/set"
 	"github.com/pingcap/tiup/pkg/utils"
 	"github.com/pingcap/tiup/pkg/version"
-	"golang.org/x/mod/semver"
+	"golang.org/x/sync/errgroup"
 )
 
+// defaultCloneConcurrency is the number of files downloaded in parallel by default
+const defaultCloneConcurrency = 4
+
 // CloneOptions represents the options of clone a remote mirror
 type CloneOptions struct {
 	Archs      []string
@@ -41,6 +46,18 @@ type CloneOptions struct {
 	Full       bool
 	Components map[string]*[]string
 	Prefix     bool
+
+	// only the components listed are cloned if it's not empty
+	Allowlist []string
+	// the versions satisfying any of the constraints are cloned, see
+	// ParseVersionConstraint for the syntax
+	Constraints []string
+	// the nightly versions are not cloned
+	SkipNightly bool
+	// the number of files downloaded in parallel
+	Concurrency int
+	// print the number and size of the files to download only
+	DryRun bool
 }
 
 // CloneMirror clones a local mirror from the remote repository
@@ -48,14 +65,55 @@ func CloneMirror(repo *V1Repository, components []string, targetDir string, sele
 	fmt.Printf("Start to clone mirror, targetDir is %s, selectedVersions are [%s]\n", targetDir, strings.Join(selectedVersions, ","))
 	fmt.Println("If this does not meet expectations, please abort this process, read `tiup mirror clone --help` and run again")
 
+	fmt.Println("Arch", options.Archs)
+	fmt.Println("OS", options.OSs)
+
+	if len(options.OSs) == 0 || len(options.Archs) == 0 {
+		return nil
+	}
+
+	constraints := make([]VersionConstraint, 0, len(options.Constraints))
+	for _, c := range options.Constraints {
+		vc, err := ParseVersionConstraint(c)
+		if err != nil {
+			return err
+		}
+		constraints = append(constraints, vc)
+	}
+	if len(options.Allowlist) > 0 {
+		allowed := set.NewStringSet(options.Allowlist...)
+		var selected []string
+		for _, name := range components {
+			if allowed.Exist(name) {
+				selected = append(selected, name)
+			}
+		}
+		components = selected
+	}
+
+	componentManifests, items, err := cloneComponents(repo, components, selectedVersions, constraints, options)
+	if err != nil {
+		return err
+	}
+	var total uint
+	for _, item := range items {
+		total += item.Length
+	}
+	fmt.Printf("%d files of %d components to download, %d bytes (%.1f MiB) in total, besides the TiUP binaries of %d platforms\n",
+		len(items), len(componentManifests), total, float64(total)/1024/1024, len(options.OSs)*len(options.Archs))
+	if options.DryRun {
+		return nil
+	}
+
 	if utils.IsNotExist(targetDir) {
 		if err := os.MkdirAll(targetDir, 0755); err != nil {
 			return err
 		}
 	}
 
-	// Temporary directory is used to save the unverified tarballs
-	tmpDir := filepath.Join(targetDir, fmt.Sprintf("_tmp_%d", time.Now().UnixNano()))
+	// Temporary directory is used to save the unverified tarballs, it's kept
+	// if the cloning fails, so the interrupted downloads can be resumed
+	tmpDir := filepath.Join(targetDir, "_tmp")
 	keyDir := filepath.Join(targetDir, "keys")
 
 	if utils.IsNotExist(tmpDir) {
@@ -68,13 +126,12 @@ func CloneMirror(repo *V1Repository, components []string, targetDir string, sele
 			return err
 		}
 	}
-	defer os.RemoveAll(tmpDir)
-
-	fmt.Println("Arch", options.Archs)
-	fmt.Println("OS", options.OSs)
 
-	if len(options.OSs) == 0 || len(options.Archs) == 0 {
-		return nil
+	if err := downloadAll(repo, items, targetDir, tmpDir, options.Concurrency); err != nil {
+		return err
+	}
+	if err := downloadTiUP(repo, targetDir, tmpDir, options); err != nil {
+		return err
 	}
 
 	var (
@@ -146,11 +203,6 @@ func CloneMirror(repo *V1Repository, components []string, targetDir string, sele
 	snapshot := v1manifest.NewSnapshot(initTime)
 	snapshot.SetExpiresAt(expirsAt)
 
-	componentManifests, err := cloneComponents(repo, components, selectedVersions, targetDir, tmpDir, options)
-	if err != nil {
-		return err
-	}
-
 	for name, component := range componentManifests {
 		component.SetExpiresAt(expirsAt)
 		fname := fmt.Sprintf("%s.json", name)
@@ -238,21 +290,32 @@ func CloneMirror(repo *V1Repository, components []string, targetDir string, sele
 		}
 	}
 
-	return install.WriteLocalInstallScript(filepath.Join(targetDir, "local_install.sh"))
+	if err := install.WriteLocalInstallScript(filepath.Join(targetDir, "local_install.sh")); err != nil {
+		return err
+	}
+	return os.RemoveAll(tmpDir)
 }
 
+// cloneComponents returns the manifests of the components to clone, and the
+// files to download for them
 func cloneComponents(repo *V1Repository,
 	components, selectedVersions []string,
-	targetDir, tmpDir string,
-	options CloneOptions) (map[string]*v1manifest.Component, error) {
+	constraints []VersionConstraint,
+	options CloneOptions) (map[string]*v1manifest.Component, []*v1manifest.VersionItem, error) {
 	compManifests := map[string]*v1manifest.Component{}
+	var items []*v1manifest.VersionItem
+	urls := set.NewStringSet()
 	for _, name := range components {
 		manifest, err := repo.FetchComponentManifest(name, true)
 		if err != nil {
-			return nil, errors.Annotatef(err, "fetch component '%s' manifest failed", name)
+			return nil, nil, errors.Annotatef(err, "fetch component '%s' manifest failed", name)
 		}
 
-		vs := combineVersions(options.Components[name], manifest, options.OSs, options.Archs, selectedVersions)
+		vs := combineVersions(options.Components[name], manifest, options.OSs, options.Archs, selectedVersions, len(constraints) > 0)
+		vs = constrainedVersions(vs, manifest, options.OSs, options.Archs, constraints)
+		if options.SkipNightly {
+			vs = skipNightly(vs)
+		}
 		var newManifest *v1manifest.Component
 		if options.Full {
 			newManifest = manifest
@@ -294,19 +357,68 @@ func cloneComponents(repo *V1Repository,
 							continue
 						}
 					}
-					if versionItem.Yanked {
+					if versionItem.Yanked || urls.Exist(versionItem.URL) {
 						continue
 					}
-					if err := download(targetDir, tmpDir, repo, &versionItem); err != nil {
-						return nil, errors.Annotatef(err, "download resource: %s", name)
-					}
+					urls.Insert(versionItem.URL)
+					item := versionItem
+					items = append(items, &item)
 				}
 			}
 		}
 		compManifests[name] = newManifest
 	}
 
-	// Download TiUP binary
+	return compManifests, items, nil
+}
+
+// downloadAll downloads the files with at most concurrency of them in parallel,
+// no more files are downloaded after one fails and the downloading ones are
+// canceled
+func downloadAll(repo *V1Repository, items []*v1manifest.VersionItem, targetDir, tmpDir string, concurrency int) error {
+	if concurrency <= 0 {
+		concurrency = defaultCloneConcurrency
+	}
+
+	var (
+		mu         sync.Mutex
+		done       int
+		doneBytes  uint
+		totalBytes uint
+	)
+	for _, item := range items {
+		totalBytes += item.Length
+	}
+
+	sem := make(chan struct{}, concurrency)
+	errg, ctx := errgroup.WithContext(context.Background())
+schedule:
+	for _, item := range items {
+		item := item
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break schedule
+		}
+		errg.Go(func() error {
+			defer func() { <-sem }()
+			if err := download(ctx, targetDir, tmpDir, repo, item); err != nil {
+				return errors.Annotatef(err, "download resource: %s", item.URL)
+			}
+			mu.Lock()
+			done++
+			doneBytes += item.Length
+			fmt.Printf("[%d/%d] %s downloaded, %.1f/%.1f MiB\n", done, len(items), strings.TrimPrefix(item.URL, "/"),
+				float64(doneBytes)/1024/1024, float64(totalBytes)/1024/1024)
+			mu.Unlock()
+			return nil
+		})
+	}
+	return errg.Wait()
+}
+
+// downloadTiUP downloads the TiUP binaries of the platforms
+func downloadTiUP(repo *V1Repository, targetDir, tmpDir string, options CloneOptions) error {
 	for _, goos := range options.OSs {
 		for _, goarch := range options.Archs {
 			url := fmt.Sprintf("/tiup-%s-%s.tar.gz", goos, goarch)
@@ -318,19 +430,22 @@ func cloneComponents(repo *V1Repository,
 					fmt.Printf("TiUP donesn't have %s/%s, skipped\n", goos, goarch)
 					continue
 				}
-				return nil, err
+				return err
 			}
 			// Move file to target directory if hashes pass verify.
 			if err := os.Rename(tmpFile, dstFile); err != nil {
-				return nil, err
+				return err
 			}
 		}
 	}
-
-	return compManifests, nil
+	return nil
 }
 
-func download(targetDir, tmpDir string, repo *V1Repository, item *v1manifest.VersionItem) error {
+func download(ctx context.Context, targetDir, tmpDir string, repo *V1Repository, item *v1manifest.VersionItem) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	validate := func(dir string) error {
 		hashes, n, err := ru.HashFile(path.Join(dir, item.URL))
 		if err != nil {
@@ -362,8 +477,24 @@ func download(targetDir, tmpDir string, repo *V1Repository, item *v1manifest.Ver
 		}
 	}
 
-	err := repo.Mirror().Download(item.URL, tmpDir)
-	if err != nil {
+	if rd, ok := repo.Mirror().(ResumableDownloader); ok {
+		// the partial file left by an interrupted cloning is resumed, and it's
+		// downloaded again from scratch if the result is corrupted
+		err := rd.DownloadResumable(ctx, item.URL, tmpFile)
+		if err == nil {
+			err = validate(tmpDir)
+		}
+		if err != nil {
+			// the partial file is kept to be resumed if it's canceled
+			if ctx.Err() != nil {
+				return err
+			}
+			_ = os.Remove(tmpFile)
+			if err := rd.DownloadResumable(ctx, item.URL, tmpFile); err != nil {
+				return err
+			}
+		}
+	} else if err := repo.Mirror().Download(item.URL, tmpDir); err != nil {
 		return err
 	}
 
@@ -376,6 +507,9 @@ func download(targetDir, tmpDir string, repo *V1Repository, item *v1manifest.Ver
 }
 
 func checkVersion(options CloneOptions, versions set.StringSet, version string) bool {
+	if options.SkipNightly && v0manifest.Version(version).IsNightly() {
+		return false
+	}
 	if options.Full || versions.Exist("all") || versions.Exist(version) {
 		return true
 	}
@@ -391,8 +525,11 @@ func checkVersion(options CloneOptions, versions set.StringSet, version string)
 	return false
 }
 
-func combineVersions(versions *[]string, manifest *v1manifest.Component, oss, archs, selectedVersions []string) set.StringSet {
-	if (versions == nil || len(*versions) < 1) && len(selectedVersions) < 1 {
+// combineVersions returns the versions of the component selected, the
+// components not bound to the version of TiDB are pinned if anything is
+// selected, which includes the versions selected by constraints
+func combineVersions(versions *[]string, manifest *v1manifest.Component, oss, archs, selectedVersions []string, constrained bool) set.StringSet {
+	if (versions == nil || len(*versions) < 1) && len(selectedVersions) < 1 && !constrained {
 		return nil
 	}
 
@@ -430,10 +567,10 @@ func combineVersions(versions *[]string, manifest *v1manifest.Component, oss, ar
 					// Use the latest stable versionS if the selected version doesn't exist in specific platform
 					var latest string
 					for v := range versions {
-						if v0manifest.Version(v).IsNightly() {
+						if version.IsNightly(v) {
 							continue
 						}
-						if latest == "" || semver.Compare(v, latest) > 0 {
+						if latest == "" || version.Compare(v, latest) > 0 {
 							latest = v
 						}
 					}
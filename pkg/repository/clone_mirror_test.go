@@ -0,0 +1,173 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionConstraint(t *testing.T) {
+	for _, tc := range []struct {
+		constraint string
+		matched    []string
+		unmatched  []string
+	}{
+		{"v4", []string{"v4.0.0", "v4.1.2", "v4.0.0-rc"}, []string{"v3.0.20", "v5.0.0", "v4.0.0-beta-nightly-20200603"}},
+		{"4.0", []string{"v4.0.0", "v4.0.9"}, []string{"v4.1.0"}},
+		{"v4.0.1", []string{"v4.0.1"}, []string{"v4.0.10"}},
+		{">=v4.0.0, <v5.0.0", []string{"v4.0.0", "v4.9.0"}, []string{"v4.0.0-rc", "v5.0.0"}},
+		{">v4.0.0 <=v4.0.2", []string{"v4.0.1", "v4.0.2"}, []string{"v4.0.0", "v4.0.3"}},
+		{"=v4.0.0", []string{"v4.0.0"}, []string{"v4.0.1"}},
+	} {
+		c, err := ParseVersionConstraint(tc.constraint)
+		require.NoError(t, err, tc.constraint)
+		for _, v := range tc.matched {
+			assert.True(t, c.Check(v), "%s should satisfy %s", v, tc.constraint)
+		}
+		for _, v := range tc.unmatched {
+			assert.False(t, c.Check(v), "%s should not satisfy %s", v, tc.constraint)
+		}
+	}
+
+	for _, s := range []string{"", ">=", ">=v4.x", "latest"} {
+		_, err := ParseVersionConstraint(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestCloneMirrorFilters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiup-clone")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	require.NoError(t, os.MkdirAll(src, 0755))
+	ownerKey := initPublishMirror(t, src)
+	tarball := filepath.Join(dir, "tool.tar.gz")
+	writeTestTarball(t, tarball, map[string]os.FileMode{"tool": 0755})
+	for _, id := range []string{"tool", "other"} {
+		for _, plat := range []string{"linux/amd64", "darwin/amd64"} {
+			for _, ver := range []string{"v3.0.0", "v4.0.0", "v4.0.1", "v5.0.0", "v5.0.0-nightly-20200603"} {
+				info := PublishInfo{ID: id, Version: ver, Platform: plat, Entry: id}
+				require.NoError(t, Publish(src, tarball, info, ownerKey, PublishOptions{}))
+			}
+		}
+	}
+
+	client := func(profileDir, mirror string) *V1Repository {
+		require.NoError(t, os.MkdirAll(filepath.Join(profileDir, "bin"), 0755))
+		require.NoError(t, utils.Copy(filepath.Join(mirror, v1manifest.ManifestFilenameRoot), filepath.Join(profileDir, "bin", v1manifest.ManifestFilenameRoot)))
+		local, err := v1manifest.NewManifests(localdata.NewProfile(profileDir, &localdata.TiUPConfig{}))
+		require.NoError(t, err)
+		return NewV1Repo(NewMirror(mirror, MirrorOptions{}), Options{GOOS: "linux", GOARCH: "amd64"}, local)
+	}
+	repo := client(filepath.Join(dir, "profile"), src)
+
+	target := filepath.Join(dir, "target")
+	options := CloneOptions{
+		OSs:         []string{"linux"},
+		Archs:       []string{"amd64"},
+		Components:  map[string]*[]string{},
+		Allowlist:   []string{"tool"},
+		Constraints: []string{"v4.0", "v5"},
+		SkipNightly: true,
+		Concurrency: 2,
+		DryRun:      true,
+	}
+	require.NoError(t, CloneMirror(repo, []string{"other", "tool"}, target, nil, options))
+	assert.NoDirExists(t, target)
+
+	options.DryRun = false
+	require.NoError(t, CloneMirror(repo, []string{"other", "tool"}, target, nil, options))
+	for _, ver := range []string{"v4.0.0", "v4.0.1", "v5.0.0"} {
+		assert.FileExists(t, filepath.Join(target, "tool-"+ver+"-linux-amd64.tar.gz"))
+	}
+	for _, fname := range []string{"tool-v3.0.0-linux-amd64.tar.gz", "tool-v4.0.0-darwin-amd64.tar.gz",
+		"tool-v5.0.0-nightly-20200603-linux-amd64.tar.gz", "other-v4.0.0-linux-amd64.tar.gz"} {
+		assert.NoFileExists(t, filepath.Join(target, fname))
+	}
+	assert.NoDirExists(t, filepath.Join(target, "_tmp"))
+
+	// the cloned mirror is usable on its own
+	cloned := client(filepath.Join(dir, "cloned-profile"), target)
+	comp, err := cloned.FetchComponentManifest("tool", false)
+	require.NoError(t, err)
+	versions := comp.VersionList("linux/amd64")
+	assert.Len(t, versions, 3)
+	assert.Contains(t, versions, "v5.0.0")
+	_, err = cloned.FetchComponentManifest("other", false)
+	assert.Error(t, err)
+}
+
+func TestDownloadAllCanceled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiup-clone")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var mu sync.Mutex
+	requested := make(map[string]struct{})
+	release := make(chan struct{})
+	defer close(release)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested[r.URL.Path] = struct{}{}
+		mu.Unlock()
+		if r.URL.Path == "/broken.tar.gz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// the others are never finished unless canceled
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+
+	items := []*v1manifest.VersionItem{{URL: "/broken.tar.gz"}}
+	for i := 0; i < 10; i++ {
+		items = append(items, &v1manifest.VersionItem{URL: fmt.Sprintf("/slow-%d.tar.gz", i)})
+	}
+	repo := &V1Repository{mirror: NewMirror(srv.URL, MirrorOptions{Progress: DisableProgress{}})}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- downloadAll(repo, items, filepath.Join(dir, "target"), filepath.Join(dir, "tmp"), 2)
+	}()
+	select {
+	case err := <-errCh:
+		assert.Contains(t, fmt.Sprint(err), "broken.tar.gz")
+	case <-time.After(10 * time.Second):
+		t.Fatal("the downloads in progress are not canceled")
+	}
+
+	// no more files are downloaded after the failure
+	mu.Lock()
+	defer mu.Unlock()
+	assert.LessOrEqual(t, len(requested), 3)
+}
@@ -14,12 +14,14 @@
 package repository
 
 import (
+	"context"
 	stderrors "errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -46,6 +48,12 @@ type (
 	// MirrorOptions is used to customize the mirror download options
 	MirrorOptions struct {
 		Progress DownloadProgress
+		// the hooks notified of the requests to the mirror besides the ones
+		// registered by RegisterMirrorHook
+		Hooks []MirrorHook
+		// the proxy the requests to the mirror go through, the one of the
+		// environment variables is used if it's nil
+		Proxy func(*http.Request) (*url.URL, error)
 	}
 
 	// Mirror represents a repository mirror, which can be remote HTTP
@@ -170,12 +178,98 @@ func (l *httpMirror) Open() error {
 	return nil
 }
 
+// ResumableDownloader is implemented by the mirrors which can resume the
+// interrupted downloads of resources
+type ResumableDownloader interface {
+	// DownloadResumable fetches a resource to the file, the download is resumed
+	// if the file exists with the beginning part of the resource. It's aborted
+	// once the ctx is done.
+	DownloadResumable(ctx context.Context, resource, file string) error
+}
+
 func (l *httpMirror) download(url string, to string, maxSize int64) (io.ReadCloser, error) {
-	defer func(start time.Time) {
-		verbose.Log("Download resource %s in %s", url, time.Since(start))
-	}(time.Now())
+	var progress DownloadProgress
+	if strings.Contains(url, ".tar.gz") {
+		progress = l.options.Progress
+	} else {
+		progress = DisableProgress{}
+	}
+	return l.downloadWithProgress(context.Background(), url, to, maxSize, progress)
+}
+
+func (l *httpMirror) hooks() []MirrorHook {
+	return append(registeredMirrorHooks(), l.options.Hooks...)
+}
+
+// the times a request to a mirror is retried if it fails by the network or a
+// server error, and the delay before each retry
+var (
+	mirrorRequestRetries = 2
+	mirrorRetryDelay     = time.Second
+)
+
+func (l *httpMirror) downloadWithProgress(ctx context.Context, url string, to string, maxSize int64, progress DownloadProgress) (rc io.ReadCloser, err error) {
+	hooks := l.hooks()
+	resource := strings.TrimPrefix(strings.TrimPrefix(url, strings.TrimSuffix(l.server, "/")), "/")
+	if i := strings.Index(resource, "?"); i >= 0 {
+		resource = resource[:i]
+	}
+	hookReq := &MirrorRequest{
+		Mirror:   l.server,
+		Resource: resource,
+		URL:      url,
+		Start:    time.Now(),
+	}
+	for _, h := range hooks {
+		h.RequestStart(hookReq)
+	}
+
+	var resp *grab.Response
+	retries := 0
+	defer func() {
+		verbose.Log("Download resource %s in %s", url, time.Since(hookReq.Start))
+		hookResp := &MirrorResponse{Duration: time.Since(hookReq.Start), Retries: retries, Err: err}
+		if resp != nil && resp.IsComplete() {
+			hookResp.Bytes = resp.BytesComplete()
+			if resp.HTTPResponse != nil {
+				hookResp.Status = resp.HTTPResponse.StatusCode
+			}
+		}
+		for _, h := range hooks {
+			h.RequestEnd(hookReq, hookResp)
+		}
+	}()
 
 	client := grab.NewClient()
+	if l.options.Proxy != nil {
+		// keep the timeouts and other settings of the default transport
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = l.options.Proxy
+		client.HTTPClient = &http.Client{Transport: transport}
+	}
+	for {
+		resp, err = l.downloadOnce(ctx, client, url, to, maxSize, progress)
+		if err == nil || retries >= mirrorRequestRetries || !retryable(ctx, resp) {
+			break
+		}
+		retries++
+		verbose.Log("Retry downloading resource %s (%d/%d): %s", url, retries, mirrorRequestRetries, err)
+		select {
+		case <-ctx.Done():
+			return nil, errors.Trace(ctx.Err())
+		case <-time.After(mirrorRetryDelay):
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Open()
+}
+
+// downloadOnce downloads the url once, the response is returned even if it
+// fails, so the status is reported and the failure is told if it's retryable
+func (l *httpMirror) downloadOnce(ctx context.Context, client *grab.Client, url string, to string, maxSize int64, progress DownloadProgress) (*grab.Response, error) {
 	req, err := grab.NewRequest(to, url)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -183,6 +277,7 @@ func (l *httpMirror) download(url string, to string, maxSize int64) (io.ReadClos
 	if len(to) == 0 {
 		req.NoStore = true
 	}
+	req = req.WithContext(ctx)
 
 	resp := client.Do(req)
 
@@ -190,12 +285,6 @@ func (l *httpMirror) download(url string, to string, maxSize int64) (io.ReadClos
 	t := time.NewTicker(time.Millisecond)
 	defer t.Stop()
 
-	var progress DownloadProgress
-	if strings.Contains(url, ".tar.gz") {
-		progress = l.options.Progress
-	} else {
-		progress = DisableProgress{}
-	}
 	progress.Start(url, resp.Size())
 
 L:
@@ -204,7 +293,7 @@ L:
 		case <-t.C:
 			if maxSize > 0 && resp.BytesComplete() > maxSize {
 				_ = resp.Cancel()
-				return nil, errors.Errorf("download from %s failed, resp size %d exceeds maximum size %d", url, resp.BytesComplete(), maxSize)
+				return resp, errors.Errorf("download from %s failed, resp size %d exceeds maximum size %d", url, resp.BytesComplete(), maxSize)
 			}
 			progress.SetCurrent(resp.BytesComplete())
 		case <-resp.Done:
@@ -218,16 +307,32 @@ L:
 		if grab.IsStatusCodeError(err) {
 			code := err.(grab.StatusCodeError)
 			if int(code) == http.StatusNotFound {
-				return nil, errors.Annotatef(ErrNotFound, "url %s", url)
+				return resp, errors.Annotatef(ErrNotFound, "url %s", url)
 			}
 		}
-		return nil, errors.Annotatef(err, "download from %s failed", url)
+		return resp, errors.Annotatef(err, "download from %s failed", url)
 	}
 	if maxSize > 0 && resp.BytesComplete() > maxSize {
-		return nil, errors.Errorf("download from %s failed, resp size %d exceeds maximum size %d", url, resp.BytesComplete(), maxSize)
+		return resp, errors.Errorf("download from %s failed, resp size %d exceeds maximum size %d", url, resp.BytesComplete(), maxSize)
 	}
+	return resp, nil
+}
 
-	return resp.Open()
+// retryable checks if the failed download of resp is worth retrying, i.e.,
+// it failed by the network or a server error, rather than being canceled or
+// rejected by the mirror
+func retryable(ctx context.Context, resp *grab.Response) bool {
+	if ctx.Err() != nil || resp == nil {
+		return false
+	}
+	err := resp.Err()
+	if err == nil || errors.Cause(err) == context.Canceled {
+		return false
+	}
+	if grab.IsStatusCodeError(err) {
+		return int(err.(grab.StatusCodeError)) >= http.StatusInternalServerError
+	}
+	return true
 }
 
 func (l *httpMirror) prepareURL(resource string) string {
@@ -256,6 +361,19 @@ func (l *httpMirror) Download(resource, targetDir string) error {
 	return utils.Move(tmpFilePath, dstFilePath)
 }
 
+// DownloadResumable implements the ResumableDownloader interface, the
+// progress is not shown as the downloads may be in parallel
+func (l *httpMirror) DownloadResumable(ctx context.Context, resource, file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return errors.Trace(err)
+	}
+	r, err := l.downloadWithProgress(ctx, l.prepareURL(resource), file, 0, DisableProgress{})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return r.Close()
+}
+
 // Fetch implements the Mirror interface
 func (l *httpMirror) Fetch(resource string, maxSize int64) (io.ReadCloser, error) {
 	return l.download(l.prepareURL(resource), "", maxSize)
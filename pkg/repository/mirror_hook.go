@@ -0,0 +1,206 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// EnvMirrorDebug is the name of an env var the user can set to log every
+// request to the mirrors at debug level.
+const EnvMirrorDebug = "TIUP_MIRROR_DEBUG"
+
+type (
+	// MirrorRequest represents a request to a mirror
+	MirrorRequest struct {
+		// the address of the mirror
+		Mirror string
+		// the resource requested, e.g. timestamp.json
+		Resource string
+		// the URL requested
+		URL   string
+		Start time.Time
+	}
+
+	// MirrorResponse represents the end of a request to a mirror
+	MirrorResponse struct {
+		// the HTTP status code, it's 0 if no response is received
+		Status int
+		// the bytes received
+		Bytes    int64
+		Duration time.Duration
+		// the times the request is retried before the response
+		Retries int
+		Err     error
+	}
+
+	// MirrorSelection represents the decision of which mirror to use
+	MirrorSelection struct {
+		// the address of the mirror selected
+		Mirror string
+		// where the mirror is from, it's one of "env", "config" and "default"
+		Source string
+		// the mirrors considered but not selected
+		Rejected []string
+		// why the mirror is selected
+		Reason string
+	}
+
+	// MirrorHook is notified of the requests to the mirrors and of the mirror
+	// selected, the methods may be called concurrently
+	MirrorHook interface {
+		RequestStart(req *MirrorRequest)
+		RequestEnd(req *MirrorRequest, resp *MirrorResponse)
+		MirrorSelected(sel *MirrorSelection)
+	}
+)
+
+var (
+	mirrorHooksMu sync.RWMutex
+	mirrorHooks   []MirrorHook
+	// the last mirror selected, see NotifyMirrorSelected
+	lastMirrorSelection *MirrorSelection
+)
+
+func init() {
+	v := strings.ToLower(os.Getenv(EnvMirrorDebug))
+	if v == "1" || v == "enable" {
+		RegisterMirrorHook(NewDebugHook())
+	}
+}
+
+// RegisterMirrorHook registers a hook notified of the requests to all mirrors
+// and of the mirror selected
+func RegisterMirrorHook(hook MirrorHook) {
+	mirrorHooksMu.Lock()
+	defer mirrorHooksMu.Unlock()
+	mirrorHooks = append(mirrorHooks, hook)
+}
+
+// NotifyMirrorSelected notifies the registered hooks of the mirror selected,
+// once per selection, i.e., the hooks are not notified again until another
+// mirror, or the same one from another source, is selected
+func NotifyMirrorSelected(sel *MirrorSelection) {
+	mirrorHooksMu.Lock()
+	last := lastMirrorSelection
+	if last != nil && last.Mirror == sel.Mirror && last.Source == sel.Source {
+		mirrorHooksMu.Unlock()
+		return
+	}
+	lastMirrorSelection = sel
+	mirrorHooksMu.Unlock()
+	for _, h := range registeredMirrorHooks() {
+		h.MirrorSelected(sel)
+	}
+}
+
+func registeredMirrorHooks() []MirrorHook {
+	mirrorHooksMu.RLock()
+	defer mirrorHooksMu.RUnlock()
+	return append([]MirrorHook(nil), mirrorHooks...)
+}
+
+// the query parameters which may carry credentials, the ones prefixed with
+// "x-amz-" or "x-goog-" are redacted as well
+var sensitiveParams = []string{
+	"token", "access_token", "auth", "authorization", "key", "secret",
+	"password", "passwd", "signature", "sig", "credential",
+}
+
+func isSensitiveParam(name string) bool {
+	name = strings.ToLower(name)
+	if strings.HasPrefix(name, "x-amz-") || strings.HasPrefix(name, "x-goog-") {
+		return true
+	}
+	for _, p := range sensitiveParams {
+		if name == p || strings.HasSuffix(name, "_"+p) || strings.HasSuffix(name, "-"+p) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactURL replaces the password in the user info and the values of the
+// sensitive query parameters of a URL, so it can be logged
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		}
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for name := range query {
+			if isSensitiveParam(name) {
+				query[name] = []string{"xxxxx"}
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+type debugHook struct{}
+
+// NewDebugHook returns a hook logging every request and the mirror selected at
+// debug level, the credentials in the URLs are redacted
+func NewDebugHook() MirrorHook {
+	return debugHook{}
+}
+
+// RequestStart implements the MirrorHook interface
+func (debugHook) RequestStart(req *MirrorRequest) {
+	zap.L().Debug("Mirror request started",
+		zap.String("mirror", RedactURL(req.Mirror)),
+		zap.String("url", RedactURL(req.URL)))
+}
+
+// RequestEnd implements the MirrorHook interface
+func (debugHook) RequestEnd(req *MirrorRequest, resp *MirrorResponse) {
+	fields := []zap.Field{
+		zap.String("mirror", RedactURL(req.Mirror)),
+		zap.String("url", RedactURL(req.URL)),
+		zap.Int("status", resp.Status),
+		zap.Int64("bytes", resp.Bytes),
+		zap.Duration("duration", resp.Duration),
+		zap.Int("retries", resp.Retries),
+	}
+	if resp.Err != nil {
+		fields = append(fields, zap.Error(resp.Err))
+	}
+	zap.L().Debug("Mirror request finished", fields...)
+}
+
+// MirrorSelected implements the MirrorHook interface
+func (debugHook) MirrorSelected(sel *MirrorSelection) {
+	rejected := make([]string, 0, len(sel.Rejected))
+	for _, m := range sel.Rejected {
+		rejected = append(rejected, RedactURL(m))
+	}
+	zap.L().Debug("Mirror selected",
+		zap.String("mirror", RedactURL(sel.Mirror)),
+		zap.String("source", sel.Source),
+		zap.Strings("rejected", rejected),
+		zap.String("reason", sel.Reason))
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type prometheusHook struct {
+	requests   *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+	bytes      *prometheus.CounterVec
+	retries    *prometheus.CounterVec
+	selections *prometheus.CounterVec
+}
+
+// NewPrometheusHook returns a hook counting the requests to the mirrors and
+// the mirrors selected, the metrics are registered to reg
+func NewPrometheusHook(reg prometheus.Registerer) (MirrorHook, error) {
+	h := &prometheusHook{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "tiup",
+			Subsystem: "mirror",
+			Name:      "requests_total",
+			Help:      "Total number of requests to the mirrors by status.",
+		}, []string{"mirror", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "tiup",
+			Subsystem: "mirror",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of the requests to the mirrors.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 15),
+		}, []string{"mirror"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "tiup",
+			Subsystem: "mirror",
+			Name:      "received_bytes_total",
+			Help:      "Total bytes received from the mirrors.",
+		}, []string{"mirror"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "tiup",
+			Subsystem: "mirror",
+			Name:      "retries_total",
+			Help:      "Total number of retries of the requests to the mirrors.",
+		}, []string{"mirror"}),
+		selections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "tiup",
+			Subsystem: "mirror",
+			Name:      "selections_total",
+			Help:      "Total number of the mirrors selected by source.",
+		}, []string{"mirror", "source"}),
+	}
+	for _, c := range []prometheus.Collector{h.requests, h.duration, h.bytes, h.retries, h.selections} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// RequestStart implements the MirrorHook interface
+func (h *prometheusHook) RequestStart(req *MirrorRequest) {}
+
+// RequestEnd implements the MirrorHook interface
+func (h *prometheusHook) RequestEnd(req *MirrorRequest, resp *MirrorResponse) {
+	mirror := RedactURL(req.Mirror)
+	status := "error"
+	if resp.Status != 0 {
+		status = strconv.Itoa(resp.Status)
+	}
+	h.requests.WithLabelValues(mirror, status).Inc()
+	h.duration.WithLabelValues(mirror).Observe(resp.Duration.Seconds())
+	h.bytes.WithLabelValues(mirror).Add(float64(resp.Bytes))
+	h.retries.WithLabelValues(mirror).Add(float64(resp.Retries))
+}
+
+// MirrorSelected implements the MirrorHook interface
+func (h *prometheusHook) MirrorSelected(sel *MirrorSelection) {
+	h.selections.WithLabelValues(RedactURL(sel.Mirror), sel.Source).Inc()
+}
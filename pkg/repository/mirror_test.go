@@ -0,0 +1,52 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirrorProxy(t *testing.T) {
+	// the proxy answers the requests to the mirror, which isn't resolvable
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		_, _ = w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	mirror := NewMirror("http://mirror.invalid", MirrorOptions{
+		Proxy: func(*http.Request) (*url.URL, error) { return proxyURL, nil },
+	})
+	require.NoError(t, mirror.Open())
+	defer mirror.Close()
+
+	r, err := mirror.Fetch("timestamp.json", 0)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	r.Close()
+	assert.Equal(t, "proxied", string(data))
+	require.Len(t, proxied, 1)
+	assert.Contains(t, proxied[0], "http://mirror.invalid/timestamp.json")
+}
@@ -0,0 +1,545 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pingcap/errors"
+	ru "github.com/pingcap/tiup/pkg/repository/utils"
+	"github.com/pingcap/tiup/pkg/repository/v0manifest"
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// mirrorLockFile is the file in a local mirror locked by the modifications
+const mirrorLockFile = ".lock"
+
+var (
+	// ErrVersionExists means the version to publish is already in the mirror
+	ErrVersionExists = stderrors.New("version already exists")
+	// ErrNotOwner means the key to publish with is not of the owner of the component
+	ErrNotOwner = stderrors.New("not the owner of the component")
+)
+
+// PublishInfo is the metadata of a component tarball to publish
+type PublishInfo struct {
+	ID          string
+	Version     string
+	Platform    string // os/arch
+	Entry       string
+	Description string
+	Standalone  bool // only for a new component
+	Hidden      bool // only for a new component
+}
+
+// PublishOptions represents the options of publishing a component
+type PublishOptions struct {
+	// the version may replace a yanked one of the same platform, it can't
+	// replace any other existing version
+	OverwriteYanked bool
+	// the directory of the private keys of the index, snapshot and timestamp
+	// manifests, it's the keys directory of the mirror if empty
+	KeyDir string
+}
+
+func (info *PublishInfo) validate() error {
+	if info.ID == "" {
+		return errors.New("component id is empty")
+	}
+	if _, found := v1manifest.ManifestsConfig[strings.ToLower(info.ID)]; found {
+		return errors.Errorf("component id '%s' is not allowed, please use another one", info.ID)
+	}
+	if v := v0manifest.Version(info.Version); !v.IsValid() && !v.IsNightly() {
+		return errors.Errorf("invalid version '%s'", info.Version)
+	}
+	if plat := strings.Split(info.Platform, "/"); len(plat) != 2 || plat[0] == "" || plat[1] == "" {
+		return errors.Errorf("invalid platform '%s', it should be like linux/amd64", info.Platform)
+	}
+	if info.Entry == "" {
+		return errors.New("entry of the component is empty")
+	}
+	return nil
+}
+
+// tarballBinaries returns the executable files in the tarball
+func tarballBinaries(tarball string) ([]string, error) {
+	f, err := os.Open(tarball)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer f.Close()
+	return utils.TarBinaries(f)
+}
+
+// tarballName returns the name of the tarball in the mirror
+func (info *PublishInfo) tarballName() string {
+	return fmt.Sprintf("%s-%s-%s.tar.gz", info.ID, info.Version, strings.Replace(info.Platform, "/", "-", 1))
+}
+
+// Publish publishes the tarball of a component to the local mirror in dir, the
+// component manifest is signed with the key of its owner, and the index, snapshot
+// and timestamp manifests are signed with the keys of the mirror. The versioned
+// manifests are written before the snapshot and the timestamp, so the mirror
+// either serves the new version or stays as it was, and all the files written
+// are removed if any step fails.
+func Publish(dir, tarball string, info PublishInfo, ownerKey *v1manifest.KeyInfo, opt PublishOptions) error {
+	if err := info.validate(); err != nil {
+		return err
+	}
+
+	unlock, err := lockMirror(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	m, err := openLocalMirror(dir, opt.KeyDir)
+	if err != nil {
+		return err
+	}
+	owner, err := keyOwner(&m.index, ownerKey)
+	if err != nil {
+		return err
+	}
+
+	initTime := time.Now().UTC()
+	comp := v1manifest.NewComponent(info.ID, info.Description, initTime)
+	item, exists := m.index.Components[info.ID]
+	if exists {
+		if item.Owner != owner {
+			return errors.Annotatef(ErrNotOwner, "component '%s' is owned by '%s'", info.ID, item.Owner)
+		}
+		if comp, err = m.component(info.ID); err != nil {
+			return err
+		}
+		comp.Version++
+		renewManifest(comp, initTime)
+		if info.Description != "" {
+			comp.Description = info.Description
+		}
+		if vi, found := comp.Platforms[info.Platform][info.Version]; found && !(vi.Yanked && opt.OverwriteYanked) {
+			if vi.Yanked {
+				return errors.Annotatef(ErrVersionExists, "%s:%s for %s is yanked, overwrite it with --overwrite-yanked", info.ID, info.Version, info.Platform)
+			}
+			return errors.Annotatef(ErrVersionExists, "%s:%s for %s", info.ID, info.Version, info.Platform)
+		}
+	}
+
+	hashes, length, err := ru.HashFile(tarball)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	binaries, err := tarballBinaries(tarball)
+	if err != nil {
+		return errors.Annotatef(err, "read the binaries of %s", tarball)
+	}
+	if v0manifest.Version(info.Version).IsNightly() {
+		comp.Nightly = info.Version
+	}
+	if comp.Platforms[info.Platform] == nil {
+		comp.Platforms[info.Platform] = map[string]v1manifest.VersionItem{}
+	}
+	comp.Platforms[info.Platform][info.Version] = v1manifest.VersionItem{
+		Entry:    info.Entry,
+		Released: initTime.Format(time.RFC3339),
+		URL:      "/" + info.tarballName(),
+		Binaries: binaries,
+		FileHash: v1manifest.FileHash{
+			Hashes: hashes,
+			Length: uint(length),
+		},
+	}
+
+	signedManifests := make(map[string]*v1manifest.Manifest)
+	if signedManifests[info.ID], err = v1manifest.SignManifest(comp, ownerKey); err != nil {
+		return err
+	}
+
+	if !exists {
+		m.index.Components[info.ID] = v1manifest.ComponentItem{
+			Owner:      owner,
+			URL:        "/" + comp.Filename(),
+			Standalone: info.Standalone,
+			Hidden:     info.Hidden,
+		}
+	}
+	m.index.Version++
+	renewManifest(&m.index, initTime)
+	if signedManifests[v1manifest.ManifestTypeIndex], err = v1manifest.SignManifest(&m.index, m.keys[v1manifest.ManifestTypeIndex]...); err != nil {
+		return err
+	}
+
+	return m.commit(initTime, signedManifests, func(txn *mirrorTxn) error {
+		return txn.copyFile(tarball, info.tarballName())
+	})
+}
+
+// localMirror is a local mirror being modified, with its manifests and the
+// keys to sign them
+type localMirror struct {
+	dir       string
+	root      v1manifest.Root
+	snapshot  v1manifest.Snapshot
+	timestamp v1manifest.Timestamp
+	index     v1manifest.Index
+	keys      map[string][]*v1manifest.KeyInfo
+}
+
+// openLocalMirror reads the manifests of the local mirror in dir, and the
+// private keys of the index, snapshot and timestamp manifests in keyDir,
+// which is the keys directory of the mirror if empty
+func openLocalMirror(dir, keyDir string) (*localMirror, error) {
+	m, err := readLocalMirror(dir)
+	if err != nil {
+		return nil, err
+	}
+	privKeys, err := loadPrivateKeys(mirrorKeyDir(dir, keyDir))
+	if err != nil {
+		return nil, err
+	}
+	m.keys, err = roleKeys(privKeys, &m.root,
+		v1manifest.ManifestTypeIndex, v1manifest.ManifestTypeSnapshot, v1manifest.ManifestTypeTimestamp)
+	if err != nil {
+		return nil, errors.Annotatef(err, "keys in %s", mirrorKeyDir(dir, keyDir))
+	}
+	return m, nil
+}
+
+// mirrorKeyDir returns keyDir, or the keys directory of the mirror if it's empty
+func mirrorKeyDir(dir, keyDir string) string {
+	if keyDir == "" {
+		return filepath.Join(dir, "keys")
+	}
+	return keyDir
+}
+
+// readLocalMirror reads the manifests of the local mirror in dir
+func readLocalMirror(dir string) (*localMirror, error) {
+	m := &localMirror{dir: dir}
+	for fname, role := range map[string]v1manifest.ValidManifest{
+		v1manifest.ManifestFilenameRoot:      &m.root,
+		v1manifest.ManifestFilenameSnapshot:  &m.snapshot,
+		v1manifest.ManifestFilenameTimestamp: &m.timestamp,
+	} {
+		if err := readMirrorManifest(filepath.Join(dir, fname), role); err != nil {
+			return nil, err
+		}
+	}
+	indexVer := m.snapshot.Meta[v1manifest.ManifestURLIndex].Version
+	if err := readMirrorManifest(filepath.Join(dir, FnameWithVersion(v1manifest.ManifestFilenameIndex, indexVer)), &m.index); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// component reads the latest manifest of the component
+func (m *localMirror) component(id string) (*v1manifest.Component, error) {
+	fname := v1manifest.ComponentManifestFilename(id)
+	ver := m.snapshot.Meta["/"+fname].Version
+	comp := &v1manifest.Component{}
+	if err := readMirrorManifest(filepath.Join(m.dir, FnameWithVersion(fname, ver)), comp); err != nil {
+		return nil, err
+	}
+	return comp, nil
+}
+
+// commit bumps the snapshot and the timestamp with the signed manifests, and
+// writes them, see write.
+func (m *localMirror) commit(initTime time.Time, signedManifests map[string]*v1manifest.Manifest, prepare func(txn *mirrorTxn) error) error {
+	signedSnapshot, signedTimestamp, err := m.bump(initTime, signedManifests)
+	if err != nil {
+		return err
+	}
+	return m.write(signedManifests, signedSnapshot, signedTimestamp, prepare)
+}
+
+// bump bumps the snapshot with the versions of the signed manifests and the
+// timestamp with the snapshot, and signs them
+func (m *localMirror) bump(initTime time.Time, signedManifests map[string]*v1manifest.Manifest) (signedSnapshot, signedTimestamp *v1manifest.Manifest, err error) {
+	if _, err := m.snapshot.SetVersions(signedManifests); err != nil {
+		return nil, nil, err
+	}
+	m.snapshot.Version++
+	renewManifest(&m.snapshot, initTime)
+	signedSnapshot, err = v1manifest.SignManifest(&m.snapshot, m.keys[v1manifest.ManifestTypeSnapshot]...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := m.timestamp.SetSnapshot(signedSnapshot); err != nil {
+		return nil, nil, err
+	}
+	m.timestamp.Version++
+	renewManifest(&m.timestamp, initTime)
+	signedTimestamp, err = v1manifest.SignManifest(&m.timestamp, m.keys[v1manifest.ManifestTypeTimestamp]...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signedSnapshot, signedTimestamp, nil
+}
+
+// write writes the files by prepare, the signed manifests, the snapshot and
+// the timestamp in order. The mirror is restored if any of them fails.
+func (m *localMirror) write(signedManifests map[string]*v1manifest.Manifest, signedSnapshot, signedTimestamp *v1manifest.Manifest, prepare func(txn *mirrorTxn) error) (err error) {
+	txn := &mirrorTxn{dir: m.dir}
+	defer func() {
+		if err != nil {
+			if rerr := txn.rollback(); rerr != nil {
+				err = errors.Annotatef(err, "rollback failed: %s", rerr)
+			}
+		}
+	}()
+	if prepare != nil {
+		if err := prepare(txn); err != nil {
+			return err
+		}
+	}
+	for _, sm := range signedManifests {
+		if err := txn.writeManifest(FnameWithVersion(sm.Signed.Filename(), sm.Signed.Base().Version), sm); err != nil {
+			return err
+		}
+	}
+	// the timestamp is the last one, clients see the changes only after it's written
+	if err := txn.writeManifest(v1manifest.ManifestFilenameSnapshot, signedSnapshot); err != nil {
+		return err
+	}
+	if err := txn.writeManifest(v1manifest.ManifestFilenameTimestamp, signedTimestamp); err != nil {
+		return err
+	}
+	txn.commit()
+	return nil
+}
+
+// lockMirror locks the local mirror in dir exclusively, the modifications of
+// it wait for each other
+func lockMirror(dir string) (func(), error) {
+	lock, err := os.OpenFile(filepath.Join(dir, mirrorLockFile), os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		lock.Close()
+		return nil, errors.Trace(err)
+	}
+	return func() {
+		_ = syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+		lock.Close()
+	}, nil
+}
+
+// renewManifest extends the expiration of the manifest as v1manifest.RenewManifest
+// does, but never shortens it, e.g. for a mirror cloned for offline use
+func renewManifest(m v1manifest.ValidManifest, initTime time.Time) {
+	old := m.Base().Expires
+	v1manifest.RenewManifest(m, initTime)
+	oldExpires, err := time.Parse(time.RFC3339, old)
+	if err != nil {
+		return
+	}
+	if expires, err := time.Parse(time.RFC3339, m.Base().Expires); err == nil && oldExpires.After(expires) {
+		m.Base().Expires = old
+	}
+}
+
+func readMirrorManifest(fname string, role v1manifest.ValidManifest) error {
+	f, err := os.Open(fname)
+	if err != nil {
+		return errors.Annotatef(err, "read manifest of the mirror")
+	}
+	defer f.Close()
+	return errors.Annotatef(v1manifest.ReadNoVerify(f, role), "read %s", fname)
+}
+
+// loadRoleKeys loads the private keys in keyDir of the roles declared in root
+func loadRoleKeys(keyDir string, root *v1manifest.Root, roles ...string) (map[string][]*v1manifest.KeyInfo, error) {
+	privKeys, err := loadPrivateKeys(keyDir)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := roleKeys(privKeys, root, roles...)
+	return keys, errors.Annotatef(err, "keys in %s", keyDir)
+}
+
+// loadPrivateKeys loads the private keys in keyDir by their IDs
+func loadPrivateKeys(keyDir string) (map[string]*v1manifest.KeyInfo, error) {
+	files, err := ioutil.ReadDir(keyDir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	privKeys := make(map[string]*v1manifest.KeyInfo)
+	for _, fi := range files {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ".json" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(keyDir, fi.Name()))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		ki := &v1manifest.KeyInfo{}
+		if err := json.Unmarshal(data, ki); err != nil || !ki.IsPrivate() {
+			continue
+		}
+		id, err := ki.ID()
+		if err != nil {
+			continue
+		}
+		privKeys[id] = ki
+	}
+	return privKeys, nil
+}
+
+// roleKeys returns the private keys of the roles declared in root, there must
+// be enough of them to meet the thresholds
+func roleKeys(privKeys map[string]*v1manifest.KeyInfo, root *v1manifest.Root, roles ...string) (map[string][]*v1manifest.KeyInfo, error) {
+	keys := make(map[string][]*v1manifest.KeyInfo)
+	for _, ty := range roles {
+		role, ok := root.Roles[ty]
+		if !ok {
+			return nil, errors.Errorf("role %s not found in the root manifest", ty)
+		}
+		for id := range role.Keys {
+			if ki, ok := privKeys[id]; ok {
+				keys[ty] = append(keys[ty], ki)
+			}
+		}
+		if uint(len(keys[ty])) < role.Threshold {
+			return nil, errors.Annotatef(v1manifest.ErrorInsufficientKeys, "%s: found %d of %d", ty, len(keys[ty]), role.Threshold)
+		}
+	}
+	return keys, nil
+}
+
+// keyOwner returns the owner in the index the key belongs to
+func keyOwner(index *v1manifest.Index, key *v1manifest.KeyInfo) (string, error) {
+	if key == nil || !key.IsPrivate() {
+		return "", errors.New("the private key of the owner is required")
+	}
+	id, err := key.ID()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	for name, owner := range index.Owners {
+		if _, ok := owner.Keys[id]; ok {
+			return name, nil
+		}
+	}
+	return "", errors.Annotatef(ErrNotOwner, "key %s is not of any owner", id[:v1manifest.ShortKeyIDLength])
+}
+
+// mirrorTxn writes the files of a modification to the mirror, and restores
+// the mirror if the modification fails
+type mirrorTxn struct {
+	dir     string
+	written []string          // the files written
+	backups map[string]string // the files replaced -> their backups
+}
+
+// prepare backs up the file to be replaced
+func (t *mirrorTxn) prepare(name string) (string, error) {
+	fp := filepath.Join(t.dir, name)
+	t.written = append(t.written, fp)
+	if utils.IsNotExist(fp) {
+		return fp, nil
+	}
+	backup := fmt.Sprintf("%s.bak.%d", fp, time.Now().UnixNano())
+	if err := utils.Copy(fp, backup); err != nil {
+		return "", errors.Trace(err)
+	}
+	if t.backups == nil {
+		t.backups = make(map[string]string)
+	}
+	t.backups[fp] = backup
+	return fp, nil
+}
+
+// write writes the file atomically through a temporary file
+func (t *mirrorTxn) write(name string, writeTo func(w io.Writer) error) error {
+	fp, err := t.prepare(name)
+	if err != nil {
+		return err
+	}
+	tmp := fp + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := writeTo(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return errors.Trace(err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return errors.Trace(err)
+	}
+	return errors.Trace(os.Rename(tmp, fp))
+}
+
+func (t *mirrorTxn) writeManifest(name string, m *v1manifest.Manifest) error {
+	return t.write(name, func(w io.Writer) error {
+		return v1manifest.WriteManifest(w, m)
+	})
+}
+
+func (t *mirrorTxn) copyFile(src, name string) error {
+	return t.write(name, func(w io.Writer) error {
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+}
+
+// commit removes the backups of the files replaced
+func (t *mirrorTxn) commit() {
+	for _, backup := range t.backups {
+		_ = os.Remove(backup)
+	}
+	t.backups = nil
+}
+
+// rollback removes the files written, and restores the ones replaced
+func (t *mirrorTxn) rollback() error {
+	var errs []string
+	for i := len(t.written) - 1; i >= 0; i-- {
+		fp := t.written[i]
+		if backup, ok := t.backups[fp]; ok {
+			if err := os.Rename(backup, fp); err != nil {
+				errs = append(errs, err.Error())
+			}
+			delete(t.backups, fp)
+			continue
+		}
+		if err := os.Remove(fp); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initPublishMirror initializes a local mirror with an owner, and returns the
+// private key of the owner
+func initPublishMirror(t *testing.T, dir string) *v1manifest.KeyInfo {
+	keyDir := filepath.Join(dir, "keys")
+	require.NoError(t, os.MkdirAll(keyDir, 0755))
+	require.NoError(t, v1manifest.Init(dir, keyDir, time.Now()))
+
+	var root v1manifest.Root
+	var index v1manifest.Index
+	require.NoError(t, readMirrorManifest(filepath.Join(dir, v1manifest.ManifestFilenameRoot), &root))
+	require.NoError(t, readMirrorManifest(filepath.Join(dir, v1manifest.ManifestFilenameIndex), &index))
+	keys, err := loadRoleKeys(keyDir, &root, v1manifest.ManifestTypeIndex, v1manifest.ManifestTypeSnapshot)
+	require.NoError(t, err)
+
+	ownerKey, err := v1manifest.GenKeyInfo()
+	require.NoError(t, err)
+	id, err := ownerKey.ID()
+	require.NoError(t, err)
+	pub, err := ownerKey.Public()
+	require.NoError(t, err)
+	index.Owners["team"] = v1manifest.Owner{Name: "Team", Keys: map[string]*v1manifest.KeyInfo{id: pub}, Threshold: 1}
+	signed, err := v1manifest.SignManifest(&index, keys[v1manifest.ManifestTypeIndex]...)
+	require.NoError(t, err)
+	require.NoError(t, v1manifest.WriteManifestFile(filepath.Join(dir, "1.index.json"), signed))
+
+	// v1manifest.Init doesn't add the root to the snapshot
+	var snapshot v1manifest.Snapshot
+	require.NoError(t, readMirrorManifest(filepath.Join(dir, v1manifest.ManifestFilenameSnapshot), &snapshot))
+	snapshot.Meta[v1manifest.ManifestURLRoot] = v1manifest.FileVersion{Version: root.Version}
+	signed, err = v1manifest.SignManifest(&snapshot, keys[v1manifest.ManifestTypeSnapshot]...)
+	require.NoError(t, err)
+	require.NoError(t, v1manifest.WriteManifestFile(filepath.Join(dir, v1manifest.ManifestFilenameSnapshot), signed))
+	return ownerKey
+}
+
+// writeTestTarball writes a gzipped tarball of the empty files of the modes
+func writeTestTarball(t *testing.T, fp string, files map[string]os.FileMode) {
+	f, err := os.Create(fp)
+	require.NoError(t, err)
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for name, mode := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: int64(mode), Typeflag: tar.TypeReg}))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+}
+
+func readMirrorComponent(t *testing.T, dir, id string) *v1manifest.Component {
+	var snapshot v1manifest.Snapshot
+	require.NoError(t, readMirrorManifest(filepath.Join(dir, v1manifest.ManifestFilenameSnapshot), &snapshot))
+	ver := snapshot.Meta["/"+v1manifest.ComponentManifestFilename(id)].Version
+	comp := &v1manifest.Component{}
+	require.NoError(t, readMirrorManifest(filepath.Join(dir, FnameWithVersion(v1manifest.ComponentManifestFilename(id), ver)), comp))
+	return comp
+}
+
+func TestPublish(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiup-publish")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ownerKey := initPublishMirror(t, dir)
+
+	tarball := filepath.Join(dir, "tool.tar.gz")
+	writeTestTarball(t, tarball, map[string]os.FileMode{"tool": 0755, "bin/tool-ctl": 0755, "LICENSE": 0644})
+	fi, err := os.Stat(tarball)
+	require.NoError(t, err)
+	info := PublishInfo{ID: "tool", Version: "v1.0.0", Platform: "linux/amd64", Entry: "tool", Description: "an internal tool"}
+
+	// a new component
+	require.NoError(t, Publish(dir, tarball, info, ownerKey, PublishOptions{}))
+	comp := readMirrorComponent(t, dir, "tool")
+	assert.Equal(t, uint(1), comp.Version)
+	vi := comp.Platforms["linux/amd64"]["v1.0.0"]
+	assert.Equal(t, "/tool-v1.0.0-linux-amd64.tar.gz", vi.URL)
+	assert.Equal(t, uint(fi.Size()), vi.Length)
+	// the executable files are listed as the binaries
+	assert.Equal(t, []string{"bin/tool-ctl", "tool"}, vi.Binaries)
+	assert.FileExists(t, filepath.Join(dir, "tool-v1.0.0-linux-amd64.tar.gz"))
+
+	var index v1manifest.Index
+	require.NoError(t, readMirrorManifest(filepath.Join(dir, "2.index.json"), &index))
+	assert.Equal(t, "team", index.Components["tool"].Owner)
+	var timestamp v1manifest.Timestamp
+	require.NoError(t, readMirrorManifest(filepath.Join(dir, v1manifest.ManifestFilenameTimestamp), &timestamp))
+	assert.Equal(t, uint(2), timestamp.Version)
+
+	// the version exists
+	err = Publish(dir, tarball, info, ownerKey, PublishOptions{OverwriteYanked: true})
+	assert.Equal(t, ErrVersionExists, errors.Cause(err))
+
+	// the key is not of any owner
+	otherKey, err := v1manifest.GenKeyInfo()
+	require.NoError(t, err)
+	info.Version = "v1.1.0"
+	err = Publish(dir, tarball, info, otherKey, PublishOptions{})
+	assert.Equal(t, ErrNotOwner, errors.Cause(err))
+
+	// the yanked version is only overwritten with OverwriteYanked
+	vi.Yanked = true
+	comp.Platforms["linux/amd64"]["v1.0.0"] = vi
+	signed, err := v1manifest.SignManifest(comp, ownerKey)
+	require.NoError(t, err)
+	require.NoError(t, v1manifest.WriteManifestFile(filepath.Join(dir, "1.tool.json"), signed))
+	info.Version = "v1.0.0"
+	err = Publish(dir, tarball, info, ownerKey, PublishOptions{})
+	assert.Equal(t, ErrVersionExists, errors.Cause(err))
+	require.NoError(t, Publish(dir, tarball, info, ownerKey, PublishOptions{OverwriteYanked: true}))
+	comp = readMirrorComponent(t, dir, "tool")
+	assert.Equal(t, uint(2), comp.Version)
+	assert.False(t, comp.Platforms["linux/amd64"]["v1.0.0"].Yanked)
+
+	// the mirror is restored if it fails to write the timestamp
+	snapshot, err := ioutil.ReadFile(filepath.Join(dir, v1manifest.ManifestFilenameSnapshot))
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, v1manifest.ManifestFilenameTimestamp+".tmp"), 0755))
+	info.Version = "v1.2.0"
+	assert.Error(t, Publish(dir, tarball, info, ownerKey, PublishOptions{}))
+	restored, err := ioutil.ReadFile(filepath.Join(dir, v1manifest.ManifestFilenameSnapshot))
+	require.NoError(t, err)
+	assert.Equal(t, snapshot, restored)
+	for _, fname := range []string{"3.tool.json", "4.index.json", "tool-v1.2.0-linux-amd64.tar.gz"} {
+		assert.NoFileExists(t, filepath.Join(dir, fname))
+	}
+	backups, err := filepath.Glob(filepath.Join(dir, "*.bak.*"))
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
+
+func TestYank(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiup-yank")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ownerKey := initPublishMirror(t, dir)
+
+	tarball := filepath.Join(dir, "tool.tar.gz")
+	writeTestTarball(t, tarball, map[string]os.FileMode{"tool": 0755})
+	for _, plat := range []string{"linux/amd64", "linux/arm64"} {
+		info := PublishInfo{ID: "tool", Version: "v1.0.0", Platform: plat, Entry: "tool"}
+		require.NoError(t, Publish(dir, tarball, info, ownerKey, PublishOptions{}))
+	}
+
+	// a client of the mirror
+	profileDir := filepath.Join(dir, "profile")
+	require.NoError(t, os.MkdirAll(filepath.Join(profileDir, "bin"), 0755))
+	require.NoError(t, utils.Copy(filepath.Join(dir, v1manifest.ManifestFilenameRoot), filepath.Join(profileDir, "bin", v1manifest.ManifestFilenameRoot)))
+	local, err := v1manifest.NewManifests(localdata.NewProfile(profileDir, &localdata.TiUPConfig{}))
+	require.NoError(t, err)
+	repo := NewV1Repo(NewMirror(dir, MirrorOptions{}), Options{GOOS: "linux", GOARCH: "amd64"}, local)
+	comp, err := repo.FetchComponentManifest("tool", true)
+	require.NoError(t, err)
+	assert.Contains(t, comp.VersionList("linux/amd64"), "v1.0.0")
+
+	// nothing is modified in the dry run
+	diff, err := Yank(dir, "tool", "v1.0.0", true, ownerKey, YankOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Contains(t, diff, "yanked")
+	assert.False(t, readMirrorComponent(t, dir, "tool").Platforms["linux/amd64"]["v1.0.0"].Yanked)
+
+	_, err = Yank(dir, "tool", "v1.0.0", true, ownerKey, YankOptions{})
+	require.NoError(t, err)
+	comp = readMirrorComponent(t, dir, "tool")
+	assert.Equal(t, uint(3), comp.Version)
+	for _, plat := range []string{"linux/amd64", "linux/arm64"} {
+		assert.True(t, comp.Platforms[plat]["v1.0.0"].Yanked)
+	}
+
+	// the client sees it after refreshing the manifests
+	comp, err = repo.FetchComponentManifest("tool", true)
+	require.NoError(t, err)
+	assert.NotContains(t, comp.VersionList("linux/amd64"), "v1.0.0")
+	assert.True(t, comp.VersionListWithYanked("linux/amd64")["v1.0.0"].Yanked)
+
+	// yanking it again is a no-op
+	diff, err = Yank(dir, "tool", "v1.0.0", true, ownerKey, YankOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+
+	_, err = Yank(dir, "tool", "v1.0.0", false, ownerKey, YankOptions{})
+	require.NoError(t, err)
+	comp, err = repo.FetchComponentManifest("tool", true)
+	require.NoError(t, err)
+	assert.Contains(t, comp.VersionList("linux/amd64"), "v1.0.0")
+
+	_, err = Yank(dir, "tool", "v2.0.0", true, ownerKey, YankOptions{})
+	assert.Error(t, err)
+}
+
+func TestRotateKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiup-rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ownerKey := initPublishMirror(t, dir)
+
+	tarball := filepath.Join(dir, "tool.tar.gz")
+	writeTestTarball(t, tarball, map[string]os.FileMode{"tool": 0755})
+	info := PublishInfo{ID: "tool", Version: "v1.0.0", Platform: "linux/amd64", Entry: "tool"}
+	require.NoError(t, Publish(dir, tarball, info, ownerKey, PublishOptions{}))
+
+	// a client trusting the old root
+	profileDir := filepath.Join(dir, "profile")
+	require.NoError(t, os.MkdirAll(filepath.Join(profileDir, "bin"), 0755))
+	require.NoError(t, utils.Copy(filepath.Join(dir, v1manifest.ManifestFilenameRoot), filepath.Join(profileDir, "bin", v1manifest.ManifestFilenameRoot)))
+	local, err := v1manifest.NewManifests(localdata.NewProfile(profileDir, &localdata.TiUPConfig{}))
+	require.NoError(t, err)
+	repo := NewV1Repo(NewMirror(dir, MirrorOptions{}), Options{GOOS: "linux", GOARCH: "amd64"}, local)
+	_, err = repo.FetchComponentManifest("tool", false)
+	require.NoError(t, err)
+
+	opt := RotateOptions{
+		Roles:  []string{v1manifest.ManifestTypeRoot, v1manifest.ManifestTypeIndex, v1manifest.ManifestTypeSnapshot, v1manifest.ManifestTypeTimestamp},
+		Owners: []string{"team"},
+	}
+	result, err := RotateKeys(dir, opt)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(result.BackupDir, v1manifest.ManifestFilenameRoot))
+	assert.FileExists(t, filepath.Join(dir, "2.root.json"))
+
+	var newOwnerKey *v1manifest.KeyInfo
+	for _, fname := range result.KeyFiles {
+		assert.FileExists(t, fname)
+		if strings.HasSuffix(fname, "-team.json") {
+			data, err := ioutil.ReadFile(fname)
+			require.NoError(t, err)
+			newOwnerKey = &v1manifest.KeyInfo{}
+			require.NoError(t, json.Unmarshal(data, newOwnerKey))
+		}
+	}
+	require.NotNil(t, newOwnerKey)
+
+	// the client moves to the new root
+	comp, err := repo.FetchComponentManifest("tool", false)
+	require.NoError(t, err)
+	assert.Contains(t, comp.VersionList("linux/amd64"), "v1.0.0")
+
+	// the component is published with the new owner key only
+	info.Version = "v1.1.0"
+	err = Publish(dir, tarball, info, ownerKey, PublishOptions{})
+	assert.Equal(t, ErrNotOwner, errors.Cause(err))
+	require.NoError(t, Publish(dir, tarball, info, newOwnerKey, PublishOptions{}))
+	comp, err = repo.FetchComponentManifest("tool", false)
+	require.NoError(t, err)
+	assert.Contains(t, comp.VersionList("linux/amd64"), "v1.1.0")
+
+	// the new root must be signed by both its old and new keys
+	keyDir := filepath.Join(dir, "keys")
+	m, err := readLocalMirror(dir)
+	require.NoError(t, err)
+	privKeys, err := loadPrivateKeys(keyDir)
+	require.NoError(t, err)
+	oldRootKeys, err := roleKeys(privKeys, &m.root, v1manifest.ManifestTypeRoot)
+	require.NoError(t, err)
+	newRootKey, err := v1manifest.GenKeyInfo()
+	require.NoError(t, err)
+	newRootPub, err := newRootKey.Public()
+	require.NoError(t, err)
+	newRootID, err := newRootKey.ID()
+	require.NoError(t, err)
+	newRoot := &v1manifest.Root{SignedBase: m.root.SignedBase, Roles: make(map[string]*v1manifest.Role)}
+	for ty, role := range m.root.Roles {
+		r := *role
+		if ty == v1manifest.ManifestTypeRoot {
+			r.Keys = map[string]*v1manifest.KeyInfo{newRootID: newRootPub}
+		}
+		newRoot.Roles[ty] = &r
+	}
+	newRoot.Version++
+	renewManifest(newRoot, time.Now().UTC())
+	for _, signers := range [][]*v1manifest.KeyInfo{oldRootKeys[v1manifest.ManifestTypeRoot], {newRootKey}} {
+		signed, err := v1manifest.SignManifest(newRoot, signers...)
+		require.NoError(t, err)
+		err = verifyRotation(m, &m.root, map[string]*v1manifest.Manifest{v1manifest.ManifestTypeRoot: signed}, nil, nil)
+		assert.NotNil(t, err)
+	}
+
+	// it's refused without the root keys for cross-signing
+	files, err := filepath.Glob(filepath.Join(keyDir, "*-root.json"))
+	require.NoError(t, err)
+	for _, f := range files {
+		require.NoError(t, os.Remove(f))
+	}
+	_, err = RotateKeys(dir, RotateOptions{Roles: []string{v1manifest.ManifestTypeSnapshot}})
+	assert.Equal(t, v1manifest.ErrorInsufficientKeys, errors.Cause(err))
+	assert.NoFileExists(t, filepath.Join(dir, "3.root.json"))
+}
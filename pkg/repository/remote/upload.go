@@ -15,6 +15,7 @@ package remote
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 	"strings"
@@ -51,6 +52,7 @@ type transporter struct {
 	endpoint    string
 	options     map[string]bool
 	filehash    v1manifest.FileHash
+	binaries    []string
 }
 
 // NewTransporter returns a Transporter
@@ -112,6 +114,14 @@ func (t *transporter) Open(tarball string) error {
 	if err != nil {
 		return err
 	}
+	if t.binaries, err = utils.TarBinaries(file); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return errors.Trace(err)
+	}
 	t.tarFile = file
 
 	return nil
@@ -173,6 +183,7 @@ func (t *transporter) Sign(key *v1manifest.KeyInfo, m *v1manifest.Component) err
 		Released: initTime.Format(time.RFC3339),
 		URL:      fmt.Sprintf("/%s-%s-%s-%s.tar.gz", t.component, t.version, t.os, t.arch),
 		FileHash: t.filehash,
+		Binaries: t.binaries,
 	}
 
 	url := fmt.Sprintf("%s/api/v1/component/%s/%s", t.endpoint, sha256, t.component)
@@ -0,0 +1,379 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	cjson "github.com/gibson042/canonicaljson-go"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// RotateOptions represents the options of rotating the keys of a local mirror
+type RotateOptions struct {
+	// the directory of the private keys of the mirror, it's the keys directory
+	// of the mirror if empty, the new keys are saved to it
+	KeyDir string
+	// the roles to rotate the keys of, they are root, index, snapshot and timestamp
+	Roles []string
+	// the owners in the index to rotate the keys of
+	Owners []string
+	// the new private keys of the roles or owners, keys are generated for the
+	// ones not in it
+	NewRoleKeys  map[string][]*v1manifest.KeyInfo
+	NewOwnerKeys map[string][]*v1manifest.KeyInfo
+}
+
+// RotateResult is the result of rotating the keys of a local mirror
+type RotateResult struct {
+	// the files of the new private keys saved
+	KeyFiles []string
+	// the directory of the backups of the manifests replaced
+	BackupDir string
+}
+
+// RotateKeys rotates the keys of the roles and owners of the local mirror in
+// dir. A new version of the root manifest is signed by both the old and new root
+// keys, so the clients trusting the old root can move to the new one, and the
+// index, the components of the owners rotated, the snapshot and the timestamp
+// are signed again by the new keys. The threshold of the old root keys must be
+// in the key directory. The new chain is verified from the old root before any
+// file is replaced, and the replaced manifests are backed up in a timestamped
+// directory of the mirror.
+func RotateKeys(dir string, opt RotateOptions) (*RotateResult, error) {
+	keyDir := mirrorKeyDir(dir, opt.KeyDir)
+
+	unlock, err := lockMirror(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	m, err := readLocalMirror(dir)
+	if err != nil {
+		return nil, err
+	}
+	privKeys, err := loadPrivateKeys(keyDir)
+	if err != nil {
+		return nil, err
+	}
+	oldRoot := m.root
+	oldRootKeys, err := roleKeys(privKeys, &oldRoot, v1manifest.ManifestTypeRoot)
+	if err != nil {
+		return nil, errors.Annotatef(err, "the old root keys in %s are required to sign the new root", keyDir)
+	}
+
+	// the new keys of the roles and owners
+	var newKeyFiles []string
+	newKeys := func(name string, threshold int, given []*v1manifest.KeyInfo) ([]*v1manifest.KeyInfo, error) {
+		keys := given
+		if len(keys) == 0 {
+			for i := 0; i < threshold; i++ {
+				k, err := v1manifest.GenKeyInfo()
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
+				keys = append(keys, k)
+			}
+		}
+		if len(keys) < threshold {
+			return nil, errors.Annotatef(v1manifest.ErrorInsufficientKeys, "%s: %d new keys given, %d required", name, len(keys), threshold)
+		}
+		for _, k := range keys {
+			if !k.IsPrivate() {
+				return nil, errors.Errorf("the new key of %s is not a private key", name)
+			}
+			id, err := k.ID()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			newKeyFiles = append(newKeyFiles, filepath.Join(keyDir, fmt.Sprintf("%s-%s.json", id[:v1manifest.ShortKeyIDLength], name)))
+		}
+		return keys, nil
+	}
+	publicKeys := func(keys []*v1manifest.KeyInfo) (map[string]*v1manifest.KeyInfo, error) {
+		pubs := make(map[string]*v1manifest.KeyInfo)
+		for _, k := range keys {
+			id, err := k.ID()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if pubs[id], err = k.Public(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		return pubs, nil
+	}
+
+	rotatedRoles := make(map[string][]*v1manifest.KeyInfo)
+	for _, ty := range opt.Roles {
+		role, ok := m.root.Roles[ty]
+		if !ok {
+			return nil, errors.Errorf("role %s not found in the root manifest", ty)
+		}
+		keys, err := newKeys(ty, int(role.Threshold), opt.NewRoleKeys[ty])
+		if err != nil {
+			return nil, err
+		}
+		rotatedRoles[ty] = keys
+	}
+	rotatedOwners := make(map[string][]*v1manifest.KeyInfo)
+	for _, name := range opt.Owners {
+		owner, ok := m.index.Owners[name]
+		if !ok {
+			return nil, errors.Errorf("owner %s not found in the index manifest", name)
+		}
+		keys, err := newKeys(name, owner.Threshold, opt.NewOwnerKeys[name])
+		if err != nil {
+			return nil, err
+		}
+		rotatedOwners[name] = keys
+	}
+
+	// the keys to sign the manifests with, the old ones are required for the
+	// roles not rotated
+	m.keys = make(map[string][]*v1manifest.KeyInfo)
+	for _, ty := range []string{v1manifest.ManifestTypeIndex, v1manifest.ManifestTypeSnapshot, v1manifest.ManifestTypeTimestamp} {
+		if keys, ok := rotatedRoles[ty]; ok {
+			m.keys[ty] = keys
+			continue
+		}
+		keys, err := roleKeys(privKeys, &oldRoot, ty)
+		if err != nil {
+			return nil, errors.Annotatef(err, "keys in %s", keyDir)
+		}
+		m.keys[ty] = keys[ty]
+	}
+
+	initTime := time.Now().UTC()
+	signedManifests := make(map[string]*v1manifest.Manifest)
+
+	// the new root is signed by both the old and new root keys
+	newRoot := &v1manifest.Root{SignedBase: oldRoot.SignedBase, Roles: make(map[string]*v1manifest.Role)}
+	for ty, role := range oldRoot.Roles {
+		r := *role
+		if keys, ok := rotatedRoles[ty]; ok {
+			if r.Keys, err = publicKeys(keys); err != nil {
+				return nil, err
+			}
+		}
+		newRoot.Roles[ty] = &r
+	}
+	newRoot.Version++
+	renewManifest(newRoot, initTime)
+	rootSigners := oldRootKeys[v1manifest.ManifestTypeRoot]
+	if keys, ok := rotatedRoles[v1manifest.ManifestTypeRoot]; ok {
+		rootSigners = append(append([]*v1manifest.KeyInfo{}, rootSigners...), keys...)
+	}
+	if signedManifests[v1manifest.ManifestTypeRoot], err = v1manifest.SignManifest(newRoot, rootSigners...); err != nil {
+		return nil, err
+	}
+
+	// the components of the owners rotated are signed by their new keys
+	for name, keys := range rotatedOwners {
+		owner := m.index.Owners[name]
+		if owner.Keys, err = publicKeys(keys); err != nil {
+			return nil, err
+		}
+		m.index.Owners[name] = owner
+	}
+	for id, item := range m.index.Components {
+		keys, ok := rotatedOwners[item.Owner]
+		if !ok {
+			continue
+		}
+		comp, err := m.component(id)
+		if err != nil {
+			return nil, err
+		}
+		comp.Version++
+		renewManifest(comp, initTime)
+		if signedManifests[id], err = v1manifest.SignManifest(comp, keys...); err != nil {
+			return nil, err
+		}
+	}
+
+	m.index.Version++
+	renewManifest(&m.index, initTime)
+	if signedManifests[v1manifest.ManifestTypeIndex], err = v1manifest.SignManifest(&m.index, m.keys[v1manifest.ManifestTypeIndex]...); err != nil {
+		return nil, err
+	}
+
+	signedSnapshot, signedTimestamp, err := m.bump(initTime, signedManifests)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyRotation(m, &oldRoot, signedManifests, signedSnapshot, signedTimestamp); err != nil {
+		return nil, errors.Annotate(err, "verify the rotated manifests")
+	}
+
+	// save the new keys before replacing anything, so they are never lost
+	result := &RotateResult{KeyFiles: newKeyFiles}
+	for name, keys := range rotatedRoles {
+		for _, k := range keys {
+			if err := v1manifest.SaveKeyInfo(k, name, keyDir); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+	}
+	for name, keys := range rotatedOwners {
+		for _, k := range keys {
+			if err := v1manifest.SaveKeyInfo(k, name, keyDir); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+	}
+	sort.Strings(result.KeyFiles)
+
+	result.BackupDir = filepath.Join(dir, "backups", initTime.Format("20060102150405"))
+	if err := os.MkdirAll(result.BackupDir, 0755); err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, fname := range []string{v1manifest.ManifestFilenameRoot, v1manifest.ManifestFilenameSnapshot, v1manifest.ManifestFilenameTimestamp} {
+		if err := utils.Copy(filepath.Join(dir, fname), filepath.Join(result.BackupDir, fname)); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
+	err = m.write(signedManifests, signedSnapshot, signedTimestamp, func(txn *mirrorTxn) error {
+		// the unversioned root is for bootstrapping new clients
+		return txn.writeManifest(v1manifest.ManifestFilenameRoot, signedManifests[v1manifest.ManifestTypeRoot])
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// verifyRotation verifies the rotated manifests as a client trusting the old
+// root does, the new root must also be trusted by its own keys as the clients
+// bootstrapped from it do
+func verifyRotation(m *localMirror, oldRoot *v1manifest.Root, signedManifests map[string]*v1manifest.Manifest, signedSnapshot, signedTimestamp *v1manifest.Manifest) error {
+	ks := v1manifest.NewKeyStore()
+	addRoles := func(root *v1manifest.Root) error {
+		for name, role := range root.Roles {
+			if err := ks.AddKeys(name, role.Threshold, root.Expires, role.Keys); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := addRoles(oldRoot); err != nil {
+		return err
+	}
+
+	encode := func(sm *v1manifest.Manifest) (*bytes.Reader, error) {
+		data, err := cjson.Marshal(sm)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return bytes.NewReader(data), nil
+	}
+
+	var root v1manifest.Root
+	r, err := encode(signedManifests[v1manifest.ManifestTypeRoot])
+	if err != nil {
+		return err
+	}
+	if _, err := v1manifest.ReadManifest(r, &root, ks); err != nil {
+		return err
+	}
+	if err := v1manifest.ExpiresAfter(&root, oldRoot); err != nil {
+		return err
+	}
+	// the rest of the chain is verified by the keys of the new root only
+	ks = v1manifest.NewKeyStore()
+	if err := addRoles(&root); err != nil {
+		return err
+	}
+	if r, err = encode(signedManifests[v1manifest.ManifestTypeRoot]); err != nil {
+		return err
+	}
+	if _, err := v1manifest.ReadManifest(r, &v1manifest.Root{}, ks); err != nil {
+		return errors.Annotate(err, "the new root is not signed by its own keys")
+	}
+
+	var index v1manifest.Index
+	if r, err = encode(signedManifests[v1manifest.ManifestTypeIndex]); err != nil {
+		return err
+	}
+	if _, err := v1manifest.ReadManifest(r, &index, ks); err != nil {
+		return err
+	}
+	for name, owner := range index.Owners {
+		if err := ks.AddKeys(name, uint(owner.Threshold), index.Expires, owner.Keys); err != nil {
+			return err
+		}
+	}
+	for id, item := range index.Components {
+		item := item
+		if sm, ok := signedManifests[id]; ok {
+			if r, err = encode(sm); err != nil {
+				return err
+			}
+			if _, err := v1manifest.ReadComponentManifest(r, &v1manifest.Component{}, &item, ks); err != nil {
+				return err
+			}
+			continue
+		}
+		// the components not signed again are verified as they are
+		fname := v1manifest.ComponentManifestFilename(id)
+		f, err := os.Open(filepath.Join(m.dir, FnameWithVersion(fname, m.snapshot.Meta["/"+fname].Version)))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		_, err = v1manifest.ReadComponentManifest(f, &v1manifest.Component{}, &item, ks)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	var snapshot v1manifest.Snapshot
+	if r, err = encode(signedSnapshot); err != nil {
+		return err
+	}
+	if _, err := v1manifest.ReadManifest(r, &snapshot, ks); err != nil {
+		return err
+	}
+	if v := snapshot.Meta[v1manifest.ManifestURLRoot].Version; v != root.Version {
+		return errors.Errorf("root version in the snapshot is %d, expected %d", v, root.Version)
+	}
+
+	var timestamp v1manifest.Timestamp
+	if r, err = encode(signedTimestamp); err != nil {
+		return err
+	}
+	if _, err := v1manifest.ReadManifest(r, &timestamp, ks); err != nil {
+		return err
+	}
+	data, err := cjson.Marshal(signedSnapshot)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	hash := sha256.Sum256(data)
+	if timestamp.SnapshotHash().Hashes[v1manifest.SHA256] != hex.EncodeToString(hash[:]) {
+		return errors.New("snapshot hash in the timestamp mismatches")
+	}
+	return nil
+}
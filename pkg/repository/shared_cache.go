@@ -0,0 +1,165 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/pingcap/tiup/pkg/verbose"
+)
+
+// SharedCache is a directory of component packages shared by all users of
+// the same machine, it is consulted before downloading a component. The
+// cache is read-only unless writing is explicitly enabled.
+type SharedCache struct {
+	dir      string
+	writable bool
+}
+
+// NewSharedCache returns a SharedCache of dir
+func NewSharedCache(dir string, writable bool) *SharedCache {
+	return &SharedCache{dir: dir, writable: writable}
+}
+
+// ConfiguredSharedCache returns the SharedCache configured by environment
+// variables or the config file of the profile, the environment variables
+// take precedence, nil is returned if it is not configured
+func ConfiguredSharedCache() *SharedCache {
+	return sharedCacheOf(localdata.InitProfile().Config)
+}
+
+// sharedCacheOf returns the SharedCache configured by environment variables,
+// or by cfg for the ones not set
+func sharedCacheOf(cfg *localdata.TiUPConfig) *SharedCache {
+	dir := os.Getenv(localdata.EnvNameSharedCacheDir)
+	if dir == "" && cfg != nil {
+		dir = cfg.SharedCacheDir
+	}
+	if dir == "" {
+		return nil
+	}
+	writable := cfg != nil && cfg.SharedCacheWrite
+	if w := os.Getenv(localdata.EnvNameSharedCacheWrite); w != "" {
+		writable = w == "1"
+	}
+	return NewSharedCache(dir, writable)
+}
+
+// path returns the path of the package of item in cache, the file name in
+// the url contains component, version and platform of the package
+func (c *SharedCache) path(item *v1manifest.VersionItem) string {
+	return filepath.Join(c.dir, filepath.Base(item.URL))
+}
+
+// Lookup returns the path of the cached package of item, the package is
+// ignored if its hash does not match
+func (c *SharedCache) Lookup(item *v1manifest.VersionItem) (string, bool) {
+	fp := c.path(item)
+	f, err := os.Open(fp)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	if err := utils.CheckSHA256(f, item.Hashes[v1manifest.SHA256]); err != nil {
+		verbose.Log("Ignore %s in shared cache: %s", fp, err)
+		return "", false
+	}
+	return fp, true
+}
+
+// Open returns the content of the cached package of item
+func (c *SharedCache) Open(item *v1manifest.VersionItem) (io.Reader, bool) {
+	fp, ok := c.Lookup(item)
+	if !ok {
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(fp)
+	if err != nil {
+		return nil, false
+	}
+	verbose.Log("Use %s from shared cache", fp)
+	return bytes.NewReader(data), true
+}
+
+// Link makes target a hard link of the cached package of item, the package
+// is copied if a hard link can not be created, e.g. they are on different
+// file systems. It returns false if the package is not cached.
+func (c *SharedCache) Link(item *v1manifest.VersionItem, target string) (bool, error) {
+	fp, ok := c.Lookup(item)
+	if !ok {
+		return false, nil
+	}
+	verbose.Log("Use %s from shared cache", fp)
+
+	_ = os.Remove(target)
+	if err := os.Link(fp, target); err == nil {
+		return true, nil
+	}
+	if err := utils.CopyFile(fp, target); err != nil {
+		return false, errors.Trace(err)
+	}
+	return true, nil
+}
+
+// Store adds the package of item to cache if writing is enabled, the content
+// is written to a temporary file and renamed, with an exclusive lock held so
+// concurrent writers of the same package do not conflict.
+func (c *SharedCache) Store(item *v1manifest.VersionItem, reader io.Reader) error {
+	if !c.writable {
+		return nil
+	}
+
+	fp := c.path(item)
+	lock, err := os.OpenFile(fp+".lock", os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return errors.Trace(err)
+	}
+	defer func() { _ = syscall.Flock(int(lock.Fd()), syscall.LOCK_UN) }()
+
+	// another user may have stored it while we are waiting for the lock
+	if _, ok := c.Lookup(item); ok {
+		return nil
+	}
+
+	tmp, err := ioutil.TempFile(c.dir, filepath.Base(fp)+".tmp-")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, reader); err != nil {
+		tmp.Close()
+		return errors.Trace(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Trace(err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(os.Rename(tmp.Name(), fp))
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiup-shared-cache")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	content := "component package"
+	hash, err := utils.SHA256(strings.NewReader(content))
+	assert.Nil(t, err)
+	item := &v1manifest.VersionItem{
+		URL:      "/tidb-v4.0.0-linux-amd64.tar.gz",
+		FileHash: v1manifest.FileHash{Hashes: map[string]string{v1manifest.SHA256: hash}},
+	}
+
+	// read-only cache never stores anything
+	readOnly := NewSharedCache(dir, false)
+	assert.Nil(t, readOnly.Store(item, strings.NewReader(content)))
+	_, ok := readOnly.Lookup(item)
+	assert.False(t, ok)
+
+	cache := NewSharedCache(dir, true)
+	assert.Nil(t, cache.Store(item, strings.NewReader(content)))
+	fp, ok := readOnly.Lookup(item)
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(dir, "tidb-v4.0.0-linux-amd64.tar.gz"), fp)
+
+	target := filepath.Join(dir, "linked.tar.gz")
+	ok, err = readOnly.Link(item, target)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	data, err := ioutil.ReadFile(target)
+	assert.Nil(t, err)
+	assert.Equal(t, content, string(data))
+
+	// packages with mismatched hash are ignored
+	item.Hashes[v1manifest.SHA256] = "mismatch"
+	_, ok = readOnly.Lookup(item)
+	assert.False(t, ok)
+	ok, err = readOnly.Link(item, target)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestSharedCacheConfig(t *testing.T) {
+	defer os.Unsetenv(localdata.EnvNameSharedCacheDir)
+	defer os.Unsetenv(localdata.EnvNameSharedCacheWrite)
+	os.Unsetenv(localdata.EnvNameSharedCacheDir)
+	os.Unsetenv(localdata.EnvNameSharedCacheWrite)
+
+	assert.Nil(t, sharedCacheOf(nil))
+	assert.Nil(t, sharedCacheOf(&localdata.TiUPConfig{}))
+
+	// the profile configures the cache if the variables are not set
+	cfg := &localdata.TiUPConfig{SharedCacheDir: "/shared/profile", SharedCacheWrite: true}
+	assert.Equal(t, NewSharedCache("/shared/profile", true), sharedCacheOf(cfg))
+
+	// the variables take precedence
+	os.Setenv(localdata.EnvNameSharedCacheDir, "/shared/env")
+	assert.Equal(t, NewSharedCache("/shared/env", true), sharedCacheOf(cfg))
+	os.Setenv(localdata.EnvNameSharedCacheWrite, "0")
+	assert.Equal(t, NewSharedCache("/shared/env", false), sharedCacheOf(cfg))
+	os.Unsetenv(localdata.EnvNameSharedCacheDir)
+	assert.Equal(t, NewSharedCache("/shared/profile", false), sharedCacheOf(cfg))
+}
@@ -16,8 +16,8 @@ package v0manifest
 import (
 	"fmt"
 	"sort"
-	"strings"
 
+	"github.com/pingcap/tiup/pkg/version"
 	"golang.org/x/mod/semver"
 )
 
@@ -54,7 +54,7 @@ func (v Version) IsEmpty() bool {
 
 // IsNightly returns true if the version is nightly
 func (v Version) IsNightly() bool {
-	return strings.Contains(string(v), "nightly")
+	return version.IsNightly(string(v))
 }
 
 // String implements the fmt.Stringer interface
@@ -67,7 +67,7 @@ func (manifest *VersionManifest) Sort() {
 	sort.Slice(manifest.Versions, func(i, j int) bool {
 		lhs := manifest.Versions[i].Version.String()
 		rhs := manifest.Versions[j].Version.String()
-		return semver.Compare(lhs, rhs) < 0
+		return version.Compare(lhs, rhs) < 0
 	})
 }
 
@@ -29,12 +29,12 @@ import (
 	"github.com/fatih/color"
 	cjson "github.com/gibson042/canonicaljson-go"
 	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/localdata"
 	"github.com/pingcap/tiup/pkg/repository/v0manifest"
 	"github.com/pingcap/tiup/pkg/repository/v1manifest"
 	"github.com/pingcap/tiup/pkg/utils"
 	"github.com/pingcap/tiup/pkg/verbose"
 	"github.com/pingcap/tiup/pkg/version"
-	"golang.org/x/mod/semver"
 )
 
 // errUnknownComponent represents the specific component cannot be found in index.json
@@ -167,6 +167,23 @@ func (r *V1Repository) UpdateComponents(specs []ComponentSpec) error {
 			errs = append(errs, err.Error())
 			continue
 		}
+
+		// only the installs into the profile are recorded
+		if spec.TargetDir == "" {
+			receipt := &localdata.InstallReceipt{
+				Component:       spec.ID,
+				Version:         spec.Version,
+				InstalledAt:     time.Now(),
+				Mirror:          r.mirror.Source(),
+				SnapshotVersion: r.local.ManifestVersion(v1manifest.ManifestFilenameSnapshot),
+				URL:             versionItem.URL,
+				Hashes:          versionItem.Hashes,
+				Binaries:        versionItem.EntryPoints(),
+			}
+			if err := r.local.SaveInstallReceipt(receipt); err != nil {
+				fmt.Println(color.YellowString("Failed to save the install receipt of %s:%s: %s", spec.ID, spec.Version, err))
+			}
+		}
 	}
 
 	if len(errs) > 0 {
@@ -213,13 +230,13 @@ func (r *V1Repository) selectVersion(id string, versions map[string]v1manifest.V
 	if target == "" {
 		var latest string
 		var latestItem v1manifest.VersionItem
-		for version, item := range versions {
-			if v0manifest.Version(version).IsNightly() {
+		for v, item := range versions {
+			if version.IsNightly(v) {
 				continue
 			}
 
-			if latest == "" || semver.Compare(version, latest) > 0 {
-				latest = version
+			if latest == "" || version.Compare(v, latest) > 0 {
+				latest = v
 				latestItem = item
 			}
 		}
@@ -320,13 +337,13 @@ func (r *V1Repository) updateLocalRoot() error {
 		return errors.AddStack(err)
 	}
 	startVersion := oldRoot.Version
-	keyStore := *r.local.KeyStore()
+	keyStore := r.local.KeyStore()
 
 	var newManifest *v1manifest.Manifest
 	var newRoot v1manifest.Root
 	for {
 		url := FnameWithVersion(v1manifest.ManifestURLRoot, oldRoot.Version+1)
-		nextManifest, err := r.fetchManifestWithKeyStore(url, &newRoot, maxRootSize, &keyStore)
+		nextManifest, err := r.fetchManifestWithKeyStore(url, &newRoot, maxRootSize, keyStore)
 		if err != nil {
 			// Break if we have read the newest version.
 			if errors.Cause(err) == ErrNotFound {
@@ -475,15 +492,35 @@ func (r *V1Repository) updateComponentManifest(id string, withYanked bool) (*v1m
 	return &component, nil
 }
 
-// FetchComponent downloads the component specified by item.
+// FetchComponent downloads the component specified by item, the shared
+// cache is consulted first if it is configured.
 func (r *V1Repository) FetchComponent(item *v1manifest.VersionItem) (io.Reader, error) {
+	cache := ConfiguredSharedCache()
+	if cache != nil {
+		if reader, ok := cache.Open(item); ok {
+			return reader, nil
+		}
+	}
+
 	reader, err := r.mirror.Fetch(item.URL, int64(item.Length))
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 	defer reader.Close()
 
-	return checkHash(reader, item.Hashes[v1manifest.SHA256])
+	bufReader, err := checkHash(reader, item.Hashes[v1manifest.SHA256])
+	if err != nil || cache == nil {
+		return bufReader, err
+	}
+
+	// failing to write the shared cache should not fail the download
+	if err := cache.Store(item, bufReader); err != nil {
+		verbose.Log("Failed to add %s to shared cache: %s", item.URL, err)
+	}
+	if _, err := bufReader.(io.Seeker).Seek(0, io.SeekStart); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return bufReader, nil
 }
 
 // FetchTimestamp downloads the timestamp file, validates it, and checks if the snapshot hash in it
@@ -665,6 +702,67 @@ func (r *V1Repository) FetchComponentManifest(id string, withYanked bool) (com *
 	return r.updateComponentManifest(id, withYanked)
 }
 
+// FetchIndexManifestRaw returns the bytes of the index manifest, the local
+// manifests are updated first if they are stale. The bytes are verified before
+// returned, so they could be trusted by the callers.
+func (r *V1Repository) FetchIndexManifestRaw() ([]byte, error) {
+	if _, err := r.FetchIndexManifest(); err != nil {
+		return nil, err
+	}
+	return r.loadRawIndex()
+}
+
+// FetchComponentManifestRaw returns the bytes of the manifest of the component,
+// the local manifests are updated first if they are stale. The bytes are
+// verified before returned, so they could be trusted by the callers.
+func (r *V1Repository) FetchComponentManifestRaw(id string) ([]byte, error) {
+	if _, err := r.FetchComponentManifest(id, false); err != nil {
+		return nil, err
+	}
+	return r.loadRawComponentManifest(id)
+}
+
+// loadRawIndex loads and verifies the bytes of the local index manifest
+func (r *V1Repository) loadRawIndex() ([]byte, error) {
+	raw, err := r.local.LoadRawManifest(v1manifest.ManifestFilenameIndex)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	if raw == nil {
+		return nil, errors.Errorf("no index manifest")
+	}
+	if _, err := v1manifest.ReadManifest(bytes.NewReader(raw), new(v1manifest.Index), r.local.KeyStore()); err != nil {
+		return nil, errors.Annotate(err, "verify index manifest")
+	}
+	return raw, nil
+}
+
+// loadRawComponentManifest loads and verifies the bytes of the local manifest
+// of the component, against the owner of it in the index manifest
+func (r *V1Repository) loadRawComponentManifest(id string) ([]byte, error) {
+	var index v1manifest.Index
+	if _, _, err := r.local.LoadManifest(&index); err != nil {
+		return nil, errors.Trace(err)
+	}
+	item, ok := index.ComponentList()[id]
+	if !ok {
+		return nil, errors.AddStack(errUnknownComponent)
+	}
+
+	filename := v1manifest.ComponentManifestFilename(id)
+	raw, err := r.local.LoadRawManifest(filename)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	if raw == nil {
+		return nil, errors.Errorf("no manifest of component %s", id)
+	}
+	if _, err := v1manifest.ReadComponentManifest(bytes.NewReader(raw), new(v1manifest.Component), &item, r.local.KeyStore()); err != nil {
+		return nil, errors.Annotatef(err, "verify manifest of component %s", id)
+	}
+	return raw, nil
+}
+
 // ComponentVersion returns version item of a component
 func (r *V1Repository) ComponentVersion(id, version string, includeYanked bool) (*v1manifest.VersionItem, error) {
 	manifest, err := r.FetchComponentManifest(id, includeYanked)
@@ -695,11 +793,11 @@ func (r *V1Repository) LatestStableVersion(id string, withYanked bool) (v0manife
 
 	var last string
 	for v := range versions {
-		if v0manifest.Version(v).IsNightly() {
+		if version.IsNightly(v) {
 			continue
 		}
 
-		if last == "" || semver.Compare(last, v) < 0 {
+		if last == "" || version.Compare(last, v) < 0 {
 			last = v
 		}
 	}
@@ -16,11 +16,14 @@ package repository
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"testing"
 
 	cjson "github.com/gibson042/canonicaljson-go"
@@ -347,6 +350,45 @@ func TestUpdateComponent(t *testing.T) {
 	// TODO test that invalid signature of component manifest causes an error
 }
 
+func TestRawManifests(t *testing.T) {
+	mirror := MockMirror{
+		Resources: map[string]string{},
+	}
+	local := v1manifest.NewMockManifests()
+	priv := setNewRoot(t, local)
+	repo := NewV1Repo(&mirror, Options{}, local)
+
+	index, indexPriv := indexManifest(t)
+	snapshot := snapshotManifest()
+	mirror.Resources["/5.index.json"] = serialize(t, index, priv)
+	mirror.Resources["/7.foo.json"] = serialize(t, componentManifest(), indexPriv)
+	local.Manifests[v1manifest.ManifestFilenameSnapshot] = &v1manifest.Manifest{Signed: snapshot}
+	assert.Nil(t, repo.updateLocalIndex(snapshot))
+	_, err := repo.updateComponentManifest("foo", false)
+	assert.Nil(t, err)
+
+	raw, err := repo.loadRawIndex()
+	assert.Nil(t, err)
+	assert.Contains(t, string(raw), `"/foo.json"`)
+
+	raw, err = repo.loadRawComponentManifest("foo")
+	assert.Nil(t, err)
+	var m v1manifest.RawManifest
+	assert.Nil(t, json.Unmarshal(raw, &m))
+	var foo v1manifest.Component
+	assert.Nil(t, json.Unmarshal(m.Signed, &foo))
+	assert.Equal(t, "foo", foo.ID)
+
+	// the yanked components are unknown
+	_, err = repo.loadRawComponentManifest("bar")
+	assert.Equal(t, errUnknownComponent, errors.Cause(err))
+
+	// the manifests modified locally are not trusted
+	local.Manifests["foo.json"].Signed.(*v1manifest.Component).Description = "modified"
+	_, err = repo.loadRawComponentManifest("foo")
+	assert.NotNil(t, err)
+}
+
 func TestDownloadManifest(t *testing.T) {
 	mirror := MockMirror{
 		Resources: map[string]string{},
@@ -505,6 +547,13 @@ func TestUpdateComponents(t *testing.T) {
 	assert.Equal(t, 1, len(local.Installed))
 	assert.Equal(t, "v2.0.1", local.Installed["foo"].Version)
 	assert.Equal(t, "foo201", local.Installed["foo"].Contents)
+	receipt := local.Receipts["foo"]
+	assert.NotNil(t, receipt)
+	assert.Equal(t, "v2.0.1", receipt.Version)
+	assert.Equal(t, mirror.Source(), receipt.Mirror)
+	assert.Equal(t, snapshot.Version, receipt.SnapshotVersion)
+	assert.Equal(t, "/foo-2.0.1.tar.gz", receipt.URL)
+	assert.False(t, receipt.InstalledAt.IsZero())
 
 	// Update
 	foo.Version = 8
@@ -600,6 +649,74 @@ func TestUpdateComponents(t *testing.T) {
 	assert.Equal(t, "foo202", local.Installed["foo"].Contents)
 }
 
+// TestConcurrentManifestUpdates shares one profile and repository between
+// goroutines updating the manifests and listing the installed components, it's
+// meant to be run with -race.
+func TestConcurrentManifestUpdates(t *testing.T) {
+	profileDir, err := ioutil.TempDir("", "tiup-*")
+	assert.Nil(t, err)
+	defer os.RemoveAll(profileDir)
+
+	root, priv := rootManifest(t)
+	assert.Nil(t, os.MkdirAll(path.Join(profileDir, "bin"), 0755))
+	err = ioutil.WriteFile(path.Join(profileDir, "bin", "root.json"), []byte(serialize(t, root, priv)), 0644)
+	assert.Nil(t, err)
+	for _, comp := range []string{"bar", "foo"} {
+		assert.Nil(t, os.MkdirAll(path.Join(profileDir, localdata.ComponentParentDir, comp, "v1.0.0"), 0755))
+	}
+
+	profile := localdata.NewProfile(profileDir, &localdata.TiUPConfig{})
+	local, err := v1manifest.NewManifests(profile)
+	assert.Nil(t, err)
+
+	mirror := MockMirror{
+		Resources: map[string]string{},
+	}
+	index, indexPriv := indexManifest(t)
+	snapshot := snapshotManifest()
+	snapStr := serialize(t, snapshot, priv)
+	ts := timestampManifest()
+	ts.Meta[v1manifest.ManifestURLSnapshot].Hashes[v1manifest.SHA256] = hash(snapStr)
+	indexURL, _, _ := snapshot.VersionedURL(v1manifest.ManifestURLIndex)
+	mirror.Resources[indexURL] = serialize(t, index, priv)
+	mirror.Resources[v1manifest.ManifestURLSnapshot] = snapStr
+	mirror.Resources[v1manifest.ManifestURLTimestamp] = serialize(t, ts, priv)
+	mirror.Resources["/7.foo.json"] = serialize(t, componentManifest(), indexPriv)
+
+	repo := NewV1Repo(&mirror, Options{GOOS: "plat", GOARCH: "form"}, local)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, repo.UpdateComponentManifests())
+			com, err := repo.FetchComponentManifest("foo", false)
+			assert.Nil(t, err)
+			assert.NotNil(t, com)
+		}()
+		go func() {
+			defer wg.Done()
+			comps, err := profile.InstalledComponents()
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"bar", "foo"}, comps)
+		}()
+	}
+	wg.Wait()
+
+	// The manifests on disk are complete and still verify.
+	local, err = v1manifest.NewManifests(profile)
+	assert.Nil(t, err)
+	var localIndex v1manifest.Index
+	_, exists, err := local.LoadManifest(&localIndex)
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	item := localIndex.Components["foo"]
+	com, err := local.LoadComponentManifest(&item, v1manifest.ComponentManifestFilename("foo"))
+	assert.Nil(t, err)
+	assert.NotNil(t, com)
+}
+
 func timestampManifest() *v1manifest.Timestamp {
 	return &v1manifest.Timestamp{
 		SignedBase: v1manifest.SignedBase{
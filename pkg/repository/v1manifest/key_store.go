@@ -15,13 +15,18 @@ package v1manifest
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/repository/crypto"
 )
 
 // KeyStore tracks roles, keys, etc. and verifies signatures against this metadata.
-type KeyStore map[string]roleKeys
+// It's safe for concurrent use.
+type KeyStore struct {
+	mu    sync.RWMutex
+	roles map[string]roleKeys
+}
 
 type roleKeys struct {
 	threshold uint
@@ -32,23 +37,32 @@ type roleKeys struct {
 
 // NewKeyStore return a KeyStore
 func NewKeyStore() *KeyStore {
-	return &KeyStore{}
+	return &KeyStore{roles: map[string]roleKeys{}}
 }
 
 // AddKeys clears all keys for role, then adds all supplied keys and stores the threshold value.
 func (s *KeyStore) AddKeys(role string, threshold uint, expiry string, keys map[string]*KeyInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addKeys(role, threshold, expiry, keys)
+}
+
+func (s *KeyStore) addKeys(role string, threshold uint, expiry string, keys map[string]*KeyInfo) error {
 	if threshold == 0 {
 		return errors.Errorf("invalid threshold (0)")
 	}
 
-	(*s)[role] = roleKeys{threshold: threshold, expiry: expiry, keys: map[string]crypto.PubKey{}}
+	if s.roles == nil {
+		s.roles = map[string]roleKeys{}
+	}
+	s.roles[role] = roleKeys{threshold: threshold, expiry: expiry, keys: map[string]crypto.PubKey{}}
 
 	for id, info := range keys {
 		pub, err := info.publicKey()
 		if err != nil {
 			return err
 		}
-		(*s)[role].keys[id] = pub
+		s.roles[role].keys[id] = pub
 	}
 
 	return nil
@@ -80,17 +94,20 @@ func (s *SignatureError) Error() string {
 // transitionRoot checks that signed is verified by signatures using newThreshold, and if so, updates the keys for the root
 // role in the key store.
 func (s *KeyStore) transitionRoot(signed []byte, newThreshold uint, expiry string, signatures []Signature, newKeys map[string]*KeyInfo) error {
-	oldKeys := (*s)[ManifestTypeRoot]
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	err := s.AddKeys(ManifestTypeRoot, newThreshold, expiry, newKeys)
+	oldKeys := s.roles[ManifestTypeRoot]
+
+	err := s.addKeys(ManifestTypeRoot, newThreshold, expiry, newKeys)
 	if err != nil {
 		return err
 	}
 
-	err = s.verifySignature(signed, ManifestTypeRoot, signatures, ManifestFilenameRoot)
+	err = s.verify(signed, ManifestTypeRoot, signatures, ManifestFilenameRoot)
 	if err != nil {
 		// Restore the old root keys.
-		(*s)[ManifestTypeRoot] = oldKeys
+		s.roles[ManifestTypeRoot] = oldKeys
 		return err
 	}
 
@@ -105,6 +122,12 @@ func (s *KeyStore) verifySignature(signed []byte, role string, signatures []Sign
 		return nil
 	}
 
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.verify(signed, role, signatures, filename)
+}
+
+func (s *KeyStore) verify(signed []byte, role string, signatures []Signature, filename string) error {
 	// Check for duplicate signatures.
 	has := make(map[string]struct{})
 	for _, sig := range signatures {
@@ -114,7 +137,7 @@ func (s *KeyStore) verifySignature(signed []byte, role string, signatures []Sign
 		has[sig.KeyID] = struct{}{}
 	}
 
-	keys, ok := (*s)[role]
+	keys, ok := s.roles[role]
 	if !ok {
 		return errors.Errorf("Unknown role %s", role)
 	}
@@ -140,7 +163,7 @@ func (s *KeyStore) verifySignature(signed []byte, role string, signatures []Sign
 		}
 	}
 	if validSigs < keys.threshold {
-		return errors.Errorf("not enough signatures (%v) for threshold %v in %s", validSigs, keys.threshold, filename)
+		return newSignatureError(filename, errors.Errorf("not enough signatures (%v) for threshold %v in %s", validSigs, keys.threshold, filename))
 	}
 
 	return nil
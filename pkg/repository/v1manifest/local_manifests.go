@@ -21,6 +21,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	cjson "github.com/gibson042/canonicaljson-go"
 	"github.com/pingcap/errors"
@@ -43,20 +44,29 @@ type LocalManifests interface {
 	ComponentInstalled(component, version string) (bool, error)
 	// InstallComponent installs the component from the reader.
 	InstallComponent(reader io.Reader, targetDir, component, version, filename string, noExpand bool) error
+	// SaveInstallReceipt records when and where the installed version of component is installed from.
+	SaveInstallReceipt(receipt *localdata.InstallReceipt) error
 	// Return the local key store.
 	KeyStore() *KeyStore
 	// ManifestVersion opens filename, if it exists and is a manifest, returns its manifest version number. Otherwise
 	// returns 0.
 	ManifestVersion(filename string) uint
+	// LoadRawManifest returns the bytes of the manifest at filename as they are stored, without validating them. The
+	// returned bytes are nil if the file does not exist.
+	LoadRawManifest(filename string) ([]byte, error)
 }
 
 // FsManifests represents a collection of v1 manifests on disk.
 // Invariant: any manifest written to disk should be valid, but may have expired. (It is also possible the manifest was
 // ok when written and has expired since).
+// FsManifests is safe for concurrent use, manifests are replaced atomically on disk.
 type FsManifests struct {
 	profile *localdata.Profile
 	keys    *KeyStore
-	cache   map[string]string
+
+	// mu protects the cache
+	mu    sync.RWMutex
+	cache map[string]string
 }
 
 // FIXME implement garbage collection of old manifests
@@ -91,7 +101,7 @@ func NewManifests(profile *localdata.Profile) (*FsManifests, error) {
 		return nil, errors.AddStack(err)
 	}
 
-	result.cache[ManifestFilenameRoot] = manifest
+	result.setCache(ManifestFilenameRoot, manifest)
 
 	return result, nil
 }
@@ -117,22 +127,21 @@ func (ms *FsManifests) save(manifest *Manifest, filename string) error {
 	}
 
 	// Save all manifests in `$TIUP_HOME/manifests`
-	path := filepath.Join(ms.profile.Root(), localdata.ManifestParentDir, filename)
-
-	// create sub directory if needed
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return errors.Trace(err)
-	}
-
-	err = ioutil.WriteFile(path, bytes, 0644)
+	err = ms.profile.SaveTo(filepath.Join(localdata.ManifestParentDir, filename), bytes, 0644)
 	if err != nil {
 		return err
 	}
 
-	ms.cache[filename] = string(bytes)
+	ms.setCache(filename, string(bytes))
 	return nil
 }
 
+func (ms *FsManifests) setCache(filename, manifest string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.cache[filename] = manifest
+}
+
 // LoadManifest implements LocalManifests.
 func (ms *FsManifests) LoadManifest(role ValidManifest) (*Manifest, bool, error) {
 	filename := role.Filename()
@@ -154,7 +163,7 @@ func (ms *FsManifests) LoadManifest(role ValidManifest) (*Manifest, bool, error)
 		return m, true, err
 	}
 
-	ms.cache[filename] = manifest
+	ms.setCache(filename, manifest)
 	return m, true, loadKeys(role, ms.keys)
 }
 
@@ -179,14 +188,16 @@ func (ms *FsManifests) LoadComponentManifest(item *ComponentItem, filename strin
 		return nil, err
 	}
 
-	ms.cache[filename] = manifest
+	ms.setCache(filename, manifest)
 	return component, nil
 }
 
 // load return the file for the manifest from disk.
 // The returned string is empty if the file does not exist.
 func (ms *FsManifests) load(filename string) (string, error) {
+	ms.mu.RLock()
 	str, cached := ms.cache[filename]
+	ms.mu.RUnlock()
 	if cached {
 		return str, nil
 	}
@@ -222,6 +233,15 @@ func (ms *FsManifests) load(filename string) (string, error) {
 	return builder.String(), nil
 }
 
+// LoadRawManifest implements LocalManifests.
+func (ms *FsManifests) LoadRawManifest(filename string) ([]byte, error) {
+	manifest, err := ms.load(filename)
+	if err != nil || manifest == "" {
+		return nil, err
+	}
+	return []byte(manifest), nil
+}
+
 // ComponentInstalled implements LocalManifests.
 func (ms *FsManifests) ComponentInstalled(component, version string) (bool, error) {
 	return ms.profile.VersionIsInstalled(component, version)
@@ -253,6 +273,11 @@ func (ms *FsManifests) InstallComponent(reader io.Reader, targetDir, component,
 	return nil
 }
 
+// SaveInstallReceipt implements LocalManifests.
+func (ms *FsManifests) SaveInstallReceipt(receipt *localdata.InstallReceipt) error {
+	return ms.profile.SaveInstallReceipt(receipt)
+}
+
 // KeyStore implements LocalManifests.
 func (ms *FsManifests) KeyStore() *KeyStore {
 	return ms.keys
@@ -285,6 +310,7 @@ type MockManifests struct {
 	Manifests map[string]*Manifest
 	Saved     []string
 	Installed map[string]MockInstalled
+	Receipts  map[string]*localdata.InstallReceipt // component -> receipt
 	Ks        *KeyStore
 }
 
@@ -300,6 +326,7 @@ func NewMockManifests() *MockManifests {
 		Manifests: map[string]*Manifest{},
 		Saved:     []string{},
 		Installed: map[string]MockInstalled{},
+		Receipts:  map[string]*localdata.InstallReceipt{},
 		Ks:        NewKeyStore(),
 	}
 }
@@ -362,6 +389,15 @@ func (ms *MockManifests) LoadComponentManifest(item *ComponentItem, filename str
 	return comp, nil
 }
 
+// LoadRawManifest implements LocalManifests.
+func (ms *MockManifests) LoadRawManifest(filename string) ([]byte, error) {
+	manifest, ok := ms.Manifests[filename]
+	if !ok {
+		return nil, nil
+	}
+	return cjson.Marshal(manifest)
+}
+
 // ComponentInstalled implements LocalManifests.
 func (ms *MockManifests) ComponentInstalled(component, version string) (bool, error) {
 	inst, ok := ms.Installed[component]
@@ -386,6 +422,12 @@ func (ms *MockManifests) InstallComponent(reader io.Reader, targetDir string, co
 	return nil
 }
 
+// SaveInstallReceipt implements LocalManifests.
+func (ms *MockManifests) SaveInstallReceipt(receipt *localdata.InstallReceipt) error {
+	ms.Receipts[receipt.Component] = receipt
+	return nil
+}
+
 // KeyStore implements LocalManifests.
 func (ms *MockManifests) KeyStore() *KeyStore {
 	return ms.Ks
@@ -105,6 +105,11 @@ type VersionItem struct {
 	Entry        string   `json:"entry"`
 	Released     string   `json:"released"`
 	Dependencies []string `json:"dependencies"`
+	// the URL or summary of the release notes, optional
+	ReleaseNotes string `json:"release_notes,omitempty"`
+	// the executable files in the package relative to the directory it's
+	// installed to, i.e. the entry and the other binaries it ships, optional
+	Binaries []string `json:"binaries,omitempty"`
 
 	FileHash
 }
@@ -266,3 +271,19 @@ func (manifest *Component) VersionListWithYanked(platform string) map[string]Ver
 
 	return vs
 }
+
+// EntryPoints returns the binaries the version can be invoked by, the entry
+// is the first. Only the entry is known for the versions published to the
+// mirrors before the binaries are listed in the manifests.
+func (v *VersionItem) EntryPoints() []string {
+	var points []string
+	if v.Entry != "" {
+		points = append(points, v.Entry)
+	}
+	for _, bin := range v.Binaries {
+		if bin != v.Entry {
+			points = append(points, bin)
+		}
+	}
+	return points
+}
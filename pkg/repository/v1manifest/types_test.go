@@ -14,6 +14,7 @@
 package v1manifest
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/alecthomas/assert"
@@ -83,3 +84,13 @@ func TestVersionList(t *testing.T) {
 	versions = manifest.VersionList("windows/amd64")
 	assert.Equal(t, len(versions), 0)
 }
+
+func TestEntryPoints(t *testing.T) {
+	// the manifests published before the binaries are listed
+	var item VersionItem
+	assert.NoError(t, json.Unmarshal([]byte(`{"url":"/tidb-v4.0.0-linux-amd64.tar.gz","entry":"tidb-server"}`), &item))
+	assert.Equal(t, []string{"tidb-server"}, item.EntryPoints())
+
+	item.Binaries = []string{"bin/tidb-lightning", "tidb-server"}
+	assert.Equal(t, []string{"tidb-server", "bin/tidb-lightning"}, item.EntryPoints())
+}
@@ -0,0 +1,74 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/version"
+)
+
+// VersionConstraint is a set of conditions, a version satisfies it if all of
+// the conditions are met, the nightly versions never do
+type VersionConstraint = version.Constraint
+
+// ParseVersionConstraint parses a non-empty constraint like ">=v4.0.0, <v5.0.0"
+// or "v4.0", see version.ParseConstraint for the syntax
+func ParseVersionConstraint(s string) (VersionConstraint, error) {
+	if strings.Trim(s, ", ") == "" {
+		return nil, errors.Errorf("empty version constraint '%s'", s)
+	}
+	c, err := version.ParseConstraint(s)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	return c, nil
+}
+
+// constrainedVersions adds the versions of the component on the platforms
+// satisfying any of the constraints to vs
+func constrainedVersions(vs set.StringSet, manifest *v1manifest.Component, oss, archs []string, constraints []VersionConstraint) set.StringSet {
+	if len(constraints) == 0 {
+		return vs
+	}
+	if vs == nil {
+		vs = set.NewStringSet()
+	}
+	for _, goos := range oss {
+		for _, goarch := range archs {
+			for v := range manifest.VersionList(PlatformString(goos, goarch)) {
+				for _, c := range constraints {
+					if c.Check(v) {
+						vs.Insert(v)
+						break
+					}
+				}
+			}
+		}
+	}
+	return vs
+}
+
+// skipNightly removes the nightly versions from vs
+func skipNightly(vs set.StringSet) set.StringSet {
+	for v := range vs {
+		if version.IsNightly(v) {
+			delete(vs, v)
+		}
+	}
+	return vs
+}
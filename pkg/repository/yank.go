@@ -0,0 +1,113 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/repository/v1manifest"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// YankOptions represents the options of yanking a version of a component
+type YankOptions struct {
+	// show the diff of the component manifest only, the mirror is not modified
+	DryRun bool
+	// the directory of the private keys of the index, snapshot and timestamp
+	// manifests, it's the keys directory of the mirror if empty
+	KeyDir string
+}
+
+// Yank marks the version of a component in the local mirror in dir as yanked
+// on all the platforms it's published for, or reverses it if yanked is false.
+// The component manifest is signed with the key of its owner, and the snapshot
+// and timestamp are bumped as Publish does. The diff of the component manifest
+// is returned, it's empty if the version is already as expected.
+func Yank(dir, id, version string, yanked bool, ownerKey *v1manifest.KeyInfo, opt YankOptions) (string, error) {
+	unlock, err := lockMirror(dir)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	m, err := openLocalMirror(dir, opt.KeyDir)
+	if err != nil {
+		return "", err
+	}
+	owner, err := keyOwner(&m.index, ownerKey)
+	if err != nil {
+		return "", err
+	}
+	item, ok := m.index.Components[id]
+	if !ok {
+		return "", errors.Annotatef(errUnknownComponent, "%s", id)
+	}
+	if item.Owner != owner {
+		return "", errors.Annotatef(ErrNotOwner, "component '%s' is owned by '%s'", id, item.Owner)
+	}
+
+	comp, err := m.component(id)
+	if err != nil {
+		return "", err
+	}
+	origin, err := json.MarshalIndent(comp, "", "  ")
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+
+	found, changed := false, false
+	for plat, versions := range comp.Platforms {
+		vi, ok := versions[version]
+		if !ok {
+			continue
+		}
+		found = true
+		if vi.Yanked != yanked {
+			vi.Yanked = yanked
+			comp.Platforms[plat][version] = vi
+			changed = true
+		}
+	}
+	if !found {
+		return "", errors.Errorf("version %s of %s is not found", version, id)
+	}
+	if !changed {
+		return "", nil
+	}
+
+	initTime := time.Now().UTC()
+	comp.Version++
+	renewManifest(comp, initTime)
+	modified, err := json.MarshalIndent(comp, "", "  ")
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	var diff bytes.Buffer
+	utils.ShowDiff(string(origin), string(modified), &diff)
+	if opt.DryRun {
+		return diff.String(), nil
+	}
+
+	signed, err := v1manifest.SignManifest(comp, ownerKey)
+	if err != nil {
+		return "", err
+	}
+	if err := m.commit(initTime, map[string]*v1manifest.Manifest{id: signed}, nil); err != nil {
+		return "", err
+	}
+	return diff.String(), nil
+}
@@ -0,0 +1,428 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package server implements an embedded HTTP server exposing the status of
+// the operations run by the process, e.g., for dashboards and automation
+// watching a long running operation without parsing the terminal output.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster"
+	"github.com/pingcap/tiup/pkg/cluster/audit"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	"github.com/pingcap/tiup/pkg/errutil"
+	"go.uber.org/zap"
+)
+
+// EnvNameStatusToken is the environment variable of the auth token of the status server
+const EnvNameStatusToken = "TIUP_STATUS_TOKEN"
+
+// the buffer size of the event channel of each stream
+const eventBufferSize = 256
+
+// Server is an HTTP server of the status of operations, which only changes
+// anything by resuming the operations paused at breakpoints. All the
+// endpoints require the token if it's set, either as a bearer token in the
+// Authorization header or as the token query parameter. Without a token the
+// server only listens on the loopback addresses.
+type Server struct {
+	manager *cluster.Manager
+	addr    string
+	token   string
+
+	mu       sync.Mutex
+	listener net.Listener
+	httpSrv  *http.Server
+	done     chan struct{}
+}
+
+// New returns a status server listening on addr, it's not started until
+// Start is called
+func New(manager *cluster.Manager, addr, token string) *Server {
+	return &Server{
+		manager: manager,
+		addr:    addr,
+		token:   token,
+	}
+}
+
+// Start starts listening and serving in background
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		return errors.New("status server already started")
+	}
+	if s.token == "" && !isLoopback(s.addr) {
+		return errors.Errorf("the status server requires a token to listen on %s, which is not a loopback address, set it by --status-token or %s",
+			s.addr, EnvNameStatusToken)
+	}
+
+	l, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return errors.Annotatef(err, "failed to listen on %s", s.addr)
+	}
+	s.listener = l
+	s.done = make(chan struct{})
+	s.httpSrv = &http.Server{Handler: s.router()}
+
+	go func(srv *http.Server) {
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			zap.L().Warn("Status server stopped", zap.Error(err))
+		}
+	}(s.httpSrv)
+	zap.L().Info("Status server started", zap.String("addr", l.Addr().String()))
+	return nil
+}
+
+// isLoopback checks if the host of addr is a loopback one, the empty host
+// listening on all the addresses is not
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Addr returns the address listened on, it's useful if the port is 0
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return s.addr
+	}
+	return s.listener.Addr().String()
+}
+
+// Stop closes the event streams and shuts the server down, waiting at most
+// timeout for the pending requests
+func (s *Server) Stop(timeout time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+
+	close(s.done)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := s.httpSrv.Shutdown(ctx)
+	s.listener = nil
+	s.httpSrv = nil
+	return errors.AddStack(err)
+}
+
+func (s *Server) router() http.Handler {
+	r := mux.NewRouter()
+
+	r.HandleFunc("/operations", s.listOperations).Methods(http.MethodGet)
+	r.HandleFunc("/operations/{cluster}/progress", s.operationProgress).Methods(http.MethodGet)
+	r.HandleFunc("/operations/{cluster}/resume", s.resumeOperation).Methods(http.MethodPost)
+	r.HandleFunc("/operations/{cluster}/follow", s.followHost).Methods(http.MethodGet)
+	r.HandleFunc("/clusters", s.listClusters).Methods(http.MethodGet)
+	r.HandleFunc("/clusters/{cluster}", s.clusterSummary).Methods(http.MethodGet)
+	r.HandleFunc("/events", s.streamEvents).Methods(http.MethodGet)
+	r.HandleFunc("/messages", s.listMessages).Methods(http.MethodGet)
+	r.HandleFunc("/topology/schema", s.topologySchema).Methods(http.MethodGet)
+	r.HandleFunc("/logs/usage", s.logUsage).Methods(http.MethodGet)
+
+	return s.auth(r)
+}
+
+// auth rejects the requests without the token if it's set
+func (s *Server) auth(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			token := r.URL.Query().Get("token")
+			if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+				token = strings.TrimPrefix(auth, "Bearer ")
+			}
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				writeError(w, http.StatusUnauthorized, "invalid or missing token")
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) listOperations(w http.ResponseWriter, r *http.Request) {
+	progresses := []cluster.OperationProgress{}
+	for _, info := range cluster.ListOperations() {
+		progresses = append(progresses, info.ComputeProgress())
+	}
+	writeJSON(w, http.StatusOK, progresses)
+}
+
+func (s *Server) operationProgress(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["cluster"]
+	info := cluster.GetCurrentOperation(name)
+	if info == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no operation of cluster %s", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, info.ComputeProgress())
+}
+
+// resumeOperation resumes the operation of the cluster paused at a breakpoint.
+// The requests from web pages are refused, the server without a token only
+// listens on loopback where any page opened locally could post to it.
+func (s *Server) resumeOperation(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Origin") != "" {
+		writeError(w, http.StatusForbidden, "resuming operations from web pages is not allowed")
+		return
+	}
+	name := mux.Vars(r)["cluster"]
+	info := cluster.GetCurrentOperation(name)
+	if info == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no operation of cluster %s", name))
+		return
+	}
+	where, paused := info.Paused()
+	if !paused || !info.Resume() {
+		writeError(w, http.StatusConflict, fmt.Sprintf("the operation of cluster %s is not paused", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"resumed": where})
+}
+
+// listClusters lists the summaries of the clusters, which may be filtered by
+// the tag query parameters in the form of key=value or key
+func (s *Server) listClusters(w http.ResponseWriter, r *http.Request) {
+	filters, err := cluster.ParseTagFilters(r.URL.Query()["tag"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	summaries, err := s.manager.ClusterSummaries(filters...)
+	if err != nil {
+		writeTypedError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// clusterSummary returns the summary of the cluster, the cluster not existing
+// and the corrupt metadata are told apart by the status codes, see errorStatus
+func (s *Server) clusterSummary(w http.ResponseWriter, r *http.Request) {
+	summary, err := s.manager.ClusterSummary(mux.Vars(r)["cluster"])
+	if err != nil {
+		writeTypedError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// listMessages lists the English templates of the identified messages of
+// steps and tasks, which the events carry with their parameters
+func (s *Server) listMessages(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, task.MessageDefinitions())
+}
+
+// topologySchema returns the schema of the topology file for completion
+func (s *Server) topologySchema(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, spec.TopologySchema())
+}
+
+// logUsage reports the disk usage of the audit logs and the operation logs
+// under the profile against their budget
+func (s *Server) logUsage(w http.ResponseWriter, r *http.Request) {
+	usage, err := audit.Usage(spec.ProfileDir())
+	if err != nil {
+		writeTypedError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// streamEvents streams the operation events as server-sent events, the
+// events may be filtered by the cluster query parameter
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	name := r.URL.Query().Get("cluster")
+
+	events, unsubscribe := cluster.SubscribeOperationEvents(eventBufferSize)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case e := <-events:
+			if name != "" && e.Cluster != name {
+				continue
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				zap.L().Warn("Failed to marshal operation event", zap.Error(err))
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Kind, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// followHost streams the commands executed on the host of the host query
+// parameter by the running operation of the cluster and their outputs as
+// server-sent events, until the operation finishes. The secrets found are
+// masked, but the outputs may still leak the hosts, so it requires the token
+// even if the server is only listening on loopback.
+func (s *Server) followHost(w http.ResponseWriter, r *http.Request) {
+	if s.token == "" {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("following hosts requires the token of the status server, set it by --status-token or %s", EnvNameStatusToken))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	name := mux.Vars(r)["cluster"]
+	host := r.URL.Query().Get("host")
+	if host == "" {
+		writeError(w, http.StatusBadRequest, "the host to follow is required")
+		return
+	}
+	info := cluster.GetCurrentOperation(name)
+	if info == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no operation of cluster %s", name))
+		return
+	}
+
+	out := &sseWriter{w: w, flusher: flusher, event: "output"}
+	done, stop, err := info.FollowHost(host, out)
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	select {
+	case <-r.Context().Done():
+		return
+	case <-s.done:
+		return
+	case <-done:
+	}
+	// nothing is written by the operation after done is closed
+	data, _ := json.Marshal(map[string]string{"cluster": name, "host": host})
+	_, _ = fmt.Fprintf(w, "event: end\ndata: %s\n\n", data)
+	flusher.Flush()
+}
+
+// sseWriter writes each Write as a server-sent event, a line of data each
+// line, it's not thread-safe
+type sseWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+	event   string
+}
+
+// Write implements io.Writer
+func (sw *sseWriter) Write(p []byte) (int, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\n", sw.event)
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	if _, err := io.WriteString(sw.w, b.String()); err != nil {
+		return 0, err
+	}
+	sw.flusher.Flush()
+	return len(p), nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		zap.L().Warn("Failed to write response", zap.Error(err))
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
+
+// errorStatus maps the typed errors to the HTTP status codes, the untyped
+// ones are internal errors
+func errorStatus(errx *errorx.Error) int {
+	switch {
+	case errx.IsOfType(spec.ErrClusterNotExist):
+		return http.StatusNotFound
+	case errx.IsOfType(spec.ErrMetaCorrupt):
+		// the request is fine, but the metadata can't be processed
+		return http.StatusUnprocessableEntity
+	case errx.IsOfType(cluster.ErrClusterBusy):
+		return http.StatusConflict
+	case errx.HasTrait(errutil.ErrTraitPreCheck):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// writeTypedError writes err with the status code of its type, and the type
+// in the body for the clients to tell the errors apart
+func writeTypedError(w http.ResponseWriter, err error) {
+	errx := errutil.Cast(err)
+	if errx == nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, errorStatus(errx), map[string]string{
+		"error": err.Error(),
+		"type":  errx.Type().FullName(),
+	})
+}
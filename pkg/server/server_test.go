@@ -0,0 +1,184 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/cluster"
+	"github.com/pingcap/tiup/pkg/cluster/audit"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiup-status-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	specManager := spec.NewSpec(dir, func() spec.Metadata {
+		return &spec.ClusterMeta{Topology: new(spec.Specification)}
+	})
+	require.Nil(t, os.MkdirAll(specManager.Path("test"), 0755))
+	require.Nil(t, ioutil.WriteFile(specManager.Path("test", "meta.yaml"),
+		[]byte("user: tidb\ntidb_version: v4.0.0\n"), 0644))
+
+	s := New(cluster.NewManager("tidb", specManager, spec.TiDBComponentVersion), "127.0.0.1:0", "secret")
+	require.Nil(t, s.Start())
+	defer s.Stop(time.Second)
+	base := "http://" + s.Addr()
+
+	get := func(path, token string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, base+path, nil)
+		require.Nil(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		return resp
+	}
+
+	resp := get("/clusters", "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = get("/clusters", "wrong")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = get("/clusters", "secret")
+	var summaries []cluster.ClusterSummary
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&summaries))
+	resp.Body.Close()
+	require.Len(t, summaries, 1)
+	assert.Equal(t, "test", summaries[0].Name)
+	assert.Equal(t, "tidb", summaries[0].User)
+	assert.Equal(t, "v4.0.0", summaries[0].Version)
+	assert.Nil(t, summaries[0].LastOperation)
+
+	// the typed errors are mapped to the status codes
+	resp = get("/clusters/test", "secret")
+	var summary cluster.ClusterSummary
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&summary))
+	resp.Body.Close()
+	assert.Equal(t, "tidb", summary.User)
+	require.Nil(t, os.MkdirAll(specManager.Path("broken"), 0755))
+	require.Nil(t, ioutil.WriteFile(specManager.Path("broken", "meta.yaml"), []byte("user: [tidb\n"), 0644))
+	for name, code := range map[string]int{
+		"tset":   http.StatusNotFound,
+		"broken": http.StatusUnprocessableEntity,
+	} {
+		resp = get("/clusters/"+name, "secret")
+		var body map[string]string
+		require.Nil(t, json.NewDecoder(resp.Body).Decode(&body))
+		resp.Body.Close()
+		assert.Equal(t, code, resp.StatusCode, name)
+		assert.NotEmpty(t, body["type"], name)
+	}
+
+	resp = get("/operations", "secret")
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Nil(t, err)
+	assert.Equal(t, "[]", strings.TrimSpace(string(body)))
+
+	resp = get("/operations/test/progress", "secret")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = get("/logs/usage", "secret")
+	var usage audit.LogUsage
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&usage))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, audit.LogBudget(), usage.Budget)
+
+	resp = get("/operations/test/follow?host=127.0.0.1", "secret")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = get("/operations/test/follow", "secret")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodPost, base+"/operations/test/resume?token=secret", nil)
+	require.Nil(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// the token is also accepted as a query parameter, and the stream is
+	// closed when the server stops
+	resp = get("/events?token=secret", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	go s.Stop(time.Second)
+	_, err = bufio.NewReader(resp.Body).ReadString('\n')
+	assert.NotNil(t, err)
+	resp.Body.Close()
+}
+
+func TestStatusServerLoopback(t *testing.T) {
+	for addr, loopback := range map[string]bool{
+		"127.0.0.1:9180": true,
+		"localhost:9180": true,
+		"[::1]:9180":     true,
+		":9180":          false,
+		"0.0.0.0:9180":   false,
+		"10.0.1.1:9180":  false,
+		"9180":           false,
+	} {
+		assert.Equal(t, loopback, isLoopback(addr), addr)
+	}
+
+	// a token is required unless only the local users can connect
+	s := New(nil, "0.0.0.0:0", "")
+	err := s.Start()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), EnvNameStatusToken)
+	s = New(nil, "0.0.0.0:0", "secret")
+	require.Nil(t, s.Start())
+	assert.Nil(t, s.Stop(time.Second))
+	s = New(nil, "127.0.0.1:0", "")
+	require.Nil(t, s.Start())
+	defer s.Stop(time.Second)
+
+	// the pages opened in browsers can't resume the operations
+	req, err := http.NewRequest(http.MethodPost, "http://"+s.Addr()+"/operations/test/resume", nil)
+	require.Nil(t, err)
+	req.Header.Set("Origin", "http://evil.example.com")
+	resp, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	req.Header.Del("Origin")
+	resp, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// nor follow the hosts without the token
+	resp, err = http.Get("http://" + s.Addr() + "/operations/test/follow?host=127.0.0.1")
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
@@ -0,0 +1,133 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tui
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// ColorMode controls whether color escapes are written to the terminal
+type ColorMode int
+
+// the modes of color output
+const (
+	// ColorAuto enables colors only if stdout is a terminal
+	ColorAuto ColorMode = iota
+	// ColorAlways enables colors even if stdout is not a terminal
+	ColorAlways
+	// ColorNever disables colors
+	ColorNever
+)
+
+// the environment variables controlling the color output, see https://no-color.org
+const (
+	EnvNameNoColor    = "NO_COLOR"
+	EnvNameForceColor = "FORCE_COLOR"
+)
+
+var colorModeNames = [...]string{"auto", "always", "never"}
+
+// String implements the fmt.Stringer interface
+func (m ColorMode) String() string {
+	if m < 0 || int(m) >= len(colorModeNames) {
+		return fmt.Sprintf("unknown-color-mode(%d)", int(m))
+	}
+	return colorModeNames[m]
+}
+
+// ParseColorMode parses the name of a color mode, i.e., auto, always or never
+func ParseColorMode(name string) (ColorMode, error) {
+	for i, n := range colorModeNames {
+		if n == strings.ToLower(name) {
+			return ColorMode(i), nil
+		}
+	}
+	return ColorAuto, fmt.Errorf("unknown color mode '%s', should be one of auto, always and never", name)
+}
+
+// ansiEscapeRegexp matches the SGR escape sequences of colors and styles
+var ansiEscapeRegexp = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// colorMode is the mode set explicitly, the environment is checked if it's auto
+var colorMode = ColorAuto
+
+func init() {
+	SetColorMode(ColorAuto)
+}
+
+// colorModeFromEnv returns the mode requested by the environment variables,
+// FORCE_COLOR takes precedence over NO_COLOR, as it's an explicit override
+func colorModeFromEnv() ColorMode {
+	if v, ok := os.LookupEnv(EnvNameForceColor); ok {
+		switch strings.ToLower(v) {
+		case "0", "false", "no":
+			return ColorNever
+		default:
+			return ColorAlways
+		}
+	}
+	// any value of NO_COLOR disables colors, even an empty one
+	if _, ok := os.LookupEnv(EnvNameNoColor); ok {
+		return ColorNever
+	}
+	return ColorAuto
+}
+
+// isTerminal checks if stdout is a terminal supporting colors
+func isTerminal() bool {
+	return os.Getenv("TERM") != "dumb" && terminal.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// SetColorMode sets the mode of color output. In the auto mode NO_COLOR and
+// FORCE_COLOR are honored, and colors are enabled only if stdout is a terminal.
+// All colors written by the color package follow the mode.
+func SetColorMode(mode ColorMode) {
+	colorMode = mode
+	if mode == ColorAuto {
+		mode = colorModeFromEnv()
+	}
+	switch mode {
+	case ColorAlways:
+		color.NoColor = false
+	case ColorNever:
+		color.NoColor = true
+	default:
+		color.NoColor = !isTerminal()
+	}
+}
+
+// GetColorMode returns the mode set by SetColorMode
+func GetColorMode() ColorMode {
+	return colorMode
+}
+
+// ColorEnabled checks if color escapes are written in the current mode
+func ColorEnabled() bool {
+	return !color.NoColor
+}
+
+// StripColor removes the color escapes from s, it's used for outputs meant to
+// be parsed, e.g., JSON and CSV, which must never contain escapes
+func StripColor(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return ansiEscapeRegexp.ReplaceAllString(s, "")
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tui
+
+import (
+	"os"
+	"testing"
+
+	"github.com/fatih/color"
+	. "github.com/pingcap/check"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&colorSuite{})
+
+type colorSuite struct{}
+
+func (s *colorSuite) SetUpTest(c *C) {
+	os.Unsetenv(EnvNameNoColor)
+	os.Unsetenv(EnvNameForceColor)
+}
+
+func (s *colorSuite) TearDownTest(c *C) {
+	s.SetUpTest(c)
+	SetColorMode(ColorAuto)
+}
+
+func (s *colorSuite) TestParseColorMode(c *C) {
+	for _, m := range []ColorMode{ColorAuto, ColorAlways, ColorNever} {
+		parsed, err := ParseColorMode(m.String())
+		c.Assert(err, IsNil)
+		c.Assert(parsed, Equals, m)
+	}
+	_, err := ParseColorMode("sometimes")
+	c.Assert(err, NotNil)
+}
+
+func (s *colorSuite) TestColorMode(c *C) {
+	SetColorMode(ColorAlways)
+	c.Assert(ColorEnabled(), IsTrue)
+	c.Assert(color.RedString("x"), Not(Equals), "x")
+
+	SetColorMode(ColorNever)
+	c.Assert(ColorEnabled(), IsFalse)
+	c.Assert(color.RedString("x"), Equals, "x")
+
+	// stdout of tests is not a terminal
+	SetColorMode(ColorAuto)
+	c.Assert(ColorEnabled(), IsFalse)
+
+	os.Setenv(EnvNameForceColor, "1")
+	SetColorMode(ColorAuto)
+	c.Assert(ColorEnabled(), IsTrue)
+
+	// FORCE_COLOR overrides NO_COLOR, but not an explicit mode
+	os.Setenv(EnvNameNoColor, "")
+	SetColorMode(ColorAuto)
+	c.Assert(ColorEnabled(), IsTrue)
+	SetColorMode(ColorNever)
+	c.Assert(ColorEnabled(), IsFalse)
+
+	os.Unsetenv(EnvNameForceColor)
+	SetColorMode(ColorAuto)
+	c.Assert(ColorEnabled(), IsFalse)
+	c.Assert(GetColorMode(), Equals, ColorAuto)
+}
+
+func (s *colorSuite) TestStripColor(c *C) {
+	SetColorMode(ColorAlways)
+	c.Assert(StripColor(color.New(color.FgHiBlue, color.Bold).Sprint("Pass")+" ok"), Equals, "Pass ok")
+	c.Assert(StripColor("no escapes"), Equals, "no escapes")
+}
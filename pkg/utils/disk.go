@@ -0,0 +1,70 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// existingParent returns the path itself or its nearest existing parent
+func existingParent(path string) string {
+	path = filepath.Clean(path)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// FreeSpace returns the bytes available to unprivileged users on the file
+// system of the path, the path doesn't need to exist yet
+func FreeSpace(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(existingParent(path), &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
+
+// Writable checks if files can be created under the path by the current user,
+// the path doesn't need to exist yet. The error tells why it's not writable,
+// e.g., the file system is mounted read-only.
+func Writable(path string) error {
+	return unix.Access(existingParent(path), unix.W_OK)
+}
+
+// SameFileSystem checks if the paths are on the same file system, the paths
+// don't need to exist yet
+func SameFileSystem(a, b string) (bool, error) {
+	sa, err := os.Stat(existingParent(a))
+	if err != nil {
+		return false, err
+	}
+	sb, err := os.Stat(existingParent(b))
+	if err != nil {
+		return false, err
+	}
+	da, oka := sa.Sys().(*syscall.Stat_t)
+	db, okb := sb.Sys().(*syscall.Stat_t)
+	return oka && okb && da.Dev == db.Dev, nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&TestDiskSuite{})
+
+type TestDiskSuite struct{}
+
+func (s *TestDiskSuite) TestFreeSpace(c *C) {
+	dir, err := ioutil.TempDir("", "tiup-disk-test")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	free, err := FreeSpace(dir)
+	c.Assert(err, IsNil)
+	c.Assert(free > 0, IsTrue)
+
+	// the nearest existing parent is used for paths not created yet
+	missing := filepath.Join(dir, "not", "created")
+	freeMissing, err := FreeSpace(missing)
+	c.Assert(err, IsNil)
+	c.Assert(freeMissing > 0, IsTrue)
+
+	same, err := SameFileSystem(dir, missing)
+	c.Assert(err, IsNil)
+	c.Assert(same, IsTrue)
+}
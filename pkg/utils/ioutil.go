@@ -24,6 +24,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 
 	"github.com/otiai10/copy"
 	"github.com/pingcap/errors"
@@ -111,6 +112,33 @@ func Untar(reader io.Reader, to string) error {
 	return nil
 }
 
+// TarBinaries returns the executable files in the gzipped tarball, the paths
+// are relative to the directory it's decompressed to
+func TarBinaries(reader io.Reader) ([]string, error) {
+	gr, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer gr.Close()
+
+	var binaries []string
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if mode := hdr.FileInfo().Mode(); mode.IsRegular() && mode&0111 != 0 {
+			binaries = append(binaries, path.Clean(hdr.Name))
+		}
+	}
+	sort.Strings(binaries)
+	return binaries, nil
+}
+
 // Copy copies a file or directory from src to dst
 func Copy(src, dst string) error {
 	// check if src is a directory
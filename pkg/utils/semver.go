@@ -14,21 +14,21 @@
 package utils
 
 import (
-	"fmt"
 	"strings"
 
-	"golang.org/x/mod/semver"
+	"github.com/pingcap/tiup/pkg/version"
 )
 
 // FmtVer converts a version string to SemVer format, if the string is not a valid
-// SemVer and fails to parse and convert it, an error is raised.
+// SemVer and fails to parse and convert it, an error is raised. The version is
+// normalized by version.Parse, e.g., "6.5" is converted to v6.5.0.
 func FmtVer(ver string) (string, error) {
-	v := ver
-	if !strings.HasPrefix(ver, "v") {
-		v = fmt.Sprintf("v%s", ver)
+	v, err := version.Parse(ver)
+	if err != nil {
+		if !strings.HasPrefix(ver, "v") {
+			ver = "v" + ver
+		}
+		return ver, err
 	}
-	if !semver.IsValid(v) {
-		return v, fmt.Errorf("version %s is not a valid SemVer string", ver)
-	}
-	return v, nil
+	return v.String(), nil
 }
@@ -12,6 +12,7 @@ func (s *TestSemverSuite) TestSemverc(c *C) {
 	cases := [][]interface{}{
 		{"v0.0.1", "v0.0.1", true},
 		{"0.0.1", "v0.0.1", true},
+		{"6.5", "v6.5.0", true},
 		{"invalid", "vinvalid", false},
 		{"", "v", false},
 	}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"os/exec"
 	"testing"
 
 	. "github.com/pingcap/check"
@@ -9,3 +10,15 @@ import (
 func TestUtils(t *testing.T) {
 	TestingT(t)
 }
+
+type utilsSuite struct{}
+
+var _ = Suite(&utilsSuite{})
+
+func (s *utilsSuite) TestShellQuote(c *C) {
+	for _, word := range []string{"", "/data/tikv", "/data dir/it's $(id) `id` \\ \"x\"", "a\nb"} {
+		out, err := exec.Command("sh", "-c", "printf %s "+ShellQuote(word)).Output()
+		c.Assert(err, IsNil)
+		c.Assert(string(out), Equals, word)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Version is a version of a component normalized by Parse, it's either
+// NightlyVersion, or a SemVer string with the "v" prefix and all the three
+// parts of the release, e.g., v4.0.0, v5.0.0-rc+build.
+type Version string
+
+// Parse parses the version of a component, the "v" prefix and the minor and
+// patch parts are optional, e.g., "6.5" is parsed as v6.5.0. NightlyVersion
+// and the versions of daily builds, e.g., v5.0.0-nightly-20201231, are valid.
+// The build metadata is kept but ignored in comparisons.
+func Parse(ver string) (Version, error) {
+	ver = strings.TrimSpace(ver)
+	if strings.EqualFold(ver, NightlyVersion) {
+		return NightlyVersion, nil
+	}
+	v := ver
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	if !semver.IsValid(v) {
+		return "", fmt.Errorf("version %s is not a valid SemVer string", ver)
+	}
+	return Version(semver.Canonical(v) + semver.Build(v)), nil
+}
+
+// Normalize returns the version normalized by Parse, it's returned as it is
+// if it's not valid
+func Normalize(ver string) string {
+	v, err := Parse(ver)
+	if err != nil {
+		return ver
+	}
+	return v.String()
+}
+
+// String implements the fmt.Stringer interface
+func (v Version) String() string {
+	return string(v)
+}
+
+// IsNightly returns true if it's NightlyVersion or a daily build
+func (v Version) IsNightly() bool {
+	return strings.Contains(string(v), NightlyVersion)
+}
+
+// IsPrerelease returns true if it's not a formal release, e.g., v5.0.0-rc,
+// the nightly versions are not either
+func (v Version) IsPrerelease() bool {
+	return v.IsNightly() || semver.Prerelease(string(v)) != ""
+}
+
+// Major returns the major release of the version, e.g., v4, it's empty for
+// NightlyVersion
+func (v Version) Major() string {
+	return semver.Major(string(v))
+}
+
+// MajorMinor returns the minor release of the version, e.g., v4.0, it's
+// empty for NightlyVersion
+func (v Version) MajorMinor() string {
+	return semver.MajorMinor(string(v))
+}
+
+// Compare returns -1, 0 or 1 if v is lower than, equal to or higher than o.
+// NightlyVersion is the latest build of the master branch, so it's higher
+// than any other version, and the daily builds are prereleases of their
+// versions as SemVer defines.
+func (v Version) Compare(o Version) int {
+	switch {
+	case v == o:
+		return 0
+	case v == NightlyVersion:
+		return 1
+	case o == NightlyVersion:
+		return -1
+	}
+	return semver.Compare(string(v), string(o))
+}
+
+// Compare returns -1, 0 or 1 if the version a is lower than, equal to or
+// higher than b, see Version.Compare. The invalid versions are lower than
+// the valid ones, and compared as strings among themselves, so that the
+// versions are always sorted in the same order.
+func Compare(a, b string) int {
+	va, erra := Parse(a)
+	vb, errb := Parse(b)
+	switch {
+	case erra == nil && errb == nil:
+		return va.Compare(vb)
+	case erra == nil:
+		return 1
+	case errb == nil:
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+// IsNightly returns true if the version is NightlyVersion or a daily build
+func IsNightly(ver string) bool {
+	return Version(strings.ToLower(ver)).IsNightly()
+}
+
+// IsPrerelease returns true if the version is valid and not a formal release
+func IsPrerelease(ver string) bool {
+	v, err := Parse(ver)
+	return err == nil && v.IsPrerelease()
+}
+
+// Constraint is a set of conditions, a version satisfies it if all of the
+// conditions are met
+type Constraint []condition
+
+type condition struct {
+	op      string // one of >=, >, <=, <, = and !=, or empty for matching the release
+	version Version
+}
+
+var constraintOps = []string{">=", "<=", "!=", ">", "<", "="}
+
+// ParseConstraint parses a constraint like ">= v4.0.0, < v5.0.0" or "v4.0",
+// the conditions are separated by commas or spaces. A condition is a version
+// following one of the operators >=, >, <=, <, = and !=, or a version without
+// any operator, which matches the versions of the same major, minor or patch
+// release by how many parts it has, e.g., "v4" matches v4.0.9, and "v4.0"
+// doesn't match v4.1.0. An empty constraint is satisfied by any version.
+func ParseConstraint(s string) (Constraint, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+
+	var c Constraint
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		op := ""
+		for _, o := range constraintOps {
+			if strings.HasPrefix(f, o) {
+				op = o
+				f = strings.TrimPrefix(f, o)
+				break
+			}
+		}
+		// the operator is separated from the version, e.g., ">= v4.0.0"
+		if op != "" && f == "" && i+1 < len(fields) {
+			i++
+			f = fields[i]
+		}
+		v, err := Parse(f)
+		if err != nil || v == NightlyVersion {
+			return nil, fmt.Errorf("invalid version '%s' in constraint '%s'", f, s)
+		}
+		cond := condition{op: op, version: v}
+		if op == "" {
+			// the parts not specified are not matched
+			release := strings.TrimPrefix(strings.SplitN(strings.SplitN(f, "+", 2)[0], "-", 2)[0], "v")
+			switch strings.Count(release, ".") {
+			case 0:
+				cond.op = "major"
+			case 1:
+				cond.op = "minor"
+			default:
+				cond.op = "="
+			}
+		}
+		c = append(c, cond)
+	}
+	return c, nil
+}
+
+// Check returns true if the version satisfies the constraint, the nightly
+// and invalid versions only satisfy the empty constraint
+func (c Constraint) Check(ver string) bool {
+	if len(c) == 0 {
+		return true
+	}
+	v, err := Parse(ver)
+	if err != nil || v.IsNightly() {
+		return false
+	}
+	for _, cond := range c {
+		if !cond.check(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (cond condition) check(v Version) bool {
+	cmp := v.Compare(cond.version)
+	switch cond.op {
+	case ">=":
+		return cmp >= 0
+	case ">":
+		return cmp > 0
+	case "<=":
+		return cmp <= 0
+	case "<":
+		return cmp < 0
+	case "!=":
+		return cmp != 0
+	case "major":
+		return v.Major() == cond.version.Major()
+	case "minor":
+		return v.MajorMinor() == cond.version.MajorMinor()
+	default:
+		return cmp == 0
+	}
+}
+
+// MatchConstraint checks if the version satisfies the constraint, see
+// ParseConstraint for the syntax
+func MatchConstraint(ver, constraint string) (bool, error) {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return false, err
+	}
+	return c.Check(ver), nil
+}
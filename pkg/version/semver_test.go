@@ -0,0 +1,155 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	for ver, expected := range map[string]string{
+		"v4.0.0":                       "v4.0.0",
+		"4.0.0":                        "v4.0.0",
+		" v4.0.0 ":                     "v4.0.0",
+		"v6.5":                         "v6.5.0",
+		"6":                            "v6.0.0",
+		"v5.0.0-rc":                    "v5.0.0-rc",
+		"5.0.0-rc.1":                   "v5.0.0-rc.1",
+		"v6.5.0+build.1":               "v6.5.0+build.1",
+		"6.5.0+20230101":               "v6.5.0+20230101",
+		"v5.0.0-nightly-20201231":      "v5.0.0-nightly-20201231",
+		"5.0.0-nightly-20201231+abcde": "v5.0.0-nightly-20201231+abcde",
+		"nightly":                      "nightly",
+		"Nightly":                      "nightly",
+	} {
+		v, err := Parse(ver)
+		require.Nil(t, err, ver)
+		assert.Equal(t, expected, v.String(), ver)
+		assert.Equal(t, expected, Normalize(ver), ver)
+	}
+
+	for _, ver := range []string{"", "v", "latest", "v4.x", "4.0.0.1", "vv4.0.0", "v04.0.0", "v4.0.0-", "nightly-20201231"} {
+		_, err := Parse(ver)
+		assert.NotNil(t, err, ver)
+		assert.Equal(t, ver, Normalize(ver), ver)
+	}
+}
+
+func TestNightlyAndPrerelease(t *testing.T) {
+	for _, ver := range []string{"nightly", "Nightly", "v5.0.0-nightly-20201231", "v4.0.0-beta-nightly-20200603"} {
+		assert.True(t, IsNightly(ver), ver)
+		assert.True(t, IsPrerelease(ver), ver)
+	}
+	for _, ver := range []string{"v5.0.0-rc", "5.0.0-beta.1", "v6.5.0-alpha+build"} {
+		assert.False(t, IsNightly(ver), ver)
+		assert.True(t, IsPrerelease(ver), ver)
+	}
+	for _, ver := range []string{"v5.0.0", "5.0.0", "v6.5.0+build", "invalid"} {
+		assert.False(t, IsNightly(ver), ver)
+		assert.False(t, IsPrerelease(ver), ver)
+	}
+
+	v, err := Parse("v6.5.3+build")
+	require.Nil(t, err)
+	assert.Equal(t, "v6", v.Major())
+	assert.Equal(t, "v6.5", v.MajorMinor())
+	assert.Equal(t, "", Version(NightlyVersion).MajorMinor())
+}
+
+func TestCompare(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		cmp  int
+	}{
+		// the v prefix and the missing parts don't matter
+		{"v6.5.0", "6.5.0", 0},
+		{"6.5", "v6.5.0", 0},
+		{"v6.5.0", "v6.5.1", -1},
+		{"6.10.0", "v6.9.0", 1},
+		// the build metadata is ignored
+		{"v6.5.0+build.1", "v6.5.0", 0},
+		{"v6.5.0+build.1", "v6.5.0+build.2", 0},
+		{"v6.5.0+build.1", "v6.5.1", -1},
+		// the prereleases are lower than their releases
+		{"v5.0.0-rc", "v5.0.0", -1},
+		{"v5.0.0-rc", "v5.0.0-beta", 1},
+		{"v5.0.0-rc", "v4.0.9", 1},
+		// the daily builds are prereleases, the nightly alias is the latest
+		{"v5.0.0-nightly-20201231", "v5.0.0", -1},
+		{"v5.0.0-nightly-20201231", "v5.0.0-nightly-20210101", -1},
+		{"v5.0.0-nightly-20201231", "v4.0.9", 1},
+		{"nightly", "v99.0.0", 1},
+		{"nightly", "v5.0.0-nightly-20201231", 1},
+		{"v4.0.0", "nightly", -1},
+		{"nightly", "Nightly", 0},
+		// the invalid versions are lower than the valid ones
+		{"latest", "v0.0.1", -1},
+		{"v0.0.1", "latest", 1},
+		{"abc", "abd", -1},
+		{"abc", "abc", 0},
+	} {
+		assert.Equal(t, tc.cmp, Compare(tc.a, tc.b), "%s %s", tc.a, tc.b)
+		assert.Equal(t, -tc.cmp, Compare(tc.b, tc.a), "%s %s", tc.b, tc.a)
+	}
+
+	// the nightly version is the last one of the list, rather than the first
+	versions := []string{"nightly", "v4.0.10", "v4.0.9", "v5.0.0-rc", "v5.0.0", "v3.0.0"}
+	sort.Slice(versions, func(i, j int) bool {
+		return Compare(versions[i], versions[j]) < 0
+	})
+	assert.Equal(t, []string{"v3.0.0", "v4.0.9", "v4.0.10", "v5.0.0-rc", "v5.0.0", "nightly"}, versions)
+}
+
+func TestConstraint(t *testing.T) {
+	for _, tc := range []struct {
+		constraint string
+		matched    []string
+		unmatched  []string
+	}{
+		{"", []string{"v4.0.0", "nightly", "invalid"}, nil},
+		{"v4", []string{"v4.0.0", "v4.1.2", "4.0.0-rc"}, []string{"v3.0.20", "v5.0.0", "v4.0.0-beta-nightly-20200603"}},
+		{"4.0", []string{"v4.0.0", "v4.0.9", "v4.0.9+build"}, []string{"v4.1.0"}},
+		{"v4.0.1", []string{"v4.0.1", "4.0.1", "v4.0.1+build"}, []string{"v4.0.10"}},
+		{">=v4.0.0, <v5.0.0", []string{"v4.0.0", "v4.9.0"}, []string{"v4.0.0-rc", "v5.0.0"}},
+		{">= v4.0.0, < v5.0.0", []string{"v4.0.8"}, []string{"v5.0.0", "nightly"}},
+		{">v4.0.0 <=v4.0.2", []string{"v4.0.1", "v4.0.2"}, []string{"v4.0.0", "v4.0.3"}},
+		{"=v4.0.0", []string{"v4.0.0"}, []string{"v4.0.1"}},
+		{"!= v4.0.0", []string{"4.0.1"}, []string{"v4.0.0", "invalid"}},
+		{"<=3.1.2", []string{"v3.1.2"}, nil},
+		{">3.1.2", nil, []string{"v3.1.2"}},
+	} {
+		c, err := ParseConstraint(tc.constraint)
+		require.Nil(t, err, tc.constraint)
+		for _, v := range tc.matched {
+			assert.True(t, c.Check(v), "%s should satisfy %s", v, tc.constraint)
+			match, err := MatchConstraint(v, tc.constraint)
+			assert.Nil(t, err)
+			assert.True(t, match, "%s should satisfy %s", v, tc.constraint)
+		}
+		for _, v := range tc.unmatched {
+			assert.False(t, c.Check(v), "%s should not satisfy %s", v, tc.constraint)
+		}
+	}
+
+	for _, s := range []string{">=", ">= four", ">=v4.x", "latest", "nightly", "v4.0.0, >="} {
+		_, err := ParseConstraint(s)
+		assert.NotNil(t, err, s)
+		_, err = MatchConstraint("v4.0.0", s)
+		assert.NotNil(t, err, s)
+	}
+}
@@ -9,6 +9,7 @@ global:
 
 # Load and evaluate rules in this file every 'evaluation_interval' seconds.
 rule_files:
+  # BEGIN managed by tiup, regenerated on changes of the cluster, add rule files after the END line
   - 'node.rules.yml'
   - 'blacker.rules.yml'
   - 'bypass.rules.yml'
@@ -31,6 +32,7 @@ rule_files:
 {{- if .LightningAddrs}}
   - 'lightning.rules.yml'
 {{- end}}
+  # END managed by tiup
 
 {{- if .AlertmanagerAddrs}}
 alerting:
@@ -43,6 +45,7 @@ alerting:
 {{- end}}
 
 scrape_configs:
+  # BEGIN managed by tiup, regenerated on changes of the cluster, add scrape jobs after the END line
 {{- if .PushgatewayAddr}}
   - job_name: 'overwritten-cluster'
     scrape_interval: 15s
@@ -277,4 +280,5 @@ scrape_configs:
         regex: .*
         target_label: __address__
         replacement: {{$addr}}
-{{- end}}
\ No newline at end of file
+{{- end}}
+  # END managed by tiup
@@ -12,8 +12,14 @@ mkdir -p {{.DeployDir}}/provisioning/dashboards
 mkdir -p {{.DeployDir}}/provisioning/datasources
 
 cp {{.DeployDir}}/bin/*.json {{.DeployDir}}/dashboards/
+{{- if .ProvisionFiles}}
 cp {{.DeployDir}}/conf/datasource.yml {{.DeployDir}}/provisioning/datasources
 cp {{.DeployDir}}/conf/dashboard.yml {{.DeployDir}}/provisioning/dashboards
+{{- else}}
+# the dashboards and the datasource are provisioned via the HTTP API
+rm -f {{.DeployDir}}/provisioning/datasources/datasource.yml
+rm -f {{.DeployDir}}/provisioning/dashboards/dashboard.yml
+{{- end}}
 
 find {{.DeployDir}}/dashboards/ -type f -exec sed -i "s/\${DS_.*-CLUSTER}/{{.ClusterName}}/g" {} \;
 find {{.DeployDir}}/dashboards/ -type f -exec sed -i "s/\${DS_LIGHTNING}/{{.ClusterName}}/g" {} \;
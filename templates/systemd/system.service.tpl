@@ -18,6 +18,9 @@ IOWriteBandwidthMax={{.IOWriteBandwidthMax}}
 LimitNOFILE=1000000
 #LimitCORE=infinity
 LimitSTACK=10485760
+{{- if .EnvironmentFile}}
+EnvironmentFile={{.EnvironmentFile}}
+{{- end}}
 
 User={{.User}}
 ExecStart={{.DeployDir}}/scripts/run_{{.ServiceName}}.sh
@@ -7,6 +7,9 @@ User={{.User}}
 {{- if ne .JavaHome ""}}
 Environment="JAVA_HOME={{.JavaHome}}"
 {{- end}}
+{{- if .EnvironmentFile}}
+EnvironmentFile={{.EnvironmentFile}}
+{{- end}}
 ExecStart={{.DeployDir}}/sbin/start-{{.ServiceName}}.sh
 ExecStop={{.DeployDir}}/sbin/stop-{{.ServiceName}}.sh
 Type=forking